@@ -0,0 +1,217 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/agent"
+	"github.com/OpenCHAMI/ochami/internal/config"
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// agentServices are the services whose GET requests the agent serves.
+var agentServices = []config.ServiceName{
+	config.ServiceBSS,
+	config.ServiceCloudInit,
+	config.ServicePCS,
+	config.ServiceSMD,
+}
+
+// isAgent is set when this process is the agent, so that it does not try to use
+// itself.
+var isAgent bool
+
+// agentCmd represents the "agent" command
+var agentCmd = &cobra.Command{
+	Use:   "agent [--socket <path>] [--refresh-before <duration>] [--cache-ttl <duration>] [--warm <service>:<path>]...",
+	Args:  cobra.NoArgs,
+	Short: "Keep the token of a cluster fresh and serve cached queries over a Unix socket",
+	Long: `Run the ochami agent for a cluster in the foreground until it is
+interrupted. The agent keeps the access token of the cluster fresh and
+caches the responses to GET requests to its services, and serves both
+over an HTTP API on a Unix socket that only the current user can
+connect to.
+
+While the agent runs, other ochami invocations for the cluster get
+their token from it (unless --token or the cluster's access token
+environment variable is set) and send their GET requests through it,
+so that repeated queries (e.g. by shell completion or scripts) are
+served from its cache. Pass --no-agent to any command to bypass it.
+
+The token is refreshed --refresh-before it expires (every
+--refresh-before if its expiration is unknown) by running the
+token-command of the cluster, or by reading its access-token from the
+config again if it has none. Responses are cached for --cache-ttl.
+Those that keep being requested, and the endpoints passed with --warm,
+are refreshed in the background so that they never expire.
+
+See ochami-agent(1) for the HTTP API of the agent and more details.`,
+	Example: `  # Run the agent for the default cluster
+  ochami agent
+
+  # Run the agent for cluster foobar, keeping the list of components warm
+  ochami --cluster foobar agent --warm smd:/State/Components
+
+  # Query the agent from a site tool
+  curl --unix-socket $XDG_RUNTIME_DIR/ochami/agent-foobar.sock \
+    http://ochami-agent/v1/services/smd/State/Components`,
+	Run: func(cmd *cobra.Command, args []string) {
+		isAgent = true
+		cl, ok := getCluster(cmd)
+		if !ok {
+			log.Logger.Error().Msg("no cluster to run agent for, pass --cluster or set default-cluster")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		socket := agentSocketFlag(cmd, cl.Name)
+		refreshBefore, err := cmd.Flags().GetDuration("refresh-before")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --refresh-before")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		cacheTTL, err := cmd.Flags().GetDuration("cache-ttl")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --cache-ttl")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		warm, err := cmd.Flags().GetStringArray("warm")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --warm")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Serve the services whose base URI can be determined
+		cfg := agent.Config{
+			Cluster:       cl.Name,
+			Services:      make(map[string]string),
+			RefreshBefore: refreshBefore,
+			CacheTTL:      cacheTTL,
+			Warm:          warm,
+		}
+		for _, svc := range agentServices {
+			uri, err := getBaseURI(cmd, svc)
+			if err != nil {
+				log.Logger.Debug().Err(err).Msgf("not serving %s", svc)
+				continue
+			}
+			cfg.Services[string(svc)] = uri
+		}
+
+		// Requests to the services honor --insecure and --cacert
+		oc, err := client.NewOchamiClient("agent", "", insecure)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("error creating new client")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		useCACert(oc)
+		cfg.Client = oc.Client
+
+		// Refresh the token from the config of the cluster unless it
+		// was passed with --token or in the environment
+		handleToken(cmd)
+		cfg.Token = token
+		_, envSet := os.LookupEnv(clusterEnvVar(cl.Name, "ACCESS_TOKEN"))
+		if token != "" && !cmd.Flag("token").Changed && !envSet {
+			cfg.TokenSource = func() (string, error) {
+				if err := initConfig(cmd, false); err != nil {
+					return "", err
+				}
+				return clusterConfigToken(cl.Name)
+			}
+		}
+
+		a, err := agent.New(cfg)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to create agent")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		log.Logger.Info().Msgf("agent for cluster %s listening on %s", cl.Name, socket)
+		if err := a.Serve(ctx, socket); err != nil {
+			log.Logger.Error().Err(err).Msg("agent failed")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		log.Logger.Info().Msg("agent stopped")
+	},
+}
+
+// agentStatusCmd represents the "agent status" command
+var agentStatusCmd = &cobra.Command{
+	Use:   "status [--socket <path>]",
+	Args:  cobra.NoArgs,
+	Short: "Print the status of the running agent",
+	Long: `Print the status of the ochami agent running for the cluster: its
+services, when its token expires and was last refreshed (and why the
+last refresh failed, if it did), and statistics of its cache. If no
+agent is running, the command exits with a status of 1.
+
+See ochami-agent(1) for more details.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cl, ok := getCluster(cmd)
+		if !ok {
+			log.Logger.Error().Msg("no cluster to get agent status of, pass --cluster or set default-cluster")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		socket := agentSocketFlag(cmd, cl.Name)
+		status, err := agent.GetStatus(socket)
+		if err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to get status of agent at %s", socket)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if outBytes, err := format.MarshalData(status, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+	},
+}
+
+// agentSocketFlag returns the value of --socket, or the default socket path of
+// the agent of clusterName if it was not passed.
+func agentSocketFlag(cmd *cobra.Command, clusterName string) string {
+	socket, err := cmd.Flags().GetString("socket")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --socket")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if socket == "" {
+		socket = agent.DefaultSocketPath(clusterName)
+	}
+
+	return socket
+}
+
+func init() {
+	agentCmd.PersistentFlags().String("socket", "", "path of the socket of the agent (default: $XDG_RUNTIME_DIR/ochami/agent-<cluster>.sock)")
+	agentCmd.Flags().Duration("refresh-before", agent.DefaultRefreshBefore, "how long before it expires to refresh the token")
+	agentCmd.Flags().Duration("cache-ttl", agent.DefaultCacheTTL, "how long to serve responses from the cache (0 disables caching)")
+	agentCmd.Flags().StringArray("warm", []string{}, "endpoint to keep in the cache (<service>:<path>, e.g. smd:/State/Components), can be passed more than once")
+	agentStatusCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	agentCmd.MarkPersistentFlagFilename("socket")
+	agentStatusCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	agentCmd.AddCommand(agentStatusCmd)
+	rootCmd.AddCommand(agentCmd)
+}
@@ -0,0 +1,122 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/config"
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/authcheck"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// authCheckCmd represents the "auth check" command
+var authCheckCmd = &cobra.Command{
+	Use:   "check [--only <service>[:<operation>],...] [-F <format>]",
+	Args:  cobra.NoArgs,
+	Short: "Check which operations the access token is authorized for",
+	Long: `Check which operations the current access token is authorized for on
+each service (SMD, BSS, cloud-init, and PCS), so that missing
+privileges are found before a long workflow fails partway through.
+
+For each service, reading and writing are checked by sending a test
+request with the token: a GET that returns little data for reading,
+and a POST whose payload is not valid JSON for writing, which an
+authorized request has rejected before anything is written. Requests
+rejected with 401 or 403 are denied. If the token is a JWT with
+scopes, whether its scopes grant each operation is reported as well.
+
+Pass --only to check some services or operations only, e.g.
+--only smd:write,bss. The report is printed, and the command fails if
+any checked operation is not known to be allowed, including when a
+service is not configured or cannot be reached.
+
+See ochami-auth(1) for more details.`,
+	Example: `  # Check all operations on all services
+  ochami auth check
+
+  # Check that the token can write to SMD and BSS before a discovery run
+  ochami auth check --only smd:write,bss:write`,
+	Run: func(cmd *cobra.Command, args []string) {
+		only, err := cmd.Flags().GetStringSlice("only")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --only")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		probes, err := authcheck.ParseOnly(only)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("invalid value for --only")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Create clients for the services that are configured
+		clients := make(map[string]*client.OchamiClient)
+		for _, p := range probes {
+			if _, ok := clients[p.Service]; ok {
+				continue
+			}
+			if _, err := getBaseURI(cmd, config.ServiceName(p.Service)); err != nil {
+				log.Logger.Debug().Err(err).Msgf("not checking %s", p.Service)
+				clients[p.Service] = nil
+				continue
+			}
+			switch config.ServiceName(p.Service) {
+			case config.ServiceSMD:
+				clients[p.Service] = smdGetClient(cmd).OchamiClient
+			case config.ServiceBSS:
+				clients[p.Service] = bssGetClient(cmd).OchamiClient
+			case config.ServiceCloudInit:
+				clients[p.Service] = cloudInitGetClient(cmd).OchamiClient
+			case config.ServicePCS:
+				clients[p.Service] = pcsGetClient(cmd).OchamiClient
+			}
+		}
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		r := authcheck.CheckToken(clients, probes, token)
+		if outBytes, err := format.MarshalData(r, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+
+		if failed := r.Failed(); len(failed) > 0 {
+			for _, c := range failed {
+				log.Logger.Error().Msgf("%s %s: %s (%s)", c.Service, c.Operation, c.Result, c.Detail)
+			}
+			log.Logger.Error().Msgf("%d of %d operation(s) not known to be allowed", len(failed), len(r.Checks))
+			exitWithStatus(1)
+		}
+	},
+}
+
+func init() {
+	authCheckCmd.Flags().StringSlice("only", []string{}, "only check these services or operations (<service>[:read|write], e.g. smd:write)")
+	authCheckCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	authCheckCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+	authCheckCmd.RegisterFlagCompletionFunc("only", authCheckCompletionOnly)
+
+	authCmd.AddCommand(authCheckCmd)
+}
+
+// authCheckCompletionOnly completes the services and operations that can be
+// passed to --only.
+func authCheckCompletionOnly(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var comps []string
+	for _, svc := range authcheck.Services {
+		comps = append(comps, svc, svc+":"+authcheck.OpRead, svc+":"+authcheck.OpWrite)
+	}
+	return comps, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,30 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// authCmd represents the auth command
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Args:  cobra.NoArgs,
+	Short: "Inspect the access token used for OpenCHAMI services",
+	Long: `Inspect the access token used for OpenCHAMI services.
+
+See ochami-auth(1) for more details.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Check that all required args are passed
+		if len(args) == 0 {
+			printUsageHandleError(cmd)
+			os.Exit(0)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(authCmd)
+}
@@ -0,0 +1,186 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/internal/version"
+	"github.com/OpenCHAMI/ochami/pkg/backup"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+)
+
+// backupCreateCmd represents the "backup create" command
+var backupCreateCmd = &cobra.Command{
+	Use:   "create <file>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Back up cluster configuration to an archive",
+	Long: `Back up the configuration of the cluster to an archive. The archive
+captures the SMD components, redfish endpoints, ethernet interfaces,
+and groups, the BSS boot parameters, and the cloud-init cluster
+defaults and groups, along with a manifest recording when the backup
+was made, the ochami version, and the versions reported by the
+services. It can be restored with 'ochami backup restore'.
+
+The compression of the archive is determined by the extension of
+file: .tar for none, .tar.gz or .tgz for gzip, and .tar.zst or .tzst
+for zstd. zstd compression requires the zstd program to be in PATH.
+
+The archive is written to a temporary file that replaces file only
+once the backup is complete, so a failed backup never leaves a
+partial archive behind.
+
+See ochami-backup(1) for more details.`,
+	Example: `  # Back up the default cluster
+  ochami backup create backup.tar.zst
+
+  # Back up cluster foobar with gzip compression
+  ochami --cluster foobar backup create foobar-$(date +%F).tar.gz`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path := args[0]
+		compression, err := backup.Compression(path)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to determine backup archive compression")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Create clients to use for requests
+		smdClient := smdGetClient(cmd)
+		bssClient := bssGetClient(cmd)
+		cloudInitClient := cloudInitGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		var clusterName string
+		if cl, ok := getCluster(cmd); ok {
+			clusterName = cl.Name
+		}
+		b := backup.New(clusterName, version.Version, time.Now())
+
+		// Large datasets are spilled to temporary files if they are too
+		// large to hold in memory, which are removed once the archive
+		// is written
+		var bodies []*client.StreamBody
+		closeBodies := func() {
+			for _, body := range bodies {
+				body.Close()
+			}
+		}
+		defer closeBodies()
+
+		// Every dataset must be captured for the backup to be usable
+		capture := func(service, name, what string, henv client.HTTPEnvelope, err error) {
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msgf("%s request yielded unsuccessful HTTP response", what)
+				} else {
+					log.Logger.Error().Err(err).Msgf("failed to get %s", what)
+				}
+				closeBodies()
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			b.Add(service, name, henv.Body)
+			log.Logger.Info().Msgf("captured %s", what)
+		}
+		captureStream := func(service, name, what string, henv client.HTTPEnvelope, body *client.StreamBody, err error) {
+			if err != nil {
+				capture(service, name, what, henv, err)
+			}
+			bodies = append(bodies, body)
+			b.AddSource(service, name, body)
+			log.Logger.Info().Msgf("captured %s", what)
+		}
+		// Versions are only informational, so failing to get them is
+		// only a warning
+		setVersion := func(service string, henv client.HTTPEnvelope, err error) {
+			if err != nil {
+				log.Logger.Warn().Err(err).Msgf("failed to get %s version, not recording it in backup manifest", service)
+				return
+			}
+			b.SetVersion(service, henv.Body)
+		}
+
+		// SMD
+		henv, body, err := smdClient.GetComponentsAllStream()
+		captureStream(backup.ServiceSMD, backup.FileSMDComponents, "SMD components", henv, body, err)
+		henv, err = smdClient.GetRedfishEndpoints("", token)
+		capture(backup.ServiceSMD, backup.FileSMDRedfishEndpoints, "SMD redfish endpoints", henv, err)
+		henv, body, err = smdClient.GetEthernetInterfacesStream("", token)
+		captureStream(backup.ServiceSMD, backup.FileSMDEthernetInterfaces, "SMD ethernet interfaces", henv, body, err)
+		henv, err = smdClient.GetGroups("", token)
+		capture(backup.ServiceSMD, backup.FileSMDGroups, "SMD groups", henv, err)
+
+		// BSS
+		henv, err = bssClient.GetStatus("version")
+		setVersion(backup.ServiceBSS, henv, err)
+		// BSS responds with 404 if there are no boot parameters
+		henv, body, err = bssClient.GetBootParamsStream("", token)
+		if err != nil && henv.StatusCode == http.StatusNotFound {
+			henv.Body, err = []byte("[]"), nil
+			capture(backup.ServiceBSS, backup.FileBSSBootParams, "BSS boot parameters", henv, err)
+		} else {
+			captureStream(backup.ServiceBSS, backup.FileBSSBootParams, "BSS boot parameters", henv, body, err)
+		}
+
+		// cloud-init
+		henv, err = cloudInitClient.GetVersion()
+		setVersion(backup.ServiceCloudInit, henv, err)
+		henv, err = cloudInitClient.GetDefaults(token)
+		capture(backup.ServiceCloudInit, backup.FileCloudInitDefaults, "cloud-init cluster defaults", henv, err)
+		henvs, errs, err := cloudInitClient.GetGroups(token)
+		if err == nil {
+			err = errs[0]
+			henv = henvs[0]
+		}
+		capture(backup.ServiceCloudInit, backup.FileCloudInitGroups, "cloud-init groups", henv, err)
+
+		// Write archive to a temporary file next to the destination so
+		// that it can be renamed into place
+		f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+		if err != nil {
+			closeBodies()
+			log.Logger.Error().Err(err).Msg("failed to create backup archive")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		fail := func(err error, msg string) {
+			closeBodies()
+			f.Close()
+			os.Remove(f.Name())
+			log.Logger.Error().Err(err).Msg(msg)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		w, err := backup.NewWriter(f, compression)
+		if err != nil {
+			fail(err, "failed to compress backup archive")
+		}
+		if err := backup.Write(w, b); err != nil {
+			fail(err, "failed to write backup archive")
+		}
+		if err := w.Close(); err != nil {
+			fail(err, "failed to compress backup archive")
+		}
+		if err := f.Close(); err != nil {
+			fail(err, "failed to write backup archive")
+		}
+		if err := os.Rename(f.Name(), path); err != nil {
+			fail(err, "failed to move backup archive into place")
+		}
+		log.Logger.Info().Msgf("wrote backup of %d dataset(s) to %s", b.Len(), path)
+	},
+}
+
+func init() {
+	backupCmd.AddCommand(backupCreateCmd)
+}
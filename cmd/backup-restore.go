@@ -0,0 +1,486 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+	"github.com/openchami/schemas/schemas/csm"
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/backup"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/client/ci"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// backupRestoreCmd represents the "backup restore" command
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore [--only <service>,...] [--overwrite] [--no-confirm] <file>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Restore cluster configuration from an archive",
+	Long: `Restore the configuration of the cluster from an archive created by
+'ochami backup create'. By default, the data of every service in the
+archive is restored. Pass --only to restore only the data of some of
+them (smd, bss, cloud-init).
+
+Each record is created in its service. Records that already exist are
+skipped with a warning unless --overwrite is passed, in which case
+they are replaced with those of the backup. Records that exist in the
+cluster but not in the backup are left alone.
+
+Since SMD does not return BMC credentials, redfish endpoints are
+restored without them.
+
+See ochami-backup(1) for more details.`,
+	Example: `  # Restore everything in a backup
+  ochami backup restore backup.tar.zst
+
+  # Restore only the BSS boot parameters, replacing existing ones
+  ochami backup restore --only bss --overwrite backup.tar.zst`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path := args[0]
+		only, err := cmd.Flags().GetStringSlice("only")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --only")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, s := range only {
+			if !slices.Contains(backup.Services, s) {
+				log.Logger.Error().Msgf("unknown service %q passed to --only (must be one of: %s)", s, strings.Join(backup.Services, ","))
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+		overwrite := cmd.Flag("overwrite").Changed
+
+		b := backupRead(cmd, path)
+
+		// Determine which services to restore
+		var services []string
+		for _, s := range backup.Services {
+			if len(only) > 0 && !slices.Contains(only, s) {
+				continue
+			}
+			if _, ok := b.Manifest.Services[s]; !ok {
+				if len(only) > 0 {
+					log.Logger.Warn().Msgf("backup has no %s data, not restoring it", s)
+				}
+				continue
+			}
+			services = append(services, s)
+		}
+		if len(services) == 0 {
+			log.Logger.Error().Msg("backup has no data to restore")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Ask before restoring unless --no-confirm was passed
+		if !cmd.Flag("no-confirm").Changed {
+			log.Logger.Debug().Msg("--no-confirm not passed, prompting user to confirm restore")
+			from := "backup"
+			if b.Manifest.Cluster != "" {
+				from = fmt.Sprintf("backup of cluster %s", b.Manifest.Cluster)
+			}
+			prompt := fmt.Sprintf("Really restore %s data from %s created %s?", strings.Join(services, ", "), from, b.Manifest.Created.Format("2006-01-02 15:04:05 MST"))
+			if overwrite {
+				prompt = fmt.Sprintf("Really restore %s data from %s created %s, overwriting existing records?", strings.Join(services, ", "), from, b.Manifest.Created.Format("2006-01-02 15:04:05 MST"))
+			}
+			respRestore, err := ios.loopYesNo(prompt)
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("Error fetching user input")
+				os.Exit(1)
+			} else if !respRestore {
+				log.Logger.Info().Msg("User aborted restore")
+				os.Exit(0)
+			} else {
+				log.Logger.Debug().Msg("User answered affirmatively to restore")
+			}
+		}
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		unmarshal := func(name string, v any) {
+			data, _ := b.File(name)
+			if err := json.Unmarshal(data, v); err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to unmarshal %s from backup", name)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+		has := func(name string) bool {
+			_, ok := b.File(name)
+			return ok
+		}
+
+		var failed []string
+		for _, service := range services {
+			var errorsOccurred bool
+			switch service {
+			case backup.ServiceSMD:
+				smdClient := smdGetClient(cmd)
+
+				// Components are restored first so that their NIDs
+				// are not generated from redfish endpoints
+				if has(backup.FileSMDComponents) {
+					var comps smd.ComponentSlice
+					unmarshal(backup.FileSMDComponents, &comps)
+					errorsOccurred = backupRestoreComponents(smdClient, comps, overwrite) || errorsOccurred
+				}
+				if has(backup.FileSMDRedfishEndpoints) {
+					var rfes smd.RedfishEndpointSlice
+					unmarshal(backup.FileSMDRedfishEndpoints, &rfes)
+					errorsOccurred = backupRestoreEach(rfes.RedfishEndpoints, "SMD redfish endpoint", overwrite,
+						func(rfes []csm.RedfishEndpoint) string { return rfes[0].ID },
+						func(rfes []csm.RedfishEndpoint) ([]client.HTTPEnvelope, []error, error) {
+							return smdClient.PostRedfishEndpoints(smd.RedfishEndpointSlice{RedfishEndpoints: rfes}, token)
+						},
+						func(rfes []csm.RedfishEndpoint) ([]client.HTTPEnvelope, []error, error) {
+							return smdClient.PutRedfishEndpoints(smd.RedfishEndpointSlice{RedfishEndpoints: rfes}, token)
+						},
+					) || errorsOccurred
+				}
+				if has(backup.FileSMDEthernetInterfaces) {
+					var eis []smd.EthernetInterface
+					unmarshal(backup.FileSMDEthernetInterfaces, &eis)
+					errorsOccurred = backupRestoreEach(eis, "SMD ethernet interface", overwrite,
+						func(eis []smd.EthernetInterface) string { return eis[0].MACAddress },
+						func(eis []smd.EthernetInterface) ([]client.HTTPEnvelope, []error, error) {
+							return smdClient.PostEthernetInterfaces(eis, token)
+						},
+						func(eis []smd.EthernetInterface) ([]client.HTTPEnvelope, []error, error) {
+							return smdClient.PatchEthernetInterfaces(eis, token)
+						},
+					) || errorsOccurred
+				}
+				if has(backup.FileSMDGroups) {
+					var groups []smd.Group
+					unmarshal(backup.FileSMDGroups, &groups)
+					errorsOccurred = backupRestoreEach(groups, "SMD group", overwrite,
+						func(groups []smd.Group) string { return groups[0].Label },
+						func(groups []smd.Group) ([]client.HTTPEnvelope, []error, error) {
+							return smdClient.PostGroups(groups, token)
+						},
+						func(groups []smd.Group) ([]client.HTTPEnvelope, []error, error) {
+							return smdClient.PatchGroups(groups, token)
+						},
+					) || errorsOccurred
+				}
+			case backup.ServiceBSS:
+				bssClient := bssGetClient(cmd)
+
+				if has(backup.FileBSSBootParams) {
+					var bps []bssTypes.BootParams
+					unmarshal(backup.FileBSSBootParams, &bps)
+					// BSS does not refuse to add boot parameters
+					// that exist, so only add those that do not
+					// exist unless overwriting
+					errorsOccurred = backupRestoreEach(bps, "BSS boot parameters", overwrite,
+						func(bps []bssTypes.BootParams) string { return backupBootParamsID(bps[0]) },
+						func(bps []bssTypes.BootParams) ([]client.HTTPEnvelope, []error, error) {
+							if overwrite {
+								henv, err := bssClient.PutBootParams(bps[0], token)
+								return []client.HTTPEnvelope{henv}, []error{err}, nil
+							}
+							henv, err := backupBootParamsExist(bssClient, bps[0])
+							if err != nil || henv.StatusCode == http.StatusConflict {
+								return []client.HTTPEnvelope{henv}, []error{err}, nil
+							}
+							henv, err = bssClient.PostBootParams(bps[0], token)
+							return []client.HTTPEnvelope{henv}, []error{err}, nil
+						},
+						nil,
+					) || errorsOccurred
+				}
+			case backup.ServiceCloudInit:
+				cloudInitClient := cloudInitGetClient(cmd)
+
+				if has(backup.FileCloudInitDefaults) {
+					var dflts cistore.ClusterDefaults
+					unmarshal(backup.FileCloudInitDefaults, &dflts)
+					errorsOccurred = backupRestoreCloudInitDefaults(cloudInitClient, dflts, overwrite) || errorsOccurred
+				}
+				if has(backup.FileCloudInitGroups) {
+					var groupMap map[string]cistore.GroupData
+					unmarshal(backup.FileCloudInitGroups, &groupMap)
+					names := make([]string, 0, len(groupMap))
+					for name := range groupMap {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+					groups := make([]cistore.GroupData, 0, len(names))
+					for _, name := range names {
+						g := groupMap[name]
+						if g.Name == "" {
+							g.Name = name
+						}
+						groups = append(groups, g)
+					}
+					errorsOccurred = backupRestoreEach(groups, "cloud-init group", overwrite,
+						func(groups []cistore.GroupData) string { return groups[0].Name },
+						func(groups []cistore.GroupData) ([]client.HTTPEnvelope, []error, error) {
+							return cloudInitClient.PostGroups(groups, token)
+						},
+						func(groups []cistore.GroupData) ([]client.HTTPEnvelope, []error, error) {
+							return cloudInitClient.PutGroups(groups, token)
+						},
+					) || errorsOccurred
+				}
+			}
+			if errorsOccurred {
+				failed = append(failed, service)
+			} else {
+				log.Logger.Info().Msgf("restored %s data", service)
+			}
+		}
+
+		if len(failed) > 0 {
+			logHelpError(cmd)
+			log.Logger.Warn().Msgf("restore of %s data completed with errors", strings.Join(failed, ", "))
+			os.Exit(1)
+		}
+	},
+}
+
+// backupRead reads the backup archive at path, exiting if it cannot be read.
+func backupRead(cmd *cobra.Command, path string) backup.Backup {
+	compression, err := backup.Compression(path)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to determine backup archive compression")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to open backup archive")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	defer f.Close()
+	r, err := backup.NewReader(f, compression)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to decompress backup archive")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	b, err := backup.Read(r)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to read backup archive")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if err := r.Close(); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to decompress backup archive")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return b
+}
+
+// backupRestoreEach sends post for each item in items, one at a time, and
+// returns true if any errors occurred. If post responds that an item already
+// exists (409), it is sent with update if overwrite is true and update is not
+// nil, otherwise it is skipped with a warning. id returns the identifier of an
+// item to log. Since the client functions take slices, each is passed a slice
+// of one item.
+func backupRestoreEach[T any](items []T, what string, overwrite bool, id func([]T) string, post, update func([]T) ([]client.HTTPEnvelope, []error, error)) bool {
+	var errorsOccurred bool
+	logErr := func(err error, verb, name string) {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msgf("%s %s request for %s yielded unsuccessful HTTP response", what, verb, name)
+		} else {
+			log.Logger.Error().Err(err).Msgf("failed to %s %s %s", verb, what, name)
+		}
+		errorsOccurred = true
+	}
+	for _, item := range items {
+		wrapper := []T{item}
+		name := id(wrapper)
+		henvs, errs, err := post(wrapper)
+		if err == nil && errs[0] != nil {
+			err = errs[0]
+		}
+		if err == nil && henvs[0].StatusCode != http.StatusConflict {
+			log.Logger.Debug().Msgf("restored %s %s", what, name)
+			continue
+		}
+		if len(henvs) == 0 || henvs[0].StatusCode != http.StatusConflict {
+			logErr(err, "add", name)
+			reportNotAttempted(errs)
+			continue
+		}
+		if !overwrite || update == nil {
+			log.Logger.Warn().Msgf("%s %s already exists, skipping (pass --overwrite to replace it)", what, name)
+			continue
+		}
+		log.Logger.Info().Msgf("%s %s exists, attempting to update it", what, name)
+		_, errs, err = update(wrapper)
+		if err == nil && errs[0] != nil {
+			err = errs[0]
+		}
+		if err != nil {
+			logErr(err, "update", name)
+			reportNotAttempted(errs)
+		}
+	}
+
+	return errorsOccurred
+}
+
+// backupBootParamsID returns the hosts, MAC addresses, and NIDs that bp is for,
+// for logging.
+func backupBootParamsID(bp bssTypes.BootParams) string {
+	ids := slices.Clone(bp.Hosts)
+	ids = append(ids, bp.Macs...)
+	for _, nid := range bp.Nids {
+		ids = append(ids, fmt.Sprint(nid))
+	}
+
+	return strings.Join(ids, ",")
+}
+
+// backupBootParamsExist returns an HTTPEnvelope with a 409 status code if BSS
+// already has boot parameters for any of the hosts, MAC addresses, or NIDs of
+// bp. Since BSS does not refuse to add boot parameters that exist, this is used
+// to detect them before adding bp.
+func backupBootParamsExist(bssClient *bss.BSSClient, bp bssTypes.BootParams) (client.HTTPEnvelope, error) {
+	values := url.Values{}
+	for _, h := range bp.Hosts {
+		values.Add("name", h)
+	}
+	for _, m := range bp.Macs {
+		values.Add("mac", m)
+	}
+	for _, nid := range bp.Nids {
+		values.Add("nid", fmt.Sprint(nid))
+	}
+	if len(values) == 0 {
+		return client.HTTPEnvelope{}, nil
+	}
+	henv, err := bssClient.GetBootParams(values.Encode(), token)
+	if err != nil {
+		if henv.StatusCode == http.StatusNotFound {
+			return client.HTTPEnvelope{}, nil
+		}
+		return henv, err
+	}
+	var bps []bssTypes.BootParams
+	if err := json.Unmarshal(henv.Body, &bps); err != nil {
+		return henv, fmt.Errorf("failed to unmarshal existing boot parameters: %w", err)
+	}
+	if len(bps) > 0 {
+		henv.StatusCode = http.StatusConflict
+	}
+
+	return henv, nil
+}
+
+// backupRestoreComponents restores the SMD components in comps and returns true
+// if any errors occurred. Like 'discover static', components are PUT and then
+// have their NIDs PATCHed if overwrite is true, since PUT does not modify NIDs.
+// Otherwise, they are POSTed.
+func backupRestoreComponents(smdClient *smd.SMDClient, comps smd.ComponentSlice, overwrite bool) bool {
+	if len(comps.Components) == 0 {
+		return false
+	}
+	if !overwrite {
+		if _, err := smdClient.PostComponents(comps, token); err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("SMD component request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to add components to SMD")
+			}
+			return true
+		}
+		return false
+	}
+
+	var errorsOccurred bool
+	_, errs, err := smdClient.PutComponents(comps, token)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to add/overwrite components in SMD")
+		errorsOccurred = true
+	}
+	for _, err := range errs {
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("SMD component request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to add/overwrite component in SMD")
+			}
+			errorsOccurred = true
+		}
+	}
+	reportNotAttempted(errs)
+	if _, err := smdClient.PatchComponentsNID(comps, token); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to update NIDs for components in SMD")
+		errorsOccurred = true
+	}
+
+	return errorsOccurred
+}
+
+// backupRestoreCloudInitDefaults restores the cloud-init cluster defaults dflts
+// and returns true if any errors occurred. Since setting the defaults always
+// replaces them, they are skipped with a warning if they are already set and
+// overwrite is false.
+func backupRestoreCloudInitDefaults(cloudInitClient *ci.CloudInitClient, dflts cistore.ClusterDefaults, overwrite bool) bool {
+	if reflect.DeepEqual(dflts, cistore.ClusterDefaults{}) {
+		return false
+	}
+	if !overwrite {
+		henv, err := cloudInitClient.GetDefaults(token)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get existing cloud-init cluster defaults")
+			return true
+		}
+		var current cistore.ClusterDefaults
+		if err := json.Unmarshal(henv.Body, &current); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal existing cloud-init cluster defaults")
+			return true
+		}
+		if !reflect.DeepEqual(current, cistore.ClusterDefaults{}) {
+			log.Logger.Warn().Msg("cloud-init cluster defaults are already set, skipping (pass --overwrite to replace them)")
+			return false
+		}
+	}
+	if _, err := cloudInitClient.PostDefaults(dflts, token); err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("cloud-init cluster defaults request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to set cloud-init cluster defaults")
+		}
+		return true
+	}
+
+	return false
+}
+
+func init() {
+	backupRestoreCmd.Flags().StringSlice("only", []string{}, "only restore the data of these services (smd,bss,cloud-init)")
+	backupRestoreCmd.Flags().Bool("overwrite", false, "replace records that already exist instead of skipping them")
+	backupRestoreCmd.Flags().Bool("no-confirm", false, "do not ask before restoring")
+
+	backupRestoreCmd.RegisterFlagCompletionFunc("only", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return backup.Services, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	backupCmd.AddCommand(backupRestoreCmd)
+}
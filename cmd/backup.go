@@ -0,0 +1,31 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// backupCmd represents the backup command
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Args:  cobra.NoArgs,
+	Short: "Back up and restore cluster configuration",
+	Long: `Back up the configuration of a cluster held by SMD, BSS, and
+cloud-init to a single archive, and restore it from one.
+
+See ochami-backup(1) for more details.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Check that all required args are passed
+		if len(args) == 0 {
+			printUsageHandleError(cmd)
+			os.Exit(0)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+}
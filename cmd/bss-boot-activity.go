@@ -0,0 +1,155 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// bssBootActivityCmd represents the "bss boot activity" command
+var bssBootActivityCmd = &cobra.Command{
+	Use:   "activity [--since <time>] [--xname <xname>]... [--group <group>]... [--target <target>]...",
+	Args:  cobra.NoArgs,
+	Short: "Show which nodes have fetched a boot script recently",
+	Long: `Show which nodes have fetched a boot script recently, according to the
+endpoint history of BSS. Nodes that fetched a boot script since --since
+are listed as recent along with the time of their last fetch. The rest
+are listed as stale, including nodes that BSS has no record of. This is
+useful for finding nodes that are wedged.
+
+--since can be an RFC 3339 timestamp or a duration before now (e.g.
+36h or 7d) and is 24h by default.
+
+By default, all nodes (components of type Node) in SMD are checked.
+--xname, --group (SMD groups), and/or --target (xnames, nid:<nid>,
+mac:<mac>, or group:<group>) limit the nodes checked to those listed.
+
+BSS only records the last time each node fetched a boot script, so
+the number of boots is not available.
+
+See ochami-bss(1) for more details.`,
+	Example: `  # Show nodes that have not booted in the last week
+  ochami bss boot activity --since 7d
+
+  # Check whether the compute group booted since a maintenance started
+  ochami bss boot activity --group compute --since 2025-01-01T10:00:00Z`,
+	Run: func(cmd *cobra.Command, args []string) {
+		sinceStr, err := cmd.Flags().GetString("since")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --since")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		since, err := parseSince(sinceStr, time.Now().Truncate(time.Second))
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("invalid --since")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Determine which nodes to check
+		xnames, err := cmd.Flags().GetStringSlice("xname")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --xname")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if cmd.Flag("group").Changed {
+			groups, err := cmd.Flags().GetStringSlice("group")
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to get value for --group")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			xnames = append(xnames, smdGetGroupMembers(cmd, groups...)...)
+		}
+		xnames = append(xnames, targetXnames(cmd)...)
+		if !cmd.Flag("xname").Changed && !cmd.Flag("group").Changed && !cmd.Flag("target").Changed {
+			henv, err := smdGetClient(cmd).GetComponentsAll()
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msg("SMD component request yielded unsuccessful HTTP response")
+				} else {
+					log.Logger.Error().Err(err).Msg("failed to request components from SMD")
+				}
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			var comps smd.ComponentSlice
+			if err := json.Unmarshal(henv.Body, &comps); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to unmarshal components")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			for _, c := range comps.Components {
+				if c.Type == "Node" {
+					xnames = append(xnames, c.ID)
+				}
+			}
+		}
+		if len(xnames) == 0 {
+			log.Logger.Warn().Msg("no nodes to check")
+		}
+
+		// Get boot script fetch history from BSS
+		values := url.Values{}
+		values.Add("endpoint", string(bssTypes.EndpointTypeBootscript))
+		henv, err := bssGetClient(cmd).GetEndpointHistory(values.Encode())
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("BSS endpoint history request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to request endpoint history from BSS")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var history []bssTypes.EndpointAccess
+		if err := json.Unmarshal(henv.Body, &history); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal endpoint history")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		report := bss.NewBootActivityReport(history, xnames, since)
+		if len(report.Stale) > 0 {
+			log.Logger.Warn().Msgf("%d of %d node(s) have not fetched a boot script since %s",
+				len(report.Stale), len(report.Stale)+len(report.Recent), report.Since.Format(time.RFC3339))
+		}
+
+		// Print output
+		if outBytes, err := format.MarshalData(report, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+	},
+}
+
+func init() {
+	bssBootActivityCmd.Flags().String("since", "24h", "RFC 3339 timestamp or duration before now (e.g. 36h, 7d) after which a boot counts as recent")
+	bssBootActivityCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more node xnames to check")
+	bssBootActivityCmd.Flags().StringSliceP("group", "g", []string{}, "one or more SMD groups whose members to check")
+	bssBootActivityCmd.Flags().StringSlice("target", []string{}, targetFlagUsage)
+	bssBootActivityCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	bssBootActivityCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	bssBootCmd.AddCommand(bssBootActivityCmd)
+}
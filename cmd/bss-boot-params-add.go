@@ -5,6 +5,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/OpenCHAMI/bss/pkg/bssTypes"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // bssBootParamsAddCmd represents the "bss boot params add" command
@@ -21,12 +23,17 @@ var bssBootParamsAddCmd = &cobra.Command{
 	Short: "Add new boot parameters for one or more components",
 	Long: `Add new boot parameters for one or more components. At least one of --kernel,
 --initrd, or --params must be specified as well as at least one of --xname,
---mac, or --nid. Alternatively, pass -d to pass raw payload data or (if
+--mac, --nid, or --target. --target accepts xnames, NIDs (nid:<nid>), MAC
+addresses (mac:<mac>), and SMD groups (group:<group>) alike. Alternatively, pass -d to pass raw payload data or (if
 flag argument starts with @) a file containing the payload data. -f can
 be specified to change the format of the input payload data ('json' by
 default), but the rules above still apply for the payload. If "-" is used
 as the input payload filename, the data is read from standard input.
 
+The boot parameters are checked for kernel command line pitfalls
+as with 'bss boot params lint' before being sent, and any findings
+are logged as warnings.
+
 This command sends a POST to BSS. An access token is required.
 
 See ochami-bss(1) for more details.`,
@@ -61,16 +68,16 @@ See ochami-bss(1) for more details.`,
 		}
 		if cmd.Flag("data").Changed {
 			// -d/--data trumps all, ignore values of other flags if specified
-			if anyChanged("xname", "nid", "mac", "kernel", "initrd", "params") {
+			if anyChanged("xname", "nid", "mac", "target", "kernel", "initrd", "params") {
 				log.Logger.Warn().Msgf("raw data passed, ignoring CLI configuration")
 			}
 		} else {
-			// If -d/--data not passed, then at least one of --xname/--nid/--mac must
-			// be specified, along with at least one of --kernel/--initrd/--params
-			if !anyChanged("xname", "nid", "mac") {
-				return fmt.Errorf("expected -d or one of --xname, --nid, or --mac")
+			// If -d/--data not passed, then at least one of --xname/--nid/--mac/--target
+			// must be specified, along with at least one of --kernel/--initrd/--params
+			if !anyChanged("xname", "nid", "mac", "target") {
+				return fmt.Errorf("expected -d or one of --xname, --nid, --mac, or --target")
 			} else if !anyChanged("kernel", "initrd", "params") {
-				return fmt.Errorf("specifying any of --xname, --nid, or --mac also requires specifying at least one of --kernel, --initrd, or --params")
+				return fmt.Errorf("specifying any of --xname, --nid, --mac, or --target also requires specifying at least one of --kernel, --initrd, or --params")
 			}
 		}
 
@@ -120,6 +127,7 @@ See ochami-bss(1) for more details.`,
 				os.Exit(1)
 			}
 		}
+		targetBootParams(cmd, &bp)
 
 		// Set the boot parameters
 		if cmd.Flag("kernel").Changed {
@@ -147,8 +155,10 @@ See ochami-bss(1) for more details.`,
 			}
 		}
 
-		// Send 'em off
-		_, err = bssClient.PostBootParams(bp, token)
+		// Warn about any pitfalls, then send 'em off
+		bssLintWarn(cmd, []bssTypes.BootParams{bp})
+		henv, err := bssClient.PostBootParams(bp, token)
+		err = ignoreStatusOne(cmd, "if-not-exists", http.StatusConflict, "boot parameters", henv, err, bssBootParamsTargets(bp))
 		if err != nil {
 			if errors.Is(err, client.UnsuccessfulHTTPError) {
 				log.Logger.Error().Err(err).Msg("BSS boot parameter request yielded unsuccessful HTTP response")
@@ -168,8 +178,10 @@ func init() {
 	bssBootParamsAddCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames whose boot parameters to add")
 	bssBootParamsAddCmd.Flags().StringSliceP("mac", "m", []string{}, "one or more MAC addresses whose boot parameters to add")
 	bssBootParamsAddCmd.Flags().Int32SliceP("nid", "n", []int32{}, "one or more node IDs whose boot parameters to add")
+	bssBootParamsAddCmd.Flags().StringSlice("target", []string{}, targetFlagUsage)
 	bssBootParamsAddCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
-	bssBootParamsAddCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	bssBootParamsAddCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
+	bssBootParamsAddCmd.Flags().Bool("if-not-exists", false, ifNotExistsFlagUsage)
 
 	bssBootParamsAddCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
 
@@ -3,27 +3,34 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/OpenCHAMI/bss/pkg/bssTypes"
 	"github.com/spf13/cobra"
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/pool"
 )
 
 // bssBootParamsDelete represents the "bss boot params delete" command
 var bssBootParamsDelete = &cobra.Command{
-	Use:   "delete",
+	Use:   "delete [--kernel-glob <glob> | --kernel-regex <regex> [--replace-with <uri>] [--batch-size <n>] [--batch-delay <duration>] [--dry-run [-F <format>]]]",
 	Args:  cobra.NoArgs,
 	Short: "Delete boot parameters for one or more components",
 	Long: `Delete boot parameters for one or more components. At least one of --kernel,
---initrd, --params, --xname, --mac, or --nid must be specified.
+--initrd, --params, --xname, --mac, --nid, or --target must be specified.
 This command can delete boot parameters by config (kernel URI,
 initrd URI, or kernel command line) or by component (--xname,
---mac, or --nid). The user will be asked for confirmation before
+--mac, --nid, or --target). --target accepts xnames, NIDs (nid:<nid>), MAC
+addresses (mac:<mac>), and SMD groups (group:<group>) alike. The user will be asked for confirmation before
 deletion unless --no-confirm is passed. Alternatively, pass -d to pass
 raw payload data or (if flag argument starts with @) a file containing
 the payload data. -f can be specified to change the format of the
@@ -31,13 +38,33 @@ input payload data ('json' by default), but the rules above still
 apply for the payload. If "-" is used as the input payload filename,
 the data is read from standard input.
 
-This command sends a DELETE to BSS. An access token is required.
+To retire an image server, pass --kernel-glob or --kernel-regex instead
+to delete every entry whose kernel or initrd URI matches the pattern,
+or, with --replace-with, rewrite the URIs that match. The matching
+entries are listed with the hosts they apply to before asking for
+confirmation, and are then deleted or rewritten --batch-size at a time,
+waiting --batch-delay between batches. In globs, * matches anything,
+including slashes, and ? any single character, and --replace-with can
+refer to what they matched as $1, $2, etc., as it can to the groups of
+a regular expression. Pass --dry-run to print the matching entries
+without changing them.
+
+This command sends a DELETE to BSS, or a GET and then DELETEs or, with
+--replace-with, PATCHes with --kernel-glob or --kernel-regex. An access
+token is required.
 
 See ochami-bss(1) for more details.`,
 	Example: `  # Delete boot parameters using CLI flags
   ochami bss boot params delete --kernel https://example.com/kernel
   ochami bss boot params delete --kernel https://example.com/kernel --initrd https://example.com/initrd
 
+  # Delete boot parameters whose kernel or initrd is on an old image server
+  ochami bss boot params delete --kernel-glob 'https://old-repo/*'
+
+  # Point them to a new image server instead, 20 entries every 5 seconds
+  ochami bss boot params delete --kernel-glob 'https://old-repo/*' --replace-with 'https://new-repo/$1' \
+    --batch-size 20 --batch-delay 5s
+
   # Delete boot parameters using input payload data
   ochami bss boot params delete -d '{"macs":["00:de:ad:be:ef:00"]}'
   ochami bss boot params delete -d '{"kernel":"https://example.com/kernel"}'
@@ -59,18 +86,27 @@ See ochami-bss(1) for more details.`,
 			}
 			return false
 		}
+		if anyChanged("kernel-glob", "kernel-regex") {
+			// Entries are selected by their kernel and initrd URIs
+			if anyChanged("data", "xname", "nid", "mac", "target", "kernel", "initrd", "params") {
+				return fmt.Errorf("--kernel-glob and --kernel-regex cannot be used with -d or other flags selecting boot parameters")
+			}
+			return nil
+		} else if anyChanged("replace-with", "batch-size", "batch-delay", "dry-run") {
+			return fmt.Errorf("--replace-with, --batch-size, --batch-delay, and --dry-run require --kernel-glob or --kernel-regex")
+		}
 		if cmd.Flag("data").Changed {
 			// -d/--data trumps all, ignore values of other flags if specified
-			if anyChanged("xname", "nid", "mac", "kernel", "initrd", "params") {
+			if anyChanged("xname", "nid", "mac", "target", "kernel", "initrd", "params") {
 				log.Logger.Warn().Msgf("raw data passed, ignoring CLI configuration")
 			}
 		} else {
-			// If -d/--data not passed, then at least one of --xname/--nid/--mac must
-			// be specified, along with at least one of --kernel/--initrd/--params
-			if !anyChanged("xname", "nid", "mac") {
-				return fmt.Errorf("expected -d or one of --xname, --nid, or --mac")
+			// If -d/--data not passed, then at least one of --xname/--nid/--mac/--target
+			// must be specified, along with at least one of --kernel/--initrd/--params
+			if !anyChanged("xname", "nid", "mac", "target") {
+				return fmt.Errorf("expected -d or one of --xname, --nid, --mac, or --target")
 			} else if !anyChanged("kernel", "initrd", "params") {
-				return fmt.Errorf("specifying any of --xname, --nid, or --mac also requires specifying at least one of --kernel, --initrd, or --params")
+				return fmt.Errorf("specifying any of --xname, --nid, --mac, or --target also requires specifying at least one of --kernel, --initrd, or --params")
 			}
 		}
 
@@ -83,6 +119,12 @@ See ochami-bss(1) for more details.`,
 		// Handle token for this command
 		handleToken(cmd)
 
+		// Delete or rewrite entries by image instead if requested
+		if cmd.Flag("kernel-glob").Changed || cmd.Flag("kernel-regex").Changed {
+			bssBootParamsDeleteImages(cmd, bssClient)
+			return
+		}
+
 		// The BSS BootParams struct we will send
 		bp := bssTypes.BootParams{}
 
@@ -120,6 +162,7 @@ See ochami-bss(1) for more details.`,
 				os.Exit(1)
 			}
 		}
+		targetBootParams(cmd, &bp)
 
 		// Set the boot parameters
 		if cmd.Flag("kernel").Changed {
@@ -147,23 +190,13 @@ See ochami-bss(1) for more details.`,
 			}
 		}
 
-		// Ask before attempting deletion unless --no-confirm was passed
-		if !cmd.Flag("no-confirm").Changed {
-			log.Logger.Debug().Msg("--no-confirm not passed, prompting user to confirm deletion")
-			respDelete, err := ios.loopYesNo("Really delete?")
-			if err != nil {
-				log.Logger.Error().Err(err).Msg("Error fetching user input")
-				os.Exit(1)
-			} else if !respDelete {
-				log.Logger.Info().Msg("User aborted boot parameter deletion")
-				os.Exit(0)
-			} else {
-				log.Logger.Debug().Msg("User answered affirmatively to delete boot parameters")
-			}
-		}
+		// Ask before attempting deletion, requiring the number of
+		// hosts to be confirmed if there are many
+		confirmDeletion(cmd, "Really delete?", "boot parameter", len(bp.Hosts)+len(bp.Macs)+len(bp.Nids))
 
 		// Send 'em off
-		_, err = bssClient.DeleteBootParams(bp, token)
+		henv, err := bssClient.DeleteBootParams(bp, token)
+		err = ignoreStatusOne(cmd, "if-exists", http.StatusNotFound, "boot parameters", henv, err, bssBootParamsTargets(bp))
 		if err != nil {
 			if errors.Is(err, client.UnsuccessfulHTTPError) {
 				log.Logger.Error().Err(err).Msg("BSS boot parameter request yielded unsuccessful HTTP response")
@@ -176,6 +209,161 @@ See ochami-bss(1) for more details.`,
 	},
 }
 
+// bssBootParamsDeleteImages deletes the boot parameters in BSS whose kernel or
+// initrd URI matches --kernel-glob or --kernel-regex, or, with --replace-with,
+// rewrites the URIs that match. The matching entries are previewed and
+// confirmed first, then sent --batch-size at a time, waiting --batch-delay
+// between batches. With --dry-run, they are printed instead. If a request
+// fails, the remaining entries are still sent and the program exits with an
+// error at the end.
+func bssBootParamsDeleteImages(cmd *cobra.Command, bssClient *bss.BSSClient) {
+	glob := cmd.Flag("kernel-glob").Changed
+	flag := "kernel-regex"
+	if glob {
+		flag = "kernel-glob"
+	}
+	pattern, err := cmd.Flags().GetString(flag)
+	if err != nil {
+		log.Logger.Error().Err(err).Msgf("failed to get value for --%s", flag)
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	re, err := bss.ImagePattern(pattern, glob)
+	if err != nil {
+		log.Logger.Error().Err(err).Msgf("invalid --%s", flag)
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	replace := cmd.Flag("replace-with").Changed
+	replacement, err := cmd.Flags().GetString("replace-with")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --replace-with")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	batchSize, err := cmd.Flags().GetInt("batch-size")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --batch-size")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if batchSize < 1 {
+		log.Logger.Error().Msg("--batch-size must be at least 1")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	batchDelay, err := cmd.Flags().GetDuration("batch-delay")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --batch-delay")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	// Find the entries whose images match
+	henv, err := bssClient.GetBootParams("", token)
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("BSS boot parameter request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to get boot parameters from BSS")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	var bps []bssTypes.BootParams
+	if err := json.Unmarshal(henv.Body, &bps); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to unmarshal boot parameters")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	matches := bss.MatchImages(bps, re, replacement, replace)
+	if len(matches) == 0 {
+		log.Logger.Info().Msgf("no boot parameters have a kernel or initrd matching %s", pattern)
+		return
+	}
+
+	// Print matches and exit if only a dry run
+	if cmd.Flag("dry-run").Changed {
+		if outBytes, err := format.MarshalData(matches, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+		return
+	}
+
+	// Show the hosts affected
+	var targets int
+	fmt.Fprintf(os.Stderr, "Boot parameters with a kernel or initrd matching %s:\n", pattern)
+	for _, m := range matches {
+		targets += m.Targets()
+		fmt.Fprintf(os.Stderr, "  %s\n", bssBootParamsTargets(m.BootParams()))
+		for _, uri := range []struct{ kind, old, new string }{
+			{"kernel", m.Kernel, m.NewKernel},
+			{"initrd", m.Initrd, m.NewInitrd},
+		} {
+			if uri.new != "" {
+				fmt.Fprintf(os.Stderr, "    %s: %s -> %s\n", uri.kind, uri.old, uri.new)
+			} else if uri.old != "" {
+				fmt.Fprintf(os.Stderr, "    %s: %s\n", uri.kind, uri.old)
+			}
+		}
+	}
+
+	// Ask before changing anything, requiring the number of hosts to be
+	// confirmed if there are many entries to delete
+	action, done := "delete", "deleted"
+	if replace {
+		action, done = "rewrite", "rewrote"
+	}
+	prompt := fmt.Sprintf("Really %s %d matching boot parameter(s)?", action, len(matches))
+	if !replace {
+		confirmDeletion(cmd, prompt, "boot parameter", targets)
+	} else if !cmd.Flag("no-confirm").Changed {
+		log.Logger.Debug().Msg("--no-confirm not passed, prompting user to confirm rewriting boot parameters")
+		respRewrite, err := ios.loopYesNo(prompt)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("Error fetching user input")
+			os.Exit(1)
+		} else if !respRewrite {
+			log.Logger.Info().Msg("User aborted rewriting boot parameters")
+			os.Exit(0)
+		}
+	}
+
+	// Send a request for each entry, a batch at a time
+	var failed int
+	batches := pool.Batches(len(matches), batchSize)
+	for i, batch := range batches {
+		if i > 0 && batchDelay > 0 {
+			log.Logger.Debug().Msgf("waiting %s before batch %d of %d", batchDelay, i+1, len(batches))
+			time.Sleep(batchDelay)
+		}
+		log.Logger.Info().Msgf("sending batch %d of %d (%d boot parameter(s))", i+1, len(batches), len(batch))
+		for _, j := range batch {
+			bp := matches[j].BootParams()
+			var err error
+			if replace {
+				_, err = bssClient.PatchBootParams(bp, token)
+			} else {
+				_, err = bssClient.DeleteBootParams(bp, token)
+			}
+			if err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to %s boot parameters of %s", action, bssBootParamsTargets(bp))
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		log.Logger.Warn().Msgf("failed to %s %d of %d boot parameter(s)", action, failed, len(matches))
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	log.Logger.Info().Msgf("%s %d boot parameter(s)", done, len(matches))
+}
+
 func init() {
 	bssBootParamsDelete.Flags().String("kernel", "", "URI of kernel")
 	bssBootParamsDelete.Flags().String("initrd", "", "URI of initrd/initramfs")
@@ -183,9 +371,23 @@ func init() {
 	bssBootParamsDelete.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames whose boot parameters to delete")
 	bssBootParamsDelete.Flags().StringSliceP("mac", "m", []string{}, "one or more MAC addresses whose boot parameters to delete")
 	bssBootParamsDelete.Flags().Int32SliceP("nid", "n", []int32{}, "one or more node IDs whose boot parameters to delete")
+	bssBootParamsDelete.Flags().StringSlice("target", []string{}, targetFlagUsage)
 	bssBootParamsDelete.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
-	bssBootParamsDelete.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	bssBootParamsDelete.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
 	bssBootParamsDelete.Flags().Bool("no-confirm", false, "do not ask before attempting deletion")
+	bssBootParamsDelete.Flags().Int("yes-really-delete", 0, "confirm deleting more than delete-threshold items by passing their exact number")
+	bssBootParamsDelete.Flags().Bool("if-exists", false, ifExistsFlagUsage)
+	bssBootParamsDelete.Flags().String("kernel-glob", "", "delete the boot parameters whose kernel or initrd URI matches this glob")
+	bssBootParamsDelete.Flags().String("kernel-regex", "", "delete the boot parameters whose kernel or initrd URI matches this regular expression")
+	bssBootParamsDelete.Flags().String("replace-with", "", "with --kernel-glob or --kernel-regex, rewrite the URIs that match to this instead of deleting the entries ($1, $2, etc. are what the pattern matched)")
+	bssBootParamsDelete.Flags().Int("batch-size", 50, "with --kernel-glob or --kernel-regex, number of entries to send at a time")
+	bssBootParamsDelete.Flags().Duration("batch-delay", time.Second, "with --kernel-glob or --kernel-regex, time to wait between batches")
+	bssBootParamsDelete.Flags().Bool("dry-run", false, "with --kernel-glob or --kernel-regex, print the matching entries without changing them")
+	bssBootParamsDelete.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed with --dry-run ("+format.DataFormatList()+")")
+
+	bssBootParamsDelete.MarkFlagsMutuallyExclusive("kernel-glob", "kernel-regex")
+
+	bssBootParamsDelete.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
 	bssBootParamsCmd.AddCommand(bssBootParamsDelete)
 }
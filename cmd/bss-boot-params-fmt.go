@@ -0,0 +1,120 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// bssBootParamsFmtCmd represents the "bss boot params fmt" command
+var bssBootParamsFmtCmd = &cobra.Command{
+	Use:   "fmt [-d (<data> | @<path>)] [-f <format>] [-F <format>]",
+	Args:  cobra.NoArgs,
+	Short: "Print boot parameters in canonical form",
+	Long: `Print a boot parameter payload in canonical form, so that boot
+parameter files kept in Git have minimal, reviewable diffs. Hosts and
+NIDs are sorted and de-duplicated, MAC addresses are lowercased,
+colon-separated, sorted, and de-duplicated, surrounding whitespace
+is trimmed, and keys are sorted. If the payload is a list of boot
+parameters, duplicate entries are removed and the entries are sorted
+by their hosts, then MAC addresses, then NIDs.
+
+The payload is read from -d, or from standard input if it is not
+passed, and is either a single set of boot parameters or a list of
+them. It is printed in the format passed to -F or, if it is not
+passed, in the input format (pretty-printed for JSON). Nothing is
+sent to BSS.
+
+See ochami-bss(1) for more details.`,
+	Example: `  # Canonicalize a YAML boot parameter file in place
+  ochami bss boot params fmt -d @params.yaml -f yaml > params.yaml.new &&
+    mv params.yaml.new params.yaml
+
+  # Print boot parameters from standard input as one-line JSON
+  ochami bss boot params fmt -F json < params.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		bps, single := bssReadParamsPayload(cmd)
+		canon := bss.CanonicalParamsList(bps)
+		if n := len(bps) - len(canon); n > 0 {
+			log.Logger.Info().Msgf("removed %d duplicate set(s) of boot parameters", n)
+		}
+
+		// Go through JSON so that keys are named and sorted the same way
+		// in every output format, dropping the empty cloud-init fields
+		// that BootParams always has
+		var data any = canon
+		if single {
+			data = canon[0]
+		}
+		raw, err := json.Marshal(data)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to marshal boot parameters into JSON")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var out any
+		if err := json.Unmarshal(raw, &out); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal boot parameters from JSON")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		out = bssFmtPrune(out)
+
+		outFormat := formatOutput
+		if !cmd.Flag("format-output").Changed {
+			outFormat = formatInput
+			if outFormat == format.DataFormatJson {
+				outFormat = format.DataFormatJsonPretty
+			}
+		}
+		if outBytes, err := format.MarshalData(out, outFormat); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+	},
+}
+
+// bssFmtPrune returns v, unmarshalled from JSON, without the null values,
+// empty strings, and objects left empty by removing them in its objects.
+func bssFmtPrune(v any) any {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, e := range t {
+			e = bssFmtPrune(e)
+			if m, ok := e.(map[string]any); e == nil || e == "" || (ok && len(m) == 0) {
+				delete(t, k)
+			} else {
+				t[k] = e
+			}
+		}
+	case []any:
+		for i, e := range t {
+			t[i] = bssFmtPrune(e)
+		}
+	}
+
+	return v
+}
+
+func init() {
+	bssBootParamsFmtCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
+	bssBootParamsFmtCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
+	bssBootParamsFmtCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output, the input format if unset ("+format.DataFormatList()+")")
+
+	bssBootParamsFmtCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
+	bssBootParamsFmtCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	bssBootParamsCmd.AddCommand(bssBootParamsFmtCmd)
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // bssBootParamsGetCmd represents the "bss boot params get" command
@@ -20,8 +21,9 @@ var bssBootParamsGetCmd = &cobra.Command{
 	Args:  cobra.NoArgs,
 	Short: "Get boot parameters for one or all nodes",
 	Long: `Get boot parameters for one or all nodes. If no options are passed, all boot
-parameters are returned. Optionally, --mac, --xname, and/or --nid can be passed at least once
-to get boot parameters for specific components.
+parameters are returned. Optionally, --mac, --xname, --nid, and/or --target can be passed at
+least once to get boot parameters for specific components. --target accepts xnames, NIDs
+(nid:<nid>), MAC addresses (mac:<mac>), and SMD groups (group:<group>) alike.
 
 This command sends a GET to BSS. An access token is required.
 
@@ -29,7 +31,8 @@ See ochami-bss(1) for more details.`,
 	Example: `  ochami bss boot params get
   ochami bss boot params get --mac 00:de:ad:be:ef:00
   ochami bss boot params get --mac 00:de:ad:be:ef:00,00:c0:ff:ee:00:00
-  ochami bss boot params get --mac 00:de:ad:be:ef:00 --mac 00:c0:ff:ee:00:00`,
+  ochami bss boot params get --mac 00:de:ad:be:ef:00 --mac 00:c0:ff:ee:00:00
+  ochami bss boot params get --target x3000c0s0b0n0,nid:42,group:compute`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Create client to use for requests
 		bssClient := bssGetClient(cmd)
@@ -41,7 +44,8 @@ See ochami-bss(1) for more details.`,
 		qstr := ""
 		if cmd.Flag("xname").Changed ||
 			cmd.Flag("mac").Changed ||
-			cmd.Flag("nid").Changed {
+			cmd.Flag("nid").Changed ||
+			cmd.Flag("target").Changed {
 			values := url.Values{}
 			if cmd.Flag("xname").Changed {
 				s, err := cmd.Flags().GetStringSlice("xname")
@@ -76,6 +80,7 @@ See ochami-bss(1) for more details.`,
 					values.Add("nid", fmt.Sprintf("%d", n))
 				}
 			}
+			targetBootQuery(cmd, values)
 			qstr = values.Encode()
 		}
 		httpEnv, err := bssClient.GetBootParams(qstr, token)
@@ -103,7 +108,8 @@ func init() {
 	bssBootParamsGetCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames whose boot parameters to get")
 	bssBootParamsGetCmd.Flags().StringSliceP("mac", "m", []string{}, "one or more MAC addresses whose boot parameters to get")
 	bssBootParamsGetCmd.Flags().Int32SliceP("nid", "n", []int32{}, "one or more node IDs whose boot parameters to get")
-	bssBootParamsGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	bssBootParamsGetCmd.Flags().StringSlice("target", []string{}, targetFlagUsage)
+	bssBootParamsGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	bssBootParamsGetCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
@@ -0,0 +1,212 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/report"
+	"github.com/OpenCHAMI/ochami/pkg/xname"
+)
+
+// bssLintResult is a finding of "bss boot params lint" for the boot parameters
+// of a target.
+type bssLintResult struct {
+	Target   string `json:"target" yaml:"target"`
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+	bss.LintFinding
+}
+
+// bssBootParamsLintCmd represents the "bss boot params lint" command
+var bssBootParamsLintCmd = &cobra.Command{
+	Use:   "lint [--mac <mac>,...] [--nid <nid>,...] [--xname <xname>,...] [-d (<data> | @<path>)] [--rules <path>] [-F <format>] [--report-format <format> [--report-file <path>]]",
+	Args:  cobra.NoArgs,
+	Short: "Check boot parameters for kernel command line pitfalls",
+	Long: `Check boot parameters for kernel command line pitfalls, such as
+root= being set more than once, ip= arguments that configure the
+same interface differently (e.g. DHCP alongside a static address),
+and arguments that need an initramfs when no initrd is set.
+
+The boot parameters in BSS are checked, optionally filtered with
+--mac, --xname, and/or --nid as with 'bss boot params get'. Pass -d
+to check boot parameters in a payload, which is either a single set
+of boot parameters or a list of them, instead.
+
+Site rules are read from the file passed to --rules or, if it is
+not passed, from the file set as bss.lint-rules in the cluster
+config. In YAML, it looks like:
+
+rules:
+- name: serial-console
+  level: error
+  require: ["console=ttyS0,*"]
+- name: no-quiet
+  forbid: [quiet]
+  message: quiet hides boot problems from the console log
+- name: efi-stub-needs-initrd
+  require: [initrd]
+
+Findings are printed, and the command fails if there are any. The
+same checks are run when setting or adding boot parameters, where
+findings are only logged as warnings. Pass --report-format to output
+the findings as a JUnit XML or SARIF report for CI systems instead,
+or to --report-file in addition to the normal output.
+
+This command sends a GET to BSS unless -d is passed. An access token
+is required.
+
+See ochami-bss(1) for more details.`,
+	Example: `  # Check all boot parameters in BSS
+  ochami bss boot params lint
+
+  # Check the boot parameters of one node against site rules
+  ochami bss boot params lint --xname x1000c1s7b0n0 --rules rules.yaml
+
+  # Check boot parameters in a file before setting them
+  ochami bss boot params lint -d @payload.yaml -f yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		rules := bssLintRules(cmd)
+
+		var bps []bssTypes.BootParams
+		if cmd.Flag("data").Changed {
+			bps, _ = bssReadParamsPayload(cmd)
+		} else {
+			// Create client to use for requests
+			bssClient := bssGetClient(cmd)
+
+			// Handle token for this command
+			handleToken(cmd)
+
+			values := url.Values{}
+			if cmd.Flag("xname").Changed {
+				s, err := cmd.Flags().GetStringSlice("xname")
+				if err != nil {
+					log.Logger.Error().Err(err).Msg("unable to fetch xname list")
+					logHelpError(cmd)
+					os.Exit(1)
+				}
+				for _, x := range s {
+					values.Add("name", x)
+				}
+			}
+			if cmd.Flag("mac").Changed {
+				s, err := cmd.Flags().GetStringSlice("mac")
+				if err != nil {
+					log.Logger.Error().Err(err).Msg("unable to fetch mac list")
+					logHelpError(cmd)
+					os.Exit(1)
+				}
+				for _, m := range s {
+					values.Add("mac", m)
+				}
+			}
+			if cmd.Flag("nid").Changed {
+				s, err := cmd.Flags().GetInt32Slice("nid")
+				if err != nil {
+					log.Logger.Error().Err(err).Msg("unable to fetch nid list")
+					logHelpError(cmd)
+					os.Exit(1)
+				}
+				for _, n := range s {
+					values.Add("nid", fmt.Sprintf("%d", n))
+				}
+			}
+			henv, err := bssClient.GetBootParams(values.Encode(), token)
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msg("BSS boot parameter request yielded unsuccessful HTTP response")
+				} else {
+					log.Logger.Error().Err(err).Msg("failed to request boot parameters from BSS")
+				}
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			if err := json.Unmarshal(henv.Body, &bps); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to unmarshal boot parameters from BSS")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+
+		rep := report.Report{Name: "bss boot params lint"}
+		results := []bssLintResult{}
+		locs, _ := showLocations(cmd)
+		for _, bp := range bps {
+			target := bss.LintTarget(bp)
+			repRes := report.Result{Target: target, Location: bssLintLocation(locs, bp)}
+			for _, f := range bss.LintParams(bp, rules) {
+				results = append(results, bssLintResult{Target: target, Location: repRes.Location, LintFinding: f})
+				level := report.LevelWarning
+				if f.Level == bss.LintLevelError {
+					level = report.LevelError
+				}
+				repRes.Findings = append(repRes.Findings, report.Finding{Rule: f.Rule, Level: level, Message: f.Message})
+			}
+			rep.Results = append(rep.Results, repRes)
+		}
+
+		// Print output, unless replaced by the report
+		if !writeReport(cmd, rep) {
+			if outBytes, err := format.MarshalData(results, formatOutput); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			} else {
+				fmt.Println(string(outBytes))
+			}
+		}
+
+		if len(results) > 0 {
+			log.Logger.Error().Msgf("found %d problem(s) in %d set(s) of boot parameters", len(results), len(bps))
+			exitWithStatus(1)
+		}
+	},
+}
+
+// bssLintLocation returns the physical locations of the hosts of bp that have
+// one, separated by semicolons.
+func bssLintLocation(locs xname.Locations, bp bssTypes.BootParams) string {
+	var descs []string
+	for _, h := range bp.Hosts {
+		if desc := locs.Describe(h); desc != "" {
+			descs = append(descs, desc)
+		}
+	}
+
+	return strings.Join(descs, "; ")
+}
+
+func init() {
+	bssBootParamsLintCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames whose boot parameters to check")
+	bssBootParamsLintCmd.Flags().StringSliceP("mac", "m", []string{}, "one or more MAC addresses whose boot parameters to check")
+	bssBootParamsLintCmd.Flags().Int32SliceP("nid", "n", []int32{}, "one or more node IDs whose boot parameters to check")
+	bssBootParamsLintCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing boot parameters to check instead of those in BSS (can be - to read from stdin)")
+	bssBootParamsLintCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
+	bssBootParamsLintCmd.Flags().String("rules", "", "file containing site lint rules, overriding bss.lint-rules in the cluster config")
+	bssBootParamsLintCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+	bssBootParamsLintCmd.Flags().Bool("show-location", false, showLocationFlagUsage)
+	bssBootParamsLintCmd.Flags().Var(&reportFormat, "report-format", "write findings as a report for CI systems (junit,sarif)")
+	bssBootParamsLintCmd.Flags().String("report-file", "-", "file to write report to, or - to write it to standard output instead of the normal output")
+
+	bssBootParamsLintCmd.MarkFlagsMutuallyExclusive("data", "xname")
+	bssBootParamsLintCmd.MarkFlagsMutuallyExclusive("data", "mac")
+	bssBootParamsLintCmd.MarkFlagsMutuallyExclusive("data", "nid")
+
+	bssBootParamsLintCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
+	bssBootParamsLintCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+	bssBootParamsLintCmd.RegisterFlagCompletionFunc("report-format", completionReportFormat)
+
+	bssBootParamsCmd.AddCommand(bssBootParamsLintCmd)
+}
@@ -12,6 +12,8 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // bssBootParamsSetCmd represents the "bss boot params set" command
@@ -22,14 +24,35 @@ var bssBootParamsSetCmd = &cobra.Command{
 	Long: `Set boot parameters for one or mote components, overwriting any previously-set
 parameters. At least one of --kernel, --initrd, or --params is
 required to tell ochami which boot data to set. Also, at least
-one of --xname, --mac, or --nid is required to tell ochami which
-components need modification. Alternatively, pass -d to pass raw
+one of --xname, --mac, --nid, --target, or --selector is required to
+tell ochami which components need modification. --target accepts
+xnames, NIDs (nid:<nid>), MAC addresses (mac:<mac>), and SMD groups
+(group:<group>) alike. --selector selects components by their
+metadata (see ochami-meta(1)). Alternatively, pass -d to pass raw
 payload data or (if flag argument starts with @) a file containing
 the payload data. -f can be specified to change the format of the
 input payload data ('json' by default), but the rules above still
 apply for the payload. If "-" is used as the input payload filename,
 the data is read from standard input.
 
+Kernel parameters containing "{{" are treated as a template that
+is rendered for each targeted component using its SMD data (.xname,
+.nid, .type, .role, .arch, .mac) and, if referenced, its cloud-init
+meta-data (.meta_data) and variables set with 'ochami vars' (.vars).
+
+Pass --arch (x86_64 or aarch64) to only set the boot parameters for
+the targets of that architecture according to SMD, e.g. to set a
+kernel per architecture for a group of mixed nodes. Without targets,
+--arch sets the boot parameters BSS serves to nodes of the
+architecture that are not in SMD. The payload can instead hold
+variants of the kernel, initrd, and kernel parameters per
+architecture under "arch", which override the others for the
+targets of their architecture.
+
+The boot parameters are checked for kernel command line pitfalls
+as with 'bss boot params lint' before being sent, and any findings
+are logged as warnings.
+
 This command sends a PUT to BSS. An access token is required.
 
 See ochami-bss(1) for more details.`,
@@ -39,6 +62,24 @@ See ochami-bss(1) for more details.`,
   ochami bss boot params set --xname x1000c1s7b0 --xname x1000c1s7b1 --kernel https://example.com/kernel
   ochami bss boot params set --xname x1000c1s7b0 --nid 1 --mac 00:c0:ff:ee:00:00 --params 'quiet nosplash'
 
+  # Set the kernel of all nodes in rack 12
+  ochami bss boot params set --selector meta.rack=12 --kernel https://example.com/kernel
+
+  # Set per-node kernel parameters rendered from SMD and cloud-init data
+  ochami bss boot params set --xname x1000c1s7b0,x1000c1s7b1 --params 'nid={{ .nid }} ip={{ index .meta_data "local-ipv4" }}'
+
+  # Set the kernel of the aarch64 nodes of a mixed group
+  ochami bss boot params set --target group:compute --arch aarch64 --kernel https://example.com/kernel-aarch64
+
+  # Set the kernel per architecture for a mixed group in one go
+  ochami bss boot params set -f yaml -d @- <<EOF
+  hosts: [x1000c1s7b0, x1000c1s7b1]
+  params: quiet
+  arch:
+    x86_64: {kernel: https://example.com/kernel-x86_64}
+    aarch64: {kernel: https://example.com/kernel-aarch64}
+  EOF
+
   # Set boot parameters using input payload data
   ochami bss boot params set -d '{"macs":["00:de:ad:be:ef:00"],"kernel":"https://example.com/kernel"}'
 
@@ -61,16 +102,17 @@ See ochami-bss(1) for more details.`,
 		}
 		if cmd.Flag("data").Changed {
 			// -d/--data trumps all, ignore values of other flags if specified
-			if anyChanged("xname", "nid", "mac", "kernel", "initrd", "params") {
+			if anyChanged("xname", "nid", "mac", "target", "selector", "kernel", "initrd", "params") {
 				log.Logger.Warn().Msgf("raw data passed, ignoring CLI configuration")
 			}
 		} else {
-			// If -d/--data not passed, then at least one of --xname/--nid/--mac must
-			// be specified, along with at least one of --kernel/--initrd/--params
-			if !anyChanged("xname", "nid", "mac") {
-				return fmt.Errorf("expected -d or one of --xname, --nid, or --mac")
+			// If -d/--data not passed, then at least one of --xname/--nid/--mac/--target/--selector
+			// (or --arch alone for hosts not in SMD) must be specified, along with at least one
+			// of --kernel/--initrd/--params
+			if !anyChanged("xname", "nid", "mac", "target", "selector", "arch") {
+				return fmt.Errorf("expected -d or one of --xname, --nid, --mac, --target, --selector, or --arch")
 			} else if !anyChanged("kernel", "initrd", "params") {
-				return fmt.Errorf("specifying any of --xname, --nid, or --mac also requires specifying at least one of --kernel, --initrd, or --params")
+				return fmt.Errorf("specifying any of --xname, --nid, --mac, --target, --selector, or --arch also requires specifying at least one of --kernel, --initrd, or --params")
 			}
 		}
 
@@ -83,11 +125,13 @@ See ochami-bss(1) for more details.`,
 		// Handle token for this command
 		handleToken(cmd)
 
-		// The BSS BootParams struct we will send
-		bp := bssTypes.BootParams{}
+		// The BSS BootParams struct we will send, along with any
+		// per-architecture variants
+		abp := bss.ArchBootParams{}
+		bp := &abp.BootParams
 
 		// Read payload from file first, allowing overwrites from flags
-		handlePayload(cmd, &bp)
+		handlePayload(cmd, &abp)
 
 		// Set the hosts the boot parameters are for
 		var err error
@@ -99,6 +143,9 @@ See ochami-bss(1) for more details.`,
 				os.Exit(1)
 			}
 		}
+		if cmd.Flag("selector").Changed {
+			bp.Hosts = append(bp.Hosts, metaSelectorXnames(cmd)...)
+		}
 		if cmd.Flag("mac").Changed {
 			bp.Macs, err = cmd.Flags().GetStringSlice("mac")
 			if err != nil {
@@ -120,6 +167,7 @@ See ochami-bss(1) for more details.`,
 				os.Exit(1)
 			}
 		}
+		targetBootParams(cmd, bp)
 
 		// Set the boot parameters
 		if cmd.Flag("kernel").Changed {
@@ -147,16 +195,26 @@ See ochami-bss(1) for more details.`,
 			}
 		}
 
-		// Send 'em off
-		_, err = bssClient.PutBootParams(bp, token)
-		if err != nil {
-			if errors.Is(err, client.UnsuccessfulHTTPError) {
-				log.Logger.Error().Err(err).Msg("BSS boot parameter request yielded unsuccessful HTTP response")
-			} else {
-				log.Logger.Error().Err(err).Msg("failed to set boot parameters in BSS")
+		// Split the boot parameters by architecture if --arch or
+		// variants were passed, render per-target kernel parameters if
+		// they are templated, warn about any pitfalls, then send 'em off
+		bssArchFlag(cmd, &abp)
+		var bps []bssTypes.BootParams
+		for _, bp := range bssSplitArch(cmd, abp) {
+			bps = append(bps, bssExpandParams(cmd, bp)...)
+		}
+		bssLintWarn(cmd, bps)
+		for _, bp := range bps {
+			_, err = bssClient.PutBootParams(bp, token)
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msg("BSS boot parameter request yielded unsuccessful HTTP response")
+				} else {
+					log.Logger.Error().Err(err).Msg("failed to set boot parameters in BSS")
+				}
+				logHelpError(cmd)
+				os.Exit(1)
 			}
-			logHelpError(cmd)
-			os.Exit(1)
 		}
 	},
 }
@@ -164,13 +222,17 @@ See ochami-bss(1) for more details.`,
 func init() {
 	bssBootParamsSetCmd.Flags().String("kernel", "", "URI of kernel")
 	bssBootParamsSetCmd.Flags().String("initrd", "", "URI of initrd/initramfs")
-	bssBootParamsSetCmd.Flags().String("params", "", "kernel parameters")
+	bssBootParamsSetCmd.Flags().String("params", "", "kernel parameters, optionally a template rendered per target (e.g. 'nid={{ .nid }}')")
 	bssBootParamsSetCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames whose boot parameters to set")
 	bssBootParamsSetCmd.Flags().StringSliceP("mac", "m", []string{}, "one or more MAC addresses whose boot parameters to set")
+	bssBootParamsSetCmd.Flags().StringSlice("target", []string{}, targetFlagUsage)
+	bssBootParamsSetCmd.Flags().StringArray("selector", []string{}, "select components whose boot parameters to set by metadata (meta.<key>=<value>), can be passed more than once")
 	bssBootParamsSetCmd.Flags().Int32SliceP("nid", "n", []int32{}, "one or more node IDs whose boot parameters to set")
+	bssBootParamsSetCmd.Flags().String("arch", "", "only set boot parameters for targets of this architecture (x86_64,aarch64), or for nodes of it not in SMD if there are no targets")
 	bssBootParamsSetCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
-	bssBootParamsSetCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	bssBootParamsSetCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
 
+	bssBootParamsSetCmd.RegisterFlagCompletionFunc("arch", completionArch)
 	bssBootParamsSetCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
 
 	bssBootParamsCmd.AddCommand(bssBootParamsSetCmd)
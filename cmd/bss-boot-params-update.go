@@ -3,8 +3,10 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 
 	"github.com/OpenCHAMI/bss/pkg/bssTypes"
@@ -12,6 +14,8 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // bssBootParamsUpdateCmd represents the "bss boot params update" command
@@ -28,7 +32,25 @@ input payload data ('json' by default), but the rules above still
 apply for the payload. If "-" is used as the input payload filename,
 the data is read from standard input.
 
-This command sends a PATCH to BSS. An access token is required.
+Kernel parameters containing "{{" are treated as a template that
+is rendered for each targeted component using its SMD data (.xname,
+.nid, .type, .role, .arch, .mac) and, if referenced, its cloud-init
+meta-data (.meta_data) and variables set with 'ochami vars' (.vars).
+
+Pass --arch (x86_64 or aarch64) to only update the boot parameters
+of the targets of that architecture according to SMD, or, without
+targets, those BSS serves to nodes of it that are not in SMD. The
+payload can instead hold per-architecture variants under "arch", as
+with 'bss boot params set'.
+
+Alternatively, pass --patch along with at least one of --xname,
+--mac, or --nid to apply a JSON Patch (RFC 6902, --patch-type json,
+the default) or JSON Merge Patch (RFC 7396, --patch-type merge)
+document client-side to each set of matching boot parameters
+fetched from BSS. Each patched set of boot parameters is then sent
+to BSS with a PUT, replacing the existing set.
+
+This command sends a PATCH to BSS (or a PUT if --patch is used). An access token is required.
 
 See ochami-bss(1) for details.`,
 	Example: `  # Update boot parameters using CLI flags
@@ -37,6 +59,9 @@ See ochami-bss(1) for details.`,
   ochami bss boot params update --xname x1000c1s7b0 --xname x1000c1s7b1 --kernel https://example.com/kernel
   ochami bss boot params update --xname x1000c1s7b0 --nid 1 --mac 00:c0:ff:ee:00:00 --params 'quiet nosplash'
 
+  # Update per-node kernel parameters rendered from SMD and cloud-init data
+  ochami bss boot params update --xname x1000c1s7b0,x1000c1s7b1 --params 'nid={{ .nid }} ip={{ index .meta_data "local-ipv4" }}'
+
   # Update boot parameters using input payload data
   ochami bss boot params update -d '{"macs":["00:de:ad:be:ef:00"],"kernel":"https://example.com/kernel"}'
 
@@ -44,6 +69,11 @@ See ochami-bss(1) for details.`,
   ochami bss boot params update -d @payload.json
   ochami bss boot params update -d @payload.yaml -f yaml
 
+  # Update boot parameters using a JSON Patch or merge patch
+  ochami bss boot params update --xname x1000c1s7b0 --patch '[{"op":"replace","path":"/params","value":"quiet"}]'
+  ochami bss boot params update --xname x1000c1s7b0 --patch '{"initrd":"https://example.com/initrd"}' --patch-type merge
+  ochami bss boot params update --nid 1 --patch @patch.yaml -f yaml
+
   # Update boot parameters using data from standard input
   echo '<json_data>' | ochami bss boot params update -d @-
   echo '<yaml_data>' | ochami bss boot params update -d @- -f yaml`,
@@ -57,7 +87,14 @@ See ochami-bss(1) for details.`,
 			}
 			return false
 		}
-		if cmd.Flag("data").Changed {
+		if cmd.Flag("patch").Changed {
+			// --patch only needs to know which boot parameters to patch
+			if !anyChanged("xname", "nid", "mac") {
+				return fmt.Errorf("--patch requires at least one of --xname, --nid, or --mac")
+			} else if anyChanged("kernel", "initrd", "params", "arch") {
+				return fmt.Errorf("--patch cannot be used with --kernel, --initrd, --params, or --arch")
+			}
+		} else if cmd.Flag("data").Changed {
 			// -d/--data trumps all, ignore values of other flags if specified
 			if anyChanged("xname", "nid", "mac", "kernel", "initrd", "params") {
 				log.Logger.Warn().Msgf("raw data passed, ignoring CLI configuration")
@@ -65,10 +102,10 @@ See ochami-bss(1) for details.`,
 		} else {
 			// If -d/--data not passed, then at least one of --xname/--nid/--mac must
 			// be specified, along with at least one of --kernel/--initrd/--params
-			if !anyChanged("xname", "nid", "mac") {
-				return fmt.Errorf("expected -d or one of --xname, --nid, or --mac")
+			if !anyChanged("xname", "nid", "mac", "arch") {
+				return fmt.Errorf("expected -d or one of --xname, --nid, --mac, or --arch")
 			} else if !anyChanged("kernel", "initrd", "params") {
-				return fmt.Errorf("specifying any of --xname, --nid, or --mac also requires specifying at least one of --kernel, --initrd, or --params")
+				return fmt.Errorf("specifying any of --xname, --nid, --mac, or --arch also requires specifying at least one of --kernel, --initrd, or --params")
 			}
 		}
 
@@ -81,11 +118,20 @@ See ochami-bss(1) for details.`,
 		// Handle token for this command
 		handleToken(cmd)
 
-		// The BSS BootParams struct we will send
-		bp := bssTypes.BootParams{}
+		// If a patch was passed, apply it against current boot
+		// parameters and send those instead
+		if cmd.Flag("patch").Changed {
+			bssBootParamsUpdatePatch(cmd, bssClient, handlePatchRaw(cmd))
+			return
+		}
+
+		// The BSS BootParams struct we will send, along with any
+		// per-architecture variants
+		abp := bss.ArchBootParams{}
+		bp := &abp.BootParams
 
 		// Read payload from file first, allowing overwrites from flags
-		handlePayload(cmd, &bp)
+		handlePayload(cmd, &abp)
 
 		// Set the hosts the boot parameters are for
 		var err error
@@ -145,31 +191,130 @@ See ochami-bss(1) for details.`,
 			}
 		}
 
-		// Send 'em off
-		_, err = bssClient.PatchBootParams(bp, token)
+		// Split the boot parameters by architecture if --arch or
+		// variants were passed, render per-target kernel parameters if
+		// they are templated, then send 'em off
+		bssArchFlag(cmd, &abp)
+		var bps []bssTypes.BootParams
+		for _, bp := range bssSplitArch(cmd, abp) {
+			bps = append(bps, bssExpandParams(cmd, bp)...)
+		}
+		for _, bp := range bps {
+			_, err = bssClient.PatchBootParams(bp, token)
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msg("BSS boot parameter request yielded unsuccessful HTTP response")
+				} else {
+					log.Logger.Error().Err(err).Msg("failed to set boot parameters in BSS")
+				}
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+// bssBootParamsUpdatePatch fetches the boot parameters matching --xname, --mac,
+// and/or --nid, applies patchBytes (read from --patch) to each, and PUTs the
+// result. If an error occurs, the program exits.
+func bssBootParamsUpdatePatch(cmd *cobra.Command, bssClient *bss.BSSClient, patchBytes []byte) {
+	values := url.Values{}
+	if cmd.Flag("xname").Changed {
+		s, err := cmd.Flags().GetStringSlice("xname")
 		if err != nil {
+			log.Logger.Error().Err(err).Msg("unable to fetch xname list")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, x := range s {
+			values.Add("name", x)
+		}
+	}
+	if cmd.Flag("mac").Changed {
+		s, err := cmd.Flags().GetStringSlice("mac")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("unable to fetch mac list")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, m := range s {
+			values.Add("mac", m)
+		}
+	}
+	if cmd.Flag("nid").Changed {
+		s, err := cmd.Flags().GetInt32Slice("nid")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("unable to fetch nid list")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, n := range s {
+			values.Add("nid", fmt.Sprintf("%d", n))
+		}
+	}
+	// Patch the boot parameters in BSS itself rather than a cached copy,
+	// since they are written back
+	uncached := &bss.BSSClient{OchamiClient: bssClient.Uncached()}
+	henv, err := uncached.GetBootParams(values.Encode(), token)
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("BSS boot parameter request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to request boot parameters from BSS")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	var current []bssTypes.BootParams
+	if err := json.Unmarshal(henv.Body, &current); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to unmarshal boot parameters from BSS")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if len(current) == 0 {
+		log.Logger.Error().Msg("no boot parameters found to patch")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	var errorsOccurred = false
+	for _, cur := range current {
+		var bp bssTypes.BootParams
+		handlePatch(cmd, patchBytes, cur, &bp)
+		if _, err := bssClient.PutBootParams(bp, token); err != nil {
 			if errors.Is(err, client.UnsuccessfulHTTPError) {
 				log.Logger.Error().Err(err).Msg("BSS boot parameter request yielded unsuccessful HTTP response")
 			} else {
-				log.Logger.Error().Err(err).Msg("failed to set boot parameters in BSS")
+				log.Logger.Error().Err(err).Msg("failed to set patched boot parameters in BSS")
 			}
-			logHelpError(cmd)
-			os.Exit(1)
+			errorsOccurred = true
 		}
-	},
+	}
+	if errorsOccurred {
+		log.Logger.Warn().Msg("BSS boot parameter patching completed with errors")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
 }
 
 func init() {
 	bssBootParamsUpdateCmd.Flags().String("kernel", "", "URI of kernel")
 	bssBootParamsUpdateCmd.Flags().String("initrd", "", "URI of initrd/initramfs")
-	bssBootParamsUpdateCmd.Flags().String("params", "", "kernel parameters")
+	bssBootParamsUpdateCmd.Flags().String("params", "", "kernel parameters, optionally a template rendered per target (e.g. 'nid={{ .nid }}')")
 	bssBootParamsUpdateCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames whose boot parameters to update")
 	bssBootParamsUpdateCmd.Flags().StringSliceP("mac", "m", []string{}, "one or more MAC addresses whose boot parameters to update")
 	bssBootParamsUpdateCmd.Flags().Int32SliceP("nid", "n", []int32{}, "one or more node IDs whose boot parameters to update")
+	bssBootParamsUpdateCmd.Flags().String("arch", "", "only update boot parameters of targets of this architecture (x86_64,aarch64), or of nodes of it not in SMD if there are no targets")
 	bssBootParamsUpdateCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
-	bssBootParamsUpdateCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	bssBootParamsUpdateCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
+
+	bssBootParamsUpdateCmd.Flags().String("patch", "", "patch data or (if starting with @) file containing patch data to apply to current boot parameters (can be - to read from stdin)")
+	bssBootParamsUpdateCmd.Flags().Var(&patchType, "patch-type", "type of patch passed to --patch (json,merge)")
 
+	bssBootParamsUpdateCmd.RegisterFlagCompletionFunc("arch", completionArch)
 	bssBootParamsUpdateCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
+	bssBootParamsUpdateCmd.RegisterFlagCompletionFunc("patch-type", completionPatchType)
+	bssBootParamsUpdateCmd.MarkFlagsMutuallyExclusive("data", "patch")
 
 	bssBootParamsCmd.AddCommand(bssBootParamsUpdateCmd)
 }
@@ -3,8 +3,11 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
+	"strings"
 
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
 	"github.com/spf13/cobra"
 )
 
@@ -25,6 +28,17 @@ See ochami-bss(1) for more details.`,
 	},
 }
 
+// bssBootParamsTargets returns the hosts, MAC addresses, and NIDs whose boot
+// parameters bp are, separated by commas, for logging.
+func bssBootParamsTargets(bp bssTypes.BootParams) string {
+	targets := append(append([]string{}, bp.Hosts...), bp.Macs...)
+	for _, nid := range bp.Nids {
+		targets = append(targets, fmt.Sprint(nid))
+	}
+
+	return strings.Join(targets, ",")
+}
+
 func init() {
 	bssBootCmd.AddCommand(bssBootParamsCmd)
 }
@@ -20,7 +20,9 @@ var bssBootScriptGetCmd = &cobra.Command{
 	Args:  cobra.NoArgs,
 	Short: "Get iPXE boot script for a component",
 	Long: `Get iPXE boot script for a component. Specifying one of --mac, --xname,
-or --nid is required to specify which component to fetch the boot script for.
+--nid, or --target is required to specify which component to fetch the boot
+script for. --target accepts xnames, NIDs (nid:<nid>), MAC addresses
+(mac:<mac>), and SMD groups (group:<group>) alike.
 
 This command sends a GET to BSS. An access token is not required.
 
@@ -68,6 +70,8 @@ See ochami-bss(1) for more details.`,
 			}
 		}
 
+		targetBootQuery(cmd, values)
+
 		// These are optional
 		if cmd.Flag("retry").Changed {
 			s, err := cmd.Flags().GetInt("retry")
@@ -116,11 +120,12 @@ func init() {
 	bssBootScriptGetCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames whose boot script to get")
 	bssBootScriptGetCmd.Flags().StringSliceP("mac", "m", []string{}, "one or more MAC addresses whose boot script to get")
 	bssBootScriptGetCmd.Flags().Int32SliceP("nid", "n", []int32{}, "one or more node IDs whose boot script to get")
+	bssBootScriptGetCmd.Flags().StringSlice("target", []string{}, targetFlagUsage)
 	bssBootScriptGetCmd.Flags().Int("retry", 0, "number of times to retry fetching boot script on failed boot")
 	bssBootScriptGetCmd.Flags().String("arch", "", "architecture value from iPXE variable ${buildarch}")
 	bssBootScriptGetCmd.Flags().Int("timestamp", 0, "timestamp in seconds since Unix epoch for when SMD state needs to be updated by")
 
-	bssBootScriptGetCmd.MarkFlagsOneRequired("xname", "mac", "nid")
+	bssBootScriptGetCmd.MarkFlagsOneRequired("xname", "mac", "nid", "target")
 
 	bssBootScriptCmd.AddCommand(bssBootScriptGetCmd)
 }
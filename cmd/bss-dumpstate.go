@@ -3,6 +3,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -11,22 +12,35 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // bssDumpStateCmd represents the "bss dumpstate" command
 var bssDumpStateCmd = &cobra.Command{
-	Use:   "dumpstate",
+	Use:   "dumpstate [--redact [--mapping-file <path>]] [-F <format>]",
 	Args:  cobra.NoArgs,
 	Short: "Retrieve the current state of BSS",
 	Long: `Retrieve the current state of BSS.
 
+Pass --redact to pseudonymize the hostnames, MAC addresses, IP
+addresses, and UUIDs in it (see 'ochami redact'), e.g. to attach it
+to a bug report.
+
 See ochami-bss(1) for more details.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		redactOut, err := cmd.Flags().GetBool("redact")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --redact")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
 		// Create client to use for requests
 		bssClient := bssGetClient(cmd)
 
-		// Send request
-		httpEnv, err := bssClient.GetDumpState()
+		// Send request, spilling the state to a temporary file if it is
+		// too large to hold in memory
+		_, body, err := bssClient.GetDumpStateStream()
 		if err != nil {
 			if errors.Is(err, client.UnsuccessfulHTTPError) {
 				log.Logger.Error().Err(err).Msg("BSS dump state request yielded unsuccessful HTTP response")
@@ -36,20 +50,44 @@ See ochami-bss(1) for more details.`,
 			logHelpError(cmd)
 			os.Exit(1)
 		}
+		defer body.Close()
 
 		// Print output
-		if outBytes, err := client.FormatBody(httpEnv.Body, formatOutput); err != nil {
+		r, err := body.Reader()
+		if err == nil && redactOut {
+			var data any
+			if err = json.NewDecoder(r).Decode(&data); err != nil {
+				body.Close()
+				log.Logger.Error().Err(err).Msg("failed to unmarshal dump state")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			if outBytes, err := format.MarshalData(redactData(cmd, data), formatOutput); err != nil {
+				body.Close()
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			} else {
+				fmt.Println(string(outBytes))
+			}
+			return
+		}
+		if err == nil {
+			err = client.FormatStream(os.Stdout, r, formatOutput)
+		}
+		if err != nil {
+			body.Close()
 			log.Logger.Error().Err(err).Msg("failed to format output")
 			logHelpError(cmd)
 			os.Exit(1)
-		} else {
-			fmt.Print(string(outBytes))
 		}
 	},
 }
 
 func init() {
-	bssDumpStateCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	bssDumpStateCmd.Flags().Bool("redact", false, "pseudonymize hostnames, MAC addresses, IP addresses, and UUIDs in the output")
+	bssDumpStateCmd.Flags().String("mapping-file", "", "with --redact, read pseudonyms from and save them to this file")
+	bssDumpStateCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	bssDumpStateCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
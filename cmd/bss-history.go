@@ -12,6 +12,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // bssHistoryCmd represents the "bss history" command
@@ -77,7 +78,7 @@ See ochami-bss(1) for more details.`,
 func init() {
 	bssHistoryCmd.Flags().String("xname", "", "filter by xname")
 	bssHistoryCmd.Flags().String("endpoint", "", "filter by endpoint")
-	bssHistoryCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	bssHistoryCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	bssHistoryCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
@@ -12,6 +12,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // bssHostsGetCmd represents the "bss hosts get" command
@@ -87,7 +88,7 @@ func init() {
 	bssHostsGetCmd.Flags().StringP("xname", "x", "", "xname whose host information to get")
 	bssHostsGetCmd.Flags().StringP("mac", "m", "", "MAC address whose boot parameters to get")
 	bssHostsGetCmd.Flags().Int32P("nid", "n", 0, "node ID whose host information to get")
-	bssHostsGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	bssHostsGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	bssHostsGetCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
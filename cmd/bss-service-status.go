@@ -11,6 +11,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // bssServiceStatusCmd represents the "bss service status" command
@@ -62,7 +63,7 @@ func init() {
 	bssServiceStatusCmd.Flags().Bool("all", false, "print all status data from BSS")
 	bssServiceStatusCmd.Flags().Bool("storage", false, "print status of storage backend from BSS")
 	bssServiceStatusCmd.Flags().Bool("smd", false, "print status of BSS connection to SMD")
-	bssServiceStatusCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	bssServiceStatusCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	bssServiceStatusCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 	bssServiceStatusCmd.MarkFlagsMutuallyExclusive("all", "storage", "smd")
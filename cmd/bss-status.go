@@ -11,6 +11,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // bssStatusCmd represents the "bss status" command
@@ -66,7 +67,7 @@ func init() {
 	bssStatusCmd.Flags().Bool("storage", false, "print status of storage backend from BSS")
 	bssStatusCmd.Flags().Bool("smd", false, "print status of BSS connection to SMD")
 	bssStatusCmd.Flags().Bool("version", false, "print version of BSS")
-	bssStatusCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	bssStatusCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	bssStatusCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 	bssStatusCmd.MarkFlagsMutuallyExclusive("all", "storage", "smd", "version")
@@ -3,12 +3,23 @@
 package cmd
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 
+	"github.com/OpenCHAMI/ochami/internal/config"
 	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
 	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/client/ci"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
 )
 
 // bssGetClient sets up the BSS client with the BSS base URI and certificates
@@ -33,9 +44,311 @@ func bssGetClient(cmd *cobra.Command) *bss.BSSClient {
 	// Check if a CA certificate was passed and load it into client if valid
 	useCACert(bssClient.OchamiClient)
 
+	// Send GET requests through the agent of the cluster, if running
+	useAgent(cmd, bssClient.OchamiClient)
+
+	// Apply any endpoint path overrides configured for the cluster
+	bssClient.PathOverrides = getServicePaths(cmd, config.ServiceBSS)
+
+	// Refuse to send mutating requests in read-only mode
+	bssClient.ReadOnly = readOnlyEnabled(cmd)
+
+	// Validate payloads against the OpenAPI spec of the service if
+	// --validate-against-server was passed
+	bssClient.ValidatePayload = payloadValidator(cmd, bssClient.OchamiClient)
+
+	// Stop sending requests once the --context-timeout deadline passes
+	bssClient.Deadline = commandDeadline
+
+	// Make requests on behalf of the user passed with --as, if any, and
+	// record those that modify data in the audit log
+	bssClient.OnBehalfOf, bssClient.ImpersonationHeader = onBehalfOf(cmd)
+	bssClient.AuditLog = getAuditLog(cmd)
+
+	// Spill large response bodies to a temporary file
+	bssClient.MaxMemoryBuffer = maxMemoryBuffer(cmd)
+
 	return bssClient
 }
 
+// bssExpandParams renders the kernel parameters of bp as a template for each
+// of its targets (see bss.ExpandParamsTemplate), returning one set of boot
+// parameters per distinct set of rendered kernel parameters. Template
+// variables are looked up in SMD and, only if the template references
+// .meta_data, in cloud-init, and only if it references .vars, in the variable
+// store (see ochami vars). Only if it references .mac are the ethernet
+// interfaces of xname and NID targets looked up in SMD. If bp.Params is not a
+// template, bp is returned unchanged. If an error occurs, the program exits.
+func bssExpandParams(cmd *cobra.Command, bp bssTypes.BootParams) []bssTypes.BootParams {
+	if !bss.IsParamsTemplate(bp.Params) {
+		return []bssTypes.BootParams{bp}
+	}
+
+	varsFunc := bssParamsVars(cmd, strings.Contains(bp.Params, "meta_data"), strings.Contains(bp.Params, ".vars"), strings.Contains(bp.Params, ".mac"))
+	bps, err := bss.ExpandParamsTemplate(bp, varsFunc)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to render kernel parameters")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	log.Logger.Debug().Msgf("kernel parameter template rendered into %d distinct set(s) of boot parameters", len(bps))
+
+	return bps
+}
+
+// bssSplitArch splits abp into boot parameters per architecture (see
+// bss.SplitByArch) if it has variants, using the architecture of the SMD
+// component of each target. Targets skipped for having no variant for their
+// architecture are logged. If an error occurs or no boot parameters are left,
+// the program exits.
+func bssSplitArch(cmd *cobra.Command, abp bss.ArchBootParams) []bssTypes.BootParams {
+	if len(abp.Arch) == 0 {
+		return []bssTypes.BootParams{abp.BootParams}
+	}
+
+	varsFunc := bssParamsVars(cmd, false, false, false)
+	archOf := func(t bss.ParamsTarget) (string, error) {
+		vars, err := varsFunc(t)
+		if err != nil {
+			return "", err
+		}
+		arch, _ := vars["arch"].(string)
+		return bss.ComponentArch(arch), nil
+	}
+	bps, skipped, err := bss.SplitByArch(abp, archOf)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to split boot parameters by architecture")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	for _, t := range skipped {
+		log.Logger.Info().Msgf("skipping %s %s: no boot parameters for its architecture", t.Kind, t.ID)
+	}
+	if len(bps) == 0 {
+		log.Logger.Error().Msg("no targets of an architecture with boot parameters")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	log.Logger.Debug().Msgf("boot parameters split into %d set(s) by architecture", len(bps))
+
+	return bps
+}
+
+// bssArchFlag moves the kernel, initrd, and kernel parameters of abp into a
+// variant for the architecture passed with --arch, if it was, so that they are
+// only set for its targets of that architecture. If an error occurs, the
+// program exits.
+func bssArchFlag(cmd *cobra.Command, abp *bss.ArchBootParams) {
+	if !cmd.Flag("arch").Changed {
+		return
+	}
+	arch, err := bss.ParseArch(cmd.Flag("arch").Value.String())
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("invalid --arch")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if len(abp.Arch) > 0 {
+		log.Logger.Error().Msg("--arch cannot be used with per-architecture variants in the payload")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	abp.Arch = map[string]bss.ArchVariant{
+		arch: {Kernel: abp.Kernel, Initrd: abp.Initrd, Params: abp.Params},
+	}
+	abp.Kernel, abp.Initrd, abp.Params = "", "", ""
+}
+
+// completionArch is the cobra completion function for --arch.
+func completionArch(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return bss.Arches, cobra.ShellCompDirectiveNoFileComp
+}
+
+// bssLintRules returns the site rules that boot parameters are linted against,
+// read from the file passed to --rules if the command has it and it was passed,
+// or else from the file set as lint-rules in the cluster's BSS config. If
+// neither is set, no rules are returned. If the file cannot be read, the program
+// exits.
+func bssLintRules(cmd *cobra.Command) []bss.LintRule {
+	var rulesFile string
+	if f := cmd.Flag("rules"); f != nil && f.Changed {
+		rulesFile = f.Value.String()
+	} else if cl, found := getCluster(cmd); found {
+		rulesFile = cl.Cluster.BSS.LintRules
+	}
+	if rulesFile == "" {
+		return nil
+	}
+	rules, err := bss.ReadLintRules(rulesFile)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to read boot parameter lint rules")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	log.Logger.Debug().Msgf("read %d boot parameter lint rule(s) from %s", len(rules), rulesFile)
+
+	return rules
+}
+
+// bssLintWarn lints each of bps (see bss.LintParams), logging any findings as
+// warnings. It is used before sending boot parameters so that pitfalls are
+// pointed out without refusing to send them; 'bss boot params lint' is the
+// strict form.
+func bssLintWarn(cmd *cobra.Command, bps []bssTypes.BootParams) {
+	rules := bssLintRules(cmd)
+	for _, bp := range bps {
+		for _, f := range bss.LintParams(bp, rules) {
+			log.Logger.Warn().Msgf("boot parameters for %s: %s (%s)", bss.LintTarget(bp), f.Message, f.Rule)
+		}
+	}
+}
+
+// bssReadParamsPayload reads the boot parameters passed with -d, or from
+// standard input if it is not passed, which are either a list of boot
+// parameters or a single set of them. single is true if they are a single set.
+// If an error occurs, the program exits.
+func bssReadParamsPayload(cmd *cobra.Command) (bps []bssTypes.BootParams, single bool) {
+	var payload any
+	if cmd.Flag("data").Changed {
+		handlePayload(cmd, &payload)
+	} else {
+		handlePayloadStdin(cmd, &payload)
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to marshal payload into JSON")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if err := json.Unmarshal(raw, &bps); err != nil {
+		var bp bssTypes.BootParams
+		if err := json.Unmarshal(raw, &bp); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal boot parameters from payload")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		return []bssTypes.BootParams{bp}, true
+	}
+
+	return bps, false
+}
+
+// bssParamsVars returns a bss.ParamsVarsFunc that looks up the variables
+// available to kernel parameter templates for a target. The target's component
+// is fetched from SMD (for MAC addresses, via its ethernet interface) to
+// provide xname, nid, type, role, and arch. If withMetaData is true, the
+// component's cloud-init meta-data is also fetched and provided as meta_data.
+// If withVars is true, the variables of the component, merged over those of
+// its groups, are provided as vars. mac is the MAC address of a MAC target or,
+// if withMAC is true, that of the component's ethernet interface in SMD for
+// other targets (see bssBootMAC).
+func bssParamsVars(cmd *cobra.Command, withMetaData, withVars, withMAC bool) bss.ParamsVarsFunc {
+	smdClient := smdGetClient(cmd)
+	var cloudInitClient *ci.CloudInitClient
+	if withMetaData {
+		cloudInitClient = cloudInitGetClient(cmd)
+	}
+	var varsOf func(xname string) map[string]string
+	if withVars {
+		varsOf = varsResolver(cmd)
+	}
+
+	return func(target bss.ParamsTarget) (map[string]any, error) {
+		var (
+			vars = make(map[string]any)
+			comp smd.Component
+			henv client.HTTPEnvelope
+			err  error
+		)
+		switch target.Kind {
+		case bss.ParamsTargetNID:
+			var nid int64
+			if nid, err = strconv.ParseInt(target.ID, 10, 32); err != nil {
+				return nil, fmt.Errorf("invalid nid: %w", err)
+			}
+			henv, err = smdClient.GetComponentsNid(int32(nid), token)
+		case bss.ParamsTargetMAC:
+			vars["mac"] = target.ID
+			henv, err = smdClient.GetEthernetInterfaces("MACAddress="+url.QueryEscape(target.ID), token)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get ethernet interface from SMD: %w", err)
+			}
+			var eis []smd.EthernetInterface
+			if err := json.Unmarshal(henv.Body, &eis); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal ethernet interfaces: %w", err)
+			}
+			if len(eis) == 0 || eis[0].ComponentID == "" {
+				return nil, fmt.Errorf("no component found in SMD with this MAC address")
+			}
+			henv, err = smdClient.GetComponentsXname(eis[0].ComponentID, token)
+		default:
+			henv, err = smdClient.GetComponentsXname(target.ID, token)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get component from SMD: %w", err)
+		}
+		if err := json.Unmarshal(henv.Body, &comp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal component: %w", err)
+		}
+		vars["xname"] = comp.ID
+		vars["nid"] = comp.NID
+		vars["type"] = comp.Type
+		vars["role"] = comp.Role
+		vars["arch"] = comp.Arch
+		if _, ok := vars["mac"]; !ok && withMAC {
+			henv, err := smdClient.GetEthernetInterfaces("ComponentID="+url.QueryEscape(comp.ID), token)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get ethernet interfaces of %s from SMD: %w", comp.ID, err)
+			}
+			var eis []smd.EthernetInterface
+			if err := json.Unmarshal(henv.Body, &eis); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal ethernet interfaces: %w", err)
+			}
+			mac, ok := bssBootMAC(eis)
+			if !ok {
+				return nil, fmt.Errorf("template uses .mac, but %s has no ethernet interfaces in SMD", comp.ID)
+			}
+			vars["mac"] = mac
+		}
+
+		if withMetaData {
+			henvs, errs, err := cloudInitClient.GetNodeData(ci.CloudInitMetaData, token, comp.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get cloud-init meta-data for %s: %w", comp.ID, err)
+			}
+			if errs[0] != nil {
+				return nil, fmt.Errorf("failed to get cloud-init meta-data for %s: %w", comp.ID, errs[0])
+			}
+			var md map[string]any
+			if err := yaml.Unmarshal(henvs[0].Body, &md); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal cloud-init meta-data for %s: %w", comp.ID, err)
+			}
+			vars["meta_data"] = md
+		}
+		if withVars {
+			vars["vars"] = varsOf(comp.ID)
+		}
+
+		return vars, nil
+	}
+}
+
+// bssBootMAC returns the MAC address of the ethernet interface of a component
+// that it most likely boots from, which is the first of eis that has an IP
+// address, or else the first of eis. It returns false if eis is empty.
+func bssBootMAC(eis []smd.EthernetInterface) (string, bool) {
+	for _, ei := range eis {
+		if len(ei.IPAddresses) > 0 {
+			return ei.MACAddress, true
+		}
+	}
+	if len(eis) == 0 {
+		return "", false
+	}
+
+	return eis[0].MACAddress, true
+}
+
 // bssCmd represents the bss command
 var bssCmd = &cobra.Command{
 	Use:   "bss",
@@ -9,6 +9,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // cloudInitDefaultsSetCmd represents the "cloud-init defaults set" command
@@ -77,7 +78,7 @@ See ochami-cloud-init(1) for more details.`,
 }
 
 func init() {
-	cloudInitDefaultsSetCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	cloudInitDefaultsSetCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
 	cloudInitDefaultsSetCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
 
 	cloudInitDefaultsSetCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
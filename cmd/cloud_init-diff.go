@@ -0,0 +1,222 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/ci"
+	"github.com/OpenCHAMI/ochami/pkg/diff"
+)
+
+// cloudInitDiffCmd represents the "cloud-init diff" command
+var cloudInitDiffCmd = &cobra.Command{
+	Use:   "diff [--prune] [--apply [--no-confirm] [--yes-really-delete <n>]] <dir>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Compare cloud-init group configs with those in a directory",
+	Long: `Compare the cloud-configs of the groups in cloud-init with those in a
+directory and print the differences as a unified diff. The directory
+contains one cloud-config per group, named after the group with an
+optional extension (e.g. compute.yaml for the compute group). Hidden
+files and subdirectories are ignored.
+
+Groups in the directory but not in cloud-init are shown as new.
+Groups in cloud-init but not in the directory are left alone unless
+--prune is passed, in which case they are shown as deleted.
+
+Pass --apply to make cloud-init match the directory after printing
+the differences: new groups are added, changed groups have their
+cloud-config replaced (keeping their description and meta-data), and,
+with --prune, groups not in the directory are deleted. The user is
+asked to confirm unless --no-confirm is passed. Together with a git
+repository of cloud-configs, this allows a simple GitOps workflow.
+
+An access token is required.
+
+See ochami-cloud-init(1) for more details.`,
+	Example: `  # Show how cloud-init differs from a directory of cloud-configs
+  ochami cloud-init diff ./cloud-configs
+
+  # Make cloud-init match the directory, deleting groups not in it
+  ochami cloud-init diff --prune --apply ./cloud-configs`,
+	Run: func(cmd *cobra.Command, args []string) {
+		prune, err := cmd.Flags().GetBool("prune")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --prune")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		apply, err := cmd.Flags().GetBool("apply")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --apply")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		locals, err := ci.ReadCloudConfigDir(args[0])
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to read cloud-configs")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Get all group data, creating the client and handling the
+		// token
+		remote := make(map[string]cistore.GroupData)
+		for _, g := range cloudInitGetGroupData(cmd, nil) {
+			remote[g.Name] = g
+		}
+
+		// Compare each local cloud-config with that of its group
+		var (
+			toAdd, toSet []cistore.GroupData
+			toDelete     []string
+			inDir        = make(map[string]bool)
+		)
+		for _, lcc := range locals {
+			inDir[lcc.Group] = true
+			g, exists := remote[lcc.Group]
+			var current []byte
+			fromName := "/dev/null"
+			if exists {
+				if current, err = ci.GroupFileContent(g); err != nil {
+					log.Logger.Error().Err(err).Msg("failed to decode cloud-config")
+					logHelpError(cmd)
+					os.Exit(1)
+				}
+				fromName = "cloud-init/" + g.Name
+			}
+			if exists && bytes.Equal(current, lcc.Content) {
+				log.Logger.Debug().Msgf("group %s is unchanged", lcc.Group)
+				continue
+			}
+			fmt.Print(diff.Unified(fromName, lcc.Path, string(current), string(lcc.Content), diff.DefaultContext))
+
+			if !exists {
+				g = cistore.GroupData{
+					Name: lcc.Group,
+					File: cistore.CloudConfigFile{Name: filepath.Base(lcc.Path)},
+				}
+			}
+			g.File.Content = lcc.Content
+			g.File.Encoding = "plain"
+			if exists {
+				toSet = append(toSet, g)
+			} else {
+				toAdd = append(toAdd, g)
+			}
+		}
+
+		// Handle groups only in cloud-init
+		var remoteOnly []string
+		for name := range remote {
+			if !inDir[name] {
+				remoteOnly = append(remoteOnly, name)
+			}
+		}
+		sort.Strings(remoteOnly)
+		for _, name := range remoteOnly {
+			if !prune {
+				log.Logger.Info().Msgf("group %s is in cloud-init but not in %s, pass --prune to delete it", name, args[0])
+				continue
+			}
+			current, err := ci.GroupFileContent(remote[name])
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to decode cloud-config")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			fmt.Print(diff.Unified("cloud-init/"+name, "/dev/null", string(current), "", diff.DefaultContext))
+			toDelete = append(toDelete, name)
+		}
+
+		changes := len(toAdd) + len(toSet) + len(toDelete)
+		if changes == 0 {
+			log.Logger.Info().Msgf("cloud-init matches %s", args[0])
+			return
+		}
+		log.Logger.Info().Msgf("%d group(s) to add, %d to change, %d to delete", len(toAdd), len(toSet), len(toDelete))
+		if !apply {
+			return
+		}
+
+		// Ask before applying unless --no-confirm was passed, requiring
+		// the number of groups to delete to be confirmed if there are
+		// many
+		prompt := fmt.Sprintf("Really apply %d change(s) to cloud-init?", changes)
+		if len(toDelete) > 0 {
+			confirmDeletion(cmd, prompt, "cloud-init group", len(toDelete))
+		} else if !cmd.Flag("no-confirm").Changed {
+			log.Logger.Debug().Msg("--no-confirm not passed, prompting user to confirm applying changes")
+			respApply, err := ios.loopYesNo(prompt)
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("Error fetching user input")
+				os.Exit(1)
+			} else if !respApply {
+				log.Logger.Info().Msg("User aborted applying changes")
+				os.Exit(0)
+			} else {
+				log.Logger.Debug().Msg("User answered affirmatively to apply changes")
+			}
+		}
+
+		// Send data
+		cloudInitClient := cloudInitGetClient(cmd)
+		errorsOccurred := false
+		handleErrs := func(what string, errs []error, err error) {
+			if err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to %s groups", what)
+				errorsOccurred = true
+				return
+			}
+			for _, err := range errs {
+				if err != nil {
+					if errors.Is(err, client.UnsuccessfulHTTPError) {
+						log.Logger.Error().Err(err).Msg("cloud-init group request yielded unsuccessful HTTP response")
+					} else {
+						log.Logger.Error().Err(err).Msgf("failed to %s group in cloud-init", what)
+					}
+					errorsOccurred = true
+				}
+			}
+			reportNotAttempted(errs)
+		}
+		if len(toAdd) > 0 {
+			_, errs, err := cloudInitClient.PostGroups(toAdd, token)
+			handleErrs("add", errs, err)
+		}
+		if len(toSet) > 0 {
+			_, errs, err := cloudInitClient.PutGroups(toSet, token)
+			handleErrs("set", errs, err)
+		}
+		if len(toDelete) > 0 {
+			_, errs, err := cloudInitClient.DeleteGroups(token, toDelete...)
+			handleErrs("delete", errs, err)
+		}
+		if errorsOccurred {
+			log.Logger.Warn().Msg("applying cloud-init changes completed with errors")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		log.Logger.Info().Msgf("applied %d change(s) to cloud-init", changes)
+	},
+}
+
+func init() {
+	cloudInitDiffCmd.Flags().Bool("prune", false, "show (and, with --apply, delete) groups in cloud-init that are not in the directory")
+	cloudInitDiffCmd.Flags().Bool("apply", false, "make cloud-init match the directory after showing the differences")
+	cloudInitDiffCmd.Flags().Bool("no-confirm", false, "do not ask before applying changes")
+	cloudInitDiffCmd.Flags().Int("yes-really-delete", 0, "confirm deleting more than delete-threshold items by passing their exact number")
+
+	cloudInitCmd.AddCommand(cloudInitDiffCmd)
+}
@@ -4,6 +4,7 @@ package cmd
 
 import (
 	"errors"
+	"net/http"
 	"os"
 
 	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // cloudInitGroupAddCmd represents the "cloud-init group add" command
@@ -26,6 +28,11 @@ payload data ('json' by default), but the rules above still apply
 for the payload. If "-" is used as the input payload filename, the
 data is read from standard input.
 
+Secret references in cloud-config files (e.g.
+{{ secret "vault:kv/cluster/root-pass" }}) are stored as-is unless
+--resolve-secrets is passed, in which case they are replaced by the
+values of the secrets before sending.
+
 See ochami-cloud-init(1) for more details.`,
 	Example: `  # Add cloud-init groups using input payload data
   ochami cloud-init group add -d '[{
@@ -67,13 +74,26 @@ See ochami-cloud-init(1) for more details.`,
 			handlePayloadStdin(cmd, &ciGroups)
 		}
 
+		// Resolve or warn about secret references
+		cloudInitHandleGroupSecrets(cmd, ciGroups)
+
+		// Stamp groups with who changed them
+		for i := range ciGroups {
+			ciGroups[i].Description = annotate(cmd, ciGroups[i].Description)
+		}
+
 		// Send data
-		_, errs, err := cloudInitClient.PostGroups(ciGroups, token)
+		henvs, errs, err := cloudInitClient.PostGroups(ciGroups, token)
 		if err != nil {
 			log.Logger.Error().Err(err).Msgf("failed to add groups")
 			logHelpError(cmd)
 			os.Exit(1)
 		}
+		names := make([]string, len(ciGroups))
+		for i, g := range ciGroups {
+			names[i] = g.Name
+		}
+		ignoreStatus(cmd, "if-not-exists", http.StatusConflict, "group", henvs, errs, names)
 		// Since the requests are done iteratively, we need to deal with
 		// each error that might have occurred.
 		var errorsOccurred = false
@@ -87,6 +107,7 @@ See ochami-cloud-init(1) for more details.`,
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		if errorsOccurred {
 			log.Logger.Warn().Msg("cloud-init group addition completed with errors")
 			logHelpError(cmd)
@@ -96,8 +117,10 @@ See ochami-cloud-init(1) for more details.`,
 }
 
 func init() {
-	cloudInitGroupAddCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	cloudInitGroupAddCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
 	cloudInitGroupAddCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
+	cloudInitGroupAddCmd.Flags().Bool("resolve-secrets", false, "replace secret references in cloud-config files with their values before sending")
+	cloudInitGroupAddCmd.Flags().Bool("if-not-exists", false, ifNotExistsFlagUsage)
 
 	cloudInitGroupAddCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
 
@@ -5,6 +5,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
@@ -12,6 +13,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // cloudInitGroupDeleteCmd represents the "cloud-init group delete" command
@@ -76,28 +78,18 @@ See ochami-cloud-init(1) for more details.`,
 			groupsToDel = args
 		}
 
-		// Ask before attempting deletion unless --no-confirm was passed
-		if !cmd.Flag("no-confirm").Changed {
-			log.Logger.Debug().Msg("--no-confirm not passed, prompting user to confirm deletion")
-			respDelete, err := ios.loopYesNo("Really delete?")
-			if err != nil {
-				log.Logger.Error().Err(err).Msg("Error fetching user input")
-				os.Exit(1)
-			} else if !respDelete {
-				log.Logger.Info().Msg("User aborted cloud-init group deletion")
-				os.Exit(0)
-			} else {
-				log.Logger.Debug().Msg("User answered affirmatively to delete cloud-init groups")
-			}
-		}
+		// Ask before attempting deletion, requiring the number of
+		// groups to be confirmed if there are many
+		confirmDeletion(cmd, "Really delete?", "cloud-init group", len(groupsToDel))
 
 		// Send data
-		_, errs, err := cloudInitClient.DeleteGroups(token, groupsToDel...)
+		henvs, errs, err := cloudInitClient.DeleteGroups(token, groupsToDel...)
 		if err != nil {
 			log.Logger.Error().Err(err).Msgf("failed to delete groups")
 			logHelpError(cmd)
 			os.Exit(1)
 		}
+		ignoreStatus(cmd, "if-exists", http.StatusNotFound, "group", henvs, errs, groupsToDel)
 		// Since the requests are done iteratively, we need to deal with
 		// each error that might have occurred.
 		var errorsOccurred = false
@@ -111,6 +103,7 @@ See ochami-cloud-init(1) for more details.`,
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		if errorsOccurred {
 			log.Logger.Warn().Msg("cloud-init group deletion completed with errors")
 			logHelpError(cmd)
@@ -121,8 +114,10 @@ See ochami-cloud-init(1) for more details.`,
 
 func init() {
 	cloudInitGroupDeleteCmd.Flags().Bool("no-confirm", false, "do not ask before attempting deletion")
-	cloudInitGroupDeleteCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	cloudInitGroupDeleteCmd.Flags().Int("yes-really-delete", 0, "confirm deleting more than delete-threshold items by passing their exact number")
+	cloudInitGroupDeleteCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
 	cloudInitGroupDeleteCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
+	cloudInitGroupDeleteCmd.Flags().Bool("if-exists", false, ifExistsFlagUsage)
 
 	cloudInitGroupDeleteCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
 
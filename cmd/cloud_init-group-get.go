@@ -18,13 +18,19 @@ import (
 
 // cloudInitGetGroupData returns a slice of cloud-init group data for the
 // requested groups. If an error occurs, the program exits.
-func cloudInitGetGroupData(cmd *cobra.Command, args []string) (groupSlice []cistore.GroupData) {
+func cloudInitGetGroupData(cmd *cobra.Command, args []string) []cistore.GroupData {
 	// Create client to use for requests
 	cloudInitClient := cloudInitGetClient(cmd)
 
 	// Handle token for this command
 	handleToken(cmd)
 
+	return cloudInitFetchGroupData(cmd, cloudInitClient, args)
+}
+
+// cloudInitFetchGroupData is cloudInitGetGroupData using cloudInitClient, for
+// commands that already set up a client and token.
+func cloudInitFetchGroupData(cmd *cobra.Command, cloudInitClient *ci.CloudInitClient, args []string) (groupSlice []cistore.GroupData) {
 	// Get data
 	if len(args) == 0 {
 		// No args passed, get all group data at once
@@ -78,6 +84,7 @@ func cloudInitGetGroupData(cmd *cobra.Command, args []string) (groupSlice []cist
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		if errorsOccurred {
 			log.Logger.Warn().Msg("cloud-init group retrieval completed with errors")
 			logHelpError(cmd)
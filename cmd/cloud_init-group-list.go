@@ -0,0 +1,142 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/ci"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// cloudInitGroupListCmd represents the "cloud-init group list" command
+var cloudInitGroupListCmd = &cobra.Command{
+	Use:   "list",
+	Args:  cobra.NoArgs,
+	Short: "List cloud-init groups, optionally with SMD usage",
+	Long: `List cloud-init groups, optionally with SMD usage. If --with-usage is
+passed, SMD groups and components are also fetched to determine which
+nodes each cloud-init group applies to, whether each group's cloud-config
+is empty or unparseable, which groups are orphaned, and which nodes have
+no applicable config.
+
+Without --with-usage, the annotation of who last changed each group
+with ochami, when, and with what --comment is printed separately from
+its description.
+
+See ochami-cloud-init(1) for more details.`,
+	Example: `  ochami cloud-init group list
+  ochami cloud-init group list --with-usage
+  ochami cloud-init group list --with-usage -F yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Get all cloud-init groups
+		groupSlice := cloudInitGetGroupData(cmd, []string{})
+
+		var output interface{}
+		if !cmd.Flag("with-usage").Changed {
+			type listGroup struct {
+				Name        string          `json:"name" yaml:"name"`
+				Description string          `json:"description,omitempty" yaml:"description,omitempty"`
+				Annotation  *smd.Annotation `json:"annotation,omitempty" yaml:"annotation,omitempty"`
+			}
+			list := []listGroup{}
+			for _, g := range groupSlice {
+				desc, a := smd.ParseAnnotation(g.Description)
+				list = append(list, listGroup{Name: g.Name, Description: desc, Annotation: a})
+			}
+			output = list
+		} else {
+			// Create client to use for SMD requests
+			smdClient := smdGetClient(cmd)
+
+			// Get SMD groups and their members
+			henv, err := smdClient.GetGroups("", token)
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msg("SMD group request yielded unsuccessful HTTP response")
+				} else {
+					log.Logger.Error().Err(err).Msg("failed to request groups from SMD")
+				}
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			var smdGroups []smd.Group
+			if err := json.Unmarshal(henv.Body, &smdGroups); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to unmarshal SMD groups")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			smdGroupMap := make(map[string][]string)
+			for _, g := range smdGroups {
+				smdGroupMap[g.Label] = g.Members.IDs
+			}
+
+			// Get SMD nodes
+			henv, err = smdClient.GetComponentsAll()
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msg("SMD component request yielded unsuccessful HTTP response")
+				} else {
+					log.Logger.Error().Err(err).Msg("failed to request components from SMD")
+				}
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			var comps smd.ComponentSlice
+			if err := json.Unmarshal(henv.Body, &comps); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to unmarshal SMD components")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			var nodes []string
+			for _, c := range comps.Components {
+				if c.Type == "Node" {
+					nodes = append(nodes, c.ID)
+				}
+			}
+
+			report := ci.GroupUsageReport(groupSlice, smdGroupMap, nodes)
+			for _, g := range report.Groups {
+				switch {
+				case g.Config == ci.ConfigStateInvalid:
+					log.Logger.Warn().Msgf("cloud-config for group %s is invalid: %s", g.Name, g.ConfigError)
+				case g.Config == ci.ConfigStateEmpty:
+					log.Logger.Warn().Msgf("cloud-config for group %s is empty", g.Name)
+				}
+				if g.Orphaned {
+					log.Logger.Warn().Msgf("group %s is not referenced by any SMD nodes", g.Name)
+				}
+			}
+			if len(report.UnconfiguredNodes) > 0 {
+				log.Logger.Warn().Msgf("%d node(s) have no applicable cloud-config", len(report.UnconfiguredNodes))
+			}
+			output = report
+		}
+
+		// Print output
+		if outBytes, err := format.MarshalData(output, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+	},
+}
+
+func init() {
+	cloudInitGroupListCmd.Flags().Bool("with-usage", false, "include SMD node usage and config health of each group")
+	cloudInitGroupListCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	cloudInitGroupListCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	cloudInitGroupCmd.AddCommand(cloudInitGroupListCmd)
+}
@@ -3,9 +3,20 @@
 package cmd
 
 import (
-	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
@@ -16,27 +27,197 @@ import (
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
 	"github.com/OpenCHAMI/ochami/pkg/client/ci"
+	"github.com/OpenCHAMI/ochami/pkg/diff"
+	"github.com/OpenCHAMI/ochami/pkg/pool"
 )
 
+type CIFlagRenderFormat string
+
+const (
+	CIFlagRenderYAML       = "yaml"
+	CIFlagRenderMIME       = "mime"
+	CIFlagRenderShell      = "shell"
+	CIFlagRenderWriteFiles = "write-files-extract"
+)
+
+var (
+	CIFlagRenderFormatHelp = map[string]string{
+		string(CIFlagRenderYAML):       "Print the rendered config",
+		string(CIFlagRenderMIME):       "Print the rendered config as a MIME multi-part archive",
+		string(CIFlagRenderShell):      "Print the runcmd list as a shell script",
+		string(CIFlagRenderWriteFiles): "Extract write_files to the directory passed with --output-dir",
+	}
+	ciRenderFormat CIFlagRenderFormat = CIFlagRenderYAML
+)
+
+func (cfrf CIFlagRenderFormat) String() string {
+	return string(cfrf)
+}
+
+func (cfrf *CIFlagRenderFormat) Set(v string) error {
+	switch CIFlagRenderFormat(v) {
+	case CIFlagRenderYAML,
+		CIFlagRenderMIME,
+		CIFlagRenderShell,
+		CIFlagRenderWriteFiles:
+		*cfrf = CIFlagRenderFormat(v)
+		return nil
+	default:
+		return fmt.Errorf("must be one of %v", []CIFlagRenderFormat{
+			CIFlagRenderYAML,
+			CIFlagRenderMIME,
+			CIFlagRenderShell,
+			CIFlagRenderWriteFiles,
+		})
+	}
+}
+
+func (cfrf CIFlagRenderFormat) Type() string {
+	return "CIFlagRenderFormat"
+}
+
+func cloudInitCompletionRenderFormat(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var helpSlice []string
+	for k, v := range CIFlagRenderFormatHelp {
+		helpSlice = append(helpSlice, fmt.Sprintf("%s\t%s", k, v))
+	}
+	return helpSlice, cobra.ShellCompDirectiveDefault
+}
+
 // cloudInitGroupRenderCmd represents the "cloud-init group render" command
 var cloudInitGroupRenderCmd = &cobra.Command{
-	Use:   "render <group_name> <node_id>",
-	Args:  cobra.ExactArgs(2),
-	Short: "Render cloud-init config for specific group using a node",
-	Long: `Render cloud-init config for specific group using a node.
+	Use:   "render [--meta-data-snapshot <file> [--diff]] <group_name> <node_id>...",
+	Args:  cobra.MinimumNArgs(2),
+	Short: "Render cloud-init config for specific group using one or more nodes",
+	Long: `Render cloud-init config for specific group using one or more
+nodes. Secret references (e.g.
+{{ secret "vault:kv/cluster/root-pass" }}) are resolved before
+rendering. If the config or an included template references vars,
+the variables of the node set with 'ochami vars set' (e.g.
+{{ vars.console }}) are available as well.
+
+If the rendered config uses #include directives or is a MIME
+multi-part archive, the included URLs are fetched and all
+cloud-config parts are merged the way cloud-init would, honoring
+merge_how and Merge-Type directives, so that the output matches
+what the node applies. Pass --no-resolve to skip this.
+
+Pass --format to post-process the resolved config: 'mime' prints it
+as a MIME multi-part archive along with the parts that are not
+cloud-config, 'shell' prints its runcmd list as the shell script
+cloud-init would run, and 'write-files-extract' writes the files in
+its write_files list under the directory passed with --output-dir
+for inspection.
+
+More than one node can be passed to render the config for each of
+them, which requires --output-dir. The output for each node is
+written to <node_id>.yaml (.mime or .sh with --format mime or shell)
+in that directory, or its files are extracted under <node_id>/ with
+--format write-files-extract. The group config, secrets, variables,
+and included URLs are only fetched once for all nodes, and the
+meta-data of up to --concurrency nodes is fetched at once.
+
+Pass --meta-data-snapshot to render against the meta-data saved in a
+snapshot file by 'cloud-init snapshot' instead of the current
+meta-data of the nodes, e.g. to test template changes against the
+data of the day before. With --diff, the config is rendered against
+both and the differences from the snapshot to the current meta-data
+are printed as a unified diff (written to <node_id>.diff for more than
+one node, only if there are any).
 
 See ochami-cloud-init(1) for more details.`,
 	Example: `  # Render group 'compute' cloud-init config for node x3000c0s0b0n0
-  ochami cloud-init group render compute x3000c0s0b0n0`,
+  ochami cloud-init group render compute x3000c0s0b0n0
+
+  # Review the commands that runcmd would run on x3000c0s0b0n0
+  ochami cloud-init group render compute x3000c0s0b0n0 --format shell
+
+  # Inspect the files that write_files would write on x3000c0s0b0n0
+  ochami cloud-init group render compute x3000c0s0b0n0 --format write-files-extract -o ./files
+
+  # Render group 'compute' cloud-init config for every node of a rack
+  ochami cloud-init group render compute x3000c0s{0..31}b0n0 -o ./rendered
+
+  # Show how a template renders differently today than against yesterday's meta-data
+  ochami cloud-init group render compute x3000c0s0b0n0 --meta-data-snapshot snap.json --diff`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if ciRenderFormat != CIFlagRenderYAML && cmd.Flag("no-resolve").Changed {
+			return fmt.Errorf("--no-resolve can only be used with --format %s", CIFlagRenderYAML)
+		}
+		if len(args) > 2 {
+			if !cmd.Flag("output-dir").Changed {
+				return fmt.Errorf("--output-dir is required to render more than one node")
+			}
+		} else if (ciRenderFormat == CIFlagRenderWriteFiles) != cmd.Flag("output-dir").Changed {
+			return fmt.Errorf("--output-dir is required with, and only used by, --format %s", CIFlagRenderWriteFiles)
+		}
+		if cmd.Flag("diff").Changed {
+			if !cmd.Flag("meta-data-snapshot").Changed {
+				return fmt.Errorf("--diff requires --meta-data-snapshot")
+			}
+			if ciRenderFormat == CIFlagRenderWriteFiles {
+				return fmt.Errorf("--diff cannot be used with --format %s", CIFlagRenderWriteFiles)
+			}
+		}
+		if c, _ := cmd.Flags().GetInt("concurrency"); c < 1 {
+			return fmt.Errorf("--concurrency must be at least 1")
+		}
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		group := args[0]
+		var nodes []string
+		for _, n := range args[1:] {
+			if slices.Contains(nodes, n) {
+				log.Logger.Warn().Msgf("node %s passed more than once, rendering it once", n)
+				continue
+			}
+			nodes = append(nodes, n)
+		}
+		dir, err := cmd.Flags().GetString("output-dir")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --output-dir")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		concurrency, err := cmd.Flags().GetInt("concurrency")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --concurrency")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		noResolve, _ := cmd.Flags().GetBool("no-resolve")
+		showDiff, _ := cmd.Flags().GetBool("diff")
+
+		// Read the meta-data snapshot to render against, if any
+		var snap *ci.MetaDataSnapshot
+		if cmd.Flag("meta-data-snapshot").Changed {
+			snapFile, _ := cmd.Flags().GetString("meta-data-snapshot")
+			f, err := os.Open(snapFile)
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to open meta-data snapshot")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			s, err := ci.ReadMetaDataSnapshot(f)
+			f.Close()
+			if err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to read meta-data snapshot %s", snapFile)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			snap = &s
+		}
+
 		// Create client to use for requests
 		cloudInitClient := cloudInitGetClient(cmd)
 
 		// Handle token for this command
 		handleToken(cmd)
 
-		// Get group config
-		henvs, errs, err := cloudInitClient.GetNodeGroupData(token, args[1], args[0])
+		// Get group config, which is the same for every member of the
+		// group, so only through the first node
+		henvs, errs, err := cloudInitClient.GetNodeGroupData(token, nodes[0], group)
 		if err != nil {
 			log.Logger.Error().Err(err).Msg("failed to get cloud-init group")
 			logHelpError(cmd)
@@ -55,55 +236,320 @@ See ochami-cloud-init(1) for more details.`,
 
 		// Don't try to get meta-data and render if config is empty
 		if len(ciConfigFileBytes) == 0 {
-			log.Logger.Warn().Msgf("cloud-config for group %s was empty, cannot render for node %s", args[0], args[1])
-			os.Exit(0)
+			log.Logger.Warn().Msgf("cloud-config for group %s was empty, cannot render for node %s", group, nodes[0])
+			exitWithStatus(0)
 		}
 
-		// Get node instance data
-		henvs, errs, err = cloudInitClient.GetNodeData(ci.CloudInitMetaData, token, args[1])
+		// Resolve secret references before rendering
+		ciConfigFileBytes, err = cloudInitSecretResolver(cmd).ResolveSecrets(ciConfigFileBytes)
 		if err != nil {
-			log.Logger.Error().Err(err).Msg("failed to get cloud-init node meta-data")
+			log.Logger.Error().Err(err).Msg("failed to resolve secrets in cloud-config")
 			logHelpError(cmd)
 			os.Exit(1)
 		}
-		if errs[0] != nil {
-			if errors.Is(err, client.UnsuccessfulHTTPError) {
-				log.Logger.Error().Err(err).Msg("cloud-init node meta-data request yielded unsuccessful HTTP response")
-			} else {
-				log.Logger.Error().Err(err).Msg("failed to get cloud-init node meta-data")
+
+		r := &cloudInitGroupRenderer{
+			client:    cloudInitClient,
+			group:     group,
+			config:    ciConfigFileBytes,
+			noResolve: noResolve,
+			snapshot:  snap,
+			templates: ci.NewMemo(gonja.FromString),
+			includes:  ci.NewMemo(cloudInitFetchInclude),
+		}
+		// Only load variables once a template, which may be an included
+		// one, references them
+		resolveVars := sync.OnceValue(func() func(string) map[string]string { return varsResolver(cmd) })
+		r.varsOf = func(xname string) map[string]string { return resolveVars()(xname) }
+
+		// Render for a single node to standard output, unless extracting
+		// files
+		if len(nodes) == 1 {
+			render := r.render
+			if showDiff {
+				render = r.renderDiff
+			}
+			if err := render(nodes[0], os.Stdout, dir); err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to render cloud-config for node %s", nodes[0])
+				logHelpError(cmd)
+				os.Exit(1)
 			}
+			return
+		}
+
+		// Render for each node, checking that it is a member of the group
+		// since the config was only fetched through the first node
+		members := cloudInitGroupMembers(cmd, group)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to create output directory")
 			logHelpError(cmd)
 			os.Exit(1)
 		}
-		var ciData map[string]interface{}
-		dsWrapper := make(map[string]interface{})
-		if err := yaml.Unmarshal(henvs[0].Body, &ciData); err != nil {
-			log.Logger.Error().Err(err).Msg("failed to unmarshal HTTP body into map")
+		var errorsOccurred bool
+		pool.Run(context.Background(), len(nodes), concurrency, 0, func(ctx context.Context, i int) error {
+			node := nodes[i]
+			if !slices.ContainsFunc(members, func(m string) bool { return strings.EqualFold(m, node) }) {
+				return fmt.Errorf("node is not a member of group %s", group)
+			}
+			if ciRenderFormat == CIFlagRenderWriteFiles {
+				return r.render(node, nil, filepath.Join(dir, node))
+			}
+			if showDiff {
+				var out bytes.Buffer
+				if err := r.renderDiff(node, &out, ""); err != nil {
+					return err
+				}
+				if out.Len() == 0 {
+					log.Logger.Info().Msgf("node %s: no differences from the meta-data snapshot", node)
+					return nil
+				}
+				path := filepath.Join(dir, node+".diff")
+				if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+					return err
+				}
+				fmt.Println(path)
+				return nil
+			}
+			ext := map[CIFlagRenderFormat]string{CIFlagRenderMIME: ".mime", CIFlagRenderShell: ".sh"}[ciRenderFormat]
+			if ext == "" {
+				ext = ".yaml"
+			}
+			path := filepath.Join(dir, node+ext)
+			f, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			if err := r.render(node, f, ""); err != nil {
+				f.Close()
+				os.Remove(path)
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			fmt.Println(path)
+			return nil
+		}, func(i int, err error) {
+			if err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to render cloud-config for node %s", nodes[i])
+				errorsOccurred = true
+			}
+		})
+		log.Logger.Info().Msgf("rendered group %s for %d node(s), fetching %d included URL(s)", group, len(nodes), r.includes.Len())
+		if errorsOccurred {
 			logHelpError(cmd)
+			log.Logger.Warn().Msg("rendering completed with errors")
 			os.Exit(1)
 		}
-		dsWrapper["ds"] = map[string]interface{}{"meta_data": ciData}
-		refData := exec.NewContext(dsWrapper)
+	},
+}
+
+// cloudInitGroupRenderer renders the config of a group for nodes. What is the
+// same for every node (the config, variables, parsed templates, and included
+// URLs) is fetched or parsed only once, so that rendering for many nodes only
+// costs a meta-data request per node. If snapshot is set, the meta-data of
+// nodes is read from it instead. It is safe for concurrent use.
+type cloudInitGroupRenderer struct {
+	client    *ci.CloudInitClient
+	group     string
+	config    []byte
+	noResolve bool
+	snapshot  *ci.MetaDataSnapshot
+	varsOf    func(xname string) map[string]string
+	templates *ci.Memo[*exec.Template]
+	includes  *ci.Memo[[]byte]
+}
+
+// render renders the config for node, writing it to w in the format passed to
+// --format or, with --format write-files-extract, extracting its files under
+// dir and printing their paths.
+func (r *cloudInitGroupRenderer) render(node string, w io.Writer, dir string) error {
+	// Get node instance data
+	ciData, err := r.metaData(node)
+	if err != nil {
+		return err
+	}
+	dsWrapper := make(map[string]interface{})
+	dsWrapper["ds"] = map[string]interface{}{"meta_data": ciData}
 
-		// Render
-		tpl, err := gonja.FromBytes(ciConfigFileBytes)
+	// Render
+	render := func(tplBytes []byte) ([]byte, error) {
+		tpl, err := r.templates.Get(string(tplBytes))
 		if err != nil {
-			log.Logger.Error().Err(err).Msg("failed to create template")
-			logHelpError(cmd)
-			os.Exit(1)
+			return nil, fmt.Errorf("failed to create template: %w", err)
 		}
-		out := bufio.NewWriter(os.Stdout)
-		if err := tpl.Execute(out, refData); err != nil {
-			log.Logger.Error().Err(err).Msg("failed to render template")
-			logHelpError(cmd)
-			os.Exit(1)
+		if _, ok := dsWrapper["vars"]; !ok && bytes.Contains(tplBytes, []byte("vars")) {
+			dsWrapper["vars"] = r.varsOf(node)
 		}
+		var out bytes.Buffer
+		if err := tpl.Execute(&out, exec.NewContext(dsWrapper)); err != nil {
+			return nil, fmt.Errorf("failed to render template: %w", err)
+		}
+		return out.Bytes(), nil
+	}
+	rendered, err := render(r.config)
+	if err != nil {
+		return err
+	}
 
-		// Write rendered template to stdout
-		out.Flush()
-	},
+	// Resolve includes and merge parts the way cloud-init would.
+	// Other formats always need the resolved config.
+	if r.noResolve || (ciRenderFormat == CIFlagRenderYAML && !ci.NeedsResolving(rendered)) {
+		_, err := w.Write(rendered)
+		return err
+	}
+	resolver := ci.UserDataResolver{
+		Fetch:  r.includes.Get,
+		Render: render,
+	}
+	res, err := resolver.Resolve("group "+r.group, rendered)
+	if err != nil {
+		return fmt.Errorf("failed to resolve includes in cloud-config: %w", err)
+	}
+	if ciRenderFormat != CIFlagRenderMIME {
+		for _, s := range res.Skipped {
+			log.Logger.Warn().Msgf("node %s: %s is not cloud-config and was not merged", node, s)
+		}
+	}
+
+	// Write the rendered config in the requested format
+	switch ciRenderFormat {
+	case CIFlagRenderMIME:
+		mime, err := ci.MakeMIME(res)
+		if err != nil {
+			return fmt.Errorf("failed to assemble MIME multi-part archive: %w", err)
+		}
+		_, err = w.Write(mime)
+		return err
+	case CIFlagRenderShell:
+		script, err := ci.RuncmdScript(res.Config)
+		if errors.Is(err, ci.ErrNoRuncmd) {
+			log.Logger.Warn().Msgf("cloud-config for group %s has no runcmd for node %s", r.group, node)
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to convert runcmd to shell script: %w", err)
+		}
+		_, err = w.Write(script)
+		return err
+	case CIFlagRenderWriteFiles:
+		files, err := ci.ExtractWriteFiles(res.Config, dir, r.includes.Get)
+		for _, f := range files {
+			fmt.Println(f.File)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to extract write_files: %w", err)
+		}
+		if len(files) == 0 {
+			log.Logger.Warn().Msgf("cloud-config for group %s has no write_files for node %s", r.group, node)
+		}
+		return nil
+	default:
+		_, err := w.Write(res.Config)
+		return err
+	}
+}
+
+// metaData returns the meta-data of node, from the snapshot of r if it has one
+// and from cloud-init otherwise.
+func (r *cloudInitGroupRenderer) metaData(node string) (map[string]interface{}, error) {
+	if r.snapshot != nil {
+		return r.snapshot.MetaData(node)
+	}
+	henvs, errs, err := r.client.GetNodeData(ci.CloudInitMetaData, token, node)
+	if err == nil {
+		err = errs[0]
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cloud-init node meta-data: %w", err)
+	}
+	var ciData map[string]interface{}
+	if err := yaml.Unmarshal(henvs[0].Body, &ciData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal HTTP body into map: %w", err)
+	}
+
+	return ciData, nil
+}
+
+// renderDiff renders the config for node against both the snapshot of r and
+// the current meta-data of node, and writes the differences between them to w
+// as a unified diff, which is empty if there are none.
+func (r *cloudInitGroupRenderer) renderDiff(node string, w io.Writer, _ string) error {
+	var fromSnap, fromLive bytes.Buffer
+	if err := r.render(node, &fromSnap, ""); err != nil {
+		return err
+	}
+	live := *r
+	live.snapshot = nil
+	if err := live.render(node, &fromLive, ""); err != nil {
+		return err
+	}
+	fromName := fmt.Sprintf("%s (snapshot %s)", node, r.snapshot.Created.Format(time.RFC3339))
+	_, err := io.WriteString(w, diff.Unified(fromName, node+" (current)", fromSnap.String(), fromLive.String(), diff.DefaultContext))
+
+	return err
+}
+
+// cloudInitGroupMembers returns the members of the SMD group group, through
+// which cloud-init decides which nodes get its config. If they cannot be
+// read, the program exits.
+func cloudInitGroupMembers(cmd *cobra.Command, group string) []string {
+	henv, err := smdGetClient(cmd).GetGroupMembers(group, token)
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("SMD group members request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to get members of group from SMD")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	var members struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.Unmarshal(henv.Body, &members); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to unmarshal SMD group members")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return members.IDs
+}
+
+// cloudInitFetchInclude fetches url, listed in an #include part of cloud-init
+// user-data, returning its contents. Only http and https URLs are supported,
+// since other URLs refer to files on the node.
+func cloudInitFetchInclude(url string) ([]byte, error) {
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return nil, fmt.Errorf("unsupported URL %q: only http and https URLs can be fetched", url)
+	}
+	hc := &http.Client{Timeout: 30 * time.Second}
+	if insecure {
+		hc.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+	}
+	res, err := hc.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, fmt.Errorf("unsuccessful HTTP response: %s", res.Status)
+	}
+
+	return io.ReadAll(res.Body)
 }
 
 func init() {
+	cloudInitGroupRenderCmd.Flags().Bool("no-resolve", false, "do not resolve #include directives or merge multi-part configs")
+	cloudInitGroupRenderCmd.Flags().Var(&ciRenderFormat, "format", "format of rendered config (yaml,mime,shell,write-files-extract)")
+	cloudInitGroupRenderCmd.Flags().StringP("output-dir", "o", "", "directory to extract write_files to with --format write-files-extract, or to write the output for each node to")
+	cloudInitGroupRenderCmd.Flags().Int("concurrency", 8, "maximum number of nodes to render at once")
+	cloudInitGroupRenderCmd.Flags().String("meta-data-snapshot", "", "render against the meta-data in this snapshot file (see 'cloud-init snapshot') instead of the current one")
+	cloudInitGroupRenderCmd.Flags().Bool("diff", false, "with --meta-data-snapshot, print the differences from rendering against the snapshot to rendering against the current meta-data")
+
+	cloudInitGroupRenderCmd.MarkFlagFilename("meta-data-snapshot")
+
+	cloudInitGroupRenderCmd.RegisterFlagCompletionFunc("format", cloudInitCompletionRenderFormat)
+	cloudInitGroupRenderCmd.MarkFlagDirname("output-dir")
+
 	cloudInitGroupCmd.AddCommand(cloudInitGroupRenderCmd)
 }
@@ -4,6 +4,7 @@ package cmd
 
 import (
 	"errors"
+	"fmt"
 	"os"
 
 	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
@@ -11,12 +12,13 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/ci"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // cloudInitGroupSetCmd represents the "cloud-init group set" command
 var cloudInitGroupSetCmd = &cobra.Command{
-	Use:   "set [-d (<data> | @<path>)] [-f <format>]",
-	Args:  cobra.NoArgs,
+	Use:   "set ([-d (<data> | @<path>)] [-f <format>]) | (--patch (<patch_data> | @<patch_file>) [--patch-type <type>] <group_name>...)",
 	Short: "Set cloud-init group data, overwriting existing data",
 	Long: `Set cloud-init group data, overwriting existing data. Data is read from
 standard input. Alternatively, pass -d to pass raw payload data
@@ -26,6 +28,17 @@ payload data ('json' by default), but the rules above still apply
 for the payload. If "-" is used as the input payload filename, the
 data is read from standard input.
 
+Secret references in cloud-config files (e.g.
+{{ secret "vault:kv/cluster/root-pass" }}) are stored as-is unless
+--resolve-secrets is passed, in which case they are replaced by the
+values of the secrets before sending.
+
+Alternatively, pass --patch with one or more group names to apply
+a JSON Patch (RFC 6902, --patch-type json, the default) or JSON
+Merge Patch (RFC 7396, --patch-type merge) document client-side to
+each group's current data fetched from cloud-init. Each patched
+group is then set.
+
 See ochami-cloud-init(1) for more details.`,
 	Example: `  # Set cloud-init group data using input payload data
   ochami cloud-init group set -d '[{
@@ -45,11 +58,26 @@ See ochami-cloud-init(1) for more details.`,
   ochami cloud-init group set -d @payload.json
   ochami cloud-init group set -d @payload.yaml -f yaml
 
+  # Modify existing cloud-init group data using a JSON Patch or merge patch
+  ochami cloud-init group set --patch '[{"op":"replace","path":"/meta-data/foo","value":"baz"}]' compute
+  ochami cloud-init group set --patch '{"meta-data":{"foo":null}}' --patch-type merge compute gpu
+
   # Set cloud-init group data using data from standard input
   echo '<json_data>' | ochami cloud-init group set
   echo '<json_data>' | ochami cloud-init group set -d @-
   echo '<yaml_data>' | ochami cloud-init group set -f yaml
   echo '<yaml_data>' | ochami cloud-init group set -d @- -f yaml`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flag("patch").Changed {
+			if len(args) == 0 {
+				return fmt.Errorf("--patch requires at least 1 argument (group name)")
+			}
+		} else if len(args) > 0 {
+			return fmt.Errorf("group names can only be passed with --patch, got %d argument(s)", len(args))
+		}
+
+		return nil
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// Create client to use for requests
 		cloudInitClient := cloudInitGetClient(cmd)
@@ -60,13 +88,35 @@ See ochami-cloud-init(1) for more details.`,
 		// The list of group data we will send
 		ciGroups := []cistore.GroupData{}
 
-		// Read payload from file or stdin.
-		if cmd.Flag("data").Changed {
+		// Read payload from file or stdin, or patch current data.
+		if cmd.Flag("patch").Changed {
+			patchBytes := handlePatchRaw(cmd)
+			// Patch the group data in cloud-init itself rather
+			// than a cached copy, since it is written back
+			uncached := &ci.CloudInitClient{OchamiClient: cloudInitClient.Uncached()}
+			for _, cur := range cloudInitFetchGroupData(cmd, uncached, args) {
+				var group cistore.GroupData
+				handlePatch(cmd, patchBytes, cur, &group)
+				if group.Name != cur.Name {
+					log.Logger.Warn().Msgf("patch cannot change group name, keeping %s", cur.Name)
+					group.Name = cur.Name
+				}
+				ciGroups = append(ciGroups, group)
+			}
+		} else if cmd.Flag("data").Changed {
 			handlePayload(cmd, &ciGroups)
 		} else {
 			handlePayloadStdin(cmd, &ciGroups)
 		}
 
+		// Resolve or warn about secret references
+		cloudInitHandleGroupSecrets(cmd, ciGroups)
+
+		// Stamp groups with who changed them
+		for i := range ciGroups {
+			ciGroups[i].Description = annotate(cmd, ciGroups[i].Description)
+		}
+
 		// Send data
 		_, errs, err := cloudInitClient.PutGroups(ciGroups, token)
 		if err != nil {
@@ -87,6 +137,7 @@ See ochami-cloud-init(1) for more details.`,
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		if errorsOccurred {
 			log.Logger.Warn().Msg("cloud-init group data setting completed with errors")
 			logHelpError(cmd)
@@ -96,10 +147,16 @@ See ochami-cloud-init(1) for more details.`,
 }
 
 func init() {
-	cloudInitGroupSetCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	cloudInitGroupSetCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
 	cloudInitGroupSetCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
+	cloudInitGroupSetCmd.Flags().Bool("resolve-secrets", false, "replace secret references in cloud-config files with their values before sending")
+
+	cloudInitGroupSetCmd.Flags().String("patch", "", "patch data or (if starting with @) file containing patch data to apply to current group data (can be - to read from stdin)")
+	cloudInitGroupSetCmd.Flags().Var(&patchType, "patch-type", "type of patch passed to --patch (json,merge)")
 
 	cloudInitGroupSetCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
+	cloudInitGroupSetCmd.RegisterFlagCompletionFunc("patch-type", completionPatchType)
+	cloudInitGroupSetCmd.MarkFlagsMutuallyExclusive("data", "patch")
 
 	cloudInitGroupCmd.AddCommand(cloudInitGroupSetCmd)
 }
@@ -70,6 +70,7 @@ See ochami-cloud-init(1) for more details.`,
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		if errorsOccurred {
 			log.Logger.Warn().Msg("cloud-init node group data retrieval completed with errors")
 			logHelpError(cmd)
@@ -141,6 +142,7 @@ See ochami-cloud-init(1) for more details.`,
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		if errorsOccurred {
 			log.Logger.Warn().Msg("cloud-init node meta-data retrieval completed with errors")
 			logHelpError(cmd)
@@ -220,6 +222,7 @@ See ochami-cloud-init(1) for more details.`,
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		if errorsOccurred {
 			log.Logger.Warn().Msg("cloud-init node user-data retrieval completed with errors")
 			logHelpError(cmd)
@@ -286,6 +289,7 @@ See ochami-cloud-init(1) for more details.`,
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		if errorsOccurred {
 			log.Logger.Warn().Msg("cloud-init node vendor-data retrieval completed with errors")
 			logHelpError(cmd)
@@ -0,0 +1,208 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/ci"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// cloudInitNodeImportStateFile is the name of the file in the imported
+// directory that the import state is saved to if --state-file is not passed.
+const cloudInitNodeImportStateFile = ".ochami-import.json"
+
+// cloudInitNodeImportResult is the outcome of importing one file of node data.
+type cloudInitNodeImportResult struct {
+	Node   string `json:"node" yaml:"node"`
+	File   string `json:"file" yaml:"file"`
+	Status string `json:"status" yaml:"status"`
+	Error  string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// cloudInitNodeImportCmd represents the "cloud-init node import" command
+var cloudInitNodeImportCmd = &cobra.Command{
+	Use:   "import --dir <dir> [--state-file <path>] [--force] [--dry-run]",
+	Args:  cobra.NoArgs,
+	Short: "Import cloud-init node data from a directory tree",
+	Long: `Import the cloud-init data of nodes from a directory tree with one
+subdirectory per node, named after its ID (e.g. nodes/<xname>/), that
+contains any of meta-data, user-data, and vendor-data. Hidden files and
+directories are ignored, as are files directly in the directory. Any
+other file in a node directory is an error.
+
+The meta-data of each node is YAML (or JSON) instance info, the same as
+that taken by 'cloud-init node set' without the id key, which defaults
+to the name of the node directory. It is set with a PUT per node.
+cloud-init does not store node-specific user-data or vendor-data (it is
+generated from the node's groups), so those files are skipped with a
+warning.
+
+Only files that changed since they were last imported into the cluster
+are imported, so the data of nodes can be kept in a git repository and
+imported after each change. The hashes of imported files are saved per
+cluster to --state-file (.ochami-import.json in the directory by
+default). Pass --force to import every file regardless, e.g. after the
+data was changed in cloud-init by other means. Pass --dry-run to only
+show what would be imported.
+
+The outcome for each file is printed. An access token is required.
+
+See ochami-cloud-init(1) for more details.`,
+	Example: `  # Import changed node meta-data from nodes/<xname>/meta-data
+  ochami cloud-init node import --dir nodes/
+
+  # Show what would be imported
+  ochami cloud-init node import --dir nodes/ --dry-run`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := cmd.Flags().GetString("dir")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --dir")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		stateFile, err := cmd.Flags().GetString("state-file")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --state-file")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if stateFile == "" {
+			stateFile = filepath.Join(dir, cloudInitNodeImportStateFile)
+		}
+		force, err := cmd.Flags().GetBool("force")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --force")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --dry-run")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		nodes, err := ci.ReadNodeDataDir(dir)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to read node data")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		state, err := ci.LoadImportState(stateFile)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to load import state")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Create client to use for requests
+		cloudInitClient := cloudInitGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		// Find the files to import. Only meta-data can be set per
+		// node.
+		var (
+			results []cloudInitNodeImportResult
+			infos   []cistore.OpenCHAMIInstanceInfo
+			files   []ci.LocalNodeFile
+			idx     []int // Index of the result of each of infos
+		)
+		uri := cloudInitClient.BaseURI.String()
+		for _, nd := range nodes {
+			for _, lnf := range nd.Files {
+				res := cloudInitNodeImportResult{Node: nd.ID, File: string(lnf.Type)}
+				switch {
+				case lnf.Type != ci.CloudInitMetaData:
+					log.Logger.Warn().Msgf("skipping %s: cloud-init does not store node-specific %s", lnf.Path, lnf.Type)
+					res.Status = "skipped"
+				case !force && !state.Changed(uri, nd.ID, lnf):
+					res.Status = "unchanged"
+				default:
+					info, err := ci.ParseNodeMetaData(nd.ID, lnf.Content)
+					if err != nil {
+						log.Logger.Error().Err(err).Msgf("failed to parse %s", lnf.Path)
+						res.Status = "failed"
+						res.Error = err.Error()
+						break
+					}
+					res.Status = "would-import"
+					infos = append(infos, info)
+					files = append(files, lnf)
+					idx = append(idx, len(results))
+				}
+				results = append(results, res)
+			}
+		}
+
+		// Set the meta-data of the nodes whose meta-data changed,
+		// saving the state of those that succeeded
+		if !dryRun && len(infos) > 0 {
+			_, errs, err := cloudInitClient.PutInstanceInfo(infos, token)
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to set instance info")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			for i, err := range errs {
+				res := &results[idx[i]]
+				if err != nil {
+					if errors.Is(err, client.UnsuccessfulHTTPError) {
+						log.Logger.Error().Err(err).Msgf("cloud-init instance info request for %s yielded unsuccessful HTTP response", res.Node)
+					} else {
+						log.Logger.Error().Err(err).Msgf("failed to set instance info of %s in cloud-init", res.Node)
+					}
+					res.Status = "failed"
+					res.Error = err.Error()
+					continue
+				}
+				res.Status = "imported"
+				state.Imported(uri, res.Node, files[i])
+			}
+			reportNotAttempted(errs)
+			if err := state.Save(stateFile); err != nil {
+				log.Logger.Warn().Err(err).Msgf("failed to save import state to %s, all files will be imported again next time", stateFile)
+			}
+		}
+
+		if outBytes, err := format.MarshalData(results, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+		for _, res := range results {
+			if res.Status == "failed" {
+				log.Logger.Warn().Msg("cloud-init node data import completed with errors")
+				logHelpError(cmd)
+				exitWithStatus(1)
+			}
+		}
+	},
+}
+
+func init() {
+	cloudInitNodeImportCmd.Flags().String("dir", "", "directory with a subdirectory of cloud-init data per node")
+	cloudInitNodeImportCmd.Flags().String("state-file", "", "file to save the hashes of imported files to (default: "+cloudInitNodeImportStateFile+" in --dir)")
+	cloudInitNodeImportCmd.Flags().Bool("force", false, "import all files, even those that did not change since the last import")
+	cloudInitNodeImportCmd.Flags().Bool("dry-run", false, "show what would be imported without importing anything")
+	cloudInitNodeImportCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	cloudInitNodeImportCmd.MarkFlagRequired("dir")
+	cloudInitNodeImportCmd.MarkFlagDirname("dir")
+	cloudInitNodeImportCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	cloudInitNodeCmd.AddCommand(cloudInitNodeImportCmd)
+}
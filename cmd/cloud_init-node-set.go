@@ -11,6 +11,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // cloudInitNodeSetCmd represents the "cloud-init node set" command
@@ -91,6 +92,7 @@ See ochami-cloud-init(1) for more details.`,
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		if errorsOccurred {
 			log.Logger.Warn().Msg("cloud-init node instance info setting completed with errors")
 			logHelpError(cmd)
@@ -100,7 +102,7 @@ See ochami-cloud-init(1) for more details.`,
 }
 
 func init() {
-	cloudInitNodeSetCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	cloudInitNodeSetCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
 	cloudInitNodeSetCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
 
 	cloudInitNodeSetCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
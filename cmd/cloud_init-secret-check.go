@@ -0,0 +1,137 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client/ci"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/report"
+)
+
+// cloudInitSecretCheckResult is the result of resolving one secret reference.
+// The value of the secret is never included.
+type cloudInitSecretCheckResult struct {
+	Ref   string `json:"ref" yaml:"ref"`
+	OK    bool   `json:"ok" yaml:"ok"`
+	Error string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// cloudInitSecretCheckCmd represents the "cloud-init secret check" command
+var cloudInitSecretCheckCmd = &cobra.Command{
+	Use:   "check [--report-format <format> [--report-file <path>]] (<ref>... | -d (<data> | @<path>))",
+	Short: "Check that secret references can be resolved",
+	Long: `Check that secret references can be resolved, without printing
+their values. References are passed as arguments (e.g.
+vault:kv/cluster/root-pass) or are found in a cloud-init template
+passed with -d. If the flag argument starts with @, it is a file
+containing the template ("-" for standard input).
+
+Pass --report-format to output the results as a JUnit XML or SARIF
+report for CI systems instead, or to --report-file in addition to
+the normal output.
+
+See ochami-cloud-init(1) for more details.`,
+	Example: `  # Check a secret in Vault and one in the environment
+  ochami cloud-init secret check vault:kv/cluster/root-pass env:BMC_PASSWORD
+
+  # Check all secret references in a template
+  ochami cloud-init secret check -d @compute.yaml`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && !cmd.Flag("data").Changed {
+			return fmt.Errorf("expected one or more secret references or -d")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var (
+			refs     = args
+			content  []byte
+			template string
+		)
+		if cmd.Flag("data").Changed {
+			data, err := cmd.Flags().GetString("data")
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to get value for --data")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			content = []byte(data)
+			if path, isFile := strings.CutPrefix(data, "@"); isFile {
+				template = path
+				if path == "-" {
+					content, err = io.ReadAll(os.Stdin)
+				} else {
+					content, err = os.ReadFile(path)
+				}
+				if err != nil {
+					log.Logger.Error().Err(err).Msg("failed to read template")
+					logHelpError(cmd)
+					os.Exit(1)
+				}
+			}
+			refs = append(refs, ci.FindSecretRefs(content)...)
+		}
+
+		sr := cloudInitSecretResolver(cmd)
+		results := []cloudInitSecretCheckResult{}
+		rep := report.Report{Name: "cloud-init secret check"}
+		var failed int
+		for _, ref := range refs {
+			res := cloudInitSecretCheckResult{Ref: ref, OK: true}
+			repRes := report.Result{Target: ref}
+			if _, err := sr.Resolve(ref); err != nil {
+				res.OK = false
+				res.Error = err.Error()
+				failed++
+
+				// Locate the reference in the template file, if any
+				f := report.Finding{Rule: "unresolved-secret", Level: report.LevelError, Message: res.Error}
+				if idx := bytes.Index(content, []byte(ref)); template != "" && template != "-" && idx >= 0 {
+					f.File = template
+					f.Line = bytes.Count(content[:idx], []byte("\n")) + 1
+				}
+				repRes.Findings = append(repRes.Findings, f)
+			}
+			results = append(results, res)
+			rep.Results = append(rep.Results, repRes)
+		}
+
+		// Print output, unless replaced by the report
+		if !writeReport(cmd, rep) {
+			if outBytes, err := format.MarshalData(results, formatOutput); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			} else {
+				fmt.Println(string(outBytes))
+			}
+		}
+
+		if failed > 0 {
+			log.Logger.Error().Msgf("%d of %d secret reference(s) could not be resolved", failed, len(results))
+			exitWithStatus(1)
+		}
+	},
+}
+
+func init() {
+	cloudInitSecretCheckCmd.Flags().StringP("data", "d", "", "cloud-init template or (if starting with @) file containing template to find secret references in (can be - to read from stdin)")
+	cloudInitSecretCheckCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	cloudInitSecretCheckCmd.Flags().Var(&reportFormat, "report-format", "write results as a report for CI systems (junit,sarif)")
+	cloudInitSecretCheckCmd.Flags().String("report-file", "-", "file to write report to, or - to write it to standard output instead of the normal output")
+
+	cloudInitSecretCheckCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+	cloudInitSecretCheckCmd.RegisterFlagCompletionFunc("report-format", completionReportFormat)
+
+	cloudInitSecretCmd.AddCommand(cloudInitSecretCheckCmd)
+}
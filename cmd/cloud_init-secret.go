@@ -0,0 +1,95 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"os"
+
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client/ci"
+)
+
+// cloudInitSecretResolver returns a SecretResolver for resolving secret
+// references in cloud-init templates. The env, file, and sops backends are
+// always available. The vault backend is available if Vault is configured (see
+// vaultGetClient).
+func cloudInitSecretResolver(cmd *cobra.Command) *ci.SecretResolver {
+	backends := map[string]ci.SecretBackend{
+		ci.SecretBackendEnv:  ci.EnvSecretBackend,
+		ci.SecretBackendFile: ci.FileSecretBackend,
+		ci.SecretBackendSOPS: ci.SOPSSecretBackend,
+	}
+
+	vaultClient, vaultToken := vaultGetClient(cmd)
+	if vaultClient == nil {
+		log.Logger.Debug().Msg("no Vault URI configured, vault secret backend disabled")
+		return ci.NewSecretResolver(backends)
+	}
+
+	backends[ci.SecretBackendVault] = ci.VaultSecretBackend(func(mount, path string) (map[string]any, error) {
+		return vaultClient.GetKVSecret(mount, path, vaultToken)
+	})
+
+	return ci.NewSecretResolver(backends)
+}
+
+// cloudInitHandleGroupSecrets deals with secret references in the cloud-config
+// files of groups before they are sent to cloud-init. If --resolve-secrets was
+// passed, the references are replaced by the secrets' values and the program
+// exits if any cannot be resolved. Otherwise, the references are sent as-is and
+// a warning is logged for each group containing any.
+func cloudInitHandleGroupSecrets(cmd *cobra.Command, groups []cistore.GroupData) {
+	if !cmd.Flag("resolve-secrets").Changed {
+		for _, g := range groups {
+			content, err := ci.GroupFileContent(g)
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to read cloud-config")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			if refs := ci.FindSecretRefs(content); len(refs) > 0 {
+				log.Logger.Warn().Msgf("group %s contains %d secret reference(s) that will be stored unresolved (pass --resolve-secrets to resolve them)", g.Name, len(refs))
+			}
+		}
+		return
+	}
+
+	sr := cloudInitSecretResolver(cmd)
+	var errorsOccurred bool
+	for i := range groups {
+		n, err := sr.ResolveGroupSecrets(&groups[i])
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to resolve secrets")
+			errorsOccurred = true
+			continue
+		}
+		log.Logger.Debug().Msgf("resolved %d secret reference(s) in group %s", n, groups[i].Name)
+	}
+	if errorsOccurred {
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+}
+
+// cloudInitSecretCmd represents the "cloud-init secret" command
+var cloudInitSecretCmd = &cobra.Command{
+	Use:   "secret",
+	Args:  cobra.NoArgs,
+	Short: "Manage secret references in cloud-init templates",
+	Long: `Manage secret references in cloud-init templates. This is a metacommand.
+
+See ochami-cloud-init(1) for more details.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			printUsageHandleError(cmd)
+			os.Exit(0)
+		}
+	},
+}
+
+func init() {
+	cloudInitCmd.AddCommand(cloudInitSecretCmd)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // cloudInitServiceStatusCmd represents the "cloud-init service status" command
@@ -32,19 +33,19 @@ See ochami-cloud-init(1) for more details.`,
 					if !cmd.Flag("quiet").Changed {
 						fmt.Println("cloud-init is running, but not normally")
 					}
-					os.Exit(1)
+					exitWithStatus(1)
 				} else {
 					log.Logger.Error().Err(err).Msg("failed to get cloud-init status")
 					if !cmd.Flag("quiet").Changed {
 						fmt.Println("cloud-init is not running")
 					}
-					os.Exit(1)
+					exitWithStatus(1)
 				}
 			} else {
 				if !cmd.Flag("quiet").Changed {
 					fmt.Println("cloud-init is running")
 				}
-				os.Exit(0)
+				exitWithStatus(0)
 			}
 		}
 
@@ -75,7 +76,7 @@ See ochami-cloud-init(1) for more details.`,
 
 		if errOccurred {
 			log.Logger.Warn().Msg("one or more requests to cloud-init failed")
-			os.Exit(1)
+			exitWithStatus(1)
 		}
 	},
 }
@@ -83,7 +84,7 @@ See ochami-cloud-init(1) for more details.`,
 func init() {
 	cloudInitServiceStatusCmd.Flags().Bool("api", false, "print OpenAPI spec")
 	cloudInitServiceStatusCmd.Flags().BoolP("quiet", "q", false, "don't print output; return 0 if running, 1 if not")
-	cloudInitServiceStatusCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	cloudInitServiceStatusCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	cloudInitServiceStatusCmd.MarkFlagsMutuallyExclusive("quiet", "api")
 
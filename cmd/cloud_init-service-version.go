@@ -11,6 +11,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // cloudInitServiceVersionCmd represents the "cloud-init service status" command
@@ -47,7 +48,7 @@ See ochami-cloud-init(1) for more details.`,
 }
 
 func init() {
-	cloudInitServiceVersionCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	cloudInitServiceVersionCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	cloudInitServiceVersionCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
@@ -0,0 +1,149 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/ci"
+)
+
+// cloudInitSnapshotCmd represents the "cloud-init snapshot" command
+var cloudInitSnapshotCmd = &cobra.Command{
+	Use:   "snapshot [--group <group>]... [-o <file>] [<node_id>...]",
+	Short: "Save the meta-data of nodes to a snapshot file",
+	Long: `Save the meta-data that cloud-init serves for nodes to a snapshot
+file, so that cloud-configs can later be rendered against it with
+'cloud-init group render --meta-data-snapshot', e.g. to test template
+changes against the data of the day before or to compare them with
+what is rendered from the current data.
+
+The nodes are those passed as arguments and the members of the SMD
+groups passed with --group. The snapshot is written to the file passed
+with -o, or standard output if it is omitted or -, as JSON. If the
+meta-data of a node cannot be read, it is left out of the snapshot and
+the command exits with an error once the snapshot is written.
+
+See ochami-cloud-init(1) for more details.`,
+	Example: `  # Save the meta-data of the compute nodes
+  ochami cloud-init snapshot --group compute -o snap.json
+
+  # Render the compute config against it the next day
+  ochami cloud-init group render compute x3000c0s0b0n0 --meta-data-snapshot snap.json`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && !cmd.Flag("group").Changed {
+			return errors.New("expected one or more node IDs or --group")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		outFile, err := cmd.Flags().GetString("output")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --output")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		groups, err := cmd.Flags().GetStringSlice("group")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --group")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Create client to use for requests
+		cloudInitClient := cloudInitGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		// Collect the nodes, without duplicates
+		var nodes []string
+		addNode := func(n string) {
+			if !slices.ContainsFunc(nodes, func(m string) bool { return strings.EqualFold(m, n) }) {
+				nodes = append(nodes, n)
+			}
+		}
+		for _, n := range args {
+			addNode(n)
+		}
+		for _, g := range groups {
+			for _, n := range cloudInitGroupMembers(cmd, g) {
+				addNode(n)
+			}
+		}
+		if len(nodes) == 0 {
+			log.Logger.Error().Msgf("groups %s have no members", strings.Join(groups, ","))
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Get the meta-data of each node
+		henvs, errs, err := cloudInitClient.GetNodeData(ci.CloudInitMetaData, token, nodes...)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get cloud-init node meta-data")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var clusterName string
+		if cl, ok := getCluster(cmd); ok {
+			clusterName = cl.Name
+		}
+		snap := ci.NewMetaDataSnapshot(clusterName, time.Now())
+		var errorsOccurred bool
+		for i, node := range nodes {
+			if errs[i] != nil {
+				if errors.Is(errs[i], client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(errs[i]).Msgf("cloud-init meta-data request for node %s yielded unsuccessful HTTP response", node)
+				} else {
+					log.Logger.Error().Err(errs[i]).Msgf("failed to get cloud-init meta-data for node %s", node)
+				}
+				errorsOccurred = true
+				continue
+			}
+			if err := snap.Add(node, henvs[i].Body); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to add meta-data to snapshot")
+				errorsOccurred = true
+			}
+		}
+
+		// Write the snapshot
+		var buf bytes.Buffer
+		if err := snap.Write(&buf); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to write meta-data snapshot")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if outFile == "" || outFile == "-" {
+			os.Stdout.Write(buf.Bytes())
+		} else if err := os.WriteFile(outFile, buf.Bytes(), 0o644); err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to write %s", outFile)
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			log.Logger.Info().Msgf("wrote meta-data of %d node(s) to %s", len(snap.Nodes), outFile)
+		}
+		if errorsOccurred {
+			log.Logger.Warn().Msgf("meta-data of %d of %d node(s) could not be saved", len(nodes)-len(snap.Nodes), len(nodes))
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	cloudInitSnapshotCmd.Flags().StringSlice("group", []string{}, "one or more SMD groups whose members to save the meta-data of")
+	cloudInitSnapshotCmd.Flags().StringP("output", "o", "-", "file to write the snapshot to (- for standard output)")
+
+	cloudInitSnapshotCmd.MarkFlagFilename("output")
+
+	cloudInitCmd.AddCommand(cloudInitSnapshotCmd)
+}
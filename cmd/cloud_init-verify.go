@@ -0,0 +1,253 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/ci"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/pool"
+)
+
+// cloudInitVerifyCmd represents the "cloud-init verify" command
+var cloudInitVerifyCmd = &cobra.Command{
+	Use:   "verify --ssh [--ssh-user <user>] [--ssh-port <port>] [--ssh-identity <path>] [--ssh-option <option>]... --node <node_id>,...",
+	Args:  cobra.NoArgs,
+	Short: "Verify that nodes received the cloud-init data that is served for them",
+	Long: `Verify that booted nodes received the cloud-init data that cloud-init
+serves for them. With --ssh, each node is logged into with ssh(1),
+without prompting, to read what cloud-init on the node received
+(/run/cloud-init/instance-data.json, /run/cloud-init/result.json, and
+the user-data and vendor-data under /var/lib/cloud/instance), which is
+compared with what cloud-init serves for the node.
+
+Each node is checked in stages: whether cloud-init got its data from a
+datasource (delivery), whether it has the served instance ID, meta-data,
+user-data, and vendor-data, and whether it processed them without
+errors (processing). The first stage that is not ok shows where
+rendering or delivery diverged. Differences are shown as unified diffs
+from what is served to what the node has. The user-data and vendor-data
+are only readable by root on the node.
+
+Nodes are connected to by their served local-hostname, or their ID if
+it has none. Options not passed are taken from the ssh section of the
+cluster config, then from the SSH configuration of the user. The exit
+status is 1 if any stage of any node is a mismatch or failed.
+
+An access token is required.
+
+See ochami-cloud-init(1) for more details.`,
+	Example: `  # Verify two nodes, logging in as root
+  ochami cloud-init verify --ssh --ssh-user root --node x3000c0s0b0n0,x3000c0s1b0n0`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if ssh, _ := cmd.Flags().GetBool("ssh"); !ssh {
+			return fmt.Errorf("--ssh is required, since it is the only way to verify nodes")
+		}
+		if c, _ := cmd.Flags().GetInt("concurrency"); c < 1 {
+			return fmt.Errorf("--concurrency must be at least 1")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var nodes []string
+		nodeArgs, err := cmd.Flags().GetStringSlice("node")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --node")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, n := range nodeArgs {
+			if !slices.ContainsFunc(nodes, func(m string) bool { return strings.EqualFold(m, n) }) {
+				nodes = append(nodes, n)
+			}
+		}
+		concurrency, err := cmd.Flags().GetInt("concurrency")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --concurrency")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --timeout")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		opts := cloudInitVerifySSHOptions(cmd)
+
+		// Create client to use for requests
+		cloudInitClient := cloudInitGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		// Get the data served for each node, leaving out nodes whose
+		// data cannot be read
+		served := make([]ci.VerifyServed, len(nodes))
+		ok := make([]bool, len(nodes))
+		for i := range ok {
+			ok[i] = true
+		}
+		var errorsOccurred bool
+		for _, dt := range []ci.CIDataType{ci.CloudInitMetaData, ci.CloudInitUserData, ci.CloudInitVendorData} {
+			henvs, errs, err := cloudInitClient.GetNodeData(dt, token, nodes...)
+			if err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to get cloud-init node %s", dt)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			for i, node := range nodes {
+				if errs[i] != nil {
+					if errors.Is(errs[i], client.UnsuccessfulHTTPError) {
+						log.Logger.Error().Err(errs[i]).Msgf("cloud-init %s request for node %s yielded unsuccessful HTTP response", dt, node)
+					} else {
+						log.Logger.Error().Err(errs[i]).Msgf("failed to get cloud-init %s for node %s", dt, node)
+					}
+					ok[i], errorsOccurred = false, true
+					continue
+				}
+				switch dt {
+				case ci.CloudInitMetaData:
+					served[i].MetaData = henvs[i].Body
+				case ci.CloudInitUserData:
+					served[i].UserData = henvs[i].Body
+				case ci.CloudInitVendorData:
+					served[i].VendorData = henvs[i].Body
+				}
+			}
+		}
+
+		// Compare what each node received with what is served for it.
+		// A node that timed out keeps its timeout finding even if its
+		// checks finish later.
+		var mu sync.Mutex
+		results := make([][]ci.VerifyFinding, len(nodes))
+		pool.Run(context.Background(), len(nodes), concurrency, timeout, func(ctx context.Context, i int) error {
+			if !ok[i] {
+				return nil
+			}
+			host := cloudInitVerifyHost(nodes[i], served[i].MetaData)
+			log.Logger.Debug().Msgf("verifying node %s through %s", nodes[i], host)
+			res := ci.VerifyNode(nodes[i], served[i], func(path string) ([]byte, error) {
+				return ci.SSHReadFile(ctx, opts, host, path)
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if results[i] == nil {
+				results[i] = res
+			}
+			return nil
+		}, func(i int, err error) {
+			if err != nil {
+				mu.Lock()
+				defer mu.Unlock()
+				results[i] = []ci.VerifyFinding{{Node: nodes[i], Stage: ci.VerifyStageDelivery, Status: ci.VerifyFailed, Detail: err.Error()}}
+			}
+		})
+
+		findings := []ci.VerifyFinding{}
+		var diverged int
+		for i, res := range results {
+			if !ok[i] {
+				continue
+			}
+			findings = append(findings, res...)
+			for _, f := range res {
+				if f.Status == ci.VerifyMismatch || f.Status == ci.VerifyFailed {
+					log.Logger.Warn().Msgf("node %s diverged at %s: %s", f.Node, f.Stage, f.Detail)
+					diverged++
+					break
+				}
+			}
+		}
+
+		if outBytes, err := format.MarshalData(findings, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+
+		if diverged > 0 {
+			log.Logger.Error().Msgf("%d of %d node(s) did not get what cloud-init serves for them", diverged, len(nodes))
+			exitWithStatus(1)
+		}
+		if errorsOccurred {
+			log.Logger.Warn().Msg("cloud-init verification completed with errors")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	},
+}
+
+// cloudInitVerifySSHOptions returns the SSH options from the flags of cmd,
+// falling back to the ssh section of the cluster config for those not passed.
+func cloudInitVerifySSHOptions(cmd *cobra.Command) ci.SSHOptions {
+	var opts ci.SSHOptions
+	if cl, found := getCluster(cmd); found {
+		opts = ci.SSHOptions{
+			User:         cl.Cluster.SSH.User,
+			Port:         cl.Cluster.SSH.Port,
+			IdentityFile: cl.Cluster.SSH.IdentityFile,
+			Options:      cl.Cluster.SSH.Options,
+		}
+	}
+	if cmd.Flag("ssh-user").Changed {
+		opts.User, _ = cmd.Flags().GetString("ssh-user")
+	}
+	if cmd.Flag("ssh-port").Changed {
+		opts.Port, _ = cmd.Flags().GetInt("ssh-port")
+	}
+	if cmd.Flag("ssh-identity").Changed {
+		opts.IdentityFile, _ = cmd.Flags().GetString("ssh-identity")
+	}
+	if cmd.Flag("ssh-option").Changed {
+		opts.Options, _ = cmd.Flags().GetStringArray("ssh-option")
+	}
+
+	return opts
+}
+
+// cloudInitVerifyHost returns the host to connect to node with, which is the
+// local-hostname in its served meta-data, or node if it has none.
+func cloudInitVerifyHost(node string, metaData []byte) string {
+	var md struct {
+		LocalHostname string `yaml:"local-hostname"`
+	}
+	if err := yaml.Unmarshal(metaData, &md); err == nil && md.LocalHostname != "" {
+		return md.LocalHostname
+	}
+
+	return node
+}
+
+func init() {
+	cloudInitVerifyCmd.Flags().StringSlice("node", nil, "one or more nodes to verify")
+	cloudInitVerifyCmd.Flags().Bool("ssh", false, "read what each node received by logging into it with ssh")
+	cloudInitVerifyCmd.Flags().String("ssh-user", "", "user to log into nodes as (root to also verify user-data and vendor-data)")
+	cloudInitVerifyCmd.Flags().Int("ssh-port", 0, "port to connect to nodes on")
+	cloudInitVerifyCmd.Flags().String("ssh-identity", "", "private key to log into nodes with")
+	cloudInitVerifyCmd.Flags().StringArray("ssh-option", nil, "option to pass to ssh with -o (can be repeated)")
+	cloudInitVerifyCmd.Flags().Int("concurrency", 8, "maximum number of nodes to verify at once")
+	cloudInitVerifyCmd.Flags().Duration("timeout", 30*time.Second, "maximum time to spend verifying each node (0 for no limit)")
+	cloudInitVerifyCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	cloudInitVerifyCmd.MarkFlagRequired("node")
+	cloudInitVerifyCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	cloudInitCmd.AddCommand(cloudInitVerifyCmd)
+}
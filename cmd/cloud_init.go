@@ -8,6 +8,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/OpenCHAMI/ochami/internal/config"
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client/ci"
 )
@@ -84,6 +85,30 @@ func cloudInitGetClient(cmd *cobra.Command) *ci.CloudInitClient {
 	// Check if a CA certificate was passed and load it into client if valid
 	useCACert(cloudInitClient.OchamiClient)
 
+	// Send GET requests through the agent of the cluster, if running
+	useAgent(cmd, cloudInitClient.OchamiClient)
+
+	// Apply any endpoint path overrides configured for the cluster
+	cloudInitClient.PathOverrides = getServicePaths(cmd, config.ServiceCloudInit)
+
+	// Refuse to send mutating requests in read-only mode
+	cloudInitClient.ReadOnly = readOnlyEnabled(cmd)
+
+	// Validate payloads against the OpenAPI spec of the service if
+	// --validate-against-server was passed
+	cloudInitClient.ValidatePayload = payloadValidator(cmd, cloudInitClient.OchamiClient)
+
+	// Stop sending requests once the --context-timeout deadline passes
+	cloudInitClient.Deadline = commandDeadline
+
+	// Make requests on behalf of the user passed with --as, if any, and
+	// record those that modify data in the audit log
+	cloudInitClient.OnBehalfOf, cloudInitClient.ImpersonationHeader = onBehalfOf(cmd)
+	cloudInitClient.AuditLog = getAuditLog(cmd)
+
+	// Spill large response bodies to a temporary file
+	cloudInitClient.MaxMemoryBuffer = maxMemoryBuffer(cmd)
+
 	return cloudInitClient
 }
 
@@ -0,0 +1,76 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/config"
+	"github.com/OpenCHAMI/ochami/internal/log"
+)
+
+// configDecryptCmd represents the "config decrypt" command
+var configDecryptCmd = &cobra.Command{
+	Use:   "decrypt [--user | --system | --config <path>]",
+	Args:  cobra.NoArgs,
+	Short: "Decrypt sensitive values in ochami CLI configuration",
+	Long: `Replace the encrypted sensitive values in ochami CLI configuration with
+their plaintext, undoing 'ochami config encrypt' (e.g. to switch to
+another encryption method). By default, this command modifies the user
+config file, which also occurs if --user is passed. If --system is
+passed, this command edits the system configuration file. If --config
+is passed instead, this command edits the file at the path specified.
+
+Age-encrypted values are decrypted with encryption.age-identity.
+Values in the OS keyring are copied back into the file, but are not
+removed from the keyring.
+
+See ochami-config(1) for details on the config commands.
+See ochami-config(5) for details on the configuration options.`,
+	Example: `  # Switch the user config file from age to the OS keyring
+  ochami config decrypt
+  ochami config set encryption.method keyring
+  ochami config encrypt`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// It doesn't make sense to decrypt a config file that doesn't
+		// exist, so err if the specified config file doesn't exist.
+		initConfigAndLogging(cmd, false)
+
+		return nil
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// To mark both persistent and regular flags mutually exclusive,
+		// this function must be run before the command is executed. It
+		// will not work in init(). This means that this needs to be
+		// present in all child commands.
+		cmd.MarkFlagsMutuallyExclusive("system", "user", "config")
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		fileToModify, cfg, enc := configReadForEncryption(cmd)
+
+		n, err := config.DecryptConfig(&cfg, enc)
+		if err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to decrypt config values in %s", fileToModify)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if n == 0 {
+			log.Logger.Info().Msgf("no encrypted values in %s", fileToModify)
+			return
+		}
+		if err := config.WriteConfig(fileToModify, cfg); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to write config file")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		log.Logger.Warn().Msgf("decrypted %d value(s) in %s, which now contains plaintext credentials", n, fileToModify)
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configDecryptCmd)
+}
@@ -0,0 +1,114 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/config"
+	"github.com/OpenCHAMI/ochami/internal/log"
+)
+
+// configEncryptCmd represents the "config encrypt" command
+var configEncryptCmd = &cobra.Command{
+	Use:   "encrypt [--user | --system | --config <path>]",
+	Args:  cobra.NoArgs,
+	Short: "Encrypt sensitive values in ochami CLI configuration",
+	Long: `Encrypt the sensitive values (access tokens, Vault and Grafana tokens,
+and BMC passwords) in ochami CLI configuration that are not encrypted
+yet, so that the config file can be backed up or shared without leaking
+credentials. By default, this command modifies the user config file,
+which also occurs if --user is passed. If --system is passed, this
+command edits the system configuration file. If --config is passed
+instead, this command edits the file at the path specified.
+
+Values are encrypted with the method set as encryption.method in the
+file or, if it is not set there, in the merged configuration: either
+age, which encrypts values to encryption.age-recipients with the age
+program, or keyring, which moves values into the OS keyring (with
+secret-tool on Linux or security on macOS) and leaves a reference to
+them in the file. Encrypted values are decrypted transparently when
+used.
+
+See ochami-config(1) for details on the config commands.
+See ochami-config(5) for details on the configuration options.`,
+	Example: `  # Encrypt the tokens in the user config file with age
+  ochami config set encryption.method age
+  ochami config set encryption.age-recipients '["age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"]'
+  ochami config set encryption.age-identity ~/.config/ochami/age.key
+  ochami config encrypt
+
+  # Move the tokens in a config file into the OS keyring
+  ochami --config ./test.yaml config set encryption.method keyring
+  ochami --config ./test.yaml config encrypt`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// It doesn't make sense to encrypt a config file that doesn't
+		// exist, so err if the specified config file doesn't exist.
+		initConfigAndLogging(cmd, false)
+
+		return nil
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		// To mark both persistent and regular flags mutually exclusive,
+		// this function must be run before the command is executed. It
+		// will not work in init(). This means that this needs to be
+		// present in all child commands.
+		cmd.MarkFlagsMutuallyExclusive("system", "user", "config")
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		fileToModify, cfg, enc := configReadForEncryption(cmd)
+
+		n, err := config.EncryptConfig(&cfg, enc)
+		if err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to encrypt config values in %s", fileToModify)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if n == 0 {
+			log.Logger.Info().Msgf("no unencrypted sensitive values in %s", fileToModify)
+			return
+		}
+		if err := config.WriteConfig(fileToModify, cfg); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to write config file")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		log.Logger.Info().Msgf("encrypted %d value(s) in %s", n, fileToModify)
+	},
+}
+
+// configReadForEncryption reads the config file that "config encrypt" or
+// "config decrypt" operates on, returning its path, its contents, and the
+// encryption settings to use, which are those of the file if it sets
+// encryption.method and those of the merged configuration otherwise.
+func configReadForEncryption(cmd *cobra.Command) (string, config.Config, config.ConfigEncryption) {
+	var fileToModify string
+	if rootCmd.Flags().Changed("config") {
+		fileToModify = configFile
+	} else if configCmd.PersistentFlags().Lookup("system").Changed {
+		fileToModify = config.SystemConfigFile
+	} else {
+		fileToModify = config.UserConfigFile
+	}
+
+	cfg, err := config.ReadConfig(fileToModify)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to read config file")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	enc := cfg.Encryption
+	if enc.Method == "" {
+		enc = config.GlobalConfig.Encryption
+	}
+
+	return fileToModify, cfg, enc
+}
+
+func init() {
+	configCmd.AddCommand(configEncryptCmd)
+}
@@ -0,0 +1,132 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// convertSchemaDiscovery is the --schema of discovery payloads.
+const convertSchemaDiscovery = "discovery"
+
+// convertSchemas are the schemas that --schema can order keys by.
+var convertSchemas = map[string]func() ([]byte, error){
+	convertSchemaDiscovery: discover.NodeListSchema,
+}
+
+// convertCmd represents the convert command
+var convertCmd = &cobra.Command{
+	Use:   "convert [-d (<data> | @<path>)] [-f <format>] [-F <format>] [--schema <schema>]",
+	Args:  cobra.NoArgs,
+	Short: "Convert a payload between formats",
+	Long: `Convert a payload from the format of --format-input to the format of
+--format-output and print the result. The payload is read from -d, or
+from standard input. Any format that commands read payloads in or print
+output in can be converted from or to.
+
+Unlike reading a payload and printing it again, the keys of objects keep
+their order and numbers are kept as written. Pass --schema to instead
+put the keys of the objects that a schema describes in the order of the
+schema, followed by keys it does not describe. The 'discovery' schema is
+that of static discovery payloads (see 'ochami discover schema'), which
+can then also be read from CSV.
+
+See ochami-convert(1) for more details.`,
+	Example: `  # Convert a YAML payload to indented JSON
+  ochami convert -f yaml -F json-pretty -d @nodes.yaml
+
+  # Convert a CSV discovery payload to YAML
+  ochami convert -f csv -F yaml --schema discovery -d @nodes.csv`,
+	Run: func(cmd *cobra.Command, args []string) {
+		schemaName, err := cmd.Flags().GetString("schema")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --schema")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var schema []byte
+		if schemaName != "" {
+			getSchema, ok := convertSchemas[schemaName]
+			if !ok {
+				log.Logger.Error().Msgf("unknown schema %q, must be one of: %s", schemaName, strings.Join(convertSchemaNames(), ","))
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			if schema, err = getSchema(); err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to generate %s schema", schemaName)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+
+		if discoverFormatInput == discover.PayloadFormatCSV && schemaName != convertSchemaDiscovery {
+			log.Logger.Error().Msgf("CSV can only be converted with --schema %s", convertSchemaDiscovery)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// CSV is only a discovery payload format, so read it as one
+		raw := handlePayloadRaw(cmd)
+		inFormat := format.DataFormat(discoverFormatInput)
+		if discoverFormatInput == discover.PayloadFormatCSV {
+			nodes, err := discover.ParseNodeListCSV(bytes.NewReader(raw))
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("invalid discovery payload")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			if raw, err = json.Marshal(nodes); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to marshal discovery payload")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			inFormat = format.DataFormatJson
+		}
+
+		outBytes, err := format.Convert(raw, inFormat, formatOutput, schema)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to convert payload")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		fmt.Println(strings.TrimSuffix(string(outBytes), "\n"))
+	},
+}
+
+// convertSchemaNames returns the names of the schemas that --schema accepts,
+// sorted.
+func convertSchemaNames() []string {
+	var names []string
+	for name := range convertSchemas {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+func init() {
+	convertCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
+	convertCmd.Flags().VarP(&discoverFormatInput, "format-input", "f", "format of input payload data ("+discover.PayloadFormatList()+")")
+	convertCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+	convertCmd.Flags().String("schema", "", "order keys by this schema ("+strings.Join(convertSchemaNames(), ",")+")")
+
+	convertCmd.RegisterFlagCompletionFunc("format-input", completionDiscoverFormat)
+	convertCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+	convertCmd.RegisterFlagCompletionFunc("schema", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return convertSchemaNames(), cobra.ShellCompDirectiveNoFileComp
+	})
+
+	rootCmd.AddCommand(convertCmd)
+}
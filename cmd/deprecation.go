@@ -0,0 +1,206 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+)
+
+// commandAlias keeps an old command path working after the command it named
+// was renamed or moved, so that site automation does not break on upgrade.
+type commandAlias struct {
+	// OldPath is the old path of the command below the root command, e.g.
+	// "discover static".
+	OldPath string
+	// Target is the command to run instead.
+	Target *cobra.Command
+	// RemovedIn is the release in which the alias will be removed.
+	RemovedIn string
+}
+
+// flagAlias keeps an old flag name of a command working after the flag was
+// renamed.
+type flagAlias struct {
+	Cmd       *cobra.Command
+	Old       string
+	New       string
+	RemovedIn string
+}
+
+var (
+	// commandAliases and flagAliases list the deprecated command paths and
+	// flag names that are still accepted. Entries are added with
+	// registerCommandAlias and registerFlagAlias in the init function of
+	// the file of the target command and applied by applyAliases. Remove
+	// entries once their RemovedIn release is made.
+	commandAliases []commandAlias
+	flagAliases    []flagAlias
+
+	// deprecatedCalls maps each command that was reached through an alias
+	// name to the alias names it can be called as, with the deprecation
+	// warning for each.
+	deprecatedCalls = make(map[*cobra.Command]map[string]string)
+
+	// aliasTargets maps each hidden command created for an alias in
+	// another parent to the command it runs.
+	aliasTargets = make(map[*cobra.Command]*cobra.Command)
+)
+
+// registerCommandAlias keeps oldPath (a command path below the root command)
+// working as a deprecated alias of target until release removedIn. If the last
+// element of oldPath is under the same parent as target, it becomes an alias
+// of target, which works for commands with subcommands. Otherwise, a hidden
+// command that runs target is created at oldPath, which only supports commands
+// without subcommands.
+func registerCommandAlias(oldPath string, target *cobra.Command, removedIn string) {
+	commandAliases = append(commandAliases, commandAlias{OldPath: oldPath, Target: target, RemovedIn: removedIn})
+}
+
+// registerFlagAlias keeps --old working as a deprecated alias of the flag --new
+// of cmd until release removedIn.
+func registerFlagAlias(cmd *cobra.Command, old, new, removedIn string) {
+	flagAliases = append(flagAliases, flagAlias{Cmd: cmd, Old: old, New: new, RemovedIn: removedIn})
+}
+
+// deprecationMessage returns the warning logged when old is used instead of
+// new.
+func deprecationMessage(old, new, removedIn string) string {
+	return fmt.Sprintf("'%s' is deprecated and will be removed in %s, use '%s' instead", old, removedIn, new)
+}
+
+// applyAliases adds the registered command and flag aliases to the command
+// tree under root. It must be called after all commands have been added to the
+// tree, i.e. after all init functions have run.
+func applyAliases(root *cobra.Command) error {
+	// Flag aliases are added first so that forwarding commands get
+	// them too
+	for _, fa := range flagAliases {
+		fs := fa.Cmd.Flags()
+		f := fs.Lookup(fa.New)
+		if f == nil {
+			fs = fa.Cmd.PersistentFlags()
+			f = fs.Lookup(fa.New)
+		}
+		if f == nil {
+			return fmt.Errorf("flag alias --%s: %s has no flag --%s", fa.Old, fa.Cmd.CommandPath(), fa.New)
+		}
+		fs.AddFlag(&pflag.Flag{
+			Name:        fa.Old,
+			Usage:       fmt.Sprintf("deprecated alias of --%s", fa.New),
+			Value:       f.Value,
+			DefValue:    f.DefValue,
+			NoOptDefVal: f.NoOptDefVal,
+			Hidden:      true,
+		})
+	}
+
+	for _, ca := range commandAliases {
+		fields := strings.Fields(ca.OldPath)
+		if len(fields) == 0 {
+			return fmt.Errorf("empty alias path for %s", ca.Target.CommandPath())
+		}
+		name := fields[len(fields)-1]
+		parent := root
+		if len(fields) > 1 {
+			p, rest, err := root.Find(fields[:len(fields)-1])
+			if err != nil || len(rest) > 0 {
+				return fmt.Errorf("parent of alias %q not found", ca.OldPath)
+			}
+			parent = p
+		}
+		msg := deprecationMessage(root.Name()+" "+ca.OldPath, ca.Target.CommandPath(), ca.RemovedIn)
+
+		if parent == ca.Target.Parent() {
+			ca.Target.Aliases = append(ca.Target.Aliases, name)
+			if deprecatedCalls[ca.Target] == nil {
+				deprecatedCalls[ca.Target] = make(map[string]string)
+			}
+			deprecatedCalls[ca.Target][name] = msg
+			continue
+		}
+		if ca.Target.HasSubCommands() {
+			return fmt.Errorf("alias %q: %s has subcommands and can only be aliased under the same parent", ca.OldPath, ca.Target.CommandPath())
+		}
+		alias := &cobra.Command{
+			Use:               name + strings.TrimPrefix(ca.Target.Use, ca.Target.Name()),
+			Hidden:            true,
+			Args:              ca.Target.Args,
+			Short:             ca.Target.Short,
+			Long:              ca.Target.Long,
+			Example:           ca.Target.Example,
+			ValidArgsFunction: ca.Target.ValidArgsFunction,
+			Run: func(cmd *cobra.Command, args []string) {
+				ca.Target.Run(ca.Target, args)
+			},
+		}
+		// The alias itself is passed to the target's own persistent
+		// pre-run hook so that it can be detected as deprecated by
+		// handleDeprecations
+		if ca.Target.PersistentPreRunE != nil {
+			alias.PersistentPreRunE = ca.Target.PersistentPreRunE
+		}
+		if ca.Target.PreRun != nil {
+			alias.PreRun = func(cmd *cobra.Command, args []string) {
+				ca.Target.PreRun(ca.Target, args)
+			}
+		}
+		if ca.Target.PreRunE != nil {
+			alias.PreRunE = func(cmd *cobra.Command, args []string) error {
+				return ca.Target.PreRunE(ca.Target, args)
+			}
+		}
+		// Share the target's flags so that parsing them for the alias
+		// sets them for the target
+		alias.Flags().AddFlagSet(ca.Target.LocalNonPersistentFlags())
+		alias.Flags().AddFlagSet(ca.Target.InheritedFlags())
+		alias.PersistentFlags().AddFlagSet(ca.Target.PersistentFlags())
+		parent.AddCommand(alias)
+		deprecatedCalls[alias] = map[string]string{name: msg}
+		aliasTargets[alias] = ca.Target
+	}
+
+	return nil
+}
+
+// aliasTarget returns the command that cmd runs, which is cmd itself unless it
+// was created for an alias in another parent by applyAliases, so that a
+// command is treated the same whichever path it is called by.
+func aliasTarget(cmd *cobra.Command) *cobra.Command {
+	if target, ok := aliasTargets[cmd]; ok {
+		return target
+	}
+
+	return cmd
+}
+
+// handleDeprecations logs a warning for each deprecated command alias or flag
+// alias used to invoke cmd and marks the new flag of each flag alias used as
+// changed so that commands only need to check the new flag. It is called once
+// logging has been initialized.
+func handleDeprecations(cmd *cobra.Command) {
+	for c := cmd; c != nil; c = c.Parent() {
+		if msg, ok := deprecatedCalls[c][c.CalledAs()]; ok {
+			log.Logger.Warn().Msg(msg)
+		}
+	}
+	for _, fa := range flagAliases {
+		old := cmd.Flags().Lookup(fa.Old)
+		if old == nil || !old.Changed {
+			continue
+		}
+		// The alias shares the command's flag, so check that this is
+		// the command (or a descendant of the command for persistent
+		// flags) the alias is for
+		if f := cmd.Flags().Lookup(fa.New); f == nil || f.Value != old.Value {
+			continue
+		}
+		cmd.Flags().Lookup(fa.New).Changed = true
+		log.Logger.Warn().Msg(deprecationMessage("--"+fa.Old, "--"+fa.New, fa.RemovedIn))
+	}
+}
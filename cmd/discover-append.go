@@ -0,0 +1,219 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+)
+
+// discoverAppendCmd represents the "discover append" command
+var discoverAppendCmd = &cobra.Command{
+	Use:   "append [--auto-group <group>=<spec>]... [--default-group <group>,...] [-d (<data> | @<path>)] [-f <format>]",
+	Args:  cobra.NoArgs,
+	Short: "Add new nodes to SMD from a partial discovery payload",
+	Long: `Add new nodes to SMD from a partial discovery payload, e.g. one
+containing only a rack being added to an existing system. The payload
+has the same format as for 'ochami discover static'.
+
+Before anything is sent, the payload is checked against the
+components and redfish endpoints in SMD. Xnames and NIDs must be
+unique across both, so appending fails without changing anything if
+a node's xname is in SMD with a different NID or is not a node, if
+a new node's NID is used by another component in SMD, if a new
+node's BMC is already in SMD, or if an xname or NID is used more
+than once in the payload. Nodes already in SMD with the same NID are
+skipped, so the same payload can be appended again safely.
+
+Only the new nodes are added, along with their redfish endpoints
+and ethernet interfaces. They are added to the groups they are in,
+creating groups that do not exist. Existing nodes and groups are
+otherwise left alone; use 'ochami discover static --overwrite' to
+update them.
+
+See ochami-discover(1) for more details.`,
+	Example: `  # Add the nodes of a new rack
+  ochami discover append -d @rack2.yaml -f yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Read data from file or stdin, migrating it from older versions
+		// of the format if needed
+		nodes := discoverReadPayload(cmd)
+		discoverApplyGroups(cmd, &nodes)
+		log.Logger.Debug().Msgf("read %d nodes", len(nodes.Nodes))
+
+		smdBaseURI, err := getBaseURISMD(cmd)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get base URI for SMD")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		// Compare payload with what is in SMD
+		henv, err := smdClient.GetComponentsAll()
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("SMD component request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to request components from SMD")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var comps smd.ComponentSlice
+		if err := json.Unmarshal(henv.Body, &comps); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal components")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		henv, err = smdClient.GetRedfishEndpoints("", token)
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("SMD redfish endpoint request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to request redfish endpoints from SMD")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var rfes smd.RedfishEndpointSlice
+		if err := json.Unmarshal(henv.Body, &rfes); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal redfish endpoints")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		plan := discover.PlanAppend(nodes, comps.Components, rfes.RedfishEndpoints)
+		for _, n := range plan.Existing {
+			log.Logger.Info().Msgf("node %s is already in SMD, skipping", n.Xname)
+		}
+		if len(plan.Conflicts) > 0 {
+			for _, c := range plan.Conflicts {
+				log.Logger.Error().Msg(c.String())
+			}
+			log.Logger.Error().Msgf("payload conflicts with itself or SMD in %d place(s), not appending any nodes", len(plan.Conflicts))
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if len(plan.New.Nodes) == 0 {
+			log.Logger.Info().Msg("all nodes in payload are already in SMD, nothing to append")
+			exitWithStatus(0)
+		}
+		log.Logger.Info().Msgf("appending %d new node(s), skipping %d already in SMD", len(plan.New.Nodes), len(plan.Existing))
+
+		// Put together payload for different endpoints
+		newComps, newRFEs, newIfaces, warnings, err := discover.DiscoveryInfoV3(smdBaseURI, plan.New, discoverErrorPolicy)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to construct structures to send to SMD")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		discoverReportWarnings(warnings)
+		discoverApplyBMCCredentials(cmd, &newRFEs)
+
+		// Send components before redfish endpoints so that the NIDs in
+		// the payload are used instead of ones generated by SMD
+		errorsOccurred := false
+		handleErrs := func(what string, errs []error, err error) {
+			if err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to add %s to SMD", what)
+				errorsOccurred = true
+				return
+			}
+			for _, err := range errs {
+				if err != nil {
+					if errors.Is(err, client.UnsuccessfulHTTPError) {
+						log.Logger.Error().Err(err).Msgf("SMD %s request yielded unsuccessful HTTP response", what)
+					} else {
+						log.Logger.Error().Err(err).Msgf("failed to add %s to SMD", what)
+					}
+					errorsOccurred = true
+				}
+			}
+			reportNotAttempted(errs)
+		}
+		if _, err := smdClient.PostComponents(newComps, token); err != nil {
+			handleErrs("components", []error{err}, nil)
+		}
+		discoverAnnotate(cmd, &newRFEs, nil)
+		_, errs, err := smdClient.PostRedfishEndpointsV2(newRFEs, token)
+		handleErrs("redfish endpoints", errs, err)
+		if discoveryVersion == discover.DiscoveryMethodV1 {
+			_, errs, err := smdClient.PostEthernetInterfaces(newIfaces, token)
+			handleErrs("ethernet interfaces", errs, err)
+		}
+
+		// Create new groups with their members and add members to
+		// existing groups
+		henv, err = smdClient.GetGroups("", token)
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("SMD group request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to request groups from SMD")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var smdGroups []smd.Group
+		if err := json.Unmarshal(henv.Body, &smdGroups); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal groups")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		existingGroups := make(map[string]bool, len(smdGroups))
+		for _, g := range smdGroups {
+			existingGroups[g.Label] = true
+		}
+		var groupsToAdd []smd.Group
+		for _, g := range discoverNodeGroups(plan.New) {
+			if !existingGroups[g.Label] {
+				groupsToAdd = append(groupsToAdd, g)
+				continue
+			}
+			log.Logger.Info().Msgf("adding %d node(s) to existing group %s", len(g.Members.IDs), g.Label)
+			_, errs, err := smdClient.PostGroupMembers(token, g.Label, g.Members.IDs...)
+			handleErrs("group members", errs, err)
+		}
+		if len(groupsToAdd) > 0 {
+			discoverAnnotate(cmd, nil, groupsToAdd)
+			_, errs, err := smdClient.PostGroups(groupsToAdd, token)
+			handleErrs("groups", errs, err)
+		}
+
+		if errorsOccurred {
+			log.Logger.Warn().Msg("appending nodes completed with errors")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		log.Logger.Info().Msgf("appended %d node(s) to SMD", len(plan.New.Nodes))
+	},
+}
+
+func init() {
+	discoverAppendCmd.Flags().StringArray("auto-group", []string{}, "add nodes to a group by xname pattern or NID range (<group>=<xname_regex> or <group>=nid:<ranges>, can be repeated)")
+	discoverAppendCmd.Flags().StringSlice("default-group", []string{}, "one or more groups to add all nodes to")
+	discoverAppendCmd.Flags().Var(&discoveryVersion, "discovery-version", "set version for discovery method to use")
+	discoverAppendCmd.Flags().Var(&discoverErrorPolicy, "on-error", "what to do with malformed nodes (fail-fast,skip-node,skip-interface)")
+	discoverAppendCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
+	discoverAppendCmd.Flags().VarP(&discoverFormatInput, "format-input", "f", "format of input payload data ("+discover.PayloadFormatList()+")")
+	discoverAppendCmd.Flags().Bool("validate-schema", false, "check payload against the JSON Schema of the payload format before reading it (see discover schema)")
+
+	discoverAppendCmd.RegisterFlagCompletionFunc("format-input", completionDiscoverFormat)
+	discoverAppendCmd.RegisterFlagCompletionFunc("discovery-version", completionDiscoveryVersion)
+	discoverAppendCmd.RegisterFlagCompletionFunc("on-error", completionDiscoverErrorPolicy)
+
+	discoverCmd.AddCommand(discoverAppendCmd)
+}
@@ -0,0 +1,131 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+)
+
+// discoverApplyCmd represents the "discover apply" command
+var discoverApplyCmd = &cobra.Command{
+	Use:   "apply --plan <path>",
+	Args:  cobra.NoArgs,
+	Short: "Apply a discovery plan to SMD",
+	Long: `Apply a plan written by 'ochami discover plan --out', sending the
+records it creates and updates to SMD in the order of its steps.
+Records that the plan leaves as is are not sent. The hardware
+inventory of the plan, which is not compared with SMD, is sent as is
+after the ethernet interfaces.
+
+Before sending anything, the plan is compared with what is in SMD
+again. If any record would now be planned differently, e.g. because
+it was added to SMD in the meantime, SMD changed since the plan was
+made and nothing is sent; make a new plan instead.
+
+Plans hold no BMC credentials. The redfish endpoints are given those
+set with discovery.bmc-username and discovery.bmc-password in the
+config file when the plan is applied.
+
+See ochami-discover(1) for more details.`,
+	Example: `  # Apply a plan after reviewing it
+  ochami discover apply --plan plan.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Read plan
+		path, _ := cmd.Flags().GetString("plan")
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to read plan")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var plan discover.Plan
+		if err := json.Unmarshal(raw, &plan); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal plan")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if plan.Version != discover.PlanVersion {
+			log.Logger.Error().Msgf("plan has version %d, but only version %d is supported", plan.Version, discover.PlanVersion)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		// Refuse to apply a plan that SMD no longer matches
+		current := discover.NewPlan(plan.Payloads, discoverGetSMDState(cmd, smdClient, true), plan.Overwrite)
+		if changed := plan.Changed(current); len(changed) > 0 {
+			for _, c := range changed {
+				log.Logger.Error().Msgf("record is now planned differently: %s", c)
+			}
+			log.Logger.Error().Msg("SMD changed since the plan was made, make a new plan with 'ochami discover plan'")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if plan.Count(discover.PlanCreate)+plan.Count(discover.PlanUpdate) == 0 && len(plan.Payloads.HardwareInventory) == 0 {
+			log.Logger.Info().Msg("plan has nothing to do, SMD already matches the payload")
+			return
+		}
+		discoverApplyBMCCredentials(cmd, &plan.Payloads.RedfishEndpoints)
+		discoverAnnotate(cmd, &plan.Payloads.RedfishEndpoints, plan.Payloads.Groups)
+
+		// Send creates then updates of each step, in order
+		create, update := plan.PayloadsWith(discover.PlanCreate), plan.PayloadsWith(discover.PlanUpdate)
+		var errs discoverSendErrors
+		if len(create.Components.Components) > 0 {
+			errs.comps = discoverSendComponents(smdClient, create.Components, false)
+		}
+		if len(update.Components.Components) > 0 {
+			errs.comps = discoverSendComponents(smdClient, update.Components, true) || errs.comps
+		}
+		if len(create.RedfishEndpoints.RedfishEndpoints) > 0 {
+			errs.rfes = discoverSendRedfishEndpoints(smdClient, create.RedfishEndpoints, false)
+		}
+		if len(update.RedfishEndpoints.RedfishEndpoints) > 0 {
+			errs.rfes = discoverSendRedfishEndpoints(smdClient, update.RedfishEndpoints, true) || errs.rfes
+		}
+		if len(create.EthernetInterfaces) > 0 {
+			errs.ifaces = discoverSendEthernetInterfaces(smdClient, create.EthernetInterfaces, false)
+		}
+		if len(update.EthernetInterfaces) > 0 {
+			errs.ifaces = discoverSendEthernetInterfaces(smdClient, update.EthernetInterfaces, true) || errs.ifaces
+		}
+		// The hardware inventory is not planned, so send all of it
+		// as static discovery does
+		if len(plan.Payloads.HardwareInventory) > 0 {
+			errs.hw = discoverSendHardware(smdClient, plan.Payloads.HardwareInventory)
+		}
+		if len(create.Groups) > 0 {
+			errs.groups = discoverSendGroups(smdClient, create.Groups, false)
+		}
+		if len(update.Groups) > 0 {
+			errs.groups = discoverSendGroups(smdClient, update.Groups, true) || errs.groups
+		}
+		log.Logger.Info().Msgf("applied plan: %d created, %d updated", plan.Count(discover.PlanCreate), plan.Count(discover.PlanUpdate))
+		if n := plan.Count(discover.PlanConflict); n > 0 {
+			log.Logger.Warn().Msgf("%d conflicting record(s) were left as is", n)
+		}
+
+		// Notify user if any request errors occurred
+		exitWithStatus(discoverSendStatus(cmd, errs))
+	},
+}
+
+func init() {
+	discoverApplyCmd.Flags().String("plan", "", "file containing plan written by 'ochami discover plan --out'")
+
+	discoverApplyCmd.MarkFlagRequired("plan")
+	discoverApplyCmd.MarkFlagFilename("plan", "json")
+
+	discoverCmd.AddCommand(discoverApplyCmd)
+}
@@ -0,0 +1,81 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// discoverDiffCmd represents the "discover diff" command
+var discoverDiffCmd = &cobra.Command{
+	Use:   "diff [-d (<data> | @<path>)] [-f <format>] [-F <format>]",
+	Args:  cobra.NoArgs,
+	Short: "Compare a discovery payload with what is in SMD",
+	Long: `Compare a discovery payload with the components, redfish endpoints,
+and ethernet interfaces in SMD, and print what would be added,
+changed, or removed to make SMD match the payload, without changing
+anything. The payload has the same format as for 'ochami discover
+static'.
+
+Components are compared by xname (their type and NID), redfish
+endpoints by xname (their FQDN, MAC address, and IP address), and
+ethernet interfaces of nodes and BMCs by MAC address (their component
+and IP addresses). Removals are records of the kinds discovery creates
+(nodes, node BMCs, and their interfaces) that are in SMD but not in
+the payload. Note that 'ochami discover static' does not remove them.
+Groups are not compared.
+
+See ochami-discover(1) for more details.`,
+	Example: `  # Show what discovering the nodes in a payload would change in SMD
+  ochami discover diff -d @nodes.yaml -f yaml -F yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Read data from file or stdin, migrating it from older versions
+		// of the format if needed
+		nodes := discoverReadPayload(cmd)
+		log.Logger.Debug().Msgf("read %d nodes", len(nodes.Nodes))
+
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		// Get what is in SMD
+		state := discoverGetSMDState(cmd, smdClient, false)
+
+		res, err := discover.Diff(nodes, state.Components, state.RedfishEndpoints, state.EthernetInterfaces)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to compare payload with SMD")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if outBytes, err := format.MarshalData(res, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+		log.Logger.Info().Msgf("%d to add, %d to change, %d to remove",
+			res.Count(discover.DiffAdd), res.Count(discover.DiffChange), res.Count(discover.DiffRemove))
+	},
+}
+
+func init() {
+	discoverDiffCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
+	discoverDiffCmd.Flags().VarP(&discoverFormatInput, "format-input", "f", "format of input payload data ("+discover.PayloadFormatList()+")")
+	discoverDiffCmd.Flags().Bool("validate-schema", false, "check payload against the JSON Schema of the payload format before reading it (see discover schema)")
+	discoverDiffCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	discoverDiffCmd.RegisterFlagCompletionFunc("format-input", completionDiscoverFormat)
+	discoverDiffCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	discoverCmd.AddCommand(discoverDiffCmd)
+}
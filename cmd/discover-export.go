@@ -0,0 +1,64 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// discoverExportCmd represents the "discover export" command
+var discoverExportCmd = &cobra.Command{
+	Use:   "export [-F <format>]",
+	Args:  cobra.NoArgs,
+	Short: "Generate a static discovery payload from what is in SMD",
+	Long: `Generate a static discovery payload from the components, redfish
+endpoints, ethernet interfaces, and groups in SMD and print it to
+standard output. This snapshots the inventory of an existing cluster
+into a file that can be edited and passed to 'ochami discover static'.
+
+Each node and other device (see 'ochami discover static') in SMD gets
+an entry with the addresses of its BMC, its interfaces, and its
+groups. Names and bonds are only recovered for entries that were
+discovered with ochami. Power actions, bond modes, and hypervisors
+are not kept in SMD, which is warned about where it matters.
+
+See ochami-discover(1) for more details.`,
+	Example: `  # Snapshot the inventory of a cluster
+  ochami discover export -F yaml > nodes.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		nodes, warnings := discover.NodeListFromSMD(discoverGetSMDState(cmd, smdClient, true))
+		for _, w := range warnings {
+			log.Logger.Warn().Msg(w)
+		}
+
+		// Print output
+		if outBytes, err := format.MarshalData(nodes, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+	},
+}
+
+func init() {
+	discoverExportCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output payload data ("+format.DataFormatList()+")")
+
+	discoverExportCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	discoverCmd.AddCommand(discoverExportCmd)
+}
@@ -0,0 +1,122 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// discoverFromDHCPLeasesCmd represents the "discover from-dhcp-leases" command
+var discoverFromDHCPLeasesCmd = &cobra.Command{
+	Use:   "from-dhcp-leases -r <rules_file> [-f <rules_format>] [--lease-format <lease_format>] [-F <out_format>] <lease_file> [<out_file>]",
+	Args:  cobra.RangeArgs(1, 2),
+	Short: "Generate a static discovery payload from BMC DHCP leases",
+	Long: `Generate a static discovery payload (see ochami-discover(1)) from a
+dnsmasq or Kea lease file of BMCs that were brought up via DHCP,
+using a rules file that maps BMC MAC address prefixes to xname
+patterns. lease_file can be - to read from standard input. The
+payload is written to out_file, or standard output if it is omitted
+or -.
+
+Leases are ordered by IP address and each is mapped by the first rule
+whose MAC prefix matches it. A warning is logged for each lease that
+does not match any rule. The lease file format is detected from its
+content unless --lease-format is passed.
+
+See ochami-discover(1) for the format of the rules file.`,
+	Example: `  # Generate a YAML payload from dnsmasq leases using YAML rules
+  ochami discover from-dhcp-leases -r rules.yaml -f yaml -F yaml /var/lib/misc/dnsmasq.leases nodes.yaml
+
+  # Generate a payload from Kea leases and send it to SMD
+  ochami discover from-dhcp-leases -r rules.json /var/lib/kea/kea-leases4.csv | ochami discover static -d @-`,
+	Run: func(cmd *cobra.Command, args []string) {
+		leaseFile, outFile := args[0], "-"
+		if len(args) > 1 {
+			outFile = args[1]
+		}
+
+		// Read rules and leases
+		rulesFile, _ := cmd.Flags().GetString("rules")
+		var rules discover.LeaseRules
+		if err := client.ReadPayloadFile(rulesFile, formatInput, &rules); err != nil {
+			log.Logger.Error().Err(err).Msgf("unable to read rules from %s", rulesFile)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var (
+			f   *os.File
+			err error
+		)
+		if leaseFile == "-" {
+			f = os.Stdin
+		} else if f, err = os.Open(leaseFile); err != nil {
+			log.Logger.Error().Err(err).Msgf("unable to open lease file %s", leaseFile)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		leaseFormat, _ := cmd.Flags().GetString("lease-format")
+		leases, err := discover.ParseLeases(f, leaseFormat)
+		f.Close()
+		if err != nil {
+			log.Logger.Error().Err(err).Msgf("unable to parse leases from %s", leaseFile)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Map leases to nodes
+		nodes, unmatched, err := discover.NodeListFromLeases(leases, rules)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to map leases to nodes")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, l := range unmatched {
+			log.Logger.Warn().Msgf("lease does not match any rule, skipping: %s", l)
+		}
+		log.Logger.Info().Msgf("mapped %d of %d leases to nodes", len(nodes.Nodes), len(leases))
+
+		outBytes, err := format.MarshalData(nodes, formatOutput)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if outFile == "-" {
+			fmt.Println(string(outBytes))
+			return
+		}
+		if !bytes.HasSuffix(outBytes, []byte("\n")) {
+			outBytes = append(outBytes, '\n')
+		}
+		if err := os.WriteFile(outFile, outBytes, 0644); err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to write %s", outFile)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		log.Logger.Info().Msgf("wrote payload with %d nodes to %s", len(nodes.Nodes), outFile)
+	},
+}
+
+func init() {
+	discoverFromDHCPLeasesCmd.Flags().StringP("rules", "r", "", "file containing rules mapping BMC MAC address prefixes to xnames")
+	discoverFromDHCPLeasesCmd.Flags().String("lease-format", "", "format of lease file (default: detected) (dnsmasq,kea)")
+	discoverFromDHCPLeasesCmd.Flags().VarP(&formatInput, "format-input", "f", "format of rules file ("+format.DataFormatList()+")")
+	discoverFromDHCPLeasesCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output payload data ("+format.DataFormatList()+")")
+
+	discoverFromDHCPLeasesCmd.MarkFlagRequired("rules")
+
+	discoverFromDHCPLeasesCmd.RegisterFlagCompletionFunc("lease-format", completionLeaseFormat)
+	discoverFromDHCPLeasesCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
+	discoverFromDHCPLeasesCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	discoverCmd.AddCommand(discoverFromDHCPLeasesCmd)
+}
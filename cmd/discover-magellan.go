@@ -0,0 +1,158 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// discoverMagellanCmd represents the "discover magellan" command
+var discoverMagellanCmd = &cobra.Command{
+	Use:   "magellan --subnet (<cidr> | <ip>)... [--credentials-file <file>] [-r <rules_file>] [--overwrite] [--auto-group <group>=<spec>]... [--default-group <group>,...] [--sync-groups [--prune-groups]] [--push-bmc-creds] [--dry-run [--output-dir <dir>] [-F <format>]] [-f <format>]",
+	Args:  cobra.NoArgs,
+	Short: "Discover BMCs and their nodes via Redfish and populate SMD",
+	Long: `Discover BMCs and the nodes they manage via Redfish and populate SMD
+with them, like Magellan would. The subnets and addresses passed with
+--subnet are scanned for Redfish endpoints the same way as with
+'discover scan', the BMCs found are mapped to nodes by the rules in
+--rules, and the components, redfish endpoints, and groups of the
+nodes are sent to SMD the same way as with 'discover static'.
+
+Unlike 'discover static', the redfish endpoint of each BMC is populated
+with what was read from it: the Redfish URI of its first system, and
+all of the Ethernet interfaces of the system with the IPv4 addresses
+the BMC reports for them, if any. Redfish endpoints are stored with the
+credentials that the BMC accepted.
+
+--rules is required unless --dry-run is passed, since without it BMCs
+are mapped to placeholder xnames. To review the nodes before sending
+them, use 'discover scan' to write a payload and 'discover static' to
+send it instead.
+
+See ochami-discover(1) for more details.`,
+	Example: `  # Discover the BMCs of a subnet and populate SMD
+  ochami discover magellan --subnet 10.254.0.0/24 --credentials-file creds.yaml -r rules.yaml -f yaml
+
+  # Show what would be sent for two BMCs
+  ochami discover magellan --subnet 10.254.0.11 --subnet 10.254.0.12 -r rules.yaml -f yaml --dry-run -F yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Without a base URI, we cannot do anything
+		smdBaseURI, err := getBaseURISMD(cmd)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get base URI for SMD")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		dryRun, err := cmd.Flags().GetBool("dry-run")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --dry-run")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if !cmd.Flag("rules").Changed && !dryRun {
+			log.Logger.Error().Msg("--rules is required to map BMCs to xnames unless --dry-run is passed")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Check the token before scanning, which can take a while
+		if !dryRun {
+			setToken(cmd)
+			checkToken(cmd)
+		}
+
+		subnets, err := cmd.Flags().GetStringSlice("subnet")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --subnet")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		concurrency, err := cmd.Flags().GetInt("concurrency")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --concurrency")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		payloads := discoverScanPayloads(cmd, smdBaseURI, discoverScanBMCs(cmd, subnets, concurrency))
+
+		discoverSend(cmd, smdBaseURI, payloads, nil)
+	},
+}
+
+// discoverScanPayloads maps the BMCs in scanned to nodes by the rules in --rules
+// (see discoverScanRules), adds them to groups (see discoverApplyGroups), and
+// returns the payloads to send to SMD at smdBaseURI for them, populated with
+// what was read from the BMCs (see discover.DiscoveryInfoFromScan). If the
+// payloads cannot be constructed, the program exits.
+func discoverScanPayloads(cmd *cobra.Command, smdBaseURI string, scanned []discover.ScanResult) discover.Payloads {
+	rules := discoverScanRules(cmd)
+
+	// Map BMCs to nodes
+	nodes, unmatched, err := discover.NodeListFromScan(scanned, rules)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to map BMCs to nodes")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	for _, l := range unmatched {
+		log.Logger.Warn().Msgf("BMC does not match any rule, skipping: %s", l)
+	}
+	log.Logger.Info().Msgf("mapped %d of %d BMC(s) to nodes", len(nodes.Nodes), len(scanned))
+	if !cmd.Flag("rules").Changed && len(nodes.Nodes) > 0 {
+		log.Logger.Warn().Msg("no --rules passed, nodes have placeholder xnames")
+	}
+	discoverApplyGroups(cmd, &nodes)
+
+	// Put together payload for different endpoints
+	comps, rfes, ifaces, warnings, err := discover.DiscoveryInfoFromScan(smdBaseURI, nodes, scanned)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to construct structures to send to SMD")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	discoverReportWarnings(warnings)
+	payloads := discover.Payloads{
+		Components:       comps,
+		RedfishEndpoints: rfes,
+		Groups:           discoverNodeGroups(nodes),
+	}
+	if discoveryVersion == discover.DiscoveryMethodV1 {
+		payloads.EthernetInterfaces = ifaces
+	}
+
+	return payloads
+}
+
+func init() {
+	discoverMagellanCmd.Flags().StringSlice("subnet", []string{}, "one or more subnets (CIDR) or addresses to scan for Redfish endpoints")
+	discoverMagellanCmd.Flags().String("credentials-file", "", "file containing BMC credentials to try")
+	discoverMagellanCmd.Flags().StringP("rules", "r", "", "file containing rules mapping BMC MAC address prefixes to xnames")
+	discoverMagellanCmd.Flags().Uint16("port", 443, "port of the Redfish service of BMCs")
+	discoverMagellanCmd.Flags().Int("concurrency", 32, "maximum number of addresses to probe at once")
+	discoverMagellanCmd.Flags().Duration("target-timeout", 10*time.Second, "maximum time to spend probing each address (0 for no limit)")
+	discoverMagellanCmd.Flags().StringArray("auto-group", []string{}, "add nodes to a group by xname pattern or NID range (<group>=<xname_regex> or <group>=nid:<ranges>, can be repeated)")
+	discoverMagellanCmd.Flags().StringSlice("default-group", []string{}, "one or more groups to add all nodes to")
+	discoverMagellanCmd.Flags().Bool("sync-groups", false, "create missing groups and add nodes to the groups they are declared in that exist in SMD")
+	discoverMagellanCmd.Flags().Bool("prune-groups", false, "remove members of the groups of the nodes that are not among them; implies --sync-groups")
+	discoverMagellanCmd.Flags().Bool("push-bmc-creds", false, "also write the BMC credentials to Vault under discovery.bmc-creds-path for services that read them from it")
+	discoverMagellanCmd.Flags().Bool("overwrite", false, "overwrite any existing information instead of failing")
+	discoverMagellanCmd.Flags().Bool("dry-run", false, "output the payloads that would be sent to SMD without sending them")
+	discoverMagellanCmd.Flags().StringP("output-dir", "o", "", "with --dry-run, directory to write a file for each payload to instead of printing them")
+	discoverMagellanCmd.Flags().VarP(&formatInput, "format-input", "f", "format of credentials and rules files ("+format.DataFormatList()+")")
+	discoverMagellanCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output or written to --output-dir ("+format.DataFormatList()+")")
+
+	discoverMagellanCmd.MarkFlagRequired("subnet")
+
+	discoverMagellanCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
+	discoverMagellanCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	discoverCmd.AddCommand(discoverMagellanCmd)
+}
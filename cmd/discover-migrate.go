@@ -0,0 +1,109 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// discoverMigrateCmd represents the "discover migrate" command
+var discoverMigrateCmd = &cobra.Command{
+	Use:   "migrate [-f <in_format>] [-F <out_format>] <in_file> [<out_file>]",
+	Args:  cobra.RangeArgs(1, 2),
+	Short: "Update a static discovery payload file to the current format version",
+	Long: `Update a static discovery payload file to the current version of the
+format (see ochami-discover(1)), writing the result to out_file or, if
+it is omitted, back to in_file. Either file can be - to use standard
+input or standard output.
+
+Payload files without a version are from before the format was
+versioned and may use deprecated shapes, such as 'group' instead of
+'groups' or 'name' instead of 'network' in interface IP addresses.
+These are migrated and a warning is logged for each. 'discover static'
+performs the same migration in memory, but leaves the file as is.
+Compact entries describing many nodes are kept as they are.
+
+By default, the output is written in the same format as the input.
+
+See ochami-discover(1) for more details.`,
+	Example: `  # Migrate a YAML payload file
+  ochami discover migrate -f yaml nodes.yaml nodes-new.yaml
+
+  # Migrate a JSON payload file, writing YAML to standard output
+  ochami discover migrate -F yaml nodes.json -
+
+  # Upgrade a YAML payload file in place
+  ochami discover migrate -f yaml nodes.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		inFile, outFile := args[0], args[0]
+		if len(args) > 1 {
+			outFile = args[1]
+		}
+
+		// Read and migrate payload
+		var data any
+		if err := client.ReadPayloadFile(inFile, formatInput, &data); err != nil {
+			log.Logger.Error().Err(err).Msgf("unable to read payload from %s", inFile)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		payload, warnings, err := discover.MigrateNodeList(data)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("invalid discovery payload")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, w := range warnings {
+			log.Logger.Warn().Msg(w)
+		}
+
+		// Write output in input format unless otherwise specified
+		outFormat := formatInput
+		if cmd.Flag("format-output").Changed {
+			outFormat = formatOutput
+		}
+		outBytes, err := format.MarshalData(payload, outFormat)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if outFile == "-" {
+			fmt.Println(string(outBytes))
+			return
+		}
+		if !bytes.HasSuffix(outBytes, []byte("\n")) {
+			outBytes = append(outBytes, '\n')
+		}
+		// Replace the file atomically, keeping its mode if it exists
+		perm := os.FileMode(0644)
+		if fi, err := os.Stat(outFile); err == nil {
+			perm = fi.Mode().Perm()
+		}
+		if err := writeFileAtomic(outFile, outBytes, perm); err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to write %s", outFile)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		log.Logger.Info().Msgf("wrote version %d payload to %s", discover.NodeListVersion, outFile)
+	},
+}
+
+func init() {
+	discoverMigrateCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
+	discoverMigrateCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output payload data (default: same as input) ("+format.DataFormatList()+")")
+
+	discoverMigrateCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
+	discoverMigrateCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	discoverCmd.AddCommand(discoverMigrateCmd)
+}
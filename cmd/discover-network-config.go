@@ -0,0 +1,76 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// discoverNetworkConfigCmd represents the "discover network-config" command
+var discoverNetworkConfigCmd = &cobra.Command{
+	Use:   "network-config [-d (<data> | @<path>)] [-f <format>] [-F <format>]",
+	Args:  cobra.NoArgs,
+	Short: "Generate cloud-init network config for the bonds in a discovery payload",
+	Long: `Generate the cloud-init network configuration (version 2) that sets
+up the bonded interfaces of each node in a discovery payload, and
+print it keyed by the xname of the node. Nodes without bonds are
+left out. The payload has the same format as for 'ochami discover
+static'.
+
+The members of each bond are matched by MAC address, and the bond
+takes the MAC address of its primary member, which is the interface
+'ochami discover static' gives the IP addresses of the bond in SMD,
+so that the bond gets them from DHCP.
+
+See ochami-discover(1) for more details.`,
+	Example: `  # Generate the network config of the bonded nodes in a payload
+  ochami discover network-config -d @nodes.yaml -f yaml -F yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Read data from file or stdin, migrating it from older versions
+		// of the format if needed
+		nodes := discoverReadPayload(cmd)
+
+		configs := make(map[string]*discover.NetworkConfig)
+		for _, n := range nodes.Nodes {
+			nc, err := discover.BondNetworkConfig(n)
+			if err != nil {
+				log.Logger.Error().Err(err).Msgf("node %s: failed to generate network config", n.Xname)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			if nc != nil {
+				configs[n.Xname] = nc
+			}
+		}
+		if len(configs) == 0 {
+			log.Logger.Warn().Msg("no nodes in payload have bonds")
+		}
+
+		if outBytes, err := format.MarshalData(configs, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+	},
+}
+
+func init() {
+	discoverNetworkConfigCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
+	discoverNetworkConfigCmd.Flags().VarP(&discoverFormatInput, "format-input", "f", "format of input payload data ("+discover.PayloadFormatList()+")")
+	discoverNetworkConfigCmd.Flags().Bool("validate-schema", false, "check payload against the JSON Schema of the payload format before reading it (see discover schema)")
+	discoverNetworkConfigCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	discoverNetworkConfigCmd.RegisterFlagCompletionFunc("format-input", completionDiscoverFormat)
+	discoverNetworkConfigCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	discoverCmd.AddCommand(discoverNetworkConfigCmd)
+}
@@ -0,0 +1,128 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// discoverPlanCmd represents the "discover plan" command
+var discoverPlanCmd = &cobra.Command{
+	Use:   "plan [--overwrite] [--auto-group <group>=<spec>]... [--default-group <group>,...] [--out <path>] [-F <format>] [-d (<data> | @<path>)] [-f <format>]",
+	Args:  cobra.NoArgs,
+	Short: "Show what discovering a payload would send to SMD",
+	Long: `Compare a discovery payload with what is in SMD and print the
+execution plan of discovering it with 'ochami discover static': the
+components, redfish endpoints, ethernet interfaces, and groups that
+would be created or updated, in the order they would be sent, along
+with the number of requests each step would send. Nothing is changed
+in SMD. The payload and the flags it accepts are the same as for
+'ochami discover static'.
+
+Records that are in SMD are updated only if they differ and
+--overwrite is passed. Otherwise, they are reported as conflicting
+and left as is.
+
+Pass --out to write the plan to a file, which 'ochami discover apply
+--plan' then applies, so that it can be reviewed and approved first.
+The plan is printed as text unless -F is passed, in which case it is
+printed in that format like the file.
+
+See ochami-discover(1) for more details.`,
+	Example: `  # Review what discovering the nodes in a payload would do, then do it
+  ochami discover plan -d @nodes.yaml -f yaml --out plan.json
+  ochami discover apply --plan plan.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Without a base URI, we cannot do anything
+		smdBaseURI, err := getBaseURISMD(cmd)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get base URI for SMD")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Put together payload for different endpoints. BMC credentials
+		// are only set when applying, so that the plan holds no secrets.
+		payloads := discoverPayloads(cmd, smdBaseURI)
+		var withCreds int
+		for i := range payloads.RedfishEndpoints.RedfishEndpoints {
+			rfe := &payloads.RedfishEndpoints.RedfishEndpoints[i]
+			if rfe.User != "" || rfe.Password != "" {
+				rfe.User, rfe.Password = "", ""
+				withCreds++
+			}
+		}
+		if withCreds > 0 {
+			log.Logger.Warn().Msgf("BMC credentials of %d node(s) in the payload are not kept in the plan, only discovery.bmc-username and discovery.bmc-password are set when applying it", withCreds)
+		}
+
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		// Compare with what is in SMD
+		plan := discover.NewPlan(payloads, discoverGetSMDState(cmd, smdClient, true), cmd.Flag("overwrite").Changed)
+
+		// Print output
+		if cmd.Flag("format-output").Changed {
+			if outBytes, err := format.MarshalData(plan, formatOutput); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			} else {
+				fmt.Println(string(outBytes))
+			}
+		} else {
+			fmt.Println(plan)
+		}
+		if n := plan.Count(discover.PlanConflict); n > 0 {
+			log.Logger.Warn().Msgf("%d record(s) in SMD differ from the payload and would not be updated, pass --overwrite to update them", n)
+		}
+
+		// Write plan to apply later
+		if outFile, _ := cmd.Flags().GetString("out"); outFile != "" {
+			outBytes, err := format.MarshalData(plan, format.DataFormatJsonPretty)
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format plan")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			if err := os.WriteFile(outFile, append(outBytes, '\n'), 0644); err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to write %s", outFile)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			log.Logger.Info().Msgf("wrote plan to %s, run 'ochami discover apply --plan %s' to apply it", outFile, outFile)
+		}
+	},
+}
+
+func init() {
+	discoverPlanCmd.Flags().StringArray("auto-group", []string{}, "add nodes to a group by xname pattern or NID range (<group>=<xname_regex> or <group>=nid:<ranges>, can be repeated)")
+	discoverPlanCmd.Flags().StringSlice("default-group", []string{}, "one or more groups to add all nodes to")
+	discoverPlanCmd.Flags().Var(&discoveryVersion, "discovery-version", "set version for discovery method to use")
+	discoverPlanCmd.Flags().Var(&discoverErrorPolicy, "on-error", "what to do with malformed nodes (fail-fast,skip-node,skip-interface)")
+	discoverPlanCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
+	discoverPlanCmd.Flags().VarP(&discoverFormatInput, "format-input", "f", "format of input payload data ("+discover.PayloadFormatList()+")")
+	discoverPlanCmd.Flags().Bool("validate-schema", false, "check payload against the JSON Schema of the payload format before reading it (see discover schema)")
+	discoverPlanCmd.Flags().Bool("overwrite", false, "plan to update records that differ in SMD")
+	discoverPlanCmd.Flags().String("out", "", "file to write the plan to for 'ochami discover apply --plan'")
+	discoverPlanCmd.Flags().VarP(&formatOutput, "format-output", "F", "print the plan in this format instead of as text ("+format.DataFormatList()+")")
+
+	discoverPlanCmd.RegisterFlagCompletionFunc("format-input", completionDiscoverFormat)
+	discoverPlanCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+	discoverPlanCmd.RegisterFlagCompletionFunc("discovery-version", completionDiscoveryVersion)
+	discoverPlanCmd.RegisterFlagCompletionFunc("on-error", completionDiscoverErrorPolicy)
+	discoverPlanCmd.MarkFlagFilename("out", "json")
+
+	discoverCmd.AddCommand(discoverPlanCmd)
+}
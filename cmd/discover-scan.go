@@ -0,0 +1,298 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/redfish"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/pool"
+)
+
+// errScanNoCredentials is returned when a Redfish endpoint is found but none of
+// the credentials work for it.
+var errScanNoCredentials = errors.New("none of the credentials were accepted")
+
+// discoverScanCmd represents the "discover scan" command
+var discoverScanCmd = &cobra.Command{
+	Use:   "scan --subnet <cidr>... [--credentials-file <file>] [-r <rules_file>] [-f <format>] [-F <out_format>] [<out_file>]",
+	Args:  cobra.MaximumNArgs(1),
+	Short: "Generate a static discovery payload by scanning subnets for Redfish endpoints",
+	Long: `Generate a static discovery payload (see ochami-discover(1)) by
+scanning the subnets passed with --subnet for Redfish endpoints. Each
+address is probed for a Redfish service root, and the managers and
+systems of each BMC found are read using the first credentials in
+--credentials-file that it accepts, followed by discovery.bmc-username
+and discovery.bmc-password of the cluster if set. The payload is
+written to out_file, or standard output if it is omitted or -.
+
+BMCs are ordered by IP address and mapped to nodes by the rules in
+--rules, the same way as with from-dhcp-leases, using the MAC address
+of the BMC interface with the scanned IP address and the host name
+of its first system. If --rules is not passed, every BMC is mapped
+to a placeholder xname, which must be reviewed and corrected before
+the payload is sent with 'discover static'. The MAC addresses of the
+first system of each BMC become the interfaces of its node, without
+IP addresses.
+
+See ochami-discover(1) for the format of the credentials and rules
+files.`,
+	Example: `  # Scan a subnet and write a payload for review
+  ochami discover scan --subnet 10.254.0.0/24 --credentials-file creds.yaml -f yaml -F yaml nodes.yaml
+
+  # Scan two subnets, mapping BMCs to xnames with rules
+  ochami discover scan --subnet 10.254.0.0/24 --subnet 10.254.1.0/24 --credentials-file creds.yaml -r rules.yaml -f yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		outFile := "-"
+		if len(args) > 0 {
+			outFile = args[0]
+		}
+
+		subnets, err := cmd.Flags().GetStringSlice("subnet")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --subnet")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		concurrency, err := cmd.Flags().GetInt("concurrency")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --concurrency")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		scanned := discoverScanBMCs(cmd, subnets, concurrency)
+		rules := discoverScanRules(cmd)
+
+		// Map BMCs to nodes
+		nodes, unmatched, err := discover.NodeListFromScan(scanned, rules)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to map BMCs to nodes")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, l := range unmatched {
+			log.Logger.Warn().Msgf("BMC does not match any rule, skipping: %s", l)
+		}
+		log.Logger.Info().Msgf("mapped %d of %d BMC(s) to nodes", len(nodes.Nodes), len(scanned))
+		if !cmd.Flag("rules").Changed && len(nodes.Nodes) > 0 {
+			log.Logger.Warn().Msg("no --rules passed, nodes have placeholder xnames that must be reviewed before sending the payload")
+		}
+
+		outBytes, err := format.MarshalData(nodes, formatOutput)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if outFile == "-" {
+			fmt.Println(string(outBytes))
+			return
+		}
+		if !bytes.HasSuffix(outBytes, []byte("\n")) {
+			outBytes = append(outBytes, '\n')
+		}
+		if err := os.WriteFile(outFile, outBytes, 0644); err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to write %s", outFile)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		log.Logger.Info().Msgf("wrote payload with %d nodes to %s", len(nodes.Nodes), outFile)
+	},
+}
+
+// discoverScanBMCs probes the addresses of subnets for Redfish endpoints on
+// --port, up to concurrency at once and each for at most --target-timeout, and
+// returns those found whose inventory could be read with the credentials in
+// --credentials-file or those of the cluster, in the order of their addresses.
+// Subnets can also be single IP addresses. If the subnets or flags are invalid
+// or there are no credentials to try, the program exits.
+func discoverScanBMCs(cmd *cobra.Command, subnets []string, concurrency int) []discover.ScanResult {
+	// Determine addresses to scan
+	var addrs []netip.Addr
+	for _, s := range subnets {
+		prefix, err := netip.ParsePrefix(s)
+		if a, aerr := netip.ParseAddr(s); err != nil && aerr == nil {
+			prefix, err = a.Prefix(a.BitLen())
+		}
+		if err != nil {
+			log.Logger.Error().Err(err).Msgf("invalid subnet %q", s)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		a, err := discover.ScanAddrs(prefix)
+		if err != nil {
+			log.Logger.Error().Err(err).Msgf("invalid subnet %q", s)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		addrs = append(addrs, a...)
+	}
+	port, err := cmd.Flags().GetUint16("port")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --port")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if concurrency < 1 {
+		log.Logger.Error().Msg("scan concurrency must be at least 1")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	targetTimeout, err := cmd.Flags().GetDuration("target-timeout")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --target-timeout")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	// Read credentials, falling back to those of the cluster
+	var creds discover.ScanCredentials
+	if cmd.Flag("credentials-file").Changed {
+		credsFile, _ := cmd.Flags().GetString("credentials-file")
+		if err := client.ReadPayloadFile(credsFile, formatInput, &creds); err != nil {
+			log.Logger.Error().Err(err).Msgf("unable to read credentials from %s", credsFile)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	}
+	if cl, found := getCluster(cmd); found && cl.Cluster.Discovery.BMCUsername != "" {
+		creds.Credentials = append(creds.Credentials, discover.ScanCredential{
+			Username: cl.Cluster.Discovery.BMCUsername,
+			Password: configSecret(cmd, "discovery.bmc-password", cl.Cluster.Discovery.BMCPassword),
+		})
+	}
+	if len(creds.Credentials) == 0 {
+		log.Logger.Error().Msg("no BMC credentials to try, pass --credentials-file or set discovery.bmc-username for the cluster")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	// Probe each address, reading the inventory of each BMC found. An
+	// address that timed out may still be probed in the background, so
+	// what it found is only accessed under lock.
+	log.Logger.Info().Msgf("scanning %d address(es) for Redfish endpoints", len(addrs))
+	var (
+		mu      sync.Mutex
+		found   = make([]bool, len(addrs))
+		ok      = make([]bool, len(addrs))
+		results = make([]discover.ScanResult, len(addrs))
+	)
+	pool.Run(context.Background(), len(addrs), concurrency, targetTimeout, func(ctx context.Context, i int) error {
+		uri := "https://" + netip.AddrPortFrom(addrs[i], port).String()
+		rc, err := redfish.NewClient(uri, insecure)
+		if err != nil {
+			return err
+		}
+
+		// Check if a CA certificate was passed and load it into client if valid
+		useCACert(rc.OchamiClient)
+
+		// Stop sending requests once the --context-timeout or
+		// --target-timeout deadline passes
+		rc.Deadline = commandDeadline
+		if dl, ok := ctx.Deadline(); ok {
+			rc.OchamiClient = rc.WithDeadline(dl)
+		}
+
+		if !rc.IsRedfish() {
+			return nil
+		}
+		mu.Lock()
+		found[i] = true
+		mu.Unlock()
+		for _, c := range creds.Credentials {
+			inv, err := rc.GetInventory(c.Username, c.Password)
+			if err == nil {
+				mu.Lock()
+				results[i] = discover.ScanResult{
+					IP:        addrs[i].String(),
+					URI:       uri,
+					Username:  c.Username,
+					Password:  c.Password,
+					Inventory: inv,
+				}
+				mu.Unlock()
+				return nil
+			}
+			log.Logger.Debug().Err(err).Msgf("failed to read inventory of %s as %s", addrs[i], c.Username)
+		}
+		return errScanNoCredentials
+	}, func(i int, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case errors.Is(err, pool.TargetTimeoutError):
+			if found[i] {
+				log.Logger.Warn().Err(err).Msgf("timed out reading inventory of Redfish endpoint %s, skipping", addrs[i])
+			}
+		case err != nil:
+			log.Logger.Warn().Err(err).Msgf("failed to read inventory of Redfish endpoint %s, skipping", addrs[i])
+		case found[i]:
+			ok[i] = true
+			inv := results[i].Inventory
+			if len(inv.Systems) > 0 {
+				log.Logger.Info().Msgf("found Redfish endpoint %s with %d system(s) (%s %s, serial %s)", addrs[i], len(inv.Systems), inv.Systems[0].Manufacturer, inv.Systems[0].Model, inv.Systems[0].SerialNumber)
+			} else {
+				log.Logger.Info().Msgf("found Redfish endpoint %s with no systems", addrs[i])
+			}
+		}
+	})
+	var scanned []discover.ScanResult
+	for i := range results {
+		if ok[i] {
+			scanned = append(scanned, results[i])
+		}
+	}
+
+	return scanned
+}
+
+// discoverScanRules returns the rules in --rules that map scanned BMCs to nodes,
+// or discover.PlaceholderScanRules if it was not passed. If the rules cannot be
+// read, the program exits.
+func discoverScanRules(cmd *cobra.Command) discover.LeaseRules {
+	// Read rules, or map BMCs to placeholder xnames
+	rules := discover.PlaceholderScanRules
+	if cmd.Flag("rules").Changed {
+		rulesFile, _ := cmd.Flags().GetString("rules")
+		rules = discover.LeaseRules{}
+		if err := client.ReadPayloadFile(rulesFile, formatInput, &rules); err != nil {
+			log.Logger.Error().Err(err).Msgf("unable to read rules from %s", rulesFile)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	}
+
+	return rules
+}
+
+func init() {
+	discoverScanCmd.Flags().StringSlice("subnet", []string{}, "one or more subnets (CIDR) or addresses to scan for Redfish endpoints")
+	discoverScanCmd.Flags().String("credentials-file", "", "file containing BMC credentials to try")
+	discoverScanCmd.Flags().StringP("rules", "r", "", "file containing rules mapping BMC MAC address prefixes to xnames (default: placeholder xnames)")
+	discoverScanCmd.Flags().Uint16("port", 443, "port of the Redfish service of BMCs")
+	discoverScanCmd.Flags().Int("concurrency", 32, "maximum number of addresses to probe at once")
+	discoverScanCmd.Flags().Duration("target-timeout", 10*time.Second, "maximum time to spend probing each address (0 for no limit)")
+	discoverScanCmd.Flags().VarP(&formatInput, "format-input", "f", "format of credentials and rules files ("+format.DataFormatList()+")")
+	discoverScanCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output payload data ("+format.DataFormatList()+")")
+
+	discoverScanCmd.MarkFlagRequired("subnet")
+
+	discoverScanCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
+	discoverScanCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	discoverCmd.AddCommand(discoverScanCmd)
+}
@@ -0,0 +1,48 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+)
+
+// discoverSchemaCmd represents the "discover schema" command
+var discoverSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Args:  cobra.NoArgs,
+	Short: "Print the JSON Schema of static discovery payloads",
+	Long: `Print the JSON Schema of the current version of the static discovery
+payload format (see ochami-discover(1)) to standard output, e.g. for
+editors to check and complete payload files as they are written.
+
+The schema is stricter than reading payloads: keys that are not part of
+the format are not allowed and values must have the right type, instead
+of being ignored or read as empty. Pass --validate-schema to commands
+that read payloads to check them against it first.
+
+See ochami-discover(1) for more details.`,
+	Example: `  # Write the schema for an editor
+  ochami discover schema > nodes.schema.json
+
+  # Check a payload against the schema and for problems
+  ochami discover validate --validate-schema -d @nodes.yaml -f yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		schema, err := discover.NodeListSchema()
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to generate payload schema")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		fmt.Println(string(schema))
+	},
+}
+
+func init() {
+	discoverCmd.AddCommand(discoverSchemaCmd)
+}
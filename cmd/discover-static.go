@@ -3,22 +3,30 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
 	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/client/vault"
 	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/ops"
+	"github.com/OpenCHAMI/ochami/pkg/pool"
 )
 
 // discoverStaticCmd represents the discover-static command
 var discoverStaticCmd = &cobra.Command{
-	Use:   "static [--overwrite] [-d (<data> | @<path>)] [-f <format>]",
+	Use:   "static [--scan (<cidr> | <ip>)... [--credentials-file <file>] [-r <rules_file>]] [--overwrite] [--state-file <path> [--force]] [--batch-size <n>] [--concurrency <n>] [--retries <n>] [--auto-group <group>=<spec>]... [--default-group <group>,...] [--sync-groups [--prune-groups]] [--push-bmc-creds] [--dry-run [--output-dir <dir>] [-F <format>]] [-d (<data> | @<path>)] [-f <format>]",
 	Short: "Populate SMD with data statically",
 	Long: `Populate SMD using static data. This data can be from a file (if an
 argument is passed) or from standard input. This "fake" discovery
@@ -27,9 +35,10 @@ out which SMD data structures to create. This is meant to be a
 reproduceable alternative to dynamic discovery as is done by
 Magellan.
 
-The format of the payload file is an array of node specifications.
-In YAML, each node entry would look something like:
+The format of the payload file is a version and an array of node
+specifications. In YAML, each node entry would look something like:
 
+version: 1
 nodes:
 - name: node01
   nid: 1
@@ -42,17 +51,97 @@ nodes:
   interfaces:
   - mac_addr: de:ad:be:ee:ee:f1
     ip_addrs:
-    - name: internal
+    - network: internal
       ip_addr: 172.16.0.1
   - mac_addr: de:ad:be:ee:ee:f2
     ip_addrs:
-    - name: external
+    - network: external
       ip_addr: 10.15.3.100
   - mac_addr: 02:00:00:91:31:b3
     ip_addrs:
-    - name: HSN
+    - network: HSN
       ip_addr: 192.168.0.1
 
+Nodes can also be added to groups without listing them in the
+payload: --default-group adds every node to the given groups and
+--auto-group adds nodes whose xname matches a regular expression
+(e.g. 'compute=x3000c0s[0-7]b0n.*') or whose NID is in a range (e.g.
+'gpu=nid:1-16,20') to a group. Defaults for both can be set for the
+cluster in the config file (see ochami-config(5)).
+
+Groups that already exist in SMD are not added to unless
+--sync-groups is passed, in which case missing groups are created
+and each node is added to the groups it is declared in that exist.
+--prune-groups, which implies --sync-groups, also removes the
+members of those groups that the payload does not declare. Other
+groups in SMD are left as is.
+
+Payloads from before the format was versioned are migrated in
+memory, with a warning for each deprecated shape found. Use
+'ochami discover migrate' to update the file itself.
+
+A single entry can describe many identical nodes with ranges in its
+xname (e.g. 'x3000c0s[0-15]b0n0') and nid (e.g. '1-16'), with {nid}
+and {index} replaced in its strings (e.g. 'bmc_ip: 10.0.0.{nid}').
+
+Entries can also describe devices other than nodes, such as switch
+controllers (type: RouterBMC), PDUs (type: CabinetPDU), and
+management switches (type: MgmtSwitch), which get SMD Components and
+RedfishEndpoints of the matching types.
+
+The power actions PCS may use for a node are all Redfish ResetTypes
+unless its entry lists those its BMC supports in power_actions (e.g.
+[On, ForceOff, GracefulShutdown]).
+
+The credentials of the BMC of a node are set with bmc_username and
+bmc_password, or bmc_password_file to read the password from a file,
+and default to discovery.bmc-username and discovery.bmc-password of
+the cluster. They are stored in SMD with the redfish endpoints and,
+with --push-bmc-creds, also written to Vault under
+discovery.bmc-creds-path (secret/hms-creds by default) for services
+that read them from there, such as PCS.
+
+A malformed node, such as one with invalid BMC credentials or an
+interface without a valid mac_addr, stops the payloads from being
+generated unless --on-error skip-node is passed to skip such nodes,
+or --on-error skip-interface to skip only malformed interfaces. An
+interface with a mac_addr but no ip_addrs is sent without IPs.
+Whatever is skipped is reported again once the payloads are sent.
+
+Node inventories can also be read as CSV with '-f csv', with a header
+row naming the columns (name, nid, xname, bmc_mac, bmc_ip, bmc_fqdn,
+groups, bmc_username, bmc_password_file, and iface0_mac, iface0_ip,
+iface0_network, etc. for each
+interface), groups and IP addresses being separated by semicolons.
+
+Instead of reading a payload, the subnets and addresses passed with
+--scan (e.g. 10.0.0.0/24) can be scanned for BMCs with a Redfish
+service, which are mapped to nodes by the rules in --rules and sent
+the same way as with 'discover magellan'. --rules is required unless
+--dry-run is passed. To write the nodes found to a payload file for
+review instead, use 'discover scan'.
+
+With --dry-run, nothing is sent to SMD. The components, redfish
+endpoints, ethernet interfaces, and groups that would be sent are
+printed instead, or written to a file for each in --output-dir, with
+BMC passwords redacted.
+
+For large clusters, pass --batch-size or --concurrency to send the
+records in batches, up to --concurrency batches at once. Only the
+components and hardware inventory of a batch are sent in one request.
+Redfish endpoints and ethernet interfaces, which SMD takes one per
+request, are still sent one request each, with the batch only
+grouping them for --concurrency and --retries. The records of a batch
+that failed are retried up to --retries times, and a summary of how
+many records of each kind were sent or failed is printed at the end.
+
+When iterating on a large payload file, pass --state-file to only
+send the components, redfish endpoints, and ethernet interfaces that
+changed since the last run. The hashes of the records sent are saved
+per cluster to the file. Pass --force to send every record regardless,
+e.g. after records were changed or deleted in SMD by other means.
+Groups are always sent.
+
 See ochami-discover(1) for more details.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Without a base URI, we cannot do anything
@@ -63,431 +152,964 @@ See ochami-discover(1) for more details.`,
 			os.Exit(1)
 		}
 
-		// This endpoint requires authentication, so a token is needed
+		batch := discoverGetBatchOptions(cmd)
+
+		// Put together payload for different endpoints, either from
+		// the payload data or by scanning for BMCs
+		var payloads discover.Payloads
+		if cmd.Flag("scan").Changed {
+			payloads = discoverStaticScanPayloads(cmd, smdBaseURI)
+		} else {
+			payloads = discoverPayloads(cmd, smdBaseURI)
+		}
+
+		discoverSend(cmd, smdBaseURI, payloads, batch)
+	},
+}
+
+// discoverStaticScanPayloads scans the subnets passed with --scan for BMCs (see
+// discoverScanBMCs) and returns the payloads for them (see
+// discoverScanPayloads). Since scanning can take a while, the token is checked
+// first unless --dry-run is passed. If the flags are invalid, the program
+// exits.
+func discoverStaticScanPayloads(cmd *cobra.Command, smdBaseURI string) discover.Payloads {
+	if discoverFormatInput == discover.PayloadFormatCSV {
+		log.Logger.Error().Msg("--format-input csv cannot be used with --scan")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	formatInput = format.DataFormat(discoverFormatInput)
+
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --dry-run")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if !cmd.Flag("rules").Changed && !dryRun {
+		log.Logger.Error().Msg("--rules is required with --scan to map BMCs to xnames unless --dry-run is passed")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if !dryRun {
 		setToken(cmd)
 		checkToken(cmd)
+	}
 
-		// Create client to make request to SMD
-		smdClient, err := smd.NewClient(smdBaseURI, insecure)
-		if err != nil {
-			log.Logger.Error().Err(err).Msg("error creating new SMD client")
+	subnets, err := cmd.Flags().GetStringSlice("scan")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --scan")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	concurrency, err := cmd.Flags().GetInt("scan-concurrency")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --scan-concurrency")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return discoverScanPayloads(cmd, smdBaseURI, discoverScanBMCs(cmd, subnets, concurrency))
+}
+
+// discoverSend sends payloads to SMD at smdBaseURI, in batches if batch is not
+// nil, along with the BMC credentials of the cluster (see
+// discoverApplyBMCCredentials), then exits with the status of the requests
+// (see discoverSendStatus). With --dry-run, the payloads are output instead
+// (see discoverStaticDryRun). cmd must have the flags of discover static that
+// set how payloads are sent: --dry-run, --output-dir, --overwrite,
+// --push-bmc-creds, --sync-groups, and --prune-groups.
+func discoverSend(cmd *cobra.Command, smdBaseURI string, payloads discover.Payloads, batch *discoverBatchOptions) {
+	dryRun, err := cmd.Flags().GetBool("dry-run")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --dry-run")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if cmd.Flag("output-dir").Changed && !dryRun {
+		log.Logger.Error().Msg("--output-dir can only be used with --dry-run")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	// Vault must be configured to push BMC credentials to it
+	var vaultClient *vault.VaultClient
+	var vaultToken string
+	if cmd.Flag("push-bmc-creds").Changed && !dryRun {
+		if vaultClient, vaultToken = vaultGetClient(cmd); vaultClient == nil {
+			log.Logger.Error().Msg("--push-bmc-creds requires Vault, set secrets.vault-uri for the cluster or VAULT_ADDR")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	}
+
+	// This endpoint requires authentication, so a token is needed
+	// unless nothing is sent
+	if !dryRun {
+		setToken(cmd)
+		checkToken(cmd)
+	}
+
+	// Create client to make request to SMD, reading SMD itself rather
+	// than a cached copy since what is read is compared with what is sent
+	smdClient := &smd.SMDClient{OchamiClient: smdGetClient(cmd).Uncached()}
+
+	if cmd.Flag("overwrite").Changed {
+		log.Logger.Warn().Msg("--overwrite passed; overwriting any existing data")
+	}
+
+	discoverApplyBMCCredentials(cmd, &payloads.RedfishEndpoints)
+
+	// Leave out the records that did not change since they were last
+	// sent, recording what is sent before it is annotated, since
+	// annotations differ each time
+	stateFile, state := discoverLoadSendState(cmd)
+	if state != nil {
+		if force, _ := cmd.Flags().GetBool("force"); !force {
+			var skipped int
+			payloads, skipped = state.Changed(smdBaseURI, payloads)
+			if skipped > 0 {
+				log.Logger.Info().Msgf("%d record(s) did not change since they were last sent, not sending them again (pass --force to send them)", skipped)
+			}
+		}
+	}
+	sent := payloads
+	sent.RedfishEndpoints.RedfishEndpoints = slices.Clone(payloads.RedfishEndpoints.RedfishEndpoints)
+	discoverAnnotate(cmd, &payloads.RedfishEndpoints, payloads.Groups)
+
+	// Output payloads and exit if only a dry run
+	if dryRun {
+		discoverStaticDryRun(cmd, payloads)
+		exitWithStatus(0)
+	}
+
+	// Send Component requests
+	// NOTE: These are sent *before* the RedfishEndpoints so the
+	// user-specified NIDs get used instead of the SMD-generated
+	// ones. The NIDs generated by SMD assume starting at 1 and
+	// increment up in the order added.
+	overwrite := cmd.Flag("overwrite").Changed
+	var errs discoverSendErrors
+	if batch != nil {
+		// Send components, redfish endpoints, and ethernet
+		// interfaces in batches, one kind after the other
+		errs = discoverSendBatches(smdClient, payloads, overwrite, *batch)
+	} else {
+		if len(payloads.Components.Components) > 0 {
+			errs.comps = discoverSendComponents(smdClient, payloads.Components, overwrite)
+		}
+
+		// Send RedfishEndpoint requests
+		if len(payloads.RedfishEndpoints.RedfishEndpoints) > 0 {
+			errs.rfes = discoverSendRedfishEndpoints(smdClient, payloads.RedfishEndpoints, overwrite)
+		}
+
+		// Send EthernetInterfaces to SMD if discoverVersion is 1 (err
+		// handled in cmd.Args)
+		if discoveryVersion == discover.DiscoveryMethodV1 && len(payloads.EthernetInterfaces) > 0 {
+			errs.ifaces = discoverSendEthernetInterfaces(smdClient, payloads.EthernetInterfaces, overwrite)
+		}
+
+		// Send the hardware inventory of the nodes that describe
+		// their hardware
+		if len(payloads.HardwareInventory) > 0 {
+			errs.hw = discoverSendHardware(smdClient, payloads.HardwareInventory)
+		}
+	}
+
+	// Push BMC credentials for services that read them from Vault
+	if vaultClient != nil {
+		errs.creds = discoverPushBMCCredentials(cmd, vaultClient, vaultToken, payloads.RedfishEndpoints)
+	}
+
+	// Add groups and components to those groups
+	if cmd.Flag("sync-groups").Changed || cmd.Flag("prune-groups").Changed {
+		errs.groups = discoverSyncGroups(smdClient, payloads.Groups, cmd.Flag("prune-groups").Changed)
+	} else {
+		errs.groups = discoverSendGroups(smdClient, payloads.Groups, overwrite)
+	}
+
+	// Record the kinds of records that were all sent, so that those
+	// that failed are sent again next time
+	if state != nil {
+		var ok discover.Payloads
+		if !errs.comps {
+			ok.Components = sent.Components
+		}
+		if !errs.rfes {
+			ok.RedfishEndpoints = sent.RedfishEndpoints
+		}
+		if !errs.ifaces {
+			ok.EthernetInterfaces = sent.EthernetInterfaces
+		}
+		if !errs.hw {
+			ok.HardwareInventory = sent.HardwareInventory
+		}
+		state.Sent(smdBaseURI, ok)
+		if err := state.Save(stateFile); err != nil {
+			log.Logger.Warn().Err(err).Msgf("failed to save send state to %s, all records will be sent again next time", stateFile)
+		}
+	}
+
+	// Notify user if any request errors occurred
+	exitWithStatus(discoverSendStatus(cmd, errs))
+}
+
+// discoverLoadSendState returns the path passed with --state-file and the send
+// state read from it, or nil if cmd has no such flag or it was not passed. If
+// the send state cannot be read or --force is passed without --state-file, the
+// program exits.
+func discoverLoadSendState(cmd *cobra.Command) (string, discover.SendState) {
+	if cmd.Flags().Lookup("state-file") == nil {
+		return "", nil
+	}
+	path, err := cmd.Flags().GetString("state-file")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --state-file")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if path == "" {
+		if cmd.Flag("force").Changed {
+			log.Logger.Error().Msg("--force can only be used with --state-file")
 			logHelpError(cmd)
 			os.Exit(1)
 		}
+		return "", nil
+	}
+	state, err := discover.LoadSendState(path)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to load send state")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
 
-		// Check if a CA certificate was passed and load it into client if valid
-		useCACert(smdClient.OchamiClient)
+	return path, state
+}
+
+// discoverAnnotate stamps the names of the redfish endpoints in rfes and the
+// descriptions of groups with who created them (see annotate). Either can be
+// nil.
+func discoverAnnotate(cmd *cobra.Command, rfes *smd.RedfishEndpointSliceV2, groups []smd.Group) {
+	if rfes != nil {
+		for i := range rfes.RedfishEndpoints {
+			rfes.RedfishEndpoints[i].Name = annotate(cmd, rfes.RedfishEndpoints[i].Name)
+		}
+	}
+	for i := range groups {
+		groups[i].Description = annotate(cmd, groups[i].Description)
+	}
+}
 
-		if cmd.Flag("overwrite").Changed {
-			log.Logger.Warn().Msg("--overwrite passed; overwriting any existing data")
+// discoverStaticDryRun prints payloads, or writes each of them to a file in
+// --output-dir if it is passed, with the passwords of the redfish endpoints
+// redacted.
+func discoverStaticDryRun(cmd *cobra.Command, payloads discover.Payloads) {
+	rfes := make([]smd.RedfishEndpointV2, len(payloads.RedfishEndpoints.RedfishEndpoints))
+	for i, rfe := range payloads.RedfishEndpoints.RedfishEndpoints {
+		if rfe.Password != "" {
+			rfe.Password = "REDACTED"
 		}
+		rfes[i] = rfe
+	}
+	payloads.RedfishEndpoints.RedfishEndpoints = rfes
 
-		// Read data from file or stdin
-		nodes := discover.NodeList{}
-		if cmd.Flag("data").Changed {
-			handlePayload(cmd, &nodes)
+	dir, err := cmd.Flags().GetString("output-dir")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --output-dir")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if dir == "" {
+		if outBytes, err := format.MarshalData(payloads, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
 		} else {
-			handlePayloadStdin(cmd, &nodes)
+			fmt.Println(string(outBytes))
 		}
-		log.Logger.Debug().Msgf("read %d nodes", len(nodes.Nodes))
-		log.Logger.Debug().Msgf("nodes: %s", nodes)
+		return
+	}
 
-		// Put together payload for different endpoints
-		log.Logger.Debug().Msg("generating redfish structures to send to SMD")
-		comps, rfes, ifaces, err := discover.DiscoveryInfoV2(smdBaseURI, nodes)
+	ext := "json"
+	if formatOutput == format.DataFormatYaml {
+		ext = "yaml"
+	}
+	files := []struct {
+		name string
+		data any
+	}{
+		{"components", payloads.Components},
+		{"redfish-endpoints", payloads.RedfishEndpoints},
+		{"groups", payloads.Groups},
+	}
+	if payloads.EthernetInterfaces != nil {
+		files = append(files, struct {
+			name string
+			data any
+		}{"ethernet-interfaces", payloads.EthernetInterfaces})
+	}
+	if payloads.HardwareInventory != nil {
+		files = append(files, struct {
+			name string
+			data any
+		}{"hardware-inventory", payloads.HardwareInventory})
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Logger.Error().Err(err).Msgf("failed to create %s", dir)
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	for _, f := range files {
+		outBytes, err := format.MarshalData(f.data, formatOutput)
 		if err != nil {
-			log.Logger.Error().Err(err).Msg("failed to construct structures to send to SMD")
+			log.Logger.Error().Err(err).Msgf("failed to format %s", f.name)
 			logHelpError(cmd)
 			os.Exit(1)
 		}
-		log.Logger.Debug().Msgf("generated redfish structures: %v", rfes.RedfishEndpoints)
-
-		// Send Component requests
-		// NOTE: These are sent *before* the RedfishEndpoints so the
-		// user-specified NIDs get used instead of the SMD-generated
-		// ones. The NIDs generated by SMD assume starting at 1 and
-		// increment up in the order added.
-		compErrorsOccurred := false
-		if cmd.Flag("overwrite").Changed {
-			// Send a PUT if --overwrite specified to overwrite any existing components
-			_, errs, err := smdClient.PutComponents(comps, token)
-			if err != nil {
-				log.Logger.Error().Err(err).Msg("failed to add/overwrite components in SMD")
-				compErrorsOccurred = true
-			}
-			for _, err := range errs {
-				if err != nil {
-					var errMsg string
-					if errors.Is(err, client.UnsuccessfulHTTPError) {
-						errMsg = "SMD component request yielded unsuccessful HTTP response"
-					} else {
-						errMsg = "failed to add/overwrite component in SMD"
-					}
-					log.Logger.Error().Err(err).Msg(errMsg)
-					compErrorsOccurred = true
-				}
-			}
+		path := filepath.Join(dir, f.name+"."+ext)
+		if err := os.WriteFile(path, append(outBytes, '\n'), 0644); err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to write %s", path)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	}
+	log.Logger.Info().Msgf("wrote %d file(s) to %s", len(files), dir)
+}
 
-			// The SMD Components API does not modify the NID for
-			// PUTs. Thus, we explicitly do it with a PATCH to a
-			// specific endpoint that does it.
-			if _, err := smdClient.PatchComponentsNID(comps, token); err != nil {
-				log.Logger.Error().Err(err).Msg("failed to update NIDs for components in SMD")
-				compErrorsOccurred = true
-			}
-		} else {
-			// Otherwise send a normal POST
-			_, err = smdClient.PostComponents(comps, token)
+// discoverSendComponents sends the components in comps to SMD, overwriting
+// existing ones if overwrite is true, and returns whether any request failed.
+func discoverSendComponents(smdClient *smd.SMDClient, comps smd.ComponentSlice, overwrite bool) bool {
+	var errorsOccurred bool
+	if overwrite {
+		// Send a PUT if --overwrite specified to overwrite any existing components
+		_, errs, err := smdClient.PutComponents(comps, token)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to add/overwrite components in SMD")
+			errorsOccurred = true
+		}
+		for _, err := range errs {
 			if err != nil {
 				var errMsg string
 				if errors.Is(err, client.UnsuccessfulHTTPError) {
 					errMsg = "SMD component request yielded unsuccessful HTTP response"
 				} else {
-					errMsg = "failed to add components to SMD"
+					errMsg = "failed to add/overwrite component in SMD"
 				}
 				log.Logger.Error().Err(err).Msg(errMsg)
-				compErrorsOccurred = true
+				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 
-		// Send RedfishEndpoint requests
-		var (
-			rfeErrorsOccurred bool = false
-			rfeHenvs          []client.HTTPEnvelope
-			rfeErrs           []error
-			rfeErr            error
-		)
-		if cmd.Flag("overwrite").Changed {
-			// SMD's RedfishEndpoint API for PUT behaves more like
-			// PATCH. In other words, the RedfishEndpoint must exist
-			// _first_ before PUTting. This means that, to get
-			// normal PUT behavior, we have to first try to POST,
-			// then, if 409 is returned, try to PUT.
-			for _, rfe := range rfes.RedfishEndpoints {
-				// Attempt to POST the redfish endpoint
-				rfeListWrapper := smd.RedfishEndpointSliceV2{
-					RedfishEndpoints: []smd.RedfishEndpointV2{rfe},
-				}
-				rfeHenvs, rfeErrs, rfeErr = smdClient.PostRedfishEndpointsV2(rfeListWrapper, token)
-
-				if rfeErr != nil {
-					// An error in the function occurred,
-					// err for this redfish endpoint and
-					// move on.
-					log.Logger.Error().Err(rfeErr).Msg("failed to add redfish endpoint to SMD")
-					rfeErrorsOccurred = true
-					continue
-				}
+		// The SMD Components API does not modify the NID for
+		// PUTs. Thus, we explicitly do it with a PATCH to a
+		// specific endpoint that does it.
+		if _, err := smdClient.PatchComponentsNID(comps, token); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to update NIDs for components in SMD")
+			errorsOccurred = true
+		}
+	} else {
+		// Otherwise send a normal POST
+		_, err := smdClient.PostComponents(comps, token)
+		if err != nil {
+			var errMsg string
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				errMsg = "SMD component request yielded unsuccessful HTTP response"
+			} else {
+				errMsg = "failed to add components to SMD"
+			}
+			log.Logger.Error().Err(err).Msg(errMsg)
+			errorsOccurred = true
+		}
+	}
 
-				if rfeErrs[0] != nil {
-					// An HTTP error occurred
-					if errors.Is(rfeErrs[0], client.UnsuccessfulHTTPError) {
-						if rfeHenvs[0].StatusCode == 409 {
-							// RFE exists, PUT it
-							log.Logger.Info().Msgf("redfish endpoint %s exists, attempting to update it", rfe.ID)
-							_, putErrs, putErr := smdClient.PutRedfishEndpointsV2(rfeListWrapper, token)
-							if putErr != nil {
-								log.Logger.Error().Err(putErr).Msg("failed to update existing redfish endpoint in SMD")
-								rfeErrorsOccurred = true
-								continue
-							}
-							if putErrs[0] != nil {
-								var errMsg string
-								if errors.Is(putErrs[0], client.UnsuccessfulHTTPError) {
-									errMsg = "SMD redfish endpoint PUT yielded unsuccessful HTTP response"
-								} else {
-									errMsg = "failed to update existing redfish endpoint in SMD"
-								}
-								log.Logger.Error().Err(putErrs[0]).Msg(errMsg)
-								rfeErrorsOccurred = true
-								continue
+	return errorsOccurred
+}
+
+// discoverSendRedfishEndpoints sends the redfish endpoints in rfes to SMD,
+// overwriting existing ones if overwrite is true, and returns whether any
+// request failed.
+func discoverSendRedfishEndpoints(smdClient *smd.SMDClient, rfes smd.RedfishEndpointSliceV2, overwrite bool) bool {
+	var (
+		errorsOccurred bool
+		rfeHenvs       []client.HTTPEnvelope
+		rfeErrs        []error
+		rfeErr         error
+	)
+	if overwrite {
+		// SMD's RedfishEndpoint API for PUT behaves more like
+		// PATCH. In other words, the RedfishEndpoint must exist
+		// _first_ before PUTting. This means that, to get
+		// normal PUT behavior, we have to first try to POST,
+		// then, if 409 is returned, try to PUT.
+		for _, rfe := range rfes.RedfishEndpoints {
+			// Attempt to POST the redfish endpoint
+			rfeListWrapper := smd.RedfishEndpointSliceV2{
+				RedfishEndpoints: []smd.RedfishEndpointV2{rfe},
+			}
+			rfeHenvs, rfeErrs, rfeErr = smdClient.PostRedfishEndpointsV2(rfeListWrapper, token)
+
+			if rfeErr != nil {
+				// An error in the function occurred,
+				// err for this redfish endpoint and
+				// move on.
+				log.Logger.Error().Err(rfeErr).Msg("failed to add redfish endpoint to SMD")
+				errorsOccurred = true
+				continue
+			}
+
+			if rfeErrs[0] != nil {
+				// An HTTP error occurred
+				if errors.Is(rfeErrs[0], client.UnsuccessfulHTTPError) {
+					if rfeHenvs[0].StatusCode == 409 {
+						// RFE exists, PUT it
+						log.Logger.Info().Msgf("redfish endpoint %s exists, attempting to update it", rfe.ID)
+						_, putErrs, putErr := smdClient.PutRedfishEndpointsV2(rfeListWrapper, token)
+						if putErr != nil {
+							log.Logger.Error().Err(putErr).Msg("failed to update existing redfish endpoint in SMD")
+							errorsOccurred = true
+							continue
+						}
+						if putErrs[0] != nil {
+							var errMsg string
+							if errors.Is(putErrs[0], client.UnsuccessfulHTTPError) {
+								errMsg = "SMD redfish endpoint PUT yielded unsuccessful HTTP response"
+							} else {
+								errMsg = "failed to update existing redfish endpoint in SMD"
 							}
-						} else {
-							// Some other HTTP error occurred, err
-							log.Logger.Error().Err(rfeErrs[0]).Msg("SMD redfish endpoint POST yielded non-409 (duplicate) failure")
-							rfeErrorsOccurred = true
+							log.Logger.Error().Err(putErrs[0]).Msg(errMsg)
+							errorsOccurred = true
 							continue
 						}
 					} else {
-						log.Logger.Error().Err(rfeErrs[0]).Msg("failed to add redfish endpoint to SMD")
-						rfeErrorsOccurred = true
+						// Some other HTTP error occurred, err
+						log.Logger.Error().Err(rfeErrs[0]).Msg("SMD redfish endpoint POST yielded non-409 (duplicate) failure")
+						errorsOccurred = true
 						continue
 					}
+				} else {
+					log.Logger.Error().Err(rfeErrs[0]).Msg("failed to add redfish endpoint to SMD")
+					errorsOccurred = true
+					continue
 				}
 			}
-		} else {
-			// --overwrite was not passed, perform regular POST.
-			_, rfeErrs, rfeErr = smdClient.PostRedfishEndpointsV2(rfes, token)
-			if rfeErr != nil {
-				log.Logger.Error().Err(rfeErr).Msg("failed to add redfish endpoints to SMD")
-				rfeErrorsOccurred = true
-			}
-			for _, err := range rfeErrs {
-				if err != nil {
-					var errMsg string
-					if errors.Is(err, client.UnsuccessfulHTTPError) {
-						errMsg = "SMD redfish endpoint request yielded unsuccessful HTTP response"
+		}
+	} else {
+		// --overwrite was not passed, perform regular POST.
+		_, rfeErrs, rfeErr = smdClient.PostRedfishEndpointsV2(rfes, token)
+		if rfeErr != nil {
+			log.Logger.Error().Err(rfeErr).Msg("failed to add redfish endpoints to SMD")
+			errorsOccurred = true
+		}
+		for _, err := range rfeErrs {
+			if err != nil {
+				var errMsg string
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					errMsg = "SMD redfish endpoint request yielded unsuccessful HTTP response"
+				} else {
+					if overwrite {
+						errMsg = "failed to add/overwrite redfish endpoint in SMD"
 					} else {
-						if cmd.Flag("overwrite").Changed {
-							errMsg = "failed to add/overwrite redfish endpoint in SMD"
-						} else {
-							errMsg = "failed to add redfish endpoint to SMD"
-						}
+						errMsg = "failed to add redfish endpoint to SMD"
 					}
-					log.Logger.Error().Err(err).Msg(errMsg)
-					rfeErrorsOccurred = true
 				}
+				log.Logger.Error().Err(err).Msg(errMsg)
+				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(rfeErrs)
+	}
 
-		// Send EthernetInterface requests
-		var (
-			ifaceErrorsOccurred bool = false
-			ifaceHenvs          []client.HTTPEnvelope
-			ifaceErrs           []error
-			ifaceErr            error
-		)
-		// Get discovery version value (err handled in cmd.Args).
-		// Send EthernetInterfaces to SMD if discoverVersion is 1.
-		if discoveryVersion == discover.DiscoveryMethodV1 {
-			if cmd.Flag("overwrite").Changed {
-				// SMD's EthernetInterface API does not allow the PUT
-				// method. Instead, we loop over each ethernet interface
-				// to add and attempt a POST. If a 409 is returned for
-				// that interface, a PATCH is attempted. Otherwise, an
-				// error has occurred.
-				for _, iface := range ifaces {
-					// Attempt to POST the ethernet interface
-					ifaceListWrapper := []smd.EthernetInterface{iface}
-					ifaceHenvs, ifaceErrs, ifaceErr = smdClient.PostEthernetInterfaces(ifaceListWrapper, token)
-
-					if ifaceErr != nil {
-						// An error in the function occurred, err for
-						// this interface and move on.
-						log.Logger.Error().Err(ifaceErr).Msg("failed to add ethernet interface to SMD")
-						ifaceErrorsOccurred = true
-						continue
-					}
+	return errorsOccurred
+}
+
+// discoverSendHardware sends the hardware inventory entries in hw to SMD, which
+// replaces existing ones, and returns whether the request failed.
+func discoverSendHardware(smdClient *smd.SMDClient, hw []smd.HWInvByLoc) bool {
+	if _, err := smdClient.PostHardware(hw, token); err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("SMD hardware inventory request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to add hardware inventory to SMD")
+		}
+		return true
+	}
+
+	return false
+}
+
+// discoverSendEthernetInterfaces sends the ethernet interfaces in ifaces to
+// SMD, overwriting existing ones if overwrite is true, and returns whether any
+// request failed.
+func discoverSendEthernetInterfaces(smdClient *smd.SMDClient, ifaces []smd.EthernetInterface, overwrite bool) bool {
+	var (
+		errorsOccurred bool
+		ifaceHenvs     []client.HTTPEnvelope
+		ifaceErrs      []error
+		ifaceErr       error
+	)
+	if overwrite {
+		// SMD's EthernetInterface API does not allow the PUT
+		// method. Instead, we loop over each ethernet interface
+		// to add and attempt a POST. If a 409 is returned for
+		// that interface, a PATCH is attempted. Otherwise, an
+		// error has occurred.
+		for _, iface := range ifaces {
+			// Attempt to POST the ethernet interface
+			ifaceListWrapper := []smd.EthernetInterface{iface}
+			ifaceHenvs, ifaceErrs, ifaceErr = smdClient.PostEthernetInterfaces(ifaceListWrapper, token)
+
+			if ifaceErr != nil {
+				// An error in the function occurred, err for
+				// this interface and move on.
+				log.Logger.Error().Err(ifaceErr).Msg("failed to add ethernet interface to SMD")
+				errorsOccurred = true
+				continue
+			}
 
-					if ifaceErrs[0] != nil {
-						// An HTTP error occurred
-						if errors.Is(ifaceErrs[0], client.UnsuccessfulHTTPError) {
-							if ifaceHenvs[0].StatusCode == 409 {
-								// Ethernet interface exists, patch it
-								log.Logger.Info().Msgf("ethernet interface with MAC address %s exists, attempting to update it", iface.MACAddress)
-								_, patchErrs, patchErr := smdClient.PatchEthernetInterfaces(ifaceListWrapper, token)
-								if patchErr != nil {
-									log.Logger.Error().Err(patchErr).Msg("failed to update existing ethernet interface in SMD")
-									ifaceErrorsOccurred = true
-									continue
-								}
-								if patchErrs[0] != nil {
-									var errMsg string
-									if errors.Is(patchErrs[0], client.UnsuccessfulHTTPError) {
-										errMsg = "SMD ethernet interface PATCH yielded unsuccessful HTTP response"
-									} else {
-										errMsg = "failed to update existing ethernet interface in SMD"
-									}
-									log.Logger.Error().Err(patchErrs[0]).Msg(errMsg)
-									ifaceErrorsOccurred = true
-									continue
-								}
+			if ifaceErrs[0] != nil {
+				// An HTTP error occurred
+				if errors.Is(ifaceErrs[0], client.UnsuccessfulHTTPError) {
+					if ifaceHenvs[0].StatusCode == 409 {
+						// Ethernet interface exists, patch it
+						log.Logger.Info().Msgf("ethernet interface with MAC address %s exists, attempting to update it", iface.MACAddress)
+						_, patchErrs, patchErr := smdClient.PatchEthernetInterfaces(ifaceListWrapper, token)
+						if patchErr != nil {
+							log.Logger.Error().Err(patchErr).Msg("failed to update existing ethernet interface in SMD")
+							errorsOccurred = true
+							continue
+						}
+						if patchErrs[0] != nil {
+							var errMsg string
+							if errors.Is(patchErrs[0], client.UnsuccessfulHTTPError) {
+								errMsg = "SMD ethernet interface PATCH yielded unsuccessful HTTP response"
 							} else {
-								// Some other HTTP error occurred, err
-								log.Logger.Error().Err(ifaceErrs[0]).Msg("SMD ethernet interface POST yield non-409 (duplicate) failure")
-								ifaceErrorsOccurred = true
-								continue
+								errMsg = "failed to update existing ethernet interface in SMD"
 							}
-						} else {
-							log.Logger.Error().Err(ifaceErrs[0]).Msg("failed to add ethernet interface to SMD")
-							ifaceErrorsOccurred = true
+							log.Logger.Error().Err(patchErrs[0]).Msg(errMsg)
+							errorsOccurred = true
 							continue
 						}
+					} else {
+						// Some other HTTP error occurred, err
+						log.Logger.Error().Err(ifaceErrs[0]).Msg("SMD ethernet interface POST yield non-409 (duplicate) failure")
+						errorsOccurred = true
+						continue
 					}
-				}
-			} else {
-				// --overwrite was not passed, perform regular POST.
-				_, ifaceErrs, ifaceErr = smdClient.PostEthernetInterfaces(ifaces, token)
-				if ifaceErr != nil {
-					log.Logger.Error().Err(ifaceErr).Msg("failed to add ethernet interfaces to SMD")
-					ifaceErrorsOccurred = true
-				}
-				for _, err := range ifaceErrs {
-					if err != nil {
-						var errMsg string
-						if errors.Is(err, client.UnsuccessfulHTTPError) {
-							errMsg = "SMD ethernet interface request yielded unsuccessful HTTP response"
-						} else {
-							errMsg = "failed to add ethernet interface to SMD"
-						}
-						log.Logger.Error().Err(err).Msg(errMsg)
-						ifaceErrorsOccurred = true
-					}
-				}
-			}
-		}
-
-		// Put together list of groups to add and which components to add to those groups
-		groupsToAdd := make(map[string]smd.Group)
-		for _, node := range nodes.Nodes {
-			// node.Group IS DEPRECATED IN FAVOR OF node.groups. This block should be
-			// deleted when node.Group is removed.
-			//
-			// For now, we merge node.Group with node.Groups. Since we use a dictionary for
-			// deduplication, this is trivial.
-			if node.Group != "" {
-				if len(strings.Trim(node.Name, " \t")) == 0 {
-					log.Logger.Warn().Msgf("node %s contains 'group', which is deprecated; use 'groups' instead", node.Xname)
-				} else {
-					log.Logger.Warn().Msgf("node %s (%s) contains 'group', which is deprecated; use 'groups' instead", node.Xname, node.Name)
-				}
-				if g, ok := groupsToAdd[node.Group]; !ok {
-					// Group doesn't exist yet, populate groupsToAdd with it
-					newGroup := smd.Group{
-						Label:       node.Group,
-						Description: fmt.Sprintf("The %s group", node.Group),
-					}
-					groupsToAdd[node.Group] = discover.AddMemberToGroup(newGroup, node.Xname)
 				} else {
-					// Update group membership with new node in groupsToAdd map
-					groupsToAdd[node.Group] = discover.AddMemberToGroup(g, node.Xname)
+					log.Logger.Error().Err(ifaceErrs[0]).Msg("failed to add ethernet interface to SMD")
+					errorsOccurred = true
+					continue
 				}
 			}
-			for _, group := range node.Groups {
-				if g, ok := groupsToAdd[group]; !ok {
-					// Group doesn't exist yet, populate groupsToAdd with it
-					newGroup := smd.Group{
-						Label:       group,
-						Description: fmt.Sprintf("The %s group", group),
-					}
-					groupsToAdd[group] = discover.AddMemberToGroup(newGroup, node.Xname)
+		}
+	} else {
+		// --overwrite was not passed, perform regular POST.
+		_, ifaceErrs, ifaceErr = smdClient.PostEthernetInterfaces(ifaces, token)
+		if ifaceErr != nil {
+			log.Logger.Error().Err(ifaceErr).Msg("failed to add ethernet interfaces to SMD")
+			errorsOccurred = true
+		}
+		for _, err := range ifaceErrs {
+			if err != nil {
+				var errMsg string
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					errMsg = "SMD ethernet interface request yielded unsuccessful HTTP response"
 				} else {
-					// Update group membership with new node in groupsToAdd map
-					groupsToAdd[group] = discover.AddMemberToGroup(g, node.Xname)
+					errMsg = "failed to add ethernet interface to SMD"
 				}
+				log.Logger.Error().Err(err).Msg(errMsg)
+				errorsOccurred = true
 			}
 		}
-		groupList := make([]smd.Group, len(groupsToAdd))
-		var idx = 0
-		for _, g := range groupsToAdd {
-			groupList[idx] = g
-			idx++
-		}
-
-		// Add groups and components to those groups
-		var (
-			groupErrorsOccurred bool = false
-			groupHenvs          []client.HTTPEnvelope
-			groupErrs           []error
-			groupErr            error
-		)
-		if cmd.Flag("overwrite").Changed {
-			// SMD's groups API does not allow the PUT method.
-			// Instead, we loop over each group to add and attempt a
-			// POST. Iff a 409 is returned for that interface, a
-			// PATCH is attempted. Otherwise, an error has occurred.
-			for _, group := range groupList {
-				// Attempt to POST the group
-				groupListWrapper := []smd.Group{group}
-				groupHenvs, groupErrs, groupErr = smdClient.PostGroups(groupListWrapper, token)
-
-				if groupErr != nil {
-					// An error in the function occurred,
-					// err for this group and move on.
-					log.Logger.Error().Err(groupErr).Msg("failed to add group to SMD")
-					groupErrorsOccurred = true
-					continue
-				}
+		reportNotAttempted(ifaceErrs)
+	}
 
-				if groupErrs[0] != nil {
-					// An HTTP error occurred
-					if errors.Is(groupErrs[0], client.UnsuccessfulHTTPError) {
-						if groupHenvs[0].StatusCode == 409 {
-							// Group exists, patch it
-							log.Logger.Info().Msgf("group %s exists, attempting to update it", group.Label)
-							_, patchErrs, patchErr := smdClient.PatchGroups(groupListWrapper, token)
-							if patchErr != nil {
-								log.Logger.Error().Err(patchErr).Msg("failed to update existing group in SMD")
-								groupErrorsOccurred = true
-								continue
-							}
-							if patchErrs[0] != nil {
-								var errMsg string
-								if errors.Is(patchErrs[0], client.UnsuccessfulHTTPError) {
-									errMsg = "SMD group PATCH yielded unsuccessful HTTP response"
-								} else {
-									errMsg = "failed to update existing group in SMD"
-								}
-								log.Logger.Error().Err(patchErrs[0]).Msg(errMsg)
-								groupErrorsOccurred = true
-								continue
+	return errorsOccurred
+}
+
+// discoverSendGroups adds the groups in groups, along with their members, to
+// SMD, updating existing ones if overwrite is true, and returns whether any
+// request failed.
+func discoverSendGroups(smdClient *smd.SMDClient, groups []smd.Group, overwrite bool) bool {
+	var (
+		errorsOccurred bool
+		groupHenvs     []client.HTTPEnvelope
+		groupErrs      []error
+		groupErr       error
+	)
+	if overwrite {
+		// SMD's groups API does not allow the PUT method.
+		// Instead, we loop over each group to add and attempt a
+		// POST. Iff a 409 is returned for that interface, a
+		// PATCH is attempted. Otherwise, an error has occurred.
+		for _, group := range groups {
+			// Attempt to POST the group
+			groupsWrapper := []smd.Group{group}
+			groupHenvs, groupErrs, groupErr = smdClient.PostGroups(groupsWrapper, token)
+
+			if groupErr != nil {
+				// An error in the function occurred,
+				// err for this group and move on.
+				log.Logger.Error().Err(groupErr).Msg("failed to add group to SMD")
+				errorsOccurred = true
+				continue
+			}
+
+			if groupErrs[0] != nil {
+				// An HTTP error occurred
+				if errors.Is(groupErrs[0], client.UnsuccessfulHTTPError) {
+					if groupHenvs[0].StatusCode == 409 {
+						// Group exists, patch it
+						log.Logger.Info().Msgf("group %s exists, attempting to update it", group.Label)
+						_, patchErrs, patchErr := smdClient.PatchGroups(groupsWrapper, token)
+						if patchErr != nil {
+							log.Logger.Error().Err(patchErr).Msg("failed to update existing group in SMD")
+							errorsOccurred = true
+							continue
+						}
+						if patchErrs[0] != nil {
+							var errMsg string
+							if errors.Is(patchErrs[0], client.UnsuccessfulHTTPError) {
+								errMsg = "SMD group PATCH yielded unsuccessful HTTP response"
+							} else {
+								errMsg = "failed to update existing group in SMD"
 							}
-						} else {
-							// Some other HTTP error occurred, err
-							log.Logger.Error().Err(groupErrs[0]).Msg("SMD group POST yielded non-409 (duplicate) failure")
-							groupErrorsOccurred = true
+							log.Logger.Error().Err(patchErrs[0]).Msg(errMsg)
+							errorsOccurred = true
 							continue
 						}
 					} else {
-						log.Logger.Error().Err(groupErrs[0]).Msg("failed to add group to SMD")
-						groupErrorsOccurred = true
+						// Some other HTTP error occurred, err
+						log.Logger.Error().Err(groupErrs[0]).Msg("SMD group POST yielded non-409 (duplicate) failure")
+						errorsOccurred = true
 						continue
 					}
+				} else {
+					log.Logger.Error().Err(groupErrs[0]).Msg("failed to add group to SMD")
+					errorsOccurred = true
+					continue
 				}
 			}
-		} else {
-			_, groupErrs, groupErr = smdClient.PostGroups(groupList, token)
-			if groupErr != nil {
-				log.Logger.Error().Err(groupErr).Msg("failed to add groups to SMD")
-				groupErrorsOccurred = true
-			}
-			for _, err := range groupErrs {
-				if err != nil {
-					var errMsg string
-					if errors.Is(err, client.UnsuccessfulHTTPError) {
-						errMsg = "SMD groups request yielded unsuccessful HTTP response"
-					} else {
-						errMsg = "failed to add groups to SMD"
-					}
-					log.Logger.Error().Err(err).Msg(errMsg)
-					groupErrorsOccurred = true
+		}
+	} else {
+		_, groupErrs, groupErr = smdClient.PostGroups(groups, token)
+		if groupErr != nil {
+			log.Logger.Error().Err(groupErr).Msg("failed to add groups to SMD")
+			errorsOccurred = true
+		}
+		for _, err := range groupErrs {
+			if err != nil {
+				var errMsg string
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					errMsg = "SMD groups request yielded unsuccessful HTTP response"
+				} else {
+					errMsg = "failed to add groups to SMD"
 				}
+				log.Logger.Error().Err(err).Msg(errMsg)
+				errorsOccurred = true
+			}
+		}
+		reportNotAttempted(groupErrs)
+	}
+
+	return errorsOccurred
+}
+
+// discoverSyncGroups syncs the groups in groups with those in SMD, creating the
+// missing ones and adding the members of the existing ones that are not in
+// them, as well as removing those not in groups if prune is true. It returns
+// whether any request failed.
+func discoverSyncGroups(smdClient *smd.SMDClient, groups []smd.Group, prune bool) bool {
+	res, err := ops.SyncGroups(context.Background(), smdClient, token, groups, prune)
+	logFailed := func(results ops.Results, msg string) {
+		for _, r := range results.Failed() {
+			if errors.Is(r.Err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(r.Err).Msgf("SMD %s yielded unsuccessful HTTP response", msg)
+			} else {
+				log.Logger.Error().Err(r.Err).Msgf("failed to %s", msg)
 			}
 		}
+		reportNotAttempted(results.Errs())
+	}
+	logFailed(res.Created, "group request")
+	for _, g := range groups {
+		if members := res.Add[g.Label]; len(members) > 0 {
+			log.Logger.Info().Msgf("group %s: adding %d member(s)", g.Label, len(members))
+			logFailed(res.Added[g.Label], "group member request for group "+g.Label)
+		}
+		if members := res.Remove[g.Label]; len(members) > 0 {
+			log.Logger.Info().Msgf("group %s: removing %d member(s) not in payload: %v", g.Label, len(members), members)
+			logFailed(res.Removed[g.Label], "group member deletion for group "+g.Label)
+		}
+		mc := res.Verified[g.Label]
+		if mc.Concurrent() {
+			log.Logger.Warn().Msgf("members of group %s changed concurrently: added [%s], removed [%s]", g.Label, strings.Join(mc.Added, ","), strings.Join(mc.Removed, ","))
+		}
+		if mc.Conflict() {
+			log.Logger.Error().Msgf("sync of members of group %s was undone concurrently for member(s) %s", g.Label, strings.Join(mc.Lost, ","))
+		}
+	}
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to sync groups with SMD")
+		return true
+	}
+
+	return res.Failed()
+}
+
+// discoverSendErrors records which kinds of discovery requests failed.
+type discoverSendErrors struct {
+	comps, rfes, ifaces, hw, groups, creds bool
+}
+
+// discoverSendStatus warns about each kind of discovery requests that failed
+// in errs and returns the exit status to exit with.
+func discoverSendStatus(cmd *cobra.Command, errs discoverSendErrors) int {
+	exitStatus := 0
+	if errs.comps || errs.rfes || errs.ifaces || errs.hw || errs.groups || errs.creds {
+		logHelpError(cmd)
+	}
+	if errs.comps {
+		log.Logger.Warn().Msg("component requests completed with errors")
+		exitStatus = 1
+	}
+	if errs.rfes {
+		log.Logger.Warn().Msg("redfish endpoint requests completed with errors")
+		exitStatus = 1
+	}
+	if errs.ifaces {
+		log.Logger.Warn().Msg("ethernet interface requests completed with errors")
+		exitStatus = 1
+	}
+	if errs.hw {
+		log.Logger.Warn().Msg("hardware inventory requests completed with errors")
+		exitStatus = 1
+	}
+	if errs.groups {
+		log.Logger.Warn().Msg("group requests completed with errors")
+		exitStatus = 1
+	}
+	if errs.creds {
+		log.Logger.Warn().Msg("pushing BMC credentials to Vault completed with errors")
+		exitStatus = 1
+	}
+	if len(discoverSkipped) > 0 {
+		counts := discover.CountWarnings(discoverSkipped)
+		for _, w := range discoverSkipped {
+			log.Logger.Warn().Msgf("not sent: %s", w)
+		}
+		log.Logger.Warn().Msgf("%d malformed node(s) and %d interface(s) of the payload were skipped and not sent", counts[discover.WarningSkippedNode], counts[discover.WarningSkippedInterface])
+	}
 
-		// Notify user if any request errors occurred
-		exitStatus := 0
-		if compErrorsOccurred || rfeErrorsOccurred || ifaceErrorsOccurred || groupErrorsOccurred {
+	return exitStatus
+}
+
+// discoverBatchResult counts the records of a kind sent by discoverSendBatched.
+type discoverBatchResult struct {
+	kind         string
+	batches      int
+	sent, failed int
+}
+
+// discoverBatchOptions are how discoverSendBatches sends records.
+type discoverBatchOptions struct {
+	size, concurrency, retries int
+}
+
+// discoverGetBatchOptions returns the values of --batch-size, --concurrency,
+// and --retries, or nil if neither --batch-size nor --concurrency was passed
+// and records should not be sent in batches. If a value is invalid, the
+// program exits.
+func discoverGetBatchOptions(cmd *cobra.Command) *discoverBatchOptions {
+	if !cmd.Flag("batch-size").Changed && !cmd.Flag("concurrency").Changed {
+		return nil
+	}
+	var opts discoverBatchOptions
+	for _, f := range []struct {
+		name string
+		v    *int
+		min  int
+	}{
+		{"batch-size", &opts.size, 1},
+		{"concurrency", &opts.concurrency, 1},
+		{"retries", &opts.retries, 0},
+	} {
+		v, err := cmd.Flags().GetInt(f.name)
+		if err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to get value for --%s", f.name)
 			logHelpError(cmd)
+			os.Exit(1)
 		}
-		if compErrorsOccurred {
-			log.Logger.Warn().Msg("component requests completed with errors")
-			exitStatus = 1
+		if v < f.min {
+			log.Logger.Error().Msgf("--%s must be at least %d", f.name, f.min)
+			logHelpError(cmd)
+			os.Exit(1)
 		}
-		if rfeErrorsOccurred {
-			log.Logger.Warn().Msg("redfish endpoint requests completed with errors")
-			exitStatus = 1
+		*f.v = v
+	}
+
+	return &opts
+}
+
+// discoverSendBatches sends the components, redfish endpoints, (with discovery
+// version 1) ethernet interfaces, and hardware inventory in payloads to SMD in
+// batches as set by opts, retrying the records of a batch that failed. Each
+// kind is only sent once the previous one is done, so that components still
+// come first. A summary of each kind is printed to standard error, and which
+// kinds had failures is returned.
+func discoverSendBatches(smdClient *smd.SMDClient, payloads discover.Payloads, overwrite bool, opts discoverBatchOptions) discoverSendErrors {
+	send := func(kind string, n int, fn func(idx []int) []int) discoverBatchResult {
+		return discoverSendBatched(kind, n, opts.size, opts.concurrency, opts.retries, fn)
+	}
+
+	// Components and hardware inventory are sent in one request per
+	// batch. SMD takes one redfish endpoint or ethernet interface per
+	// request, so those are sent one request at a time, and only those
+	// that failed are retried.
+	var results []discoverBatchResult
+	comps := payloads.Components.Components
+	results = append(results, send("component", len(comps), func(idx []int) []int {
+		var batch smd.ComponentSlice
+		for _, i := range idx {
+			batch.Components = append(batch.Components, comps[i])
 		}
-		if ifaceErrorsOccurred {
-			log.Logger.Warn().Msg("ethernet interface requests completed with errors")
-			exitStatus = 1
+		if discoverSendComponents(smdClient, batch, overwrite) {
+			return idx
 		}
-		if groupErrorsOccurred {
-			log.Logger.Warn().Msg("group requests completed with errors")
-			exitStatus = 1
+		return nil
+	}))
+	rfes := payloads.RedfishEndpoints.RedfishEndpoints
+	results = append(results, send("redfish endpoint", len(rfes), func(idx []int) []int {
+		var failed []int
+		for _, i := range idx {
+			rfe := smd.RedfishEndpointSliceV2{RedfishEndpoints: []smd.RedfishEndpointV2{rfes[i]}}
+			if discoverSendRedfishEndpoints(smdClient, rfe, overwrite) {
+				failed = append(failed, i)
+			}
 		}
-		os.Exit(exitStatus)
-	},
+		return failed
+	}))
+	if discoveryVersion == discover.DiscoveryMethodV1 {
+		ifaces := payloads.EthernetInterfaces
+		results = append(results, send("ethernet interface", len(ifaces), func(idx []int) []int {
+			var failed []int
+			for _, i := range idx {
+				if discoverSendEthernetInterfaces(smdClient, ifaces[i:i+1], overwrite) {
+					failed = append(failed, i)
+				}
+			}
+			return failed
+		}))
+	}
+
+	if hw := payloads.HardwareInventory; len(hw) > 0 {
+		results = append(results, send("hardware location", len(hw), func(idx []int) []int {
+			var batch []smd.HWInvByLoc
+			for _, i := range idx {
+				batch = append(batch, hw[i])
+			}
+			if discoverSendHardware(smdClient, batch) {
+				return idx
+			}
+			return nil
+		}))
+	}
+
+	fmt.Fprintln(os.Stderr, "Records sent to SMD:")
+	for _, r := range results {
+		fmt.Fprintf(os.Stderr, "  %-20s %d sent, %d failed (%d batch(es))\n", r.kind+"s", r.sent, r.failed, r.batches)
+	}
+
+	var errs discoverSendErrors
+	for _, r := range results {
+		failed := r.failed > 0
+		switch r.kind {
+		case "component":
+			errs.comps = failed
+		case "redfish endpoint":
+			errs.rfes = failed
+		case "ethernet interface":
+			errs.ifaces = failed
+		case "hardware location":
+			errs.hw = failed
+		}
+	}
+
+	return errs
+}
+
+// discoverSendBatched sends n records of kind in batches of up to size records,
+// up to concurrency batches at once. fn sends the records of a batch with the
+// indices passed and returns those that failed, which are passed to it again up
+// to retries times, waiting a little longer before each retry.
+func discoverSendBatched(kind string, n, size, concurrency, retries int, fn func(idx []int) []int) discoverBatchResult {
+	batches := pool.Batches(n, size)
+	res := discoverBatchResult{kind: kind, batches: len(batches)}
+
+	// Each batch only writes its own count, read once all are done
+	failed := make([]int, len(batches))
+	pool.Run(context.Background(), len(batches), concurrency, 0, func(ctx context.Context, b int) error {
+		pending := batches[b]
+		for attempt := 0; ; attempt++ {
+			pending = fn(pending)
+			failed[b] = len(pending)
+			if len(pending) == 0 {
+				return nil
+			}
+			if attempt >= retries {
+				return fmt.Errorf("%d %s(s) failed after %d attempt(s)", len(pending), kind, attempt+1)
+			}
+			log.Logger.Warn().Msgf("batch %d of %d %ss: %d failed, retrying", b+1, len(batches), kind, len(pending))
+			time.Sleep(time.Duration(attempt+1) * 500 * time.Millisecond)
+		}
+	}, func(b int, err error) {
+		if err != nil {
+			log.Logger.Error().Err(err).Msgf("batch %d of %d %ss failed", b+1, len(batches), kind)
+		}
+	})
+	for b, batch := range batches {
+		res.sent += len(batch) - failed[b]
+		res.failed += failed[b]
+	}
+
+	return res
 }
 
 func init() {
+	discoverStaticCmd.Flags().StringArray("auto-group", []string{}, "add nodes to a group by xname pattern or NID range (<group>=<xname_regex> or <group>=nid:<ranges>, can be repeated)")
+	discoverStaticCmd.Flags().StringSlice("default-group", []string{}, "one or more groups to add all nodes to")
+	discoverStaticCmd.Flags().Bool("sync-groups", false, "create missing groups and add nodes to the groups they are declared in that exist in SMD")
+	discoverStaticCmd.Flags().Bool("prune-groups", false, "remove members of the payload's groups that the payload does not declare; implies --sync-groups")
+	discoverStaticCmd.Flags().Bool("push-bmc-creds", false, "also write the BMC credentials to Vault under discovery.bmc-creds-path for services that read them from it")
 	discoverStaticCmd.Flags().Var(&discoveryVersion, "discovery-version", "set version for discovery method to use")
+	discoverStaticCmd.Flags().Var(&discoverErrorPolicy, "on-error", "what to do with malformed nodes (fail-fast,skip-node,skip-interface)")
 	discoverStaticCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
-	discoverStaticCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	discoverStaticCmd.Flags().VarP(&discoverFormatInput, "format-input", "f", "format of input payload data ("+discover.PayloadFormatList()+")")
+	discoverStaticCmd.Flags().Bool("validate-schema", false, "check payload against the JSON Schema of the payload format before reading it (see discover schema)")
 	discoverStaticCmd.Flags().Bool("overwrite", false, "overwrite any existing information instead of failing")
+	discoverStaticCmd.Flags().String("state-file", "", "file to save the hashes of sent records to, to only send records that changed since the last run")
+	discoverStaticCmd.Flags().Bool("force", false, "with --state-file, send all records, even those that did not change since the last run")
+	discoverStaticCmd.Flags().Int("batch-size", 100, "group records into batches of this many, sending the components and hardware inventory of each in one request (see --concurrency and --retries)")
+	discoverStaticCmd.Flags().Int("concurrency", 1, "maximum number of batches to send at once")
+	discoverStaticCmd.Flags().Int("retries", 2, "with --batch-size or --concurrency, number of times to retry the records of a batch that failed")
+	discoverStaticCmd.Flags().Bool("dry-run", false, "output the payloads that would be sent to SMD without sending them")
+	discoverStaticCmd.Flags().StringP("output-dir", "o", "", "with --dry-run, directory to write a file for each payload to instead of printing them")
+	discoverStaticCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output or written to --output-dir ("+format.DataFormatList()+")")
+	discoverStaticCmd.Flags().StringSlice("scan", []string{}, "one or more subnets (CIDR) or addresses to scan for BMCs to send instead of reading payload data")
+	discoverStaticCmd.Flags().String("credentials-file", "", "with --scan, file containing BMC credentials to try")
+	discoverStaticCmd.Flags().StringP("rules", "r", "", "with --scan, file containing rules mapping BMC MAC address prefixes to xnames")
+	discoverStaticCmd.Flags().Uint16("port", 443, "with --scan, port of the Redfish service of BMCs")
+	discoverStaticCmd.Flags().Int("scan-concurrency", 32, "with --scan, maximum number of addresses to probe at once")
+	discoverStaticCmd.Flags().Duration("target-timeout", 10*time.Second, "with --scan, maximum time to spend probing each address (0 for no limit)")
+
+	discoverStaticCmd.MarkFlagsMutuallyExclusive("scan", "data")
 
-	discoverStaticCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
+	discoverStaticCmd.RegisterFlagCompletionFunc("format-input", completionDiscoverFormat)
+	discoverStaticCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 	discoverStaticCmd.RegisterFlagCompletionFunc("discovery-version", completionDiscoveryVersion)
+	discoverStaticCmd.RegisterFlagCompletionFunc("on-error", completionDiscoverErrorPolicy)
 
 	discoverCmd.AddCommand(discoverStaticCmd)
 }
@@ -0,0 +1,118 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/report"
+)
+
+// discoverValidateCmd represents the "discover validate" command
+var discoverValidateCmd = &cobra.Command{
+	Use:   "validate [-d (<data> | @<path>)] [-f <format>] [-F <format>] [--report-format <format> [--report-file <path>]]",
+	Args:  cobra.NoArgs,
+	Short: "Check a static discovery payload for problems without sending it",
+	Long: `Check a static discovery payload for problems that would otherwise
+only surface as warnings during discovery or as errors from SMD, such
+as invalid xnames, MAC addresses, or IP addresses, duplicate xnames,
+NIDs, or addresses, and nodes without interfaces or a BMC. Nothing is
+sent to SMD.
+
+The payload is read from -d, or from standard input if it is not
+passed, as with 'discover static'. Each problem is printed with the
+index and xname of its node and the path of the field (e.g.
+interfaces[0].mac_addr), and the command fails if there are any.
+Pass --report-format to output the problems as a JUnit XML or SARIF
+report for CI systems instead, or to --report-file in addition to the
+normal output.
+
+See ochami-discover(1) for more details.`,
+	Example: `  # Check a YAML payload file
+  ochami discover validate -d @nodes.yaml -f yaml
+
+  # Check a payload read from standard input, printing problems as YAML
+  ochami discover validate -F yaml < nodes.json
+
+  # Check a payload in CI, writing a JUnit XML report
+  ochami discover validate -d @nodes.json --report-format junit --report-file validate.xml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		nodes := discoverReadPayload(cmd)
+
+		errs := discover.Validate(nodes)
+		if errs == nil {
+			errs = []discover.ValidationError{}
+		}
+
+		// Print output, unless replaced by the report
+		if !writeReport(cmd, discoverValidateReport(nodes, errs)) {
+			if outBytes, err := format.MarshalData(errs, formatOutput); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			} else {
+				fmt.Println(string(outBytes))
+			}
+		}
+
+		if len(errs) > 0 {
+			log.Logger.Error().Msgf("found %d problem(s) in %d node(s)", len(errs), len(nodes.Nodes))
+			exitWithStatus(1)
+		}
+		log.Logger.Info().Msgf("payload with %d node(s) is valid", len(nodes.Nodes))
+	},
+}
+
+// discoverValidateReport returns errs as a report with a result for each node
+// of nl, so that nodes without problems are listed as passing, and one for the
+// payload as a whole if any problem is not specific to a node. The node is the
+// target of a result, so messages only name the field.
+func discoverValidateReport(nl discover.NodeList, errs []discover.ValidationError) report.Report {
+	rep := report.Report{Name: "discover validate"}
+	for idx, node := range nl.Nodes {
+		target := node.Xname
+		if target == "" {
+			target = fmt.Sprintf("node %d", idx)
+		}
+		rep.Results = append(rep.Results, report.Result{Target: target})
+	}
+	var payload *report.Result
+	for _, e := range errs {
+		res := payload
+		if e.Node >= 0 && e.Node < len(rep.Results) {
+			res = &rep.Results[e.Node]
+		} else if res == nil {
+			rep.Results = append(rep.Results, report.Result{Target: "payload"})
+			payload = &rep.Results[len(rep.Results)-1]
+			res = payload
+		}
+		msg := fmt.Sprintf("%s: %s", e.Field, e.Message)
+		if e.Value != "" {
+			msg = fmt.Sprintf("%s %q: %s", e.Field, e.Value, e.Message)
+		}
+		res.Findings = append(res.Findings, report.Finding{Rule: "invalid-payload", Level: report.LevelError, Message: msg})
+	}
+
+	return rep
+}
+
+func init() {
+	discoverValidateCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
+	discoverValidateCmd.Flags().VarP(&discoverFormatInput, "format-input", "f", "format of input payload data ("+discover.PayloadFormatList()+")")
+	discoverValidateCmd.Flags().Bool("validate-schema", false, "check payload against the JSON Schema of the payload format before reading it (see discover schema)")
+	discoverValidateCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+	discoverValidateCmd.Flags().Var(&reportFormat, "report-format", "write problems as a report for CI systems (junit,sarif)")
+	discoverValidateCmd.Flags().String("report-file", "-", "file to write report to, or - to write it to standard output instead of the normal output")
+
+	discoverValidateCmd.RegisterFlagCompletionFunc("format-input", completionDiscoverFormat)
+	discoverValidateCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+	discoverValidateCmd.RegisterFlagCompletionFunc("report-format", completionReportFormat)
+
+	discoverCmd.AddCommand(discoverValidateCmd)
+}
@@ -3,9 +3,24 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/client/vault"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // discoverCmd represents the discover command
@@ -25,3 +40,358 @@ var discoverCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(discoverCmd)
 }
+
+// discoverReadPayload reads the discovery payload passed with -d, or from
+// standard input, in the format passed with --format-input. CSV payloads are
+// parsed with discover.ParseNodeListCSV, while payloads in the other formats
+// are checked against the schema of the format if --validate-schema was passed
+// (see discoverValidateSchema) and migrated to the current version of the
+// format (see discoverMigrateNodeList). If the payload cannot be read, the
+// program exits.
+func discoverReadPayload(cmd *cobra.Command) discover.NodeList {
+	if discoverFormatInput != discover.PayloadFormatCSV {
+		formatInput = format.DataFormat(discoverFormatInput)
+		var data any
+		if cmd.Flag("data").Changed {
+			handlePayload(cmd, &data)
+		} else {
+			handlePayloadStdin(cmd, &data)
+		}
+		if validate, _ := cmd.Flags().GetBool("validate-schema"); validate {
+			discoverValidateSchema(cmd, data)
+		}
+		return discoverMigrateNodeList(cmd, data)
+	}
+	if validate, _ := cmd.Flags().GetBool("validate-schema"); validate {
+		log.Logger.Warn().Msg("--validate-schema has no effect on CSV payloads")
+	}
+
+	raw := handlePayloadRaw(cmd)
+	nodes, err := discover.ParseNodeListCSV(bytes.NewReader(raw))
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("invalid discovery payload")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return nodes
+}
+
+// discoverValidateSchema checks payload data, as read generically from a file
+// or standard input, against the JSON Schema of the discovery payload format
+// (see discover.ValidateSchema), logging each mismatch. If there are any, the
+// program exits.
+func discoverValidateSchema(cmd *cobra.Command, data any) {
+	errs, err := discover.ValidateSchema(data)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to validate discovery payload against schema")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if len(errs) == 0 {
+		log.Logger.Debug().Msg("discovery payload matches schema")
+		return
+	}
+	for _, e := range errs {
+		log.Logger.Error().Msg(e.Error())
+	}
+	log.Logger.Error().Msgf("discovery payload does not match schema (%d mismatch(es)), see 'ochami discover schema'", len(errs))
+	logHelpError(cmd)
+	os.Exit(1)
+}
+
+// discoverMigrateNodeList migrates payload data in the discovery payload format
+// (see discover.NodeList), as read generically from a file or standard input,
+// to the current version of the format, logging a warning for each legacy
+// shape that was migrated, and expands its compact node entries. If the data
+// cannot be migrated or expanded, the program exits.
+func discoverMigrateNodeList(cmd *cobra.Command, data any) discover.NodeList {
+	payload, warnings, err := discover.MigrateNodeList(data)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("invalid discovery payload")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	for _, w := range warnings {
+		log.Logger.Warn().Msg(w)
+	}
+	if len(warnings) > 0 {
+		log.Logger.Warn().Msgf("payload uses a deprecated format, run 'ochami discover migrate' to update it to version %d", discover.NodeListVersion)
+	}
+	nodes, err := discover.ExpandNodeList(payload)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("invalid discovery payload")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return nodes
+}
+
+// discoverApplyGroups adds the nodes in nodes to the default groups and
+// auto-grouping rules configured for the cluster under discovery, as well as
+// those passed via --default-group and --auto-group. If a rule is invalid, the
+// program exits.
+func discoverApplyGroups(cmd *cobra.Command, nodes *discover.NodeList) {
+	var defaults, specs []string
+	if cl, found := getCluster(cmd); found {
+		defaults = append(defaults, cl.Cluster.Discovery.DefaultGroups...)
+		specs = append(specs, cl.Cluster.Discovery.AutoGroups...)
+	}
+	groups, err := cmd.Flags().GetStringSlice("default-group")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --default-group")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	defaults = append(defaults, groups...)
+	autoGroups, err := cmd.Flags().GetStringArray("auto-group")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --auto-group")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	specs = append(specs, autoGroups...)
+	rules, err := discover.ParseAutoGroupRules(specs)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("invalid auto-group rule")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if added := discover.ApplyGroups(nodes, defaults, rules); added > 0 {
+		log.Logger.Info().Msgf("added %d group membership(s) from default groups and auto-group rules", added)
+	}
+}
+
+// discoverApplyBMCCredentials sets the user and password of the redfish
+// endpoints in rfes that have none to discovery.bmc-username and
+// discovery.bmc-password of the cluster under discovery, if set, so that SMD
+// can talk to the BMCs discovered.
+func discoverApplyBMCCredentials(cmd *cobra.Command, rfes *smd.RedfishEndpointSliceV2) {
+	cl, found := getCluster(cmd)
+	if !found || (cl.Cluster.Discovery.BMCUsername == "" && cl.Cluster.Discovery.BMCPassword == "") {
+		return
+	}
+	password := configSecret(cmd, "discovery.bmc-password", cl.Cluster.Discovery.BMCPassword)
+	for i := range rfes.RedfishEndpoints {
+		rfe := &rfes.RedfishEndpoints[i]
+		if rfe.User == "" && rfe.Password == "" {
+			rfe.User = cl.Cluster.Discovery.BMCUsername
+			rfe.Password = password
+		}
+	}
+}
+
+// discoverDefaultBMCCredsPath is where in Vault BMC credentials are pushed
+// unless discovery.bmc-creds-path is set: the KV mount and path under which
+// the services of OpenCHAMI look up the credentials of each BMC by xname.
+const discoverDefaultBMCCredsPath = "secret/hms-creds"
+
+// discoverPushBMCCredentials writes the credentials of each of the redfish
+// endpoints in rfes that has any to the secret <path>/<xname> in vaultClient,
+// path being discovery.bmc-creds-path, so that services reading them from
+// Vault, such as PCS, can talk to the BMCs. It returns whether any write
+// failed.
+func discoverPushBMCCredentials(cmd *cobra.Command, vaultClient *vault.VaultClient, vaultToken string, rfes smd.RedfishEndpointSliceV2) bool {
+	credsPath := discoverDefaultBMCCredsPath
+	if cl, found := getCluster(cmd); found && cl.Cluster.Discovery.BMCCredsPath != "" {
+		credsPath = cl.Cluster.Discovery.BMCCredsPath
+	}
+	mount, prefix, _ := strings.Cut(strings.Trim(credsPath, "/"), "/")
+
+	var errorsOccurred bool
+	var n int
+	for _, rfe := range rfes.RedfishEndpoints {
+		if rfe.User == "" && rfe.Password == "" {
+			continue
+		}
+		host := rfe.FQDN
+		if host == "" {
+			host = rfe.IPAddress
+		}
+		creds := map[string]any{
+			"xname":    rfe.ID,
+			"url":      host + "/redfish/v1",
+			"username": rfe.User,
+			"password": rfe.Password,
+		}
+		if err := vaultClient.PutKVSecret(mount, path.Join(prefix, rfe.ID), vaultToken, creds); err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to push credentials of BMC %s to Vault", rfe.ID)
+			errorsOccurred = true
+			continue
+		}
+		n++
+	}
+	log.Logger.Info().Msgf("pushed credentials of %d BMC(s) to Vault under %s", n, credsPath)
+
+	return errorsOccurred
+}
+
+// discoverNodeGroups returns the SMD groups that the nodes in nodes are in,
+// each with the nodes as members.
+func discoverNodeGroups(nodes discover.NodeList) []smd.Group {
+	groupsToAdd := make(map[string]smd.Group)
+	for _, node := range nodes.Nodes {
+		// node.Group IS DEPRECATED IN FAVOR OF node.groups. This block should be
+		// deleted when node.Group is removed.
+		//
+		// For now, we merge node.Group with node.Groups. Since we use a dictionary for
+		// deduplication, this is trivial.
+		if node.Group != "" {
+			if len(strings.Trim(node.Name, " \t")) == 0 {
+				log.Logger.Warn().Msgf("node %s contains 'group', which is deprecated; use 'groups' instead", node.Xname)
+			} else {
+				log.Logger.Warn().Msgf("node %s (%s) contains 'group', which is deprecated; use 'groups' instead", node.Xname, node.Name)
+			}
+			if g, ok := groupsToAdd[node.Group]; !ok {
+				// Group doesn't exist yet, populate groupsToAdd with it
+				newGroup := smd.Group{
+					Label:       node.Group,
+					Description: fmt.Sprintf("The %s group", node.Group),
+				}
+				groupsToAdd[node.Group] = discover.AddMemberToGroup(newGroup, node.Xname)
+			} else {
+				// Update group membership with new node in groupsToAdd map
+				groupsToAdd[node.Group] = discover.AddMemberToGroup(g, node.Xname)
+			}
+		}
+		for _, group := range node.Groups {
+			if g, ok := groupsToAdd[group]; !ok {
+				// Group doesn't exist yet, populate groupsToAdd with it
+				newGroup := smd.Group{
+					Label:       group,
+					Description: fmt.Sprintf("The %s group", group),
+				}
+				groupsToAdd[group] = discover.AddMemberToGroup(newGroup, node.Xname)
+			} else {
+				// Update group membership with new node in groupsToAdd map
+				groupsToAdd[group] = discover.AddMemberToGroup(g, node.Xname)
+			}
+		}
+	}
+	groupList := make([]smd.Group, 0, len(groupsToAdd))
+	for _, g := range groupsToAdd {
+		groupList = append(groupList, g)
+	}
+	sort.Slice(groupList, func(i, j int) bool { return groupList[i].Label < groupList[j].Label })
+
+	return groupList
+}
+
+// discoverPayloads reads the discovery payload passed to cmd (see
+// discoverReadPayload), adds its nodes to groups (see discoverApplyGroups),
+// and returns the payloads to send to SMD for it, with the BMC credentials of
+// the nodes that have any but without the defaults of the cluster (see
+// discoverApplyBMCCredentials) and the hardware inventory of the nodes that
+// describe their hardware. Ethernet interfaces are only included with
+// discovery version 1. If the payloads cannot be generated, the program
+// exits.
+func discoverPayloads(cmd *cobra.Command, smdBaseURI string) discover.Payloads {
+	// Read data from file or stdin, migrating it from older versions
+	// of the format if needed
+	nodes := discoverReadPayload(cmd)
+	discoverApplyGroups(cmd, &nodes)
+	log.Logger.Debug().Msgf("read %d nodes", len(nodes.Nodes))
+	log.Logger.Debug().Msgf("nodes: %s", nodes)
+
+	// Put together payload for different endpoints
+	log.Logger.Debug().Msg("generating redfish structures to send to SMD")
+	comps, rfes, ifaces, warnings, err := discover.DiscoveryInfoV3(smdBaseURI, nodes, discoverErrorPolicy)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to construct structures to send to SMD")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	discoverReportWarnings(warnings)
+	log.Logger.Debug().Msgf("generated redfish structures: %v", rfes.RedfishEndpoints)
+
+	// Leave out the hardware of the nodes that were skipped
+	sent := discover.NodeList{Version: nodes.Version}
+	for _, n := range nodes.Nodes {
+		if slices.ContainsFunc(comps.Components, func(c smd.Component) bool { return strings.EqualFold(c.ID, n.Xname) }) {
+			sent.Nodes = append(sent.Nodes, n)
+		}
+	}
+
+	payloads := discover.Payloads{
+		Components:        comps,
+		RedfishEndpoints:  rfes,
+		HardwareInventory: discover.HardwareInventory(sent),
+		Groups:            discoverNodeGroups(nodes),
+	}
+	if discoveryVersion == discover.DiscoveryMethodV1 {
+		payloads.EthernetInterfaces = ifaces
+	}
+
+	return payloads
+}
+
+// discoverSkipped are the malformed nodes and interfaces that were left out of
+// the payloads by --on-error, which discoverSendStatus reports again once the
+// payloads are sent.
+var discoverSkipped []discover.Warning
+
+// discoverReportWarnings logs each of warnings, followed by a summary of how
+// many there are of each code if there is more than one. With --fail-on-warn,
+// the command thus exits with an error in the end if there are any.
+func discoverReportWarnings(warnings []discover.Warning) {
+	for _, w := range warnings {
+		log.Logger.Warn().Msgf("%s [%s]", w, w.Code)
+		if w.Code == discover.WarningSkippedNode || w.Code == discover.WarningSkippedInterface {
+			discoverSkipped = append(discoverSkipped, w)
+		}
+	}
+	if len(warnings) < 2 {
+		return
+	}
+	counts := discover.CountWarnings(warnings)
+	codes := make([]string, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, string(code))
+	}
+	sort.Strings(codes)
+	summary := make([]string, len(codes))
+	for i, code := range codes {
+		summary[i] = fmt.Sprintf("%d %s", counts[discover.WarningCode(code)], code)
+	}
+	log.Logger.Warn().Msgf("%d warning(s) generating SMD structures from payload: %s", len(warnings), strings.Join(summary, ", "))
+}
+
+// discoverGetSMDState returns the components, redfish endpoints, ethernet
+// interfaces, and, if withGroups is true, groups in SMD, which discovery
+// payloads are compared with. If any cannot be read, the program exits.
+func discoverGetSMDState(cmd *cobra.Command, smdClient *smd.SMDClient, withGroups bool) discover.SMDState {
+	get := func(kind string, fn func() (client.HTTPEnvelope, error), v any) {
+		henv, err := fn()
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msgf("SMD %s request yielded unsuccessful HTTP response", kind)
+			} else {
+				log.Logger.Error().Err(err).Msgf("failed to request %ss from SMD", kind)
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if err := json.Unmarshal(henv.Body, v); err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to unmarshal %ss", kind)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	}
+
+	var (
+		state discover.SMDState
+		comps smd.ComponentSlice
+		rfes  smd.RedfishEndpointSlice
+	)
+	get("component", smdClient.GetComponentsAll, &comps)
+	get("redfish endpoint", func() (client.HTTPEnvelope, error) { return smdClient.GetRedfishEndpoints("", token) }, &rfes)
+	get("ethernet interface", func() (client.HTTPEnvelope, error) { return smdClient.GetEthernetInterfaces("", token) }, &state.EthernetInterfaces)
+	if withGroups {
+		get("group", func() (client.HTTPEnvelope, error) { return smdClient.GetGroups("", token) }, &state.Groups)
+	}
+	state.Components = comps.Components
+	state.RedfishEndpoints = rfes.RedfishEndpoints
+
+	return state
+}
@@ -0,0 +1,489 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+	"github.com/openchami/schemas/schemas/csm"
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/events"
+)
+
+// Services whose events can be tailed
+const (
+	eventsServiceBSS = "bss"
+	eventsServiceSMD = "smd"
+)
+
+// Kinds of items whose changes are reported as events
+const (
+	eventsKindBootParameters    = "boot-parameters"
+	eventsKindBootScript        = "boot-script"
+	eventsKindComponent         = "component"
+	eventsKindEthernetInterface = "ethernet-interface"
+	eventsKindGroup             = "group"
+	eventsKindRedfishEndpoint   = "redfish-endpoint"
+)
+
+// Actions of events that are not derived from comparing snapshots
+const (
+	eventsActionFetched  = "fetched"
+	eventsActionNotified = "notified"
+)
+
+// eventsSCNStates are the component states that SMD is asked to send state
+// change notifications for, which are all of them.
+var eventsSCNStates = []string{"Unknown", "Empty", "Populated", "Off", "On", "Standby", "Halt", "Ready"}
+
+// eventsTailCmd represents the "events tail" command
+var eventsTailCmd = &cobra.Command{
+	Use:   "tail [--service <service>]... [--interval <duration>] [--listen <addr> [--callback-url <url>]] [--sse <service>=<path>]...",
+	Args:  cobra.NoArgs,
+	Short: "Print change events from SMD and BSS as NDJSON",
+	Long: `Print change events from SMD and BSS to standard output as
+newline-delimited JSON (NDJSON), one timestamped event per line, until
+interrupted. This is meant to feed operator dashboards and log pipelines.
+
+Events are received using the best mechanism available for each service:
+
+  1. If --sse is passed for the service, the server-sent event stream at
+     the path is read.
+  2. For SMD, if --listen is passed, a local listener is started and SMD
+     is subscribed to send state change notifications (SCNs) to it. The
+     subscription is deleted on exit.
+  3. Otherwise, the service is polled every --interval and each item
+     that was added, changed, or removed since the previous poll is
+     reported.
+
+If the event stream or SCN subscription cannot be set up, the service
+is polled instead.
+
+See ochami-events(1) for more details.`,
+	Example: `  # Print changes to SMD and BSS, polling every 10 seconds
+  ochami events tail
+
+  # Only print changes to SMD, receiving SCNs on port 9000
+  ochami events tail --service smd --listen :9000 \
+    --callback-url http://mgmt01.example.com:9000/
+
+  # Read events from an SSE endpoint in front of BSS
+  ochami events tail --sse bss=/events`,
+	Run: func(cmd *cobra.Command, args []string) {
+		services, err := cmd.Flags().GetStringSlice("service")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --service")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, svc := range services {
+			if svc != eventsServiceBSS && svc != eventsServiceSMD {
+				log.Logger.Error().Msgf("unknown service %q, must be %s or %s", svc, eventsServiceBSS, eventsServiceSMD)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+		interval, err := cmd.Flags().GetDuration("interval")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --interval")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if interval <= 0 {
+			log.Logger.Error().Msg("--interval must be greater than zero")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		ssePaths, err := cmd.Flags().GetStringToString("sse")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --sse")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for svc := range ssePaths {
+			if !slices.Contains(services, svc) {
+				log.Logger.Error().Msgf("--sse passed for %s, which is not in --service", svc)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+		listen, err := cmd.Flags().GetString("listen")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --listen")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		callbackURL, err := cmd.Flags().GetString("callback-url")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --callback-url")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		// Run until interrupted or until events can no longer be
+		// written
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		w := events.NewWriter(os.Stdout)
+		emit := func(e events.Event) {
+			if e.Time.IsZero() {
+				e.Time = time.Now().UTC()
+			}
+			if err := w.Write(e); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to write event")
+				cancel()
+			}
+		}
+
+		var cleanups []func()
+		for _, svc := range services {
+			oc := eventsTailClient(cmd, svc)
+			if path, ok := ssePaths[svc]; ok {
+				go func() {
+					err := eventsTailSSE(ctx, oc, svc, path, interval, emit)
+					if err != nil {
+						log.Logger.Warn().Err(err).Msgf("failed to read %s event stream, polling instead", svc)
+						eventsTailPoll(ctx, cmd, svc, interval, emit)
+					}
+				}()
+				continue
+			}
+			if svc == eventsServiceSMD && listen != "" {
+				cleanup, err := eventsTailWebhook(cmd, listen, callbackURL, emit)
+				if err == nil {
+					cleanups = append(cleanups, cleanup)
+					continue
+				}
+				log.Logger.Warn().Err(err).Msgf("failed to subscribe to %s state change notifications, polling instead", svc)
+			}
+			go eventsTailPoll(ctx, cmd, svc, interval, emit)
+		}
+
+		<-ctx.Done()
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+	},
+}
+
+// eventsTailClient returns the OchamiClient for service.
+func eventsTailClient(cmd *cobra.Command, service string) *client.OchamiClient {
+	if service == eventsServiceBSS {
+		return bssGetClient(cmd).OchamiClient
+	}
+
+	return smdGetClient(cmd).OchamiClient
+}
+
+// eventsTailSSE reads the server-sent event stream at path of oc, emitting an
+// event for each message, until ctx is done. If the stream ends, it is
+// reconnected to after interval. An error is only returned if the first
+// connection fails, so that the caller can fall back to polling.
+func eventsTailSSE(ctx context.Context, oc *client.OchamiClient, service, path string, interval time.Duration, emit func(events.Event)) error {
+	headers := client.NewHTTPHeaders()
+	if err := headers.Add("Accept", "text/event-stream"); err != nil {
+		return fmt.Errorf("error setting Accept in HTTP headers: %w", err)
+	}
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			return fmt.Errorf("error setting token in HTTP headers: %w", err)
+		}
+	}
+
+	connected := false
+	for {
+		res, err := oc.MakeOchamiRequest(http.MethodGet, path, "", headers, nil)
+		if err == nil && (res.StatusCode < 200 || res.StatusCode > 299) {
+			res.Body.Close()
+			err = fmt.Errorf("%w: %s", client.UnsuccessfulHTTPError, res.Status)
+		}
+		if err != nil {
+			if !connected {
+				return err
+			}
+			log.Logger.Warn().Err(err).Msgf("failed to reconnect to %s event stream, retrying in %s", service, interval)
+		} else {
+			connected = true
+			log.Logger.Info().Msgf("reading %s event stream at %s", service, path)
+			err = events.ReadSSE(res.Body, func(m events.SSEMessage) error {
+				kind := m.Event
+				if kind == "" {
+					kind = "message"
+				}
+				e := events.Event{Service: service, Source: events.SourceSSE, Kind: kind, Action: eventsActionNotified, ID: m.ID}
+				if json.Valid([]byte(m.Data)) {
+					e.Data = json.RawMessage(m.Data)
+				} else if m.Data != "" {
+					e.Data = m.Data
+				}
+				emit(e)
+				return ctx.Err()
+			})
+			res.Body.Close()
+			if ctx.Err() == nil {
+				log.Logger.Warn().Err(err).Msgf("%s event stream ended, reconnecting in %s", service, interval)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// eventsTailWebhook starts listening on listen for SMD state change
+// notifications and subscribes to them, with SMD sending them to callbackURL
+// (or to listen if empty). Each notification is emitted as one event per
+// component in it. The returned function deletes the subscription and stops
+// the listener.
+func eventsTailWebhook(cmd *cobra.Command, listen, callbackURL string, emit func(events.Event)) (func(), error) {
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", listen, err)
+	}
+	if callbackURL == "" {
+		callbackURL = "http://" + ln.Addr().String() + "/"
+	}
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			var scn smd.SCN
+			if err := json.NewDecoder(r.Body).Decode(&scn); err != nil {
+				log.Logger.Warn().Err(err).Msg("failed to decode state change notification")
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data := scn
+			data.Components = nil
+			for _, id := range scn.Components {
+				emit(events.Event{Service: eventsServiceSMD, Source: events.SourceWebhook, Kind: eventsKindComponent, Action: eventsActionNotified, ID: id, Data: data})
+			}
+			w.WriteHeader(http.StatusOK)
+		}),
+	}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Logger.Error().Err(err).Msg("state change notification listener failed")
+		}
+	}()
+
+	hostname, _ := os.Hostname()
+	enabled := true
+	sub := smd.SCNSubscription{
+		Subscriber: "ochami@" + hostname,
+		Url:        callbackURL,
+		Enabled:    &enabled,
+		States:     eventsSCNStates,
+	}
+	smdClient := smdGetClient(cmd)
+	henv, err := smdClient.PostSCNSubscription(sub, token)
+	if err == nil {
+		err = json.Unmarshal(henv.Body, &sub)
+	}
+	if err != nil {
+		srv.Close()
+		return nil, err
+	}
+	log.Logger.Info().Msgf("subscribed to SMD state change notifications at %s (subscription %d)", callbackURL, sub.ID)
+
+	return func() {
+		if _, err := smdClient.DeleteSCNSubscription(sub.ID, token); err != nil {
+			log.Logger.Warn().Err(err).Msgf("failed to delete SMD state change notification subscription %d", sub.ID)
+		}
+		srv.Shutdown(context.Background())
+	}, nil
+}
+
+// eventsTailPoll polls service every interval until ctx is done, emitting an
+// event for each item that was added, changed, or removed since the previous
+// poll. The first poll only records the current state. Failed polls are
+// logged and retried at the next interval.
+func eventsTailPoll(ctx context.Context, cmd *cobra.Command, service string, interval time.Duration, emit func(events.Event)) {
+	log.Logger.Info().Msgf("polling %s every %s", service, interval)
+	var prev map[string]events.Snapshot
+	for {
+		snaps, err := eventsTailSnapshots(cmd, service)
+		if err != nil {
+			log.Logger.Warn().Err(err).Msgf("failed to poll %s", service)
+		} else {
+			now := time.Now().UTC()
+			for kind, snap := range snaps {
+				old, ok := prev[kind]
+				if !ok {
+					continue
+				}
+				for _, e := range events.Diff(kind, old, snap) {
+					e.Time = now
+					e.Service = service
+					e.Source = events.SourcePoll
+					// A new or later boot script fetch time means a
+					// node fetched its boot script
+					if kind == eventsKindBootScript {
+						if e.Action == events.ActionRemoved {
+							continue
+						}
+						e.Action = eventsActionFetched
+					}
+					emit(e)
+				}
+			}
+			prev = snaps
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// eventsTailSnapshots returns a snapshot of each kind of item polled in
+// service, keyed by kind.
+func eventsTailSnapshots(cmd *cobra.Command, service string) (map[string]events.Snapshot, error) {
+	snaps := make(map[string]events.Snapshot)
+	if service == eventsServiceBSS {
+		bssClient := bssGetClient(cmd)
+
+		henv, err := bssClient.GetBootParams("", token)
+		if err != nil {
+			return nil, err
+		}
+		var bps []bssTypes.BootParams
+		if err := json.Unmarshal(henv.Body, &bps); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal boot parameters: %w", err)
+		}
+		// BSS groups hosts with the same boot parameters, so report
+		// changes per host
+		type hostParams struct {
+			Params string `json:"params,omitempty"`
+			Kernel string `json:"kernel,omitempty"`
+			Initrd string `json:"initrd,omitempty"`
+		}
+		snap := make(events.Snapshot)
+		for _, bp := range bps {
+			b, err := json.Marshal(hostParams{Params: bp.Params, Kernel: bp.Kernel, Initrd: bp.Initrd})
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal boot parameters: %w", err)
+			}
+			for _, h := range bp.Hosts {
+				snap[h] = b
+			}
+			for _, m := range bp.Macs {
+				snap[m] = b
+			}
+			for _, nid := range bp.Nids {
+				snap[fmt.Sprint(nid)] = b
+			}
+		}
+		snaps[eventsKindBootParameters] = snap
+
+		henv, err = bssClient.GetEndpointHistory("endpoint=" + string(bssTypes.EndpointTypeBootscript))
+		if err != nil {
+			return nil, err
+		}
+		var history []bssTypes.EndpointAccess
+		if err := json.Unmarshal(henv.Body, &history); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal endpoint history: %w", err)
+		}
+		if snaps[eventsKindBootScript], err = events.NewSnapshot(history, func(ea bssTypes.EndpointAccess) string { return ea.Name }); err != nil {
+			return nil, err
+		}
+
+		return snaps, nil
+	}
+
+	smdClient := smdGetClient(cmd)
+
+	henv, err := smdClient.GetComponentsAll()
+	if err != nil {
+		return nil, err
+	}
+	// smd.Component only has a subset of the fields of components, so
+	// keep all of them to report changes to any of them
+	var comps struct {
+		Components []map[string]any `json:"Components"`
+	}
+	if err := json.Unmarshal(henv.Body, &comps); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal components: %w", err)
+	}
+	if snaps[eventsKindComponent], err = events.NewSnapshot(comps.Components, func(c map[string]any) string { return fmt.Sprint(c["ID"]) }); err != nil {
+		return nil, err
+	}
+
+	henv, err = smdClient.GetRedfishEndpoints("", token)
+	if err != nil {
+		return nil, err
+	}
+	var rfes smd.RedfishEndpointSlice
+	if err := json.Unmarshal(henv.Body, &rfes); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal redfish endpoints: %w", err)
+	}
+	if snaps[eventsKindRedfishEndpoint], err = events.NewSnapshot(rfes.RedfishEndpoints, func(r csm.RedfishEndpoint) string { return r.ID }); err != nil {
+		return nil, err
+	}
+
+	henv, err = smdClient.GetEthernetInterfaces("", token)
+	if err != nil {
+		return nil, err
+	}
+	var eis []smd.EthernetInterface
+	if err := json.Unmarshal(henv.Body, &eis); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal ethernet interfaces: %w", err)
+	}
+	if snaps[eventsKindEthernetInterface], err = events.NewSnapshot(eis, func(ei smd.EthernetInterface) string { return ei.ID }); err != nil {
+		return nil, err
+	}
+
+	henv, err = smdClient.GetGroups("", token)
+	if err != nil {
+		return nil, err
+	}
+	var groups []smd.Group
+	if err := json.Unmarshal(henv.Body, &groups); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal groups: %w", err)
+	}
+	if snaps[eventsKindGroup], err = events.NewSnapshot(groups, func(g smd.Group) string { return g.Label }); err != nil {
+		return nil, err
+	}
+
+	return snaps, nil
+}
+
+func init() {
+	eventsTailCmd.Flags().StringSlice("service", []string{eventsServiceSMD, eventsServiceBSS}, "services to print events from ("+strings.Join([]string{eventsServiceBSS, eventsServiceSMD}, ",")+")")
+	eventsTailCmd.Flags().Duration("interval", 10*time.Second, "interval at which to poll services, and to reconnect to event streams")
+	eventsTailCmd.Flags().String("listen", "", "address to listen on for SMD state change notifications (e.g. :9000)")
+	eventsTailCmd.Flags().String("callback-url", "", "URL for SMD to send state change notifications to (default: http://<listen address>/)")
+	eventsTailCmd.Flags().StringToString("sse", map[string]string{}, "path of a server-sent event stream to read for a service (e.g. bss=/events)")
+
+	// Output is streamed, so it must not be held back for paging
+	eventsTailCmd.Annotations = map[string]string{annotationNoPager: ""}
+
+	eventsCmd.AddCommand(eventsTailCmd)
+}
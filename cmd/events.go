@@ -0,0 +1,30 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// eventsCmd represents the events command
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Args:  cobra.NoArgs,
+	Short: "Watch change events from OpenCHAMI services",
+	Long: `Watch change events from OpenCHAMI services.
+
+See ochami-events(1) for more details.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Check that all required args are passed
+		if len(args) == 0 {
+			printUsageHandleError(cmd)
+			os.Exit(0)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(eventsCmd)
+}
@@ -0,0 +1,237 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/internal/version"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/export"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/pool"
+)
+
+// exportAllCmd represents the "export all" command
+var exportAllCmd = &cobra.Command{
+	Use:   "all --output-dir <dir> [--only <artifact>,...] [--template-dir <dir>]",
+	Args:  cobra.NoArgs,
+	Short: "Generate all configuration files from one SMD snapshot",
+	Long: `Generate all configuration files (an /etc/hosts file, dnsmasq
+dhcp-host entries, an Ansible inventory, Prometheus targets, conman
+console entries, and powerman config) from a single snapshot of the
+components, redfish endpoints, and ethernet interfaces in SMD, and
+write them to --output-dir along with a manifest.json describing them.
+The manifest is also printed.
+
+The data is requested from SMD concurrently and the files are
+generated concurrently. Files whose content did not change are left
+alone, and the others are replaced atomically, so this command can
+regenerate the configuration from cron.
+
+Pass --only to only generate some of the files, and --template-dir
+to override the built-in template of an artifact with the Go
+text/template in <artifact>.tmpl in that directory.
+
+An access token is required.
+
+See ochami-export(1) for more details.`,
+	Example: `  # Regenerate all config files
+  ochami export all --output-dir /etc/ochami/generated
+
+  # Only generate the hosts file and dnsmasq entries
+  ochami export all --output-dir ./out --only hosts,dnsmasq
+
+  # Override built-in templates with ./templates/<artifact>.tmpl
+  ochami export all --output-dir ./out --template-dir ./templates`,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := cmd.Flags().GetString("output-dir")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --output-dir")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		arts := exportAllArtifacts(cmd)
+
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		// Take one snapshot of SMD so that all files agree
+		var (
+			comps smd.ComponentSlice
+			rfes  smd.RedfishEndpointSlice
+			eis   []smd.EthernetInterface
+		)
+		requests := []struct {
+			what string
+			get  func() (client.HTTPEnvelope, error)
+			into any
+		}{
+			{"components", smdClient.GetComponentsAll, &comps},
+			{"redfish endpoints", func() (client.HTTPEnvelope, error) { return smdClient.GetRedfishEndpoints("", token) }, &rfes},
+			{"ethernet interfaces", func() (client.HTTPEnvelope, error) { return smdClient.GetEthernetInterfaces("", token) }, &eis},
+		}
+		failed := false
+		pool.Run(context.Background(), len(requests), len(requests), 0, func(ctx context.Context, i int) error {
+			henv, err := requests[i].get()
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(henv.Body, requests[i].into); err != nil {
+				return fmt.Errorf("failed to unmarshal %s: %w", requests[i].what, err)
+			}
+			return nil
+		}, func(i int, err error) {
+			if err == nil {
+				return
+			}
+			failed = true
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msgf("SMD %s request yielded unsuccessful HTTP response", requests[i].what)
+			} else {
+				log.Logger.Error().Err(err).Msgf("failed to get %s from SMD", requests[i].what)
+			}
+		})
+		if failed {
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		data := export.NewData(comps.Components, rfes.RedfishEndpoints, eis)
+		if len(data.Nodes) == 0 {
+			log.Logger.Warn().Msg("no nodes were found in SMD")
+		}
+		outs, err := export.RenderAll(arts, data, len(arts))
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to generate config")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		var clusterName string
+		if cl, ok := getCluster(cmd); ok {
+			clusterName = cl.Name
+		}
+		m, err := export.WriteDir(dir, outs, export.Manifest{
+			Generated:     time.Now().UTC(),
+			OchamiVersion: version.Version,
+			Cluster:       clusterName,
+			Nodes:         len(data.Nodes),
+			BMCs:          len(data.BMCs),
+		})
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to write config")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		changed := 0
+		for _, f := range m.Files {
+			if f.Changed {
+				changed++
+			}
+		}
+		log.Logger.Info().Msgf("wrote %d file(s) to %s, %d of which changed", len(m.Files), dir, changed)
+
+		if outBytes, err := format.MarshalData(m, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+	},
+}
+
+// exportAllArtifacts returns the artifacts to generate: those passed with
+// --only, or all of them, with the templates in --template-dir overriding the
+// built-in ones. If a flag is invalid or a template cannot be read, an error is
+// logged and the program exits.
+func exportAllArtifacts(cmd *cobra.Command) []export.Artifact {
+	only, err := cmd.Flags().GetStringSlice("only")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --only")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	tmplDir, err := cmd.Flags().GetString("template-dir")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --template-dir")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	var arts []export.Artifact
+	for _, a := range export.Artifacts {
+		if len(only) > 0 && !exportAllSelected(only, a.Name) {
+			continue
+		}
+		if tmplDir != "" {
+			b, err := os.ReadFile(filepath.Join(tmplDir, a.Name+".tmpl"))
+			if err == nil {
+				a.Template = string(b)
+			} else if !errors.Is(err, os.ErrNotExist) {
+				log.Logger.Error().Err(err).Msgf("failed to read %s template", a.Name)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+		arts = append(arts, a)
+	}
+	for _, name := range only {
+		if !exportAllSelected(exportAllNames(), name) {
+			log.Logger.Error().Msgf("unknown artifact %q passed to --only (must be one of %s)", name, strings.Join(exportAllNames(), ", "))
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	}
+
+	return arts
+}
+
+// exportAllSelected returns whether name is in names, regardless of case.
+func exportAllSelected(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// exportAllNames returns the names of all artifacts.
+func exportAllNames() []string {
+	names := make([]string, len(export.Artifacts))
+	for i, a := range export.Artifacts {
+		names[i] = a.Name
+	}
+
+	return names
+}
+
+func init() {
+	exportAllCmd.Flags().StringP("output-dir", "o", "", "directory to write generated files and manifest to")
+	exportAllCmd.Flags().StringSlice("only", []string{}, "only generate these artifacts ("+strings.Join(exportAllNames(), ",")+")")
+	exportAllCmd.Flags().String("template-dir", "", "directory of <artifact>.tmpl Go text/templates overriding the built-in ones")
+	exportAllCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	exportAllCmd.MarkFlagRequired("output-dir")
+
+	exportAllCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	exportCmd.AddCommand(exportAllCmd)
+}
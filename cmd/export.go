@@ -0,0 +1,32 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// exportCmd represents the export command
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Args:  cobra.NoArgs,
+	Short: "Generate configuration files for infrastructure services",
+	Long: `Generate configuration files for infrastructure services (e.g.
+hosts files, DHCP, monitoring, and consoles) from the inventory in SMD
+so that they stay in sync with it.
+
+See ochami-export(1) for more details.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Check that all required args are passed
+		if len(args) == 0 {
+			printUsageHandleError(cmd)
+			os.Exit(0)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+}
@@ -0,0 +1,240 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/fsck"
+)
+
+// fsckCmd represents the fsck command
+var fsckCmd = &cobra.Command{
+	Use:   "fsck [--skip <service>,...] [--fix [--no-confirm]] [-F <format>] [--report-format <format> [--report-file <path>]]",
+	Args:  cobra.NoArgs,
+	Short: "Check the consistency of data across services",
+	Long: `Check the data in SMD, BSS, and cloud-init for references that do
+not resolve across services:
+
+  - nodes without any ethernet interfaces
+  - ethernet interfaces of components that do not exist
+  - redfish endpoints without any components
+  - boot parameters for xnames, MAC addresses, or NIDs not in SMD
+  - cloud-init groups without an SMD group, which no node receives
+  - SMD group members that are not components
+
+Pass --skip to skip checking the data of BSS and/or cloud-init, e.g.
+if they are not deployed. Problems are printed, and the command fails
+if there are any that were not fixed. Pass --report-format to output
+them as a JUnit XML or SARIF report for CI systems instead, or to
+--report-file in addition to the normal output.
+
+Pass --fix to make the repairs that lose no data, which is removing
+members that do not exist from SMD groups. The user is asked to
+confirm first unless --no-confirm is passed. Other problems must be
+repaired by hand.
+
+An access token is required.
+
+See ochami-fsck(1) for more details.`,
+	Example: `  # Check all services
+  ochami fsck
+
+  # Check SMD and BSS only, and remove nonexistent group members
+  ochami fsck --skip cloud-init --fix
+
+  # Write a JUnit report for CI in addition to the normal output
+  ochami fsck --report-format junit --report-file fsck.xml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		skip, err := cmd.Flags().GetStringSlice("skip")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --skip")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, s := range skip {
+			if s != fsck.SourceBSS && s != fsck.SourceCloudInit {
+				log.Logger.Error().Msgf("cannot skip %q (must be %s or %s)", s, fsck.SourceBSS, fsck.SourceCloudInit)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		data := fsck.Data{Sources: []string{fsck.SourceSMD}}
+		get := func(what string, henv client.HTTPEnvelope, err error, v any) {
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msgf("%s request yielded unsuccessful HTTP response", what)
+				} else {
+					log.Logger.Error().Err(err).Msgf("failed to get %s", what)
+				}
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			if err := json.Unmarshal(henv.Body, v); err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to unmarshal %s", what)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+
+		// SMD
+		var (
+			comps smd.ComponentSlice
+			rfes  smd.RedfishEndpointSlice
+		)
+		henv, err := smdClient.GetComponentsAll()
+		get("SMD components", henv, err, &comps)
+		data.Components = comps.Components
+		henv, err = smdClient.GetEthernetInterfaces("", token)
+		get("SMD ethernet interfaces", henv, err, &data.EthernetInterfaces)
+		henv, err = smdClient.GetRedfishEndpoints("", token)
+		get("SMD redfish endpoints", henv, err, &rfes)
+		data.RedfishEndpoints = rfes.RedfishEndpoints
+		henv, err = smdClient.GetGroups("", token)
+		get("SMD groups", henv, err, &data.Groups)
+
+		// BSS
+		if !slices.Contains(skip, fsck.SourceBSS) {
+			bssClient := bssGetClient(cmd)
+			henv, err := bssClient.GetBootParams("", token)
+			// BSS responds with 404 if there are no boot parameters
+			if err != nil && henv.StatusCode == http.StatusNotFound {
+				data.BootParams = []bssTypes.BootParams{}
+			} else {
+				get("BSS boot parameters", henv, err, &data.BootParams)
+			}
+			data.Sources = append(data.Sources, fsck.SourceBSS)
+		}
+
+		// cloud-init
+		if !slices.Contains(skip, fsck.SourceCloudInit) {
+			data.CloudInitGroups = cloudInitGetGroupData(cmd, []string{})
+			data.Sources = append(data.Sources, fsck.SourceCloudInit)
+		}
+
+		problems := fsck.Check(data)
+		if cmd.Flag("fix").Changed {
+			fsckFix(cmd, smdClient, problems)
+		}
+
+		// Print output, unless replaced by the report
+		if !writeReport(cmd, fsck.Report("fsck", problems)) {
+			if outBytes, err := format.MarshalData(problems, formatOutput); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			} else {
+				fmt.Println(string(outBytes))
+			}
+		}
+
+		var remaining, fixable int
+		for _, p := range problems {
+			if !p.Fixed {
+				remaining++
+				if p.Fix != nil {
+					fixable++
+				}
+			}
+		}
+		if remaining > 0 {
+			if fixable > 0 && !cmd.Flag("fix").Changed {
+				log.Logger.Info().Msgf("%d problem(s) can be repaired with --fix", fixable)
+			}
+			log.Logger.Error().Msgf("found %d problem(s)", remaining)
+			exitWithStatus(1)
+		}
+	},
+}
+
+// fsckFix repairs the problems that have a fix, after asking the user to
+// confirm unless --no-confirm was passed, and marks those that were repaired as
+// fixed. Failed repairs are logged.
+func fsckFix(cmd *cobra.Command, smdClient *smd.SMDClient, problems []fsck.Problem) {
+	var (
+		groups  []string
+		members = make(map[string][]int) // Indexes in problems of the fixes of each group
+	)
+	for i, p := range problems {
+		if p.Fix == nil {
+			continue
+		}
+		if _, ok := members[p.Fix.Group]; !ok {
+			groups = append(groups, p.Fix.Group)
+		}
+		members[p.Fix.Group] = append(members[p.Fix.Group], i)
+	}
+	n := 0
+	for _, idxs := range members {
+		n += len(idxs)
+	}
+	if n == 0 {
+		log.Logger.Info().Msg("no problems can be repaired automatically")
+		return
+	}
+	for _, g := range groups {
+		for _, i := range members[g] {
+			log.Logger.Info().Msgf("will %s", problems[i].Fix)
+		}
+	}
+	confirmDeletion(cmd, fmt.Sprintf("Really remove %d nonexistent member(s) from SMD groups?", n), "group member", n)
+
+	for _, g := range groups {
+		var ids []string
+		for _, i := range members[g] {
+			ids = append(ids, problems[i].Fix.Member)
+		}
+		_, errs, err := smdClient.DeleteGroupMembers(token, g, ids...)
+		if err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to remove members from SMD group %s", g)
+			continue
+		}
+		for j, i := range members[g] {
+			if errs[j] != nil {
+				if errors.Is(errs[j], client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(errs[j]).Msg("SMD group member deletion yielded unsuccessful HTTP response")
+				} else {
+					log.Logger.Error().Err(errs[j]).Msgf("failed to %s", problems[i].Fix)
+				}
+				continue
+			}
+			problems[i].Fixed = true
+			log.Logger.Info().Msgf("fixed: %s", problems[i].Fix)
+		}
+		reportNotAttempted(errs)
+	}
+}
+
+func init() {
+	fsckCmd.Flags().StringSlice("skip", []string{}, "services whose data not to check (bss,cloud-init)")
+	fsckCmd.Flags().Bool("fix", false, "remove members that do not exist from SMD groups")
+	fsckCmd.Flags().Bool("no-confirm", false, "do not ask before making repairs")
+	fsckCmd.Flags().Int("yes-really-delete", 0, "confirm deleting more than delete-threshold items by passing their exact number")
+	fsckCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+	fsckCmd.Flags().Var(&reportFormat, "report-format", "write problems as a report for CI systems (junit,sarif)")
+	fsckCmd.Flags().String("report-file", "-", "file to write report to, or - to write it to standard output instead of the normal output")
+
+	fsckCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+	fsckCmd.RegisterFlagCompletionFunc("report-format", completionReportFormat)
+
+	rootCmd.AddCommand(fsckCmd)
+}
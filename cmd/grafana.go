@@ -0,0 +1,97 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/config"
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/grafana"
+)
+
+// grafanaGetClient sets up a Grafana client using the grafana section of the
+// config of the cluster being used and returns it along with that config. If
+// grafana.uri is not set for the cluster, nil is returned. If an error occurs
+// creating the client, the program exits.
+func grafanaGetClient(cmd *cobra.Command) (*grafana.GrafanaClient, config.ConfigClusterGrafana) {
+	cl, found := getCluster(cmd)
+	if !found || cl.Cluster.Grafana.URI == "" {
+		return nil, config.ConfigClusterGrafana{}
+	}
+	gcfg := cl.Cluster.Grafana
+
+	grafanaClient, err := grafana.NewClient(gcfg.URI, insecure)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("error creating new Grafana client")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	// Check if a CA certificate was passed and load it into client if valid
+	useCACert(grafanaClient.OchamiClient)
+
+	// Refuse to send mutating requests in read-only mode
+	grafanaClient.ReadOnly = readOnlyEnabled(cmd)
+
+	// Stop sending requests once the --context-timeout deadline passes
+	grafanaClient.Deadline = commandDeadline
+
+	return grafanaClient, gcfg
+}
+
+// grafanaToken returns the Grafana API token for the cluster being used, read
+// from the environment variable <CLUSTER>_GRAFANA_TOKEN (see clusterEnvVar) or,
+// if it is unset, grafana.token in the cluster config. If neither is set, an
+// empty string is returned and requests are sent without authentication.
+func grafanaToken(cmd *cobra.Command) string {
+	cl, found := getCluster(cmd)
+	if !found {
+		return ""
+	}
+	envVar := clusterEnvVar(cl.Name, "GRAFANA_TOKEN")
+	t, set := os.LookupEnv(envVar)
+	if !set {
+		if cl.Cluster.Grafana.Token != "" {
+			return configSecret(cmd, "grafana.token", cl.Cluster.Grafana.Token)
+		}
+		log.Logger.Debug().Msgf("%s unset, sending Grafana requests without a token", envVar)
+	}
+
+	return t
+}
+
+// grafanaAnnotate pushes an annotation for action performed on xnames to the
+// Grafana instance configured for the cluster being used, if any. This is
+// meant to be called after a command performs an operational action, so
+// failures are only logged as warnings.
+func grafanaAnnotate(cmd *cobra.Command, action string, xnames []string) {
+	grafanaAnnotateSpan(cmd, action, xnames, time.Now(), time.Time{})
+}
+
+// grafanaAnnotateSpan is like grafanaAnnotate, but annotates the time from
+// start to end, e.g. that an action took once it is over. If end is zero, only
+// start is annotated.
+func grafanaAnnotateSpan(cmd *cobra.Command, action string, xnames []string, start, end time.Time) {
+	grafanaClient, gcfg := grafanaGetClient(cmd)
+	if grafanaClient == nil {
+		return
+	}
+
+	a := grafana.NewActionAnnotation(action, xnames, start, end, gcfg.Tags...)
+	a.DashboardUID = gcfg.DashboardUID
+	if _, err := grafanaClient.PostAnnotation(a, grafanaToken(cmd)); err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Warn().Err(err).Msg("Grafana annotation request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Warn().Err(err).Msg("failed to push annotation to Grafana")
+		}
+		return
+	}
+	log.Logger.Debug().Msgf("pushed Grafana annotation for %q", action)
+}
@@ -0,0 +1,217 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/artifact"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/pool"
+	"github.com/OpenCHAMI/ochami/pkg/report"
+)
+
+// imageVerifyResult is the result of verifying one artifact of a manifest.
+type imageVerifyResult struct {
+	Image    string `json:"image" yaml:"image"`
+	Kind     string `json:"kind" yaml:"kind"`
+	URI      string `json:"uri" yaml:"uri"`
+	OK       bool   `json:"ok" yaml:"ok"`
+	Size     int64  `json:"size,omitempty" yaml:"size,omitempty"`
+	Checksum string `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// imageVerifyCmd represents the "image verify" command
+var imageVerifyCmd = &cobra.Command{
+	Use:   "verify --manifest <path> [-f <format>] [--concurrency <n>] [--target-timeout <duration>] [--report-format <format> [--report-file <path>]]",
+	Args:  cobra.NoArgs,
+	Short: "Check that the artifacts of boot images are available",
+	Long: `Check that every kernel, initrd, and root filesystem referenced by
+an image manifest is available and, if the manifest specifies them,
+that its size and checksum match. This catches broken image pushes
+before nodes try to boot from them. Artifacts are checked
+concurrently. Those with a checksum are downloaded (but not stored)
+to verify it; others are only checked with a HEAD request.
+
+The manifest is read from the file passed to --manifest ("-" for
+standard input). In YAML, it looks like:
+
+images:
+- name: compute-base
+  params: console=ttyS0,115200 ip=dhcp
+  kernel:
+    uri: https://images.example.com/compute/vmlinuz
+    size: 12345678
+    checksum: sha256:3b9c...
+  initrd:
+    uri: https://images.example.com/compute/initrd.img
+  rootfs:
+    uri: file:///srv/images/compute/rootfs.squashfs
+
+The status of each artifact is printed, and the command fails if any
+artifact could not be verified. Pass --report-format to output the
+results as a JUnit XML or SARIF report for CI systems instead, or to
+--report-file in addition to the normal output.
+
+See ochami-image(1) for more details.`,
+	Example: `  # Verify the artifacts of the images in a manifest
+  ochami image verify --manifest images.json
+
+  # Verify a YAML manifest, giving up on each artifact after 10 minutes
+  ochami image verify --manifest images.yaml -f yaml --target-timeout 10m`,
+	Run: func(cmd *cobra.Command, args []string) {
+		manifestPath, err := cmd.Flags().GetString("manifest")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --manifest")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		concurrency, err := cmd.Flags().GetInt("concurrency")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --concurrency")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if concurrency < 1 {
+			log.Logger.Error().Msg("--concurrency must be at least 1")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		targetTimeout, err := cmd.Flags().GetDuration("target-timeout")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --target-timeout")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		var manifest artifact.Manifest
+		if err := client.ReadPayloadFile(manifestPath, formatInput, &manifest); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to read image manifest")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		refs := manifest.Refs()
+		if len(refs) == 0 {
+			log.Logger.Warn().Msg("image manifest does not reference any artifacts")
+		}
+
+		// Create client to fetch artifacts
+		artifactClient, err := artifact.NewClient(insecure)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("error creating new artifact client")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Check if a CA certificate was passed and load it into client if valid
+		useCACert(artifactClient.OchamiClient)
+
+		// Artifacts are fetched without the client's deadline, so bound
+		// them by the --context-timeout deadline here
+		ctx := context.Background()
+		if !commandDeadline.IsZero() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, commandDeadline)
+			defer cancel()
+		}
+
+		// An artifact that timed out may still finish verifying in the
+		// background, so what it found is only read under lock
+		var (
+			mu      sync.Mutex
+			found   = make([]artifact.Result, len(refs))
+			results = make([]imageVerifyResult, len(refs))
+			errs    = make([]error, len(refs))
+		)
+		pool.Run(ctx, len(refs), concurrency, targetTimeout, func(ctx context.Context, i int) error {
+			res, err := artifactClient.Verify(ctx, refs[i].Artifact)
+			mu.Lock()
+			found[i] = res
+			mu.Unlock()
+			return err
+		}, func(i int, err error) {
+			ref := refs[i]
+			mu.Lock()
+			res := found[i]
+			mu.Unlock()
+			results[i] = imageVerifyResult{Image: ref.Image, Kind: ref.Kind, URI: ref.URI, OK: err == nil, Checksum: res.Checksum}
+			if res.Size > 0 {
+				results[i].Size = res.Size
+			}
+			errs[i] = err
+			if err != nil {
+				results[i].Error = err.Error()
+				log.Logger.Error().Err(err).Msgf("failed to verify %s of image %s", ref.Kind, ref.Image)
+			} else {
+				log.Logger.Info().Msgf("verified %s of image %s", ref.Kind, ref.Image)
+			}
+		})
+
+		// Convert results to report, classifying failures by why the
+		// artifact could not be verified
+		rep := report.Report{Name: "image verify"}
+		var failed int
+		for i, res := range results {
+			repRes := report.Result{Target: res.Image + "/" + res.Kind}
+			if err := errs[i]; err != nil {
+				failed++
+				rule := "artifact-error"
+				switch {
+				case errors.Is(err, artifact.UnavailableError):
+					rule = "artifact-unavailable"
+				case errors.Is(err, artifact.SizeMismatchError):
+					rule = "artifact-size-mismatch"
+				case errors.Is(err, artifact.ChecksumMismatchError):
+					rule = "artifact-checksum-mismatch"
+				case errors.Is(err, pool.TargetTimeoutError):
+					rule = "artifact-timeout"
+				}
+				repRes.Findings = append(repRes.Findings, report.Finding{Rule: rule, Level: report.LevelError, Message: res.Error, File: res.URI})
+			}
+			rep.Results = append(rep.Results, repRes)
+		}
+
+		// Print output, unless replaced by the report
+		if !writeReport(cmd, rep) {
+			if outBytes, err := format.MarshalData(results, formatOutput); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			} else {
+				fmt.Println(string(outBytes))
+			}
+		}
+
+		if failed > 0 {
+			log.Logger.Error().Msgf("%d of %d artifact(s) could not be verified", failed, len(results))
+			exitWithStatus(1)
+		}
+	},
+}
+
+func init() {
+	imageVerifyCmd.Flags().String("manifest", "", "file containing image manifest (can be - to read from stdin)")
+	imageVerifyCmd.Flags().VarP(&formatInput, "format-input", "f", "format of image manifest ("+format.DataFormatList()+")")
+	imageVerifyCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+	imageVerifyCmd.Flags().Int("concurrency", 4, "maximum number of artifacts to verify at once")
+	imageVerifyCmd.Flags().Duration("target-timeout", 0, "maximum time to spend verifying each artifact before marking it failed (0 for no limit)")
+	imageVerifyCmd.Flags().Var(&reportFormat, "report-format", "write results as a report for CI systems (junit,sarif)")
+	imageVerifyCmd.Flags().String("report-file", "-", "file to write report to, or - to write it to standard output instead of the normal output")
+
+	imageVerifyCmd.MarkFlagRequired("manifest")
+
+	imageVerifyCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
+	imageVerifyCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+	imageVerifyCmd.RegisterFlagCompletionFunc("report-format", completionReportFormat)
+
+	imageCmd.AddCommand(imageVerifyCmd)
+}
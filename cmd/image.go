@@ -0,0 +1,31 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// imageCmd represents the image command
+var imageCmd = &cobra.Command{
+	Use:   "image",
+	Args:  cobra.NoArgs,
+	Short: "Work with boot image artifacts",
+	Long: `Work with the artifacts (kernels, initrds, and root filesystems) of
+boot images.
+
+See ochami-image(1) for more details.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Check that all required args are passed
+		if len(args) == 0 {
+			printUsageHandleError(cmd)
+			os.Exit(0)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(imageCmd)
+}
@@ -0,0 +1,201 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/ipam"
+)
+
+// ipamApplyCmd represents the "ipam apply" command
+var ipamApplyCmd = &cobra.Command{
+	Use:   "apply --plan <path> [--smd] [--payload <path> [-f <format>]]",
+	Args:  cobra.NoArgs,
+	Short: "Apply a static IP address plan to SMD and discovery payloads",
+	Long: `Apply a plan written by 'ochami ipam plan', adding the addresses it
+allocated to the interfaces they were allocated for. Addresses that
+interfaces already had when the plan was made are left as they are.
+An address an interface already has on the network of a new one is
+replaced.
+
+With --smd, the ethernet interfaces in SMD are updated. With
+--payload, the interfaces of the discovery payload file are updated
+in place, e.g. so that 'discover static' sends the addresses along
+with the nodes; the file is written in the current version of the
+format (see 'discover migrate'). At least one of them is required.
+Interfaces of the plan that are not in SMD or the payload are skipped
+with a warning.
+
+Before anything is changed, the plan is checked against SMD and the
+payload again. If an address of the plan is now used by another
+interface, e.g. because it was assigned in the meantime, nothing is
+changed; make a new plan instead.
+
+See ochami-ipam(1) for more details.`,
+	Example: `  # Apply a plan to SMD
+  ochami ipam apply --plan plan.yaml --smd
+
+  # Add the addresses of a plan to a payload before sending it
+  ochami ipam apply --plan plan.yaml --payload nodes.yaml -f yaml
+  ochami discover static -d @nodes.yaml -f yaml`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if !cmd.Flag("smd").Changed && !cmd.Flag("payload").Changed {
+			return errors.New("at least one of --smd or --payload is required")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		// Read plan
+		path, err := cmd.Flags().GetString("plan")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --plan")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		// YAML is a superset of JSON, so both can be read as YAML
+		var plan ipam.Plan
+		if err := client.ReadPayloadFile(path, format.DataFormatYaml, &plan); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to read plan")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if plan.Version != ipam.PlanVersion {
+			log.Logger.Error().Msgf("plan has version %d, but only version %d is supported", plan.Version, ipam.PlanVersion)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if n, _ := plan.Count(); n == 0 {
+			log.Logger.Info().Msg("plan has no new addresses, nothing to do")
+			return
+		}
+		toSMD, err := cmd.Flags().GetBool("smd")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --smd")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		payloadFile, err := cmd.Flags().GetString("payload")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --payload")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Check the plan against everything it is applied to before
+		// changing anything
+		var (
+			nodes      discover.NodeList
+			payloadOut []byte
+		)
+		if payloadFile != "" {
+			var data any
+			if err := client.ReadPayloadFile(payloadFile, formatInput, &data); err != nil {
+				log.Logger.Error().Err(err).Msgf("unable to read payload from %s", payloadFile)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			nodes = discoverMigrateNodeList(cmd, data)
+			added, missing, err := plan.ApplyNodeList(&nodes)
+			if err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to apply plan to %s", payloadFile)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			for _, a := range missing {
+				log.Logger.Warn().Msgf("interface is not in payload, skipping: %s", a)
+			}
+			if payloadOut, err = format.MarshalData(nodes, formatInput); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format payload")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			if !bytes.HasSuffix(payloadOut, []byte("\n")) {
+				payloadOut = append(payloadOut, '\n')
+			}
+			log.Logger.Info().Msgf("adding %d address(es) to %s", added, payloadFile)
+		}
+		var updated []smd.EthernetInterface
+		if toSMD {
+			handleToken(cmd)
+			eis, missing, err := plan.ApplyEthernetInterfaces(ipamGetEthernetInterfaces(cmd))
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to apply plan to SMD")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			for _, a := range missing {
+				log.Logger.Warn().Msgf("interface is not in SMD, skipping: %s", a)
+			}
+			updated = eis
+		}
+
+		// Update the payload
+		if payloadFile != "" {
+			if err := os.WriteFile(payloadFile, payloadOut, 0o644); err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to write %s", payloadFile)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+
+		// Update SMD
+		if !toSMD {
+			return
+		}
+		if len(updated) == 0 {
+			log.Logger.Info().Msg("no ethernet interfaces in SMD to update")
+			return
+		}
+		log.Logger.Info().Msgf("updating %d ethernet interface(s) in SMD", len(updated))
+		_, errs, err := smdGetClient(cmd).PatchEthernetInterfaces(updated, token)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to update ethernet interfaces in SMD")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		// Since PatchEthernetInterfaces does the update iteratively, we
+		// need to deal with each error that might have occurred.
+		var errorsOccurred = false
+		for i, err := range errs {
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msgf("SMD ethernet interface request for %s yielded unsuccessful HTTP response", updated[i].MACAddress)
+				} else {
+					log.Logger.Error().Err(err).Msgf("failed to update ethernet interface %s in SMD", updated[i].MACAddress)
+				}
+				errorsOccurred = true
+			}
+		}
+		reportNotAttempted(errs)
+		if errorsOccurred {
+			log.Logger.Warn().Msg("applying plan to SMD completed with errors")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	ipamApplyCmd.Flags().String("plan", "", "path of the plan to apply (JSON or YAML)")
+	ipamApplyCmd.Flags().Bool("smd", false, "add the addresses to the ethernet interfaces in SMD")
+	ipamApplyCmd.Flags().String("payload", "", "discovery payload file to add the addresses to, in place")
+	ipamApplyCmd.Flags().VarP(&formatInput, "format-input", "f", "format of the payload file ("+format.DataFormatList()+")")
+
+	ipamApplyCmd.MarkFlagRequired("plan")
+	ipamApplyCmd.MarkFlagFilename("plan")
+	ipamApplyCmd.MarkFlagFilename("payload")
+
+	ipamApplyCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
+
+	ipamCmd.AddCommand(ipamApplyCmd)
+}
@@ -0,0 +1,187 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/ipam"
+)
+
+// ipamPlanCmd represents the "ipam plan" command
+var ipamPlanCmd = &cobra.Command{
+	Use:   "plan --network <name>=<cidr>... [--per-rack <n>] [--reserve <n>] (-d (<data> | @<path>) [-f <format>] [--no-smd] | --group <group>,...) [-o <file>] [-F <format>]",
+	Args:  cobra.NoArgs,
+	Short: "Allocate static IP addresses for node interfaces",
+	Long: `Allocate a static IP address on each network passed with --network
+for each interface of a set of nodes that does not have one there yet,
+and write the plan, which lists the address of each interface on each
+network. The plan can be reviewed and then applied with 'ochami ipam
+apply'.
+
+The nodes are those of a discovery payload passed with -d (or read
+from standard input), in the same format as for 'discover static', or
+the members of the SMD groups passed with --group, with their ethernet
+interfaces in SMD. Addresses are allocated in order of the nodes and
+their interfaces, skipping those already assigned to any ethernet
+interface in SMD or interface of the payload, the network and
+broadcast addresses, and the --reserve addresses after the network
+address (e.g. for gateways). Interfaces that already have an address
+on a network, in the payload or in SMD, keep it. Pass --no-smd to plan
+a payload without reading SMD.
+
+With --per-rack, the nodes of each rack (cabinet) get addresses from a
+block of that many addresses of their own: those of the rack with the
+lowest cabinet number from the first block of each network, those of
+the next from the second, and so on.
+
+The plan is written to the file passed with -o, or standard output
+if it is omitted or -.
+
+See ochami-ipam(1) for more details.`,
+	Example: `  # Plan management addresses of a payload, 64 per rack
+  ochami ipam plan --network mgmt=10.1.0.0/20 --per-rack 64 -d @nodes.yaml -f yaml -o plan.yaml -F yaml
+
+  # Plan addresses on two networks for the members of an SMD group
+  ochami ipam plan --network mgmt=10.1.0.0/20 --network hsn=10.2.0.0/20 --group compute -o plan.json`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flag("no-smd").Changed && cmd.Flag("group").Changed {
+			return errors.New("--no-smd cannot be used with --group, whose members are read from SMD")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var networks []ipam.Network
+		nws, err := cmd.Flags().GetStringArray("network")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --network")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, s := range nws {
+			nw, err := ipam.ParseNetwork(s)
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("invalid --network")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			networks = append(networks, nw)
+		}
+		var opts ipam.Options
+		if opts.PerRack, err = cmd.Flags().GetInt("per-rack"); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --per-rack")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if opts.Reserve, err = cmd.Flags().GetInt("reserve"); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --reserve")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		noSMD, err := cmd.Flags().GetBool("no-smd")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --no-smd")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		outFile, err := cmd.Flags().GetString("output")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --output")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Get the nodes and the addresses already in use
+		var (
+			nodes []discover.Node
+			eis   []smd.EthernetInterface
+		)
+		if !noSMD {
+			handleToken(cmd)
+			eis = ipamGetEthernetInterfaces(cmd)
+			opts.Used = ipam.UsedAddresses(eis)
+		}
+		if cmd.Flag("group").Changed {
+			groups, err := cmd.Flags().GetStringSlice("group")
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to get value for --group")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			var without []string
+			nodes, without = ipam.NodesFromEthernetInterfaces(smdGetGroupMembers(cmd, groups...), eis)
+			for _, x := range without {
+				log.Logger.Warn().Msgf("group member %s has no ethernet interfaces in SMD, skipping", x)
+			}
+			if len(nodes) == 0 {
+				log.Logger.Error().Msgf("groups %s have no members with ethernet interfaces", strings.Join(groups, ","))
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		} else {
+			nodes = ipam.MergeEthernetInterfaces(discoverReadPayload(cmd).Nodes, eis)
+		}
+
+		// Allocate addresses
+		plan, err := ipam.NewPlan(nodes, networks, opts)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to plan addresses")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		n, existing := plan.Count()
+
+		// Write the plan
+		outBytes, err := format.MarshalData(plan, formatOutput)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format plan")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if outFile == "" || outFile == "-" {
+			fmt.Println(string(outBytes))
+		} else {
+			if !bytes.HasSuffix(outBytes, []byte("\n")) {
+				outBytes = append(outBytes, '\n')
+			}
+			if err := os.WriteFile(outFile, outBytes, 0o644); err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to write %s", outFile)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+		log.Logger.Info().Msgf("planned %d new address(es) for %d node(s), keeping %d existing one(s)", n, len(nodes), existing)
+	},
+}
+
+func init() {
+	ipamPlanCmd.Flags().StringArray("network", []string{}, "network to allocate addresses on (<name>=<cidr>, can be repeated)")
+	ipamPlanCmd.Flags().Int("per-rack", 0, "number of addresses of each network to set aside for each rack (0 to allocate across racks)")
+	ipamPlanCmd.Flags().Int("reserve", 1, "number of addresses after the network address of each network to leave unallocated")
+	ipamPlanCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
+	ipamPlanCmd.Flags().VarP(&discoverFormatInput, "format-input", "f", "format of input payload data ("+discover.PayloadFormatList()+")")
+	ipamPlanCmd.Flags().Bool("validate-schema", false, "check payload against the JSON Schema of the payload format before reading it (see discover schema)")
+	ipamPlanCmd.Flags().StringSlice("group", []string{}, "one or more SMD groups whose members to plan addresses for instead of a payload")
+	ipamPlanCmd.Flags().Bool("no-smd", false, "do not read the ethernet interfaces in SMD to avoid their addresses")
+	ipamPlanCmd.Flags().StringP("output", "o", "-", "file to write the plan to (- for standard output)")
+	ipamPlanCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of the plan ("+format.DataFormatList()+")")
+
+	ipamPlanCmd.MarkFlagRequired("network")
+	ipamPlanCmd.MarkFlagsMutuallyExclusive("data", "group")
+	ipamPlanCmd.MarkFlagFilename("output")
+
+	ipamPlanCmd.RegisterFlagCompletionFunc("format-input", completionDiscoverFormat)
+	ipamPlanCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	ipamCmd.AddCommand(ipamPlanCmd)
+}
@@ -0,0 +1,61 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// ipamCmd represents the ipam command
+var ipamCmd = &cobra.Command{
+	Use:   "ipam",
+	Args:  cobra.NoArgs,
+	Short: "Plan and apply static IP addresses of node interfaces",
+	Long: `Plan static IP addresses of the interfaces of nodes on the networks of
+the cluster, avoiding those already assigned, and apply the plan to
+the ethernet interfaces in SMD and to discovery payloads.
+
+See ochami-ipam(1) for more details.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Check that all required args are passed
+		if len(args) == 0 {
+			printUsageHandleError(cmd)
+			os.Exit(0)
+		}
+	},
+}
+
+// ipamGetEthernetInterfaces returns all ethernet interfaces in SMD. If they
+// cannot be read, the program exits.
+func ipamGetEthernetInterfaces(cmd *cobra.Command) []smd.EthernetInterface {
+	henv, err := smdGetClient(cmd).GetEthernetInterfaces("", token)
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("SMD ethernet interface request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to request ethernet interfaces from SMD")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	var eis []smd.EthernetInterface
+	if err := json.Unmarshal(henv.Body, &eis); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to unmarshal ethernet interfaces")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return eis
+}
+
+func init() {
+	rootCmd.AddCommand(ipamCmd)
+}
@@ -7,22 +7,37 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/lestrrat-go/jwx/jwt"
 	"github.com/spf13/cobra"
 
+	"github.com/OpenCHAMI/ochami/internal/agent"
+	"github.com/OpenCHAMI/ochami/internal/audit"
 	"github.com/OpenCHAMI/ochami/internal/config"
+	oio "github.com/OpenCHAMI/ochami/internal/io"
 	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/internal/usage"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/client/vault"
 	"github.com/OpenCHAMI/ochami/pkg/discover"
 	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/openapi"
+	"github.com/OpenCHAMI/ochami/pkg/ops"
+	"github.com/OpenCHAMI/ochami/pkg/patch"
+	"github.com/OpenCHAMI/ochami/pkg/report"
 
 	"github.com/OpenCHAMI/ochami/internal/version"
 )
@@ -87,6 +102,9 @@ func (i ioStream) askToCreate(path string) (bool, error) {
 // function redisplays the prompt. If the user's response is "y", true is
 // returned. If the user's response is "n", false is returned.
 func (i ioStream) loopYesNo(p string) (bool, error) {
+	// Make sure any paged output has been read before prompting
+	stopPager()
+
 	s := bufio.NewScanner(i.stdin)
 
 	for {
@@ -107,6 +125,175 @@ func (i ioStream) loopYesNo(p string) (bool, error) {
 	return false, s.Err()
 }
 
+// confirmCount prompts the user with p followed by an instruction to type n
+// and reads one line of input. If the input is exactly n, true is returned.
+// Otherwise, false is returned.
+func (i ioStream) confirmCount(p string, n int) (bool, error) {
+	// Make sure any paged output has been read before prompting
+	stopPager()
+
+	s := bufio.NewScanner(i.stdin)
+
+	fmt.Fprintf(i.stderr, "%s Type %d to confirm:", p, n)
+	if !s.Scan() {
+		return false, s.Err()
+	}
+
+	return strings.TrimSpace(s.Text()) == strconv.Itoa(n), nil
+}
+
+// confirmDeletion asks the user to confirm deleting n items of kind (e.g.
+// "component") using prompt, unless --no-confirm was passed. If n is more
+// than delete-threshold (see config.Config), the user must instead confirm the
+// exact number of items, either by typing it or by passing it to
+// --yes-really-delete, and --no-confirm is not enough. If the user declines,
+// the program exits successfully. If the deletion is refused, the program exits
+// with an error.
+func confirmDeletion(cmd *cobra.Command, prompt, kind string, n int) {
+	threshold := config.GlobalConfig.GetDeleteThreshold()
+	if threshold > 0 && n > threshold {
+		if cmd.Flag("yes-really-delete").Changed {
+			confirmed, err := cmd.Flags().GetInt("yes-really-delete")
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to get value for --yes-really-delete")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			if confirmed != n {
+				log.Logger.Error().Msgf("--yes-really-delete %d does not match the %d %s(s) to be deleted", confirmed, n, kind)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			log.Logger.Debug().Msgf("deletion of %d %s(s) confirmed with --yes-really-delete", n, kind)
+			return
+		}
+		if cmd.Flag("no-confirm").Changed {
+			log.Logger.Error().Msgf("refusing to delete %d %s(s), which is more than the delete-threshold of %d, without --yes-really-delete %d", n, kind, threshold, n)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		log.Logger.Debug().Msgf("%d %s(s) to delete is more than delete-threshold of %d, prompting user to confirm count", n, kind, threshold)
+		respDelete, err := ios.confirmCount(fmt.Sprintf("%s This will delete %d %s(s).", prompt, n, kind), n)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("Error fetching user input")
+			os.Exit(1)
+		} else if !respDelete {
+			log.Logger.Warn().Msgf("number of %s(s) to delete not confirmed, aborting deletion", kind)
+			os.Exit(0)
+		}
+		log.Logger.Debug().Msgf("User confirmed count to delete %s(s)", kind)
+		return
+	}
+
+	// Ask before attempting deletion unless --no-confirm was passed
+	if !cmd.Flag("no-confirm").Changed {
+		log.Logger.Debug().Msg("--no-confirm not passed, prompting user to confirm deletion")
+		respDelete, err := ios.loopYesNo(prompt)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("Error fetching user input")
+			os.Exit(1)
+		} else if !respDelete {
+			log.Logger.Info().Msgf("User aborted %s deletion", kind)
+			os.Exit(0)
+		} else {
+			log.Logger.Debug().Msgf("User answered affirmatively to delete %s(s)", kind)
+		}
+	}
+}
+
+// allowMajorImpactFlagUsage is the usage of --allow-major-impact, which commands
+// checked with guardImpact have.
+const allowMajorImpactFlagUsage = "allow affecting more than max-impact-percent of the items in SMD at once"
+
+// exceedsImpact returns whether affecting n of total items is affecting more
+// than percent of them. A percent of 0 disables the check.
+func exceedsImpact(n, total, percent int) bool {
+	return percent > 0 && total > 0 && n*100 > percent*total
+}
+
+// guardImpact refuses to action (e.g. "delete" or "power off") n items of kind
+// (e.g. "component") if that is more than max-impact-percent (see
+// config.Config.GetMaxImpact) of the items in SMD they are part of, unless
+// --allow-major-impact was passed. total is only called to count those items,
+// which is a request to SMD, if the check is enabled. If the action is refused,
+// the program exits with an error.
+func guardImpact(cmd *cobra.Command, action, kind string, n int, total func() int) {
+	if cmd.Flag("allow-major-impact").Changed {
+		log.Logger.Debug().Msgf("--allow-major-impact passed, not checking impact of %s", action)
+		return
+	}
+	cl, _ := getCluster(cmd)
+	percent, err := config.GlobalConfig.GetMaxImpact(cl.Cluster)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("invalid config")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if percent == 0 || n == 0 {
+		return
+	}
+	t := total()
+	if exceedsImpact(n, t, percent) {
+		log.Logger.Error().Msgf("refusing to %s %d of %d %s(s) (%d%%), which is more than the max-impact-percent of %d%%, without --allow-major-impact", action, n, t, kind, n*100/t, percent)
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	log.Logger.Debug().Msgf("%s of %d of %d %s(s) is within max-impact-percent of %d%%", action, n, t, kind, percent)
+}
+
+// writeReport writes r in the format passed to --report-format, if it was
+// passed, to the file passed to --report-file, or to standard output if that is
+// - (the default). True is returned if the report was written to standard
+// output, in which case it replaces the command's normal output. If the report
+// cannot be written, the program exits.
+func writeReport(cmd *cobra.Command, r report.Report) bool {
+	if !cmd.Flag("report-format").Changed {
+		return false
+	}
+	r.ToolVersion = version.Version
+	b, err := r.Marshal(reportFormat)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to generate report")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	reportFile, err := cmd.Flags().GetString("report-file")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --report-file")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if reportFile == "-" {
+		fmt.Println(string(b))
+		return true
+	}
+	if err := os.WriteFile(reportFile, append(b, '\n'), 0644); err != nil {
+		log.Logger.Error().Err(err).Msgf("failed to write report to %s", reportFile)
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	log.Logger.Info().Msgf("wrote %s report to %s", reportFormat, reportFile)
+
+	return false
+}
+
+// countItems returns the number of items in body, which is either a JSON
+// array or a JSON object containing one under key (e.g. "Components" for SMD
+// components). It is used to count the items that deleting all items of a kind
+// would delete.
+func countItems(body []byte, key string) (int, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err == nil {
+		return len(items), nil
+	}
+	var obj map[string][]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal items: %w", err)
+	}
+
+	return len(obj[key]), nil
+}
+
 // initConfig initializes the global configuration for a command, creating the
 // config file if create is true, if it does not already exist.
 func initConfig(cmd *cobra.Command, create bool) error {
@@ -152,8 +339,61 @@ func initConfig(cmd *cobra.Command, create bool) error {
 	return err
 }
 
-// Set log level verbosity based on config file (log.level) or --log-level.
-// The command line option overrides the config file option.
+// verbosityFlag is the value of --verbose, the number of times it was passed.
+// Like a pflag count, each -v increments it and --verbose=<n> sets it. It also
+// accepts --verbose=true and --verbose=false, as when --verbose was a boolean.
+type verbosityFlag int
+
+func (vf verbosityFlag) String() string {
+	return strconv.Itoa(int(vf))
+}
+
+func (vf *verbosityFlag) Set(v string) error {
+	if v == "+1" {
+		*vf++
+		return nil
+	}
+	if b, err := strconv.ParseBool(v); err == nil {
+		switch {
+		case !b:
+			*vf = 0
+		case *vf == 0:
+			*vf = 1
+		}
+		return nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return fmt.Errorf("must be true, false, or the number of times to increase verbosity")
+	}
+	*vf = verbosityFlag(n)
+
+	return nil
+}
+
+func (vf verbosityFlag) Type() string {
+	return "count"
+}
+
+// verbosityLogLevel returns the log level that corresponds to --quiet (which
+// overrides any verbosity) or to verbosity, the number of times --verbose was
+// passed. If neither was passed, an empty string is returned to signify that
+// the configured log level should be left alone.
+func verbosityLogLevel(verbosity int, quiet bool) string {
+	switch {
+	case quiet:
+		return "error"
+	case verbosity == 1:
+		return "info"
+	case verbosity >= 2:
+		return "debug"
+	default:
+		return ""
+	}
+}
+
+// Set log level verbosity based on config file (log.level), --quiet/--verbose,
+// or --log-level, in increasing order of precedence.
 func initLogging(cmd *cobra.Command) error {
 	if cmd.Flags().Changed("log-format") {
 		lf, err := cmd.Flags().GetString("log-format")
@@ -162,6 +402,9 @@ func initLogging(cmd *cobra.Command) error {
 		}
 		config.GlobalConfig.Log.Format = lf
 	}
+	if ll := verbosityLogLevel(verbosity, quiet); ll != "" {
+		config.GlobalConfig.Log.Level = ll
+	}
 	if cmd.Flags().Changed("log-level") {
 		ll, err := cmd.Flags().GetString("log-level")
 		if err != nil {
@@ -184,6 +427,9 @@ func initLogging(cmd *cobra.Command) error {
 // missing. This creation only applies when a config file is explicitly
 // specified on the command line and not the merged config.
 func initConfigAndLogging(cmd *cobra.Command, createCfg bool) {
+	// Print early log messages if --verbose was passed
+	log.EarlyLogger.EarlyVerbose = verbosity > 0
+
 	if err := initConfig(cmd, createCfg); err != nil {
 		el.BasicLogf("failed to initialize config: %v", err)
 		el.BasicLogf("see '%s --help' for long command help", cmd.CommandPath())
@@ -194,6 +440,27 @@ func initConfigAndLogging(cmd *cobra.Command, createCfg bool) {
 		el.BasicLogf("see '%s --help' for long command help", cmd.CommandPath())
 		os.Exit(1)
 	}
+
+	// Warn about any deprecated command or flag names used
+	handleDeprecations(cmd)
+
+	// Record usage of cmd if usage-stats is configured
+	usageSetCommand(cmd)
+
+	// Refuse to run cmd if the policy of the cluster does not allow it
+	enforcePolicy(cmd)
+
+	// Refuse to run cmd in read-only mode if it changes cluster data
+	enforceReadOnly(cmd)
+
+	// Start the clock for --context-timeout
+	if contextTimeout > 0 {
+		commandDeadline = time.Now().Add(contextTimeout)
+		log.Logger.Debug().Msgf("command deadline is %s", commandDeadline.Format(time.RFC3339))
+	}
+
+	// Page long output if printing to a terminal
+	startPager(cmd)
 }
 
 // createIfNotExists creates path (a file with optional leading directories) if
@@ -286,7 +553,9 @@ func getBaseURISMD(cmd *cobra.Command) (string, error) {
 	return getBaseURI(cmd, config.ServiceSMD)
 }
 
-func getBaseURI(cmd *cobra.Command, serviceName config.ServiceName) (string, error) {
+// getClusterConfig returns the name and configuration of the cluster to use for
+// requests to serviceName, with any URIs passed on the command line merged in.
+func getClusterConfig(cmd *cobra.Command, serviceName config.ServiceName) (string, config.ConfigClusterConfig, error) {
 	// Precedence of getting base URI for requests (higher numbers override
 	// all preceding numbers):
 	//
@@ -299,6 +568,7 @@ func getBaseURI(cmd *cobra.Command, serviceName config.ServiceName) (string, err
 	var (
 		clusterName   string
 		clusterToUse  config.ConfigCluster
+		clusterFound  bool
 		clusterConfig config.ConfigClusterConfig
 		clusterList   = config.GlobalConfig.Clusters
 	)
@@ -310,11 +580,12 @@ func getBaseURI(cmd *cobra.Command, serviceName config.ServiceName) (string, err
 		for _, c := range clusterList {
 			if c.Name == clusterName {
 				clusterToUse = c
+				clusterFound = true
 				break
 			}
 		}
-		if clusterToUse == (config.ConfigCluster{}) {
-			return "", fmt.Errorf("default cluster %s not found", clusterName)
+		if !clusterFound {
+			return clusterName, clusterConfig, fmt.Errorf("default cluster %s not found", clusterName)
 		}
 		clusterConfig = clusterToUse.Cluster
 	} else if cmd.Flag("cluster").Changed {
@@ -324,35 +595,54 @@ func getBaseURI(cmd *cobra.Command, serviceName config.ServiceName) (string, err
 		for _, c := range clusterList {
 			if c.Name == clusterName {
 				clusterToUse = c
+				clusterFound = true
 				break
 			}
 		}
-		if clusterToUse == (config.ConfigCluster{}) {
-			return "", fmt.Errorf("cluster %s not found", clusterName)
+		if !clusterFound {
+			return clusterName, clusterConfig, fmt.Errorf("cluster %s not found", clusterName)
 		}
 
 		clusterConfig = clusterToUse.Cluster
 	}
 	// 1. Check flags (--cluster-uri and/or --uri) and override any
 	// previously-set values while leaving unspecified ones alone.
-	if cmd.Flag("cluster-uri").Changed || (cmd.Flag("uri") != nil && cmd.Flag("uri").Changed) {
+	//
+	// --uri belongs to the service metacommand (e.g. "ochami smd"), so it is
+	// only used if the command is under the metacommand of serviceName. This
+	// keeps commands that talk to multiple services from applying one
+	// service's --uri to another.
+	uriFlag := ""
+	if cmd.Flag("uri") != nil && cmd.Flag("uri").Changed && cmdServiceName(cmd) == string(serviceName) {
+		uriFlag = cmd.Flag("uri").Value.String()
+	}
+	if cmd.Flag("cluster-uri").Changed || uriFlag != "" {
 		log.Logger.Debug().Msg("using base URI passed on command line")
 		ccc := config.ConfigClusterConfig{URI: cmd.Flag("cluster-uri").Value.String()}
 		switch serviceName {
 		case config.ServiceBSS:
-			ccc.BSS.URI = cmd.Flag("uri").Value.String()
+			ccc.BSS.URI = uriFlag
 		case config.ServiceCloudInit:
-			ccc.CloudInit.URI = cmd.Flag("uri").Value.String()
+			ccc.CloudInit.URI = uriFlag
 		case config.ServicePCS:
-			ccc.PCS.URI = cmd.Flag("uri").Value.String()
+			ccc.PCS.URI = uriFlag
 		case config.ServiceSMD:
-			ccc.SMD.URI = cmd.Flag("uri").Value.String()
+			ccc.SMD.URI = uriFlag
 		default:
-			return "", fmt.Errorf("unknown service %q specified when generating base URI", serviceName)
+			return clusterName, clusterConfig, fmt.Errorf("unknown service %q specified when generating base URI", serviceName)
 		}
 		clusterConfig = clusterConfig.MergeURIConfig(ccc)
 	}
 
+	return clusterName, clusterConfig, nil
+}
+
+func getBaseURI(cmd *cobra.Command, serviceName config.ServiceName) (string, error) {
+	clusterName, clusterConfig, err := getClusterConfig(cmd, serviceName)
+	if err != nil {
+		return "", err
+	}
+
 	baseURI, err := clusterConfig.GetServiceBaseURI(serviceName)
 	if err != nil {
 		if strings.TrimSpace(clusterName) != "" {
@@ -365,6 +655,456 @@ func getBaseURI(cmd *cobra.Command, serviceName config.ServiceName) (string, err
 	return baseURI, err
 }
 
+// getServicePaths returns the endpoint path overrides configured for
+// serviceName in the cluster being used. If the cluster cannot be determined,
+// no overrides are returned since getBaseURI will have already reported the
+// error.
+func getServicePaths(cmd *cobra.Command, serviceName config.ServiceName) map[string]string {
+	_, clusterConfig, err := getClusterConfig(cmd, serviceName)
+	if err != nil {
+		return nil
+	}
+	paths, err := clusterConfig.GetServicePaths(serviceName)
+	if err != nil {
+		log.Logger.Warn().Err(err).Msgf("failed to get endpoint path overrides for %s", serviceName)
+		return nil
+	}
+	return paths
+}
+
+// cmdServiceName returns the name of the top-level command that cmd is under,
+// e.g. "smd" for "ochami smd component get". Service metacommands are named
+// after the service they communicate with.
+func cmdServiceName(cmd *cobra.Command) string {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Parent() != nil && !c.Parent().HasParent() {
+			return c.Name()
+		}
+	}
+	return ""
+}
+
+// readOnlyEnabled returns true if read-only mode is enabled, either by
+// --read-only, by read-only in the config file, or by read-only in the config of
+// the cluster being used (passed via --cluster or set as default-cluster).
+func readOnlyEnabled(cmd *cobra.Command) bool {
+	if cmd.Flag("read-only").Changed {
+		ro, err := cmd.Flags().GetBool("read-only")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --read-only")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		return ro
+	}
+	if config.GlobalConfig.ReadOnly {
+		return true
+	}
+
+	cl, found := getCluster(cmd)
+
+	return found && cl.Cluster.ReadOnly
+}
+
+// payloadValidator returns a function that validates the payloads of requests
+// made with oc against the OpenAPI spec of the service if
+// --validate-against-server was passed, or nil otherwise. The spec is fetched
+// from the service (at client.OpenAPIRelpath, which may be overridden in the
+// paths of the cluster config) once, before the first request with a payload
+// is sent. If it cannot be fetched, requests with a payload are not sent.
+// Requests for which the spec has no schema are sent without validation.
+func payloadValidator(cmd *cobra.Command, oc *client.OchamiClient) func(method, uri string, body client.HTTPBody) error {
+	if v, err := cmd.Flags().GetBool("validate-against-server"); err != nil || !v {
+		return nil
+	}
+
+	var (
+		spec    *openapi.Spec
+		specErr error
+		fetched bool
+	)
+	return func(method, uri string, body client.HTTPBody) error {
+		if !fetched {
+			fetched = true
+			headers := client.NewHTTPHeaders()
+			if token != "" {
+				if err := headers.SetAuthorization(token); err != nil {
+					specErr = fmt.Errorf("error setting token in HTTP headers: %w", err)
+					return specErr
+				}
+			}
+			henv, err := oc.GetData(client.OpenAPIRelpath, "", headers)
+			if err != nil {
+				specErr = fmt.Errorf("failed to fetch OpenAPI spec of %s to validate against: %w", oc.ServiceName, err)
+				return specErr
+			}
+			if spec, err = openapi.Load(henv.Body); err != nil {
+				specErr = fmt.Errorf("failed to load OpenAPI spec of %s: %w", oc.ServiceName, err)
+				return specErr
+			}
+			log.Logger.Debug().Msgf("validating payloads against OpenAPI spec of %s", oc.ServiceName)
+		}
+		if specErr != nil {
+			return specErr
+		}
+
+		path := uri
+		if u, err := url.Parse(uri); err == nil {
+			path = u.Path
+		}
+		err := spec.Validate(method, path, body)
+		if errors.Is(err, openapi.NoSchemaError) {
+			log.Logger.Warn().Msgf("OpenAPI spec of %s has no schema for %s %s, sending payload without validating it", oc.ServiceName, method, path)
+			return nil
+		}
+
+		return err
+	}
+}
+
+// policyExemptCommands are the top-level commands that are never restricted by
+// cluster policies, since they do not contact a cluster and are needed to
+// inspect and fix the config.
+var policyExemptCommands = []string{"config", "version", "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd}
+
+// enforcePolicy exits with an error if the policy of the cluster being used
+// (cluster.policy in the config file) does not allow cmd to be run against it.
+func enforcePolicy(cmd *cobra.Command) {
+	cl, found := getCluster(cmd)
+	if !found || cmd == cmd.Root() {
+		return
+	}
+	command := policyCommand(cmd)
+	if slices.Contains(policyExemptCommands, strings.Fields(command)[0]) {
+		return
+	}
+	if ok, reason := cl.Cluster.Policy.Allows(command); !ok {
+		log.Logger.Error().Msgf("policy of cluster %s does not allow '%s' (%s)", cl.Name, command, reason)
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+}
+
+// mutatingCommands are the commands that change data in a cluster and are
+// therefore refused in read-only mode, given as patterns matched like those of
+// cluster policies (see config.PolicyPatternMatches). The first pattern that
+// matches a command decides. If when is set, the command only changes cluster
+// data if it returns true.
+var mutatingCommands = []struct {
+	pattern string
+	when    func(cmd *cobra.Command) bool
+}{
+	{pattern: "vars set", when: func(cmd *cobra.Command) bool { return varsFile(cmd) == "" }},
+	{pattern: "fsck", when: func(cmd *cobra.Command) bool { return cmd.Flag("fix").Changed }},
+	{pattern: "ipam apply", when: func(cmd *cobra.Command) bool { return cmd.Flag("smd").Changed }},
+	{pattern: "pcs transition show", when: func(cmd *cobra.Command) bool { return cmd.Flag("annotate").Changed }},
+	{pattern: "add"},
+	{pattern: "set"},
+	{pattern: "unset"},
+	{pattern: "update"},
+	{pattern: "delete"},
+	{pattern: "import"},
+	{pattern: "discover apply"},
+	{pattern: "restore"},
+	{pattern: "rename"},
+	{pattern: "clone"},
+	{pattern: "retag"},
+	{pattern: "rotate-creds"},
+	{pattern: "rediscover"},
+	{pattern: "reimage"},
+	{pattern: "delete-subtree"},
+	{pattern: "discover static"},
+	{pattern: "discover magellan"},
+	{pattern: "pcs transition start"},
+	{pattern: "pcs transition abort"},
+	{pattern: "pcs power off"},
+	{pattern: "smd export grafana-annotations"},
+}
+
+// enforceReadOnly exits with an error if read-only mode is enabled (see
+// readOnlyEnabled) and cmd changes cluster data (see isMutating), so that it is
+// refused before it does any work instead of at its first mutating request.
+func enforceReadOnly(cmd *cobra.Command) {
+	if !isMutating(cmd) || !readOnlyEnabled(cmd) {
+		return
+	}
+	log.Logger.Error().Msgf("read-only mode is enabled, refusing to run '%s', which changes cluster data", policyCommand(cmd))
+	logHelpError(cmd)
+	os.Exit(1)
+}
+
+// isMutating returns whether cmd, as it was invoked, changes cluster data, i.e.
+// the command it runs (see policyCommand) is one of mutatingCommands. Commands
+// run with --dry-run change nothing.
+func isMutating(cmd *cobra.Command) bool {
+	if cmd == cmd.Root() {
+		return false
+	}
+	command := policyCommand(cmd)
+	if slices.Contains(policyExemptCommands, strings.Fields(command)[0]) {
+		return false
+	}
+	if cmd.Flags().Lookup("dry-run") != nil {
+		if dryRun, err := cmd.Flags().GetBool("dry-run"); err == nil && dryRun {
+			return false
+		}
+	}
+	for _, mc := range mutatingCommands {
+		if config.PolicyPatternMatches(mc.pattern, command) {
+			return mc.when == nil || mc.when(cmd)
+		}
+	}
+
+	return false
+}
+
+// policyCommand returns the path of cmd without the program name that cluster
+// policies are matched against. For a deprecated alias of a command, this is
+// the path of the command it runs, so that an alias cannot be used to get
+// around a policy.
+func policyCommand(cmd *cobra.Command) string {
+	target := aliasTarget(cmd)
+
+	return strings.TrimPrefix(target.CommandPath(), target.Root().Name()+" ")
+}
+
+// maxMemoryBuffer returns the size in bytes above which response bodies of
+// large dumps are spilled to a temporary file, passed with --max-memory-buffer
+// or set as max-memory-buffer in the config file, or 0 (meaning the client
+// default) if neither is set. If the size is invalid, the program exits.
+func maxMemoryBuffer(cmd *cobra.Command) int64 {
+	if cmd.Flag("max-memory-buffer").Changed {
+		s, err := cmd.Flags().GetString("max-memory-buffer")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --max-memory-buffer")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		n, err := config.ParseByteSize(s)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("invalid --max-memory-buffer")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		return n
+	}
+	n, err := config.GlobalConfig.GetMaxMemoryBuffer()
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("invalid config")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return n
+}
+
+// onBehalfOf returns the user passed with --as, if any, and the request header
+// to send it in, which is impersonation-header in the config of the cluster
+// being used or, if unset, client.DefaultImpersonationHeader.
+func onBehalfOf(cmd *cobra.Command) (user, header string) {
+	user, err := cmd.Flags().GetString("as")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --as")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if user == "" {
+		return "", ""
+	}
+	header = client.DefaultImpersonationHeader
+	if cl, ok := getCluster(cmd); ok && cl.Cluster.ImpersonationHeader != "" {
+		header = cl.Cluster.ImpersonationHeader
+	}
+
+	return user, header
+}
+
+// auditLog is the audit log opened by getAuditLog, shared by all clients, and
+// auditLogOpened whether getAuditLog has been called.
+var (
+	auditLog       *audit.Log
+	auditLogOpened bool
+)
+
+// getAuditLog returns the audit log at audit-log in the config file, opening it
+// the first time, or nil if audit-log is unset. Since the audit log is meant to
+// make actions traceable, failing to open it is fatal.
+func getAuditLog(cmd *cobra.Command) *audit.Log {
+	if auditLogOpened {
+		return auditLog
+	}
+	auditLogOpened = true
+	path := config.GlobalConfig.AuditLog
+	if path == "" {
+		if cmd.Flag("as").Changed {
+			log.Logger.Warn().Msg("--as passed but audit-log is not set in the config file, actions will not be recorded locally")
+		}
+		return nil
+	}
+	clusterName := cmd.Flag("cluster-uri").Value.String()
+	if cl, ok := getCluster(cmd); ok {
+		clusterName = cl.Name
+	}
+	var err error
+	if auditLog, err = audit.Open(path, clusterName); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to open audit log")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	log.Logger.Debug().Msgf("recording requests that modify data in audit log %s", path)
+
+	return auditLog
+}
+
+// annotate returns s, the description or name of a record the command creates
+// or changes, stamped with an annotation (see smd.Annotation) of the user
+// passed with --created-by or, if unset, --as or the current local user, the
+// current time, and --comment. Unless --no-annotate is passed, in which case s
+// is returned as is.
+func annotate(cmd *cobra.Command, s string) string {
+	if noAnnotate, _ := cmd.Flags().GetBool("no-annotate"); noAnnotate {
+		return s
+	}
+	by, _ := cmd.Flags().GetString("created-by")
+	if by == "" {
+		by, _ = cmd.Flags().GetString("as")
+	}
+	if by == "" {
+		by = audit.CurrentUser()
+	}
+	comment, _ := cmd.Flags().GetString("comment")
+
+	return smd.Annotate(s, smd.Annotation{By: by, At: time.Now(), Comment: comment})
+}
+
+// reportNotAttempted logs a warning summarizing how many of the requests of a
+// bulk operation, whose per-request errors are errs, were not attempted because
+// the --context-timeout deadline passed. The individual requests are reported
+// by the caller as usual, which also decides the exit status, since requests
+// that were not attempted are among the errors it checks.
+func reportNotAttempted(errs []error) {
+	var n int
+	for _, e := range errs {
+		if errors.Is(e, client.NotAttemptedError) {
+			n++
+		}
+	}
+	if n > 0 {
+		log.Logger.Warn().Msgf("%d of %d item(s) not attempted: --context-timeout of %s exceeded", n, len(errs), contextTimeout)
+	}
+}
+
+// Usages of --if-not-exists and --if-exists, which add and delete commands
+// checked with ignoreStatus have.
+const (
+	ifNotExistsFlagUsage = "succeed with a note instead of failing for items that already exist (409)"
+	ifExistsFlagUsage    = "succeed with a note instead of failing for items that do not exist (404)"
+)
+
+// ignoredStatusNotes are the notes logged by ignoreStatus for each status.
+var ignoredStatusNotes = map[int]string{
+	http.StatusConflict: "already exists",
+	http.StatusNotFound: "does not exist",
+}
+
+// ignoreStatus clears the errors in errs of the requests whose responses in
+// henvs have status if flag (e.g. "if-not-exists") was passed to cmd, so that
+// idempotent automation need not check first. An informational note is logged
+// for each with the kind and, from items, ID of the item the request was for.
+// henvs, errs, and items correspond, as returned by the bulk client functions.
+// It returns the number of errors cleared.
+func ignoreStatus(cmd *cobra.Command, flag string, status int, kind string, henvs []client.HTTPEnvelope, errs []error, items []string) int {
+	if f := cmd.Flag(flag); f == nil || !f.Changed {
+		return 0
+	}
+	var n int
+	for i, e := range errs {
+		if e == nil || i >= len(henvs) || henvs[i].StatusCode != status {
+			continue
+		}
+		item := kind
+		if i < len(items) && items[i] != "" {
+			item += " " + items[i]
+		}
+		log.Logger.Info().Msgf("%s: %s, ignoring (--%s)", item, ignoredStatusNotes[status], flag)
+		errs[i] = nil
+		n++
+	}
+
+	return n
+}
+
+// ignoreStatusOne is ignoreStatus for a single request, returning its error
+// if it was not cleared.
+func ignoreStatusOne(cmd *cobra.Command, flag string, status int, kind string, henv client.HTTPEnvelope, err error, item string) error {
+	errs := []error{err}
+	ignoreStatus(cmd, flag, status, kind, []client.HTTPEnvelope{henv}, errs, []string{item})
+
+	return errs[0]
+}
+
+// parseSince parses s as either an RFC 3339 timestamp or a duration before now
+// (e.g. 36h), returning the resulting time. In addition to the units accepted
+// by time.ParseDuration, durations may be in whole days (e.g. 7d).
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return time.Time{}, fmt.Errorf("invalid number of days: %q", s)
+		}
+		return now.AddDate(0, 0, -n), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 timestamp nor a positive duration", s)
+	}
+
+	return now.Add(-d), nil
+}
+
+// exitWithStatus exits the program with status after finishing paging output,
+// if any, and notifying sinks if the command notifies (see notifyOnExit). If
+// --fail-on-warn was passed and status is 0, the program instead exits with
+// status 1 if any warnings were logged, listing them as the reasons for failing.
+func exitWithStatus(status int) {
+	stopPager()
+	if failOnWarn && status == 0 {
+		if warnings := log.Warnings(); len(warnings) > 0 {
+			usageRecord(1, usage.FailureWarnings)
+			log.Logger.Error().Msgf("failing because --fail-on-warn was passed and %d warning(s) occurred:", len(warnings))
+			for _, w := range warnings {
+				fmt.Fprintf(os.Stderr, "  - %s\n", w)
+			}
+			status = 1
+		}
+	}
+	usageRecord(status, "")
+	notifySend(status)
+	os.Exit(status)
+}
+
+// getCluster returns the config of the cluster being used, i.e. the one passed
+// via --cluster or, if not passed, default-cluster. If neither is set or the
+// cluster is not in the config, false is returned.
+func getCluster(cmd *cobra.Command) (config.ConfigCluster, bool) {
+	clusterName := config.GlobalConfig.DefaultCluster
+	if cmd.Flag("cluster").Changed {
+		clusterName = cmd.Flag("cluster").Value.String()
+	}
+	if clusterName == "" {
+		return config.ConfigCluster{}, false
+	}
+	cl, err := config.GlobalConfig.GetCluster(clusterName)
+	if err != nil {
+		return config.ConfigCluster{}, false
+	}
+
+	return cl, true
+}
+
 // handleToken is a wrapper function around code that reads, checks, and
 // performs any other setup tasks for tokens. It is called by all commands that
 // require a token.
@@ -420,14 +1160,12 @@ func handleToken(cmd *cobra.Command) {
 // The value of <CLUSTER> is determined by taking the cluster name, passed
 // either by --cluster or reading default-cluster from the config file (the
 // former preceding the latter), replacing spaces and dashes (-) with
-// underscores, and making the letters uppercase. If no config file is set or
-// the environment variable is not set, an error is logged and the program
+// underscores, and making the letters uppercase. If the environment variable
+// is not set, the cluster's access-token is used, decrypting it if needed. If
+// no config file is set or neither is set, an error is logged and the program
 // exits.
 func setToken(cmd *cobra.Command) {
-	var (
-		clusterName string
-		varPrefix   string
-	)
+	var clusterName string
 	if cmd.Flag("token").Changed {
 		token = cmd.Flag("token").Value.String()
 		log.Logger.Debug().Msg("--token passed, setting token to its value: " + token)
@@ -447,22 +1185,188 @@ func setToken(cmd *cobra.Command) {
 		os.Exit(1)
 	}
 
-	varPrefix = strings.ReplaceAll(clusterName, "-", "_")
-	varPrefix = strings.ReplaceAll(varPrefix, " ", "_")
-
-	envVarToRead := strings.ToUpper(varPrefix) + "_ACCESS_TOKEN"
+	envVarToRead := clusterEnvVar(clusterName, "ACCESS_TOKEN")
 	log.Logger.Debug().Msg("Reading token from environment variable: " + envVarToRead)
 	if t, tokenSet := os.LookupEnv(envVarToRead); tokenSet {
 		log.Logger.Debug().Msgf("Token found from environment variable: %s=%s", envVarToRead, t)
 		token = t
 		return
 	}
+	if t, ok := agentToken(cmd, clusterName); ok {
+		log.Logger.Debug().Msgf("%s unset, using token of agent of cluster %q", envVarToRead, clusterName)
+		token = t
+		return
+	}
+	if t, err := clusterConfigToken(clusterName); err != nil {
+		log.Logger.Error().Err(err).Msgf("failed to get token of cluster %q from config", clusterName)
+		logHelpError(cmd)
+		os.Exit(1)
+	} else if t != "" {
+		log.Logger.Debug().Msgf("%s unset, using token from config of cluster %q", envVarToRead, clusterName)
+		token = t
+		return
+	}
 
 	log.Logger.Error().Msgf("Environment variable %s unset for reading token for cluster %q", envVarToRead, clusterName)
 	os.Exit(1)
 	logHelpError(cmd)
 }
 
+// clusterConfigToken returns the access token that the config of clusterName
+// provides: the output of its token-command if set, otherwise its
+// access-token, decrypted if it was encrypted. If neither is set (or the
+// cluster is not in the config), an empty token is returned.
+func clusterConfigToken(clusterName string) (string, error) {
+	cl, err := config.GlobalConfig.GetCluster(clusterName)
+	if err != nil {
+		return "", nil
+	}
+	switch {
+	case cl.Cluster.TokenCommand != "":
+		log.Logger.Debug().Msgf("running token-command of cluster %q", clusterName)
+		return agent.CommandTokenSource(cl.Cluster.TokenCommand)()
+	case config.IsEncrypted(cl.Cluster.AccessToken):
+		log.Logger.Debug().Msg("decrypting access-token from config")
+		return config.DecryptValue(config.GlobalConfig.Encryption, cl.Cluster.AccessToken)
+	default:
+		return cl.Cluster.AccessToken, nil
+	}
+}
+
+// agentDisabled returns whether cmd should not use the ochami agent of the
+// cluster, either because --no-agent was passed or because this process is the
+// agent.
+func agentDisabled(cmd *cobra.Command) bool {
+	return isAgent || cmd.Flag("no-agent").Changed
+}
+
+// agentSocket returns the socket of the ochami agent of clusterName and
+// whether it can be used, i.e. it exists and is owned by the current user.
+func agentSocket(clusterName string) (string, bool) {
+	socket := agent.DefaultSocketPath(clusterName)
+	if _, err := os.Lstat(socket); err != nil {
+		return "", false
+	}
+	if err := agent.CheckSocket(socket); err != nil {
+		log.Logger.Warn().Err(err).Msg("not using ochami agent")
+		return "", false
+	}
+
+	return socket, true
+}
+
+// agentToken returns the token of the ochami agent of clusterName and whether
+// one is running and has a token.
+func agentToken(cmd *cobra.Command, clusterName string) (string, bool) {
+	if agentDisabled(cmd) {
+		return "", false
+	}
+	socket, ok := agentSocket(clusterName)
+	if !ok {
+		return "", false
+	}
+	t, err := agent.GetToken(socket)
+	if err != nil {
+		log.Logger.Debug().Err(err).Msgf("failed to get token from agent at %s", socket)
+		return "", false
+	}
+
+	return t.Value, true
+}
+
+// useAgent configures oc to send GET requests through the ochami agent of the
+// cluster, if one is running, so that they are served from its cache. Requests
+// the agent does not serve are sent directly.
+func useAgent(cmd *cobra.Command, oc *client.OchamiClient) {
+	if agentDisabled(cmd) {
+		return
+	}
+	cl, ok := getCluster(cmd)
+	if !ok {
+		return
+	}
+	socket, ok := agentSocket(cl.Name)
+	if !ok {
+		return
+	}
+	log.Logger.Debug().Msgf("sending GET requests to %s through agent at %s", oc.ServiceName, socket)
+	c := *oc.Client
+	c.Transport = agent.Transport(socket, c.Transport)
+	oc.Client = &c
+}
+
+// clusterEnvVar returns the name of the cluster-specific environment variable
+// <CLUSTER>_<suffix>, where <CLUSTER> is clusterName with spaces and dashes (-)
+// replaced with underscores, in upper case.
+func clusterEnvVar(clusterName, suffix string) string {
+	varPrefix := strings.ReplaceAll(clusterName, "-", "_")
+	varPrefix = strings.ReplaceAll(varPrefix, " ", "_")
+
+	return strings.ToUpper(varPrefix) + "_" + suffix
+}
+
+// configSecret returns the plaintext of the sensitive config value v, named name
+// in log messages, decrypting it if it was encrypted by 'ochami config encrypt'.
+// The program exits if it cannot be decrypted.
+func configSecret(cmd *cobra.Command, name, v string) string {
+	if !config.IsEncrypted(v) {
+		return v
+	}
+	log.Logger.Debug().Msgf("decrypting %s from config", name)
+	s, err := config.DecryptValue(config.GlobalConfig.Encryption, v)
+	if err != nil {
+		log.Logger.Error().Err(err).Msgf("failed to decrypt %s from config", name)
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return s
+}
+
+// vaultGetClient returns a client for Vault and the token to use with it if
+// secrets.vault-uri is set for the cluster being used or VAULT_ADDR is set, or
+// nil otherwise. The token is the one in <CLUSTER>_VAULT_TOKEN (see
+// clusterEnvVar), secrets.vault-token, or VAULT_TOKEN, in that order. The
+// program exits if the client cannot be created.
+func vaultGetClient(cmd *cobra.Command) (*vault.VaultClient, string) {
+	var vaultURI, vaultToken string
+	cl, found := getCluster(cmd)
+	if found {
+		vaultURI = cl.Cluster.Secrets.VaultURI
+		vaultToken = os.Getenv(clusterEnvVar(cl.Name, "VAULT_TOKEN"))
+		if vaultToken == "" && cl.Cluster.Secrets.VaultToken != "" {
+			vaultToken = configSecret(cmd, "secrets.vault-token", cl.Cluster.Secrets.VaultToken)
+		}
+	}
+	if vaultURI == "" {
+		vaultURI = os.Getenv("VAULT_ADDR")
+	}
+	if vaultToken == "" {
+		vaultToken = os.Getenv("VAULT_TOKEN")
+	}
+	if vaultURI == "" {
+		return nil, ""
+	}
+
+	vaultClient, err := vault.NewClient(vaultURI, insecure)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("error creating new Vault client")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	// Check if a CA certificate was passed and load it into client if valid
+	useCACert(vaultClient.OchamiClient)
+
+	// Refuse to write secrets in read-only mode
+	vaultClient.ReadOnly = readOnlyEnabled(cmd)
+
+	// Stop sending requests once the --context-timeout deadline passes
+	vaultClient.Deadline = commandDeadline
+
+	return vaultClient, vaultToken
+}
+
 // handlePayload unmarshals raw data or data from a payload file into v for
 // command cmd if --data and, optionally, --format-input, are passed.
 func handlePayload(cmd *cobra.Command, v any) {
@@ -485,6 +1389,107 @@ func handlePayloadStdin(cmd *cobra.Command, v any) {
 	}
 }
 
+// handlePayloadRaw returns the raw payload passed via --data, from the file it
+// names if it starts with @, or from standard input if it is not passed or is
+// - or @-, without unmarshalling it. If it cannot be read, the program exits.
+func handlePayloadRaw(cmd *cobra.Command) []byte {
+	var (
+		raw []byte
+		err error
+	)
+	data := cmd.Flag("data").Value.String()
+	switch {
+	case !cmd.Flag("data").Changed || data == "-" || data == "@-":
+		raw, err = oio.ReadStdin()
+	case strings.HasPrefix(data, "@"):
+		raw, err = os.ReadFile(strings.TrimPrefix(data, "@"))
+	default:
+		raw = []byte(data)
+	}
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("unable to read payload data or file")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return raw
+}
+
+// handlePatchRaw reads the patch document passed via --patch (in the format
+// specified by --format-input) and returns it as JSON, to be passed to
+// handlePatch. If an error occurs, the program exits.
+func handlePatchRaw(cmd *cobra.Command) []byte {
+	var patchDoc any
+	if err := client.ReadPayload(cmd.Flag("patch").Value.String(), formatInput, &patchDoc); err != nil {
+		log.Logger.Error().Err(err).Msg("unable to read patch data or file")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	patchBytes, err := json.Marshal(patchDoc)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to marshal patch into JSON")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return patchBytes
+}
+
+// handlePatch applies the JSON patch document patchBytes (as returned by
+// handlePatchRaw) client-side to current according to --patch-type, and
+// unmarshals the patched result into result. If an error occurs, the program
+// exits.
+func handlePatch(cmd *cobra.Command, patchBytes []byte, current, result any) {
+	currentBytes, err := json.Marshal(current)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to marshal current data into JSON")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	log.Logger.Debug().Msgf("applying %s patch %s to %s", patchType, patchBytes, currentBytes)
+	patchedBytes, err := patch.Apply(currentBytes, patchBytes, patchType)
+	if err != nil {
+		log.Logger.Error().Err(err).Msgf("failed to apply %s patch", patchType)
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if err := json.Unmarshal(patchedBytes, result); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to unmarshal patched data")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+}
+
+// writeFileAtomic writes content to a temporary file next to path that then
+// replaces path with mode perm, so that readers never see a partially written
+// file.
+func writeFileAtomic(path string, content []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
+
 // printUsageHandleError is a simple wrapper around printing a command's usage
 // that handles errors.
 func printUsageHandleError(cmd *cobra.Command) {
@@ -498,8 +1503,12 @@ func printUsageHandleError(cmd *cobra.Command) {
 // logHelpError logs a message at error level telling the user to use the
 // '--help' flag of the passed command to get more information on the command.
 // The full command invocation without flags or arguments is printed in the
-// message.
+// message. Since it is logged right before commands fail, sinks are notified
+// of the failure first if the command notifies.
 func logHelpError(cmd *cobra.Command) {
+	// Make sure output printed before the error is not lost
+	stopPager()
+	notifySend(1)
 	log.Logger.Error().Msgf("see '%s --help' for long command help", cmd.CommandPath())
 }
 
@@ -520,6 +1529,16 @@ func completionFormatData(cmd *cobra.Command, args []string, toComplete string)
 	return helpSlice, cobra.ShellCompDirectiveDefault
 }
 
+// completionDiscoverFormat is the cobra completion function for the
+// --format-input flag of commands that read discovery payloads.
+func completionDiscoverFormat(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var helpSlice []string
+	for k, v := range discover.PayloadFormatHelp {
+		helpSlice = append(helpSlice, fmt.Sprintf("%s\t%s", k, v))
+	}
+	return helpSlice, cobra.ShellCompDirectiveDefault
+}
+
 // completionDiscoveryVersion is the cobra completion function for the
 // --discovery-version flag.
 func completionDiscoveryVersion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
@@ -529,3 +1548,53 @@ func completionDiscoveryVersion(cmd *cobra.Command, args []string, toComplete st
 	}
 	return helpSlice, cobra.ShellCompDirectiveDefault
 }
+
+// completionDiscoverErrorPolicy is the cobra completion function for the
+// --on-error flag.
+func completionDiscoverErrorPolicy(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var helpSlice []string
+	for k, v := range discover.ErrorPolicyHelp {
+		helpSlice = append(helpSlice, fmt.Sprintf("%s\t%s", k, v))
+	}
+	return helpSlice, cobra.ShellCompDirectiveDefault
+}
+
+// completionLeaseFormat is the cobra completion function for the
+// --lease-format flag.
+func completionLeaseFormat(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var helpSlice []string
+	for k, v := range discover.LeaseFormatHelp {
+		helpSlice = append(helpSlice, fmt.Sprintf("%s\t%s", k, v))
+	}
+	return helpSlice, cobra.ShellCompDirectiveDefault
+}
+
+// completionReportFormat is the cobra completion function for the
+// --report-format flag.
+func completionReportFormat(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var helpSlice []string
+	for k, v := range report.ReportFormatHelp {
+		helpSlice = append(helpSlice, fmt.Sprintf("%s\t%s", k, v))
+	}
+	return helpSlice, cobra.ShellCompDirectiveDefault
+}
+
+// completionReportOnly is the cobra completion function for the --only flag
+// of commands that accept --targets-from.
+func completionReportOnly(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var helpSlice []string
+	for k, v := range ops.ReportOnlyHelp {
+		helpSlice = append(helpSlice, fmt.Sprintf("%s\t%s", k, v))
+	}
+	return helpSlice, cobra.ShellCompDirectiveDefault
+}
+
+// completionPatchType is the cobra completion function for any flag that uses
+// the patch.PatchType type.
+func completionPatchType(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var helpSlice []string
+	for k, v := range patch.PatchTypeHelp {
+		helpSlice = append(helpSlice, fmt.Sprintf("%s\t%s", k, v))
+	}
+	return helpSlice, cobra.ShellCompDirectiveDefault
+}
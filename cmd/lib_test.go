@@ -7,10 +7,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/config"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
 )
 
 func TestIOStream_askToCreate(t *testing.T) {
@@ -171,6 +179,86 @@ func TestIOStream_loopYesNo(t *testing.T) {
 	}
 }
 
+func TestIOStream_confirmCount(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  bool
+	}{
+		{name: "exact count", input: "51\n", want: true},
+		{name: "exact count with whitespace", input: "  51 \n", want: true},
+		{name: "wrong count", input: "50\n", want: false},
+		{name: "yes is not enough", input: "y\n", want: false},
+		{name: "no input", input: "", want: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			errBuf := &bytes.Buffer{}
+			ios := newIOStream(bytes.NewBufferString(tc.input), io.Discard, errBuf)
+
+			got, err := ios.confirmCount("Really delete?", 51)
+			if err != nil {
+				t.Fatalf("confirmCount() error = %v, want nil", err)
+			}
+			if got != tc.want {
+				t.Errorf("confirmCount() = %v, want %v", got, tc.want)
+			}
+			if !strings.Contains(errBuf.String(), "Type 51 to confirm") {
+				t.Errorf("confirmCount() prompt = %q, want it to contain the count", errBuf.String())
+			}
+		})
+	}
+}
+
+func Test_exceedsImpact(t *testing.T) {
+	cases := []struct {
+		name              string
+		n, total, percent int
+		want              bool
+	}{
+		{name: "disabled", n: 100, total: 100, percent: 0, want: false},
+		{name: "below", n: 9, total: 100, percent: 10, want: false},
+		{name: "at limit", n: 10, total: 100, percent: 10, want: false},
+		{name: "above", n: 11, total: 100, percent: 10, want: true},
+		{name: "all of few", n: 1, total: 3, percent: 25, want: true},
+		{name: "nothing in SMD", n: 5, total: 0, percent: 10, want: false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := exceedsImpact(tc.n, tc.total, tc.percent); got != tc.want {
+				t.Errorf("exceedsImpact(%d, %d, %d) = %v, want %v", tc.n, tc.total, tc.percent, got, tc.want)
+			}
+		})
+	}
+}
+
+func Test_countItems(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		key     string
+		want    int
+		wantErr bool
+	}{
+		{name: "array", body: `[{"ID":"a"},{"ID":"b"}]`, want: 2},
+		{name: "object", body: `{"Components":[{"ID":"a"}]}`, key: "Components", want: 1},
+		{name: "object without key", body: `{"Other":[{"ID":"a"}]}`, key: "Components", want: 0},
+		{name: "invalid", body: `{"Components":`, key: "Components", wantErr: true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := countItems([]byte(tc.body), tc.key)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("countItems() error = %v, wantErr %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Errorf("countItems() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
 func Test_createIfNotExists(t *testing.T) {
 	type args struct {
 		path string
@@ -210,3 +298,331 @@ func Test_createIfNotExists(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseSince(t *testing.T) {
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		s       string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "timestamp", s: "2025-01-01T00:00:00Z", want: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "duration", s: "36h", want: time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)},
+		{name: "days", s: "7d", want: time.Date(2025, 1, 3, 12, 0, 0, 0, time.UTC)},
+		{name: "negative duration", s: "-1h", wantErr: true},
+		{name: "invalid days", s: "xd", wantErr: true},
+		{name: "garbage", s: "yesterday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseSince(tt.s, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSince() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseSince() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ignoreStatus(t *testing.T) {
+	errFailed := errors.New("failed")
+	newArgs := func() ([]client.HTTPEnvelope, []error) {
+		return []client.HTTPEnvelope{
+				{StatusCode: http.StatusConflict},
+				{StatusCode: http.StatusInternalServerError},
+				{StatusCode: http.StatusOK},
+			},
+			[]error{errFailed, errFailed, nil}
+	}
+	items := []string{"a", "b", "c"}
+
+	t.Run("flag not passed", func(t *testing.T) {
+		cmd := &cobra.Command{Use: "x"}
+		cmd.Flags().Bool("if-not-exists", false, "")
+		henvs, errs := newArgs()
+		if n := ignoreStatus(cmd, "if-not-exists", http.StatusConflict, "group", henvs, errs, items); n != 0 {
+			t.Errorf("ignoreStatus() = %d, want 0", n)
+		}
+		if errs[0] == nil {
+			t.Errorf("ignoreStatus() cleared error without flag")
+		}
+	})
+	t.Run("flag passed", func(t *testing.T) {
+		cmd := &cobra.Command{Use: "x"}
+		cmd.Flags().Bool("if-not-exists", false, "")
+		cmd.Flags().Set("if-not-exists", "true")
+		henvs, errs := newArgs()
+		if n := ignoreStatus(cmd, "if-not-exists", http.StatusConflict, "group", henvs, errs, items); n != 1 {
+			t.Errorf("ignoreStatus() = %d, want 1", n)
+		}
+		if errs[0] != nil {
+			t.Errorf("ignoreStatus() did not clear error for status %d", http.StatusConflict)
+		}
+		if errs[1] == nil {
+			t.Errorf("ignoreStatus() cleared error for status %d", http.StatusInternalServerError)
+		}
+	})
+	t.Run("flag not defined", func(t *testing.T) {
+		henvs, errs := newArgs()
+		if n := ignoreStatus(&cobra.Command{Use: "x"}, "if-exists", http.StatusNotFound, "group", henvs, errs, items); n != 0 {
+			t.Errorf("ignoreStatus() = %d, want 0", n)
+		}
+	})
+}
+
+func Test_verbosityLogLevel(t *testing.T) {
+	tests := []struct {
+		name      string
+		verbosity int
+		quiet     bool
+		want      string
+	}{
+		{name: "default", want: ""},
+		{name: "quiet", quiet: true, want: "error"},
+		{name: "-v", verbosity: 1, want: "info"},
+		{name: "-vv", verbosity: 2, want: "debug"},
+		{name: "-vvv", verbosity: 3, want: "debug"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := verbosityLogLevel(tt.verbosity, tt.quiet); got != tt.want {
+				t.Errorf("verbosityLogLevel() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_verbosityFlag(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		want  int
+		isErr bool
+	}{
+		{name: "none", want: 0},
+		{name: "-v", args: []string{"-v"}, want: 1},
+		{name: "-vv", args: []string{"-vv"}, want: 2},
+		{name: "repeated", args: []string{"-v", "--verbose"}, want: 2},
+		{name: "number", args: []string{"--verbose=3"}, want: 3},
+		{name: "true", args: []string{"--verbose=true"}, want: 1},
+		{name: "true after -vv", args: []string{"-vv", "--verbose=true"}, want: 2},
+		{name: "false", args: []string{"-v", "--verbose=false"}, want: 0},
+		{name: "invalid", args: []string{"--verbose=loud"}, isErr: true},
+		{name: "negative", args: []string{"--verbose=-1"}, isErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var verbosity int
+			cmd := &cobra.Command{}
+			cmd.Flags().VarP((*verbosityFlag)(&verbosity), "verbose", "v", "")
+			cmd.Flags().Lookup("verbose").NoOptDefVal = "+1"
+			err := cmd.Flags().Parse(tt.args)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("unexpected error state: got err=%v, want error=%v", err, tt.isErr)
+			}
+			if !tt.isErr && verbosity != tt.want {
+				t.Errorf("got verbosity %d, want %d", verbosity, tt.want)
+			}
+		})
+	}
+}
+
+func Test_applyAliases(t *testing.T) {
+	savedCmds, savedFlags := commandAliases, flagAliases
+	t.Cleanup(func() { commandAliases, flagAliases = savedCmds, savedFlags })
+	commandAliases, flagAliases = nil, nil
+
+	var (
+		gotArgs []string
+		gotName string
+		changed bool
+	)
+	root := &cobra.Command{Use: "ochami"}
+	discoverCmd := &cobra.Command{Use: "discover"}
+	staticCmd := &cobra.Command{
+		Use:  "static <file>",
+		Args: cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) {
+			handleDeprecations(cmd)
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			gotArgs = args
+			gotName, _ = cmd.Flags().GetString("name")
+			changed = cmd.Flag("name").Changed
+		},
+	}
+	staticCmd.Flags().String("name", "", "")
+	discoverCmd.AddCommand(staticCmd)
+	root.AddCommand(discoverCmd)
+	root.AddCommand(&cobra.Command{Use: "bss"})
+
+	registerCommandAlias("discover file", staticCmd, "v1.0.0")
+	registerCommandAlias("bss static", staticCmd, "v1.0.0")
+	registerFlagAlias(staticCmd, "old-name", "name", "v1.0.0")
+	if err := applyAliases(root); err != nil {
+		t.Fatalf("applyAliases() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"new path and flag", []string{"discover", "static", "--name", "n", "f"}},
+		{"alias in same parent", []string{"discover", "file", "--name", "n", "f"}},
+		{"alias in other parent", []string{"bss", "static", "--name", "n", "f"}},
+		{"old flag", []string{"discover", "static", "--old-name", "n", "f"}},
+		{"old path and old flag", []string{"bss", "static", "--old-name=n", "f"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotArgs, gotName, changed = nil, "", false
+			staticCmd.Flag("name").Changed = false
+			staticCmd.Flag("old-name").Changed = false
+			root.SetArgs(tt.args)
+			if err := root.Execute(); err != nil {
+				t.Fatalf("Execute(%v) error = %v", tt.args, err)
+			}
+			if len(gotArgs) != 1 || gotArgs[0] != "f" {
+				t.Errorf("Execute(%v): args = %v, want [f]", tt.args, gotArgs)
+			}
+			if gotName != "n" || !changed {
+				t.Errorf("Execute(%v): --name = %q (changed=%v), want \"n\" (changed=true)", tt.args, gotName, changed)
+			}
+		})
+	}
+
+	t.Run("unknown flag", func(t *testing.T) {
+		commandAliases = nil
+		flagAliases = []flagAlias{{Cmd: staticCmd, Old: "foo", New: "bar", RemovedIn: "v1.0.0"}}
+		if err := applyAliases(&cobra.Command{Use: "ochami"}); err == nil {
+			t.Errorf("applyAliases() error = nil, want error")
+		}
+	})
+}
+
+func Test_bssBootMAC(t *testing.T) {
+	withIP := func(mac string) smd.EthernetInterface {
+		return smd.EthernetInterface{MACAddress: mac, IPAddresses: []smd.EthernetIP{{IPAddress: "10.0.0.1"}}}
+	}
+	tests := []struct {
+		name   string
+		eis    []smd.EthernetInterface
+		want   string
+		wantOK bool
+	}{
+		{"none", nil, "", false},
+		{"first with IP", []smd.EthernetInterface{{MACAddress: "de:ad:be:ef:00:01"}, withIP("de:ad:be:ef:00:02"), withIP("de:ad:be:ef:00:03")}, "de:ad:be:ef:00:02", true},
+		{"none with IP", []smd.EthernetInterface{{MACAddress: "de:ad:be:ef:00:01"}, {MACAddress: "de:ad:be:ef:00:02"}}, "de:ad:be:ef:00:01", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := bssBootMAC(tt.eis)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("bssBootMAC() = %q, %t, want %q, %t", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func Test_registeredAliases(t *testing.T) {
+	if err := applyAliases(rootCmd); err != nil {
+		t.Fatalf("applyAliases() error = %v", err)
+	}
+
+	cmd, _, err := rootCmd.Find([]string{"smd", "status"})
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if got := policyCommand(cmd); got != "smd service status" {
+		t.Errorf("policyCommand() for 'smd status' = %q, want %q", got, "smd service status")
+	}
+}
+
+func Test_policyCommand(t *testing.T) {
+	savedCmds, savedFlags := commandAliases, flagAliases
+	t.Cleanup(func() { commandAliases, flagAliases = savedCmds, savedFlags })
+	commandAliases, flagAliases = nil, nil
+
+	root := &cobra.Command{Use: "ochami"}
+	discoverCmd := &cobra.Command{Use: "discover"}
+	staticCmd := &cobra.Command{Use: "static", Run: func(cmd *cobra.Command, args []string) {}}
+	discoverCmd.AddCommand(staticCmd)
+	root.AddCommand(discoverCmd)
+	root.AddCommand(&cobra.Command{Use: "bss"})
+
+	registerCommandAlias("discover file", staticCmd, "v1.0.0")
+	registerCommandAlias("bss static", staticCmd, "v1.0.0")
+	if err := applyAliases(root); err != nil {
+		t.Fatalf("applyAliases() error = %v", err)
+	}
+
+	policy := config.ConfigClusterPolicy{Deny: []string{"discover static"}}
+	for _, args := range [][]string{{"discover", "static"}, {"discover", "file"}, {"bss", "static"}} {
+		cmd, _, err := root.Find(args)
+		if err != nil {
+			t.Fatalf("Find(%v) error = %v", args, err)
+		}
+		if got := policyCommand(cmd); got != "discover static" {
+			t.Errorf("policyCommand() for %v = %q, want %q", args, got, "discover static")
+		}
+		if ok, _ := policy.Allows(policyCommand(cmd)); ok {
+			t.Errorf("policy denying 'discover static' allows %v", args)
+		}
+	}
+}
+
+func Test_isMutating(t *testing.T) {
+	savedCmds, savedFlags := commandAliases, flagAliases
+	t.Cleanup(func() { commandAliases, flagAliases = savedCmds, savedFlags })
+	commandAliases, flagAliases = nil, nil
+
+	run := func(cmd *cobra.Command, args []string) {}
+	root := &cobra.Command{Use: "ochami"}
+	smdCmd := &cobra.Command{Use: "smd"}
+	componentCmd := &cobra.Command{Use: "component"}
+	addCmd := &cobra.Command{Use: "add", Run: run}
+	getCmd := &cobra.Command{Use: "get", Run: run}
+	componentCmd.AddCommand(addCmd, getCmd)
+	subtreeCmd := &cobra.Command{Use: "delete-subtree", Run: run}
+	subtreeCmd.Flags().Bool("dry-run", false, "")
+	smdCmd.AddCommand(componentCmd, subtreeCmd)
+	fsckCmd := &cobra.Command{Use: "fsck", Run: run}
+	fsckCmd.Flags().Bool("fix", false, "")
+	configCmd := &cobra.Command{Use: "config"}
+	configCmd.AddCommand(&cobra.Command{Use: "set", Run: run})
+	root.AddCommand(smdCmd, fsckCmd, configCmd)
+
+	registerCommandAlias("smd component create", addCmd, "v1.0.0")
+	if err := applyAliases(root); err != nil {
+		t.Fatalf("applyAliases() error = %v", err)
+	}
+
+	tests := []struct {
+		args []string
+		want bool
+	}{
+		{args: []string{"smd", "component", "add"}, want: true},
+		{args: []string{"smd", "component", "create"}, want: true},
+		{args: []string{"smd", "component", "get"}, want: false},
+		{args: []string{"smd", "delete-subtree"}, want: true},
+		{args: []string{"smd", "delete-subtree", "--dry-run"}, want: false},
+		{args: []string{"fsck"}, want: false},
+		{args: []string{"fsck", "--fix"}, want: true},
+		{args: []string{"config", "set"}, want: false},
+	}
+	for _, tt := range tests {
+		cmd, flags, err := root.Find(tt.args)
+		if err != nil {
+			t.Fatalf("Find(%v) error = %v", tt.args, err)
+		}
+		if err := cmd.ParseFlags(flags); err != nil {
+			t.Fatalf("ParseFlags(%v) error = %v", flags, err)
+		}
+		if got := isMutating(cmd); got != tt.want {
+			t.Errorf("isMutating() for %v = %v, want %v", tt.args, got, tt.want)
+		}
+	}
+}
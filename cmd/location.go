@@ -0,0 +1,44 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/xname"
+)
+
+// showLocationFlagUsage is the usage of --show-location, shared by the commands
+// that accept it.
+const showLocationFlagUsage = "show the physical location of each component, from cluster.locations in the config file"
+
+// showLocations returns the physical locations of the xnames of the cluster if
+// --show-location was passed, and whether it was. The locations are read from
+// cluster.locations-file (a JSON or YAML file), overridden by cluster.locations.
+// If the file cannot be read, the program exits.
+func showLocations(cmd *cobra.Command) (xname.Locations, bool) {
+	var locs xname.Locations
+	if f := cmd.Flag("show-location"); f == nil || f.Value.String() != "true" {
+		return locs, false
+	}
+	cl, found := getCluster(cmd)
+	if !found || (cl.Cluster.LocationsFile == "" && len(cl.Cluster.Locations) == 0) {
+		log.Logger.Warn().Msg("--show-location passed but no locations are configured for the cluster")
+		return locs, true
+	}
+	if cl.Cluster.LocationsFile != "" {
+		// YAML is a superset of JSON, so both can be read as YAML
+		if err := client.ReadPayloadFile(cl.Cluster.LocationsFile, format.DataFormatYaml, &locs); err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to read locations from %s", cl.Cluster.LocationsFile)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	}
+
+	return locs.Merge(xname.Locations{Locations: cl.Cluster.Locations}), true
+}
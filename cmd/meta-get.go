@@ -0,0 +1,71 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// metaGetCmd represents the "meta get" command
+var metaGetCmd = &cobra.Command{
+	Use:   "get (<xname>... | --selector meta.<key>=<value>...) [-F <format>]",
+	Short: "Get metadata of components",
+	Long: `Get the metadata of one or more components, printed as a map of
+xnames to their key/value pairs. Components are either passed as
+arguments or selected by their metadata with --selector, which can be
+passed more than once to select components matching all selectors.
+
+An access token is required.
+
+See ochami-meta(1) for more details.`,
+	Example: `  # Get the metadata of a node
+  ochami meta get x3000c0s0b0n0
+
+  # Get the metadata of all nodes in rack 12
+  ochami meta get --selector meta.rack=12`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 && !cmd.Flag("selector").Changed {
+			return fmt.Errorf("expected one or more xnames or --selector")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		xnames := append(args, metaSelectorXnames(cmd)...)
+		groups := metaGetGroups(cmd, smdClient)
+		out := make(map[string]map[string]string)
+		for _, xname := range xnames {
+			out[xname] = smd.MetaOf(groups, xname)
+		}
+
+		// Print output
+		if outBytes, err := format.MarshalData(out, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+	},
+}
+
+func init() {
+	metaGetCmd.Flags().StringArray("selector", []string{}, "select components by metadata (meta.<key>=<value>), can be passed more than once")
+	metaGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	metaGetCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	metaCmd.AddCommand(metaGetCmd)
+}
@@ -0,0 +1,117 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// metaSetCmd represents the "meta set" command
+var metaSetCmd = &cobra.Command{
+	Use:   "set <xname> <key>=<value>...",
+	Args:  cobra.MinimumNArgs(2),
+	Short: "Set metadata of a component",
+	Long: `Set one or more key/value pairs of metadata of a component,
+replacing the value of any key that is already set. Keys consist of
+lower case letters, digits, and '_'. Values consist of letters,
+digits, '_', '.', and '-' and are matched case insensitively by
+selectors.
+
+An access token is required.
+
+See ochami-meta(1) for more details.`,
+	Example: `  # Record the rack and purchase year of a node
+  ochami meta set x3000c0s0b0n0 rack=12 purchase=2023`,
+	Run: func(cmd *cobra.Command, args []string) {
+		xname := args[0]
+		type pair struct{ key, value string }
+		var pairs []pair
+		for _, arg := range args[1:] {
+			key, value, err := smd.ParseMetaPair(arg)
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("invalid metadata")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			pairs = append(pairs, pair{key, value})
+		}
+
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		groups := metaGetGroups(cmd, smdClient)
+		current := smd.MetaOf(groups, xname)
+		exists := make(map[string]bool)
+		for _, g := range groups {
+			exists[strings.ToLower(g.Label)] = true
+		}
+
+		errorsOccurred := false
+		logErr := func(err error, msg string) {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msgf("%s: unsuccessful HTTP response", msg)
+			} else {
+				log.Logger.Error().Err(err).Msg(msg)
+			}
+			errorsOccurred = true
+		}
+		for _, p := range pairs {
+			if old, ok := current[p.key]; ok {
+				if strings.EqualFold(old, p.value) {
+					log.Logger.Info().Msgf("%s already has %s=%s", xname, p.key, old)
+					continue
+				}
+				// A component can only be in one group of a key
+				_, errs, err := smdClient.DeleteGroupMembers(token, smd.MetaGroupLabel(p.key, old), xname)
+				if err == nil {
+					err = errs[0]
+				}
+				if err != nil {
+					logErr(err, "failed to remove "+xname+" from metadata group for "+p.key+"="+old)
+					continue
+				}
+			}
+
+			label := smd.MetaGroupLabel(p.key, p.value)
+			var (
+				errs []error
+				err  error
+			)
+			if exists[label] {
+				_, errs, err = smdClient.PostGroupMembers(token, label, xname)
+			} else {
+				_, errs, err = smdClient.PostGroups([]smd.Group{smd.NewMetaGroup(p.key, p.value, xname)}, token)
+				exists[label] = true
+			}
+			if err == nil {
+				err = errs[0]
+			}
+			if err != nil {
+				logErr(err, "failed to set "+p.key+"="+p.value+" for "+xname)
+				continue
+			}
+			log.Logger.Info().Msgf("set %s=%s for %s", p.key, p.value, xname)
+		}
+
+		if errorsOccurred {
+			logHelpError(cmd)
+			log.Logger.Warn().Msg("metadata requests completed with errors")
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	metaCmd.AddCommand(metaSetCmd)
+}
@@ -0,0 +1,97 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// metaUnsetCmd represents the "meta unset" command
+var metaUnsetCmd = &cobra.Command{
+	Use:   "unset <xname> <key>...",
+	Args:  cobra.MinimumNArgs(2),
+	Short: "Remove metadata of a component",
+	Long: `Remove one or more keys of metadata of a component. Metadata
+groups that are left without members are deleted.
+
+An access token is required.
+
+See ochami-meta(1) for more details.`,
+	Example: `  # Remove the purchase year of a node
+  ochami meta unset x3000c0s0b0n0 purchase`,
+	Run: func(cmd *cobra.Command, args []string) {
+		xname := args[0]
+		for _, key := range args[1:] {
+			if err := smd.CheckMetaKey(key); err != nil {
+				log.Logger.Error().Err(err).Msg("invalid metadata key")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		groups := metaGetGroups(cmd, smdClient)
+		current := smd.MetaOf(groups, xname)
+
+		errorsOccurred := false
+		logErr := func(err error, msg string) {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msgf("%s: unsuccessful HTTP response", msg)
+			} else {
+				log.Logger.Error().Err(err).Msg(msg)
+			}
+			errorsOccurred = true
+		}
+		for _, key := range args[1:] {
+			value, ok := current[key]
+			if !ok {
+				log.Logger.Warn().Msgf("%s does not have metadata key %s", xname, key)
+				continue
+			}
+			label := smd.MetaGroupLabel(key, value)
+			members := smd.MembersWithMeta(groups, key, value)
+
+			// Delete the group if xname is its last member
+			var (
+				errs []error
+				err  error
+			)
+			if len(members) == 1 && strings.EqualFold(members[0], xname) {
+				_, errs, err = smdClient.DeleteGroups(token, label)
+			} else {
+				_, errs, err = smdClient.DeleteGroupMembers(token, label, xname)
+			}
+			if err == nil {
+				err = errs[0]
+			}
+			if err != nil {
+				logErr(err, "failed to remove "+key+"="+value+" from "+xname)
+				continue
+			}
+			log.Logger.Info().Msgf("removed %s=%s from %s", key, value, xname)
+		}
+
+		if errorsOccurred {
+			logHelpError(cmd)
+			log.Logger.Warn().Msg("metadata requests completed with errors")
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	metaCmd.AddCommand(metaUnsetCmd)
+}
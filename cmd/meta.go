@@ -0,0 +1,114 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// metaCmd represents the meta command
+var metaCmd = &cobra.Command{
+	Use:   "meta",
+	Args:  cobra.NoArgs,
+	Short: "Manage free-form key/value metadata of components",
+	Long: `Manage free-form key/value metadata of components, such as rack
+numbers or asset tags. Metadata is stored in SMD as groups tagged
+'ochami-meta', one per key/value pair. Components can be targeted by
+their metadata with --selector meta.<key>=<value> on commands that
+support it, e.g. 'ochami pcs transition start'.
+
+See ochami-meta(1) for more details.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Check that all required args are passed
+		if len(args) == 0 {
+			printUsageHandleError(cmd)
+			os.Exit(0)
+		}
+	},
+}
+
+// metaGetGroups returns all SMD groups, from which metadata is read, exiting if
+// they cannot be read.
+func metaGetGroups(cmd *cobra.Command, smdClient *smd.SMDClient) []smd.Group {
+	henv, err := smdClient.GetGroups("", token)
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("SMD group request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to request groups from SMD")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	var groups []smd.Group
+	if err := json.Unmarshal(henv.Body, &groups); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to unmarshal SMD groups")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return groups
+}
+
+// metaSelectorXnames returns the sorted xnames of the components matching all
+// of the selectors passed to --selector, each of the form meta.<key>=<value>.
+// If no selectors were passed, nil is returned. The program exits if a selector
+// is invalid, the metadata cannot be read, or no components match, since
+// targeting no components is almost certainly a mistake.
+func metaSelectorXnames(cmd *cobra.Command) []string {
+	selectors, err := cmd.Flags().GetStringArray("selector")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --selector")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if len(selectors) == 0 {
+		return nil
+	}
+	type pair struct{ key, value string }
+	var pairs []pair
+	for _, s := range selectors {
+		key, value, err := smd.ParseMetaSelector(s)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("invalid --selector")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		pairs = append(pairs, pair{key, value})
+	}
+
+	groups := metaGetGroups(cmd, smdGetClient(cmd))
+	var xnames []string
+	for i, p := range pairs {
+		members := smd.MembersWithMeta(groups, p.key, p.value)
+		if i == 0 {
+			xnames = members
+			continue
+		}
+		xnames = slices.DeleteFunc(xnames, func(x string) bool {
+			return !slices.ContainsFunc(members, func(m string) bool { return strings.EqualFold(m, x) })
+		})
+	}
+	if len(xnames) == 0 {
+		log.Logger.Error().Msgf("no components match selector(s) %s", strings.Join(selectors, ", "))
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	log.Logger.Debug().Msgf("selector(s) %s matched: %v", strings.Join(selectors, ", "), xnames)
+
+	return xnames
+}
+
+func init() {
+	rootCmd.AddCommand(metaCmd)
+}
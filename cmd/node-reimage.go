@@ -0,0 +1,496 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/artifact"
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/client/pcs"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/reimage"
+)
+
+// nodeReimageCmd represents the "node reimage" command
+var nodeReimageCmd = &cobra.Command{
+	Use:   "reimage --image <name> --manifest <path> (--xname <xname>... | --group <group>... | --target <target>... | --targets-from <path> [--only <status>...] | --selector <selector>...) [--reboot [--wave-size <n>] [--track [--track-by <method>] [--timeout <duration>]]]",
+	Args:  cobra.NoArgs,
+	Short: "Set nodes to boot an image, optionally rebooting them and tracking their boot",
+	Long: `Set the boot parameters of nodes passed with --xname, --group (SMD
+groups, whose members are looked up in SMD), --target, or
+--targets-from (see TARGETS in ochami(1)) and/or selected by their
+metadata with --selector (see ochami-meta(1)) to boot the image
+--image of the image manifest --manifest (see ochami-image(1)). The
+kernel and initrd of the image replace those of the nodes, the kernel
+command line is that of the image (or the current one of each node if
+the image has none), and, if the image has a root filesystem, root= is
+set to a live image of it.
+
+If --reboot is passed, the nodes are then power-cycled with PCS init
+transitions in waves of --wave-size nodes. If --track is also passed,
+the boot of each wave is tracked until all of its nodes booted or
+--timeout elapses before the next wave is started. How boots are
+tracked is set by --track-by:
+
+  smd          a node booted once its SMD state is Ready after
+               having been in another state since it was
+               power-cycled (the default)
+  boot-script  a node booted once it fetched its boot script from
+               BSS after it was power-cycled
+
+The cloud-init server only logs the phone-home requests of nodes,
+so they cannot be used to track boots.
+
+If a node of a wave fails to be power-cycled or to boot, the waves
+after it are skipped unless --continue-on-failure is passed. A roster
+of the outcome for each node is printed at the end, and this command
+exits with a nonzero status if any node failed.
+
+An access token is required.
+
+See ochami-node(1) for more details.`,
+	Example: `  # Set the compute nodes to boot compute-9.4 at their next boot
+  ochami node reimage --group compute --image compute-9.4 --manifest images.yaml -f yaml
+
+  # Reimage the compute nodes now, 32 at a time, waiting for each wave to boot
+  ochami node reimage --group compute --image compute-9.4 --manifest images.yaml -f yaml \
+    --reboot --wave-size 32 --track`,
+	Run: func(cmd *cobra.Command, args []string) {
+		imageName, err := cmd.Flags().GetString("image")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --image")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		manifestPath, err := cmd.Flags().GetString("manifest")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --manifest")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		waveSize, err := cmd.Flags().GetInt("wave-size")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --wave-size")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		waveDelay, err := cmd.Flags().GetDuration("wave-delay")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --wave-delay")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		trackBy, err := cmd.Flags().GetString("track-by")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --track-by")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if !slices.Contains(reimage.TrackMethods, trackBy) {
+			log.Logger.Error().Msgf("unknown --track-by %q (must be one of %s)", trackBy, strings.Join(reimage.TrackMethods, ", "))
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --timeout")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		interval, err := cmd.Flags().GetDuration("poll-interval")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --poll-interval")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if interval <= 0 {
+			log.Logger.Error().Msg("--poll-interval must be positive")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		reboot := cmd.Flag("reboot").Changed
+		track := cmd.Flag("track").Changed
+		if track && !reboot {
+			log.Logger.Error().Msg("--track requires --reboot")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Find the image to boot
+		var manifest artifact.Manifest
+		if err := client.ReadPayloadFile(manifestPath, formatInput, &manifest); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to read image manifest")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		img, err := reimage.FindImage(manifest, imageName)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to find image")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Determine which nodes to reimage
+		xnames, err := cmd.Flags().GetStringSlice("xname")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --xname")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if cmd.Flag("group").Changed {
+			groups, err := cmd.Flags().GetStringSlice("group")
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to get value for --group")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			members := smdGetGroupMembers(cmd, groups...)
+			if len(members) == 0 {
+				log.Logger.Error().Msgf("no members found in SMD group(s) %v", groups)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			xnames = append(xnames, members...)
+		}
+		xnames = append(xnames, targetXnames(cmd)...)
+		xnames = append(xnames, metaSelectorXnames(cmd)...)
+		xnames = nodeReimageDedup(xnames)
+		if len(xnames) == 0 {
+			log.Logger.Error().Msg("no nodes to reimage")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if reboot {
+			guardImpact(cmd, "power-cycle", "node", len(xnames), func() int { return smdCountNodes(cmd) })
+		}
+
+		// Create client to use for requests
+		bssClient := bssGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		// Notify the sinks of the cluster once the nodes are reimaged
+		notifyOnExit(cmd)
+
+		// Set the boot parameters of the nodes, keeping what the image does
+		// not set from their current ones
+		results := make(map[string]*reimage.Result, len(xnames))
+		for _, x := range xnames {
+			results[x] = &reimage.Result{Xname: x, Status: reimage.StatusParamsSet}
+		}
+		current := nodeReimageGetBootParams(cmd, bssClient, xnames)
+		var (
+			keys  []string
+			bps   = make(map[string]bssTypes.BootParams)
+			hosts = make(map[string][]string)
+		)
+		for _, x := range xnames {
+			// Nodes with the same boot parameters are set at once
+			bp := reimage.BootParams(img, current[strings.ToLower(x)], nil)
+			b, err := json.Marshal(bp)
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to marshal boot parameters")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			key := string(b)
+			if _, ok := bps[key]; !ok {
+				keys = append(keys, key)
+				bps[key] = bp
+			}
+			hosts[key] = append(hosts[key], x)
+		}
+		for _, key := range keys {
+			bp := bps[key]
+			bp.Hosts = hosts[key]
+			if _, err := bssClient.PutBootParams(bp, token); err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msg("BSS boot parameter PUT request yielded unsuccessful HTTP response")
+				} else {
+					log.Logger.Error().Err(err).Msg("failed to set boot parameters in BSS")
+				}
+				for _, x := range bp.Hosts {
+					results[x].Status = reimage.StatusFailed
+					results[x].Error = err.Error()
+				}
+			}
+		}
+		var ready []string
+		for _, x := range xnames {
+			if results[x].Status != reimage.StatusFailed {
+				ready = append(ready, x)
+			}
+		}
+		log.Logger.Info().Msgf("set boot parameters of %d of %d node(s) to boot image %s", len(ready), len(xnames), img.Name)
+
+		// Power-cycle the nodes in waves, tracking their boot if requested
+		if reboot {
+			pcsClient := pcsGetClient(cmd)
+			var smdClient *smd.SMDClient
+			if track && trackBy == reimage.TrackSMD {
+				smdClient = smdGetClient(cmd)
+			}
+			continueOnFailure := cmd.Flag("continue-on-failure").Changed
+			waves := reimage.Waves(ready, waveSize)
+			halted := false
+			skipped := 0
+			for i, wave := range waves {
+				for _, x := range wave {
+					results[x].Wave = i + 1
+				}
+				if halted {
+					for _, x := range wave {
+						results[x].Status = reimage.StatusSkipped
+					}
+					skipped += len(wave)
+					continue
+				}
+				if i > 0 && waveDelay > 0 {
+					log.Logger.Info().Msgf("waiting %s before wave %d", waveDelay, i+1)
+					time.Sleep(waveDelay)
+				}
+
+				log.Logger.Info().Msgf("power-cycling wave %d of %d (%d node(s))", i+1, len(waves), len(wave))
+				start := time.Now()
+				if err := nodeReimagePowerCycle(cmd, pcsClient, wave); err != nil {
+					for _, x := range wave {
+						results[x].Status = reimage.StatusFailed
+						results[x].Error = err.Error()
+					}
+					halted = !continueOnFailure
+					continue
+				}
+				if !track {
+					for _, x := range wave {
+						results[x].Status = reimage.StatusRebooted
+					}
+					continue
+				}
+
+				tracker := nodeReimageTrack(cmd, smdClient, bssClient, trackBy, wave, start, timeout, interval)
+				for _, x := range wave {
+					if d, ok := tracker.BootTime(x); ok {
+						results[x].Status = reimage.StatusBooted
+						results[x].BootTime = d.Round(time.Second).String()
+					} else {
+						results[x].Status = reimage.StatusTimedOut
+					}
+				}
+				if pending := tracker.Pending(); len(pending) > 0 {
+					log.Logger.Warn().Msgf("%d node(s) of wave %d did not boot within %s", len(pending), i+1, timeout)
+					halted = !continueOnFailure
+				}
+			}
+			if skipped > 0 {
+				log.Logger.Warn().Msgf("skipped power-cycling %d node(s) since a wave failed", skipped)
+			}
+		}
+
+		// Print the roster
+		var list []reimage.Result
+		for _, x := range xnames {
+			list = append(list, *results[x])
+		}
+		roster := reimage.NewRoster(img.Name, list)
+		if locs, ok := showLocations(cmd); ok {
+			for i := range roster.Results {
+				roster.Results[i].Location = locs.Describe(roster.Results[i].Xname)
+			}
+		}
+		failed := roster.Counts[reimage.StatusFailed] + roster.Counts[reimage.StatusTimedOut] + roster.Counts[reimage.StatusSkipped]
+		if roster.Failed() {
+			notifySetReport(fmt.Sprintf("failed to reimage %d of %d node(s) with image %s", failed, len(xnames), img.Name), roster)
+		} else {
+			notifySetReport(fmt.Sprintf("reimaged %d node(s) with image %s", len(xnames), img.Name), roster)
+		}
+		if outBytes, err := format.MarshalData(roster, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+
+		if roster.Failed() {
+			log.Logger.Error().Msgf("failed to reimage %d of %d node(s)", failed, len(xnames))
+			exitWithStatus(1)
+		}
+	},
+}
+
+// nodeReimageDedup returns xnames without duplicates, regardless of case, in
+// the order they are first encountered.
+func nodeReimageDedup(xnames []string) []string {
+	var (
+		deduped []string
+		seen    = make(map[string]bool)
+	)
+	for _, x := range xnames {
+		if !seen[strings.ToLower(x)] {
+			seen[strings.ToLower(x)] = true
+			deduped = append(deduped, x)
+		}
+	}
+
+	return deduped
+}
+
+// nodeReimageGetBootParams returns the current boot parameters of xnames in
+// BSS, keyed by lower case xname, requesting them in batches. Nodes without
+// boot parameters are left out. If a request fails, an error is logged and the
+// program exits.
+func nodeReimageGetBootParams(cmd *cobra.Command, bssClient *bss.BSSClient, xnames []string) map[string]bssTypes.BootParams {
+	current := make(map[string]bssTypes.BootParams)
+	for i := 0; i < len(xnames); i += componentWaitBatchSize {
+		values := url.Values{}
+		for _, x := range xnames[i:min(i+componentWaitBatchSize, len(xnames))] {
+			values.Add("name", x)
+		}
+		henv, err := bssClient.GetBootParams(values.Encode(), token)
+		// BSS responds with 404 if none of the nodes have boot parameters
+		if err != nil && henv.StatusCode == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("BSS boot parameter request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to request boot parameters from BSS")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var bps []bssTypes.BootParams
+		if err := json.Unmarshal(henv.Body, &bps); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal boot params")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, bp := range bps {
+			for _, h := range bp.Hosts {
+				current[strings.ToLower(h)] = bp
+			}
+		}
+	}
+
+	return current
+}
+
+// nodeReimagePowerCycle starts a PCS init transition of xnames, which powers
+// them off if they are on and then on, annotating it in Grafana if configured.
+// Errors are logged and returned.
+func nodeReimagePowerCycle(cmd *cobra.Command, pcsClient *pcs.PCSClient, xnames []string) error {
+	henv, err := pcsClient.CreateTransition("init", nil, xnames, token)
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("PCS init transition create request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to create init transition")
+		}
+		return err
+	}
+	grafanaAnnotate(cmd, "node reimage", xnames)
+
+	var output createOutput
+	if err := json.Unmarshal(henv.Body, &output); err != nil {
+		log.Logger.Warn().Err(err).Msg("failed to unmarshal transition")
+		return nil
+	}
+	log.Logger.Info().Msgf("started init transition %s for %d node(s)", output.TransitionID, len(xnames))
+
+	return nil
+}
+
+// nodeReimageTrack polls the boot of xnames, power-cycled at start, every
+// interval with method until they all booted or timeout elapses, returning the
+// tracker. If polling fails, an error is logged and the program exits.
+func nodeReimageTrack(cmd *cobra.Command, smdClient *smd.SMDClient, bssClient *bss.BSSClient, method string, xnames []string, start time.Time, timeout, interval time.Duration) *reimage.Tracker {
+	tracker := reimage.NewTracker(xnames, start)
+	deadline := start.Add(timeout)
+	log.Logger.Info().Msgf("waiting up to %s for %d node(s) to boot", timeout, len(xnames))
+	for {
+		var booted []string
+		switch method {
+		case reimage.TrackSMD:
+			booted = tracker.UpdateSMD(componentWaitGet(cmd, smdClient, tracker.Pending()), time.Now())
+		case reimage.TrackBootScript:
+			values := url.Values{}
+			values.Add("endpoint", string(bssTypes.EndpointTypeBootscript))
+			henv, err := bssClient.GetEndpointHistory(values.Encode())
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msg("BSS endpoint history request yielded unsuccessful HTTP response")
+				} else {
+					log.Logger.Error().Err(err).Msg("failed to request endpoint history from BSS")
+				}
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			var history []bssTypes.EndpointAccess
+			if err := json.Unmarshal(henv.Body, &history); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to unmarshal endpoint history")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			booted = tracker.UpdateBootScript(history)
+		}
+		for _, x := range booted {
+			d, _ := tracker.BootTime(x)
+			log.Logger.Info().Msgf("%s booted after %s", x, d.Round(time.Second))
+		}
+
+		pending := tracker.Pending()
+		if len(pending) == 0 || !time.Now().Before(deadline) {
+			return tracker
+		}
+		log.Logger.Info().Msgf("%d node(s) not yet booted", len(pending))
+		time.Sleep(min(interval, time.Until(deadline)))
+	}
+}
+
+func init() {
+	nodeReimageCmd.Flags().String("image", "", "name of the image in the manifest to boot")
+	nodeReimageCmd.Flags().String("manifest", "", "path to image manifest, or - to read it from standard input")
+	nodeReimageCmd.Flags().VarP(&formatInput, "format-input", "f", "format of manifest ("+format.DataFormatList()+")")
+	nodeReimageCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames of nodes to reimage")
+	nodeReimageCmd.Flags().StringSliceP("group", "g", []string{}, "one or more SMD groups whose members to reimage")
+	nodeReimageCmd.Flags().StringSlice("target", []string{}, targetFlagUsage)
+	targetAddFromFlags(nodeReimageCmd)
+	nodeReimageCmd.Flags().StringArray("selector", []string{}, "select target components by metadata (meta.<key>=<value>), can be passed more than once")
+	nodeReimageCmd.Flags().Bool("reboot", false, "power-cycle the nodes after setting their boot parameters")
+	nodeReimageCmd.Flags().Int("wave-size", 0, "with --reboot, number of nodes to power-cycle at once (0 for all)")
+	nodeReimageCmd.Flags().Duration("wave-delay", 0, "with --reboot, how long to wait between waves")
+	nodeReimageCmd.Flags().Bool("continue-on-failure", false, "with --reboot, start the remaining waves even if a node of a wave failed")
+	nodeReimageCmd.Flags().Bool("allow-major-impact", false, allowMajorImpactFlagUsage)
+	nodeReimageCmd.Flags().Bool("track", false, "with --reboot, wait for the nodes of each wave to boot before starting the next")
+	nodeReimageCmd.Flags().String("track-by", reimage.TrackSMD, "how to tell that nodes booted ("+strings.Join(reimage.TrackMethods, ",")+")")
+	nodeReimageCmd.Flags().Duration("timeout", 20*time.Minute, "with --track, how long to wait for the nodes of a wave to boot")
+	nodeReimageCmd.Flags().Duration("poll-interval", 10*time.Second, "with --track, interval at which to poll whether nodes booted")
+	nodeReimageCmd.Flags().Bool("show-location", false, showLocationFlagUsage)
+	nodeReimageCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	nodeReimageCmd.MarkFlagRequired("image")
+	nodeReimageCmd.MarkFlagRequired("manifest")
+	nodeReimageCmd.MarkFlagsOneRequired("xname", "group", "target", "targets-from", "selector")
+
+	nodeReimageCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
+	nodeReimageCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	nodeCmd.AddCommand(nodeReimageCmd)
+}
@@ -0,0 +1,32 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// nodeCmd represents the node command
+var nodeCmd = &cobra.Command{
+	Use:   "node",
+	Args:  cobra.NoArgs,
+	Short: "Manage nodes across services",
+	Long: `Manage nodes across services. This is a metacommand. Commands under
+this one combine requests to several services (e.g. BSS, PCS, and SMD)
+to carry out common administrative tasks on nodes.
+
+See ochami-node(1) for more details.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Check that all required args are passed
+		if len(args) == 0 {
+			printUsageHandleError(cmd)
+			os.Exit(0)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(nodeCmd)
+}
@@ -0,0 +1,110 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+// notify.go notifies the sinks configured for a cluster when long-running
+// commands finish or fail.
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/audit"
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/internal/notify"
+)
+
+var (
+	// Long-running command that notifies the sinks of the cluster when it
+	// exits, when it started doing so, and whether it already has.
+	notifyCmd     *cobra.Command
+	notifyStarted time.Time
+	notifySent    bool
+
+	// Summary of the outcome of the command and the report it printed, set
+	// by the command once it has them.
+	notifySummary string
+	notifyReport  any
+)
+
+// notifyOnExit makes cmd notify the sinks in notify.sinks of the cluster being
+// used when it exits, whether it finished or failed, unless --no-notify was
+// passed. Long-running commands call it once they start acting on targets, so
+// failing to parse flags or find targets does not notify anyone.
+func notifyOnExit(cmd *cobra.Command) {
+	if noNotify, _ := cmd.Flags().GetBool("no-notify"); noNotify {
+		return
+	}
+	notifyCmd = cmd
+	notifyStarted = time.Now()
+}
+
+// notifySetReport sets the line summarizing the outcome of the command and the
+// report it printed, which is attached to the notification.
+func notifySetReport(summary string, report any) {
+	notifySummary = summary
+	notifyReport = report
+}
+
+// notifySend sends the notification that the command exited with status to the
+// sinks of the cluster, if the command notifies and it has not been sent
+// already. It is called by exitWithStatus and, since commands fail in many
+// places, by logHelpError, in which case the summary is the last error logged
+// unless one was set. Failing to send is only logged as a warning so that it
+// never changes the outcome of the command.
+func notifySend(status int) {
+	if notifyCmd == nil || notifySent {
+		return
+	}
+	notifySent = true
+	cl, found := getCluster(notifyCmd)
+	if !found || len(cl.Cluster.Notify.Sinks) == 0 {
+		return
+	}
+
+	n := notify.Notification{
+		Command:  strings.TrimPrefix(strings.TrimPrefix(notifyCmd.CommandPath(), notifyCmd.Root().Name()), " "),
+		Cluster:  cl.Name,
+		Status:   status,
+		Summary:  notifySummary,
+		Started:  notifyStarted,
+		Finished: time.Now(),
+	}
+	if n.Summary == "" && status != 0 {
+		if errs := log.Errors(); len(errs) > 0 {
+			n.Summary = errs[len(errs)-1]
+		}
+	}
+	n.Host, _ = os.Hostname()
+	if n.User, _ = notifyCmd.Flags().GetString("as"); n.User == "" {
+		n.User = audit.CurrentUser()
+	}
+	if notifyReport != nil {
+		if b, err := json.Marshal(notifyReport); err != nil {
+			log.Logger.Warn().Err(err).Msg("failed to marshal report for notification, sending it without")
+		} else {
+			n.Report = b
+		}
+	}
+
+	var sinks []notify.Sink
+	for i, s := range cl.Cluster.Notify.Sinks {
+		sinks = append(sinks, notify.Sink{
+			Type:     s.Type,
+			URL:      configSecret(notifyCmd, fmt.Sprintf("notify.sinks.%d.url", i), s.URL),
+			To:       s.To,
+			From:     s.From,
+			Sendmail: s.Sendmail,
+		})
+	}
+	if err := notify.Send(sinks, n); err != nil {
+		log.Logger.Warn().Err(err).Msg("failed to notify all sinks")
+		return
+	}
+	log.Logger.Debug().Msgf("sent notification to %d sink(s)", len(sinks))
+}
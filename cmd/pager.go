@@ -0,0 +1,96 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+// pager.go pages long output of commands through the user's pager when
+// standard output is a terminal.
+
+import (
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/config"
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/internal/pager"
+)
+
+// annotationNoPager is the annotation set on commands whose output should never
+// be paged, e.g. because it is streamed.
+const annotationNoPager = "ochami-no-pager"
+
+var (
+	// Standard output replaced by pagerPipe while output is being paged, and
+	// the channel receiving the result of paging once pagerPipe is closed.
+	pagerStdout *os.File
+	pagerPipe   *os.File
+	pagerDone   chan error
+)
+
+// pagerEnabled returns true if output of cmd should be paged, which is the case
+// unless --no-pager was passed, pager is false in the config file, or cmd has
+// the annotationNoPager annotation.
+func pagerEnabled(cmd *cobra.Command) bool {
+	if noPager, err := cmd.Flags().GetBool("no-pager"); err == nil && noPager {
+		return false
+	}
+	if !config.GlobalConfig.PagerEnabled() {
+		return false
+	}
+	_, noPager := cmd.Annotations[annotationNoPager]
+
+	return !noPager
+}
+
+// startPager replaces standard output so that, if it is a terminal and output
+// of cmd exceeds the terminal height, output is paged through the pager command
+// (see pager.Command). Paging must be finished with stopPager before the
+// program exits.
+func startPager(cmd *cobra.Command) {
+	if !pagerEnabled(cmd) {
+		return
+	}
+	height, ok := pager.TerminalHeight(os.Stdout)
+	if !ok {
+		return
+	}
+	command := pager.Command()
+	if command == "" {
+		return
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		log.Logger.Warn().Err(err).Msg("failed to set up pager, output will not be paged")
+		return
+	}
+	log.Logger.Debug().Msgf("paging output longer than %d lines with %q", height, command)
+
+	pw := pager.NewWriter(command, os.Stdout, height)
+	pagerDone = make(chan error, 1)
+	go func() {
+		// If the user quits the pager early, discard the rest of the
+		// output
+		if _, err := io.Copy(pw, r); err != nil {
+			io.Copy(io.Discard, r)
+		}
+		pagerDone <- pw.Close()
+	}()
+	pagerStdout, pagerPipe = os.Stdout, w
+	os.Stdout = w
+}
+
+// stopPager restores standard output after printing any output that was not
+// paged or, if it was, waiting for the user to quit the pager. It does nothing
+// if output is not being paged.
+func stopPager() {
+	if pagerPipe == nil {
+		return
+	}
+	pagerPipe.Close()
+	err := <-pagerDone
+	os.Stdout, pagerPipe = pagerStdout, nil
+	if err != nil {
+		log.Logger.Warn().Err(err).Msg("failed to page output")
+	}
+}
@@ -0,0 +1,241 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/pcs"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// pcsPowerOffCmd represents the "pcs power off" command
+var pcsPowerOffCmd = &cobra.Command{
+	Use:   "off (--xname <xname>... | --group <group>... | --target <target>... | --selector <selector>...) [--graceful-then-force [--grace-period <duration>] [--force-timeout <duration>]]",
+	Args:  cobra.NoArgs,
+	Short: "Power off components, optionally escalating from graceful shutdown to forced power off",
+	Long: `Power off components passed with --xname, --group (SMD groups,
+whose members are looked up in SMD), or --target and/or selected
+by their metadata with --selector (see ochami-meta(1)). By default,
+an off PCS transition is started and its ID is printed.
+
+If --graceful-then-force is passed, the standard shutdown runbook
+is followed instead: a soft-off (graceful shutdown) transition is
+started, the power state of the components is polled until they
+are all off or --grace-period elapses, a force-off transition is
+started for any stragglers, and their power state is polled until
+they are off or --force-timeout elapses. The outcome for each
+component (graceful, forced, or failed) is then printed, and this
+command exits with a nonzero status if any component failed to
+power off.
+
+See ochami-pcs(1) for more details.`,
+	Example: `  # Power off a node
+  ochami pcs power off --xname x1000c0s0b0n0
+
+  # Shut down the compute group gracefully, forcing off stragglers after 10 minutes
+  ochami pcs power off --group compute --graceful-then-force --grace-period 10m`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Determine which components to power off
+		xnames, err := cmd.Flags().GetStringSlice("xname")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --xname")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if cmd.Flag("group").Changed {
+			groups, err := cmd.Flags().GetStringSlice("group")
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to get value for --group")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			members := smdGetGroupMembers(cmd, groups...)
+			if len(members) == 0 {
+				log.Logger.Error().Msgf("no members found in SMD group(s) %v", groups)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			xnames = append(xnames, members...)
+		}
+		xnames = append(xnames, targetXnames(cmd)...)
+		xnames = append(xnames, metaSelectorXnames(cmd)...)
+		if len(xnames) == 0 {
+			log.Logger.Error().Msg("no components to power off")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		guardImpact(cmd, "power off", "node", len(nodeReimageDedup(xnames)), func() int { return smdCountNodes(cmd) })
+
+		// Create client to use for requests
+		pcsClient := pcsGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		if !cmd.Flag("graceful-then-force").Changed {
+			output := pcsPowerOffTransition(cmd, pcsClient, "off", xnames)
+			if outBytes, err := format.MarshalData(output, formatOutput); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			} else {
+				fmt.Println(string(outBytes))
+			}
+			return
+		}
+
+		gracePeriod, err := cmd.Flags().GetDuration("grace-period")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --grace-period")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		forceTimeout, err := cmd.Flags().GetDuration("force-timeout")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --force-timeout")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		interval, err := cmd.Flags().GetDuration("poll-interval")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --poll-interval")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if interval <= 0 {
+			log.Logger.Error().Msg("--poll-interval must be positive")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Notify the sinks of the cluster once the components are off
+		notifyOnExit(cmd)
+
+		// Shut down gracefully, waiting up to the grace period
+		pcsPowerOffTransition(cmd, pcsClient, "soft-off", xnames)
+		log.Logger.Info().Msgf("waiting up to %s for %d component(s) to shut down gracefully", gracePeriod, len(xnames))
+		statuses := pcsPollPowerOff(cmd, pcsClient, xnames, gracePeriod, interval)
+
+		// Force off any stragglers, waiting up to the force timeout
+		forced := pcs.NotInPowerState(statuses, xnames, "off")
+		if len(forced) > 0 {
+			log.Logger.Warn().Msgf("%d component(s) did not shut down gracefully within %s, forcing them off", len(forced), gracePeriod)
+			pcsPowerOffTransition(cmd, pcsClient, "force-off", forced)
+			statuses = pcsPollPowerOff(cmd, pcsClient, xnames, forceTimeout, interval)
+		}
+
+		// Print the outcome for each component
+		report := pcs.PowerOffOutcomes(xnames, forced, statuses)
+		if locs, ok := showLocations(cmd); ok {
+			for i := range report.Results {
+				report.Results[i].Location = locs.Describe(report.Results[i].Xname)
+			}
+		}
+		if report.Failed() {
+			notifySetReport(fmt.Sprintf("%d of %d component(s) failed to power off", report.Counts[pcs.PowerOffFailed], len(xnames)), report)
+		} else {
+			notifySetReport(fmt.Sprintf("powered off %d component(s), %d of them forcibly", len(xnames), len(forced)), report)
+		}
+		if outBytes, err := format.MarshalData(report, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+
+		if report.Failed() {
+			log.Logger.Error().Msgf("%d component(s) failed to power off", report.Counts[pcs.PowerOffFailed])
+			exitWithStatus(1)
+		}
+	},
+}
+
+// pcsPowerOffTransition starts a PCS transition of operation on xnames,
+// annotating it in Grafana if configured, and returns its ID and operation. If
+// starting it fails, an error is logged and the program exits.
+func pcsPowerOffTransition(cmd *cobra.Command, pcsClient *pcs.PCSClient, operation string, xnames []string) createOutput {
+	henv, err := pcsClient.CreateTransition(operation, nil, xnames, token)
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msgf("PCS %s transition create request yielded unsuccessful HTTP response", operation)
+		} else {
+			log.Logger.Error().Err(err).Msgf("failed to create %s transition", operation)
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	grafanaAnnotate(cmd, "pcs transition "+operation, xnames)
+
+	var output createOutput
+	if err := json.Unmarshal(henv.Body, &output); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to unmarshal transition")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	log.Logger.Info().Msgf("started %s transition %s for %d component(s)", operation, output.TransitionID, len(xnames))
+
+	return output
+}
+
+// pcsPollPowerOff polls the power status of xnames every interval until they
+// are all off or timeout elapses, returning the last power statuses. If getting
+// the power status fails, an error is logged and the program exits.
+func pcsPollPowerOff(cmd *cobra.Command, pcsClient *pcs.PCSClient, xnames []string, timeout, interval time.Duration) []pcs.PowerStatus {
+	deadline := time.Now().Add(timeout)
+	for {
+		henv, err := pcsClient.GetPowerStatus(xnames, token)
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("PCS power status request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to get power status from PCS")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var psl pcs.PowerStatusList
+		if err := json.Unmarshal(henv.Body, &psl); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal power status")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		remaining := pcs.NotInPowerState(psl.Status, xnames, "off")
+		if len(remaining) == 0 || !time.Now().Before(deadline) {
+			return psl.Status
+		}
+		log.Logger.Info().Msgf("%d component(s) not yet off", len(remaining))
+		time.Sleep(min(interval, time.Until(deadline)))
+	}
+}
+
+func init() {
+	pcsPowerOffCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames to power off")
+	pcsPowerOffCmd.Flags().StringSliceP("group", "g", []string{}, "one or more SMD groups whose members to power off")
+	pcsPowerOffCmd.Flags().StringSlice("target", []string{}, targetFlagUsage)
+	targetAddFromFlags(pcsPowerOffCmd)
+	pcsPowerOffCmd.Flags().StringArray("selector", []string{}, "select target components by metadata (meta.<key>=<value>), can be passed more than once")
+	pcsPowerOffCmd.MarkFlagsOneRequired("xname", "group", "target", "targets-from", "selector")
+
+	pcsPowerOffCmd.Flags().Bool("graceful-then-force", false, "shut down gracefully, then force off components still on after --grace-period")
+	pcsPowerOffCmd.Flags().Duration("grace-period", 5*time.Minute, "how long to wait for components to shut down gracefully before forcing them off")
+	pcsPowerOffCmd.Flags().Duration("force-timeout", 2*time.Minute, "how long to wait for components to be forced off")
+	pcsPowerOffCmd.Flags().Duration("poll-interval", 10*time.Second, "interval at which to poll the power state of components")
+	pcsPowerOffCmd.Flags().Bool("allow-major-impact", false, allowMajorImpactFlagUsage)
+	pcsPowerOffCmd.Flags().Bool("show-location", false, showLocationFlagUsage)
+	pcsPowerOffCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	pcsPowerOffCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	pcsPowerCmd.AddCommand(pcsPowerOffCmd)
+}
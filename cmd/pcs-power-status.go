@@ -0,0 +1,157 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/pcs"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// pcsPowerStatusCmd represents the "pcs power status" command
+var pcsPowerStatusCmd = &cobra.Command{
+	Use:   "status [--xname <xname>]... [--group <group>]... [--target <target>]... [--summary] [--expect on|off]",
+	Args:  cobra.NoArgs,
+	Short: "Get power status of components, optionally as a fleet summary",
+	Long: `Get power status of components. By default, the power status of all
+components known to PCS is printed. --xname, --group (SMD groups,
+whose members are looked up in SMD), and/or --target (xnames,
+nid:<nid>, mac:<mac>, or group:<group>, resolved through SMD) can
+be used to limit the components whose power status is fetched.
+
+If --summary is passed, a rollup of component counts per power
+state is printed instead, along with a list of exceptions
+(components with errors or, if --expect is passed, components
+whose power state does not match the expected state).
+
+If --expect is passed, this command exits with a nonzero status if
+any component does not match the expected power state, could not
+have its power state determined, or was requested but not reported
+by PCS.
+
+See ochami-pcs(1) for more details.`,
+	Example: `  # Get power status of all components
+  ochami pcs power status
+
+  # Summarize power state of the compute group
+  ochami pcs power status --group compute --summary
+
+  # Fail if any node in the compute group is not on
+  ochami pcs power status --group compute --summary --expect on`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flag("expect").Changed {
+			switch e, _ := cmd.Flags().GetString("expect"); e {
+			case "on", "off":
+			default:
+				return fmt.Errorf("--expect must be one of [on off], got %q", e)
+			}
+		}
+
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		// Determine which components to get power status of
+		xnames, err := cmd.Flags().GetStringSlice("xname")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --xname")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if cmd.Flag("group").Changed {
+			groups, err := cmd.Flags().GetStringSlice("group")
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to get value for --group")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			members := smdGetGroupMembers(cmd, groups...)
+			if len(members) == 0 {
+				log.Logger.Error().Msgf("no members found in SMD group(s) %v", groups)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			xnames = append(xnames, members...)
+		}
+		xnames = append(xnames, targetXnames(cmd)...)
+
+		// Create client to use for requests
+		pcsClient := pcsGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		// Get power status
+		henv, err := pcsClient.GetPowerStatus(xnames, token)
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("PCS power status request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to get power status from PCS")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var psl pcs.PowerStatusList
+		if err := json.Unmarshal(henv.Body, &psl); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal power status")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		expect, _ := cmd.Flags().GetString("expect")
+		summary := pcs.SummarizePowerStatus(psl.Status, expect, xnames)
+
+		if locs, ok := showLocations(cmd); ok {
+			for i := range psl.Status {
+				psl.Status[i].Location = locs.Describe(psl.Status[i].Xname)
+			}
+			for i := range summary.Exceptions {
+				summary.Exceptions[i].Location = locs.Describe(summary.Exceptions[i].Xname)
+			}
+		}
+
+		// Print output
+		var output interface{} = psl
+		if cmd.Flag("summary").Changed {
+			output = summary
+		}
+		if outBytes, err := format.MarshalData(output, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+
+		if expect != "" && summary.Deviates() {
+			log.Logger.Error().Msgf("%d component(s) deviate from expected power state %s, %d missing", len(summary.Exceptions), expect, len(summary.Missing))
+			exitWithStatus(1)
+		}
+	},
+}
+
+func init() {
+	pcsPowerStatusCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames to get power status of")
+	pcsPowerStatusCmd.Flags().StringSliceP("group", "g", []string{}, "one or more SMD groups whose members to get power status of")
+	pcsPowerStatusCmd.Flags().StringSlice("target", []string{}, targetFlagUsage)
+	targetAddFromFlags(pcsPowerStatusCmd)
+	pcsPowerStatusCmd.Flags().Bool("summary", false, "print counts per power state and exceptions instead of per-component status")
+	pcsPowerStatusCmd.Flags().String("expect", "", "expected power state (on,off); exit nonzero if any component deviates")
+	pcsPowerStatusCmd.Flags().Bool("show-location", false, showLocationFlagUsage)
+	pcsPowerStatusCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	pcsPowerStatusCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+	pcsPowerStatusCmd.RegisterFlagCompletionFunc("expect", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"on", "off"}, cobra.ShellCompDirectiveNoFileComp
+	})
+
+	pcsPowerCmd.AddCommand(pcsPowerStatusCmd)
+}
@@ -0,0 +1,23 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// pcsPowerCmd represents the "pcs power" command
+var pcsPowerCmd = &cobra.Command{
+	Use:   "power",
+	Args:  cobra.NoArgs,
+	Short: "Query and control component power state via PCS",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			printUsageHandleError(cmd)
+		}
+	},
+}
+
+func init() {
+	pcsCmd.AddCommand(pcsPowerCmd)
+}
@@ -186,7 +186,7 @@ func init() {
 		pcsServiceStatusCmd.MarkFlagsMutuallyExclusive("all", flags[i])
 	}
 
-	pcsServiceStatusCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	pcsServiceStatusCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 	pcsServiceStatusCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
 	pcsServiceCmd.AddCommand(pcsServiceStatusCmd)
@@ -72,7 +72,7 @@ See ochami-pcs(1) for more details.`,
 }
 
 func init() {
-	pcsTransitionAbortCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	pcsTransitionAbortCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	pcsTransitionAbortCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
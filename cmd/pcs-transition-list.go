@@ -64,7 +64,7 @@ See ochami-pcs(1) for more details.`,
 }
 
 func init() {
-	pcsTransitionListCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	pcsTransitionListCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	pcsTransitionListCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
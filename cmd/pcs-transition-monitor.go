@@ -4,6 +4,7 @@ package cmd
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"time"
 
@@ -49,6 +50,39 @@ type transitionProgress struct {
 	TaskCounts transitionTaskCounts `json:"taskCounts" yaml:"taskCounts"`
 }
 
+// transitionSummary represents what a PCS transition did to which components,
+// as annotated in Grafana.
+type transitionSummary struct {
+	Operation  string `json:"operation"`
+	Status     string `json:"transitionStatus"`
+	CreateTime string `json:"createTime"`
+	Tasks      []struct {
+		Xname string `json:"xname"`
+	} `json:"tasks"`
+}
+
+// pcsTransitionAnnotate pushes an annotation for the PCS transition whose
+// response body is body to the Grafana instance configured for the cluster
+// being used, if any, spanning from when the transition was created until end
+// (see grafanaAnnotateSpan). Failures are only logged as warnings.
+func pcsTransitionAnnotate(cmd *cobra.Command, body []byte, end time.Time) {
+	var ts transitionSummary
+	if err := json.Unmarshal(body, &ts); err != nil {
+		log.Logger.Warn().Err(err).Msg("failed to unmarshal transition to annotate")
+		return
+	}
+	start, err := time.Parse(time.RFC3339, ts.CreateTime)
+	if err != nil {
+		log.Logger.Warn().Err(err).Msgf("invalid creation time of transition, annotating it at %s", end.Format(time.RFC3339))
+		start, end = end, time.Time{}
+	}
+	xnames := make([]string, 0, len(ts.Tasks))
+	for _, t := range ts.Tasks {
+		xnames = append(xnames, t.Xname)
+	}
+	grafanaAnnotateSpan(cmd, "pcs transition "+ts.Operation+" "+ts.Status, xnames, start, end)
+}
+
 // Create and style a progress bar
 func createBar(p *mpb.Progress, name string) *mpb.Bar {
 	return p.AddBar(0, mpb.PrependDecorators(
@@ -67,6 +101,9 @@ var pcsTransitionMonitorCmd = &cobra.Command{
 	Short: "Monitor a PCS transition",
 	Long: `Abort a PCS transition.
 
+Once the transition is completed or aborted, an annotation spanning it
+is pushed to the Grafana instance configured for the cluster, if any.
+
 See ochami-pcs(1) for more details.`,
 	Example: `  # Monitor the progress of a transition
   ochami pcs transition monitor 8f252166-c53c-435e-8354-e69649537a0f`,
@@ -79,6 +116,9 @@ See ochami-pcs(1) for more details.`,
 		// Handle token for this command
 		handleToken(cmd)
 
+		// Notify the sinks of the cluster once the transition is over
+		notifyOnExit(cmd)
+
 		p := mpb.New(mpb.WithWidth(64))
 
 		newBar := createBar(p, transitionTaskStateNew)
@@ -121,6 +161,11 @@ See ochami-pcs(1) for more details.`,
 
 			// Check if the transition is complete
 			if progress.Status == transitionStatusCompleted || progress.Status == transitionStatusAborted {
+				notifySetReport(fmt.Sprintf("transition %s %s: %d of %d task(s) succeeded, %d failed", transitionID, progress.Status,
+					progress.TaskCounts.Succeeded, progress.TaskCounts.Total, progress.TaskCounts.Failed), progress)
+				// Annotate the time the transition took in
+				// Grafana, if configured
+				pcsTransitionAnnotate(cmd, transitionHttpEnv.Body, time.Now())
 				break
 			}
 
@@ -134,5 +179,9 @@ See ochami-pcs(1) for more details.`,
 
 func init() {
 	pcsTransitionMonitorCmd.Flags().IntVarP(&pollInterval, "poll-interval", "p", 1, "The interval at which to poll the transition status")
+
+	// Status is printed as the transition progresses, so do not page it
+	pcsTransitionMonitorCmd.Annotations = map[string]string{annotationNoPager: ""}
+
 	pcsTransitionCmd.AddCommand(pcsTransitionMonitorCmd)
 }
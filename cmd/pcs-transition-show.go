@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -17,14 +18,21 @@ import (
 
 // pcsTransition show Cmd represents the "pcs transition show" command
 var pcsTransitionShowCmd = &cobra.Command{
-	Use:   "show <transition_id>",
+	Use:   "show [--annotate] <transition_id>",
 	Args:  cobra.ExactArgs(1),
 	Short: "Show details of a PCS transition",
 	Long: `Show details of a PCS transition.
 
+If --annotate is passed, an annotation of the transition, from when it
+was created until now, is pushed to the Grafana instance configured for
+the cluster, e.g. to report a transition that was not monitored.
+
 See ochami-pcs(1) for more details.`,
 	Example: `  # Show a transition
-  ochami pcs transition show 8f252166-c53c-435e-8354-e69649537a0f`,
+  ochami pcs transition show 8f252166-c53c-435e-8354-e69649537a0f
+
+  # Show a transition and annotate it in Grafana
+  ochami pcs transition show --annotate 8f252166-c53c-435e-8354-e69649537a0f`,
 	Run: func(cmd *cobra.Command, args []string) {
 		transitionID := args[0]
 
@@ -46,6 +54,11 @@ See ochami-pcs(1) for more details.`,
 			os.Exit(1)
 		}
 
+		// Annotate the transition in Grafana, if requested
+		if cmd.Flag("annotate").Changed {
+			pcsTransitionAnnotate(cmd, transitionHttpEnv.Body, time.Now())
+		}
+
 		// Unmarshal output
 		var output interface{}
 		err = json.Unmarshal(transitionHttpEnv.Body, &output)
@@ -67,7 +80,8 @@ See ochami-pcs(1) for more details.`,
 }
 
 func init() {
-	pcsTransitionShowCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	pcsTransitionShowCmd.Flags().Bool("annotate", false, "push an annotation of the transition to the Grafana instance configured for the cluster")
+	pcsTransitionShowCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	pcsTransitionShowCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
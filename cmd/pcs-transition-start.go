@@ -45,11 +45,22 @@ var pcsTransitionStartCmd = &cobra.Command{
 	Use:   "start",
 	Args:  cobra.ExactArgs(1),
 	Short: "Start a PCS transition",
-	Long: `Start a PCS transition.
+	Long: `Start a PCS transition on the components passed with --xname or
+--target and/or selected by their metadata with --selector (see
+ochami-meta(1)). --target accepts xnames, NIDs (nid:<nid>), MAC
+addresses (mac:<mac>), and SMD groups (group:<group>), resolving
+them to xnames through SMD. --selector can be passed more than once
+to select components matching all selectors.
 
 See ochami-pcs(1) for more details.`,
 	Example: `  # Turn on a set of nodes
-  ochami pcs transition start --xname "x0c0s7b0n1,x0c0s7b0n0,x0c0s4b0n1" on`,
+  ochami pcs transition start --xname "x0c0s7b0n1,x0c0s7b0n0,x0c0s4b0n1" on
+
+  # Restart all nodes in rack 12
+  ochami pcs transition start --selector meta.rack=12 soft-restart
+
+  # Turn off a node by NID and all nodes in a group
+  ochami pcs transition start --target nid:42,group:compute off`,
 	Run: func(cmd *cobra.Command, args []string) {
 		operation = args[0]
 
@@ -74,6 +85,14 @@ See ochami-pcs(1) for more details.`,
 			logHelpError(cmd)
 			os.Exit(1)
 		}
+		xnames = append(xnames, targetXnames(cmd)...)
+		xnames = append(xnames, metaSelectorXnames(cmd)...)
+
+		// Refuse to take down much of the system at once. Turning
+		// components on cannot cause an outage, so it is not checked.
+		if operation != "on" {
+			guardImpact(cmd, "power "+operation, "node", len(nodeReimageDedup(xnames)), func() int { return smdCountNodes(cmd) })
+		}
 
 		// Create transition
 		transitionHttpEnv, err := pcsClient.CreateTransition(operation, nil, xnames, token)
@@ -87,6 +106,9 @@ See ochami-pcs(1) for more details.`,
 			os.Exit(1)
 		}
 
+		// Annotate the transition in Grafana, if configured
+		grafanaAnnotate(cmd, "pcs transition "+operation, xnames)
+
 		// Unmarshall the transition
 		var output createOutput
 		err = json.Unmarshal(transitionHttpEnv.Body, &output)
@@ -109,11 +131,13 @@ See ochami-pcs(1) for more details.`,
 
 func init() {
 	pcsTransitionStartCmd.Flags().StringSliceP("xname", "x", []string{}, "The list of target components")
-	if err := pcsTransitionStartCmd.MarkFlagRequired("xname"); err != nil {
-		log.Logger.Fatal().Err(err).Msg("failed to mark xname as required")
-	}
+	pcsTransitionStartCmd.Flags().StringArray("selector", []string{}, "select target components by metadata (meta.<key>=<value>), can be passed more than once")
+	pcsTransitionStartCmd.Flags().StringSlice("target", []string{}, targetFlagUsage)
+	targetAddFromFlags(pcsTransitionStartCmd)
+	pcsTransitionStartCmd.MarkFlagsOneRequired("xname", "target", "targets-from", "selector")
+	pcsTransitionStartCmd.Flags().Bool("allow-major-impact", false, allowMajorImpactFlagUsage)
 
-	pcsTransitionStartCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	pcsTransitionStartCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	pcsTransitionStartCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
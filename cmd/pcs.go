@@ -7,6 +7,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/OpenCHAMI/ochami/internal/config"
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client/pcs"
 )
@@ -31,6 +32,30 @@ func pcsGetClient(cmd *cobra.Command) *pcs.PCSClient {
 	// Check if a CA certificate was passed and load it into client if valid
 	useCACert(pcsClient.OchamiClient)
 
+	// Send GET requests through the agent of the cluster, if running
+	useAgent(cmd, pcsClient.OchamiClient)
+
+	// Apply any endpoint path overrides configured for the cluster
+	pcsClient.PathOverrides = getServicePaths(cmd, config.ServicePCS)
+
+	// Refuse to send mutating requests in read-only mode
+	pcsClient.ReadOnly = readOnlyEnabled(cmd)
+
+	// Validate payloads against the OpenAPI spec of the service if
+	// --validate-against-server was passed
+	pcsClient.ValidatePayload = payloadValidator(cmd, pcsClient.OchamiClient)
+
+	// Stop sending requests once the --context-timeout deadline passes
+	pcsClient.Deadline = commandDeadline
+
+	// Make requests on behalf of the user passed with --as, if any, and
+	// record those that modify data in the audit log
+	pcsClient.OnBehalfOf, pcsClient.ImpersonationHeader = onBehalfOf(cmd)
+	pcsClient.AuditLog = getAuditLog(cmd)
+
+	// Spill large response bodies to a temporary file
+	pcsClient.MaxMemoryBuffer = maxMemoryBuffer(cmd)
+
 	return pcsClient
 }
 
@@ -0,0 +1,106 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/redact"
+)
+
+// redactCmd represents the redact command
+var redactCmd = &cobra.Command{
+	Use:   "redact [-d (<data> | @<path>)] [-f <format>] [-F <format>] [--mapping-file <path>]",
+	Args:  cobra.NoArgs,
+	Short: "Pseudonymize infrastructure details in a payload for sharing",
+	Long: `Replace the hostnames, domains, MAC addresses, IP addresses, and
+UUIDs in a payload (e.g. a discovery payload or the output of another
+command) with pseudonyms, and print the result, so that it can be
+attached to bug reports without leaking details of the site. The
+payload is read from -d, or from standard input.
+
+Each value is replaced by the same pseudonym wherever it appears, so
+that references between records still match, and the structure of the
+payload is kept. MAC addresses keep their format, and IPv4 addresses
+keep their host part while their /24 network is replaced, so that
+addresses stay in the same networks. Xnames are kept, and credentials
+(e.g. passwords) are replaced with REDACTED.
+
+Pass --mapping-file to use the pseudonyms saved in a file and save
+the new ones to it, so that payloads redacted separately agree with
+each other. The file maps pseudonyms back to the real values, so keep
+it private.
+
+See ochami-redact(1) for more details.`,
+	Example: `  # Redact a discovery payload
+  ochami redact -d @nodes.yaml -f yaml -F yaml
+
+  # Redact the state of BSS and a payload with the same pseudonyms
+  ochami bss dumpstate --redact --mapping-file map.json > bss.json
+  ochami redact -d @nodes.json --mapping-file map.json > nodes.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		var data any
+		if cmd.Flag("data").Changed {
+			handlePayload(cmd, &data)
+		} else {
+			handlePayloadStdin(cmd, &data)
+		}
+
+		if outBytes, err := format.MarshalData(redactData(cmd, data), formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+	},
+}
+
+// redactData returns data pseudonymized with the mapping in --mapping-file, if
+// passed, which is then updated with the new pseudonyms. If the mapping cannot
+// be read or written, an error is logged and the program exits.
+func redactData(cmd *cobra.Command, data any) any {
+	path, err := cmd.Flags().GetString("mapping-file")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --mapping-file")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	var m *redact.Mapping
+	if path != "" {
+		if m, err = redact.ReadMapping(path); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to read redaction mapping")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	}
+	r := redact.New(m)
+	data = r.Redact(data)
+	if path != "" {
+		if err := redact.WriteMapping(path, r.Mapping()); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to save redaction mapping")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	}
+
+	return data
+}
+
+func init() {
+	redactCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
+	redactCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
+	redactCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+	redactCmd.Flags().String("mapping-file", "", "read pseudonyms from and save them to this file")
+
+	redactCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
+	redactCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	rootCmd.AddCommand(redactCmd)
+}
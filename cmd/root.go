@@ -4,6 +4,7 @@ package cmd
 
 import (
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -11,6 +12,8 @@ import (
 	"github.com/OpenCHAMI/ochami/internal/version"
 	"github.com/OpenCHAMI/ochami/pkg/discover"
 	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/patch"
+	"github.com/OpenCHAMI/ochami/pkg/report"
 )
 
 var (
@@ -23,13 +26,42 @@ var (
 	formatInput  = format.DataFormatJson
 	formatOutput = format.DataFormatJson
 
+	// Variable to store the value of --patch-type.
+	patchType = patch.PatchTypeJSON
+
+	// Variable to store the value of --report-format. Empty means no
+	// report is written.
+	reportFormat report.ReportFormat
+
+	// Variable to store the value of --format-input for commands that read
+	// discovery payloads, which also accept CSV.
+	discoverFormatInput = discover.PayloadFormat(format.DataFormatJson)
+
 	// Variable to store the value of --discovery-method.
 	discoveryVersion = discover.DiscoveryMethodV2
 
+	// Variable to store the value of --on-error for commands that generate
+	// SMD structures from discovery payloads.
+	discoverErrorPolicy = discover.ErrorPolicyFailFast
+
 	// These are only used by subcommands.
 	cacertPath string
 	token      string
 	insecure   bool
+
+	// Variables to store the values of --verbose (the number of times it
+	// was passed) and --quiet.
+	verbosity int
+	quiet     bool
+
+	// Variable to store the value of --fail-on-warn.
+	failOnWarn bool
+
+	// Variables to store the value of --context-timeout and the deadline
+	// it results in for the whole command. The deadline is zero if no
+	// timeout was passed.
+	contextTimeout  time.Duration
+	commandDeadline time.Time
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -65,6 +97,11 @@ See ochami-config(5) for more details on configuring the ochami config file(s).`
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	if err := applyAliases(rootCmd); err != nil {
+		el.BasicLogf("failed to set up command aliases: %v", err)
+		os.Exit(1)
+	}
+
 	err := rootCmd.Execute()
 	if err != nil {
 		log.Logger.Error().Err(err).Msg("failed to execute command")
@@ -81,24 +118,42 @@ func Execute() {
 		}
 		os.Exit(1)
 	}
+	exitWithStatus(0)
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "path to configuration file to use")
 	rootCmd.PersistentFlags().StringP("log-format", "L", "", "log format (json,rfc3339,basic)")
-	rootCmd.PersistentFlags().StringP("log-level", "l", "", "set verbosity of logs (info,warning,debug)")
+	rootCmd.PersistentFlags().StringP("log-level", "l", "", "set verbosity of logs (error,warning,info,debug)")
 	rootCmd.PersistentFlags().StringP("cluster", "C", "", "name of cluster whose config to use for this command")
 	rootCmd.PersistentFlags().StringP("cluster-uri", "u", "", "base URI for OpenCHAMI services, excluding service base path (overrides cluster.uri in config file)")
 	rootCmd.PersistentFlags().StringVar(&cacertPath, "cacert", "", "path to root CA certificate in PEM format")
 	rootCmd.PersistentFlags().StringVarP(&token, "token", "t", "", "access token to present for authentication")
+	rootCmd.PersistentFlags().String("as", "", "user to make requests on behalf of, sent in the cluster's impersonation header and recorded in the audit log")
+	rootCmd.PersistentFlags().String("created-by", "", "user to stamp the groups and redfish endpoints created or changed with (default: --as or the local user)")
+	rootCmd.PersistentFlags().String("comment", "", "comment to stamp the groups and redfish endpoints created or changed with, along with who changed them and when")
+	rootCmd.PersistentFlags().Bool("no-annotate", false, "do not stamp the groups and redfish endpoints created or changed with who changed them, when, and --comment")
 	rootCmd.PersistentFlags().Bool("no-token", false, "do not check for or use an access token")
+	rootCmd.PersistentFlags().Bool("no-agent", false, "do not get the token from or send requests through a running ochami agent")
 	rootCmd.PersistentFlags().BoolVarP(&insecure, "insecure", "k", false, "do not verify TLS certificates")
 	rootCmd.PersistentFlags().Bool("ignore-config", false, "do not use any config file")
-	rootCmd.PersistentFlags().BoolVarP(&log.EarlyLogger.EarlyVerbose, "verbose", "v", false, "be verbose before logging is initialized")
+	rootCmd.PersistentFlags().Bool("no-pager", false, "do not page long output through $PAGER (overrides pager in config file)")
+	rootCmd.PersistentFlags().Bool("read-only", false, "refuse to send requests that modify data (overrides read-only in config file)")
+	rootCmd.PersistentFlags().Bool("validate-against-server", false, "validate request payloads against the OpenAPI spec the service publishes before sending them")
+	rootCmd.PersistentFlags().String("max-memory-buffer", "", "size above which large response bodies are spilled to a temporary file instead of held in memory (e.g. 512MiB; overrides max-memory-buffer in config file; default: 128MiB)")
+	rootCmd.PersistentFlags().DurationVar(&contextTimeout, "context-timeout", 0, "deadline for the whole command, after which no more requests are sent (e.g. 5m; default: none)")
+	rootCmd.PersistentFlags().VarP((*verbosityFlag)(&verbosity), "verbose", "v", "increase verbosity of logs (-v for info, -vv for debug), including before logging is initialized")
+	rootCmd.PersistentFlags().Lookup("verbose").NoOptDefVal = "+1"
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "only print data and errors (log level error)")
+	rootCmd.PersistentFlags().BoolVar(&failOnWarn, "fail-on-warn", false, "exit with a nonzero status if any warnings were logged")
+	rootCmd.PersistentFlags().Bool("no-notify", false, "do not notify the sinks in notify.sinks of the cluster when a long-running command exits")
 
 	// Either use cluster from config file or specify details on CLI
 	rootCmd.MarkFlagsMutuallyExclusive("cluster", "cluster-uri")
 
 	// Do not allow simultaneously passing a token and ignoring it
 	rootCmd.MarkFlagsMutuallyExclusive("token", "no-token")
+
+	// Verbosity can either be raised or lowered
+	rootCmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
 }
@@ -0,0 +1,152 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/run"
+)
+
+// runCmd represents the run command
+var runCmd = &cobra.Command{
+	Use:   "run -f (<path> | -) [--continue-on-error] [-F <format>]",
+	Args:  cobra.NoArgs,
+	Short: "Run ochami commands described by a JSON or YAML document",
+	Long: `Run the ochami commands described by a JSON or YAML document and
+print a document with the outcome of each, so that other programs can
+call ochami without building command lines. The document describes
+either one operation or a list of them under 'operations', which are
+run in order:
+
+  {
+    "flags": {"cluster": "foobar"},
+    "operations": [
+      {"command": "smd group add", "payload": {"label": "compute"}},
+      {"command": "smd group get", "flags": {"name": ["compute"]}}
+    ]
+  }
+
+Each operation has a 'command' (e.g. "smd group get"), and optionally
+'flags', positional 'args', and either a 'payload', which is passed
+as JSON with --data=@-, or raw 'stdin'. Flags at the top level are
+passed to every operation, which can override them. Once an operation
+fails, the rest are skipped unless 'continue_on_error' is set in the
+document or --continue-on-error is passed.
+
+Each operation runs as a separate ochami process, with the global
+flags passed to this command and JSON logs. The result of each has its
+status (ok, failed, or skipped), exit code, output (parsed if it is
+JSON), and logs. This command exits with a nonzero status if any
+operation failed.
+
+See ochami-run(1) for more details.`,
+	Example: `  # Run the operations in a file, printing the results as YAML
+  ochami run -f request.json -F yaml
+
+  # Run a single operation read from standard input
+  echo '{"command": "smd component get", "flags": {"xname": "x1000c0s0b0n0"}}' | ochami run -f -`,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := cmd.Flags().GetString("file")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --file")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// YAML is a superset of JSON, so either can be read as YAML
+		var doc run.Document
+		if err := client.ReadPayloadFile(path, format.DataFormatYaml, &doc); err != nil {
+			log.Logger.Error().Err(err).Msg("unable to read run document")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if cmd.Flag("continue-on-error").Changed {
+			doc.ContinueOnError = true
+		}
+
+		exe, err := os.Executable()
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to find ochami executable")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		runner := run.Runner{Executable: exe, GlobalArgs: runGlobalArgs(cmd)}
+
+		ctx := context.Background()
+		if !commandDeadline.IsZero() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithDeadline(ctx, commandDeadline)
+			defer cancel()
+		}
+		// Notify the sinks of the cluster once the operations are run
+		notifyOnExit(cmd)
+
+		report, err := runner.Run(ctx, doc)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("invalid run document")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		if report.Failed > 0 {
+			notifySetReport(fmt.Sprintf("%d of %d operation(s) failed, %d skipped", report.Failed, len(report.Results), report.Skipped), report)
+		} else {
+			notifySetReport(fmt.Sprintf("ran %d operation(s)", report.Succeeded), report)
+		}
+		if outBytes, err := format.MarshalData(report, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+
+		if report.Failed > 0 {
+			log.Logger.Warn().Msgf("%d of %d operation(s) failed", report.Failed, len(report.Results))
+			exitWithStatus(1)
+		}
+	},
+}
+
+// runGlobalArgs returns the arguments passed before those of each operation run
+// by the run command: JSON logs, no pager, and no notifications, since the run
+// command notifies of all operations at once, followed by the global flags
+// passed to cmd so that operations use the same cluster, token, etc.
+func runGlobalArgs(cmd *cobra.Command) []string {
+	args := []string{"--log-format=json", "--no-pager", "--no-notify"}
+	cmd.InheritedFlags().VisitAll(func(f *pflag.Flag) {
+		if !f.Changed {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range sv.GetSlice() {
+				args = append(args, "--"+f.Name+"="+v)
+			}
+			return
+		}
+		args = append(args, "--"+f.Name+"="+f.Value.String())
+	})
+
+	return args
+}
+
+func init() {
+	runCmd.Flags().StringP("file", "f", "", "file containing the document describing the operations to run (can be - to read from stdin)")
+	runCmd.Flags().Bool("continue-on-error", false, "run the remaining operations after one fails")
+	runCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	runCmd.MarkFlagRequired("file")
+
+	runCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	rootCmd.AddCommand(runCmd)
+}
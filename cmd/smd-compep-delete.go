@@ -5,13 +5,16 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"sync"
 
 	"github.com/OpenCHAMI/smd/v2/pkg/sm"
 	"github.com/spf13/cobra"
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // compepDeleteCmd represents the "smd compep delete" command
@@ -67,38 +70,35 @@ See ochami-smd(1) for more details.`,
 		// Handle token for this command
 		handleToken(cmd)
 
-		// Ask before attempting deletion unless --no-confirm was passed
-		if !cmd.Flag("no-confirm").Changed {
-			log.Logger.Debug().Msg("--no-confirm not passed, prompting user to confirm deletion")
-			var respDelete bool
-			var err error
-			if cmd.Flag("all").Changed {
-				respDelete, err = ios.loopYesNo("Really delete ALL COMPONENT ENDPOINTS?")
-			} else {
-				respDelete, err = ios.loopYesNo("Really delete?")
-			}
-			if err != nil {
-				log.Logger.Error().Err(err).Msg("Error fetching user input")
-				os.Exit(1)
-			} else if !respDelete {
-				log.Logger.Info().Msg("User aborted component endpoint deletion")
-				os.Exit(0)
-			} else {
-				log.Logger.Debug().Msg("User answered affirmatively to delete component endpoints")
-			}
-		}
-
 		// Create list of xnames to delete
 		var ceSlice []sm.ComponentEndpoint
 		var xnameSlice []string
 		if cmd.Flag("data").Changed {
 			// Use payload file if passed
 			handlePayload(cmd, &ceSlice)
+			for _, ce := range ceSlice {
+				xnameSlice = append(xnameSlice, ce.ID)
+			}
 		} else {
 			// ...otherwise, use passed CLI arguments
 			xnameSlice = args
 		}
 
+		// Ask before attempting deletion, requiring the number of
+		// component endpoints to be confirmed if there are many
+		prompt, n := "Really delete?", len(xnameSlice)
+		countAll := sync.OnceValue(func() int {
+			return smdCountAll(cmd, "component endpoint", func() (client.HTTPEnvelope, error) {
+				return smdClient.GetComponentEndpointsAll(token)
+			}, "ComponentEndpoints")
+		})
+		if cmd.Flag("all").Changed {
+			prompt = "Really delete ALL COMPONENT ENDPOINTS?"
+			n = countAll()
+		}
+		guardImpact(cmd, "delete", "component endpoint", n, countAll)
+		confirmDeletion(cmd, prompt, "component endpoint", n)
+
 		// Perform deletion
 		if cmd.Flag("all").Changed {
 			// If --all passed, we don't care about any passed arguments
@@ -114,17 +114,18 @@ See ochami-smd(1) for more details.`,
 			}
 		} else {
 			// If --all not passed, pass argument list to deletion logic
-			_, errs, err := smdClient.DeleteComponentEndpoints(token, xnameSlice...)
+			henvs, errs, err := smdClient.DeleteComponentEndpoints(token, xnameSlice...)
 			if err != nil {
 				log.Logger.Error().Err(err).Msg("failed to delete redfish endpoints in SMD")
 				logHelpError(cmd)
 				os.Exit(1)
 			}
+			ignoreStatus(cmd, "if-exists", http.StatusNotFound, "component endpoint", henvs, errs, xnameSlice)
 			// Since smdClient.DeleteComponentEndpoints does the deletion iteratively, we need to
 			// deal with each error that might have occurred.
 			var errorsOccurred = false
 			for _, e := range errs {
-				if err != nil {
+				if e != nil {
 					if errors.Is(e, client.UnsuccessfulHTTPError) {
 						log.Logger.Error().Err(e).Msg("SMD component endpoint deletion yielded unsuccessful HTTP response")
 					} else {
@@ -133,6 +134,7 @@ See ochami-smd(1) for more details.`,
 					errorsOccurred = true
 				}
 			}
+			reportNotAttempted(errs)
 			// Warn the user if any errors occurred during deletion iterations
 			if errorsOccurred {
 				log.Logger.Warn().Msg("SMD component endpoint deletion completed with errors")
@@ -146,8 +148,11 @@ See ochami-smd(1) for more details.`,
 func init() {
 	compepDeleteCmd.Flags().BoolP("all", "a", false, "delete all redfish endpoints in SMD")
 	compepDeleteCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
-	compepDeleteCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	compepDeleteCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
 	compepDeleteCmd.Flags().Bool("no-confirm", false, "do not ask before attempting deletion")
+	compepDeleteCmd.Flags().Int("yes-really-delete", 0, "confirm deleting more than delete-threshold items by passing their exact number")
+	compepDeleteCmd.Flags().Bool("allow-major-impact", false, allowMajorImpactFlagUsage)
+	compepDeleteCmd.Flags().Bool("if-exists", false, ifExistsFlagUsage)
 
 	compepDeleteCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
 
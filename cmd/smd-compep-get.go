@@ -12,6 +12,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // compepGetCmd represents the "smd compep get" command
@@ -62,7 +63,7 @@ See ochami-smd(1) for more details.`,
 			// deal with each error that might have occurred.
 			var errorsOccurred = false
 			for _, e := range errs {
-				if err != nil {
+				if e != nil {
 					if errors.Is(e, client.UnsuccessfulHTTPError) {
 						log.Logger.Error().Err(e).Msg("SMD redfish endpoint deletion yielded unsuccessful HTTP response")
 					} else {
@@ -71,6 +72,7 @@ See ochami-smd(1) for more details.`,
 					errorsOccurred = true
 				}
 			}
+			reportNotAttempted(errs)
 
 			// Put selected ComponentEndpoints into array and marshal
 			type compEp struct {
@@ -117,7 +119,7 @@ See ochami-smd(1) for more details.`,
 }
 
 func init() {
-	compepGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	compepGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	compepGetCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
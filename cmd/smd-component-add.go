@@ -12,11 +12,13 @@ import (
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
 	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // componentAddCmd represents the "smd component add" command
 var componentAddCmd = &cobra.Command{
-	Use:   "add (-d (<payload_data> | @<payload_file>)) | (<xname> <node_id>)",
+	Use:   "add (-d (<payload_data> | @<payload_file>)) | (--pattern <xname_pattern> [--nid-start <node_id>]) | (<xname> <node_id>)",
 	Short: "Add new component(s)",
 	Long: `Add new component(s). A name (xname) and node ID (int64) are
 required. Alternatively, pass -d to pass raw payload data
@@ -26,6 +28,12 @@ the input payload data ('json' by default), but the rules
 above still apply for the payload. If "-" is used as the
 input payload filename, the data is read from standard input.
 
+To add many components at once without a payload, e.g. to stub out
+hardware that is not installed yet, pass --pattern with an xname
+containing ranges in brackets (e.g. x3001c0s[0-15]b0n0). A component
+is added for each xname it describes, numbered from --nid-start in
+order if it is passed.
+
 This command sends a POST to SMD. An access token is required.
 
 See ochami-smd(1) for more details.`,
@@ -33,6 +41,9 @@ See ochami-smd(1) for more details.`,
   ochami smd component add x3000c1s7b56n0 56
   ochami smd component add --state Ready --enabled --role Compute --arch X86 x3000c1s7b56n0 56
 
+  # Add 16 nodes that are not installed yet, numbered from 128
+  ochami smd component add --pattern 'x3001c0s[0-15]b0n0' --nid-start 128 --type Node --state Empty
+
   # Add components using input payload data
   ochami smd component add -d '{
     "Components":[
@@ -56,6 +67,17 @@ See ochami-smd(1) for more details.`,
   echo '<yaml_data>' | ochami smd component add -d @- -f yaml`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		// Check that all required args are passed
+		if cmd.Flag("pattern").Changed {
+			if len(args) > 0 {
+				return fmt.Errorf("--pattern cannot be passed with arguments, got %d: %v", len(args), args)
+			}
+			if nid, _ := cmd.Flags().GetInt64("nid-start"); cmd.Flag("nid-start").Changed && nid < 1 {
+				return fmt.Errorf("--nid-start must be at least 1")
+			}
+			return nil
+		} else if cmd.Flag("nid-start").Changed {
+			return fmt.Errorf("--nid-start can only be passed with --pattern")
+		}
 		if !cmd.Flag("data").Changed {
 			if len(args) == 0 {
 				return fmt.Errorf("expected -d or 2 arguments (xname, nid), got neither")
@@ -84,7 +106,7 @@ See ochami-smd(1) for more details.`,
 		} else {
 			// ...otherwise use CLI options
 			comp := smd.Component{
-				ID:    args[0],
+				Type:  cmd.Flag("type").Value.String(),
 				State: cmd.Flag("state").Value.String(),
 				Role:  cmd.Flag("role").Value.String(),
 				Arch:  cmd.Flag("arch").Value.String(),
@@ -95,7 +117,31 @@ See ochami-smd(1) for more details.`,
 				comp.Enabled = true
 			}
 
-			compSlice.Components = append(compSlice.Components, comp)
+			var xnames []string
+			if !cmd.Flag("pattern").Changed {
+				xnames = []string{args[0]}
+			} else {
+				pattern, _ := cmd.Flags().GetString("pattern")
+				if xnames, err = discover.ExpandRanges(pattern); err != nil {
+					log.Logger.Error().Err(err).Msgf("invalid xname pattern %q", pattern)
+					logHelpError(cmd)
+					os.Exit(1)
+				}
+			}
+			nidStart, err := cmd.Flags().GetInt64("nid-start")
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to get value for --nid-start")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			for i, x := range xnames {
+				comp.ID = x
+				if nidStart > 0 {
+					comp.NID = nidStart + int64(i)
+				}
+				compSlice.Components = append(compSlice.Components, comp)
+			}
+			log.Logger.Debug().Msgf("adding %d component(s)", len(compSlice.Components))
 		}
 
 		// Send off request
@@ -117,8 +163,11 @@ func init() {
 	componentAddCmd.Flags().Bool("enabled", true, "set if new component is enabled")
 	componentAddCmd.Flags().String("role", "Compute", "role of new component")
 	componentAddCmd.Flags().String("arch", "X86", "CPU architecture of new component")
+	componentAddCmd.Flags().String("type", "", "type of new component (e.g. Node), if not the type of its xname")
+	componentAddCmd.Flags().String("pattern", "", "add a component for each xname described by ranges in brackets (e.g. x3001c0s[0-15]b0n0)")
+	componentAddCmd.Flags().Int64("nid-start", 0, "node ID of the first component added with --pattern, the others following it")
 	componentAddCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
-	componentAddCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	componentAddCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
 
 	componentAddCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
 
@@ -126,6 +175,8 @@ func init() {
 	componentAddCmd.MarkFlagsMutuallyExclusive("enabled", "data")
 	componentAddCmd.MarkFlagsMutuallyExclusive("role", "data")
 	componentAddCmd.MarkFlagsMutuallyExclusive("arch", "data")
+	componentAddCmd.MarkFlagsMutuallyExclusive("type", "data")
+	componentAddCmd.MarkFlagsMutuallyExclusive("pattern", "data")
 
 	componentCmd.AddCommand(componentAddCmd)
 }
@@ -5,13 +5,16 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"sync"
 
 	"github.com/spf13/cobra"
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
 	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // componentDeleteCmd represents the "smd component delete" command
@@ -70,38 +73,33 @@ See ochami-smd(1) for more details.`,
 		// Handle token for this command
 		handleToken(cmd)
 
-		// Ask before attempting deletion unless --no-confirm was passed
-		if !cmd.Flag("no-confirm").Changed {
-			log.Logger.Debug().Msg("--no-confirm not passed, prompting user to confirm deletion")
-			var respDelete bool
-			var err error
-			if cmd.Flag("all").Changed {
-				respDelete, err = ios.loopYesNo("Really delete ALL COMPONENTS?")
-			} else {
-				respDelete, err = ios.loopYesNo("Really delete?")
-			}
-			if err != nil {
-				log.Logger.Error().Err(err).Msg("Error fetching user input")
-				os.Exit(1)
-			} else if !respDelete {
-				log.Logger.Info().Msg("User aborted component deletion")
-				os.Exit(0)
-			} else {
-				log.Logger.Debug().Msg("User answered affirmatively to delete components")
-			}
-		}
-
 		// Create list of xnames to delete
 		var compSlice smd.ComponentSlice
 		var xnameSlice []string
 		if cmd.Flag("data").Changed {
 			// Use payload file if passed
 			handlePayload(cmd, &compSlice)
+			for _, c := range compSlice.Components {
+				xnameSlice = append(xnameSlice, c.ID)
+			}
 		} else {
 			// ...otherwise, use passed CLI arguments
 			xnameSlice = args
 		}
 
+		// Ask before attempting deletion, requiring the number of
+		// components to be confirmed if there are many
+		prompt, n := "Really delete?", len(xnameSlice)
+		countAll := sync.OnceValue(func() int {
+			return smdCountAll(cmd, "component", smdClient.GetComponentsAll, "Components")
+		})
+		if cmd.Flag("all").Changed {
+			prompt = "Really delete ALL COMPONENTS?"
+			n = countAll()
+		}
+		guardImpact(cmd, "delete", "component", n, countAll)
+		confirmDeletion(cmd, prompt, "component", n)
+
 		// Perform deletion
 		if cmd.Flag("all").Changed {
 			// If --all passed, we don't care about any passed arguments
@@ -116,16 +114,17 @@ See ochami-smd(1) for more details.`,
 			}
 		} else {
 			// If --all not passed, pass argument list to deletion logic
-			_, errs, err := smdClient.DeleteComponents(token, xnameSlice...)
+			henvs, errs, err := smdClient.DeleteComponents(token, xnameSlice...)
 			if err != nil {
 				log.Logger.Error().Err(err).Msg("failed to delete components in SMD")
 				os.Exit(1)
 			}
+			ignoreStatus(cmd, "if-exists", http.StatusNotFound, "component", henvs, errs, xnameSlice)
 			// Since smdClient.DeleteComponents does the deletion iteratively, we need to deal with
 			// each error that might have occurred.
 			var errorsOccurred = false
 			for _, e := range errs {
-				if err != nil {
+				if e != nil {
 					if errors.Is(e, client.UnsuccessfulHTTPError) {
 						log.Logger.Error().Err(e).Msg("SMD component deletion yielded unsuccessful HTTP response")
 					} else {
@@ -134,6 +133,7 @@ See ochami-smd(1) for more details.`,
 					errorsOccurred = true
 				}
 			}
+			reportNotAttempted(errs)
 			// Warn the user if any errors occurred during dletion iterations
 			if errorsOccurred {
 				log.Logger.Warn().Msg("SMD component deletion completed with errors")
@@ -146,8 +146,11 @@ See ochami-smd(1) for more details.`,
 func init() {
 	componentDeleteCmd.Flags().BoolP("all", "a", false, "delete all components in SMD")
 	componentDeleteCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
-	componentDeleteCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	componentDeleteCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
 	componentDeleteCmd.Flags().Bool("no-confirm", false, "do not ask before attempting deletion")
+	componentDeleteCmd.Flags().Int("yes-really-delete", 0, "confirm deleting more than delete-threshold items by passing their exact number")
+	componentDeleteCmd.Flags().Bool("allow-major-impact", false, allowMajorImpactFlagUsage)
+	componentDeleteCmd.Flags().Bool("if-exists", false, ifExistsFlagUsage)
 
 	componentDeleteCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
 
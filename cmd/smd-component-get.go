@@ -11,6 +11,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // componentGetCmd represents the "smd component get" command
@@ -47,7 +48,30 @@ See ochami-smd(1) for more details.`,
 			}
 			httpEnv, err = smdClient.GetComponentsNid(nid, token)
 		} else {
-			httpEnv, err = smdClient.GetComponentsAll()
+			// All components can be huge, so spill them to a
+			// temporary file if they are too large to hold in
+			// memory and print them as a stream
+			_, body, err := smdClient.GetComponentsAllStream()
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msg("SMD component request yielded unsuccessful HTTP response")
+				} else {
+					log.Logger.Error().Err(err).Msg("failed to request components from SMD")
+				}
+				os.Exit(1)
+			}
+			defer body.Close()
+			r, err := body.Reader()
+			if err == nil {
+				err = client.FormatStream(os.Stdout, r, formatOutput)
+			}
+			if err != nil {
+				body.Close()
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			return
 		}
 		if err != nil {
 			if errors.Is(err, client.UnsuccessfulHTTPError) {
@@ -72,7 +96,7 @@ See ochami-smd(1) for more details.`,
 func init() {
 	componentGetCmd.Flags().StringP("xname", "x", "", "xname whose Component to fetch")
 	componentGetCmd.Flags().Int32P("nid", "n", 0, "node ID whose Component to fetch")
-	componentGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	componentGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	componentGetCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 	componentGetCmd.MarkFlagsMutuallyExclusive("xname", "nid")
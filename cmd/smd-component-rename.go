@@ -0,0 +1,364 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/client/ci"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// componentRenamePlan lists the records that renaming a component copies to
+// its new xname before the records of the old xname are removed, and the steps
+// that copy and remove them, in order.
+type componentRenamePlan struct {
+	From               string                         `json:"from" yaml:"from"`
+	To                 string                         `json:"to" yaml:"to"`
+	Component          smd.Component                  `json:"component" yaml:"component"`
+	EthernetInterfaces []smd.EthernetInterface        `json:"ethernet_interfaces" yaml:"ethernet_interfaces"`
+	Groups             []string                       `json:"groups" yaml:"groups"`
+	BootParams         []bssTypes.BootParams          `json:"boot_parameters" yaml:"boot_parameters"`
+	CloudInit          *cistore.OpenCHAMIInstanceInfo `json:"cloud_init,omitempty" yaml:"cloud_init,omitempty"`
+	Steps              []string                       `json:"steps" yaml:"steps"`
+}
+
+// componentRenameStep is a step of renaming a component, which is described by
+// desc when it is done or planned.
+type componentRenameStep struct {
+	desc string
+	run  func() error
+}
+
+// componentRenameCmd represents the "smd component rename" command
+var componentRenameCmd = &cobra.Command{
+	Use:   "rename [--dry-run [-F <format>]] [--no-confirm] <old_xname> <new_xname>",
+	Args:  cobra.ExactArgs(2),
+	Short: "Move a component and its dependent records to a new xname",
+	Long: `Move a component and its dependent records to a new xname, e.g. when
+hardware is moved to a different slot. Instead of deleting and
+recreating the component in each service, the following are copied
+to <new_xname> and then removed from <old_xname>:
+
+  - the SMD component
+  - SMD ethernet interfaces belonging to the component (reassigned)
+  - SMD group memberships
+  - BSS boot parameters
+  - cloud-init instance info (hostnames), if the impersonation API
+    is enabled
+
+<new_xname> must not already exist in SMD. Pass --dry-run to print
+what would be copied, and the steps that copy and remove each
+record, without changing anything.
+
+See ochami-smd(1) for more details.`,
+	Example: `  # Show what renaming a node would copy
+  ochami smd component rename --dry-run x1000c0s0b0n0 x1000c0s7b0n0
+
+  # Rename a node without being asked to confirm
+  ochami smd component rename --no-confirm x1000c0s0b0n0 x1000c0s7b0n0`,
+	Run: func(cmd *cobra.Command, args []string) {
+		from, to := args[0], args[1]
+		if strings.EqualFold(from, to) {
+			log.Logger.Error().Msg("old and new xnames must be different")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Create clients to use for requests
+		smdClient := smdGetClient(cmd)
+		bssClient := bssGetClient(cmd)
+		cloudInitClient := cloudInitGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		plan := componentRenameGetPlan(cmd, smdClient, bssClient, cloudInitClient, from, to)
+		steps := componentRenameSteps(plan, smdClient, bssClient, cloudInitClient)
+		for _, s := range steps {
+			plan.Steps = append(plan.Steps, s.desc)
+		}
+
+		// Print plan and exit if only a dry run
+		if cmd.Flag("dry-run").Changed {
+			if outBytes, err := format.MarshalData(plan, formatOutput); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			} else {
+				fmt.Println(string(outBytes))
+			}
+			exitWithStatus(0)
+		}
+
+		// Ask before attempting rename unless --no-confirm was passed
+		if !cmd.Flag("no-confirm").Changed {
+			log.Logger.Debug().Msg("--no-confirm not passed, prompting user to confirm rename")
+			respRename, err := ios.loopYesNo(fmt.Sprintf("Really rename %s to %s? Records of %s will be deleted.", from, to, from))
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("Error fetching user input")
+				os.Exit(1)
+			} else if !respRename {
+				log.Logger.Info().Msg("User aborted component rename")
+				os.Exit(0)
+			} else {
+				log.Logger.Debug().Msg("User answered affirmatively to rename component")
+			}
+		}
+
+		// Copy records to the new xname first, then remove those of the
+		// old xname, stopping at the first failure so that the old
+		// records remain if anything was not copied
+		var done []string
+		for _, s := range steps {
+			if err := s.run(); err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msgf("failed to %s: unsuccessful HTTP response", s.desc)
+				} else {
+					log.Logger.Error().Err(err).Msgf("failed to %s", s.desc)
+				}
+				reportNotAttempted([]error{err})
+				if len(done) > 0 {
+					log.Logger.Warn().Msgf("rename of %s to %s is incomplete, completed steps: %s", from, to, strings.Join(done, "; "))
+				}
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			log.Logger.Info().Msg(s.desc)
+			done = append(done, s.desc)
+		}
+	},
+}
+
+// componentRenameGetPlan reads the records of the component from that renaming
+// it to to would copy and returns the plan to copy them. If to already exists
+// or a record cannot be read, the program exits. Since cloud-init instance info
+// can only be read with the impersonation API enabled, failing to read it is
+// only a warning.
+func componentRenameGetPlan(cmd *cobra.Command, smdClient *smd.SMDClient, bssClient *bss.BSSClient, cloudInitClient *ci.CloudInitClient, from, to string) componentRenamePlan {
+	exitOnErr := func(err error, what string) {
+		if err == nil {
+			return
+		}
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msgf("%s request yielded unsuccessful HTTP response", what)
+		} else {
+			log.Logger.Error().Err(err).Msgf("failed to request %s", what)
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	unmarshal := func(body []byte, v any, what string) {
+		if err := json.Unmarshal(body, v); err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to unmarshal %s", what)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	}
+	plan := componentRenamePlan{From: from, To: to}
+
+	// Component
+	henv, err := smdClient.GetComponentsXname(from, token)
+	exitOnErr(err, "SMD component")
+	unmarshal(henv.Body, &plan.Component, "SMD component")
+	plan.Component.ID = to
+	henv, err = smdClient.GetComponentsXname(to, token)
+	if err == nil {
+		log.Logger.Error().Msgf("component %s already exists in SMD", to)
+		logHelpError(cmd)
+		os.Exit(1)
+	} else if henv.StatusCode != http.StatusNotFound {
+		exitOnErr(err, "SMD component")
+	}
+
+	// Ethernet interfaces
+	henv, err = smdClient.GetEthernetInterfaces("ComponentID="+url.QueryEscape(from), token)
+	exitOnErr(err, "SMD ethernet interfaces")
+	var eis []smd.EthernetInterface
+	unmarshal(henv.Body, &eis, "SMD ethernet interfaces")
+	plan.EthernetInterfaces = smd.RenameEthernetInterfaces(eis, from, to)
+
+	// Group memberships
+	henv, err = smdClient.GetGroups("", token)
+	exitOnErr(err, "SMD groups")
+	var groups []smd.Group
+	unmarshal(henv.Body, &groups, "SMD groups")
+	plan.Groups = smd.GroupsWithMember(groups, from)
+
+	// Boot parameters, of which there are none if BSS does not find any
+	values := url.Values{}
+	values.Add("name", from)
+	henv, err = bssClient.GetBootParams(values.Encode(), token)
+	if err != nil && henv.StatusCode != http.StatusNotFound {
+		exitOnErr(err, "BSS boot parameters")
+	} else if err == nil {
+		var bps []bssTypes.BootParams
+		unmarshal(henv.Body, &bps, "BSS boot parameters")
+		for _, bp := range bps {
+			plan.BootParams = append(plan.BootParams, bssTypes.BootParams{
+				Hosts:     []string{to},
+				Params:    bp.Params,
+				Kernel:    bp.Kernel,
+				Initrd:    bp.Initrd,
+				CloudInit: bp.CloudInit,
+			})
+		}
+	}
+
+	// Cloud-init instance info
+	henvs, errs, err := cloudInitClient.GetNodeData(ci.CloudInitMetaData, token, from)
+	if err == nil && len(errs) > 0 && errs[0] != nil {
+		err = errs[0]
+	}
+	if err != nil {
+		log.Logger.Warn().Err(err).Msgf("unable to read cloud-init meta-data for %s (is the impersonation API enabled?), not copying cloud-init instance info", from)
+	} else {
+		var md struct {
+			LocalHostname string `yaml:"local-hostname"`
+			Hostname      string `yaml:"hostname"`
+		}
+		if err := yaml.Unmarshal(henvs[0].Body, &md); err != nil {
+			log.Logger.Warn().Err(err).Msgf("failed to unmarshal cloud-init meta-data for %s, not copying cloud-init instance info", from)
+		} else if md.LocalHostname != "" || md.Hostname != "" {
+			plan.CloudInit = &cistore.OpenCHAMIInstanceInfo{
+				ID:            to,
+				LocalHostname: md.LocalHostname,
+				Hostname:      md.Hostname,
+			}
+		}
+	}
+	if plan.EthernetInterfaces == nil {
+		plan.EthernetInterfaces = []smd.EthernetInterface{}
+	}
+	if plan.Groups == nil {
+		plan.Groups = []string{}
+	}
+	if plan.BootParams == nil {
+		plan.BootParams = []bssTypes.BootParams{}
+	}
+
+	return plan
+}
+
+// componentRenameSteps returns the steps of carrying out plan: copying its
+// records to the new xname, then removing those of the old xname.
+func componentRenameSteps(plan componentRenamePlan, smdClient *smd.SMDClient, bssClient *bss.BSSClient, cloudInitClient *ci.CloudInitClient) []componentRenameStep {
+	from, to := plan.From, plan.To
+	firstErr := func(errs []error, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, e := range errs {
+			if e != nil {
+				return e
+			}
+		}
+		return nil
+	}
+
+	steps := []componentRenameStep{{
+		desc: fmt.Sprintf("create component %s in SMD", to),
+		run: func() error {
+			_, err := smdClient.PostComponents(smd.ComponentSlice{Components: []smd.Component{plan.Component}}, token)
+			return err
+		},
+	}}
+	if len(plan.EthernetInterfaces) > 0 {
+		steps = append(steps, componentRenameStep{
+			desc: fmt.Sprintf("reassign %d ethernet interface(s) to %s in SMD", len(plan.EthernetInterfaces), to),
+			run: func() error {
+				_, errs, err := smdClient.PatchEthernetInterfaces(plan.EthernetInterfaces, token)
+				return firstErr(errs, err)
+			},
+		})
+	}
+	for _, g := range plan.Groups {
+		steps = append(steps, componentRenameStep{
+			desc: fmt.Sprintf("add %s to SMD group %s", to, g),
+			run: func() error {
+				_, errs, err := smdClient.PostGroupMembers(token, g, to)
+				return firstErr(errs, err)
+			},
+		})
+	}
+	for _, bp := range plan.BootParams {
+		steps = append(steps, componentRenameStep{
+			desc: fmt.Sprintf("add boot parameters for %s to BSS", to),
+			run: func() error {
+				_, err := bssClient.PostBootParams(bp, token)
+				return err
+			},
+		})
+	}
+	if plan.CloudInit != nil {
+		steps = append(steps, componentRenameStep{
+			desc: fmt.Sprintf("set cloud-init instance info for %s", to),
+			run: func() error {
+				_, errs, err := cloudInitClient.PutInstanceInfo([]cistore.OpenCHAMIInstanceInfo{*plan.CloudInit}, token)
+				return firstErr(errs, err)
+			},
+		})
+	}
+
+	for _, g := range plan.Groups {
+		steps = append(steps, componentRenameStep{
+			desc: fmt.Sprintf("remove %s from SMD group %s", from, g),
+			run: func() error {
+				_, errs, err := smdClient.DeleteGroupMembers(token, g, from)
+				return firstErr(errs, err)
+			},
+		})
+	}
+	if len(plan.BootParams) > 0 {
+		steps = append(steps, componentRenameStep{
+			desc: fmt.Sprintf("delete boot parameters for %s from BSS", from),
+			run: func() error {
+				_, err := bssClient.DeleteBootParams(bssTypes.BootParams{Hosts: []string{from}}, token)
+				return err
+			},
+		})
+	}
+	if plan.CloudInit != nil {
+		steps = append(steps, componentRenameStep{
+			desc: fmt.Sprintf("delete cloud-init instance info for %s", from),
+			run: func() error {
+				_, errs, err := cloudInitClient.DeleteInstanceInfo(token, from)
+				return firstErr(errs, err)
+			},
+		})
+	}
+	steps = append(steps, componentRenameStep{
+		desc: fmt.Sprintf("delete component %s from SMD", from),
+		run: func() error {
+			_, errs, err := smdClient.DeleteComponents(token, from)
+			return firstErr(errs, err)
+		},
+	})
+
+	return steps
+}
+
+func init() {
+	componentRenameCmd.Flags().Bool("dry-run", false, "print what would be copied without changing anything")
+	componentRenameCmd.Flags().Bool("no-confirm", false, "do not ask before renaming")
+	componentRenameCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output with --dry-run ("+format.DataFormatList()+")")
+
+	componentRenameCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	componentCmd.AddCommand(componentRenameCmd)
+}
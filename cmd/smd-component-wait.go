@@ -0,0 +1,230 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// componentWaitBatchSize is the maximum number of components requested from
+// SMD at once, so that the query string of each request stays short.
+const componentWaitBatchSize = 100
+
+// componentWaitCmd represents the "smd component wait" command
+var componentWaitCmd = &cobra.Command{
+	Use:   "wait --for <field>=<value>... (--xname <xname>... | --group <group>... | --target <target>... | --selector <selector>...) [--any | --all] [--timeout <duration>]",
+	Args:  cobra.NoArgs,
+	Short: "Wait for components to reach a state or flag",
+	Long: `Wait for components passed with --xname, --group (SMD groups, whose
+members are looked up in SMD), or --target and/or selected by their
+metadata with --selector (see ochami-meta(1)) to satisfy all of the
+conditions passed with --for, e.g. state=Ready or flag=OK. The
+components are polled in SMD every --poll-interval until all of them
+(or, with --any, any of them) have satisfied the conditions, or
+--timeout elapses.
+
+The outcome for each component is then printed, including how long
+it took to satisfy the conditions, and this command exits with a
+nonzero status if the wait timed out.
+
+See ochami-smd(1) for more details.`,
+	Example: `  # Wait up to 20 minutes for the compute nodes to be Ready
+  ochami smd component wait --for state=Ready --group compute --timeout 20m
+
+  # Wait for any of two nodes to be Ready with an OK flag
+  ochami smd component wait --for state=Ready --for flag=OK -x x1000c0s0b0n0,x1000c0s1b0n0 --any`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Parse conditions
+		forValues, err := cmd.Flags().GetStringArray("for")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --for")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var conditions []smd.WaitCondition
+		for _, f := range forValues {
+			wc, err := smd.ParseWaitCondition(f)
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("invalid --for")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			conditions = append(conditions, wc)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --timeout")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		interval, err := cmd.Flags().GetDuration("poll-interval")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --poll-interval")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if interval <= 0 {
+			log.Logger.Error().Msg("--poll-interval must be positive")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		waitAny := cmd.Flag("any").Changed
+
+		// Determine which components to wait for
+		xnames, err := cmd.Flags().GetStringSlice("xname")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --xname")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if cmd.Flag("group").Changed {
+			groups, err := cmd.Flags().GetStringSlice("group")
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to get value for --group")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			members := smdGetGroupMembers(cmd, groups...)
+			if len(members) == 0 {
+				log.Logger.Error().Msgf("no members found in SMD group(s) %v", groups)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			xnames = append(xnames, members...)
+		}
+		xnames = append(xnames, targetXnames(cmd)...)
+		xnames = append(xnames, metaSelectorXnames(cmd)...)
+		if len(xnames) == 0 {
+			log.Logger.Error().Msg("no components to wait for")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Create client to use for requests, bypassing caches since
+		// components are polled for changes
+		smdClient := &smd.SMDClient{OchamiClient: smdGetClient(cmd).Uncached()}
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		// Poll components until done or timed out
+		start := time.Now()
+		waiter := smd.NewWaiter(xnames, conditions, start)
+		pending := waiter.Pending()
+		log.Logger.Info().Msgf("waiting for %d component(s) to satisfy %s", len(pending), componentWaitConditions(conditions))
+		var deadline time.Time
+		if timeout > 0 {
+			deadline = start.Add(timeout)
+		}
+		for {
+			comps := componentWaitGet(cmd, smdClient, pending)
+			for _, x := range waiter.Update(comps, time.Now()) {
+				log.Logger.Info().Msgf("%s satisfied %s after %s", x, componentWaitConditions(conditions), time.Since(start).Round(time.Second))
+			}
+			pending = waiter.Pending()
+			if waiter.Done(waitAny) || (!deadline.IsZero() && !time.Now().Before(deadline)) {
+				break
+			}
+			log.Logger.Info().Msgf("%d component(s) pending", len(pending))
+			sleep := interval
+			if !deadline.IsZero() {
+				sleep = min(interval, time.Until(deadline))
+			}
+			time.Sleep(sleep)
+		}
+
+		// Print the outcome for each component
+		results := waiter.Results()
+		if locs, ok := showLocations(cmd); ok {
+			for i := range results {
+				results[i].Location = locs.Describe(results[i].Xname)
+			}
+		}
+		if outBytes, err := format.MarshalData(results, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+
+		if !waiter.Done(waitAny) {
+			log.Logger.Error().Msgf("timed out after %s with %d component(s) pending", timeout, len(pending))
+			exitWithStatus(1)
+		}
+	},
+}
+
+// componentWaitConditions returns conditions as a human-readable string for
+// logging.
+func componentWaitConditions(conditions []smd.WaitCondition) string {
+	s := make([]string, len(conditions))
+	for i, wc := range conditions {
+		s[i] = wc.String()
+	}
+
+	return strings.Join(s, " and ")
+}
+
+// componentWaitGet returns the components xnames from SMD, requesting them in
+// batches. If a request fails, an error is logged and the program exits.
+func componentWaitGet(cmd *cobra.Command, smdClient *smd.SMDClient, xnames []string) []smd.Component {
+	var comps []smd.Component
+	for i := 0; i < len(xnames); i += componentWaitBatchSize {
+		batch := xnames[i:min(i+componentWaitBatchSize, len(xnames))]
+		henv, err := smdClient.GetComponentsIDs(token, batch...)
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("SMD component request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to request components from SMD")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var cs smd.ComponentSlice
+		if err := json.Unmarshal(henv.Body, &cs); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal components")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		comps = append(comps, cs.Components...)
+	}
+
+	return comps
+}
+
+func init() {
+	componentWaitCmd.Flags().StringArray("for", []string{}, "condition to wait for (state=<state>, flag=<flag>, enabled=<bool>, role=<role>), can be passed more than once to wait for all of them")
+	componentWaitCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames to wait for")
+	componentWaitCmd.Flags().StringSliceP("group", "g", []string{}, "one or more SMD groups whose members to wait for")
+	componentWaitCmd.Flags().StringSlice("target", []string{}, targetFlagUsage)
+	targetAddFromFlags(componentWaitCmd)
+	componentWaitCmd.Flags().StringArray("selector", []string{}, "select target components by metadata (meta.<key>=<value>), can be passed more than once")
+	componentWaitCmd.Flags().Bool("any", false, "stop waiting once any component satisfies the conditions")
+	componentWaitCmd.Flags().Bool("all", false, "stop waiting once all components satisfy the conditions (default)")
+	componentWaitCmd.Flags().Duration("timeout", 10*time.Minute, "how long to wait before giving up (0 for no limit)")
+	componentWaitCmd.Flags().Duration("poll-interval", 10*time.Second, "interval at which to poll components in SMD")
+	componentWaitCmd.Flags().Bool("show-location", false, showLocationFlagUsage)
+	componentWaitCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	componentWaitCmd.MarkFlagRequired("for")
+	componentWaitCmd.MarkFlagsOneRequired("xname", "group", "target", "targets-from", "selector")
+	componentWaitCmd.MarkFlagsMutuallyExclusive("any", "all")
+
+	componentWaitCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	componentCmd.AddCommand(componentWaitCmd)
+}
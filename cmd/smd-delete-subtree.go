@@ -0,0 +1,350 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+	"github.com/OpenCHAMI/ochami/pkg/pool"
+)
+
+// smdDeleteSubtreeCmd represents the "smd delete-subtree" command
+var smdDeleteSubtreeCmd = &cobra.Command{
+	Use:   "delete-subtree [--concurrency <n>] [--target-timeout <duration>] [--state-file <path>] [--dry-run] [--no-confirm] <xname>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Delete everything in SMD located under an xname",
+	Long: `Delete everything in SMD located under an xname, e.g. an entire
+cabinet (x3000) or chassis (x3000c0). This is meant for decommissioning
+hardware.
+
+All components, ethernet interfaces (by component ID), redfish endpoints,
+and component endpoints at or under <xname> in the xname hierarchy are
+found and their counts are shown. After confirming (unless --no-confirm
+is passed), they are deleted bottom-up: ethernet interfaces, component
+endpoints, and redfish endpoints first, then components from the deepest
+up to <xname> itself. Up to --concurrency deletions run at once within
+each step. A deletion that does not finish within --target-timeout is
+marked failed without holding up the others in its step.
+
+If --state-file is passed, the list of resources and the progress of the
+deletion are saved to it. If the command is interrupted or some
+deletions fail, running it again with the same --state-file resumes
+where it left off instead of searching SMD again. The state file is
+removed once everything has been deleted. Resources that no longer exist
+in SMD count as deleted.
+
+Pass --dry-run to print the resources that would be deleted without
+deleting them.
+
+See ochami-smd(1) for more details.`,
+	Example: `  # Show what would be deleted for chassis x3000c0
+  ochami smd delete-subtree --dry-run x3000c0
+
+  # Delete cabinet x3000, saving progress so it can be resumed
+  ochami smd delete-subtree --state-file x3000.json --concurrency 8 x3000`,
+	Run: func(cmd *cobra.Command, args []string) {
+		root := args[0]
+
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		concurrency, err := cmd.Flags().GetInt("concurrency")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --concurrency")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if concurrency < 1 {
+			log.Logger.Error().Msg("--concurrency must be at least 1")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		targetTimeout, err := cmd.Flags().GetDuration("target-timeout")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --target-timeout")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		stateFile, err := cmd.Flags().GetString("state-file")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --state-file")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Resume from the state file, if present. Otherwise, find
+		// everything under root in SMD.
+		plan, resumed := smdDeleteSubtreeLoadState(cmd, stateFile, root)
+		if !resumed {
+			plan = smdDeleteSubtreePlan(cmd, smdClient, root)
+		}
+
+		// Print plan and exit if only a dry run
+		if cmd.Flag("dry-run").Changed {
+			if outBytes, err := format.MarshalData(plan, formatOutput); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			} else {
+				fmt.Println(string(outBytes))
+			}
+			exitWithStatus(0)
+		}
+
+		// Show counts
+		remaining := plan.Remaining()
+		var total int
+		kinds := make([]string, 0, len(remaining))
+		for k, n := range remaining {
+			kinds = append(kinds, k)
+			total += n
+		}
+		sort.Strings(kinds)
+		fmt.Fprintf(os.Stderr, "Resources under %s to delete:\n", root)
+		for _, k := range kinds {
+			fmt.Fprintf(os.Stderr, "  %-18s %d\n", k, remaining[k])
+		}
+		if total == 0 {
+			log.Logger.Info().Msgf("nothing to delete under %s", root)
+			smdDeleteSubtreeRemoveState(stateFile)
+			exitWithStatus(0)
+		}
+
+		// Ask before attempting deletion, requiring the number of
+		// resources to be confirmed if there are many
+		guardImpact(cmd, "delete", "component", remaining[smd.SubtreeKindComponent], func() int {
+			return smdCountAll(cmd, "component", smdClient.GetComponentsAll, "Components")
+		})
+		confirmDeletion(cmd, "Really delete?", "resource", total)
+
+		// Delete each stage in order, stopping if a stage has errors
+		// since later stages may depend on it. Each deletion is bounded
+		// by --target-timeout so that one unresponsive request fails on
+		// its own instead of stalling the rest of the stage.
+		var errs []error
+		smdDeleteSubtreeSaveState(stateFile, plan)
+		for _, stage := range plan.Stages() {
+			var stageErrs []error
+			pool.Run(context.Background(), len(stage), concurrency, targetTimeout, func(ctx context.Context, j int) error {
+				c := smdClient
+				if dl, ok := ctx.Deadline(); ok {
+					c = &smd.SMDClient{OchamiClient: smdClient.WithDeadline(dl)}
+				}
+				return smdDeleteSubtreeItem(c, plan.Items[stage[j]])
+			}, func(j int, err error) {
+				item := &plan.Items[stage[j]]
+				if err != nil {
+					if errors.Is(err, pool.TargetTimeoutError) {
+						log.Logger.Error().Err(err).Msgf("timed out deleting %s %s", item.Kind, item.ID)
+					} else if errors.Is(err, client.UnsuccessfulHTTPError) {
+						log.Logger.Error().Err(err).Msg("SMD delete request yielded unsuccessful HTTP response")
+					} else {
+						log.Logger.Error().Err(err).Msgf("failed to delete %s %s", item.Kind, item.ID)
+					}
+					stageErrs = append(stageErrs, err)
+				} else {
+					item.Done = true
+					smdDeleteSubtreeSaveState(stateFile, plan)
+				}
+			})
+			errs = append(errs, stageErrs...)
+			if len(stageErrs) > 0 {
+				break
+			}
+		}
+		reportNotAttempted(errs)
+		if len(errs) > 0 {
+			log.Logger.Warn().Msgf("subtree deletion of %s completed with errors", root)
+			if stateFile != "" {
+				log.Logger.Warn().Msgf("rerun with --state-file %s to resume", stateFile)
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		smdDeleteSubtreeRemoveState(stateFile)
+	},
+}
+
+// smdDeleteSubtreePlan finds all components, ethernet interfaces, redfish
+// endpoints, and component endpoints under root in SMD and returns the plan to
+// delete them. If an error occurs, the program exits.
+func smdDeleteSubtreePlan(cmd *cobra.Command, smdClient *smd.SMDClient, root string) smd.SubtreePlan {
+	exitOnErr := func(err error, what string) {
+		if err == nil {
+			return
+		}
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msgf("SMD %s request yielded unsuccessful HTTP response", what)
+		} else {
+			log.Logger.Error().Err(err).Msgf("failed to request %s from SMD", what)
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	unmarshal := func(body []byte, v any, what string) {
+		if err := json.Unmarshal(body, v); err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to unmarshal %s", what)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	}
+
+	henv, err := smdClient.GetComponentsAll()
+	exitOnErr(err, "components")
+	var comps smd.ComponentSlice
+	unmarshal(henv.Body, &comps, "components")
+
+	henv, err = smdClient.GetEthernetInterfaces("", token)
+	exitOnErr(err, "ethernet interfaces")
+	var eis []smd.EthernetInterface
+	unmarshal(henv.Body, &eis, "ethernet interfaces")
+
+	henv, err = smdClient.GetRedfishEndpoints("", token)
+	exitOnErr(err, "redfish endpoints")
+	var rfes smd.RedfishEndpointSlice
+	unmarshal(henv.Body, &rfes, "redfish endpoints")
+	var rfeIDs []string
+	for _, rfe := range rfes.RedfishEndpoints {
+		rfeIDs = append(rfeIDs, rfe.ID)
+	}
+
+	henv, err = smdClient.GetComponentEndpointsAll(token)
+	exitOnErr(err, "component endpoints")
+	var compEPs struct {
+		ComponentEndpoints []struct {
+			ID string `json:"ID"`
+		} `json:"ComponentEndpoints"`
+	}
+	unmarshal(henv.Body, &compEPs, "component endpoints")
+	var compEPIDs []string
+	for _, ce := range compEPs.ComponentEndpoints {
+		compEPIDs = append(compEPIDs, ce.ID)
+	}
+
+	return smd.NewSubtreePlan(root, comps.Components, eis, rfeIDs, compEPIDs)
+}
+
+// smdDeleteSubtreeItem deletes the SMD resource item. An item that no longer
+// exists in SMD (e.g. because it was deleted by a run that was interrupted
+// before saving its state) counts as deleted.
+func smdDeleteSubtreeItem(smdClient *smd.SMDClient, item smd.SubtreeItem) error {
+	var (
+		henvs []client.HTTPEnvelope
+		errs  []error
+		err   error
+	)
+	switch item.Kind {
+	case smd.SubtreeKindEthernetInterface:
+		henvs, errs, err = smdClient.DeleteEthernetInterfaces(token, item.ID)
+	case smd.SubtreeKindComponentEndpoint:
+		henvs, errs, err = smdClient.DeleteComponentEndpoints(token, item.ID)
+	case smd.SubtreeKindRedfishEndpoint:
+		henvs, errs, err = smdClient.DeleteRedfishEndpoints(token, item.ID)
+	case smd.SubtreeKindComponent:
+		henvs, errs, err = smdClient.DeleteComponents(token, item.ID)
+	default:
+		return fmt.Errorf("unknown kind %q for %s", item.Kind, item.ID)
+	}
+	if err != nil {
+		return err
+	}
+	if len(errs) > 0 && errs[0] != nil {
+		if len(henvs) > 0 && henvs[0].StatusCode == http.StatusNotFound {
+			log.Logger.Debug().Msgf("%s %s already deleted", item.Kind, item.ID)
+			return nil
+		}
+		return errs[0]
+	}
+
+	return nil
+}
+
+// smdDeleteSubtreeLoadState returns the plan saved in stateFile, if stateFile
+// is set and exists, and true. If it does not, false is returned. If the file
+// cannot be read or is for a different root, the program exits.
+func smdDeleteSubtreeLoadState(cmd *cobra.Command, stateFile, root string) (smd.SubtreePlan, bool) {
+	var plan smd.SubtreePlan
+	if stateFile == "" {
+		return plan, false
+	}
+	b, err := os.ReadFile(stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return plan, false
+	} else if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to read state file")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if err := json.Unmarshal(b, &plan); err != nil {
+		log.Logger.Error().Err(err).Msgf("failed to unmarshal state file %s", stateFile)
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if plan.Root != root {
+		log.Logger.Error().Msgf("state file %s is for %s, not %s", stateFile, plan.Root, root)
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	log.Logger.Info().Msgf("resuming deletion of %s from state file %s", root, stateFile)
+
+	return plan, true
+}
+
+// smdDeleteSubtreeSaveState writes plan to stateFile, if set, replacing it
+// atomically so that an interruption cannot leave a truncated state file.
+// Failures are only logged as warnings so that deletion can continue.
+func smdDeleteSubtreeSaveState(stateFile string, plan smd.SubtreePlan) {
+	if stateFile == "" {
+		return
+	}
+	b, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		log.Logger.Warn().Err(err).Msg("failed to marshal state")
+		return
+	}
+	if err := writeFileAtomic(stateFile, append(b, '\n'), 0600); err != nil {
+		log.Logger.Warn().Err(err).Msgf("failed to write state file %s", stateFile)
+	}
+}
+
+// smdDeleteSubtreeRemoveState removes stateFile, if set, once deletion is
+// complete.
+func smdDeleteSubtreeRemoveState(stateFile string) {
+	if stateFile == "" {
+		return
+	}
+	if err := os.Remove(stateFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Logger.Warn().Err(err).Msgf("failed to remove state file %s", stateFile)
+	}
+}
+
+func init() {
+	smdDeleteSubtreeCmd.Flags().Int("concurrency", 4, "maximum number of deletions to run at once")
+	smdDeleteSubtreeCmd.Flags().Duration("target-timeout", time.Minute, "maximum time to wait for each deletion before marking it failed (0 for no limit)")
+	smdDeleteSubtreeCmd.Flags().String("state-file", "", "file to save progress to and resume from")
+	smdDeleteSubtreeCmd.Flags().Bool("dry-run", false, "print what would be deleted without deleting anything")
+	smdDeleteSubtreeCmd.Flags().Bool("no-confirm", false, "do not ask before attempting deletion")
+	smdDeleteSubtreeCmd.Flags().Int("yes-really-delete", 0, "confirm deleting more than delete-threshold items by passing their exact number")
+	smdDeleteSubtreeCmd.Flags().Bool("allow-major-impact", false, allowMajorImpactFlagUsage)
+	smdDeleteSubtreeCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output with --dry-run ("+format.DataFormatList()+")")
+
+	smdDeleteSubtreeCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	smdCmd.AddCommand(smdDeleteSubtreeCmd)
+}
@@ -0,0 +1,42 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/pkg/export"
+)
+
+// smdExportConmanCmd represents the "smd export conman" command
+var smdExportConmanCmd = &cobra.Command{
+	Use:   "conman [--template <file>]",
+	Args:  cobra.NoArgs,
+	Short: "Generate conman console entries from SMD",
+	Long: `Generate conman.conf console entries from the node BMCs in SMD and
+the nodes they manage so that the console configuration stays in sync
+with the inventory. The entries are printed to standard output.
+
+By default, one CONSOLE entry is generated per node, connecting to
+its BMC with IPMI Serial-Over-LAN. BMCs are reached by their FQDN
+in SMD, or their IP address if they have none. Pass --template with
+a Go text/template file to generate something else.
+
+An access token is required.
+
+See ochami-smd(1) for the data available to templates.`,
+	Example: `  # Generate console entries to include from conman.conf
+  ochami smd export conman > /etc/conman.d/ochami.conf
+
+  # Generate console entries with a custom template
+  ochami smd export conman --template ./conman.tmpl`,
+	Run: func(cmd *cobra.Command, args []string) {
+		smdExportTemplate(cmd, export.ConmanTemplate)
+	},
+}
+
+func init() {
+	smdExportConmanCmd.Flags().String("template", "", "path to Go text/template to generate config with instead of the built-in one")
+
+	smdExportCmd.AddCommand(smdExportConmanCmd)
+}
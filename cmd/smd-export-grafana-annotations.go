@@ -0,0 +1,182 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/grafana"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// smdExportGrafanaAnnotationsCmd represents the "smd export grafana-annotations" command
+var smdExportGrafanaAnnotationsCmd = &cobra.Command{
+	Use:   "grafana-annotations --action <action> [--xname <xname>]... [--group <group>]... [--state <state>]...",
+	Args:  cobra.NoArgs,
+	Short: "Push a Grafana annotation for an action performed on SMD components",
+	Long: `Push a Grafana annotation for an action performed on SMD components
+so that dashboards can correlate changes in metrics with operational
+actions. The annotation is tagged with "ochami", the action, and
+any tags in grafana.tags or passed with --tag, and its text lists the
+affected components.
+
+Components are read from SMD. By default, all components are
+included. --xname and/or --group (SMD groups) limit the components
+to those listed, and --state further limits them to those in one of
+the passed states (e.g. Off).
+
+The annotation spans --start to --end (RFC 3339 timestamps). If
+--start is not passed, the current time is used. If --end is not
+passed, the annotation marks a single point in time.
+
+Grafana is configured per-cluster with grafana.uri and, optionally,
+grafana.dashboard-uid and grafana.tags in the config file. The API
+token is read from <CLUSTER>_GRAFANA_TOKEN.
+
+The annotation that was pushed is printed. See ochami-smd(1) for
+more details.`,
+	Example: `  # Annotate that the compute group is entering maintenance
+  ochami smd export grafana-annotations --group compute --action maintenance
+
+  # Annotate a past firmware update window for nodes that are now off
+  ochami smd export grafana-annotations --group compute --state Off \
+    --action 'firmware update' --start 2025-01-01T10:00:00Z --end 2025-01-01T11:30:00Z`,
+	Run: func(cmd *cobra.Command, args []string) {
+		action, err := cmd.Flags().GetString("action")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --action")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Determine annotation time span
+		start, end := time.Now(), time.Time{}
+		for _, f := range []struct {
+			name string
+			t    *time.Time
+		}{{"start", &start}, {"end", &end}} {
+			if !cmd.Flag(f.name).Changed {
+				continue
+			}
+			s, _ := cmd.Flags().GetString(f.name)
+			if *f.t, err = time.Parse(time.RFC3339, s); err != nil {
+				log.Logger.Error().Err(err).Msgf("invalid --%s timestamp", f.name)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+
+		// Make sure Grafana is configured before querying SMD
+		grafanaClient, gcfg := grafanaGetClient(cmd)
+		if grafanaClient == nil {
+			log.Logger.Error().Msg("grafana.uri is not set for the cluster in the config file")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Determine which components to include
+		xnames, err := cmd.Flags().GetStringSlice("xname")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --xname")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if cmd.Flag("group").Changed {
+			groups, err := cmd.Flags().GetStringSlice("group")
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to get value for --group")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			xnames = append(xnames, smdGetGroupMembers(cmd, groups...)...)
+		}
+		states, err := cmd.Flags().GetStringSlice("state")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --state")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Get components from SMD
+		smdClient := smdGetClient(cmd)
+		henv, err := smdClient.GetComponentsAll()
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("SMD component request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to request components from SMD")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var comps smd.ComponentSlice
+		if err := json.Unmarshal(henv.Body, &comps); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal components")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		restrict := cmd.Flag("xname").Changed || cmd.Flag("group").Changed
+		var annotated []string
+		for _, c := range comps.Components {
+			if restrict && !slices.Contains(xnames, c.ID) {
+				continue
+			}
+			if len(states) > 0 && !slices.Contains(states, c.State) {
+				continue
+			}
+			annotated = append(annotated, c.ID)
+		}
+		if len(annotated) == 0 {
+			log.Logger.Warn().Msg("no components matched, annotation will not list any components")
+		}
+
+		// Push annotation
+		extraTags, _ := cmd.Flags().GetStringSlice("tag")
+		a := grafana.NewActionAnnotation(action, annotated, start, end, slices.Concat(gcfg.Tags, extraTags)...)
+		a.DashboardUID = gcfg.DashboardUID
+		if _, err := grafanaClient.PostAnnotation(a, grafanaToken(cmd)); err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("Grafana annotation request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to push annotation to Grafana")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Print output
+		if outBytes, err := format.MarshalData(a, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+	},
+}
+
+func init() {
+	smdExportGrafanaAnnotationsCmd.Flags().String("action", "", "action to annotate (e.g. maintenance)")
+	smdExportGrafanaAnnotationsCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames to include in the annotation")
+	smdExportGrafanaAnnotationsCmd.Flags().StringSliceP("group", "g", []string{}, "one or more SMD groups whose members to include in the annotation")
+	smdExportGrafanaAnnotationsCmd.Flags().StringSlice("state", []string{}, "only include components in one of these SMD states")
+	smdExportGrafanaAnnotationsCmd.Flags().StringSlice("tag", []string{}, "additional tags to add to the annotation")
+	smdExportGrafanaAnnotationsCmd.Flags().String("start", "", "start time of annotation in RFC 3339 format (default: now)")
+	smdExportGrafanaAnnotationsCmd.Flags().String("end", "", "end time of annotation in RFC 3339 format (default: none)")
+	smdExportGrafanaAnnotationsCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	smdExportGrafanaAnnotationsCmd.MarkFlagRequired("action")
+	smdExportGrafanaAnnotationsCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	smdExportCmd.AddCommand(smdExportGrafanaAnnotationsCmd)
+}
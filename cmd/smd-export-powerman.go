@@ -0,0 +1,44 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/pkg/export"
+)
+
+// smdExportPowermanCmd represents the "smd export powerman" command
+var smdExportPowermanCmd = &cobra.Command{
+	Use:   "powerman [--template <file>]",
+	Args:  cobra.NoArgs,
+	Short: "Generate powerman device and node config from SMD",
+	Long: `Generate powerman.conf device and node entries from the node BMCs
+in SMD and the nodes they manage so that the power control
+configuration stays in sync with the inventory. The config is
+printed to standard output.
+
+By default, one redfishpower device is generated per BMC, with a
+node entry for each node it manages. BMCs are reached by their FQDN
+in SMD, or their IP address if they have none. Pass --template with
+a Go text/template file to generate something else, e.g. for BMCs
+managing several nodes.
+
+An access token is required.
+
+See ochami-smd(1) for the data available to templates.`,
+	Example: `  # Generate powerman config
+  ochami smd export powerman > /etc/powerman/powerman.conf
+
+  # Generate powerman config with a custom template
+  ochami smd export powerman --template ./powerman.tmpl`,
+	Run: func(cmd *cobra.Command, args []string) {
+		smdExportTemplate(cmd, export.PowermanTemplate)
+	},
+}
+
+func init() {
+	smdExportPowermanCmd.Flags().String("template", "", "path to Go text/template to generate config with instead of the built-in one")
+
+	smdExportCmd.AddCommand(smdExportPowermanCmd)
+}
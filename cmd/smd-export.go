@@ -0,0 +1,106 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/export"
+)
+
+// smdExportCmd represents the "smd export" command
+var smdExportCmd = &cobra.Command{
+	Use:   "export",
+	Args:  cobra.NoArgs,
+	Short: "Export SMD data to external systems",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			printUsageHandleError(cmd)
+		}
+	},
+}
+
+// smdExportTemplate generates a config file from the node BMCs in SMD and the
+// nodes they manage by executing defaultTmpl, or the template passed with
+// --template, and prints it to standard output.
+func smdExportTemplate(cmd *cobra.Command, defaultTmpl string) {
+	tmpl := defaultTmpl
+	if cmd.Flag("template").Changed {
+		p, err := cmd.Flags().GetString("template")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --template")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to read template")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		tmpl = string(b)
+	}
+
+	// Create client to use for requests
+	smdClient := smdGetClient(cmd)
+
+	// Handle token for this command
+	handleToken(cmd)
+
+	henv, err := smdClient.GetRedfishEndpoints("", token)
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("SMD redfish endpoint request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to request redfish endpoints from SMD")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	var rfes smd.RedfishEndpointSlice
+	if err := json.Unmarshal(henv.Body, &rfes); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to unmarshal redfish endpoints")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	henv, err = smdClient.GetComponentsAll()
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("SMD component request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to request components from SMD")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	var comps smd.ComponentSlice
+	if err := json.Unmarshal(henv.Body, &comps); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to unmarshal components")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	bmcs := export.BMCs(rfes.RedfishEndpoints, comps.Components)
+	if len(bmcs) == 0 {
+		log.Logger.Warn().Msg("no node BMCs managing nodes were found in SMD")
+	}
+	out, err := export.Render(tmpl, export.Data{BMCs: bmcs})
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to generate config")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	fmt.Print(string(out))
+}
+
+func init() {
+	smdCmd.AddCommand(smdExportCmd)
+}
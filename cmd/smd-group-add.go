@@ -5,6 +5,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -12,6 +13,7 @@ import (
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
 	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // groupAddCmd represents the "smd group add" command
@@ -126,13 +128,23 @@ See ochami-smd(1) for more details.`,
 			groups = append(groups, group)
 		}
 
+		// Stamp groups with who created them
+		for i := range groups {
+			groups[i].Description = annotate(cmd, groups[i].Description)
+		}
+
 		// Send off request
-		_, errs, err := smdClient.PostGroups(groups, token)
+		henvs, errs, err := smdClient.PostGroups(groups, token)
 		if err != nil {
 			log.Logger.Error().Err(err).Msg("failed to add group to SMD")
 			logHelpError(cmd)
 			os.Exit(1)
 		}
+		labels := make([]string, len(groups))
+		for i, g := range groups {
+			labels[i] = g.Label
+		}
+		ignoreStatus(cmd, "if-not-exists", http.StatusConflict, "group", henvs, errs, labels)
 		// Since smdClient.PostGroups does the addition iteratively, we need to deal with
 		// each error that might have occurred.
 		var errorsOccurred = false
@@ -146,6 +158,7 @@ See ochami-smd(1) for more details.`,
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		if errorsOccurred {
 			logHelpError(cmd)
 			log.Logger.Warn().Msg("SMD group addition completed with errors")
@@ -160,7 +173,8 @@ func init() {
 	groupAddCmd.Flags().StringP("exclusive-group", "e", "", "name of group that cannot share members with this one")
 	groupAddCmd.Flags().StringSliceP("member", "m", []string{}, "one or more component IDs to add to the new group")
 	groupAddCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
-	groupAddCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	groupAddCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
+	groupAddCmd.Flags().Bool("if-not-exists", false, ifNotExistsFlagUsage)
 
 	groupAddCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
 	groupAddCmd.MarkFlagsMutuallyExclusive("description", "data")
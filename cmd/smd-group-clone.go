@@ -0,0 +1,193 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// groupCloneCmd represents the "smd group clone" command
+var groupCloneCmd = &cobra.Command{
+	Use:   "clone [--selector <selector>]... [--target <target>,...] [--remap <from>=<to>]... [-D <description>] [--tag <tag>,...] [-e <group>] [--dry-run] <group_label> <new_label>",
+	Args:  cobra.ExactArgs(2),
+	Short: "Clone a group to a new one, optionally filtering or remapping its members",
+	Long: `Clone a group to a new one, copying its description, tags, and
+members. The clone has no exclusive group unless one is passed with
+--exclusive-group, since it would share members with the original.
+
+The members of the clone can be a subset of those of the original:
+only members matching all --selector selectors (meta.<key>=<value>)
+and, if --target is passed, among its targets are kept. This is
+useful for splitting a group, e.g. compute into compute-a100 and
+compute-h100. Each --remap <from>=<to> then rewrites the members
+starting with the xname prefix <from> to start with <to> instead
+(e.g. x1000=x1001 to clone a group of one cabinet to another). The
+first remap that applies to a member is used.
+
+Pass --dry-run to print the group that would be added without adding
+it.
+
+This command sends a POST to SMD. An access token is required.
+
+See ochami-smd(1) for more details.`,
+	Example: `  # Clone a group
+  ochami smd group clone compute compute-old
+
+  # Split the compute group by GPU metadata
+  ochami smd group clone --selector meta.gpu=a100 compute compute-a100
+  ochami smd group clone --selector meta.gpu=h100 compute compute-h100
+
+  # Clone the group of cabinet x1000 for cabinet x1001
+  ochami smd group clone --remap x1000=x1001 -D "Cabinet x1001" cab-x1000 cab-x1001`,
+	Run: func(cmd *cobra.Command, args []string) {
+		source, label := args[0], args[1]
+
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		var remaps []smd.MemberRemap
+		remapStrs, err := cmd.Flags().GetStringArray("remap")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --remap")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, s := range remapStrs {
+			mr, err := smd.ParseMemberRemap(s)
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("invalid --remap")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			remaps = append(remaps, mr)
+		}
+
+		// Get the group to clone
+		values := url.Values{}
+		values.Add("group", source)
+		henv, err := smdClient.GetGroups(values.Encode(), token)
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("SMD group request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to request groups from SMD")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var groups []smd.Group
+		if err := json.Unmarshal(henv.Body, &groups); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal groups")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if len(groups) == 0 {
+			log.Logger.Error().Msgf("group %s not found in SMD", source)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Keep only the members matching --selector and --target, if
+		// passed
+		var keep func(string) bool
+		for _, xnames := range [][]string{metaSelectorXnames(cmd), targetXnames(cmd)} {
+			if xnames == nil {
+				continue
+			}
+			prev := keep
+			keep = func(id string) bool {
+				return (prev == nil || prev(id)) && smd.HasMember(xnames, id)
+			}
+		}
+		clone := smd.CloneGroup(groups[0], label, keep, remaps)
+		if cmd.Flag("description").Changed {
+			if clone.Description, err = cmd.Flags().GetString("description"); err != nil {
+				log.Logger.Error().Err(err).Msg("unable to fetch description")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+		if cmd.Flag("tag").Changed {
+			if clone.Tags, err = cmd.Flags().GetStringSlice("tag"); err != nil {
+				log.Logger.Error().Err(err).Msg("unable to fetch tags")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+		if cmd.Flag("exclusive-group").Changed {
+			if clone.ExclusiveGroup, err = cmd.Flags().GetString("exclusive-group"); err != nil {
+				log.Logger.Error().Err(err).Msg("unable to fetch exclusive group name")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+		if len(clone.Members.IDs) == 0 {
+			log.Logger.Warn().Msgf("no members of %s were kept, %s will be empty", source, label)
+		}
+		log.Logger.Info().Msgf("cloning %s to %s with %d of its %d member(s)", source, label, len(clone.Members.IDs), len(groups[0].Members.IDs))
+
+		// Stamp clone with who created it, replacing the stamp of the source
+		clone.Description = annotate(cmd, clone.Description)
+
+		// Print group and exit if only a dry run
+		if cmd.Flag("dry-run").Changed {
+			if outBytes, err := format.MarshalData(clone, formatOutput); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			} else {
+				fmt.Println(string(outBytes))
+			}
+			exitWithStatus(0)
+		}
+
+		// Send off request
+		_, errs, err := smdClient.PostGroups([]smd.Group{clone}, token)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to add group to SMD")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, err := range errs {
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msg("SMD group request yielded unsuccessful HTTP response")
+				} else {
+					log.Logger.Error().Err(err).Msgf("failed to add group %s to SMD", label)
+				}
+				reportNotAttempted(errs)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+	},
+}
+
+func init() {
+	groupCloneCmd.Flags().StringArray("selector", []string{}, "keep only members matching metadata (meta.<key>=<value>), can be passed more than once")
+	groupCloneCmd.Flags().StringSlice("target", []string{}, "keep only members among "+targetFlagUsage)
+	groupCloneCmd.Flags().StringArray("remap", []string{}, "rewrite members starting with xname prefix <from> to start with <to> (<from>=<to>), can be passed more than once")
+	groupCloneCmd.Flags().StringP("description", "D", "", "description of the clone (default: that of the group)")
+	groupCloneCmd.Flags().StringSlice("tag", []string{}, "tags of the clone (default: those of the group)")
+	groupCloneCmd.Flags().StringP("exclusive-group", "e", "", "name of group that cannot share members with the clone")
+	groupCloneCmd.Flags().Bool("dry-run", false, "print the group that would be added without adding it")
+	groupCloneCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output with --dry-run ("+format.DataFormatList()+")")
+
+	groupCloneCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	groupCmd.AddCommand(groupCloneCmd)
+}
@@ -5,6 +5,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -12,6 +13,7 @@ import (
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
 	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // groupDeleteCmd represents the "smd group delete" command
@@ -66,44 +68,37 @@ See ochami-smd(1) for more details.`,
 		// Handle token for this command
 		handleToken(cmd)
 
-		// Ask before attempting deletion unless --no-confirm was passed
-		if !cmd.Flag("no-confirm").Changed {
-			log.Logger.Debug().Msg("--no-confirm not passed, prompting user to confirm deletion")
-			respDelete, err := ios.loopYesNo("Really delete?")
-			if err != nil {
-				log.Logger.Error().Err(err).Msg("Error fetching user input")
-				os.Exit(1)
-			} else if !respDelete {
-				log.Logger.Info().Msg("User aborted group deletion")
-				os.Exit(0)
-			} else {
-				log.Logger.Debug().Msg("User answered affirmatively to delete groups")
-			}
-		}
-
 		// Create list of group labels to delete
 		var groups []smd.Group
 		var gLabelSlice []string
 		if cmd.Flag("data").Changed {
 			// Use payload file if passed
 			handlePayload(cmd, &groups)
+			for _, g := range groups {
+				gLabelSlice = append(gLabelSlice, g.Label)
+			}
 		} else {
 			// ...otherwise, use passed CLI arguments
 			gLabelSlice = args
 		}
 
+		// Ask before attempting deletion, requiring the number of
+		// groups to be confirmed if there are many
+		confirmDeletion(cmd, "Really delete?", "group", len(gLabelSlice))
+
 		// Perform deletion
-		_, errs, err := smdClient.DeleteGroups(token, gLabelSlice...)
+		henvs, errs, err := smdClient.DeleteGroups(token, gLabelSlice...)
 		if err != nil {
 			log.Logger.Error().Err(err).Msg("failed to delete groups in SMD")
 			logHelpError(cmd)
 			os.Exit(1)
 		}
+		ignoreStatus(cmd, "if-exists", http.StatusNotFound, "group", henvs, errs, gLabelSlice)
 		// Since smdClient.DeleteGroups does the deletion iteratively, we need to deal with
 		// each error that might have occurred.
 		var errorsOccurred = false
 		for _, e := range errs {
-			if err != nil {
+			if e != nil {
 				if errors.Is(e, client.UnsuccessfulHTTPError) {
 					log.Logger.Error().Err(e).Msg("SMD group deletion yielded unsuccessful HTTP response")
 				} else {
@@ -112,6 +107,7 @@ See ochami-smd(1) for more details.`,
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		// Warn the user if any errors occurred during deletion iterations
 		if errorsOccurred {
 			logHelpError(cmd)
@@ -123,8 +119,10 @@ See ochami-smd(1) for more details.`,
 
 func init() {
 	groupDeleteCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
-	groupDeleteCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	groupDeleteCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
 	groupDeleteCmd.Flags().Bool("no-confirm", false, "do not ask before attempting deletion")
+	groupDeleteCmd.Flags().Int("yes-really-delete", 0, "confirm deleting more than delete-threshold items by passing their exact number")
+	groupDeleteCmd.Flags().Bool("if-exists", false, ifExistsFlagUsage)
 
 	groupDeleteCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
 
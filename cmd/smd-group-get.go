@@ -3,6 +3,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
@@ -12,6 +13,8 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // groupGetCmd represents the "smd group get" command
@@ -19,7 +22,10 @@ var groupGetCmd = &cobra.Command{
 	Use:   "get",
 	Args:  cobra.NoArgs,
 	Short: "Get all groups or group(s) identified by name and/or tag",
-	Long: `Get all groups or group(s) identified by name and/or tag.
+	Long: `Get all groups or group(s) identified by name and/or tag. With
+--annotations, only the label and description of each group are
+printed, along with who created or last changed it with ochami, when,
+and with what --comment.
 
 See ochami-smd(1) for more details.`,
 	Example: `  ochami smd group get
@@ -28,7 +34,8 @@ See ochami-smd(1) for more details.`,
   ochami smd group get --name group1,group2
   ochami smd group get --name group1 --name group2
   ochami smd group get --name group1,group2 --tag tag1,tag2
-  ochami smd group get --name group1 --name group2 --tag tag1 --tag tag2`,
+  ochami smd group get --name group1 --name group2 --tag tag1 --tag tag2
+  ochami smd group get --annotations -F yaml`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Create client to use for requests
 		smdClient := smdGetClient(cmd)
@@ -75,6 +82,34 @@ See ochami-smd(1) for more details.`,
 			os.Exit(1)
 		}
 
+		// Print only annotations if requested
+		if cmd.Flag("annotations").Changed {
+			var groups []smd.Group
+			if err := json.Unmarshal(httpEnv.Body, &groups); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to unmarshal groups")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			type annotatedGroup struct {
+				Label       string          `json:"label" yaml:"label"`
+				Description string          `json:"description,omitempty" yaml:"description,omitempty"`
+				Annotation  *smd.Annotation `json:"annotation,omitempty" yaml:"annotation,omitempty"`
+			}
+			list := make([]annotatedGroup, len(groups))
+			for i, g := range groups {
+				list[i].Label = g.Label
+				list[i].Description, list[i].Annotation = smd.ParseAnnotation(g.Description)
+			}
+			if outBytes, err := format.MarshalData(list, formatOutput); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			} else {
+				fmt.Println(string(outBytes))
+			}
+			return
+		}
+
 		// Print output
 		if outBytes, err := client.FormatBody(httpEnv.Body, formatOutput); err != nil {
 			log.Logger.Error().Err(err).Msg("failed to format output")
@@ -89,7 +124,8 @@ See ochami-smd(1) for more details.`,
 func init() {
 	groupGetCmd.Flags().StringSlice("name", []string{}, "filter groups by name")
 	groupGetCmd.Flags().StringSlice("tag", []string{}, "filter groups by tag")
-	groupGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	groupGetCmd.Flags().Bool("annotations", false, "only print the label, description, and annotation of who created or last changed each group")
+	groupGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	groupGetCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
@@ -4,12 +4,14 @@ package cmd
 
 import (
 	"errors"
+	"net/http"
 	"os"
 
 	"github.com/spf13/cobra"
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
 )
 
 // groupMemberAddCmd represents the "smd group member add" command
@@ -19,8 +21,19 @@ var groupMemberAddCmd = &cobra.Command{
 	Short: "Add one or more components to a group",
 	Long: `Add one or more components to a group.
 
+Pass --verify to read the members before and after adding them, only
+adding those not in the group already, to verify that none of them
+were removed concurrently by another client, e.g. one setting the
+members of the group, retrying up to --retries times if any were.
+Pass --expected-version to also fail if the members changed since
+they were read with 'ochami smd group member get --print-version'.
+
 See ochami-smd(1) for more details.`,
-	Example: `  ochami smd group member add compute x3000c1s7b56n0`,
+	Example: `  ochami smd group member add compute x3000c1s7b56n0
+
+  # Only add the member if the group did not change since it was read
+  v=$(ochami smd group member get compute --print-version)
+  ochami smd group member add --expected-version "$v" compute x3000c1s7b56n0`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Create client to use for requests
 		smdClient := smdGetClient(cmd)
@@ -28,34 +41,59 @@ See ochami-smd(1) for more details.`,
 		// Handle token for this command
 		handleToken(cmd)
 
-		// Send off request
-		_, errs, err := smdClient.PostGroupMembers(token, args[0], args[1:]...)
-		if err != nil {
-			log.Logger.Error().Err(err).Msgf("failed to add group member(s) to group %s in SMD", args[0])
-			logHelpError(cmd)
-			os.Exit(1)
-		}
-		// Since smdClient.PostGroupMembers does the addition iteratively, we need to deal with
-		// each error that might have occurred.
-		var errorsOccurred = false
-		for _, err := range errs {
-			if err != nil {
-				if errors.Is(err, client.UnsuccessfulHTTPError) {
-					log.Logger.Error().Err(err).Msgf("SMD group member request for group %s yielded unsuccessful HTTP response", args[0])
-				} else {
-					log.Logger.Error().Err(err).Msgf("failed to add group member(s) to group %s in SMD", args[0])
+		if groupMemberVerifying(cmd) {
+			groupMemberEdit(cmd, smdClient, args[0], func(before []string) ([]string, []string, bool) {
+				// Only add members that are not already in the group
+				add, _ := smd.DiffMembers(before, args[1:])
+				if len(add) == 0 {
+					return nil, nil, true
 				}
-				errorsOccurred = true
-			}
+				return add, nil, groupMemberAdd(cmd, smdClient, args[0], add)
+			})
+			return
 		}
-		if errorsOccurred {
+
+		if !groupMemberAdd(cmd, smdClient, args[0], args[1:]) {
 			logHelpError(cmd)
-			log.Logger.Warn().Msg("SMD group addition completed with errors")
 			os.Exit(1)
 		}
 	},
 }
 
+// groupMemberAdd adds members to group in SMD, returning false if any of them
+// could not be added. Errors are logged.
+func groupMemberAdd(cmd *cobra.Command, smdClient *smd.SMDClient, group string, members []string) bool {
+	henvs, errs, err := smdClient.PostGroupMembers(token, group, members...)
+	if err != nil {
+		log.Logger.Error().Err(err).Msgf("failed to add group member(s) to group %s in SMD", group)
+		return false
+	}
+	ignoreStatus(cmd, "if-not-exists", http.StatusConflict, "group "+group+" member", henvs, errs, members)
+	// Since smdClient.PostGroupMembers does the addition iteratively, we need to deal with
+	// each error that might have occurred.
+	var errorsOccurred = false
+	for _, err := range errs {
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msgf("SMD group member request for group %s yielded unsuccessful HTTP response", group)
+			} else {
+				log.Logger.Error().Err(err).Msgf("failed to add group member(s) to group %s in SMD", group)
+			}
+			errorsOccurred = true
+		}
+	}
+	reportNotAttempted(errs)
+	if errorsOccurred {
+		log.Logger.Warn().Msg("SMD group addition completed with errors")
+		return false
+	}
+
+	return true
+}
+
 func init() {
+	groupMemberAddVerifyFlags(groupMemberAddCmd)
+	groupMemberAddCmd.Flags().Bool("if-not-exists", false, ifNotExistsFlagUsage)
+
 	groupMemberCmd.AddCommand(groupMemberAddCmd)
 }
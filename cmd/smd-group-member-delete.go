@@ -4,12 +4,14 @@ package cmd
 
 import (
 	"errors"
+	"net/http"
 	"os"
 
 	"github.com/spf13/cobra"
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
 )
 
 // groupMemberDeleteCmd represents the "smd group member delete" command
@@ -19,6 +21,13 @@ var groupMemberDeleteCmd = &cobra.Command{
 	Short: "Delete one or more members from a group",
 	Long: `Delete one or more members froma group.
 
+Pass --verify to read the members before and after deleting them,
+only deleting those in the group, to verify that none of them were
+added back concurrently by another client, e.g. one setting the
+members of the group, retrying up to --retries times if any were.
+Pass --expected-version to also fail if the members changed since
+they were read with 'ochami smd group member get --print-version'.
+
 See ochami-smd(1) for more details.`,
 	Example: `  ochami smd group member delete compute x3000c1s7b56n0`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -28,51 +37,79 @@ See ochami-smd(1) for more details.`,
 		// Handle token for this command
 		handleToken(cmd)
 
-		// Ask before attempting deletion unless --no-confirm was passed
-		if !cmd.Flag("no-confirm").Changed {
-			log.Logger.Debug().Msg("--no-confirm not passed, prompting user to confirm deletion")
-			respDelete, err := ios.loopYesNo("Really delete?")
-			if err != nil {
-				log.Logger.Error().Err(err).Msg("Error fetching user input")
-				os.Exit(1)
-			} else if !respDelete {
-				log.Logger.Info().Msg("User aborted group deletion")
-				os.Exit(0)
-			} else {
-				log.Logger.Debug().Msg("User answered affirmatively to delete groups members")
-			}
+		// Refuse to delete much of the group at once, checked against
+		// its members in SMD
+		guardImpact(cmd, "delete", "group member", len(args[1:]), func() int {
+			return len(smdGetGroupMembers(cmd, args[0]))
+		})
+
+		// Ask before attempting deletion, requiring the number of
+		// members to be confirmed if there are many
+		confirmDeletion(cmd, "Really delete?", "group member", len(args[1:]))
+
+		if groupMemberVerifying(cmd) {
+			groupMemberEdit(cmd, smdClient, args[0], func(before []string) ([]string, []string, bool) {
+				// Only delete members that are in the group
+				var remove []string
+				for _, m := range args[1:] {
+					if smd.HasMember(before, m) {
+						remove = append(remove, m)
+					}
+				}
+				if len(remove) == 0 {
+					return nil, nil, true
+				}
+				return nil, remove, groupMemberDelete(cmd, smdClient, args[0], remove)
+			})
+			return
 		}
 
 		// Perform deletion from arguments
-		_, errs, err := smdClient.DeleteGroupMembers(token, args[0], args[1:]...)
-		if err != nil {
-			log.Logger.Error().Err(err).Msgf("failed to delete members from group %s in SMD", args[0])
+		if !groupMemberDelete(cmd, smdClient, args[0], args[1:]) {
 			logHelpError(cmd)
 			os.Exit(1)
 		}
-		// Since smdClient.DeleteGroupMembers does the deletion iteratively, we need to deal with
-		// each error that might have occurred.
-		var errorsOccurred = false
-		for _, e := range errs {
-			if err != nil {
-				if errors.Is(e, client.UnsuccessfulHTTPError) {
-					log.Logger.Error().Err(e).Msg("SMD group member deletion yielded unsuccessful HTTP response")
-				} else {
-					log.Logger.Error().Err(e).Msg("failed to delete group member(s)")
-				}
-				errorsOccurred = true
+	},
+}
+
+// groupMemberDelete deletes members from group in SMD, returning false if any of
+// them could not be deleted. Errors are logged.
+func groupMemberDelete(cmd *cobra.Command, smdClient *smd.SMDClient, group string, members []string) bool {
+	henvs, errs, err := smdClient.DeleteGroupMembers(token, group, members...)
+	if err != nil {
+		log.Logger.Error().Err(err).Msgf("failed to delete members from group %s in SMD", group)
+		return false
+	}
+	ignoreStatus(cmd, "if-exists", http.StatusNotFound, "group "+group+" member", henvs, errs, members)
+	// Since smdClient.DeleteGroupMembers does the deletion iteratively, we need to deal with
+	// each error that might have occurred.
+	var errorsOccurred = false
+	for _, e := range errs {
+		if e != nil {
+			if errors.Is(e, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(e).Msg("SMD group member deletion yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(e).Msg("failed to delete group member(s)")
 			}
+			errorsOccurred = true
 		}
-		// Warn the user if any errors occurred during deletion iterations
-		if errorsOccurred {
-			logHelpError(cmd)
-			log.Logger.Warn().Msg("SMD group member deletion completed with errors")
-			os.Exit(1)
-		}
-	},
+	}
+	reportNotAttempted(errs)
+	// Warn the user if any errors occurred during deletion iterations
+	if errorsOccurred {
+		log.Logger.Warn().Msg("SMD group member deletion completed with errors")
+		return false
+	}
+
+	return true
 }
 
 func init() {
+	groupMemberAddVerifyFlags(groupMemberDeleteCmd)
+
 	groupMemberDeleteCmd.Flags().Bool("no-confirm", false, "do not ask before attempting deletion")
+	groupMemberDeleteCmd.Flags().Int("yes-really-delete", 0, "confirm deleting more than delete-threshold items by passing their exact number")
+	groupMemberDeleteCmd.Flags().Bool("allow-major-impact", false, allowMajorImpactFlagUsage)
+	groupMemberDeleteCmd.Flags().Bool("if-exists", false, ifExistsFlagUsage)
 	groupMemberCmd.AddCommand(groupMemberDeleteCmd)
 }
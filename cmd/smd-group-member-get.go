@@ -3,6 +3,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -11,6 +12,8 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // groupMemberGetCmd represents the "smd group member get" command
@@ -18,7 +21,10 @@ var groupMemberGetCmd = &cobra.Command{
 	Use:   "get <group_label>",
 	Args:  cobra.ExactArgs(1),
 	Short: "Get members of a group",
-	Long: `Get members of a group.
+	Long: `Get members of a group. Pass --print-version to print the version of
+the membership list instead, which changes whenever the members do,
+to pass to --expected-version of the member add, delete, and set
+commands.
 
 See ochami-smd(1) for more details.`,
 	Example: `  ochami smd group member get compute`,
@@ -41,6 +47,18 @@ See ochami-smd(1) for more details.`,
 			os.Exit(1)
 		}
 
+		// Print version only if requested
+		if cmd.Flag("print-version").Changed {
+			var gm smd.GroupMembers
+			if err := json.Unmarshal(httpEnv.Body, &gm); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to unmarshal group members")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			fmt.Println(smd.MembersVersion(gm.IDs))
+			return
+		}
+
 		// Print output
 		if outBytes, err := client.FormatBody(httpEnv.Body, formatOutput); err != nil {
 			log.Logger.Error().Err(err).Msg("failed to format output")
@@ -53,7 +71,8 @@ See ochami-smd(1) for more details.`,
 }
 
 func init() {
-	groupMemberGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	groupMemberGetCmd.Flags().Bool("print-version", false, "print the version of the membership list instead of the members")
+	groupMemberGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	groupMemberGetCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
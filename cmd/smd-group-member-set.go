@@ -10,6 +10,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
 )
 
 // groupMemberSetCmd represents the "smd group member set" command
@@ -24,8 +25,21 @@ component specified is not already in te group, it is added to the
 group. If a component is in the group but not specified, it is
 removed from the group.
 
+Since the whole membership list is replaced, members added by another
+client between reading the members and setting them are dropped. Pass
+--expected-version with the version printed by 'ochami smd group
+member get --print-version' when the members were read to fail
+instead if they changed since. Pass --verify (implied by
+--expected-version) to read the members again after setting them
+and report members changed concurrently, retrying up to --retries
+times if the members set were changed.
+
 See ochami-smd(1) for more details.`,
-	Example: `  ochami smd group member set compute x1000c1s7b1n0 x1000c1s7b2n0`,
+	Example: `  ochami smd group member set compute x1000c1s7b1n0 x1000c1s7b2n0
+
+  # Only replace the members if they did not change since they were read
+  v=$(ochami smd group member get compute --print-version)
+  ochami smd group member set --expected-version "$v" compute x1000c1s7b1n0`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Create client to use for requests
 		smdClient := smdGetClient(cmd)
@@ -33,20 +47,40 @@ See ochami-smd(1) for more details.`,
 		// Handle token for this command
 		handleToken(cmd)
 
+		if groupMemberVerifying(cmd) {
+			groupMemberEdit(cmd, smdClient, args[0], func(before []string) ([]string, []string, bool) {
+				add, remove := smd.DiffMembers(before, args[1:])
+				return add, remove, groupMemberSet(smdClient, args[0], args[1:])
+			})
+			return
+		}
+
 		// Send off request
-		_, err := smdClient.PutGroupMembers(token, args[0], args[1:]...)
-		if err != nil {
-			if errors.Is(err, client.UnsuccessfulHTTPError) {
-				log.Logger.Error().Err(err).Msgf("SMD group member request for group %s yielded unsuccessful HTTP response", args[0])
-			} else {
-				log.Logger.Error().Err(err).Msgf("failed to set group membership for group %s in SMD", args[0])
-			}
+		if !groupMemberSet(smdClient, args[0], args[1:]) {
 			logHelpError(cmd)
 			os.Exit(1)
 		}
 	},
 }
 
+// groupMemberSet sets the members of group in SMD, returning false if they
+// could not be set. Errors are logged.
+func groupMemberSet(smdClient *smd.SMDClient, group string, members []string) bool {
+	_, err := smdClient.PutGroupMembers(token, group, members...)
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msgf("SMD group member request for group %s yielded unsuccessful HTTP response", group)
+		} else {
+			log.Logger.Error().Err(err).Msgf("failed to set group membership for group %s in SMD", group)
+		}
+		return false
+	}
+
+	return true
+}
+
 func init() {
+	groupMemberAddVerifyFlags(groupMemberSetCmd)
+
 	groupMemberCmd.AddCommand(groupMemberSetCmd)
 }
@@ -3,9 +3,17 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
 )
 
 // groupMemberCmd represents the "smd group member" command
@@ -25,6 +33,101 @@ See ochami-smd(1) for more details.`,
 	},
 }
 
+// groupMemberVerifying returns whether the members of a group are to be edited
+// with groupMemberEdit, i.e. whether --verify or --expected-version was passed.
+func groupMemberVerifying(cmd *cobra.Command) bool {
+	return cmd.Flag("verify").Changed || cmd.Flag("expected-version").Changed
+}
+
+// groupMemberGet returns the members of group in SMD. If they cannot be
+// fetched, an error is logged and the program exits.
+func groupMemberGet(cmd *cobra.Command, smdClient *smd.SMDClient, group string) []string {
+	henv, err := smdClient.GetGroupMembers(group, token)
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("SMD group member request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to request group members from SMD")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	var gm smd.GroupMembers
+	if err := json.Unmarshal(henv.Body, &gm); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to unmarshal group members")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return gm.IDs
+}
+
+// groupMemberEdit edits the members of group as a read-verify-write. It reads
+// the members, failing if they are not at --expected-version (if passed), and
+// calls write with them to make the edit. write returns the members it added
+// and removed, or false if the edit failed, in which case the program exits.
+// The members are then read again to verify the edit. If part of it was lost,
+// e.g. because another client set the members of the group concurrently, the
+// edit is retried with the new members up to --retries times. Other members
+// changed concurrently are reported.
+//
+// SMD supports neither ETags nor conditional writes, so a change made between
+// the read and the write of an attempt can be detected afterwards but not
+// prevented.
+func groupMemberEdit(cmd *cobra.Command, smdClient *smd.SMDClient, group string, write func(before []string) (add, remove []string, ok bool)) {
+	expected, err := cmd.Flags().GetString("expected-version")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --expected-version")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	retries, err := cmd.Flags().GetInt("retries")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --retries")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	// Read the members from SMD itself rather than a cache, since the
+	// write is verified against them
+	smdClient = &smd.SMDClient{OchamiClient: smdClient.Uncached()}
+	before := groupMemberGet(cmd, smdClient, group)
+	if v := smd.MembersVersion(before); expected != "" && v != expected {
+		log.Logger.Error().Msgf("members of group %s are at version %s, not the expected version %s: they were changed since they were read", group, v, expected)
+		exitWithStatus(1)
+	}
+	for attempt := 0; ; attempt++ {
+		add, remove, ok := write(before)
+		if !ok {
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		after := groupMemberGet(cmd, smdClient, group)
+		mc := smd.CompareMembers(before, after, add, remove)
+		if mc.Concurrent() {
+			log.Logger.Warn().Msgf("members of group %s changed concurrently: added [%s], removed [%s]", group, strings.Join(mc.Added, ","), strings.Join(mc.Removed, ","))
+		}
+		if !mc.Conflict() {
+			log.Logger.Info().Msgf("members of group %s are now at version %s", group, smd.MembersVersion(after))
+			return
+		}
+		if attempt >= retries {
+			log.Logger.Error().Msgf("edit of members of group %s was undone concurrently for member(s) %s after %d attempt(s)", group, strings.Join(mc.Lost, ","), attempt+1)
+			exitWithStatus(1)
+		}
+		log.Logger.Warn().Msgf("edit of members of group %s was undone concurrently for member(s) %s, retrying", group, strings.Join(mc.Lost, ","))
+		time.Sleep(time.Duration(attempt+1) * 100 * time.Millisecond)
+		before = after
+	}
+}
+
+// groupMemberAddVerifyFlags adds the flags used by groupMemberEdit to cmd.
+func groupMemberAddVerifyFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("verify", false, "read members before and after the edit to verify it, retrying if it is undone concurrently")
+	cmd.Flags().String("expected-version", "", "fail unless the members are at this version (see 'member get --print-version'); implies --verify")
+	cmd.Flags().Int("retries", 3, "with --verify, number of times to retry an edit undone concurrently")
+}
+
 func init() {
 	groupCmd.AddCommand(groupMemberCmd)
 }
@@ -3,8 +3,10 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"net/url"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -12,11 +14,12 @@ import (
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
 	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // groupUpdateCmd represents the "smd group update" command
 var groupUpdateCmd = &cobra.Command{
-	Use:   "update (-d (<payload_data> | @<payload_file>)) | ([--description <description>] [--tag <tag>]... <group_label>)",
+	Use:   "update (-d (<payload_data> | @<payload_file>)) | (--patch (<patch_data> | @<patch_file>) [--patch-type <type>] <group_label>) | ([--description <description>] [--tag <tag>]... <group_label>)",
 	Args:  cobra.MaximumNArgs(1),
 	Short: "Update the description and/or tags of a group",
 	Long: `Update the description and/or tags of a group. At least one of --description
@@ -27,6 +30,12 @@ the format of the input payload data ('json' by default), but
 the rules above still apply for the payload. If "-" is used as
 the input payload filename, the data is read from standard input.
 
+Alternatively, pass --patch with a group label to apply a JSON
+Patch (RFC 6902, --patch-type json, the default) or JSON Merge
+Patch (RFC 7396, --patch-type merge) document client-side to the
+group's current data fetched from SMD. The patched description
+and tags are then sent.
+
 This command sends a PATCH to SMD. An access token is required.
 
 See ochami-smd(1) for more details.`,
@@ -48,12 +57,21 @@ See ochami-smd(1) for more details.`,
   ochami smd group update -d @payload.json
   ochami smd group update -d @payload.yaml -f yaml
 
+  # Update a group using a JSON Patch or merge patch
+  ochami smd group update --patch '[{"op":"add","path":"/tags/-","value":"new_tag"}]' compute
+  ochami smd group update --patch @patch.json compute
+  ochami smd group update --patch '{"description":"New description"}' --patch-type merge compute
+
   # Update groups using data from standard input
   echo '<json_data>' | ochami smd group update -d @-
   echo '<yaml_data>' | ochami smd group update -d @- -f yaml`,
 	PreRunE: func(cmd *cobra.Command, args []string) error {
 		// cmd.LocalFlags().NFlag() doesn't seem to work, so we check every flag
-		if !cmd.Flag("data").Changed {
+		if cmd.Flag("patch").Changed {
+			if len(args) != 1 {
+				return fmt.Errorf("--patch requires exactly 1 argument (group label), got %d", len(args))
+			}
+		} else if !cmd.Flag("data").Changed {
 			if len(args) == 0 {
 				return fmt.Errorf("expected -d or >= 1 argument (group label), got %d", len(args))
 			} else {
@@ -83,6 +101,42 @@ See ochami-smd(1) for more details.`,
 		var err error
 		if cmd.Flag("data").Changed {
 			handlePayload(cmd, &groups)
+		} else if cmd.Flag("patch").Changed {
+			patchBytes := handlePatchRaw(cmd)
+			// Fetch current group data to apply patch against
+			// from SMD itself rather than a cache, since it is
+			// written back
+			values := url.Values{}
+			values.Add("group", args[0])
+			uncached := &smd.SMDClient{OchamiClient: smdClient.Uncached()}
+			henv, err := uncached.GetGroups(values.Encode(), token)
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msg("SMD group request yielded unsuccessful HTTP response")
+				} else {
+					log.Logger.Error().Err(err).Msg("failed to request group from SMD")
+				}
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			var current []smd.Group
+			if err := json.Unmarshal(henv.Body, &current); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to unmarshal group from SMD")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			if len(current) != 1 {
+				log.Logger.Error().Msgf("expected 1 group with label %s in SMD, found %d", args[0], len(current))
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			var group smd.Group
+			handlePatch(cmd, patchBytes, current[0], &group)
+			if group.Label != args[0] {
+				log.Logger.Warn().Msgf("patch cannot change group label, keeping %s", args[0])
+				group.Label = args[0]
+			}
+			groups = append(groups, group)
 		} else {
 			// ...otherwise use CLI options/args
 			group := smd.Group{Label: args[0]}
@@ -123,6 +177,7 @@ See ochami-smd(1) for more details.`,
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		if errorsOccurred {
 			log.Logger.Warn().Msg("SMD group update completed with errors")
 			logHelpError(cmd)
@@ -135,10 +190,17 @@ func init() {
 	groupUpdateCmd.Flags().StringP("description", "D", "", "short description to update group with")
 	groupUpdateCmd.Flags().StringSlice("tag", []string{}, "one or more tags to set for group")
 	groupUpdateCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
-	groupUpdateCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	groupUpdateCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
+
+	groupUpdateCmd.Flags().String("patch", "", "patch data or (if starting with @) file containing patch data to apply to current group (can be - to read from stdin)")
+	groupUpdateCmd.Flags().Var(&patchType, "patch-type", "type of patch passed to --patch (json,merge)")
 
 	groupUpdateCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
-	groupUpdateCmd.MarkFlagsOneRequired("description", "tag", "data")
+	groupUpdateCmd.RegisterFlagCompletionFunc("patch-type", completionPatchType)
+	groupUpdateCmd.MarkFlagsOneRequired("description", "tag", "data", "patch")
+	groupUpdateCmd.MarkFlagsMutuallyExclusive("data", "patch")
+	groupUpdateCmd.MarkFlagsMutuallyExclusive("description", "patch")
+	groupUpdateCmd.MarkFlagsMutuallyExclusive("tag", "patch")
 
 	groupCmd.AddCommand(groupUpdateCmd)
 }
@@ -0,0 +1,42 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// hwinvExportCmd represents the "smd hwinv export" command
+var hwinvExportCmd = &cobra.Command{
+	Use:   "export [--xname <xname>,...] [--type <type>] [--serial <serial>,...]",
+	Args:  cobra.NoArgs,
+	Short: "Export the FRU info of hardware in SMD as CSV",
+	Long: `Export the hardware inventory in SMD as CSV for asset management,
+with a header row and one row per piece of hardware sorted by xname.
+The columns are xname, type, status, fru_id, manufacturer, model,
+part_number, and serial_number. The CSV is printed to standard output.
+
+See ochami-smd(1) for more details.`,
+	Example: `  ochami smd hwinv export > assets.csv
+  ochami smd hwinv export --type Node > nodes.csv`,
+	Run: func(cmd *cobra.Command, args []string) {
+		recs := hwinvGetRecords(cmd)
+
+		if err := smd.WriteHWInvCSV(os.Stdout, recs); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to write CSV")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	hwinvAddFilterFlags(hwinvExportCmd)
+
+	hwinvCmd.AddCommand(hwinvExportCmd)
+}
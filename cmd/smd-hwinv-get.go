@@ -0,0 +1,54 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// hwinvGetCmd represents the "smd hwinv get" command
+var hwinvGetCmd = &cobra.Command{
+	Use:   "get [--xname <xname>,...] [--type <type>] [--serial <serial>,...] [-F <format>]",
+	Args:  cobra.NoArgs,
+	Short: "Get the FRU info of hardware in SMD",
+	Long: `Get the hardware inventory in SMD, i.e. the xname, type, and status of
+each piece of hardware along with the FRU ID, manufacturer, model, part
+number, and serial number of the FRU populating it.
+
+Pass --serial with the serial number on the label of a board to find
+where it is. The command exits with status 1 if nothing matches.
+
+See ochami-smd(1) for more details.`,
+	Example: `  ochami smd hwinv get --xname x1000c1s7b0n0
+  ochami smd hwinv get --serial SN12345 -F yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		recs := hwinvGetRecords(cmd)
+
+		// Print output
+		if outBytes, err := format.MarshalData(recs, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+		if len(recs) == 0 {
+			exitWithStatus(1)
+		}
+	},
+}
+
+func init() {
+	hwinvAddFilterFlags(hwinvGetCmd)
+	hwinvGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	hwinvGetCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	hwinvCmd.AddCommand(hwinvGetCmd)
+}
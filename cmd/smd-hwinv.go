@@ -0,0 +1,97 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// hwinvCmd represents the "smd hwinv" command
+var hwinvCmd = &cobra.Command{
+	Use:   "hwinv",
+	Args:  cobra.NoArgs,
+	Short: "Manage hardware inventory",
+	Long: `Manage hardware inventory, i.e. the FRU info (serial numbers, part
+numbers, etc.) of the hardware at each location. This is a metacommand.
+Commands under this one interact with the State Management Database
+(SMD).
+
+See ochami-smd(1) for more details.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			printUsageHandleError(cmd)
+			os.Exit(0)
+		}
+	},
+}
+
+// hwinvGetRecords fetches the hardware inventory of the xnames and type passed
+// with --xname and --type from SMD and returns it flattened, only keeping the
+// entries with a serial number passed with --serial (if any). If an error
+// occurs, the program exits.
+func hwinvGetRecords(cmd *cobra.Command) []smd.HWInvRecord {
+	xnames, err := cmd.Flags().GetStringSlice("xname")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --xname")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	serials, err := cmd.Flags().GetStringSlice("serial")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --serial")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	values := url.Values{}
+	for _, x := range xnames {
+		values.Add("id", x)
+	}
+	if cmd.Flag("type").Changed {
+		values.Add("type", cmd.Flag("type").Value.String())
+	}
+
+	// Create client to use for requests
+	smdClient := smdGetClient(cmd)
+
+	// Handle token for this command
+	handleToken(cmd)
+
+	henv, err := smdClient.GetHardware(values.Encode(), token)
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("SMD hardware inventory request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to request hardware inventory from SMD")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	var inv []smd.HWInv
+	if err := json.Unmarshal(henv.Body, &inv); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to unmarshal hardware inventory")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return smd.HWInvRecords(inv, serials...)
+}
+
+// hwinvAddFilterFlags adds the flags read by hwinvGetRecords to c.
+func hwinvAddFilterFlags(c *cobra.Command) {
+	c.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames whose hardware inventory to fetch")
+	c.Flags().String("type", "", "only fetch hardware of this type (e.g. Node, Processor, Memory)")
+	c.Flags().StringSlice("serial", []string{}, "only keep hardware with one of these serial numbers, regardless of case")
+}
+
+func init() {
+	smdCmd.AddCommand(hwinvCmd)
+}
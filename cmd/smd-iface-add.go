@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"os"
 	"strings"
 
@@ -14,6 +15,7 @@ import (
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
 	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // ifaceAddCmd represents the "smd iface add" command
@@ -94,12 +96,17 @@ See ochami-smd(1) for more details.`,
 		}
 
 		// Send off request
-		_, errs, err := smdClient.PostEthernetInterfaces(eis, token)
+		henvs, errs, err := smdClient.PostEthernetInterfaces(eis, token)
 		if err != nil {
 			log.Logger.Error().Err(err).Msg("failed to add ethernet interface in SMD")
 			logHelpError(cmd)
 			os.Exit(1)
 		}
+		macs := make([]string, len(eis))
+		for i, ei := range eis {
+			macs[i] = ei.MACAddress
+		}
+		ignoreStatus(cmd, "if-not-exists", http.StatusConflict, "ethernet interface", henvs, errs, macs)
 		// Since smdClient.PostEthernetInterfaces does the addition iteratively, we need to deal with
 		// each error that might have occurred.
 		var errorsOccurred = false
@@ -113,6 +120,7 @@ See ochami-smd(1) for more details.`,
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		if errorsOccurred {
 			logHelpError(cmd)
 			log.Logger.Warn().Msg("SMD ethernet interface addition completed with errors")
@@ -124,7 +132,8 @@ See ochami-smd(1) for more details.`,
 func init() {
 	ifaceAddCmd.Flags().StringP("description", "D", "Undescribed Ethernet Interface", "description of interface")
 	ifaceAddCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
-	ifaceAddCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	ifaceAddCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
+	ifaceAddCmd.Flags().Bool("if-not-exists", false, ifNotExistsFlagUsage)
 
 	ifaceAddCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
 	ifaceAddCmd.MarkFlagsMutuallyExclusive("description", "data")
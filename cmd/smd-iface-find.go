@@ -0,0 +1,156 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// ifaceFindCmd represents the "smd iface find" command
+var ifaceFindCmd = &cobra.Command{
+	Use:   "find (--mac <mac_addr> | --ip <ip_addr>)",
+	Args:  cobra.NoArgs,
+	Short: "Find the component that owns a MAC or IP address",
+	Long: `Find the component that owns a MAC or IP address by searching SMD's ethernet
+interfaces and Redfish endpoints and BSS's hosts. For each owning component, its
+group memberships, its BMC, and the records that matched are printed. The
+command exits with status 1 if nothing matches.
+
+See ochami-smd(1) for more details.`,
+	Example: `  ochami smd iface find --mac de:ca:fc:0f:fe:e1
+  ochami smd iface find --ip 172.16.0.101 -F yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		mac, err := cmd.Flags().GetString("mac")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --mac")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		ip, err := cmd.Flags().GetString("ip")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --ip")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		// Validate before making any requests
+		if _, err := smd.FindInterface(smd.FindData{}, mac, ip); err != nil {
+			log.Logger.Error().Err(err).Msg("invalid search")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		var data smd.FindData
+		henv, err := smdClient.GetEthernetInterfaces("", token)
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("SMD ethernet interface request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to request ethernet interfaces from SMD")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if err := json.Unmarshal(henv.Body, &data.EthernetInterfaces); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal ethernet interfaces")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		henv, err = smdClient.GetRedfishEndpoints("", token)
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("SMD redfish endpoint request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to request redfish endpoints from SMD")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var rfes smd.RedfishEndpointSlice
+		if err := json.Unmarshal(henv.Body, &rfes); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal redfish endpoints")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		data.RedfishEndpoints = rfes.RedfishEndpoints
+		data.Groups = metaGetGroups(cmd, smdClient)
+
+		// BSS hosts only have MAC addresses and are only extra context, so
+		// failing to get them is not fatal
+		if mac != "" {
+			data.BSSHosts = ifaceFindBSSHosts(cmd)
+		}
+
+		owners, err := smd.FindInterface(data, mac, ip)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to search for interface")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		if locs, ok := showLocations(cmd); ok {
+			for i := range owners {
+				owners[i].Location = locs.Describe(owners[i].Component)
+			}
+		}
+
+		// Print output
+		if outBytes, err := format.MarshalData(owners, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+		if len(owners) == 0 {
+			exitWithStatus(1)
+		}
+	},
+}
+
+// ifaceFindBSSHosts returns the hosts known to BSS, or nil (with a warning) if
+// they cannot be fetched.
+func ifaceFindBSSHosts(cmd *cobra.Command) []bss.Host {
+	bssClient := bssGetClient(cmd)
+	henv, err := bssClient.GetHosts("")
+	if err != nil {
+		log.Logger.Warn().Err(err).Msg("failed to request hosts from BSS, not searching them")
+		return nil
+	}
+	var hosts []bss.Host
+	if err := json.Unmarshal(henv.Body, &hosts); err != nil {
+		log.Logger.Warn().Err(err).Msg("failed to unmarshal BSS hosts, not searching them")
+		return nil
+	}
+
+	return hosts
+}
+
+func init() {
+	ifaceFindCmd.Flags().StringP("mac", "m", "", "MAC address to find the owner of")
+	ifaceFindCmd.Flags().String("ip", "", "IP address to find the owner of")
+	ifaceFindCmd.Flags().Bool("show-location", false, showLocationFlagUsage)
+	ifaceFindCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	ifaceFindCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+	ifaceFindCmd.MarkFlagsOneRequired("mac", "ip")
+	ifaceFindCmd.MarkFlagsMutuallyExclusive("mac", "ip")
+
+	ifaceCmd.AddCommand(ifaceFindCmd)
+}
@@ -12,6 +12,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // ifaceGetCmd represents the "smd iface get" command
@@ -55,7 +56,7 @@ See ochami-smd(1) for more details.`,
 				os.Exit(1)
 			}
 			fmt.Println(string(httpEnv.Body))
-			os.Exit(0)
+			exitWithStatus(0)
 		} else if cmd.Flag("by-ip").Changed {
 			log.Logger.Error().Msg("--by-ip can only be used with --id")
 			logHelpError(cmd)
@@ -141,7 +142,7 @@ See ochami-smd(1) for more details.`,
 				values.Add("NewerThan", s)
 			}
 		}
-		httpEnv, err := smdClient.GetEthernetInterfaces(qstr)
+		httpEnv, err := smdClient.GetEthernetInterfaces(qstr, token)
 		if err != nil {
 			if errors.Is(err, client.UnsuccessfulHTTPError) {
 				log.Logger.Error().Err(err).Msg("SMD ethernet interface request yielded unsuccessful HTTP response")
@@ -173,7 +174,7 @@ func init() {
 	ifaceGetCmd.Flags().StringSlice("type", []string{}, "filter ethernet interfaces by type")
 	ifaceGetCmd.Flags().String("older-than", "", "filter ethernet interfaces by update time older than specified time (RFC3339-formatted)")
 	ifaceGetCmd.Flags().String("newer-than", "", "filter ethernet interfaces by update time older than specified time (RFC3339-formatted)")
-	ifaceGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	ifaceGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	ifaceGetCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 	ifaceGetCmd.MarkFlagsMutuallyExclusive("id", "mac")
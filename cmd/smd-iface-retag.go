@@ -0,0 +1,168 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// ifaceRetagCmd represents the "smd iface retag" command
+var ifaceRetagCmd = &cobra.Command{
+	Use:   "retag [--from <old_network>] [--cidr <cidr>] --to <new_network>",
+	Args:  cobra.NoArgs,
+	Short: "Change the network name of ethernet interface IP addresses",
+	Long: `Change the network name of ethernet interface IP addresses in bulk. The Network
+field of each IP address that matches is set to the value of --to. An IP
+address matches if its network is the value of --from and/or its address is
+within the value of --cidr. At least one of --from or --cidr is required. If
+both are passed, an IP address must match both.
+
+Each ethernet interface with at least one matching IP address is updated in
+SMD, keeping its other IP addresses as they are. The updated ethernet
+interfaces are printed.
+
+See ochami-smd(1) for more details.`,
+	Example: `  # Rename a network
+  ochami smd iface retag --from old-net --to new-net
+
+  # Rename a network, only for IP addresses in a subnet
+  ochami smd iface retag --from old-net --cidr 10.2.0.0/16 --to new-net
+
+  # Set the network of all IP addresses in a subnet
+  ochami smd iface retag --cidr 10.2.0.0/16 --to new-net`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !cmd.Flag("from").Changed && !cmd.Flag("cidr").Changed {
+			log.Logger.Error().Msg("at least one of --from or --cidr is required")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		from, err := cmd.Flags().GetString("from")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --from")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		to, err := cmd.Flags().GetString("to")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --to")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var cidr *net.IPNet
+		if cmd.Flag("cidr").Changed {
+			c, err := cmd.Flags().GetString("cidr")
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to get value for --cidr")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			if _, cidr, err = net.ParseCIDR(c); err != nil {
+				log.Logger.Error().Err(err).Msg("invalid --cidr")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+
+		// This endpoint requires authentication, so a token is needed
+		handleToken(cmd)
+
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Get ethernet interfaces, letting SMD filter by network if possible
+		qstr := ""
+		if from != "" {
+			values := url.Values{}
+			values.Add("Network", from)
+			qstr = values.Encode()
+		}
+		henv, err := smdClient.GetEthernetInterfaces(qstr, token)
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("SMD ethernet interface request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to request ethernet interfaces from SMD")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var eis []smd.EthernetInterface
+		if err := json.Unmarshal(henv.Body, &eis); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal ethernet interfaces")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Determine which ethernet interfaces need updating
+		retagged := smd.RetagEthernetInterfaces(eis, from, cidr, to)
+		if len(retagged) == 0 {
+			log.Logger.Info().Msg("no ethernet interface IP addresses matched, nothing to do")
+			exitWithStatus(0)
+		}
+		log.Logger.Info().Msgf("updating %d ethernet interface(s)", len(retagged))
+
+		// Send off request
+		_, errs, err := smdClient.PatchEthernetInterfaces(retagged, token)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to update ethernet interfaces in SMD")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		// Since smdClient.PatchEthernetInterfaces does the update iteratively,
+		// we need to deal with each error that might have occurred.
+		var errorsOccurred = false
+		var updated []smd.EthernetInterface
+		for i, err := range errs {
+			if err != nil {
+				if errors.Is(err, client.UnsuccessfulHTTPError) {
+					log.Logger.Error().Err(err).Msg("SMD ethernet interface request yielded unsuccessful HTTP response")
+				} else {
+					log.Logger.Error().Err(err).Msg("failed to update ethernet interface in SMD")
+				}
+				errorsOccurred = true
+				continue
+			}
+			updated = append(updated, retagged[i])
+		}
+		reportNotAttempted(errs)
+
+		// Print output
+		if outBytes, err := format.MarshalData(updated, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+
+		if errorsOccurred {
+			log.Logger.Warn().Msg("SMD ethernet interface retag completed with errors")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	ifaceRetagCmd.Flags().String("from", "", "network name of IP addresses to retag")
+	ifaceRetagCmd.Flags().String("cidr", "", "only retag IP addresses within this CIDR (e.g. 10.2.0.0/16)")
+	ifaceRetagCmd.Flags().String("to", "", "network name to set for matching IP addresses")
+	ifaceRetagCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	ifaceRetagCmd.MarkFlagRequired("to")
+	ifaceRetagCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	ifaceCmd.AddCommand(ifaceRetagCmd)
+}
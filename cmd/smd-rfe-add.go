@@ -5,6 +5,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 
 	"github.com/openchami/schemas/schemas/csm"
@@ -13,6 +14,7 @@ import (
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
 	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // rfeAddCmd represents the "smd rfe add" command
@@ -124,13 +126,23 @@ See ochami-smd(1) for more details.`,
 			rfes.RedfishEndpoints = append(rfes.RedfishEndpoints, rfe)
 		}
 
+		// Stamp redfish endpoints with who created them
+		for i := range rfes.RedfishEndpoints {
+			rfes.RedfishEndpoints[i].Name = annotate(cmd, rfes.RedfishEndpoints[i].Name)
+		}
+
 		// Send off request
-		_, errs, err := smdClient.PostRedfishEndpoints(rfes, token)
+		henvs, errs, err := smdClient.PostRedfishEndpoints(rfes, token)
 		if err != nil {
 			log.Logger.Error().Err(err).Msg("failed to add redfish endpoint in SMD")
 			logHelpError(cmd)
 			os.Exit(1)
 		}
+		xnames := make([]string, len(rfes.RedfishEndpoints))
+		for i, rfe := range rfes.RedfishEndpoints {
+			xnames[i] = rfe.ID
+		}
+		ignoreStatus(cmd, "if-not-exists", http.StatusConflict, "redfish endpoint", henvs, errs, xnames)
 		// Since smdClient.PostRedfishEndpoints does the addition iteratively, we need to deal with
 		// each error that might have occurred.
 		var errorsOccurred = false
@@ -144,6 +156,7 @@ See ochami-smd(1) for more details.`,
 				errorsOccurred = true
 			}
 		}
+		reportNotAttempted(errs)
 		if errorsOccurred {
 			logHelpError(cmd)
 			log.Logger.Warn().Msg("SMD redfish endpoint addition completed with errors")
@@ -158,7 +171,8 @@ func init() {
 	rfeAddCmd.Flags().String("username", "", "username to use when interrogating endpoint")
 	rfeAddCmd.Flags().String("password", "", "password to use when interrogating endpoint")
 	rfeAddCmd.Flags().StringP("data", "d", "", "payload data or (if starting with @) file containing payload data (can be - to read from stdin)")
-	rfeAddCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data (json,json-pretty,yaml)")
+	rfeAddCmd.Flags().VarP(&formatInput, "format-input", "f", "format of input payload data ("+format.DataFormatList()+")")
+	rfeAddCmd.Flags().Bool("if-not-exists", false, ifNotExistsFlagUsage)
 
 	rfeAddCmd.RegisterFlagCompletionFunc("format-input", completionFormatData)
 	rfeAddCmd.MarkFlagsMutuallyExclusive("domain", "data")
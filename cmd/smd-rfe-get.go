@@ -3,6 +3,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/url"
@@ -12,6 +13,8 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // rfeGetCmd represents the "smd rfe get" command
@@ -21,7 +24,9 @@ var rfeGetCmd = &cobra.Command{
 	Short: "Get all redfish endpoints or some based on filter(s)",
 	Long: `Get all redfish endpoints or some based on filter(s). If no options are passed,
 all redfish endpoints are returned. Optionally, options can be passed to limit the redfish
-endpoints returned.
+endpoints returned. With --annotations, only the ID and name of each
+redfish endpoint are printed, along with who created or last changed it
+with ochami, when, and with what --comment.
 
 See ochami-smd(1) for more details.`,
 	Run: func(cmd *cobra.Command, args []string) {
@@ -115,6 +120,34 @@ See ochami-smd(1) for more details.`,
 			os.Exit(1)
 		}
 
+		// Print only annotations if requested
+		if cmd.Flag("annotations").Changed {
+			var rfes smd.RedfishEndpointSlice
+			if err := json.Unmarshal(httpEnv.Body, &rfes); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to unmarshal redfish endpoints")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			type annotatedRFE struct {
+				ID         string          `json:"ID" yaml:"ID"`
+				Name       string          `json:"Name,omitempty" yaml:"Name,omitempty"`
+				Annotation *smd.Annotation `json:"annotation,omitempty" yaml:"annotation,omitempty"`
+			}
+			list := make([]annotatedRFE, len(rfes.RedfishEndpoints))
+			for i, rfe := range rfes.RedfishEndpoints {
+				list[i].ID = rfe.ID
+				list[i].Name, list[i].Annotation = smd.ParseAnnotation(rfe.Name)
+			}
+			if outBytes, err := format.MarshalData(list, formatOutput); err != nil {
+				log.Logger.Error().Err(err).Msg("failed to format output")
+				logHelpError(cmd)
+				os.Exit(1)
+			} else {
+				fmt.Println(string(outBytes))
+			}
+			return
+		}
+
 		// Print output
 		if outBytes, err := client.FormatBody(httpEnv.Body, formatOutput); err != nil {
 			log.Logger.Error().Err(err).Msg("failed to format output")
@@ -133,7 +166,8 @@ func init() {
 	rfeGetCmd.Flags().StringSlice("uuid", []string{}, "filter redfish endpoints by UUID")
 	rfeGetCmd.Flags().StringSliceP("mac", "m", []string{}, "filter redfish endpoints by MAC address")
 	rfeGetCmd.Flags().StringSliceP("ip", "i", []string{}, "filter redfish endpoints by IP address")
-	rfeGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	rfeGetCmd.Flags().Bool("annotations", false, "only print the ID, name, and annotation of who created or last changed each redfish endpoint")
+	rfeGetCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	rfeGetCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
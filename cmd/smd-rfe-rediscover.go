@@ -0,0 +1,250 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// rfeRediscoverBatchSize is the number of redfish endpoints whose discovery
+// status is requested at once while watching.
+const rfeRediscoverBatchSize = 100
+
+// rfeRediscoverCmd represents the "smd rfe rediscover" command
+var rfeRediscoverCmd = &cobra.Command{
+	Use:   "rediscover (--xname <xname>... | --last-status <status> | --all) [--force] [--watch [--timeout <duration>] [--poll-interval <duration>] [-F <format>]]",
+	Args:  cobra.NoArgs,
+	Short: "Flag redfish endpoints in SMD for rediscovery",
+	Long: `Flag redfish endpoints in SMD for rediscovery, so that SMD queries
+their BMCs again and updates the inventory it discovered from them.
+The endpoints are those passed with --xname, those whose last
+discovery status is --last-status (which can be negated, e.g.
+'!DiscoverOK'), or all of them with --all. Endpoints that are already
+being discovered are skipped by SMD unless --force is passed.
+
+With --watch, the discovery status of the endpoints is then polled
+every --poll-interval until all of them have finished being
+rediscovered, or --timeout elapses. The outcome for each endpoint is
+printed, and this command exits with a nonzero status if the watch
+timed out or any endpoint was not discovered successfully
+(DiscoverOK).
+
+See ochami-smd(1) for more details.`,
+	Example: `  # Rediscover two BMCs and wait for the outcome
+  ochami smd rfe rediscover -x x1000c0s0b0,x1000c0s1b0 --watch
+
+  # Retry the discovery of all endpoints that failed
+  ochami smd rfe rediscover --last-status '!DiscoverOK'`,
+	Run: func(cmd *cobra.Command, args []string) {
+		xnames, err := cmd.Flags().GetStringSlice("xname")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --xname")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		lastStatus, err := cmd.Flags().GetString("last-status")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --last-status")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		timeout, err := cmd.Flags().GetDuration("timeout")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --timeout")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		interval, err := cmd.Flags().GetDuration("poll-interval")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --poll-interval")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		if interval <= 0 {
+			log.Logger.Error().Msg("--poll-interval must be positive")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		// Look up the endpoints to rediscover, both to check that they
+		// exist and to know their discovery status beforehand
+		var rfes []smd.RedfishEndpointDiscovery
+		if cmd.Flag("xname").Changed {
+			rfes = rfeRediscoverGet(cmd, smdClient, xnames)
+			found := make(map[string]bool)
+			for _, rfe := range rfes {
+				found[strings.ToLower(rfe.ID)] = true
+			}
+			var missing []string
+			for _, x := range xnames {
+				if !found[strings.ToLower(x)] {
+					missing = append(missing, x)
+				}
+			}
+			if len(missing) > 0 {
+				log.Logger.Error().Msgf("redfish endpoint(s) not found in SMD: %s", strings.Join(missing, ", "))
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		} else {
+			values := url.Values{}
+			if lastStatus != "" {
+				values.Set("laststatus", lastStatus)
+			}
+			rfes = rfeRediscoverQuery(cmd, smdClient, values.Encode())
+		}
+		if len(rfes) == 0 {
+			log.Logger.Warn().Msg("no redfish endpoints to rediscover")
+			return
+		}
+
+		// Flag the endpoints for rediscovery. With --all, no xnames are
+		// passed so that SMD rediscovers all endpoints, including any
+		// added since they were looked up.
+		in := smd.DiscoverInput{Force: cmd.Flag("force").Changed}
+		if !cmd.Flag("all").Changed {
+			for _, rfe := range rfes {
+				in.XNames = append(in.XNames, rfe.ID)
+			}
+		}
+		start := time.Now()
+		if _, err := smdClient.PostDiscover(in, token); err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("SMD discover request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to request rediscovery from SMD")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		log.Logger.Info().Msgf("flagged %d redfish endpoint(s) for rediscovery", len(rfes))
+		if !cmd.Flag("watch").Changed {
+			return
+		}
+
+		// Follow the discovery status of the endpoints until they are
+		// done or the timeout elapses, reading it from SMD itself rather
+		// than a cache
+		smdClient = &smd.SMDClient{OchamiClient: smdClient.Uncached()}
+		watcher := smd.NewRediscoverWatcher(rfes, start)
+		pending := watcher.Pending()
+		var deadline time.Time
+		if timeout > 0 {
+			deadline = start.Add(timeout)
+		}
+		for {
+			sleep := interval
+			if !deadline.IsZero() {
+				sleep = min(interval, time.Until(deadline))
+			}
+			time.Sleep(sleep)
+			for _, x := range watcher.Update(rfeRediscoverGet(cmd, smdClient, pending), time.Now()) {
+				log.Logger.Info().Msgf("%s rediscovered after %s", x, time.Since(start).Round(time.Second))
+			}
+			pending = watcher.Pending()
+			if watcher.Done() || (!deadline.IsZero() && !time.Now().Before(deadline)) {
+				break
+			}
+			log.Logger.Info().Msgf("%d redfish endpoint(s) pending", len(pending))
+		}
+
+		results := watcher.Results()
+		if outBytes, err := format.MarshalData(results, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+
+		if !watcher.Done() {
+			log.Logger.Error().Msgf("timed out after %s with %d redfish endpoint(s) pending", timeout, len(pending))
+			exitWithStatus(1)
+		}
+		var failed int
+		for _, res := range results {
+			if !res.OK {
+				failed++
+			}
+		}
+		if failed > 0 {
+			log.Logger.Warn().Msgf("rediscovery failed for %d of %d redfish endpoint(s)", failed, len(results))
+			exitWithStatus(1)
+		}
+	},
+}
+
+// rfeRediscoverGet returns the discovery status of the redfish endpoints with
+// xnames in SMD, requesting them in batches.
+func rfeRediscoverGet(cmd *cobra.Command, smdClient *smd.SMDClient, xnames []string) []smd.RedfishEndpointDiscovery {
+	var rfes []smd.RedfishEndpointDiscovery
+	for i := 0; i < len(xnames); i += rfeRediscoverBatchSize {
+		values := url.Values{}
+		for _, x := range xnames[i:min(i+rfeRediscoverBatchSize, len(xnames))] {
+			values.Add("id", x)
+		}
+		rfes = append(rfes, rfeRediscoverQuery(cmd, smdClient, values.Encode())...)
+	}
+
+	return rfes
+}
+
+// rfeRediscoverQuery returns the discovery status of the redfish endpoints in
+// SMD matching query. If the request fails, the program exits.
+func rfeRediscoverQuery(cmd *cobra.Command, smdClient *smd.SMDClient, query string) []smd.RedfishEndpointDiscovery {
+	henv, err := smdClient.GetRedfishEndpoints(query, token)
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("SMD redfish endpoint request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to request redfish endpoints from SMD")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	var rfes smd.RedfishEndpointDiscoverySlice
+	if err := json.Unmarshal(henv.Body, &rfes); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to unmarshal redfish endpoints")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return rfes.RedfishEndpoints
+}
+
+func init() {
+	rfeRediscoverCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames of redfish endpoints to rediscover")
+	rfeRediscoverCmd.Flags().String("last-status", "", "rediscover redfish endpoints whose last discovery status is this (can be negated with !, e.g. !DiscoverOK)")
+	rfeRediscoverCmd.Flags().Bool("all", false, "rediscover all redfish endpoints")
+	rfeRediscoverCmd.Flags().Bool("force", false, "rediscover endpoints even if they are already being discovered")
+	rfeRediscoverCmd.Flags().Bool("watch", false, "follow the discovery status of the endpoints until they are rediscovered")
+	rfeRediscoverCmd.Flags().Duration("timeout", 10*time.Minute, "how long to watch before giving up (0 for no limit)")
+	rfeRediscoverCmd.Flags().Duration("poll-interval", 5*time.Second, "interval at which to poll the discovery status in SMD")
+	rfeRediscoverCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	rfeRediscoverCmd.MarkFlagsOneRequired("xname", "last-status", "all")
+	rfeRediscoverCmd.MarkFlagsMutuallyExclusive("xname", "last-status", "all")
+
+	rfeRediscoverCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	rfeCmd.AddCommand(rfeRediscoverCmd)
+}
@@ -0,0 +1,313 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/redfish"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// Possible values of the bmc and smd fields of rfeRotateCredsResult.
+const (
+	rotateCredsAccepted = "accepted"
+	rotateCredsRejected = "rejected"
+	rotateCredsUpdated  = "updated"
+	rotateCredsFailed   = "failed"
+	rotateCredsSkipped  = "skipped"
+)
+
+// rfeRotateCredsResult reports the outcome of rotating the credentials of one
+// BMC. Password is only set if it was generated, since it would otherwise be
+// lost.
+type rfeRotateCredsResult struct {
+	Xname    string `json:"xname" yaml:"xname"`
+	User     string `json:"user,omitempty" yaml:"user,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	BMC      string `json:"bmc,omitempty" yaml:"bmc,omitempty"`
+	SMD      string `json:"smd" yaml:"smd"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// rfeRotateCredsCmd represents the "smd rfe rotate-creds" command
+var rfeRotateCredsCmd = &cobra.Command{
+	Use:   "rotate-creds --xname <xname>... (--password-stdin | --generate [--length <n>]) [--username <user>] [--push --current-password-file <path>]",
+	Args:  cobra.NoArgs,
+	Short: "Rotate the BMC credentials stored in SMD for redfish endpoints",
+	Long: `Rotate the BMC credentials stored in SMD for one or more redfish
+endpoints. The new password is either read from the first line of
+standard input (--password-stdin), in which case it is used for all
+endpoints, or generated randomly for each endpoint (--generate), in
+which case the generated passwords are included in the report.
+
+The user name stored in SMD for each endpoint is kept unless
+--username is passed.
+
+If --push is passed, the password is first changed on each BMC via
+Redfish, authenticating as the user with the current password read
+from --current-password-file. SMD is then only updated for BMCs that
+accepted the change so that SMD never stores credentials the BMC
+rejects.
+
+A report of the outcome for each BMC is printed. The command exits
+with an error if any BMC rejected the change or SMD failed to update.
+
+See ochami-smd(1) for more details.`,
+	Example: `  # Set a new password read from a file for two BMCs in SMD only
+  ochami smd rfe rotate-creds -x x1000c0s0b0 -x x1000c0s1b0 --password-stdin < newpass
+
+  # Generate random passwords, push them to the BMCs, then update SMD
+  ochami smd rfe rotate-creds -x x1000c0s0b0,x1000c0s1b0 --generate --push --current-password-file oldpass -F yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Create client to use for requests
+		smdClient := smdGetClient(cmd)
+
+		// Handle token for this command
+		handleToken(cmd)
+
+		xnames, err := cmd.Flags().GetStringSlice("xname")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --xname")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		username, err := cmd.Flags().GetString("username")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --username")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		length, err := cmd.Flags().GetInt("length")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --length")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+
+		// Read the new password, if it is shared
+		var password string
+		if cmd.Flag("password-stdin").Changed {
+			password, err = bufio.NewReader(os.Stdin).ReadString('\n')
+			password = strings.TrimRight(password, "\r\n")
+			if password == "" {
+				log.Logger.Error().Err(err).Msg("failed to read new password from standard input")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+		}
+
+		// Read the current password, if pushing to BMCs
+		var currentPassword string
+		push := cmd.Flag("push").Changed
+		if push {
+			path, err := cmd.Flags().GetString("current-password-file")
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to get value for --current-password-file")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("failed to read current password")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			currentPassword = strings.TrimRight(string(b), "\r\n")
+		}
+
+		// Get the current redfish endpoints for their user names and
+		// addresses
+		values := url.Values{}
+		for _, x := range xnames {
+			values.Add("id", x)
+		}
+		henv, err := smdClient.GetRedfishEndpoints(values.Encode(), token)
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msg("SMD redfish endpoint request yielded unsuccessful HTTP response")
+			} else {
+				log.Logger.Error().Err(err).Msg("failed to request redfish endpoints from SMD")
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var rfes smd.RedfishEndpointSlice
+		if err := json.Unmarshal(henv.Body, &rfes); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to unmarshal redfish endpoints")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		rfeMap := make(map[string]int)
+		for i, rfe := range rfes.RedfishEndpoints {
+			rfeMap[rfe.ID] = i
+		}
+
+		// Determine new credentials and push them to BMCs, if requested
+		var results []rfeRotateCredsResult
+		var creds []smd.RedfishEndpointCreds
+		var credsIdx []int
+		for _, x := range xnames {
+			res := rfeRotateCredsResult{Xname: x, SMD: rotateCredsSkipped}
+			if push {
+				res.BMC = rotateCredsSkipped
+			}
+			i, ok := rfeMap[x]
+			if !ok {
+				res.Error = "redfish endpoint not found in SMD"
+				results = append(results, res)
+				continue
+			}
+			rfe := rfes.RedfishEndpoints[i]
+			res.User = rfe.User
+			if username != "" {
+				res.User = username
+			}
+			if res.User == "" {
+				res.Error = "no user name stored in SMD, pass --username"
+				results = append(results, res)
+				continue
+			}
+			newPassword := password
+			if cmd.Flag("generate").Changed {
+				newPassword, err = redfish.GeneratePassword(length)
+				if err != nil {
+					res.Error = err.Error()
+					results = append(results, res)
+					continue
+				}
+				res.Password = newPassword
+			}
+			if push {
+				if err := rfeRotateCredsPush(cmd, rfe.FQDN, rfe.IPAddress, rfe.Hostname, res.User, currentPassword, newPassword); err != nil {
+					log.Logger.Error().Err(err).Msgf("BMC %s rejected the new password", x)
+					res.BMC = rotateCredsRejected
+					res.Error = err.Error()
+					results = append(results, res)
+					continue
+				}
+				res.BMC = rotateCredsAccepted
+			}
+			credsIdx = append(credsIdx, len(results))
+			creds = append(creds, smd.RedfishEndpointCreds{ID: x, User: res.User, Password: newPassword})
+			results = append(results, res)
+		}
+
+		// Update the credentials stored in SMD
+		if len(creds) > 0 {
+			_, errs, err := smdClient.PatchRedfishEndpointCreds(creds, token)
+			if err != nil {
+				// Still print the report below, since it holds
+				// any passwords already set on BMCs
+				log.Logger.Error().Err(err).Msg("failed to update redfish endpoint credentials in SMD")
+				for _, i := range credsIdx {
+					results[i].SMD = rotateCredsFailed
+					results[i].Error = err.Error()
+				}
+			}
+			for i, e := range errs {
+				res := &results[credsIdx[i]]
+				if e != nil {
+					if errors.Is(e, client.UnsuccessfulHTTPError) {
+						log.Logger.Error().Err(e).Msg("SMD redfish endpoint request yielded unsuccessful HTTP response")
+					} else {
+						log.Logger.Error().Err(e).Msg("failed to update redfish endpoint credentials in SMD")
+					}
+					res.SMD = rotateCredsFailed
+					res.Error = e.Error()
+					continue
+				}
+				res.SMD = rotateCredsUpdated
+			}
+			reportNotAttempted(errs)
+		}
+
+		// Print output
+		if outBytes, err := format.MarshalData(results, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+
+		var failed int
+		for _, res := range results {
+			if res.Error != "" {
+				failed++
+			}
+		}
+		if failed > 0 {
+			log.Logger.Warn().Msgf("credential rotation failed for %d of %d BMC(s)", failed, len(results))
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	},
+}
+
+// rfeRotateCredsPush changes the password of user on the BMC reachable at the
+// first non-empty address of fqdn, ip, and hostname from currentPassword to
+// newPassword via Redfish.
+func rfeRotateCredsPush(cmd *cobra.Command, fqdn, ip, hostname, user, currentPassword, newPassword string) error {
+	addr := fqdn
+	if addr == "" {
+		addr = ip
+	}
+	if addr == "" {
+		addr = hostname
+	}
+	if addr == "" {
+		return fmt.Errorf("redfish endpoint has no FQDN, IP address, or hostname in SMD")
+	}
+	// IPv6 addresses must be bracketed in URLs
+	if a, err := netip.ParseAddr(addr); err == nil && a.Is6() {
+		addr = "[" + addr + "]"
+	}
+	rfClient, err := redfish.NewClient("https://"+addr, insecure)
+	if err != nil {
+		return err
+	}
+
+	// Check if a CA certificate was passed and load it into client if valid
+	useCACert(rfClient.OchamiClient)
+
+	// Refuse to send mutating requests in read-only mode
+	rfClient.ReadOnly = readOnlyEnabled(cmd)
+
+	// Stop sending requests once the --context-timeout deadline passes
+	rfClient.Deadline = commandDeadline
+
+	return rfClient.SetAccountPassword(user, currentPassword, newPassword)
+}
+
+func init() {
+	rfeRotateCredsCmd.Flags().StringSliceP("xname", "x", []string{}, "one or more xnames of redfish endpoints whose credentials to rotate")
+	rfeRotateCredsCmd.Flags().String("username", "", "user name to store (default: keep the user name stored in SMD)")
+	rfeRotateCredsCmd.Flags().Bool("password-stdin", false, "read the new password for all endpoints from the first line of standard input")
+	rfeRotateCredsCmd.Flags().Bool("generate", false, "generate a random password for each endpoint")
+	rfeRotateCredsCmd.Flags().Int("length", 20, "length of generated passwords")
+	rfeRotateCredsCmd.Flags().Bool("push", false, "change the password on each BMC via Redfish before updating SMD")
+	rfeRotateCredsCmd.Flags().String("current-password-file", "", "file containing the current BMC password, used to authenticate with --push")
+	rfeRotateCredsCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	rfeRotateCredsCmd.MarkFlagRequired("xname")
+	rfeRotateCredsCmd.MarkFlagsOneRequired("password-stdin", "generate")
+	rfeRotateCredsCmd.MarkFlagsMutuallyExclusive("password-stdin", "generate")
+	rfeRotateCredsCmd.MarkFlagsRequiredTogether("push", "current-password-file")
+
+	rfeRotateCredsCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	rfeCmd.AddCommand(rfeRotateCredsCmd)
+}
@@ -11,6 +11,7 @@ import (
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
 // smdServiceStatusCmd represents the "smd service status" command
@@ -56,9 +57,12 @@ See ochami-smd(1) for more details.`,
 
 func init() {
 	smdServiceStatusCmd.Flags().Bool("all", false, "print all status data from SMD")
-	smdServiceStatusCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output (json,json-pretty,yaml)")
+	smdServiceStatusCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
 
 	smdServiceStatusCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
 
 	smdServiceCmd.AddCommand(smdServiceStatusCmd)
+
+	// Moved from "smd status"
+	registerCommandAlias("smd status", smdServiceStatusCmd, "v1.0.0")
 }
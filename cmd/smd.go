@@ -3,11 +3,15 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
 
 	"github.com/spf13/cobra"
 
+	"github.com/OpenCHAMI/ochami/internal/config"
 	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
 	"github.com/OpenCHAMI/ochami/pkg/client/smd"
 )
 
@@ -33,9 +37,74 @@ func smdGetClient(cmd *cobra.Command) *smd.SMDClient {
 	// Check if a CA certificate was passed and load it into client if valid
 	useCACert(smdClient.OchamiClient)
 
+	// Send GET requests through the agent of the cluster, if running
+	useAgent(cmd, smdClient.OchamiClient)
+
+	// Apply any endpoint path overrides configured for the cluster
+	smdClient.PathOverrides = getServicePaths(cmd, config.ServiceSMD)
+
+	// Refuse to send mutating requests in read-only mode
+	smdClient.ReadOnly = readOnlyEnabled(cmd)
+
+	// Validate payloads against the OpenAPI spec of the service if
+	// --validate-against-server was passed
+	smdClient.ValidatePayload = payloadValidator(cmd, smdClient.OchamiClient)
+
+	// Stop sending requests once the --context-timeout deadline passes
+	smdClient.Deadline = commandDeadline
+
+	// Make requests on behalf of the user passed with --as, if any, and
+	// record those that modify data in the audit log
+	smdClient.OnBehalfOf, smdClient.ImpersonationHeader = onBehalfOf(cmd)
+	smdClient.AuditLog = getAuditLog(cmd)
+
+	// Spill large response bodies to a temporary file
+	smdClient.MaxMemoryBuffer = maxMemoryBuffer(cmd)
+
 	return smdClient
 }
 
+// smdGetGroupMembers returns the deduplicated list of member IDs of the passed
+// SMD groups, in the order they are first encountered. The SMD client and token
+// are set up as needed. If an error occurs, the program exits.
+func smdGetGroupMembers(cmd *cobra.Command, groups ...string) []string {
+	smdClient := smdGetClient(cmd)
+	handleToken(cmd)
+
+	var (
+		members []string
+		seen    = make(map[string]bool)
+	)
+	for _, g := range groups {
+		henv, err := smdClient.GetGroupMembers(g, token)
+		if err != nil {
+			if errors.Is(err, client.UnsuccessfulHTTPError) {
+				log.Logger.Error().Err(err).Msgf("SMD group member request for group %s yielded unsuccessful HTTP response", g)
+			} else {
+				log.Logger.Error().Err(err).Msgf("failed to get members of SMD group %s", g)
+			}
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		var gm struct {
+			IDs []string `json:"ids"`
+		}
+		if err := json.Unmarshal(henv.Body, &gm); err != nil {
+			log.Logger.Error().Err(err).Msgf("failed to unmarshal members of SMD group %s", g)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		for _, id := range gm.IDs {
+			if !seen[id] {
+				seen[id] = true
+				members = append(members, id)
+			}
+		}
+	}
+
+	return members
+}
+
 // smdCmd represents the bss command
 var smdCmd = &cobra.Command{
 	Use:   "smd",
@@ -56,3 +125,62 @@ func init() {
 	smdCmd.PersistentFlags().String("uri", "", "absolute base URI or relative base path of SMD")
 	rootCmd.AddCommand(smdCmd)
 }
+
+// smdCountAll returns the number of items of kind (e.g. "component") in SMD,
+// counting the items under key in the response of get (or the items of the
+// response if key is empty). It is used to confirm deleting all items of a
+// kind. If an error occurs, the program exits.
+func smdCountAll(cmd *cobra.Command, kind string, get func() (client.HTTPEnvelope, error), key string) int {
+	henv, err := get()
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msgf("SMD %s request yielded unsuccessful HTTP response", kind)
+		} else {
+			log.Logger.Error().Err(err).Msgf("failed to request %ss from SMD", kind)
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	n, err := countItems(henv.Body, key)
+	if err != nil {
+		log.Logger.Error().Err(err).Msgf("failed to count %ss in SMD", kind)
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return n
+}
+
+// smdCountNodes returns the number of node Components (of type Node or
+// VirtualNode) in SMD, against which the impact of power operations is
+// checked. The SMD client and token are set up as needed. If an error occurs,
+// the program exits.
+func smdCountNodes(cmd *cobra.Command) int {
+	smdClient := smdGetClient(cmd)
+	handleToken(cmd)
+
+	henv, err := smdClient.GetComponentsAll()
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("SMD component request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to request components from SMD")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	var comps smd.ComponentSlice
+	if err := json.Unmarshal(henv.Body, &comps); err != nil {
+		log.Logger.Error().Err(err).Msg("failed to unmarshal components from SMD")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	var n int
+	for _, c := range comps.Components {
+		if c.Type == "Node" || c.Type == "VirtualNode" {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,200 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/ops"
+)
+
+// targetFlagUsage is the usage of --target, shared by the commands that accept
+// it.
+const targetFlagUsage = "one or more targets of the form [xname:|nid:|mac:|group:]<value> (e.g. x3000c0s0b0n0,nid:42,group:compute)"
+
+// targetAddFromFlags adds --targets-from and --only to a command that accepts
+// --target, so that it can be re-run on the components of the report of an
+// earlier run, e.g. those that failed.
+func targetAddFromFlags(cmd *cobra.Command) {
+	cmd.Flags().String("targets-from", "", "path to a report printed by an earlier bulk run (e.g. of node reimage) whose components to target")
+	cmd.Flags().StringSlice("only", []string{}, "with --targets-from, target only components with one of these statuses, or that "+ops.ReportOnlyFailed+" or "+ops.ReportOnlySucceeded)
+
+	cmd.RegisterFlagCompletionFunc("only", completionReportOnly)
+}
+
+// targetParse returns the targets passed with --target and those of the report
+// passed with --targets-from, or nil if neither was passed. The program exits
+// if a target is invalid or the report cannot be read. If the report has no
+// components matching --only and no other targets were passed, there is
+// nothing to do and the program exits successfully.
+func targetParse(cmd *cobra.Command) []smd.Target {
+	var targets []smd.Target
+	if cmd.Flag("target").Changed {
+		ss, err := cmd.Flags().GetStringSlice("target")
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to get value for --target")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		targets, err = smd.ParseTargets(ss)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("invalid --target")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+	}
+
+	return append(targets, targetParseReport(cmd)...)
+}
+
+// targetParseReport returns the xname targets of the components of the report
+// passed with --targets-from that match --only, or nil if the command does not
+// accept --targets-from or it was not passed.
+func targetParseReport(cmd *cobra.Command) []smd.Target {
+	if f := cmd.Flag("targets-from"); f == nil || !f.Changed {
+		if f != nil && cmd.Flag("only").Changed {
+			log.Logger.Error().Msg("--only requires --targets-from")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		return nil
+	}
+	path, err := cmd.Flags().GetString("targets-from")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --targets-from")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	only, err := cmd.Flags().GetStringSlice("only")
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get value for --only")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to read --targets-from report")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	items, err := ops.ParseReportItems(data)
+	if err != nil {
+		log.Logger.Error().Err(err).Msgf("invalid --targets-from report %s", path)
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	xnames := ops.SelectReportXnames(items, only)
+	if len(xnames) == 0 {
+		msg := fmt.Sprintf("none of the %d component(s) of report %s", len(items), path)
+		if len(only) > 0 {
+			msg += " match --only " + strings.Join(only, ",")
+		}
+		var others bool
+		for _, name := range []string{"xname", "nid", "mac", "group", "target", "selector"} {
+			if f := cmd.Flag(name); f != nil && f.Changed {
+				others = true
+			}
+		}
+		if !others {
+			log.Logger.Info().Msgf("%s, nothing to do", msg)
+			exitWithStatus(0)
+		}
+		log.Logger.Warn().Msg(msg)
+	}
+	log.Logger.Debug().Msgf("--targets-from %s selected: %v", path, xnames)
+
+	targets := make([]smd.Target, 0, len(xnames))
+	for _, x := range xnames {
+		targets = append(targets, smd.Target{Kind: smd.TargetXname, Value: x})
+	}
+
+	return targets
+}
+
+// targetResolve resolves targets to xnames, fetching from SMD only the data
+// needed for the kinds of targets passed. The program exits if the data cannot
+// be fetched or a target cannot be resolved.
+func targetResolve(cmd *cobra.Command, targets []smd.Target) []string {
+	var smdClient *smd.SMDClient
+	if ops.TargetsNeedSMD(targets) {
+		smdClient = smdGetClient(cmd)
+		handleToken(cmd)
+	}
+	xnames, err := ops.ResolveTargets(context.Background(), smdClient, token, targets)
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("SMD request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to resolve --target")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	log.Logger.Debug().Msgf("--target resolved to: %v", xnames)
+
+	return xnames
+}
+
+// targetXnames returns the xnames of the components passed with --target,
+// resolving NIDs, MAC addresses, and groups through SMD, or nil if --target
+// was not passed.
+func targetXnames(cmd *cobra.Command) []string {
+	targets := targetParse(cmd)
+	if len(targets) == 0 {
+		return nil
+	}
+
+	return targetResolve(cmd, targets)
+}
+
+// targetBootParams adds the components passed with --target to the hosts,
+// MAC addresses, and NIDs of bp. BSS identifies components by any of these
+// itself, so only groups are resolved (to xnames) through SMD.
+func targetBootParams(cmd *cobra.Command, bp *bssTypes.BootParams) {
+	var groups []smd.Target
+	for _, t := range targetParse(cmd) {
+		switch t.Kind {
+		case smd.TargetXname:
+			bp.Hosts = append(bp.Hosts, t.Value)
+		case smd.TargetMAC:
+			bp.Macs = append(bp.Macs, t.Value)
+		case smd.TargetNID:
+			nid, _ := strconv.ParseInt(t.Value, 10, 32)
+			bp.Nids = append(bp.Nids, int32(nid))
+		case smd.TargetGroup:
+			groups = append(groups, t)
+		}
+	}
+	if len(groups) > 0 {
+		bp.Hosts = append(bp.Hosts, targetResolve(cmd, groups)...)
+	}
+}
+
+// targetBootQuery adds the components passed with --target to values as the
+// name, mac, and nid query parameters of BSS, as targetBootParams does.
+func targetBootQuery(cmd *cobra.Command, values url.Values) {
+	var bp bssTypes.BootParams
+	targetBootParams(cmd, &bp)
+	for _, x := range bp.Hosts {
+		values.Add("name", x)
+	}
+	for _, m := range bp.Macs {
+		values.Add("mac", m)
+	}
+	for _, n := range bp.Nids {
+		values.Add("nid", strconv.Itoa(int(n)))
+	}
+}
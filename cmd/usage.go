@@ -0,0 +1,83 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+// usage.go records opt-in usage statistics of commands when usage-stats is
+// configured.
+
+import (
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/config"
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/internal/usage"
+	"github.com/OpenCHAMI/ochami/internal/version"
+)
+
+var (
+	// Time the program started, used for the duration of the command.
+	usageStart = time.Now()
+
+	// Command being run, set once the config has been read, and whether its
+	// usage has already been recorded.
+	usageCmd      *cobra.Command
+	usageRecorded bool
+)
+
+// usageSetCommand sets cmd as the command whose usage is recorded. Since
+// commands exit in many places, a command is recorded as failed as soon as it
+// logs its first error, and otherwise when it exits via exitWithStatus. Shell
+// completion requests are not recorded.
+func usageSetCommand(cmd *cobra.Command) {
+	if strings.HasPrefix(cmd.Name(), cobra.ShellCompRequestCmd) {
+		return
+	}
+	usageCmd = cmd
+	log.OnError = func() { usageRecord(1, "") }
+}
+
+// usageRecord records the usage of the command being run with exit status
+// status if usage-stats is configured. If status is not 0, failure is the
+// category of the failure or, if empty, it is determined from the errors logged.
+// It only records once, since the first exit path reached determines the status
+// of the command. Failing to record is only logged at debug level so that it
+// never affects the command.
+func usageRecord(status int, failure string) {
+	if usageRecorded || usageCmd == nil {
+		return
+	}
+	usageRecorded = true
+	c := usage.Collector{
+		File:   config.GlobalConfig.UsageStats.File,
+		StatsD: config.GlobalConfig.UsageStats.StatsD,
+		Prefix: config.GlobalConfig.UsageStats.Prefix,
+	}
+	if !c.Enabled() {
+		return
+	}
+
+	e := usage.Entry{
+		Command:    strings.TrimPrefix(strings.TrimPrefix(usageCmd.CommandPath(), usageCmd.Root().Name()), " "),
+		Version:    version.Version,
+		DurationMS: time.Since(usageStart).Milliseconds(),
+		Status:     status,
+	}
+	if e.Command == "" {
+		e.Command = usageCmd.Root().Name()
+	}
+	if cl, ok := getCluster(usageCmd); ok {
+		e.Cluster = cl.Name
+	}
+	if status != 0 {
+		e.Failure = failure
+		if e.Failure == "" {
+			e.Failure = usage.Categorize(log.Errors())
+		}
+	}
+	if err := c.Record(e); err != nil {
+		log.Logger.Debug().Err(err).Msg("failed to record usage statistics")
+	}
+}
@@ -0,0 +1,67 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+)
+
+// varsGetCmd represents the "vars get" command
+var varsGetCmd = &cobra.Command{
+	Use:   "get (--node <xname> | --group <name>) <key> [--resolve]",
+	Args:  cobra.ExactArgs(1),
+	Short: "Get a variable of a node or group",
+	Long: `Print the value of a variable of a node or group. If the variable is
+not set, an error is printed and the exit status is 1.
+
+Pass --resolve with --node to get the value that templates see for
+the node, which may be set for one of the SMD groups it is a member
+of.
+
+An access token is required if variables are stored in cloud-init
+or --resolve is passed.
+
+See ochami-vars(1) for more details.`,
+	Example: `  # Get the serial console of all compute nodes
+  ochami vars get --group compute console
+
+  # Get the serial console that templates use for a node
+  ochami vars get --node x3000c0s0b0n0 --resolve console`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flag("resolve").Changed && !cmd.Flag("node").Changed {
+			return fmt.Errorf("--resolve can only be used with --node")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		scope, name, _ := varsScope(cmd)
+		var m map[string]string
+		if resolve, _ := cmd.Flags().GetBool("resolve"); resolve {
+			m = varsResolver(cmd)(name)
+		} else {
+			v, _ := varsLoad(cmd)
+			m = v.Get(scope, name)
+		}
+
+		value, ok := m[args[0]]
+		if !ok {
+			log.Logger.Error().Msgf("variable %s is not set for %s %s", args[0], scope, name)
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		fmt.Println(value)
+	},
+}
+
+func init() {
+	varsAddScopeFlags(varsGetCmd)
+	varsGetCmd.MarkFlagsOneRequired("node", "group")
+	varsGetCmd.Flags().Bool("resolve", false, "include variables of the groups the node is a member of")
+
+	varsCmd.AddCommand(varsGetCmd)
+}
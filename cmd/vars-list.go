@@ -0,0 +1,74 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// varsListCmd represents the "vars list" command
+var varsListCmd = &cobra.Command{
+	Use:   "list [--node <xname> [--resolve] | --group <name>] [-F <format>]",
+	Args:  cobra.NoArgs,
+	Short: "List variables of nodes and groups",
+	Long: `List the variables of a node or group, printed as a map of keys to
+values. If neither --node nor --group is passed, the variables of all
+nodes and groups are printed, as the maps 'nodes' and 'groups' of
+their names to their variables.
+
+Pass --resolve with --node to list the variables that templates see
+for the node, including those set for the SMD groups it is a member
+of.
+
+An access token is required if variables are stored in cloud-init
+or --resolve is passed.
+
+See ochami-vars(1) for more details.`,
+	Example: `  # List the variables of all nodes and groups
+  ochami vars list
+
+  # List the variables that templates see for a node
+  ochami vars list --node x3000c0s0b0n0 --resolve`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flag("resolve").Changed && !cmd.Flag("node").Changed {
+			return fmt.Errorf("--resolve can only be used with --node")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		var out any
+		if scope, name, ok := varsScope(cmd); !ok {
+			out, _ = varsLoad(cmd)
+		} else if resolve, _ := cmd.Flags().GetBool("resolve"); resolve {
+			out = varsResolver(cmd)(name)
+		} else {
+			v, _ := varsLoad(cmd)
+			out = v.Get(scope, name)
+		}
+
+		// Print output
+		if outBytes, err := format.MarshalData(out, formatOutput); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to format output")
+			logHelpError(cmd)
+			os.Exit(1)
+		} else {
+			fmt.Println(string(outBytes))
+		}
+	},
+}
+
+func init() {
+	varsAddScopeFlags(varsListCmd)
+	varsListCmd.Flags().Bool("resolve", false, "include variables of the groups the node is a member of")
+	varsListCmd.Flags().VarP(&formatOutput, "format-output", "F", "format of output printed to standard output ("+format.DataFormatList()+")")
+
+	varsListCmd.RegisterFlagCompletionFunc("format-output", completionFormatData)
+
+	varsCmd.AddCommand(varsListCmd)
+}
@@ -0,0 +1,67 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/vars"
+)
+
+// varsSetCmd represents the "vars set" command
+var varsSetCmd = &cobra.Command{
+	Use:   "set (--node <xname> | --group <name>) <key>=<value>...",
+	Args:  cobra.MinimumNArgs(1),
+	Short: "Set variables of a node or group",
+	Long: `Set one or more variables of a node or group, replacing the value
+of any key that is already set. Keys consist of letters, digits, and
+'_' and do not start with a digit, so that they can be referenced in
+templates. Values can be anything, including empty.
+
+An access token is required if variables are stored in cloud-init.
+
+See ochami-vars(1) for more details.`,
+	Example: `  # Set the serial console of all compute nodes
+  ochami vars set --group compute console=ttyS0,115200
+
+  # Override it for one node and record its management address
+  ochami vars set --node x3000c0s0b0n0 console=ttyS1,115200 mgmt_ip=10.1.0.10`,
+	Run: func(cmd *cobra.Command, args []string) {
+		scope, name, _ := varsScope(cmd)
+		type pair struct{ key, value string }
+		var pairs []pair
+		for _, arg := range args {
+			key, value, err := vars.ParsePair(arg)
+			if err != nil {
+				log.Logger.Error().Err(err).Msg("invalid variable")
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			pairs = append(pairs, pair{key, value})
+		}
+
+		v, exists := varsLoad(cmd)
+		changed := false
+		for _, p := range pairs {
+			if old, ok := v.Set(scope, name, p.key, p.value); ok && old == p.value {
+				log.Logger.Info().Msgf("%s %s already has %s=%s", scope, name, p.key, old)
+				continue
+			}
+			changed = true
+			log.Logger.Info().Msgf("set %s=%s for %s %s", p.key, p.value, scope, name)
+		}
+		if changed {
+			varsSave(cmd, v, exists)
+		}
+	},
+}
+
+func init() {
+	varsAddScopeFlags(varsSetCmd)
+	varsSetCmd.MarkFlagsOneRequired("node", "group")
+
+	varsCmd.AddCommand(varsSetCmd)
+}
@@ -0,0 +1,197 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+	"github.com/spf13/cobra"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/vars"
+)
+
+// varsCmd represents the vars command
+var varsCmd = &cobra.Command{
+	Use:   "vars",
+	Args:  cobra.NoArgs,
+	Short: "Manage per-node and per-group template variables",
+	Long: `Manage key/value variables of nodes and groups, such as site-specific
+addresses or console settings, that are available to templates as
+vars when rendering kernel parameters (e.g. {{ .vars.console }}) and
+cloud-init configs with 'ochami cloud-init group render' (e.g.
+{{ vars.console }}). The variables of a node are merged over those of
+the SMD groups it is a member of.
+
+Variables are stored in the file set as cluster.vars-file in the
+config file or, if it is not set, in the meta-data of the cloud-init
+group '` + vars.CloudInitGroup + `', which has no members.
+
+See ochami-vars(1) for more details.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// Check that all required args are passed
+		if len(args) == 0 {
+			printUsageHandleError(cmd)
+			os.Exit(0)
+		}
+	},
+}
+
+// varsFile returns cluster.vars-file of the cluster being used, or an empty
+// string if variables are stored in cloud-init.
+func varsFile(cmd *cobra.Command) string {
+	if cl, found := getCluster(cmd); found {
+		return cl.Cluster.VarsFile
+	}
+	return ""
+}
+
+// varsLoad returns the variables of the cluster and whether they are stored
+// in a cloud-init group that exists. If they cannot be read, the program
+// exits.
+func varsLoad(cmd *cobra.Command) (vars.Vars, bool) {
+	if path := varsFile(cmd); path != "" {
+		v, err := vars.ReadFile(path)
+		if err != nil {
+			log.Logger.Error().Err(err).Msg("failed to load variables")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		return v, false
+	}
+
+	handleToken(cmd)
+	henvs, errs, err := cloudInitGetClient(cmd).GetGroups(token, vars.CloudInitGroup)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to get variables from cloud-init")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	if errs[0] != nil {
+		if henvs[0].StatusCode == http.StatusNotFound {
+			log.Logger.Debug().Msgf("cloud-init group %s does not exist, no variables are set", vars.CloudInitGroup)
+			return vars.Vars{}, false
+		}
+		if errors.Is(errs[0], client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(errs[0]).Msg("cloud-init group request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(errs[0]).Msg("failed to get variables from cloud-init")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	var group cistore.GroupData
+	if err := json.Unmarshal(henvs[0].Body, &group); err != nil {
+		log.Logger.Error().Err(err).Msgf("failed to unmarshal cloud-init group %s", vars.CloudInitGroup)
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+	v, err := vars.FromMetaData(group.Data)
+	if err != nil {
+		log.Logger.Error().Err(err).Msg("failed to load variables")
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+
+	return v, true
+}
+
+// varsSave stores v as the variables of the cluster, creating the cloud-init
+// group they are stored in unless exists is true. If they cannot be stored,
+// the program exits.
+func varsSave(cmd *cobra.Command, v vars.Vars, exists bool) {
+	if path := varsFile(cmd); path != "" {
+		if err := vars.WriteFile(path, v); err != nil {
+			log.Logger.Error().Err(err).Msg("failed to save variables")
+			logHelpError(cmd)
+			os.Exit(1)
+		}
+		return
+	}
+
+	cloudInitClient := cloudInitGetClient(cmd)
+	group := []cistore.GroupData{{
+		Name:        vars.CloudInitGroup,
+		Description: "Template variables managed by 'ochami vars'",
+		Data:        v.MetaData(),
+	}}
+	putOrPost := cloudInitClient.PostGroups
+	if exists {
+		putOrPost = cloudInitClient.PutGroups
+	}
+	_, errs, err := putOrPost(group, token)
+	if err == nil {
+		err = errs[0]
+	}
+	if err != nil {
+		if errors.Is(err, client.UnsuccessfulHTTPError) {
+			log.Logger.Error().Err(err).Msg("cloud-init group request yielded unsuccessful HTTP response")
+		} else {
+			log.Logger.Error().Err(err).Msg("failed to save variables to cloud-init")
+		}
+		logHelpError(cmd)
+		os.Exit(1)
+	}
+}
+
+// varsResolver returns a function that returns the variables of a node (see
+// vars.Vars.Resolve), merged over those of the SMD groups it is a member of.
+// The variables and groups are read once, exiting if they cannot be.
+func varsResolver(cmd *cobra.Command) func(xname string) map[string]string {
+	v, _ := varsLoad(cmd)
+	var groups []string
+	memberOf := make(map[string][]string)
+	if len(v.Groups) > 0 {
+		handleToken(cmd)
+		for _, g := range metaGetGroups(cmd, smdGetClient(cmd)) {
+			groups = append(groups, g.Label)
+			for _, m := range g.Members.IDs {
+				memberOf[strings.ToLower(m)] = append(memberOf[strings.ToLower(m)], g.Label)
+			}
+		}
+	}
+	for _, g := range v.Names(vars.ScopeGroup) {
+		if !slices.Contains(groups, g) {
+			log.Logger.Warn().Msgf("variables are set for group %s, which does not exist in SMD", g)
+		}
+	}
+
+	return func(xname string) map[string]string {
+		return v.Resolve(xname, memberOf[strings.ToLower(xname)])
+	}
+}
+
+// varsScope returns the scope and name passed with --node or --group, which
+// are mutually exclusive. If neither was passed, false is returned.
+func varsScope(cmd *cobra.Command) (vars.Scope, string, bool) {
+	for _, s := range []vars.Scope{vars.ScopeNode, vars.ScopeGroup} {
+		if cmd.Flag(string(s)).Changed {
+			name, err := cmd.Flags().GetString(string(s))
+			if err != nil {
+				log.Logger.Error().Err(err).Msgf("failed to get value for --%s", s)
+				logHelpError(cmd)
+				os.Exit(1)
+			}
+			return s, name, true
+		}
+	}
+	return "", "", false
+}
+
+// varsAddScopeFlags adds the --node and --group flags to cmd.
+func varsAddScopeFlags(cmd *cobra.Command) {
+	cmd.Flags().String("node", "", "xname of node whose variables to use")
+	cmd.Flags().String("group", "", "name of group whose variables to use")
+	cmd.MarkFlagsMutuallyExclusive("node", "group")
+}
+
+func init() {
+	rootCmd.AddCommand(varsCmd)
+}
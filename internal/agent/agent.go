@@ -0,0 +1,797 @@
+// Package agent implements the ochami agent, a long-running process that keeps
+// the access token of a cluster fresh and a cache of responses to GET requests
+// to its services warm. Both are served over an HTTP API on a Unix socket so
+// that other ochami invocations and site tools can make fast, authenticated
+// queries without each fetching a token and waiting on the services.
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+)
+
+const (
+	// DefaultRefreshBefore is how long before it expires the token is
+	// refreshed if Config.RefreshBefore is not set.
+	DefaultRefreshBefore = 5 * time.Minute
+
+	// DefaultCacheTTL is how long responses are cached if Config.CacheTTL
+	// is not set.
+	DefaultCacheTTL = 30 * time.Second
+
+	// WarmIdle is how long a cached response is kept warm after it was
+	// last requested. Responses not requested for longer are evicted,
+	// except those of Config.Warm.
+	WarmIdle = 10 * time.Minute
+
+	// CacheHeader is the response header the agent sets on proxied
+	// responses to "hit" or "miss", depending on whether the response was
+	// served from the cache.
+	CacheHeader = "X-Ochami-Agent-Cache"
+
+	// maxTick is the longest time between token and cache refreshes.
+	maxTick = 30 * time.Second
+)
+
+// Token is an access token and the time it expires, which is zero if it is
+// unknown (e.g. because the token is not a JWT).
+type Token struct {
+	Value   string    `json:"token" yaml:"token"`
+	Expires time.Time `json:"expires,omitempty" yaml:"expires,omitempty"`
+}
+
+// ParseToken returns the Token for the access token s, reading its expiration
+// from it if it is a JWT. The token is not validated.
+func ParseToken(s string) Token {
+	t := Token{Value: s}
+	if jt, err := jwt.ParseString(s, jwt.WithValidate(false)); err == nil {
+		t.Expires = jt.Expiration()
+	}
+
+	return t
+}
+
+// TokenSource returns a fresh access token.
+type TokenSource func() (string, error)
+
+// CommandTokenSource returns a TokenSource that runs command with sh -c and
+// returns its standard output, trimmed of whitespace, as the token.
+func CommandTokenSource(command string) TokenSource {
+	return func() (string, error) {
+		out, err := exec.Command("sh", "-c", command).Output()
+		if err != nil {
+			var ee *exec.ExitError
+			if errors.As(err, &ee) && len(ee.Stderr) > 0 {
+				return "", fmt.Errorf("token command failed: %w: %s", err, strings.TrimSpace(string(ee.Stderr)))
+			}
+			return "", fmt.Errorf("token command failed: %w", err)
+		}
+		t := strings.TrimSpace(string(out))
+		if t == "" {
+			return "", fmt.Errorf("token command printed no token")
+		}
+
+		return t, nil
+	}
+}
+
+// Config is the configuration of an Agent.
+type Config struct {
+	// Cluster is the name of the cluster the agent is for.
+	Cluster string
+
+	// Services maps the names of the services of the cluster (e.g. smd)
+	// to their base URIs. Only requests for URIs under one of them are
+	// proxied.
+	Services map[string]string
+
+	// Token is the initial access token, if any.
+	Token string
+
+	// TokenSource, if not nil, is used to refresh the token when it is
+	// within RefreshBefore of expiring, or every RefreshBefore if its
+	// expiration is unknown.
+	TokenSource   TokenSource
+	RefreshBefore time.Duration
+
+	// CacheTTL is how long responses are served from the cache before
+	// they are fetched again. Responses that keep being requested are
+	// refreshed in the background before they expire.
+	CacheTTL time.Duration
+
+	// Warm is the list of <service>:<path> endpoints (e.g.
+	// smd:/State/Components) to keep in the cache from the start, whether
+	// or not they are requested.
+	Warm []string
+
+	// Client is the client used to make requests to the services
+	// (http.DefaultClient if nil).
+	Client *http.Client
+}
+
+// Status is the status of an Agent, as served at /v1/status.
+type Status struct {
+	Cluster        string            `json:"cluster" yaml:"cluster"`
+	PID            int               `json:"pid" yaml:"pid"`
+	Started        time.Time         `json:"started" yaml:"started"`
+	Services       map[string]string `json:"services" yaml:"services"`
+	TokenExpires   *time.Time        `json:"token_expires,omitempty" yaml:"token_expires,omitempty"`
+	TokenRefreshed *time.Time        `json:"token_refreshed,omitempty" yaml:"token_refreshed,omitempty"`
+	TokenError     string            `json:"token_error,omitempty" yaml:"token_error,omitempty"`
+	CacheEntries   int               `json:"cache_entries" yaml:"cache_entries"`
+	CacheHits      int               `json:"cache_hits" yaml:"cache_hits"`
+	CacheMisses    int               `json:"cache_misses" yaml:"cache_misses"`
+}
+
+// cacheEntry is a cached response. Only responses fetched with the agent's
+// token (auth is empty) are refreshed in the background, since the credentials
+// of clients are not kept.
+type cacheEntry struct {
+	uri         string
+	auth        string
+	status      int
+	contentType string
+	body        []byte
+	fetched     time.Time
+	used        time.Time
+}
+
+// Agent keeps the token of a cluster fresh and caches responses to GET requests
+// to its services. Create one with New.
+type Agent struct {
+	cfg      Config
+	services map[string]*url.URL
+	warm     []string
+	started  time.Time
+	now      func() time.Time
+
+	mu         sync.Mutex
+	token      Token
+	refreshed  time.Time
+	refreshErr error
+	cache      map[string]*cacheEntry
+	hits       int
+	misses     int
+}
+
+// New returns a new Agent configured by cfg, returning an error if any of its
+// service base URIs or endpoints to keep warm are invalid.
+func New(cfg Config) (*Agent, error) {
+	if cfg.RefreshBefore <= 0 {
+		cfg.RefreshBefore = DefaultRefreshBefore
+	}
+	if cfg.CacheTTL < 0 {
+		cfg.CacheTTL = 0
+	}
+	if cfg.Client == nil {
+		cfg.Client = http.DefaultClient
+	}
+	a := &Agent{
+		cfg:      cfg,
+		services: make(map[string]*url.URL),
+		started:  time.Now(),
+		now:      time.Now,
+		token:    ParseToken(cfg.Token),
+		cache:    make(map[string]*cacheEntry),
+	}
+	if cfg.Token != "" {
+		a.refreshed = a.started
+	}
+	for name, base := range cfg.Services {
+		u, err := url.Parse(base)
+		if err != nil || !u.IsAbs() {
+			return nil, fmt.Errorf("invalid base URI %q for %s", base, name)
+		}
+		u.Path = strings.TrimSuffix(u.Path, "/")
+		a.services[name] = u
+	}
+	for _, w := range cfg.Warm {
+		uri, err := a.ServiceURI(w)
+		if err != nil {
+			return nil, err
+		}
+		a.warm = append(a.warm, uri)
+	}
+
+	return a, nil
+}
+
+// ServiceURI returns the URI of the endpoint ep of the form <service>:<path>
+// (e.g. smd:/State/Components), returning an error if ep is malformed or its
+// service is unknown.
+func (a *Agent) ServiceURI(ep string) (string, error) {
+	svc, path, ok := strings.Cut(ep, ":")
+	if !ok || svc == "" {
+		return "", fmt.Errorf("invalid endpoint %q: expected <service>:<path>", ep)
+	}
+	base, ok := a.services[svc]
+	if !ok {
+		return "", fmt.Errorf("invalid endpoint %q: unknown service %q", ep, svc)
+	}
+
+	return base.String() + "/" + strings.TrimPrefix(path, "/"), nil
+}
+
+// allowed returns whether uri is under the base URI of one of the services of
+// the agent, so that the agent cannot be used to send its token elsewhere.
+func (a *Agent) allowed(uri string) bool {
+	return a.serviceOf(uri) != nil
+}
+
+// serviceOf returns the base URI of the service of the agent that uri is
+// under, or nil if there is none.
+func (a *Agent) serviceOf(uri string) *url.URL {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil
+	}
+	for _, base := range a.services {
+		if u.Scheme == base.Scheme && u.Host == base.Host &&
+			(u.Path == base.Path || strings.HasPrefix(u.Path, base.Path+"/")) {
+			return base
+		}
+	}
+
+	return nil
+}
+
+// Invalidate drops the cached responses of the service that uri is under, e.g.
+// because a request that may have modified its data was sent to uri, and
+// returns the number of responses dropped. Endpoints of Config.Warm are
+// fetched again when the cache is next warmed.
+func (a *Agent) Invalidate(uri string) int {
+	base := a.serviceOf(uri)
+	if base == nil {
+		return 0
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := 0
+	for key, e := range a.cache {
+		if a.serviceOf(e.uri) == base {
+			delete(a.cache, key)
+			n++
+		}
+	}
+
+	return n
+}
+
+// refreshDue returns whether the token should be refreshed at now. The caller
+// must hold a.mu.
+func (a *Agent) refreshDue(now time.Time) bool {
+	switch {
+	case a.cfg.TokenSource == nil:
+		return false
+	case a.token.Value == "":
+		return true
+	case a.token.Expires.IsZero():
+		return now.Sub(a.refreshed) >= a.cfg.RefreshBefore
+	default:
+		return a.token.Expires.Sub(now) <= a.cfg.RefreshBefore
+	}
+}
+
+// refreshToken refreshes the token if it is due, keeping the current one if
+// refreshing it fails. The caller must hold a.mu.
+func (a *Agent) refreshToken() {
+	now := a.now()
+	if !a.refreshDue(now) {
+		return
+	}
+	t, err := a.cfg.TokenSource()
+	if err != nil {
+		log.Logger.Warn().Err(err).Msgf("failed to refresh token of cluster %s", a.cfg.Cluster)
+		a.refreshErr = err
+		return
+	}
+	a.token = ParseToken(t)
+	a.refreshed = now
+	a.refreshErr = nil
+	if a.token.Expires.IsZero() {
+		log.Logger.Info().Msgf("refreshed token of cluster %s", a.cfg.Cluster)
+	} else {
+		log.Logger.Info().Msgf("refreshed token of cluster %s, expires %s", a.cfg.Cluster, a.token.Expires.Local().Format(time.RFC1123))
+	}
+}
+
+// Token returns the current token of the agent, refreshing it first if it is
+// due.
+func (a *Agent) Token() Token {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.refreshToken()
+
+	return a.token
+}
+
+// Status returns the current status of the agent.
+func (a *Agent) Status() Status {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s := Status{
+		Cluster:      a.cfg.Cluster,
+		PID:          os.Getpid(),
+		Started:      a.started,
+		Services:     make(map[string]string),
+		CacheEntries: len(a.cache),
+		CacheHits:    a.hits,
+		CacheMisses:  a.misses,
+	}
+	for name, u := range a.services {
+		s.Services[name] = u.String()
+	}
+	if !a.token.Expires.IsZero() {
+		exp := a.token.Expires
+		s.TokenExpires = &exp
+	}
+	if !a.refreshed.IsZero() {
+		r := a.refreshed
+		s.TokenRefreshed = &r
+	}
+	if a.refreshErr != nil {
+		s.TokenError = a.refreshErr.Error()
+	}
+
+	return s
+}
+
+// cacheKey returns the key of the response to a request for uri made with the
+// credentials auth, which are hashed so that they are not kept in memory.
+func cacheKey(uri, auth string) string {
+	if auth == "" {
+		return uri
+	}
+	h := sha256.Sum256([]byte(auth))
+
+	return hex.EncodeToString(h[:8]) + " " + uri
+}
+
+// fetch makes a GET request for uri with the credentials auth, or the token of
+// the agent if auth is empty, returning the response as a cache entry.
+func (a *Agent) fetch(ctx context.Context, uri, auth string) (*cacheEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	} else if t := a.Token(); t.Value != "" {
+		req.Header.Set("Authorization", "Bearer "+t.Value)
+	}
+	res, err := a.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	return &cacheEntry{
+		uri:         uri,
+		auth:        auth,
+		status:      res.StatusCode,
+		contentType: res.Header.Get("Content-Type"),
+		body:        body,
+		fetched:     a.now(),
+	}, nil
+}
+
+// get returns the response to a GET request for uri with the credentials auth
+// (the token of the agent if empty) and whether it was served from the cache.
+// Unless refresh is true, a cached response younger than the cache TTL is
+// returned. Only successful responses are cached.
+func (a *Agent) get(ctx context.Context, uri, auth string, refresh bool) (*cacheEntry, bool, error) {
+	key := cacheKey(uri, auth)
+	now := a.now()
+	a.mu.Lock()
+	if e, ok := a.cache[key]; ok && !refresh && now.Sub(e.fetched) < a.cfg.CacheTTL {
+		e.used = now
+		a.hits++
+		a.mu.Unlock()
+		return e, true, nil
+	}
+	a.misses++
+	a.mu.Unlock()
+
+	e, err := a.fetch(ctx, uri, auth)
+	if err != nil {
+		return nil, false, err
+	}
+	e.used = now
+	if a.cfg.CacheTTL > 0 && e.status >= 200 && e.status < 300 {
+		a.mu.Lock()
+		a.cache[key] = e
+		a.mu.Unlock()
+	}
+
+	return e, false, nil
+}
+
+// warmCache refreshes the cached responses fetched with the token of the agent
+// that were requested within WarmIdle and are past half of the cache TTL, as
+// well as the endpoints of Config.Warm, and evicts the responses not requested
+// within WarmIdle (or expired ones fetched with other credentials).
+func (a *Agent) warmCache(ctx context.Context) {
+	if a.cfg.CacheTTL == 0 {
+		return
+	}
+	now := a.now()
+	warm := make(map[string]bool)
+	for _, uri := range a.warm {
+		warm[uri] = true
+	}
+	a.mu.Lock()
+	for key, e := range a.cache {
+		switch {
+		case e.auth != "" && now.Sub(e.fetched) >= a.cfg.CacheTTL,
+			e.auth == "" && !warm[e.uri] && now.Sub(e.used) > WarmIdle:
+			delete(a.cache, key)
+		case e.auth == "" && now.Sub(e.fetched) >= a.cfg.CacheTTL/2:
+			warm[e.uri] = true
+		case e.auth == "":
+			delete(warm, e.uri)
+		}
+	}
+	a.mu.Unlock()
+
+	uris := make([]string, 0, len(warm))
+	for uri := range warm {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	for _, uri := range uris {
+		e, err := a.fetch(ctx, uri, "")
+		if err != nil {
+			log.Logger.Warn().Err(err).Msgf("failed to refresh cached response of %s", uri)
+			continue
+		}
+		if e.status < 200 || e.status >= 300 {
+			log.Logger.Warn().Msgf("failed to refresh cached response of %s: status %d", uri, e.status)
+			continue
+		}
+		a.mu.Lock()
+		if old, ok := a.cache[uri]; ok {
+			e.used = old.used
+		} else {
+			e.used = now
+		}
+		a.cache[uri] = e
+		a.mu.Unlock()
+	}
+}
+
+// Run refreshes the token and warms the cache of the agent periodically until
+// ctx is done.
+func (a *Agent) Run(ctx context.Context) {
+	tick := maxTick
+	if a.cfg.CacheTTL > 0 && a.cfg.CacheTTL/2 < tick {
+		tick = max(a.cfg.CacheTTL/2, time.Second)
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		a.Token()
+		a.warmCache(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Handler returns the handler of the HTTP API of the agent:
+//
+//	GET /v1/status                       status of the agent (see Status)
+//	GET /v1/token                        current token (see Token)
+//	GET /v1/get?uri=<uri>                response to a GET request for uri
+//	GET /v1/services/<service>/<path>   response to a GET request for path
+//	                                     of service
+//	POST /v1/invalidate?uri=<uri>        drop the cached responses of the
+//	                                     service of uri (see Invalidate)
+//
+// Proxied requests are made with the Authorization header of the request, or
+// the token of the agent if it has none, and are served from the cache unless
+// the request has the header "Cache-Control: no-cache". URIs not under the base
+// URI of one of the services of the agent are refused with 421 Misdirected
+// Request.
+func (a *Agent) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, a.Status())
+	})
+	mux.HandleFunc("GET /v1/token", func(w http.ResponseWriter, r *http.Request) {
+		t := a.Token()
+		if t.Value == "" {
+			http.Error(w, "agent has no token", http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, t)
+	})
+	mux.HandleFunc("GET /v1/get", func(w http.ResponseWriter, r *http.Request) {
+		a.serveProxy(w, r, r.URL.Query().Get("uri"))
+	})
+	mux.HandleFunc("GET /v1/services/{service}/{path...}", func(w http.ResponseWriter, r *http.Request) {
+		uri, err := a.ServiceURI(r.PathValue("service") + ":" + r.PathValue("path"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if r.URL.RawQuery != "" {
+			uri += "?" + r.URL.RawQuery
+		}
+		a.serveProxy(w, r, uri)
+	})
+	mux.HandleFunc("POST /v1/invalidate", func(w http.ResponseWriter, r *http.Request) {
+		uri := r.URL.Query().Get("uri")
+		if !a.allowed(uri) {
+			http.Error(w, fmt.Sprintf("%q is not under a service of cluster %s", uri, a.cfg.Cluster), http.StatusMisdirectedRequest)
+			return
+		}
+		if n := a.Invalidate(uri); n > 0 {
+			log.Logger.Debug().Msgf("dropped %d cached response(s) after a request for %s", n, uri)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return mux
+}
+
+// serveProxy serves the response to a GET request for uri.
+func (a *Agent) serveProxy(w http.ResponseWriter, r *http.Request, uri string) {
+	if !a.allowed(uri) {
+		http.Error(w, fmt.Sprintf("%q is not under a service of cluster %s", uri, a.cfg.Cluster), http.StatusMisdirectedRequest)
+		return
+	}
+
+	// Requests made with the token of the agent (e.g. by ochami
+	// invocations that got it from the agent) share its cache entries
+	auth := r.Header.Get("Authorization")
+	if t := a.Token(); t.Value != "" && auth == "Bearer "+t.Value {
+		auth = ""
+	}
+	e, hit, err := a.get(r.Context(), uri, auth, r.Header.Get("Cache-Control") == "no-cache")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("request for %s failed: %v", uri, err), http.StatusBadGateway)
+		return
+	}
+	if e.contentType != "" {
+		w.Header().Set("Content-Type", e.contentType)
+	}
+	if hit {
+		w.Header().Set(CacheHeader, "hit")
+	} else {
+		w.Header().Set(CacheHeader, "miss")
+	}
+	w.WriteHeader(e.status)
+	w.Write(e.body)
+}
+
+// writeJSON writes v to w as JSON.
+func writeJSON(w http.ResponseWriter, v any) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(b, '\n'))
+}
+
+// Serve serves the HTTP API of the agent on the Unix socket socket, which only
+// the current user can connect to, and runs the agent until ctx is done. The
+// directory of the socket is created if needed, and must be owned by the
+// current user with mode 0700, so that another user cannot have replaced the
+// socket. A stale socket left by an agent that exited is replaced, but an error
+// is returned if another agent is listening on it.
+func (a *Agent) Serve(ctx context.Context, socket string) error {
+	dir := filepath.Dir(socket)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create directory for socket: %w", err)
+	}
+	if err := checkSocketDir(dir); err != nil {
+		return fmt.Errorf("refusing to use directory for socket: %w", err)
+	}
+	if _, err := os.Stat(socket); err == nil {
+		if c, err := net.DialTimeout("unix", socket, time.Second); err == nil {
+			c.Close()
+			return fmt.Errorf("an agent is already listening on %s", socket)
+		}
+		if err := os.Remove(socket); err != nil {
+			return fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+	l, err := listenPrivate(socket)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socket, err)
+	}
+	defer os.Remove(socket)
+	if err := os.Chmod(socket, 0600); err != nil {
+		l.Close()
+		return fmt.Errorf("failed to set permissions of socket: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	srv := &http.Server{Handler: a.Handler()}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	go a.Run(ctx)
+
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// DefaultSocketPath returns the default path of the socket of the agent for the
+// cluster named cluster: agent-<cluster>.sock in $XDG_RUNTIME_DIR/ochami, or in
+// ochami-<uid> in the temporary directory if XDG_RUNTIME_DIR is not set.
+func DefaultSocketPath(cluster string) string {
+	name := "agent-" + strings.NewReplacer("/", "_", " ", "_").Replace(cluster) + ".sock"
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "ochami", name)
+	}
+
+	return filepath.Join(os.TempDir(), fmt.Sprintf("ochami-%d", os.Getuid()), name)
+}
+
+// CheckSocket returns an error unless socket is a Unix socket owned by the
+// current user, so that tokens are not sent to an agent run by another user.
+func CheckSocket(socket string) error {
+	return checkSocketOwner(socket)
+}
+
+// socketBaseURI is the base URI of requests to the agent over its socket.
+const socketBaseURI = "http://ochami-agent"
+
+// SocketClient returns an HTTP client whose requests are sent to the agent
+// listening on socket, and whose requests time out after timeout (none if 0).
+func SocketClient(socket string, timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+}
+
+// getJSON unmarshals the response to a GET request for path from the agent
+// listening on socket into v.
+func getJSON(socket, path string, v any) error {
+	res, err := SocketClient(socket, 5*time.Second).Get(socketBaseURI + path)
+	if err != nil {
+		return fmt.Errorf("failed to reach agent: %w", err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response of agent: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("agent responded with %s: %s", res.Status, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// GetToken returns the current token of the agent listening on socket.
+func GetToken(socket string) (Token, error) {
+	var t Token
+	err := getJSON(socket, "/v1/token", &t)
+
+	return t, err
+}
+
+// GetStatus returns the status of the agent listening on socket.
+func GetStatus(socket string) (Status, error) {
+	var s Status
+	err := getJSON(socket, "/v1/status", &s)
+
+	return s, err
+}
+
+// transport is the http.RoundTripper returned by Transport.
+type transport struct {
+	agent *http.Client
+	base  http.RoundTripper
+}
+
+// Transport returns an http.RoundTripper that sends GET requests without a
+// body through the agent listening on socket, so that they are served from its
+// cache, and sends other requests with base (http.DefaultTransport if nil).
+// GET requests are also sent with base if the agent cannot be reached or does
+// not serve their URI (e.g. because it is for another cluster). Once a request
+// that may modify data has been sent, the agent is told to drop the cached
+// responses of its service, so that reads that follow see the change.
+func Transport(socket string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{agent: SocketClient(socket, 0), base: base}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+	default:
+		res, err := t.base.RoundTrip(req)
+		t.invalidate(req)
+		return res, err
+	}
+	if req.Method != http.MethodGet || (req.Body != nil && req.Body != http.NoBody) {
+		return t.base.RoundTrip(req)
+	}
+	areq, err := http.NewRequestWithContext(req.Context(), http.MethodGet,
+		socketBaseURI+"/v1/get?"+url.Values{"uri": {req.URL.String()}}.Encode(), nil)
+	if err != nil {
+		return t.base.RoundTrip(req)
+	}
+	for _, h := range []string{"Authorization", "Cache-Control"} {
+		if v := req.Header.Get(h); v != "" {
+			areq.Header.Set(h, v)
+		}
+	}
+	res, err := t.agent.Transport.RoundTrip(areq)
+	if err != nil {
+		log.Logger.Debug().Err(err).Msg("failed to reach agent, sending request directly")
+		return t.base.RoundTrip(req)
+	}
+	if res.StatusCode == http.StatusMisdirectedRequest {
+		res.Body.Close()
+		return t.base.RoundTrip(req)
+	}
+	log.Logger.Debug().Msgf("request for %s served by agent (cache %s)", req.URL, res.Header.Get(CacheHeader))
+	res.Request = req
+
+	return res, nil
+}
+
+// invalidate tells the agent to drop the cached responses of the service req
+// was sent to. Failures are only logged, since the agent may not be running or
+// may not serve the URI of req.
+func (t *transport) invalidate(req *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	areq, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		socketBaseURI+"/v1/invalidate?"+url.Values{"uri": {req.URL.String()}}.Encode(), nil)
+	if err != nil {
+		return
+	}
+	res, err := t.agent.Transport.RoundTrip(areq)
+	if err != nil {
+		log.Logger.Debug().Err(err).Msg("failed to tell agent to drop its cached responses")
+		return
+	}
+	res.Body.Close()
+}
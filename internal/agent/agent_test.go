@@ -0,0 +1,309 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+)
+
+// testJWT returns a JWT that expires at exp.
+func testJWT(t *testing.T, exp time.Time) string {
+	t.Helper()
+	jt := jwt.New()
+	if err := jt.Set(jwt.ExpirationKey, exp); err != nil {
+		t.Fatal(err)
+	}
+	b, err := jwt.Sign(jt, jwa.HS256, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestParseToken(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	if got := ParseToken(testJWT(t, exp)); !got.Expires.Equal(exp) {
+		t.Errorf("ParseToken() expires = %s, want %s", got.Expires, exp)
+	}
+	if got := ParseToken("opaque"); got.Value != "opaque" || !got.Expires.IsZero() {
+		t.Errorf("ParseToken(opaque) = %+v, want no expiration", got)
+	}
+}
+
+func TestCommandTokenSource(t *testing.T) {
+	if tok, err := CommandTokenSource("echo ' tok '")(); err != nil || tok != "tok" {
+		t.Errorf("CommandTokenSource() = %q, %v, want tok", tok, err)
+	}
+	if _, err := CommandTokenSource("echo oops >&2; exit 1")(); err == nil || !strings.Contains(err.Error(), "oops") {
+		t.Errorf("CommandTokenSource() error = %v, want error with stderr", err)
+	}
+	if _, err := CommandTokenSource("true")(); err == nil {
+		t.Error("CommandTokenSource() succeeded without output, want error")
+	}
+}
+
+func TestAgent_Token(t *testing.T) {
+	now := time.Now()
+	refreshes := 0
+	a, err := New(Config{
+		Token: testJWT(t, now.Add(time.Hour)),
+		TokenSource: func() (string, error) {
+			refreshes++
+			return testJWT(t, now.Add(2*time.Hour)), nil
+		},
+		RefreshBefore: 10 * time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a.Token()
+	if refreshes != 0 {
+		t.Errorf("token refreshed %d times an hour before expiring, want 0", refreshes)
+	}
+	a.now = func() time.Time { return now.Add(55 * time.Minute) }
+	if tok := a.Token(); refreshes != 1 || tok.Expires.Sub(now) < 90*time.Minute {
+		t.Errorf("token refreshed %d times 5m before expiring (expires %s), want 1", refreshes, tok.Expires)
+	}
+
+	// A failed refresh keeps the current token
+	a.cfg.TokenSource = func() (string, error) { return "", fmt.Errorf("idp down") }
+	a.now = func() time.Time { return now.Add(115 * time.Minute) }
+	if tok := a.Token(); tok.Value == "" || a.Status().TokenError != "idp down" {
+		t.Errorf("Token() after failed refresh = %+v, status = %+v", tok, a.Status())
+	}
+}
+
+// newTestAgent returns an agent for a test service that counts the requests it
+// receives and responds with the request path and Authorization header.
+func newTestAgent(t *testing.T) (*Agent, *httptest.Server, *int) {
+	t.Helper()
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/hsm/v2/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path":%q,"auth":%q}`, r.URL.Path, r.Header.Get("Authorization"))
+	}))
+	t.Cleanup(srv.Close)
+	a, err := New(Config{
+		Cluster:  "test",
+		Services: map[string]string{"smd": srv.URL + "/hsm/v2"},
+		Token:    "agent-token",
+		CacheTTL: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return a, srv, &requests
+}
+
+func TestAgent_Handler(t *testing.T) {
+	a, srv, requests := newTestAgent(t)
+	h := a.Handler()
+	get := func(path, auth string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	uri := srv.URL + "/hsm/v2/State/Components"
+	rec := get("/v1/get?uri="+uri, "")
+	if rec.Code != http.StatusOK || rec.Header().Get(CacheHeader) != "miss" || !strings.Contains(rec.Body.String(), "Bearer agent-token") {
+		t.Fatalf("first GET = %d %q (cache %s), want miss with agent token", rec.Code, rec.Body, rec.Header().Get(CacheHeader))
+	}
+	if rec := get("/v1/services/smd/State/Components", "Bearer agent-token"); rec.Header().Get(CacheHeader) != "hit" || *requests != 1 {
+		t.Errorf("second GET with agent token = cache %s after %d requests, want hit after 1", rec.Header().Get(CacheHeader), *requests)
+	}
+	if rec := get("/v1/get?uri="+uri, "Bearer other"); rec.Header().Get(CacheHeader) != "miss" || !strings.Contains(rec.Body.String(), "Bearer other") {
+		t.Errorf("GET with other credentials = %q (cache %s), want miss with them", rec.Body, rec.Header().Get(CacheHeader))
+	}
+	if rec := get("/v1/get?uri="+srv.URL+"/hsm/v2/missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("GET of missing endpoint = %d, want 404", rec.Code)
+	}
+	if get("/v1/get?uri="+srv.URL+"/hsm/v2/missing", "").Header().Get(CacheHeader) != "miss" {
+		t.Error("unsuccessful response was cached")
+	}
+	for _, uri := range []string{srv.URL + "/boot/v1/bootparameters", srv.URL + "/hsm/v2x", "http://example.com/hsm/v2/State/Components"} {
+		if rec := get("/v1/get?uri="+uri, ""); rec.Code != http.StatusMisdirectedRequest {
+			t.Errorf("GET of %s = %d, want %d", uri, rec.Code, http.StatusMisdirectedRequest)
+		}
+	}
+	if rec := get("/v1/token", ""); !strings.Contains(rec.Body.String(), `"token":"agent-token"`) {
+		t.Errorf("GET /v1/token = %q", rec.Body)
+	}
+	if s := a.Status(); s.CacheEntries != 2 || s.CacheHits != 1 {
+		t.Errorf("Status() = %+v, want 2 entries and 1 hit", s)
+	}
+}
+
+func TestAgent_warmCache(t *testing.T) {
+	a, srv, requests := newTestAgent(t)
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	uri := srv.URL + "/hsm/v2/State/Components"
+	if _, _, err := a.get(context.Background(), uri, "", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := a.get(context.Background(), uri, "Bearer other", false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries past half of their TTL are refreshed, except those of
+	// other credentials, which are evicted once expired
+	a.now = func() time.Time { return now.Add(time.Minute) }
+	a.warmCache(context.Background())
+	if *requests != 3 || len(a.cache) != 1 || !a.cache[uri].fetched.Equal(now.Add(time.Minute)) {
+		t.Errorf("after warming: %d requests, %d entries, want 3 requests and 1 refreshed entry", *requests, len(a.cache))
+	}
+
+	// Idle entries are evicted
+	a.now = func() time.Time { return now.Add(WarmIdle + time.Minute) }
+	a.warmCache(context.Background())
+	if len(a.cache) != 0 {
+		t.Errorf("after idling: %d entries, want 0", len(a.cache))
+	}
+}
+
+func TestTransport(t *testing.T) {
+	a, srv, requests := newTestAgent(t)
+	socket := filepath.Join(t.TempDir(), "ochami", "agent.sock")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- a.Serve(ctx, socket) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	})
+	for i := 0; i < 100; i++ {
+		if _, err := GetStatus(socket); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if tok, err := GetToken(socket); err != nil || tok.Value != "agent-token" {
+		t.Fatalf("GetToken() = %+v, %v", tok, err)
+	}
+
+	c := &http.Client{Transport: Transport(socket, nil)}
+	for i := 0; i < 2; i++ {
+		res, err := c.Get(srv.URL + "/hsm/v2/State/Components")
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+	}
+	if *requests != 1 {
+		t.Errorf("service received %d requests for 2 GETs through agent, want 1", *requests)
+	}
+
+	// Requests not served by the agent are sent directly
+	if res, err := c.Post(srv.URL+"/hsm/v2/State/Components", "application/json", strings.NewReader("{}")); err != nil || res.Header.Get(CacheHeader) != "" {
+		t.Errorf("POST through transport = %v, want sent directly", err)
+	}
+
+	// Writes drop the cached responses of their service, and reads with
+	// "Cache-Control: no-cache" are not served from the cache
+	get := func(noCache bool) string {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/hsm/v2/State/Components", nil)
+		if noCache {
+			req.Header.Set("Cache-Control", "no-cache")
+		}
+		res, err := c.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		return res.Header.Get(CacheHeader)
+	}
+	if got := get(false); got != "miss" {
+		t.Errorf("got cache %q for GET after POST, want miss", got)
+	}
+	if got := get(false); got != "hit" {
+		t.Errorf("got cache %q for second GET after POST, want hit", got)
+	}
+	if got := get(true); got != "miss" {
+		t.Errorf("got cache %q for GET with no-cache, want miss", got)
+	}
+	if *requests != 4 {
+		t.Errorf("service received %d requests, want 4", *requests)
+	}
+	if res, err := c.Get(srv.URL + "/other"); err != nil || res.Header.Get(CacheHeader) != "" {
+		t.Errorf("GET of other URI through transport = %v, want sent directly", err)
+	}
+	c = &http.Client{Transport: Transport(filepath.Join(t.TempDir(), "missing.sock"), nil)}
+	if _, err := c.Get(srv.URL + "/hsm/v2/State/Components"); err != nil {
+		t.Errorf("GET without agent error = %v, want sent directly", err)
+	}
+}
+
+func TestServe_SocketDir(t *testing.T) {
+	a, _, _ := newTestAgent(t)
+	dir := filepath.Join(t.TempDir(), "shared")
+	if err := os.Mkdir(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Serve(context.Background(), filepath.Join(dir, "agent.sock")); err == nil {
+		t.Errorf("Serve() in directory with mode 0777 did not fail")
+	}
+	link := filepath.Join(t.TempDir(), "link")
+	if err := os.Symlink(t.TempDir(), link); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Serve(context.Background(), filepath.Join(link, "agent.sock")); err == nil {
+		t.Errorf("Serve() in symbolic link to directory did not fail")
+	}
+}
+
+func TestCheckSocket(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(dir, "agent.sock")
+	l, err := listenPrivate(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	fi, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := fi.Mode().Perm(); perm&0077 != 0 {
+		t.Errorf("socket created with mode %04o, want no access for group and others", perm)
+	}
+	if err := CheckSocket(socket); err != nil {
+		t.Errorf("CheckSocket() of own socket = %v", err)
+	}
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckSocket(file); err == nil {
+		t.Errorf("CheckSocket() of regular file did not fail")
+	}
+}
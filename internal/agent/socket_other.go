@@ -0,0 +1,37 @@
+//go:build !unix
+
+package agent
+
+import (
+	"fmt"
+	"net"
+	"os"
+)
+
+// listenPrivate listens on the Unix socket socket. File permissions are not
+// enforced on this platform.
+func listenPrivate(socket string) (net.Listener, error) {
+	return net.Listen("unix", socket)
+}
+
+// checkSocketDir returns an error unless dir is a directory. Its owner and
+// permissions are not checked on this platform.
+func checkSocketDir(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	return nil
+}
+
+// checkSocketOwner returns an error if socket does not exist. Its owner is not
+// checked on this platform.
+func checkSocketOwner(socket string) error {
+	_, err := os.Lstat(socket)
+
+	return err
+}
@@ -0,0 +1,68 @@
+//go:build unix
+
+package agent
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenPrivate listens on the Unix socket socket, creating it with a umask of
+// 0077 so that no other user can connect to it before its permissions are set.
+func listenPrivate(socket string) (net.Listener, error) {
+	old := syscall.Umask(0077)
+	defer syscall.Umask(old)
+
+	return net.Listen("unix", socket)
+}
+
+// checkSocketDir returns an error unless dir is a directory, not a symbolic
+// link, that is owned by the current user and has mode 0700, so that no other
+// user can have put a socket in it.
+func checkSocketDir(dir string) error {
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if err := checkOwner(dir, fi); err != nil {
+		return err
+	}
+	if perm := fi.Mode().Perm(); perm != 0700 {
+		return fmt.Errorf("%s has mode %04o, not 0700", dir, perm)
+	}
+
+	return nil
+}
+
+// checkSocketOwner returns an error unless socket is a Unix socket owned by the
+// current user.
+func checkSocketOwner(socket string) error {
+	fi, err := os.Lstat(socket)
+	if err != nil {
+		return err
+	}
+	if fi.Mode().Type() != os.ModeSocket {
+		return fmt.Errorf("%s is not a socket", socket)
+	}
+
+	return checkOwner(socket, fi)
+}
+
+// checkOwner returns an error unless the file described by fi, found at path,
+// is owned by the current user.
+func checkOwner(path string, fi os.FileInfo) error {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("failed to get owner of %s", path)
+	}
+	if int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is owned by uid %d, not the current user (uid %d)", path, st.Uid, os.Getuid())
+	}
+
+	return nil
+}
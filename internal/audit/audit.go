@@ -0,0 +1,100 @@
+// Package audit records requests that may modify data in a local audit log, so
+// that actions taken with shared accounts can be traced to who performed them.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Entry is a record of one request that may have modified data. User is the
+// local user that ran ochami and OnBehalfOf the user it was run on behalf of
+// (see --as), if any. Status is the HTTP status of the response, or 0 if the
+// request failed without one, in which case Error describes the failure.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	User       string    `json:"user"`
+	OnBehalfOf string    `json:"on_behalf_of,omitempty"`
+	Cluster    string    `json:"cluster,omitempty"`
+	Service    string    `json:"service"`
+	Method     string    `json:"method"`
+	URI        string    `json:"uri"`
+	Status     int       `json:"status,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Log is an audit log file that entries are appended to as JSON lines. It is
+// safe for concurrent use.
+type Log struct {
+	path    string
+	user    string
+	cluster string
+
+	mu sync.Mutex
+}
+
+// Open returns the audit log at path, creating it and its parent directories
+// if they do not exist. Entries recorded with it are for cluster and the
+// current local user.
+func Open(path, cluster string) (*Log, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	f.Close()
+
+	return &Log{path: path, user: CurrentUser(), cluster: cluster}, nil
+}
+
+// CurrentUser returns the name of the current local user, or their user ID if
+// it cannot be looked up.
+func CurrentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return fmt.Sprint(os.Getuid())
+}
+
+// Path returns the path of the audit log.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Record appends e to the audit log, filling in its time (if zero), user, and
+// cluster. Each entry is written with a single append so that entries from
+// concurrent ochami processes are not interleaved.
+func (l *Log) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	e.User = l.user
+	e.Cluster = l.cluster
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit log entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write audit log entry: %w", err)
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLog_Record(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "audit.log")
+	l, err := Open(path, "foobar")
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if fi, err := os.Stat(path); err != nil {
+		t.Fatalf("audit log not created: %v", err)
+	} else if fi.Mode().Perm() != 0600 {
+		t.Errorf("audit log mode = %v, want 0600", fi.Mode().Perm())
+	}
+
+	ts := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e := Entry{Time: ts, OnBehalfOf: "alice", Service: "SMD", Method: "DELETE", URI: "https://foobar/hsm/v2/State/Components", Status: 200}
+			if err := l.Record(e); err != nil {
+				t.Errorf("Record() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var n int
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		n++
+		var e Entry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatalf("line %d is not a JSON entry: %v", n, err)
+		}
+		if e.Cluster != "foobar" || e.User == "" || e.OnBehalfOf != "alice" || !e.Time.Equal(ts) {
+			t.Errorf("unexpected entry %+v", e)
+		}
+	}
+	if n != 10 {
+		t.Errorf("audit log has %d entries, want 10", n)
+	}
+}
@@ -4,10 +4,12 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/url"
 	"os"
 	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	kyaml "github.com/knadh/koanf/parsers/yaml"
@@ -47,6 +49,10 @@ var DefaultConfig = Config{
 	},
 }
 
+// DefaultDeleteThreshold is the number of items above which deletions require
+// the exact number of items to be confirmed if delete-threshold is not set.
+const DefaultDeleteThreshold = 50
+
 var (
 	GlobalConfig   = DefaultConfig // Global config struct
 	GlobalKoanf    *koanf.Koanf    // Koanf instance for gobal config struct
@@ -61,9 +67,100 @@ var (
 
 // Config represents the structure of a configuration file.
 type Config struct {
-	Log            ConfigLog       `yaml:"log,omitempty"`
-	DefaultCluster string          `yaml:"default-cluster,omitempty"`
-	Clusters       []ConfigCluster `yaml:"clusters,omitempty"`
+	Log             ConfigLog        `yaml:"log,omitempty"`
+	DefaultCluster  string           `yaml:"default-cluster,omitempty"`
+	ReadOnly        bool             `yaml:"read-only,omitempty"`
+	DeleteThreshold *int             `yaml:"delete-threshold,omitempty"`
+	MaxImpact       *int             `yaml:"max-impact-percent,omitempty"`
+	Pager           *bool            `yaml:"pager,omitempty"`
+	AuditLog        string           `yaml:"audit-log,omitempty"`
+	MaxMemoryBuffer string           `yaml:"max-memory-buffer,omitempty"`
+	UsageStats      ConfigUsageStats `yaml:"usage-stats,omitempty"`
+	Encryption      ConfigEncryption `yaml:"encryption,omitempty"`
+	Clusters        []ConfigCluster  `yaml:"clusters,omitempty"`
+}
+
+// GetDeleteThreshold returns the number of items above which deletions require
+// the exact number of items to be confirmed, which is DefaultDeleteThreshold if
+// delete-threshold is not set. A threshold of 0 disables the check.
+func (c Config) GetDeleteThreshold() int {
+	if c.DeleteThreshold == nil {
+		return DefaultDeleteThreshold
+	}
+
+	return *c.DeleteThreshold
+}
+
+// GetMaxImpact returns the percentage of the items in SMD above which
+// deleting or powering off items requires --allow-major-impact, which is
+// max-impact-percent of cluster if it is set and otherwise that of c. A value of
+// 0 (the default) disables the check. An error is returned if the percentage
+// is not between 0 and 100.
+func (c Config) GetMaxImpact(cluster ConfigClusterConfig) (int, error) {
+	maxImpact := c.MaxImpact
+	if cluster.MaxImpact != nil {
+		maxImpact = cluster.MaxImpact
+	}
+	if maxImpact == nil {
+		return 0, nil
+	}
+	if *maxImpact < 0 || *maxImpact > 100 {
+		return 0, fmt.Errorf("invalid max-impact-percent %d: must be between 0 and 100", *maxImpact)
+	}
+
+	return *maxImpact, nil
+}
+
+// PagerEnabled returns whether long output printed to a terminal should be
+// paged, which is the case unless pager is set to false.
+func (c Config) PagerEnabled() bool {
+	return c.Pager == nil || *c.Pager
+}
+
+// GetMaxMemoryBuffer returns max-memory-buffer in bytes (see ParseByteSize), or
+// 0 if it is not set.
+func (c Config) GetMaxMemoryBuffer() (int64, error) {
+	if c.MaxMemoryBuffer == "" {
+		return 0, nil
+	}
+	n, err := ParseByteSize(c.MaxMemoryBuffer)
+	if err != nil {
+		return 0, fmt.Errorf("invalid max-memory-buffer: %w", err)
+	}
+
+	return n, nil
+}
+
+// ParseByteSize parses a positive size in bytes with an optional unit suffix,
+// either decimal (k, KB, M, MB, G, GB) or binary (Ki, KiB, Mi, MiB, Gi, GiB),
+// e.g. 512MiB or 2G. Units are case insensitive and a bare B is bytes.
+func ParseByteSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"kib", 1 << 10}, {"mib", 1 << 20}, {"gib", 1 << 30},
+		{"ki", 1 << 10}, {"mi", 1 << 20}, {"gi", 1 << 30},
+		{"kb", 1e3}, {"mb", 1e6}, {"gb", 1e9},
+		{"k", 1e3}, {"m", 1e6}, {"g", 1e9},
+		{"b", 1},
+	}
+	num, mult := strings.ToLower(strings.TrimSpace(s)), int64(1)
+	for _, u := range units {
+		if n, ok := strings.CutSuffix(num, u.suffix); ok {
+			num, mult = strings.TrimSpace(n), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid size %q: must be a positive integer with an optional unit (e.g. 512MiB)", s)
+	}
+	if n > math.MaxInt64/mult {
+		return 0, fmt.Errorf("invalid size %q: too large", s)
+	}
+
+	return n * mult, nil
 }
 
 // GetCluster searches for a cluster by name and returns it if it exists in the
@@ -82,6 +179,16 @@ type ConfigLog struct {
 	Level  string `yaml:"level,omitempty"`
 }
 
+// ConfigUsageStats represents configuration for collecting usage statistics of
+// commands, which is enabled if File (a JSON lines file) and/or StatsD (a
+// host:port StatsD endpoint) is set. Prefix is the prefix of StatsD metric
+// names.
+type ConfigUsageStats struct {
+	File   string `yaml:"file,omitempty"`
+	StatsD string `yaml:"statsd,omitempty"`
+	Prefix string `yaml:"prefix,omitempty"`
+}
+
 // ConfigCluster is a "wrapper" around an individual cluster configuration. It
 // contains the cluster's name, as well as the actual configuration structure.
 type ConfigCluster struct {
@@ -92,12 +199,27 @@ type ConfigCluster struct {
 // ConfigClusterConfig is the actual structure for an individual cluster
 // configuration.
 type ConfigClusterConfig struct {
-	URI        string                 `yaml:"uri,omitempty"`
-	BSS        ConfigClusterBSS       `yaml:"bss,omitempty"`
-	CloudInit  ConfigClusterCloudInit `yaml:"cloud-init,omitempty"`
-	PCS        ConfigClusterPCS       `yaml:"pcs,omitempty"`
-	SMD        ConfigClusterSMD       `yaml:"smd,omitempty"`
-	EnableAuth bool                   `yaml:"enable-auth"`
+	URI                 string                 `yaml:"uri,omitempty"`
+	PathTemplate        string                 `yaml:"path-template,omitempty"`
+	BSS                 ConfigClusterBSS       `yaml:"bss,omitempty"`
+	CloudInit           ConfigClusterCloudInit `yaml:"cloud-init,omitempty"`
+	Discovery           ConfigClusterDiscovery `yaml:"discovery,omitempty"`
+	PCS                 ConfigClusterPCS       `yaml:"pcs,omitempty"`
+	SMD                 ConfigClusterSMD       `yaml:"smd,omitempty"`
+	Grafana             ConfigClusterGrafana   `yaml:"grafana,omitempty"`
+	Notify              ConfigClusterNotify    `yaml:"notify,omitempty"`
+	Secrets             ConfigClusterSecrets   `yaml:"secrets,omitempty"`
+	SSH                 ConfigClusterSSH       `yaml:"ssh,omitempty"`
+	EnableAuth          bool                   `yaml:"enable-auth"`
+	ReadOnly            bool                   `yaml:"read-only,omitempty"`
+	MaxImpact           *int                   `yaml:"max-impact-percent,omitempty"`
+	Policy              ConfigClusterPolicy    `yaml:"policy,omitempty"`
+	LocationsFile       string                 `yaml:"locations-file,omitempty"`
+	Locations           map[string]string      `yaml:"locations,omitempty"`
+	VarsFile            string                 `yaml:"vars-file,omitempty"`
+	ImpersonationHeader string                 `yaml:"impersonation-header,omitempty"`
+	AccessToken         string                 `yaml:"access-token,omitempty"`
+	TokenCommand        string                 `yaml:"token-command,omitempty"`
 }
 
 // UnmarshalYAML unmarshals YAML into a ConfigClusterConfig, handling default
@@ -155,27 +277,150 @@ func (c *ConfigClusterConfig) UnmarshalYAML(value *yaml.Node) error {
 }
 
 // ConfigClusterBSS represents configuration specifically for the Boot Script
-// Service.
+// Service. LintRules is the path to a file of site rules that boot parameters
+// are linted against (see bss.ReadLintRules).
 type ConfigClusterBSS struct {
-	URI string `yaml:"uri,omitempty"`
+	URI       string            `yaml:"uri,omitempty"`
+	Paths     map[string]string `yaml:"paths,omitempty"`
+	LintRules string            `yaml:"lint-rules,omitempty"`
 }
 
 // ConfigClusterCloudInit represents configuration specifically for the
 // cloud-init service.
 type ConfigClusterCloudInit struct {
-	URI string `yaml:"uri,omitempty"`
+	URI   string            `yaml:"uri,omitempty"`
+	Paths map[string]string `yaml:"paths,omitempty"`
+}
+
+// ConfigClusterDiscovery represents configuration for static discovery into
+// the cluster. Nodes are added to each of DefaultGroups and to the group of
+// each rule in AutoGroups that matches them (see discover.ParseAutoGroupRule).
+// BMCUsername and BMCPassword are the credentials stored in SMD for the BMCs
+// discovered. BMCCredsPath is where the credentials are written in Vault when
+// they are pushed to it.
+type ConfigClusterDiscovery struct {
+	DefaultGroups []string `yaml:"default-groups,omitempty"`
+	AutoGroups    []string `yaml:"auto-groups,omitempty"`
+	BMCUsername   string   `yaml:"bmc-username,omitempty"`
+	BMCPassword   string   `yaml:"bmc-password,omitempty"`
+	BMCCredsPath  string   `yaml:"bmc-creds-path,omitempty"`
 }
 
 // ConfigClusterPCS represents configuration specifically for the Power Control
 // Service.
 type ConfigClusterPCS struct {
-	URI string `yaml:"uri,omitempty"`
+	URI   string            `yaml:"uri,omitempty"`
+	Paths map[string]string `yaml:"paths,omitempty"`
 }
 
 // ConfigClusterSMD represents configuration specifically for the State
 // Management Database service.
 type ConfigClusterSMD struct {
-	URI string `yaml:"uri,omitempty"`
+	URI   string            `yaml:"uri,omitempty"`
+	Paths map[string]string `yaml:"paths,omitempty"`
+}
+
+// ConfigClusterGrafana represents configuration for pushing annotations to
+// Grafana for a cluster. Annotations are only pushed if URI is set.
+type ConfigClusterGrafana struct {
+	URI          string   `yaml:"uri,omitempty"`
+	DashboardUID string   `yaml:"dashboard-uid,omitempty"`
+	Tags         []string `yaml:"tags,omitempty"`
+	Token        string   `yaml:"token,omitempty"`
+}
+
+// ConfigClusterNotify represents configuration for notifying operators when
+// long-running commands run against a cluster, such as node reimage or pcs
+// power off, finish or fail. A notification is sent to each of Sinks.
+type ConfigClusterNotify struct {
+	Sinks []ConfigNotifySink `yaml:"sinks,omitempty"`
+}
+
+// ConfigNotifySink represents a destination of notifications (see
+// notify.Sink). Type is webhook, slack, or email. URL is where webhook and
+// slack sinks POST to. Email sinks send mail from From to To with the sendmail
+// program Sendmail.
+type ConfigNotifySink struct {
+	Type     string   `yaml:"type,omitempty"`
+	URL      string   `yaml:"url,omitempty"`
+	To       []string `yaml:"to,omitempty"`
+	From     string   `yaml:"from,omitempty"`
+	Sendmail string   `yaml:"sendmail,omitempty"`
+}
+
+// ConfigClusterSecrets represents configuration for the external secret stores
+// that secret references in cloud-init templates are resolved from.
+type ConfigClusterSecrets struct {
+	VaultURI   string `yaml:"vault-uri,omitempty"`
+	VaultToken string `yaml:"vault-token,omitempty"`
+}
+
+// ConfigClusterSSH represents how to connect to the nodes of the cluster with
+// SSH, e.g. to verify what cloud-init delivered to them. Unset options are left
+// to the SSH configuration of the user. Options are passed to ssh with -o.
+type ConfigClusterSSH struct {
+	User         string   `yaml:"user,omitempty"`
+	Port         int      `yaml:"port,omitempty"`
+	IdentityFile string   `yaml:"identity-file,omitempty"`
+	Options      []string `yaml:"options,omitempty"`
+}
+
+// ConfigClusterPolicy restricts which commands may be run against a cluster, so
+// that commands meant for one cluster cannot be run against another by using
+// the wrong cluster. Allow and Deny are lists of command patterns (see
+// PolicyPatternMatches). If Allow is not empty, only the commands matching one
+// of its patterns may be run, and commands matching any pattern in Deny may not
+// be run.
+type ConfigClusterPolicy struct {
+	Allow []string `yaml:"allow,omitempty"`
+	Deny  []string `yaml:"deny,omitempty"`
+}
+
+// Allows returns whether the policy allows command, the path of a command
+// without the program name (e.g. "smd component get"). If not, the reason is
+// returned as well.
+func (p ConfigClusterPolicy) Allows(command string) (bool, string) {
+	for _, pattern := range p.Deny {
+		if PolicyPatternMatches(pattern, command) {
+			return false, fmt.Sprintf("denied by policy pattern %q", pattern)
+		}
+	}
+	if len(p.Allow) == 0 {
+		return true, ""
+	}
+	for _, pattern := range p.Allow {
+		if PolicyPatternMatches(pattern, command) {
+			return true, ""
+		}
+	}
+
+	return false, fmt.Sprintf("not matched by any allowed policy pattern %q", p.Allow)
+}
+
+// PolicyPatternMatches returns whether pattern, a space-separated list of
+// command words in which * matches any single word, matches command. A pattern
+// matches if its words appear consecutively anywhere in command, so that "get"
+// matches every get command (e.g. "smd component get" and "cloud-init group get
+// config") and "smd component" matches every command under it.
+func PolicyPatternMatches(pattern, command string) bool {
+	pw, cw := strings.Fields(pattern), strings.Fields(command)
+	if len(pw) == 0 {
+		return false
+	}
+	for start := 0; start+len(pw) <= len(cw); start++ {
+		match := true
+		for i, w := range pw {
+			if w != "*" && w != cw[start+i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+
+	return false
 }
 
 // MergeURIConfig takes a ConfigClusterConfig and returns a ConfigClusterConfig
@@ -189,29 +434,66 @@ func (ccc *ConfigClusterConfig) MergeURIConfig(c ConfigClusterConfig) ConfigClus
 		}
 		return oldStr
 	}
-	newCCC := ConfigClusterConfig{URI: compare(ccc.URI, c.URI)}
-	if ccc.BSS == (ConfigClusterBSS{}) {
-		newCCC.BSS = ConfigClusterBSS{URI: c.BSS.URI}
-	} else {
-		newCCC.BSS.URI = compare(ccc.BSS.URI, c.BSS.URI)
+	newCCC := ConfigClusterConfig{
+		URI:          compare(ccc.URI, c.URI),
+		PathTemplate: compare(ccc.PathTemplate, c.PathTemplate),
 	}
-	if ccc.CloudInit == (ConfigClusterCloudInit{}) {
-		newCCC.CloudInit = ConfigClusterCloudInit{URI: c.CloudInit.URI}
-	} else {
-		newCCC.CloudInit.URI = compare(ccc.CloudInit.URI, c.CloudInit.URI)
+	newCCC.BSS = ConfigClusterBSS{URI: compare(ccc.BSS.URI, c.BSS.URI), Paths: mergePaths(ccc.BSS.Paths, c.BSS.Paths)}
+	newCCC.CloudInit = ConfigClusterCloudInit{URI: compare(ccc.CloudInit.URI, c.CloudInit.URI), Paths: mergePaths(ccc.CloudInit.Paths, c.CloudInit.Paths)}
+	newCCC.PCS = ConfigClusterPCS{URI: compare(ccc.PCS.URI, c.PCS.URI), Paths: mergePaths(ccc.PCS.Paths, c.PCS.Paths)}
+	newCCC.SMD = ConfigClusterSMD{URI: compare(ccc.SMD.URI, c.SMD.URI), Paths: mergePaths(ccc.SMD.Paths, c.SMD.Paths)}
+
+	return newCCC
+}
+
+// mergePaths returns the union of oldPaths and newPaths, with values in newPaths
+// taking precedence. If both are empty, nil is returned.
+func mergePaths(oldPaths, newPaths map[string]string) map[string]string {
+	if len(oldPaths) == 0 && len(newPaths) == 0 {
+		return nil
 	}
-	if ccc.PCS == (ConfigClusterPCS{}) {
-		newCCC.PCS = ConfigClusterPCS{URI: c.PCS.URI}
-	} else {
-		newCCC.PCS.URI = compare(ccc.PCS.URI, c.PCS.URI)
+	merged := make(map[string]string, len(oldPaths)+len(newPaths))
+	for k, v := range oldPaths {
+		merged[k] = v
 	}
-	if ccc.SMD == (ConfigClusterSMD{}) {
-		newCCC.SMD = ConfigClusterSMD{URI: c.SMD.URI}
-	} else {
-		newCCC.SMD.URI = compare(ccc.SMD.URI, c.SMD.URI)
+	for k, v := range newPaths {
+		merged[k] = v
 	}
+	return merged
+}
 
-	return newCCC
+// ExpandPathTemplate replaces the placeholders in the service URI or path
+// template tmpl with values for the service svcName. The supported
+// placeholders are:
+//
+//	{service} - the service name (e.g. "smd")
+//	{base}    - the default base path of the service (e.g. "/hsm/v2")
+//
+// For instance, "/apis/{service}{base}" becomes "/apis/smd/hsm/v2" for SMD.
+func ExpandPathTemplate(tmpl string, svcName ServiceName, defaultBase string) string {
+	return strings.NewReplacer(
+		"{service}", string(svcName),
+		"{base}", strings.TrimSuffix(defaultBase, "/"),
+	).Replace(tmpl)
+}
+
+// GetServicePaths returns the endpoint path overrides configured for the
+// service identified by svcName. Keys are the default endpoint paths used by
+// the client (e.g. "/State/Components") and values are the paths to use
+// instead. If svcName is unknown, an ErrUnknownService is returned.
+func (ccc *ConfigClusterConfig) GetServicePaths(svcName ServiceName) (map[string]string, error) {
+	switch svcName {
+	case ServiceBSS:
+		return ccc.BSS.Paths, nil
+	case ServiceCloudInit:
+		return ccc.CloudInit.Paths, nil
+	case ServicePCS:
+		return ccc.PCS.Paths, nil
+	case ServiceSMD:
+		return ccc.SMD.Paths, nil
+	default:
+		return nil, ErrUnknownService{Service: string(svcName)}
+	}
 }
 
 // GetServiceBaseURI returns a URI string for the service identified by svcName
@@ -222,7 +504,10 @@ func (ccc *ConfigClusterConfig) MergeURIConfig(c ConfigClusterConfig) ConfigClus
 // ErrInvalidURI or ErrInvalidServiceURI is returned, respectively.
 //
 // The cluster URI must be an absolute URI: proto://host[:port][/path]
-// The service URI can be a relative path (/path) or an absolute URI.
+// The service URI can be a relative path (/path) or an absolute URI. If the
+// service URI is not set and the cluster has a path template, the template is
+// used as the service's relative path. Placeholders in either are expanded
+// with ExpandPathTemplate.
 func (ccc *ConfigClusterConfig) GetServiceBaseURI(svcName ServiceName) (string, error) {
 	var (
 		serviceBaseURI string
@@ -243,48 +528,35 @@ func (ccc *ConfigClusterConfig) GetServiceBaseURI(svcName ServiceName) (string,
 
 	// Parse service URI for ConfigClusterConfig field based on passed
 	// ServiceName.
-	var svcURI *url.URL
-	var err error
+	var (
+		svcURIStr   string
+		defaultBase string
+	)
 	switch svcName {
 	case ServiceBSS:
-		if ccc.URI == "" && ccc.BSS.URI == "" {
-			return "", ErrMissingURI{Service: svcName}
-		}
-		if ccc.BSS.URI != "" {
-			svcURI, err = url.Parse(ccc.BSS.URI)
-		} else {
-			svcURI, err = url.Parse(DefaultBasePathBSS)
-		}
+		svcURIStr, defaultBase = ccc.BSS.URI, DefaultBasePathBSS
 	case ServiceCloudInit:
-		if ccc.URI == "" && ccc.CloudInit.URI == "" {
-			return "", ErrMissingURI{Service: svcName}
-		}
-		if ccc.CloudInit.URI != "" {
-			svcURI, err = url.Parse(ccc.CloudInit.URI)
-		} else {
-			svcURI, err = url.Parse(DefaultBasePathCloudInit)
-		}
+		svcURIStr, defaultBase = ccc.CloudInit.URI, DefaultBasePathCloudInit
 	case ServicePCS:
-		if ccc.URI == "" && ccc.PCS.URI == "" {
-			return "", ErrMissingURI{Service: svcName}
-		}
-		if ccc.PCS.URI != "" {
-			svcURI, err = url.Parse(ccc.PCS.URI)
-		} else {
-			svcURI, err = url.Parse(DefaultBasePathPCS)
-		}
+		svcURIStr, defaultBase = ccc.PCS.URI, DefaultBasePathPCS
 	case ServiceSMD:
-		if ccc.URI == "" && ccc.SMD.URI == "" {
-			return "", ErrMissingURI{Service: svcName}
-		}
-		if ccc.SMD.URI != "" {
-			svcURI, err = url.Parse(ccc.SMD.URI)
-		} else {
-			svcURI, err = url.Parse(DefaultBasePathSMD)
-		}
+		svcURIStr, defaultBase = ccc.SMD.URI, DefaultBasePathSMD
 	default:
 		return "", ErrUnknownService{Service: string(svcName)}
 	}
+	if ccc.URI == "" && svcURIStr == "" {
+		return "", ErrMissingURI{Service: svcName}
+	}
+	if svcURIStr == "" {
+		// Use the cluster's path template if set, otherwise the
+		// service's default base path.
+		if ccc.PathTemplate != "" {
+			svcURIStr = ccc.PathTemplate
+		} else {
+			svcURIStr = defaultBase
+		}
+	}
+	svcURI, err := url.Parse(ExpandPathTemplate(svcURIStr, svcName, defaultBase))
 	if err != nil {
 		return "", ErrInvalidServiceURI{Service: svcName, Err: err}
 	}
@@ -491,11 +491,12 @@ func TestConfigClusterConfig_MergeURIConfig(t *testing.T) {
 
 func TestConfigClusterConfig_GetServiceBaseURI(t *testing.T) {
 	type fields struct {
-		URI       string
-		BSS       ConfigClusterBSS
-		CloudInit ConfigClusterCloudInit
-		PCS       ConfigClusterPCS
-		SMD       ConfigClusterSMD
+		URI          string
+		PathTemplate string
+		BSS          ConfigClusterBSS
+		CloudInit    ConfigClusterCloudInit
+		PCS          ConfigClusterPCS
+		SMD          ConfigClusterSMD
 	}
 	type args struct {
 		svcName ServiceName
@@ -668,15 +669,55 @@ func TestConfigClusterConfig_GetServiceBaseURI(t *testing.T) {
 			want:    "",
 			wantErr: true,
 		},
+		{
+			name: "cluster path template",
+			fields: fields{
+				URI:          "https://cluster.local",
+				PathTemplate: "/apis/{service}{base}",
+			},
+			args: args{
+				svcName: ServiceSMD,
+			},
+			want:    "https://cluster.local/apis/smd/hsm/v2",
+			wantErr: false,
+		},
+		{
+			name: "cluster path template with root base path",
+			fields: fields{
+				URI:          "https://cluster.local",
+				PathTemplate: "/apis/{service}{base}",
+			},
+			args: args{
+				svcName: ServicePCS,
+			},
+			want:    "https://cluster.local/apis/pcs",
+			wantErr: false,
+		},
+		{
+			name: "service URI overrides path template",
+			fields: fields{
+				URI:          "https://cluster.local",
+				PathTemplate: "/apis/{service}{base}",
+				BSS: ConfigClusterBSS{
+					URI: "/gateway/{service}",
+				},
+			},
+			args: args{
+				svcName: ServiceBSS,
+			},
+			want:    "https://cluster.local/gateway/bss",
+			wantErr: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			ccc := &ConfigClusterConfig{
-				URI:       tt.fields.URI,
-				BSS:       tt.fields.BSS,
-				CloudInit: tt.fields.CloudInit,
-				PCS:       tt.fields.PCS,
-				SMD:       tt.fields.SMD,
+				URI:          tt.fields.URI,
+				PathTemplate: tt.fields.PathTemplate,
+				BSS:          tt.fields.BSS,
+				CloudInit:    tt.fields.CloudInit,
+				PCS:          tt.fields.PCS,
+				SMD:          tt.fields.SMD,
 			}
 			got, err := ccc.GetServiceBaseURI(tt.args.svcName)
 			if (err != nil) != tt.wantErr {
@@ -2033,3 +2074,133 @@ func TestWriteConfig(t *testing.T) {
 		}
 	})
 }
+
+func TestConfigClusterConfig_GetServicePaths(t *testing.T) {
+	ccc := ConfigClusterConfig{
+		SMD: ConfigClusterSMD{
+			Paths: map[string]string{"/State/Components": "/components"},
+		},
+	}
+	got, err := ccc.GetServicePaths(ServiceSMD)
+	if err != nil {
+		t.Fatalf("GetServicePaths() unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, ccc.SMD.Paths) {
+		t.Errorf("GetServicePaths() = %v, want %v", got, ccc.SMD.Paths)
+	}
+	if got, _ := ccc.GetServicePaths(ServiceBSS); got != nil {
+		t.Errorf("GetServicePaths(bss) = %v, want nil", got)
+	}
+	if _, err := ccc.GetServicePaths(ServiceName("unknown")); err == nil {
+		t.Errorf("GetServicePaths(unknown) expected error")
+	}
+}
+
+func TestConfig_GetDeleteThreshold(t *testing.T) {
+	zero, ten := 0, 10
+	tests := []struct {
+		name string
+		cfg  Config
+		want int
+	}{
+		{name: "unset", cfg: Config{}, want: DefaultDeleteThreshold},
+		{name: "disabled", cfg: Config{DeleteThreshold: &zero}, want: 0},
+		{name: "set", cfg: Config{DeleteThreshold: &ten}, want: 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cfg.GetDeleteThreshold(); got != tt.want {
+				t.Errorf("GetDeleteThreshold() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_GetMaxImpact(t *testing.T) {
+	zero, ten, twenty, invalid := 0, 10, 20, 101
+	tests := []struct {
+		name    string
+		cfg     Config
+		cluster ConfigClusterConfig
+		want    int
+		wantErr bool
+	}{
+		{name: "unset", cfg: Config{}, want: 0},
+		{name: "global", cfg: Config{MaxImpact: &ten}, want: 10},
+		{name: "cluster", cfg: Config{}, cluster: ConfigClusterConfig{MaxImpact: &twenty}, want: 20},
+		{name: "cluster overrides global", cfg: Config{MaxImpact: &ten}, cluster: ConfigClusterConfig{MaxImpact: &zero}, want: 0},
+		{name: "invalid", cfg: Config{MaxImpact: &invalid}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cfg.GetMaxImpact(tt.cluster)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetMaxImpact() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("GetMaxImpact() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    int64
+		wantErr bool
+	}{
+		{s: "1024", want: 1024},
+		{s: "512MiB", want: 512 << 20},
+		{s: "2Gi", want: 2 << 30},
+		{s: "64 kb", want: 64000},
+		{s: "3G", want: 3e9},
+		{s: "100B", want: 100},
+		{s: "", wantErr: true},
+		{s: "0", wantErr: true},
+		{s: "-1MiB", wantErr: true},
+		{s: "1.5GiB", wantErr: true},
+		{s: "12XB", wantErr: true},
+		{s: "99999999999GiB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseByteSize(tt.s)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("ParseByteSize(%q) = %d, %v, want %d (error: %v)", tt.s, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestConfigClusterPolicy_Allows(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  ConfigClusterPolicy
+		command string
+		want    bool
+	}{
+		{name: "empty policy", policy: ConfigClusterPolicy{}, command: "smd component delete", want: true},
+		{name: "allowed verb", policy: ConfigClusterPolicy{Allow: []string{"get", "watch"}}, command: "smd component get", want: true},
+		{name: "allowed verb not last", policy: ConfigClusterPolicy{Allow: []string{"get", "watch"}}, command: "cloud-init group get config", want: true},
+		{name: "verb not allowed", policy: ConfigClusterPolicy{Allow: []string{"get", "watch"}}, command: "smd component delete", want: false},
+		{name: "allowed subtree", policy: ConfigClusterPolicy{Allow: []string{"smd component"}}, command: "smd component add", want: true},
+		{name: "wildcard", policy: ConfigClusterPolicy{Allow: []string{"bss * get"}}, command: "bss bootparams get", want: true},
+		{name: "wildcard too short", policy: ConfigClusterPolicy{Allow: []string{"bss * get"}}, command: "bss get", want: false},
+		{name: "denied", policy: ConfigClusterPolicy{Deny: []string{"delete"}}, command: "smd group delete", want: false},
+		{name: "deny overrides allow", policy: ConfigClusterPolicy{Allow: []string{"smd"}, Deny: []string{"smd * delete"}}, command: "smd group delete", want: false},
+		{name: "partial word does not match", policy: ConfigClusterPolicy{Allow: []string{"get"}}, command: "smd component getter", want: false},
+		{name: "empty pattern matches nothing", policy: ConfigClusterPolicy{Allow: []string{""}}, command: "smd component get", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := tt.policy.Allows(tt.command)
+			if got != tt.want {
+				t.Errorf("Allows(%q) = %v (%s), want %v", tt.command, got, reason, tt.want)
+			}
+			if !got && reason == "" {
+				t.Errorf("Allows(%q) returned no reason for refusing", tt.command)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Methods for encrypting sensitive config values at rest, set as
+// encryption.method.
+const (
+	EncryptionAge     = "age"
+	EncryptionKeyring = "keyring"
+)
+
+// KeyringService is the service under which sensitive config values are stored
+// in the OS keyring.
+const KeyringService = "ochami"
+
+// Prefixes of encrypted config values. An age-encrypted value is the base64
+// encoding of its ciphertext. A value stored in the OS keyring is replaced by
+// the name of its keyring entry so that the value stays out of the file.
+const (
+	agePrefix     = "age:"
+	keyringPrefix = "keyring:"
+)
+
+// ConfigEncryption represents configuration for encrypting the sensitive values
+// of the config (see SecretFields). Method is age or keyring. With age, values
+// are encrypted to AgeRecipients and decrypted with the identity file
+// AgeIdentity.
+type ConfigEncryption struct {
+	Method        string   `yaml:"method,omitempty"`
+	AgeRecipients []string `yaml:"age-recipients,omitempty"`
+	AgeIdentity   string   `yaml:"age-identity,omitempty"`
+}
+
+// SecretField is a sensitive value of a config, such as an access token or
+// password. Name identifies it in the config (e.g.
+// clusters.foobar.access-token) and Value points to it.
+type SecretField struct {
+	Name  string
+	Value *string
+}
+
+// SecretFields returns the sensitive values that are set in c.
+func (c *Config) SecretFields() []SecretField {
+	var fields []SecretField
+	add := func(name string, v *string) {
+		if *v != "" {
+			fields = append(fields, SecretField{Name: name, Value: v})
+		}
+	}
+	for i := range c.Clusters {
+		cl := &c.Clusters[i].Cluster
+		prefix := "clusters." + c.Clusters[i].Name + "."
+		add(prefix+"access-token", &cl.AccessToken)
+		add(prefix+"discovery.bmc-password", &cl.Discovery.BMCPassword)
+		add(prefix+"grafana.token", &cl.Grafana.Token)
+		add(prefix+"secrets.vault-token", &cl.Secrets.VaultToken)
+		for j := range cl.Notify.Sinks {
+			add(fmt.Sprintf("%snotify.sinks.%d.url", prefix, j), &cl.Notify.Sinks[j].URL)
+		}
+	}
+
+	return fields
+}
+
+// IsEncrypted returns whether the config value v is encrypted.
+func IsEncrypted(v string) bool {
+	return strings.HasPrefix(v, agePrefix) || strings.HasPrefix(v, keyringPrefix)
+}
+
+// runCommand runs the program name with args, passing it stdin, and returns its
+// standard output. It is a variable so that tests need not have age or a
+// keyring available.
+var runCommand = func(stdin []byte, name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	c := exec.Command(name, args...)
+	c.Stdin = bytes.NewReader(stdin)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%s was not found in PATH: %w", name, err)
+		}
+		return nil, fmt.Errorf("%s failed: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+// EncryptValue encrypts the sensitive config value named name with the method
+// set in enc, returning what to store in the config instead. Values already
+// encrypted are returned as is.
+func EncryptValue(enc ConfigEncryption, name, value string) (string, error) {
+	if IsEncrypted(value) {
+		return value, nil
+	}
+	switch enc.Method {
+	case EncryptionAge:
+		if len(enc.AgeRecipients) == 0 {
+			return "", fmt.Errorf("encryption.age-recipients must be set to encrypt with age")
+		}
+		var args []string
+		for _, r := range enc.AgeRecipients {
+			args = append(args, "-r", r)
+		}
+		out, err := runCommand([]byte(value), "age", append([]string{"-e"}, args...)...)
+		if err != nil {
+			return "", fmt.Errorf("failed to encrypt %s: %w", name, err)
+		}
+		return agePrefix + base64.StdEncoding.EncodeToString(out), nil
+	case EncryptionKeyring:
+		if err := keyringSet(name, value); err != nil {
+			return "", fmt.Errorf("failed to store %s in keyring: %w", name, err)
+		}
+		return keyringPrefix + name, nil
+	case "":
+		return "", fmt.Errorf("encryption.method must be set (to %s or %s) to encrypt config values", EncryptionAge, EncryptionKeyring)
+	default:
+		return "", fmt.Errorf("unknown encryption.method %q (expected %s or %s)", enc.Method, EncryptionAge, EncryptionKeyring)
+	}
+}
+
+// DecryptValue returns the plaintext of the config value v, which was either
+// encrypted by EncryptValue or is returned as is. The method is determined by
+// v, so enc is only needed for the age identity.
+func DecryptValue(enc ConfigEncryption, v string) (string, error) {
+	if s, ok := strings.CutPrefix(v, agePrefix); ok {
+		if enc.AgeIdentity == "" {
+			return "", fmt.Errorf("encryption.age-identity must be set to decrypt age-encrypted config values")
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return "", fmt.Errorf("invalid age-encrypted value: %w", err)
+		}
+		out, err := runCommand(ciphertext, "age", "-d", "-i", enc.AgeIdentity)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt value: %w", err)
+		}
+		return string(out), nil
+	}
+	if name, ok := strings.CutPrefix(v, keyringPrefix); ok {
+		value, err := keyringGet(name)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s from keyring: %w", name, err)
+		}
+		return value, nil
+	}
+
+	return v, nil
+}
+
+// EncryptConfig encrypts the sensitive values of cfg that are not encrypted yet
+// with the method set in enc, returning how many were encrypted.
+func EncryptConfig(cfg *Config, enc ConfigEncryption) (int, error) {
+	n := 0
+	for _, f := range cfg.SecretFields() {
+		if IsEncrypted(*f.Value) {
+			continue
+		}
+		v, err := EncryptValue(enc, f.Name, *f.Value)
+		if err != nil {
+			return n, err
+		}
+		*f.Value = v
+		n++
+	}
+
+	return n, nil
+}
+
+// DecryptConfig replaces the encrypted sensitive values of cfg with their
+// plaintext, returning how many were decrypted. Entries in the OS keyring are
+// left in place.
+func DecryptConfig(cfg *Config, enc ConfigEncryption) (int, error) {
+	n := 0
+	for _, f := range cfg.SecretFields() {
+		if !IsEncrypted(*f.Value) {
+			continue
+		}
+		v, err := DecryptValue(enc, *f.Value)
+		if err != nil {
+			return n, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		*f.Value = v
+		n++
+	}
+
+	return n, nil
+}
+
+// keyringSet stores value in the OS keyring as the entry name under
+// KeyringService, using security(1) on macOS and secret-tool(1) (libsecret)
+// elsewhere. The value is passed on standard input, never as an argument,
+// so that it does not show up in the process list.
+func keyringSet(name, value string) error {
+	var err error
+	if runtime.GOOS == "darwin" {
+		// With -w last, security prompts for the password and
+		// then to retype it, reading a line each time
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("value of %s contains a newline, which the macOS keyring cannot read", name)
+		}
+		_, err = runCommand([]byte(value+"\n"+value+"\n"), "security", "add-generic-password", "-U", "-s", KeyringService, "-a", name, "-w")
+	} else {
+		_, err = runCommand([]byte(value), "secret-tool", "store", "--label", KeyringService+" "+name, "service", KeyringService, "account", name)
+	}
+
+	return err
+}
+
+// keyringGet returns the value of the OS keyring entry name under
+// KeyringService.
+func keyringGet(name string) (string, error) {
+	var (
+		out []byte
+		err error
+	)
+	if runtime.GOOS == "darwin" {
+		out, err = runCommand(nil, "security", "find-generic-password", "-s", KeyringService, "-a", name, "-w")
+	} else {
+		out, err = runCommand(nil, "secret-tool", "lookup", "service", KeyringService, "account", name)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(out), "\n"), nil
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeCommands replaces runCommand with fakes of age ("encrypting" by
+// reversing), secret-tool, and security backed by an in-memory keyring,
+// restoring it when the test ends.
+func fakeCommands(t *testing.T) {
+	keyring := make(map[string]string)
+	orig := runCommand
+	t.Cleanup(func() { runCommand = orig })
+	arg := func(args []string, flag string) string {
+		i := slices.Index(args, flag)
+		if i < 0 || i+1 >= len(args) {
+			return ""
+		}
+		return args[i+1]
+	}
+	runCommand = func(stdin []byte, name string, args ...string) ([]byte, error) {
+		switch {
+		case name == "age" && args[0] == "-e":
+			out := slices.Clone(stdin)
+			slices.Reverse(out)
+			return append([]byte("AGE"), out...), nil
+		case name == "age" && args[0] == "-d":
+			out, ok := bytes.CutPrefix(stdin, []byte("AGE"))
+			if !ok {
+				return nil, fmt.Errorf("age failed: no identity matched any of the recipients")
+			}
+			out = slices.Clone(out)
+			slices.Reverse(out)
+			return out, nil
+		case name == "secret-tool" && args[0] == "store":
+			keyring[arg(args, "account")] = string(stdin)
+			return nil, nil
+		case name == "secret-tool" && args[0] == "lookup":
+			v, ok := keyring[arg(args, "account")]
+			if !ok {
+				return nil, fmt.Errorf("secret-tool failed: exit status 1")
+			}
+			return []byte(v), nil
+		case name == "security" && args[0] == "add-generic-password":
+			if args[len(args)-1] != "-w" {
+				return nil, fmt.Errorf("security was passed the password as an argument")
+			}
+			pw, retyped, _ := strings.Cut(strings.TrimSuffix(string(stdin), "\n"), "\n")
+			if pw != retyped {
+				return nil, fmt.Errorf("security failed: passwords don't match")
+			}
+			keyring[arg(args, "-a")] = pw
+			return nil, nil
+		case name == "security" && args[0] == "find-generic-password":
+			v, ok := keyring[arg(args, "-a")]
+			if !ok {
+				return nil, fmt.Errorf("security failed: exit status 44")
+			}
+			return []byte(v + "\n"), nil
+		}
+		return nil, fmt.Errorf("unexpected command: %s %s", name, strings.Join(args, " "))
+	}
+}
+
+func testSecretConfig() Config {
+	cfg := Config{Clusters: []ConfigCluster{{Name: "foobar"}, {Name: "other"}}}
+	cfg.Clusters[0].Cluster.AccessToken = "eyJhbGciOi"
+	cfg.Clusters[0].Cluster.Grafana.Token = "glsa_123"
+	cfg.Clusters[1].Cluster.Discovery.BMCPassword = "hunter2"
+	cfg.Clusters[1].Cluster.Discovery.BMCUsername = "root"
+	cfg.Clusters[1].Cluster.Notify.Sinks = []ConfigNotifySink{
+		{Type: "email", To: []string{"ops@example.com"}},
+		{Type: "slack", URL: "https://hooks.slack.com/services/T000/B000/secret"},
+	}
+
+	return cfg
+}
+
+func TestSecretFields(t *testing.T) {
+	cfg := testSecretConfig()
+	var names []string
+	for _, f := range cfg.SecretFields() {
+		names = append(names, f.Name)
+	}
+	want := []string{"clusters.foobar.access-token", "clusters.foobar.grafana.token", "clusters.other.discovery.bmc-password", "clusters.other.notify.sinks.1.url"}
+	if !slices.Equal(names, want) {
+		t.Errorf("SecretFields() = %v, want %v", names, want)
+	}
+}
+
+func TestEncryptConfig(t *testing.T) {
+	tests := []struct {
+		name       string
+		enc        ConfigEncryption
+		wantPrefix string
+	}{
+		{
+			name:       "age",
+			enc:        ConfigEncryption{Method: EncryptionAge, AgeRecipients: []string{"age1xyz"}, AgeIdentity: "/keys.txt"},
+			wantPrefix: "age:",
+		},
+		{
+			name:       "keyring",
+			enc:        ConfigEncryption{Method: EncryptionKeyring},
+			wantPrefix: "keyring:clusters.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeCommands(t)
+			cfg := testSecretConfig()
+			n, err := EncryptConfig(&cfg, tt.enc)
+			if err != nil || n != 4 {
+				t.Fatalf("EncryptConfig() = %d, %v, want 4, nil", n, err)
+			}
+			for _, f := range cfg.SecretFields() {
+				if !strings.HasPrefix(*f.Value, tt.wantPrefix) || !IsEncrypted(*f.Value) {
+					t.Errorf("%s = %q, want encrypted with prefix %q", f.Name, *f.Value, tt.wantPrefix)
+				}
+			}
+			if cfg.Clusters[1].Cluster.Discovery.BMCUsername != "root" {
+				t.Errorf("bmc-username was changed to %q", cfg.Clusters[1].Cluster.Discovery.BMCUsername)
+			}
+
+			// Encrypting again changes nothing
+			if n, err := EncryptConfig(&cfg, tt.enc); err != nil || n != 0 {
+				t.Errorf("EncryptConfig() on encrypted config = %d, %v, want 0, nil", n, err)
+			}
+
+			if v, err := DecryptValue(tt.enc, cfg.Clusters[0].Cluster.AccessToken); err != nil || v != "eyJhbGciOi" {
+				t.Errorf("DecryptValue() = %q, %v", v, err)
+			}
+			n, err = DecryptConfig(&cfg, tt.enc)
+			if err != nil || n != 4 {
+				t.Fatalf("DecryptConfig() = %d, %v, want 4, nil", n, err)
+			}
+			if want := testSecretConfig(); !reflect.DeepEqual(cfg, want) {
+				t.Errorf("DecryptConfig() = %+v, want %+v", cfg, want)
+			}
+		})
+	}
+}
+
+func TestEncryptValue_Errors(t *testing.T) {
+	fakeCommands(t)
+	for _, enc := range []ConfigEncryption{
+		{},
+		{Method: "rot13"},
+		{Method: EncryptionAge},
+	} {
+		if _, err := EncryptValue(enc, "clusters.foobar.access-token", "secret"); err == nil {
+			t.Errorf("EncryptValue() with %+v succeeded, want error", enc)
+		}
+	}
+
+	if v, err := DecryptValue(ConfigEncryption{}, "plain"); err != nil || v != "plain" {
+		t.Errorf("DecryptValue() of plaintext = %q, %v, want it unchanged", v, err)
+	}
+	for _, tt := range []struct {
+		enc ConfigEncryption
+		v   string
+	}{
+		{enc: ConfigEncryption{}, v: "age:QUdF"},
+		{enc: ConfigEncryption{AgeIdentity: "/keys.txt"}, v: "age:not base64!"},
+		{enc: ConfigEncryption{AgeIdentity: "/keys.txt"}, v: "age:b3RoZXI="},
+		{enc: ConfigEncryption{}, v: "keyring:clusters.missing.access-token"},
+	} {
+		if _, err := DecryptValue(tt.enc, tt.v); err == nil {
+			t.Errorf("DecryptValue(%q) succeeded, want error", tt.v)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"regexp"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -21,13 +22,70 @@ var (
 	// A BasicLogger that is turned off until turned on by the
 	// --verbose flag.
 	EarlyLogger = NewBasicLogger(os.Stderr, false, version.ProgName)
+
+	// Messages of the warnings and errors logged by Logger, see Warnings
+	// and Errors.
+	warningsMu sync.Mutex
+	warnings   []string
+	errs       []string
+
+	// OnError, if set, is called after the message of each error logged by
+	// Logger is recorded, before the error is written.
+	OnError func()
 )
 
+// warningHook is a zerolog.Hook that records the message of each warning and
+// error logged. Since warnings must be recorded even if the log level hides
+// them, the logger always enables warnings and the hook discards events below
+// level after recording them.
+type warningHook struct {
+	level zerolog.Level
+}
+
+func (h warningHook) Run(e *zerolog.Event, l zerolog.Level, msg string) {
+	switch l {
+	case zerolog.WarnLevel:
+		warningsMu.Lock()
+		warnings = append(warnings, msg)
+		warningsMu.Unlock()
+	case zerolog.ErrorLevel:
+		warningsMu.Lock()
+		errs = append(errs, msg)
+		warningsMu.Unlock()
+		if OnError != nil {
+			OnError()
+		}
+	}
+	if l < h.level {
+		e.Discard()
+	}
+}
+
+// Warnings returns the messages of the warnings logged by Logger since it was
+// initialized, regardless of the log level.
+func Warnings() []string {
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+
+	return append([]string(nil), warnings...)
+}
+
+// Errors returns the messages of the errors logged by Logger since it was
+// initialized.
+func Errors() []string {
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+
+	return append([]string(nil), errs...)
+}
+
 // Init() initializes the global logging object so it can be used for logging by
 // any package that imports this internal log package.
 func Init(ll, lf string) error {
 	var loggerLevel zerolog.Level
 	switch ll {
+	case "error":
+		loggerLevel = zerolog.ErrorLevel
 	case "warning":
 		loggerLevel = zerolog.WarnLevel
 	case "info":
@@ -38,19 +96,26 @@ func Init(ll, lf string) error {
 		return fmt.Errorf("unknown log level: %s", ll)
 	}
 
+	warningsMu.Lock()
+	warnings, errs = nil, nil
+	warningsMu.Unlock()
+	base := func(cw zerolog.ConsoleWriter) zerolog.Logger {
+		return zerolog.New(cw).Level(min(loggerLevel, zerolog.WarnLevel)).Hook(warningHook{level: loggerLevel})
+	}
+
 	cw := zerolog.ConsoleWriter{Out: os.Stderr}
 	switch lf {
 	case "rfc3339":
 		cw.TimeFormat = time.RFC3339
 		cw.FormatCaller = getFormatCaller(cw.NoColor)
-		Logger = zerolog.New(cw).Level(loggerLevel).With().Timestamp().Caller().Logger()
+		Logger = base(cw).With().Timestamp().Caller().Logger()
 	case "basic":
 		cw.FormatTimestamp = func(i interface{}) string { return "" }
 		cw.FormatLevel = func(i interface{}) string { return strings.ToUpper(fmt.Sprintf("%-6s|", i)) }
 		cw.FormatCaller = getFormatCaller(cw.NoColor)
-		Logger = zerolog.New(cw).Level(loggerLevel).With().Caller().Logger()
+		Logger = base(cw).With().Caller().Logger()
 	case "json":
-		Logger = zerolog.New(cw).Level(loggerLevel).With().Timestamp().Logger()
+		Logger = base(cw).With().Timestamp().Logger()
 	default:
 		return fmt.Errorf("unknown log format: %s", lf)
 	}
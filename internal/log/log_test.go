@@ -24,6 +24,14 @@ func TestInit(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "error level and supported format",
+			args: args{
+				ll: "error",
+				lf: "json",
+			},
+			wantErr: false,
+		},
 		{
 			name: "unsupported level and supported format",
 			args: args{
@@ -58,6 +66,40 @@ func TestInit(t *testing.T) {
 	}
 }
 
+func TestWarnings(t *testing.T) {
+	// Warnings are recorded even if the log level hides them
+	if err := Init("error", "basic"); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	var onError []string
+	OnError = func() { onError = Errors() }
+	defer func() { OnError = nil }()
+	Logger.Info().Msg("info")
+	Logger.Warn().Msgf("duplicate xname %s skipped", "x1000c0s0b0n0")
+	Logger.Error().Msg("error")
+	if want := []string{"error"}; !reflect.DeepEqual(onError, want) {
+		t.Errorf("Errors() in OnError = %v, want %v", onError, want)
+	}
+	want := []string{"duplicate xname x1000c0s0b0n0 skipped"}
+	if got := Warnings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Warnings() = %v, want %v", got, want)
+	}
+	if got, want := Errors(), []string{"error"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Errors() = %v, want %v", got, want)
+	}
+
+	// Reinitializing the logger resets the warnings and errors
+	if err := Init("warning", "basic"); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+	if got := Warnings(); len(got) != 0 {
+		t.Errorf("Warnings() after Init() = %v, want none", got)
+	}
+	if got := Errors(); len(got) != 0 {
+		t.Errorf("Errors() after Init() = %v, want none", got)
+	}
+}
+
 func TestNewBasicLogger(t *testing.T) {
 	type args struct {
 		prefix  string
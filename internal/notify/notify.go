@@ -0,0 +1,289 @@
+// Package notify sends notifications that long-running ochami commands (e.g.
+// reimaging nodes or powering them off) finished or failed to site-configured
+// sinks, so that operators need not watch their terminals. Each notification
+// carries a summary of the outcome and, if the command produced one, its
+// report.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Types of sinks.
+const (
+	// SinkWebhook POSTs the notification as JSON to a URL.
+	SinkWebhook = "webhook"
+
+	// SinkSlack POSTs the notification as the text of a message to a
+	// Slack-compatible incoming webhook (e.g. Slack or Mattermost).
+	SinkSlack = "slack"
+
+	// SinkEmail sends the notification as an email with sendmail, with the
+	// report attached.
+	SinkEmail = "email"
+)
+
+// SinkTypes are the supported types of sinks.
+var SinkTypes = []string{SinkWebhook, SinkSlack, SinkEmail}
+
+// DefaultSendmail is the sendmail program used by email sinks if none is
+// configured.
+const DefaultSendmail = "/usr/sbin/sendmail"
+
+// sendTimeout bounds sending a notification to a webhook so that an
+// unreachable sink never delays the exit of a command for long.
+const sendTimeout = 10 * time.Second
+
+// maxSlackReport is the number of bytes of the report included in Slack
+// messages, since they cannot have attachments and are limited in length.
+const maxSlackReport = 3000
+
+// Notification is the outcome of one command run. Command is the command path
+// without the program name (e.g. "node reimage"), Status is its exit status,
+// and Summary describes the outcome in a line (e.g. "2 of 64 node(s) failed to
+// reimage"). Report is the report the command printed, if any, as JSON.
+type Notification struct {
+	Command  string          `json:"command"`
+	Cluster  string          `json:"cluster,omitempty"`
+	Host     string          `json:"host,omitempty"`
+	User     string          `json:"user,omitempty"`
+	Status   int             `json:"status"`
+	Summary  string          `json:"summary"`
+	Started  time.Time       `json:"started"`
+	Finished time.Time       `json:"finished"`
+	Report   json.RawMessage `json:"report,omitempty"`
+}
+
+// Succeeded returns whether the command of n exited successfully.
+func (n Notification) Succeeded() bool {
+	return n.Status == 0
+}
+
+// Subject returns a line saying which command finished where and whether it
+// succeeded, e.g. "ochami node reimage on cluster foobar failed after 12m3s".
+func (n Notification) Subject() string {
+	outcome := "succeeded"
+	if !n.Succeeded() {
+		outcome = fmt.Sprintf("failed (status %d)", n.Status)
+	}
+	where := ""
+	if n.Cluster != "" {
+		where = " on cluster " + n.Cluster
+	}
+
+	return fmt.Sprintf("ochami %s%s %s after %s", n.Command, where, outcome, n.Finished.Sub(n.Started).Round(time.Second))
+}
+
+// Text returns the subject and summary of n, followed by who ran the command
+// where and when.
+func (n Notification) Text() string {
+	var b strings.Builder
+	b.WriteString(n.Subject())
+	b.WriteString("\n")
+	if n.Summary != "" {
+		b.WriteString(n.Summary)
+		b.WriteString("\n")
+	}
+	if n.User != "" || n.Host != "" {
+		fmt.Fprintf(&b, "Run by %s on %s, ", n.User, n.Host)
+	} else {
+		b.WriteString("Run ")
+	}
+	fmt.Fprintf(&b, "from %s to %s.\n", n.Started.UTC().Format(time.RFC3339), n.Finished.UTC().Format(time.RFC3339))
+
+	return b.String()
+}
+
+// Sink is a destination of notifications. Type is one of SinkTypes. Webhook
+// and Slack sinks POST to URL. Email sinks send mail from From (the default
+// of sendmail if empty) to To with Sendmail (DefaultSendmail if empty).
+type Sink struct {
+	Type     string
+	URL      string
+	To       []string
+	From     string
+	Sendmail string
+}
+
+// Validate returns an error if s is missing what its type needs.
+func (s Sink) Validate() error {
+	switch s.Type {
+	case SinkWebhook, SinkSlack:
+		if s.URL == "" {
+			return fmt.Errorf("%s sink has no url", s.Type)
+		}
+	case SinkEmail:
+		if len(s.To) == 0 {
+			return fmt.Errorf("%s sink has no recipients", s.Type)
+		}
+	default:
+		return fmt.Errorf("unknown sink type %q (must be one of %v)", s.Type, SinkTypes)
+	}
+
+	return nil
+}
+
+// String returns the type of s and where it sends notifications, without the
+// path or query of webhook URLs since they often contain credentials.
+func (s Sink) String() string {
+	switch s.Type {
+	case SinkEmail:
+		return fmt.Sprintf("%s to %s", s.Type, strings.Join(s.To, ","))
+	default:
+		u := s.URL
+		if i := strings.Index(u, "://"); i >= 0 {
+			if j := strings.Index(u[i+3:], "/"); j >= 0 {
+				u = u[:i+3+j]
+			}
+		}
+		return fmt.Sprintf("%s to %s", s.Type, u)
+	}
+}
+
+// Send sends n to s.
+func (s Sink) Send(n Notification) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	switch s.Type {
+	case SinkWebhook:
+		body, err := json.Marshal(n)
+		if err != nil {
+			return fmt.Errorf("failed to marshal notification: %w", err)
+		}
+		return postJSON(s.URL, body)
+	case SinkSlack:
+		body, err := json.Marshal(map[string]string{"text": SlackText(n)})
+		if err != nil {
+			return fmt.Errorf("failed to marshal Slack message: %w", err)
+		}
+		return postJSON(s.URL, body)
+	default:
+		msg, err := EmailMessage(n, s.From, s.To)
+		if err != nil {
+			return err
+		}
+		sendmail := s.Sendmail
+		if sendmail == "" {
+			sendmail = DefaultSendmail
+		}
+		return runSendmail(sendmail, msg)
+	}
+}
+
+// Send sends n to each of sinks, returning an error naming those it failed
+// to be sent to. It is sent to all of them even if some fail.
+func Send(sinks []Sink, n Notification) error {
+	var errs []string
+	for _, s := range sinks {
+		if err := s.Send(n); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", s, err))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to send notification: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// SlackText returns the text of the Slack message for n: its text followed by
+// its report in a code block, truncated if long.
+func SlackText(n Notification) string {
+	text := n.Text()
+	if len(n.Report) > 0 {
+		report := indentReport(n.Report)
+		if len(report) > maxSlackReport {
+			report = report[:maxSlackReport] + "\n... (truncated)"
+		}
+		text += "```\n" + report + "\n```"
+	}
+
+	return text
+}
+
+// EmailMessage returns the email for n from from to to, with its text as the
+// body and its report, if any, attached as report.json.
+func EmailMessage(n Notification, from string, to []string) ([]byte, error) {
+	var b bytes.Buffer
+	if from != "" {
+		fmt.Fprintf(&b, "From: %s\r\n", from)
+	}
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", n.Subject()))
+	fmt.Fprintf(&b, "Date: %s\r\n", n.Finished.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	mw := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "Content-Type: multipart/mixed; boundary=%s\r\n\r\n", mw.Boundary())
+	part, err := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/plain; charset=utf-8"}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create email body: %w", err)
+	}
+	part.Write([]byte(strings.ReplaceAll(n.Text(), "\n", "\r\n")))
+	if len(n.Report) > 0 {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":        {"application/json"},
+			"Content-Disposition": {`attachment; filename="report.json"`},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create email attachment: %w", err)
+		}
+		part.Write([]byte(indentReport(n.Report)))
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish email: %w", err)
+	}
+
+	return b.Bytes(), nil
+}
+
+// indentReport returns report indented for reading, or as is if it cannot be.
+func indentReport(report json.RawMessage) string {
+	var b bytes.Buffer
+	if err := json.Indent(&b, report, "", "  "); err != nil {
+		return string(report)
+	}
+
+	return b.String()
+}
+
+// postJSON POSTs body to url as JSON, returning an error if the response is
+// not successful.
+func postJSON(url string, body []byte) error {
+	c := http.Client{Timeout: sendTimeout}
+	resp, err := c.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to POST notification: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unsuccessful HTTP status: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// runSendmail sends the email msg with the sendmail program at path, reading
+// the recipients from its headers. It is a variable so that tests need not
+// have sendmail available.
+var runSendmail = func(path string, msg []byte) error {
+	var stderr bytes.Buffer
+	c := exec.Command(path, "-t", "-i")
+	c.Stdin = bytes.NewReader(msg)
+	c.Stderr = &stderr
+	if err := c.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", path, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return nil
+}
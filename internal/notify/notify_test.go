@@ -0,0 +1,142 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNotification = Notification{
+	Command:  "node reimage",
+	Cluster:  "foobar",
+	Host:     "admin01",
+	User:     "jdoe",
+	Status:   1,
+	Summary:  "2 of 64 node(s) failed to reimage",
+	Started:  time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	Finished: time.Date(2026, 1, 2, 3, 16, 8, 0, time.UTC),
+	Report:   json.RawMessage(`{"counts":{"failed":2,"booted":62}}`),
+}
+
+func TestNotification_Text(t *testing.T) {
+	want := "ochami node reimage on cluster foobar failed (status 1) after 12m3s\n" +
+		"2 of 64 node(s) failed to reimage\n" +
+		"Run by jdoe on admin01, from 2026-01-02T03:04:05Z to 2026-01-02T03:16:08Z.\n"
+	if got := testNotification.Text(); got != want {
+		t.Errorf("Text() = %q, want %q", got, want)
+	}
+
+	n := testNotification
+	n.Status = 0
+	n.Cluster = ""
+	if got, want := n.Subject(), "ochami node reimage succeeded after 12m3s"; got != want {
+		t.Errorf("Subject() = %q, want %q", got, want)
+	}
+}
+
+func TestSink_Validate(t *testing.T) {
+	tests := []struct {
+		sink    Sink
+		wantErr bool
+	}{
+		{Sink{Type: SinkWebhook, URL: "https://hooks.example.com/x"}, false},
+		{Sink{Type: SinkSlack}, true},
+		{Sink{Type: SinkEmail, To: []string{"ops@example.com"}}, false},
+		{Sink{Type: SinkEmail}, true},
+		{Sink{Type: "pager"}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.sink.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.sink, err, tt.wantErr)
+		}
+	}
+}
+
+func TestSink_String(t *testing.T) {
+	s := Sink{Type: SinkSlack, URL: "https://hooks.slack.com/services/T000/B000/secret"}
+	if got, want := s.String(), "slack to https://hooks.slack.com"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestSend(t *testing.T) {
+	var bodies [][]byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q", r.Header.Get("Content-Type"))
+		}
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, b)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	var mail []byte
+	orig := runSendmail
+	defer func() { runSendmail = orig }()
+	runSendmail = func(path string, msg []byte) error {
+		if path != DefaultSendmail {
+			t.Errorf("sendmail path = %q, want %q", path, DefaultSendmail)
+		}
+		mail = msg
+		return nil
+	}
+
+	sinks := []Sink{
+		{Type: SinkWebhook, URL: srv.URL + "/hook"},
+		{Type: SinkSlack, URL: srv.URL + "/fail"},
+		{Type: SinkEmail, To: []string{"ops@example.com"}, From: "ochami@example.com"},
+	}
+	err := Send(sinks, testNotification)
+	if err == nil || !strings.Contains(err.Error(), "slack to "+srv.URL) || strings.Contains(err.Error(), "webhook") {
+		t.Errorf("Send() error = %v, want one for the Slack sink only", err)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("got %d requests, want 2", len(bodies))
+	}
+	var got Notification
+	if err := json.Unmarshal(bodies[0], &got); err != nil {
+		t.Fatalf("webhook body is not a notification: %v", err)
+	}
+	if !reflect.DeepEqual(got, testNotification) {
+		t.Errorf("webhook got %+v, want %+v", got, testNotification)
+	}
+	var msg map[string]string
+	if err := json.Unmarshal(bodies[1], &msg); err != nil {
+		t.Fatalf("Slack body is not a message: %v", err)
+	}
+	if !strings.HasPrefix(msg["text"], testNotification.Text()) || !strings.Contains(msg["text"], "```\n{\n  \"counts\"") {
+		t.Errorf("Slack text = %q, want the text and the report", msg["text"])
+	}
+
+	for _, s := range []string{
+		"From: ochami@example.com\r\n",
+		"To: ops@example.com\r\n",
+		"Subject: ochami node reimage on cluster foobar failed (status 1) after 12m3s\r\n",
+		"Content-Type: multipart/mixed; boundary=",
+		"2 of 64 node(s) failed to reimage\r\n",
+		`Content-Disposition: attachment; filename="report.json"`,
+		`"failed": 2`,
+	} {
+		if !strings.Contains(string(mail), s) {
+			t.Errorf("email does not contain %q:\n%s", s, mail)
+		}
+	}
+}
+
+func TestSlackText_Truncated(t *testing.T) {
+	n := testNotification
+	n.Report = json.RawMessage(`"` + strings.Repeat("x", 2*maxSlackReport) + `"`)
+	text := SlackText(n)
+	if !strings.HasSuffix(text, "... (truncated)\n```") || len(text) > len(n.Text())+maxSlackReport+64 {
+		t.Errorf("SlackText() is not truncated: %d bytes", len(text))
+	}
+}
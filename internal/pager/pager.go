@@ -0,0 +1,145 @@
+// Package pager pages long output through the user's pager (e.g. less) when
+// standard output is a terminal, like git does.
+package pager
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// DefaultPager is the pager used if neither OCHAMI_PAGER nor PAGER is set.
+const DefaultPager = "less"
+
+// Command returns the pager command to use: the value of OCHAMI_PAGER if set,
+// otherwise that of PAGER if set, otherwise DefaultPager. An empty string or
+// "cat" means output should not be paged, so an empty string is returned for
+// both.
+func Command() string {
+	command := DefaultPager
+	for _, env := range []string{"OCHAMI_PAGER", "PAGER"} {
+		if v, ok := os.LookupEnv(env); ok {
+			command = v
+			break
+		}
+	}
+	command = strings.TrimSpace(command)
+	if command == "cat" {
+		return ""
+	}
+
+	return command
+}
+
+// Writer buffers output until it no longer fits on a screen of height lines,
+// leaving room for the shell prompt, at which point it starts the pager command and writes all output to it from then on. If Close is
+// called before that, the buffered output is written to out instead, so that
+// output fitting on one screen is printed as usual.
+type Writer struct {
+	command string
+	out     io.Writer
+	height  int
+
+	buf   bytes.Buffer
+	lines int
+	pager *exec.Cmd
+	stdin io.WriteCloser
+
+	// startErr is the error starting the pager, if any, in which case
+	// output is written to out unpaged.
+	startErr error
+}
+
+// NewWriter returns a Writer that pages output not fitting on a screen of
+// height lines through command, which is run with the shell like git does, and
+// writes shorter output to out. The pager's output goes to out.
+func NewWriter(command string, out io.Writer, height int) *Writer {
+	return &Writer{
+		command: command,
+		out:     out,
+		height:  height,
+	}
+}
+
+// Write writes p to the pager if it was started, otherwise buffers it, starting
+// the pager once the buffered output no longer fits on the screen. If the pager
+// cannot be started, output is written to out instead.
+func (w *Writer) Write(p []byte) (int, error) {
+	switch {
+	case w.stdin != nil:
+		return w.stdin.Write(p)
+	case w.startErr != nil:
+		return w.out.Write(p)
+	}
+
+	w.buf.Write(p)
+	w.lines += bytes.Count(p, []byte("\n"))
+	if w.lines < w.height {
+		return len(p), nil
+	}
+	var dst io.Writer = w.out
+	if w.startErr = w.start(); w.startErr == nil {
+		dst = w.stdin
+	}
+	_, err := dst.Write(w.buf.Bytes())
+	w.buf.Reset()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// start starts the pager with its output going to out.
+func (w *Writer) start() error {
+	// The shell would start even if the pager does not exist, so check
+	// for it to be able to fall back to not paging
+	if _, err := exec.LookPath(strings.Fields(w.command)[0]); err != nil {
+		return fmt.Errorf("failed to find pager %q: %w", w.command, err)
+	}
+	pager := exec.Command("sh", "-c", w.command)
+	pager.Stdout = w.out
+	pager.Stderr = os.Stderr
+	// Like git, have less quit if output fits on one screen, show colors,
+	// and not clear the screen on exit unless configured otherwise
+	pager.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		pager.Env = append(pager.Env, "LESS=FRX")
+	}
+	if _, ok := os.LookupEnv("LV"); !ok {
+		pager.Env = append(pager.Env, "LV=-c")
+	}
+	stdin, err := pager.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to create pager input: %w", err)
+	}
+	if err := pager.Start(); err != nil {
+		return fmt.Errorf("failed to start pager %q: %w", w.command, err)
+	}
+	w.pager, w.stdin = pager, stdin
+
+	return nil
+}
+
+// Close writes any buffered output to out or, if the pager was started,
+// closes its input and waits for the user to quit it. If the pager could not be
+// started, the error doing so is returned.
+func (w *Writer) Close() error {
+	if w.stdin == nil {
+		if _, err := w.out.Write(w.buf.Bytes()); err != nil {
+			return err
+		}
+		w.buf.Reset()
+		return w.startErr
+	}
+	w.stdin.Close()
+	w.stdin = nil
+	if err := w.pager.Wait(); err != nil {
+		return fmt.Errorf("pager %q failed: %w", w.command, err)
+	}
+
+	return nil
+}
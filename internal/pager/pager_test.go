@@ -0,0 +1,93 @@
+package pager
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		name   string
+		env    map[string]string
+		unset  []string
+		expect string
+	}{
+		{name: "default", unset: []string{"OCHAMI_PAGER", "PAGER"}, expect: DefaultPager},
+		{name: "PAGER", env: map[string]string{"PAGER": "more"}, unset: []string{"OCHAMI_PAGER"}, expect: "more"},
+		{name: "OCHAMI_PAGER overrides PAGER", env: map[string]string{"OCHAMI_PAGER": "less -S", "PAGER": "more"}, expect: "less -S"},
+		{name: "empty disables", env: map[string]string{"PAGER": ""}, unset: []string{"OCHAMI_PAGER"}, expect: ""},
+		{name: "cat disables", env: map[string]string{"OCHAMI_PAGER": "cat"}, expect: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, k := range tt.unset {
+				// Register restoring the variable, then unset it
+				t.Setenv(k, "")
+				os.Unsetenv(k)
+			}
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if got := Command(); got != tt.expect {
+				t.Errorf("Command() = %q, want %q", got, tt.expect)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	paged := filepath.Join(t.TempDir(), "paged")
+	command := "cat > " + paged
+
+	// Output fitting on the screen is written to out without starting the
+	// pager
+	var out bytes.Buffer
+	w := NewWriter(command, &out, 3)
+	w.Write([]byte("a\nb\n"))
+	if out.Len() != 0 {
+		t.Errorf("output written before Close: %q", out.String())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if out.String() != "a\nb\n" {
+		t.Errorf("unpaged output = %q, want %q", out.String(), "a\nb\n")
+	}
+	if _, err := os.Stat(paged); err == nil {
+		t.Error("pager was started for output fitting on the screen")
+	}
+
+	// Longer output goes to the pager
+	out.Reset()
+	w = NewWriter(command, &out, 3)
+	long := strings.Repeat("line\n", 5)
+	for _, l := range strings.SplitAfter(long, "\n") {
+		w.Write([]byte(l))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("paged output also written to out: %q", out.String())
+	}
+	if got, err := os.ReadFile(paged); err != nil {
+		t.Fatal(err)
+	} else if string(got) != long {
+		t.Errorf("paged output = %q, want %q", got, long)
+	}
+
+	// If the pager cannot be started, output is written to out
+	out.Reset()
+	w = NewWriter("/nonexistent/pager", &out, 1)
+	w.Write([]byte(long))
+	w.Write([]byte("more\n"))
+	if err := w.Close(); err == nil {
+		t.Error("Close() succeeded for pager that cannot be started, want error")
+	}
+	if out.String() != long+"more\n" {
+		t.Errorf("output when pager fails = %q, want %q", out.String(), long+"more\n")
+	}
+}
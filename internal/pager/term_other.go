@@ -0,0 +1,10 @@
+//go:build !unix
+
+package pager
+
+// TerminalHeight returns the number of rows of the terminal f is connected to.
+// Terminals are not detected on this platform, so false is always returned and
+// output is never paged.
+func TerminalHeight(f interface{ Fd() uintptr }) (int, bool) {
+	return 0, false
+}
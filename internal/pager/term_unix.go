@@ -0,0 +1,18 @@
+//go:build unix
+
+package pager
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// TerminalHeight returns the number of rows of the terminal f is connected to.
+// If f is not a terminal, false is returned.
+func TerminalHeight(f interface{ Fd() uintptr }) (int, bool) {
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Row == 0 {
+		return 0, false
+	}
+
+	return int(ws.Row), true
+}
@@ -0,0 +1,191 @@
+// Package usage records opt-in usage statistics of ochami commands (which
+// command was run, how long it took, and whether and how it failed) to a
+// site-owned file and/or StatsD endpoint, so that cluster administrators can
+// see which workflows are used most and which fail most often. Arguments,
+// flag values, and responses are never recorded.
+package usage
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Categories of failures, determined from the errors logged by a failed
+// command (see Categorize).
+const (
+	FailureTimeout    = "timeout"
+	FailureReadOnly   = "read-only"
+	FailurePolicy     = "policy"
+	FailureHTTP       = "http"
+	FailureAuth       = "auth"
+	FailureConfig     = "config"
+	FailureConnection = "connection"
+	FailureUsage      = "usage"
+	FailureWarnings   = "warnings"
+	FailureOther      = "other"
+)
+
+// DefaultPrefix is the prefix of StatsD metric names if none is configured.
+const DefaultPrefix = "ochami"
+
+// statsdTimeout bounds sending metrics to StatsD so that an unreachable
+// endpoint never delays a command noticeably.
+const statsdTimeout = 500 * time.Millisecond
+
+// Entry is a record of one command run. Command is the command path without
+// the program name (e.g. "smd component get"). Status is the exit status and
+// Failure is the category of the failure if Status is not 0.
+type Entry struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Cluster    string    `json:"cluster,omitempty"`
+	Version    string    `json:"version"`
+	DurationMS int64     `json:"duration_ms"`
+	Status     int       `json:"status"`
+	Failure    string    `json:"failure,omitempty"`
+}
+
+// Collector records entries to File as JSON lines and/or sends them to the
+// StatsD endpoint StatsD (host:port, over UDP) as metrics named with Prefix
+// (DefaultPrefix if empty).
+type Collector struct {
+	File   string
+	StatsD string
+	Prefix string
+}
+
+// Enabled returns whether c records entries anywhere.
+func (c Collector) Enabled() bool {
+	return c.File != "" || c.StatsD != ""
+}
+
+// Record records e to the file and StatsD endpoint of c, filling in its time
+// if zero. Each entry is appended to the file with a single write so that
+// entries from concurrent ochami processes are not interleaved. Both are
+// attempted even if one fails.
+func (c Collector) Record(e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+
+	var errs []string
+	if c.File != "" {
+		if err := appendEntry(c.File, e); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if c.StatsD != "" {
+		if err := sendStatsD(c.StatsD, StatsDMetrics(c.Prefix, e)); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to record usage: %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// appendEntry appends e to the file at path as a JSON line, creating the file
+// if it does not exist.
+func appendEntry(path string, e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage entry: %w", err)
+	}
+	line = append(line, '\n')
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open usage file: %w", err)
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write usage entry: %w", err)
+	}
+
+	return f.Close()
+}
+
+// metricUnsafe matches characters that are not safe in StatsD metric names.
+var metricUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// StatsDMetrics returns the StatsD metrics for e, named
+// <prefix>.<command>.<metric> with the words of the command separated by dots
+// (e.g. ochami.smd.component.get.count): a count of runs, the duration as a
+// timer, and, if e failed, a count of failures of its category.
+func StatsDMetrics(prefix string, e Entry) []string {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	words := strings.Fields(e.Command)
+	for i, w := range words {
+		words[i] = metricUnsafe.ReplaceAllString(w, "_")
+	}
+	name := strings.Join(append([]string{prefix}, words...), ".")
+	metrics := []string{
+		fmt.Sprintf("%s.count:1|c", name),
+		fmt.Sprintf("%s.duration:%d|ms", name, e.DurationMS),
+	}
+	if e.Status != 0 {
+		failure := e.Failure
+		if failure == "" {
+			failure = FailureOther
+		}
+		metrics = append(metrics, fmt.Sprintf("%s.failure.%s:1|c", name, metricUnsafe.ReplaceAllString(failure, "_")))
+	}
+
+	return metrics
+}
+
+// sendStatsD sends metrics to the StatsD endpoint addr in a single UDP packet.
+func sendStatsD(addr string, metrics []string) error {
+	conn, err := net.DialTimeout("udp", addr, statsdTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to StatsD endpoint %s: %w", addr, err)
+	}
+	defer conn.Close()
+	conn.SetWriteDeadline(time.Now().Add(statsdTimeout))
+	if _, err := conn.Write([]byte(strings.Join(metrics, "\n"))); err != nil {
+		return fmt.Errorf("failed to send metrics to StatsD endpoint %s: %w", addr, err)
+	}
+
+	return nil
+}
+
+// Categorize returns the category of a failure from the messages of the errors
+// logged by the failed command, in the order they were logged. The first
+// message that matches a category determines it, so that follow-up messages
+// (e.g. a hint to see --help) do not. FailureOther is returned if none match.
+func Categorize(msgs []string) string {
+	rules := []struct {
+		category string
+		words    []string
+	}{
+		{FailureTimeout, []string{"--context-timeout", "deadline", "not attempted", "timed out"}},
+		{FailureReadOnly, []string{"read-only"}},
+		{FailurePolicy, []string{"policy of cluster"}},
+		{FailureHTTP, []string{"unsuccessful http"}},
+		{FailureAuth, []string{"token", "auth"}},
+		{FailureConfig, []string{"config"}},
+		{FailureConnection, []string{"failed to request", "failed to execute http request", "connection"}},
+		{FailureUsage, []string{"invalid", "failed to get value", "required", "failed to execute command", "unknown", "must"}},
+	}
+	for _, msg := range msgs {
+		lower := strings.ToLower(msg)
+		for _, r := range rules {
+			for _, w := range r.words {
+				if strings.Contains(lower, w) {
+					return r.category
+				}
+			}
+		}
+	}
+
+	return FailureOther
+}
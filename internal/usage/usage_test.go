@@ -0,0 +1,108 @@
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCollector_Record(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	c := Collector{File: path, StatsD: pc.LocalAddr().String(), Prefix: "site"}
+	if !c.Enabled() || (Collector{}).Enabled() {
+		t.Errorf("Enabled() is wrong")
+	}
+	ts := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	entries := []Entry{
+		{Time: ts, Command: "smd component get", Version: "v0.5.0", DurationMS: 42},
+		{Time: ts, Command: "bss boot params set", Cluster: "foobar", Version: "v0.5.0", DurationMS: 7, Status: 1, Failure: FailureHTTP},
+	}
+	for _, e := range entries {
+		if err := c.Record(e); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []Entry
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		var e Entry
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatalf("line is not a JSON entry: %v", err)
+		}
+		got = append(got, e)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("usage file has %+v, want %+v", got, entries)
+	}
+
+	pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 1024)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("no StatsD packet received: %v", err)
+	}
+	if want := "site.smd.component.get.count:1|c\nsite.smd.component.get.duration:42|ms"; string(buf[:n]) != want {
+		t.Errorf("StatsD packet = %q, want %q", buf[:n], want)
+	}
+}
+
+func TestCollector_Record_Error(t *testing.T) {
+	c := Collector{File: filepath.Join(t.TempDir(), "missing", "usage.jsonl")}
+	if err := c.Record(Entry{Command: "smd component get"}); err == nil {
+		t.Errorf("Record() to a missing directory succeeded, want error")
+	}
+}
+
+func TestStatsDMetrics(t *testing.T) {
+	got := StatsDMetrics("", Entry{Command: "cloud-init group get config", DurationMS: 1500, Status: 1})
+	want := []string{
+		"ochami.cloud-init.group.get.config.count:1|c",
+		"ochami.cloud-init.group.get.config.duration:1500|ms",
+		"ochami.cloud-init.group.get.config.failure.other:1|c",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StatsDMetrics() = %v, want %v", got, want)
+	}
+}
+
+func TestCategorize(t *testing.T) {
+	tests := []struct {
+		msgs []string
+		want string
+	}{
+		{[]string{"SMD component request yielded unsuccessful HTTP response", "see 'ochami smd component get --help' for long command help"}, FailureHTTP},
+		{[]string{"failed to request components from SMD"}, FailureConnection},
+		{[]string{"no token set"}, FailureAuth},
+		{[]string{"failed to initialize config"}, FailureConfig},
+		{[]string{"invalid --target"}, FailureUsage},
+		{[]string{"failed to execute command"}, FailureUsage},
+		{[]string{"3 item(s) not attempted"}, FailureTimeout},
+		{[]string{"refusing to send mutating request in read-only mode"}, FailureReadOnly},
+		{[]string{"policy of cluster prod does not allow 'smd group delete' (denied by policy pattern \"delete\")"}, FailurePolicy},
+		{[]string{"see 'ochami --help' for long command help", "failed to request groups from SMD"}, FailureConnection},
+		{[]string{"something broke"}, FailureOther},
+		{nil, FailureOther},
+	}
+	for _, tt := range tests {
+		if got := Categorize(tt.msgs); got != tt.want {
+			t.Errorf("Categorize(%q) = %q, want %q", tt.msgs, got, tt.want)
+		}
+	}
+}
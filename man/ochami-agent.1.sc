@@ -0,0 +1,166 @@
+OCHAMI-AGENT(1) "OpenCHAMI" "Manual Page for ochami-agent"
+
+# NAME
+
+ochami-agent - Keep the token of a cluster fresh and serve cached queries
+
+# SYNOPSIS
+
+ochami agent [--socket _path_] [--refresh-before _duration_] [--cache-ttl _duration_] [--warm _service_:_path_]...
+
+ochami agent status [--socket _path_] [-F _format_]
+
+# DESCRIPTION
+
+The *agent* command runs the ochami agent for a cluster (the one passed with
+*--cluster* or the *default-cluster*) in the foreground until it is interrupted
+(e.g. with *SIGINT* or *SIGTERM*). The agent keeps the access token of the
+cluster fresh, caches the responses to GET requests to the services of the
+cluster (BSS, cloud-init, PCS, and SMD), and serves both over an HTTP API on a
+Unix socket that only the current user can connect to. It is meant to be run
+as a user service, e.g. with *systemd --user*.
+
+While the agent runs, other *ochami* invocations for the cluster use it
+automatically:
+
+- If *--token* is not passed and the cluster's access token environment
+  variable is unset, the token is taken from the agent instead of from the
+  cluster config.
+- GET requests to the services of the cluster are sent through the agent, so
+  that repeated queries (e.g. by shell completion or scripts) are served from
+  its cache. Other requests, and GET requests for URIs the agent does not
+  serve, are sent directly. Once a request that may modify data (e.g. a POST
+  or DELETE) has been sent, the agent is told to drop the cached responses of
+  its service, so that what follows reads the change. Reads that a command
+  checks a write against or polls for a change, e.g. by *smd group member add
+  --verify* or *smd component wait*, are not served from the cache. If the
+  agent cannot be reached, all requests are sent directly.
+
+Pass *--no-agent* to any command to bypass the agent (see *ochami*(1)). Only the
+agent listening on the default socket of the cluster is used, and only if the
+socket is owned by the current user.
+
+# TOKEN REFRESH
+
+The initial token of the agent is found like for any other command (see
+*ochami*(1)). If it was passed with *--token* or in the environment, it is never
+refreshed. Otherwise, the agent refreshes it *--refresh-before* it expires, or
+every *--refresh-before* if its expiration is unknown (i.e. it is not a JWT), by
+reading the config again and running the *token-command* of the cluster, or
+taking its *access-token* if it has none (see *ochami-config*(5)). Changes to
+the config thus take effect without restarting the agent. If refreshing the
+token fails, the agent keeps the current token, retries every 30 seconds, and
+reports the error in its status.
+
+# CACHING
+
+Successful responses are cached for *--cache-ttl*. Responses to requests made
+with the token of the agent that keep being requested (within the last 10
+minutes) are refreshed in the background before they expire, as are the
+endpoints passed with *--warm*, so that they are always served from the cache.
+Responses to requests made with other credentials are cached separately and are
+not refreshed. A request with the header _Cache-Control: no-cache_ bypasses the
+cache. Unsuccessful responses are never cached. All cached responses of a
+service are dropped when the agent is told that a request that may modify its
+data was sent (see *POST /v1/invalidate*).
+
+# HTTP API
+
+The agent serves the following endpoints on its socket. Any host name can be
+used in requests, e.g. _http://ochami-agent_.
+
+*GET /v1/status*
+	The status of the agent as JSON: its cluster, PID, start time, and
+	services, when its token expires and was last refreshed, the error of the
+	last refresh if it failed, and the number of entries, hits, and misses of
+	its cache.
+
+*GET /v1/token*
+	The current token of the agent as JSON (_{"token": "...", "expires":
+	"..."}_). If the agent has no token (e.g. authentication is disabled for
+	the cluster), it responds with _503 Service Unavailable_.
+
+*GET /v1/get?uri=*_uri_
+	The response to a GET request for _uri_ (URL-encoded), which must be under
+	the base URI of one of the services of the cluster. Other URIs are refused
+	with _421 Misdirected Request_ so that the token of the agent is never sent
+	elsewhere. The request is made with the _Authorization_ header of the
+	request to the agent, or the token of the agent if it has none. The
+	response has the status, _Content-Type_, and body of the response of the
+	service, and the header _X-Ochami-Agent-Cache_ set to _hit_ or _miss_. If
+	the service cannot be reached, the agent responds with _502 Bad Gateway_.
+
+*GET /v1/services/*_service_*/*_path_
+	Like */v1/get* for _path_ (and query string) under the base URI of
+	_service_ (_bss_, _cloud-init_, _pcs_, or _smd_).
+
+*POST /v1/invalidate?uri=*_uri_
+	Drop the cached responses of the service that _uri_ (URL-encoded) is
+	under, e.g. after sending it a request that modified its data. The agent
+	responds with _204 No Content_, or _421 Misdirected Request_ if _uri_ is not
+	under the base URI of one of the services of the cluster.
+
+For example:
+
+```
+curl --unix-socket $XDG_RUNTIME_DIR/ochami/agent-foobar.sock \
+  http://ochami-agent/v1/services/smd/State/Components
+```
+
+# OPTIONS
+
+*--cache-ttl* _duration_
+	How long to serve responses from the cache before fetching them again (e.g.
+	_1m_). _0_ disables caching.
+
+	Default: _30s_
+
+*-F, --format-output* _format_
+	For *status*, output the status in the specified format. Supported values
+	are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*--refresh-before* _duration_
+	How long before the token expires to refresh it, or how often to refresh it
+	if its expiration is unknown.
+
+	Default: _5m_
+
+*--socket* _path_
+	The path of the socket of the agent. Other *ochami* invocations only use
+	the agent listening on the default socket, so this is for agents used only
+	by site tools. If an agent is already listening on the socket, the command
+	fails. A socket left behind by an agent that exited is replaced. The
+	directory of the socket is created if needed and must be owned by the
+	current user and have mode _0700_, otherwise the command fails, since
+	another user could replace a socket in it and receive tokens.
+
+	Default: _$XDG_RUNTIME_DIR/ochami/agent-<cluster>.sock_, or
+	_ochami-<uid>/agent-<cluster>.sock_ in *TMPDIR* (or _/tmp_) if
+	*XDG_RUNTIME_DIR* is unset
+
+*--warm* _service_:_path_
+	Keep the response to a GET request for _path_ under the base URI of
+	_service_ in the cache from the start, whether or not it is requested, e.g.
+	_smd:/State/Components_. This option can be passed more than once.
+
+# COMMANDS
+
+## status
+
+Print the status of the agent running for the cluster (see */v1/status* above).
+If no agent is running, the command exits with a status of _1_.
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1), *ochami-config*(5)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
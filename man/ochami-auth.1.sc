@@ -0,0 +1,90 @@
+OCHAMI-AUTH(1) "OpenCHAMI" "Manual Page for ochami-auth"
+
+# NAME
+
+ochami-auth - Inspect the access token used for OpenCHAMI services
+
+# SYNOPSIS
+
+ochami auth check [--only _service_[:_operation_],...] [-F _format_]
+
+# DESCRIPTION
+
+The *auth* command inspects the access token that other commands use for the
+cluster (see *ochami*(1) for how it is found). It is meant to be run before a
+long workflow so that missing privileges are found before the workflow fails
+partway through.
+
+# COMMANDS
+
+## check
+
+Check which operations the token is authorized for on each service: reading
+and writing on SMD, BSS, cloud-init, and PCS. Each operation is checked by
+sending a test request with the token that cannot modify data:
+
+- Reading: a GET that returns little data, e.g. SMD's _/groups_ filtered by a
+  group that does not exist.
+- Writing: a POST to an endpoint that creates records (e.g. SMD's
+  _/State/Components_ or BSS's _/bootparameters_) with a payload that is not
+  valid JSON, which an authorized request has rejected with _400 Bad Request_
+  before anything is written.
+
+The result of each check is one of:
+
+- _allowed_: the request was authorized, i.e. it was answered with a status
+  other than _401_, _403_, _404_, _405_, or _5XX_.
+- _denied_: the request was rejected with _401 Unauthorized_ (the token is
+  not accepted) or _403 Forbidden_ (the token lacks the privilege).
+- _unknown_: the service is not configured or could not be reached, answered
+  with _404_, _405_, or _5XX_, or the request was not sent because
+  *read-only* is set (see *ochami-config*(5)).
+
+If the token is a JWT with scopes (in its _scope_, _scp_, or _scopes_ claim),
+its subject, issuer, expiration, and scopes are reported, and so is whether
+its scopes grant each operation (_granted_ or _missing_). A scope grants an
+operation if it names the operation (e.g. _write_), the service (e.g. _smd_),
+the operation on the service (e.g. _smd.write_, _smd:write_, or _smd/write_),
+or _admin_ on its own or for the service. Scopes that grant writing also grant
+reading. Services may name their scopes differently, so the test requests,
+not the scopes, decide the result.
+
+The report is printed, and the command exits with a status of _1_ if any
+checked operation is not _allowed_. Each of those is also logged.
+
+Since write checks are POST requests, they are recorded in the audit log if
+one is configured.
+
+This command accepts the following options:
+
+*-F, --format-output* _format_
+	Output the report in the specified format. Supported values are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*--only* _service_[:_operation_],...
+	Only check the operations passed, each a service (_smd_, _bss_,
+	_cloud-init_, or _pcs_) for both of its operations, or a service and an
+	operation (_read_ or _write_), e.g. _--only smd:write,bss_. This flag can
+	also be passed more than once.
+
+# EXAMPLES
+
+Check that the token can write to SMD and BSS before populating them:
+
+```
+ochami auth check --only smd:write,bss:write
+```
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1), *ochami-config*(5)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
@@ -0,0 +1,103 @@
+OCHAMI-BACKUP(1) "OpenCHAMI" "Manual Page for ochami-backup"
+
+# NAME
+
+ochami-backup - Back up and restore cluster configuration
+
+# SYNOPSIS
+
+ochami backup create _file_
+
+ochami backup restore [--only _service_,...] [--overwrite] [--no-confirm] _file_
+
+# DESCRIPTION
+
+The *backup* command is a metacommand for backing up the configuration of a
+cluster held by SMD, BSS, and cloud-init to a single archive and restoring it
+from one, e.g. for disaster recovery or to move configuration to a new cluster.
+
+# ARCHIVE FORMAT
+
+A backup archive is a tar archive whose compression is determined by the
+extension of its file name:
+
+- _.tar_ - No compression.
+- _.tar.gz_, _.tgz_ - gzip compression.
+- _.tar.zst_, _.tzst_ - zstd compression. This requires the *zstd*(1) program to
+be in *PATH*.
+
+The first file in the archive is _manifest.json_, which records the version of
+the manifest format, when the backup was created, the version of *ochami* that
+created it, the name of the cluster, and, for each service, the version
+information reported by the service (if any) and the files holding its data.
+Each of the following files holds the JSON data returned by the service:
+
+- _smd/components.json_ - SMD components.
+- _smd/redfish-endpoints.json_ - SMD redfish endpoints.
+- _smd/ethernet-interfaces.json_ - SMD ethernet interfaces.
+- _smd/groups.json_ - SMD groups and their members.
+- _bss/boot-parameters.json_ - BSS boot parameters.
+- _cloud-init/defaults.json_ - cloud-init cluster defaults.
+- _cloud-init/groups.json_ - cloud-init groups.
+
+Since SMD does not return BMC credentials, they are not part of the backup.
+
+# COMMANDS
+
+## create
+
+Back up the configuration of the cluster to _file_.
+
+The format of this command is:
+
+*create* _file_
+
+If any of the data cannot be read, the command fails without writing a backup.
+The archive is written to a temporary file in the same directory as _file_ that
+replaces _file_ once the backup is complete.
+
+## restore
+
+Restore the configuration of the cluster from the backup archive _file_.
+
+The format of this command is:
+
+*restore* [--only _service_,...] [--overwrite] [--no-confirm] _file_
+
+The data of each service is restored in the order SMD, BSS, cloud-init. Within
+SMD, components are restored before redfish endpoints so that they keep their
+NIDs. Each record is added to its service. Records that already exist are
+skipped with a warning unless *--overwrite* is passed, in which case they are
+replaced with those of the backup. Records that exist in the cluster but not in
+the backup are left alone.
+
+Unless *--no-confirm* is passed, the user is asked to confirm the restore. If
+any record could not be restored, the command exits with a status of _1_ after
+attempting the rest.
+
+This command accepts the following options:
+
+*--no-confirm*
+	Do not ask the user to confirm the restore.
+
+*--only* _service_,...
+	Only restore the data of the listed services. Supported values are:
+
+	- _smd_
+	- _bss_
+	- _cloud-init_
+
+*--overwrite*
+	Replace records that already exist with those of the backup instead of
+	skipping them.
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1), *ochami-bss*(1), *ochami-cloud-init*(1), *ochami-smd*(1)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
@@ -6,8 +6,9 @@ ochami-bss - Communicate with the Boot Script Service (BSS)
 
 # SYNOPSIS
 
+ochami bss boot activity [OPTIONS]++
 ochami bss boot image set [OPTIONS]++
-ochami bss boot params (add | delete | get | set | update) [OPTIONS]++
+ochami bss boot params (add | delete | fmt | get | lint | set | update) [OPTIONS]++
 ochami bss boot script get [OPTIONS]++
 ochami bss service status [OPTIONS]++
 ochami bss service version
@@ -39,11 +40,65 @@ The data structure for sending and receiving data with subcommands under the
 	base URI (set with the *--cluster-uri* flag or the *cluster.uri* cluster
 	config option), which is required to be set if a relative path is used here.
 
+	This only applies to BSS. Commands that also communicate with other services,
+	e.g. SMD to look up nodes, use the URIs of those services from *--cluster-uri*
+	or the config file, not this one. Earlier versions applied *--uri* to every
+	service such a command communicated with.
+
 	See *ochami*(1) for *--cluster-uri* and *ochami-config*(5) for details on
 	cluster configuration options.
 
 # COMMANDS
 
+## boot activity
+
+Show which nodes have fetched a boot script recently, according to BSS's
+endpoint history. This is useful for finding nodes that are wedged.
+
+The format of this command is:
+
+*activity* [-F _format_] [--since _time_] [-x _xname_,...] [-g _group_,...] [--target _target_,...]
+
+The output contains *since*, the time after which a boot counts as recent,
+*recent*, the nodes that fetched a boot script since then, and *stale*, the
+nodes that have not. Each node is listed with its xname and, if BSS has a record
+of it fetching a boot script, *last_boot*, the time it last did so. A warning
+is logged if any nodes are stale.
+
+BSS only records the last time each node fetched a boot script, so the number of
+times a node has booted is not available.
+
+By default, all components of type _Node_ in SMD are checked. *--xname*,
+*--group*, and/or *--target* limit the nodes checked to those passed.
+
+This command sends a GET to BSS's /endpoint-history endpoint and, unless
+*--xname*, *--group*, or *--target* is passed, a GET to SMD's /State/Components
+endpoint.
+
+This command accepts the following options:
+
+*-F, --format-output* _format_
+	Output response data in specified _format_. Supported values are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*-g, --group* _group_,...
+	Check the members of one or more SMD groups.
+
+*--since* _time_
+	Either an RFC 3339 timestamp (e.g. _2025-01-01T10:00:00Z_) or a duration
+	before now (e.g. _36h_ or _7d_). Nodes that fetched a boot script at or
+	after this time are recent. The default is _24h_.
+
+*--target* _target_,...
+	Check one or more nodes by xname, NID, MAC address, or SMD group. See
+	*TARGETS* in *ochami*(1).
+
+*-x, --xname* _xname_,...
+	Check one or more nodes by xname.
+
 ## boot image
 
 Manage how nodes boot images.
@@ -84,15 +139,16 @@ Manage boot parameters for components.
 
 Subcommands for this command are as follows:
 
-*add* ([--mac _mac_,...] [--nid _nid_,...] [--xname _xname_,...]) ([--initrd _initrd_] [--kernel _kernel_])++
-*add* -d _data_ [-f _format_]++
-*add* -d @_file_ [-f _format_]++
-*add* -d @- [-f _format_] < _file_
+*add* [--if-not-exists] ([--mac _mac_,...] [--nid _nid_,...] [--xname _xname_,...] [--target _target_,...]) ([--initrd _initrd_] [--kernel _kernel_])++
+*add* [--if-not-exists] -d _data_ [-f _format_]++
+*add* [--if-not-exists] -d @_file_ [-f _format_]++
+*add* [--if-not-exists] -d @- [-f _format_] < _file_
 	Add new boot parameters for one or more components. If boot parameters
 	already exist for the specified components, this command will fail.
 
-	In the first form of the command, one or more of *--mac*, *--nid*, or
-	*--xname* is required to identify which component(s) to add boot config for.
+	In the first form of the command, one or more of *--mac*, *--nid*,
+	*--target*, or *--xname* is required to identify which component(s) to add
+	boot config for.
 	One or more of *--initrd*, *--kernel*, or *--params* is also required to
 	know which boot parameters to add for the specified components.  For any of
 	these options, multiple arguments can be passed either by specifying the
@@ -108,6 +164,9 @@ Subcommands for this command are as follows:
 	In the fourth form of the command, the payload data is read from standard
 	input.
 
+	Before sending, the boot parameters are checked as with *lint*, and any
+	findings are logged as warnings.
+
 	This command sends a POST request to BSS's /bootparameters endpoint.
 
 	This command accepts the following options:
@@ -136,6 +195,10 @@ Subcommands for this command are as follows:
 		either this flag can be specified multiple times or this flag can be
 		specified once and multiple NIDs can be specified, separated by commas.
 
+	*--target* _target_,...
+		One or more xnames, NIDs, MAC addresses, or SMD groups to add boot
+		parameters for. See *TARGETS* in *ochami*(1).
+
 	*-x, --xname* _xname_,...
 		One or more xnames to add boot parameters for. For multiple xnames,
 		either this flag can be specified multiple times or this flag can be
@@ -150,17 +213,23 @@ Subcommands for this command are as follows:
 	*--params* _kernel_params_
 		Command line arguments to pass to kernel for components.
 
-*delete* [--no-confirm] ([--mac, _mac_,...] [--nid, _nid_,...] [--xname _xname_,...] [--kernel _kernel_] [--initrd _initrd_])++
-*delete* [--no-confirm] -d _data_ [-f _format_]++
-*delete* [--no-confirm] -d @_file_ [-f _format_]++
-*delete* [--no-confirm] -d @- [-f _format_]
+	*--if-not-exists*
+		Succeed with an informational note instead of failing for items that
+		already exist (HTTP 409), so that the command can be rerun safely, e.g.
+		by automation.
+
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] ([--mac, _mac_,...] [--nid, _nid_,...] [--xname _xname_,...] [--target _target_,...] [--kernel _kernel_] [--initrd _initrd_])++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] -d _data_ [-f _format_]++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] -d @_file_ [-f _format_]++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] -d @- [-f _format_]++
+*delete* [--no-confirm] [--yes-really-delete _n_] (--kernel-glob _glob_ | --kernel-regex _regex_) [--replace-with _uri_] [--batch-size _n_] [--batch-delay _duration_] [--dry-run [-F _format_]]
 	Delete boot parameters for one or more components. Which boot parameters are
 	deleted are determined by passed filters, which can be passed via CLI flag
 	or within a payload file. Unless *--no-confirm* is passed, the user is asked
 	to confirm deletion.
 
 	In the first form of the command, one or more of *--mac*, *--nid*,
-	*--xname*, *--kernel*, or *--initrd* is required to identify which
+	*--target*, *--xname*, *--kernel*, or *--initrd* is required to identify which
 	component(s) whose boot parameters to delete. For any of these options,
 	multiple arguments can be passed either by specifying the flag multiple
 	times (e.g. *--mac* _mac1_ *--mac* _mac2_) or by using one flag and
@@ -175,7 +244,18 @@ Subcommands for this command are as follows:
 	In the fourth form of the command, the payload data is read from standard
 	input.
 
-	This command sends a DELETE request to BSS's /bootparameters endoint.
+	The fifth form of the command is meant for retiring an image server. Every
+	entry of boot parameters in BSS whose kernel or initrd URI matches
+	*--kernel-glob* or *--kernel-regex* is deleted or, with *--replace-with*,
+	has the URIs that match rewritten. The matching entries are listed with
+	their hosts and URIs before the user is asked to confirm, and are then sent
+	*--batch-size* at a time, waiting *--batch-delay* between batches so that
+	BSS is not flooded. If an entry fails, the others are still sent and the
+	command exits with an error at the end.
+
+	This command sends a DELETE request to BSS's /bootparameters endoint. The
+	fifth form sends a GET and then a DELETE, or with *--replace-with* a PATCH,
+	for each matching entry.
 
 	This command accepts the following options:
 
@@ -206,6 +286,10 @@ Subcommands for this command are as follows:
 		either this flag can be specified multiple times or this flag can be
 		specified once and multiple NIDs can be specified, separated by commas.
 
+	*--target* _target_,...
+		One or more xnames, NIDs, MAC addresses, or SMD groups to delete boot
+		parameters for. See *TARGETS* in *ochami*(1).
+
 	*-x, --xname* _xname_,...
 		One or more xnames to delete boot parameters for. For multiple xnames,
 		either this flag can be specified multiple times or this flag can be
@@ -220,9 +304,93 @@ Subcommands for this command are as follows:
 	*--params* _kernel_params_
 		Command line arguments to pass to kernel for components.
 
-*get* [-F _format_] [--mac _mac_,...] [--nid _nid_,...] [--xname _xname_,...]
+	*--yes-really-delete* _n_
+		Confirm deleting more than *delete-threshold* (see *ochami-config*(5))
+		items. _n_ must be the exact number of items to be deleted. Without it,
+		such deletions require the user to type the number of items, and are
+		refused if *--no-confirm* is passed.
+
+	*--if-exists*
+		Succeed with an informational note instead of failing for items that do
+		not exist (HTTP 404), so that the command can be rerun safely, e.g. by
+		automation.
+
+	*--kernel-glob* _glob_
+		Select the entries whose kernel or initrd URI matches _glob_ as a whole,
+		in which *\** matches any characters, including slashes, and *?* any
+		single character (e.g. _https://old-repo/\*_).
+
+	*--kernel-regex* _regex_
+		Select the entries whose kernel or initrd URI matches the regular
+		expression _regex_ as a whole.
+
+	*--replace-with* _uri_
+		Rewrite the kernel and initrd URIs that match to _uri_ instead of
+		deleting the entries. _$1_, _$2_, etc. (or _${1}_ when followed by a
+		letter or digit) are replaced with what each wildcard of the glob, or
+		group of the regular expression, matched. For example:
+
+		```
+		ochami bss boot params delete --kernel-glob 'https://old-repo/*' \
+		  --replace-with 'https://new-repo/$1'
+		```
+
+	*--batch-size* _n_
+		Number of entries to send at a time. Default: _50_.
+
+	*--batch-delay* _duration_
+		Time to wait between batches (e.g. _5s_). Default: _1s_.
+
+	*--dry-run*
+		Print the matching entries, with the URIs they would be rewritten to,
+		without changing them.
+
+	*-F, --format-output* _format_
+		With *--dry-run*, format of the output. Supported formats are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+*fmt* [-d (_data_ | @_path_ | @-)] [-f _format_] [-F _format_]
+	Print a boot parameter payload, which is either a single set of boot
+	parameters or a list of them, in canonical form so that boot parameter files
+	kept in version control have minimal diffs:
+
+	- Hosts and NIDs are sorted and de-duplicated.
+	- MAC addresses are lowercased, colon-separated, sorted, and de-duplicated.
+	- Surrounding whitespace is trimmed from hosts, MAC addresses, *params*,
+	  *kernel*, and *initrd*.
+	- Keys are sorted, and empty values are left out.
+	- In a list, duplicate entries are removed and the entries are sorted by
+	  their hosts, then MAC addresses, then NIDs.
+
+	If *-d* is not passed, the payload is read from standard input. Nothing is
+	sent to BSS.
+
+	This command accepts the following options:
+
+	*-d, --data* (_data_ | @_path_ | @-)
+		Specify raw _data_ to format, the _path_ to a file to read it from, or
+		to read it from standard input (@-).
+
+	*-F, --format-output* _format_
+		Output the payload in specified _format_. If unset, the input format
+		is used, with JSON being pretty-printed. Supported values are:
+
+		- _json_
+		- _json-pretty_
+		- _yaml_
+
+	*-f, --format-input* _format_
+		Format of the payload. Supported values are:
+
+		- _json_ (default)
+		- _yaml_
+
+*get* [-F _format_] [--mac _mac_,...] [--nid _nid_,...] [--xname _xname_,...] [--target _target_,...]
 	Get boot parameters for all components or a subset of components, filtered
-	by MAC address, node ID, and/or xname.
+	by MAC address, node ID, xname, and/or target.
 
 	This command sends a GET to BSS's /bootparameters endpoint.
 
@@ -245,12 +413,116 @@ Subcommands for this command are as follows:
 		either this flag can be specified multiple times or this flag can be
 		specified once and multiple NIDs can be specified, separated by commas.
 
+	*--target* _target_,...
+		One or more xnames, NIDs, MAC addresses, or SMD groups to filter boot
+		parameters by. See *TARGETS* in *ochami*(1).
+
 	*-x, --xname* _xname_,...
 		One or more xnames to filter boot parameters by. For multiple xnames,
 		either this flag can be specified multiple times or this flag can be
 		specified once and multiple xnames, separated by commas.
 
-*set* ([--mac _mac_,...] [--nid _nid_,...] [--xname _xname_,...]) ([--initrd _initrd_] [--kernel _kernel_])++
+*lint* [--mac _mac_,...] [--nid _nid_,...] [--xname _xname_,...] [--rules _path_] [-F _format_] [--show-location] [--report-format _format_ [--report-file _path_]]++
+*lint* -d (_data_ | @_path_ | @-) [-f _format_] [--rules _path_] [-F _format_] [--show-location] [--report-format _format_ [--report-file _path_]]
+	Check boot parameters for kernel command line pitfalls. In the first form
+	of the command, the boot parameters in BSS are checked, optionally filtered
+	as with *get*. In the second form of the command, the boot parameters in the
+	payload, which is either a single set of boot parameters or a list of them,
+	are checked instead.
+
+	The following built-in rules are checked:
+
+	- _duplicate-arg_ (warning): an argument is passed more than once.
+	- _multiple-root_ (error): *root=* is set to more than one value.
+	- _conflicting-arg_ (error): *init=* or *rootfstype=* is set to more
+	  than one value.
+	- _ip-conflict_ (error): two *ip=* arguments configure the same
+	  interface, e.g. DHCP alongside a static address for it, or one of
+	  them configures all interfaces.
+	- _missing-initrd_ (error): no initrd is set, but *root=* is one that
+	  only an initramfs can mount (e.g. _live:_, _nfs:_, _LABEL=_, or _UUID=_)
+	  or *rd.\** arguments are passed.
+
+	Site rules are read from the file passed to *--rules* or, if it is not
+	passed, from the file set as *lint-rules* in the cluster's *bss* config
+	(see *ochami-config*(5)). Each rule has a _name_, a _level_ (_error_ or
+	_warning_, the default), an optional _message_ to report instead of the
+	default one, and at least one of:
+
+	- _forbid_: arguments that must not be passed.
+	- _require_: arguments that must be passed.
+	- _pattern_: a regular expression that the kernel parameters must not
+	  match.
+
+	Arguments in _forbid_ and _require_ are either a key (e.g. _quiet_),
+	matching the argument with any value, or a key and a value pattern (e.g.
+	_console=ttyS0,\*_). For example, to require a serial console and, for
+	nodes booting an EFI stub kernel, an *initrd=* argument:
+
+	```
+	rules:
+	- name: serial-console
+	  level: error
+	  require: ["console=ttyS0,*"]
+	- name: efi-stub-needs-initrd
+	  require: [initrd]
+	```
+
+	The findings are printed, and the command fails if there are any. The same
+	checks are run by *add* and *set*, which only log findings as warnings.
+
+	This command sends a GET to BSS's /bootparameters endpoint unless *-d* is
+	passed.
+
+	This command accepts the following options:
+
+	*-d, --data* (_data_ | @_path_ | @-)
+		Specify raw _data_ containing the boot parameters to check, the _path_
+		to a file to read it from, or to read it from standard input (@-). The
+		format of data read in any of these forms is JSON by default unless
+		*-f* is specified to change it.
+
+	*-F, --format-output* _format_
+		Output findings in specified _format_. Supported values are:
+
+		- _json_ (default)
+		- _yaml_
+
+	*-f, --format-input* _format_
+		Format of raw data being used by *-d*. Supported formats are:
+
+		- _json_ (default)
+		- _yaml_
+
+	*-m, --mac* _mac_addr_,...
+		One or more MAC addresses whose boot parameters to check.
+
+	*-n, --nid* _nid_,...
+		One or more node IDs whose boot parameters to check.
+
+	*-x, --xname* _xname_,...
+		One or more xnames whose boot parameters to check.
+
+	*--report-file* _path_
+		Write the report requested with *--report-format* to _path_ in addition
+		to the normal output. By default, the report is written to standard
+		output instead of the normal output.
+
+	*--report-format* _format_
+		Write findings as a report for CI systems. Supported values are:
+
+		- _junit_
+		- _sarif_
+
+	*--rules* _path_
+		Read site rules from _path_ instead of the file set as *lint-rules* in
+		the cluster config.
+
+	*--show-location*
+		Include the physical location of the hosts of each set of boot
+		parameters in the findings and report (see *locations* in *ochami-config*(5)).
+
+*set* ([--mac _mac_,...] [--nid _nid_,...] [--xname _xname_,...] [--target _target_,...] [--selector meta._key_=_value_]...) [--arch _arch_] ([--initrd _initrd_] [--kernel _kernel_])++
 *set* -d _data_ [-f _format_]++
 *set* -d @_file_ [-f _format_]++
 *set* -d @- [-f _format_] < _file_
@@ -259,8 +531,9 @@ Subcommands for this command are as follows:
 	parameters to set for which components, but isn't sure if boot parameters
 	have already been set for one or more of them.
 
-	In the first form of the command, one or more of *--mac*, *--nid*, or
-	*--xname* is required to identify which component(s) to set boot config for.
+	In the first form of the command, one or more of *--mac*, *--nid*,
+	*--selector*, *--target*, or *--xname* is required to identify which
+	component(s) to set boot config for.
 	One or more of *--initrd*, *--kernel*, or *--params* is also required to
 	know which boot parameters to set for the specified components.  For any of
 	these options, multiple arguments can be passed either by specifying the
@@ -276,10 +549,18 @@ Subcommands for this command are as follows:
 	In the fourth form of the command, the payload data is read from standard
 	input.
 
+	Before sending, the boot parameters are checked as with *lint*, and any
+	findings are logged as warnings.
+
 	This command sends a PUT request to BSS's /bootparameters endpoint.
 
 	This command accepts the following options:
 
+	*--arch* _arch_
+		Only set boot parameters for the targets of architecture _arch_
+		(_x86_64_ or _aarch64_), or, if no targets are passed, for the nodes of
+		it that are not in SMD. See *ARCHITECTURE VARIANTS*.
+
 	*-d, --data* (_data_ | @_path_ | @-)
 		Specify raw _data_ to send, the _path_ to a file to read payload data
 		from, or to read the data from standard input (@-). The format of data
@@ -304,6 +585,15 @@ Subcommands for this command are as follows:
 		either this flag can be specified multiple times or this flag can be
 		specified once and multiple NIDs can be specified, separated by commas.
 
+	*--selector* meta._key_=_value_
+		Set boot parameters for the components whose metadata has _key_ set to
+		_value_ (see *ochami-meta*(1)). This flag can be passed more than once
+		to select the components matching all selectors.
+
+	*--target* _target_,...
+		One or more xnames, NIDs, MAC addresses, or SMD groups to set boot
+		parameters for. See *TARGETS* in *ochami*(1).
+
 	*-x, --xname* _xname_,...
 		One or more xnames to set boot parameters for. For multiple xnames,
 		either this flag can be specified multiple times or this flag can be
@@ -316,12 +606,16 @@ Subcommands for this command are as follows:
 		URI from which to fetch the components' kernel.
 
 	*--params* _kernel_params_
-		Command line arguments to pass to kernel for components.
+		Command line arguments to pass to kernel for components. If
+		_kernel_params_ (whether passed here or in the payload) contains
+		*{{*, it is treated as a template that is rendered separately for
+		each targeted component. See *KERNEL PARAMETER TEMPLATES*.
 
-*update* ([--mac _mac_,...] [--nid _nid_,...] [--xname _xname_,...]) ([--initrd _initrd_] [--kernel _kernel_])++
+*update* ([--mac _mac_,...] [--nid _nid_,...] [--xname _xname_,...]) [--arch _arch_] ([--initrd _initrd_] [--kernel _kernel_])++
 *update* -d _data_ [-f _format_]++
 *update* -d @_file_ [-f _format_]++
-*update* -d @- [-f _format_] < _file_
+*update* -d @- [-f _format_] < _file_++
+*update* ([--mac _mac_,...] [--nid _nid_,...] [--xname _xname_,...]) --patch (_data_ | @_path_) [--patch-type _type_] [-f _format_]
 	Update boot parameters for existing components.
 
 	In the first form of the command, one or more of *--mac*, *--nid*, or
@@ -341,10 +635,22 @@ Subcommands for this command are as follows:
 	In the fourth form of the command, the payload data is read from standard
 	input.
 
-	This command sends a PUT request to BSS's /bootparameters endpoint.
+	In the fifth form of the command, the boot parameters matching *--mac*,
+	*--nid*, and/or *--xname* are fetched from BSS and the patch document
+	passed to *--patch* is applied to each set client-side. Each patched set
+	is then sent with a PUT, replacing the existing set. *--initrd*,
+	*--kernel*, and *--params* cannot be used with this form.
+
+	This command sends a PATCH request to BSS's /bootparameters endpoint, or
+	a PUT request if *--patch* is used.
 
 	This command accepts the following options:
 
+	*--arch* _arch_
+		Only update boot parameters of the targets of architecture _arch_
+		(_x86_64_ or _aarch64_), or, if no targets are passed, of the nodes of
+		it that are not in SMD. See *ARCHITECTURE VARIANTS*.
+
 	*-d, --data* (_data_ | @_path_ | @-)
 		Specify raw _data_ to send, the _path_ to a file to read payload data
 		from, or to read the data from standard input (@-). The format of data
@@ -352,12 +658,24 @@ Subcommands for this command are as follows:
 		to change it.
 
 	*-f, --format-input* _format_
-		Format of raw data being used by *-d* as the payload. Supported formats
+		Format of raw data being used by *-d* or *--patch*. Supported formats
 		are:
 
 		- _json_ (default)
 		- _yaml_
 
+	*--patch* (_data_ | @_path_ | @-)
+		Specify a patch document to apply client-side to the current
+		boot parameters. Like *-d*, the document can be passed raw, read from
+		_path_, or read from standard input (@-), and is JSON unless *-f* is
+		specified.
+
+	*--patch-type* _type_
+		Type of the patch document passed to *--patch*. Supported types are:
+
+		- _json_ (default): a JSON Patch (RFC 6902) array of operations
+		- _merge_: a JSON Merge Patch (RFC 7396) partial document
+
 	*-m, --mac* _mac_addr_,...
 		One or more MAC addresses to update boot parameters for. For multiple
 		MAC addresses, either this flag can be specified multiple times or this
@@ -381,7 +699,10 @@ Subcommands for this command are as follows:
 		URI from which to fetch the components' kernel.
 
 	*--params* _kernel_params_
-		Command line arguments to pass to kernel for components.
+		Command line arguments to pass to kernel for components. If
+		_kernel_params_ (whether passed here or in the payload) contains
+		*{{*, it is treated as a template that is rendered separately for
+		each targeted component. See *KERNEL PARAMETER TEMPLATES*.
 
 ## boot script
 
@@ -389,9 +710,10 @@ Manage boot scripts for components.
 
 Subcommands for this command are as follows:
 
-*get* ([--mac _mac_] [--nid _nid_] [--xname _xname_])
+*get* ([--mac _mac_] [--nid _nid_] [--xname _xname_] [--target _target_])
 	Get the iPXE boot script for a component. Exactly one of *--mac*, *--nid*,
-	or *--xname* is required to specify the component whose boot script to get.
+	*--target*, or *--xname* is required to specify the component whose boot
+	script to get.
 	Note that only *one* component's boot script is fetched.
 
 	This command sends a GET to BSS's /bootscript endpoint.
@@ -404,6 +726,10 @@ Subcommands for this command are as follows:
 	*-n, --nid* _nid_
 		Node ID corresponding to component whose boot script to get.
 
+	*--target* _target_
+		Xname, NID, MAC address, or SMD group corresponding to component whose
+		boot script to get. See *TARGETS* in *ochami*(1).
+
 	*-x, --xname* _xname_
 		Xname corresponding to component whose boot script to get.
 
@@ -416,9 +742,11 @@ components list.
 
 The format of this command is:
 
-*dumpstate* [-F _format_]
+*dumpstate* [--redact [--mapping-file _path_]] [-F _format_]
 
-This command sends a GET to BSS's /dumpstate endpoint.
+This command sends a GET to BSS's /dumpstate endpoint. On large systems, the
+response is spilled to a temporary file and printed as a stream (see
+*--max-memory-buffer* in *ochami*(1)), unless *--redact* is passed.
 
 This command accepts the following options:
 
@@ -428,6 +756,14 @@ This command accepts the following options:
 	- _json_ (default)
 	- _yaml_
 
+*--mapping-file* _path_
+	With *--redact*, read pseudonyms from and save them to _path_. See
+	*ochami-redact*(1).
+
+*--redact*
+	Pseudonymize the hostnames, MAC addresses, IP addresses, and UUIDs in the
+	output, e.g. to attach it to a bug report. See *ochami-redact*(1).
+
 ## history
 
 Print endpoint access history. This command outputs a list of logs of accesses
@@ -533,13 +869,98 @@ Subcommands for this command are as follows:
 
 This command is DEPRECATED. Use *service status* instead.
 
+# ARCHITECTURE VARIANTS
+
+BSS serves the same boot parameters to a node regardless of its architecture,
+except to nodes that are not in SMD, which get the boot parameters of the host
+_Unknown-<arch>_, where _<arch>_ is the architecture iPXE reports (_x86_64_ or
+_arm64_). To set different boot parameters for nodes of different architectures
+with one command, *boot params set* and *boot params update* accept variants of
+the kernel, initrd, and kernel parameters per architecture, either with
+*--arch* or in the payload under _arch_, keyed by architecture (_x86_64_ or
+_aarch64_, or their aliases _amd64_ and _arm64_).
+
+The architecture of each target is its _Arch_ in SMD (_X86_ or _ARM_). The
+kernel, initrd, and kernel parameters set in the variant of its architecture
+override those set outside of _arch_ for the target. Targets of other or
+unknown architectures get the boot parameters set outside of _arch_, or are
+skipped (and logged) if there are none. Without targets, each variant is set
+for the nodes of its architecture that are not in SMD. For example, to set the
+kernel per architecture for two nodes:
+
+```
+ochami bss boot params set -f yaml -d @- <<EOF
+hosts: [x1000c1s7b0n0, x1000c1s7b1n0]
+params: quiet
+arch:
+  x86_64: {kernel: https://example.com/kernel-x86_64}
+  aarch64: {kernel: https://example.com/kernel-aarch64}
+EOF
+```
+
+Passing *--arch* _arch_ is the same as putting the *--initrd*, *--kernel*, and
+*--params* (or those of the payload) in the variant of _arch_, so only the
+targets of _arch_ are set.
+
+# KERNEL PARAMETER TEMPLATES
+
+The kernel parameters passed to *boot params set* and *boot params update* can
+be a Go template (see https://pkg.go.dev/text/template) so that one command can
+set node-specific command lines. For each xname, MAC address, or NID targeted,
+the component is looked up in SMD and the template is rendered with the
+following variables:
+
+	*.xname*
+		The component's xname.
+
+	*.nid*
+		The component's node ID.
+
+	*.type*
+		The component's type (e.g. _Node_).
+
+	*.role*
+		The component's role.
+
+	*.arch*
+		The component's architecture.
+
+	*.mac*
+		The targeted MAC address for targets passed as MAC addresses. For other
+		targets, the MAC address of the component's first ethernet interface in
+		SMD that has an IP address, or else of its first ethernet interface.
+		These are only fetched from SMD if the template references *.mac*, and
+		it is an error if the component has none.
+
+	*.meta_data*
+		The component's cloud-init meta-data, as a map. This is only fetched
+		from cloud-init if the template references it. Keys containing hyphens
+		can be accessed with *index*, e.g. *{{ index .meta_data "local-ipv4" }}*.
+
+	*.vars*
+		The component's variables set with *ochami vars set*, merged over those
+		of the SMD groups it is a member of (see *ochami-vars*(1)). These are
+		only read if the template references them, e.g. *{{ .vars.console }}*.
+
+Referencing a variable that does not exist is an error. Components whose
+rendered parameters are identical are sent in the same request, so the number
+of requests sent to BSS is the number of distinct rendered command lines.
+
+For example, to set a static IP configuration for each node in the cloud-init
+meta-data:
+
+```
+ochami bss boot params set --xname x1000c0s0b0n0,x1000c0s1b0n0 \
+	--params 'console=ttyS0 nid={{ .nid }} ip={{ index .meta_data "local-ipv4" }}::10.0.0.254:255.255.255.0::eth0:none'
+```
+
 # AUTHOR
 
 Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
 
 # SEE ALSO
 
-*ochami*(1)
+*ochami*(1), *ochami-redact*(1)
 
 ; Vim modeline settings
 ; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
@@ -13,15 +13,20 @@ ochami cloud-init group delete [OPTIONS] ([-d (_data_ | @_path_)] [-f _format_])
 ochami cloud-init group get [OPTIONS] raw [_id_...]++
 ochami cloud-init group get [OPTIONS] config [_id_...]++
 ochami cloud-init group get [OPTIONS] meta-data [_id_...]++
-ochami cloud-init group render _group_ _id_++
+ochami cloud-init group list [OPTIONS]++
+ochami cloud-init group render [OPTIONS] _group_ _id_...++
 ochami cloud-init group set [OPTIONS]++
 ochami cloud-init node get group [OPTIONS] _group_ _id_...++
 ochami cloud-init node get meta-data [OPTIONS] _id_...++
 ochami cloud-init node get user-data [OPTIONS] _id_...++
 ochami cloud-init node get vendor-data [OPTIONS] _id_...++
+ochami cloud-init node import [OPTIONS] --dir _dir_++
 ochami cloud-init node set [OPTIONS]++
+ochami cloud-init secret check [OPTIONS] (_ref_... | -d (_data_ | @_path_))++
 ochami cloud-init service status [OPTIONS]++
-ochami cloud-init service version [OPTIONS]
+ochami cloud-init service version [OPTIONS]++
+ochami cloud-init snapshot [OPTIONS] [_id_...]++
+ochami cloud-init verify --ssh [OPTIONS] --node _id_,...
 
 # DATA STRUCTURE
 
@@ -163,6 +168,55 @@ http://172.16.0.254:8081/cloud-init/compute.yaml
 http://172.16.0.254:8081/cloud-init/slurm.yaml
 ```
 
+# SECRET REFERENCES
+
+Cloud-config files can reference secrets stored outside of the cloud-init
+service with the syntax:
+
+```
+{{ secret "<backend>:<path>" }}
+```
+
+References are resolved by *ochami*, not by cloud-init, so that the values of
+secrets do not need to be stored in cloud-init where policy does not allow it.
+They are resolved by *cloud-init group render* and, if *--resolve-secrets* is
+passed, by *cloud-init group add* and *cloud-init group set* before sending.
+References that are not resolved are stored as-is.
+
+A reference is replaced by the value of the secret as a YAML double-quoted
+string, with any quotes around the reference, so that values containing e.g.
+colons, *#*, quotes, or newlines are read back as is. References should
+therefore be the whole value of a key or list item, e.g.:
+
+```
+password: {{ secret "vault:kv/cluster/root-pass" }}
+```
+
+The following backends are supported:
+
+*env:*_VAR_
+	The value of the environment variable _VAR_.
+
+*file:*_path_
+	The contents of the file at _path_, without any trailing newline.
+
+*sops:*_path_[#_key_]
+	The value at _key_ (a dot-separated path, e.g. _root.password_) of the
+	SOPS-encrypted file at _path_, decrypted with *sops*(1). If _key_ is
+	omitted, the whole decrypted file is used.
+
+*vault:*_mount_/_path_/_key_++
+*vault:*_mount_/_path_#_key_
+	The value of _key_ in the secret at _path_ in the Vault KV version 2
+	secrets engine mounted at _mount_. For instance,
+	_vault:kv/cluster/root-pass_ is the key _root-pass_ of the secret
+	_cluster_ in the engine mounted at _kv_. The Vault URI is read from
+	*cluster.secrets.vault-uri* (see *ochami-config*(5)) or, if unset, the
+	*VAULT_ADDR* environment variable. The Vault token is read from an
+	environment variable named in the same manner as the access token but
+	ending in *\_VAULT_TOKEN* (e.g. *MY_CLUSTER_VAULT_TOKEN*) or, if unset,
+	*VAULT_TOKEN*.
+
 # GLOBAL FLAGS
 
 The *cloud-init* command accepts the following global flags:
@@ -179,6 +233,11 @@ The *cloud-init* command accepts the following global flags:
 	base URI (set with the *--cluster-uri* flag or the *cluster.uri* cluster
 	config option), which is required to be set if a relative path is used here.
 
+	This only applies to cloud-init. Commands that also communicate with other
+	services, e.g. SMD to look up group members, use the URIs of those services
+	from *--cluster-uri* or the config file, not this one. Earlier versions applied
+	*--uri* to every service such a command communicated with.
+
 	See *ochami*(1) for *--cluster-uri* and *ochami-config*(5) for details on
 	cluster configuration options.
 
@@ -205,6 +264,67 @@ Subcommands for this command are as follows:
 		- _json-pretty_
 		- _yaml_
 
+*import* --dir _dir_ [--state-file _path_] [--force] [--dry-run] [-F _format_]
+	Import the data of nodes from a directory tree with one subdirectory per
+	node, named after its ID, so that the data can be kept in a git
+	repository:
+
+	```
+	nodes/
+	├── x3000c0s0b0n0/
+	│   └── meta-data
+	└── x3000c0s1b0n0/
+	    ├── meta-data
+	    └── user-data
+	```
+
+	Each node directory may contain _meta-data_, _user-data_, and
+	_vendor-data_. Hidden files and directories are ignored, as are files
+	directly in _dir_. Any other file in a node directory is an error, so that
+	misnamed files are not silently ignored.
+
+	_meta-data_ is YAML (or JSON) instance info (see *INSTANCE INFO*). Its *id*
+	key may be omitted and defaults to the name of the node directory; if set,
+	it must match it. Unknown keys are an error. It is set with a PUT to the
+	*/cloud-init/admin/instance-info/{id}* endpoint for each node. cloud-init
+	does not store node-specific user-data or vendor-data (it generates them
+	from the groups of the node), so _user-data_ and _vendor-data_ files are
+	skipped with a warning.
+
+	Only files that changed since they were last imported into the cluster are
+	imported. The hash of each imported file is saved to a state file per
+	cloud-init base URI, so one directory can be imported into several
+	clusters. Changes made in cloud-init by other means are not detected; pass
+	*--force* to import every file again.
+
+	The outcome for each file is printed: _imported_, _unchanged_, _skipped_,
+	_failed_, or, with *--dry-run*, _would-import_. The command exits with an
+	error if any file failed to be imported.
+
+	This command accepts the following options:
+
+	*--dir* _dir_
+		Directory of node data to import. Required.
+
+	*--dry-run*
+		Show what would be imported without importing anything.
+
+	*-F, --format-output* _format_
+		Output data in the specified _format_. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--force*
+		Import every file, even those that did not change since they were last
+		imported.
+
+	*--state-file* _path_
+		File to save the hashes of imported files to. Default:
+		_.ochami-import.json_ in _dir_, which may be added to the
+		_.gitignore_ of the repository.
+
 *set* [-f _format_] < _file_++
 *set* [-f _format_] -d @_file_++
 *set* [-f _format_] -d @- < _file_++
@@ -239,16 +359,56 @@ Subcommands for this command are as follows:
 		- _json-pretty_
 		- _yaml_
 
+## diff
+
+*diff* [--prune] [--apply [--no-confirm] [--yes-really-delete _n_]] _dir_
+	Compare the cloud-configs of the groups in cloud-init with those in the
+	directory _dir_ and print the differences as a unified diff. _dir_ contains
+	one cloud-config per group, named after the group with an optional
+	extension (e.g. _compute.yaml_ for the _compute_ group). Hidden files and
+	subdirectories are ignored.
+
+	Groups in _dir_ but not in cloud-init are shown as new. Groups in cloud-init
+	but not in _dir_ are left alone unless *--prune* is passed, in which case
+	they are shown as deleted.
+
+	With *--apply*, cloud-init is then changed to match _dir_: new groups are
+	added, changed groups have their cloud-config replaced, keeping their
+	description and meta-data, and, with *--prune*, groups not in _dir_ are
+	deleted. Keeping _dir_ in a git repository and running this command when it
+	changes gives a simple GitOps workflow for cloud-init content.
+
+	This command sends a GET to the */cloud-init/admin/groups* endpoint and, with
+	*--apply*, a POST, PUT, or DELETE for each group that differs.
+
+	This command accepts the following options:
+
+	*--apply*
+		Make cloud-init match _dir_ after printing the differences. Unless
+		*--no-confirm* is passed, the user is asked to confirm.
+
+	*--no-confirm*
+		Do not ask the user to confirm applying changes. Use with caution.
+
+	*--prune*
+		Show groups in cloud-init that are not in _dir_ as deleted and, with
+		*--apply*, delete them.
+
+	*--yes-really-delete* _n_
+		Confirm deleting more than *delete-threshold* (see *ochami-config*(5))
+		groups with *--prune*. _n_ must be the exact number of groups to be
+		deleted.
+
 ## group
 
 Get and manage cloud-init group data.
 
 Subcommands for this command are as follows:
 
-*add* [-f _format_] < _file_
-*add* [-f _format_] -d @_file_++
-*add* [-f _format_] -d @- < _file_++
-*add* [-f _format_] -d _data_
+*add* [--if-not-exists] [-f _format_] < _file_
+*add* [--if-not-exists] [-f _format_] -d @_file_++
+*add* [--if-not-exists] [-f _format_] -d @- < _file_++
+*add* [--if-not-exists] [-f _format_] -d _data_
 	Add one or more new cloud-init groups. This command only accepts an array of
 	group data (see *GROUP DATA*) and uses the *name* field to determine how to
 	name the new group.
@@ -280,10 +440,21 @@ Subcommands for this command are as follows:
 		- _json-pretty_
 		- _yaml_
 
-*delete* [--no-confirm] _group_name_...++
-*delete* [--no-confirm] [-f _format_] -d @_file_++
-*delete* [--no-confirm] [-f _format_] -d @- < _file_++
-*delete* [--no-confirm] [-f _format_] -d _data_
+	*--resolve-secrets*
+		Replace secret references (see *SECRET REFERENCES*) in the cloud-config
+		file of each group with the values of the secrets before sending. If
+		any cannot be resolved, nothing is sent. Without this flag, a warning
+		is logged for each group containing secret references.
+
+	*--if-not-exists*
+		Succeed with an informational note instead of failing for items that
+		already exist (HTTP 409), so that the command can be rerun safely, e.g.
+		by automation.
+
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] _group_name_...++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] [-f _format_] -d @_file_++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] [-f _format_] -d @- < _file_++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] [-f _format_] -d _data_
 	Delete one or more cloud-init groups, identified by one or more _group_name_
 	arguments or *name* fields in payload data.
 
@@ -321,6 +492,17 @@ Subcommands for this command are as follows:
 		- _json-pretty_
 		- _yaml_
 
+	*--yes-really-delete* _n_
+		Confirm deleting more than *delete-threshold* (see *ochami-config*(5))
+		items. _n_ must be the exact number of items to be deleted. Without it,
+		such deletions require the user to type the number of items, and are
+		refused if *--no-confirm* is passed.
+
+	*--if-exists*
+		Succeed with an informational note instead of failing for items that do
+		not exist (HTTP 404), so that the command can be rerun safely, e.g. by
+		automation.
+
 *get*
 	Get cloud-init group data.
 
@@ -406,7 +588,37 @@ Subcommands for this command are as follows:
 			- _json-pretty_
 			- _yaml_
 
-*render* _group_name_ _node_id_
+*list* [--with-usage] [-F _format_]
+	List all cloud-init groups. By default, the name and description of each
+	group is printed, along with the _annotation_ of who last changed it with
+	ochami, when, and with what comment, if it has one (see *ANNOTATIONS* in
+	*ochami*(1)).
+
+	If *--with-usage* is passed, SMD is also queried for its groups and
+	components to report, for each cloud-init group, which nodes it applies
+	to (members of the SMD group with the same name), whether its
+	cloud-config is _ok_, _empty_, or _invalid_ (not parseable as YAML), and
+	whether it is orphaned (no nodes reference it). Nodes in SMD that do not
+	receive a usable cloud-config from any group are listed under
+	*unconfigured_nodes*. Warnings are logged for each of these conditions.
+
+	This command accepts the following flags:
+
+	*-F, --format-output* _format_
+		Format the response output as _format_.
+
+		Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--with-usage*
+		Include SMD usage and config health for each group.
+
+*render* [--no-resolve] [--format _format_ [-o _dir_]] _group_name_ _node_id_++
+*render* -o _dir_ [--concurrency _n_] [--no-resolve] [--format _format_] _group_name_ _node_id_...++
+*render* --meta-data-snapshot _file_ [--diff] [OPTIONS] _group_name_ _node_id_...
 	Print the cloud-init group configuration for _group_name_, impersonating
 	node _node_id_, populating Jinja2 variables. _node_id_ must be a member of
 	group _group_name_. This command is similar to the *cloud-init get config*
@@ -418,7 +630,54 @@ Subcommands for this command are as follows:
 	_node_id_ (*cloud-init node get meta-data*), then using the meta-data to
 	render the group config locally. Note that this command only renders the
 	group configuration for a node and does not go through cloud-init's full
-	render process.
+	render process. Secret references (see *SECRET REFERENCES*) are resolved
+	before rendering.
+
+	If the config or an included template references _vars_, the node's
+	variables set with *ochami vars set* (see *ochami-vars*(1)) are also
+	available, e.g. _{{ vars.console }}_.
+	Since cloud-init does not know these variables, they are only rendered by
+	this command.
+
+	If the rendered config is an _#include_ part or a MIME multi-part
+	archive, it is resolved the way cloud-init would on the node: each URL
+	listed by _#include_ or _#include-once_ (or a _text/x-include-url_ MIME
+	part) is fetched with a GET and processed in turn, parts that are Jinja
+	templates are rendered with the node's meta-data, and all cloud-config
+	parts are merged in order. Each part is merged using the merge algorithm
+	in its *merge_how* (or *merge_type*) key or *Merge-Type* MIME header,
+	e.g. _dict(no_replace,recurse_list)+list(append)_, or cloud-init's default
+	_dict(replace)+list()+str()_ if it has none. The merged cloud-config is
+	printed with a comment listing the parts it was merged from. Parts that
+	are not cloud-config (e.g. shell scripts) are not merged, and a warning is
+	printed for each. Only http and https URLs can be included.
+
+	With *--format*, the resolved config is post-processed for inspection
+	instead of being printed as is (see below).
+
+	In the second form of the command, the config is rendered for each
+	_node_id_, e.g. to review the configs of a whole rack before booting it.
+	The output for each node is written to _dir_/_node_id_.yaml
+	(_.mime_ or _.sh_ with *--format mime* or *shell*), whose path is
+	printed, or its files are extracted under _dir_/_node_id_ with *--format
+	write-files-extract*. The group config, secrets, variables, and included
+	URLs are fetched only once, and concurrent fetches of the same URL are
+	coalesced, so that each node only costs a meta-data request. Since the
+	group config is fetched through the first _node_id_ only, the members of
+	the group are fetched from SMD and a node that is not one of them is an
+	error. If rendering fails for any node, the others are still rendered and
+	the exit status is 1.
+
+	In the third form of the command, the config is rendered against the
+	meta-data saved for each _node_id_ in _file_ by *cloud-init snapshot*
+	instead of its current meta-data, e.g. to test template changes against
+	the data of the day before, and no meta-data requests are sent. It is an
+	error if _file_ has no meta-data for a _node_id_. With *--diff*, the
+	config is rendered against both the snapshot and the current meta-data,
+	and the differences from the former to the latter are printed as a
+	unified diff, which is empty if there are none. When rendering for more
+	than one node, the diff of each node that has differences is written to
+	_dir_/_node_id_.diff instead.
 
 	This command is meant as a troubleshooting tool.
 
@@ -427,10 +686,63 @@ Subcommands for this command are as follows:
 	- */cloud-init/admin/impersonation/{id}/{group}.yaml*
 	- */cloud-init/admin/impersonation/{id}/meta-data*
 
+	When rendering for more than one node, it also sends a GET request to the
+	*/hsm/v2/groups/{label}/members* SMD endpoint.
+
+	This command accepts the following options:
+
+	*--concurrency* _n_
+		Maximum number of nodes to render at once when rendering for more
+		than one node. Default: _8_.
+
+	*--diff*
+		With *--meta-data-snapshot*, print the differences from rendering
+		against the snapshot to rendering against the current meta-data
+		instead of the rendered config. It cannot be used with *--format
+		write-files-extract*.
+
+	*--format* _format_
+		Format to print the rendered config in. Supported values are:
+
+		- _yaml_ (default): the config, resolved if needed as described
+		  above.
+		- _mime_: a MIME multi-part archive, like the one *cloud-init devel
+		  make-mime* assembles, of the merged cloud-config followed by the
+		  parts that are not cloud-config (e.g. shell scripts), which are
+		  thus not warned about.
+		- _shell_: the *runcmd* list of the config as the shell script
+		  cloud-init runs, so that the commands can be reviewed or run by
+		  hand. Items that are lists are quoted for the shell. If the config
+		  has no *runcmd*, a warning is printed.
+		- _write-files-extract_: the files in the *write_files* list of the
+		  config are written under the directory passed with *--output-dir*,
+		  each at its path on the node, and the paths written are printed.
+		  Encoded content (_b64_, _gzip_, or _gz+b64_), *append*, and
+		  *permissions* are honored, but *owner* is not. The content of files
+		  with a *source* URI is fetched like an _#include_, falling back to
+		  their *content* if fetching fails.
+
+		All formats other than _yaml_ always resolve the config, so they
+		cannot be used with *--no-resolve*.
+
+	*--meta-data-snapshot* _file_
+		Render against the meta-data in _file_, written by *cloud-init
+		snapshot*, instead of the current meta-data of the nodes.
+
+	*--no-resolve*
+		Print the rendered config as-is, without resolving _#include_
+		directives or merging parts.
+
+	*-o, --output-dir* _dir_
+		Directory to extract files to with *--format write-files-extract*,
+		or to write the output for each node to when rendering for more than
+		one node, both of which require it. Missing directories are created.
+
 *set* [-f _format_] < _file_++
 *set* [-f _format_] -d @_file_++
 *set* [-f _format_] -d @- < _file_++
-*set* [-f _format_] -d _data_
+*set* [-f _format_] -d _data_++
+*set* [-f _format_] --patch (_data_ | @_path_) [--patch-type _type_] _group_name_...
 	Set cloud-init group data for one or more groups, creating the group if
 	non-existent or overwriting group data if the group exists. This command
 	only accepts an array of group data (see *GROUP DATA*) and uses the *name*
@@ -446,6 +758,11 @@ Subcommands for this command are as follows:
 	In the fourth form of the command, the payload is passed raw on the command
 	line. This data is passed raw to the server.
 
+	In the fifth form of the command, the current data for each _group_name_
+	is fetched from cloud-init and the patch document passed to *--patch* is
+	applied to it client-side before it is set. The group name cannot be
+	changed this way.
+
 	This command sends a PUT to the */cloud-init/admin/groups* endpoint.
 
 	This command accepts the following options:
@@ -464,6 +781,24 @@ Subcommands for this command are as follows:
 		- _json-pretty_
 		- _yaml_
 
+	*--patch* (_data_ | @_path_ | @-)
+		Specify a patch document to apply client-side to the current
+		group data. Like *-d*, the document can be passed raw, read from
+		_path_, or read from standard input (@-), and is JSON unless *-f* is
+		specified.
+
+	*--patch-type* _type_
+		Type of the patch document passed to *--patch*. Supported types are:
+
+		- _json_ (default): a JSON Patch (RFC 6902) array of operations
+		- _merge_: a JSON Merge Patch (RFC 7396) partial document
+
+	*--resolve-secrets*
+		Replace secret references (see *SECRET REFERENCES*) in the cloud-config
+		file of each group with the values of the secrets before sending. If
+		any cannot be resolved, nothing is sent. Without this flag, a warning
+		is logged for each group containing secret references.
+
 ## node
 
 Get and manage cloud-init node data.
@@ -617,6 +952,49 @@ Subcommands for this command are as follows:
 		- _json-pretty_
 		- _yaml_
 
+## secret
+
+Check secret references in cloud-config files.
+
+Subcommands for this command are as follows:
+
+*check* [-F _format_] [--report-format _format_ [--report-file _path_]] _ref_...++
+*check* [-F _format_] [--report-format _format_ [--report-file _path_]] -d (_data_ | @_path_ | @-)
+	Resolve each secret reference (see *SECRET REFERENCES*) and report whether
+	it could be resolved, without printing the values of the secrets. In the
+	first form of the command, the references are passed as arguments (e.g.
+	_vault:kv/cluster/root-pass_). In the second form of the command, the
+	references are found in a cloud-config file passed raw, read from _path_,
+	or read from standard input (@-). The command exits with an error if any
+	reference cannot be resolved.
+
+	This command accepts the following options:
+
+	*-d, --data* (_data_ | @_path_ | @-)
+		Cloud-config file to find secret references in.
+
+	*-F, --format-output* _format_
+		Format the output as _format_.
+
+		Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--report-file* _path_
+		Write the report to _path_ in addition to the normal output. Default: _-_
+		(write the report to standard output instead of the normal output).
+
+	*--report-format* _format_
+		Output the results as a report for CI systems (see *OUTPUT* in
+		*ochami*(1)). Each reference is a test case or, for SARIF, each
+		unresolved reference is an _unresolved-secret_ result, located in
+		_path_ if the references were read from a file. Supported values are:
+
+		- _junit_
+		- _sarif_
+
 ## service
 
 Manage and check cloud-init itself.
@@ -670,6 +1048,122 @@ Subcommands for this command are as follows:
 		- _json_ (default)
 		- _yaml_
 
+## snapshot
+
+Save the meta-data of nodes to a snapshot file.
+
+*snapshot* [--group _group_]... [-o _file_] [_node_id_...]
+	Save the meta-data that cloud-init serves for each _node_id_ and each
+	member of each SMD group passed with *--group* to a snapshot file, so
+	that group configs can later be rendered against it with *cloud-init
+	group render --meta-data-snapshot*. The snapshot is a JSON object with
+	its format _version_, the time it was _created_, the _cluster_, and the
+	meta-data of each node under _nodes_, keyed by node ID. If the
+	meta-data of a node cannot be read, it is left out of the snapshot, the
+	snapshot is still written, and the exit status is 1.
+
+	This command sends a GET request to the
+	*/cloud-init/admin/impersonation/{id}/meta-data* cloud-init endpoint for
+	each node and, with *--group*, to the */hsm/v2/groups/{label}/members*
+	SMD endpoint for each group.
+
+	This command accepts the following options:
+
+	*--group* _group_,...
+		One or more SMD groups whose members to save the meta-data of. Can
+		be repeated.
+
+	*-o, --output* _file_
+		File to write the snapshot to. Default: _-_ (standard output).
+
+## verify
+
+Verify that booted nodes received the cloud-init data served for them.
+
+*verify* --ssh [--ssh-user _user_] [--ssh-port _port_] [--ssh-identity _path_] [--ssh-option _option_]... [--concurrency _n_] [--timeout _duration_] [-F _format_] --node _node_id_,...
+	Compare what cloud-init on each _node_id_ received with what cloud-init
+	serves for it. With *--ssh*, each node is logged into with *ssh*(1) in
+	batch mode (so it never prompts) to read
+	_/run/cloud-init/result.json_, _/run/cloud-init/instance-data.json_,
+	_/var/lib/cloud/instance/user-data.txt_, and
+	_/var/lib/cloud/instance/vendor-data.txt_. Nodes are connected to by the
+	_local-hostname_ in their served meta-data, or their ID if they have
+	none. The user-data and vendor-data files are only readable by root, so
+	without logging in as root their stages are _unchecked_.
+
+	Each node is checked in these stages, in order, so that the first one
+	that is not _ok_ shows where rendering or delivery diverged:
+
+	_delivery_
+		cloud-init finished and got its data from a datasource, rather
+		than falling back to _DataSourceNone_.
+
+	_instance-id_
+		The node has the served _instance-id_.
+
+	_meta-data_
+		Each served meta-data key has the served value on the node.
+
+	_user-data_
+		The node has the served user-data.
+
+	_vendor-data_
+		The node has the served vendor-data.
+
+	_processing_
+		cloud-init reported no errors while processing its data.
+
+	One finding is printed per stage per node, with the _node_, the _stage_,
+	its _status_ (_ok_, _mismatch_, _failed_, or _unchecked_), a _detail_,
+	and, for a mismatch, a unified _diff_ from what is served to what the
+	node has. The exit status is 1 if any stage of any node is a _mismatch_
+	or _failed_, or if the served data of a node could not be read.
+
+	Options not passed are taken from the *ssh* section of the cluster
+	config (see *ochami-config*(5)), then from the SSH configuration of the
+	user.
+
+	This command sends GET requests to the
+	*/cloud-init/admin/impersonation/{id}/meta-data*,
+	*/cloud-init/admin/impersonation/{id}/user-data*, and
+	*/cloud-init/admin/impersonation/{id}/vendor-data* cloud-init endpoints
+	for each node.
+
+	This command accepts the following options:
+
+	*--concurrency* _n_
+		Maximum number of nodes to verify at once. Default: _8_.
+
+	*-F, --format-output* _format_
+		Output findings in specified _format_. Supported values are:
+
+		- _json_ (default)
+		- _yaml_
+
+	*--node* _node_id_,...
+		One or more nodes to verify. Required.
+
+	*--ssh*
+		Read what each node received by logging into it with *ssh*(1).
+		Required, since it is currently the only way to verify nodes.
+
+	*--ssh-identity* _path_
+		Private key to log into nodes with.
+
+	*--ssh-option* _option_
+		Option to pass to *ssh*(1) with *-o* (e.g.
+		_StrictHostKeyChecking=accept-new_). Can be repeated.
+
+	*--ssh-port* _port_
+		Port to connect to nodes on.
+
+	*--ssh-user* _user_
+		User to log into nodes as.
+
+	*--timeout* _duration_
+		Maximum time to spend verifying each node, or _0_ for no limit.
+		Default: _30s_.
+
 # AUTHOR
 
 Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
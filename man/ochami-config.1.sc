@@ -10,6 +10,8 @@ ochami config [GLOBALOPTS] cluster delete _cluster_name_++
 ochami config [GLOBALOPTS] cluster set [-d] _cluster_name_ _key_ _value_++
 ochami config [GLOBALOPTS] cluster show [-f _format_] [_cluster_name_] [_key_]++
 ochami config [GLOBALOPTS] cluster unset _cluster_name_ _key_++
+ochami config [GLOBALOPTS] decrypt++
+ochami config [GLOBALOPTS] encrypt++
 ochami config [GLOBALOPTS] set _key_ _value_++
 ochami config [GLOBALOPTS] show [-f _format_] [_key_]++
 ochami config [GLOBALOPTS] unset _key_
@@ -99,6 +101,44 @@ Subcommands for this command are as follows:
 
 	This command has some key-setting caveats. See *WARNINGS* below.
 
+## decrypt
+
+Decrypt sensitive configuration values.
+
+The format of this command is:
+
+*decrypt*
+
+This command replaces the values in the file encrypted by *encrypt* with their
+plaintext, e.g. to switch to another encryption method. Age-encrypted values are
+decrypted with *encryption.age-identity*. Values in the OS keyring are copied
+back into the file but are not removed from the keyring.
+
+## encrypt
+
+Encrypt sensitive configuration values.
+
+The format of this command is:
+
+*encrypt*
+
+This command encrypts the sensitive values in the file that are not encrypted
+yet: the *access-token*, *discovery.bmc-password*, *grafana.token*, and
+*secrets.vault-token* of each cluster. They are encrypted with the method set
+as *encryption.method* in the file or, if it is not set there, in the merged
+config. See *encryption* in *ochami-config*(5). Encrypted values are decrypted
+transparently when they are used, so the file can be backed up or shared
+without leaking credentials.
+
+For example, to encrypt the values in the user-level config file with age:
+
+```
+ochami config set encryption.method age
+ochami config set encryption.age-recipients '["age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p"]'
+ochami config set encryption.age-identity ~/.config/ochami/age.key
+ochami config encrypt
+```
+
 ## set
 
 Set configuration option for ochami CLI.
@@ -13,6 +13,18 @@ can be set via the *ochami config* command.
 
 These configuration options are global configuration options.
 
+*audit-log:* _path_
+	Record every request that may modify data (i.e. any request other than GET,
+	HEAD, or OPTIONS) sent to OpenCHAMI services in the file at _path_, which
+	is created if it does not exist. Each line is a JSON object with the time,
+	the local user, the user passed with *--as* (if any), the cluster, the
+	service, the HTTP method and URI, and the HTTP status or error of the
+	request. This makes actions taken with shared automation accounts
+	traceable to their operators. If the audit log cannot be opened, commands
+	that send requests fail.
+
+	If unset, no audit log is kept.
+
 *clusters*
 	The list of cluster configurations. Each cluster configuration is a block
 	that has a *name* field that contains a string uniquely identifying the cluster,
@@ -40,6 +52,62 @@ These configuration options are global configuration options.
 	the command line. A cluster configuration must exist for _cluster_name_ or
 	further commands will fail.
 
+*delete-threshold:* _n_
+	The number of items above which delete commands require the exact number
+	of items to be deleted to be confirmed, either by typing it when prompted
+	or by passing it to *--yes-really-delete*. *--no-confirm* alone is refused
+	for such deletions. A value of _0_ disables this check.
+
+	The default value is _50_ if left unset.
+
+*max-impact-percent:* _n_
+	The percentage (_0_ to _100_) of the items of a kind in SMD above which
+	commands that delete or power off items at once are refused unless
+	*--allow-major-impact* is passed. Deletions are checked against the items
+	of the kind being deleted (or the members of the group for *smd group
+	member delete*), and power operations other than _on_ (*pcs power off*,
+	*pcs transition start*, and *node reimage --reboot*) against the nodes in
+	SMD. This guards against mistakes such as an overly broad selector. It can
+	also be set per-cluster with *cluster.max-impact-percent*, which takes
+	precedence. A value of _0_ disables this check.
+
+	The default value is _0_ if left unset.
+
+*encryption*
+	How sensitive values (*access-token*, *discovery.bmc-password*,
+	*grafana.token*, *notify.sinks* URLs, and *secrets.vault-token* of each
+	cluster) are encrypted
+	by *ochami config encrypt* (see *ochami-config*(1)). Encrypted values are
+	decrypted transparently when used, so config files can be backed up
+	without leaking credentials.
+
+	The following options are recognized:
+
+	*method:* _method_
+		The encryption method. Supported values are:
+
+		- _age_: encrypt values to *age-recipients* with *age*(1), storing
+		  them in the file as _age:_ followed by the base64-encoded ciphertext.
+		- _keyring_: move values into the OS keyring (with *secret-tool*(1) on
+		  Linux or *security*(1) on macOS) under the service _ochami_, leaving
+		  _keyring:_ followed by the name of the keyring entry in the file.
+
+	*age-recipients:* [_recipient_,...]
+		The age recipients (public keys) to encrypt values to.
+
+	*age-identity:* _path_
+		The age identity file to decrypt values with.
+
+	The format is:
+
+	```
+	encryption:
+	  method: age
+	  age-recipients:
+	    - age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+	  age-identity: ~/.config/ochami/age.key
+	```
+
 *log*
 	Logging options.
 
@@ -59,10 +127,71 @@ These configuration options are global configuration options.
 		Default: *warning*
 		Supported:
 
+		- _error_
 		- _info_
 		- _warning_
 		- _debug_
 
+*max-memory-buffer:* _size_
+	The size above which the response bodies of large dumps are spilled to a
+	temporary file instead of being held in memory, e.g. _512MiB_. Decimal (k,
+	M, G) and binary (Ki, Mi, Gi) units are supported, optionally followed by
+	B. See *--max-memory-buffer* in *ochami*(1), which overrides this.
+
+	The default value is _128MiB_ if left unset.
+
+*pager:* true|false
+	Page output that does not fit on the screen through *OCHAMI_PAGER*, *PAGER*,
+	or *less*(1) when standard output is a terminal. See *OUTPUT* in
+	*ochami*(1). This can be overridden by *--no-pager*.
+
+	The default value is _true_ if left unset.
+
+*read-only:* true|false
+	Refuse to send any request that may modify data to any cluster. See
+	*--read-only* in *ochami*(1). This can also be set per-cluster with
+	*cluster.read-only*, and can be overridden by *--read-only=false*.
+
+	The default value is _false_ if left unset.
+
+*usage-stats*
+	Opt-in collection of usage statistics, so that sites can see which
+	commands are used most and which fail most often. Collection is enabled if
+	*file* and/or *statsd* is set. For each command run, the command (e.g.
+	_smd component get_, without arguments or flag values), cluster, *ochami*
+	version, duration, exit status, and, for failed commands, the category of
+	the failure are recorded. The categories are _auth_, _config_,
+	_connection_, _http_, _policy_, _read-only_, _timeout_, _usage_,
+	_warnings_ (failed because of *--fail-on-warn*), and _other_. A command is
+	recorded as failed as soon as it logs an error. Failing to record never
+	affects the command.
+
+	*file:* _path_
+		Path of a file to append each command run to as a line of JSON.
+		The file is created if it does not exist, but its directory is
+		not.
+
+	*statsd:* _host:port_
+		StatsD endpoint to send metrics of each command run to over UDP:
+		a _<prefix>.<command>.count_ counter, a
+		_<prefix>.<command>.duration_ timer in milliseconds, and, for
+		failed commands, a _<prefix>.<command>.failure.<category>_
+		counter, with the words of the command separated by dots (e.g.
+		_ochami.smd.component.get.count_).
+
+	*prefix:* _prefix_
+		Prefix of StatsD metric names.
+
+		Default: *ochami*
+
+	For example:
+
+	```
+	usage-stats:
+	  file: /var/log/ochami/usage.jsonl
+	  statsd: statsd.example.com:8125
+	```
+
 # CLUSTER CONFIGURATION
 
 These configuration options apply only to cluster configuration, i.e. under the
@@ -73,6 +202,13 @@ the array containing the below configuration options.
 	The name of the cluster. This is what *--cluster* and the *default-cluster*
 	key use to identify the cluster.
 
+*access-token:* _token_
+	The access token for the cluster, used if the cluster's access token
+	environment variable (see *clusters* above) is unset and no *ochami
+	agent* (see *ochami-agent*(1)) or *token-command* provides one. This
+	value is sensitive and should be encrypted with *ochami config encrypt*
+	(see *encryption* above).
+
 *<service>*
 	The service-specific configuration for *<service>*. Currently recognized
 	values of *<service>* are:
@@ -104,6 +240,75 @@ the array containing the below configuration options.
 		of these need to be set.  Otherwise, the base URI is not able to be
 		determined for that service.
 
+		The placeholders *{service}* and *{base}* are expanded in this value
+		to the service name (e.g. _smd_) and the service's default base path
+		(e.g. _/hsm/v2_), respectively. See *path-template* below.
+
+	*paths*
+		A map of endpoint path overrides for the service, for deployments
+		that do not serve the service's endpoints under their upstream paths.
+		Each key is an endpoint path as used by *ochami* (e.g.
+		_/State/Components_) and each value is the path to use instead (e.g.
+		_/state/components_). An override also applies to any endpoint under
+		it, e.g. _/State/Components/x1000c0s0b0n0_ would become
+		_/state/components/x1000c0s0b0n0_. If multiple keys match, the
+		longest one is used.
+
+		The key _/openapi.json_ overrides where the service's OpenAPI spec
+		is fetched from for *--validate-against-server* (see *ochami*(1)).
+
+		The format is:
+
+		```
+		smd:
+		  paths:
+		    /State/Components: /state/components
+		```
+
+	The *bss* service config can also have the following option:
+
+	*lint-rules:* _path_
+		Path to a file of site rules that boot parameters are checked against,
+		in addition to the built-in rules, by *ochami bss boot params lint* and
+		when setting or adding boot parameters. See *ochami-bss*(1) for its
+		format.
+
+*discovery*
+	Configuration for *ochami discover static* (see *ochami-discover*(1)).
+
+	The following options are recognized:
+
+	*auto-groups:* [_group_=_spec_,...]
+		Rules adding the nodes that match _spec_ to _group_, in the same
+		format as *--auto-group*.
+
+	*bmc-creds-path:* _mount_/_path_
+		Path in the KV secret engine of Vault under which *--push-bmc-creds*
+		writes the credentials of each BMC, the first element being the
+		mount of the engine. Default: _secret/hms-creds_.
+
+	*bmc-password:* _password_
+		The password stored in SMD for discovered BMCs, along with
+		*bmc-username*. This value is sensitive and should be encrypted with
+		*ochami config encrypt*.
+
+	*bmc-username:* _username_
+		The username stored in SMD for discovered BMCs.
+
+	*default-groups:* [_group_,...]
+		Groups to add every discovered node to.
+
+	The format is:
+
+	```
+	discovery:
+	  default-groups:
+	    - all
+	  auto-groups:
+	    - compute=x3000c0s[0-7]b0n.*
+	    - gpu=nid:1-16
+	```
+
 *enable-auth:* true|false
 	Enable authentication for this cluster.
 
@@ -119,6 +324,244 @@ the array containing the below configuration options.
 
 	*enable-auth* can be overridden by the *--no-token* flag.
 
+*grafana*
+	Configuration for pushing annotations to Grafana when operational actions
+	are performed on the cluster, e.g. by *ochami smd export
+	grafana-annotations* or *ochami pcs transition start*. The Grafana API
+	token is read from an environment variable named in the same manner as the
+	access token (see *clusters* above) but ending in *\_GRAFANA_TOKEN*, e.g.
+	*MY_CLUSTER_GRAFANA_TOKEN*, or, if it is unset, from *token*. If neither is
+	set, no token is sent.
+
+	The following options are recognized:
+
+	*uri:* _absolute_uri_
+		The root URI of Grafana (e.g. _https://grafana.example.com_). If
+		unset, no annotations are pushed automatically.
+
+	*dashboard-uid:* _uid_
+		The UID of the dashboard to add annotations to. If unset, annotations
+		are organization-wide.
+
+	*tags:* [_tag_,...]
+		Additional tags to add to every annotation.
+
+	*token:* _token_
+		The Grafana API token. This value is sensitive and should be
+		encrypted with *ochami config encrypt*.
+
+	The format is:
+
+	```
+	grafana:
+	  uri: https://grafana.example.com
+	  dashboard-uid: abc123
+	  tags:
+	    - maintenance
+	```
+
+*impersonation-header:* _header_
+	The request header in which the user passed with *--as* is sent, for API
+	gateways that support making requests on behalf of another user (e.g.
+	_X-Forwarded-User_). The gateway must only honor it for accounts allowed
+	to impersonate others.
+
+	The default value is _Impersonate-User_ if left unset.
+
+*locations*
+	Map of xnames to descriptions of their physical locations, shown by
+	commands passed *--show-location* (e.g. *pcs power status* and *smd
+	component wait*) so that technicians can find hardware from their output.
+	A description applies to the xname and everything under it, unless a
+	descendant has its own, with the levels of the xname hierarchy below the
+	mapped xname appended. For example:
+
+	```
+	locations:
+	  x3000: Row A, Rack 12
+	  x3000c0s30b0n0: Rack 12, U30, left node
+	```
+
+	describes x3000c0s30b0n0 as "Rack 12, U30, left node" and x3000c0s4b0n1 as
+	"Row A, Rack 12, chassis 0, slot 4, BMC 0, node 1". Xnames are compared
+	regardless of case. These override the locations in *locations-file*.
+
+*locations-file:* _path_
+	Path to a JSON or YAML file containing a *locations* map in the same format
+	as *locations*, so that a generated or shared mapping can be used.
+
+*notify*
+	Configuration for notifying operators when long-running commands run
+	against the cluster, such as *node reimage* and *pcs power off*, finish or
+	fail. See *NOTIFICATIONS* in *ochami*(1).
+
+	The following options are recognized:
+
+	*sinks:* [_sink_,...]
+		The sinks each notification is sent to. Each sink is a map with the
+		following keys:
+
+		*type:* _type_
+			The type of the sink: _webhook_, _slack_, or _email_.
+
+		*url:* _absolute_uri_
+			For _webhook_ and _slack_ sinks, the URL the notification is
+			POSTed to. Since webhook URLs often contain credentials, this
+			value is sensitive and should be encrypted with *ochami config
+			encrypt*.
+
+		*to:* [_address_,...]
+			For _email_ sinks, the recipients.
+
+		*from:* _address_
+			For _email_ sinks, the sender. If unset, sendmail's default is
+			used.
+
+		*sendmail:* _path_
+			For _email_ sinks, the sendmail program to send mail with.
+			Default: _/usr/sbin/sendmail_
+
+	The format is:
+
+	```
+	notify:
+	  sinks:
+	    - type: slack
+	      url: https://hooks.slack.com/services/T000/B000/XXXX
+	    - type: email
+	      to:
+	        - ops@example.com
+	      from: ochami@example.com
+	```
+
+*path-template:* _template_
+	A template for the base path of each service that does not have
+	*cluster.<service>.uri* set. This is useful when all services are mounted
+	under nonstandard paths behind a gateway. The placeholders *{service}* and
+	*{base}* are replaced with the service name and the service's default base
+	path, respectively. For instance, a template of _/apis/{service}{base}_
+	results in a base path of _/apis/smd/hsm/v2_ for SMD and
+	_/apis/bss/boot/v1_ for BSS. The result is appended to *cluster.uri*.
+
+	If unset, each service's default base path is used.
+
+*read-only:* true|false
+	Refuse to send any request that may modify data to this cluster. Reading
+	data is still permitted. This is useful for sharing config templates with
+	monitoring accounts. See *--read-only* in *ochami*(1).
+
+	The default value is _false_ if left unset. Read-only mode is enabled if
+	either this or the top-level *read-only* is _true_, unless overridden by
+	*--read-only=false*.
+
+*max-impact-percent:* _n_
+	Override the top-level *max-impact-percent* for this cluster. A value of
+	_0_ disables the check for this cluster.
+
+*policy*
+	Restrictions on which commands may be run against this cluster, so that
+	commands meant for one cluster (e.g. staging) cannot mutate another (e.g.
+	production) because the wrong cluster was used. Commands refused by the
+	policy exit with an error before sending any request. The *config*,
+	*version*, *help*, and *completion* commands are never restricted.
+
+	Policies match commands by patterns, which are lists of command words
+	separated by spaces, where _\*_ matches any single word. A pattern matches
+	a command if its words appear consecutively anywhere in the command (e.g.
+	_smd component get_, without arguments or flags), so _get_ matches every
+	*get* command and _smd component_ matches every command under *smd
+	component*. Deprecated aliases of a command are matched by the path of
+	the command they run, so they are restricted like it.
+
+	The following options are recognized:
+
+	*allow:* [_pattern_,...]
+		If set, only commands matching one of these patterns may be run.
+
+	*deny:* [_pattern_,...]
+		Commands matching any of these patterns may not be run, even if
+		they match a pattern in *allow*.
+
+	For example, to only allow reading data from a production cluster:
+
+	```
+	policy:
+	  allow:
+	  - get
+	  - list
+	  - show
+	  - status
+	  - watch
+	```
+
+*secrets*
+	Configuration for the external secret stores that secret references in
+	cloud-config files are resolved from (see *SECRET REFERENCES* in
+	*ochami-cloud-init*(1)).
+
+	The following options are recognized:
+
+	*vault-token:* _token_
+		The Vault token, used if the cluster's *\_VAULT_TOKEN* environment
+		variable is unset. If neither is set, the *VAULT_TOKEN* environment
+		variable is used. This value is sensitive and should be encrypted with
+		*ochami config encrypt*.
+
+	*vault-uri:* _absolute_uri_
+		The root URI of Vault (e.g. _https://vault.example.com:8200_). If
+		unset, the *VAULT_ADDR* environment variable is used.
+
+	The format is:
+
+	```
+	secrets:
+	  vault-uri: https://vault.example.com:8200
+	```
+
+*ssh*
+	How to connect to the nodes of the cluster with *ssh*(1), e.g. for
+	*ochami cloud-init verify --ssh* (see *ochami-cloud-init*(1)). Options
+	that are not set are left to the SSH configuration of the user. Flags
+	passed to a command override them.
+
+	The following options are recognized:
+
+	*identity-file:* _path_
+		The private key to log into nodes with.
+
+	*options:* [_option_,...]
+		Options to pass to *ssh* with *-o*.
+
+	*port:* _port_
+		The port to connect to nodes on.
+
+	*user:* _user_
+		The user to log into nodes as.
+
+	The format is:
+
+	```
+	ssh:
+	  user: root
+	  identity-file: ~/.ssh/cluster_ed25519
+	  options:
+	  - StrictHostKeyChecking=accept-new
+	```
+
+*token-command:* _command_
+	A shell command (run with *sh -c*) that prints a fresh access token for
+	the cluster to standard output, e.g. one that gets it from the identity
+	provider with a refresh token (such as *oidc-token*(1)). It is used
+	instead of *access-token* if the cluster's access token environment
+	variable is unset and no *ochami agent* provides a token. *ochami agent* runs it whenever the token is
+	close to expiring (see *ochami-agent*(1)).
+
+	The format is:
+
+	```
+	token-command: oidc-token foobar
+	```
+
 *uri:* _absolute_uri_
 	The base URI for the OpenCHAMI services for the cluster. This is
 	normally used when most or all of the OpenCHAMI services are behind a
@@ -134,6 +577,15 @@ the array containing the below configuration options.
 	*cluster.<service>.uri* directives for overrides, or a
 	*cluster.<service>.uri* must be specified for each *<service>*.
 
+*vars-file:* _path_
+	Path to a YAML file in which *ochami vars* (see *ochami-vars*(1)) stores
+	the template variables of nodes and groups of the cluster, e.g. one kept in
+	Git with the rest of the site configuration. The file is created if it does
+	not exist, but its directory is not.
+
+	If unset, the variables are stored in the meta-data of the cloud-init group
+	_ochami-vars_.
+
 # EXAMPLES
 
 ## Cluster Config Variations
@@ -201,6 +653,23 @@ log:
     level: debug
 ```
 
+*5. Services behind a gateway under nonstandard paths*
+
+```
+clusters:
+    - cluster:
+        uri: https://foobar.openchami.cluster
+        path-template: /apis/{service}{base}
+        smd:
+          paths:
+            /State/Components: /state/components
+      name: foobar
+default-cluster: foobar
+log:
+    format: json
+    level: debug
+```
+
 # FILES
 
 _/etc/ochami/config.yaml_
@@ -212,7 +681,7 @@ Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
 
 # SEE ALSO
 
-*ochami*(1), *ochami-config*(1)
+*ochami*(1), *ochami-agent*(1), *ochami-config*(1)
 
 ; Vim modeline settings
 ; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
@@ -0,0 +1,82 @@
+OCHAMI-CONVERT(1) "OpenCHAMI" "Manual Page for ochami-convert"
+
+# NAME
+
+ochami-convert - Convert a payload between formats
+
+# SYNOPSIS
+
+ochami convert [-d (_data_ | @_path_)] [-f _format_] [-F _format_] [--schema _schema_]
+
+# DESCRIPTION
+
+The *convert* command converts a payload from the format of *-f* to the format
+of *-F* and prints the result. The payload is read from *-d*, or from standard
+input if it is not passed. Any format that commands read payloads in or print
+output in can be converted from or to, so that, for instance, a YAML payload
+can be turned into JSON for a command or script that only reads JSON.
+
+Unlike reading a payload and printing it again (e.g. with *redact*), the keys of
+objects keep the order they have in the payload, and numbers are kept as
+written, so that large integers do not lose precision. Strings that look like
+other types (e.g. _"123"_ or _"true"_) stay strings.
+
+With *--schema*, the keys of the objects that the schema describes are instead
+put in the order of its properties, followed by the keys it does not describe in
+the order they have in the payload. Keys are only reordered; the payload is not
+validated against the schema.
+
+# OPTIONS
+
+*-d, --data* (_data_ | @_path_ | @-)
+	Specify raw _data_ to convert, the _path_ to a file to read payload data
+	from, or to read the data from standard input (@-). The format of data read
+	in any of these forms is JSON by default unless *-f* is specified to change
+	it.
+
+*-f, --format-input* _format_
+	Format of the input data. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+	- _csv_ (only with *--schema discovery*)
+
+*-F, --format-output* _format_
+	Format of the output. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*--schema* _schema_
+	Order keys by _schema_. Supported schemas are:
+
+	- _discovery_: Static discovery payloads, as printed by *discover schema*
+	  (see *ochami-discover*(1)). CSV discovery payloads can be converted with
+	  this schema, and are read as *discover static* reads them.
+
+# EXAMPLES
+
+Convert a YAML payload to indented JSON:
+
+```
+ochami convert -f yaml -F json-pretty -d @nodes.yaml
+```
+
+Convert a CSV discovery payload to YAML:
+
+```
+ochami convert -f csv -F yaml --schema discovery -d @nodes.csv
+```
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1), *ochami-discover*(1)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
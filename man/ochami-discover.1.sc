@@ -6,7 +6,19 @@ ochami-discover - Populate SMD using a file
 
 # SYNOPSIS
 
-ochami discover static [--overwrite] [-d (_data_ | @_path_)] [-f _format_]
+ochami discover static [--scan (_cidr_ | _ip_)... [--credentials-file _creds_file_] [-r _rules_file_]] [--overwrite] [--state-file _path_ [--force]] [--batch-size _n_] [--concurrency _n_] [--retries _n_] [--auto-group _group_=_spec_]... [--default-group _group_,...] [--sync-groups [--prune-groups]] [--push-bmc-creds] [--dry-run [--output-dir _dir_] [-F _format_]] [-d (_data_ | @_path_)] [-f _format_] [--validate-schema]++
+ochami discover append [--auto-group _group_=_spec_]... [--default-group _group_,...] [-d (_data_ | @_path_)] [-f _format_] [--validate-schema]++
+ochami discover diff [-d (_data_ | @_path_)] [-f _format_] [--validate-schema] [-F _format_]++
+ochami discover plan [--overwrite] [--auto-group _group_=_spec_]... [--default-group _group_,...] [--out _path_] [-F _format_] [-d (_data_ | @_path_)] [-f _format_] [--validate-schema]++
+ochami discover apply --plan _path_++
+ochami discover network-config [-d (_data_ | @_path_)] [-f _format_] [--validate-schema] [-F _format_]++
+ochami discover validate [-d (_data_ | @_path_)] [-f _format_] [--validate-schema] [-F _format_] [--report-format _format_ [--report-file _path_]]++
+ochami discover schema++
+ochami discover migrate [-f _in_format_] [-F _out_format_] _in_file_ [_out_file_]++
+ochami discover export [-F _format_]++
+ochami discover from-dhcp-leases -r _rules_file_ [-f _format_] [--lease-format _lease_format_] [-F _format_] _lease_file_ [_out_file_]++
+ochami discover scan --subnet _cidr_... [--credentials-file _creds_file_] [-r _rules_file_] [-f _format_] [-F _format_] [_out_file_]++
+ochami discover magellan --subnet _cidr_... [--credentials-file _creds_file_] [-r _rules_file_] [--overwrite] [--auto-group _group_=_spec_]... [--default-group _group_,...] [--sync-groups [--prune-groups]] [--push-bmc-creds] [--dry-run [--output-dir _dir_] [-F _format_]] [-f _format_]
 
 # DESCRIPTION
 
@@ -15,11 +27,12 @@ with the data.
 
 # DATA STRUCTURE
 
-The format of the data for *static* discovery is a *nodes* object containing an
-array of node data.  An example of such an array containing one node in YAML
-format is as follows:
+The format of the data for *static* discovery is an object containing a
+*version* and a *nodes* array of node data.  An example of such an object
+containing one node in YAML format is as follows:
 
 ```
+version: 1
 nodes:
 - name: node01
   nid: 1
@@ -32,20 +45,24 @@ nodes:
   interfaces:
   - mac_addr: de:ad:be:ee:ee:f1
     ip_addrs:
-    - name: internal
+    - network: internal
       ip_addr: 172.16.0.1
   - mac_addr: de:ad:be:ee:ee:f2
     ip_addrs:
-    - name: external
+    - network: external
       ip_addr: 10.15.3.100
   - mac_addr: 02:00:00:91:31:b3
     ip_addrs:
-    - name: HSN
+    - network: HSN
       ip_addr: 192.168.0.1
 ```
 
 A description of each key in the above is as follows:
 
+- *version* - The version of the format. The current version is _1_. Payloads
+without a version are from before the format was versioned and may contain
+deprecated keys, which are migrated when read with a warning for each (see
+*migrate*).
 - *name* - A short name identifying the node. This is used as the
 RedfishEndpoint name and is used to generate a short description of the node for
 the description field in EthernetInterfaces it creates.
@@ -55,29 +72,332 @@ the Component that is created for the node.
 xname (see *XNAMES*) because this is used to calculate a BMC xname for the
 RedfishEndpoint and Component structures created for the BMC for the node. This
 is used as the unique identifier for the node within the Component that gets
-created for node.
+created for node. Nodes whose xnames have the same BMC xname (e.g. the 2 to 4
+nodes of a dense blade, _x1000c0s0b0n0_ to _x1000c0s0b0n3_) share its
+RedfishEndpoint, with a System for each node, whose address is set from the BMC
+keys of the first of them. The others may leave the BMC keys out, or set them to
+the same values; different values are ignored with a _bmc-mismatch_ warning.
 - *bmc_mac* - MAC address of node's BMC.
 - *bmc_ip* - Desired IP address of node's BMC.
 - *bmc_fqdn* - FQDN of node's BMC. If omitted, SMD sets this equal to the xname.
+- *bmc_interfaces* - Optional list of network interfaces of the node's BMC, for
+BMCs with more than one (e.g. both a dedicated and a shared NIC). This replaces
+*bmc_mac* and *bmc_ip*, which cannot be set along with it. The BMC's Manager
+gets an EthernetInterface for each, described by its kind. A *mac_addr* can be
+listed more than once, with the same *kind* and *shared_with*, to give a port
+several addresses, e.g. both an IPv4 and an IPv6 address; SMD then has one
+EthernetInterface for the port with all of them.
+    - *mac_addr* - MAC address of BMC interface.
+    - *ip_addr* - Desired IPv4 or IPv6 address of BMC interface.
+    - *kind* - _dedicated_ (the default) if the interface has its own port or
+      _shared_ if it uses the port of one of the node's interfaces (e.g. using
+      NC-SI).
+    - *shared_with* - Optional MAC address of the node interface whose port a
+      _shared_ interface uses. It must be one of the node's *interfaces* or a
+      member of one of its *bonds*.
+    - *primary* - Whether this is the interface that SMD identifies the BMC
+      by, whose MAC and IP addresses are used for the RedfishEndpoint. At most
+      one interface can be primary. If none is, the first _dedicated_
+      interface is, or the first interface if all are _shared_, preferring
+      those with IPv4 addresses.
+- *bmc_username* - Optional username of the node's BMC stored in SMD with its
+RedfishEndpoint, instead of *discovery.bmc-username* of the cluster (see
+*ochami-config*(5)).
+- *bmc_password* - Optional password of the node's BMC, along with
+*bmc_username*, instead of *discovery.bmc-password* of the cluster.
+- *bmc_password_file* - Optional path of a file whose contents, without a
+trailing newline, are the password of the node's BMC, so that it need not be in
+the payload. It cannot be set along with *bmc_password*.
+- *virtual* - Whether the node is a virtual machine. Virtual nodes get a
+_VirtualNode_ Component instead of a _Node_ one. Unless *hypervisor* is set,
+they have no BMC, so no RedfishEndpoint is created for them and the BMC keys are
+ignored with a warning; their interfaces are still added as EthernetInterfaces.
+- *hypervisor* - Optional xname of the virtual BMC of the hypervisor that manages
+a virtual node (e.g. *sushy-tools* or another Redfish emulator). Instead of
+deriving a BMC xname from *xname*, the node is added as a System of the
+RedfishEndpoint of this virtual BMC, which is shared by all of its guests and
+whose address is set from the BMC keys of the first of them.
+- *type* - Optional type of the device the entry describes, if it is not a node
+(the default, _Node_). Devices get a Component of their type, without a NID,
+and need not have *interfaces*. Their BMC, described by the BMC keys, gets a
+RedfishEndpoint without Systems, shared by devices with the same BMC. The
+supported types, with the form of their xnames and their BMCs, are:
+    - _ChassisBMC_ - _xXcCbB_, its own BMC.
+    - _RouterBMC_ - _xXcCrRbB_ (e.g. a switch controller), its own BMC.
+    - _CabinetPDUController_ - _xXmM_, its own BMC.
+    - _CabinetPDU_ - _xXmMpP_, managed by its PDU controller _xXmM_ (a
+      _CabinetPDUController_ RedfishEndpoint).
+    - _MgmtSwitch_ - _xXcCwW_, which has no BMC, so only its *interfaces* are
+      added as EthernetInterfaces.
+- *power_actions* - Optional list of the Redfish ResetTypes that the node's BMC
+supports (e.g. _On_, _ForceOff_, _GracefulShutdown_), which become the Actions
+of the node's System so that PCS does not attempt transitions the BMC cannot
+perform. If omitted, all ResetTypes are listed: _On_, _ForceOff_,
+_GracefulShutdown_, _GracefulRestart_, _ForceRestart_, _Nmi_, _ForceOn_,
+_PushPowerButton_, _PowerCycle_, _Suspend_, _Pause_, and _Resume_. Devices with
+a *type* other than _Node_ cannot have it.
+- *hardware* - Optional description of the node's hardware, from which SMD
+hardware inventory entries are created alongside its Component, so that tools
+reading the SMD hardware inventory work with nodes discovered from a payload.
+All keys are optional. Devices with a *type* other than _Node_ cannot have it.
+    - *manufacturer*, *model*, *serial_number* - The node itself, which gets a
+      _Node_ entry at its xname.
+    - *cpu_model*, *cpu_count*, *cpu_cores* - The node's CPUs, each of which
+      gets a _Processor_ entry (e.g. _x1000c0s0b0n0p0_) with *cpu_cores* cores.
+    - *cpu_serial_numbers* - Serial numbers of the CPUs, in order. There can be
+      no more than *cpu_count*; if *cpu_count* is not set, there is one CPU for
+      each.
+    - *memory_gib* - Total memory of the node in GiB, which gets a single
+      _Memory_ entry (e.g. _x1000c0s0b0n0d0_).
+    - *gpus* - List of GPUs or other accelerators, each of which gets a
+      _NodeAccel_ entry (e.g. _x1000c0s0b0n0a0_), with an optional
+      *manufacturer*, *model*, and *serial_number*.
+
+  Entries with a serial number get a FRU ID made from their type, manufacturer,
+  and serial number, as SMD makes them, and other entries one made from their
+  location.
 - *group* - *DEPRECATED.* Use *groups* instead. *group* will be removed in a
 future release.
 - *groups* - Optional list of groups to add node to. These will get created
 during discovery if they do not exist.
 - *interfaces* - A list of network interfaces for the node.
     - *mac_addr* - MAC address of network interface.
-    - *ip_addrs* - List of IP addresses assigned to interface.
-        - *name* - Short name identifying the network for the IP address.
+    - *ip_addrs* - Optional list of IP addresses assigned to interface.
+        - *network* - Short name identifying the network for the IP address.
+          Unversioned payloads may use *name* instead, which is *DEPRECATED*.
         - *ip_addr* - IP address for interface.
+- *bonds* - Optional list of bonded interfaces of the node, e.g. for a
+management network using LACP. Each member gets an EthernetInterface, and the
+bond uses the MAC address of its primary member, so only that member's
+EthernetInterface gets the IP addresses of the bond. See *network-config* for
+the cloud-init network configuration that sets up the bonds on the node.
+    - *name* - Name of the bond on the node, e.g. _bond0_. Names must be
+      unique for the node.
+    - *members* - List of MAC addresses of the interfaces in the bond. Members
+      must not also be listed in *interfaces* or be in another bond.
+    - *primary* - Optional MAC address of the member whose MAC address the bond
+      uses. Defaults to the first member.
+    - *mode* - Optional Linux bonding mode (_balance-rr_, _active-backup_,
+      _balance-xor_, _broadcast_, _802.3ad_, _balance-tlb_, or _balance-alb_).
+      Defaults to _802.3ad_ (LACP).
+    - *ip_addrs* - List of IP addresses of the bond, in the same format as for
+      *interfaces*.
+
+For example, two virtual machines managed by the virtual BMC of their
+hypervisor are described as follows:
+
+```
+- name: vm01
+  nid: 101
+  xname: x1000c1s7b1n0
+  virtual: true
+  hypervisor: x1000c1s7b1
+  bmc_ip: 172.16.0.110
+  interfaces:
+  - mac_addr: 52:54:00:00:00:01
+    ip_addrs:
+    - network: internal
+      ip_addr: 172.16.0.11
+- name: vm02
+  nid: 102
+  xname: x1000c1s7b1n1
+  virtual: true
+  hypervisor: x1000c1s7b1
+  interfaces:
+  - mac_addr: 52:54:00:00:00:02
+    ip_addrs:
+    - network: internal
+      ip_addr: 172.16.0.12
+```
+
+For example, a switch controller and the two PDUs of a PDU controller are
+described as follows:
+
+```
+- name: sw-hsn01
+  xname: x3000c0r1b0
+  type: RouterBMC
+  bmc_mac: de:ca:fc:0f:ee:01
+  bmc_ip: 172.16.0.201
+- name: pdu0
+  xname: x3000m0p0
+  type: CabinetPDU
+  bmc_mac: de:ca:fc:0f:ee:02
+  bmc_ip: 172.16.0.202
+- name: pdu1
+  xname: x3000m0p1
+  type: CabinetPDU
+  bmc_mac: de:ca:fc:0f:ee:02
+  bmc_ip: 172.16.0.202
+```
+
+For example, a node whose BMC has a dedicated NIC as well as one sharing the
+port of the node's first interface is described as follows:
+
+```
+- name: node01
+  nid: 1
+  xname: x1000c1s7b0n0
+  bmc_interfaces:
+  - mac_addr: de:ca:fc:0f:ee:ee
+    ip_addr: 172.16.0.101
+    kind: dedicated
+    primary: true
+  - mac_addr: de:ca:fc:0f:ee:ef
+    ip_addr: 172.16.1.101
+    kind: shared
+    shared_with: de:ad:be:ee:ee:f1
+  interfaces:
+  - mac_addr: de:ad:be:ee:ee:f1
+    ip_addrs:
+    - network: internal
+      ip_addr: 172.16.0.1
+```
+
+A node whose BMC has a dual-stack dedicated NIC and a second, IPv6-only NIC is
+described as follows, with the IPv4 address of the first NIC used for the
+RedfishEndpoint:
+
+```
+- name: node01
+  nid: 1
+  xname: x1000c1s7b0n0
+  bmc_interfaces:
+  - mac_addr: de:ca:fc:0f:ee:ee
+    ip_addr: fd00:16::101
+  - mac_addr: de:ca:fc:0f:ee:ee
+    ip_addr: 172.16.0.101
+  - mac_addr: de:ca:fc:0f:ee:ef
+    ip_addr: fd00:17::101
+  interfaces:
+  - mac_addr: de:ad:be:ee:ee:f1
+    ip_addrs:
+    - network: internal
+      ip_addr: 172.16.0.1
+```
+
+For example, a node whose management network is an LACP bond of two interfaces
+is described as follows:
+
+```
+- name: node01
+  nid: 1
+  xname: x1000c1s7b0n0
+  bmc_mac: de:ca:fc:0f:ee:ee
+  bmc_ip: 172.16.0.101
+  interfaces: []
+  bonds:
+  - name: bond0
+    members:
+    - de:ad:be:ee:ee:f1
+    - de:ad:be:ee:ee:f2
+    ip_addrs:
+    - network: internal
+      ip_addr: 172.16.0.1
+```
+
+## Compact node entries
+
+Many identical nodes can be described by a single entry, which is expanded into
+one node for each xname or NID it describes before the payload is used:
+
+- An *xname* containing ranges in brackets describes a node for each value, e.g.
+  _x3000c0s[0-15]b0n0_. A range is a comma-separated list of numbers and
+  inclusive number ranges (e.g. _[0-3,8]_), and numbers are padded to the
+  width of a start with a leading zero (e.g. _[00-15]_). With several ranges,
+  every combination is described.
+- A *nid* that is a string of NIDs and inclusive NID ranges describes a node for
+  each NID, e.g. _"1-16"_ or _"1-8,20"_. If the xname also has ranges, both
+  must describe the same number of nodes. A single NID with an xname range is
+  the NID of the first node, the others following it.
+
+In every string of an entry, *{nid}* is replaced with the node's NID and
+*{index}* with its 0-based position in the entry. Either can be offset and
+formatted with a width and a verb, _d_ (decimal, the default) or _x_
+(hexadecimal), e.g. *{nid+100}*, *{nid:03}*, or *{nid:02x}*. For example, the
+following entry describes 16 nodes, named nid001 to nid016:
+
+```
+- name: nid{nid:03}
+  nid: 1-16
+  xname: x3000c0s[0-15]b0n0
+  bmc_mac: de:ca:fc:0f:ee:{nid:02x}
+  bmc_ip: 172.16.0.{nid+100}
+  groups:
+  - compute
+  interfaces:
+  - mac_addr: de:ad:be:ee:ee:{nid:02x}
+    ip_addrs:
+    - network: internal
+      ip_addr: 172.16.1.{nid}
+```
+
+An entry can describe at most 100000 nodes. *migrate* keeps compact entries as
+they are.
+
+## CSV
+
+Node inventories kept in a spreadsheet can be read as CSV by passing *-f csv*
+to the commands that read payloads (*static*, *append*, *diff*, and
+*network-config*). The first row is a header naming the column of each field,
+in any order and case. Empty cells are skipped, as are lines starting with *#*.
+The columns are:
+
+*name*, *nid*, *xname*, *bmc_mac*, *bmc_ip*, *bmc_fqdn*, *bmc_username*, *bmc_password*, *bmc_password_file*, *virtual*, *hypervisor*, *type*
+	The node fields of the same names. Only *xname* is required.
+
+*groups* (or *group*)
+	The groups of the node, separated by semicolons.
+
+*power_actions*
+	The power actions supported by the node's BMC, separated by semicolons.
+
+*ifaceN_mac*, *ifaceN_ip*, *ifaceN_network*
+	The MAC address, IP addresses, and networks of interface N of the node,
+	where N is the index of the interface, starting from 0 (e.g.
+	*iface0_mac*). Several IP addresses are separated by semicolons, with
+	either one network for each or one for all of them.
+
+Other columns are an error. For example, the following is the same as the first
+node of the YAML example above, with only its first two interfaces:
+
+```
+name,nid,xname,bmc_mac,bmc_ip,groups,iface0_mac,iface0_ip,iface0_network,iface1_mac,iface1_ip,iface1_network
+node01,1,x1000c1s7b0n0,de:ca:fc:0f:ee:ee,172.16.0.101,compute;slurm,de:ad:be:ee:ee:f1,172.16.0.1,internal,de:ad:be:ee:ee:f2,10.15.3.100,external
+```
+
+Bonds and BMCs with more than one interface cannot be described in CSV.
+
+## Schema
+
+*schema* prints the JSON Schema of the current version of the format, generated
+from the structures that payloads are read into, e.g. for editors to check and
+complete payload files. Payloads are read leniently: keys that are not part of
+the format are ignored and values of the wrong type are read as empty, so a
+misspelt key silently drops what it sets. Passing *--validate-schema* to the
+commands that read payloads checks them against the schema first and fails
+with a message for each mismatch naming the node and field, e.g.:
+
+```
+node 0 (x1000c1s7b0n0): interfaces[0].mac_adr: unknown key
+```
+
+The schema checks the keys and types of values, plus the *kind* of BMC
+interfaces, but not what values mean; *validate* checks that. Payloads without
+a version are checked as migrated (see *migrate*), keys set to null are treated
+as unset, and a *nid* can also be a string for compact node entries. CSV
+payloads are not checked.
 
 # COMMANDS
 
 ## static
 
-Populate SMD using static data from a file or standard input.
+Populate SMD using static data from a file or standard input, or from a scan
+for BMCs.
 
 The format of this command is:
 
-*static* [--overwrite] [-d (_data_ | @_path_)] [-f _format_]
+*static* [--scan (_cidr_ | _ip_)... [--credentials-file _creds_file_] [-r _rules_file_]] [--overwrite] [--state-file _path_ [--force]] [--batch-size _n_] [--concurrency _n_] [--retries _n_] [--auto-group _group_=_spec_]... [--default-group _group_,...] [--sync-groups [--prune-groups]] [--push-bmc-creds] [--dry-run [--output-dir _dir_] [-F _format_]] [-d (_data_ | @_path_)] [-f _format_]
 
 The *static* subcommand provides a way to use structured data (from standard
 input or a file) to emulate the SMD discovery process in a reproducable way
@@ -90,10 +410,20 @@ If *-d* is not specified, then the data is read from standard input. The format
 of the input data is JSON by default, but *-f* can be used to specify a
 different format.
 
+Instead of reading data, *--scan* scans the subnets (e.g. _10.0.0.0/24_) and
+addresses passed to it for BMCs with a Redfish service on *--port*, up to
+*--scan-concurrency* addresses at once, the same way as the *scan* subcommand,
+and the BMCs found are mapped to nodes by the rules in *--rules* and sent the
+same way as with the *magellan* subcommand, so that a new rack can be added
+without writing a payload for it. *--rules* is required unless *--dry-run* is
+passed, since BMCs are otherwise mapped to placeholder xnames, and *-f* is the
+format of the files passed to *--credentials-file* and *--rules*. To write the
+nodes found to a payload file for review instead, use the *scan* subcommand.
+
 The data should contain a list of "nodes", each with its own configuration (see
 *DATA STRUCTURE*). The *static* command reads this data and creates the SMD
-RedfishEndpoints, EthernetInterfaces, Components, and groups data in SMD
-corresponding to each node. It also creates Components corresponding to each
+RedfishEndpoints, EthernetInterfaces, Components, hardware inventory, and groups
+data in SMD corresponding to each node. It also creates Components corresponding to each
 node's BMC which corresponds to each RedfishEndpoint created.
 
 The *--discovery-version* sets which discovery method to use when running the
@@ -102,12 +432,121 @@ to create the EthernetInterfaces separately in SMD. If set to 2 (the default),
 the EthernetInterfaces are created with the first discovery request.
 This flag is only for backward compatibility with earlier versions of SMD and
 may be deprecated in a later version of ochami.
+
+Nodes can be added to groups without listing the groups for each node in the
+data. *--default-group* adds every node to the given groups, and *--auto-group*
+adds the nodes matching a rule to a group. Defaults for both can be configured
+per cluster with the *discovery* key (see *ochami-config*(5)), to which any
+passed on the command line are added. Groups that a node already lists are not
+added again.
+
+Each group is created in SMD with the nodes that list it as its members. If a
+group already exists, its request fails unless *--overwrite* is passed, in
+which case its description is updated but not its members. To keep the members of existing groups in sync with the data, pass
+*--sync-groups*: missing groups are created and nodes that are not yet members
+of the groups they list are added to them. With *--prune-groups*, members of
+those groups that no node in the data lists them in are removed as well. Groups
+that no node in the data lists are left as is.
+
+The credentials of each node's BMC are stored in SMD with its RedfishEndpoint:
+its *bmc_username* and *bmc_password* (or *bmc_password_file*) if set, or
+*discovery.bmc-username* and *discovery.bmc-password* of the cluster. Services
+that read BMC credentials from Vault instead (e.g. PCS) need them there too:
+with *--push-bmc-creds*, they are also written to the KV secret engine of Vault
+(see *secrets.vault-uri* in *ochami-config*(5)) under
+*discovery.bmc-creds-path* (_secret/hms-creds_ by default), one secret for each
+BMC named by its xname, after the RedfishEndpoints are sent. BMCs without
+credentials are skipped.
+
+If *--dry-run* is passed, nothing is sent to SMD and no token is needed.
+Instead, the payloads that would be sent are printed as a single document with
+the keys _components_, _redfish_endpoints_, _ethernet_interfaces_ (only with
+discovery version 1), _hardware_inventory_ (only if nodes have *hardware*), and
+_groups_. Each is exactly the body that would be sent
+to the corresponding SMD endpoint, except that BMC passwords are replaced with
+_REDACTED_. With *--output-dir*, each payload is written to its own file in the
+directory instead, e.g. _components.json_ and _redfish-endpoints.json_ (or
+_.yaml_ with *-F yaml*), which is convenient for inspecting them or keeping them
+in version control.
+
+By default, all records of a kind are sent at once, one kind after the other.
+For large clusters, *--batch-size* or *--concurrency* sends the Components,
+RedfishEndpoints, EthernetInterfaces, and hardware inventory in batches of up to
+*--batch-size* records, up to *--concurrency* batches at once. All of a kind are
+sent before the next kind, so Components are still created before their
+RedfishEndpoints. The Components and hardware inventory entries of a batch are
+sent in a single request and, if it fails, are retried together. SMD takes one
+RedfishEndpoint or EthernetInterface per request, so these are still sent one
+request each, the batch only grouping them for *--concurrency* and *--retries*,
+and only those that failed are retried. A batch is
+retried up to *--retries* times, waiting a little longer before each retry. At
+the end, the number of records of each kind that were sent and that failed is
+printed to standard error. Groups are always sent as without batching.
+
+When iterating on a large payload file, *--state-file* only sends the
+Components, RedfishEndpoints, EthernetInterfaces, and hardware inventory entries
+that changed since the last run. A hash of each record sent is saved to the
+file, per SMD base URI so that one file can be used for several clusters, and
+records whose hash did not change are not sent again. Records are only recorded
+once all records of their kind were sent, so that those that failed are sent
+again next time. The hashes cover BMC credentials, so the file is created
+readable only by the user. Groups are always sent. Since the state file only
+knows what was sent, pass *--force* to send every record, e.g. after records
+were changed or deleted in SMD by other means. With *--dry-run*, only the
+records that would be sent are output, and the file is not changed.
+
+Problems with nodes that do not stop the payloads from being generated are
+logged as warnings, each with the index and xname of its node and a code in
+brackets: _duplicate-xname_ for a node with the xname of an earlier one,
+_bmc-xname-fallback_ for a node whose BMC xname cannot be derived from its
+xname, _bmc-mismatch_ for BMC keys that differ from those of an earlier node
+sharing the BMC, _ignored-bmc_ for BMC keys that are ignored, and
+_no-interfaces_ for a node without interfaces or bonds. If there is more than
+one, the number with each code is logged after them. Pass *--fail-on-warn* (see
+*ochami*(1)) to exit with a nonzero status if any are logged.
+
+Malformed nodes, such as a node with invalid BMC credentials, invalid bonds, or
+an interface without a valid _mac_addr_ or with an invalid _ip_addr_, stop the
+payloads from being generated by default. With *--on-error skip-node*, they are
+left out of the payloads instead, each with a _skipped-node_ warning, and the
+rest are sent. With *--on-error skip-interface*, only the malformed interfaces
+are left out, each with a _skipped-interface_ warning, and nodes that are
+malformed otherwise are skipped. An interface with a _mac_addr_ but no
+_ip_addrs_ is not malformed, and is sent without IP addresses. What was skipped
+is logged again once the payloads are sent.
+
 This command accepts the following options:
 
+*--auto-group* _group_=_spec_
+	Add the nodes matching _spec_ to _group_. This flag can be passed more than
+	once. _spec_ is one of:
+
+	- *nid:*_ranges_, a comma-separated list of NIDs and inclusive NID ranges,
+	  e.g. _gpu=nid:1-16,20_
+	- a regular expression that must match the entire xname of a node, e.g.
+	  _compute=x3000c0s[0-7]b0n.\*_
+
+*--batch-size* _n_
+	Group records into batches of up to _n_ (default: _100_). Only the
+	Components and hardware inventory of a batch are sent in one request. See
+	above.
+
+*--concurrency* _n_
+	Send up to _n_ batches at once (default: _1_). See above.
+
+*--credentials-file* _creds_file_
+	With *--scan*, file containing the credentials to try on each BMC, like
+	for the *scan* subcommand.
+
 *-d, --data* (_data_ | @_path_ | @-)
 	Specify raw _data_ to send, the _path_ to a file to read payload data from,
 	or to read the data from standard input (@-). The format of data read in any
 	of these forms is JSON by default unless *-f* is specified to change it.
+	Cannot be used with *--scan*.
+
+*--default-group* _group_,...
+	Add every node to each _group_, in addition to the groups listed for the
+	node in the data.
 
 *--discovery-version*
 	Set the version of the discovery method to use for static discovery.
@@ -116,6 +555,16 @@ This command accepts the following options:
 	- _1_
 	- _2_ (default)
 
+*--dry-run*
+	Output the payloads that would be sent to SMD without sending them.
+
+*-F, --format-output* _format_
+	With *--dry-run*, format of the payloads output. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
 *-f, --format-input* _format_
 	Format of the input data. If unspecified, the payload format is _json_ by
 	default. Supported formats are:
@@ -123,11 +572,66 @@ This command accepts the following options:
 	- _json_ (default)
 	- _json-pretty_
 	- _yaml_
+	- _csv_ (see *CSV*)
+
+*--force*
+	With *--state-file*, send every record, even those that did not change since
+	the last run.
+
+*-o, --output-dir* _dir_
+	With *--dry-run*, write each payload to a file in _dir_, which is created
+	if needed, instead of printing them.
+
+*--on-error* _policy_
+	What to do with malformed nodes. See above. Supported values are:
+
+	- _fail-fast_ (default): fail without sending anything
+	- _skip-node_: skip malformed nodes
+	- _skip-interface_: skip malformed interfaces, or else nodes
 
 *--overwrite*
 	Instead of failing if data already exists, overwrite it with new data
 	contained in the payload.
 
+*--prune-groups*
+	Remove the members of the groups in the data that no node in the data lists
+	them in. Implies *--sync-groups*.
+
+*--port* _port_
+	With *--scan*, port of the Redfish service of BMCs (default: _443_).
+
+*--push-bmc-creds*
+	Also write the BMC credentials to Vault. See above.
+
+*-r, --rules* _rules_file_
+	With *--scan*, file containing the rules mapping BMCs to xnames, like for
+	the *scan* subcommand.
+
+*--retries* _n_
+	With *--batch-size* or *--concurrency*, retry the records of a batch that
+	failed up to _n_ times (default: _2_).
+
+*--scan* (_cidr_ | _ip_),...
+	Scan these subnets and addresses for BMCs and send them instead of reading
+	data. Can be repeated. See above.
+
+*--scan-concurrency* _n_
+	With *--scan*, probe up to _n_ addresses at once (default: _32_).
+
+*--state-file* _path_
+	Save the hashes of the records sent to _path_ and only send the records that
+	changed since the last run. See above.
+
+*--sync-groups*
+	Create the groups in the data that are missing in SMD and add nodes to the
+	existing groups they list. See above. The members of each group that is
+	changed are read back afterwards, and the command fails if a change was
+	undone concurrently by another client.
+
+*--target-timeout* _duration_
+	With *--scan*, maximum time to spend probing each address (default:
+	_10s_, _0_ for no limit).
+
 *--discovery-version*
 	Set the version of the discovery method to use for static discovery.
 
@@ -135,6 +639,750 @@ This command accepts the following options:
 	- _1_
 	- _2_ (default)
 
+*--validate-schema*
+	Check the payload against the schema of the format before reading it and
+	fail if it does not match (see *Schema*).
+
+## append
+
+Add new nodes to SMD from a partial payload, e.g. one containing only a rack
+being added to an existing system.
+
+The format of this command is:
+
+*append* [--auto-group _group_=_spec_]... [--default-group _group_,...] [-d (_data_ | @_path_)] [-f _format_]
+
+The payload has the same format as for *static* (see *DATA STRUCTURE*). Before
+anything is sent, it is checked against the Components and RedfishEndpoints in
+SMD, since xnames and NIDs must be unique across both the payload and SMD.
+Appending fails without changing anything if:
+
+- an xname or NID is used by more than one node in the payload
+- a node's xname is in SMD with a different NID, or is not a node
+- a new node's NID is already used by another Component in SMD
+- a new node's BMC is already in SMD (use *static --overwrite* to add nodes to
+  existing BMCs)
+
+Nodes already in SMD with the same NID are skipped, so the same payload can be
+appended more than once. Only the new nodes are added, along with their
+RedfishEndpoints and EthernetInterfaces. New nodes are added to the groups they
+are in: groups that do not exist are created and groups that do have the nodes
+added as members. Existing nodes and groups are otherwise left alone.
+
+This command sends GETs to SMD's /State/Components, /Inventory/RedfishEndpoints,
+and /groups endpoints, then POSTs for the new data.
+
+This command accepts the following options:
+
+*--auto-group* _group_=_spec_
+	Add the nodes matching _spec_ to _group_. See *static*.
+
+*-d, --data* (_data_ | @_path_ | @-)
+	Specify raw _data_ to send, the _path_ to a file to read payload data from,
+	or to read the data from standard input (@-). The format of data read in any
+	of these forms is JSON by default unless *-f* is specified to change it.
+
+*--default-group* _group_,...
+	Add every node to each _group_, in addition to the groups listed for the
+	node in the data.
+
+*--discovery-version*
+	Set the version of the discovery method to use. See *static*.
+
+*-f, --format-input* _format_
+	Format of the input data. If unspecified, the payload format is _json_ by
+	default. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+	- _csv_ (see *CSV*)
+
+*--on-error* _policy_
+	What to do with malformed nodes. See *static*.
+
+*--validate-schema*
+	Check the payload against the schema of the format before reading it and
+	fail if it does not match (see *Schema*).
+
+## diff
+
+Compare a payload with the data in SMD and print what would be added, changed,
+or removed to make SMD match it, without changing anything.
+
+The format of this command is:
+
+*diff* [-d (_data_ | @_path_)] [-f _format_] [-F _format_]
+
+The payload has the same format as for *static* (see *DATA STRUCTURE*). The
+Components, RedfishEndpoints, and EthernetInterfaces that discovering it would
+create are compared with those in SMD:
+
+- Components by xname, comparing their *Type* and *NID*
+- RedfishEndpoints by xname, comparing their *FQDN*, *MACAddr*, and
+  *IPAddress*
+- EthernetInterfaces of nodes and their BMCs by MAC address (printed as the ID
+  SMD gives them, e.g. _decafc0feeee_), comparing their *ComponentID* and
+  *IPAddresses*
+
+Fields that SMD sets itself, like the *State* of Components, are not compared,
+and neither are groups. Records in SMD that are not in the payload are printed
+as removed if they are of the kinds discovery creates: Components of type
+_Node_ or _VirtualNode_, RedfishEndpoints of type _NodeBMC_, and their
+EthernetInterfaces. Note that *static* never removes anything.
+
+Each entry printed has the *kind* of the record (_Component_,
+_RedfishEndpoint_, or _EthernetInterface_), its *id*, the *action* (_add_,
+_change_, or _remove_), and, for changes, the *changes* of each field *from* the
+value in SMD *to* the one in the payload. For example, in YAML:
+
+```
+entries:
+    - kind: Component
+      id: x1000c1s7b1n0
+      action: change
+      changes:
+        - field: NID
+          from: "12"
+          to: "2"
+    - kind: RedfishEndpoint
+      id: x1000c1s7b2
+      action: add
+```
+
+This command sends GETs to SMD's /State/Components, /Inventory/RedfishEndpoints,
+and /Inventory/EthernetInterfaces endpoints.
+
+This command accepts the following options:
+
+*-d, --data* (_data_ | @_path_ | @-)
+	Specify raw _data_ to compare, the _path_ to a file to read payload data
+	from, or to read the data from standard input (@-). The format of data read
+	in any of these forms is JSON by default unless *-f* is specified to change
+	it.
+
+*-f, --format-input* _format_
+	Format of the input data. If unspecified, the payload format is _json_ by
+	default. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+	- _csv_ (see *CSV*)
+
+*-F, --format-output* _format_
+	Format of the output. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*--validate-schema*
+	Check the payload against the schema of the format before reading it and
+	fail if it does not match (see *Schema*).
+
+## plan
+
+Compare a payload with the data in SMD and print the execution plan of
+discovering it with *static*: the records that would be created or updated,
+step by step in the order they would be sent, and the number of requests each
+step would send. Nothing is changed in SMD. Pass *--out* to write the plan to a
+file that *apply* applies once it has been reviewed.
+
+The format of this command is:
+
+*plan* [--overwrite] [--auto-group _group_=_spec_]... [--default-group _group_,...] [--out _path_] [-F _format_] [-d (_data_ | @_path_)] [-f _format_]
+
+The payload, groups, and discovery version are handled as for *static*. The
+steps and what they depend on are:
+
+. Components, sent first so that SMD uses their NIDs
+. RedfishEndpoints, after the Components
+. EthernetInterfaces, with discovery version 1 only, after the Components
+. groups, after the Components they have as members
+
+The hardware inventory of the payload, if any, is not compared with SMD, which
+replaces existing entries with it. It is kept in the plan and listed after the
+steps, and *apply* sends all of it.
+
+Each record is compared with SMD the way *diff* compares it, groups being
+compared by their members, and gets one of these actions:
+
+- _create_: it is not in SMD
+- _update_: it differs from SMD and *--overwrite* was passed
+- _conflict_: it differs from SMD, but *--overwrite* was not passed, so it is
+  left as is and a warning is printed
+- _no-op_: it is in SMD as is
+
+New Components are sent in a single request and other new records one per
+request. Updating sends one request per Component and one more to set their
+NIDs, and two per other record (a POST, then a PUT or PATCH), as *static
+--overwrite* does.
+
+The plan is printed as text, with records to create marked with _+_, to update
+with _~_, and conflicting ones with _!_, followed by the changes of their fields,
+e.g.:
+
+```
+1. SMD Component: 3 request(s)
+  ~ x1000c1s7b1n0
+      NID: "12" -> "2"
+  + x1000c1s7b2n0
+  1 unchanged
+2. SMD RedfishEndpoint (after Component): 1 request(s)
+  + x1000c1s7b2
+Plan: 2 to create, 1 to update, 1 unchanged, 0 conflicting; 4 request(s)
+```
+
+With *-F*, it is printed in that format instead, as the *version* of the plan
+format, whether it *overwrite*s, its *steps* (each with the *service*, *kind*,
+*depends_on*, *resources* with their *id*, *action*, and *changes*, and
+*requests*), the total *requests*, and the *payloads* to send. The plan holds no
+BMC credentials: those set per node in the payload are dropped with a warning,
+and *apply* sets those of the cluster (see *ochami-config*(5)).
+
+This command sends GETs to SMD's /State/Components, /Inventory/RedfishEndpoints,
+/Inventory/EthernetInterfaces, and /groups endpoints.
+
+This command accepts the following options:
+
+*--auto-group* _group_=_spec_
+	Add nodes to _group_ as for *static*. Can be passed more than once.
+
+*--default-group* _group_,...
+	Add every node to one or more groups as for *static*.
+
+*--discovery-version*
+	Version of the discovery method to plan for, as for *static*.
+
+*-d, --data* (_data_ | @_path_ | @-)
+	Specify raw _data_ to plan for, the _path_ to a file to read payload data
+	from, or to read the data from standard input (@-). The format of data read
+	in any of these forms is JSON by default unless *-f* is specified to change
+	it.
+
+*-f, --format-input* _format_
+	Format of the input data. If unspecified, the payload format is _json_ by
+	default. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+	- _csv_ (see *CSV*)
+
+*-F, --format-output* _format_
+	Print the plan in this format instead of as text. Supported formats are:
+
+	- _json_
+	- _json-pretty_
+	- _yaml_
+
+*--on-error* _policy_
+	What to do with malformed nodes. See *static*.
+
+*--out* _path_
+	Write the plan to _path_ as JSON for *apply --plan*.
+
+*--overwrite*
+	Plan to update records that differ in SMD instead of leaving them as is.
+
+*--validate-schema*
+	Check the payload against the schema of the format before reading it and
+	fail if it does not match (see *Schema*).
+
+## apply
+
+Apply a plan written by *plan --out*, sending the records it creates and
+updates to SMD step by step. Records whose action is _no-op_ or _conflict_ are
+not sent. The hardware inventory of the plan is sent as is after the
+EthernetInterfaces, as with *static*.
+
+The format of this command is:
+
+*apply* --plan _path_
+
+Before anything is sent, the plan is made again from its payloads and the data
+in SMD. If the action of any record differs, e.g. because it was added to SMD
+after the plan was written, each such record is printed, nothing is sent, and
+the exit status is 1, since the plan that was reviewed is no longer what would
+happen. Make a new plan in that case.
+
+Since plans hold no BMC credentials, the RedfishEndpoints that have none are
+given *discovery.bmc-username* and *discovery.bmc-password* of the cluster (see
+*ochami-config*(5)) when the plan is applied, as with *static*.
+
+This command sends GETs to the same SMD endpoints as *plan*, then the requests
+of the plan.
+
+This command accepts the following options:
+
+*--plan* _path_
+	File containing the plan to apply. Required.
+
+## network-config
+
+Generate the cloud-init network configuration that sets up the *bonds* of the
+nodes in a payload.
+
+The format of this command is:
+
+*network-config* [-d (_data_ | @_path_)] [-f _format_] [-F _format_]
+
+The payload has the same format as for *static* (see *DATA STRUCTURE*). The
+network configuration (version 2) of each node with bonds is printed, keyed by
+the xname of the node; nodes without bonds are left out. Each member of a bond
+is matched by MAC address and named after the bond and its index (e.g.
+_bond0m0_). The bond takes the MAC address of its primary member, which is the
+EthernetInterface that *static* gives the IP addresses of the bond, so it gets
+them from DHCP. Bonds are monitored with MII every 100 ms, LACP bonds use a fast
+LACP rate and hash by layer 3 and 4 headers, and bonds in modes that prefer a
+primary member (_active-backup_, _balance-tlb_, and _balance-alb_) get the
+primary set. For example, in YAML:
+
+```
+x1000c1s7b0n0:
+    network:
+        version: 2
+        ethernets:
+            bond0m0:
+                match:
+                    macaddress: de:ad:be:ee:ee:f1
+            bond0m1:
+                match:
+                    macaddress: de:ad:be:ee:ee:f2
+        bonds:
+            bond0:
+                interfaces:
+                    - bond0m0
+                    - bond0m1
+                macaddress: de:ad:be:ee:ee:f1
+                dhcp4: true
+                parameters:
+                    mode: 802.3ad
+                    lacp-rate: fast
+                    transmit-hash-policy: layer3+4
+                    mii-monitor-interval: 100
+```
+
+No requests are sent.
+
+This command accepts the following options:
+
+*-d, --data* (_data_ | @_path_ | @-)
+	Specify raw _data_, the _path_ to a file to read payload data from, or to
+	read the data from standard input (@-). The format of data read in any of
+	these forms is JSON by default unless *-f* is specified to change it.
+
+*-f, --format-input* _format_
+	Format of the input data. If unspecified, the payload format is _json_ by
+	default. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+	- _csv_ (see *CSV*)
+
+*-F, --format-output* _format_
+	Format of the output. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*--validate-schema*
+	Check the payload against the schema of the format before reading it and
+	fail if it does not match (see *Schema*).
+
+## validate
+
+Check a payload for problems without sending anything to SMD.
+
+The format of this command is:
+
+*validate* [-d (_data_ | @_path_)] [-f _format_] [-F _format_] [--report-format _format_ [--report-file _path_]]
+
+The payload has the same format as for *static* (see *DATA STRUCTURE*) and is
+read the same way. It is checked for problems that would otherwise only surface
+as warnings during discovery or as errors from SMD:
+
+- A missing or invalid node *xname*, or a *hypervisor* that is not a BMC xname
+  or is set for a node that is not *virtual*.
+- A missing *nid*.
+- A MAC address or IP address of the BMC, an interface, or a bond that is
+  missing or invalid.
+- A node without *interfaces* (or *bonds*) or, unless it is *virtual*, without
+  a *bmc_mac* (or *bmc_interfaces*).
+- A *power_actions* entry that is not a Redfish ResetType (they are case
+  sensitive) or is listed twice.
+- Invalid *bmc_interfaces* or *bonds*, as described in *DATA STRUCTURE*.
+- A negative *hardware* count or size, or more *cpu_serial_numbers* than
+  *cpu_count*.
+- An xname, NID, *name*, MAC address, IP address, or *hardware* serial number
+  used more than once, except for the BMC addresses of nodes that share a BMC.
+- For devices with a *type* other than _Node_, an unknown *type*, an *xname*
+  that does not match it, or a *nid*, *virtual*, *hypervisor*,
+  *power_actions*, or *hardware*, which devices cannot have, instead of the
+  problems specific to nodes. Devices of types without a BMC cannot have BMC
+  keys either.
+
+Each problem is printed with the index of its node in the payload (after
+compact entries are expanded), its xname, the path of the field (e.g.
+_interfaces[0].mac_addr_), its value, and a message. The command exits with a
+nonzero status if any problems are found. Pass *--report-format* to output the
+problems as a report for CI systems instead, with a result for each node.
+
+This command accepts the following options:
+
+*-d, --data* (_data_ | @_path_ | @-)
+	Specify raw _data_ to check, the _path_ to a file to read payload data
+	from, or to read the data from standard input (@-). If not passed, the data
+	is read from standard input. The format of data read in any of these forms
+	is JSON by default unless *-f* is specified to change it.
+
+*-f, --format-input* _format_
+	Format of the input data. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+	- _csv_ (see *CSV*)
+
+*-F, --format-output* _format_
+	Format of the output. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*--report-file* _path_
+	Write the report requested with *--report-format* to _path_ in addition to
+	the normal output. By default, the report is written to standard output
+	instead of the normal output.
+
+*--report-format* _format_
+	Write problems as a report for CI systems. Supported values are:
+
+	- _junit_
+	- _sarif_
+
+*--validate-schema*
+	Check the payload against the schema of the format before reading it and
+	fail if it does not match (see *Schema*).
+
+## schema
+
+Print the JSON Schema of the current version of the format to standard output
+(see *Schema*).
+
+The format of this command is:
+
+*schema*
+
+## migrate
+
+Update a static discovery payload file to the current version of the format.
+
+The format of this command is:
+
+*migrate* [-f _in_format_] [-F _out_format_] _in_file_ [_out_file_]
+
+Read the payload in _in_file_, migrate any deprecated shapes it contains (see
+*DATA STRUCTURE*), and write the result, including the current *version*, to
+_out_file_ or, if it is omitted, back to _in_file_, upgrading it in place.
+Either can be _-_ to use standard input or standard output. The file is
+replaced atomically and keeps its permissions. Compact entries describing many
+nodes are kept as they are. A warning is logged for each migration performed. The migrations performed for
+unversioned payloads are:
+
+- A list of nodes that is not under a *nodes* key is put under one.
+- *group* is merged into *groups*.
+- *name* in *ip_addrs* is renamed to *network*.
+
+The *static* command performs the same migration in memory without changing
+the file.
+
+This command accepts the following options:
+
+*-f, --format-input* _format_
+	Format of _in_file_. Supported formats are:
+
+	- _json_ (default)
+	- _yaml_
+
+*-F, --format-output* _format_
+	Format of _out_file_. By default, this is the same as the input format.
+	Supported formats are:
+
+	- _json_
+	- _json-pretty_
+	- _yaml_
+
+## export
+
+Generate a static discovery payload from what is in SMD.
+
+The format of this command is:
+
+*export* [-F _format_]
+
+The Components, RedfishEndpoints, EthernetInterfaces, and groups in SMD are
+read and a payload in the current version of the format (see *DATA STRUCTURE*)
+is printed to standard output that, passed to *static*, would generate the same
+records. This snapshots the inventory of an existing cluster into a file that
+can be edited and replayed.
+
+Each Component of type _Node_, _VirtualNode_, or one of the device types (see
+*static*) gets an entry, sorted by xname, with:
+
+- its NID, for nodes
+- the MAC address, IP address, and FQDN of the RedfishEndpoint of its BMC
+- its EthernetInterfaces, as *interfaces*
+- the groups it is a member of, except those holding metadata (see
+  *ochami-smd*(1))
+
+Other Components, such as the _NodeBMC_ Components SMD creates for
+RedfishEndpoints, do not get entries of their own. The *name* of an entry and
+its *bonds* are recovered from the descriptions *static* gives the
+EthernetInterfaces it generates, so they are only set for entries that were
+discovered with ochami. The *power_actions* of nodes, the *mode* of bonds, and
+the *hypervisor* of virtual nodes are not kept in SMD records and are left
+unset, with a warning for each bond and virtual node. BMCs with more than one
+interface are described with *bmc_mac* and *bmc_ip* of their primary interface.
+
+This command sends GET requests to SMD's /State/Components,
+/Inventory/RedfishEndpoints, /Inventory/EthernetInterfaces, and /groups
+endpoints. An access token is required.
+
+This command accepts the following options:
+
+*-F, --format-output* _format_
+	Format of the payload printed. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+## from-dhcp-leases
+
+Generate a static discovery payload from the DHCP leases of BMCs.
+
+The format of this command is:
+
+*from-dhcp-leases* -r _rules_file_ [-f _format_] [--lease-format _lease_format_] [-F _format_] _lease_file_ [_out_file_]
+
+This is useful for sites that bring BMCs up via DHCP before OpenCHAMI is
+populated. Read the leases in _lease_file_ (a dnsmasq or Kea lease file, or _-_
+for standard input), map each to a node using the rules in _rules_file_, and
+write the resulting payload (see *DATA STRUCTURE*) to _out_file_, or standard
+output if it is omitted or _-_. The MAC and IP address of each lease become the
+*bmc_mac* and *bmc_ip* of its node. The payload can then be edited to add node
+interfaces and passed to *static*.
+
+The rules file is an object containing a *rules* array. Leases are ordered by IP
+address and each is mapped by the first rule whose *mac_prefix* matches the
+start of its MAC address (case insensitive, with : or - separators). A warning
+is logged for each lease that matches no rule. An example rules file in YAML
+format is as follows:
+
+```
+rules:
+- mac_prefix: de:ca:fc
+  xname: x1000c1s{{.Index}}b0n0
+  name: nid{{printf "%03d" (add .Index 1)}}
+  nid_start: 1
+  groups:
+  - compute
+- mac_prefix: aa:bb:cc
+  xname: x1000c2s{{index .Octets 3}}b0n0
+  bmc_fqdn: "{{.Hostname}}.bmc.example.com"
+```
+
+Each rule has the following keys:
+
+*mac_prefix*
+	Prefix of the BMC MAC addresses the rule maps. An empty prefix matches any
+	lease.
+
+*xname*
+	Required template of the xname of the node. Rules that map two leases to
+	the same xname are an error.
+
+*name*
+	Template of the name of the node. Defaults to the node's xname.
+
+*bmc_fqdn*
+	Template of the FQDN of the node's BMC. Defaults to empty.
+
+*nid_start*
+	NID of the first node mapped by the rule, subsequent nodes getting
+	consecutive NIDs. If not set, the rule's nodes are assigned the lowest NIDs
+	not already used.
+
+*groups*
+	Groups to add the rule's nodes to.
+
+The templates are Go templates with the following fields:
+
+- *.Index*: position of the lease among those matched by the rule, starting at
+  0
+- *.MAC*: MAC address of the lease, in lower case with : separators
+- *.IP*: IP address of the lease
+- *.Octets*: list of the octets of the lease's IPv4 address
+- *.Hostname*: hostname of the lease, if any
+
+along with an *add* function for adding two numbers.
+
+This command accepts the following options:
+
+*-f, --format-input* _format_
+	Format of _rules_file_. Supported formats are:
+
+	- _json_ (default)
+	- _yaml_
+
+*-F, --format-output* _format_
+	Format of the output payload. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*--lease-format* _lease_format_
+	Format of _lease_file_. By default, this is detected from its content.
+	Supported formats are:
+
+	- _dnsmasq_
+	- _kea_ (memfile CSV)
+
+*-r, --rules* _rules_file_
+	File containing the rules for mapping leases to nodes. Required.
+
+## scan
+
+Generate a static discovery payload by scanning subnets for Redfish endpoints.
+
+The format of this command is:
+
+*scan* --subnet _cidr_... [--credentials-file _creds_file_] [-r _rules_file_] [-f _format_] [-F _format_] [_out_file_]
+
+This is useful for sites whose BMCs are already up but not yet known to
+OpenCHAMI. Probe each address of the subnets passed with *--subnet* for a
+Redfish service root, read the managers and systems of each BMC found, map each
+BMC to a node, and write the resulting payload (see *DATA STRUCTURE*) to
+_out_file_, or standard output if it is omitted or _-_. The payload should be
+reviewed before it is passed to *static*.
+
+Each BMC is read using the first credentials that it accepts, trying those in
+_creds_file_ in order and then *discovery.bmc-username* and
+*discovery.bmc-password* of the cluster if set (see *ochami-config*(5)). A
+warning is logged for each Redfish endpoint that accepts none of them. An
+example credentials file in YAML format is as follows:
+
+```
+credentials:
+- username: root
+  password: initial0
+- username: admin
+  password: changeme
+```
+
+BMCs are ordered by IP address and mapped to nodes by _rules_file_, which has
+the same format as for *from-dhcp-leases*. The MAC address of the BMC
+interface that has the scanned IP address (or of its first interface) is used as
+the lease MAC address, and the host name of its first system as the lease
+hostname. If *--rules* is not passed, each BMC is mapped to the placeholder
+xname *x0c0s*_N_*b0n0*, where _N_ is its index, which must be corrected before
+the payload is used. The MAC addresses of the Ethernet interfaces of the first
+system of each BMC become the *interfaces* of its node, without IP addresses.
+
+This command accepts the following options:
+
+*--concurrency* _n_
+	Maximum number of addresses to probe at once. Defaults to 32.
+
+*--credentials-file* _creds_file_
+	File containing the BMC credentials to try.
+
+*-f, --format-input* _format_
+	Format of _creds_file_ and _rules_file_. Supported formats are:
+
+	- _json_ (default)
+	- _yaml_
+
+*-F, --format-output* _format_
+	Format of the output payload. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*--port* _port_
+	Port of the Redfish service of BMCs. Defaults to 443. BMCs are always
+	reached via HTTPS.
+
+*-r, --rules* _rules_file_
+	File containing the rules for mapping BMCs to nodes.
+
+*--subnet* _cidr_
+	Subnet to scan, in CIDR notation, or a single IP address. Can be passed
+	more than once. Subnets with more than 65536 addresses are rejected, and
+	the network and broadcast addresses of IPv4 subnets are skipped.
+	Required.
+
+*--target-timeout* _duration_
+	Maximum time to spend probing each address, after which it is skipped.
+	Defaults to _10s_. _0_ means no limit.
+
+## magellan
+
+Discover BMCs and their nodes via Redfish and populate SMD with them.
+
+The format of this command is:
+
+*magellan* --subnet _cidr_... [--credentials-file _creds_file_] [-r _rules_file_] [--overwrite] [--auto-group _group_=_spec_]... [--default-group _group_,...] [--sync-groups [--prune-groups]] [--push-bmc-creds] [--dry-run [--output-dir _dir_] [-F _format_]] [-f _format_]
+
+This performs live discovery the way _magellan_ does, sending what was read from
+the BMCs to SMD without an intermediate payload. The subnets and addresses
+passed with *--subnet* are scanned and the BMCs found are read and mapped to
+nodes by _rules_file_ as with *scan*, then the Components, RedfishEndpoints, and
+groups of the nodes are sent to SMD as with *static*, whose options of the
+same names this command accepts.
+
+The RedfishEndpoint of each BMC is populated with what was read from it rather
+than generated: its System is the first system of the BMC, with its Redfish URI
+and all of its Ethernet interfaces, along with the IPv4 addresses the BMC
+reports for them, if any. The credentials that the BMC accepted are stored with
+the RedfishEndpoint.
+
+*--rules* is required unless *--dry-run* is passed, since BMCs would otherwise
+be sent with placeholder xnames. To review and edit the nodes before they are
+sent, use *scan* and *static* instead.
+
+This command sends POSTs to SMD's /State/Components, /Inventory/RedfishEndpoints,
+and /groups endpoints, which require a token unless *--dry-run* is passed.
+
+This command accepts the options of *scan* other than _out_file_, as well as
+the following options, which are the same as for *static*:
+
+*--auto-group* _group_=_spec_, *--default-group* _group_,...
+	Add nodes to groups. See *static*.
+
+*--dry-run*
+	Output the payloads that would be sent to SMD without sending them.
+
+*-o, --output-dir* _dir_
+	With *--dry-run*, write each payload to a file in _dir_ instead of printing
+	them.
+
+*--overwrite*
+	Instead of failing if data already exists, overwrite it with new data.
+
+*--push-bmc-creds*
+	Also write the BMC credentials to Vault. See *static*.
+
+*--sync-groups*, *--prune-groups*
+	Sync the groups of the nodes with those in SMD. See *static*.
+
 # XNAMES
 
 An *xname* is a structured and succinct way to identify a node based on its type
@@ -0,0 +1,104 @@
+OCHAMI-EVENTS(1) "OpenCHAMI" "Manual Page for ochami-events"
+
+# NAME
+
+ochami-events - Watch change events from OpenCHAMI services
+
+# SYNOPSIS
+
+ochami events tail [--service _service_]... [--interval _duration_] [--listen _addr_ [--callback-url _url_]] [--sse _service_=_path_]...
+
+# DESCRIPTION
+
+The *events* command is a metacommand for watching changes made to data in
+OpenCHAMI services.
+
+# EVENT FORMAT
+
+Events are printed to standard output as newline-delimited JSON (NDJSON), one
+event per line. An example of an event is as follows:
+
+```
+{"time":"2025-01-02T03:04:05Z","service":"smd","source":"poll","kind":"component","action":"changed","id":"x1000c1s7b0n0","data":{"ID":"x1000c1s7b0n0","Type":"Node","State":"Ready"}}
+```
+
+A description of each key in the above is as follows:
+
+- *time* - The time the event was received, in RFC 3339 format.
+- *service* - The service the event is from, _smd_ or _bss_.
+- *source* - The mechanism the event was received by: _poll_, _webhook_ (SMD
+state change notifications), or _sse_ (server-sent events).
+- *kind* - The kind of item the event is about. For polled events, this is one
+of _component_, _redfish-endpoint_, _ethernet-interface_, and _group_ for SMD
+and _boot-parameters_ and _boot-script_ for BSS. For server-sent events, this
+is the event type of the message (_message_ if it has none).
+- *action* - What happened to the item. For polled events, this is _added_,
+_changed_, or _removed_, except that boot script fetches are _fetched_. For
+state change notifications and server-sent events, this is _notified_.
+- *id* - The ID of the item, e.g. the xname of a component, the label of a
+group, or the host, MAC address, or NID that boot parameters are for. This is
+omitted if it is not known.
+- *data* - The item after it was added or changed, or before it was removed.
+For state change notifications, these are the new values of the component's
+fields. For server-sent events, this is the data of the message.
+
+# COMMANDS
+
+## tail
+
+Print change events from SMD and BSS as they happen until interrupted.
+
+The format of this command is:
+
+*tail* [--service _service_]... [--interval _duration_] [--listen _addr_ [--callback-url _url_]] [--sse _service_=_path_]...
+
+Events from each service are received using the first of the following
+mechanisms that is available:
+
+. If *--sse* is passed for the service, the server-sent event stream at the path
+is read, reconnecting every *--interval* if it ends.
+. For SMD, if *--listen* is passed, a listener for state change notifications
+(SCNs) is started on the address and SMD is subscribed to send SCNs for all
+component states to it. The subscription is deleted when the command exits.
+. Otherwise, the service is polled every *--interval* and an event is printed
+for each item that was added, changed, or removed since the previous poll. The
+first poll only records the current state.
+
+If the event stream cannot be read or the SCN subscription cannot be created
+(e.g. in read-only mode), the service is polled instead. Failed polls are logged
+as warnings and retried at the next interval.
+
+This command accepts the following options:
+
+*--callback-url* _url_
+	URL for SMD to send state change notifications to. This must reach the
+	address passed to *--listen* from SMD. By default, this is
+	_http://<listen address>/_.
+
+*--interval* _duration_
+	Interval at which to poll services and to reconnect to event streams that
+	ended, e.g. _30s_. The default is _10s_.
+
+*--listen* _addr_
+	Address to listen on for SMD state change notifications, e.g. _:9000_.
+
+*--service* _service_
+	Service to print events from, _smd_ or _bss_. This can be passed more
+	than once or as a comma-separated list. By default, events from both are
+	printed.
+
+*--sse* _service_=_path_
+	Path of a server-sent event stream for _service_ to read events from
+	instead of polling, relative to the base URI of the service. This can be
+	passed more than once or as a comma-separated list.
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1), *ochami-bss*(1), *ochami-smd*(1)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
@@ -0,0 +1,114 @@
+OCHAMI-EXPORT(1) "OpenCHAMI" "Manual Page for ochami-export"
+
+# NAME
+
+ochami-export - Generate configuration files for infrastructure services
+
+# SYNOPSIS
+
+ochami export all --output-dir _dir_ [--only _artifact_,...] [--template-dir _dir_] [-F _format_]
+
+# DESCRIPTION
+
+The *export* command is a metacommand for generating configuration files for
+infrastructure services from the inventory in SMD so that they stay in sync
+with it. To generate a single file to standard output, see the *export*
+command of *ochami-smd*(1).
+
+# ARTIFACTS
+
+Each generated file is an artifact with a name and a file name within the
+output directory:
+
+- _hosts_ (_hosts_) - An */etc/hosts* entry for each node with an IP address.
+- _dnsmasq_ (_dnsmasq-hosts.conf_) - A *dnsmasq*(8) _dhcp-host_ entry for each
+  MAC and IP address of each node.
+- _ansible_ (_inventory.ini_) - An Ansible inventory in INI format with all
+  nodes in the group _nodes_ and each node in a group named after its SMD role
+  in lower case. Nodes with an IP address have it as _ansible_host_.
+- _prometheus_ (_prometheus-targets.json_) - A Prometheus file-based service
+  discovery file with a node_exporter target (port _9100_) for each node,
+  labeled with its _xname_, _nid_, and _role_.
+- _conman_ (_conman.conf_) - *conman.conf*(5) console entries, as generated by
+  *ochami smd export conman*.
+- _powerman_ (_powerman.conf_) - *powerman.conf*(5) devices and nodes, as
+  generated by *ochami smd export powerman*.
+
+Nodes are read from SMD's /State/Components endpoint, their addresses from its
+/Inventory/EthernetInterfaces endpoint, and their BMCs from its
+/Inventory/RedfishEndpoints endpoint. Output is sorted by xname and contains no
+timestamps so that it only changes when the inventory does.
+
+Templates are executed with the data described for *export* in *ochami-smd*(1),
+as well as:
+
+- *.Nodes*: list of nodes, each with:
+	- *.ID*: xname of the node
+	- *.NID*: NID of the node
+	- *.Role*, *.Arch*: role and architecture of the node in SMD
+	- *.IP*: first IP address of the node, if any
+	- *.BMC*: host name or IP address of the BMC of the node, if any
+	- *.Interfaces*: list of addresses of the node, each with *.MAC*, *.IP*,
+	  and *.Network*
+- *.Roles*: list of the roles of the nodes, sorted by name, each with *.Name*
+  and *.Nodes*
+
+Besides the built-in functions of Go templates, templates can use _json_, which
+encodes its argument as JSON, and _lower_, which converts a string to lower
+case.
+
+# COMMANDS
+
+## all
+
+Generate all artifacts from a single snapshot of SMD and write them to the
+directory _dir_, creating it if needed, along with _manifest.json_. The
+manifest records when the files were generated, the version of *ochami*, the
+name of the cluster, the number of nodes and BMCs, and, for each file, its
+artifact, file name, SHA-256 checksum, size, and whether it changed. The
+manifest is also printed to standard output.
+
+The format of this command is:
+
+*all* --output-dir _dir_ [--only _artifact_,...] [--template-dir _dir_] [-F _format_]
+
+The SMD data is requested concurrently and the artifacts are generated
+concurrently. If any request fails or any artifact fails to generate, no files
+are written. Files whose content did not change are left alone so that their
+modification times only change when the inventory does, and the others are
+written to a temporary file that replaces them, so services never read a
+partially written file. The manifest is written last. This makes this command
+suitable for regenerating the configuration from *cron*(8).
+
+An access token is required.
+
+This command accepts the following options:
+
+*-F, --format-output* _format_
+	Output the manifest in the specified _format_. Supported values are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*--only* _artifact_,...
+	Only generate the listed artifacts (see *ARTIFACTS*).
+
+*-o, --output-dir* _dir_
+	Directory to write the files and manifest to. This is required.
+
+*--template-dir* _dir_
+	Directory of Go text/templates overriding the built-in templates. The
+	template of an artifact is read from _<artifact>.tmpl_ in _dir_, e.g.
+	_hosts.tmpl_. Artifacts without a template in _dir_ use the built-in one.
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1), *ochami-smd*(1)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
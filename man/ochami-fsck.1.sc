@@ -0,0 +1,99 @@
+OCHAMI-FSCK(1) "OpenCHAMI" "Manual Page for ochami-fsck"
+
+# NAME
+
+ochami-fsck - Check the consistency of data across services
+
+# SYNOPSIS
+
+ochami fsck [--skip _service_,...] [--fix [--no-confirm] [--yes-really-delete _n_]] [-F _format_] [--report-format _format_ [--report-file _path_]]
+
+# DESCRIPTION
+
+The *fsck* command checks the data in SMD, BSS, and cloud-init for references
+that do not resolve across services, e.g. ethernet interfaces of components
+that were deleted or boot parameters of nodes that no longer exist. Such
+references are left behind since the services do not check each other's data.
+
+The data of SMD is always checked. The data of BSS and cloud-init is checked
+unless the service is passed to *--skip*. The problems found are printed as a
+list, each with the rule it violates, its level, its target (the record with
+the reference), a message describing it, and the repair *--fix* would make, if
+any. If any problems remain (i.e. were not fixed), the command exits with a
+status of _1_.
+
+# CHECKS
+
+The following checks are run:
+
+- _component-no-interfaces_ (warning): a component of type _Node_ has no
+  ethernet interfaces in SMD.
+- _interface-unknown-component_ (error): an ethernet interface in SMD belongs
+  to a component that does not exist. Ethernet interfaces without a component
+  ID are not checked.
+- _redfish-endpoint-no-component_ (warning): neither a redfish endpoint itself
+  nor any component it manages is a component in SMD.
+- _boot-params-unknown-xname_ (warning): boot parameters in BSS are for an
+  xname that is not a component in SMD. Hosts that are not xnames, e.g.
+  _Default_, are not checked.
+- _boot-params-unknown-mac_ (warning): boot parameters in BSS are for a MAC
+  address that is not an ethernet interface in SMD.
+- _boot-params-unknown-nid_ (warning): boot parameters in BSS are for a NID
+  that no component in SMD has.
+- _cloud-init-group-orphaned_ (warning): a cloud-init group has no SMD group
+  with its name, so no node receives its data.
+- _group-member-unknown_ (error): a member of an SMD group is not a component
+  in SMD. This can be repaired with *--fix*.
+
+# OPTIONS
+
+*--fix*
+	Make the repairs that lose no data, which is removing members that do not
+	exist from SMD groups, and mark the problems repaired as _fixed_. The
+	repairs are listed and the user is asked to confirm them before they are
+	made. Other problems must be repaired by hand.
+
+*-F, --format-output* _format_
+	Output the problems in the specified format. Supported values are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*--no-confirm*
+	With *--fix*, do not ask the user to confirm the repairs.
+
+*--report-file* _path_
+	Write the report requested with *--report-format* to _path_ in addition to
+	the normal output. By default, the report is written to standard output
+	instead of the normal output.
+
+*--report-format* _format_
+	Write the problems that remain as a report for CI systems. Supported values
+	are:
+
+	- _junit_
+	- _sarif_
+
+*--skip* _service_,...
+	Do not read or check the data of the listed services, e.g. if they are not
+	deployed. Supported values are:
+
+	- _bss_
+	- _cloud-init_
+
+*--yes-really-delete* _n_
+	With *--fix*, confirm removing more group members than the
+	*delete-threshold* of the config by passing their exact number.
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1), *ochami-bss*(1), *ochami-cloud-init*(1), *ochami-smd*(1),
+*ochami-config*(5)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
@@ -0,0 +1,121 @@
+OCHAMI-IMAGE(1) "OpenCHAMI" "Manual Page for ochami-image"
+
+# NAME
+
+ochami-image - Work with boot image artifacts
+
+# SYNOPSIS
+
+ochami image verify --manifest _path_ [-f _format_] [-F _format_] [--concurrency _n_] [--target-timeout _duration_] [--report-format _format_ [--report-file _path_]]
+
+# DESCRIPTION
+
+The *image* command is a metacommand for working with the artifacts (kernels,
+initrds, and root filesystems) of boot images.
+
+# MANIFEST FORMAT
+
+An image manifest lists boot images and the URIs of their artifacts. In YAML,
+an example is as follows:
+
+```
+images:
+- name: compute-base
+  params: console=ttyS0,115200 ip=dhcp
+  kernel:
+    uri: https://images.example.com/compute/vmlinuz
+    size: 12345678
+    checksum: sha256:3b9c...
+  initrd:
+    uri: https://images.example.com/compute/initrd.img
+  rootfs:
+    uri: file:///srv/images/compute/rootfs.squashfs
+```
+
+A description of each key in the above is as follows:
+
+- *name* - The name of the image, used to identify its artifacts in output.
+- *params* - (Optional) The kernel command line to boot the image with, used
+by *ochami node reimage* (see *ochami-node*(1)).
+- *kernel*, *initrd*, *rootfs* - The artifacts of the image. Any of them can be
+omitted.
+- *uri* - The URI of the artifact. The _http_, _https_, and _file_ schemes are
+supported.
+- *size* - (Optional) The size of the artifact in bytes.
+- *checksum* - (Optional) The checksum of the artifact in the form
+_algorithm_:_hex digest_. The supported algorithms are _sha256_ and _sha512_.
+
+# COMMANDS
+
+## verify
+
+Check that every artifact referenced by an image manifest is available and that
+its size and checksum match, if they are in the manifest. This catches broken
+image pushes before nodes try to boot from them.
+
+The format of this command is:
+
+*verify* --manifest _path_ [-f _format_] [-F _format_] [--concurrency _n_] [--target-timeout _duration_] [--report-format _format_ [--report-file _path_]]
+
+Artifacts are verified concurrently. An artifact with a checksum is downloaded
+and hashed, without being stored. Otherwise, HTTP(S) artifacts are checked with
+a HEAD request, falling back to a GET request if the server does not support
+HEAD or, when a size is given, does not report the size. The TLS options of
+*ochami*(1), e.g. *--cacert* and *--insecure*, apply to these requests.
+
+The status of each artifact is printed to standard output, including its size
+and, if verified, its checksum. If any artifact could not be verified, the
+command exits with a status of _1_.
+
+This command accepts the following options:
+
+*--concurrency* _n_
+	Verify up to _n_ artifacts at once. The default is _4_.
+
+*-F, --format-output* _format_
+	Output response data in specified _format_. Supported values are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*-f, --format-input* _format_
+	Format of the manifest. Supported values are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*--manifest* _path_
+	Read the image manifest from _path_, or from standard input if _path_ is
+	_-_. This option is required.
+
+*--report-file* _path_
+	Write the report requested with *--report-format* to _path_ in addition to
+	the normal output. If _path_ is _-_ (the default), the report replaces the
+	normal output on standard output.
+
+*--report-format* _format_
+	Write the results as a report for CI systems (see *OUTPUT* in
+	*ochami*(1)). Each artifact is a test case or result, and failures are
+	classified by the rules _artifact-unavailable_, _artifact-size-mismatch_,
+	_artifact-checksum-mismatch_, _artifact-timeout_, and _artifact-error_.
+	Supported values are:
+
+	- _junit_
+	- _sarif_
+
+*--target-timeout* _duration_
+	Mark an artifact as failed if it has not been verified within _duration_,
+	e.g. _10m_. The default is _0_, meaning no limit.
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1), *ochami-bss*(1), *ochami-node*(1)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
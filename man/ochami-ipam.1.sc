@@ -0,0 +1,213 @@
+OCHAMI-IPAM(1) "OpenCHAMI" "Manual Page for ochami-ipam"
+
+# NAME
+
+ochami-ipam - Plan and apply static IP addresses of node interfaces
+
+# SYNOPSIS
+
+ochami ipam plan --network _name_=_cidr_... [--per-rack _n_] [--reserve _n_] (-d (_data_ | @_path_) [-f _format_] [--no-smd] | --group _group_,...) [-o _file_] [-F _format_]
+
+ochami ipam apply --plan _path_ [--smd] [--payload _path_ [-f _format_]]
+
+# DESCRIPTION
+
+The *ipam* command is a metacommand for allocating static IP addresses to the
+interfaces of nodes from the networks of a cluster, instead of planning them in
+a spreadsheet. *plan* allocates the addresses and writes them to a plan file,
+which can be reviewed, kept in version control, and then applied with *apply*
+to the ethernet interfaces in SMD and to discovery payloads (see
+*ochami-discover*(1)).
+
+# PLAN FORMAT
+
+A plan lists, for each interface of each node and each network, the address of
+the interface on the network:
+
+```
+version: 1
+networks:
+  - name: mgmt
+    cidr: 10.1.0.0/20
+per_rack: 64
+reserve: 1
+assignments:
+  - xname: x3000c0s0b0n0
+    mac_addr: de:ad:be:ee:ef:01
+    network: mgmt
+    ip_addr: 10.1.0.2
+  - xname: x3000c0s1b0n0
+    mac_addr: de:ad:be:ee:ef:02
+    network: mgmt
+    ip_addr: 10.1.0.7
+    existing: true
+```
+
+Assignments with _existing_ set are addresses that the interface already had
+when the plan was made, which *apply* leaves as they are. Plans are written in
+JSON or YAML and can be read back in either.
+
+# COMMANDS
+
+## plan
+
+Allocate an address on each network passed with *--network* for each interface
+of a set of nodes that does not have one there yet, and write the plan. An
+interface has an address on a network if it has an address within its CIDR or
+one tagged with its name.
+
+The nodes are those of a discovery payload passed with *-d* (or read from
+standard input), in the same format as for *ochami discover static*, or the
+members of the SMD groups passed with *--group*, with their ethernet
+interfaces in SMD. Only the *interfaces* of payload nodes are planned, not
+their BMCs or *bonds*. Members of the groups without ethernet interfaces are
+skipped with a warning.
+
+Addresses are allocated in order of the nodes and their interfaces, starting at
+the beginning of each network and skipping:
+
+- addresses assigned to any ethernet interface in SMD or interface of the
+  payload
+- the network address and, for IPv4 networks, the broadcast address
+- the *--reserve* addresses after the network address, e.g. for gateways
+
+Interfaces of the payload that are in SMD keep the addresses they have there.
+Pass *--no-smd* to plan a payload without reading SMD, e.g. before the cluster
+is deployed.
+
+With *--per-rack*, the nodes of each rack get addresses from a block of that
+many addresses of their own, so that the address of a node tells its rack. The
+rack of a node is the cabinet of its xname (e.g. _x3000_ for _x3000c0s0b0n0_)
+and the racks are numbered in order of their cabinet numbers: the nodes of the
+first rack get addresses from the first block of each network, those of the
+second from the second block, and so on. The reserved addresses count towards
+the first block. Nodes without a cabinet in their xname cannot be planned per
+rack.
+
+The command fails, without writing a plan, if a network has too few addresses
+for all nodes, or for all racks with *--per-rack*, or if a rack runs out of
+addresses in its block.
+
+The format of this command is:
+
+*plan* --network _name_=_cidr_... [--per-rack _n_] [--reserve _n_] (-d (_data_ | @_path_) [-f _format_] [--no-smd] | --group _group_,...) [-o _file_] [-F _format_]
+
+This command accepts the following options:
+
+	*-d, --data* (_data_ | @_path_ | @-)
+		Specify raw _data_ of the discovery payload, the _path_ to a file to
+		read it from, or to read it from standard input (@-). If neither
+		*--data* nor *--group* is passed, the payload is read from standard
+		input.
+
+	*-f, --format-input* _format_
+		Format of the payload. Supported values are _json_ (default),
+		_json-pretty_, _yaml_, and _csv_ (see *ochami-discover*(1)).
+
+	*-F, --format-output* _format_
+		Format of the plan. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--group* _group_,...
+		Plan addresses for the members of one or more SMD groups instead of the
+		nodes of a payload.
+
+	*--network* _name_=_cidr_
+		Allocate addresses on the network _name_ with the CIDR _cidr_ (e.g.
+		_mgmt=10.1.0.0/20_). Can be passed more than once, and at least once.
+
+	*--no-smd*
+		Do not read the ethernet interfaces in SMD. Their addresses are not
+		avoided then, so use this only if SMD holds none on the networks. It
+		cannot be used with *--group*.
+
+	*-o, --output* _file_
+		Write the plan to _file_ instead of standard output (_-_, the default).
+
+	*--per-rack* _n_
+		Allocate the addresses of the nodes of each rack from a block of _n_
+		addresses of each network. The default, _0_, allocates addresses
+		across racks.
+
+	*--reserve* _n_
+		Leave the _n_ addresses after the network address of each network
+		unallocated. The default is _1_, e.g. for the gateway.
+
+	*--validate-schema*
+		Check the payload against the schema of the discovery payload format
+		before reading it (see *Schema* in *ochami-discover*(1)).
+
+## apply
+
+Add the new addresses of a plan written by *plan* to the interfaces they were
+allocated for. An address an interface already has on the network of a new one
+is replaced, while its addresses on other networks are kept.
+
+With *--smd*, the ethernet interfaces in SMD are updated. With *--payload*, the
+interfaces of the discovery payload file are updated in place, so that *ochami
+discover static* sends them with the addresses; the file is written in the
+current version of the format (see *migrate* in *ochami-discover*(1)). At least
+one of them is required. Interfaces of the plan that are not in SMD or in the
+payload are skipped with a warning.
+
+Before anything is changed, the plan is checked against SMD and the payload
+again. If an address of the plan is now used by an interface other than the one
+it was allocated for, e.g. because it was assigned in the meantime, nothing is
+changed. Make a new plan instead.
+
+The format of this command is:
+
+*apply* --plan _path_ [--smd] [--payload _path_ [-f _format_]]
+
+This command accepts the following options:
+
+	*-f, --format-input* _format_
+		Format of the payload file, which it is also written in. Supported
+		values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--payload* _path_
+		Add the addresses to the interfaces of the discovery payload file
+		_path_.
+
+	*--plan* _path_
+		Path of the plan to apply. Required.
+
+	*--smd*
+		Add the addresses to the ethernet interfaces in SMD.
+
+# EXAMPLES
+
+Plan the management addresses of a new payload, 64 per rack, then add them to
+the payload and send it:
+
+```
+ochami ipam plan --network mgmt=10.1.0.0/20 --per-rack 64 \
+	-d @nodes.yaml -f yaml -o plan.yaml -F yaml
+ochami ipam apply --plan plan.yaml --payload nodes.yaml -f yaml
+ochami discover static -d @nodes.yaml -f yaml
+```
+
+Assign addresses on a new network to the nodes of a group in SMD:
+
+```
+ochami ipam plan --network hsn=10.2.0.0/20 --group compute -o plan.json
+ochami ipam apply --plan plan.json --smd
+```
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1), *ochami-discover*(1), *ochami-smd*(1)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
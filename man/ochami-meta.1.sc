@@ -0,0 +1,91 @@
+OCHAMI-META(1) "OpenCHAMI" "Manual Page for ochami-meta"
+
+# NAME
+
+ochami-meta - Manage free-form key/value metadata of components
+
+# SYNOPSIS
+
+ochami meta get (_xname_... | --selector meta._key_=_value_...) [-F _format_]
+
+ochami meta set _xname_ _key_=_value_...
+
+ochami meta unset _xname_ _key_...
+
+# DESCRIPTION
+
+The *meta* command is a metacommand for managing free-form key/value metadata
+of components, such as rack numbers, purchase dates, or asset tags.
+
+Components can be targeted by their metadata with *--selector*
+meta._key_=_value_ on commands that support it, such as *ochami bss boot params
+set* and *ochami pcs transition start*. Such selectors can be passed more than
+once to select the components matching all of them. A command fails if its
+selectors match no components.
+
+# STORAGE
+
+Since SMD has no place for free-form component metadata, each key/value pair is
+stored as an SMD group whose members are the components that have it. Such a
+group has the label _meta-<key>-<value>_ (with the value in lower case), the tag
+_ochami-meta_, the description _<key>=<value>_, and the exclusive group
+_meta-<key>_, so that SMD ensures a component has at most one value per key.
+These groups are listed by *ochami smd group get* along with all other groups
+and are part of backups created by *ochami backup create*.
+
+Keys consist of lower case letters, digits, and underscores. Values consist of
+letters, digits, underscores, periods, and dashes and are compared case
+insensitively.
+
+# COMMANDS
+
+## get
+
+Get the metadata of one or more components, printed as a map of xnames to their
+key/value pairs.
+
+The format of this command is:
+
+*get* (_xname_... | --selector meta._key_=_value_...) [-F _format_]
+
+This command accepts the following options:
+
+*-F, --format-output* _format_
+	Output response data in specified _format_. Supported values are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*--selector* meta._key_=_value_
+	Get the metadata of the components whose metadata has _key_ set to
+	_value_, in addition to any xnames passed. Can be passed more than once.
+
+## set
+
+Set one or more key/value pairs of metadata of a component. If the component
+already has a value for a key, it is replaced.
+
+The format of this command is:
+
+*set* _xname_ _key_=_value_...
+
+## unset
+
+Remove one or more keys of metadata of a component. Metadata groups that are
+left without members are deleted.
+
+The format of this command is:
+
+*unset* _xname_ _key_...
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1), *ochami-bss*(1), *ochami-pcs*(1), *ochami-smd*(1)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
@@ -0,0 +1,164 @@
+OCHAMI-NODE(1) "OpenCHAMI" "Manual Page for ochami-node"
+
+# NAME
+
+ochami-node - Manage nodes across services
+
+# SYNOPSIS
+
+ochami node reimage --image _name_ --manifest _path_ [-f _format_] (--xname _xname_,... | --group _group_,... | --target _target_,... | --selector _selector_...) [--reboot [--wave-size _n_] [--wave-delay _duration_] [--continue-on-failure] [--allow-major-impact] [--track [--track-by _method_] [--timeout _duration_] [--poll-interval _duration_]]] [--show-location] [-F _format_]
+
+# DESCRIPTION
+
+The *node* command is a metacommand for common administrative tasks on nodes
+that combine requests to several services, e.g. BSS, PCS, and SMD.
+
+# COMMANDS
+
+## reimage
+
+Set nodes to boot an image from an image manifest, optionally power-cycling
+them and tracking their boot.
+
+The format of this command is:
+
+*reimage* --image _name_ --manifest _path_ [-f _format_] (--xname _xname_,... | --group _group_,... | --target _target_,... | --selector _selector_...) [--reboot [--wave-size _n_] [--wave-delay _duration_] [--continue-on-failure] [--allow-major-impact] [--track [--track-by _method_] [--timeout _duration_] [--poll-interval _duration_]]] [--show-location] [-F _format_]
+
+The boot parameters of the nodes in BSS are set to boot the image _name_ of the
+image manifest (see *ochami-image*(1)) at _path_. The kernel and initrd of the
+image replace those of each node and the kernel command line is the *params* of
+the image, or the current one of the node if the image has none. If the image
+has a root filesystem, *root=* is set to a live image of it, i.e.
+_live:<uri>_. The rest of the boot parameters of each node, e.g. its cloud-init
+data, is kept. Nodes whose boot parameters end up the same are set with a
+single request.
+
+If *--reboot* is passed, the nodes whose boot parameters were set are then
+power-cycled with PCS _init_ transitions, which power nodes that are on off and
+then on and nodes that are off on, in waves of *--wave-size* nodes. If *--track*
+is also passed, each wave is tracked until all of its nodes have booted or
+*--timeout* elapses before the next wave is started. If a node of a wave fails
+to be power-cycled or to boot in time, the remaining waves are skipped unless
+*--continue-on-failure* is passed. If *max-impact-percent* is set (see
+*ochami-config*(5)), power-cycling more than that percentage of the nodes in SMD
+is refused before any boot parameters are set unless *--allow-major-impact* is
+passed.
+
+A roster of the outcome for each node is printed at the end, including the wave
+it was in and, if its boot was tracked, how long it took to boot. The status of
+each node is one of:
+
+- _params-set_ - Its boot parameters were set, and it was not power-cycled.
+- _rebooted_ - It was power-cycled, and its boot was not tracked.
+- _booted_ - It was power-cycled and booted.
+- _timed-out_ - It was power-cycled, but did not boot within *--timeout*.
+- _failed_ - Setting its boot parameters or power-cycling it failed.
+- _skipped_ - It was not power-cycled since an earlier wave failed.
+
+If any node failed, timed out, or was skipped, the command exits with a status
+of _1_.
+
+This command accepts the following options:
+
+*--allow-major-impact*
+	With *--reboot*, allow power-cycling more than *max-impact-percent* of the
+	nodes in SMD.
+
+*--continue-on-failure*
+	With *--reboot*, start the remaining waves even if a node of a wave failed
+	to be power-cycled or to boot.
+
+*-F, --format-output* _format_
+	Output the roster in the specified format. Supported values are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*-f, --format-input* _format_
+	Format of the manifest. Supported values are:
+
+	- _json_ (default)
+	- _yaml_
+
+*-g, --group* _group_,...
+	One or more SMD groups whose members to reimage.
+
+*--image* _name_
+	Name of the image in the manifest to boot. This flag is required.
+
+*--manifest* _path_
+	Path to the image manifest, or _-_ to read it from standard input. This
+	flag is required.
+
+*--only* _status_,...
+	With *--targets-from*, reimage only the components of the report with
+	one of these statuses, or that _failed_ or _succeeded_. See *TARGETS* in
+	*ochami*(1).
+
+*--poll-interval* _duration_
+	With *--track*, interval at which to poll whether nodes booted. The default
+	is _10s_.
+
+*--reboot*
+	Power-cycle the nodes after setting their boot parameters.
+
+*--selector* _selector_
+	Select the nodes to reimage by their metadata (see *ochami-meta*(1)). This
+	flag can be passed more than once.
+
+*--show-location*
+	Include the physical location of each node in the roster (see *locations*
+	in *ochami-config*(5)).
+
+*--target* _target_,...
+	One or more xnames, NIDs, MAC addresses, or SMD groups to reimage, resolved
+	to xnames through SMD. See *TARGETS* in *ochami*(1).
+
+*--targets-from* _path_
+	Reimage the components of the report at _path_ printed by an earlier
+	bulk run, e.g. to retry those that failed with *--only failed*.
+	See *TARGETS* in *ochami*(1).
+
+*--timeout* _duration_
+	With *--track*, how long to wait for the nodes of a wave to boot. The
+	default is _20m_.
+
+*--track*
+	With *--reboot*, wait for the nodes of each wave to boot before starting
+	the next wave.
+
+*--track-by* _method_
+	How to tell that a node booted. Supported values are:
+
+	- _smd_ (default) - The SMD state of the node is _Ready_ after having been
+	  in another state since it was power-cycled. *--poll-interval* must be
+	  shorter than the time nodes take to reboot for the other state to be
+	  seen.
+	- _boot-script_ - The node fetched its boot script from BSS after it was
+	  power-cycled, according to the endpoint history of BSS.
+
+	The cloud-init server only logs the phone-home requests of nodes, so they
+	cannot be used to track boots.
+
+*--wave-delay* _duration_
+	With *--reboot*, how long to wait between waves. The default is _0_.
+
+*--wave-size* _n_
+	With *--reboot*, power-cycle up to _n_ nodes at once. The default is _0_,
+	which power-cycles all nodes at once.
+
+*-x, --xname* _xname_,...
+	One or more xnames of nodes to reimage.
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1), *ochami-bss*(1), *ochami-image*(1), *ochami-meta*(1),
+*ochami-pcs*(1), *ochami-smd*(1)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
@@ -36,11 +36,200 @@ The data structure for sending and receiving data with subcommands under the
 	base URI (set with the *--cluster-uri* flag or the *cluster.uri* cluster
 	config option), which is required to be set if a relative path is used here.
 
+	This only applies to PCS. Commands that also communicate with other services,
+	e.g. SMD to look up group members, use the URIs of those services from
+	*--cluster-uri* or the config file, not this one. Earlier versions applied
+	*--uri* to every service such a command communicated with.
+
 	See *ochami*(1) for *--cluster-uri* and *ochami-config*(5) for details on
 	cluster configuration options.
 
 # COMMANDS
 
+## power
+
+Query and control component power state.
+
+Subcommands for this command are as follows:
+
+*off* [-F _format_] [-x _xname_,...]... [-g _group_,...]... [--target _target_,...]... [--selector meta._key_=_value_]... [--graceful-then-force [--grace-period _duration_] [--force-timeout _duration_] [--poll-interval _duration_]] [--allow-major-impact] [--show-location]
+	Power off components. At least one of *--xname*, *--group*, *--target*,
+	*--targets-from*, or *--selector* is required. By default, this command starts an _off_
+	transition and prints its ID and operation, as *transition start* does.
+
+	If *max-impact-percent* is set (see *ochami-config*(5)), powering off
+	more than that percentage of the nodes in SMD at once is refused unless
+	*--allow-major-impact* is passed.
+
+	If *--graceful-then-force* is passed, the standard shutdown runbook is
+	followed instead:
+
+	- A _soft-off_ (graceful shutdown) transition is started for all
+	  components.
+	- Their power state is polled every *--poll-interval* until all of them
+	  are off or *--grace-period* elapses.
+	- A _force-off_ transition is started for the components that are not
+	  off.
+	- Their power state is polled until all of them are off or
+	  *--force-timeout* elapses.
+
+	The outcome for each component is then printed: _graceful_ if it shut
+	down gracefully, _forced_ if it was forced off, or _failed_ if it is still
+	not off or its power state could not be determined, along with the number
+	of components with each outcome. For example, in JSON format:
+
+	```
+	{
+	  "counts": { "failed": 1, "forced": 1, "graceful": 1 },
+	  "results": [
+	    { "xname": "x1000c0s0b0n0", "outcome": "graceful", "powerState": "off" },
+	    { "xname": "x1000c0s1b0n0", "outcome": "forced", "powerState": "off" },
+	    { "xname": "x1000c0s2b0n0", "outcome": "failed", "powerState": "on" }
+	  ]
+	}
+	```
+
+	This command exits with a nonzero status if any component failed to
+	power off.
+
+	This command sends a POST to PCS's /transitions endpoint for each
+	transition and, with *--graceful-then-force*, a GET to PCS's
+	/power-status endpoint for each poll.
+
+	This command accepts the following options:
+
+	*--allow-major-impact*
+		Allow powering off more than *max-impact-percent* of the nodes in SMD
+		at once.
+
+	*--force-timeout* _duration_
+		How long to wait for components to be forced off, e.g. _30s_.
+
+		Default: *2m*
+
+	*-F, --format-output* _format_
+		Output response data in specified _format_. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*-g, --group* _group_,...
+		One or more SMD groups whose members to power off.
+
+	*--grace-period* _duration_
+		How long to wait for components to shut down gracefully before
+		forcing them off, e.g. _10m_.
+
+		Default: *5m*
+
+	*--graceful-then-force*
+		Shut down components gracefully, then force off those that are
+		still not off after *--grace-period*.
+
+	*--only* _status_,...
+		With *--targets-from*, power off only the components of the report with
+		one of these statuses, or that _failed_ or _succeeded_. See *TARGETS* in
+		*ochami*(1).
+
+	*--poll-interval* _duration_
+		Interval at which to poll the power state of components.
+
+		Default: *10s*
+
+	*--selector* meta._key_=_value_
+		Power off the components whose metadata has _key_ set to _value_
+		(see *ochami-meta*(1)). This flag can be passed more than once to
+		select the components matching all selectors.
+
+	*--show-location*
+		Include the physical location of each component in the outcome
+		(see *locations* in *ochami-config*(5)).
+
+	*--target* _target_,...
+		One or more xnames, NIDs, MAC addresses, or SMD groups to power
+		off, resolved to xnames through SMD. See *TARGETS* in *ochami*(1).
+
+	*--targets-from* _path_
+		Power off the components of the report at _path_ printed by an
+		earlier bulk run, e.g. to retry those that failed with *--only failed*.
+		See *TARGETS* in *ochami*(1).
+
+	*-x, --xname* _xname_,...
+		One or more xnames to power off.
+
+*status* [-F _format_] [-x _xname_,...]... [-g _group_,...]... [--target _target_,...]... [--summary] [--expect _state_] [--show-location]
+	Get the power status of components. If none of *--xname*, *--group*,
+	*--target*, or *--targets-from* is passed, the power status of all
+	components known to PCS is fetched.
+
+	This command sends a GET to PCS's /power-status endpoint. If *--group* is
+	passed, a GET is also sent to the members subendpoint under SMD's /groups
+	endpoint for each group.
+
+	If *--expect* is passed, this command exits with a nonzero status if any
+	component is not in _state_, has an error reported by PCS, or was
+	requested but not reported by PCS. This is useful in maintenance runbooks.
+
+	This command accepts the following options:
+
+	*--expect* _state_
+		The expected power state of all components. Supported values are:
+
+		- _on_
+		- _off_
+
+	*-F, --format-output* _format_
+		Output response data in specified _format_. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*-g, --group* _group_,...
+		One or more SMD groups whose members to get the power status of. This
+		flag can be combined with *--xname*.
+
+	*--only* _status_,...
+		With *--targets-from*, get the power status of only the components of the
+		report with one of these statuses, or that _failed_ or _succeeded_. See
+		*TARGETS* in *ochami*(1).
+
+	*--show-location*
+		Include the physical location of each component and exception
+		(see *locations* in *ochami-config*(5)).
+
+	*--summary*
+		Instead of the per-component power status, print a summary containing
+		the total number of components, the number of components in each power
+		state, and a list of exceptions. Exceptions are components with an
+		error or, if *--expect* is passed, components whose power state differs
+		from the expected state. Requested components that PCS did not report
+		are listed under *missing*. For example, in JSON format:
+
+		```
+		{
+		  "total": 4,
+		  "counts": { "on": 3, "off": 1 },
+		  "expected": "on",
+		  "exceptions": [
+		    { "xname": "x1000c0s2b0n0", "powerState": "off" }
+		  ]
+		}
+		```
+
+	*--target* _target_,...
+		One or more xnames, NIDs, MAC addresses, or SMD groups to get the power
+		status of, resolved to xnames through SMD. See *TARGETS* in *ochami*(1).
+
+	*--targets-from* _path_
+		Get the power status of the components of the report at _path_ printed
+		by an earlier bulk run, e.g. those that failed with *--only failed*. See
+		*TARGETS* in *ochami*(1).
+
+	*-x, --xname* _xname_,...
+		One or more xnames to get the power status of.
+
 ## service
 
 Manage and check PCS itself.
@@ -81,11 +270,24 @@ Manages PCS transitions.
 
 Subcommands for this command are as follows:
 
-*start*  [-F _format_] [-x _xname1,xname2,..._]... _operation_
-	Starts a power transition on one or more nodes.
+*start*  [-F _format_] [-x _xname1,xname2,..._]... [--target _target_,...]... [--selector meta._key_=_value_]... [--allow-major-impact] _operation_
+	Starts a power transition on one or more nodes. At least one of *--xname*,
+	*--target*, *--targets-from*, or *--selector* is required.
+
+	If *cluster.grafana.uri* is set in the config file, an annotation for the
+	transition is also pushed to Grafana. Failing to push it does not cause
+	the command to fail. See *ochami-config*(5).
+
+	If *max-impact-percent* is set (see *ochami-config*(5)), any operation
+	other than _on_ on more than that percentage of the nodes in SMD at once
+	is refused unless *--allow-major-impact* is passed.
 
 	This command accepts the following options:
 
+	*--allow-major-impact*
+		Allow an operation other than _on_ on more than *max-impact-percent*
+		of the nodes in SMD at once.
+
 	*-F, --format-output* _format_
 		Output response data in specified _format_. Supported values are:
 
@@ -93,6 +295,25 @@ Subcommands for this command are as follows:
 		- _json-pretty_
 		- _yaml_
 
+	*--only* _status_,...
+		With *--targets-from*, transition only the components of the report with
+		one of these statuses, or that _failed_ or _succeeded_. See *TARGETS* in
+		*ochami*(1).
+
+	*--selector* meta._key_=_value_
+		Transition the components whose metadata has _key_ set to _value_ (see
+		*ochami-meta*(1)). This flag can be passed more than once to select the
+		components matching all selectors.
+
+	*--target* _target_,...
+		One or more xnames, NIDs, MAC addresses, or SMD groups to transition,
+		resolved to xnames through SMD. See *TARGETS* in *ochami*(1).
+
+	*--targets-from* _path_
+		Transition the components of the report at _path_ printed by an
+		earlier bulk run, e.g. to retry those that failed with *--only failed*.
+		See *TARGETS* in *ochami*(1).
+
 	*-x, --xname* _xname_,...
 		Comma-separated list of xnames to transition.
 
@@ -119,11 +340,19 @@ Subcommands for this command are as follows:
 			- _json-pretty_
 			- _yaml_
 
-*show* [-F _format_] _id_
+*show* [--annotate] [-F _format_] _id_
 	Show the details of a power transition.
 
+	If *--annotate* is passed and *cluster.grafana.uri* is set in the config
+	file, an annotation of the transition, from when it was created until now,
+	is also pushed to Grafana, e.g. to report a transition that was not
+	monitored. Failing to push it does not cause the command to fail.
+
 	This command accepts the following options:
 
+	*--annotate*
+		Push an annotation of the transition to Grafana.
+
 	*-F, --format-output* _format_
 		Output response data in specified _format_. Supported values are:
 
@@ -138,6 +367,11 @@ Subcommands for this command are as follows:
 *monitor* _id_
 	Monitor active power transitions and provide progress information
 
+	If *cluster.grafana.uri* is set in the config file, an annotation spanning
+	the transition, from when it was created until it completed or was aborted,
+	is pushed to Grafana once it is over. Failing to push it does not cause the
+	command to fail.
+
 	This command accepts the following options:
 
 	*-F, --format-output* _format_
@@ -0,0 +1,100 @@
+OCHAMI-REDACT(1) "OpenCHAMI" "Manual Page for ochami-redact"
+
+# NAME
+
+ochami-redact - Pseudonymize infrastructure details in a payload for sharing
+
+# SYNOPSIS
+
+ochami redact [-d (_data_ | @_path_)] [-f _format_] [-F _format_] [--mapping-file _path_]
+
+# DESCRIPTION
+
+The *redact* command replaces the infrastructure details in a payload with
+pseudonyms and prints the result, so that payloads (e.g. discovery payloads or
+the output of other commands) can be attached to upstream bug reports without
+leaking details of the site. The payload is read from *-d*, or from standard
+input if it is not passed.
+
+The structure of the payload is kept, and each value is replaced by the same
+pseudonym everywhere it appears, including inside longer strings such as kernel
+parameters, so that references between records still match. Object keys are
+redacted like values. The following are replaced:
+
+- Hostnames and domains, learned from the values of keys such as _hostname_,
+  _fqdn_, and _bmc_fqdn_, and from the _name_ of objects that have an _xname_,
+  _nid_, or _ID_ (i.e. nodes and BMCs). They are replaced wherever they appear
+  as words, with _host-N_ and _domainN.example_. Xnames are kept, since they
+  are locations rather than names.
+- MAC addresses, with locally administered addresses starting with _02:00_.
+  Their separators and case are kept, and MAC addresses that have been seen with
+  separators are also replaced where they appear without them (e.g. in the IDs
+  of SMD EthernetInterfaces).
+- IPv4 addresses, whose /24 network is replaced with one in _10.0.0.0/8_ while
+  their host part is kept, so that addresses stay in the same networks.
+  Loopback, multicast, and unspecified addresses and netmasks are kept.
+- IPv6 addresses (and prefixes), with addresses in _fd00::/64_.
+- UUIDs, with _00000000-0000-4000-8000-N_.
+- Credentials, the values of keys such as _password_, _passwd_, _secret_, and
+  _token_, with _REDACTED_.
+
+Only data in the payload is redacted, so check the result before sharing it.
+
+*bss dumpstate --redact* redacts the state of BSS the same way (see
+*ochami-bss*(1)).
+
+# OPTIONS
+
+*-d, --data* (_data_ | @_path_ | @-)
+	Specify raw _data_ to redact, the _path_ to a file to read payload data
+	from, or to read the data from standard input (@-). The format of data read
+	in any of these forms is JSON by default unless *-f* is specified to change
+	it.
+
+*-f, --format-input* _format_
+	Format of the input data. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*-F, --format-output* _format_
+	Format of the output. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+*--mapping-file* _path_
+	Start from the pseudonyms saved in _path_, if it exists, and save them along
+	with the new ones to it as JSON. Payloads redacted with the same mapping
+	file use the same pseudonyms, so that they agree with each other, and
+	redacted values in replies to a bug report can be looked up in it. Since it
+	maps pseudonyms back to the real values, the file is created readable only
+	by the user and must not be shared.
+
+# EXAMPLES
+
+Redact a discovery payload:
+
+```
+ochami redact -d @nodes.yaml -f yaml -F yaml
+```
+
+Redact the state of BSS and a payload with the same pseudonyms:
+
+```
+ochami bss dumpstate --redact --mapping-file map.json > bss.json
+ochami redact -d @nodes.json --mapping-file map.json > nodes.json
+```
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1), *ochami-bss*(1)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
@@ -0,0 +1,128 @@
+OCHAMI-RUN(1) "OpenCHAMI" "Manual Page for ochami-run"
+
+# NAME
+
+ochami-run - Run ochami commands described by a JSON or YAML document
+
+# SYNOPSIS
+
+ochami run -f (_path_ | -) [--continue-on-error] [-F _format_]
+
+# DESCRIPTION
+
+The *run* command runs the ochami commands described by a JSON or YAML document
+and prints a document with the outcome of each, so that other programs can call
+ochami without building command lines and parsing their output, and so that
+batches of operations can be kept in files.
+
+A document describes either a single operation, with the keys of an operation
+at its top level, or a list of them under _operations_, which are run in order.
+The keys of a document are:
+
+*operations*
+	List of operations to run, in order.
+
+*flags*
+	Flags passed to every operation, in the same form as those of an operation.
+	An operation can override them. For a document with a single operation,
+	these are its flags.
+
+*continue_on_error*
+	If _true_, run the remaining operations after one fails. By default, they
+	are skipped.
+
+The keys of an operation are:
+
+*command*
+	The ochami command to run, e.g. _smd group get_. This cannot be *run*.
+
+*flags*
+	Map of flags to pass, by their long or short name without dashes. Each
+	value is passed as *--*_name_*=*_value_ (*-*_name_*=*_value_ for a short
+	name), _true_ as *--*_name_, and each element of a list as a separate flag.
+
+*args*
+	List of positional arguments to pass.
+
+*payload*
+	Data to pass to the command, as JSON on standard input with *--data=@-*.
+
+*stdin*
+	String to pass on standard input as is. At most one of *payload* and
+	*stdin* can be set.
+
+*id*
+	Label copied into the result of the operation, to tell results apart.
+
+Each operation runs as a separate ochami process, with JSON logs, no pager, and
+the global options passed to this command (e.g. *--cluster* or *--token*), so
+that all operations run against the same cluster.
+
+The output has a list of _results_, one per operation, with the number that
+_succeeded_, _failed_, and were _skipped_. Each result has:
+
+- _id_ and _command_, from the operation.
+- _args_, the arguments the command was run with.
+- _status_, one of _ok_, _failed_, or _skipped_.
+- _exit_code_, the exit status of the command.
+- _output_, the standard output of the command if it is JSON, or _stdout_ with
+  the output as a string otherwise.
+- _logs_, the lines the command logged.
+- _error_, why the command could not be run, if it could not.
+- _elapsed_seconds_, how long the command ran.
+
+*run* exits with a nonzero status if any operation failed.
+
+# OPTIONS
+
+*-f, --file* (_path_ | -)
+	Read the document from _path_, or from standard input if _-_.
+
+*--continue-on-error*
+	Run the remaining operations after one fails, as if _continue_on_error_
+	were set in the document.
+
+*-F, --format-output* _format_
+	Format of the output. Supported formats are:
+
+	- _json_ (default)
+	- _json-pretty_
+	- _yaml_
+
+# EXAMPLES
+
+Add a group and a member to it, then print the group:
+
+```
+cat <<EOF > request.yaml
+operations:
+- id: add
+  command: smd group add
+  payload:
+    label: compute
+- command: smd group member add
+  args: [compute, x1000c0s0b0n0]
+- command: smd group get
+  flags:
+    name: [compute]
+    F: json
+EOF
+ochami run -f request.yaml -F yaml
+```
+
+Run a single operation read from standard input:
+
+```
+echo '{"command": "smd component get", "flags": {"xname": "x1000c0s0b0n0"}}' | ochami run -f -
+```
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
@@ -225,6 +225,11 @@ structure contains a single *RedfishEndpoints* object containing an array.
 	base URI (set with the *--cluster-uri* flag or the *cluster.uri* cluster
 	config option), which is required to be set if a relative path is used here.
 
+	This only applies to SMD. Commands that also communicate with other services,
+	e.g. BSS and cloud-init when renaming a component, use the URIs of those
+	services from *--cluster-uri* or the config file, not this one. Earlier
+	versions applied *--uri* to every service such a command communicated with.
+
 	See *ochami*(1) for *--cluster-uri* and *ochami-config*(5) for details on
 	cluster configuration options.
 
@@ -236,11 +241,11 @@ Manage component endpoints.
 
 Subcommands for this command are as follows:
 
-*delete* [--no-confirm] --all++
-*delete* [--no-confirm] _xname_...++
-*delete* [--no-confirm] -d _data_ [-f _format_]++
-*delete* [--no-confirm] -d @_file_ [-f _format_]++
-*delete* [--no-confirm] -d @- [-f _format_]
+*delete* [--no-confirm] [--yes-really-delete _n_] [--allow-major-impact] --all++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] [--allow-major-impact] _xname_...++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] [--allow-major-impact] -d _data_ [-f _format_]++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] [--allow-major-impact] -d @_file_ [-f _format_]++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] [--allow-major-impact] -d @- [-f _format_]
 	Delete one or more component endpoints. Unless *--no-confirm* is passed, the
 	user is asked to confirm deletion.
 
@@ -284,6 +289,21 @@ Subcommands for this command are as follows:
 		- _json_ (default)
 		- _yaml_
 
+	*--yes-really-delete* _n_
+		Confirm deleting more than *delete-threshold* (see *ochami-config*(5))
+		items. _n_ must be the exact number of items to be deleted. Without it,
+		such deletions require the user to type the number of items, and are
+		refused if *--no-confirm* is passed.
+
+	*--allow-major-impact*
+		Allow deleting more than *max-impact-percent* (see *ochami-config*(5))
+		of the component endpoints in SMD at once. Without it, such deletions are refused.
+
+	*--if-exists*
+		Succeed with an informational note instead of failing for items that do
+		not exist (HTTP 404), so that the command can be rerun safely, e.g. by
+		automation.
+
 *get* [-F _format_] [_xname_]...
 	Get all or a subset of component endpoints.
 
@@ -306,7 +326,8 @@ Manage components.
 
 Subcommands for this command are as follows:
 
-*add* [--arch _arch_] [--enabled] [--role _role_] [--state _state_] _xname_ _node_id_++
+*add* [--arch _arch_] [--enabled] [--role _role_] [--state _state_] [--type _type_] _xname_ _node_id_++
+*add* --pattern _xname_pattern_ [--nid-start _node_id_] [--arch _arch_] [--enabled] [--role _role_] [--state _state_] [--type _type_]++
 *add* -d _data_ [-f _format_]++
 *add* -d @_file_ [-f _format_]++
 *add* -d @- [-f _format_]
@@ -315,16 +336,23 @@ Subcommands for this command are as follows:
 
 	In the first form of the command, an _xname_ and _node_id_ is required to
 	identify the component to add. One or more of *--arch*, *--enabled*,
-	*--role*, or *--state* can optionally be specified to specify details of the
-	component.
+	*--role*, *--state*, or *--type* can optionally be specified to specify
+	details of the component.
 
-	In the second form of the command, raw data is passed as an argument to be
+	In the second form of the command, a component is added for each xname
+	described by _xname_pattern_, which contains ranges in brackets:
+	comma-separated lists of numbers and inclusive number ranges (e.g.
+	x3001c0s[0-15]b0n0, or x3001c0s[0-3,8]b[0-1]n0 for every combination). This
+	is convenient for stubbing out hardware that is not installed yet without
+	writing a payload. The options of the first form apply to every component.
+
+	In the third form of the command, raw data is passed as an argument to be
 	the payload.
 
-	In the third form of the command, a file containing the payload data is
+	In the fourth form of the command, a file containing the payload data is
 	passed. This is convenient in cases of dealing with many components at once.
 
-	In the fourth form of the command, the payload data is read from standard
+	In the fifth form of the command, the payload data is read from standard
 	input.
 
 	This command sends a POST request to SMD's /Components endpoint.
@@ -354,6 +382,13 @@ Subcommands for this command are as follows:
 		- _json_ (default)
 		- _yaml_
 
+	*--nid-start* _node_id_
+		Only with *--pattern*, number the components it describes in order,
+		starting with _node_id_. Without it, the components have no node ID.
+
+	*--pattern* _xname_pattern_
+		Add a component for each xname described by _xname_pattern_.
+
 	*--role* _role_
 		Specify the SMD role for the new component.
 
@@ -364,11 +399,15 @@ Subcommands for this command are as follows:
 
 		Default: *Ready*
 
+	*--type* _type_
+		Specify the SMD type of the new component (e.g. _Node_). By default,
+		SMD uses the type of its xname.
+
 *delete* --all++
-*delete* _xname_...++
-*delete* -d _data_ [-f _format_]++
-*delete* -d @_file_ [-f _format_]++
-*delete* -d @- [-f _format_]
+*delete* [--if-exists] _xname_...++
+*delete* [--if-exists] -d _data_ [-f _format_]++
+*delete* [--if-exists] -d @_file_ [-f _format_]++
+*delete* [--if-exists] -d @- [-f _format_]
 	Delete one or more components in SMD. Unless *--no-confirm* is passed, the
 	user is asked to confirm deletion.
 
@@ -410,6 +449,21 @@ Subcommands for this command are as follows:
 		- _json_ (default)
 		- _yaml_
 
+	*--yes-really-delete* _n_
+		Confirm deleting more than *delete-threshold* (see *ochami-config*(5))
+		items. _n_ must be the exact number of items to be deleted. Without it,
+		such deletions require the user to type the number of items, and are
+		refused if *--no-confirm* is passed.
+
+	*--allow-major-impact*
+		Allow deleting more than *max-impact-percent* (see *ochami-config*(5))
+		of the components in SMD at once. Without it, such deletions are refused.
+
+	*--if-exists*
+		Succeed with an informational note instead of failing for items that do
+		not exist (HTTP 404), so that the command can be rerun safely, e.g. by
+		automation.
+
 *get* [-F _format_] [--nid _nid_] [--xname _xname_]
 	Get all components or one identified by xname or node ID.
 
@@ -436,16 +490,349 @@ Subcommands for this command are as follows:
 		this flag can be specified multiple times or this flag can be specified
 		once and multiple xnames, separated by commas.
 
+*rename* [--dry-run [-F _format_]] [--no-confirm] _old_xname_ _new_xname_
+	Move a component and the records that depend on it to a new xname, e.g.
+	when hardware is moved to a different slot. _new_xname_ must not already
+	exist in SMD.
+
+	The following are copied to _new_xname_, in order:
+
+	- The SMD component, with the same type, state, role, architecture, and
+	  NID.
+	- The SMD ethernet interfaces of the component, which are reassigned to
+	  _new_xname_ via PATCH since their IDs are derived from their MAC
+	  addresses.
+	- Memberships of SMD groups.
+	- The BSS boot parameters of the component.
+	- The hostnames of the component in cloud-init's meta-data, which are set
+	  as cloud-init instance info. These are read via cloud-init's
+	  impersonation API; if it is not enabled, a warning is logged and
+	  cloud-init is skipped.
+
+	Then, _old_xname_ is removed from its SMD groups, its BSS boot parameters
+	and cloud-init instance info are deleted, and its SMD component is
+	deleted. Versions of cloud-init that cannot delete instance info have it
+	cleared instead, keeping its instance ID. Redfish endpoints of _old_xname_
+	are left as is. The command stops at the first failure, logging the steps
+	that were completed, so that the records of _old_xname_ are only removed
+	once everything has been copied.
+
+	After confirmation (unless *--no-confirm* is passed), this command sends
+	requests to SMD's /State/Components, /Inventory/EthernetInterfaces, and
+	/groups endpoints, BSS's /bootparameters endpoint, and cloud-init's
+	/admin/impersonation and /admin/instance-info endpoints.
+
+	This command accepts the following options:
+
+	*--dry-run*
+		Print what would be copied, and the steps that copy and remove each
+		record in order, to standard output without changing anything.
+
+	*-F, --format-output* _format_
+		Format of the output of *--dry-run*. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--no-confirm*
+		Do not ask before renaming.
+
+*wait* --for _field_=_value_... [-F _format_] [-x _xname_,...]... [-g _group_,...]... [--target _target_,...]... [--selector meta._key_=_value_]... [--any | --all] [--timeout _duration_] [--poll-interval _duration_] [--show-location]
+	Wait for components to satisfy all of the conditions passed with *--for*,
+	which is useful for orchestrating boots in scripts. At least one of
+	*--xname*, *--group*, *--target*, *--targets-from*, or *--selector* is
+	required to select the components.
+
+	The components are polled every *--poll-interval* until all of them (or,
+	with *--any*, any of them) have satisfied the conditions, or *--timeout*
+	elapses. A component that satisfied the conditions once is counted as
+	done even if it stops satisfying them later. The outcome for each
+	component is then printed, with how long after the start of the wait it
+	was first seen satisfying the conditions and its last known state and
+	flag. For example, in JSON format:
+
+	```
+	[
+	  { "xname": "x1000c0s0b0n0", "reached": true, "elapsed_seconds": 92.4, "state": "Ready", "flag": "OK", "found": true },
+	  { "xname": "x1000c0s1b0n0", "reached": false, "state": "On", "flag": "OK", "found": true }
+	]
+	```
+
+	This command exits with a nonzero status if *--timeout* elapsed before
+	the components satisfied the conditions.
+
+	This command sends a GET request to SMD's /State/Components endpoint for
+	each poll.
+
+	This command accepts the following options:
+
+	*--all*
+		Wait until all components satisfy the conditions. This is the
+		default.
+
+	*--any*
+		Wait until any component satisfies the conditions.
+
+	*-F, --format-output* _format_
+		Output response data in specified _format_. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--for* _field_=_value_
+		Condition to wait for. _field_ is one of _state_ (e.g. _Ready_),
+		_flag_ (e.g. _OK_), _enabled_ (_true_ or _false_), or _role_ (e.g.
+		_Compute_), and values are compared regardless of case. This flag
+		can be passed more than once to wait for all of the conditions.
+		Required.
+
+	*-g, --group* _group_,...
+		One or more SMD groups whose members to wait for.
+
+	*--only* _status_,...
+		With *--targets-from*, wait for only the components of the report with
+		one of these statuses, or that _failed_ or _succeeded_. See *TARGETS* in
+		*ochami*(1).
+
+	*--poll-interval* _duration_
+		Interval at which to poll components in SMD.
+
+		Default: *10s*
+
+	*--selector* meta._key_=_value_
+		Wait for the components whose metadata has _key_ set to _value_ (see
+		*ochami-meta*(1)). This flag can be passed more than once to select
+		the components matching all selectors.
+
+	*--show-location*
+		Include the physical location of each component in the outcome
+		(see *locations* in *ochami-config*(5)).
+
+	*--target* _target_,...
+		One or more xnames, NIDs, MAC addresses, or SMD groups to wait for,
+		resolved to xnames through SMD. See *TARGETS* in *ochami*(1).
+
+	*--targets-from* _path_
+		Wait for the components of the report at _path_ printed by an
+		earlier bulk run, e.g. to retry those that failed with *--only failed*.
+		See *TARGETS* in *ochami*(1).
+
+	*--timeout* _duration_
+		How long to wait before giving up, e.g. _20m_. _0_ means no limit.
+
+		Default: *10m*
+
+	*-x, --xname* _xname_,...
+		One or more xnames to wait for.
+
+## delete-subtree
+
+*delete-subtree* [--concurrency _n_] [--target-timeout _duration_] [--state-file _path_] [--dry-run [-F _format_]] [--no-confirm] [--yes-really-delete _n_] [--allow-major-impact] _xname_
+	Delete everything in SMD that is located at or under _xname_ in the xname
+	hierarchy, e.g. an entire cabinet (_x3000_) or chassis (_x3000c0_). This is
+	meant for decommissioning hardware. Note that _x3000c1_ is not under
+	_x3000c10_ or vice versa.
+
+	All components, ethernet interfaces (matched by component ID), Redfish
+	endpoints, and component endpoints under _xname_ are found and their
+	counts are printed to standard error. After confirmation (unless
+	*--no-confirm* is passed), they are deleted bottom-up: ethernet
+	interfaces, component endpoints, and Redfish endpoints first, then
+	components from the deepest in the hierarchy up to _xname_ itself. Each of
+	these steps is completed before the next starts, and up to *--concurrency*
+	deletions run at once within a step. Each deletion runs independently and
+	is marked failed if it does not finish within *--target-timeout*, so that
+	an unresponsive request does not hold up the rest of its step. If any
+	deletion in a step fails, the following steps are not started.
+
+	If *--state-file* is passed, the resources to delete and which have been
+	deleted are saved to _path_ as they are deleted. Running the command again
+	with the same _xname_ and *--state-file* resumes from the state file
+	instead of searching SMD again. The state file is only readable by the
+	user, replaced atomically on each update, and removed once everything has
+	been deleted. A resource that no longer exists (HTTP 404) counts as
+	deleted, so that one deleted just before an interruption does not fail the
+	resumed run.
+
+	This command sends GET requests to SMD's /State/Components,
+	/Inventory/EthernetInterfaces, /Inventory/RedfishEndpoints, and
+	/Inventory/ComponentEndpoints endpoints, followed by a DELETE for each
+	resource to the corresponding endpoint.
+
+	This command accepts the following options:
+
+	*--concurrency* _n_
+		Maximum number of deletions to run at once. Default: 4.
+
+	*--dry-run*
+		Print the resources that would be deleted to standard output, in
+		order, without deleting anything.
+
+	*-F, --format-output* _format_
+		Format of the output of *--dry-run*. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--no-confirm*
+		Do not ask before deleting.
+
+	*--state-file* _path_
+		Save progress to and resume from _path_.
+
+	*--target-timeout* _duration_
+		Maximum time to wait for each deletion before marking it failed, e.g.
+		_30s_. _0_ means no limit other than *--context-timeout*. Default: _1m_.
+
+	*--yes-really-delete* _n_
+		Confirm deleting more than *delete-threshold* (see *ochami-config*(5))
+		items. _n_ must be the exact number of items to be deleted. Without it,
+		such deletions require the user to type the number of items, and are
+		refused if *--no-confirm* is passed.
+
+	*--allow-major-impact*
+		Allow deleting more than *max-impact-percent* (see *ochami-config*(5))
+		of the components in SMD at once. Without it, such deletions are refused.
+
+## hwinv
+
+Manage hardware inventory, i.e. the FRU info of the hardware at each location.
+
+Subcommands for this command are as follows:
+
+*export* [--xname _xname_,...] [--type _type_] [--serial _serial_,...]
+	Export the hardware inventory as CSV for asset management and print it to
+	standard output. The first row is a header naming the columns _xname_,
+	_type_, _status_, _fru_id_, _manufacturer_, _model_, _part_number_, and
+	_serial_number_, followed by one row per piece of hardware, sorted by xname.
+	Hardware that is not populated has empty FRU columns.
+
+	This command sends a GET request to SMD's /Inventory/Hardware endpoint. An
+	access token is required.
+
+	This command accepts the same *--serial*, *--type*, and *--xname* options as
+	*get*.
+
+*get* [--xname _xname_,...] [--type _type_] [--serial _serial_,...] [-F _format_]
+	Get the hardware inventory. For each piece of hardware, its xname, type, and
+	status are printed along with the FRU ID, manufacturer, model, part number,
+	and serial number of the FRU populating it, if any.
+
+	To locate a physical board from its label, pass its serial number with
+	*--serial*. If nothing matches, an empty list is printed and the command
+	exits with status 1.
+
+	This command sends a GET request to SMD's /Inventory/Hardware endpoint. An
+	access token is required.
+
+	This command accepts the following options:
+
+	*-F, --format-output* _format_
+		Output response data in specified _format_. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--serial* _serial_,...
+		Only include hardware whose serial number is one of _serial_. Serial
+		numbers match regardless of case and surrounding white space.
+
+	*--type* _type_
+		Only include hardware of SMD type _type_, e.g. _Node_, _Processor_, or
+		_Memory_.
+
+	*-x, --xname* _xname_,...
+		Only include the hardware at each _xname_.
+
+## iface
+
+Manage ethernet interfaces.
+
+Subcommands for this command are as follows:
+
+*find* [-F _format_] [--show-location] --mac _mac_addr_++
+*find* [-F _format_] [--show-location] --ip _ip_addr_
+	Find the component that owns a MAC or IP address, e.g. an unknown MAC
+	address seen in DHCP logs. SMD's ethernet interfaces and Redfish endpoints
+	are searched for the address, as are BSS's hosts when searching for a MAC
+	address. MAC addresses match regardless of case and separators.
+
+	For each owning component, the groups it is a member of, its BMC (and the
+	BMC's FQDN or IP address, if SMD has a Redfish endpoint for it), and the
+	matching records are printed. Matching ethernet interfaces not associated
+	with a component are printed with an empty component. If BSS cannot be
+	reached, a warning is printed and only SMD is searched. If nothing
+	matches, an empty list is printed and the command exits with status 1.
+
+	This command sends GET requests to SMD's /Inventory/EthernetInterfaces,
+	/Inventory/RedfishEndpoints, and /groups endpoints and to BSS's /hosts
+	endpoint. An access token is required.
+
+	This command accepts the following options:
+
+	*-F, --format-output* _format_
+		Output response data in specified _format_. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--ip* _ip_addr_
+		Find the owner of the IP address _ip_addr_.
+
+	*-m, --mac* _mac_addr_
+		Find the owner of the MAC address _mac_addr_.
+
+	*--show-location*
+		Include the physical location of each owning component
+		(see *locations* in *ochami-config*(5)).
+
+*retag* [--from _old_network_] [--cidr _cidr_] --to _new_network_ [-F _format_]
+	Change the network name (the *Network* field) of ethernet interface IP
+	addresses in bulk, e.g. after a network has been renamed. An IP address
+	matches if its network is _old_network_ and/or its address is within
+	_cidr_. At least one of *--from* or *--cidr* is required. If both are
+	passed, an IP address must match both.
+
+	Each ethernet interface with at least one matching IP address is updated
+	with its other IP addresses left as they are. The updated ethernet
+	interfaces are printed.
+
+	This command sends a GET and then a PATCH for each ethernet interface to
+	update to SMD's /Inventory/EthernetInterfaces endpoint.
+
+	This command accepts the following options:
+
+	*--cidr* _cidr_
+		Only retag IP addresses within _cidr_ (e.g. _10.2.0.0/16_).
+
+	*-F, --format-output* _format_
+		Output response data in specified _format_. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--from* _old_network_
+		Only retag IP addresses whose network is _old_network_.
+
+	*--to* _new_network_
+		The network name to set for matching IP addresses. This is required.
+
 ## rfe
 
 Manage Redfish endpoints. 
 
 Subcommands for this command are as follows:
 
-*add* [--domain _domain_] [--hostname _hostname_] [--username _user_] [--password _pass_] _xname_ _name_ _ip_addr_ _mac_addr_++
-*add* [-f _format_] -d _data_++
-*add* [-f _format_] -d @_path_++
-*add* [-f _format_] -d @-++
+*add* [--if-not-exists] [--domain _domain_] [--hostname _hostname_] [--username _user_] [--password _pass_] _xname_ _name_ _ip_addr_ _mac_addr_++
+*add* [--if-not-exists] [-f _format_] -d _data_++
+*add* [--if-not-exists] [-f _format_] -d @_path_++
+*add* [--if-not-exists] [-f _format_] -d @-++
 	Add one or more new Redfish endpoints to SMD.
 
 	In the first form of the command, an _xname_ (unique identifier), _name_
@@ -493,11 +880,16 @@ Subcommands for this command are as follows:
 	*--username* _username_
 		Specify the username to use when interrogating the endpoint (stored in SMD).
 
-*delete* [--no-confirm] --all++
-*delete* [--no-confirm] _xname_...++
-*delete* [--no-confirm] [-f _format_] -d _data_++
-*delete* [--no-confirm] [-f _format_] -d @_path_++
-*delete* [--no-confirm] [-f _format_] -d @-++
+	*--if-not-exists*
+		Succeed with an informational note instead of failing for items that
+		already exist (HTTP 409), so that the command can be rerun safely, e.g.
+		by automation.
+
+*delete* [--no-confirm] [--yes-really-delete _n_] [--allow-major-impact] --all++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] [--allow-major-impact] _xname_...++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] [--allow-major-impact] [-f _format_] -d _data_++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] [--allow-major-impact] [-f _format_] -d @_path_++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] [--allow-major-impact] [-f _format_] -d @-++
 	Delete one or more Redfish endpoints in SMD. Unless *--no-confirm* is passed, the
 	user may be asked to confirm deletion.
 
@@ -533,7 +925,22 @@ Subcommands for this command are as follows:
 	*--no-confirm*
 		Do not ask the user to confirm deletion.
 
-*get* [-F _format_] [--fqdn _fqdn_,...] [-i _ip_,...] [-m _mac_,...] [--type _type_,...] [--uuid _uuid_,...] [-x _xname_,...]
+	*--yes-really-delete* _n_
+		Confirm deleting more than *delete-threshold* (see *ochami-config*(5))
+		items. _n_ must be the exact number of items to be deleted. Without it,
+		such deletions require the user to type the number of items, and are
+		refused if *--no-confirm* is passed.
+
+	*--allow-major-impact*
+		Allow deleting more than *max-impact-percent* (see *ochami-config*(5))
+		of the Redfish endpoints in SMD at once. Without it, such deletions are refused.
+
+	*--if-exists*
+		Succeed with an informational note instead of failing for items that do
+		not exist (HTTP 404), so that the command can be rerun safely, e.g. by
+		automation.
+
+*get* [--annotations] [-F _format_] [--fqdn _fqdn_,...] [-i _ip_,...] [-m _mac_,...] [--type _type_,...] [--uuid _uuid_,...] [-x _xname_,...]
 	Get all Redfish endpoints or filter by various attributes.
 
 	If no filter flags are passed, all Redfish endpoints are returned.
@@ -546,6 +953,12 @@ Subcommands for this command are as follows:
 
 	This command accepts the following options:
 
+	*--annotations*
+		Only print the _ID_ and _Name_ of each Redfish endpoint and, if it
+		has one, the _annotation_ of who created or last changed it, when,
+		and with what comment (see *ANNOTATIONS* in *ochami*(1)), split off
+		from its name.
+
 	*-F, --format-output* _format_
 		Output response data in specified _format_. Supported values are:
 
@@ -571,16 +984,237 @@ Subcommands for this command are as follows:
 	*-x, --xname* _xname_,...
 		Filter Redfish endpoints by one or more xnames.
 
+*rediscover* (-x _xname_,... | --last-status _status_ | --all) [--force] [--watch [--timeout _duration_] [--poll-interval _duration_] [-F _format_]]
+	Flag Redfish endpoints in SMD for rediscovery, so that SMD queries their
+	BMCs again and updates the inventory discovered from them. The endpoints
+	are those passed with *-x*, those whose last discovery status is
+	*--last-status*, or all of them with *--all*. Endpoints that SMD is already
+	discovering are skipped unless *--force* is passed.
+
+	With *--watch*, the discovery status (LastDiscoveryStatus) of the
+	endpoints is then polled until each has finished being rediscovered, i.e.
+	its status is no longer one SMD sets during discovery (e.g.
+	_DiscoveryStarted_) and it was seen in progress or its last discovery
+	attempt changed. A report is printed with, for each endpoint, whether it
+	is _done_, whether it is _ok_ (_DiscoverOK_), its status and the time of
+	its last attempt, and how long it took. The command exits with an error if
+	the watch timed out or any endpoint was not discovered successfully.
+
+	This command sends a GET request to SMD's /RedfishEndpoints endpoint to look
+	up the endpoints and a POST to /Inventory/Discover, then, with *--watch*,
+	GET requests to /RedfishEndpoints until the endpoints are done.
+
+	This command accepts the following options:
+
+	*--all*
+		Rediscover all Redfish endpoints.
+
+	*-F, --format-output* _format_
+		Output report in specified _format_. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--force*
+		Rediscover endpoints even if SMD is already discovering them.
+
+	*--last-status* _status_
+		Rediscover the endpoints whose last discovery status is _status_, e.g.
+		_HTTPsGetFailed_. Prefix it with *!* to rediscover those whose status
+		is not _status_, e.g. _!DiscoverOK_.
+
+	*--poll-interval* _duration_
+		Interval at which to poll the discovery status with *--watch*. Default:
+		5s.
+
+	*--timeout* _duration_
+		How long to watch before giving up, or _0_ for no limit. Default: 10m.
+
+	*--watch*
+		Follow the discovery status of the endpoints until they are
+		rediscovered, and print the outcome.
+
+	*-x, --xname* _xname_,...
+		One or more xnames of Redfish endpoints to rediscover. All of them must
+		exist in SMD.
+
+*rotate-creds* -x _xname_,... (--password-stdin | --generate [--length _n_]) [--username _user_] [--push --current-password-file _path_] [-F _format_]
+	Rotate the BMC credentials stored in SMD for one or more Redfish
+	endpoints. The new password is either read from the first line of standard
+	input and used for all endpoints (*--password-stdin*) or generated randomly
+	for each endpoint (*--generate*). The user name stored in SMD is kept
+	unless *--username* is passed.
+
+	If *--push* is passed, the password is first changed on each BMC via
+	Redfish by finding the account with the user name in the BMC's
+	AccountService and patching its password, authenticating with the current
+	password. The BMC is reached at the FQDN, IP address, or hostname stored in
+	SMD, in that order of preference. SMD is only updated for BMCs that
+	accepted the change.
+
+	A report is printed with, for each endpoint, the user name, the generated
+	password (only with *--generate*), whether the BMC _accepted_ or
+	_rejected_ the change (only with *--push*), whether SMD was _updated_, and
+	any error. The report is printed even if SMD could not be updated, since
+	it holds the passwords set on BMCs. The command exits with an error if
+	rotation failed for any endpoint.
+
+	This command sends a GET request to SMD's /RedfishEndpoints endpoint and a
+	PATCH to /RedfishEndpoints/{xname} for each endpoint.
+
+	This command accepts the following options:
+
+	*--current-password-file* _path_
+		Read the current BMC password from _path_ to authenticate to BMCs
+		with. Required with *--push*.
+
+	*-F, --format-output* _format_
+		Output report in specified _format_. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--generate*
+		Generate a random password for each endpoint.
+
+	*--length* _n_
+		Length of passwords generated by *--generate*. Default: 20.
+
+	*--password-stdin*
+		Read the new password from the first line of standard input.
+
+	*--push*
+		Change the password on each BMC via Redfish before updating SMD.
+
+	*--username* _user_
+		User name to store in SMD and, with *--push*, whose password to
+		change on the BMC. Defaults to the user name stored in SMD.
+
+	*-x, --xname* _xname_,...
+		One or more xnames of Redfish endpoints whose credentials to rotate.
+
+## export
+
+Export SMD data to external systems. To generate the configuration files of several services
+from one snapshot of SMD, see *ochami-export*(1).
+
+Subcommands for this command are as follows:
+
+*conman* [--template _file_]
+	Generate *conman.conf*(5) console entries from SMD and print them to
+	standard output. By default, one _CONSOLE_ entry is generated per node,
+	connecting to its BMC using IPMI Serial-Over-LAN. IPMI credentials can be
+	set with a _GLOBAL ipmiopts_ line in the including *conman.conf*.
+
+	BMCs are read from SMD's /RedfishEndpoints endpoint and nodes from its
+	/State/Components endpoint. Only node BMCs managing at least one node are
+	included, and each BMC is reached by its FQDN in SMD, or its IP address if
+	it has none. Output is sorted by xname and contains no timestamps so that
+	it only changes when the inventory does.
+
+	Templates are executed with the following data:
+
+	- *.BMCs*: list of BMCs, each with:
+		- *.ID*: xname of the BMC
+		- *.Host*: host name or IP address of the BMC
+		- *.Nodes*: list of managed nodes, each with *.ID* (xname) and *.NID*
+
+	This command accepts the following options:
+
+	*--template* _file_
+		Generate the config with the Go text/template in _file_ instead of the
+		built-in template.
+
+*grafana-annotations* --action _action_ [-x _xname_]... [-g _group_]... [--state _state_]... [--tag _tag_]... [--start _time_] [--end _time_] [-F _format_]
+	Push an annotation for _action_ having been performed on SMD components to
+	the Grafana instance configured with *cluster.grafana.uri* (see
+	*ochami-config*(5)) so that dashboards can correlate changes in metrics
+	with operational actions. The annotation is tagged with _ochami_, the
+	action, any tags in *cluster.grafana.tags*, and any passed with *--tag*.
+	Its text lists the affected components. The annotation that was pushed is
+	printed.
+
+	The Grafana API token is read from the environment variable
+	*<CLUSTER>\_GRAFANA_TOKEN*, where *<CLUSTER>* is the upper-case cluster
+	name with dashes replaced by underscores. If unset, no token is sent.
+
+	This command sends a GET to SMD's /State/Components endpoint and a POST to
+	Grafana's /api/annotations endpoint.
+
+	This command accepts the following options:
+
+	*--action* _action_
+		The action to annotate (e.g. _maintenance_). This is required.
+
+	*--end* _time_
+		End time of the annotation in RFC 3339 format (e.g.
+		_2025-01-01T11:30:00Z_). If unset, the annotation marks a single point
+		in time.
+
+	*-F, --format-output* _format_
+		Output response data in specified _format_. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*-g, --group* _group_,...
+		Include the members of one or more SMD groups. Can be combined with
+		*--xname*.
+
+	*--start* _time_
+		Start time of the annotation in RFC 3339 format. If unset, the current
+		time is used.
+
+	*--state* _state_,...
+		Only include components in one of the specified SMD states (e.g.
+		_Off_).
+
+	*--tag* _tag_,...
+		Additional tags to add to the annotation.
+
+	*-x, --xname* _xname_,...
+		Include one or more components by xname. If neither this nor *--group*
+		is passed, all components are included.
+
+*powerman* [--template _file_]
+	Generate *powerman.conf*(5) device and node entries from SMD and print
+	them to standard output. By default, one _redfishpower_ device is
+	generated per BMC, with a _node_ entry for each node it manages using the
+	BMC as its plug. BMCs managing several nodes need a custom template whose
+	plugs match the device script.
+
+	BMCs are read from SMD's /RedfishEndpoints endpoint and nodes from its
+	/State/Components endpoint. Only node BMCs managing at least one node are
+	included, and each BMC is reached by its FQDN in SMD, or its IP address if
+	it has none. Output is sorted by xname and contains no timestamps so that
+	it only changes when the inventory does.
+
+	Templates are executed with the following data:
+
+	- *.BMCs*: list of BMCs, each with:
+		- *.ID*: xname of the BMC
+		- *.Host*: host name or IP address of the BMC
+		- *.Nodes*: list of managed nodes, each with *.ID* (xname) and *.NID*
+
+	This command accepts the following options:
+
+	*--template* _file_
+		Generate the config with the Go text/template in _file_ instead of the
+		built-in template.
+
 ## group
 
 Manage SMD groups. For managing group membership, see *group member* below.
 
 Subcommands for this command are as follows:
 
-*add* [--description _desc_] [--tag _tag_,...] [--member _xname_,...] [--exclusive-group _group_] _group_name_++
-*add* -d _data_ [-f _format_]++
-*add* -d @_file_ [-f _format_]++
-*add* -d @- [-f _format_]
+*add* [--if-not-exists] [--description _desc_] [--tag _tag_,...] [--member _xname_,...] [--exclusive-group _group_] _group_name_++
+*add* [--if-not-exists] -d _data_ [-f _format_]++
+*add* [--if-not-exists] -d @_file_ [-f _format_]++
+*add* [--if-not-exists] -d @- [-f _format_]
 	Add a new group to SMD, optionally specifying members to add to the group.
 
 	In the first form of the command, a _group_name_ is required to create the
@@ -637,10 +1271,67 @@ Subcommands for this command are as follows:
 		flag can be specified multiple times or this flag can be specified once
 		and multiple tags can be specified, separated by commas.
 
-*delete* [--no-confirm] _group_name_...++
-*delete* [--no-confirm] -d _data_ [-f _format_]++
-*delete* [--no-confirm] -d @_file_ [-f _format_]++
-*delete* [--no-confirm] -d @- [-f _format_]
+	*--if-not-exists*
+		Succeed with an informational note instead of failing for items that
+		already exist (HTTP 409), so that the command can be rerun safely, e.g.
+		by automation.
+
+*clone* [--selector _selector_]... [--target _target_,...] [--remap _from_=_to_]... [-D _desc_] [--tag _tag_,...] [-e _group_] [--dry-run [-F _format_]] _group_name_ _new_name_
+	Clone the group _group_name_ to a new group _new_name_, copying its
+	description, tags, and members. The clone has no exclusive group unless
+	one is passed with *--exclusive-group*, since it would share members with
+	the original.
+
+	The members of the clone can be filtered and remapped, e.g. to split a
+	_compute_ group into _compute-a100_ and _compute-h100_ by metadata. Only
+	members matching all *--selector* selectors and, if *--target* is passed,
+	among its targets are kept. Each kept member is then rewritten by the first
+	*--remap* that applies to it.
+
+	This command sends a GET and a POST request to SMD's /groups endpoint.
+
+	This command accepts the following options:
+
+	*-D, --description* _description_
+		Description of the clone. Default: that of _group_name_.
+
+	*--dry-run*
+		Print the group that would be added without adding it.
+
+	*-e, --exclusive-group* _group_name_
+		Group that the clone will be mutually exclusive with.
+
+	*-F, --format-output* _format_
+		Format of the group printed with *--dry-run*. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--remap* _from_=_to_
+		Rewrite members whose xname starts with _from_ to start with _to_
+		instead, e.g. _x1000=x1001_ to clone the group of the nodes of cabinet
+		x1000 for cabinet x1001. Only whole xname elements are matched, so
+		_x1000c0_ matches _x1000c0s0b0n0_ but not _x1000c01s0b0n0_. Can be
+		passed more than once; the first remap that applies is used.
+
+	*--selector* meta._key_=_value_
+		Only keep members with the metadata _key_=_value_ (see
+		*ochami-meta*(1)). Can be passed more than once to only keep members
+		matching all selectors.
+
+	*--tag* _tag_,...
+		Tags of the clone. Default: those of _group_name_.
+
+	*--target* _target_,...
+		Only keep members among one or more xnames, NIDs, MAC addresses, or
+		SMD groups, resolved to xnames through SMD. See *TARGETS* in
+		*ochami*(1).
+
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] _group_name_...++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] -d _data_ [-f _format_]++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] -d @_file_ [-f _format_]++
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] -d @- [-f _format_]
 	Delete one or more groups in SMD. Unless *--no-confirm* is passed, the user
 	is asked to confirm deletion.
 
@@ -676,7 +1367,18 @@ Subcommands for this command are as follows:
 		- _json_ (default)
 		- _yaml_
 
-*get* [-F _format_] [--name _name_,...] [--tag _tag_,...]
+	*--yes-really-delete* _n_
+		Confirm deleting more than *delete-threshold* (see *ochami-config*(5))
+		items. _n_ must be the exact number of items to be deleted. Without it,
+		such deletions require the user to type the number of items, and are
+		refused if *--no-confirm* is passed.
+
+	*--if-exists*
+		Succeed with an informational note instead of failing for items that do
+		not exist (HTTP 404), so that the command can be rerun safely, e.g. by
+		automation.
+
+*get* [--annotations] [-F _format_] [--name _name_,...] [--tag _tag_,...]
 	Get group information for all groups in SMD or for a subset, specified by
 	filters.
 
@@ -684,6 +1386,12 @@ Subcommands for this command are as follows:
 
 	This command accepts the following options:
 
+	*--annotations*
+		Only print the _label_ and _description_ of each group and, if it has
+		one, the _annotation_ of who created or last changed it, when, and
+		with what comment (see *ANNOTATIONS* in *ochami*(1)), split off from
+		its description.
+
 	*-F, --format-output* _format_
 		Output response data in specified _format_. Supported values are:
 
@@ -704,7 +1412,8 @@ Subcommands for this command are as follows:
 *update* [--description _description_] [--tag _tag_,...] _group_name_++
 *update* -d _data_ [-f _format_]++
 *update* -d @_file_ [-f _format_]++
-*update* -d @- [-f _format_] < _file_
+*update* -d @- [-f _format_] < _file_++
+*update* --patch (_data_ | @_path_) [--patch-type _type_] [-f _format_] _group_name_
 	Update one or more existing groups in SMD. If the group does not already
 	exist, this command will fail.
 
@@ -720,6 +1429,11 @@ Subcommands for this command are as follows:
 	In the fourth form of the command, the payload data is read from standard
 	input.
 
+	In the fifth form of the command, the current data for _group_name_ is
+	fetched from SMD and the patch document passed to *--patch* is applied to
+	it client-side. The patched description and tags are then sent. The group
+	label cannot be changed this way.
+
 	This command sends a PATCH  request to SMD's /groups endpoint.
 
 	This command accepts the following options:
@@ -740,6 +1454,18 @@ Subcommands for this command are as follows:
 		- _json_ (default)
 		- _yaml_
 
+	*--patch* (_data_ | @_path_ | @-)
+		Specify a patch document to apply client-side to the current
+		group data. Like *-d*, the document can be passed raw, read from
+		_path_, or read from standard input (@-), and is JSON unless *-f* is
+		specified.
+
+	*--patch-type* _type_
+		Type of the patch document passed to *--patch*. Supported types are:
+
+		- _json_ (default): a JSON Patch (RFC 6902) array of operations
+		- _merge_: a JSON Merge Patch (RFC 7396) partial document
+
 	*--tag* _tag_,...
 		One or more tags to assign to the group. For multiple tags, either this
 		flag can be specified multiple times or this flag can be specified once
@@ -751,21 +1477,73 @@ Subcommands for this command are as follows:
 
 Manage SMD group membership. For general group management, see *group*.
 
+The *add*, *delete*, and *set* subcommands accept the following options for
+editing groups safely while other clients may be editing them as well:
+
+*--expected-version* _version_
+	Fail without editing the group unless its members are at _version_, as
+	printed by *get --print-version*. Since SMD does not version groups, the
+	version is a hash of the sorted member list. Implies *--verify*.
+
+*--retries* _n_
+	With *--verify*, retry an edit undone concurrently up to _n_ times
+	(default _3_).
+
+*--verify*
+	Edit the group as a read-verify-write: read its members before and after
+	the edit to verify that no part of the edit was undone by another client,
+	e.g. one setting the members of the group, retrying it if it was. Members
+	changed concurrently are reported. Since SMD supports neither ETags nor
+	conditional writes, a change between reading and editing the members can
+	only be detected afterwards, not prevented.
+
 Subcommands for this command are as follows:
 
-*add* _group_name_ _xname_...
-	Add one or more components to an existing SMD group.
+*add* [--if-not-exists] [--verify] [--expected-version _version_] [--retries _n_] _group_name_ _xname_...
+	Add one or more components to an existing SMD group. With *--verify*, only
+	components not in the group already are added.
 
 	This command sends one or more POST requests to the members subendpoint
 	under SMD's /groups endpoint.
 
-*delete* _group_name_ _xname_...
-	Delete one or more components from an existing SMD group.
+	This command accepts the editing options described above as well as the
+	following option:
+
+	*--if-not-exists*
+		Succeed with an informational note instead of failing for components
+		that are already members of the group (HTTP 409), so that the command
+		can be rerun safely, e.g. by automation.
+
+*delete* [--if-exists] [--no-confirm] [--yes-really-delete _n_] [--allow-major-impact] [--verify] [--expected-version _version_] [--retries _n_] _group_name_ _xname_...
+	Delete one or more components from an existing SMD group. Unless
+	*--no-confirm* is passed, the user is asked to confirm deletion. With
+	*--verify*, only components in the group are deleted.
 
 	This command sends one or more DELETE requests to the members subendpoint
 	under SMD's /groups endpoint.
 
-*get* [-F _format_] _group_name_
+	This command accepts the editing options described above as well as the
+	following options:
+
+	*--no-confirm*
+		Do not ask the user to confirm deletion. Use with caution.
+
+	*--yes-really-delete* _n_
+		Confirm deleting more than *delete-threshold* (see *ochami-config*(5))
+		items. _n_ must be the exact number of items to be deleted. Without it,
+		such deletions require the user to type the number of items, and are
+		refused if *--no-confirm* is passed.
+
+	*--allow-major-impact*
+		Allow deleting more than *max-impact-percent* (see *ochami-config*(5))
+		of the members of the group in SMD at once. Without it, such deletions are refused.
+
+	*--if-exists*
+		Succeed with an informational note instead of failing for items that do
+		not exist (HTTP 404), so that the command can be rerun safely, e.g. by
+		automation.
+
+*get* [-F _format_] [--print-version] _group_name_
 	Get members of an SMD group.
 
 	This command sends a GET request to the members subendpoint under SMD's
@@ -779,12 +1557,26 @@ Subcommands for this command are as follows:
 		- _json_ (default)
 		- _yaml_
 
-*set* _group_name_ _xname_...
+	*--print-version*
+		Print the version of the membership list instead of the members, to
+		pass to *--expected-version* of the other subcommands.
+
+*set* [--verify] [--expected-version _version_] [--retries _n_] _group_name_ _xname_...
 	Set the membership list of _group_name_ to _xname_.... Xnames specified that
 	are not already in the group are added to it, xnames specified that are
 	already in the group remain in the group, and xnames not specified that are
 	already in the group are removed from the group.
 
+	Since the whole membership list is replaced, members added by another
+	client since the members were read are dropped. To prevent this when
+	scripting read-modify-write changes, pass the version printed by *get
+	--print-version* when reading the members to *--expected-version*.
+
+	This command sends a PUT request to the members subendpoint under SMD's
+	/groups endpoint.
+
+	This command accepts the editing options described above.
+
 ## service
 
 Manage and check SMD itself.
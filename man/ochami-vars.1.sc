@@ -0,0 +1,147 @@
+OCHAMI-VARS(1) "OpenCHAMI" "Manual Page for ochami-vars"
+
+# NAME
+
+ochami-vars - Manage per-node and per-group template variables
+
+# SYNOPSIS
+
+ochami vars get (--node _xname_ [--resolve] | --group _name_) _key_
+
+ochami vars list [--node _xname_ [--resolve] | --group _name_] [-F _format_]
+
+ochami vars set (--node _xname_ | --group _name_) _key_=_value_...
+
+# DESCRIPTION
+
+The *vars* command is a metacommand for managing key/value variables of nodes
+and groups, such as site-specific addresses or serial console settings, that
+have no place in SMD or BSS. They are available to templates as _vars_ when
+rendering:
+
+- kernel parameters passed to *ochami bss boot params set* and *ochami bss boot
+  params update* (see *KERNEL PARAMETER TEMPLATES* in *ochami-bss*(1)), e.g.
+  _{{ .vars.console }}_
+- cloud-init configs with *ochami cloud-init group render* (see
+  *ochami-cloud-init*(1)), e.g. _{{ vars.console }}_
+
+Variables are only read if a template references them. The variables of a node
+are those set for each SMD group it is a member of, merged in the order of the
+group names, overridden by those set for the node itself. Referencing a
+variable that is not set is an error when rendering kernel parameters.
+
+# STORAGE
+
+If *cluster.vars-file* is set in the config file (see *ochami-config*(5)), the
+variables are stored in that YAML file, which is created if it does not exist:
+
+```
+nodes:
+  x3000c0s0b0n0:
+    console: ttyS1,115200
+groups:
+  compute:
+    console: ttyS0,115200
+```
+
+Otherwise, they are stored in the same format in the meta-data of the
+cloud-init group _ochami-vars_, which is created if it does not exist. The
+group has no members, so its meta-data is not served to any node, and an access
+token is required if authentication is enabled.
+
+Keys consist of letters, digits, and underscores and do not start with a digit,
+so that they can be referenced as template fields. Values can be anything,
+including empty. Xnames are compared regardless of case.
+
+# COMMANDS
+
+## get
+
+Print the value of a variable of a node or group. If the variable is not set,
+an error is printed and the exit status is 1.
+
+The format of this command is:
+
+*get* (--node _xname_ [--resolve] | --group _name_) _key_
+
+This command accepts the following options:
+
+	*--group* _name_
+		Get the variable of the group _name_.
+
+	*--node* _xname_
+		Get the variable of the node _xname_.
+
+	*--resolve*
+		With *--node*, get the value that templates see for the node, which
+		may be set for one of the SMD groups it is a member of.
+
+## list
+
+List the variables of a node or group, printed as a map of keys to values. If
+neither *--node* nor *--group* is passed, the variables of all nodes and groups
+are printed in the storage format (see *STORAGE*).
+
+The format of this command is:
+
+*list* [--node _xname_ [--resolve] | --group _name_] [-F _format_]
+
+This command accepts the following options:
+
+	*-F, --format-output* _format_
+		Output data in specified _format_. Supported values are:
+
+		- _json_ (default)
+		- _json-pretty_
+		- _yaml_
+
+	*--group* _name_
+		List the variables of the group _name_.
+
+	*--node* _xname_
+		List the variables of the node _xname_.
+
+	*--resolve*
+		With *--node*, list the variables that templates see for the node,
+		including those set for the SMD groups it is a member of.
+
+## set
+
+Set one or more variables of a node or group. If a key is already set, its value
+is replaced.
+
+The format of this command is:
+
+*set* (--node _xname_ | --group _name_) _key_=_value_...
+
+This command accepts the following options:
+
+	*--group* _name_
+		Set the variables of the group _name_.
+
+	*--node* _xname_
+		Set the variables of the node _xname_.
+
+# EXAMPLES
+
+Set the serial console of all compute nodes, overriding it for one of them,
+then use it in their kernel parameters:
+
+```
+ochami vars set --group compute console=ttyS0,115200
+ochami vars set --node x3000c0s0b0n0 console=ttyS1,115200
+ochami bss boot params set --xname x3000c0s0b0n0,x3000c0s1b0n0 \
+	--kernel http://s3/vmlinuz --initrd http://s3/initrd \
+	--params 'console={{ .vars.console }}'
+```
+
+# AUTHOR
+
+Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
+
+# SEE ALSO
+
+*ochami*(1), *ochami-bss*(1), *ochami-cloud-init*(1), *ochami-config*(5)
+
+; Vim modeline settings
+; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
@@ -18,14 +18,42 @@ List of available commands:
 
 [[ *Command*
 :< *Description*
+|  *agent*
+:  Keep the token of a cluster fresh and serve cached queries over a Unix socket
+|  *auth*
+:  Check which operations the access token is authorized for
+|  *backup*
+:  Back up and restore cluster configuration
 |  *bss*
 :  Communicate with the Boot Script Service (BSS)
 |  *cloud-init*
 :  Manage cloud-init configurations
+|  *convert*
+:  Convert a payload between formats
 |  *discover*
 :  Simulate discovery of BMCs and nodes to populate SMD by reading an input file
+|  *events*
+:  Watch change events from SMD and BSS
+|  *export*
+:  Generate configuration files for infrastructure services from SMD
+|  *fsck*
+:  Check the consistency of data across SMD, BSS, and cloud-init
+|  *image*
+:  Verify the artifacts of boot images
+|  *ipam*
+:  Plan and apply static IP addresses of node interfaces
+|  *meta*
+:  Manage free-form key/value metadata of components
+|  *node*
+:  Manage nodes across services, e.g. reimage them
+|  *redact*
+:  Pseudonymize infrastructure details in a payload for sharing
+|  *run*
+:  Run ochami commands described by a JSON or YAML document
 |  *smd*
 :  Communicate with the State Management Database (SMD)
+|  *vars*
+:  Manage per-node and per-group template variables
 |  *config*
 :  Manage ochami CLI configuration, including cluster configuration
 
@@ -80,11 +108,25 @@ by underscores. In the above example, this variable would be named
 export FOOBAR_ACCESS_TOKEN=...
 ```
 
+If the variable is unset, the token is taken from the *ochami agent* of the
+cluster if one is running (see *ochami-agent*(1)), or else from the
+*token-command* or *access-token* of the cluster config (see
+*ochami-config*(5)).
+
 Once these steps are completed, *ochami* should be ready to use with cluster
 _foobar_.
 
 # GLOBAL OPTIONS
 
+*--as* _user_
+	Make requests on behalf of _user_, e.g. the operator running a shared
+	automation account. _user_ is sent in the request header set by
+	*impersonation-header* in the cluster config (_Impersonate-User_ by
+	default), which requires an API gateway that supports impersonation, and
+	is recorded along with each request that may modify data in the audit log
+	set by *audit-log* (see *ochami-config*(5)). A warning is logged if no
+	audit log is configured.
+
 *--cacert* _cacert_
 	Specify the path to a certificate authority (CA) certificate file to use to
 	verify TLS certificates. Must be PEM-formatted.
@@ -104,11 +146,35 @@ _foobar_.
 	See *ochami-config*(5) for details on cluster config options, as well as the
 	manual pages for the services in *ochami*(1) for details on *--uri*.
 
+*--comment* _comment_
+	Add _comment_ to the annotations of the records the command creates or
+	changes. See *ANNOTATIONS*.
+
 *-c, --config* _config_file_
 	Specify the path to a config file to use. By default, the configuration is
 	merged from the system config with the user config (see *FILES* below). The
 	format of this file should be YAML.
 
+*--context-timeout* _duration_
+	Set a deadline for the whole command, e.g. _30s_ or _5m_. The deadline
+	starts when the command starts and is shared by all of the requests it
+	sends. Once it has passed, any request still in flight is canceled and no
+	further requests are sent. This is useful for bounding bulk operations run
+	from CI or cron.
+
+	Commands that operate on multiple items (e.g. *ochami smd component delete*)
+	report each item that was not attempted as failed, followed by a warning
+	summarizing how many items were not attempted. Such commands then complete
+	with errors as usual.
+
+	By default, there is no deadline.
+
+*--fail-on-warn*
+	Treat warnings as failures. If any warnings were logged by a command that
+	would otherwise succeed, e.g. about a duplicate xname being skipped or an
+	empty cloud-config, exit with a status of _1_ after listing the warnings
+	that occurred. Warnings are counted even if the log level hides them.
+
 *--ignore-config*
 	Do not read configuration from any configuration file.
 
@@ -131,10 +197,48 @@ _foobar_.
 
 	Supported log levels are:
 
+	- _error_
 	- _info_
 	- _warning_
 	- _debug_
 
+	This takes precedence over *-q* and *-v*.
+
+*--created-by* _user_
+	Annotate the records the command creates or changes as created by _user_
+	instead of the user passed with *--as* or, if it is not passed, the local
+	user. See *ANNOTATIONS*.
+
+*--max-memory-buffer* _size_
+	Set the size above which the response bodies of large dumps are spilled to
+	a temporary file instead of being held in memory, e.g. _512MiB_ or _2G_.
+	This overrides *max-memory-buffer* set in the config file. Defaults to
+	_128MiB_.
+
+	This applies to responses that can grow with the size of the system: all
+	components (*ochami smd component get* without options), the BSS state
+	(*ochami bss dumpstate*), and the largest datasets of a backup (*ochami
+	backup create*). Spilled responses are printed as they are decoded, so
+	that memory use stays bounded, unless the output format is _yaml_, which
+	requires the whole response to be held in memory. Temporary files are
+	created in *TMPDIR* (or _/tmp_) and removed before the command exits.
+
+*--no-annotate*
+	Do not annotate the records the command creates or changes. See
+	*ANNOTATIONS*.
+
+*--no-agent*
+	Do not get the access token from or send GET requests through the *ochami
+	agent* of the cluster, even if one is running. See *ochami-agent*(1).
+
+*--no-notify*
+	Do not notify the sinks configured for the cluster when a long-running
+	command exits. See *NOTIFICATIONS*.
+
+*--no-pager*
+	Do not page long output through a pager, even if standard output is a
+	terminal. This overrides *pager* set in the config file. See *OUTPUT*.
+
 *--no-token*
 	Disable reading of and checking for access token and do not include any
 	token in the request headers. This overrides the value of *enable-auth* set
@@ -143,17 +247,180 @@ _foobar_.
 	This flag is useful for testing access to API endpoints that don't have JWT
 	authentication enabled, e.g. in a test environment.
 
+*-q, --quiet*
+	Only print data and errors. This sets the log level to _error_,
+	suppressing warnings and informational messages. Cannot be used with *-v*.
+
+*--read-only*
+	Refuse to send any request that may modify data (i.e. any request other
+	than GET, HEAD, or OPTIONS) to OpenCHAMI services. Commands that change
+	cluster data (e.g. the *add*, *set*, *update*, and *delete* commands,
+	*discover static*, and *fsck --fix*) fail with an error before doing
+	anything, unless *--dry-run* is passed. Any other request that may modify
+	data fails with an error instead of being sent. This is useful for monitoring
+	accounts and for operators who should only be able to inspect a cluster.
+	Passing *--read-only=false* overrides *read-only* set in the config file.
+
+	Read-only mode has no effect on commands that only modify local files, such
+	as *ochami config*.
+
 *-t, --token* _token_
 	Access token to include in request headers for authentication to protected
 	service endpoints. Overrides token set in environment variable.
 
-*-v*
-	Enable early debug logging.
+*--validate-against-server*
+	Before sending a request with a payload (e.g. data passed with *-d*) to
+	SMD, BSS, cloud-init, or PCS, validate the payload against the request body
+	schema in the OpenAPI spec the service publishes, so that payloads the
+	running version of the service would reject are caught with a list of the
+	mismatching fields instead of an unexplained _400 Bad Request_. Requests
+	with invalid payloads are not sent and the command fails.
+
+	The spec is fetched once per command from _/openapi.json_ under the
+	service's base URI. Services that publish it elsewhere can have the path
+	overridden with *paths* in the cluster config (see *ochami-config*(5)). If
+	the spec cannot be fetched or parsed, no requests with payloads are sent.
+	Requests whose endpoint has no schema in the spec are sent without
+	validation, with a warning.
+
+*-v, --verbose*
+	Increase the verbosity of log messages. Passing *-v* once sets the log level
+	to _info_ and passing it twice (*-vv*) sets it to _debug_, overriding what
+	is set in the config file. *--verbose=*_n_ sets the number of times it is
+	passed to _n_. *--verbose=true* and *--verbose=false* are also accepted, the
+	former meaning *-v* and the latter turning verbosity off.
 
 	Since the regular log message format is configurable, regular logs only get
 	printed after the configuration is merged (see *CONFIGURATION*). This can
-	make it tough to debug early configuration merge issues. This flag prints
-	early debug messages to help this purpose.
+	make it tough to debug early configuration merge issues. This flag also
+	prints early debug messages to help this purpose.
+
+# OUTPUT
+
+*ochami* prints the data a command produces (e.g. the response from a service)
+to standard output and all diagnostics (log messages, warnings, errors, and
+prompts) to standard error, so that the output of commands can be reliably
+piped to other programs. Use *-q* to suppress all diagnostics but errors, or
+use *-v* or *-vv* to see more of them.
+
+Validation and diagnostic commands accept *--report-format* _junit_ or _sarif_
+to output their results as a JUnit XML or SARIF 2.1.0 report, which CI systems
+can present natively (e.g. as test results or code scanning alerts in merge
+requests). The report replaces the command's normal output on standard output,
+unless *--report-file* _path_ is passed to write it to _path_ instead. The exit
+status of the command is unchanged.
+
+When standard output is a terminal and the output of a command does not fit on
+the screen, it is paged through the pager in the *OCHAMI_PAGER* environment
+variable or, if unset, *PAGER*, defaulting to *less*(1). Like *git*(1),
+*LESS* is set to _FRX_ if unset. Setting the pager to an empty string or _cat_,
+passing *--no-pager*, or setting *pager* to _false_ in the config file (see
+*ochami-config*(5)) disables paging. Commands that stream output, such as
+*events tail*, are never paged. Output to files or pipes is never paged.
+
+# ANNOTATIONS
+
+Commands that create or change SMD groups, SMD redfish endpoints, or cloud-init
+groups stamp them with who made the change, when, and why, so that it can be
+traced later. Since these records have no fields for this, the annotation is
+appended to the description of groups and to the name of redfish endpoints,
+e.g.:
+
+```
+Compute nodes [ochami: by=alice at=2026-01-02T15:04:05Z comment="rack 3"]
+```
+
+_by_ is the user passed with *--created-by*, *--as*, or the local user, _at_ is
+the time of the change in UTC, and _comment_ is passed with *--comment*, if any.
+An annotation replaces any annotation the record already has. Annotations are
+added by *smd group add*, *smd group clone*, *smd rfe add*, *discover static*,
+*discover magellan*, *discover append*, *discover apply*, *cloud-init group
+add*, and *cloud-init group set* unless *--no-annotate* is passed. Backups are
+restored as they were taken.
+
+Annotations are shown separately from the description in the output of
+*cloud-init group list*, and *smd group get* and *smd rfe get* print only the
+annotations of the records with *--annotations*.
+
+# NOTIFICATIONS
+
+Long-running commands notify the sinks in *notify.sinks* of the cluster (see
+*ochami-config*(5)) when they exit, whether they finished or failed, so that
+operators need not watch their terminals. These are *node reimage*, *pcs power
+off --graceful-then-force*, *pcs transition monitor*, and *run*, which passes
+*--no-notify* to the operations it runs. A command notifies once it starts
+acting on its targets, so mistakes in its flags do not notify anyone.
+
+Each notification says which command ran against which cluster, by whom, for
+how long, and whether it succeeded, along with a summary of the outcome (e.g.
+_failed to reimage 2 of 64 node(s) with image compute-v2_) or, if the command
+failed, its last error. The report the command printed, such as the roster of
+*node reimage*, is attached. The supported sinks are:
+
+- _webhook_: the notification is POSTed to a URL as JSON, with the report
+  under _report_
+- _slack_: a message is POSTed to a Slack-compatible incoming webhook (e.g.
+  Slack or Mattermost), with the beginning of the report in a code block
+- _email_: an email is sent with *sendmail*(8), with the report attached as
+  _report.json_
+
+Failing to notify a sink is logged as a warning and does not change the exit
+status of the command.
+
+# TARGETS
+
+Commands that act on components accept *--target* _target_,... to identify
+them with any mix of identifiers, each of the form [_kind_:]_value_:
+
+- _xname_:_xname_ (or a bare xname), e.g. _x3000c0s0b0n0_
+- _nid_:_nid_, e.g. _nid:42_
+- _mac_:_mac_ (or a bare MAC address), e.g. _mac:00:de:ad:be:ef:00_
+- _group_:_group_, the members of an SMD group, e.g. _group:compute_
+
+*--target* can be passed more than once. Each target is translated to the
+identifier the service behind the command expects. Services that accept the
+identifier natively get it as is (e.g. BSS accepts xnames, NIDs, and MAC
+addresses), while the rest get xnames looked up in SMD. It is an error for a
+NID, MAC address, or group to not be found in SMD.
+
+Bulk commands (*node reimage*, *pcs power off*, *pcs power status*, *pcs
+transition start*, and *smd component wait*) also accept *--targets-from*
+_path_ to target the components of a report printed by an earlier run of one
+of them, in JSON or YAML, so that a failed run can be retried on exactly the
+components it failed on. The report is either a list of items or an object
+with its items under _results_, and each item needs an _xname_. *--only*
+_status_,... limits the components to the items with one of these statuses
+(the _status_ or _outcome_ of the item, e.g. _timed-out_ or _forced_), and
+accepts two classes of items as well:
+
+- _failed_: items with an _error_, the status _failed_, _error_, _timed-out_,
+  or _skipped_, or _reached_ or _found_ set to false
+- _succeeded_: all other items
+
+If no item of the report matches and no other targets were passed, there is
+nothing to do and the command exits successfully. For example, to reimage
+again the nodes that did not boot:
+
+```
+ochami node reimage --image compute --manifest compute.yaml --reboot --track \
+	--group compute -F json > roster.json
+ochami node reimage --image compute --manifest compute.yaml --reboot --track \
+	--targets-from roster.json --only failed
+```
+
+# DEPRECATIONS
+
+When a command or flag is renamed or moved, its old name keeps working for at
+least one release so that scripts using it do not break on upgrade. Using an old
+name logs a warning to standard error naming the new command or flag and the
+release the old name will be removed in. Old flag names are hidden from help
+output.
+Scripts should be updated to use the new names when this warning is seen.
+
+The following old names are currently deprecated:
+
+- *smd status*, which is now *smd service status* and will be removed in
+  v1.0.0.
 
 # CONFIGURATION
 
@@ -179,8 +446,12 @@ Written by Devon T. Bautista and maintained by the OpenCHAMI developers.
 
 # SEE ALSO
 
-*ochami-bss*(1), *ochami-cloud-init*(1), *ochami-config*(1),
-*ochami-discover*(1), *ochami-smd*(1), *ochami-config*(5)
+*ochami-agent*(1), *ochami-auth*(1), *ochami-backup*(1), *ochami-bss*(1),
+*ochami-cloud-init*(1), *ochami-config*(1), *ochami-convert*(1),
+*ochami-discover*(1), *ochami-events*(1), *ochami-export*(1), *ochami-fsck*(1),
+*ochami-image*(1), *ochami-ipam*(1), *ochami-meta*(1), *ochami-node*(1),
+*ochami-redact*(1), *ochami-run*(1), *ochami-smd*(1), *ochami-vars*(1),
+*ochami-config*(5)
 
 ; Vim modeline settings
 ; vim: set tw=80 noet sts=4 ts=4 sw=4 syntax=scdoc:
@@ -0,0 +1,321 @@
+// Package authcheck checks which operations an access token is authorized for
+// on the OpenCHAMI services, from the scopes of the token and from test
+// requests that cannot modify data, so that missing privileges are found before
+// a workflow fails partway through.
+package authcheck
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwt"
+
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/client/ci"
+	"github.com/OpenCHAMI/ochami/pkg/client/pcs"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// Operations that are checked for each service.
+const (
+	OpRead  = "read"
+	OpWrite = "write"
+)
+
+// Results of a check.
+const (
+	ResultAllowed = "allowed"
+	ResultDenied  = "denied"
+	ResultUnknown = "unknown"
+)
+
+// Scopes of the token for an operation. ScopeNone means the token has no
+// scopes, so whether the operation is in scope is not known.
+const (
+	ScopeGranted = "granted"
+	ScopeMissing = "missing"
+	ScopeNone    = ""
+)
+
+// invalidBody is the payload of write probes. It is not valid JSON, so that
+// authorized requests are rejected when the payload is parsed, before anything
+// is written.
+var invalidBody = client.HTTPBody("{")
+
+// probeName is the name, invalid for any real record, that read probes filter
+// by to keep responses small.
+const probeName = "ochami-auth-check"
+
+// Probe is a test request for an operation on a service. Read probes are GETs
+// that only return little data, and write probes send a payload that is not
+// valid JSON to an endpoint that creates records, so that they are rejected
+// after authorization but before they can modify data. Statuses in Allowed are
+// successful responses of the probe besides 2XX (e.g. 404 for a filter that
+// matches nothing).
+type Probe struct {
+	Service   string
+	Operation string
+	Method    string
+	Endpoint  string
+	Query     string
+	Body      client.HTTPBody
+	Allowed   []int
+}
+
+// Probes are the probes for each service, keyed by the name of the service
+// (see Services).
+var Probes = map[string][]Probe{
+	"smd": {
+		{Service: "smd", Operation: OpRead, Method: http.MethodGet, Endpoint: smd.SMDRelpathGroups, Query: "group=" + probeName},
+		{Service: "smd", Operation: OpWrite, Method: http.MethodPost, Endpoint: smd.SMDRelpathComponents, Body: invalidBody},
+	},
+	"bss": {
+		{Service: "bss", Operation: OpRead, Method: http.MethodGet, Endpoint: bss.BSSRelpathBootParams, Query: "name=" + probeName, Allowed: []int{http.StatusNotFound}},
+		{Service: "bss", Operation: OpWrite, Method: http.MethodPost, Endpoint: bss.BSSRelpathBootParams, Body: invalidBody},
+	},
+	"cloud-init": {
+		{Service: "cloud-init", Operation: OpRead, Method: http.MethodGet, Endpoint: ci.CloudInitRelpathGroups},
+		{Service: "cloud-init", Operation: OpWrite, Method: http.MethodPost, Endpoint: ci.CloudInitRelpathGroups, Body: invalidBody},
+	},
+	"pcs": {
+		{Service: "pcs", Operation: OpRead, Method: http.MethodGet, Endpoint: pcs.PCSTransitions},
+		{Service: "pcs", Operation: OpWrite, Method: http.MethodPost, Endpoint: pcs.PCSTransitions, Body: invalidBody},
+	},
+}
+
+// Services are the services that are checked, in order.
+var Services = []string{"smd", "bss", "cloud-init", "pcs"}
+
+// Token is what the claims of an access token say about it.
+type Token struct {
+	Subject string     `json:"subject,omitempty" yaml:"subject,omitempty"`
+	Issuer  string     `json:"issuer,omitempty" yaml:"issuer,omitempty"`
+	Expires *time.Time `json:"expires,omitempty" yaml:"expires,omitempty"`
+	Scopes  []string   `json:"scopes,omitempty" yaml:"scopes,omitempty"`
+}
+
+// ParseToken returns the claims of JWT token, without verifying it. Scopes are
+// taken from the scope claim (a space-separated string, as in OAuth 2.0), or
+// the scp or scopes claim (a string or a list).
+func ParseToken(token string) (Token, error) {
+	t, err := jwt.ParseString(token, jwt.WithValidate(false))
+	if err != nil {
+		return Token{}, fmt.Errorf("failed to parse token: %w", err)
+	}
+
+	tok := Token{Subject: t.Subject(), Issuer: t.Issuer()}
+	if exp := t.Expiration(); !exp.IsZero() {
+		tok.Expires = &exp
+	}
+	for _, claim := range []string{"scope", "scp", "scopes"} {
+		v, ok := t.Get(claim)
+		if !ok {
+			continue
+		}
+		switch s := v.(type) {
+		case string:
+			tok.Scopes = strings.Fields(s)
+		case []any:
+			for _, e := range s {
+				tok.Scopes = append(tok.Scopes, fmt.Sprint(e))
+			}
+		case []string:
+			tok.Scopes = s
+		}
+		break
+	}
+
+	return tok, nil
+}
+
+// ScopeGrants returns whether scopes include one that grants operation op on
+// service. Scopes name an operation (e.g. write), the service or an operation on
+// it (e.g. smd, smd.write, smd:write, or smd/write), or admin, on their own or
+// for the service (e.g. smd.admin). Scopes that grant writing also grant
+// reading.
+func ScopeGrants(scopes []string, service, op string) bool {
+	ops := []string{op, "admin"}
+	if op == OpRead {
+		ops = append(ops, OpWrite)
+	}
+	for _, s := range scopes {
+		s = strings.ToLower(s)
+		if s == service {
+			return true
+		}
+		for _, o := range ops {
+			if s == o || s == service+"."+o || s == service+":"+o || s == service+"/"+o {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Check is the result of checking an operation on a service. Scope is whether
+// the scopes of the token grant it (see ScopeGrants), and Status is the status
+// of the response to its probe, if one was received.
+type Check struct {
+	Service   string `json:"service" yaml:"service"`
+	Operation string `json:"operation" yaml:"operation"`
+	Result    string `json:"result" yaml:"result"`
+	Scope     string `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Status    int    `json:"status,omitempty" yaml:"status,omitempty"`
+	Detail    string `json:"detail,omitempty" yaml:"detail,omitempty"`
+}
+
+// Report is the result of checking a token.
+type Report struct {
+	Token  *Token  `json:"token,omitempty" yaml:"token,omitempty"`
+	Checks []Check `json:"checks" yaml:"checks"`
+}
+
+// Failed returns the checks whose operations are not known to be allowed.
+func (r Report) Failed() []Check {
+	var failed []Check
+	for _, c := range r.Checks {
+		if c.Result != ResultAllowed {
+			failed = append(failed, c)
+		}
+	}
+
+	return failed
+}
+
+// Run sends probe p with oc, authenticated with token unless it is empty, and
+// returns the result. Requests rejected with 401 or 403 are denied. Any other
+// response shows that the request was authorized, except 404 and 405 (unless
+// the probe allows them), which can mean the endpoint does not exist, and 5XX,
+// which mean the service failed. Payloads of probes are not validated, since
+// those of write probes are invalid on purpose.
+func Run(oc *client.OchamiClient, p Probe, token string) Check {
+	c := Check{Service: p.Service, Operation: p.Operation, Result: ResultUnknown}
+
+	headers := client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			c.Detail = fmt.Sprintf("failed to set token: %v", err)
+			return c
+		}
+	}
+	if len(p.Body) > 0 {
+		if err := headers.SetContentType("application/json"); err != nil {
+			c.Detail = fmt.Sprintf("failed to set content type: %v", err)
+			return c
+		}
+	}
+	noValidate := *oc
+	noValidate.ValidatePayload = nil
+	res, err := noValidate.MakeOchamiRequest(p.Method, p.Endpoint, p.Query, headers, p.Body)
+	if err != nil {
+		if errors.Is(err, client.ReadOnlyError) {
+			c.Detail = "not sent in read-only mode"
+		} else {
+			c.Detail = err.Error()
+		}
+		return c
+	}
+	henv, err := client.NewHTTPEnvelopeFromResponse(res)
+	if err != nil {
+		c.Detail = err.Error()
+		return c
+	}
+	c.Status = henv.StatusCode
+
+	switch code := henv.StatusCode; {
+	case code >= 200 && code < 300, slices.Contains(p.Allowed, code):
+		c.Result = ResultAllowed
+	case code == http.StatusUnauthorized:
+		c.Result = ResultDenied
+		c.Detail = "token rejected"
+	case code == http.StatusForbidden:
+		c.Result = ResultDenied
+		c.Detail = "token not authorized"
+	case code == http.StatusNotFound, code == http.StatusMethodNotAllowed:
+		c.Detail = fmt.Sprintf("%s %s not found", p.Method, p.Endpoint)
+	case code >= 500:
+		c.Detail = "service error"
+	default:
+		c.Result = ResultAllowed
+		if p.Operation == OpWrite {
+			c.Detail = "invalid test payload rejected after authorization"
+		}
+	}
+
+	return c
+}
+
+// ParseOnly parses the operations to check, each a service (for all of its
+// operations) or a service and an operation separated by a colon (e.g.
+// smd:write), into the probes to run, in the order of Services. If only is
+// empty, all probes are returned.
+func ParseOnly(only []string) ([]Probe, error) {
+	want := make(map[string]bool)
+	for _, o := range only {
+		svc, op, hasOp := strings.Cut(o, ":")
+		if _, ok := Probes[svc]; !ok {
+			return nil, fmt.Errorf("unknown service %q (expected one of %s)", svc, strings.Join(Services, ", "))
+		}
+		if !hasOp {
+			want[svc+":"+OpRead] = true
+			want[svc+":"+OpWrite] = true
+			continue
+		}
+		if op != OpRead && op != OpWrite {
+			return nil, fmt.Errorf("unknown operation %q for %s (expected %s or %s)", op, svc, OpRead, OpWrite)
+		}
+		want[o] = true
+	}
+
+	var probes []Probe
+	for _, svc := range Services {
+		for _, p := range Probes[svc] {
+			if len(want) == 0 || want[p.Service+":"+p.Operation] {
+				probes = append(probes, p)
+			}
+		}
+	}
+
+	return probes, nil
+}
+
+// CheckToken runs probes with the clients of their services, keyed by service
+// name, authenticated with token unless it is empty, and returns the report.
+// Probes of services without a client are not sent and their result is
+// unknown. If token is a JWT, its claims are included in the report and the
+// scope of each check is set from them.
+func CheckToken(clients map[string]*client.OchamiClient, probes []Probe, token string) Report {
+	var (
+		r   Report
+		tok Token
+	)
+	if token != "" {
+		if t, err := ParseToken(token); err == nil {
+			tok = t
+			r.Token = &tok
+		}
+	}
+	for _, p := range probes {
+		var c Check
+		if oc := clients[p.Service]; oc != nil {
+			c = Run(oc, p, token)
+		} else {
+			c = Check{Service: p.Service, Operation: p.Operation, Result: ResultUnknown, Detail: "service not configured"}
+		}
+		if len(tok.Scopes) > 0 {
+			c.Scope = ScopeMissing
+			if ScopeGrants(tok.Scopes, p.Service, p.Operation) {
+				c.Scope = ScopeGranted
+			}
+		}
+		r.Checks = append(r.Checks, c)
+	}
+
+	return r
+}
@@ -0,0 +1,156 @@
+package authcheck
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/jwa"
+	"github.com/lestrrat-go/jwx/jwt"
+
+	"github.com/OpenCHAMI/ochami/pkg/client"
+)
+
+// testJWT returns a JWT with the claims in claims.
+func testJWT(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	jt := jwt.New()
+	for k, v := range claims {
+		if err := jt.Set(k, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := jwt.Sign(jt, jwa.HS256, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(b)
+}
+
+func TestParseToken(t *testing.T) {
+	exp := time.Now().Add(time.Hour).Truncate(time.Second)
+	tok, err := ParseToken(testJWT(t, map[string]any{"sub": "alice", jwt.ExpirationKey: exp, "scope": "smd.read bss.write"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Subject != "alice" || tok.Expires == nil || !tok.Expires.Equal(exp) || !reflect.DeepEqual(tok.Scopes, []string{"smd.read", "bss.write"}) {
+		t.Errorf("ParseToken() = %+v", tok)
+	}
+	if tok, err = ParseToken(testJWT(t, map[string]any{"scp": []string{"read"}})); err != nil || !reflect.DeepEqual(tok.Scopes, []string{"read"}) {
+		t.Errorf("ParseToken() with scp = %+v, %v", tok, err)
+	}
+	if _, err := ParseToken("opaque"); err == nil {
+		t.Error("ParseToken(opaque) succeeded, want error")
+	}
+}
+
+func TestScopeGrants(t *testing.T) {
+	for _, tt := range []struct {
+		scopes      []string
+		service, op string
+		want        bool
+	}{
+		{[]string{"smd.read"}, "smd", OpRead, true},
+		{[]string{"smd.read"}, "smd", OpWrite, false},
+		{[]string{"smd:write"}, "smd", OpRead, true},
+		{[]string{"bss.write"}, "smd", OpWrite, false},
+		{[]string{"write"}, "pcs", OpWrite, true},
+		{[]string{"Admin"}, "bss", OpWrite, true},
+		{[]string{"cloud-init"}, "cloud-init", OpWrite, true},
+	} {
+		if got := ScopeGrants(tt.scopes, tt.service, tt.op); got != tt.want {
+			t.Errorf("ScopeGrants(%v, %s, %s) = %t, want %t", tt.scopes, tt.service, tt.op, got, tt.want)
+		}
+	}
+}
+
+func TestParseOnly(t *testing.T) {
+	probes, err := ParseOnly([]string{"pcs", "smd:write"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range probes {
+		got = append(got, p.Service+":"+p.Operation)
+	}
+	if want := []string{"smd:write", "pcs:read", "pcs:write"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseOnly() = %v, want %v", got, want)
+	}
+	if probes, _ := ParseOnly(nil); len(probes) != 2*len(Services) {
+		t.Errorf("ParseOnly(nil) = %d probes, want %d", len(probes), 2*len(Services))
+	}
+	for _, bad := range []string{"foo", "smd:delete"} {
+		if _, err := ParseOnly([]string{bad}); err == nil {
+			t.Errorf("ParseOnly(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestCheckToken(t *testing.T) {
+	token := testJWT(t, map[string]any{"scope": "smd.read"})
+
+	// Reads are allowed and writes are forbidden, except that BSS has no
+	// boot parameters
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method != http.MethodGet:
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/boot/v1/bootparameters":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.Write([]byte("[]"))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	newClient := func(path string) *client.OchamiClient {
+		oc, err := client.NewOchamiClient("test", srv.URL+path, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return oc
+	}
+	readOnly := newClient("/boot/v1")
+	readOnly.ReadOnly = true
+	clients := map[string]*client.OchamiClient{
+		"smd": newClient("/hsm/v2"),
+		"bss": readOnly,
+	}
+
+	probes, _ := ParseOnly([]string{"smd", "bss", "pcs:read"})
+	r := CheckToken(clients, probes, token)
+	want := []Check{
+		{Service: "smd", Operation: OpRead, Result: ResultAllowed, Scope: ScopeGranted, Status: http.StatusOK},
+		{Service: "smd", Operation: OpWrite, Result: ResultDenied, Scope: ScopeMissing, Status: http.StatusForbidden, Detail: "token not authorized"},
+		{Service: "bss", Operation: OpRead, Result: ResultAllowed, Scope: ScopeMissing, Status: http.StatusNotFound},
+		{Service: "bss", Operation: OpWrite, Result: ResultUnknown, Scope: ScopeMissing, Detail: "not sent in read-only mode"},
+		{Service: "pcs", Operation: OpRead, Result: ResultUnknown, Scope: ScopeMissing, Detail: "service not configured"},
+	}
+	if !reflect.DeepEqual(r.Checks, want) {
+		t.Errorf("CheckToken() checks = %+v, want %+v", r.Checks, want)
+	}
+	if r.Token == nil || !reflect.DeepEqual(r.Token.Scopes, []string{"smd.read"}) {
+		t.Errorf("CheckToken() token = %+v", r.Token)
+	}
+	if len(r.Failed()) != 3 {
+		t.Errorf("Failed() = %+v, want 3 checks", r.Failed())
+	}
+
+	// Rejected tokens are denied, and invalid payloads rejected after
+	// authorization are allowed
+	if c := Run(clients["smd"], Probes["smd"][0], "other"); c.Result != ResultDenied || c.Status != http.StatusUnauthorized {
+		t.Errorf("Run() with other token = %+v, want denied", c)
+	}
+	srv.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	if c := Run(clients["smd"], Probes["smd"][1], token); c.Result != ResultAllowed || c.Status != http.StatusBadRequest {
+		t.Errorf("Run() of write probe rejected with 400 = %+v, want allowed", c)
+	}
+}
@@ -0,0 +1,387 @@
+// Package backup reads and writes cluster backup archives. A backup archive is
+// a tar archive, optionally compressed, holding a manifest followed by one JSON
+// file per dataset captured from the OpenCHAMI services.
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ManifestVersion is the version of the manifest written by Write. Read
+// refuses archives with a newer manifest version.
+const ManifestVersion = 1
+
+// ManifestName is the name of the manifest within the archive.
+const ManifestName = "manifest.json"
+
+// Services whose data can be captured in a backup.
+const (
+	ServiceSMD       = "smd"
+	ServiceBSS       = "bss"
+	ServiceCloudInit = "cloud-init"
+)
+
+// Services lists all services whose data can be captured in a backup, in the
+// order they are restored.
+var Services = []string{ServiceSMD, ServiceBSS, ServiceCloudInit}
+
+// Names of the dataset files within the archive.
+const (
+	FileSMDComponents         = "smd/components.json"
+	FileSMDRedfishEndpoints   = "smd/redfish-endpoints.json"
+	FileSMDEthernetInterfaces = "smd/ethernet-interfaces.json"
+	FileSMDGroups             = "smd/groups.json"
+	FileBSSBootParams         = "bss/boot-parameters.json"
+	FileCloudInitDefaults     = "cloud-init/defaults.json"
+	FileCloudInitGroups       = "cloud-init/groups.json"
+)
+
+// Compression formats of backup archives.
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// Manifest describes the contents of a backup archive.
+type Manifest struct {
+	Version       int                `json:"version" yaml:"version"`
+	Created       time.Time          `json:"created" yaml:"created"`
+	OchamiVersion string             `json:"ochami_version" yaml:"ochami_version"`
+	Cluster       string             `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Services      map[string]Service `json:"services" yaml:"services"`
+}
+
+// Service describes the data captured from one service. Version is the version
+// information reported by the service, if it reports any.
+type Service struct {
+	Version any      `json:"version,omitempty" yaml:"version,omitempty"`
+	Files   []string `json:"files" yaml:"files"`
+}
+
+// Backup is the manifest and dataset files of a backup archive. The contents of
+// dataset files are either held in Files or, for dataset files added with
+// AddSource, read from Sources when the backup is written.
+type Backup struct {
+	Manifest Manifest
+	Files    map[string][]byte
+	Sources  map[string]Source
+}
+
+// Source is the contents of a dataset file that are not held in memory, such
+// as a response body spilled to a temporary file (see client.StreamBody).
+// Reader must return a reader of the Size bytes of the contents from the
+// start.
+type Source interface {
+	Size() int64
+	Reader() (io.Reader, error)
+}
+
+// New returns an empty Backup for cluster created at created by the ochami
+// version ochamiVersion.
+func New(cluster, ochamiVersion string, created time.Time) Backup {
+	return Backup{
+		Manifest: Manifest{
+			Version:       ManifestVersion,
+			Created:       created.UTC(),
+			OchamiVersion: ochamiVersion,
+			Cluster:       cluster,
+			Services:      make(map[string]Service),
+		},
+		Files:   make(map[string][]byte),
+		Sources: make(map[string]Source),
+	}
+}
+
+// SetVersion records version as the version information reported by service.
+// If version is not JSON, it is recorded as a string.
+func (b *Backup) SetVersion(service string, version []byte) {
+	svc := b.Manifest.Services[service]
+	if err := json.Unmarshal(version, &svc.Version); err != nil {
+		svc.Version = string(bytes.TrimSpace(version))
+	}
+	b.Manifest.Services[service] = svc
+}
+
+// Add adds the dataset file name captured from service to b, replacing any
+// file with the same name.
+func (b *Backup) Add(service, name string, data []byte) {
+	b.addName(service, name)
+	delete(b.Sources, name)
+	b.Files[name] = data
+}
+
+// AddSource is like Add, but the contents of the dataset file are read from src
+// when b is written instead of being held in memory. src must remain readable
+// until then.
+func (b *Backup) AddSource(service, name string, src Source) {
+	b.addName(service, name)
+	delete(b.Files, name)
+	if b.Sources == nil {
+		b.Sources = make(map[string]Source)
+	}
+	b.Sources[name] = src
+}
+
+// addName lists the dataset file name in the manifest as captured from service
+// if it is not already.
+func (b *Backup) addName(service, name string) {
+	svc := b.Manifest.Services[service]
+	_, inFiles := b.Files[name]
+	_, inSources := b.Sources[name]
+	if !inFiles && !inSources {
+		svc.Files = append(svc.Files, name)
+	}
+	b.Manifest.Services[service] = svc
+}
+
+// Len returns the number of dataset files in b.
+func (b Backup) Len() int {
+	return len(b.Files) + len(b.Sources)
+}
+
+// File returns the contents of the dataset file name and whether b has it.
+func (b Backup) File(name string) ([]byte, bool) {
+	data, ok := b.Files[name]
+	return data, ok
+}
+
+// Write writes b to w as an uncompressed tar archive. The manifest is written
+// first so that it can be read without reading the whole archive.
+func Write(w io.Writer, b Backup) error {
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	tw := tar.NewWriter(w)
+	writeFile := func(name string, size int64, r io.Reader) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    size,
+			ModTime: b.Manifest.Created,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write header for %s: %w", name, err)
+		}
+		if _, err := io.Copy(tw, r); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		return nil
+	}
+	if err := writeFile(ManifestName, int64(len(manifest)), bytes.NewReader(manifest)); err != nil {
+		return err
+	}
+	names := make([]string, 0, b.Len())
+	for name := range b.Files {
+		names = append(names, name)
+	}
+	for name := range b.Sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if data, ok := b.Files[name]; ok {
+			if err := writeFile(name, int64(len(data)), bytes.NewReader(data)); err != nil {
+				return err
+			}
+			continue
+		}
+		src := b.Sources[name]
+		r, err := src.Reader()
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if err := writeFile(name, src.Size(), r); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	return nil
+}
+
+// Read reads a backup from the uncompressed tar archive in r. An error is
+// returned if the archive has no manifest, has a manifest version newer than
+// ManifestVersion, or is missing a file listed in the manifest.
+func Read(r io.Reader) (Backup, error) {
+	var (
+		b           = Backup{Files: make(map[string][]byte), Sources: make(map[string]Source)}
+		hasManifest bool
+	)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return b, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return b, fmt.Errorf("failed to read %s from archive: %w", hdr.Name, err)
+		}
+		name := path.Clean(hdr.Name)
+		if name == ManifestName {
+			if err := json.Unmarshal(data, &b.Manifest); err != nil {
+				return b, fmt.Errorf("failed to unmarshal manifest: %w", err)
+			}
+			hasManifest = true
+			continue
+		}
+		b.Files[name] = data
+	}
+	if !hasManifest {
+		return b, fmt.Errorf("archive has no %s, is it a backup?", ManifestName)
+	}
+	if b.Manifest.Version > ManifestVersion {
+		return b, fmt.Errorf("backup manifest version %d is newer than the supported version %d", b.Manifest.Version, ManifestVersion)
+	}
+	for service, svc := range b.Manifest.Services {
+		for _, name := range svc.Files {
+			if _, ok := b.Files[name]; !ok {
+				return b, fmt.Errorf("archive is missing %s file %s listed in manifest", service, name)
+			}
+		}
+	}
+
+	return b, nil
+}
+
+// Compression returns the compression format of a backup archive named name,
+// determined by its extension: .tar for none, .tar.gz or .tgz for gzip, and
+// .tar.zst or .tzst for zstd.
+func Compression(name string) (string, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".tar"):
+		return CompressionNone, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return CompressionGzip, nil
+	case strings.HasSuffix(lower, ".tar.zst"), strings.HasSuffix(lower, ".tzst"):
+		return CompressionZstd, nil
+	default:
+		return "", fmt.Errorf("unknown backup archive extension for %q (must be .tar, .tar.gz, .tgz, .tar.zst, or .tzst)", name)
+	}
+}
+
+// NewWriter returns a writer that compresses what is written to it into w with
+// the compression format compression. The writer must be closed to flush the
+// compressed data. Since the Go standard library has no zstd implementation,
+// zstd compression is done by the zstd program, which must be in PATH.
+func NewWriter(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		c := exec.Command("zstd", "-q", "-c")
+		c.Stdout = w
+		return startPipe(c, true)
+	default:
+		return nil, fmt.Errorf("unknown compression format %q", compression)
+	}
+}
+
+// NewReader returns a reader that decompresses r with the compression format
+// compression. The reader must be closed when done. Like NewWriter, zstd
+// decompression requires the zstd program.
+func NewReader(r io.Reader, compression string) (io.ReadCloser, error) {
+	switch compression {
+	case CompressionNone:
+		return io.NopCloser(r), nil
+	case CompressionGzip:
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read gzip data: %w", err)
+		}
+		return gr, nil
+	case CompressionZstd:
+		c := exec.Command("zstd", "-d", "-q", "-c")
+		c.Stdin = r
+		return startPipe(c, false)
+	default:
+		return nil, fmt.Errorf("unknown compression format %q", compression)
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// cmdPipe is the standard input (when writing) or standard output (when
+// reading) of a running compression program. Closing it waits for the program
+// to exit.
+type cmdPipe struct {
+	cmd    *exec.Cmd
+	w      io.WriteCloser
+	r      io.ReadCloser
+	stderr bytes.Buffer
+}
+
+// startPipe starts c, connecting the returned pipe to its standard input if
+// write is true or to its standard output otherwise.
+func startPipe(c *exec.Cmd, write bool) (*cmdPipe, error) {
+	p := &cmdPipe{cmd: c}
+	c.Stderr = &p.stderr
+	var err error
+	if write {
+		p.w, err = c.StdinPipe()
+	} else {
+		p.r, err = c.StdoutPipe()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pipe to %s: %w", c.Path, err)
+	}
+	if err := c.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("zstd compression requires the zstd program, which was not found in PATH (use a .tar.gz archive instead): %w", err)
+		}
+		return nil, fmt.Errorf("failed to start %s: %w", c.Path, err)
+	}
+
+	return p, nil
+}
+
+func (p *cmdPipe) Write(b []byte) (int, error) { return p.w.Write(b) }
+
+func (p *cmdPipe) Read(b []byte) (int, error) { return p.r.Read(b) }
+
+func (p *cmdPipe) Close() error {
+	if p.w != nil {
+		if err := p.w.Close(); err != nil {
+			return fmt.Errorf("failed to close pipe to %s: %w", p.cmd.Path, err)
+		}
+	} else {
+		// Drain any trailing output so that the program does not
+		// fail writing to a closed pipe
+		io.Copy(io.Discard, p.r)
+	}
+	if err := p.cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(p.stderr.String()); msg != "" {
+			return fmt.Errorf("%s failed: %w: %s", p.cmd.Path, err, msg)
+		}
+		return fmt.Errorf("%s failed: %w", p.cmd.Path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"bytes"
+	"io"
+	"os/exec"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteRead(t *testing.T) {
+	b := New("foobar", "v0.5.0", time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC))
+	b.SetVersion(ServiceBSS, []byte(`{"bss-version":"v1.2.3"}`+"\n"))
+	b.SetVersion(ServiceCloudInit, []byte("v0.2.0"))
+	b.Add(ServiceSMD, FileSMDComponents, []byte(`{"Components":[]}`))
+	b.Add(ServiceBSS, FileBSSBootParams, []byte(`[]`))
+
+	for _, compression := range []string{CompressionNone, CompressionGzip, CompressionZstd} {
+		t.Run("compression="+compression, func(t *testing.T) {
+			if compression == CompressionZstd {
+				if _, err := exec.LookPath("zstd"); err != nil {
+					t.Skip("zstd program not found in PATH")
+				}
+			}
+			var buf bytes.Buffer
+			w, err := NewWriter(&buf, compression)
+			if err != nil {
+				t.Fatalf("NewWriter() error = %v", err)
+			}
+			if err := Write(w, b); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+
+			r, err := NewReader(&buf, compression)
+			if err != nil {
+				t.Fatalf("NewReader() error = %v", err)
+			}
+			got, err := Read(r)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if err := r.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, b) {
+				t.Errorf("Read() = %+v, want %+v", got, b)
+			}
+		})
+	}
+}
+
+// bytesSource is a Source of in-memory data.
+type bytesSource []byte
+
+func (s bytesSource) Size() int64 { return int64(len(s)) }
+
+func (s bytesSource) Reader() (io.Reader, error) { return bytes.NewReader(s), nil }
+
+func TestWriteRead_source(t *testing.T) {
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	b := New("foobar", "v0.5.0", created)
+	b.Add(ServiceSMD, FileSMDGroups, []byte(`[]`))
+	b.AddSource(ServiceSMD, FileSMDComponents, bytesSource(`{"Components":[{"ID":"x3000c0s0b0n0"}]}`))
+	if b.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", b.Len())
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, b); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	// Sources are read back like any other file
+	want := New("foobar", "v0.5.0", created)
+	want.Add(ServiceSMD, FileSMDGroups, []byte(`[]`))
+	want.Add(ServiceSMD, FileSMDComponents, []byte(`{"Components":[{"ID":"x3000c0s0b0n0"}]}`))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRead_missingFile(t *testing.T) {
+	b := New("foobar", "v0.5.0", time.Now())
+	b.Add(ServiceSMD, FileSMDGroups, []byte(`[]`))
+	delete(b.Files, FileSMDGroups)
+
+	var buf bytes.Buffer
+	if err := Write(&buf, b); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := Read(&buf); err == nil {
+		t.Errorf("Read() succeeded, want error for file missing from archive")
+	}
+}
+
+func TestCompression(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{name: "backup.tar", want: CompressionNone},
+		{name: "backup.tar.gz", want: CompressionGzip},
+		{name: "BACKUP.TGZ", want: CompressionGzip},
+		{name: "backup.tar.zst", want: CompressionZstd},
+		{name: "backup.zip", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Compression(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compression(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Compression(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,285 @@
+// Package artifact verifies the boot image artifacts (kernels, initrds, and
+// root filesystems) referenced by an image manifest, so that broken image
+// pushes are caught before nodes try to boot from them.
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/OpenCHAMI/ochami/internal/version"
+	"github.com/OpenCHAMI/ochami/pkg/client"
+)
+
+const serviceNameArtifact = "artifact store"
+
+// Errors wrapped by the errors returned by Verify, classifying why an artifact
+// failed verification.
+var (
+	UnavailableError      = errors.New("artifact unavailable")
+	SizeMismatchError     = errors.New("size mismatch")
+	ChecksumMismatchError = errors.New("checksum mismatch")
+)
+
+// Kinds of artifacts of an Image.
+const (
+	KindKernel = "kernel"
+	KindInitrd = "initrd"
+	KindRootfs = "rootfs"
+)
+
+// Manifest lists the artifacts of one or more boot images.
+type Manifest struct {
+	Images []Image `json:"images" yaml:"images"`
+}
+
+// Image is a boot image made up of a kernel, an initrd, and a root filesystem,
+// any of which may be omitted. Params is the kernel command line to boot the
+// image with, if any.
+type Image struct {
+	Name   string    `json:"name" yaml:"name"`
+	Params string    `json:"params,omitempty" yaml:"params,omitempty"`
+	Kernel *Artifact `json:"kernel,omitempty" yaml:"kernel,omitempty"`
+	Initrd *Artifact `json:"initrd,omitempty" yaml:"initrd,omitempty"`
+	Rootfs *Artifact `json:"rootfs,omitempty" yaml:"rootfs,omitempty"`
+}
+
+// Artifact is a single file of a boot image. URI is an http, https, or file
+// URI. If Size is set, it must match the size of the artifact. If Checksum is
+// set, it has the form "<algorithm>:<hex digest>", where algorithm is sha256 or
+// sha512, and the artifact is downloaded to verify it.
+type Artifact struct {
+	URI      string `json:"uri" yaml:"uri"`
+	Size     int64  `json:"size,omitempty" yaml:"size,omitempty"`
+	Checksum string `json:"checksum,omitempty" yaml:"checksum,omitempty"`
+}
+
+// Ref is an artifact referenced by a Manifest, along with the name of its
+// image and its kind.
+type Ref struct {
+	Image string
+	Kind  string
+	Artifact
+}
+
+// Refs returns the artifacts referenced by m, in the order of their images and
+// then of kernel, initrd, and rootfs.
+func (m Manifest) Refs() []Ref {
+	var refs []Ref
+	for _, img := range m.Images {
+		for _, a := range []struct {
+			kind string
+			a    *Artifact
+		}{{KindKernel, img.Kernel}, {KindInitrd, img.Initrd}, {KindRootfs, img.Rootfs}} {
+			if a.a != nil {
+				refs = append(refs, Ref{Image: img.Name, Kind: a.kind, Artifact: *a.a})
+			}
+		}
+	}
+
+	return refs
+}
+
+// Result is what was found when verifying an artifact.
+type Result struct {
+	// Size is the size of the artifact, or -1 if the store did not report
+	// it.
+	Size int64
+	// Checksum is the checksum of the artifact in the same form as
+	// Artifact.Checksum, if it was verified.
+	Checksum string
+}
+
+// ArtifactClient is an OchamiClient that is configured to fetch artifacts from
+// artifact stores. Since artifacts are referenced by absolute URIs, it has no
+// base URI.
+type ArtifactClient struct {
+	*client.OchamiClient
+}
+
+// NewClient returns a pointer to a new ArtifactClient. If an error occurred
+// creating the embedded OchamiClient, it is returned. If insecure is true, TLS
+// certificates will not be verified.
+func NewClient(insecure bool) (*ArtifactClient, error) {
+	oc, err := client.NewOchamiClient(serviceNameArtifact, "", insecure)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OchamiClient for %s: %w", serviceNameArtifact, err)
+	}
+
+	return &ArtifactClient{OchamiClient: oc}, nil
+}
+
+// parseChecksum splits checksum into its algorithm and hex digest and returns
+// a new hash for the algorithm.
+func parseChecksum(checksum string) (hash.Hash, string, error) {
+	algo, digest, ok := strings.Cut(checksum, ":")
+	if !ok || digest == "" {
+		return nil, "", fmt.Errorf("invalid checksum %q: expected <algorithm>:<hex digest>", checksum)
+	}
+	switch strings.ToLower(algo) {
+	case "sha256":
+		return sha256.New(), strings.ToLower(digest), nil
+	case "sha512":
+		return sha512.New(), strings.ToLower(digest), nil
+	default:
+		return nil, "", fmt.Errorf("unsupported checksum algorithm %q (must be sha256 or sha512)", algo)
+	}
+}
+
+// Verify checks that a is available and, if set, that its size and checksum
+// match, returning what was found. If only availability and size are checked,
+// a HEAD request is made for HTTP(S) artifacts, falling back to GET if the
+// store does not support HEAD or does not report the size. Otherwise, the
+// artifact is downloaded and hashed without being stored. An error is returned
+// if the artifact is unavailable or does not match.
+func (ac *ArtifactClient) Verify(ctx context.Context, a Artifact) (Result, error) {
+	res := Result{Size: -1}
+	var (
+		h      hash.Hash
+		digest string
+		err    error
+	)
+	if a.Checksum != "" {
+		if h, digest, err = parseChecksum(a.Checksum); err != nil {
+			return res, err
+		}
+	}
+	u, err := url.Parse(a.URI)
+	if err != nil {
+		return res, fmt.Errorf("invalid URI %q: %w", a.URI, err)
+	}
+
+	var body io.ReadCloser
+	switch u.Scheme {
+	case "http", "https":
+		if h == nil {
+			size, supported, err := ac.head(ctx, a.URI)
+			if err != nil {
+				return res, err
+			}
+			if supported && (size >= 0 || a.Size == 0) {
+				res.Size = size
+				return res, checkSize(a, res.Size)
+			}
+		}
+		resp, err := ac.get(ctx, a.URI)
+		if err != nil {
+			return res, err
+		}
+		body = resp.Body
+		res.Size = resp.ContentLength
+	case "file":
+		f, err := os.Open(u.Path)
+		if err != nil {
+			return res, fmt.Errorf("%w: %w", UnavailableError, err)
+		}
+		fi, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return res, fmt.Errorf("%w: %w", UnavailableError, err)
+		}
+		body = f
+		res.Size = fi.Size()
+	default:
+		return res, fmt.Errorf("unsupported URI scheme %q (must be http, https, or file)", u.Scheme)
+	}
+	defer body.Close()
+
+	// Reject mismatched sizes before downloading anything
+	if res.Size >= 0 {
+		if err := checkSize(a, res.Size); err != nil {
+			return res, err
+		}
+	}
+	w := io.Discard
+	if h != nil {
+		w = h
+	}
+	n, err := io.Copy(w, body)
+	if err != nil {
+		return res, fmt.Errorf("%w: failed to read artifact: %w", UnavailableError, err)
+	}
+	res.Size = n
+	if err := checkSize(a, res.Size); err != nil {
+		return res, err
+	}
+	if h != nil {
+		got := hex.EncodeToString(h.Sum(nil))
+		algo, _, _ := strings.Cut(a.Checksum, ":")
+		res.Checksum = strings.ToLower(algo) + ":" + got
+		if got != digest {
+			return res, fmt.Errorf("%w: expected %s, got %s", ChecksumMismatchError, a.Checksum, res.Checksum)
+		}
+	}
+
+	return res, nil
+}
+
+// checkSize returns an error if a has a size that is not size.
+func checkSize(a Artifact, size int64) error {
+	if a.Size > 0 && size >= 0 && size != a.Size {
+		return fmt.Errorf("%w: expected %d bytes, got %d", SizeMismatchError, a.Size, size)
+	}
+	return nil
+}
+
+// head makes a HEAD request for uri and returns the size of the artifact, or
+// -1 if the store did not report it. If the store does not support HEAD
+// requests, false is returned.
+func (ac *ArtifactClient) head(ctx context.Context, uri string) (int64, bool, error) {
+	resp, err := ac.do(ctx, http.MethodHead, uri)
+	if err != nil {
+		return -1, false, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusMethodNotAllowed, resp.StatusCode == http.StatusNotImplemented:
+		return -1, false, nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return -1, false, fmt.Errorf("%w: %w: %s", UnavailableError, client.UnsuccessfulHTTPError, resp.Status)
+	}
+
+	return resp.ContentLength, true, nil
+}
+
+// get makes a GET request for uri and returns the response, whose body the
+// caller must close.
+func (ac *ArtifactClient) get(ctx context.Context, uri string) (*http.Response, error) {
+	resp, err := ac.do(ctx, http.MethodGet, uri)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %w: %s", UnavailableError, client.UnsuccessfulHTTPError, resp.Status)
+	}
+
+	return resp, nil
+}
+
+// do sends a request for uri with ctx. Unlike OchamiClient.MakeRequest, the
+// response body is not read, since artifacts can be large, and the request is
+// bounded by ctx instead of by Deadline.
+func (ac *ArtifactClient) do(ctx context.Context, method, uri string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new HTTP request: %w", err)
+	}
+	req.Header.Set("User-Agent", "ochami/"+version.Version)
+	resp, err := ac.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to execute HTTP request: %w", UnavailableError, err)
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,99 @@
+package artifact
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/OpenCHAMI/ochami/pkg/testutil"
+)
+
+func TestManifest_Refs(t *testing.T) {
+	m := Manifest{Images: []Image{
+		{Name: "compute", Kernel: &Artifact{URI: "k"}, Rootfs: &Artifact{URI: "r"}},
+		{Name: "login", Initrd: &Artifact{URI: "i"}},
+	}}
+	want := []Ref{
+		{Image: "compute", Kind: KindKernel, Artifact: Artifact{URI: "k"}},
+		{Image: "compute", Kind: KindRootfs, Artifact: Artifact{URI: "r"}},
+		{Image: "login", Kind: KindInitrd, Artifact: Artifact{URI: "i"}},
+	}
+	if got := m.Refs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Refs() = %v, want %v", got, want)
+	}
+}
+
+// errAny is a wantErr that matches any error.
+var errAny = errors.New("any error")
+
+func TestArtifactClient_Verify(t *testing.T) {
+	content := []byte("vmlinuz contents")
+	sum := sha256.Sum256(content)
+	checksum := "sha256:" + hex.EncodeToString(sum[:])
+
+	fs := testutil.NewFakeServer(t, "")
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		if r.Method == http.MethodGet {
+			w.Write(content)
+		}
+	}
+	fs.Handle("HEAD /vmlinuz", serve)
+	fs.Handle("GET /vmlinuz", serve)
+	fs.Handle("HEAD /nohead", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	})
+	fs.Handle("GET /nohead", serve)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "initrd.img")
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ac, err := NewClient(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		a       Artifact
+		want    Result
+		wantErr error
+	}{
+		{name: "available", a: Artifact{URI: fs.URL() + "/vmlinuz"}, want: Result{Size: int64(len(content))}},
+		{name: "size", a: Artifact{URI: fs.URL() + "/vmlinuz", Size: int64(len(content))}, want: Result{Size: int64(len(content))}},
+		{name: "size mismatch", a: Artifact{URI: fs.URL() + "/vmlinuz", Size: 1}, wantErr: SizeMismatchError},
+		{name: "checksum", a: Artifact{URI: fs.URL() + "/vmlinuz", Checksum: checksum}, want: Result{Size: int64(len(content)), Checksum: checksum}},
+		{name: "checksum mismatch", a: Artifact{URI: fs.URL() + "/vmlinuz", Checksum: "sha256:00"}, wantErr: ChecksumMismatchError},
+		{name: "unsupported checksum", a: Artifact{URI: fs.URL() + "/vmlinuz", Checksum: "md5:00"}, wantErr: errAny},
+		{name: "HEAD not allowed", a: Artifact{URI: fs.URL() + "/nohead"}, want: Result{Size: int64(len(content))}},
+		{name: "missing", a: Artifact{URI: fs.URL() + "/missing"}, wantErr: UnavailableError},
+		{name: "file", a: Artifact{URI: "file://" + path, Checksum: checksum}, want: Result{Size: int64(len(content)), Checksum: checksum}},
+		{name: "missing file", a: Artifact{URI: "file://" + filepath.Join(dir, "missing")}, wantErr: UnavailableError},
+		{name: "unsupported scheme", a: Artifact{URI: "s3://bucket/vmlinuz"}, wantErr: errAny},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ac.Verify(context.Background(), tt.a)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("Verify() error = %v, want none", err)
+			case tt.wantErr != nil && err == nil:
+				t.Fatalf("Verify() succeeded, want error")
+			case tt.wantErr != nil && tt.wantErr != errAny && !errors.Is(err, tt.wantErr):
+				t.Fatalf("Verify() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got != tt.want {
+				t.Errorf("Verify() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package bss
+
+import (
+	"sort"
+	"time"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+)
+
+// BootActivity describes when a node last fetched its boot script from BSS.
+// LastBoot is nil if BSS has no record of the node fetching a boot script.
+type BootActivity struct {
+	Xname    string     `json:"xname" yaml:"xname"`
+	LastBoot *time.Time `json:"last_boot,omitempty" yaml:"last_boot,omitempty"`
+}
+
+// BootActivityReport is the result of NewBootActivityReport. Recent contains
+// the nodes that fetched a boot script since Since and Stale contains the
+// nodes that have not, including nodes that never have.
+type BootActivityReport struct {
+	Since  time.Time      `json:"since" yaml:"since"`
+	Recent []BootActivity `json:"recent" yaml:"recent"`
+	Stale  []BootActivity `json:"stale" yaml:"stale"`
+}
+
+// NewBootActivityReport sorts the nodes in xnames into those that have and
+// have not fetched a boot script since since, according to the BSS endpoint
+// history in history. If xnames is empty, the nodes in history are used. Both
+// lists in the report are sorted by xname. Note that BSS only records the last
+// time each node fetched a boot script, so the number of boots is not known.
+func NewBootActivityReport(history []bssTypes.EndpointAccess, xnames []string, since time.Time) BootActivityReport {
+	lastBoot := make(map[string]time.Time)
+	for _, ea := range history {
+		if ea.Endpoint != bssTypes.EndpointTypeBootscript {
+			continue
+		}
+		t := time.Unix(ea.LastEpoch, 0).UTC()
+		if last, ok := lastBoot[ea.Name]; !ok || t.After(last) {
+			lastBoot[ea.Name] = t
+		}
+	}
+	if len(xnames) == 0 {
+		for x := range lastBoot {
+			xnames = append(xnames, x)
+		}
+	}
+
+	report := BootActivityReport{
+		Since:  since.UTC(),
+		Recent: []BootActivity{},
+		Stale:  []BootActivity{},
+	}
+	seen := make(map[string]bool)
+	for _, x := range xnames {
+		if seen[x] {
+			continue
+		}
+		seen[x] = true
+		ba := BootActivity{Xname: x}
+		if t, ok := lastBoot[x]; ok {
+			ba.LastBoot = &t
+			if !t.Before(since) {
+				report.Recent = append(report.Recent, ba)
+				continue
+			}
+		}
+		report.Stale = append(report.Stale, ba)
+	}
+	sort.Slice(report.Recent, func(i, j int) bool { return report.Recent[i].Xname < report.Recent[j].Xname })
+	sort.Slice(report.Stale, func(i, j int) bool { return report.Stale[i].Xname < report.Stale[j].Xname })
+
+	return report
+}
@@ -0,0 +1,54 @@
+package bss
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+)
+
+func TestNewBootActivityReport(t *testing.T) {
+	now := time.Unix(1700000000, 0).UTC()
+	since := now.Add(-24 * time.Hour)
+	recent := now.Add(-time.Hour)
+	old := now.Add(-72 * time.Hour)
+	history := []bssTypes.EndpointAccess{
+		{Name: "x1000c0s1b0n0", Endpoint: bssTypes.EndpointTypeBootscript, LastEpoch: recent.Unix()},
+		{Name: "x1000c0s0b0n0", Endpoint: bssTypes.EndpointTypeBootscript, LastEpoch: old.Unix()},
+		// Only boot script fetches count as boots
+		{Name: "x1000c0s0b0n0", Endpoint: bssTypes.EndpointTypeUserData, LastEpoch: recent.Unix()},
+	}
+
+	tests := []struct {
+		name   string
+		xnames []string
+		want   BootActivityReport
+	}{
+		{
+			name: "nodes from history",
+			want: BootActivityReport{
+				Since:  since,
+				Recent: []BootActivity{{Xname: "x1000c0s1b0n0", LastBoot: &recent}},
+				Stale:  []BootActivity{{Xname: "x1000c0s0b0n0", LastBoot: &old}},
+			},
+		},
+		{
+			name:   "nodes passed, including one that never booted",
+			xnames: []string{"x1000c0s2b0n0", "x1000c0s1b0n0", "x1000c0s1b0n0"},
+			want: BootActivityReport{
+				Since:  since,
+				Recent: []BootActivity{{Xname: "x1000c0s1b0n0", LastBoot: &recent}},
+				Stale:  []BootActivity{{Xname: "x1000c0s2b0n0"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewBootActivityReport(history, tt.xnames, since)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
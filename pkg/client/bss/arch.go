@@ -0,0 +1,176 @@
+package bss
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+)
+
+// The architectures that boot parameters can have variants for.
+const (
+	ArchX86_64  = "x86_64"
+	ArchAarch64 = "aarch64"
+)
+
+// Arches are the architectures that boot parameters can have variants for, in
+// the order their variants are sent.
+var Arches = []string{ArchX86_64, ArchAarch64}
+
+// UnknownHostPrefix is the prefix of the hosts whose boot parameters BSS serves
+// to hosts not in SMD, followed by the architecture iPXE reports in
+// ${buildarch} (see UnknownHost).
+const UnknownHostPrefix = "Unknown-"
+
+// ParseArch returns the architecture named s (x86_64 or aarch64), accepting the
+// common aliases amd64 and arm64 as well as the Arch values of SMD components
+// (X86 and ARM) regardless of case.
+func ParseArch(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "x86_64", "amd64", "x86":
+		return ArchX86_64, nil
+	case "aarch64", "arm64", "arm":
+		return ArchAarch64, nil
+	default:
+		return "", fmt.Errorf("unknown architecture %q (expected %s)", s, strings.Join(Arches, " or "))
+	}
+}
+
+// ComponentArch returns the architecture of an SMD component whose Arch is
+// arch, or an empty string if it is unknown (e.g. Other or UNKNOWN).
+func ComponentArch(arch string) string {
+	a, err := ParseArch(arch)
+	if err != nil {
+		return ""
+	}
+
+	return a
+}
+
+// BuildArch returns the name that iPXE gives arch in ${buildarch}, which is
+// what BSS uses to pick the boot parameters of hosts not in SMD.
+func BuildArch(arch string) string {
+	if arch == ArchAarch64 {
+		return "arm64"
+	}
+
+	return arch
+}
+
+// UnknownHost returns the host whose boot parameters BSS serves to hosts of
+// architecture arch that are not in SMD, e.g. Unknown-x86_64.
+func UnknownHost(arch string) string {
+	return UnknownHostPrefix + BuildArch(arch)
+}
+
+// ArchVariant is the boot parameters for one architecture. Those that are set
+// override the ones of the ArchBootParams the variant is part of.
+type ArchVariant struct {
+	Params string `json:"params,omitempty" yaml:"params,omitempty"`
+	Kernel string `json:"kernel,omitempty" yaml:"kernel,omitempty"`
+	Initrd string `json:"initrd,omitempty" yaml:"initrd,omitempty"`
+}
+
+// ArchBootParams is boot parameters with variants per architecture, keyed by
+// architecture (see ParseArch), so that one set of boot parameters can be set
+// for targets of mixed architectures.
+type ArchBootParams struct {
+	bssTypes.BootParams `yaml:",inline"`
+	Arch                map[string]ArchVariant `json:"arch,omitempty" yaml:"arch,omitempty"`
+}
+
+// hasBootData returns whether bp has a kernel, initrd, or kernel parameters.
+func hasBootData(bp bssTypes.BootParams) bool {
+	return bp.Kernel != "" || bp.Initrd != "" || bp.Params != ""
+}
+
+// SplitByArch splits abp into boot parameters per architecture. The targets of
+// abp are grouped by the architecture archOf returns for them, and those of an
+// architecture with a variant get the boot parameters of abp overridden by it.
+// Targets of other (or unknown) architectures get the boot parameters of abp as
+// is if it has any, and are returned as skipped otherwise. If abp has no
+// targets, the boot parameters of each variant are for the hosts not in SMD of
+// its architecture (see UnknownHost).
+//
+// The boot parameters are returned in the order of Arches, followed by those of
+// the targets without a variant.
+func SplitByArch(abp ArchBootParams, archOf func(target ParamsTarget) (string, error)) ([]bssTypes.BootParams, []ParamsTarget, error) {
+	variants := make(map[string]ArchVariant)
+	for a, v := range abp.Arch {
+		arch, err := ParseArch(a)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid variant: %w", err)
+		}
+		if _, ok := variants[arch]; ok {
+			return nil, nil, fmt.Errorf("more than one variant for %s", arch)
+		}
+		variants[arch] = v
+	}
+
+	variant := func(arch string) bssTypes.BootParams {
+		bp := bssTypes.BootParams{
+			Params:    abp.Params,
+			Kernel:    abp.Kernel,
+			Initrd:    abp.Initrd,
+			CloudInit: abp.CloudInit,
+		}
+		v := variants[arch]
+		if v.Params != "" {
+			bp.Params = v.Params
+		}
+		if v.Kernel != "" {
+			bp.Kernel = v.Kernel
+		}
+		if v.Initrd != "" {
+			bp.Initrd = v.Initrd
+		}
+		return bp
+	}
+
+	targets := paramsTargets(abp.BootParams)
+	if len(targets) == 0 {
+		var bps []bssTypes.BootParams
+		for _, arch := range Arches {
+			if _, ok := variants[arch]; ok {
+				bp := variant(arch)
+				bp.Hosts = []string{UnknownHost(arch)}
+				bps = append(bps, bp)
+			}
+		}
+		return bps, nil, nil
+	}
+
+	var (
+		byArch  = make(map[string]*bssTypes.BootParams)
+		skipped []ParamsTarget
+	)
+	for _, t := range targets {
+		arch, err := archOf(t)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to get architecture of %s %s: %w", t.Kind, t.ID, err)
+		}
+		if _, ok := variants[arch]; !ok {
+			arch = ""
+			if !hasBootData(abp.BootParams) {
+				skipped = append(skipped, t)
+				continue
+			}
+		}
+		bp, ok := byArch[arch]
+		if !ok {
+			v := variant(arch)
+			bp = &v
+			byArch[arch] = bp
+		}
+		addParamsTarget(bp, t)
+	}
+
+	var bps []bssTypes.BootParams
+	for _, arch := range append(append([]string{}, Arches...), "") {
+		if bp, ok := byArch[arch]; ok {
+			bps = append(bps, *bp)
+		}
+	}
+
+	return bps, skipped, nil
+}
@@ -0,0 +1,113 @@
+package bss
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+	"gopkg.in/yaml.v3"
+)
+
+func TestParseArch(t *testing.T) {
+	for in, want := range map[string]string{
+		"x86_64": ArchX86_64, "amd64": ArchX86_64, "X86": ArchX86_64,
+		"aarch64": ArchAarch64, "arm64": ArchAarch64, "ARM": ArchAarch64,
+	} {
+		if got, err := ParseArch(in); err != nil || got != want {
+			t.Errorf("ParseArch(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseArch("ppc64le"); err == nil {
+		t.Error("ParseArch(ppc64le) succeeded, want error")
+	}
+	if got := ComponentArch("UNKNOWN"); got != "" {
+		t.Errorf("ComponentArch(UNKNOWN) = %q, want empty", got)
+	}
+	if got := UnknownHost(ArchAarch64); got != "Unknown-arm64" {
+		t.Errorf("UnknownHost(aarch64) = %q, want Unknown-arm64", got)
+	}
+}
+
+func TestArchBootParams_Unmarshal(t *testing.T) {
+	var abp ArchBootParams
+	data := `{"hosts":["x1"],"params":"quiet","arch":{"x86_64":{"kernel":"k-x86"},"arm64":{"kernel":"k-arm"}}}`
+	if err := json.Unmarshal([]byte(data), &abp); err != nil {
+		t.Fatal(err)
+	}
+	if len(abp.Hosts) != 1 || abp.Params != "quiet" || abp.Arch["arm64"].Kernel != "k-arm" {
+		t.Errorf("Unmarshal() = %+v", abp)
+	}
+
+	abp = ArchBootParams{}
+	data = "hosts: [x1]\nparams: quiet\narch:\n  aarch64: {kernel: k-arm}\n"
+	if err := yaml.Unmarshal([]byte(data), &abp); err != nil {
+		t.Fatal(err)
+	}
+	if len(abp.Hosts) != 1 || abp.Params != "quiet" || abp.Arch["aarch64"].Kernel != "k-arm" {
+		t.Errorf("yaml.Unmarshal() = %+v", abp)
+	}
+}
+
+func TestSplitByArch(t *testing.T) {
+	arches := map[string]string{"x1": ArchX86_64, "x2": ArchAarch64, "x3": "", "1": ArchAarch64}
+	archOf := func(t ParamsTarget) (string, error) {
+		arch, ok := arches[t.ID]
+		if !ok {
+			return "", errors.New("not found")
+		}
+		return arch, nil
+	}
+
+	abp := ArchBootParams{
+		BootParams: bssTypes.BootParams{Hosts: []string{"x1", "x2", "x3"}, Nids: []int32{1}, Params: "quiet"},
+		Arch: map[string]ArchVariant{
+			"x86_64": {Kernel: "k-x86"},
+			"arm64":  {Kernel: "k-arm", Params: "quiet iommu.passthrough=1"},
+		},
+	}
+	got, skipped, err := SplitByArch(abp, archOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bssTypes.BootParams{
+		{Hosts: []string{"x1"}, Params: "quiet", Kernel: "k-x86"},
+		{Hosts: []string{"x2"}, Nids: []int32{1}, Params: "quiet iommu.passthrough=1", Kernel: "k-arm"},
+		{Hosts: []string{"x3"}, Params: "quiet"},
+	}
+	if !reflect.DeepEqual(got, want) || len(skipped) != 0 {
+		t.Errorf("SplitByArch() = %+v, %v, want %+v", got, skipped, want)
+	}
+
+	// Without boot parameters of its own, targets without a variant are
+	// skipped
+	abp.Params = ""
+	abp.Arch = map[string]ArchVariant{"aarch64": {Kernel: "k-arm"}}
+	got, skipped, err = SplitByArch(abp, archOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Hosts, []string{"x2"}) || len(skipped) != 2 {
+		t.Errorf("SplitByArch() = %+v, skipped %v, want only x2 and NID 1 with 2 skipped", got, skipped)
+	}
+
+	// Without targets, variants are for unknown hosts
+	got, _, err = SplitByArch(ArchBootParams{Arch: abp.Arch}, archOf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0].Hosts, []string{"Unknown-arm64"}) {
+		t.Errorf("SplitByArch() without targets = %+v, want Unknown-arm64", got)
+	}
+
+	for name, abp := range map[string]ArchBootParams{
+		"unknown arch":      {Arch: map[string]ArchVariant{"ppc64le": {}}},
+		"duplicate variant": {Arch: map[string]ArchVariant{"arm64": {}, "aarch64": {}}},
+		"unknown target":    {BootParams: bssTypes.BootParams{Hosts: []string{"x9"}}, Arch: abp.Arch},
+	} {
+		if _, _, err := SplitByArch(abp, archOf); err == nil {
+			t.Errorf("SplitByArch() with %s succeeded, want error", name)
+		}
+	}
+}
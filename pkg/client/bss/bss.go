@@ -178,6 +178,25 @@ func (bc *BSSClient) GetBootParams(query, token string) (client.HTTPEnvelope, er
 	return henv, err
 }
 
+// GetBootParamsStream is like GetBootParams, but uses
+// OchamiClient.GetDataStream so that the boot parameters are spilled to a
+// temporary file if they are too large to hold in memory. The caller must Close
+// the returned StreamBody.
+func (bc *BSSClient) GetBootParamsStream(query, token string) (client.HTTPEnvelope, *client.StreamBody, error) {
+	headers := client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			return client.HTTPEnvelope{}, nil, fmt.Errorf("GetBootParamsStream(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	henv, body, err := bc.GetDataStream(BSSRelpathBootParams, query, headers)
+	if err != nil {
+		err = fmt.Errorf("GetBootParamsStream(): error getting boot parameters: %w", err)
+	}
+
+	return henv, body, err
+}
+
 // GetBootScript is a wrapper function around OchamiClient.GetData that takes a
 // query string (without the "?") and passes it to OchamiClient.GetData, using
 // /bootscript as the API endpoint.
@@ -244,6 +263,18 @@ func (bc *BSSClient) GetDumpState() (client.HTTPEnvelope, error) {
 	return henv, err
 }
 
+// GetDumpStateStream is like GetDumpState, but uses OchamiClient.GetDataStream
+// so that the (potentially huge) state is spilled to a temporary file if it is
+// too large to hold in memory. The caller must Close the returned StreamBody.
+func (bc *BSSClient) GetDumpStateStream() (client.HTTPEnvelope, *client.StreamBody, error) {
+	henv, body, err := bc.GetDataStream(BSSRelpathDumpState, "", nil)
+	if err != nil {
+		err = fmt.Errorf("GetDumpStateStream(): error getting dump state: %w", err)
+	}
+
+	return henv, body, err
+}
+
 // GetEndpointHistory is a wrapper function around OchamiClient.GetData that
 // queries /endpoint-history and appends an optional query string (without the
 // "?").
@@ -256,6 +287,16 @@ func (bc *BSSClient) GetEndpointHistory(query string) (client.HTTPEnvelope, erro
 	return henv, err
 }
 
+// Host is a minimal subset of the host entries BSS returns from /hosts, which
+// are the components BSS knows about from SMD along with their MAC addresses.
+type Host struct {
+	ID   string   `json:"ID" yaml:"ID"`
+	Type string   `json:"Type,omitempty" yaml:"Type,omitempty"`
+	NID  int64    `json:"NID,omitempty" yaml:"NID,omitempty"`
+	FQDN string   `json:"FQDN,omitempty" yaml:"FQDN,omitempty"`
+	MAC  []string `json:"MAC,omitempty" yaml:"MAC,omitempty"`
+}
+
 // GetHosts is a wrapper function around OchamiClient.GetData that queries /hosts
 // and appends an optional query string (without the "?").
 func (bc *BSSClient) GetHosts(query string) (client.HTTPEnvelope, error) {
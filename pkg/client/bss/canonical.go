@@ -0,0 +1,77 @@
+package bss
+
+import (
+	"cmp"
+	"net"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+)
+
+// CanonicalParams returns a copy of bp in canonical form, so that equivalent
+// boot parameters compare and diff equal. Its hosts and NIDs are sorted and
+// de-duplicated, its MAC addresses are lowercased, colon-separated, sorted, and
+// de-duplicated, and surrounding whitespace is trimmed from its hosts, MAC
+// addresses, params, kernel, and initrd. Cloud-init data is kept as is.
+func CanonicalParams(bp bssTypes.BootParams) bssTypes.BootParams {
+	bp.Hosts = canonicalStrings(bp.Hosts, strings.TrimSpace)
+	bp.Macs = canonicalStrings(bp.Macs, canonicalMAC)
+	if len(bp.Nids) > 0 {
+		bp.Nids = slices.Compact(slices.Sorted(slices.Values(bp.Nids)))
+	}
+	bp.Params = strings.TrimSpace(bp.Params)
+	bp.Kernel = strings.TrimSpace(bp.Kernel)
+	bp.Initrd = strings.TrimSpace(bp.Initrd)
+
+	return bp
+}
+
+// CanonicalParamsList returns the canonical form (see CanonicalParams) of each
+// of bps without duplicates, sorted by their hosts, then MAC addresses, then
+// NIDs.
+func CanonicalParamsList(bps []bssTypes.BootParams) []bssTypes.BootParams {
+	out := make([]bssTypes.BootParams, 0, len(bps))
+	for _, bp := range bps {
+		bp = CanonicalParams(bp)
+		if !slices.ContainsFunc(out, func(o bssTypes.BootParams) bool { return reflect.DeepEqual(o, bp) }) {
+			out = append(out, bp)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b bssTypes.BootParams) int {
+		return cmp.Or(
+			slices.Compare(a.Hosts, b.Hosts),
+			slices.Compare(a.Macs, b.Macs),
+			slices.Compare(a.Nids, b.Nids),
+		)
+	})
+
+	return out
+}
+
+// canonicalStrings returns the elements of s normalized with norm, sorted, and
+// de-duplicated, or nil if s is empty.
+func canonicalStrings(s []string, norm func(string) string) []string {
+	if len(s) == 0 {
+		return nil
+	}
+	out := make([]string, len(s))
+	for i, v := range s {
+		out[i] = norm(v)
+	}
+	slices.Sort(out)
+
+	return slices.Compact(out)
+}
+
+// canonicalMAC returns the MAC address mac lowercased and colon-separated, or
+// just lowercased if it cannot be parsed.
+func canonicalMAC(mac string) string {
+	mac = strings.TrimSpace(mac)
+	if hw, err := net.ParseMAC(mac); err == nil {
+		return hw.String()
+	}
+
+	return strings.ToLower(mac)
+}
@@ -0,0 +1,50 @@
+package bss
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+)
+
+func TestCanonicalParams(t *testing.T) {
+	bp := bssTypes.BootParams{
+		Hosts:  []string{"x1000c0s1b0n0", " x1000c0s0b0n0", "x1000c0s1b0n0"},
+		Macs:   []string{"DE-AD-BE-EF-00-02", "de:ad:be:ef:00:01", "DE:AD:BE:EF:00:01", "not-a-mac"},
+		Nids:   []int32{3, 1, 3},
+		Params: " console=ttyS0 quiet\n",
+		Kernel: "http://k ",
+	}
+	want := bssTypes.BootParams{
+		Hosts:  []string{"x1000c0s0b0n0", "x1000c0s1b0n0"},
+		Macs:   []string{"de:ad:be:ef:00:01", "de:ad:be:ef:00:02", "not-a-mac"},
+		Nids:   []int32{1, 3},
+		Params: "console=ttyS0 quiet",
+		Kernel: "http://k",
+	}
+	if got := CanonicalParams(bp); !reflect.DeepEqual(got, want) {
+		t.Errorf("CanonicalParams() = %+v, want %+v", got, want)
+	}
+	if got := CanonicalParams(bssTypes.BootParams{}); !reflect.DeepEqual(got, bssTypes.BootParams{}) {
+		t.Errorf("CanonicalParams() of empty boot parameters = %+v, want empty", got)
+	}
+}
+
+func TestCanonicalParamsList(t *testing.T) {
+	bps := []bssTypes.BootParams{
+		{Hosts: []string{"x1000c0s1b0n0"}, Params: "quiet"},
+		{Macs: []string{"DE:AD:BE:EF:00:01"}, Params: "quiet"},
+		{Hosts: []string{"x1000c0s0b0n0"}, Params: "quiet"},
+		{Macs: []string{"de:ad:be:ef:00:01"}, Params: "quiet "},
+		{Nids: []int32{1}, Params: "quiet"},
+	}
+	want := []bssTypes.BootParams{
+		{Nids: []int32{1}, Params: "quiet"},
+		{Macs: []string{"de:ad:be:ef:00:01"}, Params: "quiet"},
+		{Hosts: []string{"x1000c0s0b0n0"}, Params: "quiet"},
+		{Hosts: []string{"x1000c0s1b0n0"}, Params: "quiet"},
+	}
+	if got := CanonicalParamsList(bps); !reflect.DeepEqual(got, want) {
+		t.Errorf("CanonicalParamsList() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,103 @@
+package bss
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+)
+
+// ImageMatch is an entry of boot parameters whose kernel or initrd URI matches
+// an image pattern (see MatchImages), identified by its hosts, MAC addresses,
+// and NIDs. NewKernel and NewInitrd are what its kernel and initrd URIs are
+// rewritten to, if they are, and empty otherwise.
+type ImageMatch struct {
+	Hosts     []string `json:"hosts,omitempty" yaml:"hosts,omitempty"`
+	Macs      []string `json:"macs,omitempty" yaml:"macs,omitempty"`
+	Nids      []int32  `json:"nids,omitempty" yaml:"nids,omitempty"`
+	Kernel    string   `json:"kernel,omitempty" yaml:"kernel,omitempty"`
+	Initrd    string   `json:"initrd,omitempty" yaml:"initrd,omitempty"`
+	NewKernel string   `json:"new_kernel,omitempty" yaml:"new_kernel,omitempty"`
+	NewInitrd string   `json:"new_initrd,omitempty" yaml:"new_initrd,omitempty"`
+}
+
+// Targets returns the number of hosts, MAC addresses, and NIDs of m.
+func (m ImageMatch) Targets() int {
+	return len(m.Hosts) + len(m.Macs) + len(m.Nids)
+}
+
+// BootParams returns the boot parameters that identify the entry of m, along
+// with its new kernel and initrd URIs, if any, e.g. to delete or patch it.
+func (m ImageMatch) BootParams() bssTypes.BootParams {
+	return bssTypes.BootParams{
+		Hosts:  m.Hosts,
+		Macs:   m.Macs,
+		Nids:   m.Nids,
+		Kernel: m.NewKernel,
+		Initrd: m.NewInitrd,
+	}
+}
+
+// ImagePattern compiles pattern into a regular expression that matches whole
+// kernel or initrd URIs. If glob is true, pattern is a glob in which * matches
+// any sequence of characters, including /, and ? matches any single character,
+// each of them being a group that a replacement passed to MatchImages can
+// refer to as $1, $2, and so on. Otherwise, it is a regular expression, which
+// is anchored at both ends.
+func ImagePattern(pattern string, glob bool) (*regexp.Regexp, error) {
+	if !glob {
+		re, err := regexp.Compile(`^(?:` + pattern + `)$`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", pattern, err)
+		}
+		return re, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString("(.*)")
+		case '?':
+			b.WriteString("(.)")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+
+	return regexp.MustCompile(b.String()), nil
+}
+
+// MatchImages returns the entries of bps whose kernel or initrd URI matches
+// re, in order. If replace is true, the URIs that match are rewritten to
+// replacement, in which $1, $2, and so on are replaced with what the groups of
+// re matched (see regexp.Regexp.Expand).
+func MatchImages(bps []bssTypes.BootParams, re *regexp.Regexp, replacement string, replace bool) []ImageMatch {
+	var matches []ImageMatch
+	for _, bp := range bps {
+		kernelMatch := bp.Kernel != "" && re.MatchString(bp.Kernel)
+		initrdMatch := bp.Initrd != "" && re.MatchString(bp.Initrd)
+		if !kernelMatch && !initrdMatch {
+			continue
+		}
+		m := ImageMatch{
+			Hosts:  bp.Hosts,
+			Macs:   bp.Macs,
+			Nids:   bp.Nids,
+			Kernel: bp.Kernel,
+			Initrd: bp.Initrd,
+		}
+		if replace && kernelMatch {
+			m.NewKernel = re.ReplaceAllString(bp.Kernel, replacement)
+		}
+		if replace && initrdMatch {
+			m.NewInitrd = re.ReplaceAllString(bp.Initrd, replacement)
+		}
+		matches = append(matches, m)
+	}
+
+	return matches
+}
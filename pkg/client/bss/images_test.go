@@ -0,0 +1,70 @@
+package bss
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+)
+
+func TestImagePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		glob    bool
+		uri     string
+		want    bool
+	}{
+		{pattern: "https://old-repo/*", glob: true, uri: "https://old-repo/images/vmlinuz", want: true},
+		{pattern: "https://old-repo/*", glob: true, uri: "https://new-repo/images/vmlinuz", want: false},
+		{pattern: "https://old-repo/*", glob: true, uri: "https://old-repo.example.com/vmlinuz", want: false},
+		{pattern: "*/vmlinuz-?.?", glob: true, uri: "http://s3/boot/vmlinuz-6.1", want: true},
+		{pattern: `https://old-repo/.*\.img`, uri: "https://old-repo/a/initramfs.img", want: true},
+		{pattern: `old-repo`, uri: "https://old-repo/a/initramfs.img", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.uri, func(t *testing.T) {
+			re, err := ImagePattern(tt.pattern, tt.glob)
+			if err != nil {
+				t.Fatalf("ImagePattern() error = %v", err)
+			}
+			if got := re.MatchString(tt.uri); got != tt.want {
+				t.Errorf("ImagePattern(%q).MatchString(%q) = %v, want %v", tt.pattern, tt.uri, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := ImagePattern("(", false); err == nil {
+		t.Errorf("ImagePattern() with invalid regular expression error = nil")
+	}
+}
+
+func TestMatchImages(t *testing.T) {
+	bps := []bssTypes.BootParams{
+		{Hosts: []string{"x3000c0s0b0n0"}, Kernel: "https://old-repo/k1", Initrd: "https://old-repo/i1"},
+		{Macs: []string{"de:ad:be:ee:ef:01"}, Kernel: "https://new-repo/k2", Initrd: "https://old-repo/i2"},
+		{Nids: []int32{3}, Kernel: "https://new-repo/k3", Initrd: "https://new-repo/i3"},
+	}
+	re, err := ImagePattern("https://old-repo/*", true)
+	if err != nil {
+		t.Fatalf("ImagePattern() error = %v", err)
+	}
+
+	got := MatchImages(bps, re, "", false)
+	want := []ImageMatch{
+		{Hosts: []string{"x3000c0s0b0n0"}, Kernel: "https://old-repo/k1", Initrd: "https://old-repo/i1"},
+		{Macs: []string{"de:ad:be:ee:ef:01"}, Kernel: "https://new-repo/k2", Initrd: "https://old-repo/i2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchImages() = %+v, want %+v", got, want)
+	}
+
+	got = MatchImages(bps, re, "https://new-repo/$1", true)
+	want[0].NewKernel, want[0].NewInitrd = "https://new-repo/k1", "https://new-repo/i1"
+	want[1].NewInitrd = "https://new-repo/i2"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchImages() with replacement = %+v, want %+v", got, want)
+	}
+	if bp := got[1].BootParams(); bp.Kernel != "" || bp.Initrd != "https://new-repo/i2" || got[1].Targets() != 1 {
+		t.Errorf("BootParams() = %+v, want only the new initrd", bp)
+	}
+}
@@ -0,0 +1,302 @@
+package bss
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+	"gopkg.in/yaml.v3"
+)
+
+// Levels of a LintFinding.
+const (
+	LintLevelError   = "error"
+	LintLevelWarning = "warning"
+)
+
+// Rules built into LintParams.
+const (
+	LintRuleDuplicateArg   = "duplicate-arg"
+	LintRuleMultipleRoot   = "multiple-root"
+	LintRuleConflictingArg = "conflicting-arg"
+	LintRuleIPConflict     = "ip-conflict"
+	LintRuleMissingInitrd  = "missing-initrd"
+)
+
+// LintFinding is a problem found in boot parameters.
+type LintFinding struct {
+	Rule    string `json:"rule" yaml:"rule"`
+	Level   string `json:"level" yaml:"level"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// LintRule is a site-specific rule checked by LintParams in addition to the
+// built-in ones. Arguments in Forbid and Require are either a key (e.g.
+// "quiet" or "console"), matching any argument with that key, or a key and a
+// value (e.g. "console=ttyS0,*"), where the value is a pattern as in
+// path.Match. A finding is reported for each argument in Forbid that is
+// present, each argument in Require that is absent, and if Pattern matches the
+// kernel parameters.
+type LintRule struct {
+	Name    string   `json:"name" yaml:"name"`
+	Level   string   `json:"level,omitempty" yaml:"level,omitempty"`
+	Message string   `json:"message,omitempty" yaml:"message,omitempty"`
+	Forbid  []string `json:"forbid,omitempty" yaml:"forbid,omitempty"`
+	Require []string `json:"require,omitempty" yaml:"require,omitempty"`
+	Pattern string   `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// LintRules is the format of a site rules file.
+type LintRules struct {
+	Rules []LintRule `json:"rules" yaml:"rules"`
+}
+
+// ReadLintRules reads the site rules file at p, which is YAML (or JSON), and
+// returns its rules. Rules without a level are warnings.
+func ReadLintRules(p string) ([]LintRule, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read lint rules file: %w", err)
+	}
+	var lr LintRules
+	if err := yaml.Unmarshal(data, &lr); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal lint rules file %s: %w", p, err)
+	}
+	for i := range lr.Rules {
+		r := &lr.Rules[i]
+		if r.Name == "" {
+			return nil, fmt.Errorf("lint rule %d in %s has no name", i+1, p)
+		}
+		switch r.Level {
+		case "":
+			r.Level = LintLevelWarning
+		case LintLevelError, LintLevelWarning:
+		default:
+			return nil, fmt.Errorf("lint rule %s has invalid level %q (must be %s or %s)", r.Name, r.Level, LintLevelError, LintLevelWarning)
+		}
+		if len(r.Forbid) == 0 && len(r.Require) == 0 && r.Pattern == "" {
+			return nil, fmt.Errorf("lint rule %s has none of forbid, require, or pattern", r.Name)
+		}
+		if r.Pattern != "" {
+			if r.re, err = regexp.Compile(r.Pattern); err != nil {
+				return nil, fmt.Errorf("lint rule %s has invalid pattern: %w", r.Name, err)
+			}
+		}
+	}
+
+	return lr.Rules, nil
+}
+
+// SplitParams splits kernel parameters into arguments like the kernel does:
+// on whitespace, except within double quotes. Arguments after "--" are passed
+// to init and are not returned.
+func SplitParams(params string) []string {
+	var (
+		args    []string
+		cur     strings.Builder
+		inQuote bool
+		inArg   bool
+	)
+	for _, r := range params {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			inArg = true
+			cur.WriteRune(r)
+		case !inQuote && (r == ' ' || r == '\t' || r == '\n'):
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			inArg = true
+			cur.WriteRune(r)
+		}
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	for i, a := range args {
+		if a == "--" {
+			return args[:i]
+		}
+	}
+
+	return args
+}
+
+// splitArg returns the key and value of the kernel argument arg, removing any
+// quotes around the value.
+func splitArg(arg string) (string, string) {
+	key, value, _ := strings.Cut(arg, "=")
+	return key, strings.Trim(value, `"`)
+}
+
+// uniqueArgs are kernel arguments of which only one value takes effect, other
+// than root, which has its own rule.
+var uniqueArgs = []string{"init", "rootfstype"}
+
+// ipAutoconf are the values of ip= (or of its autoconf field) that configure an
+// interface automatically.
+var ipAutoconf = map[string]bool{
+	"on": true, "any": true, "dhcp": true, "dhcp6": true, "auto6": true,
+	"bootp": true, "rarp": true, "both": true, "either6": true, "ibft": true,
+	"off": true, "none": true,
+}
+
+// ipInterface returns the interface that the value of an ip= argument
+// configures, or "" if it configures all interfaces.
+func ipInterface(value string) string {
+	if ipAutoconf[value] {
+		return ""
+	}
+	fields := strings.Split(value, ":")
+	// ip=<interface>:<autoconf>[:...] (dracut)
+	if len(fields) >= 2 && ipAutoconf[fields[1]] {
+		return fields[0]
+	}
+	// ip=<client-IP>:<server-IP>:<gw-IP>:<netmask>:<hostname>:<device>:...
+	if len(fields) >= 6 {
+		return fields[5]
+	}
+	return ""
+}
+
+// initrdRootPrefixes are prefixes of root= values that only an initramfs can
+// mount.
+var initrdRootPrefixes = []string{"live:", "nfs:", "nfs4:", "iscsi:", "nbd:", "block:", "LABEL=", "UUID=", "http:", "https:"}
+
+// LintParams checks the kernel parameters of bp for common pitfalls and against
+// rules, returning what was found. The built-in rules report arguments that are
+// repeated (duplicate-arg), more than one root filesystem (multiple-root),
+// other arguments of which only one value takes effect set to different values
+// (conflicting-arg), ip= arguments that configure the same interface
+// differently, such as DHCP alongside a static address (ip-conflict), and
+// arguments that need an initramfs without an initrd being set
+// (missing-initrd).
+func LintParams(bp bssTypes.BootParams, rules []LintRule) []LintFinding {
+	var (
+		findings []LintFinding
+		args     = SplitParams(bp.Params)
+		seen     = make(map[string]bool)
+		values   = make(map[string][]string)
+	)
+	add := func(rule, level, format string, a ...any) {
+		findings = append(findings, LintFinding{Rule: rule, Level: level, Message: fmt.Sprintf(format, a...)})
+	}
+	for _, arg := range args {
+		if seen[arg] {
+			add(LintRuleDuplicateArg, LintLevelWarning, "%s is passed more than once", arg)
+			continue
+		}
+		seen[arg] = true
+		key, value := splitArg(arg)
+		values[key] = append(values[key], value)
+	}
+
+	if roots := values["root"]; len(roots) > 1 {
+		add(LintRuleMultipleRoot, LintLevelError, "root= is set more than once (%s), only the last takes effect", strings.Join(roots, ", "))
+	}
+	for _, key := range uniqueArgs {
+		if vs := values[key]; len(vs) > 1 {
+			add(LintRuleConflictingArg, LintLevelError, "%s= is set to different values (%s), only the last takes effect", key, strings.Join(vs, ", "))
+		}
+	}
+
+	ips := values["ip"]
+	for i := 0; i < len(ips); i++ {
+		for j := i + 1; j < len(ips); j++ {
+			a, b := ipInterface(ips[i]), ipInterface(ips[j])
+			if a == "" || b == "" || a == b {
+				what := "interface " + a
+				if a == "" || b == "" {
+					what = "all interfaces"
+				}
+				add(LintRuleIPConflict, LintLevelError, "ip=%s and ip=%s both configure %s", ips[i], ips[j], what)
+			}
+		}
+	}
+
+	if bp.Initrd == "" {
+		for _, root := range values["root"] {
+			for _, p := range initrdRootPrefixes {
+				if strings.HasPrefix(root, p) {
+					add(LintRuleMissingInitrd, LintLevelError, "root=%s can only be mounted by an initramfs, but no initrd is set", root)
+					break
+				}
+			}
+		}
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "rd.") {
+				add(LintRuleMissingInitrd, LintLevelError, "%s is handled by an initramfs, but no initrd is set", arg)
+				break
+			}
+		}
+	}
+
+	for _, r := range rules {
+		for _, spec := range r.Forbid {
+			if argsMatch(args, spec) {
+				add(r.Name, r.Level, "%s", ruleMessage(r, spec+" must not be passed"))
+			}
+		}
+		for _, spec := range r.Require {
+			if !argsMatch(args, spec) {
+				add(r.Name, r.Level, "%s", ruleMessage(r, spec+" must be passed"))
+			}
+		}
+		if r.re != nil && r.re.MatchString(bp.Params) {
+			add(r.Name, r.Level, "%s", ruleMessage(r, "kernel parameters match "+r.Pattern))
+		}
+	}
+
+	return findings
+}
+
+// argsMatch returns true if any of args matches spec, which is either a key or
+// a key and a value pattern.
+func argsMatch(args []string, spec string) bool {
+	specKey, specValue, hasValue := strings.Cut(spec, "=")
+	for _, arg := range args {
+		key, value := splitArg(arg)
+		if key != specKey {
+			continue
+		}
+		if !hasValue {
+			return true
+		}
+		if ok, _ := path.Match(specValue, value); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ruleMessage returns the message of r, or def if it has none.
+func ruleMessage(r LintRule, def string) string {
+	if r.Message != "" {
+		return r.Message
+	}
+	return def
+}
+
+// LintTarget returns a description of the components bp is for, used to
+// identify it in lint output, e.g. "x1000c0s0b0n0,00:de:ad:be:ef:00,nid:1".
+func LintTarget(bp bssTypes.BootParams) string {
+	ids := append([]string{}, bp.Hosts...)
+	ids = append(ids, bp.Macs...)
+	for _, n := range bp.Nids {
+		ids = append(ids, fmt.Sprintf("nid:%d", n))
+	}
+	if len(ids) == 0 {
+		return "(no targets)"
+	}
+	return strings.Join(ids, ",")
+}
@@ -0,0 +1,153 @@
+package bss
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+)
+
+func TestSplitParams(t *testing.T) {
+	tests := []struct {
+		params string
+		want   []string
+	}{
+		{params: "", want: nil},
+		{params: "  quiet   console=ttyS0 ", want: []string{"quiet", "console=ttyS0"}},
+		{params: `foo="a b" bar`, want: []string{`foo="a b"`, "bar"}},
+		{params: "quiet -- single", want: []string{"quiet"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.params, func(t *testing.T) {
+			if got := SplitParams(tt.params); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SplitParams() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func lintRules(findings []LintFinding) []string {
+	var rules []string
+	for _, f := range findings {
+		rules = append(rules, f.Rule)
+	}
+	return rules
+}
+
+func TestLintParams(t *testing.T) {
+	tests := []struct {
+		name string
+		bp   bssTypes.BootParams
+		want []string
+	}{
+		{
+			name: "clean",
+			bp:   bssTypes.BootParams{Initrd: "initrd", Params: "root=live:http://10.0.0.1/image console=ttyS0 ip=dhcp"},
+		},
+		{
+			name: "duplicate",
+			bp:   bssTypes.BootParams{Params: "quiet quiet"},
+			want: []string{LintRuleDuplicateArg},
+		},
+		{
+			name: "multiple root",
+			bp:   bssTypes.BootParams{Params: "root=/dev/sda1 root=/dev/sda2"},
+			want: []string{LintRuleMultipleRoot},
+		},
+		{
+			name: "conflicting init",
+			bp:   bssTypes.BootParams{Params: "init=/sbin/init init=/bin/sh"},
+			want: []string{LintRuleConflictingArg},
+		},
+		{
+			name: "dhcp and static on all interfaces",
+			bp:   bssTypes.BootParams{Params: "ip=dhcp ip=10.0.0.5::10.0.0.1:255.255.255.0:node:eth0:none"},
+			want: []string{LintRuleIPConflict},
+		},
+		{
+			name: "same interface twice",
+			bp:   bssTypes.BootParams{Params: "ip=eth0:dhcp ip=10.0.0.5::10.0.0.1:255.255.255.0:node:eth0:none"},
+			want: []string{LintRuleIPConflict},
+		},
+		{
+			name: "different interfaces",
+			bp:   bssTypes.BootParams{Params: "ip=eth0:dhcp ip=10.0.0.5::10.0.0.1:255.255.255.0:node:eth1:none"},
+		},
+		{
+			name: "live root without initrd",
+			bp:   bssTypes.BootParams{Params: "root=live:http://10.0.0.1/image"},
+			want: []string{LintRuleMissingInitrd},
+		},
+		{
+			name: "dracut args without initrd",
+			bp:   bssTypes.BootParams{Params: "rd.shell rd.debug"},
+			want: []string{LintRuleMissingInitrd},
+		},
+		{
+			name: "local root without initrd",
+			bp:   bssTypes.BootParams{Params: "root=/dev/sda1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := lintRules(LintParams(tt.bp, nil)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LintParams() rules = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLintRules(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	p := write("rules.yaml", `rules:
+- name: serial-console
+  level: error
+  require: ["console=ttyS0,*"]
+- name: no-quiet
+  forbid: [quiet]
+  message: quiet hides boot problems
+- name: no-debug
+  pattern: '\bdebug\b'
+`)
+	rules, err := ReadLintRules(p)
+	if err != nil {
+		t.Fatalf("ReadLintRules() error = %v", err)
+	}
+	if rules[1].Level != LintLevelWarning {
+		t.Errorf("default level = %q, want %q", rules[1].Level, LintLevelWarning)
+	}
+
+	got := LintParams(bssTypes.BootParams{Params: "quiet console=tty0 debug"}, rules)
+	want := []LintFinding{
+		{Rule: "serial-console", Level: LintLevelError, Message: "console=ttyS0,* must be passed"},
+		{Rule: "no-quiet", Level: LintLevelWarning, Message: "quiet hides boot problems"},
+		{Rule: "no-debug", Level: LintLevelWarning, Message: `kernel parameters match \bdebug\b`},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LintParams() = %+v, want %+v", got, want)
+	}
+	if got := LintParams(bssTypes.BootParams{Params: "console=ttyS0,115200"}, rules[:1]); len(got) != 0 {
+		t.Errorf("LintParams() = %+v, want no findings", got)
+	}
+
+	for name, content := range map[string]string{
+		"noname.yaml":  "rules:\n- forbid: [quiet]\n",
+		"level.yaml":   "rules:\n- name: a\n  level: fatal\n  forbid: [quiet]\n",
+		"empty.yaml":   "rules:\n- name: a\n",
+		"pattern.yaml": "rules:\n- name: a\n  pattern: '('\n",
+	} {
+		if _, err := ReadLintRules(write(name, content)); err == nil {
+			t.Errorf("ReadLintRules(%s) succeeded, want error", name)
+		}
+	}
+}
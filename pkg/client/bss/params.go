@@ -0,0 +1,119 @@
+package bss
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+)
+
+// The kinds of targets boot parameters can be set for.
+const (
+	ParamsTargetXname = "xname"
+	ParamsTargetMAC   = "mac"
+	ParamsTargetNID   = "nid"
+)
+
+// ParamsTarget identifies a single host, MAC address, or NID that boot
+// parameters are set for.
+type ParamsTarget struct {
+	Kind string
+	ID   string
+}
+
+// ParamsVarsFunc returns the template variables to use when rendering kernel
+// parameters for target.
+type ParamsVarsFunc func(target ParamsTarget) (map[string]any, error)
+
+// IsParamsTemplate returns true if params contains template actions that need
+// to be rendered per target.
+func IsParamsTemplate(params string) bool {
+	return strings.Contains(params, "{{")
+}
+
+// RenderParams renders the kernel parameter template tmpl using vars. Missing
+// variables are an error rather than being rendered as "<no value>".
+func RenderParams(tmpl string, vars map[string]any) (string, error) {
+	t, err := template.New("params").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse kernel parameter template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("failed to render kernel parameter template: %w", err)
+	}
+	return buf.String(), nil
+}
+
+// ExpandParamsTemplate renders the kernel parameters of bp as a template for
+// each of its hosts, MACs, and NIDs, using varsFunc to get the variables for
+// each. Targets whose rendered parameters are identical are grouped together,
+// and a copy of bp is returned for each distinct set of rendered parameters,
+// in the order they were first seen. If bp.Params is not a template, bp is
+// returned as the only element.
+func ExpandParamsTemplate(bp bssTypes.BootParams, varsFunc ParamsVarsFunc) ([]bssTypes.BootParams, error) {
+	if !IsParamsTemplate(bp.Params) {
+		return []bssTypes.BootParams{bp}, nil
+	}
+
+	var (
+		bps   []bssTypes.BootParams
+		index = make(map[string]int)
+	)
+	for _, t := range paramsTargets(bp) {
+		vars, err := varsFunc(t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get template variables for %s %s: %w", t.Kind, t.ID, err)
+		}
+		params, err := RenderParams(bp.Params, vars)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: %w", t.Kind, t.ID, err)
+		}
+		i, ok := index[params]
+		if !ok {
+			i = len(bps)
+			index[params] = i
+			bps = append(bps, bssTypes.BootParams{
+				Params:    params,
+				Kernel:    bp.Kernel,
+				Initrd:    bp.Initrd,
+				CloudInit: bp.CloudInit,
+			})
+		}
+		addParamsTarget(&bps[i], t)
+	}
+
+	return bps, nil
+}
+
+// paramsTargets returns the hosts, MACs, and NIDs of bp as targets.
+func paramsTargets(bp bssTypes.BootParams) []ParamsTarget {
+	var targets []ParamsTarget
+	for _, x := range bp.Hosts {
+		targets = append(targets, ParamsTarget{Kind: ParamsTargetXname, ID: x})
+	}
+	for _, m := range bp.Macs {
+		targets = append(targets, ParamsTarget{Kind: ParamsTargetMAC, ID: m})
+	}
+	for _, n := range bp.Nids {
+		targets = append(targets, ParamsTarget{Kind: ParamsTargetNID, ID: strconv.Itoa(int(n))})
+	}
+
+	return targets
+}
+
+// addParamsTarget adds target t to the hosts, MACs, or NIDs of bp.
+func addParamsTarget(bp *bssTypes.BootParams, t ParamsTarget) {
+	switch t.Kind {
+	case ParamsTargetXname:
+		bp.Hosts = append(bp.Hosts, t.ID)
+	case ParamsTargetMAC:
+		bp.Macs = append(bp.Macs, t.ID)
+	case ParamsTargetNID:
+		n, _ := strconv.Atoi(t.ID)
+		bp.Nids = append(bp.Nids, int32(n))
+	}
+}
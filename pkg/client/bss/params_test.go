@@ -0,0 +1,93 @@
+package bss
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+)
+
+func TestRenderParams(t *testing.T) {
+	vars := map[string]any{
+		"nid":       1,
+		"meta_data": map[string]any{"cluster_name": "demo", "local-ipv4": "10.0.0.1"},
+	}
+	tests := []struct {
+		name    string
+		tmpl    string
+		want    string
+		wantErr bool
+	}{
+		{name: "plain", tmpl: "quiet", want: "quiet"},
+		{name: "nid", tmpl: "console=ttyS0 nid={{ .nid }}", want: "console=ttyS0 nid=1"},
+		{name: "meta-data", tmpl: "cluster={{ .meta_data.cluster_name }}", want: "cluster=demo"},
+		{name: "hyphenated key", tmpl: `ip={{ index .meta_data "local-ipv4" }}`, want: "ip=10.0.0.1"},
+		{name: "missing key", tmpl: "{{ .xname }}", wantErr: true},
+		{name: "bad syntax", tmpl: "{{ .nid", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderParams(tt.tmpl, vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RenderParams() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandParamsTemplate(t *testing.T) {
+	roles := map[string]string{
+		"x1000c0s0b0n0": "Compute",
+		"x1000c0s1b0n0": "Compute",
+		"2":             "Management",
+	}
+	varsFunc := func(target ParamsTarget) (map[string]any, error) {
+		role, ok := roles[target.ID]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return map[string]any{"role": role}, nil
+	}
+
+	t.Run("not a template", func(t *testing.T) {
+		bp := bssTypes.BootParams{Hosts: []string{"x1000c0s0b0n0"}, Params: "quiet"}
+		got, err := ExpandParamsTemplate(bp, varsFunc)
+		if err != nil {
+			t.Fatalf("ExpandParamsTemplate() returned error: %v", err)
+		}
+		if !reflect.DeepEqual(got, []bssTypes.BootParams{bp}) {
+			t.Errorf("got %+v, want %+v", got, bp)
+		}
+	})
+
+	t.Run("grouped by rendered params", func(t *testing.T) {
+		bp := bssTypes.BootParams{
+			Hosts:  []string{"x1000c0s0b0n0", "x1000c0s1b0n0"},
+			Nids:   []int32{2},
+			Kernel: "http://example.com/vmlinuz",
+			Params: "role={{ .role }}",
+		}
+		got, err := ExpandParamsTemplate(bp, varsFunc)
+		if err != nil {
+			t.Fatalf("ExpandParamsTemplate() returned error: %v", err)
+		}
+		want := []bssTypes.BootParams{
+			{Hosts: []string{"x1000c0s0b0n0", "x1000c0s1b0n0"}, Kernel: "http://example.com/vmlinuz", Params: "role=Compute"},
+			{Nids: []int32{2}, Kernel: "http://example.com/vmlinuz", Params: "role=Management"},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("variable lookup failure", func(t *testing.T) {
+		bp := bssTypes.BootParams{Macs: []string{"de:ad:be:ee:ef:00"}, Params: "role={{ .role }}"}
+		if _, err := ExpandParamsTemplate(bp, varsFunc); err == nil {
+			t.Error("expected error, got nil")
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
 
@@ -62,11 +63,12 @@ func DecodeCloudConfig(ccf cistore.CloudConfigFile) ([]byte, error) {
 	case "plain":
 		return ccf.Content, nil
 	case "base64":
-		contentBytes := make([]byte, base64.StdEncoding.EncodedLen(len(ccf.Content)))
-		if n, err := base64.StdEncoding.Decode(contentBytes, ccf.Content); err != nil {
+		contentBytes := make([]byte, base64.StdEncoding.DecodedLen(len(ccf.Content)))
+		n, err := base64.StdEncoding.Decode(contentBytes, ccf.Content)
+		if err != nil {
 			return []byte{}, fmt.Errorf("failed to base64 decode cloud config (read %d bytes): %w", n, err)
 		}
-		return contentBytes, nil
+		return contentBytes[:n], nil
 	default:
 		return []byte{}, fmt.Errorf("unknown encoding for cloud-config: %s", ccf.Encoding)
 	}
@@ -437,6 +439,59 @@ func (cic *CloudInitClient) PutInstanceInfo(instanceInfoList []cistore.OpenCHAMI
 	return henvs, errors, nil
 }
 
+// DeleteInstanceInfo takes a token and node IDs and sends a DELETE to
+// cloud-init for the instance info of each node, so that the hostnames and
+// other meta-data set for it are no longer served. Versions of cloud-init
+// without the DELETE endpoint respond with 405 Method Not Allowed, in which
+// case the instance info is instead cleared by PUTting one with only the ID of
+// the node, which keeps its instance ID. A slice of client.HTTPEnvelopes is
+// returned, containing one per node. Any corresponding errors are also
+// returned. If an error in the function itself occurs, an additional error is
+// returned in order to distinguish HTTP request errors from control flow
+// errors.
+func (cic *CloudInitClient) DeleteInstanceInfo(token string, ids ...string) ([]client.HTTPEnvelope, []error, error) {
+	var (
+		errors  []error
+		henvs   []client.HTTPEnvelope
+		headers *client.HTTPHeaders
+	)
+	headers = client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			return henvs, errors, fmt.Errorf("DeleteInstanceInfo(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	for _, id := range ids {
+		finalEP, err := url.JoinPath(CloudInitRelpathInstanceInfo, id)
+		if err != nil {
+			newErr := fmt.Errorf("DeleteInstanceInfo(): failed join %q with %q: %w", CloudInitRelpathInstanceInfo, id, err)
+			henvs = append(henvs, client.HTTPEnvelope{})
+			errors = append(errors, newErr)
+			continue
+		}
+		henv, err := cic.DeleteData(finalEP, "", headers, nil)
+		if err != nil && henv.StatusCode == http.StatusMethodNotAllowed {
+			var body client.HTTPBody
+			if body, err = json.Marshal(cistore.OpenCHAMIInstanceInfo{ID: id}); err != nil {
+				newErr := fmt.Errorf("DeleteInstanceInfo(): failed to marshal empty instance info: %w", err)
+				henvs = append(henvs, client.HTTPEnvelope{})
+				errors = append(errors, newErr)
+				continue
+			}
+			henv, err = cic.PutData(finalEP, "", headers, body)
+		}
+		henvs = append(henvs, henv)
+		if err != nil {
+			newErr := fmt.Errorf("DeleteInstanceInfo(): failed to DELETE instance info for %s in cloud-init: %w", id, err)
+			errors = append(errors, newErr)
+			continue
+		}
+		errors = append(errors, nil)
+	}
+
+	return henvs, errors, nil
+}
+
 // DeleteGroups takes a token and group names and iteratively calls
 // OchamiClient.DeleteData for each group. The iteration is necessary as the
 // delete endpoint only allows deleting one group at a time. A slice of
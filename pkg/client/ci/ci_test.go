@@ -0,0 +1,89 @@
+package ci
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+)
+
+func TestDecodeCloudConfig(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		ccf   cistore.CloudConfigFile
+		want  []byte
+		isErr bool
+	}{
+		{name: "plain", ccf: cistore.CloudConfigFile{Content: []byte("#cloud-config\n"), Encoding: "plain"}, want: []byte("#cloud-config\n")},
+		{name: "base64", ccf: cistore.CloudConfigFile{Content: []byte("I2Nsb3VkLWNvbmZpZwo="), Encoding: "base64"}, want: []byte("#cloud-config\n")},
+		{name: "invalid base64", ccf: cistore.CloudConfigFile{Content: []byte("!!"), Encoding: "base64"}, isErr: true},
+		{name: "unknown encoding", ccf: cistore.CloudConfigFile{Content: []byte("x"), Encoding: "rot13"}, isErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCloudConfig(tt.ccf)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("unexpected error state: got err=%v, want error=%v", err, tt.isErr)
+			}
+			if !tt.isErr && !bytes.Equal(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteInstanceInfo(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		allowDelete bool
+		wantMethods []string
+	}{
+		{name: "delete supported", allowDelete: true, wantMethods: []string{http.MethodDelete}},
+		{name: "delete not supported", allowDelete: false, wantMethods: []string{http.MethodDelete, http.MethodPut}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var methods []string
+			var put cistore.OpenCHAMIInstanceInfo
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				methods = append(methods, r.Method)
+				if r.URL.Path != CloudInitRelpathInstanceInfo+"/x1000c0s0b0n0" {
+					t.Errorf("unexpected path %s", r.URL.Path)
+				}
+				switch {
+				case r.Method == http.MethodDelete && tt.allowDelete:
+					w.WriteHeader(http.StatusOK)
+				case r.Method == http.MethodPut:
+					body, _ := io.ReadAll(r.Body)
+					json.Unmarshal(body, &put)
+					w.WriteHeader(http.StatusCreated)
+				default:
+					w.WriteHeader(http.StatusMethodNotAllowed)
+				}
+			}))
+			defer srv.Close()
+
+			cic, err := NewClient(srv.URL, false)
+			if err != nil {
+				t.Fatalf("NewClient() failed: %v", err)
+			}
+			_, errs, err := cic.DeleteInstanceInfo("", "x1000c0s0b0n0")
+			if err != nil || len(errs) != 1 || errs[0] != nil {
+				t.Fatalf("DeleteInstanceInfo() returned errors %v, %v", errs, err)
+			}
+			if len(methods) != len(tt.wantMethods) {
+				t.Fatalf("DeleteInstanceInfo() sent %v, want %v", methods, tt.wantMethods)
+			}
+			for i := range methods {
+				if methods[i] != tt.wantMethods[i] {
+					t.Errorf("DeleteInstanceInfo() sent %v, want %v", methods, tt.wantMethods)
+				}
+			}
+			if !tt.allowDelete && (put.ID != "x1000c0s0b0n0" || put.LocalHostname != "" || put.Hostname != "") {
+				t.Errorf("DeleteInstanceInfo() PUT %+v, want instance info with only the ID", put)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package ci
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LocalCloudConfig is the cloud-config of a group read from a directory of
+// cloud-configs.
+type LocalCloudConfig struct {
+	Group   string
+	Path    string
+	Content []byte
+}
+
+// ReadCloudConfigDir reads the cloud-configs in dir, one per group, each named
+// after its group with an optional extension (e.g. compute.yaml for the compute
+// group). Hidden files and subdirectories are skipped. The cloud-configs are
+// returned sorted by group. It is an error for two files to be for the same
+// group.
+func ReadCloudConfigDir(dir string) ([]LocalCloudConfig, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cloud-config directory: %w", err)
+	}
+	byGroup := make(map[string]LocalCloudConfig)
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		group := strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))
+		p := filepath.Join(dir, e.Name())
+		if other, ok := byGroup[group]; ok {
+			return nil, fmt.Errorf("both %s and %s are cloud-configs for group %s", other.Path, p, group)
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cloud-config for group %s: %w", group, err)
+		}
+		byGroup[group] = LocalCloudConfig{Group: group, Path: p, Content: content}
+	}
+
+	configs := make([]LocalCloudConfig, 0, len(byGroup))
+	for _, lcc := range byGroup {
+		configs = append(configs, lcc)
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Group < configs[j].Group })
+
+	return configs, nil
+}
@@ -0,0 +1,48 @@
+package ci
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestReadCloudConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"compute.yaml": "#cloud-config\n",
+		"gpu":          "#cloud-config\npackages: [cuda]\n",
+		".hidden.yaml": "ignored",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "subdir"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := ReadCloudConfigDir(dir)
+	if err != nil {
+		t.Fatalf("ReadCloudConfigDir() error = %v", err)
+	}
+	want := []LocalCloudConfig{
+		{Group: "compute", Path: filepath.Join(dir, "compute.yaml"), Content: []byte("#cloud-config\n")},
+		{Group: "gpu", Path: filepath.Join(dir, "gpu"), Content: []byte("#cloud-config\npackages: [cuda]\n")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadCloudConfigDir() = %+v, want %+v", got, want)
+	}
+
+	// Two files for the same group
+	if err := os.WriteFile(filepath.Join(dir, "gpu.yml"), []byte("#cloud-config\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadCloudConfigDir(dir); err == nil {
+		t.Error("ReadCloudConfigDir() succeeded with two cloud-configs for one group, want error")
+	}
+
+	if _, err := ReadCloudConfigDir(filepath.Join(dir, "missing")); err == nil {
+		t.Error("ReadCloudConfigDir() succeeded for missing directory, want error")
+	}
+}
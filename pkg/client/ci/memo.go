@@ -0,0 +1,60 @@
+package ci
+
+import "sync"
+
+// Memo caches the results of a function of a key for the duration of a run,
+// e.g. fetching the URL of an #include part that every node of a group
+// includes. The function is called at most once per key: concurrent calls of
+// Get for a key that is being fetched wait for that call and share its result
+// rather than sending the same request again. Errors are cached as well, so a
+// failing key is not retried. A Memo is safe for concurrent use.
+type Memo[V any] struct {
+	fn    func(key string) (V, error)
+	mu    sync.Mutex
+	calls map[string]*memoCall[V]
+}
+
+// memoCall is the call of the function of a Memo for one key, whose result is
+// available once done is closed.
+type memoCall[V any] struct {
+	done chan struct{}
+	v    V
+	err  error
+}
+
+// NewMemo returns a pointer to a new Memo caching the results of fn.
+func NewMemo[V any](fn func(key string) (V, error)) *Memo[V] {
+	return &Memo[V]{
+		fn:    fn,
+		calls: make(map[string]*memoCall[V]),
+	}
+}
+
+// Get returns the result of the function of m for key, calling it only if no
+// call for key has been made yet.
+func (m *Memo[V]) Get(key string) (V, error) {
+	m.mu.Lock()
+	c, ok := m.calls[key]
+	if !ok {
+		c = &memoCall[V]{done: make(chan struct{})}
+		m.calls[key] = c
+	}
+	m.mu.Unlock()
+
+	if ok {
+		<-c.done
+		return c.v, c.err
+	}
+	// Close done even if fn panics so that waiters are not stuck forever
+	defer close(c.done)
+	c.v, c.err = m.fn(key)
+
+	return c.v, c.err
+}
+
+// Len returns the number of keys whose function was called.
+func (m *Memo[V]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.calls)
+}
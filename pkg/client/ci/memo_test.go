@@ -0,0 +1,55 @@
+package ci
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestMemo(t *testing.T) {
+	var calls atomic.Int32
+	release := make(chan struct{})
+	m := NewMemo(func(key string) (string, error) {
+		calls.Add(1)
+		<-release
+		if key == "bad" {
+			return "", errors.New("failed")
+		}
+		return "value of " + key, nil
+	})
+
+	// Concurrent calls for the same key are coalesced into one
+	var wg sync.WaitGroup
+	results := make([]string, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], _ = m.Get("a")
+		}()
+	}
+	close(release)
+	wg.Wait()
+	for i, r := range results {
+		if r != "value of a" {
+			t.Errorf("Get() call %d = %q, want %q", i, r, "value of a")
+		}
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("function called %d times for concurrent calls, want 1", n)
+	}
+
+	// Errors are cached too
+	for range 2 {
+		if _, err := m.Get("bad"); err == nil {
+			t.Error("Get() of failing key returned no error")
+		}
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("function called %d times, want 2", n)
+	}
+	if n := m.Len(); n != 2 {
+		t.Errorf("Len() = %d, want 2", n)
+	}
+}
@@ -0,0 +1,162 @@
+package ci
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+	"gopkg.in/yaml.v3"
+)
+
+// LocalNodeFile is a file of cloud-init data of a node read from a directory of
+// node data.
+type LocalNodeFile struct {
+	Type    CIDataType
+	Path    string
+	Content []byte
+}
+
+// Hash returns the SHA-256 hash of the content of lnf, by which changes to it
+// are detected.
+func (lnf LocalNodeFile) Hash() string {
+	sum := sha256.Sum256(lnf.Content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// LocalNodeData is the cloud-init data of a node read from a directory of node
+// data.
+type LocalNodeData struct {
+	ID    string
+	Files []LocalNodeFile
+}
+
+// ReadNodeDataDir reads the cloud-init data of nodes in dir, which has one
+// subdirectory per node named after its ID (e.g. its xname) that contains any
+// of meta-data, user-data, and vendor-data. Hidden files and directories, and
+// files directly in dir, are skipped. It is an error for a node directory to
+// have any other file, so that misnamed files are not silently ignored. The
+// nodes are returned sorted by ID, and their files in the order above.
+func ReadNodeDataDir(dir string) ([]LocalNodeData, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read node data directory: %w", err)
+	}
+	var nodes []LocalNodeData
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		nd := LocalNodeData{ID: e.Name()}
+		nodeDir := filepath.Join(dir, e.Name())
+		files, err := os.ReadDir(nodeDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read data directory of node %s: %w", nd.ID, err)
+		}
+		for _, f := range files {
+			if strings.HasPrefix(f.Name(), ".") {
+				continue
+			}
+			p := filepath.Join(nodeDir, f.Name())
+			dt := CIDataType(f.Name())
+			switch dt {
+			case CloudInitMetaData, CloudInitUserData, CloudInitVendorData:
+			default:
+				return nil, fmt.Errorf("unknown node data file %s (want %s, %s, or %s)", p, CloudInitMetaData, CloudInitUserData, CloudInitVendorData)
+			}
+			if f.IsDir() {
+				return nil, fmt.Errorf("node data file %s is a directory", p)
+			}
+			content, err := os.ReadFile(p)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s of node %s: %w", dt, nd.ID, err)
+			}
+			nd.Files = append(nd.Files, LocalNodeFile{Type: dt, Path: p, Content: content})
+		}
+		order := map[CIDataType]int{CloudInitMetaData: 0, CloudInitUserData: 1, CloudInitVendorData: 2}
+		sort.Slice(nd.Files, func(i, j int) bool { return order[nd.Files[i].Type] < order[nd.Files[j].Type] })
+		nodes = append(nodes, nd)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	return nodes, nil
+}
+
+// ParseNodeMetaData parses content, the meta-data of node id in YAML (or
+// JSON), into the instance info to set for the node. Unknown keys are an error.
+// The id key may be omitted, but if set must be id.
+func ParseNodeMetaData(id string, content []byte) (cistore.OpenCHAMIInstanceInfo, error) {
+	var info cistore.OpenCHAMIInstanceInfo
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(&info); err != nil && !errors.Is(err, io.EOF) {
+		return info, fmt.Errorf("failed to parse meta-data of node %s: %w", id, err)
+	}
+	if info.ID != "" && info.ID != id {
+		return info, fmt.Errorf("meta-data of node %s has id %s", id, info.ID)
+	}
+	info.ID = id
+
+	return info, nil
+}
+
+// ImportState records the hashes of the node data files last imported into
+// cloud-init, so that only files that changed since are imported again. It maps
+// the base URI of each cloud-init service imported into to the hash of each
+// file, keyed by node ID and file type (e.g. x1000c0s0b0n0/meta-data), so one
+// directory can be imported into several clusters.
+type ImportState map[string]map[string]string
+
+// LoadImportState reads the import state in path. If path does not exist, the
+// import state is empty.
+func LoadImportState(path string) (ImportState, error) {
+	state := make(ImportState)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read import state: %w", err)
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal import state %s: %w", path, err)
+	}
+
+	return state, nil
+}
+
+// Save writes state to path.
+func (state ImportState) Save(path string) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal import state: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write import state: %w", err)
+	}
+
+	return nil
+}
+
+// Changed returns whether file lnf of node id changed since it was last
+// imported into the cloud-init service at uri.
+func (state ImportState) Changed(uri, id string, lnf LocalNodeFile) bool {
+	return state[uri][id+"/"+string(lnf.Type)] != lnf.Hash()
+}
+
+// Imported records that file lnf of node id was imported into the cloud-init
+// service at uri.
+func (state ImportState) Imported(uri, id string, lnf LocalNodeFile) {
+	if state[uri] == nil {
+		state[uri] = make(map[string]string)
+	}
+	state[uri][id+"/"+string(lnf.Type)] = lnf.Hash()
+}
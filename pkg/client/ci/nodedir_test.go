@@ -0,0 +1,95 @@
+package ci
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadNodeDataDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"x1000c0s0b0n0/meta-data":   "local-hostname: nid001\n",
+		"x1000c0s0b0n0/user-data":   "#cloud-config\n",
+		"x1000c0s0b1n0/vendor-data": "#cloud-config\n",
+		"x1000c0s0b1n0/.swp":        "ignored",
+		".git/meta-data":            "ignored",
+		"README.md":                 "ignored",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := ReadNodeDataDir(dir)
+	if err != nil {
+		t.Fatalf("ReadNodeDataDir() error = %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "x1000c0s0b0n0" || got[1].ID != "x1000c0s0b1n0" {
+		t.Fatalf("ReadNodeDataDir() = %+v, want nodes x1000c0s0b0n0 and x1000c0s0b1n0", got)
+	}
+	if len(got[0].Files) != 2 || got[0].Files[0].Type != CloudInitMetaData || got[0].Files[1].Type != CloudInitUserData {
+		t.Errorf("ReadNodeDataDir() files of %s = %+v, want meta-data and user-data", got[0].ID, got[0].Files)
+	}
+	if len(got[1].Files) != 1 || got[1].Files[0].Type != CloudInitVendorData {
+		t.Errorf("ReadNodeDataDir() files of %s = %+v, want vendor-data", got[1].ID, got[1].Files)
+	}
+
+	// Misnamed file
+	if err := os.WriteFile(filepath.Join(dir, "x1000c0s0b0n0", "userdata"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadNodeDataDir(dir); err == nil {
+		t.Error("ReadNodeDataDir() succeeded with unknown file, want error")
+	}
+}
+
+func TestParseNodeMetaData(t *testing.T) {
+	info, err := ParseNodeMetaData("x1", []byte("local-hostname: nid001\npublic-keys: [ssh-ed25519 AAAA]\n"))
+	if err != nil {
+		t.Fatalf("ParseNodeMetaData() error = %v", err)
+	}
+	if info.ID != "x1" || info.LocalHostname != "nid001" || len(info.PublicKeys) != 1 {
+		t.Errorf("ParseNodeMetaData() = %+v", info)
+	}
+	if _, err := ParseNodeMetaData("x1", []byte("id: x2\n")); err == nil {
+		t.Error("ParseNodeMetaData() succeeded with mismatching id, want error")
+	}
+	if _, err := ParseNodeMetaData("x1", []byte("local_hostname: nid001\n")); err == nil {
+		t.Error("ParseNodeMetaData() succeeded with unknown key, want error")
+	}
+}
+
+func TestImportState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := LoadImportState(path)
+	if err != nil {
+		t.Fatalf("LoadImportState() of missing file error = %v", err)
+	}
+	lnf := LocalNodeFile{Type: CloudInitMetaData, Content: []byte("local-hostname: nid001\n")}
+	if !state.Changed("http://a", "x1", lnf) {
+		t.Error("Changed() = false before import, want true")
+	}
+	state.Imported("http://a", "x1", lnf)
+	if err := state.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if state, err = LoadImportState(path); err != nil {
+		t.Fatal(err)
+	}
+	if state.Changed("http://a", "x1", lnf) {
+		t.Error("Changed() = true after import, want false")
+	}
+	if !state.Changed("http://b", "x1", lnf) {
+		t.Error("Changed() = false for another cluster, want true")
+	}
+	lnf.Content = []byte("local-hostname: nid002\n")
+	if !state.Changed("http://a", "x1", lnf) {
+		t.Error("Changed() = false after edit, want true")
+	}
+}
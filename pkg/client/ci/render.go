@@ -0,0 +1,282 @@
+package ci
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoRuncmd is returned by RuncmdScript for cloud-config without runcmd.
+var ErrNoRuncmd = errors.New("cloud-config has no runcmd")
+
+// shellSafePattern matches words that need no quoting in a shell command line.
+var shellSafePattern = regexp.MustCompile(`^[A-Za-z0-9@%+=:,./_-]+$`)
+
+// MakeMIME assembles resolved user-data into a MIME multi-part archive the way
+// "cloud-init devel make-mime" does: the merged cloud-config, if any parts were
+// merged into it, followed by each part that is not cloud-config. Each part is
+// sent as is (7bit) if it is ASCII, and base64-encoded otherwise.
+func MakeMIME(res ResolvedUserData) ([]byte, error) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=%q\r\nMIME-Version: 1.0\r\n\r\n", mw.Boundary())
+
+	var parts []UserDataPart
+	if len(res.Parts) > 0 {
+		parts = append(parts, UserDataPart{
+			Filename:    "cloud-config.yaml",
+			ContentType: "text/cloud-config",
+			Content:     res.Config,
+		})
+	}
+	parts = append(parts, res.Other...)
+	for i, p := range parts {
+		name := p.Filename
+		if name == "" {
+			name = fmt.Sprintf("part-%03d", i+1)
+		}
+		h := textproto.MIMEHeader{}
+		h.Set("MIME-Version", "1.0")
+		h.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+		content := p.Content
+		if isASCII(content) {
+			h.Set("Content-Type", fmt.Sprintf("%s; charset=\"us-ascii\"", p.ContentType))
+			h.Set("Content-Transfer-Encoding", "7bit")
+		} else {
+			h.Set("Content-Type", fmt.Sprintf("%s; charset=\"utf-8\"", p.ContentType))
+			h.Set("Content-Transfer-Encoding", "base64")
+			content = base64Lines(content)
+		}
+		w, err := mw.CreatePart(h)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create MIME part for %s: %w", name, err)
+		}
+		if _, err := w.Write(content); err != nil {
+			return nil, fmt.Errorf("failed to write MIME part for %s: %w", name, err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish MIME archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// isASCII returns true if b only contains 7-bit ASCII characters.
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= 0x80 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// base64Lines returns b base64-encoded in lines of 76 characters.
+func base64Lines(b []byte) []byte {
+	enc := base64.StdEncoding.EncodeToString(b)
+	var buf bytes.Buffer
+	for len(enc) > 76 {
+		buf.WriteString(enc[:76] + "\r\n")
+		enc = enc[76:]
+	}
+	buf.WriteString(enc + "\r\n")
+
+	return buf.Bytes()
+}
+
+// RuncmdScript returns the runcmd list of cloud-config config as the shell
+// script cloud-init runs on the node: each item that is a string is a line of
+// the script as is, and each item that is a list is a command whose arguments
+// are quoted for the shell. If config has no runcmd, ErrNoRuncmd is returned.
+func RuncmdScript(config []byte) ([]byte, error) {
+	var cfg struct {
+		Runcmd []any `yaml:"runcmd"`
+	}
+	if err := yaml.Unmarshal(config, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse cloud-config: %w", err)
+	}
+	if len(cfg.Runcmd) == 0 {
+		return nil, ErrNoRuncmd
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("#!/bin/sh\n")
+	for i, item := range cfg.Runcmd {
+		switch v := item.(type) {
+		case string:
+			buf.WriteString(v + "\n")
+		case []any:
+			args := make([]string, len(v))
+			for j, a := range v {
+				args[j] = shellQuote(fmt.Sprint(a))
+			}
+			buf.WriteString(strings.Join(args, " ") + "\n")
+		default:
+			return nil, fmt.Errorf("runcmd item %d is neither a string nor a list", i+1)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// shellQuote quotes s for the shell if needed.
+func shellQuote(s string) string {
+	if shellSafePattern.MatchString(s) {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// WriteFile is an item of write_files in cloud-config.
+type WriteFile struct {
+	Path        string `yaml:"path"`
+	Content     string `yaml:"content"`
+	Encoding    string `yaml:"encoding"`
+	Permissions any    `yaml:"permissions"`
+	Owner       string `yaml:"owner"`
+	Append      bool   `yaml:"append"`
+	Defer       bool   `yaml:"defer"`
+	Source      struct {
+		URI string `yaml:"uri"`
+	} `yaml:"source"`
+}
+
+// Mode returns the permissions of wf, which are octal if they are a string
+// (e.g. "0755"), defaulting to 0644 like cloud-init.
+func (wf WriteFile) Mode() (os.FileMode, error) {
+	switch p := wf.Permissions.(type) {
+	case nil:
+		return 0644, nil
+	case int:
+		return os.FileMode(p), nil
+	case string:
+		m, err := strconv.ParseUint(strings.TrimPrefix(strings.TrimSpace(p), "0o"), 8, 32)
+		if err != nil {
+			return 0, fmt.Errorf("invalid permissions %q", p)
+		}
+		return os.FileMode(m), nil
+	default:
+		return 0, fmt.Errorf("invalid permissions %v", p)
+	}
+}
+
+// Decode returns the content of wf decoded according to its encoding (b64,
+// gzip, or gz+b64 and their aliases).
+func (wf WriteFile) Decode() ([]byte, error) {
+	content := []byte(wf.Content)
+	switch strings.ToLower(wf.Encoding) {
+	case "", "text/plain":
+		return content, nil
+	case "b64", "base64":
+		return base64.StdEncoding.DecodeString(wf.Content)
+	case "gz", "gzip":
+		return gunzip(content)
+	case "gz+b64", "gz+base64", "gzip+b64", "gzip+base64":
+		b, err := base64.StdEncoding.DecodeString(wf.Content)
+		if err != nil {
+			return nil, err
+		}
+		return gunzip(b)
+	default:
+		return nil, fmt.Errorf("unknown encoding %q", wf.Encoding)
+	}
+}
+
+// gunzip returns b decompressed with gzip.
+func gunzip(b []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	return io.ReadAll(zr)
+}
+
+// ExtractedFile is a file from write_files extracted by ExtractWriteFiles.
+// Path is its path on the node and File is the path it was extracted to.
+type ExtractedFile struct {
+	Path string
+	File string
+	Mode os.FileMode
+}
+
+// ExtractWriteFiles writes the files in the write_files list of cloud-config
+// config to the directory tree under dir, each at its path on the node
+// relative to dir, so that they can be inspected. Files are written in order,
+// honoring append, with their permissions (but not their owner). The content
+// of files with a source URI is fetched with fetch, falling back to their
+// content like cloud-init if fetching fails.
+func ExtractWriteFiles(config []byte, dir string, fetch UserDataFetcher) ([]ExtractedFile, error) {
+	var cfg struct {
+		WriteFiles []WriteFile `yaml:"write_files"`
+	}
+	if err := yaml.Unmarshal(config, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse cloud-config: %w", err)
+	}
+
+	var files []ExtractedFile
+	for i, wf := range cfg.WriteFiles {
+		if wf.Path == "" {
+			return files, fmt.Errorf("write_files item %d has no path", i+1)
+		}
+		mode, err := wf.Mode()
+		if err != nil {
+			return files, fmt.Errorf("write_files item %d (%s): %w", i+1, wf.Path, err)
+		}
+		var content []byte
+		if wf.Source.URI != "" && fetch != nil {
+			content, err = fetch(wf.Source.URI)
+			if err != nil && wf.Content == "" {
+				return files, fmt.Errorf("write_files item %d (%s): failed to fetch %s: %w", i+1, wf.Path, wf.Source.URI, err)
+			}
+		}
+		if content == nil {
+			if content, err = wf.Decode(); err != nil {
+				return files, fmt.Errorf("write_files item %d (%s): failed to decode content: %w", i+1, wf.Path, err)
+			}
+		}
+
+		// Cleaning the path as an absolute one keeps it under dir
+		file := filepath.Join(dir, filepath.Clean("/"+wf.Path))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return files, fmt.Errorf("failed to create directory for %s: %w", wf.Path, err)
+		}
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if wf.Append {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		f, err := os.OpenFile(file, flags, 0600)
+		if err != nil {
+			return files, fmt.Errorf("failed to open %s: %w", file, err)
+		}
+		_, err = f.Write(content)
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return files, fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		if err := os.Chmod(file, mode.Perm()); err != nil {
+			return files, fmt.Errorf("failed to set permissions of %s: %w", file, err)
+		}
+		files = append(files, ExtractedFile{Path: wf.Path, File: file, Mode: mode.Perm()})
+	}
+
+	return files, nil
+}
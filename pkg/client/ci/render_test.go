@@ -0,0 +1,101 @@
+package ci
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMakeMIME(t *testing.T) {
+	res := ResolvedUserData{
+		Config: []byte("#cloud-config\nhostname: x\n"),
+		Parts:  []string{"group compute"},
+		Other: []UserDataPart{
+			{ContentType: "text/x-shellscript", Content: []byte("#!/bin/sh\necho hi\n")},
+			{Filename: "motd.sh", ContentType: "text/x-shellscript", Content: []byte("#!/bin/sh\necho héllo\n")},
+		},
+	}
+	mime, err := MakeMIME(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The archive resolves back to the same parts
+	got, err := UserDataResolver{}.Resolve("mime", mime)
+	if err != nil {
+		t.Fatalf("Resolve(MakeMIME()) error = %v", err)
+	}
+	if want := []string{"mime part 1 (cloud-config.yaml)"}; !reflect.DeepEqual(got.Parts, want) {
+		t.Errorf("Parts = %v, want %v", got.Parts, want)
+	}
+	if len(got.Other) != 2 || got.Other[0].Filename != "part-002" || !bytes.Equal(got.Other[1].Content, res.Other[1].Content) {
+		t.Errorf("Other = %+v, want both scripts", got.Other)
+	}
+}
+
+func TestRuncmdScript(t *testing.T) {
+	got, err := RuncmdScript([]byte("#cloud-config\nruncmd:\n- echo $HOME > /tmp/x\n- [ls, -l, /my dir, \"it's\"]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "#!/bin/sh\necho $HOME > /tmp/x\nls -l '/my dir' 'it'\"'\"'s'\n"
+	if string(got) != want {
+		t.Errorf("RuncmdScript() = %q, want %q", got, want)
+	}
+	if _, err := RuncmdScript([]byte("#cloud-config\nhostname: x\n")); !errors.Is(err, ErrNoRuncmd) {
+		t.Errorf("RuncmdScript() without runcmd error = %v, want ErrNoRuncmd", err)
+	}
+	if _, err := RuncmdScript([]byte("runcmd: [{a: b}]\n")); err == nil {
+		t.Error("RuncmdScript() with map item succeeded, want error")
+	}
+}
+
+func TestExtractWriteFiles(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("zipped\n"))
+	zw.Close()
+	config := "#cloud-config\nwrite_files:\n" +
+		"- path: /etc/motd\n  content: hello\n" +
+		"- path: /etc/motd\n  content: \" again\"\n  append: true\n" +
+		"- path: /usr/local/bin/run\n  content: " + base64.StdEncoding.EncodeToString([]byte("#!/bin/sh\n")) + "\n  encoding: b64\n  permissions: '0755'\n" +
+		"- path: ../../escape\n  content: " + base64.StdEncoding.EncodeToString(gz.Bytes()) + "\n  encoding: gz+b64\n  permissions: 0600\n" +
+		"- path: /etc/fetched\n  source: {uri: http://example.com/f}\n"
+	dir := t.TempDir()
+	fetch := func(url string) ([]byte, error) { return []byte("fetched " + url), nil }
+	files, err := ExtractWriteFiles([]byte(config), dir, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 5 {
+		t.Fatalf("ExtractWriteFiles() = %+v, want 5 files", files)
+	}
+	for path, want := range map[string]string{
+		"etc/motd":          "hello again",
+		"usr/local/bin/run": "#!/bin/sh\n",
+		"escape":            "zipped\n",
+		"etc/fetched":       "fetched http://example.com/f",
+	} {
+		b, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil || string(b) != want {
+			t.Errorf("%s = %q, %v, want %q", path, b, err, want)
+		}
+	}
+	for path, want := range map[string]os.FileMode{"usr/local/bin/run": 0755, "escape": 0600, "etc/motd": 0644} {
+		if fi, err := os.Stat(filepath.Join(dir, path)); err != nil || fi.Mode().Perm() != want {
+			t.Errorf("mode of %s = %v, %v, want %v", path, fi.Mode().Perm(), err, want)
+		}
+	}
+
+	if _, err := ExtractWriteFiles([]byte("write_files: [{content: x}]\n"), dir, nil); err == nil {
+		t.Error("ExtractWriteFiles() without path succeeded, want error")
+	}
+	if _, err := ExtractWriteFiles([]byte("write_files: [{path: /x, permissions: rwx}]\n"), dir, nil); err == nil {
+		t.Error("ExtractWriteFiles() with invalid permissions succeeded, want error")
+	}
+}
@@ -0,0 +1,253 @@
+package ci
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+)
+
+// The names of the built-in secret backends, used as the prefix of secret
+// references (e.g. "env:ROOT_PASSWORD").
+const (
+	SecretBackendEnv   = "env"
+	SecretBackendFile  = "file"
+	SecretBackendSOPS  = "sops"
+	SecretBackendVault = "vault"
+)
+
+// secretRefPattern matches secret references in cloud-init templates, e.g.
+// {{ secret "vault:kv/cluster/root-pass" }}. The first submatch is the
+// reference.
+var secretRefPattern = regexp.MustCompile(`\{\{-?\s*secret\s+"([^"]+)"\s*-?\}\}`)
+
+// quotedSecretRefPattern matches secret references like secretRefPattern,
+// along with any quote right before or after them.
+var quotedSecretRefPattern = regexp.MustCompile(`["']?` + secretRefPattern.String() + `["']?`)
+
+// SecretBackend returns the value of the secret at path, which is the part of
+// a secret reference after the backend name and colon.
+type SecretBackend func(path string) (string, error)
+
+// SecretResolver resolves secret references of the form <backend>:<path> using
+// the SecretBackend registered for <backend>. Resolved values are cached so
+// that each secret is only read once.
+type SecretResolver struct {
+	Backends map[string]SecretBackend
+	cache    map[string]string
+}
+
+// NewSecretResolver returns a pointer to a new SecretResolver using backends.
+func NewSecretResolver(backends map[string]SecretBackend) *SecretResolver {
+	return &SecretResolver{
+		Backends: backends,
+		cache:    make(map[string]string),
+	}
+}
+
+// FindSecretRefs returns the unique secret references in content, in the order
+// they first appear.
+func FindSecretRefs(content []byte) []string {
+	var refs []string
+	seen := make(map[string]bool)
+	for _, m := range secretRefPattern.FindAllSubmatch(content, -1) {
+		ref := string(m[1])
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+
+	return refs
+}
+
+// Resolve returns the value of the secret referenced by ref.
+func (sr *SecretResolver) Resolve(ref string) (string, error) {
+	if v, ok := sr.cache[ref]; ok {
+		return v, nil
+	}
+	name, path, ok := strings.Cut(ref, ":")
+	if !ok || path == "" {
+		return "", fmt.Errorf("invalid secret reference %q: must be <backend>:<path>", ref)
+	}
+	backend, ok := sr.Backends[name]
+	if !ok || backend == nil {
+		return "", fmt.Errorf("invalid secret reference %q: unknown or unconfigured backend %q", ref, name)
+	}
+	v, err := backend(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve secret %q: %w", ref, err)
+	}
+	sr.cache[ref] = v
+
+	return v, nil
+}
+
+// ResolveSecrets returns content with each secret reference replaced by the
+// value of the secret as a YAML double-quoted scalar, so that values
+// containing e.g. colons, quotes, or newlines are read as is. A reference that
+// is already quoted is replaced along with its quotes. If any references
+// cannot be resolved, an error joining each failure is returned.
+func (sr *SecretResolver) ResolveSecrets(content []byte) ([]byte, error) {
+	var errs []error
+	for _, ref := range FindSecretRefs(content) {
+		if _, err := sr.Resolve(ref); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return quotedSecretRefPattern.ReplaceAllFunc(content, func(m []byte) []byte {
+		loc := secretRefPattern.FindSubmatchIndex(m)
+		before, after := m[:loc[0]], m[loc[1]:]
+		if len(before) > 0 && bytes.Equal(before, after) {
+			before, after = nil, nil
+		}
+		out := append(slices.Clone(before), yamlQuote(sr.cache[string(m[loc[2]:loc[3]])])...)
+		return append(out, after...)
+	}), nil
+}
+
+// yamlQuote returns s as a YAML double-quoted scalar. JSON strings are valid
+// YAML double-quoted scalars.
+func yamlQuote(s string) string {
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// ResolveGroupSecrets resolves the secret references in the cloud-config file
+// of group in place, taking the file's encoding into account, and returns the
+// number of unique references resolved.
+func (sr *SecretResolver) ResolveGroupSecrets(group *cistore.GroupData) (int, error) {
+	content, err := GroupFileContent(*group)
+	if err != nil {
+		return 0, err
+	}
+	refs := FindSecretRefs(content)
+	if len(refs) == 0 {
+		return 0, nil
+	}
+	resolved, err := sr.ResolveSecrets(content)
+	if err != nil {
+		return 0, fmt.Errorf("group %s: %w", group.Name, err)
+	}
+	if group.File.Encoding == "base64" {
+		resolved = []byte(base64.StdEncoding.EncodeToString(resolved))
+	}
+	group.File.Content = resolved
+
+	return len(refs), nil
+}
+
+// GroupFileContent returns the decoded content of the cloud-config file of
+// group.
+func GroupFileContent(group cistore.GroupData) ([]byte, error) {
+	if group.File.Encoding != "base64" {
+		return group.File.Content, nil
+	}
+	content, err := base64.StdEncoding.DecodeString(string(group.File.Content))
+	if err != nil {
+		return nil, fmt.Errorf("group %s: failed to decode base64 cloud-config: %w", group.Name, err)
+	}
+
+	return content, nil
+}
+
+// EnvSecretBackend is a SecretBackend that returns the value of the
+// environment variable path.
+func EnvSecretBackend(path string) (string, error) {
+	v, ok := os.LookupEnv(path)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", path)
+	}
+
+	return v, nil
+}
+
+// FileSecretBackend is a SecretBackend that returns the contents of the file at
+// path, without any trailing newline.
+func FileSecretBackend(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// SOPSSecretBackend is a SecretBackend that decrypts a SOPS-encrypted file
+// using the sops command. path is <file>[#<key>], where <key> is a
+// dot-separated path to the value to extract (e.g. root.password). If no key
+// is specified, the whole decrypted file is returned.
+func SOPSSecretBackend(path string) (string, error) {
+	file, key, _ := strings.Cut(path, "#")
+	args := []string{"--decrypt"}
+	if key != "" {
+		var extract string
+		for _, k := range strings.Split(key, ".") {
+			extract += fmt.Sprintf("[%q]", k)
+		}
+		args = append(args, "--extract", extract)
+	}
+	args = append(args, file)
+
+	var stderr bytes.Buffer
+	c := exec.Command("sops", args...)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err != nil {
+		return "", fmt.Errorf("sops failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// VaultSecretBackend returns a SecretBackend that reads secrets from a Vault
+// KV version 2 secrets engine using getKV, which is passed the mount and path
+// of a secret and returns its key/value pairs (e.g. VaultClient.GetKVSecret).
+// The path of a reference is either <mount>/<path>#<key> or, if no key is
+// specified, <mount>/<path>/<key>, e.g. kv/cluster/root-pass is the key
+// root-pass of the secret cluster in the engine mounted at kv.
+func VaultSecretBackend(getKV func(mount, path string) (map[string]any, error)) SecretBackend {
+	return func(path string) (string, error) {
+		secretPath, key, hasKey := strings.Cut(path, "#")
+		if !hasKey {
+			idx := strings.LastIndex(path, "/")
+			if idx < 0 {
+				return "", fmt.Errorf("vault secret path %q must be <mount>/<path>/<key> or <mount>/<path>#<key>", path)
+			}
+			secretPath, key = path[:idx], path[idx+1:]
+		}
+		mount, secretPath, ok := strings.Cut(secretPath, "/")
+		if !ok || mount == "" || secretPath == "" || key == "" {
+			return "", fmt.Errorf("vault secret path %q must be <mount>/<path>/<key> or <mount>/<path>#<key>", path)
+		}
+		data, err := getKV(mount, secretPath)
+		if err != nil {
+			return "", err
+		}
+		v, ok := data[key]
+		if !ok {
+			return "", fmt.Errorf("key %q not found in vault secret %s/%s", key, mount, secretPath)
+		}
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+
+		return fmt.Sprint(v), nil
+	}
+}
@@ -0,0 +1,156 @@
+package ci
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+	"gopkg.in/yaml.v3"
+)
+
+func TestFindSecretRefs(t *testing.T) {
+	content := []byte(`#cloud-config
+password: {{ secret "vault:kv/cluster/root-pass" }}
+keys: {{ ds.meta_data.instance_data.v1.public_keys }}
+again: {{-secret "vault:kv/cluster/root-pass"-}}
+token: {{secret "env:TOKEN"}}
+`)
+	want := []string{"vault:kv/cluster/root-pass", "env:TOKEN"}
+	if got := FindSecretRefs(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSecretResolver_ResolveSecrets(t *testing.T) {
+	t.Setenv("OCHAMI_TEST_SECRET", "s3cret")
+	file := filepath.Join(t.TempDir(), "pass")
+	if err := os.WriteFile(file, []byte("hunter2\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	sr := NewSecretResolver(map[string]SecretBackend{
+		SecretBackendEnv:  EnvSecretBackend,
+		SecretBackendFile: FileSecretBackend,
+		SecretBackendVault: VaultSecretBackend(func(mount, path string) (map[string]any, error) {
+			calls++
+			if mount != "kv" || path != "cluster" {
+				return nil, errors.New("not found")
+			}
+			return map[string]any{"root-pass": "r00t", "uid": 1000}, nil
+		}),
+	})
+
+	content := []byte(`a: {{ secret "env:OCHAMI_TEST_SECRET" }}
+b: {{ secret "file:` + file + `" }}
+c: {{ secret "vault:kv/cluster/root-pass" }}
+d: {{ secret "vault:kv/cluster#uid" }}
+e: {{ secret "vault:kv/cluster/root-pass" }}
+f: {{ ds.meta_data.local_hostname }}
+`)
+	want := `a: "s3cret"
+b: "hunter2"
+c: "r00t"
+d: "1000"
+e: "r00t"
+f: {{ ds.meta_data.local_hostname }}
+`
+	got, err := sr.ResolveSecrets(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 vault calls (one per unique reference), got %d", calls)
+	}
+
+	for _, ref := range []string{
+		"env:OCHAMI_TEST_UNSET",
+		"nope:foo",
+		"missing-colon",
+		"vault:kv",
+		"vault:kv/cluster/missing",
+	} {
+		if _, err := sr.Resolve(ref); err == nil {
+			t.Errorf("expected error resolving %q", ref)
+		}
+	}
+}
+
+func TestSecretResolver_ResolveSecrets_YAML(t *testing.T) {
+	values := map[string]string{
+		"colon":   "a: b",
+		"comment": "pass #word",
+		"quotes":  `it's "quoted"`,
+		"newline": "line1\nline2\n",
+		"tab":     "a\tb\\c",
+	}
+	sr := NewSecretResolver(map[string]SecretBackend{
+		SecretBackendEnv: func(path string) (string, error) { return values[path], nil },
+	})
+
+	content := []byte(`#cloud-config
+colon: {{ secret "env:colon" }}
+comment: {{ secret "env:comment" }}
+quotes: "{{ secret "env:quotes" }}"
+newline: '{{ secret "env:newline" }}'
+list:
+  - {{ secret "env:tab" }}
+`)
+	got, err := sr.ResolveSecrets(content)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var parsed struct {
+		Colon   string   `yaml:"colon"`
+		Comment string   `yaml:"comment"`
+		Quotes  string   `yaml:"quotes"`
+		Newline string   `yaml:"newline"`
+		List    []string `yaml:"list"`
+	}
+	if err := yaml.Unmarshal(got, &parsed); err != nil {
+		t.Fatalf("resolved cloud-config is not valid YAML: %v\n%s", err, got)
+	}
+	for name, got := range map[string]string{
+		"colon":   parsed.Colon,
+		"comment": parsed.Comment,
+		"quotes":  parsed.Quotes,
+		"newline": parsed.Newline,
+	} {
+		if got != values[name] {
+			t.Errorf("got %q for %s, want %q", got, name, values[name])
+		}
+	}
+	if len(parsed.List) != 1 || parsed.List[0] != values["tab"] {
+		t.Errorf("got list %q, want [%q]", parsed.List, values["tab"])
+	}
+}
+
+func TestSecretResolver_ResolveGroupSecrets(t *testing.T) {
+	t.Setenv("OCHAMI_TEST_SECRET", "s3cret")
+	sr := NewSecretResolver(map[string]SecretBackend{SecretBackendEnv: EnvSecretBackend})
+
+	plain := cistore.GroupData{Name: "plain"}
+	plain.File.Content = []byte(`pw: {{ secret "env:OCHAMI_TEST_SECRET" }}`)
+	if n, err := sr.ResolveGroupSecrets(&plain); err != nil || n != 1 {
+		t.Fatalf("got n=%d, err=%v", n, err)
+	}
+	if string(plain.File.Content) != `pw: "s3cret"` {
+		t.Errorf("got %q", plain.File.Content)
+	}
+
+	b64 := cistore.GroupData{Name: "b64"}
+	b64.File.Encoding = "base64"
+	b64.File.Content = []byte(base64.StdEncoding.EncodeToString([]byte(`pw: {{ secret "env:OCHAMI_TEST_SECRET" }}`)))
+	if _, err := sr.ResolveGroupSecrets(&b64); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, _ := GroupFileContent(b64); string(got) != `pw: "s3cret"` {
+		t.Errorf("got %q", got)
+	}
+}
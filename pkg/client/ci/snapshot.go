@@ -0,0 +1,94 @@
+package ci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MetaDataSnapshotVersion is the version of the format of meta-data snapshots.
+// ReadMetaDataSnapshot refuses snapshots with a newer version.
+const MetaDataSnapshotVersion = 1
+
+// MetaDataSnapshot is the meta-data that cloud-init served for nodes at a point
+// in time, keyed by node ID, so that configs can later be rendered against it
+// instead of the current meta-data, e.g. to test template changes against the
+// data of the day before.
+type MetaDataSnapshot struct {
+	Version int                       `json:"version" yaml:"version"`
+	Created time.Time                 `json:"created" yaml:"created"`
+	Cluster string                    `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Nodes   map[string]map[string]any `json:"nodes" yaml:"nodes"`
+}
+
+// NewMetaDataSnapshot returns an empty MetaDataSnapshot of cluster created at
+// created.
+func NewMetaDataSnapshot(cluster string, created time.Time) MetaDataSnapshot {
+	return MetaDataSnapshot{
+		Version: MetaDataSnapshotVersion,
+		Created: created.UTC(),
+		Cluster: cluster,
+		Nodes:   make(map[string]map[string]any),
+	}
+}
+
+// Add adds the meta-data of node, as served by cloud-init, to s, replacing any
+// that it has.
+func (s *MetaDataSnapshot) Add(node string, metaData []byte) error {
+	var md map[string]any
+	if err := yaml.Unmarshal(metaData, &md); err != nil {
+		return fmt.Errorf("failed to unmarshal meta-data of node %s: %w", node, err)
+	}
+	if md == nil {
+		md = make(map[string]any)
+	}
+	s.Nodes[node] = md
+
+	return nil
+}
+
+// MetaData returns the meta-data of node in s. Node IDs are compared
+// case-insensitively. An error is returned if s has no meta-data for node.
+func (s MetaDataSnapshot) MetaData(node string) (map[string]any, error) {
+	if md, ok := s.Nodes[node]; ok {
+		return md, nil
+	}
+	for id, md := range s.Nodes {
+		if strings.EqualFold(id, node) {
+			return md, nil
+		}
+	}
+
+	return nil, fmt.Errorf("node %s is not in the meta-data snapshot of %s", node, s.Created.Format(time.RFC3339))
+}
+
+// ReadMetaDataSnapshot reads a MetaDataSnapshot in JSON from r.
+func ReadMetaDataSnapshot(r io.Reader) (MetaDataSnapshot, error) {
+	var s MetaDataSnapshot
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return s, fmt.Errorf("failed to decode meta-data snapshot: %w", err)
+	}
+	if s.Version > MetaDataSnapshotVersion {
+		return s, fmt.Errorf("meta-data snapshot version %d is newer than the supported version %d", s.Version, MetaDataSnapshotVersion)
+	}
+	if s.Nodes == nil {
+		s.Nodes = make(map[string]map[string]any)
+	}
+
+	return s, nil
+}
+
+// Write writes s in JSON to w.
+func (s MetaDataSnapshot) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return fmt.Errorf("failed to encode meta-data snapshot: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package ci
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetaDataSnapshot(t *testing.T) {
+	created := time.Date(2026, 10, 13, 8, 0, 0, 0, time.UTC)
+	s := NewMetaDataSnapshot("demo", created)
+	if err := s.Add("x3000c0s0b0n0", []byte("instance-id: i-1\nhostname: nid001\ninstance-data:\n  cluster_name: demo\n")); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	if err := s.Add("x3000c0s1b0n0", []byte("{bad")); err == nil {
+		t.Errorf("Add() with invalid meta-data error = nil")
+	}
+
+	var buf bytes.Buffer
+	if err := s.Write(&buf); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	got, err := ReadMetaDataSnapshot(&buf)
+	if err != nil {
+		t.Fatalf("ReadMetaDataSnapshot() error = %v", err)
+	}
+	if !got.Created.Equal(created) || got.Cluster != "demo" {
+		t.Errorf("ReadMetaDataSnapshot() = %+v, want created %s for demo", got, created)
+	}
+
+	md, err := got.MetaData("X3000C0S0B0N0")
+	if err != nil {
+		t.Fatalf("MetaData() error = %v", err)
+	}
+	want := map[string]any{
+		"instance-id":   "i-1",
+		"hostname":      "nid001",
+		"instance-data": map[string]any{"cluster_name": "demo"},
+	}
+	if !reflect.DeepEqual(md, want) {
+		t.Errorf("MetaData() = %v, want %v", md, want)
+	}
+	if _, err := got.MetaData("x3000c0s1b0n0"); err == nil {
+		t.Errorf("MetaData() of node not in snapshot error = nil")
+	}
+
+	if _, err := ReadMetaDataSnapshot(strings.NewReader(`{"version": 2, "nodes": {}}`)); err == nil {
+		t.Errorf("ReadMetaDataSnapshot() of newer version error = nil")
+	}
+}
@@ -0,0 +1,113 @@
+package ci
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigState describes the state of a cloud-init group's cloud-config.
+type ConfigState string
+
+const (
+	ConfigStateOK      ConfigState = "ok"
+	ConfigStateEmpty   ConfigState = "empty"
+	ConfigStateInvalid ConfigState = "invalid"
+)
+
+// GroupUsage describes how a single cloud-init group is referenced by SMD and
+// whether its cloud-config is usable.
+type GroupUsage struct {
+	Name        string      `json:"name" yaml:"name"`
+	SMDGroup    bool        `json:"smd_group" yaml:"smd_group"`
+	Nodes       []string    `json:"nodes" yaml:"nodes"`
+	Config      ConfigState `json:"config" yaml:"config"`
+	ConfigError string      `json:"config_error,omitempty" yaml:"config_error,omitempty"`
+	Orphaned    bool        `json:"orphaned" yaml:"orphaned"`
+}
+
+// UsageReport is the result of GroupUsageReport. It contains the usage of each
+// cloud-init group as well as the SMD nodes that do not receive a usable
+// cloud-config from any group.
+type UsageReport struct {
+	Groups            []GroupUsage `json:"groups" yaml:"groups"`
+	UnconfiguredNodes []string     `json:"unconfigured_nodes" yaml:"unconfigured_nodes"`
+}
+
+// CheckCloudConfig determines the ConfigState of ccf. Content is decoded (see
+// DecodeCloudConfig) and, if it is marked as a cloud-config (starts with
+// "#cloud-config"), it is parsed as YAML. Other user-data types (e.g. scripts)
+// are not parsed. If the config is invalid, the error describing why is also
+// returned.
+func CheckCloudConfig(ccf cistore.CloudConfigFile) (ConfigState, error) {
+	if len(bytes.TrimSpace(ccf.Content)) == 0 {
+		return ConfigStateEmpty, nil
+	}
+	content, err := DecodeCloudConfig(ccf)
+	if err != nil {
+		return ConfigStateInvalid, err
+	}
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 {
+		return ConfigStateEmpty, nil
+	}
+	if !bytes.HasPrefix(content, []byte("#cloud-config")) {
+		return ConfigStateOK, nil
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(content, &node); err != nil {
+		return ConfigStateInvalid, fmt.Errorf("failed to parse cloud-config as YAML: %w", err)
+	}
+
+	return ConfigStateOK, nil
+}
+
+// GroupUsageReport cross-references ciGroups against SMD group membership
+// (smdGroups maps SMD group label to member IDs) and the list of SMD nodes.
+// A cloud-init group applies to the members of the SMD group with the same
+// name. A cloud-init group is orphaned if no nodes reference it. A node is
+// unconfigured if it is not a member of any SMD group that has a cloud-init
+// group with a usable (non-empty, parseable) cloud-config.
+func GroupUsageReport(ciGroups []cistore.GroupData, smdGroups map[string][]string, nodes []string) UsageReport {
+	var report UsageReport
+	configured := make(map[string]bool)
+	for _, g := range ciGroups {
+		gu := GroupUsage{
+			Name:  g.Name,
+			Nodes: []string{},
+		}
+		state, err := CheckCloudConfig(g.File)
+		gu.Config = state
+		if err != nil {
+			gu.ConfigError = err.Error()
+		}
+		if members, ok := smdGroups[g.Name]; ok {
+			gu.SMDGroup = true
+			gu.Nodes = append(gu.Nodes, members...)
+			sort.Strings(gu.Nodes)
+		}
+		gu.Orphaned = len(gu.Nodes) == 0
+		if gu.Config == ConfigStateOK {
+			for _, n := range gu.Nodes {
+				configured[n] = true
+			}
+		}
+		report.Groups = append(report.Groups, gu)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		return report.Groups[i].Name < report.Groups[j].Name
+	})
+
+	report.UnconfiguredNodes = []string{}
+	for _, n := range nodes {
+		if !configured[n] {
+			report.UnconfiguredNodes = append(report.UnconfiguredNodes, n)
+		}
+	}
+	sort.Strings(report.UnconfiguredNodes)
+
+	return report
+}
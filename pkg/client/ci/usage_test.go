@@ -0,0 +1,114 @@
+package ci
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+)
+
+func TestCheckCloudConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		ccf   cistore.CloudConfigFile
+		want  ConfigState
+		isErr bool
+	}{
+		{
+			name: "empty",
+			ccf:  cistore.CloudConfigFile{Encoding: "plain"},
+			want: ConfigStateEmpty,
+		},
+		{
+			name: "whitespace only",
+			ccf:  cistore.CloudConfigFile{Content: []byte(" \n\t"), Encoding: "plain"},
+			want: ConfigStateEmpty,
+		},
+		{
+			name: "valid plain",
+			ccf:  cistore.CloudConfigFile{Content: []byte("#cloud-config\npackages:\n  - vim\n"), Encoding: "plain"},
+			want: ConfigStateOK,
+		},
+		{
+			name: "valid base64",
+			ccf: cistore.CloudConfigFile{
+				Content:  []byte(base64.StdEncoding.EncodeToString([]byte("#cloud-config\nruncmd:\n  - echo hi\n"))),
+				Encoding: "base64",
+			},
+			want: ConfigStateOK,
+		},
+		{
+			name:  "invalid yaml",
+			ccf:   cistore.CloudConfigFile{Content: []byte("#cloud-config\npackages: [vim\n"), Encoding: "plain"},
+			want:  ConfigStateInvalid,
+			isErr: true,
+		},
+		{
+			name: "non-cloud-config not parsed",
+			ccf:  cistore.CloudConfigFile{Content: []byte("#!/bin/sh\necho [\n"), Encoding: "plain"},
+			want: ConfigStateOK,
+		},
+		{
+			name:  "unknown encoding",
+			ccf:   cistore.CloudConfigFile{Content: []byte("x"), Encoding: "rot13"},
+			want:  ConfigStateInvalid,
+			isErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckCloudConfig(tt.ccf)
+			if (err != nil) != tt.isErr {
+				t.Fatalf("unexpected error state: got err=%v, want error=%v", err, tt.isErr)
+			}
+			if got != tt.want {
+				t.Errorf("got state %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupUsageReport(t *testing.T) {
+	valid := cistore.CloudConfigFile{Content: []byte("#cloud-config\n{}\n"), Encoding: "plain"}
+	ciGroups := []cistore.GroupData{
+		{Name: "compute", File: valid},
+		{Name: "empty", File: cistore.CloudConfigFile{Encoding: "plain"}},
+		{Name: "orphan", File: valid},
+	}
+	smdGroups := map[string][]string{
+		"compute": {"x1000c0s1b0n0", "x1000c0s0b0n0"},
+		"empty":   {"x1000c0s2b0n0"},
+		"other":   {"x1000c0s3b0n0"},
+	}
+	nodes := []string{"x1000c0s3b0n0", "x1000c0s2b0n0", "x1000c0s1b0n0", "x1000c0s0b0n0"}
+
+	want := UsageReport{
+		Groups: []GroupUsage{
+			{
+				Name:     "compute",
+				SMDGroup: true,
+				Nodes:    []string{"x1000c0s0b0n0", "x1000c0s1b0n0"},
+				Config:   ConfigStateOK,
+			},
+			{
+				Name:     "empty",
+				SMDGroup: true,
+				Nodes:    []string{"x1000c0s2b0n0"},
+				Config:   ConfigStateEmpty,
+			},
+			{
+				Name:     "orphan",
+				Nodes:    []string{},
+				Config:   ConfigStateOK,
+				Orphaned: true,
+			},
+		},
+		UnconfiguredNodes: []string{"x1000c0s2b0n0", "x1000c0s3b0n0"},
+	}
+
+	got := GroupUsageReport(ciGroups, smdGroups, nodes)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected report:\ngot:  %+v\nwant: %+v", got, want)
+	}
+}
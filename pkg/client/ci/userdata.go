@@ -0,0 +1,562 @@
+package ci
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxIncludeDepth limits how deeply user-data parts can include each other.
+const maxIncludeDepth = 10
+
+// defaultMergeHow is the merge algorithm cloud-init uses for cloud-config parts
+// that do not specify one.
+const defaultMergeHow = "dict(replace)+list()+str()"
+
+// Kinds of user-data parts, determined from their first line or MIME type.
+const (
+	userDataKindCloudConfig = "cloud-config"
+	userDataKindInclude     = "include"
+	userDataKindJinja       = "jinja"
+	userDataKindMultipart   = "multipart"
+	userDataKindOther       = "other"
+)
+
+// jinjaHeaderPattern matches the header line of user-data that is a Jinja
+// template.
+var jinjaHeaderPattern = regexp.MustCompile(`(?i)^##\s*template:\s*jinja[ \t]*(\r?\n|$)`)
+
+// UserDataFetcher returns the contents of a URL listed in an #include part of
+// user-data.
+type UserDataFetcher func(url string) ([]byte, error)
+
+// UserDataRenderer renders the body of a user-data part that is a Jinja
+// template, without its header line.
+type UserDataRenderer func(tpl []byte) ([]byte, error)
+
+// UserDataResolver resolves user-data the way cloud-init processes it on a
+// node: #include parts (and text/x-include-url MIME parts) are replaced by the
+// contents of the URLs they list, MIME multi-part archives are split into their
+// parts, Jinja template parts are rendered, and all cloud-config parts are
+// merged in order using the merge algorithm each specifies with merge_how (or
+// merge_type) or a Merge-Type header, defaulting to cloud-init's
+// dict(replace)+list()+str().
+type UserDataResolver struct {
+	Fetch  UserDataFetcher
+	Render UserDataRenderer
+}
+
+// ResolvedUserData is the result of resolving user-data with UserDataResolver.
+// Config is the merged cloud-config. Parts lists the sources of the
+// cloud-config parts merged into it, in order, and Skipped lists the sources
+// of parts that are not cloud-config (e.g. shell scripts), which cloud-init
+// handles separately. Other holds those parts, in the same order.
+type ResolvedUserData struct {
+	Config  []byte
+	Parts   []string
+	Skipped []string
+	Other   []UserDataPart
+}
+
+// UserDataPart is a part of user-data that is not cloud-config. ContentType is
+// its MIME type, from its MIME headers or, if it was not in a MIME multi-part
+// archive, from its first line (see UserDataContentType). Filename is the file
+// name from its MIME headers, if any.
+type UserDataPart struct {
+	Source      string
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+// userDataPart is a part of user-data from source, with the merge algorithm,
+// MIME type, and file name from its MIME headers, if any.
+type userDataPart struct {
+	source      string
+	content     []byte
+	mergeHow    string
+	contentType string
+	filename    string
+}
+
+// NeedsResolving returns true if data includes other user-data or is a MIME
+// multi-part archive, i.e. if it must be resolved to see the cloud-config a
+// node applies. A leading Jinja template header is ignored.
+func NeedsResolving(data []byte) bool {
+	switch userDataKind(jinjaHeaderPattern.ReplaceAll(data, nil)) {
+	case userDataKindInclude, userDataKindMultipart:
+		return true
+	default:
+		return false
+	}
+}
+
+// Resolve resolves data, which is named source, into a single cloud-config.
+// data is assumed to be rendered already, so a leading Jinja template header
+// is removed without rendering it again. Parts fetched from URLs that are Jinja
+// templates are rendered with ur.Render.
+func (ur UserDataResolver) Resolve(source string, data []byte) (ResolvedUserData, error) {
+	var (
+		res    ResolvedUserData
+		merged = map[string]any{}
+	)
+	add := func(p userDataPart) error {
+		var cfg map[string]any
+		if err := yaml.Unmarshal(p.content, &cfg); err != nil {
+			return fmt.Errorf("failed to parse cloud-config from %s: %w", p.source, err)
+		}
+		if cfg == nil {
+			return nil
+		}
+		m, err := partMergers(cfg, p.mergeHow)
+		if err != nil {
+			return fmt.Errorf("invalid merge algorithm in %s: %w", p.source, err)
+		}
+		merged, _ = m.merge(merged, cfg).(map[string]any)
+		res.Parts = append(res.Parts, p.source)
+		return nil
+	}
+	skip := func(p userDataPart) {
+		res.Skipped = append(res.Skipped, p.source)
+		if p.contentType == "" {
+			p.contentType = UserDataContentType(p.content)
+		}
+		res.Other = append(res.Other, UserDataPart{
+			Source:      p.source,
+			Filename:    p.filename,
+			ContentType: p.contentType,
+			Content:     p.content,
+		})
+	}
+
+	data = jinjaHeaderPattern.ReplaceAll(data, nil)
+	if err := ur.walk(userDataPart{source: source, content: data}, 0, nil, add, skip); err != nil {
+		return res, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("#cloud-config\n")
+	fmt.Fprintf(&buf, "# from %d part(s)\n", len(res.Parts))
+	for _, p := range res.Parts {
+		fmt.Fprintf(&buf, "# %s\n", p)
+	}
+	buf.WriteString("\n")
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(merged); err != nil {
+		return res, fmt.Errorf("failed to marshal merged cloud-config: %w", err)
+	}
+	enc.Close()
+	res.Config = buf.Bytes()
+
+	return res, nil
+}
+
+// walk processes part p at include depth depth, calling add for each
+// cloud-config part and skip for each other part, in order. stack contains the
+// URLs being included, to detect include loops.
+func (ur UserDataResolver) walk(p userDataPart, depth int, stack []string, add func(userDataPart) error, skip func(userDataPart)) error {
+	switch userDataKind(p.content) {
+	case userDataKindCloudConfig:
+		return add(p)
+	case userDataKindInclude:
+		return ur.walkInclude(p, depth, stack, add, skip)
+	case userDataKindJinja:
+		if ur.Render == nil {
+			return fmt.Errorf("cannot render Jinja template in %s", p.source)
+		}
+		rendered, err := ur.Render(jinjaHeaderPattern.ReplaceAll(p.content, nil))
+		if err != nil {
+			return fmt.Errorf("failed to render Jinja template in %s: %w", p.source, err)
+		}
+		p.content = rendered
+		p.contentType = ""
+		return ur.walk(p, depth, stack, add, skip)
+	case userDataKindMultipart:
+		msg, err := mail.ReadMessage(bytes.NewReader(p.content))
+		if err != nil {
+			return fmt.Errorf("failed to parse MIME message in %s: %w", p.source, err)
+		}
+		return ur.walkMultipart(p.source, textproto.MIMEHeader(msg.Header), msg.Body, depth, stack, add, skip)
+	default:
+		skip(p)
+		return nil
+	}
+}
+
+// walkInclude processes each URL listed in include part p.
+func (ur UserDataResolver) walkInclude(p userDataPart, depth int, stack []string, add func(userDataPart) error, skip func(userDataPart)) error {
+	if depth >= maxIncludeDepth {
+		return fmt.Errorf("too many levels of includes in %s", p.source)
+	}
+	s := bufio.NewScanner(bytes.NewReader(p.content))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		lower := strings.ToLower(line)
+		if strings.HasPrefix(lower, "#include-once") {
+			line = strings.TrimSpace(line[len("#include-once"):])
+		} else if strings.HasPrefix(lower, "#include") {
+			line = strings.TrimSpace(line[len("#include"):])
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, u := range stack {
+			if u == line {
+				return fmt.Errorf("include loop: %s includes %s", p.source, line)
+			}
+		}
+		if ur.Fetch == nil {
+			return fmt.Errorf("cannot fetch %s included by %s", line, p.source)
+		}
+		content, err := ur.Fetch(line)
+		if err != nil {
+			return fmt.Errorf("failed to fetch %s included by %s: %w", line, p.source, err)
+		}
+		if err := ur.walk(userDataPart{source: line, content: content}, depth+1, append(stack, line), add, skip); err != nil {
+			return err
+		}
+	}
+
+	return s.Err()
+}
+
+// walkMultipart processes each part of a MIME multi-part body with header h.
+func (ur UserDataResolver) walkMultipart(source string, h textproto.MIMEHeader, body io.Reader, depth int, stack []string, add func(userDataPart) error, skip func(userDataPart)) error {
+	mediaType, params, err := mime.ParseMediaType(h.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") {
+		return fmt.Errorf("%s is not a MIME multi-part archive", source)
+	}
+	mr := multipart.NewReader(body, params["boundary"])
+	for i := 1; ; i++ {
+		mp, err := mr.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read MIME part %d of %s: %w", i, source, err)
+		}
+		p := userDataPart{
+			source:   fmt.Sprintf("%s part %d", source, i),
+			mergeHow: mp.Header.Get("Merge-Type"),
+		}
+		if p.mergeHow == "" {
+			p.mergeHow = mp.Header.Get("X-Merge-Type")
+		}
+		if name := mp.FileName(); name != "" {
+			p.source = fmt.Sprintf("%s part %d (%s)", source, i, name)
+			p.filename = name
+		}
+		var r io.Reader = mp
+		if strings.EqualFold(mp.Header.Get("Content-Transfer-Encoding"), "base64") {
+			r = base64.NewDecoder(base64.StdEncoding, mp)
+		}
+		partType, _, _ := mime.ParseMediaType(mp.Header.Get("Content-Type"))
+		if partType != "text/plain" && partType != "text/x-not-multipart" {
+			p.contentType = partType
+		}
+		if strings.HasPrefix(partType, "multipart/") {
+			if err := ur.walkMultipart(p.source, mp.Header, r, depth, stack, add, skip); err != nil {
+				return err
+			}
+			continue
+		}
+		if p.content, err = io.ReadAll(r); err != nil {
+			return fmt.Errorf("failed to read %s: %w", p.source, err)
+		}
+		switch partType {
+		case "text/cloud-config":
+			err = add(p)
+		case "text/x-include-url", "text/x-include-once-url":
+			err = ur.walkInclude(p, depth, stack, add, skip)
+		case "text/jinja2":
+			p.content = append([]byte("## template: jinja\n"), p.content...)
+			err = ur.walk(p, depth, stack, add, skip)
+		case "", "text/plain", "text/x-not-multipart":
+			err = ur.walk(p, depth, stack, add, skip)
+		default:
+			skip(p)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// userDataKind returns the kind of user-data part content from its first line.
+func userDataKind(content []byte) string {
+	switch {
+	case jinjaHeaderPattern.Match(content):
+		return userDataKindJinja
+	case bytes.HasPrefix(content, []byte("#include")):
+		return userDataKindInclude
+	case bytes.HasPrefix(content, []byte("#cloud-config-archive")):
+		return userDataKindOther
+	case bytes.HasPrefix(content, []byte("#cloud-config")):
+		return userDataKindCloudConfig
+	}
+	first, _, _ := strings.Cut(strings.ToLower(string(content)), "\n")
+	if strings.HasPrefix(first, "content-type:") || strings.HasPrefix(first, "mime-version:") {
+		return userDataKindMultipart
+	}
+
+	return userDataKindOther
+}
+
+// userDataStartTypes maps the first line prefixes of user-data parts that
+// cloud-init recognizes to their MIME types.
+var userDataStartTypes = []struct{ prefix, contentType string }{
+	{"#!", "text/x-shellscript"},
+	{"#cloud-boothook", "text/cloud-boothook"},
+	{"#cloud-config-archive", "text/cloud-config-archive"},
+	{"#cloud-config", "text/cloud-config"},
+	{"#include-once", "text/x-include-once-url"},
+	{"#include", "text/x-include-url"},
+	{"#part-handler", "text/part-handler"},
+	{"## template: jinja", "text/jinja2"},
+}
+
+// UserDataContentType returns the MIME type of a user-data part from its first
+// line the way cloud-init determines it, e.g. text/x-shellscript for a part
+// starting with "#!". Parts it does not recognize are text/plain.
+func UserDataContentType(content []byte) string {
+	for _, t := range userDataStartTypes {
+		if bytes.HasPrefix(content, []byte(t.prefix)) {
+			return t.contentType
+		}
+	}
+
+	return "text/plain"
+}
+
+// mergers is a cloud-init merge algorithm: the options of the merger for each
+// type (dict, list, or str) that is merged. Values of types without a merger
+// are left as they were.
+type mergers map[string]map[string]bool
+
+// mergerPattern matches a merger in the string form of a merge algorithm, e.g.
+// "list(append)".
+var mergerPattern = regexp.MustCompile(`^\s*(\w+)\s*(?:\(([^)]*)\))?\s*$`)
+
+// partMergers returns the merge algorithm of cloud-config part cfg, from its
+// merge_how (or merge_type) key and header, the merge algorithm from its MIME
+// headers. Mergers in cfg take precedence over those of the same type in
+// header. If neither is set, cloud-init's default is returned.
+func partMergers(cfg map[string]any, header string) (mergers, error) {
+	m := mergers{}
+	spec, ok := cfg["merge_how"]
+	if !ok {
+		spec = cfg["merge_type"]
+	}
+	if err := m.add(spec); err != nil {
+		return nil, err
+	}
+	hm := mergers{}
+	if err := hm.add(header); err != nil {
+		return nil, err
+	}
+	for name, opts := range hm {
+		if _, ok := m[name]; !ok {
+			m[name] = opts
+		}
+	}
+	if len(m) == 0 {
+		m.add(defaultMergeHow)
+	}
+
+	return m, nil
+}
+
+// add adds the mergers of spec, which is either a string (e.g.
+// "dict(no_replace,recurse_list)+list(append)") or a list of mappings with
+// name and settings keys, to m.
+func (m mergers) add(spec any) error {
+	addOne := func(name string, opts []string) error {
+		switch name {
+		case "dict", "list", "str":
+		default:
+			return fmt.Errorf("unknown merger %q", name)
+		}
+		if m[name] == nil {
+			m[name] = make(map[string]bool)
+		}
+		for _, o := range opts {
+			if o = strings.TrimSpace(o); o != "" {
+				m[name][o] = true
+			}
+		}
+		return nil
+	}
+
+	switch s := spec.(type) {
+	case nil:
+		return nil
+	case string:
+		if strings.TrimSpace(s) == "" {
+			return nil
+		}
+		for _, part := range strings.Split(s, "+") {
+			match := mergerPattern.FindStringSubmatch(part)
+			if match == nil {
+				return fmt.Errorf("invalid merger %q", part)
+			}
+			if err := addOne(match[1], strings.Split(match[2], ",")); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []any:
+		for _, item := range s {
+			im, ok := item.(map[string]any)
+			if !ok {
+				return fmt.Errorf("invalid merger %v: must be a mapping with name and settings", item)
+			}
+			name, _ := im["name"].(string)
+			var opts []string
+			settings, _ := im["settings"].([]any)
+			for _, o := range settings {
+				opts = append(opts, fmt.Sprint(o))
+			}
+			if err := addOne(name, opts); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid merge algorithm %v: must be a string or list", spec)
+	}
+}
+
+// merge merges value newV into oldV the way cloud-init's mergers do, returning
+// the result. The merger used is determined by the type of oldV.
+func (m mergers) merge(oldV, newV any) any {
+	switch o := oldV.(type) {
+	case map[string]any:
+		opts, ok := m["dict"]
+		n, isMap := newV.(map[string]any)
+		if !ok || !isMap {
+			return oldV
+		}
+		res := make(map[string]any, len(o)+len(n))
+		for k, v := range o {
+			res[k] = v
+		}
+		for k, v := range n {
+			ov, exists := res[k]
+			if !exists {
+				res[k] = v
+				continue
+			}
+			if v == nil && opts["allow_delete"] {
+				delete(res, k)
+				continue
+			}
+			res[k] = m.mergeSameKey(opts, ov, v)
+		}
+		return res
+	case []any:
+		opts, ok := m["list"]
+		if !ok {
+			return oldV
+		}
+		n, isList := newV.([]any)
+		method := listMethod(opts)
+		if !isList {
+			if method == "replace" {
+				return newV
+			}
+			n = []any{newV}
+		}
+		switch method {
+		case "prepend":
+			return append(append([]any{}, n...), o...)
+		case "append":
+			return append(append([]any{}, o...), n...)
+		}
+		res := append([]any{}, o...)
+		for i := 0; i < len(res) && i < len(n); i++ {
+			res[i] = m.mergeSameIndex(opts, method, res[i], n[i])
+		}
+		return res
+	case string:
+		opts, ok := m["str"]
+		if !ok {
+			return oldV
+		}
+		n, isStr := newV.(string)
+		if !isStr || !opts["append"] {
+			return newV
+		}
+		return o + n
+	default:
+		return oldV
+	}
+}
+
+// mergeSameKey merges newV into oldV, the values of the same key of two
+// mappings, using the options of the dict merger.
+func (m mergers) mergeSameKey(opts map[string]bool, oldV, newV any) any {
+	if !opts["no_replace"] {
+		return newV
+	}
+	switch newV.(type) {
+	case []any:
+		if opts["recurse_array"] || opts["recurse_list"] {
+			return m.merge(oldV, newV)
+		}
+	case string:
+		if opts["recurse_str"] {
+			return m.merge(oldV, newV)
+		}
+	case map[string]any:
+		return m.merge(oldV, newV)
+	}
+
+	return oldV
+}
+
+// mergeSameIndex merges newV into oldV, the values at the same index of two
+// lists, using the options of the list merger.
+func (m mergers) mergeSameIndex(opts map[string]bool, method string, oldV, newV any) any {
+	if method == "no_replace" {
+		return oldV
+	}
+	switch newV.(type) {
+	case []any:
+		if opts["recurse_array"] || opts["recurse_list"] {
+			return m.merge(oldV, newV)
+		}
+	case string:
+		if opts["recurse_str"] {
+			return m.merge(oldV, newV)
+		}
+	case map[string]any:
+		if opts["recurse_dict"] {
+			return m.merge(oldV, newV)
+		}
+	}
+
+	return newV
+}
+
+// listMethod returns the method of the list merger with opts: append, prepend,
+// no_replace, or replace (the default).
+func listMethod(opts map[string]bool) string {
+	for _, method := range []string{"append", "prepend", "no_replace"} {
+		if opts[method] {
+			return method
+		}
+	}
+
+	return "replace"
+}
@@ -0,0 +1,243 @@
+package ci
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMergers_merge(t *testing.T) {
+	base := map[string]any{
+		"packages": []any{"vim"},
+		"users":    map[string]any{"root": map[string]any{"shell": "/bin/bash"}},
+		"hostname": "a",
+		"motd":     "hello",
+	}
+	part := map[string]any{
+		"packages": []any{"git", "curl"},
+		"users":    map[string]any{"admin": map[string]any{"shell": "/bin/zsh"}},
+		"hostname": nil,
+		"motd":     " world",
+	}
+	tests := []struct {
+		mergeHow string
+		want     map[string]any
+	}{
+		{
+			mergeHow: defaultMergeHow,
+			want: map[string]any{
+				"packages": []any{"git", "curl"},
+				"users":    map[string]any{"admin": map[string]any{"shell": "/bin/zsh"}},
+				"hostname": nil,
+				"motd":     " world",
+			},
+		},
+		{
+			mergeHow: "dict(no_replace,recurse_list,recurse_str)+list(append)+str(append)",
+			want: map[string]any{
+				"packages": []any{"vim", "git", "curl"},
+				"users": map[string]any{
+					"root":  map[string]any{"shell": "/bin/bash"},
+					"admin": map[string]any{"shell": "/bin/zsh"},
+				},
+				"hostname": "a",
+				"motd":     "hello world",
+			},
+		},
+		{
+			mergeHow: "dict(allow_delete,no_replace)+list(prepend)",
+			want: map[string]any{
+				"packages": []any{"vim"},
+				"users": map[string]any{
+					"root":  map[string]any{"shell": "/bin/bash"},
+					"admin": map[string]any{"shell": "/bin/zsh"},
+				},
+				"motd": "hello",
+			},
+		},
+		{
+			mergeHow: "dict(no_replace,recurse_array)+list()",
+			want: map[string]any{
+				"packages": []any{"git"},
+				"users": map[string]any{
+					"root":  map[string]any{"shell": "/bin/bash"},
+					"admin": map[string]any{"shell": "/bin/zsh"},
+				},
+				"hostname": "a",
+				"motd":     "hello",
+			},
+		},
+		{
+			// Without a dict merger, nothing is merged
+			mergeHow: "list(append)",
+			want:     base,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mergeHow, func(t *testing.T) {
+			m := mergers{}
+			if err := m.add(tt.mergeHow); err != nil {
+				t.Fatalf("add() error = %v", err)
+			}
+			if got := m.merge(base, part); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPartMergers(t *testing.T) {
+	cfg := map[string]any{
+		"merge_how": []any{
+			map[string]any{"name": "list", "settings": []any{"append"}},
+			map[string]any{"name": "dict", "settings": []any{"no_replace", "recurse_list"}},
+		},
+	}
+	got, err := partMergers(cfg, "list(prepend)+str(append)")
+	if err != nil {
+		t.Fatalf("partMergers() error = %v", err)
+	}
+	want := mergers{
+		"list": {"append": true},
+		"dict": {"no_replace": true, "recurse_list": true},
+		"str":  {"append": true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("partMergers() = %v, want %v", got, want)
+	}
+
+	if got, _ := partMergers(map[string]any{}, ""); !reflect.DeepEqual(got, mergers{"dict": {"replace": true}, "list": {}, "str": {}}) {
+		t.Errorf("partMergers() default = %v", got)
+	}
+	for _, bad := range []any{"foo(bar)", "dict(", 42} {
+		if _, err := partMergers(map[string]any{"merge_how": bad}, ""); err == nil {
+			t.Errorf("partMergers(%v) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestNeedsResolving(t *testing.T) {
+	tests := map[string]bool{
+		"#cloud-config\npackages: [vim]\n":                       false,
+		"## template: jinja\n#cloud-config\nhostname: x\n":       false,
+		"#include\nhttp://example.com/a.yaml\n":                  true,
+		"## template: jinja\n#include-once\nhttp://example.com":  true,
+		"Content-Type: multipart/mixed; boundary=\"b\"\n\n--b--": true,
+		"#!/bin/sh\necho hi\n":                                   false,
+	}
+	for data, want := range tests {
+		if got := NeedsResolving([]byte(data)); got != want {
+			t.Errorf("NeedsResolving(%q) = %v, want %v", data, got, want)
+		}
+	}
+}
+
+func TestUserDataResolver_Resolve(t *testing.T) {
+	urls := map[string]string{
+		"http://example.com/base.yaml": "#cloud-config\npackages: [vim]\nhostname: base\n",
+		"http://example.com/extra.yaml": `## template: jinja
+#cloud-config
+merge_how: "dict(no_replace,recurse_list)+list(append)"
+packages: [{{ pkg }}]
+hostname: extra
+`,
+		"http://example.com/script.sh": "#!/bin/sh\necho hi\n",
+		"http://example.com/nested":    "#include\nhttp://example.com/base.yaml\n",
+		"http://example.com/loop":      "#include\nhttp://example.com/loop\n",
+	}
+	ur := UserDataResolver{
+		Fetch: func(url string) ([]byte, error) {
+			if s, ok := urls[url]; ok {
+				return []byte(s), nil
+			}
+			return nil, fmt.Errorf("404 Not Found")
+		},
+		Render: func(tpl []byte) ([]byte, error) {
+			return []byte(strings.ReplaceAll(string(tpl), "{{ pkg }}", "git")), nil
+		},
+	}
+
+	data := `## template: jinja
+#include
+# A comment
+http://example.com/nested
+#include-once http://example.com/extra.yaml
+http://example.com/script.sh
+`
+	got, err := ur.Resolve("group compute", []byte(data))
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if want := []string{"http://example.com/base.yaml", "http://example.com/extra.yaml"}; !reflect.DeepEqual(got.Parts, want) {
+		t.Errorf("Parts = %v, want %v", got.Parts, want)
+	}
+	if want := []string{"http://example.com/script.sh"}; !reflect.DeepEqual(got.Skipped, want) {
+		t.Errorf("Skipped = %v, want %v", got.Skipped, want)
+	}
+	if !strings.HasPrefix(string(got.Config), "#cloud-config\n# from 2 part(s)\n") {
+		t.Errorf("Config does not start with cloud-config header:\n%s", got.Config)
+	}
+	var cfg map[string]any
+	if err := yaml.Unmarshal(got.Config, &cfg); err != nil {
+		t.Fatalf("Config is not YAML: %v", err)
+	}
+	want := map[string]any{
+		"packages":  []any{"vim", "git"},
+		"hostname":  "base",
+		"merge_how": "dict(no_replace,recurse_list)+list(append)",
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("Config = %v, want %v", cfg, want)
+	}
+
+	for _, bad := range []string{"#include\nhttp://example.com/loop\n", "#include\nhttp://example.com/missing\n"} {
+		if _, err := ur.Resolve("group compute", []byte(bad)); err == nil {
+			t.Errorf("Resolve(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestUserDataResolver_Resolve_Multipart(t *testing.T) {
+	data := "Content-Type: multipart/mixed; boundary=\"XYZ\"\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"\r\n" +
+		"--XYZ\r\n" +
+		"Content-Type: text/cloud-config\r\n" +
+		"\r\n" +
+		"#cloud-config\nruncmd: [a]\n\r\n" +
+		"--XYZ\r\n" +
+		"Content-Type: text/cloud-config\r\n" +
+		"Merge-Type: dict(no_replace,recurse_list)+list(append)\r\n" +
+		"Content-Transfer-Encoding: base64\r\n" +
+		"Content-Disposition: attachment; filename=\"more.yaml\"\r\n" +
+		"\r\n" +
+		"I2Nsb3VkLWNvbmZpZwpydW5jbWQ6IFtiXQo=\r\n" +
+		"--XYZ\r\n" +
+		"Content-Type: text/x-shellscript\r\n" +
+		"\r\n" +
+		"#!/bin/sh\necho hi\n\r\n" +
+		"--XYZ--\r\n"
+	got, err := UserDataResolver{}.Resolve("group compute", []byte(data))
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	var cfg map[string]any
+	if err := yaml.Unmarshal(got.Config, &cfg); err != nil {
+		t.Fatalf("Config is not YAML: %v", err)
+	}
+	if want := map[string]any{"runcmd": []any{"a", "b"}}; !reflect.DeepEqual(cfg, want) {
+		t.Errorf("Config = %v, want %v", cfg, want)
+	}
+	if want := []string{"group compute part 1", "group compute part 2 (more.yaml)"}; !reflect.DeepEqual(got.Parts, want) {
+		t.Errorf("Parts = %v, want %v", got.Parts, want)
+	}
+	if want := []string{"group compute part 3"}; !reflect.DeepEqual(got.Skipped, want) {
+		t.Errorf("Skipped = %v, want %v", got.Skipped, want)
+	}
+	if len(got.Other) != 1 || got.Other[0].ContentType != "text/x-shellscript" || string(got.Other[0].Content) != "#!/bin/sh\necho hi\n" {
+		t.Errorf("Other = %+v, want the shell script", got.Other)
+	}
+}
@@ -0,0 +1,312 @@
+package ci
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/OpenCHAMI/ochami/pkg/diff"
+)
+
+// The files on a node that VerifyNode compares with what cloud-init serves.
+// instance-data.json is world-readable, but the user-data and vendor-data that
+// the node received are only readable by root.
+const (
+	NodeFileInstanceData = "/run/cloud-init/instance-data.json"
+	NodeFileResult       = "/run/cloud-init/result.json"
+	NodeFileUserData     = "/var/lib/cloud/instance/user-data.txt"
+	NodeFileVendorData   = "/var/lib/cloud/instance/vendor-data.txt"
+)
+
+// The stages of delivering cloud-init data to a node that VerifyNode checks,
+// in the order it checks them.
+const (
+	VerifyStageDelivery   = "delivery"
+	VerifyStageInstanceID = "instance-id"
+	VerifyStageMetaData   = "meta-data"
+	VerifyStageUserData   = "user-data"
+	VerifyStageVendorData = "vendor-data"
+	VerifyStageProcessing = "processing"
+)
+
+// VerifyStatus is the outcome of checking one stage of a node.
+type VerifyStatus string
+
+const (
+	// VerifyOK means the node has what cloud-init serves.
+	VerifyOK VerifyStatus = "ok"
+	// VerifyMismatch means the node has something other than what
+	// cloud-init serves.
+	VerifyMismatch VerifyStatus = "mismatch"
+	// VerifyFailed means cloud-init on the node did not get or process its
+	// data, or the node could not be checked.
+	VerifyFailed VerifyStatus = "failed"
+	// VerifyUnchecked means the stage could not be checked, e.g. because the
+	// file to check is not readable by the SSH user.
+	VerifyUnchecked VerifyStatus = "unchecked"
+)
+
+// VerifyFinding is the outcome of checking one stage of delivering cloud-init
+// data to a node. Diff is a unified diff from what cloud-init serves to what
+// the node has, if they differ.
+type VerifyFinding struct {
+	Node   string       `json:"node" yaml:"node"`
+	Stage  string       `json:"stage" yaml:"stage"`
+	Status VerifyStatus `json:"status" yaml:"status"`
+	Detail string       `json:"detail,omitempty" yaml:"detail,omitempty"`
+	Diff   string       `json:"diff,omitempty" yaml:"diff,omitempty"`
+}
+
+// VerifyServed is the data that cloud-init serves for a node.
+type VerifyServed struct {
+	MetaData   []byte
+	UserData   []byte
+	VendorData []byte
+}
+
+// VerifyNode compares the data that cloud-init serves for node with the data
+// that cloud-init on node received, as read by readFile from the NodeFile*
+// paths, and returns a finding for each stage, so that the first stage that
+// did not get what was served shows where delivery diverged. readFile must
+// return an error wrapping os.ErrNotExist if a file does not exist and one
+// wrapping os.ErrPermission if it cannot be read. Any other error from reading
+// result.json, which is read first, stops the checks, since the node is likely
+// unreachable.
+func VerifyNode(node string, served VerifyServed, readFile func(path string) ([]byte, error)) []VerifyFinding {
+	var findings []VerifyFinding
+	add := func(stage string, status VerifyStatus, detail, d string) {
+		findings = append(findings, VerifyFinding{Node: node, Stage: stage, Status: status, Detail: detail, Diff: d})
+	}
+
+	// Check that cloud-init finished and got its data from a datasource
+	var result struct {
+		V1 struct {
+			Datasource        string              `json:"datasource"`
+			Errors            []string            `json:"errors"`
+			RecoverableErrors map[string][]string `json:"recoverable_errors"`
+		} `json:"v1"`
+	}
+	var finished bool
+	resultBytes, err := readFile(NodeFileResult)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		add(VerifyStageDelivery, VerifyFailed, fmt.Sprintf("%s does not exist, cloud-init has not finished on the node", NodeFileResult), "")
+	case err != nil && !errors.Is(err, os.ErrPermission):
+		add(VerifyStageDelivery, VerifyFailed, fmt.Sprintf("failed to read %s: %v", NodeFileResult, err), "")
+		return findings
+	case err != nil:
+		add(VerifyStageDelivery, VerifyUnchecked, fmt.Sprintf("%s is not readable", NodeFileResult), "")
+	default:
+		if err := json.Unmarshal(resultBytes, &result); err != nil {
+			add(VerifyStageDelivery, VerifyFailed, fmt.Sprintf("failed to unmarshal %s: %v", NodeFileResult, err), "")
+		} else if result.V1.Datasource == "" || strings.HasPrefix(result.V1.Datasource, "DataSourceNone") {
+			add(VerifyStageDelivery, VerifyFailed, "cloud-init found no datasource and fell back to DataSourceNone, so the node did not get its data from cloud-init", "")
+		} else {
+			finished = true
+			add(VerifyStageDelivery, VerifyOK, result.V1.Datasource, "")
+		}
+	}
+
+	// Compare the instance ID and meta-data with what the node received
+	var servedMD map[string]any
+	mdErr := yaml.Unmarshal(served.MetaData, &servedMD)
+	var instanceData struct {
+		DS struct {
+			MetaData map[string]any `json:"meta_data"`
+		} `json:"ds"`
+		V1 struct {
+			InstanceID string `json:"instance_id"`
+		} `json:"v1"`
+	}
+	idBytes, err := readFile(NodeFileInstanceData)
+	if err == nil {
+		err = json.Unmarshal(idBytes, &instanceData)
+	}
+	if err != nil {
+		status, detail := verifyReadError(NodeFileInstanceData, err)
+		add(VerifyStageInstanceID, status, detail, "")
+		add(VerifyStageMetaData, status, detail, "")
+	} else {
+		want, _ := servedMD["instance-id"].(string)
+		switch got := instanceData.V1.InstanceID; {
+		case want == "":
+			add(VerifyStageInstanceID, VerifyUnchecked, "cloud-init serves no instance-id", "")
+		case got != want:
+			add(VerifyStageInstanceID, VerifyMismatch, fmt.Sprintf("node has instance ID %q, cloud-init serves %q", got, want), "")
+		default:
+			add(VerifyStageInstanceID, VerifyOK, got, "")
+		}
+		if mdErr != nil {
+			add(VerifyStageMetaData, VerifyFailed, fmt.Sprintf("failed to unmarshal served meta-data: %v", mdErr), "")
+		} else {
+			status, detail, d := verifyMetaData(node, servedMD, instanceData.DS.MetaData)
+			add(VerifyStageMetaData, status, detail, d)
+		}
+	}
+
+	// Compare the user-data and vendor-data with what the node received
+	for _, c := range []struct {
+		stage string
+		path  string
+		data  []byte
+	}{
+		{VerifyStageUserData, NodeFileUserData, served.UserData},
+		{VerifyStageVendorData, NodeFileVendorData, served.VendorData},
+	} {
+		got, err := readFile(c.path)
+		if errors.Is(err, os.ErrNotExist) && len(bytes.TrimSpace(c.data)) == 0 {
+			add(c.stage, VerifyOK, "cloud-init serves none", "")
+			continue
+		} else if err != nil {
+			status, detail := verifyReadError(c.path, err)
+			add(c.stage, status, detail, "")
+			continue
+		}
+		if bytes.Equal(bytes.TrimRight(got, "\n"), bytes.TrimRight(c.data, "\n")) {
+			add(c.stage, VerifyOK, "", "")
+			continue
+		}
+		add(c.stage, VerifyMismatch, fmt.Sprintf("%s differs from what cloud-init serves", c.path),
+			diff.Unified("cloud-init/"+node+"/"+c.stage, node+":"+c.path, string(c.data), string(got), diff.DefaultContext))
+	}
+
+	// Report whether cloud-init on the node processed its data without
+	// errors
+	if finished {
+		var warnings []string
+		for _, level := range slices.Sorted(maps.Keys(result.V1.RecoverableErrors)) {
+			for _, msg := range result.V1.RecoverableErrors[level] {
+				warnings = append(warnings, level+": "+msg)
+			}
+		}
+		switch {
+		case len(result.V1.Errors) > 0:
+			add(VerifyStageProcessing, VerifyFailed, strings.Join(result.V1.Errors, "; "), "")
+		case len(warnings) > 0:
+			add(VerifyStageProcessing, VerifyOK, fmt.Sprintf("%d recoverable error(s): %s", len(warnings), strings.Join(warnings, "; ")), "")
+		default:
+			add(VerifyStageProcessing, VerifyOK, "", "")
+		}
+	}
+
+	return findings
+}
+
+// verifyMetaData compares the meta-data that cloud-init serves with the
+// meta-data that the node received. Only the keys that cloud-init serves are
+// compared, since datasources add their own.
+func verifyMetaData(node string, served, got map[string]any) (VerifyStatus, string, string) {
+	var differ []string
+	gotServed := make(map[string]any)
+	for k, v := range served {
+		gv, ok := got[k]
+		if ok {
+			gotServed[k] = gv
+		}
+		if !ok || !verifyEqual(v, gv) {
+			differ = append(differ, k)
+		}
+	}
+	if len(differ) == 0 {
+		return VerifyOK, "", ""
+	}
+	slices.Sort(differ)
+	from, _ := yaml.Marshal(served)
+	to, _ := yaml.Marshal(gotServed)
+
+	return VerifyMismatch, fmt.Sprintf("keys differ from what cloud-init serves: %s", strings.Join(differ, ",")),
+		diff.Unified("cloud-init/"+node+"/meta-data", node+":"+NodeFileInstanceData, string(from), string(to), diff.DefaultContext)
+}
+
+// verifyEqual returns whether a and b, unmarshalled from YAML and JSON
+// respectively, are the same value, by comparing them marshalled into JSON.
+func verifyEqual(a, b any) bool {
+	ab, errA := json.Marshal(a)
+	bb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	var an, bn any
+	json.Unmarshal(ab, &an)
+	json.Unmarshal(bb, &bn)
+	ab, _ = json.Marshal(an)
+	bb, _ = json.Marshal(bn)
+
+	return bytes.Equal(ab, bb)
+}
+
+// verifyReadError returns the status and detail of a stage whose file on the
+// node could not be read because of err.
+func verifyReadError(path string, err error) (VerifyStatus, string) {
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return VerifyFailed, fmt.Sprintf("%s does not exist", path)
+	case errors.Is(err, os.ErrPermission):
+		return VerifyUnchecked, fmt.Sprintf("%s is not readable by the SSH user", path)
+	default:
+		return VerifyFailed, fmt.Sprintf("failed to read %s: %v", path, err)
+	}
+}
+
+// SSHOptions are the options that SSHReadFile runs ssh(1) with. Empty options
+// are left to the SSH configuration of the user.
+type SSHOptions struct {
+	User         string
+	Port         int
+	IdentityFile string
+	// Options are passed to ssh with -o, e.g. StrictHostKeyChecking=no.
+	Options []string
+}
+
+// SSHReadFile returns the contents of path on host, read by running cat(1)
+// over ssh(1) non-interactively. The returned error wraps os.ErrNotExist if
+// path does not exist and os.ErrPermission if it cannot be read.
+func SSHReadFile(ctx context.Context, opts SSHOptions, host, path string) ([]byte, error) {
+	args := []string{"-o", "BatchMode=yes"}
+	for _, o := range opts.Options {
+		args = append(args, "-o", o)
+	}
+	if opts.User != "" {
+		args = append(args, "-l", opts.User)
+	}
+	if opts.Port != 0 {
+		args = append(args, "-p", strconv.Itoa(opts.Port))
+	}
+	if opts.IdentityFile != "" {
+		args = append(args, "-i", opts.IdentityFile)
+	}
+	args = append(args, "--", host, "cat", "--", path)
+
+	var stderr bytes.Buffer
+	c := exec.CommandContext(ctx, "ssh", args...)
+	c.Stderr = &stderr
+	out, err := c.Output()
+	if err == nil {
+		return out, nil
+	}
+
+	// ssh exits with 255 if it fails itself, otherwise with the exit
+	// status of cat
+	msg := strings.TrimSpace(stderr.String())
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() != 255 {
+		switch {
+		case strings.Contains(msg, "No such file or directory"):
+			return nil, fmt.Errorf("%s: %w", path, os.ErrNotExist)
+		case strings.Contains(msg, "Permission denied"):
+			return nil, fmt.Errorf("%s: %w", path, os.ErrPermission)
+		}
+	}
+
+	return nil, fmt.Errorf("ssh to %s failed: %w: %s", host, err, msg)
+}
@@ -0,0 +1,167 @@
+package ci
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyNode(t *testing.T) {
+	served := VerifyServed{
+		MetaData: []byte("instance-id: i-123\nlocal-hostname: nid001\ncluster-name: demo\n"),
+		UserData: []byte("#cloud-config\nruncmd:\n- echo hi\n"),
+	}
+	goodFiles := map[string]string{
+		NodeFileResult:       `{"v1": {"datasource": "DataSourceNoCloudNet [seed=http://cloud-init/]", "errors": []}}`,
+		NodeFileInstanceData: `{"ds": {"meta_data": {"instance-id": "i-123", "local-hostname": "nid001", "cluster-name": "demo", "extra": 1}}, "v1": {"instance_id": "i-123"}}`,
+		NodeFileUserData:     "#cloud-config\nruncmd:\n- echo hi\n",
+	}
+	with := func(path, content string) map[string]string {
+		files := make(map[string]string)
+		for k, v := range goodFiles {
+			files[k] = v
+		}
+		if content == "" {
+			delete(files, path)
+		} else {
+			files[path] = content
+		}
+		return files
+	}
+
+	tests := []struct {
+		name   string
+		files  map[string]string
+		denied string
+		want   map[string]VerifyStatus
+	}{
+		{
+			name:  "all delivered",
+			files: goodFiles,
+			want: map[string]VerifyStatus{
+				VerifyStageDelivery:   VerifyOK,
+				VerifyStageInstanceID: VerifyOK,
+				VerifyStageMetaData:   VerifyOK,
+				VerifyStageUserData:   VerifyOK,
+				VerifyStageVendorData: VerifyOK,
+				VerifyStageProcessing: VerifyOK,
+			},
+		},
+		{
+			name:  "fell back to DataSourceNone",
+			files: with(NodeFileResult, `{"v1": {"datasource": "DataSourceNone", "errors": []}}`),
+			want:  map[string]VerifyStatus{VerifyStageDelivery: VerifyFailed},
+		},
+		{
+			name:  "not finished",
+			files: with(NodeFileResult, ""),
+			want:  map[string]VerifyStatus{VerifyStageDelivery: VerifyFailed, VerifyStageUserData: VerifyOK},
+		},
+		{
+			name:  "stale meta-data",
+			files: with(NodeFileInstanceData, `{"ds": {"meta_data": {"instance-id": "i-123", "local-hostname": "nid001", "cluster-name": "old"}}, "v1": {"instance_id": "i-123"}}`),
+			want:  map[string]VerifyStatus{VerifyStageInstanceID: VerifyOK, VerifyStageMetaData: VerifyMismatch},
+		},
+		{
+			name:  "other instance",
+			files: with(NodeFileInstanceData, `{"ds": {"meta_data": {}}, "v1": {"instance_id": "i-999"}}`),
+			want:  map[string]VerifyStatus{VerifyStageInstanceID: VerifyMismatch},
+		},
+		{
+			name:  "user-data differs",
+			files: with(NodeFileUserData, "#cloud-config\nruncmd:\n- echo bye\n"),
+			want:  map[string]VerifyStatus{VerifyStageUserData: VerifyMismatch, VerifyStageProcessing: VerifyOK},
+		},
+		{
+			name:   "user-data not readable",
+			files:  goodFiles,
+			denied: NodeFileUserData,
+			want:   map[string]VerifyStatus{VerifyStageUserData: VerifyUnchecked},
+		},
+		{
+			name:  "processing errors",
+			files: with(NodeFileResult, `{"v1": {"datasource": "DataSourceNoCloudNet", "errors": ["module runcmd failed"]}}`),
+			want:  map[string]VerifyStatus{VerifyStageDelivery: VerifyOK, VerifyStageProcessing: VerifyFailed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := VerifyNode("x1000c0s0b0n0", served, func(path string) ([]byte, error) {
+				if path == tt.denied {
+					return nil, fmt.Errorf("%s: %w", path, os.ErrPermission)
+				}
+				content, ok := tt.files[path]
+				if !ok {
+					return nil, fmt.Errorf("%s: %w", path, os.ErrNotExist)
+				}
+				return []byte(content), nil
+			})
+			got := make(map[string]VerifyFinding)
+			for _, f := range findings {
+				got[f.Stage] = f
+			}
+			for stage, status := range tt.want {
+				f, ok := got[stage]
+				if !ok {
+					t.Errorf("no finding for stage %s in %+v", stage, findings)
+				} else if f.Status != status {
+					t.Errorf("stage %s: got status %s (%s), want %s", stage, f.Status, f.Detail, status)
+				} else if status == VerifyMismatch && stage != VerifyStageInstanceID && f.Diff == "" {
+					t.Errorf("stage %s: mismatch has no diff", stage)
+				}
+			}
+		})
+	}
+
+	t.Run("unreachable", func(t *testing.T) {
+		findings := VerifyNode("x1000c0s0b0n0", served, func(path string) ([]byte, error) {
+			return nil, errors.New("connection refused")
+		})
+		if len(findings) != 1 || findings[0].Stage != VerifyStageDelivery || findings[0].Status != VerifyFailed {
+			t.Errorf("got %+v, want one failed delivery finding", findings)
+		}
+	})
+}
+
+func TestSSHReadFile(t *testing.T) {
+	// Stand in for ssh with a script that prints its arguments, or fails
+	// like cat for paths containing "missing" or "denied"
+	dir := t.TempDir()
+	script := `#!/bin/sh
+for last; do :; done
+case "$last" in
+*missing*) echo "cat: $last: No such file or directory" >&2; exit 1 ;;
+*denied*) echo "cat: $last: Permission denied" >&2; exit 1 ;;
+*down*) echo "ssh: connect to host: Connection refused" >&2; exit 255 ;;
+esac
+echo "$@"
+`
+	if err := os.WriteFile(filepath.Join(dir, "ssh"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	opts := SSHOptions{User: "root", Port: 2222, IdentityFile: "/key", Options: []string{"StrictHostKeyChecking=no"}}
+	out, err := SSHReadFile(context.Background(), opts, "nid001", "/run/file")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "-o BatchMode=yes -o StrictHostKeyChecking=no -l root -p 2222 -i /key -- nid001 cat -- /run/file"
+	if got := strings.TrimSpace(string(out)); got != want {
+		t.Errorf("got args %q, want %q", got, want)
+	}
+
+	for path, want := range map[string]error{"/missing": os.ErrNotExist, "/denied": os.ErrPermission} {
+		if _, err := SSHReadFile(context.Background(), SSHOptions{}, "nid001", path); !errors.Is(err, want) {
+			t.Errorf("%s: got error %v, want %v", path, err, want)
+		}
+	}
+	_, err = SSHReadFile(context.Background(), SSHOptions{}, "nid001", "/down")
+	if err == nil || errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrPermission) {
+		t.Errorf("got error %v, want ssh failure", err)
+	}
+}
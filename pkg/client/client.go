@@ -2,6 +2,7 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
@@ -14,6 +15,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/OpenCHAMI/ochami/internal/audit"
 	oio "github.com/OpenCHAMI/ochami/internal/io"
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/internal/version"
@@ -28,12 +30,86 @@ var (
 	responseHeaderTimeout = 120 * time.Second
 )
 
+// DefaultImpersonationHeader is the request header that identifies the user
+// requests are made on behalf of (see OchamiClient.OnBehalfOf) if no other
+// header is configured.
+const DefaultImpersonationHeader = "Impersonate-User"
+
+// OpenAPIRelpath is the endpoint, relative to the base URI of a service, at
+// which services publish the OpenAPI specification of their API.
+const OpenAPIRelpath = "/openapi.json"
+
 // OchamiClient is an *http.Client that contains metadata for OpenCHAMI services
 // being communicated with.
 type OchamiClient struct {
 	*http.Client
 	BaseURI     *url.URL // Base URL for OpenCHAMI services (e.g. https://foobar.openchami.cluster)
 	ServiceName string   // Name of service being contacted (e.g. BSS)
+
+	// PathOverrides maps default endpoint paths (e.g. /State/Components)
+	// to the paths to use instead, for deployments that do not use the
+	// upstream endpoint paths. See ResolveEndpoint.
+	PathOverrides map[string]string
+
+	// ReadOnly, if true, causes any request whose method is not GET, HEAD,
+	// or OPTIONS to fail with ReadOnlyError without being sent.
+	ReadOnly bool
+
+	// Deadline, if not zero, bounds all requests made with the client.
+	// Requests that would start after it has passed fail with
+	// NotAttemptedError without being sent, and requests still in flight
+	// when it passes are canceled.
+	Deadline time.Time
+
+	// OnBehalfOf, if not empty, is the user that requests are made on
+	// behalf of. It is sent in the ImpersonationHeader header of each
+	// request (DefaultImpersonationHeader if empty) for API gateways that
+	// support impersonation.
+	OnBehalfOf          string
+	ImpersonationHeader string
+
+	// AuditLog, if not nil, records each request that may modify data that
+	// is sent, along with OnBehalfOf.
+	AuditLog *audit.Log
+
+	// MaxMemoryBuffer is the size in bytes above which response bodies
+	// read with GetDataStream are spilled to a temporary file instead of
+	// being held in memory (DefaultMaxMemoryBuffer if not set). Response
+	// bodies larger than it are also not logged at the debug level.
+	MaxMemoryBuffer int64
+
+	// ValidatePayload, if not nil, is called with the method, URI, and
+	// payload of each request that has one before it is sent. If it
+	// returns an error, the request fails with an error wrapping
+	// InvalidPayloadError without being sent.
+	ValidatePayload func(method, uri string, body HTTPBody) error
+
+	// NoCache, if true, causes each request to be sent with the header
+	// "Cache-Control: no-cache", so that caches in front of the service
+	// (e.g. the ochami agent) do not serve a stale response.
+	NoCache bool
+}
+
+// WithDeadline returns a shallow copy of oc whose Deadline is the earlier of
+// oc.Deadline and deadline, so that requests for one target of a batch can be
+// bounded without affecting the others. A zero deadline leaves Deadline as is.
+func (oc *OchamiClient) WithDeadline(deadline time.Time) *OchamiClient {
+	c := *oc
+	if !deadline.IsZero() && (c.Deadline.IsZero() || deadline.Before(c.Deadline)) {
+		c.Deadline = deadline
+	}
+
+	return &c
+}
+
+// Uncached returns a shallow copy of oc whose NoCache is true, for reads whose
+// result must reflect writes just made, e.g. to verify them or to poll for a
+// change.
+func (oc *OchamiClient) Uncached() *OchamiClient {
+	c := *oc
+	c.NoCache = true
+
+	return &c
 }
 
 // defaultClient creates an http.DefaultClient for its OchamiClient.
@@ -76,15 +152,43 @@ func NewOchamiClient(serviceName, baseURI string, insecure bool) (*OchamiClient,
 	return oc, err
 }
 
+// ResolveEndpoint returns endpoint with any matching PathOverrides applied. The
+// longest override whose key matches endpoint exactly or is a prefix of it up
+// to a path segment boundary is used, with the matching prefix replaced by the
+// override's value. For instance, with the override
+// "/State/Components" -> "/v2/components", "/State/Components/x1000c0s0b0n0"
+// resolves to "/v2/components/x1000c0s0b0n0". If no override matches, endpoint
+// is returned unmodified.
+func (oc *OchamiClient) ResolveEndpoint(endpoint string) string {
+	var matchFrom, matchTo string
+	for from, to := range oc.PathOverrides {
+		f := strings.TrimSuffix(from, "/")
+		if f == "" || len(f) <= len(matchFrom) {
+			continue
+		}
+		if endpoint == f || strings.HasPrefix(endpoint, f+"/") {
+			matchFrom, matchTo = f, to
+		}
+	}
+	if matchFrom == "" {
+		return endpoint
+	}
+	resolved := strings.TrimSuffix(matchTo, "/") + strings.TrimPrefix(endpoint, matchFrom)
+	log.Logger.Debug().Msgf("resolved endpoint %s to %s using path override", endpoint, resolved)
+	return resolved
+}
+
 // GetURI takes an endpoint and joins it with the OchamiClient's BaseURI to form
-// the final URI to be used for a request. If query is specified, it is used as
-// a raw query string and appended onto the URL without URL encoding. query
+// the final URI to be used for a request. Any PathOverrides are applied to
+// endpoint first (see ResolveEndpoint). If query is specified, it is used as a
+// raw query string and appended onto the URL without URL encoding. query
 // should not contain the initial '?'.
 func (oc *OchamiClient) GetURI(endpoint, query string) (string, error) {
 	uri, err := url.Parse(oc.BaseURI.String())
 	if err != nil {
 		return "", fmt.Errorf("failed to parse base URI %s: %w", oc.BaseURI, err)
 	}
+	endpoint = oc.ResolveEndpoint(endpoint)
 
 	uri.Path, err = url.JoinPath(uri.Path, endpoint)
 	if err != nil {
@@ -239,12 +343,45 @@ func (oc *OchamiClient) MakeOchamiRequest(method, endpoint, query string, header
 
 // MakeRequest is a convenience function that, using an OchamiClient as the HTTP
 // client, sends an HTTP request to the passed uri including optional headers
-// and body, and uses the passed HTTP method.
+// and body, and uses the passed HTTP method. If oc.ReadOnly is true, requests
+// that may modify data are not sent and an error wrapping ReadOnlyError is
+// returned. If oc.Deadline has passed, the request is not sent and an error
+// wrapping NotAttemptedError is returned. If oc.ValidatePayload rejects the
+// payload, the request is not sent and an error wrapping InvalidPayloadError is
+// returned.
 func (oc *OchamiClient) MakeRequest(method, uri string, headers *HTTPHeaders, body HTTPBody) (*http.Response, error) {
+	// Refuse to send requests that may modify data in read-only mode
+	if oc.ReadOnly {
+		switch method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+		default:
+			return nil, fmt.Errorf("%w: %s %s", ReadOnlyError, method, uri)
+		}
+	}
+
+	// Refuse to send payloads that fail validation, e.g. against the
+	// schema of the service
+	if oc.ValidatePayload != nil && len(body) > 0 {
+		if err := oc.ValidatePayload(method, uri, body); err != nil {
+			return nil, fmt.Errorf("%w: %s %s: %w", InvalidPayloadError, method, uri, err)
+		}
+	}
+
+	// Do not start requests once the deadline has passed, and cancel the
+	// request if it is still in flight when the deadline passes
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if !oc.Deadline.IsZero() {
+		if !time.Now().Before(oc.Deadline) {
+			return nil, fmt.Errorf("%w: %s %s", NotAttemptedError, method, uri)
+		}
+		ctx, cancel = context.WithDeadline(ctx, oc.Deadline)
+	}
+
 	// Create request using function args
 	log.Logger.Debug().Msgf("%s: %s", method, uri)
-	req, err := http.NewRequest(method, uri, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, method, uri, bytes.NewBuffer(body))
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to create new HTTP request: %w", err)
 	}
 
@@ -253,13 +390,23 @@ func (oc *OchamiClient) MakeRequest(method, uri string, headers *HTTPHeaders, bo
 		headers = NewHTTPHeaders()
 	}
 
-	// Add headers, including user agent
+	// Add headers, including user agent and impersonated user
 	req.Header.Add("User-Agent", userAgent)
+	if oc.OnBehalfOf != "" {
+		h := oc.ImpersonationHeader
+		if h == "" {
+			h = DefaultImpersonationHeader
+		}
+		req.Header.Set(h, oc.OnBehalfOf)
+	}
 	for key, vals := range *headers {
 		for _, val := range vals {
 			req.Header.Add(key, val)
 		}
 	}
+	if oc.NoCache {
+		req.Header.Set("Cache-Control", "no-cache")
+	}
 
 	// Debug info for request
 	if len(req.Header) > 0 {
@@ -279,7 +426,9 @@ func (oc *OchamiClient) MakeRequest(method, uri string, headers *HTTPHeaders, bo
 
 	// Execute HTTP request
 	res, err := oc.Client.Do(req)
+	oc.audit(method, uri, res, err)
 	if err != nil {
+		cancel()
 		return nil, fmt.Errorf("failed to execute HTTP request: %w", err)
 	}
 
@@ -295,7 +444,9 @@ func (oc *OchamiClient) MakeRequest(method, uri string, headers *HTTPHeaders, bo
 			log.Logger.Debug().Msg("No headers in response")
 		}
 		resBodyLen := res.ContentLength
-		if resBodyLen > 0 {
+		if resBodyLen > oc.maxMemoryBuffer() {
+			log.Logger.Debug().Msgf("Response body of %d bytes too large to log", resBodyLen)
+		} else if resBodyLen > 0 && log.Logger.Debug().Enabled() {
 			var resBodyCopy bytes.Buffer
 			resBodyReader := io.TeeReader(res.Body, &resBodyCopy)
 			resBodyBytes, err := ioutil.ReadAll(resBodyReader)
@@ -310,11 +461,57 @@ func (oc *OchamiClient) MakeRequest(method, uri string, headers *HTTPHeaders, bo
 		}
 	} else {
 		log.Logger.Debug().Msg("Response was nil")
+		cancel()
+		return res, err
 	}
 
+	// The request context must outlive MakeRequest so the caller can read
+	// the response body, so release it when the body is closed
+	res.Body = &cancelOnCloseBody{ReadCloser: res.Body, cancel: cancel}
+
 	return res, err
 }
 
+// audit records a request that may have modified data in oc.AuditLog, if set,
+// with the response res or error err. Failing to record it is logged but does
+// not fail the request, which has already been sent.
+func (oc *OchamiClient) audit(method, uri string, res *http.Response, err error) {
+	if oc.AuditLog == nil {
+		return
+	}
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return
+	}
+	e := audit.Entry{
+		OnBehalfOf: oc.OnBehalfOf,
+		Service:    oc.ServiceName,
+		Method:     method,
+		URI:        uri,
+	}
+	if res != nil {
+		e.Status = res.StatusCode
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	if err := oc.AuditLog.Record(e); err != nil {
+		log.Logger.Warn().Err(err).Msgf("failed to record %s %s in audit log", method, uri)
+	}
+}
+
+// cancelOnCloseBody is a response body that cancels the context of its
+// request when closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
 // UseCACert takes a path to a CA certificate bundle in PEM format and sets it
 // as the OchamiClient's certificate authority certificate to verify the
 // certificates of connections to TLS-enabled HTTP URIs (HTTPS).
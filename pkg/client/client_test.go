@@ -1,13 +1,21 @@
 package client
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/OpenCHAMI/ochami/internal/audit"
 	"github.com/OpenCHAMI/ochami/pkg/format"
 )
 
@@ -264,3 +272,261 @@ func TestReadPayload(t *testing.T) {
 		})
 	}
 }
+
+func TestGetURI_PathOverrides(t *testing.T) {
+	oc, err := NewOchamiClient("svc", "https://foobar.openchami.cluster/apis/smd/hsm/v2", false)
+	if err != nil {
+		t.Fatalf("NewOchamiClient: %v", err)
+	}
+	oc.PathOverrides = map[string]string{
+		"/State/Components":        "/state/components/",
+		"/State/Components/ByNID":  "/nids",
+		"/Inventory/RedfishEndpoi": "/nope",
+	}
+
+	tests := []struct {
+		name     string
+		endpoint string
+		query    string
+		want     string
+	}{
+		{
+			name:     "no override",
+			endpoint: "/groups",
+			want:     "https://foobar.openchami.cluster/apis/smd/hsm/v2/groups",
+		},
+		{
+			name:     "exact override",
+			endpoint: "/State/Components",
+			query:    "type=Node",
+			want:     "https://foobar.openchami.cluster/apis/smd/hsm/v2/state/components?type=Node",
+		},
+		{
+			name:     "prefix override",
+			endpoint: "/State/Components/x1000c0s0b0n0",
+			want:     "https://foobar.openchami.cluster/apis/smd/hsm/v2/state/components/x1000c0s0b0n0",
+		},
+		{
+			name:     "longest prefix wins",
+			endpoint: "/State/Components/ByNID/1",
+			want:     "https://foobar.openchami.cluster/apis/smd/hsm/v2/nids/1",
+		},
+		{
+			name:     "partial segment does not match",
+			endpoint: "/Inventory/RedfishEndpoints",
+			want:     "https://foobar.openchami.cluster/apis/smd/hsm/v2/Inventory/RedfishEndpoints",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := oc.GetURI(tt.endpoint, tt.query)
+			if err != nil {
+				t.Fatalf("GetURI: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("GetURI() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMakeRequest_ReadOnly(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	oc, err := NewOchamiClient("svc", ts.URL, false)
+	if err != nil {
+		t.Fatalf("NewOchamiClient: %v", err)
+	}
+	oc.ReadOnly = true
+
+	if _, err := oc.GetData("/ok", "", nil); err != nil {
+		t.Errorf("GetData in read-only mode returned error: %v", err)
+	}
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete} {
+		_, err := oc.MakeOchamiRequest(method, "/ok", "", nil, nil)
+		if !errors.Is(err, ReadOnlyError) {
+			t.Errorf("%s in read-only mode: expected ReadOnlyError, got %v", method, err)
+		}
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("server received %d requests, want 1", n)
+	}
+}
+
+func TestMakeRequest_ValidatePayload(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	oc, err := NewOchamiClient("svc", ts.URL, false)
+	if err != nil {
+		t.Fatalf("NewOchamiClient: %v", err)
+	}
+	oc.ValidatePayload = func(method, uri string, body HTTPBody) error {
+		if string(body) != `{"ok":true}` {
+			return errors.New("bad payload")
+		}
+		return nil
+	}
+
+	if _, err := oc.MakeOchamiRequest(http.MethodPost, "/ok", "", nil, HTTPBody(`{"ok":true}`)); err != nil {
+		t.Errorf("POST with valid payload returned error: %v", err)
+	}
+	if _, err := oc.MakeOchamiRequest(http.MethodPost, "/ok", "", nil, HTTPBody(`{"ok":false}`)); !errors.Is(err, InvalidPayloadError) {
+		t.Errorf("POST with invalid payload: expected InvalidPayloadError, got %v", err)
+	}
+	if _, err := oc.MakeOchamiRequest(http.MethodDelete, "/ok", "", nil, nil); err != nil {
+		t.Errorf("DELETE without payload returned error: %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("server received %d requests, want 2", n)
+	}
+}
+
+func TestMakeRequest_Deadline(t *testing.T) {
+	var requests atomic.Int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer ts.Close()
+
+	oc, err := NewOchamiClient("svc", ts.URL, false)
+	if err != nil {
+		t.Fatalf("NewOchamiClient: %v", err)
+	}
+
+	// Requests before the deadline are sent and their bodies readable
+	oc.Deadline = time.Now().Add(200 * time.Millisecond)
+	henv, err := oc.GetData("/ok", "", nil)
+	if err != nil {
+		t.Fatalf("GetData before deadline returned error: %v", err)
+	}
+	if string(henv.Body) != `{"ok":true}` {
+		t.Errorf("unexpected body %q", henv.Body)
+	}
+
+	// Requests in flight when the deadline passes are canceled
+	if _, err := oc.GetData("/slow", "", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("in-flight request: expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// Requests after the deadline are not sent
+	if _, err := oc.PostData("/ok", "", nil, nil); !errors.Is(err, NotAttemptedError) {
+		t.Errorf("request after deadline: expected NotAttemptedError, got %v", err)
+	}
+	if n := requests.Load(); n != 2 {
+		t.Errorf("server received %d requests, want 2", n)
+	}
+}
+
+func TestOchamiClient_WithDeadline(t *testing.T) {
+	early, late := time.Now().Add(time.Minute), time.Now().Add(time.Hour)
+	tests := []struct {
+		name     string
+		deadline time.Time
+		with     time.Time
+		want     time.Time
+	}{
+		{name: "no deadline", with: late, want: late},
+		{name: "earlier", deadline: late, with: early, want: early},
+		{name: "later", deadline: early, with: late, want: early},
+		{name: "zero", deadline: early, want: early},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oc := &OchamiClient{Deadline: tt.deadline}
+			got := oc.WithDeadline(tt.with)
+			if !got.Deadline.Equal(tt.want) {
+				t.Errorf("WithDeadline() Deadline = %s, want %s", got.Deadline, tt.want)
+			}
+			if !oc.Deadline.Equal(tt.deadline) {
+				t.Errorf("WithDeadline() modified original Deadline")
+			}
+		})
+	}
+}
+
+func TestOchamiClient_Uncached(t *testing.T) {
+	var cacheControl []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cacheControl = append(cacheControl, r.Header.Get("Cache-Control"))
+	}))
+	defer ts.Close()
+
+	oc, err := NewOchamiClient("svc", ts.URL, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, c := range []*OchamiClient{oc, oc.Uncached()} {
+		if _, err := c.MakeRequest(http.MethodGet, ts.URL, nil, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if want := []string{"", "no-cache"}; !reflect.DeepEqual(cacheControl, want) {
+		t.Errorf("got Cache-Control headers %q, want %q", cacheControl, want)
+	}
+	if oc.NoCache {
+		t.Errorf("Uncached() modified original NoCache")
+	}
+}
+
+func TestMakeRequest_OnBehalfOf(t *testing.T) {
+	var impersonated []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		impersonated = append(impersonated, r.Header.Get("X-Forwarded-User"))
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	oc, err := NewOchamiClient("svc", ts.URL, false)
+	if err != nil {
+		t.Fatalf("NewOchamiClient: %v", err)
+	}
+	logPath := filepath.Join(t.TempDir(), "audit.log")
+	if oc.AuditLog, err = audit.Open(logPath, "test"); err != nil {
+		t.Fatalf("audit.Open: %v", err)
+	}
+	oc.OnBehalfOf = "alice"
+	oc.ImpersonationHeader = "X-Forwarded-User"
+
+	if _, err := oc.GetData("/ok", "", nil); err != nil {
+		t.Fatalf("GetData returned error: %v", err)
+	}
+	if _, err := oc.PostData("/ok", "", nil, nil); err != nil {
+		t.Fatalf("PostData returned error: %v", err)
+	}
+	if len(impersonated) != 2 || impersonated[0] != "alice" || impersonated[1] != "alice" {
+		t.Errorf("impersonation headers received = %q, want alice for each request", impersonated)
+	}
+
+	// Only the request that may modify data is audited
+	b, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("audit log has %d entries, want 1: %s", len(lines), b)
+	}
+	var e audit.Entry
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Method != http.MethodPost || e.OnBehalfOf != "alice" || e.Status != http.StatusCreated || e.Service != "svc" || e.URI != ts.URL+"/ok" {
+		t.Errorf("unexpected audit log entry %+v", e)
+	}
+}
@@ -0,0 +1,107 @@
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/OpenCHAMI/ochami/pkg/client"
+)
+
+const (
+	serviceNameGrafana = "Grafana"
+
+	GrafanaRelpathAnnotations = "/api/annotations"
+
+	// TagOchami is added to every annotation created by ochami so that
+	// dashboards can query for them.
+	TagOchami = "ochami"
+)
+
+// GrafanaClient is an OchamiClient that is configured to push annotations to
+// the Grafana HTTP API.
+type GrafanaClient struct {
+	*client.OchamiClient
+}
+
+// Annotation represents the payload structure of a Grafana annotation. Times
+// are in milliseconds since the Unix epoch. If DashboardUID is empty, the
+// annotation is an organization-wide annotation that can be shown on any
+// dashboard.
+type Annotation struct {
+	DashboardUID string   `json:"dashboardUID,omitempty" yaml:"dashboardUID,omitempty"`
+	PanelID      int64    `json:"panelId,omitempty" yaml:"panelId,omitempty"`
+	Time         int64    `json:"time,omitempty" yaml:"time,omitempty"`
+	TimeEnd      int64    `json:"timeEnd,omitempty" yaml:"timeEnd,omitempty"`
+	Tags         []string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Text         string   `json:"text" yaml:"text"`
+}
+
+// NewClient takes a baseURI (the root URI of Grafana, e.g.
+// https://grafana.example.com) and returns a pointer to a new GrafanaClient.
+// If an error occurred creating the embedded OchamiClient, it is returned. If
+// insecure is true, TLS certificates will not be verified.
+func NewClient(baseURI string, insecure bool) (*GrafanaClient, error) {
+	oc, err := client.NewOchamiClient(serviceNameGrafana, baseURI, insecure)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OchamiClient for %s: %w", serviceNameGrafana, err)
+	}
+	gc := &GrafanaClient{
+		OchamiClient: oc,
+	}
+
+	return gc, err
+}
+
+// NewActionAnnotation returns an Annotation describing action having been
+// performed on xnames. The annotation spans start to end, or is a point in
+// time if end is zero. The annotation is tagged with TagOchami, the action,
+// and any extra tags, and its text lists the xnames, sorted.
+func NewActionAnnotation(action string, xnames []string, start, end time.Time, tags ...string) Annotation {
+	sorted := append([]string(nil), xnames...)
+	sort.Strings(sorted)
+
+	a := Annotation{
+		Time: start.UnixMilli(),
+		Tags: append([]string{TagOchami, action}, tags...),
+		Text: fmt.Sprintf("%s: %d component(s): %s", action, len(sorted), strings.Join(sorted, ", ")),
+	}
+	if len(sorted) == 0 {
+		a.Text = action
+	}
+	if !end.IsZero() {
+		a.TimeEnd = end.UnixMilli()
+	}
+
+	return a
+}
+
+// PostAnnotation is a wrapper function around OchamiClient.PostData that takes
+// an Annotation and a Grafana API token, puts the token in the request headers
+// as an authorization bearer, marshals the annotation as JSON, and POSTs it to
+// the Grafana annotations endpoint.
+func (gc *GrafanaClient) PostAnnotation(a Annotation, token string) (client.HTTPEnvelope, error) {
+	var (
+		henv    client.HTTPEnvelope
+		headers *client.HTTPHeaders
+		body    client.HTTPBody
+		err     error
+	)
+	if body, err = json.Marshal(a); err != nil {
+		return henv, fmt.Errorf("PostAnnotation(): failed to marshal annotation: %w", err)
+	}
+	headers = client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			return henv, fmt.Errorf("PostAnnotation(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	henv, err = gc.PostData(GrafanaRelpathAnnotations, "", headers, body)
+	if err != nil {
+		err = fmt.Errorf("PostAnnotation(): failed to POST annotation to Grafana: %w", err)
+	}
+
+	return henv, err
+}
@@ -0,0 +1,52 @@
+package grafana
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewActionAnnotation(t *testing.T) {
+	start := time.UnixMilli(1700000000000)
+	end := start.Add(time.Minute)
+
+	tests := []struct {
+		name   string
+		action string
+		xnames []string
+		end    time.Time
+		tags   []string
+		want   Annotation
+	}{
+		{
+			name:   "point in time",
+			action: "pcs transition off",
+			xnames: []string{"x1000c0s1b0n0", "x1000c0s0b0n0"},
+			want: Annotation{
+				Time: 1700000000000,
+				Tags: []string{TagOchami, "pcs transition off"},
+				Text: "pcs transition off: 2 component(s): x1000c0s0b0n0, x1000c0s1b0n0",
+			},
+		},
+		{
+			name:   "region with extra tags",
+			action: "maintenance",
+			end:    end,
+			tags:   []string{"rack1"},
+			want: Annotation{
+				Time:    1700000000000,
+				TimeEnd: 1700000060000,
+				Tags:    []string{TagOchami, "maintenance", "rack1"},
+				Text:    "maintenance",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewActionAnnotation(tt.action, tt.xnames, start, tt.end, tt.tags...)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
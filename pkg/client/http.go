@@ -13,6 +13,9 @@ import (
 var (
 	UnsuccessfulHTTPError = fmt.Errorf("unsuccessful HTTP status")
 	NilMapPointerError    = fmt.Errorf("nil map pointer")
+	ReadOnlyError         = fmt.Errorf("refusing to send mutating request in read-only mode")
+	NotAttemptedError     = fmt.Errorf("not attempted: command deadline exceeded")
+	InvalidPayloadError   = fmt.Errorf("refusing to send invalid payload")
 )
 
 type HTTPHeaders map[string][]string
@@ -17,6 +17,7 @@ const (
 	PCSRelpathReadiness = "/readiness"
 	PCSRelpathHealth    = "/health"
 	PCSTransitions      = "/transitions"
+	PCSPowerStatus      = "/power-status"
 )
 
 // PCSClient is an OchamiClient that has its BasePath set configured to the one
@@ -224,3 +225,33 @@ func (pc *PCSClient) DeleteTransition(id string, token string) (client.HTTPEnvel
 
 	return henv, err
 }
+
+// GetPowerStatus is a wrapper function around OchamiClient.GetData to hit the
+// /power-status endpoint. If xnames is not empty, the power status of only
+// those components is requested. Otherwise, the power status of all components
+// known to PCS is requested.
+func (pc *PCSClient) GetPowerStatus(xnames []string, token string) (client.HTTPEnvelope, error) {
+	var (
+		henv client.HTTPEnvelope
+		err  error
+	)
+
+	headers := client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			return henv, fmt.Errorf("GetPowerStatus(): error setting token in HTTP headers: %w", err)
+		}
+	}
+
+	values := url.Values{}
+	for _, x := range xnames {
+		values.Add("xname", x)
+	}
+
+	henv, err = pc.GetData(PCSPowerStatus, values.Encode(), headers)
+	if err != nil {
+		err = fmt.Errorf("GetPowerStatus(): error getting PCS power status: %w", err)
+	}
+
+	return henv, err
+}
@@ -0,0 +1,198 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package pcs
+
+import (
+	"sort"
+	"strings"
+)
+
+// PowerStatus represents the power status of a single component as returned
+// by the PCS /power-status endpoint. Location is not returned by PCS, but can
+// be set to the physical location of the component (see xname.Locations).
+type PowerStatus struct {
+	Xname                     string   `json:"xname" yaml:"xname"`
+	PowerState                string   `json:"powerState" yaml:"powerState"`
+	ManagementState           string   `json:"managementState" yaml:"managementState"`
+	Error                     string   `json:"error" yaml:"error"`
+	SupportedPowerTransitions []string `json:"supportedPowerTransitions" yaml:"supportedPowerTransitions"`
+	LastUpdated               string   `json:"lastUpdated" yaml:"lastUpdated"`
+	Location                  string   `json:"location,omitempty" yaml:"location,omitempty"`
+}
+
+// PowerStatusList represents the response body of the PCS /power-status
+// endpoint.
+type PowerStatusList struct {
+	Status []PowerStatus `json:"status" yaml:"status"`
+}
+
+// PowerException is a component whose power state did not match the expected
+// state or whose power status could not be determined. Location, if set, is the
+// physical location of the component.
+type PowerException struct {
+	Xname      string `json:"xname" yaml:"xname"`
+	PowerState string `json:"powerState" yaml:"powerState"`
+	Error      string `json:"error,omitempty" yaml:"error,omitempty"`
+	Location   string `json:"location,omitempty" yaml:"location,omitempty"`
+}
+
+// PowerSummary is a rollup of the power status of a set of components.
+type PowerSummary struct {
+	Total      int              `json:"total" yaml:"total"`
+	Counts     map[string]int   `json:"counts" yaml:"counts"`
+	Expected   string           `json:"expected,omitempty" yaml:"expected,omitempty"`
+	Exceptions []PowerException `json:"exceptions" yaml:"exceptions"`
+	Missing    []string         `json:"missing,omitempty" yaml:"missing,omitempty"`
+}
+
+// SummarizePowerStatus counts the components in statuses by power state. If
+// expect is not empty, any component whose power state does not match expect
+// (case-insensitively) or which has an error is added to the exceptions list.
+// If expect is empty, only components with errors are exceptions. Any xname in
+// requested that does not appear in statuses is reported as missing.
+func SummarizePowerStatus(statuses []PowerStatus, expect string, requested []string) PowerSummary {
+	summary := PowerSummary{
+		Total:      len(statuses),
+		Counts:     make(map[string]int),
+		Expected:   expect,
+		Exceptions: []PowerException{},
+	}
+	seen := make(map[string]bool)
+	for _, s := range statuses {
+		seen[s.Xname] = true
+		state := strings.ToLower(s.PowerState)
+		if state == "" {
+			state = "undefined"
+		}
+		summary.Counts[state]++
+		if s.Error != "" || (expect != "" && state != strings.ToLower(expect)) {
+			summary.Exceptions = append(summary.Exceptions, PowerException{
+				Xname:      s.Xname,
+				PowerState: state,
+				Error:      s.Error,
+			})
+		}
+	}
+	for _, x := range requested {
+		if !seen[x] {
+			summary.Missing = append(summary.Missing, x)
+		}
+	}
+	sort.Slice(summary.Exceptions, func(i, j int) bool {
+		return summary.Exceptions[i].Xname < summary.Exceptions[j].Xname
+	})
+	sort.Strings(summary.Missing)
+
+	return summary
+}
+
+// Deviates returns true if any component in the summary is an exception or is
+// missing.
+func (ps PowerSummary) Deviates() bool {
+	return len(ps.Exceptions) > 0 || len(ps.Missing) > 0
+}
+
+// Outcomes of powering off a component with escalation (see PowerOffOutcomes).
+const (
+	PowerOffGraceful = "graceful"
+	PowerOffForced   = "forced"
+	PowerOffFailed   = "failed"
+)
+
+// PowerOffResult is the outcome of powering off a single component with
+// escalation from a graceful shutdown to a forced power off. Location, if set,
+// is the physical location of the component.
+type PowerOffResult struct {
+	Xname      string `json:"xname" yaml:"xname"`
+	Outcome    string `json:"outcome" yaml:"outcome"`
+	PowerState string `json:"powerState" yaml:"powerState"`
+	Error      string `json:"error,omitempty" yaml:"error,omitempty"`
+	Location   string `json:"location,omitempty" yaml:"location,omitempty"`
+}
+
+// PowerOffReport is the per-component outcome of powering off a set of
+// components with escalation, along with the number of components with each
+// outcome.
+type PowerOffReport struct {
+	Counts  map[string]int   `json:"counts" yaml:"counts"`
+	Results []PowerOffResult `json:"results" yaml:"results"`
+}
+
+// Failed returns true if any component in the report failed to power off.
+func (r PowerOffReport) Failed() bool {
+	return r.Counts[PowerOffFailed] > 0
+}
+
+// NotInPowerState returns the xnames in requested whose power state in
+// statuses is not state (case-insensitively), including those that do not
+// appear in statuses, sorted and without duplicates.
+func NotInPowerState(statuses []PowerStatus, requested []string, state string) []string {
+	inState := make(map[string]bool)
+	for _, s := range statuses {
+		inState[s.Xname] = s.Error == "" && strings.EqualFold(s.PowerState, state)
+	}
+	seen := make(map[string]bool)
+	var xnames []string
+	for _, x := range requested {
+		if seen[x] || inState[x] {
+			continue
+		}
+		seen[x] = true
+		xnames = append(xnames, x)
+	}
+	sort.Strings(xnames)
+
+	return xnames
+}
+
+// PowerOffOutcomes returns the report of powering off the xnames in requested,
+// first gracefully and then forcibly for those in forced, given their final
+// power statuses. Each component that is off is PowerOffGraceful, or
+// PowerOffForced if it was forced off. Each component that is not off,
+// including those that are not in statuses, is PowerOffFailed. Results are
+// sorted by xname.
+func PowerOffOutcomes(requested, forced []string, statuses []PowerStatus) PowerOffReport {
+	byXname := make(map[string]PowerStatus)
+	for _, s := range statuses {
+		byXname[s.Xname] = s
+	}
+	wasForced := make(map[string]bool)
+	for _, x := range forced {
+		wasForced[x] = true
+	}
+	report := PowerOffReport{
+		Counts:  make(map[string]int),
+		Results: []PowerOffResult{},
+	}
+	seen := make(map[string]bool)
+	for _, x := range requested {
+		if seen[x] {
+			continue
+		}
+		seen[x] = true
+		r := PowerOffResult{Xname: x, PowerState: "undefined"}
+		if s, ok := byXname[x]; ok {
+			r.Error = s.Error
+			if s.PowerState != "" {
+				r.PowerState = strings.ToLower(s.PowerState)
+			}
+		} else {
+			r.Error = "power status not reported by PCS"
+		}
+		switch {
+		case r.Error != "" || r.PowerState != "off":
+			r.Outcome = PowerOffFailed
+		case wasForced[x]:
+			r.Outcome = PowerOffForced
+		default:
+			r.Outcome = PowerOffGraceful
+		}
+		report.Counts[r.Outcome]++
+		report.Results = append(report.Results, r)
+	}
+	sort.Slice(report.Results, func(i, j int) bool {
+		return report.Results[i].Xname < report.Results[j].Xname
+	})
+
+	return report
+}
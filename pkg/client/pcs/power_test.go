@@ -0,0 +1,119 @@
+// This source code is licensed under the license found in the LICENSE file at
+// the root directory of this source tree.
+package pcs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummarizePowerStatus(t *testing.T) {
+	statuses := []PowerStatus{
+		{Xname: "x1000c0s1b0n0", PowerState: "on"},
+		{Xname: "x1000c0s0b0n0", PowerState: "On"},
+		{Xname: "x1000c0s2b0n0", PowerState: "off"},
+		{Xname: "x1000c0s3b0n0", PowerState: "undefined", Error: "BMC unreachable"},
+	}
+	tests := []struct {
+		name      string
+		expect    string
+		requested []string
+		want      PowerSummary
+		deviates  bool
+	}{
+		{
+			name:   "no expectation",
+			expect: "",
+			want: PowerSummary{
+				Total:  4,
+				Counts: map[string]int{"on": 2, "off": 1, "undefined": 1},
+				Exceptions: []PowerException{
+					{Xname: "x1000c0s3b0n0", PowerState: "undefined", Error: "BMC unreachable"},
+				},
+			},
+			deviates: true,
+		},
+		{
+			name:      "expect on",
+			expect:    "on",
+			requested: []string{"x1000c0s0b0n0", "x1000c0s9b0n0"},
+			want: PowerSummary{
+				Total:    4,
+				Counts:   map[string]int{"on": 2, "off": 1, "undefined": 1},
+				Expected: "on",
+				Exceptions: []PowerException{
+					{Xname: "x1000c0s2b0n0", PowerState: "off"},
+					{Xname: "x1000c0s3b0n0", PowerState: "undefined", Error: "BMC unreachable"},
+				},
+				Missing: []string{"x1000c0s9b0n0"},
+			},
+			deviates: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SummarizePowerStatus(statuses, tt.expect, tt.requested)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if got.Deviates() != tt.deviates {
+				t.Errorf("Deviates(): got %v, want %v", got.Deviates(), tt.deviates)
+			}
+		})
+	}
+
+	t.Run("all match", func(t *testing.T) {
+		got := SummarizePowerStatus(statuses[:2], "on", []string{"x1000c0s0b0n0"})
+		if got.Deviates() {
+			t.Errorf("expected no deviation, got %+v", got)
+		}
+	})
+}
+
+func TestNotInPowerState(t *testing.T) {
+	statuses := []PowerStatus{
+		{Xname: "x1000c0s0b0n0", PowerState: "Off"},
+		{Xname: "x1000c0s1b0n0", PowerState: "on"},
+		{Xname: "x1000c0s2b0n0", PowerState: "off", Error: "BMC unreachable"},
+	}
+	requested := []string{"x1000c0s3b0n0", "x1000c0s0b0n0", "x1000c0s1b0n0", "x1000c0s2b0n0", "x1000c0s1b0n0"}
+	got := NotInPowerState(statuses, requested, "off")
+	want := []string{"x1000c0s1b0n0", "x1000c0s2b0n0", "x1000c0s3b0n0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NotInPowerState() = %v, want %v", got, want)
+	}
+	if got := NotInPowerState(statuses, []string{"x1000c0s0b0n0"}, "off"); len(got) != 0 {
+		t.Errorf("NotInPowerState() = %v, want none", got)
+	}
+}
+
+func TestPowerOffOutcomes(t *testing.T) {
+	statuses := []PowerStatus{
+		{Xname: "x1000c0s0b0n0", PowerState: "off"},
+		{Xname: "x1000c0s1b0n0", PowerState: "Off"},
+		{Xname: "x1000c0s2b0n0", PowerState: "on"},
+		{Xname: "x1000c0s3b0n0", PowerState: "", Error: "BMC unreachable"},
+	}
+	requested := []string{"x1000c0s4b0n0", "x1000c0s3b0n0", "x1000c0s2b0n0", "x1000c0s1b0n0", "x1000c0s0b0n0", "x1000c0s0b0n0"}
+	forced := []string{"x1000c0s1b0n0", "x1000c0s2b0n0", "x1000c0s3b0n0", "x1000c0s4b0n0"}
+	got := PowerOffOutcomes(requested, forced, statuses)
+	want := PowerOffReport{
+		Counts: map[string]int{PowerOffGraceful: 1, PowerOffForced: 1, PowerOffFailed: 3},
+		Results: []PowerOffResult{
+			{Xname: "x1000c0s0b0n0", Outcome: PowerOffGraceful, PowerState: "off"},
+			{Xname: "x1000c0s1b0n0", Outcome: PowerOffForced, PowerState: "off"},
+			{Xname: "x1000c0s2b0n0", Outcome: PowerOffFailed, PowerState: "on"},
+			{Xname: "x1000c0s3b0n0", Outcome: PowerOffFailed, PowerState: "undefined", Error: "BMC unreachable"},
+			{Xname: "x1000c0s4b0n0", Outcome: PowerOffFailed, PowerState: "undefined", Error: "power status not reported by PCS"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PowerOffOutcomes() = %+v, want %+v", got, want)
+	}
+	if !got.Failed() {
+		t.Errorf("Failed() = false, want true")
+	}
+	if PowerOffOutcomes([]string{"x1000c0s0b0n0"}, nil, statuses).Failed() {
+		t.Errorf("Failed() = true, want false")
+	}
+}
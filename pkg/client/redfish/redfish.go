@@ -0,0 +1,280 @@
+package redfish
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/OpenCHAMI/ochami/pkg/client"
+)
+
+const (
+	serviceNameRedfish = "Redfish"
+
+	RedfishRelpathRoot     = "/redfish/v1/"
+	RedfishRelpathAccounts = "/redfish/v1/AccountService/Accounts"
+	RedfishRelpathManagers = "/redfish/v1/Managers"
+	RedfishRelpathSystems  = "/redfish/v1/Systems"
+
+	// passwordChars are the characters used by GeneratePassword. Characters
+	// that are easily confused or that need quoting in shells or BMC web
+	// interfaces are left out.
+	passwordChars = "abcdefghijkmnopqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789-_.+"
+)
+
+// RedfishClient is an OchamiClient that is configured to talk to the Redfish
+// service of a BMC.
+type RedfishClient struct {
+	*client.OchamiClient
+}
+
+// collection represents the parts of a Redfish resource collection that are
+// used.
+type collection struct {
+	Members []struct {
+		ODataID string `json:"@odata.id"`
+	} `json:"Members"`
+}
+
+// account represents the parts of a Redfish ManagerAccount that are used.
+type account struct {
+	UserName string `json:"UserName"`
+}
+
+// link represents a reference to another Redfish resource.
+type link struct {
+	ODataID string `json:"@odata.id"`
+}
+
+// EthernetInterface is the MAC address and IPv4 addresses of a Redfish
+// EthernetInterface of a manager (BMC) or system (node).
+type EthernetInterface struct {
+	MACAddress    string   `json:"mac_address" yaml:"mac_address"`
+	IPv4Addresses []string `json:"ipv4_addresses,omitempty" yaml:"ipv4_addresses,omitempty"`
+}
+
+// System is the minimal information about a Redfish ComputerSystem (node)
+// needed to discover it.
+type System struct {
+	ID           string              `json:"id" yaml:"id"`
+	HostName     string              `json:"hostname,omitempty" yaml:"hostname,omitempty"`
+	Manufacturer string              `json:"manufacturer,omitempty" yaml:"manufacturer,omitempty"`
+	Model        string              `json:"model,omitempty" yaml:"model,omitempty"`
+	SerialNumber string              `json:"serial_number,omitempty" yaml:"serial_number,omitempty"`
+	Interfaces   []EthernetInterface `json:"interfaces" yaml:"interfaces"`
+}
+
+// Inventory is the minimal information about a BMC and the systems it manages
+// needed to discover them: the Ethernet interfaces of its first manager and
+// its systems.
+type Inventory struct {
+	ManagerInterfaces []EthernetInterface `json:"manager_interfaces" yaml:"manager_interfaces"`
+	Systems           []System            `json:"systems" yaml:"systems"`
+}
+
+// NewClient takes a baseURI (the root URI of a BMC, e.g. https://172.16.0.101)
+// and returns a pointer to a new RedfishClient. If an error occurred creating
+// the embedded OchamiClient, it is returned. If insecure is true, TLS
+// certificates will not be verified.
+func NewClient(baseURI string, insecure bool) (*RedfishClient, error) {
+	oc, err := client.NewOchamiClient(serviceNameRedfish, baseURI, insecure)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OchamiClient for %s: %w", serviceNameRedfish, err)
+	}
+	rc := &RedfishClient{
+		OchamiClient: oc,
+	}
+
+	return rc, err
+}
+
+// basicAuthHeaders returns HTTPHeaders that authenticate to the BMC as user
+// with password using HTTP basic authentication.
+func basicAuthHeaders(user, password string) (*client.HTTPHeaders, error) {
+	headers := client.NewHTTPHeaders()
+	creds := base64.StdEncoding.EncodeToString([]byte(user + ":" + password))
+	if err := headers.Add("Authorization", "Basic "+creds); err != nil {
+		return nil, err
+	}
+
+	return headers, nil
+}
+
+// SetAccountPassword changes the password of the BMC account named user from
+// currentPassword to newPassword, authenticating as user. The account is found
+// by searching the BMC's AccountService for an account whose UserName is user.
+func (rc *RedfishClient) SetAccountPassword(user, currentPassword, newPassword string) error {
+	headers, err := basicAuthHeaders(user, currentPassword)
+	if err != nil {
+		return fmt.Errorf("SetAccountPassword(): error setting credentials in HTTP headers: %w", err)
+	}
+	henv, err := rc.GetData(RedfishRelpathAccounts, "", headers)
+	if err != nil {
+		return fmt.Errorf("SetAccountPassword(): failed to list BMC accounts: %w", err)
+	}
+	var accounts collection
+	if err := json.Unmarshal(henv.Body, &accounts); err != nil {
+		return fmt.Errorf("SetAccountPassword(): failed to unmarshal BMC accounts: %w", err)
+	}
+	for _, m := range accounts.Members {
+		henv, err := rc.GetData(m.ODataID, "", headers)
+		if err != nil {
+			return fmt.Errorf("SetAccountPassword(): failed to get BMC account %s: %w", m.ODataID, err)
+		}
+		var a account
+		if err := json.Unmarshal(henv.Body, &a); err != nil {
+			return fmt.Errorf("SetAccountPassword(): failed to unmarshal BMC account %s: %w", m.ODataID, err)
+		}
+		if a.UserName != user {
+			continue
+		}
+		body, err := json.Marshal(map[string]string{"Password": newPassword})
+		if err != nil {
+			return fmt.Errorf("SetAccountPassword(): failed to marshal password: %w", err)
+		}
+		if _, err := rc.PatchData(m.ODataID, "", headers, body); err != nil {
+			return fmt.Errorf("SetAccountPassword(): failed to set password of BMC account %s: %w", m.ODataID, err)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("SetAccountPassword(): no BMC account with user name %q", user)
+}
+
+// GeneratePassword returns a random password of length characters suitable for
+// BMC accounts.
+func GeneratePassword(length int) (string, error) {
+	if length <= 0 {
+		return "", fmt.Errorf("password length must be positive")
+	}
+	max := big.NewInt(int64(len(passwordChars)))
+	b := make([]byte, length)
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %w", err)
+		}
+		b[i] = passwordChars[n.Int64()]
+	}
+
+	return string(b), nil
+}
+
+// IsRedfish returns true if the service root of the BMC responds like a
+// Redfish service, which does not require authentication.
+func (rc *RedfishClient) IsRedfish() bool {
+	henv, err := rc.GetData(RedfishRelpathRoot, "", nil)
+	if err != nil {
+		return false
+	}
+	var root struct {
+		RedfishVersion string `json:"RedfishVersion"`
+	}
+
+	return json.Unmarshal(henv.Body, &root) == nil && root.RedfishVersion != ""
+}
+
+// GetInventory returns the Inventory of the BMC, authenticating as user with
+// password.
+func (rc *RedfishClient) GetInventory(user, password string) (Inventory, error) {
+	var inv Inventory
+	headers, err := basicAuthHeaders(user, password)
+	if err != nil {
+		return inv, fmt.Errorf("GetInventory(): error setting credentials in HTTP headers: %w", err)
+	}
+	get := func(path string, v any) error {
+		henv, err := rc.GetData(path, "", headers)
+		if err != nil {
+			return fmt.Errorf("GetInventory(): failed to get %s: %w", path, err)
+		}
+		if err := json.Unmarshal(henv.Body, v); err != nil {
+			return fmt.Errorf("GetInventory(): failed to unmarshal %s: %w", path, err)
+		}
+		return nil
+	}
+	getIfaces := func(l link) ([]EthernetInterface, error) {
+		ifaces := []EthernetInterface{}
+		if l.ODataID == "" {
+			return ifaces, nil
+		}
+		var c collection
+		if err := get(l.ODataID, &c); err != nil {
+			return nil, err
+		}
+		for _, m := range c.Members {
+			var ei struct {
+				MACAddress    string `json:"MACAddress"`
+				IPv4Addresses []struct {
+					Address string `json:"Address"`
+				} `json:"IPv4Addresses"`
+			}
+			if err := get(m.ODataID, &ei); err != nil {
+				return nil, err
+			}
+			if ei.MACAddress == "" {
+				continue
+			}
+			iface := EthernetInterface{MACAddress: strings.ToLower(ei.MACAddress)}
+			for _, a := range ei.IPv4Addresses {
+				if a.Address != "" {
+					iface.IPv4Addresses = append(iface.IPv4Addresses, a.Address)
+				}
+			}
+			ifaces = append(ifaces, iface)
+		}
+		return ifaces, nil
+	}
+
+	// Managers (BMCs); only the first is used
+	var managers collection
+	if err := get(RedfishRelpathManagers, &managers); err != nil {
+		return inv, err
+	}
+	inv.ManagerInterfaces = []EthernetInterface{}
+	if len(managers.Members) > 0 {
+		var mgr struct {
+			EthernetInterfaces link `json:"EthernetInterfaces"`
+		}
+		if err := get(managers.Members[0].ODataID, &mgr); err != nil {
+			return inv, err
+		}
+		if inv.ManagerInterfaces, err = getIfaces(mgr.EthernetInterfaces); err != nil {
+			return inv, err
+		}
+	}
+
+	// Systems (nodes)
+	var systems collection
+	if err := get(RedfishRelpathSystems, &systems); err != nil {
+		return inv, err
+	}
+	inv.Systems = []System{}
+	for _, m := range systems.Members {
+		var rs struct {
+			HostName           string `json:"HostName"`
+			Manufacturer       string `json:"Manufacturer"`
+			Model              string `json:"Model"`
+			SerialNumber       string `json:"SerialNumber"`
+			EthernetInterfaces link   `json:"EthernetInterfaces"`
+		}
+		if err := get(m.ODataID, &rs); err != nil {
+			return inv, err
+		}
+		sys := System{
+			ID:           m.ODataID,
+			HostName:     rs.HostName,
+			Manufacturer: rs.Manufacturer,
+			Model:        rs.Model,
+			SerialNumber: rs.SerialNumber,
+		}
+		if sys.Interfaces, err = getIfaces(rs.EthernetInterfaces); err != nil {
+			return inv, err
+		}
+		inv.Systems = append(inv.Systems, sys)
+	}
+
+	return inv, nil
+}
@@ -0,0 +1,150 @@
+package redfish
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/OpenCHAMI/ochami/pkg/testutil"
+)
+
+func TestRedfishClient_SetAccountPassword(t *testing.T) {
+	fs := testutil.NewFakeServer(t, "")
+	fs.HandleJSON("GET "+RedfishRelpathAccounts, http.StatusOK, map[string]any{
+		"Members": []map[string]string{
+			{"@odata.id": RedfishRelpathAccounts + "/1"},
+			{"@odata.id": RedfishRelpathAccounts + "/2"},
+		},
+	})
+	fs.HandleJSON("GET "+RedfishRelpathAccounts+"/1", http.StatusOK, map[string]string{"UserName": "admin"})
+	fs.HandleJSON("GET "+RedfishRelpathAccounts+"/2", http.StatusOK, map[string]string{"UserName": "root"})
+	fs.HandleJSON("PATCH "+RedfishRelpathAccounts+"/2", http.StatusOK, nil)
+
+	rc, err := NewClient(fs.URL(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rc.SetAccountPassword("root", "old", "new"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	auth := map[string]string{"Authorization": "Basic cm9vdDpvbGQ="} // root:old
+	fs.AssertRequests(t,
+		testutil.RequestMatcher{Method: http.MethodGet, Path: RedfishRelpathAccounts, Header: auth},
+		testutil.RequestMatcher{Method: http.MethodGet, Path: RedfishRelpathAccounts + "/1", Header: auth},
+		testutil.RequestMatcher{Method: http.MethodGet, Path: RedfishRelpathAccounts + "/2", Header: auth},
+		testutil.RequestMatcher{Method: http.MethodPatch, Path: RedfishRelpathAccounts + "/2", Header: auth, JSON: map[string]any{"Password": "new"}},
+	)
+
+	if err := rc.SetAccountPassword("nobody", "old", "new"); err == nil {
+		t.Error("expected error for missing account")
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	p, err := GeneratePassword(24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p) != 24 {
+		t.Errorf("got length %d, want 24", len(p))
+	}
+	for _, c := range p {
+		if !strings.ContainsRune(passwordChars, c) {
+			t.Errorf("unexpected character %q", c)
+		}
+	}
+	if _, err := GeneratePassword(0); err == nil {
+		t.Error("expected error for zero length")
+	}
+}
+
+func TestRedfishClient_IsRedfish(t *testing.T) {
+	fs := testutil.NewFakeServer(t, "")
+	fs.HandleJSON("GET /redfish/v1/{$}", http.StatusOK, map[string]string{"RedfishVersion": "1.6.0"})
+	rc, err := NewClient(fs.URL(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rc.IsRedfish() {
+		t.Error("IsRedfish() = false, want true")
+	}
+
+	other := testutil.NewFakeServer(t, "")
+	other.HandleJSON("GET /redfish/v1/{$}", http.StatusOK, map[string]string{"hello": "world"})
+	if rc, err = NewClient(other.URL(), false); err != nil {
+		t.Fatal(err)
+	}
+	if rc.IsRedfish() {
+		t.Error("IsRedfish() = true for non-Redfish service, want false")
+	}
+}
+
+func TestRedfishClient_GetInventory(t *testing.T) {
+	fs := testutil.NewFakeServer(t, "")
+	members := func(ids ...string) map[string]any {
+		var m []map[string]string
+		for _, id := range ids {
+			m = append(m, map[string]string{"@odata.id": id})
+		}
+		return map[string]any{"Members": m}
+	}
+	fs.HandleJSON("GET "+RedfishRelpathManagers, http.StatusOK, members("/redfish/v1/Managers/BMC"))
+	fs.HandleJSON("GET /redfish/v1/Managers/BMC", http.StatusOK, map[string]any{
+		"EthernetInterfaces": map[string]string{"@odata.id": "/redfish/v1/Managers/BMC/EthernetInterfaces"},
+	})
+	fs.HandleJSON("GET /redfish/v1/Managers/BMC/EthernetInterfaces", http.StatusOK, members("/redfish/v1/Managers/BMC/EthernetInterfaces/1"))
+	fs.HandleJSON("GET /redfish/v1/Managers/BMC/EthernetInterfaces/1", http.StatusOK, map[string]any{
+		"MACAddress":    "DE:CA:FC:0F:FE:E1",
+		"IPv4Addresses": []map[string]string{{"Address": "10.254.0.11"}},
+	})
+	fs.HandleJSON("GET "+RedfishRelpathSystems, http.StatusOK, members("/redfish/v1/Systems/Node0"))
+	fs.HandleJSON("GET /redfish/v1/Systems/Node0", http.StatusOK, map[string]any{
+		"HostName":           "nid001",
+		"Model":              "R272-Z30",
+		"SerialNumber":       "ABC123",
+		"EthernetInterfaces": map[string]string{"@odata.id": "/redfish/v1/Systems/Node0/EthernetInterfaces"},
+	})
+	fs.HandleJSON("GET /redfish/v1/Systems/Node0/EthernetInterfaces", http.StatusOK, members(
+		"/redfish/v1/Systems/Node0/EthernetInterfaces/1",
+		"/redfish/v1/Systems/Node0/EthernetInterfaces/2",
+	))
+	fs.HandleJSON("GET /redfish/v1/Systems/Node0/EthernetInterfaces/1", http.StatusOK, map[string]any{"MACAddress": "de:ad:be:ee:ef:01"})
+	fs.HandleJSON("GET /redfish/v1/Systems/Node0/EthernetInterfaces/2", http.StatusOK, map[string]any{"MACAddress": ""})
+
+	rc, err := NewClient(fs.URL(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := rc.GetInventory("root", "old")
+	if err != nil {
+		t.Fatalf("GetInventory() error = %v", err)
+	}
+	want := Inventory{
+		ManagerInterfaces: []EthernetInterface{{MACAddress: "de:ca:fc:0f:fe:e1", IPv4Addresses: []string{"10.254.0.11"}}},
+		Systems: []System{{
+			ID:           "/redfish/v1/Systems/Node0",
+			HostName:     "nid001",
+			Model:        "R272-Z30",
+			SerialNumber: "ABC123",
+			Interfaces:   []EthernetInterface{{MACAddress: "de:ad:be:ee:ef:01"}},
+		}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetInventory() = %+v, want %+v", got, want)
+	}
+	for _, r := range fs.Requests() {
+		if r.Header.Get("Authorization") != "Basic cm9vdDpvbGQ=" {
+			t.Errorf("request %s %s not authenticated", r.Method, r.Path)
+		}
+	}
+
+	unauth := testutil.NewFakeServer(t, "")
+	unauth.HandleJSON("GET "+RedfishRelpathManagers, http.StatusUnauthorized, nil)
+	if rc, err = NewClient(unauth.URL(), false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rc.GetInventory("root", "wrong"); err == nil {
+		t.Error("GetInventory() with wrong credentials succeeded, want error")
+	}
+}
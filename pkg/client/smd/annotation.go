@@ -0,0 +1,73 @@
+package smd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Annotation records who created or last changed a record, when, and why. Since
+// SMD groups and redfish endpoints (and cloud-init groups) have no fields for
+// this, it is stored at the end of their free-form description or name, which
+// ParseAnnotation reads it back from.
+type Annotation struct {
+	By      string    `json:"by" yaml:"by"`
+	At      time.Time `json:"at" yaml:"at"`
+	Comment string    `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+// annotationRegex matches an annotation as formatted by Annotation.String at the
+// end of a string, along with the spaces before it.
+var annotationRegex = regexp.MustCompile(`\s*\[ochami: by=(\S+) at=(\S+)(?: comment=("(?:[^"\\]|\\.)*"))?\]$`)
+
+// String returns a formatted as it is stored, e.g.
+//
+//	[ochami: by=alice at=2026-01-02T15:04:05Z comment="rack 3 replacement"]
+//
+// Whitespace in By is replaced with underscores and At is formatted in UTC.
+func (a Annotation) String() string {
+	by := strings.Join(strings.Fields(a.By), "_")
+	if by == "" {
+		by = "unknown"
+	}
+	s := fmt.Sprintf("[ochami: by=%s at=%s", by, a.At.UTC().Format(time.RFC3339))
+	if a.Comment != "" {
+		s += " comment=" + strconv.Quote(a.Comment)
+	}
+
+	return s + "]"
+}
+
+// Annotate returns s with a at its end, replacing any annotation it already
+// has.
+func Annotate(s string, a Annotation) string {
+	s, _ = ParseAnnotation(s)
+	if s == "" {
+		return a.String()
+	}
+
+	return s + " " + a.String()
+}
+
+// ParseAnnotation returns s without the annotation at its end and the
+// annotation, or s and nil if it has none.
+func ParseAnnotation(s string) (string, *Annotation) {
+	m := annotationRegex.FindStringSubmatchIndex(s)
+	if m == nil {
+		return s, nil
+	}
+	at, err := time.Parse(time.RFC3339, s[m[4]:m[5]])
+	if err != nil {
+		return s, nil
+	}
+	a := &Annotation{By: s[m[2]:m[3]], At: at}
+	if m[6] >= 0 {
+		if a.Comment, err = strconv.Unquote(s[m[6]:m[7]]); err != nil {
+			return s, nil
+		}
+	}
+
+	return s[:m[0]], a
+}
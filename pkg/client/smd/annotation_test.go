@@ -0,0 +1,69 @@
+package smd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAnnotation(t *testing.T) {
+	at := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name   string
+		s      string
+		a      Annotation
+		want   string
+		wantBy string
+	}{
+		{
+			name:   "empty",
+			a:      Annotation{By: "alice", At: at},
+			want:   `[ochami: by=alice at=2026-01-02T15:04:05Z]`,
+			wantBy: "alice",
+		},
+		{
+			name:   "comment",
+			s:      "Compute nodes",
+			a:      Annotation{By: "alice", At: at, Comment: `rack 3 "new"`},
+			want:   `Compute nodes [ochami: by=alice at=2026-01-02T15:04:05Z comment="rack 3 \"new\""]`,
+			wantBy: "alice",
+		},
+		{
+			name:   "replaces existing",
+			s:      `Compute nodes [ochami: by=bob at=2025-01-01T00:00:00Z comment="old"]`,
+			a:      Annotation{By: "Alice Smith", At: at.In(time.FixedZone("X", 3600))},
+			want:   `Compute nodes [ochami: by=Alice_Smith at=2026-01-02T15:04:05Z]`,
+			wantBy: "Alice_Smith",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Annotate(tt.s, tt.a)
+			if got != tt.want {
+				t.Fatalf("Annotate() = %q, want %q", got, tt.want)
+			}
+			rest, a := ParseAnnotation(got)
+			if a == nil || a.By != tt.wantBy || !a.At.Equal(tt.a.At) || a.Comment != tt.a.Comment {
+				t.Errorf("ParseAnnotation() annotation = %+v, want by %s at %s comment %q", a, tt.wantBy, tt.a.At, tt.a.Comment)
+			}
+			if wantRest, _ := ParseAnnotation(tt.s); rest != wantRest {
+				t.Errorf("ParseAnnotation() rest = %q, want %q", rest, wantRest)
+			}
+		})
+	}
+
+	for _, s := range []string{"", "Compute nodes", "[ochami: by=alice at=yesterday]", "[ochami: by=alice at=2026-01-02T15:04:05Z] trailing"} {
+		if rest, a := ParseAnnotation(s); rest != s || a != nil {
+			t.Errorf("ParseAnnotation(%q) = %q, %+v, want unchanged and nil", s, rest, a)
+		}
+	}
+}
+
+func TestMetaFromGroupAnnotated(t *testing.T) {
+	g := NewMetaGroup("rack", "A12")
+	g.Description = Annotate(g.Description, Annotation{By: "alice", At: time.Now()})
+	key, value, ok := MetaFromGroup(g)
+	if got, want := []any{key, value, ok}, []any{"rack", "A12", true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MetaFromGroup() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,68 @@
+package smd
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// MemberRemap rewrites the member IDs that start with From to start with To
+// instead, e.g. x1000 to x1001 to clone a group of the nodes of one cabinet to
+// one for another.
+type MemberRemap struct {
+	From string
+	To   string
+}
+
+// ParseMemberRemap parses the remap s of the form "<from>=<to>".
+func ParseMemberRemap(s string) (MemberRemap, error) {
+	from, to, ok := strings.Cut(s, "=")
+	if !ok || from == "" || to == "" {
+		return MemberRemap{}, fmt.Errorf("invalid remap %q: expected <from>=<to>", s)
+	}
+
+	return MemberRemap{From: from, To: to}, nil
+}
+
+// Apply returns id rewritten by mr and whether it was. Only whole elements of
+// an xname are matched (regardless of case), so x1000c0 matches x1000c0s0b0n0
+// but not x1000c01s0b0n0.
+func (mr MemberRemap) Apply(id string) (string, bool) {
+	if len(id) < len(mr.From) || !strings.EqualFold(id[:len(mr.From)], mr.From) {
+		return id, false
+	}
+	rest := id[len(mr.From):]
+	if rest != "" && unicode.IsDigit(rune(rest[0])) {
+		return id, false
+	}
+
+	return mr.To + rest, true
+}
+
+// CloneGroup returns a copy of g labeled label. Its members are those of g for
+// which keep returns true (all of them if keep is nil), rewritten by the first
+// of remaps that applies to each, without duplicates. The clone has no
+// exclusive group, since it would share members with g.
+func CloneGroup(g Group, label string, keep func(id string) bool, remaps []MemberRemap) Group {
+	clone := Group{
+		Label:       label,
+		Description: g.Description,
+		Tags:        append([]string(nil), g.Tags...),
+	}
+	for _, id := range g.Members.IDs {
+		if keep != nil && !keep(id) {
+			continue
+		}
+		for _, mr := range remaps {
+			if to, ok := mr.Apply(id); ok {
+				id = to
+				break
+			}
+		}
+		if !HasMember(clone.Members.IDs, id) {
+			clone.Members.IDs = append(clone.Members.IDs, id)
+		}
+	}
+
+	return clone
+}
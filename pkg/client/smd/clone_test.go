@@ -0,0 +1,57 @@
+package smd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMemberRemap(t *testing.T) {
+	if mr, err := ParseMemberRemap("x1000=x1001"); err != nil || mr != (MemberRemap{From: "x1000", To: "x1001"}) {
+		t.Errorf("ParseMemberRemap() = %+v, %v", mr, err)
+	}
+	for _, s := range []string{"x1000", "=x1001", "x1000="} {
+		if _, err := ParseMemberRemap(s); err == nil {
+			t.Errorf("ParseMemberRemap(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestMemberRemap_Apply(t *testing.T) {
+	mr := MemberRemap{From: "x1000c0", To: "x1001c0"}
+	tests := []struct {
+		id   string
+		want string
+		ok   bool
+	}{
+		{"x1000c0s0b0n0", "x1001c0s0b0n0", true},
+		{"X1000C0s1b0n0", "x1001c0s1b0n0", true},
+		{"x1000c0", "x1001c0", true},
+		{"x1000c01s0b0n0", "x1000c01s0b0n0", false},
+		{"x1000c1s0b0n0", "x1000c1s0b0n0", false},
+		{"x10", "x10", false},
+	}
+	for _, tt := range tests {
+		if got, ok := mr.Apply(tt.id); got != tt.want || ok != tt.ok {
+			t.Errorf("Apply(%q) = %q, %t, want %q, %t", tt.id, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCloneGroup(t *testing.T) {
+	g := Group{Label: "compute", Description: "Compute nodes", Tags: []string{"hpc"}, ExclusiveGroup: "role"}
+	g.Members.IDs = []string{"x1000c0s0b0n0", "x1000c0s1b0n0", "x1000c1s0b0n0"}
+
+	keep := func(id string) bool { return id != "x1000c0s1b0n0" }
+	remaps := []MemberRemap{{From: "x1000c1", To: "x1000c0"}}
+	got := CloneGroup(g, "compute-a100", keep, remaps)
+
+	want := Group{Label: "compute-a100", Description: "Compute nodes", Tags: []string{"hpc"}}
+	want.Members.IDs = []string{"x1000c0s0b0n0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("CloneGroup() = %+v, want %+v", got, want)
+	}
+
+	if got := CloneGroup(g, "copy", nil, nil); !reflect.DeepEqual(got.Members.IDs, g.Members.IDs) {
+		t.Errorf("CloneGroup() members = %v, want %v", got.Members.IDs, g.Members.IDs)
+	}
+}
@@ -0,0 +1,171 @@
+package smd
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/xname"
+)
+
+// Sources of the records matched by FindInterface.
+const (
+	FindSourceEthernetInterface = "smd-ethernet-interface"
+	FindSourceRedfishEndpoint   = "smd-redfish-endpoint"
+	FindSourceBSSHost           = "bss-host"
+)
+
+// FindData is the data FindInterface searches. Any of it may be empty, e.g.
+// if BSS could not be reached.
+type FindData struct {
+	EthernetInterfaces []EthernetInterface
+	RedfishEndpoints   []csm.RedfishEndpoint
+	BSSHosts           []bss.Host
+	Groups             []Group
+}
+
+// FindMatch is a record that has the MAC or IP address being searched for.
+// Source is one of the FindSource constants and ID is the ID of the record
+// (e.g. the ethernet interface ID).
+type FindMatch struct {
+	Source  string `json:"source" yaml:"source"`
+	ID      string `json:"id" yaml:"id"`
+	MAC     string `json:"mac,omitempty" yaml:"mac,omitempty"`
+	IP      string `json:"ip,omitempty" yaml:"ip,omitempty"`
+	Network string `json:"network,omitempty" yaml:"network,omitempty"`
+}
+
+// FindOwner is a component that owns a MAC or IP address, along with the
+// groups it is a member of and its BMC (if it is not a BMC itself).
+// BMCAddress is the FQDN or IP address of the BMC's Redfish endpoint, if SMD
+// has one. Component is empty for matches that no component owns, such as
+// ethernet interfaces discovered by DHCP and not yet associated with one.
+// Location, if set, is the physical location of the component.
+type FindOwner struct {
+	Component  string      `json:"component" yaml:"component"`
+	Groups     []string    `json:"groups" yaml:"groups"`
+	BMC        string      `json:"bmc,omitempty" yaml:"bmc,omitempty"`
+	BMCAddress string      `json:"bmc_address,omitempty" yaml:"bmc_address,omitempty"`
+	Location   string      `json:"location,omitempty" yaml:"location,omitempty"`
+	Matches    []FindMatch `json:"matches" yaml:"matches"`
+}
+
+// FindInterface returns the owners of the MAC address mac or the IP address ip
+// (exactly one of which must be set) found in the ethernet interfaces, Redfish
+// endpoints, and BSS hosts of data, sorted by component. MAC addresses are
+// compared regardless of case and separators. BSS hosts only have MAC
+// addresses, so they are not searched for IP addresses.
+func FindInterface(data FindData, mac, ip string) ([]FindOwner, error) {
+	var (
+		wantMAC net.HardwareAddr
+		wantIP  net.IP
+		err     error
+	)
+	switch {
+	case mac != "" && ip != "":
+		return nil, fmt.Errorf("only one of a MAC address or IP address can be searched for")
+	case mac != "":
+		if wantMAC, err = net.ParseMAC(mac); err != nil {
+			return nil, fmt.Errorf("invalid MAC address %q: %w", mac, err)
+		}
+	case ip != "":
+		if wantIP = net.ParseIP(ip); wantIP == nil {
+			return nil, fmt.Errorf("invalid IP address %q", ip)
+		}
+	default:
+		return nil, fmt.Errorf("a MAC address or IP address to search for is required")
+	}
+	macMatches := func(s string) bool {
+		m, err := net.ParseMAC(s)
+		return wantMAC != nil && err == nil && m.String() == wantMAC.String()
+	}
+	ipMatches := func(s string) bool {
+		addr := net.ParseIP(s)
+		return wantIP != nil && addr != nil && addr.Equal(wantIP)
+	}
+
+	var (
+		owners []*FindOwner
+		byComp = make(map[string]*FindOwner)
+	)
+	add := func(comp string, m FindMatch) {
+		key := strings.ToLower(comp)
+		o, ok := byComp[key]
+		if !ok {
+			o = &FindOwner{Component: comp, Groups: []string{}}
+			byComp[key] = o
+			owners = append(owners, o)
+		}
+		o.Matches = append(o.Matches, m)
+	}
+
+	for _, ei := range data.EthernetInterfaces {
+		if macMatches(ei.MACAddress) {
+			add(ei.ComponentID, FindMatch{Source: FindSourceEthernetInterface, ID: ei.ID, MAC: ei.MACAddress})
+			continue
+		}
+		for _, eip := range ei.IPAddresses {
+			if ipMatches(eip.IPAddress) {
+				add(ei.ComponentID, FindMatch{
+					Source:  FindSourceEthernetInterface,
+					ID:      ei.ID,
+					MAC:     ei.MACAddress,
+					IP:      eip.IPAddress,
+					Network: eip.Network,
+				})
+				break
+			}
+		}
+	}
+	for _, rfe := range data.RedfishEndpoints {
+		if macMatches(rfe.MACAddr) || ipMatches(rfe.IPAddress) {
+			add(rfe.ID, FindMatch{Source: FindSourceRedfishEndpoint, ID: rfe.ID, MAC: rfe.MACAddr, IP: rfe.IPAddress})
+		}
+	}
+	for _, h := range data.BSSHosts {
+		for _, m := range h.MAC {
+			if macMatches(m) {
+				add(h.ID, FindMatch{Source: FindSourceBSSHost, ID: h.ID, MAC: m})
+				break
+			}
+		}
+	}
+
+	rfes := make(map[string]csm.RedfishEndpoint)
+	for _, rfe := range data.RedfishEndpoints {
+		rfes[strings.ToLower(rfe.ID)] = rfe
+	}
+	result := make([]FindOwner, 0, len(owners))
+	for _, o := range owners {
+		if o.Component != "" {
+			for _, g := range data.Groups {
+				for _, id := range g.Members.IDs {
+					if strings.EqualFold(id, o.Component) {
+						o.Groups = append(o.Groups, g.Label)
+						break
+					}
+				}
+			}
+			sort.Strings(o.Groups)
+			if !csm.IsValidBMCXName(o.Component) {
+				if bmc, err := xname.NodeXnameToBMCXname(o.Component); err == nil {
+					o.BMC = bmc
+					if rfe, ok := rfes[strings.ToLower(bmc)]; ok {
+						o.BMCAddress = rfe.FQDN
+						if o.BMCAddress == "" {
+							o.BMCAddress = rfe.IPAddress
+						}
+					}
+				}
+			}
+		}
+		result = append(result, *o)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Component < result[j].Component })
+
+	return result, nil
+}
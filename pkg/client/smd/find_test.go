@@ -0,0 +1,127 @@
+package smd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+)
+
+func TestFindInterface(t *testing.T) {
+	data := FindData{
+		EthernetInterfaces: []EthernetInterface{
+			{
+				ID:          "decafc0ffee0",
+				ComponentID: "x3000c0s0b0n0",
+				MACAddress:  "de:ca:fc:0f:fe:e0",
+				IPAddresses: []EthernetIP{{IPAddress: "172.16.0.1", Network: "mgmt"}},
+			},
+			{
+				ID:          "decafc0ffee1",
+				MACAddress:  "de:ca:fc:0f:fe:e1",
+				IPAddresses: []EthernetIP{{IPAddress: "172.16.0.99"}},
+			},
+		},
+		RedfishEndpoints: []csm.RedfishEndpoint{
+			{ID: "x3000c0s0b0", FQDN: "x3000c0s0b0.example.com", MACAddr: "de:ca:fc:0f:fe:b0", IPAddress: "172.16.100.1"},
+		},
+		BSSHosts: []bss.Host{
+			{ID: "x3000c0s0b0n0", MAC: []string{"DE:CA:FC:0F:FE:E0"}},
+		},
+		Groups: []Group{
+			{Label: "compute", Members: struct {
+				IDs []string `json:"ids,omitempty" yaml:"ids,omitempty"`
+			}{IDs: []string{"x3000c0s0b0n0"}}},
+			{Label: "all", Members: struct {
+				IDs []string `json:"ids,omitempty" yaml:"ids,omitempty"`
+			}{IDs: []string{"x3000c0s0b0n0", "x3000c0s0b0"}}},
+		},
+	}
+
+	tests := []struct {
+		name string
+		mac  string
+		ip   string
+		want []FindOwner
+	}{
+		{
+			name: "node MAC",
+			mac:  "decafc0ffee0",
+			want: []FindOwner{{
+				Component:  "x3000c0s0b0n0",
+				Groups:     []string{"all", "compute"},
+				BMC:        "x3000c0s0b0",
+				BMCAddress: "x3000c0s0b0.example.com",
+				Matches: []FindMatch{
+					{Source: FindSourceEthernetInterface, ID: "decafc0ffee0", MAC: "de:ca:fc:0f:fe:e0"},
+					{Source: FindSourceBSSHost, ID: "x3000c0s0b0n0", MAC: "DE:CA:FC:0F:FE:E0"},
+				},
+			}},
+		},
+		{
+			name: "node IP",
+			ip:   "172.16.0.1",
+			want: []FindOwner{{
+				Component:  "x3000c0s0b0n0",
+				Groups:     []string{"all", "compute"},
+				BMC:        "x3000c0s0b0",
+				BMCAddress: "x3000c0s0b0.example.com",
+				Matches: []FindMatch{
+					{Source: FindSourceEthernetInterface, ID: "decafc0ffee0", MAC: "de:ca:fc:0f:fe:e0", IP: "172.16.0.1", Network: "mgmt"},
+				},
+			}},
+		},
+		{
+			name: "BMC IP",
+			ip:   "172.16.100.1",
+			want: []FindOwner{{
+				Component: "x3000c0s0b0",
+				Groups:    []string{"all"},
+				Matches: []FindMatch{
+					{Source: FindSourceRedfishEndpoint, ID: "x3000c0s0b0", MAC: "de:ca:fc:0f:fe:b0", IP: "172.16.100.1"},
+				},
+			}},
+		},
+		{
+			name: "unowned interface",
+			mac:  "de-ca-fc-0f-fe-e1",
+			want: []FindOwner{{
+				Groups: []string{},
+				Matches: []FindMatch{
+					{Source: FindSourceEthernetInterface, ID: "decafc0ffee1", MAC: "de:ca:fc:0f:fe:e1"},
+				},
+			}},
+		},
+		{
+			name: "not found",
+			ip:   "10.0.0.1",
+			want: []FindOwner{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindInterface(data, tt.mac, tt.ip)
+			if err != nil {
+				t.Fatalf("FindInterface() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindInterface() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindInterface_Errors(t *testing.T) {
+	for _, tt := range []struct{ mac, ip string }{
+		{},
+		{mac: "de:ca:fc:0f:fe:e0", ip: "172.16.0.1"},
+		{mac: "not-a-mac"},
+		{ip: "172.16.0"},
+	} {
+		if _, err := FindInterface(FindData{}, tt.mac, tt.ip); err == nil {
+			t.Errorf("FindInterface(%q, %q) succeeded, want error", tt.mac, tt.ip)
+		}
+	}
+}
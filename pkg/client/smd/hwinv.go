@@ -0,0 +1,204 @@
+package smd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+)
+
+// HWInv is a minimal subset of an SMD hardware inventory entry (HWInvByLoc):
+// the location ID of a piece of hardware and the FRU populating it, if any.
+type HWInv struct {
+	ID           string    `json:"ID" yaml:"ID"`
+	Type         string    `json:"Type" yaml:"Type"`
+	Ordinal      int       `json:"Ordinal" yaml:"Ordinal"`
+	Status       string    `json:"Status" yaml:"Status"`
+	PopulatedFRU *HWInvFRU `json:"PopulatedFRU,omitempty" yaml:"PopulatedFRU,omitempty"`
+}
+
+// HWInvFRU is a field-replaceable unit in the SMD hardware inventory. SMD keeps
+// its FRU info under a key named after its type (e.g. "NodeFRUInfo"), which
+// is read into Info.
+type HWInvFRU struct {
+	FRUID   string       `json:"FRUID" yaml:"FRUID"`
+	Type    string       `json:"Type" yaml:"Type"`
+	Subtype string       `json:"Subtype,omitempty" yaml:"Subtype,omitempty"`
+	Info    HWInvFRUInfo `json:"FRUInfo" yaml:"FRUInfo"`
+}
+
+// HWInvFRUInfo is the part of the FRU info of a FRU common to all types.
+type HWInvFRUInfo struct {
+	Manufacturer string `json:"Manufacturer,omitempty" yaml:"Manufacturer,omitempty"`
+	Model        string `json:"Model,omitempty" yaml:"Model,omitempty"`
+	PartNumber   string `json:"PartNumber,omitempty" yaml:"PartNumber,omitempty"`
+	SerialNumber string `json:"SerialNumber,omitempty" yaml:"SerialNumber,omitempty"`
+	SKU          string `json:"SKU,omitempty" yaml:"SKU,omitempty"`
+}
+
+// UnmarshalJSON reads the FRU info of f from whichever key ending in "FRUInfo"
+// SMD put it under.
+func (f *HWInvFRU) UnmarshalJSON(b []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	type fru HWInvFRU
+	var v fru
+	for k, msg := range raw {
+		var err error
+		switch {
+		case k == "FRUID":
+			err = json.Unmarshal(msg, &v.FRUID)
+		case k == "Type":
+			err = json.Unmarshal(msg, &v.Type)
+		case k == "Subtype":
+			err = json.Unmarshal(msg, &v.Subtype)
+		case strings.HasSuffix(k, "FRUInfo"):
+			err = json.Unmarshal(msg, &v.Info)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	*f = HWInvFRU(v)
+
+	return nil
+}
+
+// HWInvByLoc is a hardware inventory entry to add to SMD (an HWInvByLoc in
+// SMD): a location of type Type (e.g. "Node" or "Processor") and the FRU
+// populating it, if any. It is marshalled with the location and FRU info under
+// the keys named after their type (e.g. "NodeLocationInfo") that SMD expects.
+type HWInvByLoc struct {
+	ID           string            `yaml:"ID"`
+	Type         string            `yaml:"Type"`
+	Ordinal      int               `yaml:"Ordinal"`
+	Status       string            `yaml:"Status"`
+	LocationInfo HWInvLocationInfo `yaml:"LocationInfo"`
+	PopulatedFRU *HWInvByFRU       `yaml:"PopulatedFRU,omitempty"`
+}
+
+// HWInvLocationInfo is the part of the location info of a location common to
+// all types.
+type HWInvLocationInfo struct {
+	ID          string `json:"Id,omitempty" yaml:"Id,omitempty"`
+	Name        string `json:"Name,omitempty" yaml:"Name,omitempty"`
+	Description string `json:"Description,omitempty" yaml:"Description,omitempty"`
+}
+
+// HWInvByFRU is a FRU of an HWInvByLoc.
+type HWInvByFRU struct {
+	FRUID   string         `yaml:"FRUID"`
+	Type    string         `yaml:"Type"`
+	Subtype string         `yaml:"Subtype,omitempty"`
+	Info    HWInvByFRUInfo `yaml:"FRUInfo"`
+}
+
+// HWInvByFRUInfo is the FRU info of an HWInvByFRU. CapacityMiB only applies to
+// Memory and TotalCores to Processors.
+type HWInvByFRUInfo struct {
+	HWInvFRUInfo `yaml:",inline"`
+	CapacityMiB  int `json:"CapacityMiB,omitempty" yaml:"CapacityMiB,omitempty"`
+	TotalCores   int `json:"TotalCores,omitempty" yaml:"TotalCores,omitempty"`
+}
+
+// MarshalJSON marshals h as SMD expects, with its location info under
+// "<Type>LocationInfo".
+func (h HWInvByLoc) MarshalJSON() ([]byte, error) {
+	m := map[string]any{
+		"ID":                        h.ID,
+		"Type":                      h.Type,
+		"Ordinal":                   h.Ordinal,
+		"Status":                    h.Status,
+		"HWInventoryByLocationType": "HWInvByLoc" + h.Type,
+		h.Type + "LocationInfo":     h.LocationInfo,
+	}
+	if h.PopulatedFRU != nil {
+		m["PopulatedFRU"] = h.PopulatedFRU
+	}
+
+	return json.Marshal(m)
+}
+
+// MarshalJSON marshals f as SMD expects, with its FRU info under
+// "<Type>FRUInfo".
+func (f HWInvByFRU) MarshalJSON() ([]byte, error) {
+	m := map[string]any{
+		"FRUID":                f.FRUID,
+		"Type":                 f.Type,
+		"HWInventoryByFRUType": "HWInvByFRU" + f.Type,
+		f.Type + "FRUInfo":     f.Info,
+	}
+	if f.Subtype != "" {
+		m["Subtype"] = f.Subtype
+	}
+
+	return json.Marshal(m)
+}
+
+// HWInvRecord is a flattened hardware inventory entry, convenient for asset
+// management.
+type HWInvRecord struct {
+	XName        string `json:"xname" yaml:"xname"`
+	Type         string `json:"type" yaml:"type"`
+	Status       string `json:"status" yaml:"status"`
+	FRUID        string `json:"fru_id,omitempty" yaml:"fru_id,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty" yaml:"manufacturer,omitempty"`
+	Model        string `json:"model,omitempty" yaml:"model,omitempty"`
+	PartNumber   string `json:"part_number,omitempty" yaml:"part_number,omitempty"`
+	SerialNumber string `json:"serial_number,omitempty" yaml:"serial_number,omitempty"`
+}
+
+// hwinvCSVHeader is the header row written by WriteHWInvCSV.
+var hwinvCSVHeader = []string{"xname", "type", "status", "fru_id", "manufacturer", "model", "part_number", "serial_number"}
+
+// HWInvRecords flattens the hardware inventory entries inv into records, sorted
+// by xname. If serials is not empty, only the entries whose serial number is
+// one of serials are returned. Serial numbers are compared regardless of case
+// and surrounding white space, as they are often typed in from a label.
+func HWInvRecords(inv []HWInv, serials ...string) []HWInvRecord {
+	want := make(map[string]bool, len(serials))
+	for _, s := range serials {
+		want[normalizeSerial(s)] = true
+	}
+	recs := []HWInvRecord{}
+	for _, h := range inv {
+		rec := HWInvRecord{XName: h.ID, Type: h.Type, Status: h.Status}
+		if f := h.PopulatedFRU; f != nil {
+			rec.FRUID = f.FRUID
+			rec.Manufacturer = f.Info.Manufacturer
+			rec.Model = f.Info.Model
+			rec.PartNumber = f.Info.PartNumber
+			rec.SerialNumber = f.Info.SerialNumber
+		}
+		if len(want) > 0 && (rec.SerialNumber == "" || !want[normalizeSerial(rec.SerialNumber)]) {
+			continue
+		}
+		recs = append(recs, rec)
+	}
+	sort.SliceStable(recs, func(i, j int) bool { return recs[i].XName < recs[j].XName })
+
+	return recs
+}
+
+func normalizeSerial(s string) string {
+	return strings.ToUpper(strings.TrimSpace(s))
+}
+
+// WriteHWInvCSV writes recs to w as CSV with a header row.
+func WriteHWInvCSV(w io.Writer, recs []HWInvRecord) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(hwinvCSVHeader); err != nil {
+		return err
+	}
+	for _, r := range recs {
+		if err := cw.Write([]string{r.XName, r.Type, r.Status, r.FRUID, r.Manufacturer, r.Model, r.PartNumber, r.SerialNumber}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
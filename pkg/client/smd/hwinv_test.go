@@ -0,0 +1,99 @@
+package smd
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testHWInv = `[
+  {
+    "ID": "x1000c1s7b0n0",
+    "Type": "Node",
+    "Ordinal": 0,
+    "Status": "Populated",
+    "HWInventoryByLocationType": "HWInvByLocNode",
+    "PopulatedFRU": {
+      "FRUID": "Node.Acme.SN123",
+      "Type": "Node",
+      "HWInventoryByFRUType": "HWInvByFRUNode",
+      "NodeFRUInfo": {"Manufacturer": "Acme", "Model": "R1", "PartNumber": "PN-1", "SerialNumber": "SN123"}
+    }
+  },
+  {
+    "ID": "x1000c1s7b0n0p0",
+    "Type": "Processor",
+    "Ordinal": 0,
+    "Status": "Populated",
+    "PopulatedFRU": {
+      "FRUID": "Processor.Acme.sn456",
+      "Type": "Processor",
+      "ProcessorFRUInfo": {"Manufacturer": "Acme", "SerialNumber": "sn456"}
+    }
+  },
+  {"ID": "x1000c1s7b0n0d1", "Type": "Memory", "Ordinal": 1, "Status": "Empty"}
+]`
+
+func TestHWInvRecords(t *testing.T) {
+	var inv []HWInv
+	if err := json.Unmarshal([]byte(testHWInv), &inv); err != nil {
+		t.Fatalf("failed to unmarshal hardware inventory: %v", err)
+	}
+	node := HWInvRecord{XName: "x1000c1s7b0n0", Type: "Node", Status: "Populated", FRUID: "Node.Acme.SN123", Manufacturer: "Acme", Model: "R1", PartNumber: "PN-1", SerialNumber: "SN123"}
+	mem := HWInvRecord{XName: "x1000c1s7b0n0d1", Type: "Memory", Status: "Empty"}
+	proc := HWInvRecord{XName: "x1000c1s7b0n0p0", Type: "Processor", Status: "Populated", FRUID: "Processor.Acme.sn456", Manufacturer: "Acme", SerialNumber: "sn456"}
+
+	if got, want := HWInvRecords(inv), []HWInvRecord{node, mem, proc}; !reflect.DeepEqual(got, want) {
+		t.Errorf("HWInvRecords() = %+v, want %+v", got, want)
+	}
+	if got, want := HWInvRecords(inv, " SN456 "), []HWInvRecord{proc}; !reflect.DeepEqual(got, want) {
+		t.Errorf("HWInvRecords(serial) = %+v, want %+v", got, want)
+	}
+	if got := HWInvRecords(inv, "nope"); len(got) != 0 {
+		t.Errorf("HWInvRecords(unknown serial) = %+v, want none", got)
+	}
+}
+
+func TestWriteHWInvCSV(t *testing.T) {
+	var b strings.Builder
+	recs := []HWInvRecord{{XName: "x1000c1s7b0n0", Type: "Node", Status: "Populated", Model: "R1, rev 2", SerialNumber: "SN123"}}
+	if err := WriteHWInvCSV(&b, recs); err != nil {
+		t.Fatalf("WriteHWInvCSV() error = %v", err)
+	}
+	want := "xname,type,status,fru_id,manufacturer,model,part_number,serial_number\n" +
+		"x1000c1s7b0n0,Node,Populated,,,\"R1, rev 2\",,SN123\n"
+	if b.String() != want {
+		t.Errorf("WriteHWInvCSV() wrote %q, want %q", b.String(), want)
+	}
+}
+
+func TestHWInvByLoc_MarshalJSON(t *testing.T) {
+	in := HWInvByLoc{
+		ID:      "x1000c1s7b0n0p0",
+		Type:    "Processor",
+		Ordinal: 0,
+		Status:  "Populated",
+		PopulatedFRU: &HWInvByFRU{
+			FRUID: "Processor.Acme.sn456",
+			Type:  "Processor",
+			Info:  HWInvByFRUInfo{HWInvFRUInfo: HWInvFRUInfo{Manufacturer: "Acme", SerialNumber: "sn456"}, TotalCores: 8},
+		},
+	}
+	b, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"ProcessorFRUInfo":{"Manufacturer":"Acme","SerialNumber":"sn456","TotalCores":8}`) {
+		t.Errorf("FRU info not under ProcessorFRUInfo: %s", b)
+	}
+
+	// What is written must read back as SMD would return it
+	var out HWInv
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.ID != in.ID || out.PopulatedFRU == nil || out.PopulatedFRU.Info.SerialNumber != "sn456" {
+		t.Errorf("got %+v", out)
+	}
+}
@@ -0,0 +1,117 @@
+package smd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strings"
+)
+
+// MembersVersion returns the version of the member list ids of a group: a short
+// hash of its sorted, lower case IDs, so the version changes whenever the
+// membership does. SMD does not version groups itself, nor does it support
+// ETags, so this is what the version passed to --expected-version is compared
+// against.
+func MembersVersion(ids []string) string {
+	norm := make([]string, len(ids))
+	for i, id := range ids {
+		norm[i] = strings.ToLower(id)
+	}
+	sort.Strings(norm)
+	sum := sha256.Sum256([]byte(strings.Join(norm, "\n")))
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// HasMember returns whether id is in the member list ids, regardless of case.
+func HasMember(ids []string, id string) bool {
+	for _, i := range ids {
+		if strings.EqualFold(i, id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// DiffMembers returns the members to add to, and remove from, the member list
+// from so that it becomes to, each in the order they appear in to and from,
+// respectively.
+func DiffMembers(from, to []string) (add, remove []string) {
+	for _, id := range to {
+		if !HasMember(from, id) && !HasMember(add, id) {
+			add = append(add, id)
+		}
+	}
+	for _, id := range from {
+		if !HasMember(to, id) && !HasMember(remove, id) {
+			remove = append(remove, id)
+		}
+	}
+
+	return add, remove
+}
+
+// MemberChanges is how the member list of a group after an edit differs from
+// the one the edit was expected to produce.
+type MemberChanges struct {
+	// Lost are the members the edit added or removed that are not added or
+	// removed, i.e. the part of the edit that did not take effect or was
+	// undone by another client.
+	Lost []string
+
+	// Added and Removed are the other members that were added or removed
+	// concurrently by another client.
+	Added   []string
+	Removed []string
+}
+
+// Conflict returns whether part of the edit was lost.
+func (mc MemberChanges) Conflict() bool {
+	return len(mc.Lost) > 0
+}
+
+// Concurrent returns whether other members changed concurrently.
+func (mc MemberChanges) Concurrent() bool {
+	return len(mc.Added) > 0 || len(mc.Removed) > 0
+}
+
+// CompareMembers compares after, the member list of a group after an edit of
+// before that added add and removed remove, with the member list the edit was
+// expected to produce.
+func CompareMembers(before, after, add, remove []string) MemberChanges {
+	var (
+		mc   MemberChanges
+		want []string
+	)
+	for _, id := range before {
+		if !HasMember(remove, id) {
+			want = append(want, id)
+		}
+	}
+	want = append(want, add...)
+
+	for _, id := range add {
+		if !HasMember(after, id) {
+			mc.Lost = append(mc.Lost, id)
+		}
+	}
+	for _, id := range remove {
+		if HasMember(after, id) {
+			mc.Lost = append(mc.Lost, id)
+		}
+	}
+	added, removed := DiffMembers(want, after)
+	for _, id := range added {
+		if !HasMember(remove, id) {
+			mc.Added = append(mc.Added, id)
+		}
+	}
+	for _, id := range removed {
+		if !HasMember(add, id) {
+			mc.Removed = append(mc.Removed, id)
+		}
+	}
+
+	return mc
+}
@@ -0,0 +1,69 @@
+package smd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMembersVersion(t *testing.T) {
+	v := MembersVersion([]string{"x1000c0s0b0n0", "x1000c0s1b0n0"})
+	if got := MembersVersion([]string{"X1000C0S1B0N0", "x1000c0s0b0n0"}); got != v {
+		t.Errorf("MembersVersion() of reordered members = %s, want %s", got, v)
+	}
+	if got := MembersVersion([]string{"x1000c0s0b0n0"}); got == v {
+		t.Errorf("MembersVersion() of different members = %s, want a different version", got)
+	}
+	if len(v) != 16 {
+		t.Errorf("MembersVersion() = %s, want 16 hex digits", v)
+	}
+}
+
+func TestDiffMembers(t *testing.T) {
+	add, remove := DiffMembers([]string{"a", "b", "c"}, []string{"C", "d", "a", "d"})
+	if want := []string{"d"}; !reflect.DeepEqual(add, want) {
+		t.Errorf("DiffMembers() add = %v, want %v", add, want)
+	}
+	if want := []string{"b"}; !reflect.DeepEqual(remove, want) {
+		t.Errorf("DiffMembers() remove = %v, want %v", remove, want)
+	}
+}
+
+func TestCompareMembers(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after []string
+		add, remove   []string
+		want          MemberChanges
+	}{
+		{
+			name:   "clean",
+			before: []string{"a", "b"},
+			after:  []string{"a", "c"},
+			add:    []string{"c"},
+			remove: []string{"b"},
+		},
+		{
+			name:   "concurrent",
+			before: []string{"a", "b"},
+			after:  []string{"b", "c", "d"},
+			add:    []string{"c"},
+			want:   MemberChanges{Added: []string{"d"}, Removed: []string{"a"}},
+		},
+		{
+			name:   "lost",
+			before: []string{"a", "b"},
+			after:  []string{"a", "b", "e"},
+			add:    []string{"c"},
+			remove: []string{"b"},
+			want:   MemberChanges{Lost: []string{"c", "b"}, Added: []string{"e"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CompareMembers(tt.before, tt.after, tt.add, tt.remove)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CompareMembers() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
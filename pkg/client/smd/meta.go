@@ -0,0 +1,147 @@
+package smd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SMD has no place for free-form component metadata, so each key/value pair is
+// stored as an SMD group tagged with MetaTag whose members are the components
+// with that pair. The groups of a key share an exclusive group, which makes SMD
+// enforce that a component has at most one value per key. The label of a group
+// only holds the value in lower case (SMD normalizes labels), so the value as
+// set is kept in its description.
+
+// MetaTag is the tag of the SMD groups that hold component metadata.
+const MetaTag = "ochami-meta"
+
+// MetaSelectorPrefix is the prefix of a selector that matches components by
+// metadata, e.g. "meta.rack=12".
+const MetaSelectorPrefix = "meta."
+
+const metaLabelPrefix = "meta-"
+
+var (
+	metaKeyRegexp   = regexp.MustCompile(`^[a-z0-9_]+$`)
+	metaValueRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// ParseMetaPair parses the metadata pair s of the form "<key>=<value>". Keys
+// consist of lower case letters, digits, and underscores, and values of
+// letters, digits, underscores, periods, and dashes, so that they can be part
+// of an SMD group label.
+func ParseMetaPair(s string) (string, string, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", fmt.Errorf("invalid metadata %q: expected <key>=<value>", s)
+	}
+	if err := CheckMetaKey(key); err != nil {
+		return "", "", err
+	}
+	if !metaValueRegexp.MatchString(value) {
+		return "", "", fmt.Errorf("invalid metadata value %q for key %q: must consist of letters, digits, '_', '.', and '-'", value, key)
+	}
+
+	return key, value, nil
+}
+
+// CheckMetaKey returns an error if key is not a valid metadata key.
+func CheckMetaKey(key string) error {
+	if !metaKeyRegexp.MatchString(key) {
+		return fmt.Errorf("invalid metadata key %q: must consist of lower case letters, digits, and '_'", key)
+	}
+	return nil
+}
+
+// ParseMetaSelector parses the selector s of the form "meta.<key>=<value>",
+// returning its metadata key and value.
+func ParseMetaSelector(s string) (string, string, error) {
+	pair, ok := strings.CutPrefix(s, MetaSelectorPrefix)
+	if !ok {
+		return "", "", fmt.Errorf("invalid selector %q: expected %s<key>=<value>", s, MetaSelectorPrefix)
+	}
+	return ParseMetaPair(pair)
+}
+
+// MetaGroupLabel returns the label of the SMD group holding the metadata pair
+// key=value.
+func MetaGroupLabel(key, value string) string {
+	return metaLabelPrefix + key + "-" + strings.ToLower(value)
+}
+
+// NewMetaGroup returns the SMD group holding the metadata pair key=value with
+// members as its members.
+func NewMetaGroup(key, value string, members ...string) Group {
+	g := Group{
+		Label:          MetaGroupLabel(key, value),
+		Description:    key + "=" + value,
+		Tags:           []string{MetaTag},
+		ExclusiveGroup: metaLabelPrefix + key,
+	}
+	g.Members.IDs = members
+
+	return g
+}
+
+// MetaFromGroup returns the metadata pair held by g and true, or false if g
+// does not hold metadata.
+func MetaFromGroup(g Group) (string, string, bool) {
+	isMeta := false
+	for _, t := range g.Tags {
+		if t == MetaTag {
+			isMeta = true
+			break
+		}
+	}
+	key, ok := strings.CutPrefix(g.ExclusiveGroup, metaLabelPrefix)
+	if !isMeta || !ok {
+		return "", "", false
+	}
+	desc, _ := ParseAnnotation(g.Description)
+	if k, v, ok := strings.Cut(desc, "="); ok && k == key && strings.EqualFold(MetaGroupLabel(k, v), g.Label) {
+		return key, v, true
+	}
+	value, ok := strings.CutPrefix(g.Label, metaLabelPrefix+key+"-")
+	if !ok {
+		return "", "", false
+	}
+
+	return key, value, true
+}
+
+// MetaOf returns the metadata of the component id held by the SMD groups in
+// groups. IDs are compared case insensitively.
+func MetaOf(groups []Group, id string) map[string]string {
+	meta := make(map[string]string)
+	for _, g := range groups {
+		key, value, ok := MetaFromGroup(g)
+		if !ok {
+			continue
+		}
+		for _, m := range g.Members.IDs {
+			if strings.EqualFold(m, id) {
+				meta[key] = value
+				break
+			}
+		}
+	}
+
+	return meta
+}
+
+// MembersWithMeta returns the sorted IDs of the components that have the
+// metadata pair key=value according to the SMD groups in groups. Values are
+// compared case insensitively.
+func MembersWithMeta(groups []Group, key, value string) []string {
+	var ids []string
+	for _, g := range groups {
+		if k, _, ok := MetaFromGroup(g); ok && k == key && strings.EqualFold(g.Label, MetaGroupLabel(key, value)) {
+			ids = append(ids, g.Members.IDs...)
+		}
+	}
+	sort.Strings(ids)
+
+	return ids
+}
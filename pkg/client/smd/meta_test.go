@@ -0,0 +1,75 @@
+package smd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMetaPair(t *testing.T) {
+	tests := []struct {
+		s         string
+		wantKey   string
+		wantValue string
+		wantErr   bool
+	}{
+		{s: "rack=12", wantKey: "rack", wantValue: "12"},
+		{s: "asset_tag=SN-0042.a", wantKey: "asset_tag", wantValue: "SN-0042.a"},
+		{s: "rack", wantErr: true},
+		{s: "=12", wantErr: true},
+		{s: "rack=", wantErr: true},
+		{s: "Rack=12", wantErr: true},
+		{s: "rack-row=12", wantErr: true},
+		{s: "rack=1 2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			key, value, err := ParseMetaPair(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMetaPair(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if key != tt.wantKey || value != tt.wantValue {
+				t.Errorf("ParseMetaPair(%q) = %q, %q, want %q, %q", tt.s, key, value, tt.wantKey, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestParseMetaSelector(t *testing.T) {
+	if key, value, err := ParseMetaSelector("meta.rack=12"); err != nil || key != "rack" || value != "12" {
+		t.Errorf(`ParseMetaSelector("meta.rack=12") = %q, %q, %v`, key, value, err)
+	}
+	if _, _, err := ParseMetaSelector("rack=12"); err == nil {
+		t.Errorf(`ParseMetaSelector("rack=12") succeeded, want error`)
+	}
+}
+
+func TestMeta(t *testing.T) {
+	rack12 := NewMetaGroup("rack", "12", "x3000c0s0b0n0", "x3000c0s1b0n0")
+	tagA := NewMetaGroup("asset", "SN-A", "X3000C0S0B0N0")
+	// As returned by SMD, which normalizes labels
+	tagA.Label = "meta-asset-sn-a"
+	rack13 := NewMetaGroup("rack", "13", "x3000c0s2b0n0")
+	var compute Group
+	compute.Label = "compute"
+	compute.Members.IDs = []string{"x3000c0s0b0n0"}
+	groups := []Group{compute, rack12, tagA, rack13}
+
+	if rack12.Label != "meta-rack-12" || rack12.ExclusiveGroup != "meta-rack" {
+		t.Errorf("NewMetaGroup() = %+v", rack12)
+	}
+	if _, _, ok := MetaFromGroup(compute); ok {
+		t.Errorf("MetaFromGroup() found metadata in untagged group")
+	}
+
+	want := map[string]string{"rack": "12", "asset": "SN-A"}
+	if got := MetaOf(groups, "x3000c0s0b0n0"); !reflect.DeepEqual(got, want) {
+		t.Errorf("MetaOf() = %v, want %v", got, want)
+	}
+	wantIDs := []string{"x3000c0s0b0n0", "x3000c0s1b0n0"}
+	if got := MembersWithMeta(groups, "rack", "12"); !reflect.DeepEqual(got, wantIDs) {
+		t.Errorf("MembersWithMeta() = %v, want %v", got, wantIDs)
+	}
+	if got := MembersWithMeta(groups, "asset", "sn-a"); len(got) != 1 {
+		t.Errorf("MembersWithMeta() = %v, want case insensitive match", got)
+	}
+}
@@ -0,0 +1,167 @@
+package smd
+
+import (
+	"strings"
+	"time"
+)
+
+// Discovery statuses of RedfishEndpoints that SMD sets while it is still
+// discovering an endpoint. Any other status is the outcome of a discovery, e.g.
+// DiscoverOK or HTTPsGetFailed.
+const (
+	DiscoveryStatusStarted       = "DiscoveryStarted"
+	DiscoveryStatusNotYetQueried = "NotYetQueried"
+	DiscoveryStatusHTTPsGetOK    = "HTTPsGetOk"
+	DiscoveryStatusVerifying     = "VerifyingData"
+	DiscoveryStatusOK            = "DiscoverOK"
+)
+
+// DiscoveryInProgress returns whether status is one that SMD sets while it is
+// still discovering a RedfishEndpoint.
+func DiscoveryInProgress(status string) bool {
+	switch status {
+	case DiscoveryStatusStarted, DiscoveryStatusNotYetQueried, DiscoveryStatusHTTPsGetOK, DiscoveryStatusVerifying:
+		return true
+	}
+
+	return false
+}
+
+// DiscoverInput is the body of a request to SMD's /Inventory/Discover endpoint,
+// which flags the RedfishEndpoints with XNames (all of them if empty) for
+// rediscovery. Force starts discovery even for endpoints already being
+// discovered.
+type DiscoverInput struct {
+	XNames []string `json:"xnames,omitempty"`
+	Force  bool     `json:"force,omitempty"`
+}
+
+// RedfishEndpointDiscovery is the discovery status of the RedfishEndpoint with
+// ID, as returned by SMD. The field names differ from those of the schema the
+// other RedfishEndpoint structures use, so it is read separately.
+type RedfishEndpointDiscovery struct {
+	ID            string                       `json:"ID"`
+	DiscoveryInfo RedfishEndpointDiscoveryInfo `json:"DiscoveryInfo"`
+}
+
+// RedfishEndpointDiscoveryInfo is the DiscoveryInfo of a RedfishEndpoint.
+type RedfishEndpointDiscoveryInfo struct {
+	LastAttempt string `json:"LastDiscoveryAttempt,omitempty"`
+	LastStatus  string `json:"LastDiscoveryStatus"`
+}
+
+// RedfishEndpointDiscoverySlice is a convenience data structure for reading
+// the discovery status of RedfishEndpoints from SMD.
+type RedfishEndpointDiscoverySlice struct {
+	RedfishEndpoints []RedfishEndpointDiscovery `json:"RedfishEndpoints"`
+}
+
+// RediscoverResult is the outcome of the rediscovery of a RedfishEndpoint.
+// Status and LastAttempt are its last known discovery status and the time of
+// the attempt, and ElapsedSeconds is how long after the rediscovery was
+// triggered it was first seen done.
+type RediscoverResult struct {
+	Xname          string  `json:"xname" yaml:"xname"`
+	Done           bool    `json:"done" yaml:"done"`
+	OK             bool    `json:"ok" yaml:"ok"`
+	Status         string  `json:"status,omitempty" yaml:"status,omitempty"`
+	LastAttempt    string  `json:"last_attempt,omitempty" yaml:"last_attempt,omitempty"`
+	ElapsedSeconds float64 `json:"elapsed_seconds,omitempty" yaml:"elapsed_seconds,omitempty"`
+}
+
+// RediscoverWatcher tracks which of a set of RedfishEndpoints have finished
+// being rediscovered since a start time. An endpoint is done once its discovery
+// status is no longer one of those set during discovery (see
+// DiscoveryInProgress) and either its last discovery attempt is not the one it
+// had before the rediscovery was triggered or it was seen in progress since.
+type RediscoverWatcher struct {
+	start   time.Time
+	before  map[string]string
+	started map[string]bool
+	results []RediscoverResult
+	index   map[string]int
+}
+
+// NewRediscoverWatcher returns a RediscoverWatcher for the endpoints in
+// before, which are their discovery statuses before the rediscovery was
+// triggered at start.
+func NewRediscoverWatcher(before []RedfishEndpointDiscovery, start time.Time) *RediscoverWatcher {
+	w := &RediscoverWatcher{
+		start:   start,
+		before:  make(map[string]string),
+		started: make(map[string]bool),
+		index:   make(map[string]int),
+	}
+	for _, rfe := range before {
+		key := strings.ToLower(rfe.ID)
+		if _, ok := w.index[key]; ok {
+			continue
+		}
+		w.before[key] = rfe.DiscoveryInfo.LastAttempt
+		w.index[key] = len(w.results)
+		w.results = append(w.results, RediscoverResult{
+			Xname:       rfe.ID,
+			Status:      rfe.DiscoveryInfo.LastStatus,
+			LastAttempt: rfe.DiscoveryInfo.LastAttempt,
+		})
+	}
+
+	return w
+}
+
+// Update records the endpoints rfes as seen at now and returns the xnames of
+// the endpoints that finished being rediscovered. Endpoints that are not being
+// watched are ignored.
+func (w *RediscoverWatcher) Update(rfes []RedfishEndpointDiscovery, now time.Time) []string {
+	var done []string
+	for _, rfe := range rfes {
+		key := strings.ToLower(rfe.ID)
+		i, ok := w.index[key]
+		if !ok || w.results[i].Done {
+			continue
+		}
+		res := &w.results[i]
+		res.Status = rfe.DiscoveryInfo.LastStatus
+		res.LastAttempt = rfe.DiscoveryInfo.LastAttempt
+		if DiscoveryInProgress(res.Status) {
+			w.started[key] = true
+			continue
+		}
+		if !w.started[key] && res.LastAttempt == w.before[key] {
+			continue
+		}
+		res.Done = true
+		res.OK = res.Status == DiscoveryStatusOK
+		res.ElapsedSeconds = now.Sub(w.start).Round(time.Second).Seconds()
+		done = append(done, res.Xname)
+	}
+
+	return done
+}
+
+// Pending returns the xnames of the endpoints that have not finished being
+// rediscovered.
+func (w *RediscoverWatcher) Pending() []string {
+	var pending []string
+	for _, res := range w.results {
+		if !res.Done {
+			pending = append(pending, res.Xname)
+		}
+	}
+
+	return pending
+}
+
+// Done returns whether all endpoints finished being rediscovered.
+func (w *RediscoverWatcher) Done() bool {
+	return len(w.Pending()) == 0
+}
+
+// Results returns the outcome for each endpoint, in the order they were
+// passed to NewRediscoverWatcher.
+func (w *RediscoverWatcher) Results() []RediscoverResult {
+	results := make([]RediscoverResult, len(w.results))
+	copy(results, w.results)
+
+	return results
+}
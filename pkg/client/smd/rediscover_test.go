@@ -0,0 +1,60 @@
+package smd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRediscoverWatcher(t *testing.T) {
+	rfe := func(id, attempt, status string) RedfishEndpointDiscovery {
+		return RedfishEndpointDiscovery{ID: id, DiscoveryInfo: RedfishEndpointDiscoveryInfo{LastAttempt: attempt, LastStatus: status}}
+	}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := NewRediscoverWatcher([]RedfishEndpointDiscovery{
+		rfe("x1000c0s0b0", "t0", DiscoveryStatusOK),
+		rfe("x1000c0s1b0", "t0", "HTTPsGetFailed"),
+		rfe("x1000c0s2b0", "t0", DiscoveryStatusOK),
+		rfe("X1000C0S0B0", "t0", DiscoveryStatusOK),
+	}, start)
+
+	// Nothing has changed yet
+	if done := w.Update([]RedfishEndpointDiscovery{
+		rfe("x1000c0s0b0", "t0", DiscoveryStatusOK),
+		rfe("x1000c0s1b0", "t0", "HTTPsGetFailed"),
+	}, start.Add(time.Second)); len(done) != 0 {
+		t.Errorf("Update() before any change = %v, want none", done)
+	}
+
+	// One is in progress, one finished with a new attempt, and one not
+	// being watched is ignored
+	if done := w.Update([]RedfishEndpointDiscovery{
+		rfe("x1000c0s0b0", "t1", DiscoveryStatusStarted),
+		rfe("x1000c0s1b0", "t1", "HTTPsGetFailed"),
+		rfe("x1000c0s9b0", "t1", DiscoveryStatusOK),
+	}, start.Add(10*time.Second)); !reflect.DeepEqual(done, []string{"x1000c0s1b0"}) {
+		t.Errorf("Update() = %v, want [x1000c0s1b0]", done)
+	}
+
+	// The one in progress finishes, even with the same attempt time
+	if done := w.Update([]RedfishEndpointDiscovery{
+		rfe("x1000c0s0b0", "t1", DiscoveryStatusOK),
+	}, start.Add(20*time.Second)); !reflect.DeepEqual(done, []string{"x1000c0s0b0"}) {
+		t.Errorf("Update() = %v, want [x1000c0s0b0]", done)
+	}
+
+	if w.Done() {
+		t.Error("Done() = true with an endpoint pending")
+	}
+	if pending := w.Pending(); !reflect.DeepEqual(pending, []string{"x1000c0s2b0"}) {
+		t.Errorf("Pending() = %v, want [x1000c0s2b0]", pending)
+	}
+	want := []RediscoverResult{
+		{Xname: "x1000c0s0b0", Done: true, OK: true, Status: DiscoveryStatusOK, LastAttempt: "t1", ElapsedSeconds: 20},
+		{Xname: "x1000c0s1b0", Done: true, Status: "HTTPsGetFailed", LastAttempt: "t1", ElapsedSeconds: 10},
+		{Xname: "x1000c0s2b0", Status: DiscoveryStatusOK, LastAttempt: "t0"},
+	}
+	if got := w.Results(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Results() =\n%+v\nwant\n%+v", got, want)
+	}
+}
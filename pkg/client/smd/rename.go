@@ -0,0 +1,38 @@
+package smd
+
+import (
+	"sort"
+	"strings"
+)
+
+// RenameEthernetInterfaces returns copies of the ethernet interfaces in eis
+// that belong to the component oldID, with their ComponentID set to newID.
+// Component IDs are compared case insensitively, and eis is not modified.
+func RenameEthernetInterfaces(eis []EthernetInterface, oldID, newID string) []EthernetInterface {
+	var renamed []EthernetInterface
+	for _, ei := range eis {
+		if strings.EqualFold(ei.ComponentID, oldID) {
+			ei.ComponentID = newID
+			renamed = append(renamed, ei)
+		}
+	}
+
+	return renamed
+}
+
+// GroupsWithMember returns the sorted labels of the groups in groups that have
+// id as a member. IDs are compared case insensitively.
+func GroupsWithMember(groups []Group, id string) []string {
+	var labels []string
+	for _, g := range groups {
+		for _, m := range g.Members.IDs {
+			if strings.EqualFold(m, id) {
+				labels = append(labels, g.Label)
+				break
+			}
+		}
+	}
+	sort.Strings(labels)
+
+	return labels
+}
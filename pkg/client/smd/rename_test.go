@@ -0,0 +1,43 @@
+package smd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRenameEthernetInterfaces(t *testing.T) {
+	eis := []EthernetInterface{
+		{ID: "a", ComponentID: "X1000C0S0B0N0", MACAddress: "de:ad:be:ef:00:01"},
+		{ID: "b", ComponentID: "x1000c0s1b0n0", MACAddress: "de:ad:be:ef:00:02"},
+		{ID: "c", ComponentID: "x1000c0s0b0n0", MACAddress: "de:ad:be:ef:00:03"},
+	}
+	want := []EthernetInterface{
+		{ID: "a", ComponentID: "x1000c0s7b0n0", MACAddress: "de:ad:be:ef:00:01"},
+		{ID: "c", ComponentID: "x1000c0s7b0n0", MACAddress: "de:ad:be:ef:00:03"},
+	}
+	got := RenameEthernetInterfaces(eis, "x1000c0s0b0n0", "x1000c0s7b0n0")
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("RenameEthernetInterfaces() = %+v, want %+v", got, want)
+	}
+	if eis[0].ComponentID != "X1000C0S0B0N0" {
+		t.Errorf("RenameEthernetInterfaces() modified input")
+	}
+}
+
+func TestGroupsWithMember(t *testing.T) {
+	var compute, slurm, gpu Group
+	compute.Label = "compute"
+	compute.Members.IDs = []string{"x1000c0s0b0n0", "x1000c0s1b0n0"}
+	slurm.Label = "slurm"
+	slurm.Members.IDs = []string{"X1000C0S0B0N0"}
+	gpu.Label = "gpu"
+	gpu.Members.IDs = []string{"x1000c0s1b0n0"}
+
+	want := []string{"compute", "slurm"}
+	if got := GroupsWithMember([]Group{slurm, gpu, compute}, "x1000c0s0b0n0"); !reflect.DeepEqual(got, want) {
+		t.Errorf("GroupsWithMember() = %v, want %v", got, want)
+	}
+	if got := GroupsWithMember([]Group{gpu}, "x1000c0s0b0n0"); got != nil {
+		t.Errorf("GroupsWithMember() = %v, want nil", got)
+	}
+}
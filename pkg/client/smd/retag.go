@@ -0,0 +1,42 @@
+package smd
+
+import (
+	"net"
+)
+
+// RetagEthernetInterfaces returns copies of the ethernet interfaces in eis that
+// have at least one IP address whose Network matches, with the Network of each
+// matching IP address set to to. An IP address matches if its Network is from
+// (when from is not empty) and its IPAddress is within cidr (when cidr is not
+// nil). Interfaces without any matching IP addresses are not returned, and eis
+// is not modified.
+func RetagEthernetInterfaces(eis []EthernetInterface, from string, cidr *net.IPNet, to string) []EthernetInterface {
+	var retagged []EthernetInterface
+	for _, ei := range eis {
+		var changed bool
+		ips := make([]EthernetIP, len(ei.IPAddresses))
+		copy(ips, ei.IPAddresses)
+		for i, ip := range ips {
+			if from != "" && ip.Network != from {
+				continue
+			}
+			if cidr != nil {
+				addr := net.ParseIP(ip.IPAddress)
+				if addr == nil || !cidr.Contains(addr) {
+					continue
+				}
+			}
+			if ip.Network == to {
+				continue
+			}
+			ips[i].Network = to
+			changed = true
+		}
+		if changed {
+			ei.IPAddresses = ips
+			retagged = append(retagged, ei)
+		}
+	}
+
+	return retagged
+}
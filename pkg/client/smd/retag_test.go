@@ -0,0 +1,80 @@
+package smd
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+func TestRetagEthernetInterfaces(t *testing.T) {
+	eis := []EthernetInterface{
+		{
+			ID: "a",
+			IPAddresses: []EthernetIP{
+				{IPAddress: "10.2.0.1", Network: "old-net"},
+				{IPAddress: "10.3.0.1", Network: "old-net"},
+			},
+		},
+		{
+			ID: "b",
+			IPAddresses: []EthernetIP{
+				{IPAddress: "10.2.0.2", Network: "mgmt"},
+			},
+		},
+		{
+			ID: "c",
+			IPAddresses: []EthernetIP{
+				{IPAddress: "10.2.0.3", Network: "new-net"},
+			},
+		},
+	}
+	_, cidr, _ := net.ParseCIDR("10.2.0.0/16")
+
+	tests := []struct {
+		name string
+		from string
+		cidr *net.IPNet
+		want []EthernetInterface
+	}{
+		{
+			name: "by name",
+			from: "old-net",
+			want: []EthernetInterface{
+				{ID: "a", IPAddresses: []EthernetIP{{"10.2.0.1", "new-net"}, {"10.3.0.1", "new-net"}}},
+			},
+		},
+		{
+			name: "by CIDR",
+			cidr: cidr,
+			want: []EthernetInterface{
+				{ID: "a", IPAddresses: []EthernetIP{{"10.2.0.1", "new-net"}, {"10.3.0.1", "old-net"}}},
+				{ID: "b", IPAddresses: []EthernetIP{{"10.2.0.2", "new-net"}}},
+			},
+		},
+		{
+			name: "by name and CIDR",
+			from: "old-net",
+			cidr: cidr,
+			want: []EthernetInterface{
+				{ID: "a", IPAddresses: []EthernetIP{{"10.2.0.1", "new-net"}, {"10.3.0.1", "old-net"}}},
+			},
+		},
+		{
+			name: "no match",
+			from: "nonexistent",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RetagEthernetInterfaces(eis, tt.from, tt.cidr, "new-net")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Input must not be modified
+	if eis[0].IPAddresses[0].Network != "old-net" {
+		t.Errorf("input was modified: %+v", eis[0])
+	}
+}
@@ -27,7 +27,10 @@ const (
 	SMDRelpathEthernetInterfaces = "/Inventory/EthernetInterfaces"
 	SMDRelpathRedfishEndpoints   = "/Inventory/RedfishEndpoints"
 	SMDRelpathComponentEndpoints = "/Inventory/ComponentEndpoints"
+	SMDRelpathDiscover           = "/Inventory/Discover"
+	SMDRelpathHardware           = "/Inventory/Hardware"
 	SMDRelpathGroups             = "/groups"
+	SMDRelpathSCNSubscriptions   = "/Subscriptions/SCN"
 
 	SMDSubpathBulkNID = "BulkNID"
 )
@@ -38,6 +41,7 @@ type Component struct {
 	ID      string `json:"ID" yaml:"ID"`
 	Type    string `json:"Type" yaml:"Type"`
 	State   string `json:"State,omitempty" yaml:"State,omitempty"`
+	Flag    string `json:"Flag,omitempty" yaml:"Flag,omitempty"`
 	Enabled bool   `json:"Enabled,omitempty" yaml:"Enabled,omitempty"`
 	Role    string `json:"Role,omitempty" yaml:"Role,omitempty"`
 	Arch    string `json:"Arch,omitempty" yaml:"Arch,omitempty"`
@@ -90,6 +94,14 @@ type RedfishEndpointV2 struct {
 	Managers      []Manager `json:"Managers" yaml:"Managers"`
 }
 
+// RedfishEndpointCreds holds the credentials SMD stores for the BMC of the
+// RedfishEndpoint with ID. Only User and Password are sent when patching.
+type RedfishEndpointCreds struct {
+	ID       string `json:"-" yaml:"-"`
+	User     string `json:"User,omitempty" yaml:"User,omitempty"`
+	Password string `json:"Password" yaml:"Password"`
+}
+
 // System represents data that would be retrieved from BMC System data, except
 // reduced to a minimum needed for discovery.
 type System struct {
@@ -126,6 +138,32 @@ type GroupMembers struct {
 	IDs   []string `json:"ids" yaml:"ids"`
 }
 
+// SCNSubscription represents a subscription to SMD's state change
+// notifications (SCNs). SMD sends an SCN to Url whenever a component changes to
+// one of States or its Enabled, Role, or SoftwareStatus field changes to one of
+// those listed.
+type SCNSubscription struct {
+	ID             int64    `json:"ID,omitempty" yaml:"ID,omitempty"`
+	Subscriber     string   `json:"Subscriber" yaml:"Subscriber"`
+	Url            string   `json:"Url" yaml:"Url"`
+	Enabled        *bool    `json:"Enabled,omitempty" yaml:"Enabled,omitempty"`
+	Roles          []string `json:"Roles,omitempty" yaml:"Roles,omitempty"`
+	SoftwareStatus []string `json:"SoftwareStatus,omitempty" yaml:"SoftwareStatus,omitempty"`
+	States         []string `json:"States,omitempty" yaml:"States,omitempty"`
+}
+
+// SCN is a state change notification sent by SMD to subscribers, listing the
+// components that changed and their new values.
+type SCN struct {
+	Components     []string `json:"Components,omitempty" yaml:"Components,omitempty"`
+	Enabled        *bool    `json:"Enabled,omitempty" yaml:"Enabled,omitempty"`
+	Flag           string   `json:"Flag,omitempty" yaml:"Flag,omitempty"`
+	Role           string   `json:"Role,omitempty" yaml:"Role,omitempty"`
+	SubRole        string   `json:"SubRole,omitempty" yaml:"SubRole,omitempty"`
+	SoftwareStatus string   `json:"SoftwareStatus,omitempty" yaml:"SoftwareStatus,omitempty"`
+	State          string   `json:"State,omitempty" yaml:"State,omitempty"`
+}
+
 // NewClient takes a baseURI and returns a pointer to a new SMDClient. If an
 // error occurred creating the embedded OchamiClient, it is returned. If
 // insecure is true, TLS certificates will not be verified.
@@ -186,6 +224,19 @@ func (sc *SMDClient) GetComponentsAll() (client.HTTPEnvelope, error) {
 	return henv, err
 }
 
+// GetComponentsAllStream is like GetComponentsAll, but uses
+// OchamiClient.GetDataStream so that the components are spilled to a temporary
+// file if they are too large to hold in memory. The caller must Close the
+// returned StreamBody.
+func (sc *SMDClient) GetComponentsAllStream() (client.HTTPEnvelope, *client.StreamBody, error) {
+	henv, body, err := sc.GetDataStream(SMDRelpathComponents, "", nil)
+	if err != nil {
+		err = fmt.Errorf("GetComponentsAllStream(): error getting components: %w", err)
+	}
+
+	return henv, body, err
+}
+
 // GetComponentsXname is like GetComponentsAll except that it takes a token and
 // queries /State/Components/{xname}.
 func (sc *SMDClient) GetComponentsXname(xname, token string) (client.HTTPEnvelope, error) {
@@ -205,6 +256,26 @@ func (sc *SMDClient) GetComponentsXname(xname, token string) (client.HTTPEnvelop
 	return henv, err
 }
 
+// GetComponentsIDs is like GetComponentsAll except that it takes a token and
+// only queries the components with the IDs xnames, passed as id query
+// parameters. Components that do not exist are omitted from the response.
+func (sc *SMDClient) GetComponentsIDs(token string, xnames ...string) (client.HTTPEnvelope, error) {
+	var henv client.HTTPEnvelope
+	headers := client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			return henv, fmt.Errorf("GetComponentsIDs(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	query := url.Values{"id": xnames}
+	henv, err := sc.GetData(SMDRelpathComponents, query.Encode(), headers)
+	if err != nil {
+		err = fmt.Errorf("GetComponentsIDs(): error getting components: %w", err)
+	}
+
+	return henv, err
+}
+
 // GetComponentsNid is like GetComponentsAll except that it takes a token and
 // queries /State/Components/ByNID/{nid}.
 func (sc *SMDClient) GetComponentsNid(nid int32, token string) (client.HTTPEnvelope, error) {
@@ -249,10 +320,17 @@ func (sc *SMDClient) GetRedfishEndpoints(query, token string) (client.HTTPEnvelo
 }
 
 // GetEthernetInterfaces is a wrapper around OchamiClient.GetData that takes a
-// query string and passes it to OchamiClient.GetData using SMD's ethernet
-// interfaces endpoint.
-func (sc *SMDClient) GetEthernetInterfaces(query string) (client.HTTPEnvelope, error) {
-	henv, err := sc.GetData(SMDRelpathEthernetInterfaces, query, nil)
+// query string and a token and passes them to OchamiClient.GetData using SMD's
+// ethernet interfaces endpoint, setting the token as the authorization bearer.
+func (sc *SMDClient) GetEthernetInterfaces(query, token string) (client.HTTPEnvelope, error) {
+	var henv client.HTTPEnvelope
+	headers := client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			return henv, fmt.Errorf("GetEthernetInterfaces(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	henv, err := sc.GetData(SMDRelpathEthernetInterfaces, query, headers)
 	if err != nil {
 		err = fmt.Errorf("GetEthernetInterfaces(): error getting ethernet interfaces: %w", err)
 	}
@@ -260,6 +338,25 @@ func (sc *SMDClient) GetEthernetInterfaces(query string) (client.HTTPEnvelope, e
 	return henv, err
 }
 
+// GetEthernetInterfacesStream is like GetEthernetInterfaces, but uses
+// OchamiClient.GetDataStream so that the ethernet interfaces are spilled to a
+// temporary file if they are too large to hold in memory. The caller must Close
+// the returned StreamBody.
+func (sc *SMDClient) GetEthernetInterfacesStream(query, token string) (client.HTTPEnvelope, *client.StreamBody, error) {
+	headers := client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			return client.HTTPEnvelope{}, nil, fmt.Errorf("GetEthernetInterfacesStream(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	henv, body, err := sc.GetDataStream(SMDRelpathEthernetInterfaces, query, headers)
+	if err != nil {
+		err = fmt.Errorf("GetEthernetInterfacesStream(): error getting ethernet interfaces: %w", err)
+	}
+
+	return henv, body, err
+}
+
 // GetEthernetInterfacesByID is a wrapper around OchamiClient.GetData that takes
 // an ethernet interface ID, token, and a flag indicating if the ethernet
 // interface itself should be retrieved or a list of its IPs. It passes these to
@@ -352,6 +449,30 @@ func (sc *SMDClient) GetComponentEndpointsAll(token string) (client.HTTPEnvelope
 	return henv, err
 }
 
+// GetHardware is a wrapper function around OchamiClient.GetData that takes a
+// query string (without the "?") and token. It puts the token in the request
+// headers as an authorization bearer, then sends a get to the SMD hardware
+// inventory API endpoint with the query string.
+func (sc *SMDClient) GetHardware(query, token string) (client.HTTPEnvelope, error) {
+	var (
+		henv    client.HTTPEnvelope
+		headers *client.HTTPHeaders
+		err     error
+	)
+	headers = client.NewHTTPHeaders()
+	if token != "" {
+		if err = headers.SetAuthorization(token); err != nil {
+			return henv, fmt.Errorf("GetHardware(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	henv, err = sc.GetData(SMDRelpathHardware, query, headers)
+	if err != nil {
+		err = fmt.Errorf("GetHardware(): error getting hardware inventory: %w", err)
+	}
+
+	return henv, err
+}
+
 // GetGroups is a wrapper function around OchamiClient.GetData that takes a
 // query string and token. It puts the token in the request headers as an
 // authorization bearer, then sends a get to the SMD groups API endpoint with
@@ -543,6 +664,37 @@ func (sc *SMDClient) PostEthernetInterfaces(eis []EthernetInterface, token strin
 	return henvs, errors, nil
 }
 
+// PostHardware is a wrapper function around OchamiClient.PostData that takes a
+// slice of HWInvByLocs and a token, puts the token in the request headers as an
+// authorization bearer, marshalls hw as JSON under the "Hardware" key and sets
+// it as the request body, then passes it to Ochami.PostData. SMD adds the
+// entries that do not exist and replaces those that do.
+func (sc *SMDClient) PostHardware(hw []HWInvByLoc, token string) (client.HTTPEnvelope, error) {
+	var (
+		henv    client.HTTPEnvelope
+		headers *client.HTTPHeaders
+		body    client.HTTPBody
+		err     error
+	)
+	if body, err = json.Marshal(struct {
+		Hardware []HWInvByLoc `json:"Hardware"`
+	}{hw}); err != nil {
+		return henv, fmt.Errorf("PostHardware(): failed to marshal hardware inventory: %w", err)
+	}
+	headers = client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			return henv, fmt.Errorf("PostHardware(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	henv, err = sc.PostData(SMDRelpathHardware, "", headers, body)
+	if err != nil {
+		err = fmt.Errorf("PostHardware(): failed to POST hardware inventory to SMD: %w", err)
+	}
+
+	return henv, err
+}
+
 // PostGroups is a wrapper function around OchamiClient.PostData that takes a
 // Group slice and a token, puts the token in the request headers as an
 // authorization bearer, and iteratively calls OchamiClient.PostData using each
@@ -945,6 +1097,51 @@ func (sc *SMDClient) PatchEthernetInterfaces(eis []EthernetInterface, token stri
 	return henvs, errors, nil
 }
 
+// PatchRedfishEndpointCreds is a wrapper function around OchamiClient.PatchData
+// that takes a slice of RedfishEndpointCreds and a token, puts the token in the
+// request headers as an authorization bearer, and iteratively calls
+// OchamiClient.PatchData to update the credentials of each RedfishEndpoint.
+func (sc *SMDClient) PatchRedfishEndpointCreds(creds []RedfishEndpointCreds, token string) ([]client.HTTPEnvelope, []error, error) {
+	var (
+		errors  []error
+		henvs   []client.HTTPEnvelope
+		headers *client.HTTPHeaders
+	)
+	headers = client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			return henvs, errors, fmt.Errorf("PatchRedfishEndpointCreds(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	for _, c := range creds {
+		var body client.HTTPBody
+		var err error
+		rfePath, err := url.JoinPath(SMDRelpathRedfishEndpoints, c.ID)
+		if err != nil {
+			newErr := fmt.Errorf("PatchRedfishEndpointCreds(): failed to join redfish endpoint path (%s) with xname (%s): %w", SMDRelpathRedfishEndpoints, c.ID, err)
+			henvs = append(henvs, client.HTTPEnvelope{})
+			errors = append(errors, newErr)
+			continue
+		}
+		if body, err = json.Marshal(c); err != nil {
+			newErr := fmt.Errorf("PatchRedfishEndpointCreds(): failed to marshal RedfishEndpointCreds: %w", err)
+			henvs = append(henvs, client.HTTPEnvelope{})
+			errors = append(errors, newErr)
+			continue
+		}
+		henv, err := sc.PatchData(rfePath, "", headers, body)
+		henvs = append(henvs, henv)
+		if err != nil {
+			newErr := fmt.Errorf("PatchRedfishEndpointCreds(): failed to PATCH credentials of redfish endpoint %s in SMD: %w", c.ID, err)
+			errors = append(errors, newErr)
+			continue
+		}
+		errors = append(errors, nil)
+	}
+
+	return henvs, errors, nil
+}
+
 // PatchGroups is a wrapper function around OchamiClient.PatchData that takes a
 // Group slice and a token, puts token in the request headers as an
 // authorization bearer, marshals each group as JSON and sets it as the request
@@ -1345,3 +1542,87 @@ func (sc *SMDClient) DeleteGroupMembers(token, group string, members ...string)
 
 	return henvs, errors, nil
 }
+
+// PostDiscover is a wrapper function around OchamiClient.PostData that takes a
+// DiscoverInput and a token, puts the token in the request headers as an
+// authorization bearer, marshals in as JSON and sets it as the request body,
+// then passes it to OchamiClient.PostData to flag the RedfishEndpoints in it
+// for rediscovery.
+func (sc *SMDClient) PostDiscover(in DiscoverInput, token string) (client.HTTPEnvelope, error) {
+	var (
+		henv    client.HTTPEnvelope
+		headers *client.HTTPHeaders
+		body    client.HTTPBody
+		err     error
+	)
+	if body, err = json.Marshal(in); err != nil {
+		return henv, fmt.Errorf("PostDiscover(): failed to marshal DiscoverInput: %w", err)
+	}
+	headers = client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			return henv, fmt.Errorf("PostDiscover(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	henv, err = sc.PostData(SMDRelpathDiscover, "", headers, body)
+	if err != nil {
+		err = fmt.Errorf("PostDiscover(): failed to POST discover request to SMD: %w", err)
+	}
+
+	return henv, err
+}
+
+// PostSCNSubscription is a wrapper function around OchamiClient.PostData that
+// takes an SCNSubscription and a token, puts the token in the request headers
+// as an authorization bearer, marshalls sub as JSON and sets it as the request
+// body, then passes it to Ochami.PostData. The response body contains the
+// subscription with its ID set.
+func (sc *SMDClient) PostSCNSubscription(sub SCNSubscription, token string) (client.HTTPEnvelope, error) {
+	var (
+		henv    client.HTTPEnvelope
+		headers *client.HTTPHeaders
+		body    client.HTTPBody
+		err     error
+	)
+	if body, err = json.Marshal(sub); err != nil {
+		return henv, fmt.Errorf("PostSCNSubscription(): failed to marshal SCNSubscription: %w", err)
+	}
+	headers = client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			return henv, fmt.Errorf("PostSCNSubscription(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	henv, err = sc.PostData(SMDRelpathSCNSubscriptions, "", headers, body)
+	if err != nil {
+		err = fmt.Errorf("PostSCNSubscription(): failed to POST SCN subscription to SMD: %w", err)
+	}
+
+	return henv, err
+}
+
+// DeleteSCNSubscription is a wrapper function around OchamiClient.DeleteData
+// that takes a token and the ID of an SCN subscription and deletes the
+// subscription.
+func (sc *SMDClient) DeleteSCNSubscription(id int64, token string) (client.HTTPEnvelope, error) {
+	var (
+		henv    client.HTTPEnvelope
+		headers *client.HTTPHeaders
+	)
+	headers = client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.SetAuthorization(token); err != nil {
+			return henv, fmt.Errorf("DeleteSCNSubscription(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	subPath, err := url.JoinPath(SMDRelpathSCNSubscriptions, fmt.Sprint(id))
+	if err != nil {
+		return henv, fmt.Errorf("DeleteSCNSubscription(): failed to join SCN subscription path (%s) with ID %d: %w", SMDRelpathSCNSubscriptions, id, err)
+	}
+	henv, err = sc.DeleteData(subPath, "", headers, nil)
+	if err != nil {
+		err = fmt.Errorf("DeleteSCNSubscription(): failed to DELETE SCN subscription %d in SMD: %w", id, err)
+	}
+
+	return henv, err
+}
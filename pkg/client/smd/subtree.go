@@ -0,0 +1,129 @@
+package smd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/OpenCHAMI/ochami/pkg/xname"
+)
+
+// The kinds of SMD resources in a SubtreePlan, in the order they are deleted.
+const (
+	SubtreeKindEthernetInterface = "EthernetInterface"
+	SubtreeKindComponentEndpoint = "ComponentEndpoint"
+	SubtreeKindRedfishEndpoint   = "RedfishEndpoint"
+	SubtreeKindComponent         = "Component"
+)
+
+// SubtreeItem is an SMD resource to delete as part of a SubtreePlan. Done is
+// set once it has been deleted.
+type SubtreeItem struct {
+	Kind string `json:"kind" yaml:"kind"`
+	ID   string `json:"id" yaml:"id"`
+	Done bool   `json:"done" yaml:"done"`
+}
+
+// SubtreePlan lists the SMD resources located under the xname Root, ordered
+// bottom-up: ethernet interfaces, component endpoints, and redfish endpoints
+// first, then components from the deepest in the xname hierarchy up to Root
+// itself. It is saved to a state file while deleting so that an interrupted
+// deletion can be resumed.
+type SubtreePlan struct {
+	Root  string        `json:"root" yaml:"root"`
+	Items []SubtreeItem `json:"items" yaml:"items"`
+}
+
+// NewSubtreePlan returns a SubtreePlan for the resources under root out of the
+// passed components, ethernet interfaces (matched by ComponentID), redfish
+// endpoint IDs, and component endpoint IDs.
+func NewSubtreePlan(root string, comps []Component, eis []EthernetInterface, rfeIDs, compEPIDs []string) SubtreePlan {
+	plan := SubtreePlan{Root: root, Items: []SubtreeItem{}}
+	add := func(kind string, ids []string) {
+		sort.Strings(ids)
+		for _, id := range ids {
+			plan.Items = append(plan.Items, SubtreeItem{Kind: kind, ID: id})
+		}
+	}
+	filter := func(ids []string) []string {
+		var under []string
+		for _, id := range ids {
+			if xname.IsDescendant(id, root) {
+				under = append(under, id)
+			}
+		}
+		return under
+	}
+
+	var eiIDs []string
+	for _, ei := range eis {
+		if xname.IsDescendant(ei.ComponentID, root) {
+			eiIDs = append(eiIDs, ei.ID)
+		}
+	}
+	add(SubtreeKindEthernetInterface, eiIDs)
+	add(SubtreeKindComponentEndpoint, filter(compEPIDs))
+	add(SubtreeKindRedfishEndpoint, filter(rfeIDs))
+
+	var compIDs []string
+	for _, c := range comps {
+		compIDs = append(compIDs, c.ID)
+	}
+	compIDs = filter(compIDs)
+	sort.SliceStable(compIDs, func(i, j int) bool {
+		di, dj := xname.Depth(compIDs[i]), xname.Depth(compIDs[j])
+		if di != dj {
+			return di > dj
+		}
+		return compIDs[i] < compIDs[j]
+	})
+	for _, id := range compIDs {
+		plan.Items = append(plan.Items, SubtreeItem{Kind: SubtreeKindComponent, ID: id})
+	}
+
+	return plan
+}
+
+// Remaining returns the number of items of each kind that are not done.
+func (p SubtreePlan) Remaining() map[string]int {
+	counts := map[string]int{
+		SubtreeKindEthernetInterface: 0,
+		SubtreeKindComponentEndpoint: 0,
+		SubtreeKindRedfishEndpoint:   0,
+		SubtreeKindComponent:         0,
+	}
+	for _, it := range p.Items {
+		if !it.Done {
+			counts[it.Kind]++
+		}
+	}
+
+	return counts
+}
+
+// Stages groups the indexes of the items in the plan that are not done into
+// stages that must be deleted in order. Items within a stage do not depend on
+// each other and can be deleted concurrently. Each kind other than components
+// is one stage, and components are staged by depth in the xname hierarchy so
+// that children are always deleted before their parents.
+func (p SubtreePlan) Stages() [][]int {
+	var (
+		stages  [][]int
+		lastKey string
+	)
+	for i, it := range p.Items {
+		if it.Done {
+			continue
+		}
+		key := it.Kind
+		if it.Kind == SubtreeKindComponent {
+			key = fmt.Sprintf("%s/%d", it.Kind, xname.Depth(it.ID))
+		}
+		if len(stages) == 0 || key != lastKey {
+			stages = append(stages, []int{})
+			lastKey = key
+		}
+		stages[len(stages)-1] = append(stages[len(stages)-1], i)
+	}
+
+	return stages
+}
@@ -0,0 +1,58 @@
+package smd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewSubtreePlan(t *testing.T) {
+	comps := []Component{
+		{ID: "x3000c0"},
+		{ID: "x3000c0s0b0n0"},
+		{ID: "x3000c0s0b0"},
+		{ID: "x3000c0s1b0n0"},
+		{ID: "x3000c1s0b0n0"},
+		{ID: "x3000c01s0b0n0"},
+	}
+	eis := []EthernetInterface{
+		{ID: "decafc0ffee0", ComponentID: "x3000c0s0b0n0"},
+		{ID: "decafc0ffee1", ComponentID: "x3000c1s0b0n0"},
+		{ID: "decafc0ffee2"},
+	}
+	plan := NewSubtreePlan("x3000c0", comps, eis, []string{"x3000c0s0b0", "x3000c1s0b0"}, []string{"x3000c0s0b0n0"})
+
+	want := []SubtreeItem{
+		{Kind: SubtreeKindEthernetInterface, ID: "decafc0ffee0"},
+		{Kind: SubtreeKindComponentEndpoint, ID: "x3000c0s0b0n0"},
+		{Kind: SubtreeKindRedfishEndpoint, ID: "x3000c0s0b0"},
+		{Kind: SubtreeKindComponent, ID: "x3000c0s0b0n0"},
+		{Kind: SubtreeKindComponent, ID: "x3000c0s1b0n0"},
+		{Kind: SubtreeKindComponent, ID: "x3000c0s0b0"},
+		{Kind: SubtreeKindComponent, ID: "x3000c0"},
+	}
+	if !reflect.DeepEqual(plan.Items, want) {
+		t.Fatalf("got %+v, want %+v", plan.Items, want)
+	}
+
+	wantStages := [][]int{{0}, {1}, {2}, {3, 4}, {5}, {6}}
+	if got := plan.Stages(); !reflect.DeepEqual(got, wantStages) {
+		t.Errorf("Stages() = %v, want %v", got, wantStages)
+	}
+
+	// Resuming skips items that are done
+	plan.Items[0].Done = true
+	plan.Items[3].Done = true
+	wantStages = [][]int{{1}, {2}, {4}, {5}, {6}}
+	if got := plan.Stages(); !reflect.DeepEqual(got, wantStages) {
+		t.Errorf("Stages() after resume = %v, want %v", got, wantStages)
+	}
+	wantRemaining := map[string]int{
+		SubtreeKindEthernetInterface: 0,
+		SubtreeKindComponentEndpoint: 1,
+		SubtreeKindRedfishEndpoint:   1,
+		SubtreeKindComponent:         3,
+	}
+	if got := plan.Remaining(); !reflect.DeepEqual(got, wantRemaining) {
+		t.Errorf("Remaining() = %v, want %v", got, wantRemaining)
+	}
+}
@@ -0,0 +1,156 @@
+package smd
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Kinds of targets accepted by ParseTarget. A target without a kind prefix is
+// an xname.
+const (
+	TargetXname = "xname"
+	TargetNID   = "nid"
+	TargetMAC   = "mac"
+	TargetGroup = "group"
+)
+
+// Target identifies one or more components by xname, NID, MAC address, or SMD
+// group.
+type Target struct {
+	Kind  string
+	Value string
+}
+
+func (t Target) String() string {
+	return t.Kind + ":" + t.Value
+}
+
+// ParseTarget parses s of the form "[<kind>:]<value>", where kind is one of
+// xname, nid, mac, or group. Without a kind, s is a MAC address if it parses
+// as one and an xname otherwise. NIDs must be positive integers and MAC
+// addresses are normalized to lower case with colons.
+func ParseTarget(s string) (Target, error) {
+	t := Target{Kind: TargetXname, Value: s}
+	if _, err := net.ParseMAC(s); err == nil {
+		t.Kind = TargetMAC
+	} else if kind, value, ok := strings.Cut(s, ":"); ok {
+		switch strings.ToLower(kind) {
+		case TargetXname, TargetNID, TargetMAC, TargetGroup:
+			t = Target{Kind: strings.ToLower(kind), Value: value}
+		default:
+			return Target{}, fmt.Errorf("invalid target %q: unknown kind %q (expected xname, nid, mac, or group)", s, kind)
+		}
+	}
+	if t.Value == "" {
+		return Target{}, fmt.Errorf("invalid target %q: empty %s", s, t.Kind)
+	}
+	switch t.Kind {
+	case TargetNID:
+		if n, err := strconv.ParseInt(t.Value, 10, 32); err != nil || n < 1 {
+			return Target{}, fmt.Errorf("invalid target %q: NID must be a positive integer", s)
+		}
+	case TargetMAC:
+		mac, err := net.ParseMAC(t.Value)
+		if err != nil {
+			return Target{}, fmt.Errorf("invalid target %q: %w", s, err)
+		}
+		t.Value = mac.String()
+	}
+
+	return t, nil
+}
+
+// ParseTargets parses each of ss with ParseTarget.
+func ParseTargets(ss []string) ([]Target, error) {
+	targets := make([]Target, 0, len(ss))
+	for _, s := range ss {
+		t, err := ParseTarget(s)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, t)
+	}
+
+	return targets, nil
+}
+
+// TargetData is the SMD data targets are resolved against. Only the data for
+// the kinds of targets being resolved needs to be set: Components for NIDs,
+// EthernetInterfaces for MAC addresses, and Groups for groups.
+type TargetData struct {
+	Components         []Component
+	EthernetInterfaces []EthernetInterface
+	Groups             []Group
+}
+
+// ResolveTargets returns the xnames of the components identified by targets,
+// in order and without duplicates. It is an error for a NID, MAC address, or
+// group to not be found in data.
+func ResolveTargets(targets []Target, data TargetData) ([]string, error) {
+	var (
+		xnames []string
+		seen   = make(map[string]bool)
+	)
+	add := func(xs ...string) {
+		for _, x := range xs {
+			if !seen[strings.ToLower(x)] {
+				seen[strings.ToLower(x)] = true
+				xnames = append(xnames, x)
+			}
+		}
+	}
+	for _, t := range targets {
+		switch t.Kind {
+		case TargetXname:
+			add(t.Value)
+		case TargetNID:
+			nid, _ := strconv.ParseInt(t.Value, 10, 64)
+			found := false
+			for _, c := range data.Components {
+				if c.Type == "Node" && c.NID == nid {
+					add(c.ID)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("target %s: no node with NID %d in SMD", t, nid)
+			}
+		case TargetMAC:
+			found := false
+			for _, ei := range data.EthernetInterfaces {
+				mac, err := net.ParseMAC(ei.MACAddress)
+				if err != nil || mac.String() != t.Value {
+					continue
+				}
+				if ei.ComponentID == "" {
+					return nil, fmt.Errorf("target %s: ethernet interface %s is not associated with a component", t, ei.ID)
+				}
+				add(ei.ComponentID)
+				found = true
+				break
+			}
+			if !found {
+				return nil, fmt.Errorf("target %s: no ethernet interface with MAC address %s in SMD", t, t.Value)
+			}
+		case TargetGroup:
+			found := false
+			for _, g := range data.Groups {
+				if g.Label == t.Value {
+					add(g.Members.IDs...)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil, fmt.Errorf("target %s: no group %s in SMD", t, t.Value)
+			}
+		default:
+			return nil, fmt.Errorf("target %s: unknown kind %q", t, t.Kind)
+		}
+	}
+
+	return xnames, nil
+}
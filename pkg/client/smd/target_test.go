@@ -0,0 +1,79 @@
+package smd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    Target
+		wantErr bool
+	}{
+		{s: "x3000c0s0b0n0", want: Target{Kind: TargetXname, Value: "x3000c0s0b0n0"}},
+		{s: "xname:x3000c0s0b0", want: Target{Kind: TargetXname, Value: "x3000c0s0b0"}},
+		{s: "nid:42", want: Target{Kind: TargetNID, Value: "42"}},
+		{s: "mac:00:DE:AD:BE:EF:00", want: Target{Kind: TargetMAC, Value: "00:de:ad:be:ef:00"}},
+		{s: "00-de-ad-be-ef-00", want: Target{Kind: TargetMAC, Value: "00:de:ad:be:ef:00"}},
+		{s: "Group:compute", want: Target{Kind: TargetGroup, Value: "compute"}},
+		{s: "nid:0", wantErr: true},
+		{s: "nid:forty-two", wantErr: true},
+		{s: "mac:00:de:ad", wantErr: true},
+		{s: "group:", wantErr: true},
+		{s: "", wantErr: true},
+		{s: "host:node01", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseTarget(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTarget(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseTarget(%q) = %+v, want %+v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveTargets(t *testing.T) {
+	var compute Group
+	compute.Label = "compute"
+	compute.Members.IDs = []string{"x3000c0s0b0n0", "x3000c0s1b0n0"}
+	data := TargetData{
+		Components: []Component{
+			{ID: "x3000c0s0b0", Type: "NodeBMC"},
+			{ID: "x3000c0s0b0n0", Type: "Node", NID: 1},
+			{ID: "x3000c0s1b0n0", Type: "Node", NID: 2},
+			{ID: "x3000c0s2b0n0", Type: "Node", NID: 42},
+		},
+		EthernetInterfaces: []EthernetInterface{
+			{ID: "decafc0ffee0", ComponentID: "x3000c0s2b0n0", MACAddress: "DE:CA:FC:0F:FE:E0"},
+			{ID: "0040a6838e01", MACAddress: "00:40:a6:83:8e:01"},
+		},
+		Groups: []Group{compute},
+	}
+	mustParse := func(ss ...string) []Target {
+		targets, err := ParseTargets(ss)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return targets
+	}
+
+	got, err := ResolveTargets(mustParse("x3000c0s9b0n0", "group:compute", "nid:42", "mac:de:ca:fc:0f:fe:e0", "X3000C0S0B0N0", "nid:2"), data)
+	if err != nil {
+		t.Fatalf("ResolveTargets() error = %v", err)
+	}
+	want := []string{"x3000c0s9b0n0", "x3000c0s0b0n0", "x3000c0s1b0n0", "x3000c0s2b0n0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveTargets() = %v, want %v", got, want)
+	}
+
+	for _, s := range []string{"nid:7", "mac:00:11:22:33:44:55", "mac:00:40:a6:83:8e:01", "group:gpu"} {
+		if _, err := ResolveTargets(mustParse(s), data); err == nil {
+			t.Errorf("ResolveTargets(%s) succeeded, want error", s)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package smd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields of components that wait conditions can be on.
+const (
+	WaitFieldState   = "state"
+	WaitFieldFlag    = "flag"
+	WaitFieldEnabled = "enabled"
+	WaitFieldRole    = "role"
+)
+
+// WaitCondition is a condition on a field of a component (one of the WaitField
+// constants) having a value, e.g. state=Ready.
+type WaitCondition struct {
+	Field string
+	Value string
+}
+
+// ParseWaitCondition parses a wait condition of the form <field>=<value>, e.g.
+// state=Ready or flag=OK. Field names are case insensitive.
+func ParseWaitCondition(s string) (WaitCondition, error) {
+	field, value, ok := strings.Cut(s, "=")
+	field, value = strings.ToLower(strings.TrimSpace(field)), strings.TrimSpace(value)
+	if !ok || field == "" || value == "" {
+		return WaitCondition{}, fmt.Errorf("invalid condition %q: must be <field>=<value>", s)
+	}
+	switch field {
+	case WaitFieldState, WaitFieldFlag, WaitFieldRole:
+	case WaitFieldEnabled:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return WaitCondition{}, fmt.Errorf("invalid condition %q: enabled must be true or false", s)
+		}
+	default:
+		return WaitCondition{}, fmt.Errorf("invalid condition %q: unknown field %q (must be one of %s, %s, %s, %s)",
+			s, field, WaitFieldState, WaitFieldFlag, WaitFieldEnabled, WaitFieldRole)
+	}
+
+	return WaitCondition{Field: field, Value: value}, nil
+}
+
+// String returns the condition in the form ParseWaitCondition parses.
+func (wc WaitCondition) String() string {
+	return wc.Field + "=" + wc.Value
+}
+
+// Matches returns whether comp satisfies the condition. Values are compared
+// regardless of case, since SMD normalizes them.
+func (wc WaitCondition) Matches(comp Component) bool {
+	switch wc.Field {
+	case WaitFieldState:
+		return strings.EqualFold(comp.State, wc.Value)
+	case WaitFieldFlag:
+		return strings.EqualFold(comp.Flag, wc.Value)
+	case WaitFieldRole:
+		return strings.EqualFold(comp.Role, wc.Value)
+	case WaitFieldEnabled:
+		want, _ := strconv.ParseBool(wc.Value)
+		return comp.Enabled == want
+	}
+
+	return false
+}
+
+// WaitResult is the outcome of waiting for a component. ElapsedSeconds is how
+// long after the wait started the component was first seen satisfying the
+// conditions, and State and Flag are its last known state and flag. Found is
+// false if SMD never returned the component. Location, if set, is its physical
+// location.
+type WaitResult struct {
+	Xname          string  `json:"xname" yaml:"xname"`
+	Reached        bool    `json:"reached" yaml:"reached"`
+	ElapsedSeconds float64 `json:"elapsed_seconds,omitempty" yaml:"elapsed_seconds,omitempty"`
+	State          string  `json:"state,omitempty" yaml:"state,omitempty"`
+	Flag           string  `json:"flag,omitempty" yaml:"flag,omitempty"`
+	Found          bool    `json:"found" yaml:"found"`
+	Location       string  `json:"location,omitempty" yaml:"location,omitempty"`
+}
+
+// Waiter tracks which of a set of components have satisfied all of a set of
+// conditions since a start time. A component that satisfied them once is
+// counted as having reached them even if it later stops satisfying them, e.g.
+// a node that became Ready and then went Standby.
+type Waiter struct {
+	conditions []WaitCondition
+	start      time.Time
+	results    []WaitResult
+	index      map[string]int
+}
+
+// NewWaiter returns a Waiter for components xnames satisfying all conditions,
+// measuring elapsed times from start. Duplicate xnames (regardless of case) are
+// only tracked once.
+func NewWaiter(xnames []string, conditions []WaitCondition, start time.Time) *Waiter {
+	w := &Waiter{conditions: conditions, start: start, index: make(map[string]int)}
+	for _, x := range xnames {
+		key := strings.ToLower(x)
+		if _, ok := w.index[key]; ok {
+			continue
+		}
+		w.index[key] = len(w.results)
+		w.results = append(w.results, WaitResult{Xname: x})
+	}
+
+	return w
+}
+
+// Update records the components comps as seen at now and returns the xnames of
+// the components that reached the conditions for the first time. Components
+// that are not being waited for are ignored.
+func (w *Waiter) Update(comps []Component, now time.Time) []string {
+	var reached []string
+	for _, comp := range comps {
+		i, ok := w.index[strings.ToLower(comp.ID)]
+		if !ok {
+			continue
+		}
+		r := &w.results[i]
+		r.Found, r.State, r.Flag = true, comp.State, comp.Flag
+		if r.Reached || !w.satisfied(comp) {
+			continue
+		}
+		r.Reached = true
+		r.ElapsedSeconds = now.Sub(w.start).Round(time.Millisecond).Seconds()
+		reached = append(reached, r.Xname)
+	}
+
+	return reached
+}
+
+// satisfied returns whether comp satisfies all of the conditions.
+func (w *Waiter) satisfied(comp Component) bool {
+	for _, wc := range w.conditions {
+		if !wc.Matches(comp) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Pending returns the xnames of the components that have not reached the
+// conditions yet, in the order they were passed to NewWaiter.
+func (w *Waiter) Pending() []string {
+	var pending []string
+	for _, r := range w.results {
+		if !r.Reached {
+			pending = append(pending, r.Xname)
+		}
+	}
+
+	return pending
+}
+
+// Done returns whether waiting is done: if any is true, once any component
+// reached the conditions, otherwise once all of them did.
+func (w *Waiter) Done(any bool) bool {
+	pending := len(w.Pending())
+	if any {
+		return pending < len(w.results)
+	}
+
+	return pending == 0
+}
+
+// Results returns the outcome for each component, in the order they were
+// passed to NewWaiter.
+func (w *Waiter) Results() []WaitResult {
+	return append([]WaitResult(nil), w.results...)
+}
@@ -0,0 +1,98 @@
+package smd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseWaitCondition(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    WaitCondition
+		wantErr bool
+	}{
+		{s: "state=Ready", want: WaitCondition{Field: WaitFieldState, Value: "Ready"}},
+		{s: "Flag = OK", want: WaitCondition{Field: WaitFieldFlag, Value: "OK"}},
+		{s: "enabled=false", want: WaitCondition{Field: WaitFieldEnabled, Value: "false"}},
+		{s: "role=Compute", want: WaitCondition{Field: WaitFieldRole, Value: "Compute"}},
+		{s: "enabled=maybe", wantErr: true},
+		{s: "arch=X86", wantErr: true},
+		{s: "Ready", wantErr: true},
+		{s: "state=", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseWaitCondition(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWaitCondition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseWaitCondition() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitCondition_Matches(t *testing.T) {
+	comp := Component{ID: "x1000c0s0b0n0", State: "Ready", Flag: "OK", Enabled: true, Role: "Compute"}
+	tests := []struct {
+		wc   WaitCondition
+		want bool
+	}{
+		{WaitCondition{Field: WaitFieldState, Value: "ready"}, true},
+		{WaitCondition{Field: WaitFieldState, Value: "On"}, false},
+		{WaitCondition{Field: WaitFieldFlag, Value: "OK"}, true},
+		{WaitCondition{Field: WaitFieldFlag, Value: "Alert"}, false},
+		{WaitCondition{Field: WaitFieldEnabled, Value: "true"}, true},
+		{WaitCondition{Field: WaitFieldEnabled, Value: "false"}, false},
+		{WaitCondition{Field: WaitFieldRole, Value: "compute"}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.wc.Matches(comp); got != tt.want {
+			t.Errorf("%s.Matches() = %v, want %v", tt.wc, got, tt.want)
+		}
+	}
+}
+
+func TestWaiter(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	conds := []WaitCondition{{Field: WaitFieldState, Value: "Ready"}, {Field: WaitFieldFlag, Value: "OK"}}
+	w := NewWaiter([]string{"x1000c0s0b0n0", "x1000c0s1b0n0", "X1000C0S0B0N0", "x1000c0s2b0n0"}, conds, start)
+	if w.Done(true) || w.Done(false) {
+		t.Fatalf("Done() before any update")
+	}
+
+	reached := w.Update([]Component{
+		{ID: "x1000c0s0b0n0", State: "Ready", Flag: "OK"},
+		{ID: "x1000c0s1b0n0", State: "Ready", Flag: "Warning"},
+		{ID: "x9000c0s0b0n0", State: "Ready", Flag: "OK"},
+	}, start.Add(1500*time.Millisecond))
+	if !reflect.DeepEqual(reached, []string{"x1000c0s0b0n0"}) {
+		t.Errorf("first Update() = %v, want [x1000c0s0b0n0]", reached)
+	}
+	if !w.Done(true) || w.Done(false) {
+		t.Errorf("Done() after first update is wrong")
+	}
+
+	// A component that reached the conditions stays reached
+	reached = w.Update([]Component{
+		{ID: "x1000c0s0b0n0", State: "Standby", Flag: "OK"},
+		{ID: "x1000c0s1b0n0", State: "Ready", Flag: "OK"},
+	}, start.Add(3*time.Second))
+	if !reflect.DeepEqual(reached, []string{"x1000c0s1b0n0"}) {
+		t.Errorf("second Update() = %v, want [x1000c0s1b0n0]", reached)
+	}
+	if got := w.Pending(); !reflect.DeepEqual(got, []string{"x1000c0s2b0n0"}) {
+		t.Errorf("Pending() = %v, want [x1000c0s2b0n0]", got)
+	}
+
+	want := []WaitResult{
+		{Xname: "x1000c0s0b0n0", Reached: true, ElapsedSeconds: 1.5, State: "Standby", Flag: "OK", Found: true},
+		{Xname: "x1000c0s1b0n0", Reached: true, ElapsedSeconds: 3, State: "Ready", Flag: "OK", Found: true},
+		{Xname: "x1000c0s2b0n0"},
+	}
+	if got := w.Results(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Results() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,333 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/OpenCHAMI/ochami/internal/log"
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// DefaultMaxMemoryBuffer is the size in bytes above which response bodies read
+// by GetDataStream are spilled to a temporary file if the client's
+// MaxMemoryBuffer is not set.
+const DefaultMaxMemoryBuffer = 128 << 20
+
+// streamDepth is how deeply FormatStream streams nested JSON arrays. Values
+// nested deeper, such as each component of {"Components":[...]}, are decoded
+// and formatted one at a time.
+const streamDepth = 2
+
+// StreamBody is a response body read by GetDataStream. Bodies up to a size
+// limit are held in memory and larger ones are spilled to a temporary file, so
+// that huge responses (e.g. a dump of the full state of a large system) can be
+// processed without holding them in memory. Close must be called to remove the
+// temporary file.
+type StreamBody struct {
+	data []byte
+	file *os.File
+	size int64
+}
+
+// NewStreamBody reads r into a StreamBody, holding it in memory if it is at
+// most limit bytes and spilling it to a temporary file otherwise. A limit of 0
+// or less holds it in memory regardless of size.
+func NewStreamBody(r io.Reader, limit int64) (*StreamBody, error) {
+	if limit <= 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("could not read HTTP body: %w", err)
+		}
+		return &StreamBody{data: data, size: int64(len(data))}, nil
+	}
+
+	// Only read one byte past the limit into memory to find out whether
+	// the body must be spilled
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not read HTTP body: %w", err)
+	}
+	if int64(len(data)) <= limit {
+		return &StreamBody{data: data, size: int64(len(data))}, nil
+	}
+	f, err := os.CreateTemp("", "ochami-response-*.json")
+	if err != nil {
+		return nil, fmt.Errorf("could not create file to spill HTTP body to: %w", err)
+	}
+	b := &StreamBody{file: f}
+	n, err := io.Copy(f, io.MultiReader(bytes.NewReader(data), r))
+	b.size = n
+	if err != nil {
+		b.Close()
+		return nil, fmt.Errorf("could not spill HTTP body to %s: %w", f.Name(), err)
+	}
+
+	return b, nil
+}
+
+// Size returns the size of the body in bytes.
+func (b *StreamBody) Size() int64 {
+	return b.size
+}
+
+// Spilled returns whether the body was spilled to a temporary file.
+func (b *StreamBody) Spilled() bool {
+	return b.file != nil
+}
+
+// Reader returns a reader of the body from its start. Reading from a reader
+// returned by a previous call is not valid after Reader is called again.
+func (b *StreamBody) Reader() (io.Reader, error) {
+	if b.file == nil {
+		return bytes.NewReader(b.data), nil
+	}
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("could not rewind %s: %w", b.file.Name(), err)
+	}
+
+	return bufio.NewReader(b.file), nil
+}
+
+// Bytes returns the whole body, reading it into memory if it was spilled.
+func (b *StreamBody) Bytes() ([]byte, error) {
+	if b.file == nil {
+		return b.data, nil
+	}
+	r, err := b.Reader()
+	if err != nil {
+		return nil, err
+	}
+
+	return io.ReadAll(r)
+}
+
+// Close removes the temporary file the body was spilled to, if any.
+func (b *StreamBody) Close() error {
+	if b.file == nil {
+		return nil
+	}
+	name := b.file.Name()
+	b.file.Close()
+	b.file = nil
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("could not remove %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// GetDataStream is like GetData, but reads the body of a successful response
+// into a StreamBody that is spilled to a temporary file if it is larger than
+// oc.MaxMemoryBuffer (DefaultMaxMemoryBuffer if not set). The returned
+// HTTPEnvelope has no Body unless the response was unsuccessful (since error
+// responses are small), in which case the StreamBody is nil. The caller must
+// Close the StreamBody.
+func (oc *OchamiClient) GetDataStream(endpoint, query string, headers *HTTPHeaders) (HTTPEnvelope, *StreamBody, error) {
+	var he HTTPEnvelope
+
+	res, err := oc.MakeOchamiRequest(http.MethodGet, endpoint, query, headers, nil)
+	if err != nil {
+		return he, nil, fmt.Errorf("error making GET request to %s: %w", oc.ServiceName, err)
+	}
+	if res == nil {
+		return he, nil, fmt.Errorf("%s GET response was empty", oc.ServiceName)
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		he, err := NewHTTPEnvelopeFromResponse(res)
+		if err != nil {
+			return he, nil, fmt.Errorf("could not create HTTP envelope from GET response: %w", err)
+		}
+		return he, nil, he.CheckResponse()
+	}
+
+	he = HTTPEnvelope{
+		Status:     res.Status,
+		StatusCode: res.StatusCode,
+		Proto:      res.Proto,
+		Headers:    &HTTPHeaders{},
+	}
+	for key, vals := range res.Header {
+		(*he.Headers)[http.CanonicalHeaderKey(key)] = vals
+	}
+	body, err := NewStreamBody(res.Body, oc.maxMemoryBuffer())
+	res.Body.Close()
+	if err != nil {
+		return he, nil, fmt.Errorf("could not read body of GET response: %w", err)
+	}
+	if body.Spilled() {
+		log.Logger.Debug().Msgf("spilled %d-byte %s response body to temporary file", body.Size(), oc.ServiceName)
+	}
+
+	return he, body, he.CheckResponse()
+}
+
+// maxMemoryBuffer returns oc.MaxMemoryBuffer, or DefaultMaxMemoryBuffer if it
+// is not set.
+func (oc *OchamiClient) maxMemoryBuffer() int64 {
+	if oc.MaxMemoryBuffer > 0 {
+		return oc.MaxMemoryBuffer
+	}
+
+	return DefaultMaxMemoryBuffer
+}
+
+// FormatStream is like FormatBody, but reads the JSON body from r and writes it
+// to w in the format specified. For JSON formats, the body is decoded as a
+// stream: the elements of arrays at the top two levels (e.g. each component of
+// {"Components":[...]}) are decoded and written one at a time, so that only one
+// of them is held in memory at once. Unlike FormatBody, the members of a
+// top-level object are written in the order they were received rather than
+// sorted by key. Other formats require the whole body to be held in memory.
+func FormatStream(w io.Writer, r io.Reader, outFormat format.DataFormat) error {
+	switch outFormat {
+	case format.DataFormatJson, format.DataFormatJsonPretty:
+		bw := bufio.NewWriter(w)
+		dec := json.NewDecoder(r)
+		if err := streamJSON(dec, bw, outFormat == format.DataFormatJsonPretty, 0); err != nil {
+			return fmt.Errorf("failed to format HTTP body: %w", err)
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			return fmt.Errorf("failed to format HTTP body: unexpected data after JSON value")
+		}
+		return bw.Flush()
+	default:
+		body, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("failed to read HTTP body: %w", err)
+		}
+		out, err := FormatBody(body, outFormat)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	}
+}
+
+// streamJSON writes the next JSON value of dec to w, indenting it for a depth
+// of depth if pretty is true. Arrays are streamed up to a depth of streamDepth,
+// as is the top-level object, and other values are decoded and marshalled
+// whole.
+func streamJSON(dec *json.Decoder, w *bufio.Writer, pretty bool, depth int) error {
+	indent := func(d int) string {
+		if !pretty {
+			return ""
+		}
+		return "\n" + strings.Repeat("  ", d)
+	}
+	if depth >= streamDepth {
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return err
+		}
+		return writeJSON(w, v, pretty, depth)
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		b, err := json.Marshal(tok)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(b)
+		return err
+	}
+	if delim == '{' && depth > 0 {
+		// Decode nested objects whole so that their members are sorted
+		// by key, as json.Marshal does
+		m := make(map[string]any)
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			s, ok := key.(string)
+			if !ok {
+				return fmt.Errorf("invalid object key %v", key)
+			}
+			var v any
+			if err := dec.Decode(&v); err != nil {
+				return err
+			}
+			m[s] = v
+		}
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		return writeJSON(w, m, pretty, depth)
+	}
+	end, sep := "]", ""
+	if delim == '{' {
+		end, sep = "}", ":"
+		if pretty {
+			sep = ": "
+		}
+	}
+	w.WriteString(string(delim))
+	n := 0
+	for dec.More() {
+		if n > 0 {
+			w.WriteString(",")
+		}
+		w.WriteString(indent(depth + 1))
+		if delim == '{' {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			s, ok := key.(string)
+			if !ok {
+				return fmt.Errorf("invalid object key %v", key)
+			}
+			b, err := json.Marshal(s)
+			if err != nil {
+				return err
+			}
+			w.Write(b)
+			w.WriteString(sep)
+		}
+		if err := streamJSON(dec, w, pretty, depth+1); err != nil {
+			return err
+		}
+		n++
+	}
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	if n > 0 {
+		w.WriteString(indent(depth))
+	}
+	_, err = w.WriteString(end)
+
+	return err
+}
+
+// writeJSON marshals v to w, indenting it for a depth of depth if pretty is
+// true.
+func writeJSON(w *bufio.Writer, v any, pretty bool, depth int) error {
+	var (
+		b   []byte
+		err error
+	)
+	if pretty {
+		b, err = json.MarshalIndent(v, strings.Repeat("  ", depth), "  ")
+	} else {
+		b, err = json.Marshal(v)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+
+	return err
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+func TestNewStreamBody(t *testing.T) {
+	body := `{"Components":[{"ID":"x3000c0s0b0n0"},{"ID":"x3000c0s1b0n0"}]}`
+	tests := []struct {
+		name        string
+		limit       int64
+		wantSpilled bool
+	}{
+		{name: "no limit", limit: 0, wantSpilled: false},
+		{name: "under limit", limit: int64(len(body)), wantSpilled: false},
+		{name: "over limit", limit: 10, wantSpilled: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := NewStreamBody(strings.NewReader(body), tt.limit)
+			if err != nil {
+				t.Fatalf("NewStreamBody() returned error: %v", err)
+			}
+			if b.Spilled() != tt.wantSpilled {
+				t.Errorf("Spilled() = %v, want %v", b.Spilled(), tt.wantSpilled)
+			}
+			if b.Size() != int64(len(body)) {
+				t.Errorf("Size() = %d, want %d", b.Size(), len(body))
+			}
+			var name string
+			if b.Spilled() {
+				name = b.file.Name()
+			}
+			// Read twice to check that the body is rewound
+			for i := 0; i < 2; i++ {
+				r, err := b.Reader()
+				if err != nil {
+					t.Fatalf("Reader() returned error: %v", err)
+				}
+				got, _ := io.ReadAll(r)
+				if string(got) != body {
+					t.Errorf("read %q, want %q", got, body)
+				}
+			}
+			if err := b.Close(); err != nil {
+				t.Errorf("Close() returned error: %v", err)
+			}
+			if name != "" {
+				if _, err := os.Stat(name); !os.IsNotExist(err) {
+					t.Errorf("spill file %s not removed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatStream(t *testing.T) {
+	bodies := []string{
+		`{"Components":[{"Type":"Node","ID":"x3000c0s0b0n0","NID":1},{"ID":"x3000c0s1b0n0","Flags":["a","<b>"]}]}`,
+		`[{"b":{"c":[1,2,{"d":null}]},"a":true},{"e":"f"}]`,
+		`{"Components":[]}`,
+		`[]`,
+		`{}`,
+		`"x3000c0s0b0n0"`,
+		`42`,
+	}
+	for _, body := range bodies {
+		for _, f := range []format.DataFormat{format.DataFormatJson, format.DataFormatJsonPretty, format.DataFormatYaml} {
+			t.Run(f.String()+" "+body, func(t *testing.T) {
+				want, err := FormatBody(HTTPBody(body), f)
+				if err != nil {
+					t.Fatalf("FormatBody() returned error: %v", err)
+				}
+				var got bytes.Buffer
+				if err := FormatStream(&got, strings.NewReader(body), f); err != nil {
+					t.Fatalf("FormatStream() returned error: %v", err)
+				}
+				if got.String() != string(want) {
+					t.Errorf("FormatStream() = %s, want %s", got.String(), want)
+				}
+			})
+		}
+	}
+}
+
+func TestFormatStream_KeyOrder(t *testing.T) {
+	// Members of the top-level object are written in the order received
+	var got bytes.Buffer
+	if err := FormatStream(&got, strings.NewReader(`{"b":1,"a":{"d":2,"c":3}}`), format.DataFormatJson); err != nil {
+		t.Fatalf("FormatStream() returned error: %v", err)
+	}
+	if want := `{"b":1,"a":{"c":3,"d":2}}`; got.String() != want {
+		t.Errorf("FormatStream() = %s, want %s", got.String(), want)
+	}
+}
+
+func TestFormatStream_Invalid(t *testing.T) {
+	for _, body := range []string{`{"Components":[`, `[1,]`, `{} {}`, ``} {
+		if err := FormatStream(io.Discard, strings.NewReader(body), format.DataFormatJson); err == nil {
+			t.Errorf("FormatStream(%q) succeeded, want error", body)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/OpenCHAMI/ochami/pkg/client"
+)
+
+const serviceNameVault = "Vault"
+
+// VaultClient is an OchamiClient that is configured to read and write secrets
+// with the HashiCorp Vault HTTP API.
+type VaultClient struct {
+	*client.OchamiClient
+}
+
+// kvV2Response represents the parts of the response of the Vault KV version 2
+// read endpoint that are used.
+type kvV2Response struct {
+	Data struct {
+		Data map[string]any `json:"data"`
+	} `json:"data"`
+}
+
+// NewClient takes a baseURI (the root URI of Vault, e.g.
+// https://vault.example.com:8200) and returns a pointer to a new VaultClient.
+// If an error occurred creating the embedded OchamiClient, it is returned. If
+// insecure is true, TLS certificates will not be verified.
+func NewClient(baseURI string, insecure bool) (*VaultClient, error) {
+	oc, err := client.NewOchamiClient(serviceNameVault, baseURI, insecure)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OchamiClient for %s: %w", serviceNameVault, err)
+	}
+	vc := &VaultClient{
+		OchamiClient: oc,
+	}
+
+	return vc, err
+}
+
+// GetKVSecret is a wrapper function around OchamiClient.GetData that reads the
+// secret at path in the KV version 2 secrets engine mounted at mount, using
+// token as the Vault token, and returns its key/value pairs.
+func (vc *VaultClient) GetKVSecret(mount, path, token string) (map[string]any, error) {
+	endpoint, err := url.JoinPath("/v1", mount, "data", path)
+	if err != nil {
+		return nil, fmt.Errorf("GetKVSecret(): failed to join secret path: %w", err)
+	}
+	headers := client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.Add("X-Vault-Token", token); err != nil {
+			return nil, fmt.Errorf("GetKVSecret(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	henv, err := vc.GetData(endpoint, "", headers)
+	if err != nil {
+		return nil, fmt.Errorf("GetKVSecret(): failed to get secret %s/%s from Vault: %w", mount, path, err)
+	}
+	var res kvV2Response
+	if err := json.Unmarshal(henv.Body, &res); err != nil {
+		return nil, fmt.Errorf("GetKVSecret(): failed to unmarshal secret %s/%s: %w", mount, path, err)
+	}
+
+	return res.Data.Data, nil
+}
+
+// PutKVSecret is a wrapper function around OchamiClient.PostData that writes
+// data as the secret at path in the KV version 2 secrets engine mounted at
+// mount, using token as the Vault token. This creates a new version of the
+// secret if it exists.
+func (vc *VaultClient) PutKVSecret(mount, path, token string, data map[string]any) error {
+	endpoint, err := url.JoinPath("/v1", mount, "data", path)
+	if err != nil {
+		return fmt.Errorf("PutKVSecret(): failed to join secret path: %w", err)
+	}
+	headers := client.NewHTTPHeaders()
+	if token != "" {
+		if err := headers.Add("X-Vault-Token", token); err != nil {
+			return fmt.Errorf("PutKVSecret(): error setting token in HTTP headers: %w", err)
+		}
+	}
+	body, err := json.Marshal(map[string]any{"data": data})
+	if err != nil {
+		return fmt.Errorf("PutKVSecret(): failed to marshal secret %s/%s: %w", mount, path, err)
+	}
+	if _, err := vc.PostData(endpoint, "", headers, body); err != nil {
+		return fmt.Errorf("PutKVSecret(): failed to write secret %s/%s to Vault: %w", mount, path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package vault
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/OpenCHAMI/ochami/pkg/client"
+)
+
+func TestPutKVSecret_ReadOnly(t *testing.T) {
+	var writes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writes.Add(1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"data": {"username": "root"}}}`))
+	}))
+	defer srv.Close()
+
+	vc, err := NewClient(srv.URL, false)
+	if err != nil {
+		t.Fatalf("NewClient() failed: %v", err)
+	}
+	vc.ReadOnly = true
+
+	err = vc.PutKVSecret("secret", "bmc/x1000c0s0b0", "token", map[string]any{"username": "root"})
+	if !errors.Is(err, client.ReadOnlyError) {
+		t.Errorf("PutKVSecret() in read-only mode: expected ReadOnlyError, got %v", err)
+	}
+	if n := writes.Load(); n != 0 {
+		t.Errorf("PutKVSecret() in read-only mode sent %d request(s), want 0", n)
+	}
+
+	// Reading secrets is still allowed
+	data, err := vc.GetKVSecret("secret", "bmc/x1000c0s0b0", "token")
+	if err != nil {
+		t.Fatalf("GetKVSecret() in read-only mode failed: %v", err)
+	}
+	if data["username"] != "root" {
+		t.Errorf("GetKVSecret() = %v, want username root", data)
+	}
+}
@@ -0,0 +1,172 @@
+// Package diff produces line-based diffs of text in the unified format, as
+// printed by 'diff -u'.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change,
+// as with 'diff -u'.
+const DefaultContext = 3
+
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op is a line that is either in both texts, only in the first (deleted), or
+// only in the second (inserted).
+type op struct {
+	kind opKind
+	line string
+}
+
+// splitLines splits text into lines without their line endings. A final line
+// without a newline is still a line.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// edits returns the operations turning a into b, keeping the longest common
+// subsequence of lines.
+func edits(a, b []string) []op {
+	// Trim common prefix and suffix, which is most of the text for
+	// typical changes, to keep the table below small
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+
+	// lcs[i][j] is the length of the longest common subsequence of ma[i:]
+	// and mb[j:]
+	lcs := make([][]int, len(ma)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(mb)+1)
+	}
+	for i := len(ma) - 1; i >= 0; i-- {
+		for j := len(mb) - 1; j >= 0; j-- {
+			if ma[i] == mb[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	ops := make([]op, 0, len(a)+len(b))
+	for _, l := range a[:pre] {
+		ops = append(ops, op{opEqual, l})
+	}
+	i, j := 0, 0
+	for i < len(ma) || j < len(mb) {
+		switch {
+		case i < len(ma) && j < len(mb) && ma[i] == mb[j]:
+			ops = append(ops, op{opEqual, ma[i]})
+			i++
+			j++
+		case j == len(mb) || (i < len(ma) && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, op{opDelete, ma[i]})
+			i++
+		default:
+			ops = append(ops, op{opInsert, mb[j]})
+			j++
+		}
+	}
+	for _, l := range a[len(a)-suf:] {
+		ops = append(ops, op{opEqual, l})
+	}
+
+	return ops
+}
+
+// hunkRange formats the start and length of a hunk in one of the texts as in a
+// unified diff hunk header. start is the 0-based index of the first line.
+func hunkRange(start, length int) string {
+	switch length {
+	case 0:
+		// An empty range is given as the line before it
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, length)
+	}
+}
+
+// Unified returns the unified diff turning from into to, labelled fromName and
+// toName, showing context unchanged lines around each change. If the texts are
+// the same, "" is returned.
+func Unified(fromName, toName, from, to string, context int) string {
+	ops := edits(splitLines(from), splitLines(to))
+
+	var changes []int
+	for i, o := range ops {
+		if o.kind != opEqual {
+			changes = append(changes, i)
+		}
+	}
+	if len(changes) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+
+	// Line positions in from and to before each op
+	aPos := make([]int, len(ops)+1)
+	bPos := make([]int, len(ops)+1)
+	for i, o := range ops {
+		aPos[i+1], bPos[i+1] = aPos[i], bPos[i]
+		if o.kind != opInsert {
+			aPos[i+1]++
+		}
+		if o.kind != opDelete {
+			bPos[i+1]++
+		}
+	}
+
+	for c := 0; c < len(changes); {
+		// Extend the hunk while the next change is close enough for
+		// their context to overlap
+		last := c
+		for last+1 < len(changes) && changes[last+1]-changes[last] <= 2*context+1 {
+			last++
+		}
+		start := max(changes[c]-context, 0)
+		end := min(changes[last]+context+1, len(ops))
+
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(aPos[start], aPos[end]-aPos[start]), hunkRange(bPos[start], bPos[end]-bPos[start]))
+		for _, o := range ops[start:end] {
+			switch o.kind {
+			case opEqual:
+				sb.WriteString(" ")
+			case opDelete:
+				sb.WriteString("-")
+			case opInsert:
+				sb.WriteString("+")
+			}
+			sb.WriteString(o.line)
+			sb.WriteString("\n")
+		}
+		c = last + 1
+	}
+
+	return sb.String()
+}
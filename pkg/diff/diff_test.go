@@ -0,0 +1,50 @@
+package diff
+
+import "testing"
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     string
+	}{
+		{name: "same", from: "a\nb\n", to: "a\nb\n", want: ""},
+		{
+			name: "new file",
+			from: "",
+			to:   "a\nb\n",
+			want: "--- from\n+++ to\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "deleted file",
+			from: "a\n",
+			to:   "",
+			want: "--- from\n+++ to\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "change with context",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n",
+			to:   "1\n2\n3\n4\nfive\n6\n7\n8\n",
+			want: "--- from\n+++ to\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "separate hunks",
+			from: "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			to:   "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			want: "--- from\n+++ to\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+		{
+			name: "insertion",
+			from: "a\nc\n",
+			to:   "a\nb\nc\n",
+			want: "--- from\n+++ to\n@@ -1,2 +1,3 @@\n a\n+b\n c\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("from", "to", tt.from, tt.to, DefaultContext); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package discover
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/xname"
+)
+
+// AppendConflict is a node in a payload to append that conflicts with another
+// node in the payload or with data already in SMD.
+type AppendConflict struct {
+	Xname  string
+	Reason string
+}
+
+func (c AppendConflict) String() string {
+	return fmt.Sprintf("node %s: %s", c.Xname, c.Reason)
+}
+
+// AppendPlan is the result of comparing a payload of nodes to append with the
+// data in SMD (see PlanAppend). New are the nodes to add, Existing are those
+// already in SMD with the same NID, which are skipped, and Conflicts are the
+// reasons the payload cannot be appended. The plan must not be applied if
+// there are any conflicts.
+type AppendPlan struct {
+	New       NodeList
+	Existing  []Node
+	Conflicts []AppendConflict
+}
+
+// PlanAppend compares the nodes in nl with the components and redfish
+// endpoints in SMD to determine which nodes are new. Xnames and NIDs must be
+// unique across the payload and SMD, so the following are conflicts:
+//
+//   - an xname or NID used by more than one node in the payload
+//   - an xname in SMD that is not a node of the same type (Node or
+//     VirtualNode) or has a different NID
+//   - the NID of a new node being used by another component in SMD
+//   - the BMC of a new node (for virtual nodes, the virtual BMC of their
+//     hypervisor) already being in SMD, since appending only adds new redfish
+//     endpoints
+func PlanAppend(nl NodeList, comps []smd.Component, rfes []csm.RedfishEndpoint) AppendPlan {
+	plan := AppendPlan{New: NodeList{Version: nl.Version}}
+
+	smdComps := make(map[string]smd.Component, len(comps))
+	smdNIDs := make(map[int64]string)
+	for _, c := range comps {
+		smdComps[strings.ToLower(c.ID)] = c
+		if c.NID != 0 {
+			smdNIDs[c.NID] = c.ID
+		}
+	}
+	smdBMCs := make(map[string]bool, len(rfes))
+	for _, rfe := range rfes {
+		smdBMCs[strings.ToLower(rfe.ID)] = true
+	}
+
+	conflict := func(n Node, format string, a ...any) {
+		plan.Conflicts = append(plan.Conflicts, AppendConflict{Xname: n.Xname, Reason: fmt.Sprintf(format, a...)})
+	}
+	var (
+		xnames = make(map[string]bool)
+		nids   = make(map[int64]string)
+	)
+	for _, n := range nl.Nodes {
+		x := strings.ToLower(n.Xname)
+		if xnames[x] {
+			conflict(n, "xname is used more than once in payload")
+			continue
+		}
+		xnames[x] = true
+		if other, ok := nids[n.NID]; ok && n.NID != 0 {
+			conflict(n, "NID %d is also used by node %s in payload", n.NID, other)
+			continue
+		}
+		nids[n.NID] = n.Xname
+
+		if c, ok := smdComps[x]; ok {
+			switch {
+			case c.Type != n.ComponentType():
+				conflict(n, "xname is already in SMD as a %s", c.Type)
+			case c.NID != n.NID:
+				conflict(n, "already in SMD with NID %d instead of %d", c.NID, n.NID)
+			default:
+				plan.Existing = append(plan.Existing, n)
+			}
+			continue
+		}
+		if other, ok := smdNIDs[n.NID]; ok {
+			conflict(n, "NID %d is already used by %s in SMD", n.NID, other)
+			continue
+		}
+		if n.Virtual && n.Hypervisor == "" {
+			plan.New.Nodes = append(plan.New.Nodes, n)
+			continue
+		}
+		bmcXname, err := xname.NodeXnameToBMCXname(n.Xname)
+		if n.Virtual {
+			bmcXname = n.Hypervisor
+		} else if err != nil {
+			bmcXname = n.Xname
+		}
+		if smdBMCs[strings.ToLower(bmcXname)] {
+			conflict(n, "BMC %s is already in SMD, use 'ochami discover static --overwrite' to add nodes to existing BMCs", bmcXname)
+			continue
+		}
+		plan.New.Nodes = append(plan.New.Nodes, n)
+	}
+
+	return plan
+}
@@ -0,0 +1,89 @@
+package discover
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+func TestPlanAppend(t *testing.T) {
+	comps := []smd.Component{
+		{ID: "x1000c1s7b0n0", Type: "Node", NID: 1},
+		{ID: "x1000c1s7b1n0", Type: "Node", NID: 2},
+		{ID: "x1000c1s7b0", Type: "NodeBMC"},
+		{ID: "x1000c1s7b1", Type: "NodeBMC"},
+	}
+	rfes := []csm.RedfishEndpoint{{ID: "x1000c1s7b0"}, {ID: "x1000c1s7b1"}}
+	nl := NodeList{
+		Version: NodeListVersion,
+		Nodes: []Node{
+			{Xname: "x1000c1s7b0n0", NID: 1},  // Existing
+			{Xname: "x1000c1s7b1n0", NID: 5},  // Different NID in SMD
+			{Xname: "x1000c1s7b1", NID: 6},    // Not a node in SMD
+			{Xname: "x1000c1s7b0n1", NID: 7},  // Existing BMC
+			{Xname: "x1000c2s0b0n0", NID: 2},  // NID used in SMD
+			{Xname: "x1000c2s1b0n0", NID: 8},  // New
+			{Xname: "x1000c2s1b0n0", NID: 9},  // Duplicate xname
+			{Xname: "x1000c2s2b0n0", NID: 8},  // Duplicate NID
+			{Xname: "x1000c2s3b0n0", NID: 10}, // New
+		},
+	}
+
+	plan := PlanAppend(nl, comps, rfes)
+	wantNew := NodeList{Version: NodeListVersion, Nodes: []Node{nl.Nodes[5], nl.Nodes[8]}}
+	if !reflect.DeepEqual(plan.New, wantNew) {
+		t.Errorf("New = %v, want %v", plan.New, wantNew)
+	}
+	if !reflect.DeepEqual(plan.Existing, []Node{nl.Nodes[0]}) {
+		t.Errorf("Existing = %v, want %v", plan.Existing, nl.Nodes[:1])
+	}
+	wantConflicts := []string{
+		"node x1000c1s7b1n0: already in SMD with NID 2 instead of 5",
+		"node x1000c1s7b1: xname is already in SMD as a NodeBMC",
+		"node x1000c1s7b0n1: BMC x1000c1s7b0 is already in SMD, use 'ochami discover static --overwrite' to add nodes to existing BMCs",
+		"node x1000c2s0b0n0: NID 2 is already used by x1000c1s7b1n0 in SMD",
+		"node x1000c2s1b0n0: xname is used more than once in payload",
+		"node x1000c2s2b0n0: NID 8 is also used by node x1000c2s1b0n0 in payload",
+	}
+	var gotConflicts []string
+	for _, c := range plan.Conflicts {
+		gotConflicts = append(gotConflicts, c.String())
+	}
+	if !reflect.DeepEqual(gotConflicts, wantConflicts) {
+		t.Errorf("Conflicts =\n%q\nwant\n%q", gotConflicts, wantConflicts)
+	}
+}
+
+func TestPlanAppend_Virtual(t *testing.T) {
+	comps := []smd.Component{{ID: "x1000c1s7b0n0", Type: "Node", NID: 1}}
+	rfes := []csm.RedfishEndpoint{{ID: "x1000c1s7b0"}}
+	nl := NodeList{
+		Version: NodeListVersion,
+		Nodes: []Node{
+			{Xname: "x1000c1s7b0n0", NID: 1, Virtual: true},                            // Node in SMD
+			{Xname: "x1000c1s7b0n1", NID: 2, Virtual: true},                            // New, no BMC
+			{Xname: "x1000c2s0b0n0", NID: 3, Virtual: true, Hypervisor: "x1000c1s7b0"}, // Existing hypervisor
+			{Xname: "x1000c2s0b0n1", NID: 4, Virtual: true, Hypervisor: "x1000c2s0b1"}, // New
+		},
+	}
+
+	plan := PlanAppend(nl, comps, rfes)
+	wantNew := NodeList{Version: NodeListVersion, Nodes: []Node{nl.Nodes[1], nl.Nodes[3]}}
+	if !reflect.DeepEqual(plan.New, wantNew) {
+		t.Errorf("New = %v, want %v", plan.New, wantNew)
+	}
+	wantConflicts := []string{
+		"node x1000c1s7b0n0: xname is already in SMD as a Node",
+		"node x1000c2s0b0n0: BMC x1000c1s7b0 is already in SMD, use 'ochami discover static --overwrite' to add nodes to existing BMCs",
+	}
+	var gotConflicts []string
+	for _, c := range plan.Conflicts {
+		gotConflicts = append(gotConflicts, c.String())
+	}
+	if !reflect.DeepEqual(gotConflicts, wantConflicts) {
+		t.Errorf("Conflicts =\n%q\nwant\n%q", gotConflicts, wantConflicts)
+	}
+}
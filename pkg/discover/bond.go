@@ -0,0 +1,183 @@
+package discover
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// BondModeLACP is the default mode of a Bond, dynamic link aggregation (LACP).
+const BondModeLACP = "802.3ad"
+
+// BondModes are the bonding modes of Linux.
+var BondModes = []string{"balance-rr", "active-backup", "balance-xor", "broadcast", BondModeLACP, "balance-tlb", "balance-alb"}
+
+// bondPrimaryModes are the bonding modes in which the primary member is
+// preferred for sending, which are the only ones that accept a primary.
+var bondPrimaryModes = []string{"active-backup", "balance-tlb", "balance-alb"}
+
+// Bond represents a bonded interface of a node, aggregating the node's network
+// interfaces with the MAC addresses in Members. Members are separate from the
+// node's Ifaces. The bond uses the MAC address of its Primary member (the first
+// one by default), so its IP addresses are those of that member's interface in
+// SMD, and the other members have none.
+type Bond struct {
+	Name    string    `json:"name" yaml:"name" jsonschema:"required"`
+	Members []string  `json:"members" yaml:"members" jsonschema:"required"`
+	Primary string    `json:"primary,omitempty" yaml:"primary,omitempty"`
+	Mode    string    `json:"mode,omitempty" yaml:"mode,omitempty"`
+	IPAddrs []IfaceIP `json:"ip_addrs,omitempty" yaml:"ip_addrs,omitempty"`
+}
+
+func (b Bond) String() string {
+	bStr := fmt.Sprintf("name=%s members=[%s] primary=%s mode=%s ip_addrs=[", b.Name, strings.Join(b.Members, " "), b.Primary, b.Mode)
+	for idx, ip := range b.IPAddrs {
+		if idx == 0 {
+			bStr += fmt.Sprintf("ip%d={%s}", idx, ip)
+		} else {
+			bStr += fmt.Sprintf(" ip%d={%s}", idx, ip)
+		}
+	}
+	bStr += "]"
+
+	return bStr
+}
+
+// BondInterfaces returns the bonds of the node with their mode defaulted to
+// BondModeLACP and their primary to their first member. An error is returned if
+// a bond has no name or members, two bonds have the same name, a MAC address is
+// a member of more than one bond or is also one of the node's Ifaces, the
+// primary is not a member, or the mode is unknown.
+func (n Node) BondInterfaces() ([]Bond, error) {
+	if len(n.Bonds) == 0 {
+		return nil, nil
+	}
+
+	ifaceMACs := make(map[string]bool, len(n.Ifaces))
+	for _, iface := range n.Ifaces {
+		ifaceMACs[normalizeMAC(iface.MACAddr)] = true
+	}
+	var (
+		bonds   = make([]Bond, len(n.Bonds))
+		names   = make(map[string]bool)
+		members = make(map[string]string)
+	)
+	copy(bonds, n.Bonds)
+	for idx := range bonds {
+		b := &bonds[idx]
+		if b.Name == "" {
+			return nil, fmt.Errorf("bond %d has no name", idx)
+		}
+		if names[b.Name] {
+			return nil, fmt.Errorf("bond name %s is used more than once", b.Name)
+		}
+		names[b.Name] = true
+		if len(b.Members) == 0 {
+			return nil, fmt.Errorf("bond %s has no members", b.Name)
+		}
+		for _, m := range b.Members {
+			mac := normalizeMAC(m)
+			if other, ok := members[mac]; ok {
+				return nil, fmt.Errorf("bond %s: member %s is also a member of bond %s", b.Name, m, other)
+			}
+			if ifaceMACs[mac] {
+				return nil, fmt.Errorf("bond %s: member %s is also listed in interfaces", b.Name, m)
+			}
+			members[mac] = b.Name
+		}
+		if b.Mode == "" {
+			b.Mode = BondModeLACP
+		} else if !slices.Contains(BondModes, b.Mode) {
+			return nil, fmt.Errorf("bond %s has unknown mode %q (must be one of %s)", b.Name, b.Mode, strings.Join(BondModes, ", "))
+		}
+		if b.Primary == "" {
+			b.Primary = b.Members[0]
+		} else if members[normalizeMAC(b.Primary)] != b.Name {
+			return nil, fmt.Errorf("bond %s: primary %s is not a member", b.Name, b.Primary)
+		}
+	}
+
+	return bonds, nil
+}
+
+// NetworkConfig is cloud-init network configuration (version 2, the netplan
+// format).
+type NetworkConfig struct {
+	Network NetworkConfigV2 `json:"network" yaml:"network"`
+}
+
+// NetworkConfigV2 is the network key of NetworkConfig.
+type NetworkConfigV2 struct {
+	Version   int                        `json:"version" yaml:"version"`
+	Ethernets map[string]NetworkEthernet `json:"ethernets" yaml:"ethernets"`
+	Bonds     map[string]NetworkBond     `json:"bonds" yaml:"bonds"`
+}
+
+// NetworkEthernet is a physical interface in NetworkConfigV2, matched by MAC
+// address.
+type NetworkEthernet struct {
+	Match NetworkMatch `json:"match" yaml:"match"`
+}
+
+// NetworkMatch is how an interface in NetworkConfigV2 is matched.
+type NetworkMatch struct {
+	MACAddress string `json:"macaddress" yaml:"macaddress"`
+}
+
+// NetworkBond is a bond in NetworkConfigV2.
+type NetworkBond struct {
+	Interfaces []string          `json:"interfaces" yaml:"interfaces"`
+	MACAddress string            `json:"macaddress" yaml:"macaddress"`
+	DHCP4      bool              `json:"dhcp4" yaml:"dhcp4"`
+	Parameters NetworkBondParams `json:"parameters" yaml:"parameters"`
+}
+
+// NetworkBondParams are the parameters of a NetworkBond.
+type NetworkBondParams struct {
+	Mode               string `json:"mode" yaml:"mode"`
+	Primary            string `json:"primary,omitempty" yaml:"primary,omitempty"`
+	LACPRate           string `json:"lacp-rate,omitempty" yaml:"lacp-rate,omitempty"`
+	TransmitHashPolicy string `json:"transmit-hash-policy,omitempty" yaml:"transmit-hash-policy,omitempty"`
+	MIIMonitorInterval int    `json:"mii-monitor-interval" yaml:"mii-monitor-interval"`
+}
+
+// BondNetworkConfig returns the cloud-init network configuration that sets up
+// the bonds of node n, or nil if it has none. The members of each bond are
+// matched by MAC address and named after the bond (e.g. bond0m0), and the bond
+// takes the MAC address of its primary member so that it gets its IP address
+// from DHCP, which serves the IP addresses in SMD by MAC address. LACP bonds
+// use a fast LACP rate and hash by layer 3 and 4 headers.
+func BondNetworkConfig(n Node) (*NetworkConfig, error) {
+	bonds, err := n.BondInterfaces()
+	if err != nil || len(bonds) == 0 {
+		return nil, err
+	}
+
+	nc := &NetworkConfig{Network: NetworkConfigV2{
+		Version:   2,
+		Ethernets: make(map[string]NetworkEthernet),
+		Bonds:     make(map[string]NetworkBond),
+	}}
+	for _, b := range bonds {
+		nb := NetworkBond{
+			MACAddress: normalizeMAC(b.Primary),
+			DHCP4:      true,
+			Parameters: NetworkBondParams{Mode: b.Mode, MIIMonitorInterval: 100},
+		}
+		for idx, m := range b.Members {
+			name := fmt.Sprintf("%sm%d", b.Name, idx)
+			nc.Network.Ethernets[name] = NetworkEthernet{Match: NetworkMatch{MACAddress: normalizeMAC(m)}}
+			nb.Interfaces = append(nb.Interfaces, name)
+			if normalizeMAC(m) == normalizeMAC(b.Primary) && slices.Contains(bondPrimaryModes, b.Mode) {
+				nb.Parameters.Primary = name
+			}
+		}
+		if b.Mode == BondModeLACP {
+			nb.Parameters.LACPRate = "fast"
+			nb.Parameters.TransmitHashPolicy = "layer3+4"
+		}
+		nc.Network.Bonds[b.Name] = nb
+	}
+
+	return nc, nil
+}
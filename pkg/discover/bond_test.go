@@ -0,0 +1,181 @@
+package discover
+
+import (
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+func TestNode_BondInterfaces(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    Node
+		want    []Bond
+		wantErr bool
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "defaults",
+			node: Node{Bonds: []Bond{{Name: "bond0", Members: []string{"a", "b"}}}},
+			want: []Bond{{Name: "bond0", Members: []string{"a", "b"}, Primary: "a", Mode: BondModeLACP}},
+		},
+		{
+			name: "explicit primary and mode",
+			node: Node{Bonds: []Bond{{Name: "bond0", Members: []string{"de:ad:be:ee:ef:01", "de:ad:be:ee:ef:02"}, Primary: "DE-AD-BE-EE-EF-02", Mode: "active-backup"}}},
+			want: []Bond{{Name: "bond0", Members: []string{"de:ad:be:ee:ef:01", "de:ad:be:ee:ef:02"}, Primary: "DE-AD-BE-EE-EF-02", Mode: "active-backup"}},
+		},
+		{
+			name:    "no name",
+			node:    Node{Bonds: []Bond{{Members: []string{"a"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate name",
+			node:    Node{Bonds: []Bond{{Name: "bond0", Members: []string{"a"}}, {Name: "bond0", Members: []string{"b"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "no members",
+			node:    Node{Bonds: []Bond{{Name: "bond0"}}},
+			wantErr: true,
+		},
+		{
+			name:    "member of two bonds",
+			node:    Node{Bonds: []Bond{{Name: "bond0", Members: []string{"a"}}, {Name: "bond1", Members: []string{"A"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "member in interfaces",
+			node:    Node{Ifaces: []Iface{{MACAddr: "a"}}, Bonds: []Bond{{Name: "bond0", Members: []string{"a"}}}},
+			wantErr: true,
+		},
+		{
+			name:    "primary not a member",
+			node:    Node{Bonds: []Bond{{Name: "bond0", Members: []string{"a"}, Primary: "b"}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown mode",
+			node:    Node{Bonds: []Bond{{Name: "bond0", Members: []string{"a"}, Mode: "lacp"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.node.BondInterfaces()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BondInterfaces() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BondInterfaces() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscoveryInfoV2_Bonds(t *testing.T) {
+	nl := NodeList{
+		Nodes: []Node{
+			{
+				Name:   "nid1",
+				NID:    1,
+				Xname:  "x1000c0s0b0n0",
+				BMCMac: "de:ca:fc:0f:fe:e1",
+				Ifaces: []Iface{{MACAddr: "de:ad:be:ee:ef:01", IPAddrs: []IfaceIP{{Network: "hsn", IPAddr: "10.1.0.1"}}}},
+				Bonds: []Bond{{
+					Name:    "bond0",
+					Members: []string{"de:ad:be:ee:ef:02", "de:ad:be:ee:ef:03"},
+					Primary: "de:ad:be:ee:ef:03",
+					IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "172.16.100.1"}},
+				}},
+			},
+		},
+	}
+
+	_, rfes, ifaces, err := DiscoveryInfoV2("http://example.com", nl)
+	if err != nil {
+		t.Fatalf("DiscoveryInfoV2 returned error: %v", err)
+	}
+	want := []smd.EthernetInterface{
+		{ComponentID: "x1000c0s0b0n0", Type: ComponentTypeNode, Description: "Interface 0 for nid1", MACAddress: "de:ad:be:ee:ef:01", IPAddresses: []smd.EthernetIP{{IPAddress: "10.1.0.1", Network: "hsn"}}},
+		{ComponentID: "x1000c0s0b0n0", Type: ComponentTypeNode, Description: "Member 0 of bond bond0 for nid1", MACAddress: "de:ad:be:ee:ef:02"},
+		{ComponentID: "x1000c0s0b0n0", Type: ComponentTypeNode, Description: "Member 1 of bond bond0 for nid1 [primary]", MACAddress: "de:ad:be:ee:ef:03", IPAddresses: []smd.EthernetIP{{IPAddress: "172.16.100.1", Network: "mgmt"}}},
+	}
+	if !reflect.DeepEqual(ifaces, want) {
+		t.Errorf("DiscoveryInfoV2 returned ethernet interfaces\n%+v\nwant\n%+v", ifaces, want)
+	}
+	sysIfaces := rfes.RedfishEndpoints[0].Systems[0].EthernetInterfaces
+	if len(sysIfaces) != 3 || sysIfaces[1].IP != "" || sysIfaces[2].IP != "172.16.100.1" {
+		t.Errorf("System has ethernet interfaces %+v", sysIfaces)
+	}
+
+	nl.Nodes[0].Bonds[0].Primary = "ff:ff:ff:ff:ff:ff"
+	if _, _, _, err := DiscoveryInfoV2("http://example.com", nl); err == nil {
+		t.Error("DiscoveryInfoV2 with invalid bond succeeded, want error")
+	}
+}
+
+func TestBondNetworkConfig(t *testing.T) {
+	if nc, err := BondNetworkConfig(Node{}); nc != nil || err != nil {
+		t.Errorf("BondNetworkConfig() without bonds = %v, %v, want nil, nil", nc, err)
+	}
+
+	node := Node{Bonds: []Bond{
+		{Name: "bond0", Members: []string{"DE:AD:BE:EE:EF:02", "de:ad:be:ee:ef:03"}},
+		{Name: "bond1", Members: []string{"de:ad:be:ee:ef:04", "de:ad:be:ee:ef:05"}, Primary: "de:ad:be:ee:ef:05", Mode: "active-backup"},
+	}}
+	nc, err := BondNetworkConfig(node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := yaml.Marshal(nc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `network:
+    version: 2
+    ethernets:
+        bond0m0:
+            match:
+                macaddress: de:ad:be:ee:ef:02
+        bond0m1:
+            match:
+                macaddress: de:ad:be:ee:ef:03
+        bond1m0:
+            match:
+                macaddress: de:ad:be:ee:ef:04
+        bond1m1:
+            match:
+                macaddress: de:ad:be:ee:ef:05
+    bonds:
+        bond0:
+            interfaces:
+                - bond0m0
+                - bond0m1
+            macaddress: de:ad:be:ee:ef:02
+            dhcp4: true
+            parameters:
+                mode: 802.3ad
+                lacp-rate: fast
+                transmit-hash-policy: layer3+4
+                mii-monitor-interval: 100
+        bond1:
+            interfaces:
+                - bond1m0
+                - bond1m1
+            macaddress: de:ad:be:ee:ef:05
+            dhcp4: true
+            parameters:
+                mode: active-backup
+                primary: bond1m1
+                mii-monitor-interval: 100
+`
+	if string(got) != want {
+		t.Errorf("BondNetworkConfig() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,31 @@
+package discover
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// BMCCredentials returns the username and password of the BMC of n. If
+// BMCPasswordFile is set, the password is read from it, without trailing
+// newlines. It is an error to set both BMCPassword and BMCPasswordFile, or a
+// password without a username.
+func (n Node) BMCCredentials() (username, password string, err error) {
+	password = n.BMCPassword
+	if n.BMCPasswordFile != "" {
+		if n.BMCPassword != "" {
+			return "", "", errors.New("bmc_password and bmc_password_file are mutually exclusive")
+		}
+		b, err := os.ReadFile(n.BMCPasswordFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read BMC password: %w", err)
+		}
+		password = strings.TrimRight(string(b), "\r\n")
+	}
+	if password != "" && n.BMCUsername == "" {
+		return "", "", errors.New("BMC password set without bmc_username")
+	}
+
+	return n.BMCUsername, password, nil
+}
@@ -0,0 +1,61 @@
+package discover
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNodeBMCCredentials(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(file, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		node         Node
+		wantUser     string
+		wantPassword string
+		wantErr      bool
+	}{
+		{name: "none"},
+		{name: "inline", node: Node{BMCUsername: "root", BMCPassword: "pw"}, wantUser: "root", wantPassword: "pw"},
+		{name: "file", node: Node{BMCUsername: "root", BMCPasswordFile: file}, wantUser: "root", wantPassword: "s3cr3t"},
+		{name: "username only", node: Node{BMCUsername: "root"}, wantUser: "root"},
+		{name: "both passwords", node: Node{BMCUsername: "root", BMCPassword: "pw", BMCPasswordFile: file}, wantErr: true},
+		{name: "missing file", node: Node{BMCUsername: "root", BMCPasswordFile: file + "x"}, wantErr: true},
+		{name: "no username", node: Node{BMCPassword: "pw"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, password, err := tt.node.BMCCredentials()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BMCCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if user != tt.wantUser || password != tt.wantPassword {
+				t.Errorf("BMCCredentials() = %q, %q, want %q, %q", user, password, tt.wantUser, tt.wantPassword)
+			}
+		})
+	}
+}
+
+func TestDiscoveryInfoV2BMCCredentials(t *testing.T) {
+	nl := NodeList{Nodes: []Node{{
+		Xname:       "x3000c0s0b0n0",
+		NID:         1,
+		BMCMac:      "de:ca:fc:0f:ee:00",
+		BMCIP:       "172.16.0.100",
+		BMCUsername: "root",
+		BMCPassword: "pw",
+	}}}
+	_, rfes, _, err := DiscoveryInfoV2("https://smd.example.com", nl)
+	if err != nil {
+		t.Fatalf("DiscoveryInfoV2() error = %v", err)
+	}
+	if len(rfes.RedfishEndpoints) != 1 {
+		t.Fatalf("DiscoveryInfoV2() generated %d redfish endpoints, want 1", len(rfes.RedfishEndpoints))
+	}
+	if rfe := rfes.RedfishEndpoints[0]; rfe.User != "root" || rfe.Password != "pw" {
+		t.Errorf("redfish endpoint credentials = %q, %q, want root, pw", rfe.User, rfe.Password)
+	}
+}
@@ -0,0 +1,263 @@
+package discover
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/OpenCHAMI/ochami/pkg/format"
+)
+
+// PayloadFormat is the format of a discovery payload: one of the data formats
+// of the format package, or PayloadFormatCSV.
+type PayloadFormat string
+
+// PayloadFormatCSV is the CSV format read by ParseNodeListCSV.
+const PayloadFormatCSV PayloadFormat = "csv"
+
+var (
+	// PayloadFormats are the supported discovery payload formats: the data
+	// formats of the format package, followed by PayloadFormatCSV.
+	PayloadFormats = func() []PayloadFormat {
+		var pfs []PayloadFormat
+		for _, df := range format.DataFormats {
+			pfs = append(pfs, PayloadFormat(df))
+		}
+		return append(pfs, PayloadFormatCSV)
+	}()
+
+	// PayloadFormatHelp describes each supported discovery payload format.
+	PayloadFormatHelp = func() map[string]string {
+		help := maps.Clone(format.DataFormatHelp)
+		help[string(PayloadFormatCSV)] = "CSV with a header row (see ParseNodeListCSV)"
+		return help
+	}()
+)
+
+// PayloadFormatList returns the supported discovery payload formats separated
+// by commas, as listed in the usage of flags taking one.
+func PayloadFormatList() string {
+	return format.DataFormatList() + "," + string(PayloadFormatCSV)
+}
+
+func (pf PayloadFormat) String() string {
+	return string(pf)
+}
+
+func (pf *PayloadFormat) Set(v string) error {
+	if !slices.Contains(PayloadFormats, PayloadFormat(v)) {
+		return fmt.Errorf("must be one of %v", PayloadFormats)
+	}
+	*pf = PayloadFormat(v)
+
+	return nil
+}
+
+func (pf PayloadFormat) Type() string {
+	return "PayloadFormat"
+}
+
+// csvListSep separates the values of a CSV cell that holds a list (groups, or
+// the IP addresses and networks of an interface).
+const csvListSep = ";"
+
+// csvIfaceColumn matches the interface columns of a CSV node list, capturing
+// the interface index and the field.
+var csvIfaceColumn = regexp.MustCompile(`^iface([0-9]+)_(mac|ip|network)$`)
+
+// ParseNodeListCSV reads a NodeList from CSV data in r, e.g. a node inventory
+// exported from a spreadsheet. The first record is a header naming the column
+// of each field, case-insensitively and in any order:
+//
+//	name, nid, xname, bmc_mac, bmc_ip, bmc_fqdn, virtual, hypervisor, type
+//		The node fields of the same names. Only xname is required.
+//	groups (or group)
+//		The groups of the node, separated by semicolons.
+//	power_actions
+//		The power actions supported by the BMC of the node, separated by
+//		semicolons.
+//	bmc_username, bmc_password, bmc_password_file
+//		The credentials of the BMC of the node.
+//	iface<N>_mac, iface<N>_ip, iface<N>_network
+//		The MAC address, IP addresses, and networks of interface N of the
+//		node (N starting from 0). Several IP addresses are separated by
+//		semicolons, with either one network for each or one for all.
+//
+// Lines starting with # are skipped, as are empty cells, so nodes can have
+// different numbers of interfaces. An error naming the line is returned for an
+// unknown column or an invalid value.
+func ParseNodeListCSV(r io.Reader) (NodeList, error) {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return NodeList{}, fmt.Errorf("CSV node list is empty")
+	} else if err != nil {
+		return NodeList{}, fmt.Errorf("failed to parse CSV node list: %w", err)
+	}
+	var (
+		cols   = make([]string, len(header))
+		seen   = make(map[string]bool)
+		nIface int
+	)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "group" {
+			name = "groups"
+		}
+		switch name {
+		case "name", "nid", "xname", "bmc_mac", "bmc_ip", "bmc_fqdn", "virtual", "hypervisor", "type", "groups", "power_actions", "bmc_username", "bmc_password", "bmc_password_file":
+		default:
+			m := csvIfaceColumn.FindStringSubmatch(name)
+			if m == nil {
+				return NodeList{}, fmt.Errorf("CSV node list header has unknown column %q", header[i])
+			}
+			idx, _ := strconv.Atoi(m[1])
+			nIface = max(nIface, idx+1)
+		}
+		if seen[name] {
+			return NodeList{}, fmt.Errorf("CSV node list header has column %q more than once", name)
+		}
+		seen[name] = true
+		cols[i] = name
+	}
+	if !seen["xname"] {
+		return NodeList{}, fmt.Errorf("CSV node list header missing %q column", "xname")
+	}
+
+	nl := NodeList{Version: NodeListVersion}
+	for {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return NodeList{}, fmt.Errorf("failed to parse CSV node list: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		node, err := csvNode(cols, rec, nIface)
+		if err != nil {
+			return NodeList{}, fmt.Errorf("line %d: %w", line, err)
+		}
+		nl.Nodes = append(nl.Nodes, node)
+	}
+
+	return nl, nil
+}
+
+// csvNode returns the node in the CSV record rec, whose fields are named by
+// cols, with up to nIface interfaces.
+func csvNode(cols, rec []string, nIface int) (Node, error) {
+	var (
+		node     Node
+		macs     = make([]string, nIface)
+		ips      = make([]string, nIface)
+		networks = make([]string, nIface)
+	)
+	for i, v := range rec {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if i >= len(cols) {
+			return Node{}, fmt.Errorf("record has more fields (%d) than the header (%d)", len(rec), len(cols))
+		}
+		switch cols[i] {
+		case "name":
+			node.Name = v
+		case "nid":
+			nid, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return Node{}, fmt.Errorf("invalid nid %q", v)
+			}
+			node.NID = nid
+		case "xname":
+			node.Xname = v
+		case "bmc_mac":
+			node.BMCMac = v
+		case "bmc_ip":
+			node.BMCIP = v
+		case "bmc_fqdn":
+			node.BMCFQDN = v
+		case "virtual":
+			virtual, err := strconv.ParseBool(v)
+			if err != nil {
+				return Node{}, fmt.Errorf("invalid virtual %q (must be true or false)", v)
+			}
+			node.Virtual = virtual
+		case "hypervisor":
+			node.Hypervisor = v
+		case "type":
+			node.Type = v
+		case "groups":
+			node.Groups = csvList(v)
+		case "power_actions":
+			node.PowerActions = csvList(v)
+		case "bmc_username":
+			node.BMCUsername = v
+		case "bmc_password":
+			node.BMCPassword = v
+		case "bmc_password_file":
+			node.BMCPasswordFile = v
+		default:
+			m := csvIfaceColumn.FindStringSubmatch(cols[i])
+			idx, _ := strconv.Atoi(m[1])
+			switch m[2] {
+			case "mac":
+				macs[idx] = v
+			case "ip":
+				ips[idx] = v
+			case "network":
+				networks[idx] = v
+			}
+		}
+	}
+	if node.Xname == "" {
+		return Node{}, fmt.Errorf("node has no xname")
+	}
+
+	for idx := range nIface {
+		if macs[idx] == "" {
+			if ips[idx] != "" || networks[idx] != "" {
+				return Node{}, fmt.Errorf("interface %d has IP addresses or networks but no MAC address", idx)
+			}
+			continue
+		}
+		addrs, nets := csvList(ips[idx]), csvList(networks[idx])
+		if len(nets) > 1 && len(nets) != len(addrs) {
+			return Node{}, fmt.Errorf("interface %d has %d networks for %d IP addresses", idx, len(nets), len(addrs))
+		}
+		iface := Iface{MACAddr: macs[idx]}
+		for j, addr := range addrs {
+			ip := IfaceIP{IPAddr: addr}
+			if len(nets) == 1 {
+				ip.Network = nets[0]
+			} else if len(nets) > 1 {
+				ip.Network = nets[j]
+			}
+			iface.IPAddrs = append(iface.IPAddrs, ip)
+		}
+		node.Ifaces = append(node.Ifaces, iface)
+	}
+
+	return node, nil
+}
+
+// csvList splits the list in the CSV cell v, leaving out empty values.
+func csvList(v string) []string {
+	var list []string
+	for _, s := range strings.Split(v, csvListSep) {
+		if s = strings.TrimSpace(s); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
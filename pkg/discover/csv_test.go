@@ -0,0 +1,111 @@
+package discover
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNodeListCSV(t *testing.T) {
+	data := `# Exported from the DC spreadsheet
+Name,NID,Xname,BMC_MAC,BMC_IP,Group,iface0_mac,iface0_ip,iface0_network,iface1_mac,iface1_ip,iface1_network
+node01,1,x1000c1s7b0n0,de:ca:fc:0f:ee:01,172.16.0.101,compute;slurm,de:ad:be:ee:ee:01,172.16.1.1,mgmt,02:00:00:91:31:01,192.168.0.1;192.168.1.1,hsn0;hsn1
+node02,2,x1000c1s7b1n0,de:ca:fc:0f:ee:02,172.16.0.102,,de:ad:be:ee:ee:02,172.16.1.2;10.15.3.2,mgmt,,,
+`
+	got, err := ParseNodeListCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseNodeListCSV() returned error: %v", err)
+	}
+	want := NodeList{
+		Version: NodeListVersion,
+		Nodes: []Node{
+			{
+				Name:   "node01",
+				NID:    1,
+				Xname:  "x1000c1s7b0n0",
+				BMCMac: "de:ca:fc:0f:ee:01",
+				BMCIP:  "172.16.0.101",
+				Groups: []string{"compute", "slurm"},
+				Ifaces: []Iface{
+					{MACAddr: "de:ad:be:ee:ee:01", IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "172.16.1.1"}}},
+					{MACAddr: "02:00:00:91:31:01", IPAddrs: []IfaceIP{{Network: "hsn0", IPAddr: "192.168.0.1"}, {Network: "hsn1", IPAddr: "192.168.1.1"}}},
+				},
+			},
+			{
+				Name:   "node02",
+				NID:    2,
+				Xname:  "x1000c1s7b1n0",
+				BMCMac: "de:ca:fc:0f:ee:02",
+				BMCIP:  "172.16.0.102",
+				Ifaces: []Iface{
+					{MACAddr: "de:ad:be:ee:ee:02", IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "172.16.1.2"}, {Network: "mgmt", IPAddr: "10.15.3.2"}}},
+				},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseNodeListCSV() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseNodeListCSV_MACOnly(t *testing.T) {
+	data := "xname,iface0_mac,iface0_ip\nx1000c1s7b0n0,de:ad:be:ee:ee:01,\n"
+	got, err := ParseNodeListCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseNodeListCSV() returned error: %v", err)
+	}
+	want := []Node{{Xname: "x1000c1s7b0n0", Ifaces: []Iface{{MACAddr: "de:ad:be:ee:ee:01"}}}}
+	if !reflect.DeepEqual(got.Nodes, want) {
+		t.Errorf("ParseNodeListCSV() nodes = %+v, want %+v", got.Nodes, want)
+	}
+}
+
+func TestParseNodeListCSV_Type(t *testing.T) {
+	data := "xname,type,bmc_mac\nx3000m0p0,CabinetPDU,de:ca:fc:0f:ee:01\n"
+	got, err := ParseNodeListCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseNodeListCSV() returned error: %v", err)
+	}
+	want := []Node{{Xname: "x3000m0p0", Type: ComponentTypeCabinetPDU, BMCMac: "de:ca:fc:0f:ee:01"}}
+	if !reflect.DeepEqual(got.Nodes, want) {
+		t.Errorf("ParseNodeListCSV() nodes = %+v, want %+v", got.Nodes, want)
+	}
+}
+
+func TestParseNodeListCSV_PowerActions(t *testing.T) {
+	data := "xname,power_actions\nx1000c1s7b0n0,On; ForceOff;GracefulShutdown\n"
+	got, err := ParseNodeListCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseNodeListCSV() returned error: %v", err)
+	}
+	want := []Node{{Xname: "x1000c1s7b0n0", PowerActions: []string{"On", "ForceOff", "GracefulShutdown"}}}
+	if !reflect.DeepEqual(got.Nodes, want) {
+		t.Errorf("ParseNodeListCSV() nodes = %+v, want %+v", got.Nodes, want)
+	}
+}
+
+func TestParseNodeListCSV_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{name: "empty", data: "", wantErr: "empty"},
+		{name: "unknown column", data: "xname,bmc_addr\n", wantErr: `unknown column "bmc_addr"`},
+		{name: "duplicate column", data: "xname,group,groups\n", wantErr: `column "groups" more than once`},
+		{name: "no xname column", data: "name,nid\nnode01,1\n", wantErr: `missing "xname"`},
+		{name: "no xname", data: "name,xname\nnode01,\n", wantErr: "line 2: node has no xname"},
+		{name: "invalid nid", data: "xname,nid\nx1000c1s7b0n0,one\n", wantErr: `line 2: invalid nid "one"`},
+		{name: "ip without mac", data: "xname,iface0_mac,iface0_ip\nx1000c1s7b0n0,,172.16.1.1\n", wantErr: "no MAC address"},
+		{name: "network count", data: "xname,iface0_mac,iface0_ip,iface0_network\nx1000c1s7b0n0,de:ad:be:ee:ee:01,172.16.1.1;10.15.3.1,a;b;c\n", wantErr: "3 networks for 2 IP addresses"},
+		{name: "extra field", data: "xname\nx1000c1s7b0n0,node01\n", wantErr: "more fields"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseNodeListCSV(strings.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseNodeListCSV() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package discover
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Types of the Components generated for devices other than nodes, set with the
+// type of an entry in a payload.
+const (
+	ComponentTypeChassisBMC           = "ChassisBMC"
+	ComponentTypeRouterBMC            = "RouterBMC"
+	ComponentTypeCabinetPDUController = "CabinetPDUController"
+	ComponentTypeCabinetPDU           = "CabinetPDU"
+	ComponentTypeMgmtSwitch           = "MgmtSwitch"
+)
+
+// deviceType describes a type of device other than a node. xname matches the
+// xnames of devices of the type. bmcType is the type of the RedfishEndpoint of
+// the BMC that manages them, whose xname bmcXname returns, or empty if they
+// have none.
+type deviceType struct {
+	xname    *regexp.Regexp
+	bmcType  string
+	bmcXname func(xname string) string
+}
+
+// deviceSelf is the bmcXname of devices that are their own BMC.
+func deviceSelf(xname string) string {
+	return xname
+}
+
+// deviceTypes are the devices other than nodes that can be described in a
+// payload, by their type.
+var deviceTypes = map[string]deviceType{
+	ComponentTypeChassisBMC: {
+		xname:    regexp.MustCompile(`^x[0-9]+c[0-9]+b[0-9]+$`),
+		bmcType:  ComponentTypeChassisBMC,
+		bmcXname: deviceSelf,
+	},
+	ComponentTypeRouterBMC: {
+		xname:    regexp.MustCompile(`^x[0-9]+c[0-9]+r[0-9]+b[0-9]+$`),
+		bmcType:  ComponentTypeRouterBMC,
+		bmcXname: deviceSelf,
+	},
+	ComponentTypeCabinetPDUController: {
+		xname:    regexp.MustCompile(`^x[0-9]+m[0-9]+$`),
+		bmcType:  ComponentTypeCabinetPDUController,
+		bmcXname: deviceSelf,
+	},
+	ComponentTypeCabinetPDU: {
+		xname:   regexp.MustCompile(`^x[0-9]+m[0-9]+p[0-9]+$`),
+		bmcType: ComponentTypeCabinetPDUController,
+		bmcXname: func(xname string) string {
+			return xname[:strings.LastIndex(xname, "p")]
+		},
+	},
+	ComponentTypeMgmtSwitch: {
+		xname: regexp.MustCompile(`^x[0-9]+c[0-9]+w[0-9]+$`),
+	},
+}
+
+// DeviceTypes returns the types that an entry in a payload can have, other
+// than ComponentTypeNode, which is the default.
+func DeviceTypes() []string {
+	types := make([]string, 0, len(deviceTypes))
+	for t := range deviceTypes {
+		types = append(types, t)
+	}
+	slices.Sort(types)
+
+	return types
+}
+
+// IsNode returns true if n is a node rather than another kind of device, i.e.
+// its Type is unset or ComponentTypeNode.
+func (n Node) IsNode() bool {
+	return n.Type == "" || n.Type == ComponentTypeNode
+}
+
+// device returns the deviceType of n, which is not a node. An error is
+// returned if its type is unknown or its xname does not match it.
+func (n Node) device() (deviceType, error) {
+	dt, ok := deviceTypes[n.Type]
+	if !ok {
+		return deviceType{}, fmt.Errorf("unknown type %q (must be %s or one of %v)", n.Type, ComponentTypeNode, DeviceTypes())
+	}
+	if !dt.xname.MatchString(strings.ToLower(n.Xname)) {
+		return deviceType{}, fmt.Errorf("xname %q is not a valid %s xname", n.Xname, n.Type)
+	}
+
+	return dt, nil
+}
@@ -0,0 +1,259 @@
+package discover
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// Kinds of the SMD records compared by Diff.
+const (
+	DiffKindComponent         = "Component"
+	DiffKindRedfishEndpoint   = "RedfishEndpoint"
+	DiffKindEthernetInterface = "EthernetInterface"
+)
+
+// Actions of a DiffEntry.
+const (
+	DiffAdd    = "add"
+	DiffChange = "change"
+	DiffRemove = "remove"
+)
+
+// FieldChange is a field of an SMD record whose value in SMD (From) differs
+// from the one generated from a payload (To).
+type FieldChange struct {
+	Field string `json:"field" yaml:"field"`
+	From  string `json:"from" yaml:"from"`
+	To    string `json:"to" yaml:"to"`
+}
+
+func (fc FieldChange) String() string {
+	return fmt.Sprintf("%s: %q -> %q", fc.Field, fc.From, fc.To)
+}
+
+// DiffEntry is an SMD record that would be added, changed, or removed to make
+// SMD match a payload. ID is the xname of components and redfish endpoints and
+// the ID that SMD gives ethernet interfaces (their MAC address in lower case
+// without separators).
+type DiffEntry struct {
+	Kind    string        `json:"kind" yaml:"kind"`
+	ID      string        `json:"id" yaml:"id"`
+	Action  string        `json:"action" yaml:"action"`
+	Changes []FieldChange `json:"changes,omitempty" yaml:"changes,omitempty"`
+}
+
+func (e DiffEntry) String() string {
+	s := fmt.Sprintf("%s %s %s", e.Action, e.Kind, e.ID)
+	for _, c := range e.Changes {
+		s += "\n  " + c.String()
+	}
+
+	return s
+}
+
+// DiffResult is the result of Diff, with entries ordered by kind (components,
+// redfish endpoints, then ethernet interfaces) and then by ID.
+type DiffResult struct {
+	Entries []DiffEntry `json:"entries" yaml:"entries"`
+}
+
+// Count returns the number of entries in r with action.
+func (r DiffResult) Count(action string) int {
+	var n int
+	for _, e := range r.Entries {
+		if e.Action == action {
+			n++
+		}
+	}
+
+	return n
+}
+
+// diffRecord is the fields of an SMD record that Diff compares, in order.
+type diffRecord struct {
+	id     string
+	fields [][2]string
+}
+
+// Diff compares the SMD records that would be generated from the nodes in nl
+// (see DiscoveryInfoV2) with the components, redfish endpoints, and ethernet
+// interfaces in SMD, and returns what would be added, changed, or removed so
+// that SMD matches the payload. Components are compared by xname (their type
+// and NID), redfish endpoints by xname (their FQDN, MAC address, and IP
+// address), and ethernet interfaces, of both nodes and BMCs, by MAC address
+// (the component they belong to and their IP addresses). Fields that SMD sets
+// itself, such as the state of components and the UUIDs of redfish endpoints,
+// are not compared.
+//
+// Only records of the kinds that discovery creates can be removed: components
+// of type Node or VirtualNode, redfish endpoints of type NodeBMC, and ethernet
+// interfaces of those components.
+func Diff(nl NodeList, comps []smd.Component, rfes []csm.RedfishEndpoint, ifaces []smd.EthernetInterface) (DiffResult, error) {
+	var res DiffResult
+
+	// The base URI is only used for the URIs of Systems and Managers,
+	// which are not compared
+	wantComps, wantRFEs, wantIfaces, err := DiscoveryInfoV2("", nl)
+	if err != nil {
+		return res, err
+	}
+
+	// Components
+	var want, have []diffRecord
+	for _, c := range wantComps.Components {
+		want = append(want, componentRecord(c))
+	}
+	for _, c := range comps {
+		if c.Type == ComponentTypeNode || c.Type == ComponentTypeVirtualNode {
+			have = append(have, componentRecord(c))
+		}
+	}
+	haveAll := make(map[string]diffRecord, len(comps))
+	for _, c := range comps {
+		haveAll[strings.ToLower(c.ID)] = componentRecord(c)
+	}
+	res.Entries = append(res.Entries, diffRecords(DiffKindComponent, want, have, haveAll)...)
+
+	// Redfish endpoints, whose Manager interfaces are the ethernet
+	// interfaces of the BMC. A port with several addresses has a Manager
+	// interface for each, which are one ethernet interface in SMD.
+	want, have = nil, nil
+	haveAll = make(map[string]diffRecord, len(rfes))
+	for _, rfe := range wantRFEs.RedfishEndpoints {
+		want = append(want, rfeRecord(rfe.RedfishEndpoint))
+		ports := make(map[string]int)
+		for _, m := range rfe.Managers {
+			for _, iface := range m.EthernetInterfaces {
+				if iface.MAC == "" {
+					continue
+				}
+				idx, ok := ports[macID(iface.MAC)]
+				if !ok {
+					idx = len(wantIfaces)
+					ports[macID(iface.MAC)] = idx
+					wantIfaces = append(wantIfaces, smd.EthernetInterface{ComponentID: rfe.ID, MACAddress: iface.MAC})
+				}
+				if iface.IP != "" {
+					wantIfaces[idx].IPAddresses = append(wantIfaces[idx].IPAddresses, smd.EthernetIP{IPAddress: iface.IP})
+				}
+			}
+		}
+	}
+	for _, rfe := range rfes {
+		if rfe.Type == "" || rfe.Type == "NodeBMC" {
+			have = append(have, rfeRecord(rfe))
+		}
+		haveAll[strings.ToLower(rfe.ID)] = rfeRecord(rfe)
+	}
+	res.Entries = append(res.Entries, diffRecords(DiffKindRedfishEndpoint, want, have, haveAll)...)
+
+	// Ethernet interfaces
+	want, have = nil, nil
+	haveAll = make(map[string]diffRecord, len(ifaces))
+	for _, iface := range wantIfaces {
+		want = append(want, ifaceRecord(iface))
+	}
+	for _, iface := range ifaces {
+		switch iface.Type {
+		case "", ComponentTypeNode, ComponentTypeVirtualNode, "NodeBMC":
+			have = append(have, ifaceRecord(iface))
+		}
+		haveAll[macID(iface.MACAddress)] = ifaceRecord(iface)
+	}
+	res.Entries = append(res.Entries, diffRecords(DiffKindEthernetInterface, want, have, haveAll)...)
+
+	return res, nil
+}
+
+// diffRecords returns the entries that make the records in SMD match the
+// wanted ones. have are the records in SMD that can be removed, and haveAll are
+// all of the records in SMD, keyed by ID, that can be changed.
+func diffRecords(kind string, want, have []diffRecord, haveAll map[string]diffRecord) []DiffEntry {
+	var (
+		entries []DiffEntry
+		wanted  = make(map[string]bool, len(want))
+	)
+	for _, w := range want {
+		if wanted[w.id] {
+			continue
+		}
+		wanted[w.id] = true
+		h, ok := haveAll[w.id]
+		if !ok {
+			entries = append(entries, DiffEntry{Kind: kind, ID: w.id, Action: DiffAdd})
+			continue
+		}
+		var changes []FieldChange
+		for i, f := range w.fields {
+			if h.fields[i][1] != f[1] {
+				changes = append(changes, FieldChange{Field: f[0], From: h.fields[i][1], To: f[1]})
+			}
+		}
+		if len(changes) > 0 {
+			entries = append(entries, DiffEntry{Kind: kind, ID: w.id, Action: DiffChange, Changes: changes})
+		}
+	}
+	for _, h := range have {
+		if !wanted[h.id] {
+			wanted[h.id] = true
+			entries = append(entries, DiffEntry{Kind: kind, ID: h.id, Action: DiffRemove})
+		}
+	}
+	slices.SortStableFunc(entries, func(a, b DiffEntry) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	return entries
+}
+
+// componentRecord returns the compared fields of component c.
+func componentRecord(c smd.Component) diffRecord {
+	return diffRecord{
+		id: strings.ToLower(c.ID),
+		fields: [][2]string{
+			{"Type", c.Type},
+			{"NID", fmt.Sprint(c.NID)},
+		},
+	}
+}
+
+// rfeRecord returns the compared fields of redfish endpoint rfe.
+func rfeRecord(rfe csm.RedfishEndpoint) diffRecord {
+	return diffRecord{
+		id: strings.ToLower(rfe.ID),
+		fields: [][2]string{
+			{"FQDN", rfe.FQDN},
+			{"MACAddr", macID(rfe.MACAddr)},
+			{"IPAddress", rfe.IPAddress},
+		},
+	}
+}
+
+// ifaceRecord returns the compared fields of ethernet interface iface. Its IP
+// addresses are compared regardless of order.
+func ifaceRecord(iface smd.EthernetInterface) diffRecord {
+	var ips []string
+	for _, ip := range iface.IPAddresses {
+		ips = append(ips, ip.IPAddress)
+	}
+	slices.Sort(ips)
+
+	return diffRecord{
+		id: macID(iface.MACAddress),
+		fields: [][2]string{
+			{"ComponentID", strings.ToLower(iface.ComponentID)},
+			{"IPAddresses", strings.Join(ips, ",")},
+		},
+	}
+}
+
+// macID returns the ID SMD gives the ethernet interface with MAC address mac,
+// which is the MAC address in lower case without separators.
+func macID(mac string) string {
+	return strings.NewReplacer(":", "", "-", "", ".", "").Replace(strings.ToLower(strings.TrimSpace(mac)))
+}
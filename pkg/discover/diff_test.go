@@ -0,0 +1,134 @@
+package discover
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+func TestDiff(t *testing.T) {
+	nl := NodeList{
+		Version: NodeListVersion,
+		Nodes: []Node{
+			{
+				Name: "node01", NID: 1, Xname: "x1000c1s7b0n0",
+				BMCMac: "de:ca:fc:0f:ee:01", BMCIP: "172.16.0.101", BMCFQDN: "bmc01.example",
+				Ifaces: []Iface{{MACAddr: "de:ad:be:ee:ee:01", IPAddrs: []IfaceIP{{IPAddr: "172.16.0.1"}}}},
+			},
+			{
+				Name: "node02", NID: 2, Xname: "x1000c1s7b1n0",
+				BMCMac: "de:ca:fc:0f:ee:02", BMCIP: "172.16.0.102",
+				Ifaces: []Iface{{MACAddr: "de:ad:be:ee:ee:02", IPAddrs: []IfaceIP{{IPAddr: "172.16.0.2"}}}},
+			},
+			{
+				Name: "node03", NID: 3, Xname: "x1000c1s7b2n0",
+				BMCMac: "de:ca:fc:0f:ee:03",
+				Ifaces: []Iface{{MACAddr: "de:ad:be:ee:ee:03", IPAddrs: []IfaceIP{{IPAddr: "172.16.0.3"}}}},
+			},
+		},
+	}
+	comps := []smd.Component{
+		{ID: "x1000c1s7b0n0", Type: "Node", NID: 1, State: "Ready"},
+		{ID: "x1000c1s7b1n0", Type: "Node", NID: 12},
+		{ID: "x1000c1s7b9n0", Type: "Node", NID: 9},
+		{ID: "x1000c1s7b0", Type: "NodeBMC"},
+		{ID: "x3000c0r1b0", Type: "RouterBMC"},
+	}
+	rfes := []csm.RedfishEndpoint{
+		{ID: "x1000c1s7b0", Type: "NodeBMC", FQDN: "bmc01.example", MACAddr: "DE:CA:FC:0F:EE:01", IPAddress: "172.16.0.101"},
+		{ID: "x1000c1s7b1", Type: "NodeBMC", MACAddr: "de:ca:fc:0f:ee:02", IPAddress: "172.16.0.112"},
+		{ID: "x1000c1s7b9", Type: "NodeBMC"},
+		{ID: "x3000c0r1b0", Type: "RouterBMC"},
+	}
+	ifaces := []smd.EthernetInterface{
+		{ComponentID: "x1000c1s7b0n0", Type: "Node", MACAddress: "de:ad:be:ee:ee:01", IPAddresses: []smd.EthernetIP{{IPAddress: "172.16.0.1"}}},
+		{ComponentID: "x1000c1s7b0", Type: "NodeBMC", MACAddress: "de:ca:fc:0f:ee:01", IPAddresses: []smd.EthernetIP{{IPAddress: "172.16.0.101"}}},
+		{ComponentID: "x1000c1s7b9n0", Type: "Node", MACAddress: "de:ad:be:ee:ee:02", IPAddresses: []smd.EthernetIP{{IPAddress: "172.16.0.2"}}},
+		{ComponentID: "x1000c1s7b9n0", Type: "Node", MACAddress: "de:ad:be:ee:ee:09"},
+		{ComponentID: "x3000c0r1b0", Type: "RouterBMC", MACAddress: "de:ad:be:ee:ee:99"},
+	}
+
+	res, err := Diff(nl, comps, rfes, ifaces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DiffEntry{
+		{Kind: DiffKindComponent, ID: "x1000c1s7b1n0", Action: DiffChange, Changes: []FieldChange{{Field: "NID", From: "12", To: "2"}}},
+		{Kind: DiffKindComponent, ID: "x1000c1s7b2n0", Action: DiffAdd},
+		{Kind: DiffKindComponent, ID: "x1000c1s7b9n0", Action: DiffRemove},
+		{Kind: DiffKindRedfishEndpoint, ID: "x1000c1s7b1", Action: DiffChange, Changes: []FieldChange{{Field: "IPAddress", From: "172.16.0.112", To: "172.16.0.102"}}},
+		{Kind: DiffKindRedfishEndpoint, ID: "x1000c1s7b2", Action: DiffAdd},
+		{Kind: DiffKindRedfishEndpoint, ID: "x1000c1s7b9", Action: DiffRemove},
+		{Kind: DiffKindEthernetInterface, ID: "deadbeeeee02", Action: DiffChange, Changes: []FieldChange{{Field: "ComponentID", From: "x1000c1s7b9n0", To: "x1000c1s7b1n0"}}},
+		{Kind: DiffKindEthernetInterface, ID: "deadbeeeee03", Action: DiffAdd},
+		{Kind: DiffKindEthernetInterface, ID: "deadbeeeee09", Action: DiffRemove},
+		{Kind: DiffKindEthernetInterface, ID: "decafc0fee02", Action: DiffAdd},
+		{Kind: DiffKindEthernetInterface, ID: "decafc0fee03", Action: DiffAdd},
+	}
+	if !reflect.DeepEqual(res.Entries, want) {
+		t.Errorf("Diff() =\n%v\nwant\n%v", res.Entries, want)
+	}
+	if res.Count(DiffAdd) != 5 || res.Count(DiffChange) != 3 || res.Count(DiffRemove) != 3 {
+		t.Errorf("Count() = %d/%d/%d, want 5/3/3", res.Count(DiffAdd), res.Count(DiffChange), res.Count(DiffRemove))
+	}
+
+	// A payload that matches SMD has no differences
+	comps, rfes, ifaces = nil, nil, nil
+	wantComps, wantRFEs, wantIfaces, err := DiscoveryInfoV2("", nl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	comps = wantComps.Components
+	for _, rfe := range wantRFEs.RedfishEndpoints {
+		rfes = append(rfes, rfe.RedfishEndpoint)
+		for _, iface := range rfe.Managers[0].EthernetInterfaces {
+			smdIface := smd.EthernetInterface{ComponentID: rfe.ID, Type: "NodeBMC", MACAddress: iface.MAC}
+			if iface.IP != "" {
+				smdIface.IPAddresses = []smd.EthernetIP{{IPAddress: iface.IP}}
+			}
+			ifaces = append(ifaces, smdIface)
+		}
+	}
+	ifaces = append(ifaces, wantIfaces...)
+	if res, err := Diff(nl, comps, rfes, ifaces); err != nil || len(res.Entries) != 0 {
+		t.Errorf("Diff() of matching SMD = %v, %v, want no entries", res.Entries, err)
+	}
+}
+
+func TestDiff_BMCDualStack(t *testing.T) {
+	nl := NodeList{
+		Version: NodeListVersion,
+		Nodes: []Node{{
+			Name: "node01", NID: 1, Xname: "x1000c1s7b0n0",
+			BMCIfaces: []BMCIface{
+				{MACAddr: "de:ca:fc:0f:ee:01", IPAddr: "172.16.0.101"},
+				{MACAddr: "de:ca:fc:0f:ee:01", IPAddr: "fd00::101"},
+			},
+			Ifaces: []Iface{{MACAddr: "de:ad:be:ee:ee:01", IPAddrs: []IfaceIP{{IPAddr: "172.16.0.1"}}}},
+		}},
+	}
+	comps := []smd.Component{{ID: "x1000c1s7b0n0", Type: "Node", NID: 1}}
+	rfes := []csm.RedfishEndpoint{{ID: "x1000c1s7b0", Type: "NodeBMC", MACAddr: "de:ca:fc:0f:ee:01", IPAddress: "172.16.0.101"}}
+	ifaces := []smd.EthernetInterface{
+		{ComponentID: "x1000c1s7b0n0", Type: "Node", MACAddress: "de:ad:be:ee:ee:01", IPAddresses: []smd.EthernetIP{{IPAddress: "172.16.0.1"}}},
+		{ComponentID: "x1000c1s7b0", Type: "NodeBMC", MACAddress: "de:ca:fc:0f:ee:01", IPAddresses: []smd.EthernetIP{{IPAddress: "172.16.0.101"}}},
+	}
+
+	// The port is one ethernet interface with both addresses
+	res, err := Diff(nl, comps, rfes, ifaces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DiffEntry{{Kind: DiffKindEthernetInterface, ID: "decafc0fee01", Action: DiffChange, Changes: []FieldChange{{Field: "IPAddresses", From: "172.16.0.101", To: "172.16.0.101,fd00::101"}}}}
+	if !reflect.DeepEqual(res.Entries, want) {
+		t.Errorf("Diff() =\n%v\nwant\n%v", res.Entries, want)
+	}
+
+	ifaces[1].IPAddresses = append(ifaces[1].IPAddresses, smd.EthernetIP{IPAddress: "fd00::101"})
+	if res, err := Diff(nl, comps, rfes, ifaces); err != nil || len(res.Entries) != 0 {
+		t.Errorf("Diff() of matching SMD = %v, %v, want no entries", res.Entries, err)
+	}
+}
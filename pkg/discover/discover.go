@@ -2,10 +2,15 @@ package discover
 
 import (
 	"fmt"
+	"net"
+	"net/netip"
 	"net/url"
+	"slices"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/openchami/schemas/schemas"
+	"github.com/openchami/schemas/schemas/csm"
 
 	"github.com/OpenCHAMI/ochami/internal/log"
 	"github.com/OpenCHAMI/ochami/pkg/client/smd"
@@ -13,9 +18,11 @@ import (
 )
 
 // NodeList is simply a list of Nodes. Data from a payload file is unmarshalled
-// into this.
+// into this. Version is the version of the format (see NodeListVersion and
+// MigrateNodeList).
 type NodeList struct {
-	Nodes []Node `json:"nodes" yaml:"nodes"`
+	Version int    `json:"version,omitempty" yaml:"version,omitempty"`
+	Nodes   []Node `json:"nodes" yaml:"nodes"`
 }
 
 func (nl NodeList) String() string {
@@ -36,14 +43,69 @@ func (nl NodeList) String() string {
 // SMD to "discover" them.
 type Node struct {
 	Name    string   `json:"name" yaml:"name"`
-	NID     int64    `json:"nid" yaml:"nid"`
-	Xname   string   `json:"xname" yaml:"xname"`
-	Group   string   `json:"group" yaml:"group"` // DEPRECATED
+	NID     int64    `json:"nid" yaml:"nid" jsonschema:"oneof_type=integer;string"`
+	Xname   string   `json:"xname" yaml:"xname" jsonschema:"required"`
+	Group   string   `json:"group,omitempty" yaml:"group,omitempty"` // DEPRECATED
 	Groups  []string `json:"groups" yaml:"groups"`
 	BMCMac  string   `json:"bmc_mac" yaml:"bmc_mac"`
 	BMCIP   string   `json:"bmc_ip" yaml:"bmc_ip"`
 	BMCFQDN string   `json:"bmc_fqdn" yaml:"bmc_fqdn"`
 	Ifaces  []Iface  `json:"interfaces" yaml:"interfaces"`
+
+	// BMCIfaces replaces BMCMac and BMCIP for BMCs with more than one
+	// interface (see BMCInterfaces).
+	BMCIfaces []BMCIface `json:"bmc_interfaces,omitempty" yaml:"bmc_interfaces,omitempty"`
+
+	// Bonds are bonded interfaces whose members are not in Ifaces (see
+	// BondInterfaces).
+	Bonds []Bond `json:"bonds,omitempty" yaml:"bonds,omitempty"`
+
+	// Virtual marks the node as a virtual machine. Hypervisor is the xname
+	// of the virtual BMC of its hypervisor (e.g. sushy-tools) that manages
+	// it, if any, whose address the BMC fields then describe.
+	Virtual    bool   `json:"virtual,omitempty" yaml:"virtual,omitempty"`
+	Hypervisor string `json:"hypervisor,omitempty" yaml:"hypervisor,omitempty"`
+
+	// Type makes the entry describe a device other than a node, such as a
+	// switch or PDU, if it is one of DeviceTypes. Such devices have no NID
+	// and, unless they are MgmtSwitches, are managed by a BMC described by
+	// the BMC fields. The default is ComponentTypeNode.
+	Type string `json:"type,omitempty" yaml:"type,omitempty"`
+
+	// PowerActions are the Redfish ResetTypes that the BMC of the node
+	// supports, which PCS uses to decide which power transitions it can
+	// perform. If empty, all of ResetTypes are assumed to be supported.
+	PowerActions []string `json:"power_actions,omitempty" yaml:"power_actions,omitempty"`
+
+	// BMCUsername and BMCPassword are the credentials of the BMC of the
+	// node, which are stored with its RedfishEndpoint in SMD. The password
+	// can instead be read from BMCPasswordFile to keep it out of the
+	// payload (see BMCCredentials).
+	BMCUsername     string `json:"bmc_username,omitempty" yaml:"bmc_username,omitempty"`
+	BMCPassword     string `json:"bmc_password,omitempty" yaml:"bmc_password,omitempty"`
+	BMCPasswordFile string `json:"bmc_password_file,omitempty" yaml:"bmc_password_file,omitempty"`
+
+	// Hardware describes the hardware of the node, from which SMD hardware
+	// inventory entries are generated (see HardwareInventory).
+	Hardware *Hardware `json:"hardware,omitempty" yaml:"hardware,omitempty"`
+}
+
+// Types of the Components generated for nodes.
+const (
+	ComponentTypeNode        = "Node"
+	ComponentTypeVirtualNode = "VirtualNode"
+)
+
+// ComponentType returns the type of the SMD Component of the node.
+func (n Node) ComponentType() string {
+	if !n.IsNode() {
+		return n.Type
+	}
+	if n.Virtual {
+		return ComponentTypeVirtualNode
+	}
+
+	return ComponentTypeNode
 }
 
 func (n Node) String() string {
@@ -57,17 +119,171 @@ func (n Node) String() string {
 		}
 	}
 	nStr += "]"
+	if len(n.BMCIfaces) > 0 {
+		nStr += " bmc_interfaces=["
+		for idx, iface := range n.BMCIfaces {
+			if idx == 0 {
+				nStr += fmt.Sprintf("bmc_iface%d={%s}", idx, iface)
+			} else {
+				nStr += fmt.Sprintf(" bmc_iface%d={%s}", idx, iface)
+			}
+		}
+		nStr += "]"
+	}
+	if len(n.Bonds) > 0 {
+		nStr += " bonds=["
+		for idx, b := range n.Bonds {
+			if idx == 0 {
+				nStr += fmt.Sprintf("bond%d={%s}", idx, b)
+			} else {
+				nStr += fmt.Sprintf(" bond%d={%s}", idx, b)
+			}
+		}
+		nStr += "]"
+	}
+	if n.Virtual {
+		nStr += fmt.Sprintf(" virtual=true hypervisor=%s", n.Hypervisor)
+	}
+	if n.Type != "" {
+		nStr += fmt.Sprintf(" type=%s", n.Type)
+	}
 
 	return nStr
 }
 
+// BMCInterfaces returns the interfaces of the node's BMC. If BMCIfaces is not
+// set, this is the single dedicated interface described by BMCMac and BMCIP, if
+// either is set. Otherwise, BMCIfaces is returned with the kind of each
+// interface defaulted to dedicated and, if none is marked primary, the first
+// dedicated interface (or the first interface, if all are shared) marked
+// primary, preferring those with IPv4 addresses. A MAC address can be listed
+// more than once to give a port several addresses, e.g. an IPv4 and an IPv6
+// one. An error is returned if BMCIfaces is combined with BMCMac or BMCIP, an
+// interface has no MAC address or an unknown kind, more than one interface is
+// primary, a MAC address is listed with different kinds or shared_with, or an
+// interface is shared with a MAC address that is not one of the node's
+// interfaces or bond members.
+func (n Node) BMCInterfaces() ([]BMCIface, error) {
+	if len(n.BMCIfaces) == 0 {
+		if n.BMCMac == "" && n.BMCIP == "" {
+			return nil, nil
+		}
+		return []BMCIface{{MACAddr: n.BMCMac, IPAddr: n.BMCIP, Kind: BMCIfaceDedicated, Primary: true}}, nil
+	}
+	if n.BMCMac != "" || n.BMCIP != "" {
+		return nil, fmt.Errorf("bmc_mac and bmc_ip cannot be combined with bmc_interfaces")
+	}
+
+	var (
+		ifaces  = make([]BMCIface, len(n.BMCIfaces))
+		primary = -1
+		ports   = make(map[string]BMCIface)
+	)
+	copy(ifaces, n.BMCIfaces)
+	for idx := range ifaces {
+		iface := &ifaces[idx]
+		if iface.MACAddr == "" {
+			return nil, fmt.Errorf("BMC interface %d has no mac_addr", idx)
+		}
+		switch iface.Kind {
+		case "":
+			iface.Kind = BMCIfaceDedicated
+		case BMCIfaceDedicated, BMCIfaceShared:
+		default:
+			return nil, fmt.Errorf("BMC interface %s has unknown kind %q (must be %s or %s)", iface.MACAddr, iface.Kind, BMCIfaceDedicated, BMCIfaceShared)
+		}
+		if port, ok := ports[normalizeMAC(iface.MACAddr)]; ok {
+			if port.Kind != iface.Kind || normalizeMAC(port.SharedWith) != normalizeMAC(iface.SharedWith) {
+				return nil, fmt.Errorf("BMC interface %s is listed more than once with different kind or shared_with", iface.MACAddr)
+			}
+		} else {
+			ports[normalizeMAC(iface.MACAddr)] = *iface
+		}
+		if iface.SharedWith != "" {
+			if iface.Kind != BMCIfaceShared {
+				return nil, fmt.Errorf("BMC interface %s has shared_with but is not of kind %s", iface.MACAddr, BMCIfaceShared)
+			}
+			found := false
+			for _, nodeIface := range n.Ifaces {
+				if strings.EqualFold(nodeIface.MACAddr, iface.SharedWith) {
+					found = true
+					break
+				}
+			}
+			for _, b := range n.Bonds {
+				found = found || slices.ContainsFunc(b.Members, func(m string) bool { return normalizeMAC(m) == normalizeMAC(iface.SharedWith) })
+			}
+			if !found {
+				return nil, fmt.Errorf("BMC interface %s is shared with %s, which is not an interface of the node", iface.MACAddr, iface.SharedWith)
+			}
+		}
+		if iface.Primary {
+			if primary >= 0 {
+				return nil, fmt.Errorf("BMC interfaces %s and %s are both primary", ifaces[primary].MACAddr, iface.MACAddr)
+			}
+			primary = idx
+		}
+	}
+	if primary < 0 {
+		// Candidates in order of preference, the first of which is used
+		primary = slices.IndexFunc(ifaces, func(i BMCIface) bool { return i.Kind == BMCIfaceDedicated && i.IsIPv4() })
+		if primary < 0 {
+			primary = slices.IndexFunc(ifaces, func(i BMCIface) bool { return i.Kind == BMCIfaceDedicated })
+		}
+		if primary < 0 {
+			primary = slices.IndexFunc(ifaces, BMCIface.IsIPv4)
+		}
+		if primary < 0 {
+			primary = 0
+		}
+		ifaces[primary].Primary = true
+	}
+
+	return ifaces, nil
+}
+
 // Iface represents a single interface with multiple IP addresses. Nodes can
 // have multiple of these.
 type Iface struct {
-	MACAddr string    `json:"mac_addr" yaml:"mac_addr"`
+	MACAddr string    `json:"mac_addr" yaml:"mac_addr" jsonschema:"required"`
 	IPAddrs []IfaceIP `json:"ip_addrs" yaml:"ip_addrs"`
 }
 
+// Kinds of BMCIface.
+const (
+	BMCIfaceDedicated = "dedicated"
+	BMCIfaceShared    = "shared"
+)
+
+// BMCIface represents a network interface of a node's BMC. A dedicated
+// interface has a port of its own, while a shared interface (e.g. using NC-SI)
+// uses the port of one of the node's interfaces, optionally given by the MAC
+// address in SharedWith. SMD identifies the BMC by the MAC address of the
+// Primary interface, which is used for the RedfishEndpoint.
+type BMCIface struct {
+	MACAddr    string `json:"mac_addr" yaml:"mac_addr" jsonschema:"required"`
+	IPAddr     string `json:"ip_addr,omitempty" yaml:"ip_addr,omitempty"`
+	Kind       string `json:"kind,omitempty" yaml:"kind,omitempty" jsonschema:"enum=dedicated,enum=shared"`
+	SharedWith string `json:"shared_with,omitempty" yaml:"shared_with,omitempty"`
+	Primary    bool   `json:"primary,omitempty" yaml:"primary,omitempty"`
+}
+
+// IsIPv4 returns whether the IP address of i is an IPv4 address.
+func (i BMCIface) IsIPv4() bool {
+	addr, err := netip.ParseAddr(i.IPAddr)
+	return err == nil && addr.Unmap().Is4()
+}
+
+// IsIPv6 returns whether the IP address of i is an IPv6 address.
+func (i BMCIface) IsIPv6() bool {
+	addr, err := netip.ParseAddr(i.IPAddr)
+	return err == nil && !addr.Unmap().Is4()
+}
+
+func (i BMCIface) String() string {
+	return fmt.Sprintf("mac_addr=%s ip_addr=%s kind=%s shared_with=%s primary=%t", i.MACAddr, i.IPAddr, i.Kind, i.SharedWith, i.Primary)
+}
+
 func (i Iface) String() string {
 	ipStr := fmt.Sprintf("mac_addr=%s ip_addrs=[", i.MACAddr)
 	for idx, ip := range i.IPAddrs {
@@ -88,7 +304,7 @@ func (i Iface) String() string {
 // IPAddr.
 type IfaceIP struct {
 	Network string `json:"network" yaml:"network"`
-	IPAddr  string `json:"ip_addr" yaml:"ip_addr"`
+	IPAddr  string `json:"ip_addr" yaml:"ip_addr" jsonschema:"required"`
 }
 
 func (i IfaceIP) String() string {
@@ -103,152 +319,471 @@ func (i IfaceIP) String() string {
 // [Magellan](https://github.com/OpenCHAMI/magellan) would do), except the
 // information is sourced from a file instead of dynamically reaching out to
 // BMCs.
+//
+// Nodes with the same BMC xname, such as the nodes of a blade with several
+// nodes per BMC, share one RedfishEndpoint with a System for each of them,
+// whose address is taken from the first of them.
+//
+// Virtual nodes get VirtualNode Components. Those without a hypervisor have no
+// BMC, so no RedfishEndpoint is generated for them and their interfaces are
+// only added as EthernetInterfaces. Those with one get a System in the
+// RedfishEndpoint of the hypervisor's virtual BMC, shared by all of its guests.
+//
+// Devices other than nodes (see DeviceTypes) get Components of their type. The
+// RedfishEndpoint of their BMC has the type of the BMC and no Systems, and is
+// shared by devices with the same BMC (e.g. the PDUs of a PDU controller).
+// MgmtSwitches have no BMC, so only their interfaces are added as
+// EthernetInterfaces.
+//
+// Problems with nodes that do not stop the structures from being generated are
+// logged as warnings. Use DiscoveryInfoV3 to get them instead.
 func DiscoveryInfoV2(baseURI string, nl NodeList) (smd.ComponentSlice, smd.RedfishEndpointSliceV2, []smd.EthernetInterface, error) {
+	comps, rfes, ifaces, warnings, err := DiscoveryInfoV3(baseURI, nl, ErrorPolicyFailFast)
+	for _, w := range warnings {
+		log.Logger.Warn().Msg(w.String())
+	}
+
+	return comps, rfes, ifaces, err
+}
+
+// DiscoveryInfoV3 is like DiscoveryInfoV2, except that it returns the problems
+// with nodes that do not stop the structures from being generated as warnings,
+// in the order of the nodes, instead of logging them. Malformed nodes, such as
+// those with invalid BMC fields or interfaces without a MAC address, are handled
+// according to policy (see ErrorPolicy); the default is ErrorPolicyFailFast.
+func DiscoveryInfoV3(baseURI string, nl NodeList, policy ErrorPolicy) (smd.ComponentSlice, smd.RedfishEndpointSliceV2, []smd.EthernetInterface, []Warning, error) {
 	var (
-		comps  smd.ComponentSlice
-		rfes   smd.RedfishEndpointSliceV2
-		ifaces []smd.EthernetInterface
+		comps    smd.ComponentSlice
+		rfes     smd.RedfishEndpointSliceV2
+		ifaces   []smd.EthernetInterface
+		warnings []Warning
 	)
 	base, err := url.Parse(baseURI)
 	if err != nil {
-		return comps, rfes, ifaces, fmt.Errorf("invalid URI: %s", baseURI)
+		return comps, rfes, ifaces, warnings, fmt.Errorf("invalid URI: %s", baseURI)
 	}
 
 	var (
-		compMap    = make(map[string]string) // Deduplication map for SMD Components
-		systemMap  = make(map[string]string) // Deduplication map for BMC Systems
-		managerMap = make(map[string]string) // Deduplication map for BMC Managers
+		compMap   = make(map[string]string) // Deduplication map for SMD Components
+		systemMap = make(map[string]string) // Deduplication map for BMC Systems
+		bmcMap    = make(map[string]int)    // Index of RedfishEndpoint of each node BMC
+		deviceMap = make(map[string]string) // Deduplication map for BMCs of devices
 	)
-	for _, node := range nl.Nodes {
+	for i, node := range nl.Nodes {
+		warn := func(code WarningCode, format string, v ...any) {
+			warnings = append(warnings, Warning{Node: i, Xname: node.Xname, Code: code, Message: fmt.Sprintf(format, v...)})
+		}
+
+		// Check the node before generating anything for it, so that it can
+		// be skipped as a whole
+		bmcUser, bmcPassword, bmcIfaces, err := prepareNode(&node, policy, warn)
+		if err != nil {
+			if policy == ErrorPolicySkipNode || policy == ErrorPolicySkipInterface {
+				warn(WarningSkippedNode, "skipping node: %v", err)
+				continue
+			}
+			return comps, rfes, ifaces, warnings, fmt.Errorf("node %s: %w", node.Xname, err)
+		}
+
 		log.Logger.Debug().Msgf("generating component structure for node with xname %s", node.Xname)
 		if _, ok := compMap[node.Xname]; !ok {
 			comp := smd.Component{
 				ID:      node.Xname,
-				NID:     node.NID,
-				Type:    "Node",
+				Type:    node.ComponentType(),
 				State:   "On",
 				Enabled: true,
 			}
+			if node.IsNode() {
+				comp.NID = node.NID
+			}
 			log.Logger.Debug().Msgf("adding component %v", comp)
 			compMap[node.Xname] = "present"
 			comps.Components = append(comps.Components, comp)
 		} else {
-			log.Logger.Warn().Msgf("component with xname %s already exists (duplicate?), not adding", node.Xname)
+			warn(WarningDuplicateXname, "component with xname %s already exists (duplicate?), not adding", node.Xname)
+		}
+
+		if !node.IsNode() {
+			dt, err := node.device()
+			if err != nil {
+				return comps, rfes, ifaces, warnings, fmt.Errorf("node %s: %w", node.Xname, err)
+			}
+			if len(node.Ifaces) > 0 || len(node.Bonds) > 0 {
+				_, devIfaces := nodeSystem(base, node)
+				ifaces = append(ifaces, devIfaces...)
+			}
+			if dt.bmcType == "" {
+				if len(bmcIfaces) > 0 || node.BMCFQDN != "" {
+					warn(WarningIgnoredBMC, "%s has no BMC, ignoring its BMC fields", node.Type)
+				}
+				continue
+			}
+			bmcXname := dt.bmcXname(node.Xname)
+			if _, ok := deviceMap[bmcXname]; ok {
+				log.Logger.Debug().Msgf("%s %s: redfish endpoint of BMC %s already exists, skipping creation", node.Type, node.Xname, bmcXname)
+				continue
+			}
+			log.Logger.Debug().Msgf("generating redfish structure for %s %s", node.Type, node.Xname)
+			rfe := smd.RedfishEndpointV2{SchemaVersion: 1}
+			rfe.Name = node.Name
+			rfe.Type = csm.ComponentType(dt.bmcType)
+			rfe.ID = bmcXname
+			for _, iface := range bmcIfaces {
+				if iface.Primary {
+					rfe.MACAddr = iface.MACAddr
+					rfe.IPAddress = iface.IPAddr
+				}
+			}
+			rfe.FQDN = node.BMCFQDN
+			rfe.User, rfe.Password = bmcUser, bmcPassword
+			m, mUUID := bmcManager(base, bmcXname, dt.bmcType, bmcIfaces, len(node.BMCIfaces) > 0)
+			rfe.UID = mUUID
+			rfe.Managers = append(rfe.Managers, m)
+			deviceMap[bmcXname] = "present"
+			rfes.RedfishEndpoints = append(rfes.RedfishEndpoints, rfe)
+			continue
+		}
+
+		if len(node.Ifaces) == 0 && len(node.Bonds) == 0 {
+			warn(WarningNoInterfaces, "node has no interfaces, no ethernet interfaces are added for it")
+		}
+
+		// Virtual nodes without a hypervisor have no BMC
+		if node.Virtual && node.Hypervisor == "" {
+			if len(bmcIfaces) > 0 || node.BMCFQDN != "" {
+				warn(WarningIgnoredBMC, "virtual node has no hypervisor, ignoring its BMC")
+			}
+			if _, ok := systemMap[node.Xname]; !ok {
+				_, nodeIfaces := nodeSystem(base, node)
+				ifaces = append(ifaces, nodeIfaces...)
+				systemMap[node.Xname] = "present"
+			}
+			continue
 		}
 
 		log.Logger.Debug().Msgf("generating redfish structure for node with xname %s", node.Xname)
 		var rfe smd.RedfishEndpointV2
 
 		// Differentiate node Xname from BMC Xname
-		bmcXname, err := xname.NodeXnameToBMCXname(node.Xname)
-		if err != nil {
-			log.Logger.Warn().Err(err).Msgf("node %s: falling back to node xname as BMC xname", node.Xname)
+		var bmcXname string
+		if node.Virtual {
+			bmcXname = node.Hypervisor
+		} else if bmcXname, err = xname.NodeXnameToBMCXname(node.Xname); err != nil {
+			warn(WarningBMCXnameFallback, "falling back to node xname as BMC xname: %v", err)
 			bmcXname = node.Xname
 		}
 
+		// Nodes whose BMC already has a RedfishEndpoint, such as the nodes
+		// of a blade with several nodes per BMC or the guests of a
+		// hypervisor, are added to it as Systems
+		if idx, ok := bmcMap[bmcXname]; ok {
+			rfe := &rfes.RedfishEndpoints[idx]
+			if field, ok := bmcMismatch(*rfe, bmcIfaces, node.BMCFQDN); !ok {
+				warn(WarningBMCMismatch, "%s of BMC %s differs from that of an earlier node sharing it, using the earlier one", field, bmcXname)
+			}
+			if _, ok := systemMap[node.Xname]; !ok {
+				s, nodeIfaces := nodeSystem(base, node)
+				ifaces = append(ifaces, nodeIfaces...)
+				rfe.Systems = append(rfe.Systems, s)
+				systemMap[node.Xname] = "present"
+				log.Logger.Debug().Msgf("node %s: added system to redfish endpoint of BMC %s", node.Xname, bmcXname)
+			}
+			continue
+		}
+
 		// Populate rfe base data
 		rfe.Name = node.Name
+		if node.Virtual {
+			rfe.Name = node.Hypervisor
+		}
 		rfe.Type = "NodeBMC"
 		rfe.ID = bmcXname
-		rfe.MACAddr = node.BMCMac
-		rfe.IPAddress = node.BMCIP
+		for _, iface := range bmcIfaces {
+			if iface.Primary {
+				rfe.MACAddr = iface.MACAddr
+				rfe.IPAddress = iface.IPAddr
+			}
+		}
 		rfe.FQDN = node.BMCFQDN
+		rfe.User, rfe.Password = bmcUser, bmcPassword
 		rfe.SchemaVersion = 1 // Tells SMD to use new (v2) parsing code
 
 		// Create fake BMC "System" for node if it doesn't already exist
 		if _, ok := systemMap[node.Xname]; !ok {
-			log.Logger.Debug().Msgf("node %s: generating fake BMC System", node.Xname)
-			base.Path = "/redfish/v1/Systems/" + node.Xname
+			s, nodeIfaces := nodeSystem(base, node)
+			ifaces = append(ifaces, nodeIfaces...)
+			systemMap[node.Xname] = "present"
+			log.Logger.Debug().Msgf("node %s: generated system: %v", node.Xname, s)
+			rfe.Systems = append(rfe.Systems, s)
+		} else {
+			log.Logger.Debug().Msgf("node %s: fake BMC System already exists, skipping creation", node.Xname)
+		}
 
-			s := smd.System{
-				URI:  base.String(),
-				Name: node.Name,
-			}
+		// Create fake BMC "Manager" for BMC interface
+		m, mUUID := bmcManager(base, bmcXname, "NodeBMC", bmcIfaces, len(node.BMCIfaces) > 0)
+		rfe.UID = mUUID // Redfish UUID will be fake Manager's UUID
+		log.Logger.Debug().Msgf("BMC %s: generated manager: %v", bmcXname, m)
+		rfe.Managers = append(rfe.Managers, m)
+		bmcMap[bmcXname] = len(rfes.RedfishEndpoints)
+		rfes.RedfishEndpoints = append(rfes.RedfishEndpoints, rfe)
+	}
+	return comps, rfes, ifaces, warnings, nil
+}
 
-			// Create unique identifier for system
-			if sysUUID, err := uuid.NewRandom(); err != nil {
-				log.Logger.Warn().Err(err).Msgf("node %s: could not generate UUID for fake BMC System, it will be zero", node.Xname)
-			} else {
-				s.UUID = sysUUID.String()
-			}
+// bmcMismatch checks the BMC fields of a node, its BMC interfaces and BMC
+// FQDN, against rfe, the RedfishEndpoint generated for its BMC from an earlier
+// node sharing it. If a field is set for both and differs, it returns the name
+// of the field and false. Nodes sharing a BMC only need to set its fields once.
+func bmcMismatch(rfe smd.RedfishEndpointV2, bmcIfaces []BMCIface, fqdn string) (string, bool) {
+	for _, iface := range bmcIfaces {
+		if !iface.Primary {
+			continue
+		}
+		if iface.MACAddr != "" && rfe.MACAddr != "" && normalizeMAC(iface.MACAddr) != normalizeMAC(rfe.MACAddr) {
+			return "MAC address", false
+		}
+		if iface.IPAddr != "" && rfe.IPAddress != "" && iface.IPAddr != rfe.IPAddress {
+			return "IP address", false
+		}
+	}
+	if fqdn != "" && rfe.FQDN != "" && fqdn != rfe.FQDN {
+		return "FQDN", false
+	}
 
-			// Fake discovery as of v0.5.1 does not have a field to indicate supported power actions, and PCS requires
-			// them. We don't have direct configuration for the System struct that contains this either, so in lieu of
-			// that, simply add every possible action from from the Redfish Reference 6.5.5.1 ResetType:
-			// https://www.dmtf.org/sites/default/files/standards/documents/DSP2046_2023.3.html#aggregate-102
-			s.Actions = []string{"On", "ForceOff", "GracefulShutdown", "GracefulRestart", "ForceRestart", "Nmi",
-				"ForceOn", "PushPowerButton", "PowerCycle", "Suspend", "Pause", "Resume"}
-
-			// Node interfaces
-			for idx, iface := range node.Ifaces {
-				newIface := schemas.EthernetInterface{
-					Name:        node.Xname,
-					Description: fmt.Sprintf("Interface %d for %s", idx, node.Name),
-					MAC:         iface.MACAddr,
-					IP:          iface.IPAddrs[0].IPAddr,
-				}
-				s.EthernetInterfaces = append(s.EthernetInterfaces, newIface)
-				SMDIface := smd.EthernetInterface{
-					ComponentID: newIface.Name,
-					Type:        "Node",
-					Description: newIface.Description,
-					MACAddress:  newIface.MAC,
-				}
-				for _, ip := range iface.IPAddrs {
+	return "", true
+}
+
+// prepareNode checks node before anything is generated for it, returning the
+// credentials and interfaces of its BMC and defaulting its bonds (see
+// BondInterfaces). An interface without a valid MAC address or with an invalid
+// IP address is an error unless policy is ErrorPolicySkipInterface, in which
+// case it is removed from node and warn is called for it. An interface with a
+// MAC address but no IP addresses is valid and kept.
+func prepareNode(node *Node, policy ErrorPolicy, warn func(code WarningCode, format string, v ...any)) (string, string, []BMCIface, error) {
+	bmcUser, bmcPassword, err := node.BMCCredentials()
+	if err != nil {
+		return "", "", nil, err
+	}
+	bmcIfaces, err := node.BMCInterfaces()
+	if err != nil {
+		return "", "", nil, err
+	}
+	if node.Bonds, err = node.BondInterfaces(); err != nil {
+		return "", "", nil, err
+	}
+	if !node.IsNode() {
+		if _, err := node.device(); err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	var nodeIfaces []Iface
+	for idx, iface := range node.Ifaces {
+		err := ifaceError(iface)
+		if err == nil {
+			nodeIfaces = append(nodeIfaces, iface)
+			continue
+		}
+		if policy != ErrorPolicySkipInterface {
+			return "", "", nil, fmt.Errorf("interface %d %w", idx, err)
+		}
+		warn(WarningSkippedInterface, "skipping interface %d, which %v", idx, err)
+	}
+	node.Ifaces = nodeIfaces
+
+	return bmcUser, bmcPassword, bmcIfaces, nil
+}
+
+// ifaceError returns why iface is malformed, phrased to follow "interface ...",
+// or nil if it is not.
+func ifaceError(iface Iface) error {
+	if iface.MACAddr == "" {
+		return fmt.Errorf("has no MAC address")
+	}
+	if _, err := net.ParseMAC(iface.MACAddr); err != nil {
+		return fmt.Errorf("has invalid MAC address %q", iface.MACAddr)
+	}
+	for _, ip := range iface.IPAddrs {
+		if _, err := netip.ParseAddr(ip.IPAddr); err != nil {
+			return fmt.Errorf("(%s) has invalid IP address %q", iface.MACAddr, ip.IPAddr)
+		}
+	}
+
+	return nil
+}
+
+// ResetTypes returns every possible action from the Redfish Reference 6.5.5.1
+// ResetType, which are the power actions that a node can have:
+// https://www.dmtf.org/sites/default/files/standards/documents/DSP2046_2023.3.html#aggregate-102
+func ResetTypes() []string {
+	return []string{"On", "ForceOff", "GracefulShutdown", "GracefulRestart", "ForceRestart", "Nmi",
+		"ForceOn", "PushPowerButton", "PowerCycle", "Suspend", "Pause", "Resume"}
+}
+
+// SystemActions returns the power actions of the fake BMC System of n, which
+// are its PowerActions or, if it has none, all of ResetTypes.
+func (n Node) SystemActions() []string {
+	if len(n.PowerActions) == 0 {
+		return ResetTypes()
+	}
+	return slices.Clone(n.PowerActions)
+}
+
+// nodeSystem returns the fake BMC System generated for node, with its URI
+// relative to base, and the SMD EthernetInterfaces of its interfaces.
+func nodeSystem(base *url.URL, node Node) (smd.System, []smd.EthernetInterface) {
+	log.Logger.Debug().Msgf("node %s: generating fake BMC System", node.Xname)
+	base.Path = "/redfish/v1/Systems/" + node.Xname
+
+	s := smd.System{
+		URI:  base.String(),
+		Name: node.Name,
+	}
+
+	// Create unique identifier for system
+	if sysUUID, err := uuid.NewRandom(); err != nil {
+		log.Logger.Warn().Err(err).Msgf("node %s: could not generate UUID for fake BMC System, it will be zero", node.Xname)
+	} else {
+		s.UUID = sysUUID.String()
+	}
+
+	// PCS requires the supported power actions, which are every possible
+	// ResetType unless the payload lists those the BMC actually supports
+	s.Actions = node.SystemActions()
+
+	// Node interfaces
+	var ifaces []smd.EthernetInterface
+	for idx, iface := range node.Ifaces {
+		newIface := schemas.EthernetInterface{
+			Name:        node.Xname,
+			Description: fmt.Sprintf("Interface %d for %s", idx, node.Name),
+			MAC:         iface.MACAddr,
+		}
+		if len(iface.IPAddrs) > 0 {
+			newIface.IP = iface.IPAddrs[0].IPAddr
+		}
+		s.EthernetInterfaces = append(s.EthernetInterfaces, newIface)
+		SMDIface := smd.EthernetInterface{
+			ComponentID: newIface.Name,
+			Type:        node.ComponentType(),
+			Description: newIface.Description,
+			MACAddress:  newIface.MAC,
+		}
+		for _, ip := range iface.IPAddrs {
+			SMDIface.IPAddresses = append(SMDIface.IPAddresses, smd.EthernetIP{
+				IPAddress: ip.IPAddr,
+				Network:   ip.Network,
+			})
+		}
+		ifaces = append(ifaces, SMDIface)
+	}
+
+	// Bond members, which are expected to have been defaulted by
+	// BondInterfaces. Only the primary member has the IP addresses of the
+	// bond, since the bond uses its MAC address.
+	for _, b := range node.Bonds {
+		for idx, mac := range b.Members {
+			newIface := schemas.EthernetInterface{
+				Name:        node.Xname,
+				Description: fmt.Sprintf("Member %d of bond %s for %s", idx, b.Name, node.Name),
+				MAC:         mac,
+			}
+			SMDIface := smd.EthernetInterface{
+				ComponentID: newIface.Name,
+				Type:        node.ComponentType(),
+				MACAddress:  newIface.MAC,
+			}
+			if normalizeMAC(mac) == normalizeMAC(b.Primary) {
+				newIface.Description += " [primary]"
+				for _, ip := range b.IPAddrs {
 					SMDIface.IPAddresses = append(SMDIface.IPAddresses, smd.EthernetIP{
 						IPAddress: ip.IPAddr,
 						Network:   ip.Network,
 					})
 				}
-				ifaces = append(ifaces, SMDIface)
+				if len(b.IPAddrs) > 0 {
+					newIface.IP = b.IPAddrs[0].IPAddr
+				}
 			}
-
-			systemMap[node.Xname] = "present"
-			log.Logger.Debug().Msgf("node %s: generated system: %v", node.Xname, s)
-			rfe.Systems = append(rfe.Systems, s)
-		} else {
-			log.Logger.Debug().Msgf("node %s: fake BMC System already exists, skipping creation", node.Xname)
+			SMDIface.Description = newIface.Description
+			s.EthernetInterfaces = append(s.EthernetInterfaces, newIface)
+			ifaces = append(ifaces, SMDIface)
 		}
+	}
 
-		// Create fake BMC "Manager" for node if it doesn't already exist
-		// BMC interface
-		if _, ok := managerMap[bmcXname]; !ok {
-			log.Logger.Debug().Msgf("BMC %s: generating fake BMC Manager", bmcXname)
-			base.Path = "/redfish/v1/Managers/" + bmcXname
+	return s, ifaces
+}
 
-			m := smd.Manager{
-				System: smd.System{
-					URI:  base.String(),
-					Name: bmcXname,
-				},
-				Type: "NodeBMC",
-			}
+// bmcManager returns the fake BMC Manager of type bmcType generated for the BMC
+// bmcXname with the interfaces bmcIfaces, with its URI relative to base, and
+// its UUID, which is zero if it could not be generated. multi is passed to
+// bmcIfaceDescription.
+func bmcManager(base *url.URL, bmcXname, bmcType string, bmcIfaces []BMCIface, multi bool) (smd.Manager, uuid.UUID) {
+	log.Logger.Debug().Msgf("BMC %s: generating fake BMC Manager", bmcXname)
+	base.Path = "/redfish/v1/Managers/" + bmcXname
 
-			// Create unique identifier for manager
-			if mngerUUID, err := uuid.NewRandom(); err != nil {
-				log.Logger.Warn().Err(err).Msgf("BMC %s: could not generate UUID for fake BMC Manager, it will be zero", bmcXname)
-			} else {
-				m.UUID = mngerUUID.String()
-				rfe.UID = mngerUUID // Redfish UUID will be fake Manager's UUID
-			}
+	m := smd.Manager{
+		System: smd.System{
+			URI:  base.String(),
+			Name: bmcXname,
+		},
+		Type: bmcType,
+	}
 
-			// BMC interface
-			ifaceBMC := schemas.EthernetInterface{
-				Name:        bmcXname,
-				Description: fmt.Sprintf("Interface for BMC %s", bmcXname),
-				MAC:         node.BMCMac,
-				IP:          node.BMCIP,
-			}
-			m.EthernetInterfaces = append(m.EthernetInterfaces, ifaceBMC)
-			managerMap[bmcXname] = "present"
-			log.Logger.Debug().Msgf("BMC %s: generated manager: %v", bmcXname, m)
-			rfe.Managers = append(rfe.Managers, m)
-		} else {
-			log.Logger.Debug().Msgf("BMC %s: fake BMC Manager already exists, skipping creation", bmcXname)
+	// Create unique identifier for manager
+	mngerUUID, err := uuid.NewRandom()
+	if err != nil {
+		log.Logger.Warn().Err(err).Msgf("BMC %s: could not generate UUID for fake BMC Manager, it will be zero", bmcXname)
+	} else {
+		m.UUID = mngerUUID.String()
+	}
+
+	// BMC interfaces, numbered by port since a port with several addresses
+	// is listed once for each
+	var ports []string
+	for _, iface := range bmcIfaces {
+		port := slices.Index(ports, normalizeMAC(iface.MACAddr))
+		if port < 0 {
+			port = len(ports)
+			ports = append(ports, normalizeMAC(iface.MACAddr))
 		}
-		rfes.RedfishEndpoints = append(rfes.RedfishEndpoints, rfe)
+		ifaceBMC := schemas.EthernetInterface{
+			Name:        bmcXname,
+			Description: bmcIfaceDescription(bmcXname, port, iface, multi),
+			MAC:         iface.MACAddr,
+			IP:          iface.IPAddr,
+		}
+		m.EthernetInterfaces = append(m.EthernetInterfaces, ifaceBMC)
+	}
+
+	return m, mngerUUID
+}
+
+// bmcIfaceDescription returns the description of the Manager EthernetInterface
+// generated for iface, an address of the port with index idx of the BMC
+// bmcXname. If multi is false, the BMC was described by bmc_mac and bmc_ip and
+// has only the one interface.
+func bmcIfaceDescription(bmcXname string, idx int, iface BMCIface, multi bool) string {
+	if !multi {
+		return fmt.Sprintf("Interface for BMC %s", bmcXname)
 	}
-	return comps, rfes, ifaces, nil
+	var desc string
+	if iface.Kind == BMCIfaceShared {
+		desc = fmt.Sprintf("Shared interface %d for BMC %s", idx, bmcXname)
+		if iface.SharedWith != "" {
+			desc += fmt.Sprintf(" (shares port of %s)", iface.SharedWith)
+		}
+	} else {
+		desc = fmt.Sprintf("Dedicated interface %d for BMC %s", idx, bmcXname)
+	}
+	if iface.IsIPv6() {
+		desc += " (IPv6)"
+	}
+	if iface.Primary {
+		desc += " [primary]"
+	}
+
+	return desc
 }
 
 // AddMemberToGroup adds xname to group, ensuring deduplication.
@@ -1,15 +1,13 @@
 package discover
 
 import (
-	"fmt"
 	"reflect"
 	"testing"
 
 	"github.com/google/uuid"
 
-	"github.com/openchami/schemas/schemas"
-
 	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/testutil"
 )
 
 func TestNodeList_String(t *testing.T) {
@@ -149,92 +147,435 @@ func TestDiscoveryInfoV2_Success(t *testing.T) {
 		t.Fatalf("DiscoveryInfoV2 returned error: %v", err)
 	}
 
-	// Components
-	if len(comps.Components) != 1 {
-		t.Fatalf("got %d components, want 1", len(comps.Components))
+	// Manager UUIDs are randomly generated, so make sure they are set before
+	// scrubbing them for the golden comparison.
+	for _, r := range rfes.RedfishEndpoints {
+		for _, m := range r.Managers {
+			if m.UUID == "" || m.UUID == uuid.Nil.String() {
+				t.Errorf("Manager.UUID = %q, want a real UUID", m.UUID)
+			}
+		}
+	}
+
+	testutil.AssertGoldenJSON(t, "discovery_info_v2", map[string]any{
+		"components":          comps,
+		"redfish_endpoints":   rfes,
+		"ethernet_interfaces": ifaces,
+	}, "uuid", "UUID")
+}
+
+func TestDiscoveryInfoV2_BMCInterfaces(t *testing.T) {
+	nl := NodeList{
+		Nodes: []Node{
+			{
+				Name:  "nid1",
+				NID:   1,
+				Xname: "x1000c0s0b0n0",
+				BMCIfaces: []BMCIface{
+					{MACAddr: "de:ca:fc:0f:fe:e1", IPAddr: "172.16.101.1", Kind: BMCIfaceShared, SharedWith: "de:ad:be:ee:ef:01"},
+					{MACAddr: "de:ca:fc:0f:fe:e2", IPAddr: "172.16.102.1"},
+				},
+				Ifaces: []Iface{
+					{
+						MACAddr: "de:ad:be:ee:ef:01",
+						IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "172.16.100.1"}},
+					},
+				},
+			},
+		},
 	}
-	c := comps.Components[0]
-	if want := nl.Nodes[0].Xname; c.ID != want {
-		t.Errorf("component ID = %q, want %q", c.ID, want)
+
+	_, rfes, _, err := DiscoveryInfoV2("http://example.com", nl)
+	if err != nil {
+		t.Fatalf("DiscoveryInfoV2 returned error: %v", err)
 	}
-	if c.NID != nl.Nodes[0].NID || c.Type != "Node" || c.State != "On" || !c.Enabled {
-		t.Errorf("component = %+v", c)
+	testutil.AssertGoldenJSON(t, "discovery_info_v2_bmc_interfaces", rfes, "uuid", "UUID")
+}
+
+func TestDiscoveryInfoV2_BMCInterfacesDualStack(t *testing.T) {
+	nl := NodeList{
+		Nodes: []Node{
+			{
+				Name:  "nid1",
+				NID:   1,
+				Xname: "x1000c0s0b0n0",
+				BMCIfaces: []BMCIface{
+					{MACAddr: "de:ca:fc:0f:fe:e1", IPAddr: "fd00:16::101"},
+					{MACAddr: "de:ca:fc:0f:fe:e1", IPAddr: "172.16.101.1"},
+					{MACAddr: "de:ca:fc:0f:fe:e2", IPAddr: "fd00:17::101"},
+				},
+				Ifaces: []Iface{
+					{
+						MACAddr: "de:ad:be:ee:ef:01",
+						IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "172.16.100.1"}, {Network: "mgmt6", IPAddr: "fd00:16::1"}},
+					},
+				},
+			},
+		},
 	}
 
-	// RedfishEndpoints
-	if len(rfes.RedfishEndpoints) != 1 {
-		t.Fatalf("got %d redfish endpoints, want 1", len(rfes.RedfishEndpoints))
+	_, rfes, _, err := DiscoveryInfoV2("http://example.com", nl)
+	if err != nil {
+		t.Fatalf("DiscoveryInfoV2 returned error: %v", err)
 	}
-	r := rfes.RedfishEndpoints[0]
-	node := nl.Nodes[0]
-	if r.Name != node.Name || r.Type != "NodeBMC" || r.MACAddr != node.BMCMac || r.IPAddress != node.BMCIP || r.FQDN != node.BMCFQDN {
-		t.Errorf("RedfishEndpoint fields = %+v", r)
+	if rfe := rfes.RedfishEndpoints[0]; rfe.MACAddr != "de:ca:fc:0f:fe:e1" || rfe.IPAddress != "172.16.101.1" {
+		t.Errorf("redfish endpoint has MAC %s and IP %s, want the IPv4 address of the first port", rfe.MACAddr, rfe.IPAddress)
 	}
-	if r.SchemaVersion != 1 {
-		t.Errorf("SchemaVersion = %d, want 1", r.SchemaVersion)
+	testutil.AssertGoldenJSON(t, "discovery_info_v2_bmc_interfaces_dual_stack", rfes, "uuid", "UUID")
+}
+
+func TestDiscoveryInfoV2_Virtual(t *testing.T) {
+	mgmt := func(mac, ip string) []Iface {
+		return []Iface{{MACAddr: mac, IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: ip}}}}
+	}
+	nl := NodeList{
+		Nodes: []Node{
+			{Name: "metal1", NID: 1, Xname: "x1000c0s0b0n0", BMCMac: "de:ca:fc:0f:fe:e1", Ifaces: mgmt("de:ad:be:ee:ef:01", "172.16.100.1")},
+			{Name: "vm1", NID: 2, Xname: "x1000c0s0b1n0", Virtual: true, Hypervisor: "x1000c0s0b1", BMCIP: "172.16.101.10", Ifaces: mgmt("52:54:00:00:00:01", "172.16.100.11")},
+			{Name: "vm2", NID: 3, Xname: "x1000c0s0b1n1", Virtual: true, Hypervisor: "x1000c0s0b1", Ifaces: mgmt("52:54:00:00:00:02", "172.16.100.12")},
+			{Name: "vm3", NID: 4, Xname: "x1000c0s1b0n0", Virtual: true, Ifaces: mgmt("52:54:00:00:00:03", "172.16.100.13")},
+		},
 	}
 
-	// Systems
-	if len(r.Systems) != 1 {
-		t.Fatalf("got %d systems, want 1", len(r.Systems))
+	comps, rfes, ifaces, err := DiscoveryInfoV2("http://example.com", nl)
+	if err != nil {
+		t.Fatalf("DiscoveryInfoV2 returned error: %v", err)
 	}
-	sys := r.Systems[0]
-	expectedSysURI := fmt.Sprintf("%s/redfish/v1/Systems/%s", base, node.Xname)
-	if sys.URI != expectedSysURI || sys.Name != node.Name {
-		t.Errorf("System = %+v", sys)
+	for i, want := range []string{ComponentTypeNode, ComponentTypeVirtualNode, ComponentTypeVirtualNode, ComponentTypeVirtualNode} {
+		if got := comps.Components[i].Type; got != want {
+			t.Errorf("component %s has type %s, want %s", comps.Components[i].ID, got, want)
+		}
+	}
+	if len(ifaces) != 4 || ifaces[3].ComponentID != "x1000c0s1b0n0" || ifaces[3].Type != ComponentTypeVirtualNode {
+		t.Errorf("DiscoveryInfoV2 returned ethernet interfaces %+v", ifaces)
+	}
+	testutil.AssertGoldenJSON(t, "discovery_info_v2_virtual", rfes, "uuid", "UUID")
+}
+
+func TestDiscoveryInfoV2_Devices(t *testing.T) {
+	nl := NodeList{
+		Nodes: []Node{
+			{Name: "sw1", Xname: "x3000c0r1b0", Type: ComponentTypeRouterBMC, BMCMac: "de:ca:fc:0f:fe:01", BMCIP: "172.16.101.1"},
+			{Name: "pdu0", Xname: "x3000m0p0", Type: ComponentTypeCabinetPDU, BMCMac: "de:ca:fc:0f:fe:02", BMCIP: "172.16.101.2"},
+			{Name: "pdu1", Xname: "x3000m0p1", Type: ComponentTypeCabinetPDU, BMCMac: "de:ca:fc:0f:fe:02", BMCIP: "172.16.101.2"},
+			{Name: "mgmt1", NID: 7, Xname: "x3000c0w1", Type: ComponentTypeMgmtSwitch, Ifaces: []Iface{{MACAddr: "de:ad:be:ee:ef:01", IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "172.16.100.1"}}}}},
+		},
+	}
+
+	comps, rfes, ifaces, err := DiscoveryInfoV2("http://example.com", nl)
+	if err != nil {
+		t.Fatalf("DiscoveryInfoV2 returned error: %v", err)
+	}
+	for i, want := range []string{ComponentTypeRouterBMC, ComponentTypeCabinetPDU, ComponentTypeCabinetPDU, ComponentTypeMgmtSwitch} {
+		if got := comps.Components[i]; got.Type != want || got.NID != 0 {
+			t.Errorf("component %s has type %s and NID %d, want type %s and no NID", got.ID, got.Type, got.NID, want)
+		}
+	}
+	if len(ifaces) != 1 || ifaces[0].ComponentID != "x3000c0w1" || ifaces[0].Type != ComponentTypeMgmtSwitch {
+		t.Errorf("DiscoveryInfoV2 returned ethernet interfaces %+v", ifaces)
+	}
+	testutil.AssertGoldenJSON(t, "discovery_info_v2_devices", rfes, "uuid", "UUID")
+
+	nl.Nodes = []Node{{Xname: "x3000c0r1b0", Type: "Toaster"}}
+	if _, _, _, err := DiscoveryInfoV2("http://example.com", nl); err == nil {
+		t.Error("DiscoveryInfoV2 of unknown type returned no error")
+	}
+	nl.Nodes = []Node{{Xname: "x3000c0s1b0n0", Type: ComponentTypeCabinetPDU}}
+	if _, _, _, err := DiscoveryInfoV2("http://example.com", nl); err == nil {
+		t.Error("DiscoveryInfoV2 of xname not matching type returned no error")
+	}
+}
+
+func TestDiscoveryInfoV3_Warnings(t *testing.T) {
+	mgmt := func(mac, ip string) []Iface {
+		return []Iface{{MACAddr: mac, IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: ip}}}}
+	}
+	nl := NodeList{
+		Nodes: []Node{
+			{Name: "node1", NID: 1, Xname: "x1000c0s0b0n0", BMCMac: "de:ca:fc:0f:fe:e1", Ifaces: mgmt("de:ad:be:ee:ef:01", "172.16.100.1")},
+			{Name: "node1again", NID: 2, Xname: "x1000c0s0b0n0", BMCMac: "de:ca:fc:0f:fe:e1", Ifaces: mgmt("de:ad:be:ee:ef:02", "172.16.100.2")},
+			{Name: "odd", NID: 3, Xname: "node07", Ifaces: mgmt("de:ad:be:ee:ef:03", "172.16.100.3")},
+			{Name: "vm1", NID: 4, Xname: "x1000c0s2b0n0", Virtual: true, BMCIP: "172.16.101.4", Ifaces: mgmt("52:54:00:00:00:04", "172.16.100.4")},
+			{Name: "bare", NID: 5, Xname: "x1000c0s3b0n0", BMCMac: "de:ca:fc:0f:fe:e5"},
+		},
+	}
+
+	_, _, _, warnings, err := DiscoveryInfoV3("http://example.com", nl, ErrorPolicyFailFast)
+	if err != nil {
+		t.Fatalf("DiscoveryInfoV3 returned error: %v", err)
+	}
+	want := []struct {
+		node  int
+		xname string
+		code  WarningCode
+	}{
+		{1, "x1000c0s0b0n0", WarningDuplicateXname},
+		{2, "node07", WarningBMCXnameFallback},
+		{3, "x1000c0s2b0n0", WarningIgnoredBMC},
+		{4, "x1000c0s3b0n0", WarningNoInterfaces},
+	}
+	if len(warnings) != len(want) {
+		t.Fatalf("DiscoveryInfoV3 returned warnings %+v, want %d", warnings, len(want))
+	}
+	for i, w := range want {
+		if got := warnings[i]; got.Node != w.node || got.Xname != w.xname || got.Code != w.code || got.Message == "" {
+			t.Errorf("warning %d = %+v, want node %d (%s) with code %s", i, got, w.node, w.xname, w.code)
+		}
+	}
+	if got := CountWarnings(warnings); got[WarningNoInterfaces] != 1 || len(got) != 4 {
+		t.Errorf("CountWarnings() = %v", got)
+	}
+}
+
+func TestDiscoveryInfoV3_ErrorPolicy(t *testing.T) {
+	mgmt := func(mac, ip string) []Iface {
+		return []Iface{{MACAddr: mac, IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: ip}}}}
+	}
+	nl := NodeList{
+		Nodes: []Node{
+			{Name: "node1", NID: 1, Xname: "x1000c0s0b0n0", BMCMac: "de:ca:fc:0f:fe:e1", Ifaces: append(mgmt("de:ad:be:ee:ef:01", "172.16.100.1"), Iface{IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "172.16.100.11"}}})},
+			{Name: "node2", NID: 2, Xname: "x1000c0s1b0n0", BMCMac: "de:ca:fc:0f:fe:e2", BMCPassword: "secret", BMCPasswordFile: "/etc/secret", Ifaces: mgmt("de:ad:be:ee:ef:02", "172.16.100.2")},
+			{Name: "node3", NID: 3, Xname: "x1000c0s2b0n0", BMCMac: "de:ca:fc:0f:fe:e3", Ifaces: mgmt("de:ad:be:ee:ef:03", "172.16.100.3")},
+		},
+	}
+
+	if _, _, _, _, err := DiscoveryInfoV3("http://example.com", nl, ErrorPolicyFailFast); err == nil {
+		t.Errorf("DiscoveryInfoV3 with %s returned no error", ErrorPolicyFailFast)
 	}
-	if len(sys.EthernetInterfaces) != 1 {
-		t.Fatalf("got %d system EthernetInterfaces, want 1", len(sys.EthernetInterfaces))
+
+	tests := []struct {
+		policy    ErrorPolicy
+		wantComps []string
+		wantIface []string
+		wantCodes []WarningCode
+	}{
+		{ErrorPolicySkipNode, []string{"x1000c0s2b0n0"}, []string{"de:ad:be:ee:ef:03"}, []WarningCode{WarningSkippedNode, WarningSkippedNode}},
+		{ErrorPolicySkipInterface, []string{"x1000c0s0b0n0", "x1000c0s2b0n0"}, []string{"de:ad:be:ee:ef:01", "de:ad:be:ee:ef:03"}, []WarningCode{WarningSkippedInterface, WarningSkippedNode}},
 	}
-	e := sys.EthernetInterfaces[0]
-	if want := (schemas.EthernetInterface{
-		Name:        node.Xname,
-		Description: fmt.Sprintf("Interface 0 for %s", node.Name),
-		MAC:         node.Ifaces[0].MACAddr,
-		IP:          node.Ifaces[0].IPAddrs[0].IPAddr,
-	}); !reflect.DeepEqual(e, want) {
-		t.Errorf("System.EthernetInterface = %+v, want %+v", e, want)
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			comps, rfes, ifaces, warnings, err := DiscoveryInfoV3("http://example.com", nl, tt.policy)
+			if err != nil {
+				t.Fatalf("DiscoveryInfoV3 returned error: %v", err)
+			}
+			var gotComps, gotIfaces []string
+			for _, c := range comps.Components {
+				gotComps = append(gotComps, c.ID)
+			}
+			for _, i := range ifaces {
+				gotIfaces = append(gotIfaces, i.MACAddress)
+			}
+			if !reflect.DeepEqual(gotComps, tt.wantComps) || len(rfes.RedfishEndpoints) != len(tt.wantComps) {
+				t.Errorf("DiscoveryInfoV3 generated components %v and %d redfish endpoints, want %v", gotComps, len(rfes.RedfishEndpoints), tt.wantComps)
+			}
+			if !reflect.DeepEqual(gotIfaces, tt.wantIface) {
+				t.Errorf("DiscoveryInfoV3 generated ethernet interfaces %v, want %v", gotIfaces, tt.wantIface)
+			}
+			var gotCodes []WarningCode
+			for _, w := range warnings {
+				gotCodes = append(gotCodes, w.Code)
+			}
+			if !reflect.DeepEqual(gotCodes, tt.wantCodes) {
+				t.Errorf("DiscoveryInfoV3 returned warnings %+v, want codes %v", warnings, tt.wantCodes)
+			}
+		})
 	}
-	if !reflect.DeepEqual(
-		sys.Actions,
-		[]string{"On", "ForceOff", "GracefulShutdown", "GracefulRestart", "ForceRestart", "Nmi", "ForceOn",
-			"PushPowerButton", "PowerCycle", "Suspend", "Pause", "Resume"},
-	) {
-		t.Error("System.Actions does not match the expected value")
+}
+
+func TestDiscoveryInfoV3_MACOnlyInterface(t *testing.T) {
+	nl := NodeList{
+		Nodes: []Node{
+			{Name: "node1", NID: 1, Xname: "x1000c0s0b0n0", BMCMac: "de:ca:fc:0f:fe:e1", Ifaces: []Iface{
+				{MACAddr: "de:ad:be:ee:ef:01", IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "172.16.100.1"}}},
+				{MACAddr: "de:ad:be:ee:ef:11"},
+			}},
+		},
 	}
 
-	// Managers
-	if len(r.Managers) != 1 {
-		t.Fatalf("got %d managers, want 1", len(r.Managers))
+	_, rfes, ifaces, warnings, err := DiscoveryInfoV3("http://example.com", nl, ErrorPolicyFailFast)
+	if err != nil {
+		t.Fatalf("DiscoveryInfoV3 with %s returned error: %v", ErrorPolicyFailFast, err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("DiscoveryInfoV3 returned warnings %+v, want none", warnings)
 	}
-	m := r.Managers[0]
-	expectedMgrURI := fmt.Sprintf("%s/redfish/v1/Managers/%s", base, node.Xname)
-	if m.System.URI != expectedMgrURI || m.System.Name != node.Xname || m.Type != "NodeBMC" {
-		t.Errorf("Manager = %+v", m)
+	if len(ifaces) != 2 || ifaces[1].MACAddress != "de:ad:be:ee:ef:11" || len(ifaces[1].IPAddresses) != 0 {
+		t.Errorf("DiscoveryInfoV3 generated ethernet interfaces %+v, want the second with no IP addresses", ifaces)
 	}
-	if m.UUID == uuid.Nil.String() {
-		t.Error("Manager.UUID is nil, want a real UUID")
+	if sys := rfes.RedfishEndpoints[0].Systems[0]; len(sys.EthernetInterfaces) != 2 || sys.EthernetInterfaces[1].IP != "" {
+		t.Errorf("DiscoveryInfoV3 generated System interfaces %+v, want the second with no IP", sys.EthernetInterfaces)
 	}
 
-	// EthernetInterface slice
-	if len(ifaces) != len(node.Ifaces) {
-		t.Fatalf("got %d smd.EthernetInterface, want %d", len(ifaces), len(node.Ifaces))
+	if _, _, _, err := DiscoveryInfoV2("http://example.com", nl); err != nil {
+		t.Errorf("DiscoveryInfoV2 returned error: %v", err)
+	}
+}
+
+func TestDiscoveryInfoV2_PowerActions(t *testing.T) {
+	mgmt := func(mac, ip string) []Iface {
+		return []Iface{{MACAddr: mac, IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: ip}}}}
 	}
-	se := ifaces[0]
-	if se.ComponentID != node.Xname || se.Type != "Node" {
-		t.Errorf("EthernetInterface = %+v", se)
+	nl := NodeList{
+		Nodes: []Node{
+			{Name: "node1", NID: 1, Xname: "x1000c0s0b0n0", BMCMac: "de:ca:fc:0f:fe:e1", Ifaces: mgmt("de:ad:be:ee:ef:01", "172.16.100.1"), PowerActions: []string{"On", "ForceOff"}},
+			{Name: "node2", NID: 2, Xname: "x1000c0s0b0n1", BMCMac: "de:ca:fc:0f:fe:e1", Ifaces: mgmt("de:ad:be:ee:ef:02", "172.16.100.2")},
+		},
 	}
-	if len(se.IPAddresses) != len(node.Ifaces[0].IPAddrs) {
-		t.Fatalf("got %d IPAddresses, want %d", len(se.IPAddresses), len(node.Ifaces[0].IPAddrs))
+
+	_, rfes, _, err := DiscoveryInfoV2("http://example.com", nl)
+	if err != nil {
+		t.Fatalf("DiscoveryInfoV2 returned error: %v", err)
 	}
-	for i, ip := range se.IPAddresses {
-		orig := node.Ifaces[0].IPAddrs[i]
-		if ip.IPAddress != orig.IPAddr || ip.Network != orig.Network {
-			t.Errorf("IPAddresses[%d] = %+v, want %+v", i, ip, orig)
+	if len(rfes.RedfishEndpoints) != 1 || len(rfes.RedfishEndpoints[0].Systems) != 2 {
+		t.Fatalf("DiscoveryInfoV2 returned redfish endpoints %+v, want one with 2 systems", rfes.RedfishEndpoints)
+	}
+	for i, want := range [][]string{{"On", "ForceOff"}, ResetTypes()} {
+		if s := rfes.RedfishEndpoints[0].Systems[i]; !reflect.DeepEqual(s.Actions, want) {
+			t.Errorf("system %d has actions %v, want %v", i, s.Actions, want)
 		}
 	}
 }
 
+func TestDiscoveryInfoV3_SharedBMC(t *testing.T) {
+	mgmt := func(mac, ip string) []Iface {
+		return []Iface{{MACAddr: mac, IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: ip}}}}
+	}
+	nl := NodeList{
+		Nodes: []Node{
+			{Name: "nid1", NID: 1, Xname: "x1000c0s0b0n0", BMCMac: "de:ca:fc:0f:fe:e1", BMCIP: "172.16.101.1", Ifaces: mgmt("de:ad:be:ee:ef:01", "172.16.100.1")},
+			{Name: "nid2", NID: 2, Xname: "x1000c0s0b0n1", BMCMac: "DE:CA:FC:0F:FE:E1", BMCIP: "172.16.101.1", Ifaces: mgmt("de:ad:be:ee:ef:02", "172.16.100.2")},
+			{Name: "nid3", NID: 3, Xname: "x1000c0s0b0n2", Ifaces: mgmt("de:ad:be:ee:ef:03", "172.16.100.3")},
+			{Name: "nid4", NID: 4, Xname: "x1000c0s0b0n3", BMCMac: "de:ca:fc:0f:fe:e1", BMCIP: "172.16.101.2", Ifaces: mgmt("de:ad:be:ee:ef:04", "172.16.100.4")},
+			{Name: "nid5", NID: 5, Xname: "x1000c0s1b0n0", BMCMac: "de:ca:fc:0f:fe:e5", Ifaces: mgmt("de:ad:be:ee:ef:05", "172.16.100.5")},
+		},
+	}
+
+	comps, rfes, ifaces, warnings, err := DiscoveryInfoV3("http://example.com", nl, ErrorPolicyFailFast)
+	if err != nil {
+		t.Fatalf("DiscoveryInfoV3 returned error: %v", err)
+	}
+	if len(comps.Components) != 5 || len(ifaces) != 5 {
+		t.Errorf("DiscoveryInfoV3 returned %d components and %d ethernet interfaces, want 5 of each", len(comps.Components), len(ifaces))
+	}
+	if len(rfes.RedfishEndpoints) != 2 {
+		t.Fatalf("DiscoveryInfoV3 returned %d redfish endpoints, want 2", len(rfes.RedfishEndpoints))
+	}
+	rfe := rfes.RedfishEndpoints[0]
+	if rfe.ID != "x1000c0s0b0" || rfe.MACAddr != "de:ca:fc:0f:fe:e1" || rfe.IPAddress != "172.16.101.1" || len(rfe.Managers) != 1 {
+		t.Errorf("redfish endpoint %s has MAC %s, IP %s, and %d managers, want shared BMC x1000c0s0b0 with one manager", rfe.ID, rfe.MACAddr, rfe.IPAddress, len(rfe.Managers))
+	}
+	var systems []string
+	for _, s := range rfe.Systems {
+		systems = append(systems, s.Name)
+	}
+	if want := []string{"nid1", "nid2", "nid3", "nid4"}; !reflect.DeepEqual(systems, want) {
+		t.Errorf("redfish endpoint %s has systems %v, want %v", rfe.ID, systems, want)
+	}
+
+	if len(warnings) != 1 || warnings[0].Node != 3 || warnings[0].Code != WarningBMCMismatch {
+		t.Errorf("DiscoveryInfoV3 returned warnings %v, want a BMC mismatch for node 3", warnings)
+	}
+}
+
+func TestNode_BMCInterfaces(t *testing.T) {
+	nodeIfaces := []Iface{{MACAddr: "de:ad:be:ee:ef:01"}}
+	tests := []struct {
+		name    string
+		node    Node
+		want    []BMCIface
+		wantErr bool
+	}{
+		{
+			name: "none",
+		},
+		{
+			name: "legacy",
+			node: Node{BMCMac: "de:ca:fc:0f:fe:e1", BMCIP: "172.16.101.1"},
+			want: []BMCIface{{MACAddr: "de:ca:fc:0f:fe:e1", IPAddr: "172.16.101.1", Kind: BMCIfaceDedicated, Primary: true}},
+		},
+		{
+			name: "first dedicated is primary",
+			node: Node{Ifaces: nodeIfaces, BMCIfaces: []BMCIface{
+				{MACAddr: "a", Kind: BMCIfaceShared, SharedWith: "DE:AD:BE:EE:EF:01"},
+				{MACAddr: "b"},
+			}},
+			want: []BMCIface{
+				{MACAddr: "a", Kind: BMCIfaceShared, SharedWith: "DE:AD:BE:EE:EF:01"},
+				{MACAddr: "b", Kind: BMCIfaceDedicated, Primary: true},
+			},
+		},
+		{
+			name: "explicit primary",
+			node: Node{BMCIfaces: []BMCIface{{MACAddr: "a"}, {MACAddr: "b", Kind: BMCIfaceShared, Primary: true}}},
+			want: []BMCIface{{MACAddr: "a", Kind: BMCIfaceDedicated}, {MACAddr: "b", Kind: BMCIfaceShared, Primary: true}},
+		},
+		{
+			name: "all shared",
+			node: Node{BMCIfaces: []BMCIface{{MACAddr: "a", Kind: BMCIfaceShared}}},
+			want: []BMCIface{{MACAddr: "a", Kind: BMCIfaceShared, Primary: true}},
+		},
+		{
+			name: "IPv4 preferred as primary",
+			node: Node{BMCIfaces: []BMCIface{{MACAddr: "a", IPAddr: "fd00::101"}, {MACAddr: "a", IPAddr: "172.16.101.1"}}},
+			want: []BMCIface{
+				{MACAddr: "a", IPAddr: "fd00::101", Kind: BMCIfaceDedicated},
+				{MACAddr: "a", IPAddr: "172.16.101.1", Kind: BMCIfaceDedicated, Primary: true},
+			},
+		},
+		{
+			name: "IPv6 only",
+			node: Node{BMCIfaces: []BMCIface{{MACAddr: "a", IPAddr: "fd00::101"}}},
+			want: []BMCIface{{MACAddr: "a", IPAddr: "fd00::101", Kind: BMCIfaceDedicated, Primary: true}},
+		},
+		{
+			name:    "port listed with different kinds",
+			node:    Node{BMCIfaces: []BMCIface{{MACAddr: "a", IPAddr: "172.16.101.1"}, {MACAddr: "A", IPAddr: "fd00::101", Kind: BMCIfaceShared}}},
+			wantErr: true,
+		},
+		{
+			name:    "combined with bmc_mac",
+			node:    Node{BMCMac: "a", BMCIfaces: []BMCIface{{MACAddr: "b"}}},
+			wantErr: true,
+		},
+		{
+			name:    "no mac",
+			node:    Node{BMCIfaces: []BMCIface{{IPAddr: "172.16.101.1"}}},
+			wantErr: true,
+		},
+		{
+			name:    "unknown kind",
+			node:    Node{BMCIfaces: []BMCIface{{MACAddr: "a", Kind: "sideband"}}},
+			wantErr: true,
+		},
+		{
+			name:    "two primaries",
+			node:    Node{BMCIfaces: []BMCIface{{MACAddr: "a", Primary: true}, {MACAddr: "b", Primary: true}}},
+			wantErr: true,
+		},
+		{
+			name:    "shared with unknown interface",
+			node:    Node{Ifaces: nodeIfaces, BMCIfaces: []BMCIface{{MACAddr: "a", Kind: BMCIfaceShared, SharedWith: "ff:ff:ff:ff:ff:ff"}}},
+			wantErr: true,
+		},
+		{
+			name:    "dedicated shared with",
+			node:    Node{Ifaces: nodeIfaces, BMCIfaces: []BMCIface{{MACAddr: "a", SharedWith: "de:ad:be:ee:ef:01"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.node.BMCInterfaces()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("BMCInterfaces() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BMCInterfaces() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestAddMemberToGroup(t *testing.T) {
 	newGroup := func(members []string) smd.Group {
 		var g smd.Group
@@ -0,0 +1,254 @@
+package discover
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// expandMaxNodes is the most nodes a compact node entry can describe, so that a
+// typo in a range does not exhaust memory.
+const expandMaxNodes = 100000
+
+// expandTemplate matches a template in a string value of a compact node entry,
+// capturing the variable, the offset, and the width and verb of the format,
+// e.g. {nid}, {nid+100}, or {index:02x}.
+var expandTemplate = regexp.MustCompile(`\{(nid|index)([+-][0-9]+)?(?::(0?[0-9]+)?([dx])?)?\}`)
+
+// expandNodes replaces each compact entry in the nodes of payload m with the
+// nodes it describes, in place. An entry is compact if its xname contains
+// ranges in brackets (e.g. x3000c0s[0-15]b0n0, or x3000c0s[0-3,8]b[0-1]n0 for
+// every combination), its nid is a string of NIDs and inclusive NID ranges
+// (e.g. "1-16" or "1-8,20"), or both, in which case they must describe the same
+// number of nodes. A single nid with an xname range is the NID of the first
+// node, the others following it.
+//
+// In every string of an entry, compact or not, {nid} and {index} (the 0-based
+// position of the node in its entry) are replaced with their value, optionally
+// offset and formatted with a width and a verb, d (decimal, the default) or x
+// (hexadecimal), e.g. 10.0.0.{nid+100} or de:ad:be:ef:00:{nid:02x}.
+func expandNodes(m map[string]any) error {
+	nodes, ok := m["nodes"].([]any)
+	if !ok {
+		return nil
+	}
+	var expanded []any
+	for idx, n := range nodes {
+		node, ok := n.(map[string]any)
+		if !ok {
+			expanded = append(expanded, n)
+			continue
+		}
+		ns, err := expandNode(node)
+		if err != nil {
+			return fmt.Errorf("node %d: %w", idx, err)
+		}
+		expanded = append(expanded, ns...)
+	}
+	m["nodes"] = expanded
+
+	return nil
+}
+
+// expandNode returns the nodes described by the node entry node (see
+// expandNodes).
+func expandNode(node map[string]any) ([]any, error) {
+	xname, _ := node["xname"].(string)
+	xnames, err := ExpandRanges(xname)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xname %q: %w", xname, err)
+	}
+
+	var nids []int64
+	switch v := node["nid"].(type) {
+	case nil:
+	case int:
+		nids = []int64{int64(v)}
+	case int64:
+		nids = []int64{v}
+	case float64:
+		nids = []int64{int64(v)}
+	case string:
+		ranges, err := parseNIDRanges(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid nid %q: %w", v, err)
+		}
+		for _, r := range ranges {
+			if r[1]-r[0] >= expandMaxNodes-int64(len(nids)) {
+				return nil, fmt.Errorf("nid %q describes more than %d nodes", v, expandMaxNodes)
+			}
+			for nid := r[0]; nid <= r[1]; nid++ {
+				nids = append(nids, nid)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid nid %v: must be a number or a string of NIDs and NID ranges", v)
+	}
+
+	count := len(xnames)
+	switch {
+	case len(nids) > 1 && count == 1:
+		if !expandTemplate.MatchString(xname) {
+			return nil, fmt.Errorf("nid %v describes %d nodes but xname %q has no range or template, so they would all have the same xname", node["nid"], len(nids), xname)
+		}
+		count = len(nids)
+		xnames = make([]string, count)
+		for i := range xnames {
+			xnames[i] = xname
+		}
+	case len(nids) > 1 && len(nids) != count:
+		return nil, fmt.Errorf("nid %v describes %d nodes but xname %q describes %d", node["nid"], len(nids), xname, count)
+	case len(nids) == 1 && count > 1:
+		for i := 1; i < count; i++ {
+			nids = append(nids, nids[0]+int64(i))
+		}
+	}
+
+	var out []any
+	for i := range count {
+		vars := map[string]int64{"index": int64(i)}
+		if len(nids) > 0 {
+			vars["nid"] = nids[i]
+		}
+		n, err := expandValue(node, vars)
+		if err != nil {
+			return nil, err
+		}
+		nm := n.(map[string]any)
+		if len(xnames) > 0 && xnames[i] != "" {
+			if nm["xname"], err = expandString(xnames[i], vars); err != nil {
+				return nil, err
+			}
+		}
+		if len(nids) > 0 {
+			nm["nid"] = nids[i]
+		}
+		out = append(out, nm)
+	}
+
+	return out, nil
+}
+
+// expandValue returns a copy of the generic value v with the templates in its
+// strings replaced with vars.
+func expandValue(v any, vars map[string]int64) (any, error) {
+	switch t := v.(type) {
+	case string:
+		return expandString(t, vars)
+	case map[string]any:
+		m := make(map[string]any, len(t))
+		for k, e := range t {
+			ev, err := expandValue(e, vars)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = ev
+		}
+		return m, nil
+	case []any:
+		s := make([]any, len(t))
+		for i, e := range t {
+			ev, err := expandValue(e, vars)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = ev
+		}
+		return s, nil
+	}
+
+	return v, nil
+}
+
+// expandString replaces the templates in s with vars.
+func expandString(s string, vars map[string]int64) (string, error) {
+	var err error
+	out := expandTemplate.ReplaceAllStringFunc(s, func(tmpl string) string {
+		m := expandTemplate.FindStringSubmatch(tmpl)
+		val, ok := vars[m[1]]
+		if !ok {
+			err = fmt.Errorf("template %s in %q used without a nid", tmpl, s)
+			return tmpl
+		}
+		if m[2] != "" {
+			offset, _ := strconv.ParseInt(m[2], 10, 64)
+			val += offset
+		}
+		verb := "d"
+		if m[4] != "" {
+			verb = m[4]
+		}
+		return fmt.Sprintf("%"+m[3]+verb, val)
+	})
+
+	return out, err
+}
+
+// ExpandRanges returns the strings described by s, which may contain ranges
+// in brackets: comma-separated lists of numbers and inclusive number ranges
+// (e.g. [0-15] or [1,3,5-7]). With more than one, every combination is
+// returned, the last range varying fastest. Numbers whose start has a leading
+// zero are padded to its width (e.g. [00-15]). A string without ranges is
+// returned as is. It is an error for s to describe more than 100000 strings.
+func ExpandRanges(s string) ([]string, error) {
+	start := strings.Index(s, "[")
+	if start < 0 {
+		if strings.Contains(s, "]") {
+			return nil, fmt.Errorf("unmatched ]")
+		}
+		return []string{s}, nil
+	}
+	end := strings.Index(s[start:], "]")
+	if end < 0 {
+		return nil, fmt.Errorf("unmatched [")
+	}
+	end += start
+	if strings.Contains(s[:start], "]") {
+		return nil, fmt.Errorf("unmatched ]")
+	}
+	rest, err := ExpandRanges(s[end+1:])
+	if err != nil {
+		return nil, err
+	}
+
+	var nums []string
+	for _, part := range strings.Split(s[start+1:end], ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		from, err := strconv.ParseInt(first, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q in range", first)
+		}
+		to := from
+		if isRange {
+			if to, err = strconv.ParseInt(last, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q in range", last)
+			}
+		}
+		if to < from {
+			return nil, fmt.Errorf("invalid range %q: end is less than start", part)
+		}
+		width := 0
+		if len(first) > 1 && first[0] == '0' {
+			width = len(first)
+		}
+		if to-from >= expandMaxNodes-int64(len(nums)) {
+			return nil, fmt.Errorf("range %q describes more than %d values", s[start:end+1], expandMaxNodes)
+		}
+		for n := from; n <= to; n++ {
+			nums = append(nums, fmt.Sprintf("%0*d", width, n))
+		}
+	}
+	if len(nums)*len(rest) > expandMaxNodes {
+		return nil, fmt.Errorf("ranges describe more than %d values", expandMaxNodes)
+	}
+
+	var out []string
+	for _, n := range nums {
+		for _, r := range rest {
+			out = append(out, s[:start]+n+r)
+		}
+	}
+
+	return out, nil
+}
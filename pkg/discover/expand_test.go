@@ -0,0 +1,107 @@
+package discover
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandRanges(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    []string
+		wantErr bool
+	}{
+		{s: "x3000c0s0b0n0", want: []string{"x3000c0s0b0n0"}},
+		{s: "x3000c0s[0-2]b0n0", want: []string{"x3000c0s0b0n0", "x3000c0s1b0n0", "x3000c0s2b0n0"}},
+		{s: "x3000c0s[1,3-4]b[0-1]n0", want: []string{"x3000c0s1b0n0", "x3000c0s1b1n0", "x3000c0s3b0n0", "x3000c0s3b1n0", "x3000c0s4b0n0", "x3000c0s4b1n0"}},
+		{s: "node[08-10]", want: []string{"node08", "node09", "node10"}},
+		{s: "x3000c0s[0-", wantErr: true},
+		{s: "x3000c0s0]", wantErr: true},
+		{s: "x3000c0s[a-b]", wantErr: true},
+		{s: "x3000c0s[5-1]", wantErr: true},
+		{s: "x3000c0s[0-999999999]", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ExpandRanges(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandRanges() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExpandRanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandNodeList(t *testing.T) {
+	data := map[string]any{
+		"version": 1,
+		"nodes": []any{
+			map[string]any{
+				"name":    "nid{nid:03}",
+				"nid":     "1-3",
+				"xname":   "x3000c0s[0-2]b0n0",
+				"bmc_mac": "de:ca:fc:0f:ee:{nid:02x}",
+				"bmc_ip":  "10.0.0.{nid+100}",
+				"groups":  []any{"compute"},
+				"interfaces": []any{
+					map[string]any{
+						"mac_addr": "de:ad:be:ee:ee:{nid:02x}",
+						"ip_addrs": []any{map[string]any{"network": "mgmt", "ip_addr": "10.1.0.{nid}"}},
+					},
+				},
+			},
+			map[string]any{
+				"name":  "login{index}",
+				"nid":   float64(20),
+				"xname": "x3000c1s[4-5]b0n0",
+			},
+			map[string]any{
+				"name":  "head",
+				"nid":   float64(100),
+				"xname": "x3000c2s0b0n0",
+			},
+		},
+	}
+	nl, err := ExpandNodeList(data)
+	if err != nil {
+		t.Fatalf("ExpandNodeList() returned error: %v", err)
+	}
+	want := []Node{
+		{Name: "nid001", NID: 1, Xname: "x3000c0s0b0n0", BMCMac: "de:ca:fc:0f:ee:01", BMCIP: "10.0.0.101", Groups: []string{"compute"},
+			Ifaces: []Iface{{MACAddr: "de:ad:be:ee:ee:01", IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "10.1.0.1"}}}}},
+		{Name: "nid002", NID: 2, Xname: "x3000c0s1b0n0", BMCMac: "de:ca:fc:0f:ee:02", BMCIP: "10.0.0.102", Groups: []string{"compute"},
+			Ifaces: []Iface{{MACAddr: "de:ad:be:ee:ee:02", IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "10.1.0.2"}}}}},
+		{Name: "nid003", NID: 3, Xname: "x3000c0s2b0n0", BMCMac: "de:ca:fc:0f:ee:03", BMCIP: "10.0.0.103", Groups: []string{"compute"},
+			Ifaces: []Iface{{MACAddr: "de:ad:be:ee:ee:03", IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "10.1.0.3"}}}}},
+		{Name: "login0", NID: 20, Xname: "x3000c1s4b0n0"},
+		{Name: "login1", NID: 21, Xname: "x3000c1s5b0n0"},
+		{Name: "head", NID: 100, Xname: "x3000c2s0b0n0"},
+	}
+	if !reflect.DeepEqual(nl.Nodes, want) {
+		t.Errorf("ExpandNodeList() nodes =\n%+v\nwant\n%+v", nl.Nodes, want)
+	}
+}
+
+func TestExpandNodeList_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    map[string]any
+		wantErr string
+	}{
+		{name: "count mismatch", node: map[string]any{"nid": "1-4", "xname": "x3000c0s[0-2]b0n0"}, wantErr: "describes 4 nodes"},
+		{name: "duplicate xnames", node: map[string]any{"nid": "1-4", "xname": "x3000c0s0b0n0"}, wantErr: "same xname"},
+		{name: "invalid nid", node: map[string]any{"nid": "one", "xname": "x3000c0s0b0n0"}, wantErr: "invalid nid"},
+		{name: "nid template without nid", node: map[string]any{"xname": "x3000c0s0b0n0", "bmc_ip": "10.0.0.{nid}"}, wantErr: "without a nid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ExpandNodeList(map[string]any{"version": 1, "nodes": []any{tt.node}})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ExpandNodeList() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package discover
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/xname"
+)
+
+var (
+	// exportIfaceDesc matches the description of the EthernetInterface
+	// generated for an interface of a node (see nodeSystem), capturing the
+	// name of the node.
+	exportIfaceDesc = regexp.MustCompile(`^Interface [0-9]+ for (.+)$`)
+
+	// exportBondDesc matches the description of the EthernetInterface
+	// generated for a bond member, capturing the name of the bond, the
+	// name of the node, and whether it is the primary member.
+	exportBondDesc = regexp.MustCompile(`^Member [0-9]+ of bond (\S+) for (.+?)( \[primary\])?$`)
+)
+
+// NodeListFromSMD reconstructs a payload from the records in state, such that
+// discovering it would generate the same Components, RedfishEndpoints, and
+// EthernetInterfaces, returning it along with warnings about what could not be
+// reconstructed. Each Component that is a node or one of DeviceTypes gets an
+// entry, sorted by xname, with the addresses of the RedfishEndpoint of its BMC,
+// the EthernetInterfaces of the component, and the groups it is a member of
+// (other than those holding metadata). Other Components, e.g. BMCs, are
+// described by the entries of the devices they manage.
+//
+// Names of entries are taken from the descriptions discovery gives
+// EthernetInterfaces, so they are only set for entries that were discovered
+// with ochami. Bond members are recognized the same way. Power actions, bond
+// modes, and the hypervisors of virtual nodes are not kept in the records, so
+// they are left unset.
+func NodeListFromSMD(state SMDState) (NodeList, []string) {
+	var (
+		nl       = NodeList{Version: NodeListVersion, Nodes: []Node{}}
+		warnings []string
+		rfes     = make(map[string]csm.RedfishEndpoint, len(state.RedfishEndpoints))
+		ifaces   = make(map[string][]smd.EthernetInterface)
+		groups   = make(map[string][]string)
+	)
+	for _, rfe := range state.RedfishEndpoints {
+		rfes[strings.ToLower(rfe.ID)] = rfe
+	}
+	for _, iface := range state.EthernetInterfaces {
+		id := strings.ToLower(iface.ComponentID)
+		ifaces[id] = append(ifaces[id], iface)
+	}
+	for _, g := range state.Groups {
+		if _, _, ok := smd.MetaFromGroup(g); ok {
+			continue
+		}
+		for _, m := range g.Members.IDs {
+			id := strings.ToLower(m)
+			groups[id] = append(groups[id], g.Label)
+		}
+	}
+
+	comps := slices.Clone(state.Components)
+	sort.SliceStable(comps, func(i, j int) bool { return comps[i].ID < comps[j].ID })
+	for _, c := range comps {
+		id := strings.ToLower(c.ID)
+		node := Node{Xname: c.ID, Groups: []string{}, Ifaces: []Iface{}}
+		var bmcXname string
+		switch c.Type {
+		case ComponentTypeNode:
+			node.NID = c.NID
+			b, err := xname.NodeXnameToBMCXname(c.ID)
+			if err != nil {
+				b = c.ID
+			}
+			bmcXname = b
+		case ComponentTypeVirtualNode:
+			node.NID = c.NID
+			node.Virtual = true
+			warnings = append(warnings, fmt.Sprintf("virtual node %s: hypervisor is not kept in SMD, set it if the node has one", c.ID))
+		default:
+			dt, ok := deviceTypes[c.Type]
+			if !ok {
+				continue
+			}
+			node.Type = c.Type
+			if dt.bmcType != "" {
+				bmcXname = dt.bmcXname(id)
+			}
+		}
+
+		if bmcXname != "" {
+			if rfe, ok := rfes[strings.ToLower(bmcXname)]; ok {
+				node.BMCMac = rfe.MACAddr
+				node.BMCIP = rfe.IPAddress
+				node.BMCFQDN = rfe.FQDN
+			} else {
+				warnings = append(warnings, fmt.Sprintf("%s %s: BMC %s has no redfish endpoint in SMD", c.Type, c.ID, bmcXname))
+			}
+		}
+
+		for _, iface := range ifaces[id] {
+			var ips []IfaceIP
+			for _, ip := range iface.IPAddresses {
+				ips = append(ips, IfaceIP{Network: ip.Network, IPAddr: ip.IPAddress})
+			}
+			if m := exportBondDesc.FindStringSubmatch(iface.Description); m != nil {
+				node.Name = m[2]
+				bi := slices.IndexFunc(node.Bonds, func(b Bond) bool { return b.Name == m[1] })
+				if bi < 0 {
+					bi = len(node.Bonds)
+					node.Bonds = append(node.Bonds, Bond{Name: m[1]})
+				}
+				b := &node.Bonds[bi]
+				b.Members = append(b.Members, iface.MACAddress)
+				if m[3] != "" {
+					b.Primary = iface.MACAddress
+					b.IPAddrs = ips
+				}
+				continue
+			}
+			if m := exportIfaceDesc.FindStringSubmatch(iface.Description); m != nil {
+				node.Name = m[1]
+			}
+			node.Ifaces = append(node.Ifaces, Iface{MACAddr: iface.MACAddress, IPAddrs: ips})
+		}
+		for _, b := range node.Bonds {
+			warnings = append(warnings, fmt.Sprintf("%s %s: mode of bond %s is not kept in SMD, it defaults to %s", c.Type, c.ID, b.Name, BondModeLACP))
+		}
+
+		node.Groups = append(node.Groups, groups[id]...)
+		slices.Sort(node.Groups)
+		nl.Nodes = append(nl.Nodes, node)
+	}
+
+	return nl, warnings
+}
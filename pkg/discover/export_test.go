@@ -0,0 +1,88 @@
+package discover
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+func TestNodeListFromSMD(t *testing.T) {
+	nl := NodeList{
+		Version: NodeListVersion,
+		Nodes: []Node{
+			{
+				Name: "node01", NID: 1, Xname: "x1000c1s7b0n0", Groups: []string{"compute", "slurm"},
+				BMCMac: "de:ca:fc:0f:ee:01", BMCIP: "172.16.0.101", BMCFQDN: "bmc01.example",
+				Ifaces: []Iface{
+					{MACAddr: "de:ad:be:ee:ee:01", IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "172.16.0.1"}}},
+					{MACAddr: "de:ad:be:ee:ee:11", IPAddrs: []IfaceIP{{Network: "hsn", IPAddr: "10.0.0.1"}}},
+				},
+			},
+			{
+				Name: "node02", NID: 2, Xname: "x1000c1s7b1n0", Groups: []string{},
+				BMCMac: "de:ca:fc:0f:ee:02", BMCIP: "172.16.0.102",
+				Ifaces: []Iface{},
+				Bonds: []Bond{{
+					Name:    "bond0",
+					Members: []string{"de:ad:be:ee:ee:02", "de:ad:be:ee:ee:12"},
+					Primary: "de:ad:be:ee:ee:12",
+					IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "172.16.0.2"}},
+				}},
+			},
+			{
+				Name: "sw01", Xname: "x3000c0r1b0", Type: ComponentTypeRouterBMC, Groups: []string{},
+				BMCMac: "de:ca:fc:0f:ee:99", Ifaces: []Iface{},
+			},
+		},
+	}
+	comps, rfesV2, ifaces, err := DiscoveryInfoV2("https://demo.example", nl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := SMDState{
+		// SMD adds a Component for each BMC it discovers
+		Components:         append(comps.Components, smd.Component{ID: "x1000c1s7b0", Type: "NodeBMC"}),
+		EthernetInterfaces: ifaces,
+	}
+	for _, rfe := range rfesV2.RedfishEndpoints {
+		state.RedfishEndpoints = append(state.RedfishEndpoints, rfe.RedfishEndpoint)
+	}
+	for _, label := range []string{"slurm", "compute"} {
+		g := smd.Group{Label: label}
+		g.Members.IDs = []string{"x1000c1s7b0n0"}
+		state.Groups = append(state.Groups, g)
+	}
+	state.Groups = append(state.Groups, smd.NewMetaGroup("rack", "12", "x1000c1s7b0n0"))
+
+	got, warnings := NodeListFromSMD(state)
+
+	// The name of the switch is only kept in its RedfishEndpoint, which it
+	// could share with other devices
+	want := nl
+	want.Nodes = append([]Node{}, nl.Nodes...)
+	want.Nodes[2].Name = ""
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NodeListFromSMD() = %+v, want %+v", got, want)
+	}
+	wantWarnings := []string{"Node x1000c1s7b1n0: mode of bond bond0 is not kept in SMD, it defaults to 802.3ad"}
+	if !reflect.DeepEqual(warnings, wantWarnings) {
+		t.Errorf("got warnings %q, want %q", warnings, wantWarnings)
+	}
+
+	t.Run("missing BMC", func(t *testing.T) {
+		state := SMDState{
+			Components:       []smd.Component{{ID: "x1000c1s7b5n0", Type: "Node", NID: 5}, {ID: "x1000c1s7b6n0", Type: "VirtualNode", NID: 6}},
+			RedfishEndpoints: []csm.RedfishEndpoint{},
+		}
+		got, warnings := NodeListFromSMD(state)
+		if len(got.Nodes) != 2 || !got.Nodes[1].Virtual || got.Nodes[0].NID != 5 {
+			t.Errorf("got nodes %+v", got.Nodes)
+		}
+		if len(warnings) != 2 {
+			t.Errorf("got warnings %q, want 2", warnings)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package discover
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// autoGroupNIDPrefix is the prefix of the spec of an AutoGroupRule that matches
+// nodes by NID instead of by xname.
+const autoGroupNIDPrefix = "nid:"
+
+// AutoGroupRule adds nodes whose xname matches a regular expression, or whose
+// NID is in one of a set of ranges, to a group.
+type AutoGroupRule struct {
+	Group string
+	spec  string
+	xname *regexp.Regexp
+	nids  [][2]int64
+}
+
+func (r AutoGroupRule) String() string {
+	return r.Group + "=" + r.spec
+}
+
+// ParseAutoGroupRule parses an AutoGroupRule from s, which has the form
+// "<group>=<spec>". If spec starts with "nid:", the rest is a comma-separated
+// list of NIDs and inclusive NID ranges (e.g. "nid:1-16,20"). Otherwise, spec
+// is a regular expression that must match the entire xname of a node (e.g.
+// "x3000c0s[0-7]b0n.*").
+func ParseAutoGroupRule(s string) (AutoGroupRule, error) {
+	group, spec, ok := strings.Cut(s, "=")
+	group, spec = strings.TrimSpace(group), strings.TrimSpace(spec)
+	if !ok || group == "" || spec == "" {
+		return AutoGroupRule{}, fmt.Errorf("invalid auto-group rule %q: expected <group>=<xname_regex> or <group>=nid:<ranges>", s)
+	}
+	r := AutoGroupRule{Group: group, spec: spec}
+	if ranges, isNID := strings.CutPrefix(spec, autoGroupNIDPrefix); isNID {
+		nids, err := parseNIDRanges(ranges)
+		if err != nil {
+			return AutoGroupRule{}, fmt.Errorf("%w in auto-group rule %q", err, s)
+		}
+		r.nids = nids
+		return r, nil
+	}
+	re, err := regexp.Compile("^(?:" + spec + ")$")
+	if err != nil {
+		return AutoGroupRule{}, fmt.Errorf("invalid xname pattern in auto-group rule %q: %w", s, err)
+	}
+	r.xname = re
+
+	return r, nil
+}
+
+// parseNIDRanges parses s, a comma-separated list of NIDs and inclusive NID
+// ranges (e.g. "1-16,20"), into the ranges it contains.
+func parseNIDRanges(s string) ([][2]int64, error) {
+	var ranges [][2]int64
+	for _, part := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(strings.TrimSpace(part), "-")
+		start, err := strconv.ParseInt(first, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid NID %q", first)
+		}
+		end := start
+		if isRange {
+			if end, err = strconv.ParseInt(last, 10, 64); err != nil {
+				return nil, fmt.Errorf("invalid NID %q", last)
+			}
+		}
+		if end < start {
+			return nil, fmt.Errorf("invalid NID range %q: end is less than start", part)
+		}
+		ranges = append(ranges, [2]int64{start, end})
+	}
+
+	return ranges, nil
+}
+
+// ParseAutoGroupRules parses each of specs with ParseAutoGroupRule.
+func ParseAutoGroupRules(specs []string) ([]AutoGroupRule, error) {
+	rules := make([]AutoGroupRule, 0, len(specs))
+	for _, s := range specs {
+		r, err := ParseAutoGroupRule(s)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
+
+// Match returns true if n belongs in the group of r.
+func (r AutoGroupRule) Match(n Node) bool {
+	if r.xname != nil {
+		return r.xname.MatchString(n.Xname)
+	}
+	for _, nr := range r.nids {
+		if n.NID >= nr[0] && n.NID <= nr[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// ApplyGroups adds every node in nl to each group in defaults and to the group
+// of each rule in rules that matches it, so that payloads do not need to list
+// these groups for each node. Groups a node is already in are not added again.
+// The number of group memberships added is returned.
+func ApplyGroups(nl *NodeList, defaults []string, rules []AutoGroupRule) int {
+	var added int
+	for i := range nl.Nodes {
+		n := &nl.Nodes[i]
+		add := func(group string) {
+			if group == n.Group || slices.Contains(n.Groups, group) {
+				return
+			}
+			n.Groups = append(n.Groups, group)
+			added++
+		}
+		for _, g := range defaults {
+			add(g)
+		}
+		for _, r := range rules {
+			if r.Match(*n) {
+				add(r.Group)
+			}
+		}
+	}
+
+	return added
+}
+
+// GroupSync is what syncing the groups of a payload with the groups in SMD
+// changes: the groups to create, along with their members, and, by group
+// label, the members to add to and remove from groups that exist.
+type GroupSync struct {
+	Create []smd.Group
+	Add    map[string][]string
+	Remove map[string][]string
+}
+
+// SyncGroups compares groups, the groups of a payload, with have, the groups in
+// SMD, and returns what syncing them changes. Members of a group in have that
+// are not members of it in groups are only removed if prune is true. Groups in
+// have that are not in groups are left as is. Xnames are compared
+// case-insensitively.
+func SyncGroups(groups, have []smd.Group, prune bool) GroupSync {
+	haveGroups := make(map[string]smd.Group, len(have))
+	for _, g := range have {
+		haveGroups[g.Label] = g
+	}
+	s := GroupSync{
+		Add:    make(map[string][]string),
+		Remove: make(map[string][]string),
+	}
+	for _, g := range groups {
+		h, ok := haveGroups[g.Label]
+		if !ok {
+			s.Create = append(s.Create, g)
+			continue
+		}
+		haveMembers := groupMembers(h)
+		for _, m := range g.Members.IDs {
+			if !slices.Contains(haveMembers, strings.ToLower(m)) {
+				s.Add[g.Label] = append(s.Add[g.Label], m)
+				haveMembers = append(haveMembers, strings.ToLower(m))
+			}
+		}
+		if !prune {
+			continue
+		}
+		members := groupMembers(g)
+		for _, m := range h.Members.IDs {
+			if !slices.Contains(members, strings.ToLower(m)) {
+				s.Remove[g.Label] = append(s.Remove[g.Label], m)
+			}
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,101 @@
+package discover
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+func TestParseAutoGroupRule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "compute=x3000c0s[0-7]b0n.*"},
+		{spec: "gpu=nid:1-16,20"},
+		{spec: " login = nid:5 "},
+		{spec: "compute", wantErr: true},
+		{spec: "=x3000.*", wantErr: true},
+		{spec: "compute=", wantErr: true},
+		{spec: "compute=x3000c0s[", wantErr: true},
+		{spec: "gpu=nid:a-b", wantErr: true},
+		{spec: "gpu=nid:16-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := ParseAutoGroupRule(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseAutoGroupRule(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApplyGroups(t *testing.T) {
+	nl := NodeList{Nodes: []Node{
+		{NID: 1, Xname: "x3000c0s0b0n0", Groups: []string{"compute"}},
+		{NID: 8, Xname: "x3000c0s7b0n0"},
+		{NID: 20, Xname: "x3000c0s8b0n0", Group: "gpu"},
+	}}
+	rules, err := ParseAutoGroupRules([]string{
+		"compute=x3000c0s[0-7]b0n.*",
+		"gpu=nid:8,20-24",
+		// Must match the whole xname
+		"chassis=x3000c0",
+	})
+	if err != nil {
+		t.Fatalf("ParseAutoGroupRules() error = %v", err)
+	}
+
+	added := ApplyGroups(&nl, []string{"all"}, rules)
+	want := [][]string{
+		{"compute", "all"},
+		{"all", "compute", "gpu"},
+		{"all"},
+	}
+	for i, n := range nl.Nodes {
+		if !reflect.DeepEqual(n.Groups, want[i]) {
+			t.Errorf("node %s groups = %v, want %v", n.Xname, n.Groups, want[i])
+		}
+	}
+	if added != 5 {
+		t.Errorf("ApplyGroups() = %d, want 5", added)
+	}
+}
+
+func TestSyncGroups(t *testing.T) {
+	group := func(label string, members ...string) smd.Group {
+		g := smd.Group{Label: label}
+		g.Members.IDs = members
+		return g
+	}
+	groups := []smd.Group{
+		group("compute", "x3000c0s0b0n0", "x3000c0s1b0n0"),
+		group("gpu", "x3000c0s2b0n0"),
+		group("login", "x3000c0s3b0n0"),
+	}
+	have := []smd.Group{
+		group("compute", "X3000C0S0B0N0", "x3000c0s9b0n0"),
+		group("gpu", "x3000c0s2b0n0"),
+		group("other", "x3000c0s4b0n0"),
+	}
+
+	s := SyncGroups(groups, have, false)
+	if len(s.Create) != 1 || s.Create[0].Label != "login" {
+		t.Errorf("SyncGroups() Create = %v, want [login]", s.Create)
+	}
+	wantAdd := map[string][]string{"compute": {"x3000c0s1b0n0"}}
+	if !reflect.DeepEqual(s.Add, wantAdd) {
+		t.Errorf("SyncGroups() Add = %v, want %v", s.Add, wantAdd)
+	}
+	if len(s.Remove) != 0 {
+		t.Errorf("SyncGroups() Remove = %v without prune, want none", s.Remove)
+	}
+
+	s = SyncGroups(groups, have, true)
+	wantRemove := map[string][]string{"compute": {"x3000c0s9b0n0"}}
+	if !reflect.DeepEqual(s.Remove, wantRemove) {
+		t.Errorf("SyncGroups() Remove = %v, want %v", s.Remove, wantRemove)
+	}
+}
@@ -0,0 +1,112 @@
+package discover
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// Hardware is the hardware of a node, so that tools which read the SMD hardware
+// inventory work with nodes that were discovered from a payload rather than
+// from their BMCs. Serial numbers identify the FRUs of the inventory. CPUs are
+// CPUCount processors of model CPUModel, with the serial numbers in
+// CPUSerialNumbers in order, if any. Memory is recorded as a single module of
+// MemoryGiB.
+type Hardware struct {
+	Manufacturer     string   `json:"manufacturer,omitempty" yaml:"manufacturer,omitempty"`
+	Model            string   `json:"model,omitempty" yaml:"model,omitempty"`
+	SerialNumber     string   `json:"serial_number,omitempty" yaml:"serial_number,omitempty"`
+	CPUModel         string   `json:"cpu_model,omitempty" yaml:"cpu_model,omitempty"`
+	CPUCount         int      `json:"cpu_count,omitempty" yaml:"cpu_count,omitempty"`
+	CPUCores         int      `json:"cpu_cores,omitempty" yaml:"cpu_cores,omitempty"`
+	CPUSerialNumbers []string `json:"cpu_serial_numbers,omitempty" yaml:"cpu_serial_numbers,omitempty"`
+	MemoryGiB        int      `json:"memory_gib,omitempty" yaml:"memory_gib,omitempty"`
+	GPUs             []GPU    `json:"gpus,omitempty" yaml:"gpus,omitempty"`
+}
+
+// GPU is a GPU or other accelerator of a node.
+type GPU struct {
+	Manufacturer string `json:"manufacturer,omitempty" yaml:"manufacturer,omitempty"`
+	Model        string `json:"model,omitempty" yaml:"model,omitempty"`
+	SerialNumber string `json:"serial_number,omitempty" yaml:"serial_number,omitempty"`
+}
+
+// SMD hardware inventory types of the entries generated by HardwareInventory.
+const (
+	HWInvTypeNode      = "Node"
+	HWInvTypeProcessor = "Processor"
+	HWInvTypeMemory    = "Memory"
+	HWInvTypeNodeAccel = "NodeAccel"
+)
+
+// HardwareInventory returns the SMD hardware inventory entries of the nodes in
+// nl that have Hardware: one for the node, one for each of its CPUs (e.g.
+// x1000c0s0b0n0p0), one for its memory (x1000c0s0b0n0d0), and one for each of
+// its GPUs (x1000c0s0b0n0a0), in the order of the nodes.
+func HardwareInventory(nl NodeList) []smd.HWInvByLoc {
+	var hw []smd.HWInvByLoc
+	for _, node := range nl.Nodes {
+		h := node.Hardware
+		if h == nil || !node.IsNode() {
+			continue
+		}
+		xname := strings.ToLower(node.Xname)
+		add := func(id, typ string, ordinal int, info smd.HWInvByFRUInfo) {
+			hw = append(hw, smd.HWInvByLoc{
+				ID:           id,
+				Type:         typ,
+				Ordinal:      ordinal,
+				Status:       "Populated",
+				LocationInfo: smd.HWInvLocationInfo{ID: id, Name: node.Name},
+				PopulatedFRU: &smd.HWInvByFRU{
+					FRUID: hardwareFRUID(id, typ, info.HWInvFRUInfo),
+					Type:  typ,
+					Info:  info,
+				},
+			})
+		}
+
+		add(xname, HWInvTypeNode, 0, smd.HWInvByFRUInfo{HWInvFRUInfo: smd.HWInvFRUInfo{
+			Manufacturer: h.Manufacturer,
+			Model:        h.Model,
+			SerialNumber: h.SerialNumber,
+		}})
+		for i := range max(h.CPUCount, len(h.CPUSerialNumbers)) {
+			info := smd.HWInvByFRUInfo{HWInvFRUInfo: smd.HWInvFRUInfo{Model: h.CPUModel}, TotalCores: h.CPUCores}
+			if i < len(h.CPUSerialNumbers) {
+				info.SerialNumber = h.CPUSerialNumbers[i]
+			}
+			add(fmt.Sprintf("%sp%d", xname, i), HWInvTypeProcessor, i, info)
+		}
+		if h.MemoryGiB > 0 {
+			add(xname+"d0", HWInvTypeMemory, 0, smd.HWInvByFRUInfo{CapacityMiB: h.MemoryGiB * 1024})
+		}
+		for i, gpu := range h.GPUs {
+			add(fmt.Sprintf("%sa%d", xname, i), HWInvTypeNodeAccel, i, smd.HWInvByFRUInfo{HWInvFRUInfo: smd.HWInvFRUInfo{
+				Manufacturer: gpu.Manufacturer,
+				Model:        gpu.Model,
+				SerialNumber: gpu.SerialNumber,
+			}})
+		}
+	}
+
+	return hw
+}
+
+// hardwareFRUID returns the FRU ID of the FRU of type typ at location id, which
+// SMD builds from the manufacturer and serial number of a FRU that has one, and
+// otherwise from its location.
+func hardwareFRUID(id, typ string, info smd.HWInvFRUInfo) string {
+	if info.SerialNumber == "" {
+		return "FRUIDfor" + id
+	}
+	fruid := typ
+	for _, part := range []string{info.Manufacturer, info.PartNumber, info.SerialNumber} {
+		if part != "" {
+			fruid += "." + strings.ReplaceAll(strings.TrimSpace(part), " ", "_")
+		}
+	}
+
+	return fruid
+}
@@ -0,0 +1,69 @@
+package discover
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHardwareInventory(t *testing.T) {
+	withHW := validNode(1, "de:ca:fc:00:00:01", "de:ad:be:00:00:01", "1")
+	withHW.Xname = "X1000C0S1B0N0"
+	withHW.Hardware = &Hardware{
+		Manufacturer:     "Acme",
+		Model:            "R1",
+		SerialNumber:     "SN 1",
+		CPUModel:         "EPYC 9654",
+		CPUCount:         2,
+		CPUCores:         96,
+		CPUSerialNumbers: []string{"cpu1"},
+		MemoryGiB:        512,
+		GPUs:             []GPU{{Manufacturer: "NVIDIA", Model: "H100", SerialNumber: "gpu1"}},
+	}
+	nl := NodeList{Nodes: []Node{
+		withHW,
+		validNode(2, "de:ca:fc:00:00:02", "de:ad:be:00:00:02", "2"),
+	}}
+
+	hw := HardwareInventory(nl)
+	want := []struct {
+		id, typ, fruid string
+	}{
+		{"x1000c0s1b0n0", HWInvTypeNode, "Node.Acme.SN_1"},
+		{"x1000c0s1b0n0p0", HWInvTypeProcessor, "Processor.cpu1"},
+		{"x1000c0s1b0n0p1", HWInvTypeProcessor, "FRUIDforx1000c0s1b0n0p1"},
+		{"x1000c0s1b0n0d0", HWInvTypeMemory, "FRUIDforx1000c0s1b0n0d0"},
+		{"x1000c0s1b0n0a0", HWInvTypeNodeAccel, "NodeAccel.NVIDIA.gpu1"},
+	}
+	if len(hw) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(hw), len(want), hw)
+	}
+	for i, w := range want {
+		h := hw[i]
+		if h.ID != w.id || h.Type != w.typ || h.PopulatedFRU == nil || h.PopulatedFRU.FRUID != w.fruid {
+			t.Errorf("entry %d: got %s %s %+v, want %s %s %s", i, h.ID, h.Type, h.PopulatedFRU, w.id, w.typ, w.fruid)
+		}
+	}
+	if got := hw[3].PopulatedFRU.Info.CapacityMiB; got != 512*1024 {
+		t.Errorf("got memory capacity %d MiB, want %d", got, 512*1024)
+	}
+	if got := hw[1].PopulatedFRU.Info; got.Model != "EPYC 9654" || got.TotalCores != 96 {
+		t.Errorf("got processor info %+v", got)
+	}
+
+	// SMD expects the info under keys named after the type
+	b, err := json.Marshal(hw[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["HWInventoryByLocationType"] != "HWInvByLocNode" || m["NodeLocationInfo"] == nil {
+		t.Errorf("unexpected JSON: %s", b)
+	}
+	fru, _ := m["PopulatedFRU"].(map[string]any)
+	if fru["HWInventoryByFRUType"] != "HWInvByFRUNode" || fru["NodeFRUInfo"] == nil {
+		t.Errorf("unexpected FRU JSON: %s", b)
+	}
+}
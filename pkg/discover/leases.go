@@ -0,0 +1,328 @@
+package discover
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// Lease file formats supported by ParseLeases.
+const (
+	LeaseFormatDnsmasq = "dnsmasq"
+	LeaseFormatKea     = "kea"
+)
+
+// LeaseFormatHelp describes each supported lease file format.
+var LeaseFormatHelp = map[string]string{
+	LeaseFormatDnsmasq: "dnsmasq lease file (dnsmasq.leases)",
+	LeaseFormatKea:     "Kea memfile CSV lease file (kea-leases4.csv)",
+}
+
+// Lease is a single DHCP lease read from a lease file.
+type Lease struct {
+	MAC      string
+	IP       string
+	Hostname string
+}
+
+func (l Lease) String() string {
+	return fmt.Sprintf("mac=%s ip=%s hostname=%q", l.MAC, l.IP, l.Hostname)
+}
+
+// LeaseRules is a set of rules mapping DHCP leases of BMCs to nodes in a
+// NodeList. Each lease is mapped by the first rule whose MACPrefix matches the
+// lease's MAC address.
+type LeaseRules struct {
+	Rules []LeaseRule `json:"rules" yaml:"rules"`
+}
+
+// LeaseRule maps the DHCP leases of BMCs whose MAC address starts with
+// MACPrefix to nodes. Xname, Name, and BMCFQDN are Go templates rendered with
+// LeaseTemplateData for each lease; Xname is required and renders to the xname
+// of the node, while Name defaults to the node's xname. If NIDStart is set,
+// NIDs of the rule's nodes are assigned starting at it in order of their index.
+// Otherwise, they are assigned sequentially from the lowest NID not already
+// used.
+type LeaseRule struct {
+	MACPrefix string   `json:"mac_prefix" yaml:"mac_prefix"`
+	Xname     string   `json:"xname" yaml:"xname"`
+	Name      string   `json:"name,omitempty" yaml:"name,omitempty"`
+	BMCFQDN   string   `json:"bmc_fqdn,omitempty" yaml:"bmc_fqdn,omitempty"`
+	NIDStart  int64    `json:"nid_start,omitempty" yaml:"nid_start,omitempty"`
+	Groups    []string `json:"groups,omitempty" yaml:"groups,omitempty"`
+}
+
+// LeaseTemplateData is the data available to the templates of a LeaseRule.
+// Index is the 0-based position of the lease among the leases matched by the
+// rule, ordered by IP address, and Octets are the octets of an IPv4 address.
+type LeaseTemplateData struct {
+	Index    int
+	MAC      string
+	IP       string
+	Octets   []int
+	Hostname string
+}
+
+// leaseTemplateFuncs are the functions available to the templates of a
+// LeaseRule, in addition to the text/template builtins.
+var leaseTemplateFuncs = template.FuncMap{
+	"add": func(a, b int) int { return a + b },
+}
+
+// ParseLeases reads DHCP leases in format from r. If format is empty, it is
+// detected from the content: Kea memfile (CSV) lease files start with a header
+// line beginning with "address,", anything else is parsed as a dnsmasq lease
+// file.
+func ParseLeases(r io.Reader, format string) ([]Lease, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read leases: %w", err)
+	}
+	if format == "" {
+		format = LeaseFormatDnsmasq
+		if bytes.HasPrefix(bytes.TrimSpace(data), []byte("address,")) {
+			format = LeaseFormatKea
+		}
+	}
+	switch format {
+	case LeaseFormatDnsmasq:
+		return parseDnsmasqLeases(data)
+	case LeaseFormatKea:
+		return parseKeaLeases(data)
+	default:
+		return nil, fmt.Errorf("unknown lease file format %q (must be %s or %s)", format, LeaseFormatDnsmasq, LeaseFormatKea)
+	}
+}
+
+// parseDnsmasqLeases parses a dnsmasq lease file, where each line is
+// "<expiry> <mac> <ip> <hostname> <client_id>" and a hostname of "*" means
+// none. DHCPv6 DUID lines are skipped.
+func parseDnsmasqLeases(data []byte) ([]Lease, error) {
+	var leases []Lease
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || fields[0] == "duid" {
+			continue
+		}
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("line %d: expected at least 4 fields, got %d", n, len(fields))
+		}
+		l := Lease{MAC: fields[1], IP: fields[2]}
+		if fields[3] != "*" {
+			l.Hostname = fields[3]
+		}
+		leases = append(leases, l)
+	}
+
+	return leases, sc.Err()
+}
+
+// parseKeaLeases parses a Kea memfile (CSV) lease file. Since the file is
+// append-only, only the last entry for each address is used and entries whose
+// state is not 0 (default), e.g. declined or expired leases, are skipped.
+func parseKeaLeases(data []byte) ([]Lease, error) {
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Kea lease file: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	col := make(map[string]int)
+	for i, name := range records[0] {
+		col[strings.TrimSpace(name)] = i
+	}
+	for _, name := range []string{"address", "hwaddr"} {
+		if _, ok := col[name]; !ok {
+			return nil, fmt.Errorf("Kea lease file header missing %q column", name)
+		}
+	}
+	field := func(rec []string, name string) string {
+		if i, ok := col[name]; ok && i < len(rec) {
+			return strings.TrimSpace(rec[i])
+		}
+		return ""
+	}
+
+	var (
+		leases []Lease
+		byAddr = make(map[string]int)
+	)
+	for _, rec := range records[1:] {
+		addr := field(rec, "address")
+		if addr == "" {
+			continue
+		}
+		if state := field(rec, "state"); state != "" && state != "0" {
+			if i, ok := byAddr[addr]; ok {
+				leases[i] = Lease{}
+			}
+			continue
+		}
+		l := Lease{MAC: field(rec, "hwaddr"), IP: addr, Hostname: field(rec, "hostname")}
+		if i, ok := byAddr[addr]; ok {
+			leases[i] = l
+			continue
+		}
+		byAddr[addr] = len(leases)
+		leases = append(leases, l)
+	}
+
+	return slices.DeleteFunc(leases, func(l Lease) bool { return l.IP == "" }), nil
+}
+
+// normalizeMAC returns mac in lower case with colon separators, so that MAC
+// addresses and prefixes written differently can be compared.
+func normalizeMAC(mac string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(mac)), "-", ":")
+}
+
+// NodeListFromLeases maps leases to nodes using rules and returns the
+// resulting NodeList, along with the leases that did not match any rule. The
+// MAC and IP address of each lease become the BMC MAC and IP address of its
+// node. An error is returned if a template cannot be rendered or if the rules
+// produce duplicate xnames or NIDs.
+func NodeListFromLeases(leases []Lease, rules LeaseRules) (NodeList, []Lease, error) {
+	nl := NodeList{Version: NodeListVersion}
+	type compiled struct {
+		xname, name, fqdn *template.Template
+	}
+	tmpls := make([]compiled, len(rules.Rules))
+	for i, r := range rules.Rules {
+		if r.Xname == "" {
+			return nl, nil, fmt.Errorf("rule %d: xname is required", i)
+		}
+		var err error
+		parse := func(field, text string) *template.Template {
+			if text == "" || err != nil {
+				return nil
+			}
+			var t *template.Template
+			t, err = template.New(field).Option("missingkey=error").Funcs(leaseTemplateFuncs).Parse(text)
+			if err != nil {
+				err = fmt.Errorf("rule %d: failed to parse %s template: %w", i, field, err)
+			}
+			return t
+		}
+		tmpls[i] = compiled{
+			xname: parse("xname", r.Xname),
+			name:  parse("name", r.Name),
+			fqdn:  parse("bmc_fqdn", r.BMCFQDN),
+		}
+		if err != nil {
+			return nl, nil, err
+		}
+	}
+
+	// Order leases by IP address so indices are stable across runs
+	sorted := slices.Clone(leases)
+	slices.SortStableFunc(sorted, func(a, b Lease) int {
+		ipA, errA := netip.ParseAddr(a.IP)
+		ipB, errB := netip.ParseAddr(b.IP)
+		if errA != nil || errB != nil {
+			return strings.Compare(a.IP, b.IP)
+		}
+		return ipA.Compare(ipB)
+	})
+
+	var (
+		unmatched []Lease
+		ruleOf    []int
+		counts    = make([]int, len(rules.Rules))
+		xnames    = make(map[string]string)
+		nids      = make(map[int64]string)
+	)
+	for _, l := range sorted {
+		mac := normalizeMAC(l.MAC)
+		ri := slices.IndexFunc(rules.Rules, func(r LeaseRule) bool {
+			return strings.HasPrefix(mac, normalizeMAC(r.MACPrefix))
+		})
+		if ri < 0 {
+			unmatched = append(unmatched, l)
+			continue
+		}
+		r, t := rules.Rules[ri], tmpls[ri]
+		data := LeaseTemplateData{Index: counts[ri], MAC: mac, IP: l.IP, Hostname: l.Hostname}
+		if ip := net.ParseIP(l.IP).To4(); ip != nil {
+			for _, o := range ip {
+				data.Octets = append(data.Octets, int(o))
+			}
+		}
+		counts[ri]++
+
+		render := func(t *template.Template) (string, error) {
+			if t == nil {
+				return "", nil
+			}
+			var buf bytes.Buffer
+			if err := t.Execute(&buf, data); err != nil {
+				return "", fmt.Errorf("rule %d: failed to render %s template for lease %s: %w", ri, t.Name(), l.IP, err)
+			}
+			return strings.TrimSpace(buf.String()), nil
+		}
+		node := Node{BMCMac: mac, BMCIP: l.IP, Groups: slices.Clone(r.Groups)}
+		var err error
+		if node.Xname, err = render(t.xname); err != nil {
+			return nl, nil, err
+		}
+		if node.Xname == "" {
+			return nl, nil, fmt.Errorf("rule %d: xname template rendered empty for lease %s", ri, l.IP)
+		}
+		if prev, ok := xnames[node.Xname]; ok {
+			return nl, nil, fmt.Errorf("leases %s and %s both map to xname %s", prev, l.IP, node.Xname)
+		}
+		xnames[node.Xname] = l.IP
+		if node.Name, err = render(t.name); err != nil {
+			return nl, nil, err
+		}
+		if node.Name == "" {
+			node.Name = node.Xname
+		}
+		if node.BMCFQDN, err = render(t.fqdn); err != nil {
+			return nl, nil, err
+		}
+		if node.Groups == nil {
+			node.Groups = []string{}
+		}
+		if node.Ifaces == nil {
+			node.Ifaces = []Iface{}
+		}
+		if r.NIDStart > 0 {
+			node.NID = r.NIDStart + int64(data.Index)
+			if prev, ok := nids[node.NID]; ok {
+				return nl, nil, fmt.Errorf("nodes %s and %s both have NID %d", prev, node.Xname, node.NID)
+			}
+			nids[node.NID] = node.Xname
+		}
+		nl.Nodes = append(nl.Nodes, node)
+		ruleOf = append(ruleOf, ri)
+	}
+
+	// Fill in NIDs for rules without nid_start, skipping those already used
+	next := int64(1)
+	for i := range nl.Nodes {
+		if rules.Rules[ruleOf[i]].NIDStart > 0 {
+			continue
+		}
+		for {
+			if _, ok := nids[next]; !ok {
+				break
+			}
+			next++
+		}
+		nl.Nodes[i].NID = next
+		nids[next] = nl.Nodes[i].Xname
+	}
+
+	return nl, unmatched, nil
+}
@@ -0,0 +1,115 @@
+package discover
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLeases(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		data    string
+		want    []Lease
+		wantErr bool
+	}{
+		{
+			name: "dnsmasq",
+			data: `1735689600 de:ca:fc:00:00:02 172.16.0.102 bmc2 01:de:ca:fc:00:00:02
+1735689600 de:ca:fc:00:00:01 172.16.0.101 * *
+duid 00:01:00:01:2c:11:22:33:44:55:66:77
+`,
+			want: []Lease{
+				{MAC: "de:ca:fc:00:00:02", IP: "172.16.0.102", Hostname: "bmc2"},
+				{MAC: "de:ca:fc:00:00:01", IP: "172.16.0.101"},
+			},
+		},
+		{
+			name: "kea detected",
+			data: `address,hwaddr,client_id,valid_lifetime,expire,subnet_id,fqdn_fwd,fqdn_rev,hostname,state,user_context,pool_id
+172.16.0.101,de:ca:fc:00:00:01,,3600,1735689600,1,0,0,bmc1,0,,0
+172.16.0.102,de:ca:fc:00:00:02,,3600,1735689600,1,0,0,,0,,0
+172.16.0.101,de:ca:fc:00:00:0a,,3600,1735693200,1,0,0,bmc1,0,,0
+172.16.0.102,de:ca:fc:00:00:02,,3600,1735693200,1,0,0,,2,,0
+`,
+			want: []Lease{{MAC: "de:ca:fc:00:00:0a", IP: "172.16.0.101", Hostname: "bmc1"}},
+		},
+		{
+			name:    "dnsmasq too few fields",
+			format:  LeaseFormatDnsmasq,
+			data:    "1735689600 de:ca:fc:00:00:01\n",
+			wantErr: true,
+		},
+		{
+			name:    "kea missing column",
+			format:  LeaseFormatKea,
+			data:    "address,expire\n172.16.0.101,1735689600\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown format",
+			format:  "isc",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLeases(strings.NewReader(tt.data), tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLeases() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLeases() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNodeListFromLeases(t *testing.T) {
+	leases := []Lease{
+		{MAC: "DE-CA-FC-00-00-02", IP: "172.16.0.102"},
+		{MAC: "de:ca:fc:00:00:01", IP: "172.16.0.101", Hostname: "bmc1"},
+		{MAC: "aa:bb:cc:00:00:01", IP: "172.16.1.10"},
+		{MAC: "00:11:22:33:44:55", IP: "172.16.9.9"},
+	}
+	rules := LeaseRules{Rules: []LeaseRule{
+		{
+			MACPrefix: "de:ca:fc",
+			Xname:     "x1000c0s{{.Index}}b0n0",
+			Name:      "nid{{printf \"%03d\" (add .Index 1)}}",
+			Groups:    []string{"compute"},
+		},
+		{
+			MACPrefix: "AA:BB:CC",
+			Xname:     "x1000c1s{{index .Octets 3}}b0n0",
+			BMCFQDN:   "{{.Hostname}}",
+			NIDStart:  1,
+		},
+	}}
+	want := NodeList{
+		Version: NodeListVersion,
+		Nodes: []Node{
+			{Name: "nid001", NID: 2, Xname: "x1000c0s0b0n0", Groups: []string{"compute"}, BMCMac: "de:ca:fc:00:00:01", BMCIP: "172.16.0.101", Ifaces: []Iface{}},
+			{Name: "nid002", NID: 3, Xname: "x1000c0s1b0n0", Groups: []string{"compute"}, BMCMac: "de:ca:fc:00:00:02", BMCIP: "172.16.0.102", Ifaces: []Iface{}},
+			{Name: "x1000c1s10b0n0", NID: 1, Xname: "x1000c1s10b0n0", Groups: []string{}, BMCMac: "aa:bb:cc:00:00:01", BMCIP: "172.16.1.10", Ifaces: []Iface{}},
+		},
+	}
+
+	got, unmatched, err := NodeListFromLeases(leases, rules)
+	if err != nil {
+		t.Fatalf("NodeListFromLeases() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NodeListFromLeases() = %v, want %v", got, want)
+	}
+	if len(unmatched) != 1 || unmatched[0].IP != "172.16.9.9" {
+		t.Errorf("NodeListFromLeases() unmatched = %v, want lease 172.16.9.9", unmatched)
+	}
+
+	// Rules mapping two leases to the same xname are an error
+	rules.Rules[0].Xname = "x1000c0s0b0n0"
+	if _, _, err := NodeListFromLeases(leases, rules); err == nil {
+		t.Error("NodeListFromLeases() with duplicate xnames succeeded, want error")
+	}
+}
@@ -0,0 +1,148 @@
+package discover
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+)
+
+// NodeListVersion is the current version of the NodeList format. Payloads
+// without a version are considered to be from before the format was versioned
+// and are migrated by MigrateNodeList.
+const NodeListVersion = 1
+
+// MigrateNodeList takes payload data in the NodeList format, as unmarshalled
+// generically from JSON or YAML, in any known version and returns the
+// equivalent payload data in the current version (NodeListVersion), along with
+// a warning describing each migration that was needed. Unversioned payloads
+// may contain the following legacy shapes, which are migrated:
+//
+//   - a list of nodes not under a "nodes" key
+//   - a "group" string in a node, which is merged into "groups"
+//   - a "name" key instead of "network" in an interface IP address
+//
+// Nothing else is changed, so that compact node entries are kept as they are.
+// Pass the result to ExpandNodeList to get the nodes it describes. If the
+// payload is from a newer version or is otherwise not a NodeList, an error is
+// returned.
+func MigrateNodeList(data any) (map[string]any, []string, error) {
+	var warnings []string
+
+	// Unversioned payloads may just be a list of nodes
+	if nodes, ok := data.([]any); ok {
+		warnings = append(warnings, "payload is a list of nodes; put them under a 'nodes' key instead")
+		data = map[string]any{"nodes": nodes}
+	}
+	m, ok := data.(map[string]any)
+	if !ok {
+		return nil, warnings, fmt.Errorf("payload must be an object containing 'nodes', got %T", data)
+	}
+
+	version, err := nodeListVersion(m)
+	if err != nil {
+		return nil, warnings, err
+	}
+	switch {
+	case version > NodeListVersion:
+		return nil, warnings, fmt.Errorf("payload version %d is newer than the latest supported version (%d)", version, NodeListVersion)
+	case version == 0:
+		warnings = append(warnings, migrateNodeListV0(m)...)
+		m["version"] = NodeListVersion
+	}
+
+	return m, warnings, nil
+}
+
+// ExpandNodeList returns the NodeList of payload data in the current version of
+// the format (e.g. as returned by MigrateNodeList), expanding its compact node
+// entries, which describe many nodes with ranges and templates, into those
+// nodes (see expandNodes). The data is modified in place. If a compact entry is
+// invalid or the data is otherwise not a NodeList, an error is returned.
+func ExpandNodeList(m map[string]any) (NodeList, error) {
+	var nl NodeList
+	if err := expandNodes(m); err != nil {
+		return nl, err
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nl, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	if err := json.Unmarshal(b, &nl); err != nil {
+		return nl, fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+
+	return nl, nil
+}
+
+// nodeListVersion returns the value of "version" in m, or 0 if unset.
+// Depending on the format the payload was unmarshalled from, the number may
+// be an int or a float64.
+func nodeListVersion(m map[string]any) (int, error) {
+	switch v := m["version"].(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid payload version: %v", m["version"])
+}
+
+// migrateNodeListV0 migrates the legacy shapes of unversioned payloads in m in
+// place, returning a warning for each one migrated.
+func migrateNodeListV0(m map[string]any) []string {
+	var warnings []string
+	nodes, _ := m["nodes"].([]any)
+	for idx, n := range nodes {
+		node, ok := n.(map[string]any)
+		if !ok {
+			continue
+		}
+		id := fmt.Sprintf("node %d", idx)
+		if xname, ok := node["xname"].(string); ok && xname != "" {
+			id = fmt.Sprintf("node %s", xname)
+		}
+
+		// "group" is deprecated in favor of "groups"
+		if group, ok := node["group"]; ok {
+			if g, ok := group.(string); ok && g != "" {
+				groups, _ := node["groups"].([]any)
+				if !slices.Contains(groups, any(g)) {
+					groups = append(groups, g)
+				}
+				node["groups"] = groups
+			}
+			delete(node, "group")
+			warnings = append(warnings, fmt.Sprintf("%s: 'group' is deprecated, moved to 'groups'", id))
+		}
+
+		// Interface IP addresses used "name" for the network name
+		ifaces, _ := node["interfaces"].([]any)
+		for _, i := range ifaces {
+			iface, ok := i.(map[string]any)
+			if !ok {
+				continue
+			}
+			ips, _ := iface["ip_addrs"].([]any)
+			for _, ip := range ips {
+				ipAddr, ok := ip.(map[string]any)
+				if !ok {
+					continue
+				}
+				if name, ok := ipAddr["name"]; ok {
+					if _, ok := ipAddr["network"]; !ok {
+						ipAddr["network"] = name
+					}
+					delete(ipAddr, "name")
+					warnings = append(warnings, fmt.Sprintf("%s: 'name' in ip_addrs is deprecated, renamed to 'network'", id))
+				}
+			}
+		}
+	}
+
+	return warnings
+}
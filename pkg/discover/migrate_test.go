@@ -0,0 +1,145 @@
+package discover
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestMigrateNodeList(t *testing.T) {
+	want := NodeList{
+		Version: NodeListVersion,
+		Nodes: []Node{
+			{
+				Name:   "node01",
+				NID:    1,
+				Xname:  "x1000c1s7b0n0",
+				Groups: []string{"slurm", "compute"},
+				Ifaces: []Iface{
+					{
+						MACAddr: "de:ad:be:ee:ee:f1",
+						IPAddrs: []IfaceIP{{Network: "internal", IPAddr: "172.16.0.1"}},
+					},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		yaml         string
+		json         string
+		want         NodeList
+		wantWarnings int
+		wantErr      bool
+	}{
+		{
+			name: "legacy group and ip name",
+			yaml: `
+nodes:
+- name: node01
+  nid: 1
+  xname: x1000c1s7b0n0
+  group: compute
+  groups: [slurm]
+  interfaces:
+  - mac_addr: de:ad:be:ee:ee:f1
+    ip_addrs:
+    - name: internal
+      ip_addr: 172.16.0.1
+`,
+			want:         want,
+			wantWarnings: 2,
+		},
+		{
+			name:         "legacy bare list",
+			json:         `[{"name":"node01","nid":1,"xname":"x1000c1s7b0n0","groups":["slurm","compute"],"interfaces":[{"mac_addr":"de:ad:be:ee:ee:f1","ip_addrs":[{"network":"internal","ip_addr":"172.16.0.1"}]}]}]`,
+			want:         want,
+			wantWarnings: 1,
+		},
+		{
+			name: "current version is not migrated",
+			json: `{"version":1,"nodes":[{"name":"node01","nid":1,"xname":"x1000c1s7b0n0","groups":["slurm","compute"],"interfaces":[{"mac_addr":"de:ad:be:ee:ee:f1","ip_addrs":[{"network":"internal","ip_addr":"172.16.0.1"}]}]}]}`,
+			want: want,
+		},
+		{
+			name:    "newer version",
+			yaml:    "version: 99\nnodes: []\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid version",
+			json:    `{"version":"one","nodes":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "not a node list",
+			json:    `"nodes"`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data any
+			var err error
+			if tt.yaml != "" {
+				err = yaml.Unmarshal([]byte(tt.yaml), &data)
+			} else {
+				err = json.Unmarshal([]byte(tt.json), &data)
+			}
+			if err != nil {
+				t.Fatalf("failed to unmarshal test data: %v", err)
+			}
+
+			m, warnings, err := MigrateNodeList(data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(warnings) != tt.wantWarnings {
+				t.Errorf("got %d warnings, want %d: %v", len(warnings), tt.wantWarnings, warnings)
+			}
+			got, err := ExpandNodeList(m)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateNodeList_KeepsCompactEntries(t *testing.T) {
+	var data any
+	if err := yaml.Unmarshal([]byte(`
+nodes:
+- nid: 1-3
+  xname: x3000c0s[0-2]b0n0
+  group: compute
+`), &data); err != nil {
+		t.Fatalf("failed to unmarshal test data: %v", err)
+	}
+	m, _, err := MigrateNodeList(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{
+		"version": NodeListVersion,
+		"nodes": []any{map[string]any{
+			"nid":    "1-3",
+			"xname":  "x3000c0s[0-2]b0n0",
+			"groups": []any{"compute"},
+		}},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got %v, want %v", m, want)
+	}
+}
@@ -0,0 +1,362 @@
+package discover
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// PlanVersion is the version of the format of Plan, which is checked when a
+// plan is read back to be applied.
+const PlanVersion = 1
+
+// PlanKindGroup is the kind of the step of a Plan that adds groups. The other
+// steps have the kinds of the records compared by Diff.
+const PlanKindGroup = "Group"
+
+// Actions of a PlanResource.
+const (
+	PlanCreate   = "create"
+	PlanUpdate   = "update"
+	PlanNoOp     = "no-op"
+	PlanConflict = "conflict"
+)
+
+// Payloads are the records that discovery sends to SMD, in the order they are
+// sent. EthernetInterfaces are only sent with discovery version 1.
+// HardwareInventory is not planned, since SMD replaces existing entries with
+// it, and is sent as is when a plan is applied.
+type Payloads struct {
+	Components         smd.ComponentSlice         `json:"components" yaml:"components"`
+	RedfishEndpoints   smd.RedfishEndpointSliceV2 `json:"redfish_endpoints" yaml:"redfish_endpoints"`
+	EthernetInterfaces []smd.EthernetInterface    `json:"ethernet_interfaces,omitempty" yaml:"ethernet_interfaces,omitempty"`
+	HardwareInventory  []smd.HWInvByLoc           `json:"hardware_inventory,omitempty" yaml:"hardware_inventory,omitempty"`
+	Groups             []smd.Group                `json:"groups" yaml:"groups"`
+}
+
+// SMDState is what is in SMD when a Plan is made.
+type SMDState struct {
+	Components         []smd.Component
+	RedfishEndpoints   []csm.RedfishEndpoint
+	EthernetInterfaces []smd.EthernetInterface
+	Groups             []smd.Group
+}
+
+// PlanResource is a record of a PlanStep and what applying the plan does with
+// it: create it, update it in SMD (only if the plan overwrites), or nothing,
+// because it is already in SMD as is (no-op) or differs from it but the plan
+// does not overwrite (conflict). Changes are the fields that differ from SMD.
+type PlanResource struct {
+	ID      string        `json:"id" yaml:"id"`
+	Action  string        `json:"action" yaml:"action"`
+	Changes []FieldChange `json:"changes,omitempty" yaml:"changes,omitempty"`
+}
+
+// PlanStep is the records of one kind that applying a plan sends to a
+// service, after the steps of the kinds in DependsOn. Requests is the number of
+// requests the step sends.
+type PlanStep struct {
+	Service   string         `json:"service" yaml:"service"`
+	Kind      string         `json:"kind" yaml:"kind"`
+	DependsOn []string       `json:"depends_on,omitempty" yaml:"depends_on,omitempty"`
+	Resources []PlanResource `json:"resources" yaml:"resources"`
+	Requests  int            `json:"requests" yaml:"requests"`
+}
+
+// Count returns the number of resources of s with action.
+func (s PlanStep) Count(action string) int {
+	var n int
+	for _, r := range s.Resources {
+		if r.Action == action {
+			n++
+		}
+	}
+
+	return n
+}
+
+// Plan is what applying a discovery payload would do, as made by NewPlan:
+// its steps, in the order they are applied, and the payloads they send.
+// Requests is the total number of requests of the steps.
+type Plan struct {
+	Version   int        `json:"version" yaml:"version"`
+	Overwrite bool       `json:"overwrite" yaml:"overwrite"`
+	Steps     []PlanStep `json:"steps" yaml:"steps"`
+	Requests  int        `json:"requests" yaml:"requests"`
+	Payloads  Payloads   `json:"payloads" yaml:"payloads"`
+}
+
+// NewPlan returns the plan of sending payloads to SMD, given what is in it.
+// Records that are not in SMD are created. Records that are in SMD, compared
+// the way Diff compares them, are updated if they differ and overwrite is true.
+// Groups are compared by their members, and only differ if a member in the
+// payload is not one of them in SMD.
+//
+// The steps are in the order discovery sends records: components first, so
+// that SMD uses their NIDs instead of generating some when redfish endpoints
+// are added, then redfish endpoints, ethernet interfaces (if any), and groups,
+// which all refer to components. Payloads of components are sent in one
+// request, and other records one at a time. Updating records sends one request
+// each to components, along with one to set their NIDs, and two each to others,
+// which are posted first.
+func NewPlan(payloads Payloads, state SMDState, overwrite bool) Plan {
+	p := Plan{
+		Version:   PlanVersion,
+		Overwrite: overwrite,
+		Payloads:  payloads,
+	}
+
+	// Components
+	have := make(map[string]diffRecord, len(state.Components))
+	for _, c := range state.Components {
+		have[strings.ToLower(c.ID)] = componentRecord(c)
+	}
+	var want []diffRecord
+	for _, c := range payloads.Components.Components {
+		want = append(want, componentRecord(c))
+	}
+	p.addStep(DiffKindComponent, nil, planResources(want, have, overwrite), true)
+
+	// Redfish endpoints
+	have = make(map[string]diffRecord, len(state.RedfishEndpoints))
+	for _, rfe := range state.RedfishEndpoints {
+		have[strings.ToLower(rfe.ID)] = rfeRecord(rfe)
+	}
+	want = nil
+	for _, rfe := range payloads.RedfishEndpoints.RedfishEndpoints {
+		want = append(want, rfeRecord(rfe.RedfishEndpoint))
+	}
+	p.addStep(DiffKindRedfishEndpoint, []string{DiffKindComponent}, planResources(want, have, overwrite), false)
+
+	// Ethernet interfaces
+	if len(payloads.EthernetInterfaces) > 0 {
+		have = make(map[string]diffRecord, len(state.EthernetInterfaces))
+		for _, iface := range state.EthernetInterfaces {
+			have[macID(iface.MACAddress)] = ifaceRecord(iface)
+		}
+		want = nil
+		for _, iface := range payloads.EthernetInterfaces {
+			want = append(want, ifaceRecord(iface))
+		}
+		p.addStep(DiffKindEthernetInterface, []string{DiffKindComponent}, planResources(want, have, overwrite), false)
+	}
+
+	// Groups, whose members in SMD that are not in the payload are not a
+	// difference
+	haveGroups := make(map[string][]string, len(state.Groups))
+	for _, g := range state.Groups {
+		haveGroups[g.Label] = groupMembers(g)
+	}
+	var resources []PlanResource
+	for _, g := range payloads.Groups {
+		res := PlanResource{ID: g.Label, Action: PlanCreate}
+		members := groupMembers(g)
+		if haveMembers, ok := haveGroups[g.Label]; ok {
+			res.Action = PlanNoOp
+			for _, m := range members {
+				if !slices.Contains(haveMembers, m) {
+					res.Action = planChangeAction(overwrite)
+					res.Changes = []FieldChange{{
+						Field: "Members",
+						From:  strings.Join(haveMembers, ","),
+						To:    strings.Join(members, ","),
+					}}
+					break
+				}
+			}
+		}
+		resources = append(resources, res)
+	}
+	p.addStep(PlanKindGroup, []string{DiffKindComponent}, resources, false)
+
+	return p
+}
+
+// addStep adds the step of sending resources of kind to SMD to p, counting its
+// requests. bulk is whether the resources are created in a single request.
+func (p *Plan) addStep(kind string, dependsOn []string, resources []PlanResource, bulk bool) {
+	s := PlanStep{
+		Service:   "smd",
+		Kind:      kind,
+		DependsOn: dependsOn,
+		Resources: resources,
+	}
+	creates, updates := s.Count(PlanCreate), s.Count(PlanUpdate)
+	switch {
+	case bulk:
+		if creates > 0 {
+			s.Requests++
+		}
+		if updates > 0 {
+			s.Requests += updates + 1
+		}
+	default:
+		s.Requests = creates + 2*updates
+	}
+	p.Steps = append(p.Steps, s)
+	p.Requests += s.Requests
+}
+
+// planResources returns the resources whose records are want, given those in
+// SMD (have), keyed by ID. Records whose ID is repeated are only planned once.
+func planResources(want []diffRecord, have map[string]diffRecord, overwrite bool) []PlanResource {
+	var (
+		resources []PlanResource
+		seen      = make(map[string]bool, len(want))
+	)
+	for _, w := range want {
+		if seen[w.id] {
+			continue
+		}
+		seen[w.id] = true
+		h, ok := have[w.id]
+		if !ok {
+			resources = append(resources, PlanResource{ID: w.id, Action: PlanCreate})
+			continue
+		}
+		res := PlanResource{ID: w.id, Action: PlanNoOp}
+		for i, f := range w.fields {
+			if h.fields[i][1] != f[1] {
+				res.Changes = append(res.Changes, FieldChange{Field: f[0], From: h.fields[i][1], To: f[1]})
+			}
+		}
+		if len(res.Changes) > 0 {
+			res.Action = planChangeAction(overwrite)
+		}
+		resources = append(resources, res)
+	}
+
+	return resources
+}
+
+// planChangeAction returns the action of a resource that differs from SMD.
+func planChangeAction(overwrite bool) string {
+	if overwrite {
+		return PlanUpdate
+	}
+	return PlanConflict
+}
+
+// groupMembers returns the members of g in lower case, sorted.
+func groupMembers(g smd.Group) []string {
+	members := make([]string, 0, len(g.Members.IDs))
+	for _, m := range g.Members.IDs {
+		members = append(members, strings.ToLower(m))
+	}
+	slices.Sort(members)
+
+	return slices.Compact(members)
+}
+
+// Count returns the number of resources of all steps of p with action.
+func (p Plan) Count(action string) int {
+	var n int
+	for _, s := range p.Steps {
+		n += s.Count(action)
+	}
+
+	return n
+}
+
+// Action returns the action of the resource of kind with id in p, or "" if p
+// has none.
+func (p Plan) Action(kind, id string) string {
+	for _, s := range p.Steps {
+		if s.Kind != kind {
+			continue
+		}
+		for _, r := range s.Resources {
+			if r.ID == id {
+				return r.Action
+			}
+		}
+	}
+
+	return ""
+}
+
+// PayloadsWith returns the payloads of p whose resources have action, which
+// applying p sends the same way.
+func (p Plan) PayloadsWith(action string) Payloads {
+	var out Payloads
+	for _, c := range p.Payloads.Components.Components {
+		if p.Action(DiffKindComponent, strings.ToLower(c.ID)) == action {
+			out.Components.Components = append(out.Components.Components, c)
+		}
+	}
+	for _, rfe := range p.Payloads.RedfishEndpoints.RedfishEndpoints {
+		if p.Action(DiffKindRedfishEndpoint, strings.ToLower(rfe.ID)) == action {
+			out.RedfishEndpoints.RedfishEndpoints = append(out.RedfishEndpoints.RedfishEndpoints, rfe)
+		}
+	}
+	for _, iface := range p.Payloads.EthernetInterfaces {
+		if p.Action(DiffKindEthernetInterface, macID(iface.MACAddress)) == action {
+			out.EthernetInterfaces = append(out.EthernetInterfaces, iface)
+		}
+	}
+	for _, g := range p.Payloads.Groups {
+		if p.Action(PlanKindGroup, g.Label) == action {
+			out.Groups = append(out.Groups, g)
+		}
+	}
+
+	return out
+}
+
+// Changed returns the resources whose action differs in q, a plan made from
+// the same payloads later on, which means that SMD changed in the meantime.
+func (p Plan) Changed(q Plan) []string {
+	var changed []string
+	for _, s := range p.Steps {
+		for _, r := range s.Resources {
+			if a := q.Action(s.Kind, r.ID); a != r.Action {
+				changed = append(changed, fmt.Sprintf("%s %s: %s -> %s", s.Kind, r.ID, r.Action, a))
+			}
+		}
+	}
+
+	return changed
+}
+
+// String returns the steps of p and the resources they change, marked with '+'
+// if they are created, '~' if they are updated, and '!' if they conflict,
+// followed by a summary. Resources that are left as is are only counted.
+func (p Plan) String() string {
+	var b strings.Builder
+	for i, s := range p.Steps {
+		fmt.Fprintf(&b, "%d. %s %s", i+1, strings.ToUpper(s.Service), s.Kind)
+		if len(s.DependsOn) > 0 {
+			fmt.Fprintf(&b, " (after %s)", strings.Join(s.DependsOn, ", "))
+		}
+		fmt.Fprintf(&b, ": %d request(s)\n", s.Requests)
+		for _, r := range s.Resources {
+			switch r.Action {
+			case PlanCreate:
+				fmt.Fprintf(&b, "  + %s\n", r.ID)
+			case PlanUpdate:
+				fmt.Fprintf(&b, "  ~ %s\n", r.ID)
+			case PlanConflict:
+				fmt.Fprintf(&b, "  ! %s (differs, not updated)\n", r.ID)
+			default:
+				continue
+			}
+			for _, c := range r.Changes {
+				fmt.Fprintf(&b, "      %s\n", c)
+			}
+		}
+		if n := s.Count(PlanNoOp); n > 0 {
+			fmt.Fprintf(&b, "  %d unchanged\n", n)
+		}
+	}
+	if n := len(p.Payloads.HardwareInventory); n > 0 {
+		fmt.Fprintf(&b, "SMD hardware inventory: %d location(s) sent as is\n", n)
+	}
+	fmt.Fprintf(&b, "Plan: %d to create, %d to update, %d unchanged, %d conflicting; %d request(s)",
+		p.Count(PlanCreate), p.Count(PlanUpdate), p.Count(PlanNoOp), p.Count(PlanConflict), p.Requests)
+
+	return b.String()
+}
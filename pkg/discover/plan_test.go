@@ -0,0 +1,164 @@
+package discover
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+func TestNewPlan(t *testing.T) {
+	var payloads Payloads
+	payloads.Components.Components = []smd.Component{
+		{ID: "x1000c1s7b0n0", Type: "Node", NID: 1},
+		{ID: "x1000c1s7b1n0", Type: "Node", NID: 2},
+		{ID: "x1000c1s7b2n0", Type: "Node", NID: 3},
+	}
+	payloads.RedfishEndpoints.RedfishEndpoints = []smd.RedfishEndpointV2{
+		{RedfishEndpoint: csm.RedfishEndpoint{ID: "x1000c1s7b0", MACAddr: "de:ca:fc:0f:ee:01"}},
+		{RedfishEndpoint: csm.RedfishEndpoint{ID: "x1000c1s7b2", MACAddr: "de:ca:fc:0f:ee:03"}},
+	}
+	payloads.EthernetInterfaces = []smd.EthernetInterface{
+		{ComponentID: "x1000c1s7b0n0", MACAddress: "de:ad:be:ee:ee:01"},
+	}
+	g := smd.Group{Label: "compute"}
+	g.Members.IDs = []string{"x1000c1s7b0n0", "x1000c1s7b1n0"}
+	payloads.Groups = []smd.Group{g}
+
+	existing := smd.Group{Label: "compute"}
+	existing.Members.IDs = []string{"x1000c1s7b0n0", "x1000c1s7b9n0"}
+	state := SMDState{
+		Components: []smd.Component{
+			{ID: "x1000c1s7b0n0", Type: "Node", NID: 1},
+			{ID: "x1000c1s7b1n0", Type: "Node", NID: 12},
+		},
+		RedfishEndpoints: []csm.RedfishEndpoint{
+			{ID: "x1000c1s7b0", MACAddr: "DE:CA:FC:0F:EE:01"},
+		},
+		EthernetInterfaces: []smd.EthernetInterface{
+			{ComponentID: "x1000c1s7b0n0", MACAddress: "de:ad:be:ee:ee:01"},
+		},
+		Groups: []smd.Group{existing},
+	}
+
+	t.Run("overwrite", func(t *testing.T) {
+		p := NewPlan(payloads, state, true)
+		want := []PlanStep{
+			{
+				Service: "smd",
+				Kind:    DiffKindComponent,
+				Resources: []PlanResource{
+					{ID: "x1000c1s7b0n0", Action: PlanNoOp},
+					{ID: "x1000c1s7b1n0", Action: PlanUpdate, Changes: []FieldChange{{Field: "NID", From: "12", To: "2"}}},
+					{ID: "x1000c1s7b2n0", Action: PlanCreate},
+				},
+				Requests: 3,
+			},
+			{
+				Service:   "smd",
+				Kind:      DiffKindRedfishEndpoint,
+				DependsOn: []string{DiffKindComponent},
+				Resources: []PlanResource{
+					{ID: "x1000c1s7b0", Action: PlanNoOp},
+					{ID: "x1000c1s7b2", Action: PlanCreate},
+				},
+				Requests: 1,
+			},
+			{
+				Service:   "smd",
+				Kind:      DiffKindEthernetInterface,
+				DependsOn: []string{DiffKindComponent},
+				Resources: []PlanResource{{ID: "deadbeeeee01", Action: PlanNoOp}},
+			},
+			{
+				Service:   "smd",
+				Kind:      PlanKindGroup,
+				DependsOn: []string{DiffKindComponent},
+				Resources: []PlanResource{{
+					ID:      "compute",
+					Action:  PlanUpdate,
+					Changes: []FieldChange{{Field: "Members", From: "x1000c1s7b0n0,x1000c1s7b9n0", To: "x1000c1s7b0n0,x1000c1s7b1n0"}},
+				}},
+				Requests: 2,
+			},
+		}
+		if !reflect.DeepEqual(p.Steps, want) {
+			t.Errorf("got steps %+v, want %+v", p.Steps, want)
+		}
+		if p.Requests != 6 {
+			t.Errorf("got %d requests, want 6", p.Requests)
+		}
+
+		create := p.PayloadsWith(PlanCreate)
+		if len(create.Components.Components) != 1 || create.Components.Components[0].ID != "x1000c1s7b2n0" {
+			t.Errorf("got components to create %v", create.Components.Components)
+		}
+		if len(create.RedfishEndpoints.RedfishEndpoints) != 1 || len(create.EthernetInterfaces) != 0 || len(create.Groups) != 0 {
+			t.Errorf("got payloads to create %+v", create)
+		}
+		update := p.PayloadsWith(PlanUpdate)
+		if len(update.Components.Components) != 1 || len(update.Groups) != 1 || len(update.RedfishEndpoints.RedfishEndpoints) != 0 {
+			t.Errorf("got payloads to update %+v", update)
+		}
+
+		s := p.String()
+		for _, line := range []string{
+			"1. SMD Component: 3 request(s)",
+			"  ~ x1000c1s7b1n0\n      NID: \"12\" -> \"2\"",
+			"  + x1000c1s7b2n0",
+			"2. SMD RedfishEndpoint (after Component): 1 request(s)",
+			"Plan: 2 to create, 2 to update, 3 unchanged, 0 conflicting; 6 request(s)",
+		} {
+			if !strings.Contains(s, line) {
+				t.Errorf("plan text does not contain %q:\n%s", line, s)
+			}
+		}
+	})
+
+	t.Run("no overwrite", func(t *testing.T) {
+		p := NewPlan(payloads, state, false)
+		if got := p.Action(DiffKindComponent, "x1000c1s7b1n0"); got != PlanConflict {
+			t.Errorf("got action %q for changed component, want %q", got, PlanConflict)
+		}
+		if got := p.Action(PlanKindGroup, "compute"); got != PlanConflict {
+			t.Errorf("got action %q for changed group, want %q", got, PlanConflict)
+		}
+		if p.Count(PlanUpdate) != 0 || p.Count(PlanConflict) != 2 {
+			t.Errorf("got %d updates and %d conflicts, want 0 and 2", p.Count(PlanUpdate), p.Count(PlanConflict))
+		}
+		if p.Requests != 2 {
+			t.Errorf("got %d requests, want 2", p.Requests)
+		}
+	})
+
+	t.Run("hardware inventory", func(t *testing.T) {
+		withHW := payloads
+		withHW.HardwareInventory = []smd.HWInvByLoc{{ID: "x1000c1s7b1n0"}}
+		p := NewPlan(withHW, state, true)
+		if len(p.Payloads.HardwareInventory) != 1 {
+			t.Errorf("got hardware inventory %v in plan, want it kept", p.Payloads.HardwareInventory)
+		}
+		if p.Requests != 6 {
+			t.Errorf("got %d requests, want 6", p.Requests)
+		}
+		if s := p.String(); !strings.Contains(s, "SMD hardware inventory: 1 location(s) sent as is") {
+			t.Errorf("plan text does not list the hardware inventory:\n%s", s)
+		}
+	})
+
+	t.Run("changed", func(t *testing.T) {
+		p := NewPlan(payloads, state, true)
+		if changed := p.Changed(NewPlan(payloads, state, true)); len(changed) != 0 {
+			t.Errorf("got changes %v for the same state", changed)
+		}
+		later := state
+		later.Components = append(later.Components, smd.Component{ID: "x1000c1s7b2n0", Type: "Node", NID: 3})
+		want := []string{"Component x1000c1s7b2n0: create -> no-op"}
+		if changed := p.Changed(NewPlan(payloads, later, true)); !reflect.DeepEqual(changed, want) {
+			t.Errorf("got changes %v, want %v", changed, want)
+		}
+	})
+}
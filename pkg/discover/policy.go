@@ -0,0 +1,51 @@
+package discover
+
+import "fmt"
+
+// ErrorPolicy is what DiscoveryInfoV3 does with malformed nodes in a payload,
+// such as a node with invalid BMC credentials or an interface without a MAC
+// address.
+type ErrorPolicy string
+
+const (
+	// ErrorPolicyFailFast returns an error for the first malformed node.
+	ErrorPolicyFailFast ErrorPolicy = "fail-fast"
+
+	// ErrorPolicySkipNode generates nothing for malformed nodes and
+	// returns a WarningSkippedNode for each.
+	ErrorPolicySkipNode ErrorPolicy = "skip-node"
+
+	// ErrorPolicySkipInterface leaves the malformed interfaces of a node
+	// out, returning a WarningSkippedInterface for each, and otherwise
+	// skips malformed nodes like ErrorPolicySkipNode.
+	ErrorPolicySkipInterface ErrorPolicy = "skip-interface"
+)
+
+// ErrorPolicyHelp describes each ErrorPolicy.
+var ErrorPolicyHelp = map[string]string{
+	string(ErrorPolicyFailFast):      "Stop at the first malformed node",
+	string(ErrorPolicySkipNode):      "Skip malformed nodes and report them",
+	string(ErrorPolicySkipInterface): "Skip malformed interfaces, or else nodes, and report them",
+}
+
+func (ep ErrorPolicy) String() string {
+	return string(ep)
+}
+
+func (ep *ErrorPolicy) Set(v string) error {
+	switch ErrorPolicy(v) {
+	case ErrorPolicyFailFast, ErrorPolicySkipNode, ErrorPolicySkipInterface:
+		*ep = ErrorPolicy(v)
+		return nil
+	default:
+		return fmt.Errorf("must be one of %v", []ErrorPolicy{
+			ErrorPolicyFailFast,
+			ErrorPolicySkipNode,
+			ErrorPolicySkipInterface,
+		})
+	}
+}
+
+func (ep ErrorPolicy) Type() string {
+	return "ErrorPolicy"
+}
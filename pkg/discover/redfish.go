@@ -0,0 +1,92 @@
+package discover
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/openchami/schemas/schemas"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// DiscoveryInfoFromScan is like DiscoveryInfoV3, but for nodes whose BMCs were
+// actually discovered: nl is the NodeList mapped from results (see
+// NodeListFromScan), and the SMD structures are generated from what was read
+// from the BMCs wherever they have it. The System of each node is the first
+// system of its BMC, with its Redfish URI and all of its Ethernet interfaces
+// along with the IPv4 addresses the BMC reports for them, which may be none.
+// The interfaces and bonds of the nodes of nl are ignored. The RedfishEndpoint
+// of each BMC has the credentials that it accepted, unless its node sets its
+// own. Since the interfaces of nodes are ignored, nodes without them are not
+// warned about.
+func DiscoveryInfoFromScan(baseURI string, nl NodeList, results []ScanResult) (smd.ComponentSlice, smd.RedfishEndpointSliceV2, []smd.EthernetInterface, []Warning, error) {
+	bare := NodeList{Version: nl.Version, Nodes: make([]Node, len(nl.Nodes))}
+	for i, node := range nl.Nodes {
+		node.Ifaces, node.Bonds = nil, nil
+		bare.Nodes[i] = node
+	}
+	comps, rfes, _, warnings, err := DiscoveryInfoV3(baseURI, bare, ErrorPolicyFailFast)
+	if err != nil {
+		return comps, rfes, nil, nil, err
+	}
+	warnings = slices.DeleteFunc(warnings, func(w Warning) bool { return w.Code == WarningNoInterfaces })
+
+	byIP := make(map[string]ScanResult, len(results))
+	for _, r := range results {
+		byIP[r.IP] = r
+	}
+	var ifaces []smd.EthernetInterface
+	for _, node := range bare.Nodes {
+		r, ok := byIP[node.BMCIP]
+		if !ok {
+			continue
+		}
+		idx := slices.IndexFunc(rfes.RedfishEndpoints, func(rfe smd.RedfishEndpointV2) bool {
+			return rfe.IPAddress == node.BMCIP
+		})
+		if idx < 0 {
+			continue
+		}
+		rfe := &rfes.RedfishEndpoints[idx]
+		if rfe.User == "" && rfe.Password == "" {
+			rfe.User, rfe.Password = r.Username, r.Password
+		}
+		if len(rfe.Systems) == 0 || len(r.Inventory.Systems) == 0 {
+			continue
+		}
+
+		sys := r.Inventory.Systems[0]
+		s := &rfe.Systems[0]
+		if u, err := url.Parse(r.URI); err == nil && r.URI != "" && sys.ID != "" {
+			u.Path = sys.ID
+			s.URI = u.String()
+		}
+		if s.Name == "" {
+			s.Name = sys.HostName
+		}
+		for i, iface := range sys.Interfaces {
+			newIface := schemas.EthernetInterface{
+				Name:        node.Xname,
+				Description: fmt.Sprintf("Interface %d for %s", i, s.Name),
+				MAC:         iface.MACAddress,
+			}
+			SMDIface := smd.EthernetInterface{
+				ComponentID: newIface.Name,
+				Type:        node.ComponentType(),
+				Description: newIface.Description,
+				MACAddress:  newIface.MAC,
+			}
+			for _, ip := range iface.IPv4Addresses {
+				SMDIface.IPAddresses = append(SMDIface.IPAddresses, smd.EthernetIP{IPAddress: ip})
+			}
+			if len(iface.IPv4Addresses) > 0 {
+				newIface.IP = iface.IPv4Addresses[0]
+			}
+			s.EthernetInterfaces = append(s.EthernetInterfaces, newIface)
+			ifaces = append(ifaces, SMDIface)
+		}
+	}
+
+	return comps, rfes, ifaces, warnings, nil
+}
@@ -0,0 +1,73 @@
+package discover
+
+import (
+	"testing"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/redfish"
+)
+
+func TestDiscoveryInfoFromScan(t *testing.T) {
+	results := []ScanResult{{
+		IP:       "10.254.0.11",
+		URI:      "https://10.254.0.11:443",
+		Username: "root",
+		Password: "secret",
+		Inventory: redfish.Inventory{
+			ManagerInterfaces: []redfish.EthernetInterface{{MACAddress: "de:ca:fc:00:00:01", IPv4Addresses: []string{"10.254.0.11"}}},
+			Systems: []redfish.System{{
+				ID:       "/redfish/v1/Systems/Self",
+				HostName: "nid001",
+				Interfaces: []redfish.EthernetInterface{
+					{MACAddress: "de:ad:be:ee:ef:01", IPv4Addresses: []string{"172.16.0.1"}},
+					{MACAddress: "de:ad:be:ee:ef:02"},
+				},
+			}},
+		},
+	}}
+	rules := LeaseRules{Rules: []LeaseRule{{MACPrefix: "de:ca:fc", Xname: "x1000c0s{{ .Index }}b0n0", Name: "{{ .Hostname }}", NIDStart: 1}}}
+	nl, _, err := NodeListFromScan(results, rules)
+	if err != nil {
+		t.Fatalf("NodeListFromScan() error = %v", err)
+	}
+
+	comps, rfes, ifaces, warnings, err := DiscoveryInfoFromScan("https://smd.example.com", nl, results)
+	if err != nil {
+		t.Fatalf("DiscoveryInfoFromScan() error = %v", err)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("DiscoveryInfoFromScan() warnings = %+v, want none", warnings)
+	}
+	if len(comps.Components) != 1 || comps.Components[0].ID != "x1000c0s0b0n0" || comps.Components[0].NID != 1 {
+		t.Errorf("components = %+v, want x1000c0s0b0n0 with NID 1", comps.Components)
+	}
+	if len(rfes.RedfishEndpoints) != 1 {
+		t.Fatalf("got %d redfish endpoints, want 1", len(rfes.RedfishEndpoints))
+	}
+	rfe := rfes.RedfishEndpoints[0]
+	if rfe.ID != "x1000c0s0b0" || rfe.IPAddress != "10.254.0.11" || rfe.User != "root" || rfe.Password != "secret" {
+		t.Errorf("redfish endpoint = %+v, want x1000c0s0b0 at 10.254.0.11 as root", rfe)
+	}
+	if len(rfe.Systems) != 1 {
+		t.Fatalf("got %d systems, want 1", len(rfe.Systems))
+	}
+	s := rfe.Systems[0]
+	if s.URI != "https://10.254.0.11:443/redfish/v1/Systems/Self" || s.Name != "nid001" {
+		t.Errorf("system = %s %q, want the URI read from the BMC and the name of the node", s.URI, s.Name)
+	}
+	if len(s.EthernetInterfaces) != 2 || s.EthernetInterfaces[0].IP != "172.16.0.1" || s.EthernetInterfaces[1].IP != "" {
+		t.Errorf("system interfaces = %+v, want both interfaces, only the first with an IP", s.EthernetInterfaces)
+	}
+	if len(ifaces) != 2 || ifaces[0].ComponentID != "x1000c0s0b0n0" || len(ifaces[0].IPAddresses) != 1 || len(ifaces[1].IPAddresses) != 0 {
+		t.Errorf("ethernet interfaces = %+v", ifaces)
+	}
+
+	// Credentials of the node take precedence over those that were tried
+	nl.Nodes[0].BMCUsername, nl.Nodes[0].BMCPassword = "admin", "other"
+	_, rfes, _, _, err = DiscoveryInfoFromScan("https://smd.example.com", nl, results)
+	if err != nil {
+		t.Fatalf("DiscoveryInfoFromScan() error = %v", err)
+	}
+	if rfe := rfes.RedfishEndpoints[0]; rfe.User != "admin" || rfe.Password != "other" {
+		t.Errorf("redfish endpoint credentials = %s/%s, want those of the node", rfe.User, rfe.Password)
+	}
+}
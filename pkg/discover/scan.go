@@ -0,0 +1,118 @@
+package discover
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/redfish"
+)
+
+// maxScanAddrs is the largest number of addresses ScanAddrs returns, so that
+// a mistyped prefix (e.g. /8) does not start a scan that never finishes.
+const maxScanAddrs = 1 << 16
+
+// PlaceholderScanRules are the rules used to map scanned BMCs to nodes when no
+// rules are given: every BMC is mapped to a placeholder xname by its index,
+// which must be reviewed and corrected before the payload is used.
+var PlaceholderScanRules = LeaseRules{
+	Rules: []LeaseRule{{Xname: "x0c0s{{ .Index }}b0n0"}},
+}
+
+// ScanCredentials are the BMC credentials to try when scanning for Redfish
+// endpoints. Each BMC is authenticated to with the first credentials that
+// work.
+type ScanCredentials struct {
+	Credentials []ScanCredential `json:"credentials" yaml:"credentials"`
+}
+
+// ScanCredential is a user name and password for BMCs.
+type ScanCredential struct {
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// ScanResult is a BMC found by scanning for Redfish endpoints, with the
+// inventory read from it. URI is the base URI of its Redfish service, and
+// Username and Password are the credentials that it accepted.
+type ScanResult struct {
+	IP        string
+	URI       string
+	Username  string
+	Password  string
+	Inventory redfish.Inventory
+}
+
+// ScanAddrs returns the addresses of prefix to scan in order, which excludes
+// the network and broadcast addresses of IPv4 prefixes shorter than /31. An
+// error is returned if prefix has more than 65536 addresses.
+func ScanAddrs(prefix netip.Prefix) ([]netip.Addr, error) {
+	prefix = prefix.Masked()
+	hostBits := prefix.Addr().BitLen() - prefix.Bits()
+	if hostBits > 16 {
+		return nil, fmt.Errorf("prefix %s is too large to scan (more than %d addresses)", prefix, maxScanAddrs)
+	}
+	var addrs []netip.Addr
+	for a := prefix.Addr(); prefix.Contains(a); a = a.Next() {
+		addrs = append(addrs, a)
+		if !a.Next().IsValid() {
+			break
+		}
+	}
+	if prefix.Addr().Is4() && hostBits > 1 {
+		addrs = addrs[1 : len(addrs)-1]
+	}
+
+	return addrs, nil
+}
+
+// managerMAC returns the MAC address of the manager interface of inv that has
+// ip, or of its first interface if none has it.
+func managerMAC(inv redfish.Inventory, ip string) string {
+	for _, iface := range inv.ManagerInterfaces {
+		if slices.Contains(iface.IPv4Addresses, ip) {
+			return iface.MACAddress
+		}
+	}
+	if len(inv.ManagerInterfaces) > 0 {
+		return inv.ManagerInterfaces[0].MACAddress
+	}
+
+	return ""
+}
+
+// NodeListFromScan maps the BMCs found by a scan to nodes using rules, the same
+// way NodeListFromLeases maps DHCP leases: the MAC address of each BMC's
+// manager interface with its IP address (or its first one) and its IP address
+// are matched against the rules, and the host name of its first system is
+// available to templates as Hostname. The MAC addresses of the Ethernet
+// interfaces of the first system of each BMC become the interfaces of its
+// node. The results that did not match any rule are returned as leases.
+func NodeListFromScan(results []ScanResult, rules LeaseRules) (NodeList, []Lease, error) {
+	byIP := make(map[string]ScanResult)
+	leases := make([]Lease, 0, len(results))
+	for _, r := range results {
+		l := Lease{MAC: managerMAC(r.Inventory, r.IP), IP: r.IP}
+		if len(r.Inventory.Systems) > 0 {
+			l.Hostname = r.Inventory.Systems[0].HostName
+		}
+		leases = append(leases, l)
+		byIP[r.IP] = r
+	}
+	nl, unmatched, err := NodeListFromLeases(leases, rules)
+	if err != nil {
+		return nl, nil, err
+	}
+	for i := range nl.Nodes {
+		n := &nl.Nodes[i]
+		r := byIP[n.BMCIP]
+		if len(r.Inventory.Systems) == 0 {
+			continue
+		}
+		for _, iface := range r.Inventory.Systems[0].Interfaces {
+			n.Ifaces = append(n.Ifaces, Iface{MACAddr: iface.MACAddress, IPAddrs: []IfaceIP{}})
+		}
+	}
+
+	return nl, unmatched, nil
+}
@@ -0,0 +1,98 @@
+package discover
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/redfish"
+)
+
+func TestScanAddrs(t *testing.T) {
+	tests := []struct {
+		prefix    string
+		wantLen   int
+		wantFirst string
+		wantLast  string
+		wantErr   bool
+	}{
+		{prefix: "10.254.0.0/24", wantLen: 254, wantFirst: "10.254.0.1", wantLast: "10.254.0.254"},
+		{prefix: "10.254.0.7/30", wantLen: 2, wantFirst: "10.254.0.5", wantLast: "10.254.0.6"},
+		{prefix: "10.254.0.4/31", wantLen: 2, wantFirst: "10.254.0.4", wantLast: "10.254.0.5"},
+		{prefix: "10.254.0.9/32", wantLen: 1, wantFirst: "10.254.0.9", wantLast: "10.254.0.9"},
+		{prefix: "10.0.0.0/16", wantLen: 65534, wantFirst: "10.0.0.1", wantLast: "10.0.255.254"},
+		{prefix: "fd00::/126", wantLen: 4, wantFirst: "fd00::", wantLast: "fd00::3"},
+		{prefix: "10.0.0.0/8", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			got, err := ScanAddrs(netip.MustParsePrefix(tt.prefix))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ScanAddrs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != tt.wantLen || got[0].String() != tt.wantFirst || got[len(got)-1].String() != tt.wantLast {
+				t.Errorf("ScanAddrs() = %d addresses from %s to %s, want %d from %s to %s",
+					len(got), got[0], got[len(got)-1], tt.wantLen, tt.wantFirst, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestNodeListFromScan(t *testing.T) {
+	results := []ScanResult{
+		{
+			IP: "10.254.0.12",
+			Inventory: redfish.Inventory{
+				ManagerInterfaces: []redfish.EthernetInterface{
+					{MACAddress: "de:ca:fc:00:00:99", IPv4Addresses: []string{"192.168.0.12"}},
+					{MACAddress: "de:ca:fc:00:00:02", IPv4Addresses: []string{"10.254.0.12"}},
+				},
+				Systems: []redfish.System{{HostName: "nid002", Interfaces: []redfish.EthernetInterface{{MACAddress: "de:ad:be:ee:ef:02"}}}},
+			},
+		},
+		{
+			IP: "10.254.0.11",
+			Inventory: redfish.Inventory{
+				ManagerInterfaces: []redfish.EthernetInterface{{MACAddress: "de:ca:fc:00:00:01"}},
+				Systems:           []redfish.System{{HostName: "nid001"}},
+			},
+		},
+		{
+			IP:        "10.254.0.13",
+			Inventory: redfish.Inventory{ManagerInterfaces: []redfish.EthernetInterface{{MACAddress: "aa:bb:cc:00:00:03"}}},
+		},
+	}
+	rules := LeaseRules{Rules: []LeaseRule{{MACPrefix: "de:ca:fc", Xname: "x1000c0s{{ .Index }}b0n0", Name: "{{ .Hostname }}", NIDStart: 1}}}
+	got, unmatched, err := NodeListFromScan(results, rules)
+	if err != nil {
+		t.Fatalf("NodeListFromScan() error = %v", err)
+	}
+	want := NodeList{
+		Version: NodeListVersion,
+		Nodes: []Node{
+			{Name: "nid001", NID: 1, Xname: "x1000c0s0b0n0", Groups: []string{}, BMCMac: "de:ca:fc:00:00:01", BMCIP: "10.254.0.11", Ifaces: []Iface{}},
+			{
+				Name: "nid002", NID: 2, Xname: "x1000c0s1b0n0", Groups: []string{}, BMCMac: "de:ca:fc:00:00:02", BMCIP: "10.254.0.12",
+				Ifaces: []Iface{{MACAddr: "de:ad:be:ee:ef:02", IPAddrs: []IfaceIP{}}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NodeListFromScan() = %+v, want %+v", got, want)
+	}
+	if wantUnmatched := []Lease{{MAC: "aa:bb:cc:00:00:03", IP: "10.254.0.13"}}; !reflect.DeepEqual(unmatched, wantUnmatched) {
+		t.Errorf("unmatched = %v, want %v", unmatched, wantUnmatched)
+	}
+
+	// Without rules, every BMC gets a placeholder xname
+	got, unmatched, err = NodeListFromScan(results, PlaceholderScanRules)
+	if err != nil {
+		t.Fatalf("NodeListFromScan() with placeholder rules error = %v", err)
+	}
+	if len(got.Nodes) != 3 || len(unmatched) != 0 || got.Nodes[2].Xname != "x0c0s2b0n0" {
+		t.Errorf("NodeListFromScan() with placeholder rules = %+v, unmatched %v", got, unmatched)
+	}
+}
@@ -0,0 +1,158 @@
+package discover
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/invopop/jsonschema"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// NodeListSchema returns the JSON Schema of the current version of the
+// NodeList format, generated from NodeList. Since payloads are decoded
+// leniently, it is stricter than reading them: unknown keys are not allowed,
+// so that misspelt keys are not silently ignored, and values must have the
+// type of their field instead of being decoded as zero values. What a field
+// means is not checked; see Validate for that.
+func NodeListSchema() ([]byte, error) {
+	r := jsonschema.Reflector{
+		RequiredFromJSONSchemaTags: true,
+		ExpandedStruct:             true,
+	}
+	s := r.Reflect(NodeList{})
+	s.Title = fmt.Sprintf("OpenCHAMI discovery payload, version %d", NodeListVersion)
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload schema: %w", err)
+	}
+
+	return b, nil
+}
+
+// ValidateSchema validates payload data, as unmarshalled generically from JSON
+// or YAML before being passed to MigrateNodeList, against NodeListSchema and
+// returns a ValidationError for each mismatch, in the order of the nodes and
+// their fields, or none if it matches.
+// Unversioned payloads are validated as migrated, so that their legacy shapes
+// are not mismatches, and keys set to null are treated as unset, as they are
+// when the payload is read. Mismatches outside of nodes have a Node of -1.
+func ValidateSchema(data any) ([]ValidationError, error) {
+	// Validate a copy, since migrating changes the data in place
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	var doc any
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
+	}
+	if nodes, ok := doc.([]any); ok {
+		doc = map[string]any{"nodes": nodes}
+	}
+	if m, ok := doc.(map[string]any); ok {
+		if version, err := nodeListVersion(m); err == nil && version == 0 {
+			migrateNodeListV0(m)
+		}
+	}
+	doc = schemaDropNulls(doc)
+
+	schema, err := NodeListSchema()
+	if err != nil {
+		return nil, err
+	}
+	res, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(schema), gojsonschema.NewGoLoader(doc))
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate payload against schema: %w", err)
+	}
+
+	var errs []ValidationError
+	m, _ := doc.(map[string]any)
+	nodes, _ := m["nodes"].([]any)
+	for _, re := range res.Errors() {
+		path := strings.Split(re.Field(), ".")
+		if path[0] == gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+			path = path[1:]
+		}
+
+		// Keys that are unknown or missing are reported on their object
+		msg := strings.TrimPrefix(re.Description(), re.Field()+" ")
+		switch re.Type() {
+		case "additional_property_not_allowed":
+			path = append(path, fmt.Sprint(re.Details()["property"]))
+			msg = "unknown key"
+		case "required":
+			path = append(path, fmt.Sprint(re.Details()["property"]))
+			msg = "missing required key"
+		default:
+			if msg != "" {
+				msg = strings.ToLower(msg[:1]) + msg[1:]
+			}
+		}
+
+		verr := ValidationError{Node: -1, Message: msg}
+		if len(path) > 1 && path[0] == "nodes" {
+			if idx, err := strconv.Atoi(path[1]); err == nil && idx < len(nodes) {
+				verr.Node = idx
+				path = path[2:]
+				if node, ok := nodes[idx].(map[string]any); ok {
+					verr.Xname, _ = node["xname"].(string)
+				}
+			}
+		}
+		verr.Field = schemaFieldPath(path)
+		errs = append(errs, verr)
+	}
+	slices.SortFunc(errs, func(a, b ValidationError) int {
+		return cmp.Or(cmp.Compare(a.Node, b.Node), cmp.Compare(a.Field, b.Field))
+	})
+
+	return errs, nil
+}
+
+// schemaFieldPath returns the path of a field in the form of the Fields of
+// ValidationErrors (e.g. interfaces[0].mac_addr) from its keys and indices, or
+// "." for the whole node or payload.
+func schemaFieldPath(path []string) string {
+	var b strings.Builder
+	for _, p := range path {
+		if _, err := strconv.Atoi(p); err == nil {
+			fmt.Fprintf(&b, "[%s]", p)
+			continue
+		}
+		if b.Len() > 0 {
+			b.WriteString(".")
+		}
+		b.WriteString(p)
+	}
+	if b.Len() == 0 {
+		return "."
+	}
+
+	return b.String()
+}
+
+// schemaDropNulls returns v with the keys of its objects that are null
+// removed, recursively.
+func schemaDropNulls(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			if e == nil {
+				delete(v, k)
+				continue
+			}
+			v[k] = schemaDropNulls(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = schemaDropNulls(e)
+		}
+	}
+
+	return v
+}
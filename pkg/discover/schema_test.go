@@ -0,0 +1,91 @@
+package discover
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNodeListSchema(t *testing.T) {
+	b, err := NodeListSchema()
+	if err != nil {
+		t.Fatalf("NodeListSchema() error = %v", err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(b, &schema); err != nil {
+		t.Fatalf("schema is not JSON: %v", err)
+	}
+	if schema["additionalProperties"] != false {
+		t.Errorf("schema allows unknown keys at the top level")
+	}
+	props, _ := schema["properties"].(map[string]any)
+	for _, k := range []string{"version", "nodes"} {
+		if _, ok := props[k]; !ok {
+			t.Errorf("schema has no property %q", k)
+		}
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    []ValidationError
+	}{
+		{
+			name: "valid",
+			payload: `{"version":1,"nodes":[{"name":"nid1","nid":1,"xname":"x1000c1s7b0n0",
+				"groups":null,"bmc_mac":"de:ca:fc:0f:ee:ee","bmc_ip":"172.16.0.101",
+				"interfaces":[{"mac_addr":"de:ad:be:ee:ee:f1","ip_addrs":[{"network":"mgmt","ip_addr":"172.16.0.1"}]}]}]}`,
+		},
+		{
+			name:    "compact entry",
+			payload: `{"version":1,"nodes":[{"xname":"x1000c1s[0-3]b0n0","nid":"1-4"}]}`,
+		},
+		{
+			name:    "unversioned with legacy shapes",
+			payload: `[{"xname":"x1000c1s7b0n0","group":"compute","interfaces":[{"mac_addr":"de:ad:be:ee:ee:f1","ip_addrs":[{"name":"mgmt","ip_addr":"172.16.0.1"}]}]}]`,
+		},
+		{
+			name:    "unknown and missing keys",
+			payload: `{"version":1,"node":[],"nodes":[{"xname":"x1000c1s7b0n0","interfaces":[{"mac_adr":"de:ad:be:ee:ee:f1"}]}]}`,
+			want: []ValidationError{
+				{Node: -1, Field: "node", Message: "unknown key"},
+				{Node: 0, Xname: "x1000c1s7b0n0", Field: "interfaces[0].mac_addr", Message: "missing required key"},
+				{Node: 0, Xname: "x1000c1s7b0n0", Field: "interfaces[0].mac_adr", Message: "unknown key"},
+			},
+		},
+		{
+			name:    "wrong types",
+			payload: `{"version":1,"nodes":[{"xname":"x1000c1s7b0n0","bmc_ip":17216,"bmc_interfaces":[{"mac_addr":"de:ca:fc:0f:ee:ee","kind":"nic"}]}]}`,
+			want: []ValidationError{
+				{Node: 0, Xname: "x1000c1s7b0n0", Field: "bmc_interfaces[0].kind", Message: `must be one of the following: "dedicated", "shared"`},
+				{Node: 0, Xname: "x1000c1s7b0n0", Field: "bmc_ip", Message: "invalid type. Expected: string, given: integer"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data any
+			if err := json.Unmarshal([]byte(tt.payload), &data); err != nil {
+				t.Fatalf("invalid test payload: %v", err)
+			}
+			got, err := ValidateSchema(data)
+			if err != nil {
+				t.Fatalf("ValidateSchema() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ValidateSchema() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// The data must be left as is for MigrateNodeList
+	data := []any{map[string]any{"xname": "x1000c1s7b0n0", "group": "compute"}}
+	if _, err := ValidateSchema(data); err != nil {
+		t.Fatalf("ValidateSchema() error = %v", err)
+	}
+	if _, ok := data[0].(map[string]any)["group"]; !ok {
+		t.Errorf("ValidateSchema() changed the payload: %+v", data)
+	}
+}
@@ -0,0 +1,158 @@
+package discover
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// SendState records the hashes of the records last sent to SMD by discovery,
+// so that only records that changed since are sent again. It maps the base URI
+// of each SMD sent to to the hash of each record, keyed by its kind and ID
+// (e.g. component/x1000c0s0b0n0), so one payload can be sent to several
+// clusters. Groups are not recorded, since they are merged with those in SMD
+// rather than replaced.
+type SendState map[string]map[string]string
+
+// LoadSendState reads the send state in path. If path does not exist, the send
+// state is empty.
+func LoadSendState(path string) (SendState, error) {
+	state := make(SendState)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read send state: %w", err)
+	}
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal send state %s: %w", path, err)
+	}
+
+	return state, nil
+}
+
+// Save writes state to path. Since the hashes of redfish endpoints cover the
+// credentials of their BMCs, the file is only readable by the user.
+func (state SendState) Save(path string) error {
+	b, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal send state: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write send state: %w", err)
+	}
+
+	return nil
+}
+
+// Changed returns the components, redfish endpoints, ethernet interfaces, and
+// hardware inventory entries of p that changed since they were last sent to SMD at uri, along with the
+// groups of p, and the number of records that were left out.
+func (state SendState) Changed(uri string, p Payloads) (Payloads, int) {
+	var skipped int
+	changed := func(key string, v any) bool {
+		if state[uri][key] == sendStateHash(v) {
+			skipped++
+			return false
+		}
+		return true
+	}
+
+	out := Payloads{Groups: p.Groups}
+	for _, c := range p.Components.Components {
+		if changed(componentStateKey(c), c) {
+			out.Components.Components = append(out.Components.Components, c)
+		}
+	}
+	for _, rfe := range p.RedfishEndpoints.RedfishEndpoints {
+		if changed(redfishEndpointStateKey(rfe), sendStateRedfishEndpoint(rfe)) {
+			out.RedfishEndpoints.RedfishEndpoints = append(out.RedfishEndpoints.RedfishEndpoints, rfe)
+		}
+	}
+	if p.EthernetInterfaces != nil {
+		out.EthernetInterfaces = []smd.EthernetInterface{}
+		for _, iface := range p.EthernetInterfaces {
+			if changed(ethernetInterfaceStateKey(iface), iface) {
+				out.EthernetInterfaces = append(out.EthernetInterfaces, iface)
+			}
+		}
+	}
+	for _, h := range p.HardwareInventory {
+		if changed(hardwareStateKey(h), h) {
+			out.HardwareInventory = append(out.HardwareInventory, h)
+		}
+	}
+
+	return out, skipped
+}
+
+// Sent records that the components, redfish endpoints, ethernet interfaces, and
+// hardware inventory entries of p were sent to SMD at uri.
+func (state SendState) Sent(uri string, p Payloads) {
+	if state[uri] == nil {
+		state[uri] = make(map[string]string)
+	}
+	for _, c := range p.Components.Components {
+		state[uri][componentStateKey(c)] = sendStateHash(c)
+	}
+	for _, rfe := range p.RedfishEndpoints.RedfishEndpoints {
+		state[uri][redfishEndpointStateKey(rfe)] = sendStateHash(sendStateRedfishEndpoint(rfe))
+	}
+	for _, iface := range p.EthernetInterfaces {
+		state[uri][ethernetInterfaceStateKey(iface)] = sendStateHash(iface)
+	}
+	for _, h := range p.HardwareInventory {
+		state[uri][hardwareStateKey(h)] = sendStateHash(h)
+	}
+}
+
+func componentStateKey(c smd.Component) string {
+	return "component/" + c.ID
+}
+
+func redfishEndpointStateKey(rfe smd.RedfishEndpointV2) string {
+	return "redfish-endpoint/" + rfe.ID
+}
+
+func ethernetInterfaceStateKey(iface smd.EthernetInterface) string {
+	return "ethernet-interface/" + cmp.Or(iface.ID, iface.MACAddress)
+}
+
+func hardwareStateKey(h smd.HWInvByLoc) string {
+	return "hardware/" + h.ID
+}
+
+// sendStateRedfishEndpoint returns rfe without the UUIDs of itself and its
+// Systems and Managers, which are generated anew each time discovery runs and
+// so would make it differ from the last time it was sent.
+func sendStateRedfishEndpoint(rfe smd.RedfishEndpointV2) smd.RedfishEndpointV2 {
+	rfe.UID = uuid.Nil
+	rfe.Systems = slices.Clone(rfe.Systems)
+	for i := range rfe.Systems {
+		rfe.Systems[i].UUID = ""
+	}
+	rfe.Managers = slices.Clone(rfe.Managers)
+	for i := range rfe.Managers {
+		rfe.Managers[i].UUID = ""
+	}
+
+	return rfe
+}
+
+// sendStateHash returns the SHA-256 hash of v marshalled into JSON, by which
+// changes to a record are detected.
+func sendStateHash(v any) string {
+	b, _ := json.Marshal(v)
+	sum := sha256.Sum256(b)
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,58 @@
+package discover
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSendState(t *testing.T) {
+	mgmt := func(mac, ip string) []Iface {
+		return []Iface{{MACAddr: mac, IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: ip}}}}
+	}
+	nodes := func(ip2 string) NodeList {
+		return NodeList{Nodes: []Node{
+			{Name: "nid1", NID: 1, Xname: "x1000c0s0b0n0", BMCMac: "de:ca:fc:0f:fe:e1", Ifaces: mgmt("de:ad:be:ee:ef:01", "172.16.100.1")},
+			{Name: "nid2", NID: 2, Xname: "x1000c0s1b0n0", BMCMac: "de:ca:fc:0f:fe:e2", Ifaces: mgmt("de:ad:be:ee:ef:02", ip2)},
+		}}
+	}
+	payloads := func(nl NodeList) Payloads {
+		comps, rfes, ifaces, err := DiscoveryInfoV2("http://example.com", nl)
+		if err != nil {
+			t.Fatalf("DiscoveryInfoV2 returned error: %v", err)
+		}
+		return Payloads{Components: comps, RedfishEndpoints: rfes, EthernetInterfaces: ifaces}
+	}
+	const uri = "https://smd.example.com"
+
+	path := filepath.Join(t.TempDir(), "state.json")
+	state, err := LoadSendState(path)
+	if err != nil {
+		t.Fatalf("LoadSendState() of missing file error = %v", err)
+	}
+	p, skipped := state.Changed(uri, payloads(nodes("172.16.100.2")))
+	if skipped != 0 || len(p.Components.Components) != 2 || len(p.RedfishEndpoints.RedfishEndpoints) != 2 || len(p.EthernetInterfaces) != 2 {
+		t.Fatalf("Changed() of empty state = %+v, %d, want all records", p, skipped)
+	}
+	state.Sent(uri, p)
+	if err := state.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if state, err = LoadSendState(path); err != nil {
+		t.Fatalf("LoadSendState() error = %v", err)
+	}
+
+	// UUIDs are generated anew, but the records are the same
+	if p, skipped = state.Changed(uri, payloads(nodes("172.16.100.2"))); skipped != 6 || len(p.Components.Components) != 0 || len(p.RedfishEndpoints.RedfishEndpoints) != 0 || len(p.EthernetInterfaces) != 0 {
+		t.Errorf("Changed() of same payloads = %+v, %d, want no records and 6 skipped", p, skipped)
+	}
+	p, skipped = state.Changed(uri, payloads(nodes("172.16.100.20")))
+	if skipped != 4 || len(p.Components.Components) != 0 || len(p.RedfishEndpoints.RedfishEndpoints) != 1 || len(p.EthernetInterfaces) != 1 {
+		t.Errorf("Changed() of changed node = %+v, %d, want its redfish endpoint and ethernet interface", p, skipped)
+	}
+	if p.EthernetInterfaces[0].ComponentID != "x1000c0s1b0n0" {
+		t.Errorf("Changed() of changed node returned ethernet interface of %s, want x1000c0s1b0n0", p.EthernetInterfaces[0].ComponentID)
+	}
+	if _, skipped = state.Changed("https://other.example.com", payloads(nodes("172.16.100.2"))); skipped != 0 {
+		t.Errorf("Changed() for other SMD skipped %d records, want none", skipped)
+	}
+}
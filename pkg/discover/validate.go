@@ -0,0 +1,352 @@
+package discover
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/xname"
+)
+
+// ValidationError is a problem with a field of a node in a discovery payload.
+// Node is the index of the node in the payload, or -1 for a problem outside of
+// nodes, and Xname its xname, if it has one. Field is the path of the field in the node (e.g.
+// interfaces[0].ip_addrs[1].ip_addr) and Value its value, if it has one.
+type ValidationError struct {
+	Node    int    `json:"node" yaml:"node"`
+	Xname   string `json:"xname,omitempty" yaml:"xname,omitempty"`
+	Field   string `json:"field" yaml:"field"`
+	Value   string `json:"value,omitempty" yaml:"value,omitempty"`
+	Message string `json:"message" yaml:"message"`
+}
+
+func (e ValidationError) Error() string {
+	node := fmt.Sprintf("node %d", e.Node)
+	if e.Node < 0 {
+		node = "payload"
+	}
+	if e.Xname != "" {
+		node += fmt.Sprintf(" (%s)", e.Xname)
+	}
+	if e.Value != "" {
+		return fmt.Sprintf("%s: %s %q: %s", node, e.Field, e.Value, e.Message)
+	}
+
+	return fmt.Sprintf("%s: %s: %s", node, e.Field, e.Message)
+}
+
+// validateOwner is the node and field that a value that must be unique in a
+// payload was first seen in. BMC is the xname of the BMC for BMC addresses,
+// which nodes sharing a BMC have in common.
+type validateOwner struct {
+	node  int
+	field string
+	bmc   string
+}
+
+// validator accumulates the ValidationErrors of a payload.
+type validator struct {
+	errs    []ValidationError
+	xnames  map[string]validateOwner
+	nids    map[int64]validateOwner
+	names   map[string]validateOwner
+	macs    map[string]validateOwner
+	ips     map[string]validateOwner
+	serials map[string]validateOwner
+}
+
+// Validate checks the nodes in nl and returns a ValidationError for each
+// problem found, in the order of the nodes, or none if the payload is valid.
+// These are problems that would otherwise only surface as warnings during
+// discovery or as errors from SMD:
+//
+//   - a missing or invalid node xname, or a hypervisor that is not a BMC
+//     xname or is set for a node that is not virtual
+//   - a missing NID
+//   - a BMC or interface MAC address or IP address that is missing or invalid
+//   - a node without interfaces or, unless it is virtual, without a BMC
+//   - a power action that is not one of ResetTypes or is listed twice
+//   - invalid BMC interfaces or bonds (see BMCInterfaces and BondInterfaces)
+//   - hardware with a negative count or size, or more CPU serial numbers
+//     than CPUs
+//   - an xname, NID, name, MAC address, IP address, or hardware serial number
+//     used by more than one node, except for the BMC addresses of nodes
+//     sharing a BMC
+//
+// Entries for devices other than nodes are checked for an unknown type, an
+// xname that does not match their type, and a NID, virtual, hypervisor,
+// power actions, or hardware, which they cannot have, instead of the node-specific problems. Their BMC is
+// checked as for nodes, except for those of types without one, which cannot
+// have BMC fields.
+func Validate(nl NodeList) []ValidationError {
+	v := validator{
+		xnames:  make(map[string]validateOwner),
+		nids:    make(map[int64]validateOwner),
+		names:   make(map[string]validateOwner),
+		macs:    make(map[string]validateOwner),
+		ips:     make(map[string]validateOwner),
+		serials: make(map[string]validateOwner),
+	}
+	for idx, node := range nl.Nodes {
+		v.node(idx, node)
+	}
+
+	return v.errs
+}
+
+// add records a ValidationError for field of node idx.
+func (v *validator) add(idx int, node Node, field, value, format string, a ...any) {
+	v.errs = append(v.errs, ValidationError{
+		Node:    idx,
+		Xname:   node.Xname,
+		Field:   field,
+		Value:   value,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// node checks node, the node with index idx in the payload.
+func (v *validator) node(idx int, node Node) {
+	if !node.IsNode() {
+		v.device(idx, node)
+		return
+	}
+
+	// Identity
+	if node.Xname == "" {
+		v.add(idx, node, "xname", "", "missing xname")
+	} else if ok, err := csm.NewNodeXname(node.Xname).Valid(); !ok {
+		v.add(idx, node, "xname", node.Xname, "not a valid node xname: %v", err)
+	} else {
+		v.unique(v.xnames, strings.ToLower(node.Xname), idx, node, "xname", node.Xname, "")
+	}
+	if node.NID <= 0 {
+		v.add(idx, node, "nid", fmt.Sprint(node.NID), "missing NID (must be positive)")
+	} else if owner, ok := v.nids[node.NID]; ok {
+		v.add(idx, node, "nid", fmt.Sprint(node.NID), "duplicate NID, also used by node %d", owner.node)
+	} else {
+		v.nids[node.NID] = validateOwner{node: idx, field: "nid"}
+	}
+	if node.Name != "" {
+		v.unique(v.names, node.Name, idx, node, "name", node.Name, "")
+	}
+	if node.Hypervisor != "" {
+		if !node.Virtual {
+			v.add(idx, node, "hypervisor", node.Hypervisor, "set for a node that is not virtual")
+		}
+		if !csm.IsValidBMCXName(node.Hypervisor) {
+			v.add(idx, node, "hypervisor", node.Hypervisor, "not a valid BMC xname")
+		}
+	}
+
+	// BMC, whose addresses nodes sharing it have in common. Virtual nodes
+	// without a hypervisor have none.
+	bmc := node.Hypervisor
+	if !node.Virtual {
+		bmc = node.Xname
+		if b, err := xname.NodeXnameToBMCXname(node.Xname); err == nil {
+			bmc = b
+		}
+	}
+	if !node.Virtual || node.Hypervisor != "" {
+		v.bmc(idx, node, bmc, !node.Virtual)
+	} else if _, err := node.BMCInterfaces(); err != nil {
+		v.add(idx, node, "bmc_interfaces", "", "%v", err)
+	}
+
+	// Interfaces
+	if len(node.Ifaces) == 0 && len(node.Bonds) == 0 {
+		v.add(idx, node, "interfaces", "", "node has no interfaces")
+	}
+	v.ifaces(idx, node)
+
+	// Power actions
+	for i, a := range node.PowerActions {
+		field := fmt.Sprintf("power_actions[%d]", i)
+		if !slices.Contains(ResetTypes(), a) {
+			v.add(idx, node, field, a, "not a Redfish ResetType (must be one of %v)", ResetTypes())
+		} else if slices.Contains(node.PowerActions[:i], a) {
+			v.add(idx, node, field, a, "duplicate power action")
+		}
+	}
+
+	if node.Hardware != nil {
+		v.hardware(idx, node, *node.Hardware)
+	}
+}
+
+// hardware checks h, the hardware of node idx.
+func (v *validator) hardware(idx int, node Node, h Hardware) {
+	for _, f := range []struct {
+		field string
+		value int
+	}{
+		{"hardware.cpu_count", h.CPUCount},
+		{"hardware.cpu_cores", h.CPUCores},
+		{"hardware.memory_gib", h.MemoryGiB},
+	} {
+		if f.value < 0 {
+			v.add(idx, node, f.field, fmt.Sprint(f.value), "must not be negative")
+		}
+	}
+	if h.CPUCount > 0 && len(h.CPUSerialNumbers) > h.CPUCount {
+		v.add(idx, node, "hardware.cpu_serial_numbers", strings.Join(h.CPUSerialNumbers, ","), "more serial numbers than cpu_count (%d)", h.CPUCount)
+	}
+
+	serial := func(field, s string) {
+		if s != "" {
+			v.unique(v.serials, strings.ToUpper(strings.TrimSpace(s)), idx, node, field, s, "")
+		}
+	}
+	serial("hardware.serial_number", h.SerialNumber)
+	for i, s := range h.CPUSerialNumbers {
+		serial(fmt.Sprintf("hardware.cpu_serial_numbers[%d]", i), s)
+	}
+	for i, gpu := range h.GPUs {
+		serial(fmt.Sprintf("hardware.gpus[%d].serial_number", i), gpu.SerialNumber)
+	}
+}
+
+// device checks node, the device other than a node with index idx in the
+// payload (see DeviceTypes). Unlike nodes, devices have no NID, need not have
+// interfaces, and have a BMC unless they are of a type without one.
+func (v *validator) device(idx int, node Node) {
+	dt, known := deviceTypes[node.Type]
+	if !known {
+		v.add(idx, node, "type", node.Type, "unknown type (must be %s or one of %v)", ComponentTypeNode, DeviceTypes())
+	}
+	if node.Xname == "" {
+		v.add(idx, node, "xname", "", "missing xname")
+	} else if known && !dt.xname.MatchString(strings.ToLower(node.Xname)) {
+		v.add(idx, node, "xname", node.Xname, "not a valid %s xname", node.Type)
+	} else {
+		v.unique(v.xnames, strings.ToLower(node.Xname), idx, node, "xname", node.Xname, "")
+	}
+	if node.NID != 0 {
+		v.add(idx, node, "nid", fmt.Sprint(node.NID), "set for a %s, which has no NID", node.Type)
+	}
+	if node.Name != "" {
+		v.unique(v.names, node.Name, idx, node, "name", node.Name, "")
+	}
+	if node.Virtual {
+		v.add(idx, node, "virtual", "true", "set for a %s, which is not a node", node.Type)
+	}
+	if node.Hypervisor != "" {
+		v.add(idx, node, "hypervisor", node.Hypervisor, "set for a %s, which is not a node", node.Type)
+	}
+	if len(node.PowerActions) > 0 {
+		v.add(idx, node, "power_actions", strings.Join(node.PowerActions, ","), "set for a %s, which is not a node", node.Type)
+	}
+	if node.Hardware != nil {
+		v.add(idx, node, "hardware", "", "set for a %s, which is not a node", node.Type)
+	}
+
+	// BMC, which devices with the same BMC (e.g. the PDUs of a PDU
+	// controller) have in common
+	if known && dt.bmcType == "" {
+		if len(node.BMCIfaces) > 0 || node.BMCMac != "" || node.BMCIP != "" {
+			v.add(idx, node, "bmc_mac", node.BMCMac, "set for a %s, which has no BMC", node.Type)
+		}
+	} else if known && node.Xname != "" {
+		v.bmc(idx, node, dt.bmcXname(strings.ToLower(node.Xname)), true)
+	}
+
+	v.ifaces(idx, node)
+}
+
+// bmc checks the BMC fields of node idx, whose BMC is bmc. If required is true,
+// the BMC must have a MAC address.
+func (v *validator) bmc(idx int, node Node, bmc string, required bool) {
+	if _, err := node.BMCInterfaces(); err != nil {
+		v.add(idx, node, "bmc_interfaces", "", "%v", err)
+		return
+	}
+	if len(node.BMCIfaces) == 0 && node.BMCMac == "" && required {
+		v.add(idx, node, "bmc_mac", "", "missing BMC MAC address (or bmc_interfaces)")
+	}
+	if node.BMCMac != "" {
+		v.mac(idx, node, "bmc_mac", node.BMCMac, bmc)
+	}
+	if node.BMCIP != "" {
+		v.ip(idx, node, "bmc_ip", node.BMCIP, bmc)
+	}
+	for i, iface := range node.BMCIfaces {
+		field := fmt.Sprintf("bmc_interfaces[%d]", i)
+		v.mac(idx, node, field+".mac_addr", iface.MACAddr, bmc)
+		if iface.IPAddr != "" {
+			v.ip(idx, node, field+".ip_addr", iface.IPAddr, bmc)
+		}
+	}
+}
+
+// ifaces checks the interfaces and bonds of node idx.
+func (v *validator) ifaces(idx int, node Node) {
+	for i, iface := range node.Ifaces {
+		field := fmt.Sprintf("interfaces[%d]", i)
+		if iface.MACAddr == "" {
+			v.add(idx, node, field+".mac_addr", "", "missing MAC address")
+		} else {
+			v.mac(idx, node, field+".mac_addr", iface.MACAddr, "")
+		}
+		for j, ip := range iface.IPAddrs {
+			v.ip(idx, node, fmt.Sprintf("%s.ip_addrs[%d].ip_addr", field, j), ip.IPAddr, "")
+		}
+	}
+	if _, err := node.BondInterfaces(); err != nil {
+		v.add(idx, node, "bonds", "", "%v", err)
+	}
+	for i, b := range node.Bonds {
+		field := fmt.Sprintf("bonds[%d]", i)
+		for j, m := range b.Members {
+			v.mac(idx, node, fmt.Sprintf("%s.members[%d]", field, j), m, "")
+		}
+		for j, ip := range b.IPAddrs {
+			v.ip(idx, node, fmt.Sprintf("%s.ip_addrs[%d].ip_addr", field, j), ip.IPAddr, "")
+		}
+	}
+}
+
+// mac checks the MAC address value of field of node idx, which is a BMC
+// address of the BMC bmc if it is set.
+func (v *validator) mac(idx int, node Node, field, value, bmc string) {
+	if _, err := net.ParseMAC(value); err != nil {
+		v.add(idx, node, field, value, "not a valid MAC address")
+		return
+	}
+	v.unique(v.macs, normalizeMAC(value), idx, node, field, value, bmc)
+}
+
+// ip checks the IP address value of field of node idx, which is a BMC address
+// of the BMC bmc if it is set.
+func (v *validator) ip(idx int, node Node, field, value, bmc string) {
+	if value == "" {
+		v.add(idx, node, field, "", "missing IP address")
+		return
+	}
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		v.add(idx, node, field, value, "not a valid IP address")
+		return
+	}
+	v.unique(v.ips, addr.String(), idx, node, field, value, bmc)
+}
+
+// unique records that key, the value of field of node idx, was seen in seen,
+// adding a ValidationError if another node or field already used it. BMC
+// addresses of the same BMC bmc can be used by several nodes.
+func (v *validator) unique(seen map[string]validateOwner, key string, idx int, node Node, field, value, bmc string) {
+	owner, ok := seen[key]
+	switch {
+	case !ok:
+		seen[key] = validateOwner{node: idx, field: field, bmc: bmc}
+	case bmc != "" && owner.bmc == bmc:
+	case owner.node == idx:
+		v.add(idx, node, field, value, "duplicate value, also used by %s", owner.field)
+	default:
+		v.add(idx, node, field, value, "duplicate value, also used by %s of node %d", owner.field, owner.node)
+	}
+}
@@ -0,0 +1,201 @@
+package discover
+
+import (
+	"reflect"
+	"testing"
+)
+
+// validNode returns a valid node with NID nid, BMC MAC address bmcMAC, and an
+// interface with MAC address mac, in slot n of chassis x1000c0, which is also
+// the last byte of its IP address.
+func validNode(nid int64, bmcMAC, mac, n string) Node {
+	return Node{
+		Name:   "nid" + n,
+		NID:    nid,
+		Xname:  "x1000c0s" + n + "b0n0",
+		BMCMac: bmcMAC,
+		Ifaces: []Iface{{MACAddr: mac, IPAddrs: []IfaceIP{{Network: "mgmt", IPAddr: "172.16.0." + n}}}},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		nodes []Node
+		want  []ValidationError
+	}{
+		{
+			name: "valid",
+			nodes: []Node{
+				validNode(1, "de:ca:fc:00:00:01", "de:ad:be:00:00:01", "1"),
+				validNode(2, "de:ca:fc:00:00:02", "de:ad:be:00:00:02", "2"),
+			},
+		},
+		{
+			name: "nodes sharing a BMC",
+			nodes: func() []Node {
+				a := validNode(1, "de:ca:fc:00:00:01", "de:ad:be:00:00:01", "1")
+				b := validNode(2, "de:ca:fc:00:00:01", "de:ad:be:00:00:02", "2")
+				b.Xname = "x1000c0s1b0n1"
+				return []Node{a, b}
+			}(),
+		},
+		{
+			name: "invalid fields",
+			nodes: []Node{{
+				NID:    0,
+				Xname:  "x1000c0s1b0",
+				BMCMac: "not-a-mac",
+				BMCIP:  "300.0.0.1",
+				Ifaces: []Iface{{MACAddr: "de:ad:be:00:00:01"}},
+			}},
+			want: []ValidationError{
+				{Node: 0, Xname: "x1000c0s1b0", Field: "xname", Value: "x1000c0s1b0", Message: "not a valid node xname: XName does not match regex"},
+				{Node: 0, Xname: "x1000c0s1b0", Field: "nid", Value: "0", Message: "missing NID (must be positive)"},
+				{Node: 0, Xname: "x1000c0s1b0", Field: "bmc_mac", Value: "not-a-mac", Message: "not a valid MAC address"},
+				{Node: 0, Xname: "x1000c0s1b0", Field: "bmc_ip", Value: "300.0.0.1", Message: "not a valid IP address"},
+			},
+		},
+		{
+			name: "missing BMC and interfaces",
+			nodes: []Node{{
+				NID:   1,
+				Xname: "x1000c0s1b0n0",
+			}},
+			want: []ValidationError{
+				{Node: 0, Xname: "x1000c0s1b0n0", Field: "bmc_mac", Message: "missing BMC MAC address (or bmc_interfaces)"},
+				{Node: 0, Xname: "x1000c0s1b0n0", Field: "interfaces", Message: "node has no interfaces"},
+			},
+		},
+		{
+			name: "virtual node without BMC",
+			nodes: []Node{{
+				NID:     1,
+				Xname:   "x1000c0s1b0n0",
+				Virtual: true,
+				Ifaces:  []Iface{{MACAddr: "de:ad:be:00:00:01", IPAddrs: []IfaceIP{{IPAddr: "172.16.0.1"}}}},
+			}},
+		},
+		{
+			name: "hypervisor of physical node",
+			nodes: func() []Node {
+				n := validNode(1, "de:ca:fc:00:00:01", "de:ad:be:00:00:01", "1")
+				n.Hypervisor = "x1000c0s1"
+				return []Node{n}
+			}(),
+			want: []ValidationError{
+				{Node: 0, Xname: "x1000c0s1b0n0", Field: "hypervisor", Value: "x1000c0s1", Message: "set for a node that is not virtual"},
+				{Node: 0, Xname: "x1000c0s1b0n0", Field: "hypervisor", Value: "x1000c0s1", Message: "not a valid BMC xname"},
+			},
+		},
+		{
+			name: "duplicates",
+			nodes: []Node{
+				validNode(1, "de:ca:fc:00:00:01", "de:ad:be:00:00:01", "1"),
+				validNode(1, "de:ca:fc:00:00:01", "DE:AD:BE:00:00:01", "1"),
+			},
+			want: []ValidationError{
+				{Node: 1, Xname: "x1000c0s1b0n0", Field: "xname", Value: "x1000c0s1b0n0", Message: "duplicate value, also used by xname of node 0"},
+				{Node: 1, Xname: "x1000c0s1b0n0", Field: "nid", Value: "1", Message: "duplicate NID, also used by node 0"},
+				{Node: 1, Xname: "x1000c0s1b0n0", Field: "name", Value: "nid1", Message: "duplicate value, also used by name of node 0"},
+				{Node: 1, Xname: "x1000c0s1b0n0", Field: "interfaces[0].mac_addr", Value: "DE:AD:BE:00:00:01", Message: "duplicate value, also used by interfaces[0].mac_addr of node 0"},
+				{Node: 1, Xname: "x1000c0s1b0n0", Field: "interfaces[0].ip_addrs[0].ip_addr", Value: "172.16.0.1", Message: "duplicate value, also used by interfaces[0].ip_addrs[0].ip_addr of node 0"},
+			},
+		},
+		{
+			name: "BMC MAC of different BMCs",
+			nodes: []Node{
+				validNode(1, "de:ca:fc:00:00:01", "de:ad:be:00:00:01", "1"),
+				validNode(2, "de:ca:fc:00:00:01", "de:ad:be:00:00:02", "2"),
+			},
+			want: []ValidationError{
+				{Node: 1, Xname: "x1000c0s2b0n0", Field: "bmc_mac", Value: "de:ca:fc:00:00:01", Message: "duplicate value, also used by bmc_mac of node 0"},
+			},
+		},
+		{
+			name: "hardware",
+			nodes: func() []Node {
+				a := validNode(1, "de:ca:fc:00:00:01", "de:ad:be:00:00:01", "1")
+				a.Hardware = &Hardware{SerialNumber: "SN1", CPUCount: 1, CPUSerialNumbers: []string{"c1", "c2"}, MemoryGiB: -1}
+				b := validNode(2, "de:ca:fc:00:00:02", "de:ad:be:00:00:02", "2")
+				b.Hardware = &Hardware{GPUs: []GPU{{SerialNumber: " sn1"}}}
+				return []Node{a, b}
+			}(),
+			want: []ValidationError{
+				{Node: 0, Xname: "x1000c0s1b0n0", Field: "hardware.memory_gib", Value: "-1", Message: "must not be negative"},
+				{Node: 0, Xname: "x1000c0s1b0n0", Field: "hardware.cpu_serial_numbers", Value: "c1,c2", Message: "more serial numbers than cpu_count (1)"},
+				{Node: 1, Xname: "x1000c0s2b0n0", Field: "hardware.gpus[0].serial_number", Value: " sn1", Message: "duplicate value, also used by hardware.serial_number of node 0"},
+			},
+		},
+		{
+			name: "devices",
+			nodes: []Node{
+				{Name: "sw1", Xname: "x3000c0r1b0", Type: ComponentTypeRouterBMC, BMCMac: "de:ca:fc:00:00:01"},
+				{Name: "pdu0", Xname: "x3000m0p0", Type: ComponentTypeCabinetPDU, BMCMac: "de:ca:fc:00:00:02"},
+				{Name: "pdu1", Xname: "x3000m0p1", Type: ComponentTypeCabinetPDU, BMCMac: "de:ca:fc:00:00:02"},
+				{Name: "mgmt1", Xname: "x3000c0w1", Type: ComponentTypeMgmtSwitch},
+			},
+		},
+		{
+			name: "invalid devices",
+			nodes: []Node{
+				{Xname: "x3000c0r1b0", Type: "Toaster"},
+				{NID: 1, Xname: "x3000c0s1b0n0", Type: ComponentTypeChassisBMC, Virtual: true},
+				{Xname: "x3000c0w1", Type: ComponentTypeMgmtSwitch, BMCMac: "de:ca:fc:00:00:01"},
+			},
+			want: []ValidationError{
+				{Node: 0, Xname: "x3000c0r1b0", Field: "type", Value: "Toaster", Message: "unknown type (must be Node or one of [CabinetPDU CabinetPDUController ChassisBMC MgmtSwitch RouterBMC])"},
+				{Node: 1, Xname: "x3000c0s1b0n0", Field: "xname", Value: "x3000c0s1b0n0", Message: "not a valid ChassisBMC xname"},
+				{Node: 1, Xname: "x3000c0s1b0n0", Field: "nid", Value: "1", Message: "set for a ChassisBMC, which has no NID"},
+				{Node: 1, Xname: "x3000c0s1b0n0", Field: "virtual", Value: "true", Message: "set for a ChassisBMC, which is not a node"},
+				{Node: 1, Xname: "x3000c0s1b0n0", Field: "bmc_mac", Message: "missing BMC MAC address (or bmc_interfaces)"},
+				{Node: 2, Xname: "x3000c0w1", Field: "bmc_mac", Value: "de:ca:fc:00:00:01", Message: "set for a MgmtSwitch, which has no BMC"},
+			},
+		},
+		{
+			name: "invalid power actions",
+			nodes: func() []Node {
+				n := validNode(1, "de:ca:fc:00:00:01", "de:ad:be:00:00:01", "1")
+				n.PowerActions = []string{"On", "on", "Hibernate", "On"}
+				return []Node{n, {Xname: "x3000m0", Type: ComponentTypeCabinetPDUController, BMCMac: "de:ca:fc:00:00:02", PowerActions: []string{"On"}}}
+			}(),
+			want: []ValidationError{
+				{Node: 0, Xname: "x1000c0s1b0n0", Field: "power_actions[1]", Value: "on", Message: "not a Redfish ResetType (must be one of [On ForceOff GracefulShutdown GracefulRestart ForceRestart Nmi ForceOn PushPowerButton PowerCycle Suspend Pause Resume])"},
+				{Node: 0, Xname: "x1000c0s1b0n0", Field: "power_actions[2]", Value: "Hibernate", Message: "not a Redfish ResetType (must be one of [On ForceOff GracefulShutdown GracefulRestart ForceRestart Nmi ForceOn PushPowerButton PowerCycle Suspend Pause Resume])"},
+				{Node: 0, Xname: "x1000c0s1b0n0", Field: "power_actions[3]", Value: "On", Message: "duplicate power action"},
+				{Node: 1, Xname: "x3000m0", Field: "power_actions", Value: "On", Message: "set for a CabinetPDUController, which is not a node"},
+			},
+		},
+		{
+			name: "invalid bonds",
+			nodes: func() []Node {
+				n := validNode(1, "de:ca:fc:00:00:01", "de:ad:be:00:00:01", "1")
+				n.Bonds = []Bond{{Name: "bond0", Members: []string{"de:ad:be:00:00:01"}}}
+				return []Node{n}
+			}(),
+			want: []ValidationError{
+				{Node: 0, Xname: "x1000c0s1b0n0", Field: "bonds", Message: "bond bond0: member de:ad:be:00:00:01 is also listed in interfaces"},
+				{Node: 0, Xname: "x1000c0s1b0n0", Field: "bonds[0].members[0]", Value: "de:ad:be:00:00:01", Message: "duplicate value, also used by interfaces[0].mac_addr"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Validate(NodeList{Nodes: tt.nodes})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidationError_Error(t *testing.T) {
+	e := ValidationError{Node: 2, Xname: "x1000c0s0b0n0", Field: "bmc_mac", Value: "xx", Message: "not a valid MAC address"}
+	if got, want := e.Error(), `node 2 (x1000c0s0b0n0): bmc_mac "xx": not a valid MAC address`; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+	e = ValidationError{Node: 0, Field: "xname", Message: "missing xname"}
+	if got, want := e.Error(), "node 0: xname: missing xname"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
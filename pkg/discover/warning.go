@@ -0,0 +1,70 @@
+package discover
+
+import "fmt"
+
+// WarningCode identifies the reason for a Warning, so that callers can act on
+// some warnings and not others.
+type WarningCode string
+
+const (
+	// WarningDuplicateXname is returned for a node with the same xname as
+	// an earlier node, whose Component is not added again.
+	WarningDuplicateXname WarningCode = "duplicate-xname"
+
+	// WarningBMCXnameFallback is returned for a node whose BMC xname cannot
+	// be derived from its xname, so its own xname is used as that of its
+	// BMC.
+	WarningBMCXnameFallback WarningCode = "bmc-xname-fallback"
+
+	// WarningBMCMismatch is returned for a node sharing a BMC with an
+	// earlier node, such as another node of the same blade, whose BMC
+	// fields differ from those of the earlier node, which are used.
+	WarningBMCMismatch WarningCode = "bmc-mismatch"
+
+	// WarningIgnoredBMC is returned for an entry that has BMC fields but
+	// cannot have a BMC, such as a virtual node without a hypervisor or a
+	// device of a type without one, whose BMC fields are ignored.
+	WarningIgnoredBMC WarningCode = "ignored-bmc"
+
+	// WarningNoInterfaces is returned for a node without interfaces or
+	// bonds, for which no EthernetInterfaces are added.
+	WarningNoInterfaces WarningCode = "no-interfaces"
+
+	// WarningSkippedNode is returned for a malformed node for which
+	// nothing is generated (see ErrorPolicySkipNode).
+	WarningSkippedNode WarningCode = "skipped-node"
+
+	// WarningSkippedInterface is returned for a malformed interface of a
+	// node that is left out (see ErrorPolicySkipInterface).
+	WarningSkippedInterface WarningCode = "skipped-interface"
+)
+
+// Warning is a problem with a node in a discovery payload that does not stop
+// the payloads for SMD from being generated, but that likely makes them differ
+// from what was intended. Node is the index of the node in the payload and
+// Xname its xname.
+type Warning struct {
+	Node    int         `json:"node" yaml:"node"`
+	Xname   string      `json:"xname,omitempty" yaml:"xname,omitempty"`
+	Code    WarningCode `json:"code" yaml:"code"`
+	Message string      `json:"message" yaml:"message"`
+}
+
+func (w Warning) String() string {
+	node := fmt.Sprintf("node %d", w.Node)
+	if w.Xname != "" {
+		node += fmt.Sprintf(" (%s)", w.Xname)
+	}
+
+	return fmt.Sprintf("%s: %s", node, w.Message)
+}
+
+// CountWarnings returns the number of warnings in warnings with each code.
+func CountWarnings(warnings []Warning) map[WarningCode]int {
+	counts := make(map[WarningCode]int)
+	for _, w := range warnings {
+		counts[w.Code]++
+	}
+
+	return counts
+}
@@ -0,0 +1,174 @@
+// Package events provides a common representation for change events from
+// OpenCHAMI services, regardless of the mechanism used to receive them, and
+// helpers for receiving them via polling and server-sent events (SSE).
+package events
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mechanisms by which events can be received. These are set as the Source of
+// an Event.
+const (
+	SourcePoll    = "poll"
+	SourceSSE     = "sse"
+	SourceWebhook = "webhook"
+)
+
+// Actions of events derived from comparing snapshots.
+const (
+	ActionAdded   = "added"
+	ActionChanged = "changed"
+	ActionRemoved = "removed"
+)
+
+// Event is a single change event from a service.
+type Event struct {
+	Time    time.Time `json:"time"`
+	Service string    `json:"service"`
+	Source  string    `json:"source"`
+	Kind    string    `json:"kind"`
+	Action  string    `json:"action"`
+	ID      string    `json:"id,omitempty"`
+	Data    any       `json:"data,omitempty"`
+}
+
+// Writer writes events to an io.Writer as newline-delimited JSON (NDJSON). It
+// is safe for concurrent use, so events from multiple sources can be written to
+// a single stream.
+type Writer struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewWriter returns a pointer to a new Writer that writes to w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{enc: json.NewEncoder(w)}
+}
+
+// Write writes e as a single line of JSON.
+func (w *Writer) Write(e Event) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.enc.Encode(e)
+}
+
+// Snapshot maps the IDs of the items of one kind in a service to their JSON
+// representation at one point in time, so that two snapshots can be compared.
+type Snapshot map[string]json.RawMessage
+
+// NewSnapshot returns a Snapshot of items, keyed by the ID returned by idFunc
+// for each item. An error is returned if an item cannot be marshalled.
+func NewSnapshot[T any](items []T, idFunc func(T) string) (Snapshot, error) {
+	s := make(Snapshot, len(items))
+	for _, item := range items {
+		b, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", idFunc(item), err)
+		}
+		s[idFunc(item)] = b
+	}
+
+	return s, nil
+}
+
+// Diff compares the snapshot old to the snapshot new and returns an event for
+// each item that was added, changed, or removed between them, sorted by ID.
+// Events for added and changed items contain the new item as their data and
+// events for removed items contain the old item. Only Kind, Action, ID, and
+// Data are set in the returned events.
+func Diff(kind string, old, new Snapshot) []Event {
+	var evts []Event
+	for id, n := range new {
+		o, ok := old[id]
+		switch {
+		case !ok:
+			evts = append(evts, Event{Kind: kind, Action: ActionAdded, ID: id, Data: n})
+		case !jsonEqual(o, n):
+			evts = append(evts, Event{Kind: kind, Action: ActionChanged, ID: id, Data: n})
+		}
+	}
+	for id, o := range old {
+		if _, ok := new[id]; !ok {
+			evts = append(evts, Event{Kind: kind, Action: ActionRemoved, ID: id, Data: o})
+		}
+	}
+	sort.Slice(evts, func(i, j int) bool { return evts[i].ID < evts[j].ID })
+
+	return evts
+}
+
+// jsonEqual reports whether a and b represent the same JSON value, regardless
+// of key order or whitespace.
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb any
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return string(a) == string(b)
+	}
+	ca, _ := json.Marshal(va)
+	cb, _ := json.Marshal(vb)
+
+	return string(ca) == string(cb)
+}
+
+// SSEMessage is a single message from a server-sent event stream.
+type SSEMessage struct {
+	Event string
+	ID    string
+	Data  string
+}
+
+// ReadSSE reads the server-sent event stream r, calling fn for each message
+// until r is exhausted or fn returns an error, which is returned. Comments and
+// retry fields are ignored, as is a message not terminated by a blank line at
+// the end of the stream.
+func ReadSSE(r io.Reader, fn func(SSEMessage) error) error {
+	var (
+		msg  SSEMessage
+		data []string
+		seen bool
+	)
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			// A blank line dispatches the message
+			if seen {
+				msg.Data = strings.Join(data, "\n")
+				if err := fn(msg); err != nil {
+					return err
+				}
+			}
+			msg, data, seen = SSEMessage{}, nil, false
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			msg.Event = value
+			seen = true
+		case "id":
+			msg.ID = value
+			seen = true
+		case "data":
+			data = append(data, value)
+			seen = true
+		}
+	}
+
+	// An incomplete message at the end of the stream is discarded
+	return sc.Err()
+}
@@ -0,0 +1,105 @@
+package events
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	old := Snapshot{
+		"a": json.RawMessage(`{"State":"On","NID":1}`),
+		"b": json.RawMessage(`{"State":"On"}`),
+		"c": json.RawMessage(`{"State":"Off"}`),
+	}
+	new := Snapshot{
+		// Same value with different key order and whitespace
+		"a": json.RawMessage(`{ "NID": 1, "State": "On" }`),
+		"b": json.RawMessage(`{"State":"Ready"}`),
+		"d": json.RawMessage(`{"State":"Off"}`),
+	}
+	want := []Event{
+		{Kind: "component", Action: ActionChanged, ID: "b", Data: new["b"]},
+		{Kind: "component", Action: ActionRemoved, ID: "c", Data: old["c"]},
+		{Kind: "component", Action: ActionAdded, ID: "d", Data: new["d"]},
+	}
+	if got := Diff("component", old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+	if got := Diff("component", new, new); len(got) != 0 {
+		t.Errorf("Diff() of identical snapshots = %+v, want none", got)
+	}
+}
+
+func TestNewSnapshot(t *testing.T) {
+	type item struct {
+		ID    string
+		State string
+	}
+	got, err := NewSnapshot([]item{{"x1", "On"}, {"x2", "Off"}}, func(i item) string { return i.ID })
+	if err != nil {
+		t.Fatalf("NewSnapshot() error = %v", err)
+	}
+	want := Snapshot{
+		"x1": json.RawMessage(`{"ID":"x1","State":"On"}`),
+		"x2": json.RawMessage(`{"ID":"x2","State":"Off"}`),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NewSnapshot() = %s, want %s", got, want)
+	}
+}
+
+func TestReadSSE(t *testing.T) {
+	stream := strings.Join([]string{
+		": keepalive",
+		"event: component",
+		"id: 1",
+		"data: {\"ID\":\"x1\",",
+		"data: \"State\":\"On\"}",
+		"",
+		"data:plain",
+		"retry: 1000",
+		"",
+		"",
+		"event: incomplete",
+		"data: dropped",
+	}, "\n")
+	var got []SSEMessage
+	err := ReadSSE(strings.NewReader(stream), func(m SSEMessage) error {
+		got = append(got, m)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ReadSSE() error = %v", err)
+	}
+	want := []SSEMessage{
+		{Event: "component", ID: "1", Data: "{\"ID\":\"x1\",\n\"State\":\"On\"}"},
+		{Data: "plain"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadSSE() messages = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	ts := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, e := range []Event{
+		{Time: ts, Service: "smd", Source: SourcePoll, Kind: "component", Action: ActionAdded, ID: "x1", Data: json.RawMessage(`{"State":"On"}`)},
+		{Time: ts, Service: "bss", Source: SourceSSE, Kind: "message", Action: "notified"},
+	} {
+		if err := w.Write(e); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+	want := `{"time":"2025-01-02T03:04:05Z","service":"smd","source":"poll","kind":"component","action":"added","id":"x1","data":{"State":"On"}}
+{"time":"2025-01-02T03:04:05Z","service":"bss","source":"sse","kind":"message","action":"notified"}
+`
+	if buf.String() != want {
+		t.Errorf("Write() output = %q, want %q", buf.String(), want)
+	}
+}
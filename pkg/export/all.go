@@ -0,0 +1,312 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/pool"
+)
+
+// ManifestName is the name of the manifest written by WriteDir.
+const ManifestName = "manifest.json"
+
+// Interface is an address of a network interface of a node. An interface with
+// several IP addresses has one Interface per IP address, and one without any
+// has a single Interface with an empty IP.
+type Interface struct {
+	MAC     string
+	IP      string
+	Network string
+}
+
+// Host is a node with its network interfaces, sorted by MAC and IP address. IP
+// is the first IP address of its interfaces, or "" if it has none, and BMC is
+// the host of its BMC (see BMC), or "" if its BMC is not in SMD.
+type Host struct {
+	ID         string
+	NID        int64
+	Role       string
+	Arch       string
+	IP         string
+	BMC        string
+	Interfaces []Interface
+}
+
+// Role is a node role and the nodes having it.
+type Role struct {
+	Name  string
+	Nodes []Host
+}
+
+// Roles returns the roles of the nodes of d, sorted by name, each with its
+// nodes in the order of d.Nodes. Nodes without a role are skipped.
+func (d Data) Roles() []Role {
+	index := make(map[string]int)
+	var roles []Role
+	for _, n := range d.Nodes {
+		if n.Role == "" {
+			continue
+		}
+		i, ok := index[n.Role]
+		if !ok {
+			i = len(roles)
+			index[n.Role] = i
+			roles = append(roles, Role{Name: n.Role})
+		}
+		roles[i].Nodes = append(roles[i].Nodes, n)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+
+	return roles
+}
+
+// NewData returns the data to execute templates with from a snapshot of the
+// components, redfish endpoints, and ethernet interfaces in SMD.
+func NewData(comps []smd.Component, rfes []csm.RedfishEndpoint, eis []smd.EthernetInterface) Data {
+	bmcs := BMCs(rfes, comps)
+
+	return Data{BMCs: bmcs, Nodes: Hosts(comps, eis, bmcs)}
+}
+
+// Hosts returns the nodes in comps, sorted by xname, each with the ethernet
+// interfaces in eis belonging to it and the host of its BMC in bmcs.
+func Hosts(comps []smd.Component, eis []smd.EthernetInterface, bmcs []BMC) []Host {
+	ifaces := make(map[string][]Interface)
+	for _, ei := range eis {
+		id := strings.ToLower(ei.ComponentID)
+		if len(ei.IPAddresses) == 0 {
+			ifaces[id] = append(ifaces[id], Interface{MAC: ei.MACAddress})
+			continue
+		}
+		for _, ip := range ei.IPAddresses {
+			ifaces[id] = append(ifaces[id], Interface{MAC: ei.MACAddress, IP: ip.IPAddress, Network: ip.Network})
+		}
+	}
+	bmcHosts := make(map[string]string)
+	for _, b := range bmcs {
+		for _, n := range b.Nodes {
+			bmcHosts[strings.ToLower(n.ID)] = b.Host
+		}
+	}
+
+	var hosts []Host
+	for _, c := range comps {
+		if c.Type != "Node" {
+			continue
+		}
+		id := strings.ToLower(c.ID)
+		is := ifaces[id]
+		sort.Slice(is, func(i, j int) bool {
+			if is[i].MAC != is[j].MAC {
+				return is[i].MAC < is[j].MAC
+			}
+			return is[i].IP < is[j].IP
+		})
+		h := Host{ID: c.ID, NID: c.NID, Role: c.Role, Arch: c.Arch, BMC: bmcHosts[id], Interfaces: is}
+		for _, i := range is {
+			if i.IP != "" {
+				h.IP = i.IP
+				break
+			}
+		}
+		hosts = append(hosts, h)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].ID < hosts[j].ID })
+
+	return hosts
+}
+
+// HostsTemplate is the default template generating an /etc/hosts file with an
+// entry for each node that has an IP address.
+const HostsTemplate = `# Hosts generated by ochami from SMD. Regenerate with
+# 'ochami export all' instead of editing by hand.
+{{- range .Nodes }}{{ if .IP }}
+{{ .IP }}	{{ .ID }}
+{{- end }}{{ end }}
+`
+
+// DnsmasqTemplate is the default template generating dnsmasq dhcp-host
+// entries, one per MAC and IP address of each node, so that nodes are given
+// the addresses recorded in SMD.
+const DnsmasqTemplate = `# DHCP host entries generated by ochami from SMD. Regenerate with
+# 'ochami export all' instead of editing by hand.
+{{- range .Nodes }}{{ $node := . }}{{ range .Interfaces }}{{ if and .MAC .IP }}
+dhcp-host={{ .MAC }},{{ .IP }},{{ $node.ID }}
+{{- end }}{{ end }}{{ end }}
+`
+
+// AnsibleTemplate is the default template generating an Ansible inventory in
+// INI format, with all nodes in the group "nodes" and each node also in a
+// group named after its role in lower case.
+const AnsibleTemplate = `# Ansible inventory generated by ochami from SMD. Regenerate with
+# 'ochami export all' instead of editing by hand.
+[nodes]
+{{- range .Nodes }}
+{{ .ID }}{{ if .IP }} ansible_host={{ .IP }}{{ end }}{{ if .NID }} nid={{ .NID }}{{ end }}
+{{- end }}
+{{- range .Roles }}
+
+[{{ lower .Name }}]
+{{- range .Nodes }}
+{{ .ID }}
+{{- end }}{{ end }}
+`
+
+// PrometheusTemplate is the default template generating a Prometheus file-based
+// service discovery file with a node_exporter target for each node, labeled
+// with its xname, NID, and role. Nodes are targeted by their IP address, or
+// their xname if they have none.
+const PrometheusTemplate = `[
+{{- range $i, $n := .Nodes }}{{ if $i }},{{ end }}
+  {"targets": [{{ json (print (or .IP .ID) ":9100") }}], "labels": {"xname": {{ json .ID }}, "nid": {{ json (print .NID) }}, "role": {{ json .Role }}}}
+{{- end }}
+]
+`
+
+// Artifact is a config file generated by 'ochami export all'. Name identifies
+// it and File is the name of the file it is written to.
+type Artifact struct {
+	Name     string
+	File     string
+	Template string
+}
+
+// Artifacts are the config files generated by 'ochami export all' by default.
+var Artifacts = []Artifact{
+	{Name: "hosts", File: "hosts", Template: HostsTemplate},
+	{Name: "dnsmasq", File: "dnsmasq-hosts.conf", Template: DnsmasqTemplate},
+	{Name: "ansible", File: "inventory.ini", Template: AnsibleTemplate},
+	{Name: "prometheus", File: "prometheus-targets.json", Template: PrometheusTemplate},
+	{Name: "conman", File: "conman.conf", Template: ConmanTemplate},
+	{Name: "powerman", File: "powerman.conf", Template: PowermanTemplate},
+}
+
+// Output is the content generated for an artifact.
+type Output struct {
+	Artifact Artifact
+	Content  []byte
+}
+
+// RenderAll renders each of arts with data, rendering up to concurrency of them
+// at once, and returns their outputs in the order of arts. If any of them fail
+// to render, the errors of all of those that failed are returned.
+func RenderAll(arts []Artifact, data Data, concurrency int) ([]Output, error) {
+	outs := make([]Output, len(arts))
+	errs := make([]error, len(arts))
+	pool.Run(context.Background(), len(arts), concurrency, 0, func(ctx context.Context, i int) error {
+		content, err := Render(arts[i].Template, data)
+		outs[i] = Output{Artifact: arts[i], Content: content}
+		return err
+	}, func(i int, err error) {
+		if err != nil {
+			errs[i] = fmt.Errorf("%s: %w", arts[i].Name, err)
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	return outs, nil
+}
+
+// Manifest describes the files written by WriteDir, along with where their
+// data came from.
+type Manifest struct {
+	Generated     time.Time      `json:"generated" yaml:"generated"`
+	OchamiVersion string         `json:"ochami_version" yaml:"ochami_version"`
+	Cluster       string         `json:"cluster,omitempty" yaml:"cluster,omitempty"`
+	Nodes         int            `json:"nodes" yaml:"nodes"`
+	BMCs          int            `json:"bmcs" yaml:"bmcs"`
+	Files         []ManifestFile `json:"files" yaml:"files"`
+}
+
+// ManifestFile describes a file written by WriteDir. Changed is false if the
+// file already had the same content, in which case it was left alone.
+type ManifestFile struct {
+	Artifact string `json:"artifact" yaml:"artifact"`
+	File     string `json:"file" yaml:"file"`
+	SHA256   string `json:"sha256" yaml:"sha256"`
+	Size     int    `json:"size" yaml:"size"`
+	Changed  bool   `json:"changed" yaml:"changed"`
+}
+
+// WriteDir writes outs to the directory dir, creating it if needed, followed by
+// m, with the files written added to it, as ManifestName. Each file replaces
+// the previous one atomically, and files whose content did not change are not
+// rewritten so that their modification times only change when the inventory
+// does. The manifest written is returned.
+func WriteDir(dir string, outs []Output, m Manifest) (Manifest, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return m, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	m.Files = make([]ManifestFile, 0, len(outs))
+	for _, out := range outs {
+		sum := sha256.Sum256(out.Content)
+		mf := ManifestFile{
+			Artifact: out.Artifact.Name,
+			File:     out.Artifact.File,
+			SHA256:   hex.EncodeToString(sum[:]),
+			Size:     len(out.Content),
+		}
+		path := filepath.Join(dir, out.Artifact.File)
+		if old, err := os.ReadFile(path); err != nil || !bytes.Equal(old, out.Content) {
+			if err := writeFileAtomic(path, out.Content); err != nil {
+				return m, fmt.Errorf("failed to write %s: %w", out.Artifact.Name, err)
+			}
+			mf.Changed = true
+		}
+		m.Files = append(m.Files, mf)
+	}
+
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return m, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := writeFileAtomic(filepath.Join(dir, ManifestName), append(b, '\n')); err != nil {
+		return m, fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+// writeFileAtomic writes content to a temporary file next to path that then
+// replaces path, so that readers never see a partially written file.
+func writeFileAtomic(path string, content []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(content); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+func testAllData() Data {
+	comps := []smd.Component{
+		{ID: "x1000c1s7b0n1", Type: "Node", NID: 2, Role: "Compute"},
+		{ID: "x1000c1s7b0n0", Type: "Node", NID: 1, Role: "Compute"},
+		{ID: "x1000c1s7b1n0", Type: "Node", NID: 3, Role: "Management"},
+		{ID: "x1000c1s8b0n0", Type: "Node", NID: 4},
+		{ID: "x1000c1s7b0", Type: "NodeBMC"},
+	}
+	eis := []smd.EthernetInterface{
+		{ComponentID: "x1000c1s7b0n0", MACAddress: "de:ca:fc:0f:fe:e1", IPAddresses: []smd.EthernetIP{{IPAddress: "172.16.0.2", Network: "hsn"}}},
+		{ComponentID: "X1000C1S7B0N0", MACAddress: "de:ca:fc:0f:fe:e0", IPAddresses: []smd.EthernetIP{{IPAddress: "172.16.0.1", Network: "mgmt"}}},
+		{ComponentID: "x1000c1s7b0n1", MACAddress: "de:ca:fc:0f:fe:e2"},
+		{ComponentID: "x1000c1s7b1n0", MACAddress: "de:ca:fc:0f:fe:e3", IPAddresses: []smd.EthernetIP{{IPAddress: "172.16.0.3"}}},
+	}
+
+	return NewData(comps, testRFEs, eis)
+}
+
+func TestHosts(t *testing.T) {
+	want := []Host{
+		{ID: "x1000c1s7b0n0", NID: 1, Role: "Compute", IP: "172.16.0.1", BMC: "x1000c1s7b0.bmc.cluster", Interfaces: []Interface{
+			{MAC: "de:ca:fc:0f:fe:e0", IP: "172.16.0.1", Network: "mgmt"},
+			{MAC: "de:ca:fc:0f:fe:e1", IP: "172.16.0.2", Network: "hsn"},
+		}},
+		{ID: "x1000c1s7b0n1", NID: 2, Role: "Compute", BMC: "x1000c1s7b0.bmc.cluster", Interfaces: []Interface{{MAC: "de:ca:fc:0f:fe:e2"}}},
+		{ID: "x1000c1s7b1n0", NID: 3, Role: "Management", IP: "172.16.0.3", BMC: "172.16.0.102", Interfaces: []Interface{{MAC: "de:ca:fc:0f:fe:e3", IP: "172.16.0.3"}}},
+		{ID: "x1000c1s8b0n0", NID: 4, BMC: "172.16.0.103"},
+	}
+	if got := testAllData().Nodes; !reflect.DeepEqual(got, want) {
+		t.Errorf("Hosts() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRender_All(t *testing.T) {
+	data := testAllData()
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{
+			name: "hosts",
+			tmpl: HostsTemplate,
+			want: `# Hosts generated by ochami from SMD. Regenerate with
+# 'ochami export all' instead of editing by hand.
+172.16.0.1	x1000c1s7b0n0
+172.16.0.3	x1000c1s7b1n0
+`,
+		},
+		{
+			name: "dnsmasq",
+			tmpl: DnsmasqTemplate,
+			want: `# DHCP host entries generated by ochami from SMD. Regenerate with
+# 'ochami export all' instead of editing by hand.
+dhcp-host=de:ca:fc:0f:fe:e0,172.16.0.1,x1000c1s7b0n0
+dhcp-host=de:ca:fc:0f:fe:e1,172.16.0.2,x1000c1s7b0n0
+dhcp-host=de:ca:fc:0f:fe:e3,172.16.0.3,x1000c1s7b1n0
+`,
+		},
+		{
+			name: "ansible",
+			tmpl: AnsibleTemplate,
+			want: `# Ansible inventory generated by ochami from SMD. Regenerate with
+# 'ochami export all' instead of editing by hand.
+[nodes]
+x1000c1s7b0n0 ansible_host=172.16.0.1 nid=1
+x1000c1s7b0n1 nid=2
+x1000c1s7b1n0 ansible_host=172.16.0.3 nid=3
+x1000c1s8b0n0 nid=4
+
+[compute]
+x1000c1s7b0n0
+x1000c1s7b0n1
+
+[management]
+x1000c1s7b1n0
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.tmpl, data)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Render() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	// The Prometheus targets must be valid JSON
+	got, err := Render(PrometheusTemplate, data)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	var targets []struct {
+		Targets []string          `json:"targets"`
+		Labels  map[string]string `json:"labels"`
+	}
+	if err := json.Unmarshal(got, &targets); err != nil {
+		t.Fatalf("Render() of Prometheus targets is not valid JSON: %v\n%s", err, got)
+	}
+	if len(targets) != 4 || targets[0].Targets[0] != "172.16.0.1:9100" || targets[3].Targets[0] != "x1000c1s8b0n0:9100" || targets[0].Labels["nid"] != "1" {
+		t.Errorf("Render() of Prometheus targets = %+v", targets)
+	}
+}
+
+func TestRenderAll(t *testing.T) {
+	outs, err := RenderAll(Artifacts, testAllData(), 4)
+	if err != nil {
+		t.Fatalf("RenderAll() error = %v", err)
+	}
+	if len(outs) != len(Artifacts) {
+		t.Fatalf("RenderAll() returned %d outputs, want %d", len(outs), len(Artifacts))
+	}
+	for i, out := range outs {
+		if out.Artifact.Name != Artifacts[i].Name || len(out.Content) == 0 {
+			t.Errorf("RenderAll() output %d = %s (%d bytes), want %s", i, out.Artifact.Name, len(out.Content), Artifacts[i].Name)
+		}
+	}
+
+	bad := []Artifact{Artifacts[0], {Name: "bad", File: "bad", Template: "{{ .Missing }}"}}
+	if _, err := RenderAll(bad, testAllData(), 2); err == nil {
+		t.Error("RenderAll() succeeded with bad template, want error")
+	}
+}
+
+func TestWriteDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "out")
+	outs := []Output{
+		{Artifact: Artifact{Name: "a", File: "a.conf"}, Content: []byte("a\n")},
+		{Artifact: Artifact{Name: "b", File: "b.conf"}, Content: []byte("b\n")},
+	}
+	m, err := WriteDir(dir, outs, Manifest{Generated: time.Unix(0, 0).UTC(), Nodes: 2})
+	if err != nil {
+		t.Fatalf("WriteDir() error = %v", err)
+	}
+	if len(m.Files) != 2 || !m.Files[0].Changed || !m.Files[1].Changed || m.Files[0].Size != 2 {
+		t.Errorf("first WriteDir() manifest files = %+v", m.Files)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "b.conf")); err != nil || string(b) != "b\n" {
+		t.Errorf("b.conf = %q, %v", b, err)
+	}
+
+	// Only changed files are rewritten
+	outs[1].Content = []byte("B\n")
+	m, err = WriteDir(dir, outs, Manifest{})
+	if err != nil {
+		t.Fatalf("WriteDir() error = %v", err)
+	}
+	if m.Files[0].Changed || !m.Files[1].Changed {
+		t.Errorf("second WriteDir() manifest files = %+v", m.Files)
+	}
+
+	var written Manifest
+	b, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		t.Fatalf("failed to read manifest: %v", err)
+	}
+	if err := json.Unmarshal(b, &written); err != nil {
+		t.Fatalf("failed to unmarshal manifest: %v", err)
+	}
+	if !reflect.DeepEqual(written.Files, m.Files) {
+		t.Errorf("written manifest files = %+v, want %+v", written.Files, m.Files)
+	}
+}
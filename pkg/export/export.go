@@ -0,0 +1,127 @@
+// Package export generates configuration files for infrastructure services
+// (e.g. conman, powerman, dnsmasq, and Prometheus) from SMD data, so that they
+// stay in sync with the inventory.
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/xname"
+)
+
+// Node is a node managed by a BMC.
+type Node struct {
+	ID  string
+	NID int64
+}
+
+// BMC is a node BMC and the nodes it manages. Host is what the BMC is reached
+// by: its FQDN if SMD has one, otherwise its IP address, otherwise its xname.
+type BMC struct {
+	ID    string
+	Host  string
+	Nodes []Node
+}
+
+// Data is what templates are executed with. Nodes is only set by NewData.
+type Data struct {
+	BMCs  []BMC
+	Nodes []Host
+}
+
+// ConmanTemplate is the default template generating conman.conf console
+// entries, one per node, connecting to its BMC with IPMI Serial-Over-LAN.
+const ConmanTemplate = `# Console entries generated by ochami from SMD. Regenerate with
+# 'ochami smd export conman' instead of editing by hand. Set IPMI
+# credentials with e.g.: GLOBAL ipmiopts="U:<user>,P:<password>"
+{{- range .BMCs }}{{ $bmc := . }}{{ range .Nodes }}
+CONSOLE name="{{ .ID }}" dev="ipmi:{{ $bmc.Host }}"
+{{- end }}{{ end }}
+`
+
+// PowermanTemplate is the default template generating powerman.conf device
+// and node entries, one redfishpower device per BMC with its nodes behind it.
+// BMCs managing more than one node need a custom template whose plugs match
+// the Redfish systems of the BMC.
+const PowermanTemplate = `# Devices and nodes generated by ochami from SMD. Regenerate with
+# 'ochami smd export powerman' instead of editing by hand.
+include "/etc/powerman/redfishpower.dev"
+{{- range .BMCs }}{{ $bmc := . }}
+device "{{ .ID }}" "redfishpower" "/usr/sbin/redfishpower -h {{ .Host }} |&"
+{{- range .Nodes }}
+node "{{ .ID }}" "{{ $bmc.ID }}" "{{ $bmc.Host }}"
+{{- end }}{{ end }}
+`
+
+// BMCs returns the node BMCs in rfes, sorted by xname, each with the nodes in
+// comps that it manages (those whose BMC xname is its xname), sorted by xname.
+// BMCs that manage no nodes in comps are skipped.
+func BMCs(rfes []csm.RedfishEndpoint, comps []smd.Component) []BMC {
+	nodes := make(map[string][]Node)
+	for _, c := range comps {
+		if c.Type != "Node" {
+			continue
+		}
+		bmcXname, err := xname.NodeXnameToBMCXname(c.ID)
+		if err != nil {
+			continue
+		}
+		nodes[bmcXname] = append(nodes[bmcXname], Node{ID: c.ID, NID: c.NID})
+	}
+
+	var bmcs []BMC
+	for _, rfe := range rfes {
+		if rfe.Type != "" && rfe.Type != "NodeBMC" {
+			continue
+		}
+		ns := nodes[rfe.ID]
+		if len(ns) == 0 {
+			continue
+		}
+		sort.Slice(ns, func(i, j int) bool { return ns[i].ID < ns[j].ID })
+		host := rfe.FQDN
+		if host == "" {
+			host = rfe.IPAddress
+		}
+		if host == "" {
+			host = rfe.ID
+		}
+		bmcs = append(bmcs, BMC{ID: rfe.ID, Host: host, Nodes: ns})
+	}
+	sort.Slice(bmcs, func(i, j int) bool { return bmcs[i].ID < bmcs[j].ID })
+
+	return bmcs
+}
+
+// templateFuncs are the functions available to templates in addition to the
+// built-in ones: json, which returns its argument encoded as JSON, and lower.
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"lower": strings.ToLower,
+}
+
+// Render executes the text/template tmpl with data, returning the result with
+// exactly one trailing newline.
+func Render(tmpl string, data Data) ([]byte, error) {
+	t, err := template.New("export").Option("missingkey=error").Funcs(templateFuncs).Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+
+	return []byte(strings.TrimRight(buf.String(), "\n") + "\n"), nil
+}
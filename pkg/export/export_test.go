@@ -0,0 +1,89 @@
+package export
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+var (
+	testRFEs = []csm.RedfishEndpoint{
+		{ID: "x1000c1s7b1", Type: "NodeBMC", IPAddress: "172.16.0.102"},
+		{ID: "x1000c1s7b0", Type: "NodeBMC", FQDN: "x1000c1s7b0.bmc.cluster", IPAddress: "172.16.0.101"},
+		{ID: "x1000c1r3b0", Type: "RouterBMC", IPAddress: "172.16.0.200"},
+		{ID: "x1000c1s8b0", Type: "NodeBMC", IPAddress: "172.16.0.103"},
+	}
+	testComps = []smd.Component{
+		{ID: "x1000c1s7b0n1", Type: "Node", NID: 2},
+		{ID: "x1000c1s7b0n0", Type: "Node", NID: 1},
+		{ID: "x1000c1s7b1n0", Type: "Node", NID: 3},
+		{ID: "x1000c1s7b0", Type: "NodeBMC"},
+	}
+)
+
+func TestBMCs(t *testing.T) {
+	want := []BMC{
+		{ID: "x1000c1s7b0", Host: "x1000c1s7b0.bmc.cluster", Nodes: []Node{{ID: "x1000c1s7b0n0", NID: 1}, {ID: "x1000c1s7b0n1", NID: 2}}},
+		{ID: "x1000c1s7b1", Host: "172.16.0.102", Nodes: []Node{{ID: "x1000c1s7b1n0", NID: 3}}},
+	}
+	if got := BMCs(testRFEs, testComps); !reflect.DeepEqual(got, want) {
+		t.Errorf("BMCs() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := Data{BMCs: BMCs(testRFEs, testComps)}
+	tests := []struct {
+		name string
+		tmpl string
+		want string
+	}{
+		{
+			name: "conman",
+			tmpl: ConmanTemplate,
+			want: `# Console entries generated by ochami from SMD. Regenerate with
+# 'ochami smd export conman' instead of editing by hand. Set IPMI
+# credentials with e.g.: GLOBAL ipmiopts="U:<user>,P:<password>"
+CONSOLE name="x1000c1s7b0n0" dev="ipmi:x1000c1s7b0.bmc.cluster"
+CONSOLE name="x1000c1s7b0n1" dev="ipmi:x1000c1s7b0.bmc.cluster"
+CONSOLE name="x1000c1s7b1n0" dev="ipmi:172.16.0.102"
+`,
+		},
+		{
+			name: "powerman",
+			tmpl: PowermanTemplate,
+			want: `# Devices and nodes generated by ochami from SMD. Regenerate with
+# 'ochami smd export powerman' instead of editing by hand.
+include "/etc/powerman/redfishpower.dev"
+device "x1000c1s7b0" "redfishpower" "/usr/sbin/redfishpower -h x1000c1s7b0.bmc.cluster |&"
+node "x1000c1s7b0n0" "x1000c1s7b0" "x1000c1s7b0.bmc.cluster"
+node "x1000c1s7b0n1" "x1000c1s7b0" "x1000c1s7b0.bmc.cluster"
+device "x1000c1s7b1" "redfishpower" "/usr/sbin/redfishpower -h 172.16.0.102 |&"
+node "x1000c1s7b1n0" "x1000c1s7b1" "172.16.0.102"
+`,
+		},
+		{
+			name: "custom",
+			tmpl: "{{ range .BMCs }}{{ range .Nodes }}nid{{ .NID }}\n{{ end }}{{ end }}\n\n",
+			want: "nid1\nnid2\nnid3\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.tmpl, data)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Render() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Render("{{ .Missing }}", data); err == nil {
+		t.Error("Render() succeeded with unknown field, want error")
+	}
+}
@@ -0,0 +1,317 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Convert returns data, formatted as inFormat, formatted as outFormat. Unlike
+// unmarshalling it with UnmarshalData and marshalling it with MarshalData,
+// which sorts the keys of objects, the keys keep the order they have in data.
+// If schema, a JSON Schema, is not nil, the keys of the objects it describes
+// are instead put in the order of its properties, followed by the keys it does
+// not describe in their order in data. Numbers are kept as written, so large
+// integers do not lose precision.
+func Convert(data []byte, inFormat, outFormat DataFormat, schema []byte) ([]byte, error) {
+	in, ok := inFormat.codec()
+	if !ok {
+		return nil, fmt.Errorf("unknown data format: %s", inFormat)
+	}
+	out, ok := outFormat.codec()
+	if !ok {
+		return nil, fmt.Errorf("unknown data format: %s", outFormat)
+	}
+
+	doc, err := in.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal data from %s: %w", in.name, err)
+	}
+	if schema != nil {
+		s, err := decodeJSONNode(schema)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal schema: %w", err)
+		}
+		orderBySchema(doc, s, s, 0)
+	}
+	b, err := out.encode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data into %s: %w", out.name, err)
+	}
+
+	return b, nil
+}
+
+// schemaMaxDepth bounds how deep orderBySchema follows references, in case a
+// schema refers to itself.
+const schemaMaxDepth = 64
+
+// orderBySchema orders the keys of the objects in n as in the properties of
+// schema, in place. root is the whole schema, which references in it
+// (e.g. #/$defs/Node) are resolved against.
+func orderBySchema(n, schema, root *yaml.Node, depth int) {
+	if n == nil || schema == nil || depth > schemaMaxDepth {
+		return
+	}
+	if ref := nodeKey(schema, "$ref"); ref != nil {
+		schema = resolveRef(root, ref.Value)
+		depth++
+	}
+	switch n.Kind {
+	case yaml.DocumentNode:
+		for _, c := range n.Content {
+			orderBySchema(c, schema, root, depth)
+		}
+	case yaml.SequenceNode:
+		items := nodeKey(schema, "items")
+		for _, c := range n.Content {
+			orderBySchema(c, items, root, depth)
+		}
+	case yaml.MappingNode:
+		props := nodeKey(schema, "properties")
+		if props == nil || props.Kind != yaml.MappingNode {
+			return
+		}
+		ordered := make([]*yaml.Node, 0, len(n.Content))
+		used := make([]bool, len(n.Content)/2)
+		for i := 0; i+1 < len(props.Content); i += 2 {
+			for j := 0; j+1 < len(n.Content); j += 2 {
+				if !used[j/2] && n.Content[j].Value == props.Content[i].Value {
+					used[j/2] = true
+					orderBySchema(n.Content[j+1], props.Content[i+1], root, depth)
+					ordered = append(ordered, n.Content[j], n.Content[j+1])
+				}
+			}
+		}
+		for j := 0; j+1 < len(n.Content); j += 2 {
+			if !used[j/2] {
+				ordered = append(ordered, n.Content[j], n.Content[j+1])
+			}
+		}
+		n.Content = ordered
+	}
+}
+
+// resolveRef returns the schema in root that ref, a JSON pointer within the
+// document (e.g. #/$defs/Node), points to, or nil if there is none.
+func resolveRef(root *yaml.Node, ref string) *yaml.Node {
+	path, ok := strings.CutPrefix(ref, "#")
+	if !ok {
+		return nil
+	}
+	n := root
+	for _, seg := range strings.Split(strings.TrimPrefix(path, "/"), "/") {
+		if seg == "" {
+			continue
+		}
+		seg = strings.ReplaceAll(strings.ReplaceAll(seg, "~1", "/"), "~0", "~")
+		if n = nodeKey(n, seg); n == nil {
+			return nil
+		}
+	}
+
+	return n
+}
+
+// nodeKey returns the value of key in the mapping n, or nil if n is not a
+// mapping or does not have key.
+func nodeKey(n *yaml.Node, key string) *yaml.Node {
+	if n != nil && n.Kind == yaml.DocumentNode && len(n.Content) > 0 {
+		n = n.Content[0]
+	}
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// decodeYAMLNode decodes the first YAML document in data, keeping the order of
+// keys.
+func decodeYAMLNode(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	clearStyle(&doc)
+
+	return &doc, nil
+}
+
+// encodeYAMLNode encodes n as YAML.
+func encodeYAMLNode(n *yaml.Node) ([]byte, error) {
+	return yaml.Marshal(n)
+}
+
+// clearStyle clears the styles of n and its children, so that they are
+// encoded in the default block style whatever format they were decoded from.
+func clearStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		clearStyle(c)
+	}
+}
+
+// decodeJSONNode decodes the JSON value in data into a YAML node, keeping the
+// order of keys.
+func decodeJSONNode(data []byte) (*yaml.Node, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	n, err := decodeJSONValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid data after top-level value")
+	}
+
+	return &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{n}}, nil
+}
+
+// decodeJSONValue decodes the next JSON value of dec into a YAML node.
+func decodeJSONValue(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	switch v := tok.(type) {
+	case json.Delim:
+		if v == '[' {
+			n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			for dec.More() {
+				c, err := decodeJSONValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				n.Content = append(n.Content, c)
+			}
+			_, err := dec.Token()
+			return n, err
+		}
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for dec.More() {
+			k, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			c, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k.(string)}, c)
+		}
+		_, err := dec.Token()
+		return n, err
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}, nil
+	case json.Number:
+		tag := "!!int"
+		if strings.ContainsAny(v.String(), ".eE") {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: v.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: fmt.Sprint(v)}, nil
+	default:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	}
+}
+
+// encodeJSONNode encodes n as one-line JSON, keeping the order of keys.
+func encodeJSONNode(n *yaml.Node) ([]byte, error) {
+	var b bytes.Buffer
+	if err := writeJSONNode(&b, n); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// encodeJSONNodePretty encodes n as JSON indented with two spaces, keeping the
+// order of keys.
+func encodeJSONNodePretty(n *yaml.Node) ([]byte, error) {
+	b, err := encodeJSONNode(n)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, b, "", "  "); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+// writeJSONNode writes n to b as JSON.
+func writeJSONNode(b *bytes.Buffer, n *yaml.Node) error {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			b.WriteString("null")
+			return nil
+		}
+		return writeJSONNode(b, n.Content[0])
+	case yaml.AliasNode:
+		return writeJSONNode(b, n.Alias)
+	case yaml.SequenceNode:
+		b.WriteByte('[')
+		for i, c := range n.Content {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeJSONNode(b, c); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case yaml.MappingNode:
+		b.WriteByte('{')
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			k, err := json.Marshal(n.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			b.Write(k)
+			b.WriteByte(':')
+			if err := writeJSONNode(b, n.Content[i+1]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	default:
+		// Numbers are written as is so that they keep their precision
+		if n.ShortTag() == "!!int" || n.ShortTag() == "!!float" {
+			if json.Valid([]byte(n.Value)) {
+				b.WriteString(n.Value)
+				return nil
+			}
+		}
+		var v any
+		if err := n.Decode(&v); err != nil {
+			return err
+		}
+		s, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", n.Line, err)
+		}
+		b.Write(s)
+	}
+
+	return nil
+}
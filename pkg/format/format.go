@@ -3,6 +3,8 @@ package format
 import (
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -16,32 +18,83 @@ const (
 	DataFormatYaml       DataFormat = "yaml"
 )
 
-var (
-	DataFormatHelp = map[string]string{
-		string(DataFormatJson):       "One-line JSON format",
-		string(DataFormatJsonPretty): "Unindented JSON format",
-		string(DataFormatYaml):       "YAML format",
+// dataFormatCodec is how data is marshalled into and unmarshalled from a
+// DataFormat. encode and decode do so for documents whose keys keep their
+// order (see Convert).
+type dataFormatCodec struct {
+	format    DataFormat
+	name      string // For error messages, e.g. "JSON"
+	help      string
+	marshal   func(any) ([]byte, error)
+	unmarshal func([]byte, any) error
+	encode    func(*yaml.Node) ([]byte, error)
+	decode    func([]byte) (*yaml.Node, error)
+}
+
+// dataFormats are the supported DataFormats, in the order they are listed in.
+// DataFormats, DataFormatHelp, and the functions of this package are derived
+// from it, and so are the formats that build on DataFormat (e.g. the payload
+// formats of the discover package), so adding a format here makes it
+// available everywhere.
+var dataFormats = []dataFormatCodec{
+	{DataFormatJson, "JSON", "One-line JSON format", json.Marshal, json.Unmarshal, encodeJSONNode, decodeJSONNode},
+	{DataFormatJsonPretty, "pretty JSON", "Indented JSON format", marshalJSONPretty, json.Unmarshal, encodeJSONNodePretty, decodeJSONNode},
+	{DataFormatYaml, "YAML", "YAML format", yaml.Marshal, yaml.Unmarshal, encodeYAMLNode, decodeYAMLNode},
+}
+
+// codec returns the dataFormatCodec of df, if it is supported.
+func (df DataFormat) codec() (dataFormatCodec, bool) {
+	for _, f := range dataFormats {
+		if f.format == df {
+			return f, true
+		}
 	}
+
+	return dataFormatCodec{}, false
+}
+
+var (
+	// DataFormats are the supported data formats.
+	DataFormats = func() []DataFormat {
+		var dfs []DataFormat
+		for _, f := range dataFormats {
+			dfs = append(dfs, f.format)
+		}
+		return dfs
+	}()
+
+	// DataFormatHelp describes each supported data format.
+	DataFormatHelp = func() map[string]string {
+		help := make(map[string]string, len(dataFormats))
+		for _, f := range dataFormats {
+			help[string(f.format)] = f.help
+		}
+		return help
+	}()
 )
 
+// DataFormatList returns the supported data formats separated by commas, as
+// listed in the usage of flags taking one.
+func DataFormatList() string {
+	var names []string
+	for _, df := range DataFormats {
+		names = append(names, string(df))
+	}
+
+	return strings.Join(names, ",")
+}
+
 func (df DataFormat) String() string {
 	return string(df)
 }
 
 func (df *DataFormat) Set(v string) error {
-	switch DataFormat(v) {
-	case DataFormatJson,
-		DataFormatJsonPretty,
-		DataFormatYaml:
-		*df = DataFormat(v)
-		return nil
-	default:
-		return fmt.Errorf("must be one of %v", []DataFormat{
-			DataFormatJson,
-			DataFormatJsonPretty,
-			DataFormatYaml,
-		})
+	if !slices.Contains(DataFormats, DataFormat(v)) {
+		return fmt.Errorf("must be one of %v", DataFormats)
 	}
+	*df = DataFormat(v)
+
+	return nil
 }
 
 func (df DataFormat) Type() string {
@@ -51,50 +104,38 @@ func (df DataFormat) Type() string {
 // MarshalData marshals arbitrary data into a byte slice formatted as outFormat.
 // If a marshalling error occurs or outFormat is unknown, an error is returned.
 //
-// Supported values are: json, json-pretty, yaml
+// Supported values are those of DataFormats.
 func MarshalData(data interface{}, outFormat DataFormat) ([]byte, error) {
-	switch outFormat {
-	case DataFormatJson:
-		if bytes, err := json.Marshal(data); err != nil {
-			return nil, fmt.Errorf("failed to marshal data into JSON: %w", err)
-		} else {
-			return bytes, nil
-		}
-	case DataFormatJsonPretty:
-		if bytes, err := json.MarshalIndent(data, "", "  "); err != nil {
-			return nil, fmt.Errorf("failed to marshal data into pretty JSON: %w", err)
-		} else {
-			return bytes, nil
-		}
-	case DataFormatYaml:
-		if bytes, err := yaml.Marshal(data); err != nil {
-			return nil, fmt.Errorf("failed to marshal data into YAML: %w", err)
-		} else {
-			return bytes, nil
-		}
-	default:
+	f, ok := outFormat.codec()
+	if !ok {
 		return nil, fmt.Errorf("unknown data format: %s", outFormat)
 	}
+	bytes, err := f.marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data into %s: %w", f.name, err)
+	}
+
+	return bytes, nil
 }
 
 // UnmarshalData unmarshals a byte slice formatted as inFormat into an interface
 // v. If an unmarshalling error occurs or inFormat is unknown, an error is
 // returned.
 //
-// Supported values are: json, json-pretty, yaml
+// Supported values are those of DataFormats.
 func UnmarshalData(data []byte, v interface{}, inFormat DataFormat) error {
-	switch inFormat {
-	case DataFormatJson, DataFormatJsonPretty:
-		if err := json.Unmarshal(data, v); err != nil {
-			return fmt.Errorf("failed to unmarshal data into JSON: %w", err)
-		}
-	case DataFormatYaml:
-		if err := yaml.Unmarshal(data, v); err != nil {
-			return fmt.Errorf("failed to unmarshal data into YAML: %w", err)
-		}
-	default:
+	f, ok := inFormat.codec()
+	if !ok {
 		return fmt.Errorf("unknown data format: %s", inFormat)
 	}
+	if err := f.unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to unmarshal data into %s: %w", f.name, err)
+	}
 
 	return nil
 }
+
+// marshalJSONPretty marshals data into JSON indented with two spaces.
+func marshalJSONPretty(data any) ([]byte, error) {
+	return json.MarshalIndent(data, "", "  ")
+}
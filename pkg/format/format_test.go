@@ -220,3 +220,72 @@ arr:
 		})
 	}
 }
+
+func TestDataFormatList(t *testing.T) {
+	if got, want := DataFormatList(), "json,json-pretty,yaml"; got != want {
+		t.Errorf("DataFormatList() = %q, want %q", got, want)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	schema := []byte(`{
+		"$defs": {"Node": {"properties": {"name": {}, "xname": {}, "nid": {}}}},
+		"properties": {"version": {}, "nodes": {"items": {"$ref": "#/$defs/Node"}}}
+	}`)
+	tests := []struct {
+		name      string
+		data      string
+		inFormat  DataFormat
+		outFormat DataFormat
+		schema    []byte
+		want      string
+	}{
+		{
+			name:      "yaml to json keeps order and precision",
+			data:      "zeta: 1\nalpha: [true, null, \"007\"]\nbig: 12345678901234567890\n",
+			inFormat:  DataFormatYaml,
+			outFormat: DataFormatJson,
+			want:      `{"zeta":1,"alpha":[true,null,"007"],"big":12345678901234567890}`,
+		},
+		{
+			name:      "json to yaml",
+			data:      `{"zeta":"1","alpha":{"b":2.5,"a":"x"}}`,
+			inFormat:  DataFormatJson,
+			outFormat: DataFormatYaml,
+			want:      "zeta: \"1\"\nalpha:\n    b: 2.5\n    a: x\n",
+		},
+		{
+			name:      "json to pretty json",
+			data:      `{"b":1,"a":[]}`,
+			inFormat:  DataFormatJson,
+			outFormat: DataFormatJsonPretty,
+			want:      "{\n  \"b\": 1,\n  \"a\": []\n}",
+		},
+		{
+			name:      "ordered by schema",
+			data:      "nodes:\n- nid: 1\n  extra: e\n  xname: x1000c1s7b0n0\n  name: node01\nversion: 1\n",
+			inFormat:  DataFormatYaml,
+			outFormat: DataFormatJson,
+			schema:    schema,
+			want:      `{"version":1,"nodes":[{"name":"node01","xname":"x1000c1s7b0n0","nid":1,"extra":"e"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Convert([]byte(tt.data), tt.inFormat, tt.outFormat, tt.schema)
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Convert() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Convert([]byte(`{"a":`), DataFormatJson, DataFormatYaml, nil); err == nil {
+		t.Errorf("Convert() of invalid JSON error = nil")
+	}
+	if _, err := Convert([]byte(`a: 1`), DataFormatYaml, "toml", nil); err == nil {
+		t.Errorf("Convert() to unknown format error = nil")
+	}
+}
@@ -0,0 +1,267 @@
+// Package fsck checks the data held by the OpenCHAMI services for references
+// that do not resolve across services, e.g. ethernet interfaces of components
+// that no longer exist or boot parameters of unknown nodes.
+package fsck
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/report"
+	"github.com/OpenCHAMI/ochami/pkg/xname"
+)
+
+// Rules of the problems found by Check.
+const (
+	RuleComponentNoInterfaces      = "component-no-interfaces"
+	RuleInterfaceUnknownComponent  = "interface-unknown-component"
+	RuleRedfishEndpointNoComponent = "redfish-endpoint-no-component"
+	RuleBootParamsUnknownXname     = "boot-params-unknown-xname"
+	RuleBootParamsUnknownMAC       = "boot-params-unknown-mac"
+	RuleBootParamsUnknownNID       = "boot-params-unknown-nid"
+	RuleCloudInitGroupOrphaned     = "cloud-init-group-orphaned"
+	RuleGroupMemberUnknown         = "group-member-unknown"
+)
+
+// Rules lists all rules, in the order Check reports them.
+var Rules = []string{
+	RuleComponentNoInterfaces,
+	RuleInterfaceUnknownComponent,
+	RuleRedfishEndpointNoComponent,
+	RuleBootParamsUnknownXname,
+	RuleBootParamsUnknownMAC,
+	RuleBootParamsUnknownNID,
+	RuleCloudInitGroupOrphaned,
+	RuleGroupMemberUnknown,
+}
+
+// Data is what Check checks: a snapshot of SMD, BSS, and cloud-init. Any of it
+// may be empty, e.g. if a service was skipped, in which case the checks that
+// need it are not run (see Sources).
+type Data struct {
+	Components         []smd.Component
+	EthernetInterfaces []smd.EthernetInterface
+	RedfishEndpoints   []csm.RedfishEndpoint
+	Groups             []smd.Group
+	BootParams         []bssTypes.BootParams
+	CloudInitGroups    []cistore.GroupData
+
+	// Sources are the services whose data is in Data ("smd", "bss",
+	// "cloud-init"). Checks referencing a service not in Sources are
+	// skipped.
+	Sources []string
+}
+
+// Services whose data Check can check.
+const (
+	SourceSMD       = "smd"
+	SourceBSS       = "bss"
+	SourceCloudInit = "cloud-init"
+)
+
+// Fix is a safe repair of a problem. The only repair Check proposes is removing
+// a member that does not exist from an SMD group, which loses no data.
+type Fix struct {
+	Group  string `json:"group" yaml:"group"`
+	Member string `json:"member" yaml:"member"`
+}
+
+func (f Fix) String() string {
+	return fmt.Sprintf("remove %s from SMD group %s", f.Member, f.Group)
+}
+
+// Problem is a reference that does not resolve. Target is the record with the
+// reference (e.g. an ethernet interface ID) and Level is one of the
+// report.Level constants. Fix, if set, repairs the problem.
+type Problem struct {
+	Rule    string `json:"rule" yaml:"rule"`
+	Level   string `json:"level" yaml:"level"`
+	Target  string `json:"target" yaml:"target"`
+	Message string `json:"message" yaml:"message"`
+	Fix     *Fix   `json:"fix,omitempty" yaml:"fix,omitempty"`
+	Fixed   bool   `json:"fixed,omitempty" yaml:"fixed,omitempty"`
+}
+
+// Check returns the problems in d, ordered by rule (in the order of Rules) and
+// then by target. Only Node components are expected to have ethernet
+// interfaces, BSS hosts that are not xnames (e.g. "Default") are not checked,
+// and unassigned ethernet interfaces (without a component ID) are not
+// problems. A cloud-init group is orphaned if there is no SMD group with its
+// name, since no node can then receive its data.
+func Check(d Data) []Problem {
+	var (
+		hasSMD       = hasSource(d, SourceSMD)
+		hasBSS       = hasSource(d, SourceBSS)
+		hasCloudInit = hasSource(d, SourceCloudInit)
+		problems     []Problem
+		comps        = make(map[string]smd.Component, len(d.Components))
+		nids         = make(map[int64]bool)
+		macs         = make(map[string]bool)
+		compIfaces   = make(map[string]bool)
+		groups       = make(map[string]bool, len(d.Groups))
+	)
+	for _, c := range d.Components {
+		comps[strings.ToLower(c.ID)] = c
+		if c.NID != 0 {
+			nids[c.NID] = true
+		}
+	}
+	for _, ei := range d.EthernetInterfaces {
+		macs[normalizeMAC(ei.MACAddress)] = true
+		if ei.ComponentID != "" {
+			compIfaces[strings.ToLower(ei.ComponentID)] = true
+		}
+	}
+	for _, g := range d.Groups {
+		groups[g.Label] = true
+	}
+	add := func(rule, level, target, format string, a ...any) *Problem {
+		problems = append(problems, Problem{Rule: rule, Level: level, Target: target, Message: fmt.Sprintf(format, a...)})
+		return &problems[len(problems)-1]
+	}
+
+	if hasSMD {
+		for _, c := range d.Components {
+			if c.Type == "Node" && !compIfaces[strings.ToLower(c.ID)] {
+				add(RuleComponentNoInterfaces, report.LevelWarning, c.ID, "node has no ethernet interfaces")
+			}
+		}
+		for _, ei := range d.EthernetInterfaces {
+			if ei.ComponentID != "" {
+				if _, ok := comps[strings.ToLower(ei.ComponentID)]; !ok {
+					add(RuleInterfaceUnknownComponent, report.LevelError, ei.ID, "ethernet interface %s belongs to component %s, which does not exist", ei.MACAddress, ei.ComponentID)
+				}
+			}
+		}
+		ids := make([]string, 0, len(comps))
+		for id := range comps {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, rfe := range d.RedfishEndpoints {
+			if !hasComponentUnder(ids, rfe.ID) {
+				add(RuleRedfishEndpointNoComponent, report.LevelWarning, rfe.ID, "redfish endpoint has no components (neither itself nor any it manages)")
+			}
+		}
+	}
+
+	if hasSMD && hasBSS {
+		for _, bp := range d.BootParams {
+			target := bss.LintTarget(bp)
+			for _, h := range bp.Hosts {
+				if !isXname(h) {
+					continue
+				}
+				if _, ok := comps[strings.ToLower(h)]; !ok {
+					add(RuleBootParamsUnknownXname, report.LevelWarning, target, "boot parameters are for xname %s, which is not a component", h)
+				}
+			}
+			for _, m := range bp.Macs {
+				if !macs[normalizeMAC(m)] {
+					add(RuleBootParamsUnknownMAC, report.LevelWarning, target, "boot parameters are for MAC address %s, which is not an ethernet interface", m)
+				}
+			}
+			for _, n := range bp.Nids {
+				if !nids[int64(n)] {
+					add(RuleBootParamsUnknownNID, report.LevelWarning, target, "boot parameters are for NID %d, which is not a component", n)
+				}
+			}
+		}
+	}
+
+	if hasSMD && hasCloudInit {
+		for _, g := range d.CloudInitGroups {
+			if !groups[g.Name] {
+				add(RuleCloudInitGroupOrphaned, report.LevelWarning, g.Name, "cloud-init group has no SMD group, so no node receives its data")
+			}
+		}
+	}
+
+	if hasSMD {
+		for _, g := range d.Groups {
+			for _, m := range g.Members.IDs {
+				if _, ok := comps[strings.ToLower(m)]; !ok {
+					p := add(RuleGroupMemberUnknown, report.LevelError, g.Label, "SMD group member %s is not a component", m)
+					p.Fix = &Fix{Group: g.Label, Member: m}
+				}
+			}
+		}
+	}
+
+	rank := make(map[string]int, len(Rules))
+	for i, r := range Rules {
+		rank[r] = i
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Rule != problems[j].Rule {
+			return rank[problems[i].Rule] < rank[problems[j].Rule]
+		}
+		return problems[i].Target < problems[j].Target
+	})
+
+	return problems
+}
+
+// Report returns problems as a report named name for CI systems, with one
+// result per target. Fixed problems are left out.
+func Report(name string, problems []Problem) report.Report {
+	rep := report.Report{Name: name}
+	index := make(map[string]int)
+	for _, p := range problems {
+		if p.Fixed {
+			continue
+		}
+		i, ok := index[p.Target]
+		if !ok {
+			i = len(rep.Results)
+			index[p.Target] = i
+			rep.Results = append(rep.Results, report.Result{Target: p.Target})
+		}
+		rep.Results[i].Findings = append(rep.Results[i].Findings, report.Finding{Rule: p.Rule, Level: p.Level, Message: p.Message})
+	}
+
+	return rep
+}
+
+// hasSource returns whether the data of service src is in d.
+func hasSource(d Data, src string) bool {
+	for _, s := range d.Sources {
+		if s == src {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasComponentUnder returns whether any of the sorted lower case component IDs
+// ids is id or one of its descendants.
+func hasComponentUnder(ids []string, id string) bool {
+	id = strings.ToLower(id)
+	for i := sort.SearchStrings(ids, id); i < len(ids) && strings.HasPrefix(ids[i], id); i++ {
+		if xname.IsDescendant(ids[i], id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isXname returns whether s looks like an xname, i.e. starts with x followed
+// by a cabinet number, as opposed to e.g. the BSS host "Default".
+func isXname(s string) bool {
+	return len(s) > 1 && (s[0] == 'x' || s[0] == 'X') && s[1] >= '0' && s[1] <= '9'
+}
+
+// normalizeMAC returns mac in lower case without separators so that MAC
+// addresses written differently compare equal.
+func normalizeMAC(mac string) string {
+	return strings.NewReplacer(":", "", "-", "", ".", "").Replace(strings.ToLower(mac))
+}
@@ -0,0 +1,84 @@
+package fsck
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+	"github.com/OpenCHAMI/cloud-init/pkg/cistore"
+	"github.com/openchami/schemas/schemas/csm"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/report"
+)
+
+func testData() Data {
+	compute := smd.Group{Label: "compute"}
+	compute.Members.IDs = []string{"x1000c0s0b0n0", "x1000c0s9b0n0"}
+
+	return Data{
+		Components: []smd.Component{
+			{ID: "x1000c0s0b0n0", Type: "Node", NID: 1},
+			{ID: "x1000c0s1b0n0", Type: "Node", NID: 2},
+			{ID: "x1000c0s0b0", Type: "NodeBMC"},
+		},
+		EthernetInterfaces: []smd.EthernetInterface{
+			{ID: "decafc0ffee0", ComponentID: "X1000C0S0B0N0", MACAddress: "de:ca:fc:0f:fe:e0"},
+			{ID: "decafc0ffee1", ComponentID: "x1000c0s5b0n0", MACAddress: "de:ca:fc:0f:fe:e1"},
+			{ID: "decafc0ffee2", MACAddress: "de:ca:fc:0f:fe:e2"},
+		},
+		RedfishEndpoints: []csm.RedfishEndpoint{{ID: "x1000c0s0b0"}, {ID: "x1000c0s1b0"}, {ID: "x1000c0s7b0"}},
+		Groups:           []smd.Group{compute},
+		BootParams: []bssTypes.BootParams{
+			{Hosts: []string{"Default"}},
+			{Hosts: []string{"x1000c0s0b0n0", "x1000c0s8b0n0"}},
+			{Macs: []string{"DE-CA-FC-0F-FE-E2", "de:ca:fc:0f:fe:ff"}, Nids: []int32{2, 7}},
+		},
+		CloudInitGroups: []cistore.GroupData{{Name: "compute"}, {Name: "login"}},
+		Sources:         []string{SourceSMD, SourceBSS, SourceCloudInit},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	want := []Problem{
+		{Rule: RuleComponentNoInterfaces, Level: report.LevelWarning, Target: "x1000c0s1b0n0", Message: "node has no ethernet interfaces"},
+		{Rule: RuleInterfaceUnknownComponent, Level: report.LevelError, Target: "decafc0ffee1", Message: "ethernet interface de:ca:fc:0f:fe:e1 belongs to component x1000c0s5b0n0, which does not exist"},
+		{Rule: RuleRedfishEndpointNoComponent, Level: report.LevelWarning, Target: "x1000c0s7b0", Message: "redfish endpoint has no components (neither itself nor any it manages)"},
+		{Rule: RuleBootParamsUnknownXname, Level: report.LevelWarning, Target: "x1000c0s0b0n0,x1000c0s8b0n0", Message: "boot parameters are for xname x1000c0s8b0n0, which is not a component"},
+		{Rule: RuleBootParamsUnknownMAC, Level: report.LevelWarning, Target: "DE-CA-FC-0F-FE-E2,de:ca:fc:0f:fe:ff,nid:2,nid:7", Message: "boot parameters are for MAC address de:ca:fc:0f:fe:ff, which is not an ethernet interface"},
+		{Rule: RuleBootParamsUnknownNID, Level: report.LevelWarning, Target: "DE-CA-FC-0F-FE-E2,de:ca:fc:0f:fe:ff,nid:2,nid:7", Message: "boot parameters are for NID 7, which is not a component"},
+		{Rule: RuleCloudInitGroupOrphaned, Level: report.LevelWarning, Target: "login", Message: "cloud-init group has no SMD group, so no node receives its data"},
+		{Rule: RuleGroupMemberUnknown, Level: report.LevelError, Target: "compute", Message: "SMD group member x1000c0s9b0n0 is not a component", Fix: &Fix{Group: "compute", Member: "x1000c0s9b0n0"}},
+	}
+	if got := Check(testData()); !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestCheck_Sources(t *testing.T) {
+	d := testData()
+	d.Sources = []string{SourceSMD}
+	for _, p := range Check(d) {
+		switch p.Rule {
+		case RuleBootParamsUnknownXname, RuleBootParamsUnknownMAC, RuleBootParamsUnknownNID, RuleCloudInitGroupOrphaned:
+			t.Errorf("Check() without BSS and cloud-init found %s problem", p.Rule)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	problems := Check(testData())
+	problems[len(problems)-1].Fixed = true
+	rep := Report("fsck", problems)
+	if len(rep.Results) != 6 {
+		t.Fatalf("Report() has %d results, want 6", len(rep.Results))
+	}
+	for _, r := range rep.Results {
+		if r.Target == "compute" {
+			t.Errorf("Report() includes fixed problem of %s", r.Target)
+		}
+	}
+	if got := len(rep.Results[4].Findings); got != 2 {
+		t.Errorf("Report() has %d findings for %s, want 2", got, rep.Results[4].Target)
+	}
+}
@@ -0,0 +1,496 @@
+// Package ipam plans static IP addresses for the interfaces of nodes, so that
+// they can be allocated from the networks of a cluster without a spreadsheet.
+// A Plan lists the address of each interface on each network, avoiding those
+// already in use, and can be applied to SMD ethernet interfaces and to
+// discovery payloads.
+package ipam
+
+import (
+	"fmt"
+	"net/netip"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+	"github.com/OpenCHAMI/ochami/pkg/xname"
+)
+
+// PlanVersion is the version of the format of Plan, which is checked when a
+// plan is read back to be applied.
+const PlanVersion = 1
+
+// Network is a named network that addresses are allocated from, e.g. mgmt
+// with the CIDR 10.1.0.0/20.
+type Network struct {
+	Name string `json:"name" yaml:"name"`
+	CIDR string `json:"cidr" yaml:"cidr"`
+}
+
+// ParseNetwork parses a network in the form <name>=<cidr>.
+func ParseNetwork(s string) (Network, error) {
+	name, cidr, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return Network{}, fmt.Errorf("invalid network %q: expected <name>=<cidr>", s)
+	}
+	n := Network{Name: name, CIDR: cidr}
+	if _, err := n.Prefix(); err != nil {
+		return Network{}, err
+	}
+
+	return n, nil
+}
+
+// Prefix returns the parsed CIDR of the network.
+func (n Network) Prefix() (netip.Prefix, error) {
+	p, err := netip.ParsePrefix(n.CIDR)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid CIDR of network %s: %w", n.Name, err)
+	}
+
+	return p.Masked(), nil
+}
+
+func (n Network) String() string {
+	return n.Name + "=" + n.CIDR
+}
+
+// Assignment is the address of an interface of a node on a network. Existing
+// assignments are those the interface already had when the plan was made,
+// which applying the plan leaves as they are.
+type Assignment struct {
+	Xname    string `json:"xname" yaml:"xname"`
+	MACAddr  string `json:"mac_addr" yaml:"mac_addr"`
+	Network  string `json:"network" yaml:"network"`
+	IPAddr   string `json:"ip_addr" yaml:"ip_addr"`
+	Existing bool   `json:"existing,omitempty" yaml:"existing,omitempty"`
+}
+
+func (a Assignment) String() string {
+	return fmt.Sprintf("xname=%s mac_addr=%s network=%s ip_addr=%s", a.Xname, a.MACAddr, a.Network, a.IPAddr)
+}
+
+// Plan is the addresses of the interfaces of nodes on each of Networks, in the
+// order of the nodes and their interfaces. With PerRack, the addresses of the
+// nodes of each rack are allocated from a block of PerRack addresses of their
+// own. The first Reserve addresses of each network are never allocated.
+type Plan struct {
+	Version     int          `json:"version" yaml:"version"`
+	Networks    []Network    `json:"networks" yaml:"networks"`
+	PerRack     int          `json:"per_rack,omitempty" yaml:"per_rack,omitempty"`
+	Reserve     int          `json:"reserve" yaml:"reserve"`
+	Assignments []Assignment `json:"assignments" yaml:"assignments"`
+}
+
+// Options are the options of NewPlan. Used are addresses in use by interfaces
+// other than those being planned, e.g. those in SMD, which are not allocated.
+type Options struct {
+	PerRack int
+	Reserve int
+	Used    []string
+}
+
+// pool allocates consecutive addresses of a network, up to left of them if it
+// is not negative.
+type pool struct {
+	next netip.Addr
+	left int
+}
+
+// NewPlan allocates an address on each of networks for each interface of the
+// nodes that does not have one there yet: neither an address within the CIDR
+// of the network nor one tagged with its name. Addresses are allocated in
+// order, skipping those of other interfaces of the nodes, those in opts.Used,
+// the network and broadcast addresses, and the first opts.Reserve addresses
+// after the network address.
+//
+// If opts.PerRack is not zero, the nodes of the nth rack, in the order of the
+// cabinet numbers of their xnames, are allocated addresses from the nth block
+// of opts.PerRack addresses of each network, so that each rack has a range of
+// its own. An error is returned if a node has no cabinet in its xname, if a
+// network has too few addresses for all racks, or if a network or rack runs
+// out of addresses.
+func NewPlan(nodes []discover.Node, networks []Network, opts Options) (Plan, error) {
+	plan := Plan{
+		Version:     PlanVersion,
+		Networks:    networks,
+		PerRack:     opts.PerRack,
+		Reserve:     opts.Reserve,
+		Assignments: []Assignment{},
+	}
+	if opts.PerRack < 0 || opts.Reserve < 0 {
+		return plan, fmt.Errorf("addresses per rack and reserved addresses cannot be negative")
+	}
+
+	// Collect the addresses in use
+	used := make(map[netip.Addr]bool)
+	for _, s := range opts.Used {
+		if a, err := netip.ParseAddr(s); err == nil {
+			used[a.Unmap()] = true
+		}
+	}
+	for _, n := range nodes {
+		for _, iface := range n.Ifaces {
+			for _, ip := range iface.IPAddrs {
+				if a, err := netip.ParseAddr(ip.IPAddr); err == nil {
+					used[a.Unmap()] = true
+				}
+			}
+		}
+	}
+
+	// Number the racks
+	racks := make(map[string]int)
+	if opts.PerRack > 0 {
+		var nums []int
+		cabinets := make(map[int]string)
+		for i, n := range nodes {
+			num, cab, ok := xname.Cabinet(n.Xname)
+			if !ok {
+				return plan, fmt.Errorf("node %d (%s) has no cabinet in its xname to allocate addresses per rack", i, n.Xname)
+			}
+			if _, ok := cabinets[num]; !ok {
+				cabinets[num] = cab
+				nums = append(nums, num)
+			}
+		}
+		sort.Ints(nums)
+		for i, num := range nums {
+			racks[cabinets[num]] = i
+		}
+	}
+
+	// Allocate addresses from a pool for each network and (with PerRack)
+	// rack
+	prefixes := make([]netip.Prefix, len(networks))
+	pools := make([]map[string]*pool, len(networks))
+	for i, nw := range networks {
+		p, err := nw.Prefix()
+		if err != nil {
+			return plan, err
+		}
+		prefixes[i] = p
+		pools[i] = make(map[string]*pool)
+		for a, reserved := p.Addr(), 0; a.IsValid() && p.Contains(a) && reserved <= opts.Reserve; a, reserved = a.Next(), reserved+1 {
+			used[a] = true
+		}
+		if last := lastAddr(p); p.Addr().Is4() && p.Bits() < 31 {
+			used[last] = true
+		}
+		if opts.PerRack == 0 {
+			pools[i][""] = &pool{next: p.Addr(), left: -1}
+			continue
+		}
+		for cab, idx := range racks {
+			start, ok := advance(p, p.Addr(), idx*opts.PerRack)
+			if !ok {
+				return plan, fmt.Errorf("network %s has too few addresses for %d rack(s) of %d", nw, len(racks), opts.PerRack)
+			}
+			if _, ok := advance(p, start, opts.PerRack-1); !ok {
+				return plan, fmt.Errorf("network %s has too few addresses for %d rack(s) of %d", nw, len(racks), opts.PerRack)
+			}
+			pools[i][cab] = &pool{next: start, left: opts.PerRack}
+		}
+	}
+	for ni, n := range nodes {
+		var rack string
+		if opts.PerRack > 0 {
+			_, rack, _ = xname.Cabinet(n.Xname)
+		}
+		for ii, iface := range n.Ifaces {
+			if iface.MACAddr == "" {
+				return plan, fmt.Errorf("interface %d of node %d (%s) has no MAC address", ii, ni, n.Xname)
+			}
+			for i, nw := range networks {
+				a := Assignment{Xname: n.Xname, MACAddr: iface.MACAddr, Network: nw.Name}
+				if ip, ok := existingAddr(iface, nw.Name, prefixes[i]); ok {
+					a.IPAddr, a.Existing = ip, true
+					plan.Assignments = append(plan.Assignments, a)
+					continue
+				}
+				addr, ok := pools[i][rack].take(prefixes[i], used)
+				if !ok {
+					if rack != "" {
+						return plan, fmt.Errorf("network %s has no free addresses left for rack %s (of %d per rack) for node %d (%s)", nw, rack, opts.PerRack, ni, n.Xname)
+					}
+					return plan, fmt.Errorf("network %s has no free addresses left for node %d (%s)", nw, ni, n.Xname)
+				}
+				used[addr] = true
+				a.IPAddr = addr.String()
+				plan.Assignments = append(plan.Assignments, a)
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// existingAddr returns the address that iface already has on the network with
+// name and prefix, if any.
+func existingAddr(iface discover.Iface, name string, prefix netip.Prefix) (string, bool) {
+	for _, ip := range iface.IPAddrs {
+		if ip.IPAddr == "" {
+			continue
+		}
+		if ip.Network == name {
+			return ip.IPAddr, true
+		}
+		if a, err := netip.ParseAddr(ip.IPAddr); err == nil && prefix.Contains(a.Unmap()) {
+			return ip.IPAddr, true
+		}
+	}
+
+	return "", false
+}
+
+// take returns the next address of the pool within prefix that is not used,
+// or false if there is none left.
+func (p *pool) take(prefix netip.Prefix, used map[netip.Addr]bool) (netip.Addr, bool) {
+	for p.next.IsValid() && prefix.Contains(p.next) && p.left != 0 {
+		a := p.next
+		p.next = p.next.Next()
+		if p.left > 0 {
+			p.left--
+		}
+		if !used[a] {
+			return a, true
+		}
+	}
+
+	return netip.Addr{}, false
+}
+
+// advance returns the address n addresses after a, or false if it is not
+// within prefix.
+func advance(prefix netip.Prefix, a netip.Addr, n int) (netip.Addr, bool) {
+	for ; n > 0 && a.IsValid(); n-- {
+		a = a.Next()
+	}
+
+	return a, a.IsValid() && prefix.Contains(a)
+}
+
+// lastAddr returns the last address of prefix, which is the broadcast address
+// of IPv4 networks.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	a, _ := netip.AddrFromSlice(b)
+
+	return a
+}
+
+// Count returns the number of new (not existing) assignments of the plan, and
+// that of existing ones.
+func (p Plan) Count() (int, int) {
+	var n, existing int
+	for _, a := range p.Assignments {
+		if a.Existing {
+			existing++
+		} else {
+			n++
+		}
+	}
+
+	return n, existing
+}
+
+// ApplyEthernetInterfaces returns those of eis whose addresses the plan
+// changes, with the new addresses of the plan added to them, along with the
+// new assignments whose interfaces are not in eis. Addresses the interfaces
+// already have on the networks of the assignments are replaced. An error is
+// returned, and nothing is changed, if an address of the plan is now used by
+// another ethernet interface, e.g. because it was added since the plan was
+// made.
+func (p Plan) ApplyEthernetInterfaces(eis []smd.EthernetInterface) ([]smd.EthernetInterface, []Assignment, error) {
+	owners := make(map[string]string)
+	byMAC := make(map[string]int)
+	for i, ei := range eis {
+		byMAC[normalizeMAC(ei.MACAddress)] = i
+		for _, ip := range ei.IPAddresses {
+			owners[ip.IPAddress] = normalizeMAC(ei.MACAddress)
+		}
+	}
+	if err := p.conflicts(owners); err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		changed []int
+		missing []Assignment
+		updated = slices.Clone(eis)
+	)
+	for _, a := range p.Assignments {
+		if a.Existing {
+			continue
+		}
+		i, ok := byMAC[normalizeMAC(a.MACAddr)]
+		if !ok {
+			missing = append(missing, a)
+			continue
+		}
+		ips := slices.DeleteFunc(slices.Clone(updated[i].IPAddresses), func(ip smd.EthernetIP) bool { return ip.Network == a.Network })
+		updated[i].IPAddresses = append(ips, smd.EthernetIP{IPAddress: a.IPAddr, Network: a.Network})
+		if !slices.Contains(changed, i) {
+			changed = append(changed, i)
+		}
+	}
+	result := make([]smd.EthernetInterface, 0, len(changed))
+	for _, i := range changed {
+		result = append(result, updated[i])
+	}
+
+	return result, missing, nil
+}
+
+// ApplyNodeList adds the new addresses of the plan to the interfaces of the
+// nodes in nl and returns the number of addresses added, along with the new
+// assignments whose interfaces are not in nl. Addresses the interfaces already
+// have on the networks of the assignments are replaced. An error is returned,
+// and nl is left as is, if an address of the plan is now used by another
+// interface of nl.
+func (p Plan) ApplyNodeList(nl *discover.NodeList) (int, []Assignment, error) {
+	owners := make(map[string]string)
+	for _, n := range nl.Nodes {
+		for _, iface := range n.Ifaces {
+			for _, ip := range iface.IPAddrs {
+				owners[ip.IPAddr] = normalizeMAC(iface.MACAddr)
+			}
+		}
+	}
+	if err := p.conflicts(owners); err != nil {
+		return 0, nil, err
+	}
+
+	var (
+		added   int
+		missing []Assignment
+	)
+	for _, a := range p.Assignments {
+		if a.Existing {
+			continue
+		}
+		found := false
+		for ni := range nl.Nodes {
+			for ii, iface := range nl.Nodes[ni].Ifaces {
+				if normalizeMAC(iface.MACAddr) != normalizeMAC(a.MACAddr) {
+					continue
+				}
+				ips := slices.DeleteFunc(slices.Clone(iface.IPAddrs), func(ip discover.IfaceIP) bool { return ip.Network == a.Network })
+				nl.Nodes[ni].Ifaces[ii].IPAddrs = append(ips, discover.IfaceIP{Network: a.Network, IPAddr: a.IPAddr})
+				found = true
+			}
+		}
+		if found {
+			added++
+		} else {
+			missing = append(missing, a)
+		}
+	}
+
+	return added, missing, nil
+}
+
+// conflicts returns an error listing the new assignments of the plan whose
+// addresses are owned by interfaces with other MAC addresses, according to
+// owners, which maps addresses to the MAC addresses of their interfaces.
+func (p Plan) conflicts(owners map[string]string) error {
+	var conflicts []string
+	for _, a := range p.Assignments {
+		if owner, ok := owners[a.IPAddr]; ok && !a.Existing && owner != normalizeMAC(a.MACAddr) {
+			conflicts = append(conflicts, fmt.Sprintf("%s is now used by interface %s", a, owner))
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("addresses of plan are in use, make a new plan: %s", strings.Join(conflicts, "; "))
+	}
+
+	return nil
+}
+
+// normalizeMAC returns mac in lower case with colon separators, so that MAC
+// addresses written differently can be compared.
+func normalizeMAC(mac string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(mac)), "-", ":")
+}
+
+// NodesFromEthernetInterfaces returns a node for each of xnames with the
+// ethernet interfaces in eis of the component with its xname, e.g. to plan the
+// addresses of the members of an SMD group, along with the xnames without
+// ethernet interfaces.
+func NodesFromEthernetInterfaces(xnames []string, eis []smd.EthernetInterface) ([]discover.Node, []string) {
+	var (
+		nodes   []discover.Node
+		without []string
+	)
+	for _, x := range xnames {
+		n := discover.Node{Xname: x}
+		for _, ei := range eis {
+			if strings.EqualFold(ei.ComponentID, x) {
+				n.Ifaces = append(n.Ifaces, discover.Iface{MACAddr: ei.MACAddress, IPAddrs: ifaceIPs(ei)})
+			}
+		}
+		if len(n.Ifaces) == 0 {
+			without = append(without, x)
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+
+	return nodes, without
+}
+
+// MergeEthernetInterfaces returns a copy of nodes in which the interfaces also
+// have the addresses of the ethernet interfaces in eis with their MAC
+// addresses, so that addresses already assigned to them in SMD are kept.
+func MergeEthernetInterfaces(nodes []discover.Node, eis []smd.EthernetInterface) []discover.Node {
+	byMAC := make(map[string]smd.EthernetInterface)
+	for _, ei := range eis {
+		byMAC[normalizeMAC(ei.MACAddress)] = ei
+	}
+	merged := slices.Clone(nodes)
+	for ni, n := range merged {
+		merged[ni].Ifaces = slices.Clone(n.Ifaces)
+		for ii, iface := range n.Ifaces {
+			ei, ok := byMAC[normalizeMAC(iface.MACAddr)]
+			if !ok {
+				continue
+			}
+			ips := slices.Clone(iface.IPAddrs)
+			for _, ip := range ifaceIPs(ei) {
+				if !slices.ContainsFunc(ips, func(i discover.IfaceIP) bool { return i.IPAddr == ip.IPAddr }) {
+					ips = append(ips, ip)
+				}
+			}
+			merged[ni].Ifaces[ii].IPAddrs = ips
+		}
+	}
+
+	return merged
+}
+
+// UsedAddresses returns the addresses of the ethernet interfaces in eis.
+func UsedAddresses(eis []smd.EthernetInterface) []string {
+	var used []string
+	for _, ei := range eis {
+		for _, ip := range ei.IPAddresses {
+			used = append(used, ip.IPAddress)
+		}
+	}
+
+	return used
+}
+
+// ifaceIPs returns the addresses of ei as those of an interface of a
+// discovery payload.
+func ifaceIPs(ei smd.EthernetInterface) []discover.IfaceIP {
+	var ips []discover.IfaceIP
+	for _, ip := range ei.IPAddresses {
+		ips = append(ips, discover.IfaceIP{Network: ip.Network, IPAddr: ip.IPAddress})
+	}
+
+	return ips
+}
@@ -0,0 +1,178 @@
+package ipam
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+)
+
+func node(xname string, macs ...string) discover.Node {
+	n := discover.Node{Xname: xname}
+	for _, mac := range macs {
+		n.Ifaces = append(n.Ifaces, discover.Iface{MACAddr: mac})
+	}
+	return n
+}
+
+func addrs(p Plan) []string {
+	var s []string
+	for _, a := range p.Assignments {
+		s = append(s, a.Network+" "+a.MACAddr+" "+a.IPAddr)
+	}
+	return s
+}
+
+func TestParseNetwork(t *testing.T) {
+	if n, err := ParseNetwork("mgmt=10.1.0.0/20"); err != nil || n != (Network{Name: "mgmt", CIDR: "10.1.0.0/20"}) {
+		t.Errorf("ParseNetwork() = %+v, %v", n, err)
+	}
+	for _, s := range []string{"10.1.0.0/20", "=10.1.0.0/20", "mgmt=10.1.0.0", "mgmt="} {
+		if _, err := ParseNetwork(s); err == nil {
+			t.Errorf("ParseNetwork(%q) returned no error", s)
+		}
+	}
+}
+
+func TestNewPlan(t *testing.T) {
+	nodes := []discover.Node{
+		node("x1000c0s0b0n0", "de:ad:be:ee:ef:01"),
+		node("x1000c0s1b0n0", "de:ad:be:ee:ef:02", "de:ad:be:ee:ef:03"),
+	}
+	nodes[1].Ifaces[1].IPAddrs = []discover.IfaceIP{{Network: "hsn", IPAddr: "10.2.0.9"}}
+	nodes = append(nodes, node("x1000c0s2b0n0", "de:ad:be:ee:ef:04"))
+	nodes[2].Ifaces[0].IPAddrs = []discover.IfaceIP{{Network: "other", IPAddr: "10.1.0.3"}}
+
+	plan, err := NewPlan(nodes, []Network{{Name: "mgmt", CIDR: "10.1.0.0/29"}, {Name: "hsn", CIDR: "10.2.0.0/24"}}, Options{Reserve: 1, Used: []string{"10.1.0.2"}})
+	if err != nil {
+		t.Fatalf("NewPlan returned error: %v", err)
+	}
+	want := []string{
+		"mgmt de:ad:be:ee:ef:01 10.1.0.4",
+		"hsn de:ad:be:ee:ef:01 10.2.0.2",
+		"mgmt de:ad:be:ee:ef:02 10.1.0.5",
+		"hsn de:ad:be:ee:ef:02 10.2.0.3",
+		"mgmt de:ad:be:ee:ef:03 10.1.0.6",
+		"hsn de:ad:be:ee:ef:03 10.2.0.9",
+		"mgmt de:ad:be:ee:ef:04 10.1.0.3",
+		"hsn de:ad:be:ee:ef:04 10.2.0.4",
+	}
+	if got := addrs(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewPlan() = %v, want %v", got, want)
+	}
+	if n, existing := plan.Count(); n != 6 || existing != 2 {
+		t.Errorf("Count() = %d, %d, want 6, 2", n, existing)
+	}
+
+	// 10.1.0.7 is the broadcast address, so there is no room for another
+	nodes = append(nodes, node("x1000c0s3b0n0", "de:ad:be:ee:ef:05"))
+	if _, err := NewPlan(nodes, []Network{{Name: "mgmt", CIDR: "10.1.0.0/29"}}, Options{Reserve: 1, Used: []string{"10.1.0.2"}}); err == nil {
+		t.Errorf("NewPlan returned no error for a full network")
+	}
+}
+
+func TestNewPlanPerRack(t *testing.T) {
+	nodes := []discover.Node{
+		node("x1002c0s0b0n0", "de:ad:be:ee:ef:01"),
+		node("x1000c0s0b0n0", "de:ad:be:ee:ef:02"),
+		node("x1000c0s1b0n0", "de:ad:be:ee:ef:03"),
+	}
+	networks := []Network{{Name: "mgmt", CIDR: "10.1.0.0/24"}}
+	plan, err := NewPlan(nodes, networks, Options{PerRack: 64, Reserve: 1})
+	if err != nil {
+		t.Fatalf("NewPlan returned error: %v", err)
+	}
+	want := []string{
+		"mgmt de:ad:be:ee:ef:01 10.1.0.64",
+		"mgmt de:ad:be:ee:ef:02 10.1.0.2",
+		"mgmt de:ad:be:ee:ef:03 10.1.0.3",
+	}
+	if got := addrs(plan); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewPlan() = %v, want %v", got, want)
+	}
+
+	if _, err := NewPlan(nodes, networks, Options{PerRack: 2, Reserve: 1}); err == nil {
+		t.Errorf("NewPlan returned no error for a full rack")
+	}
+	if _, err := NewPlan(nodes, networks, Options{PerRack: 200}); err == nil {
+		t.Errorf("NewPlan returned no error for a network too small for all racks")
+	}
+	if _, err := NewPlan([]discover.Node{node("node1", "de:ad:be:ee:ef:01")}, networks, Options{PerRack: 64}); err == nil {
+		t.Errorf("NewPlan returned no error for a node without a cabinet")
+	}
+}
+
+func TestPlanApply(t *testing.T) {
+	plan := Plan{
+		Version: PlanVersion,
+		Assignments: []Assignment{
+			{Xname: "x1000c0s0b0n0", MACAddr: "DE:AD:BE:EE:EF:01", Network: "mgmt", IPAddr: "10.1.0.2"},
+			{Xname: "x1000c0s0b0n0", MACAddr: "DE:AD:BE:EE:EF:01", Network: "hsn", IPAddr: "10.2.0.5", Existing: true},
+			{Xname: "x1000c0s1b0n0", MACAddr: "de:ad:be:ee:ef:02", Network: "mgmt", IPAddr: "10.1.0.3"},
+		},
+	}
+
+	eis := []smd.EthernetInterface{
+		{ID: "deadbeeeef01", MACAddress: "de:ad:be:ee:ef:01", IPAddresses: []smd.EthernetIP{{IPAddress: "10.1.9.9", Network: "mgmt"}, {IPAddress: "10.2.0.5", Network: "hsn"}}},
+		{ID: "deadbeeeef09", MACAddress: "de:ad:be:ee:ef:09"},
+	}
+	updated, missing, err := plan.ApplyEthernetInterfaces(eis)
+	if err != nil {
+		t.Fatalf("ApplyEthernetInterfaces returned error: %v", err)
+	}
+	wantUpdated := []smd.EthernetInterface{
+		{ID: "deadbeeeef01", MACAddress: "de:ad:be:ee:ef:01", IPAddresses: []smd.EthernetIP{{IPAddress: "10.2.0.5", Network: "hsn"}, {IPAddress: "10.1.0.2", Network: "mgmt"}}},
+	}
+	if !reflect.DeepEqual(updated, wantUpdated) {
+		t.Errorf("ApplyEthernetInterfaces() = %+v, want %+v", updated, wantUpdated)
+	}
+	if len(missing) != 1 || missing[0].MACAddr != "de:ad:be:ee:ef:02" {
+		t.Errorf("ApplyEthernetInterfaces() missing = %+v", missing)
+	}
+	if eis[0].IPAddresses[0].IPAddress != "10.1.9.9" {
+		t.Errorf("ApplyEthernetInterfaces() modified its input")
+	}
+	eis[1].IPAddresses = []smd.EthernetIP{{IPAddress: "10.1.0.3"}}
+	if _, _, err := plan.ApplyEthernetInterfaces(eis); err == nil {
+		t.Errorf("ApplyEthernetInterfaces returned no error for an address in use")
+	}
+
+	nl := discover.NodeList{Nodes: []discover.Node{
+		node("x1000c0s0b0n0", "de:ad:be:ee:ef:01"),
+		node("x1000c0s1b0n0", "de:ad:be:ee:ef:02"),
+	}}
+	added, missing, err := plan.ApplyNodeList(&nl)
+	if err != nil || added != 2 || len(missing) != 0 {
+		t.Fatalf("ApplyNodeList() = %d, %+v, %v, want 2 added", added, missing, err)
+	}
+	if got := nl.Nodes[1].Ifaces[0].IPAddrs; !reflect.DeepEqual(got, []discover.IfaceIP{{Network: "mgmt", IPAddr: "10.1.0.3"}}) {
+		t.Errorf("ApplyNodeList() set addresses %v", got)
+	}
+}
+
+func TestEthernetInterfaces(t *testing.T) {
+	eis := []smd.EthernetInterface{
+		{ComponentID: "x1000c0s0b0n0", MACAddress: "de:ad:be:ee:ef:01", IPAddresses: []smd.EthernetIP{{IPAddress: "10.1.0.7", Network: "mgmt"}}},
+		{ComponentID: "X1000C0S0B0N0", MACAddress: "de:ad:be:ee:ef:02"},
+		{ComponentID: "x1000c0s9b0n0", MACAddress: "de:ad:be:ee:ef:09", IPAddresses: []smd.EthernetIP{{IPAddress: "10.1.0.9"}}},
+	}
+
+	nodes, without := NodesFromEthernetInterfaces([]string{"x1000c0s0b0n0", "x1000c0s1b0n0"}, eis)
+	if len(nodes) != 1 || len(nodes[0].Ifaces) != 2 || !reflect.DeepEqual(without, []string{"x1000c0s1b0n0"}) {
+		t.Errorf("NodesFromEthernetInterfaces() = %+v, %v", nodes, without)
+	}
+
+	in := []discover.Node{node("x1000c0s0b0n0", "DE:AD:BE:EE:EF:01")}
+	merged := MergeEthernetInterfaces(in, eis)
+	if got := merged[0].Ifaces[0].IPAddrs; !reflect.DeepEqual(got, []discover.IfaceIP{{Network: "mgmt", IPAddr: "10.1.0.7"}}) {
+		t.Errorf("MergeEthernetInterfaces() set addresses %v", got)
+	}
+	if len(in[0].Ifaces[0].IPAddrs) != 0 {
+		t.Errorf("MergeEthernetInterfaces() modified its input")
+	}
+
+	if got := UsedAddresses(eis); !reflect.DeepEqual(got, []string{"10.1.0.7", "10.1.0.9"}) {
+		t.Errorf("UsedAddresses() = %v", got)
+	}
+}
@@ -0,0 +1,304 @@
+// Package openapi validates request payloads against the request body schemas
+// of an OpenAPI 3 or Swagger 2 specification, such as the one a service
+// publishes for the version of its API it runs.
+package openapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v3"
+)
+
+// NoSchemaError is returned by Validate when the specification has no request
+// body schema for a request, so the request cannot be validated.
+var NoSchemaError = errors.New("no request body schema in OpenAPI spec")
+
+// Spec is a parsed OpenAPI (or Swagger) specification.
+type Spec struct {
+	doc   map[string]interface{}
+	paths []specPath
+}
+
+// specPath is a path template of a Spec (e.g. /State/Components/{xname}),
+// split into segments.
+type specPath struct {
+	template string
+	segs     []string
+	params   int // Number of templated segments
+}
+
+// ValidationError is returned by Validate when a payload does not match the
+// schema of its request, listing each mismatch.
+type ValidationError struct {
+	Method   string   // Method of the request
+	Template string   // Path template of the operation
+	Errors   []string // Mismatches, e.g. "Components.0.ID: ID is required"
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("payload does not match schema of %s %s: %s", e.Method, e.Template, strings.Join(e.Errors, "; "))
+}
+
+// Load parses spec, an OpenAPI specification in JSON or YAML.
+func Load(spec []byte) (*Spec, error) {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		if yerr := yaml.Unmarshal(spec, &doc); yerr != nil {
+			return nil, fmt.Errorf("failed to parse OpenAPI spec: %w", err)
+		}
+	}
+	if doc == nil {
+		return nil, errors.New("failed to parse OpenAPI spec: empty document")
+	}
+	if _, ok := doc["openapi"]; !ok {
+		if _, ok := doc["swagger"]; !ok {
+			return nil, errors.New("failed to parse OpenAPI spec: neither openapi nor swagger version set")
+		}
+	}
+
+	s := &Spec{doc: normalize(doc).(map[string]interface{})}
+	paths, _ := s.doc["paths"].(map[string]interface{})
+	for tmpl := range paths {
+		sp := specPath{template: tmpl, segs: splitPath(tmpl)}
+		for _, seg := range sp.segs {
+			if isParam(seg) {
+				sp.params++
+			}
+		}
+		s.paths = append(s.paths, sp)
+	}
+	// Prefer the most specific path: the longest, then the one with the
+	// fewest templated segments
+	sort.Slice(s.paths, func(i, j int) bool {
+		a, b := s.paths[i], s.paths[j]
+		if len(a.segs) != len(b.segs) {
+			return len(a.segs) > len(b.segs)
+		}
+		if a.params != b.params {
+			return a.params < b.params
+		}
+		return a.template < b.template
+	})
+
+	return s, nil
+}
+
+// Validate validates body, the payload of a request with method to path,
+// against the request body schema of the matching operation of s. The
+// operation is that whose path template matches the end of path, so that path
+// may include the base path of the service and any prefix of an API gateway.
+// If no operation matches or it has no request body schema, an error wrapping
+// NoSchemaError is returned. If body does not match the schema, a
+// *ValidationError is returned.
+func (s *Spec) Validate(method, path string, body []byte) error {
+	tmpl, ptr, ok := s.schemaPointer(method, path)
+	if !ok {
+		return fmt.Errorf("%w: %s %s", NoSchemaError, method, path)
+	}
+
+	// Validate against the whole document with its root referring to the
+	// schema, so that references to other schemas in it resolve
+	root := make(map[string]interface{}, len(s.doc)+1)
+	for k, v := range s.doc {
+		root[k] = v
+	}
+	root["$ref"] = ptr
+	res, err := gojsonschema.Validate(gojsonschema.NewGoLoader(root), gojsonschema.NewBytesLoader(body))
+	if err != nil {
+		return fmt.Errorf("failed to validate payload against schema of %s %s: %w", method, tmpl, err)
+	}
+	if res.Valid() {
+		return nil
+	}
+	verr := &ValidationError{Method: method, Template: tmpl}
+	for _, re := range res.Errors() {
+		// Some descriptions already start with the field
+		if desc := re.Description(); strings.HasPrefix(desc, re.Field()) {
+			verr.Errors = append(verr.Errors, desc)
+		} else {
+			verr.Errors = append(verr.Errors, re.Field()+": "+desc)
+		}
+	}
+
+	return verr
+}
+
+// schemaPointer returns the path template of the operation of s matching a
+// request with method to path, and a JSON pointer to its request body schema.
+func (s *Spec) schemaPointer(method, path string) (string, string, bool) {
+	paths, _ := s.doc["paths"].(map[string]interface{})
+	method = strings.ToLower(method)
+	segs := splitPath(path)
+	for _, sp := range s.paths {
+		if !matchSuffix(sp.segs, segs) {
+			continue
+		}
+		item, _ := paths[sp.template].(map[string]interface{})
+		op, ok := item[method].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		base := "#/paths/" + escape(sp.template) + "/" + method
+
+		// OpenAPI 3: requestBody, possibly a reference to one
+		if rb, ok := op["requestBody"].(map[string]interface{}); ok {
+			ptr := base + "/requestBody"
+			if ref, ok := rb["$ref"].(string); ok {
+				ptr = ref
+				rb, _ = s.resolve(ref).(map[string]interface{})
+			}
+			content, _ := rb["content"].(map[string]interface{})
+			ct, ok := jsonContentType(content)
+			if !ok {
+				return sp.template, "", false
+			}
+			return sp.template, ptr + "/content/" + escape(ct) + "/schema", true
+		}
+
+		// Swagger 2: body parameter of the operation or path
+		for _, p := range []struct {
+			params interface{}
+			ptr    string
+		}{
+			{op["parameters"], base + "/parameters"},
+			{item["parameters"], "#/paths/" + escape(sp.template) + "/parameters"},
+		} {
+			params, _ := p.params.([]interface{})
+			for i, param := range params {
+				ptr := fmt.Sprintf("%s/%d", p.ptr, i)
+				pm, _ := param.(map[string]interface{})
+				if ref, ok := pm["$ref"].(string); ok {
+					ptr = ref
+					pm, _ = s.resolve(ref).(map[string]interface{})
+				}
+				if pm["in"] == "body" && pm["schema"] != nil {
+					return sp.template, ptr + "/schema", true
+				}
+			}
+		}
+
+		return sp.template, "", false
+	}
+
+	return "", "", false
+}
+
+// resolve returns the value JSON pointer ref refers to in s, or nil if it
+// does not refer to a value in s.
+func (s *Spec) resolve(ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var v interface{} = s.doc
+	for _, tok := range strings.Split(ref[2:], "/") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)]
+	}
+
+	return v
+}
+
+// jsonContentType returns the JSON media type of the request body content
+// content, preferring application/json.
+func jsonContentType(content map[string]interface{}) (string, bool) {
+	if _, ok := content["application/json"]; ok {
+		return "application/json", true
+	}
+	var types []string
+	for ct := range content {
+		if strings.HasSuffix(strings.SplitN(ct, ";", 2)[0], "json") {
+			types = append(types, ct)
+		}
+	}
+	if len(types) == 0 {
+		return "", false
+	}
+	sort.Strings(types)
+
+	return types[0], true
+}
+
+// normalize returns a copy of v, a document decoded from JSON or YAML, that
+// gojsonschema can validate against: maps with non-string keys (from YAML)
+// have their keys converted to strings, and since gojsonschema does not know
+// the nullable keyword of OpenAPI 3.0, the type of nullable schemas includes
+// null.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = normalize(e)
+		}
+		if nullable, _ := m["nullable"].(bool); nullable {
+			switch t := m["type"].(type) {
+			case string:
+				m["type"] = []interface{}{t, "null"}
+			case []interface{}:
+				m["type"] = append(t, "null")
+			}
+			delete(m, "nullable")
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = e
+		}
+		return normalize(m)
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, e := range v {
+			l[i] = normalize(e)
+		}
+		return l
+	}
+
+	return v
+}
+
+// splitPath splits path into its non-empty segments.
+func splitPath(path string) []string {
+	var segs []string
+	for _, seg := range strings.Split(path, "/") {
+		if seg != "" {
+			segs = append(segs, seg)
+		}
+	}
+
+	return segs
+}
+
+// isParam returns whether seg is a templated path segment (e.g. {xname}).
+func isParam(seg string) bool {
+	return strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}")
+}
+
+// matchSuffix returns whether the path template segments tmpl match the last
+// segments of path.
+func matchSuffix(tmpl, path []string) bool {
+	if len(tmpl) > len(path) {
+		return false
+	}
+	path = path[len(path)-len(tmpl):]
+	for i, seg := range tmpl {
+		if !isParam(seg) && !strings.EqualFold(seg, path[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// escape escapes tok for use in a JSON pointer.
+func escape(tok string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(tok)
+}
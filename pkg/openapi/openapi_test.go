@@ -0,0 +1,193 @@
+package openapi
+
+import (
+	"errors"
+	"testing"
+)
+
+const testOpenAPI3 = `{
+  "openapi": "3.0.0",
+  "paths": {
+    "/State/Components": {
+      "post": {
+        "requestBody": {"$ref": "#/components/requestBodies/Components"}
+      }
+    },
+    "/State/Components/{xname}": {
+      "put": {
+        "requestBody": {
+          "content": {"application/json": {"schema": {"$ref": "#/components/schemas/Component"}}}
+        }
+      },
+      "delete": {}
+    },
+    "/State/Components/ByNID": {
+      "put": {
+        "requestBody": {
+          "content": {"application/json": {"schema": {"type": "object", "required": ["NIDs"]}}}
+        }
+      }
+    }
+  },
+  "components": {
+    "requestBodies": {
+      "Components": {
+        "content": {
+          "application/json": {
+            "schema": {
+              "type": "object",
+              "properties": {"Components": {"type": "array", "items": {"$ref": "#/components/schemas/Component"}}}
+            }
+          }
+        }
+      }
+    },
+    "schemas": {
+      "Component": {
+        "type": "object",
+        "required": ["ID"],
+        "properties": {
+          "ID": {"type": "string"},
+          "NID": {"type": "integer", "nullable": true}
+        }
+      }
+    }
+  }
+}`
+
+const testSwagger2 = `
+swagger: "2.0"
+basePath: /boot/v1
+paths:
+  /bootparameters:
+    parameters:
+      - in: body
+        name: body
+        schema:
+          $ref: "#/definitions/BootParams"
+    put: {}
+definitions:
+  BootParams:
+    type: object
+    required: [kernel]
+    properties:
+      kernel: {type: string}
+      hosts: {type: array, items: {type: string}}
+`
+
+func TestLoad(t *testing.T) {
+	if _, err := Load([]byte(testOpenAPI3)); err != nil {
+		t.Errorf("Load(OpenAPI 3) failed: %v", err)
+	}
+	if _, err := Load([]byte(testSwagger2)); err != nil {
+		t.Errorf("Load(Swagger 2) failed: %v", err)
+	}
+	if _, err := Load([]byte(`{"paths": {}}`)); err == nil {
+		t.Error("Load() of document without version succeeded, want error")
+	}
+	if _, err := Load([]byte(`not: [a, spec`)); err == nil {
+		t.Error("Load() of invalid document succeeded, want error")
+	}
+}
+
+func TestSpec_Validate(t *testing.T) {
+	oas3, err := Load([]byte(testOpenAPI3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	swagger2, err := Load([]byte(testSwagger2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		spec     *Spec
+		method   string
+		path     string
+		body     string
+		invalid  bool
+		noSchema bool
+	}{
+		{
+			name:   "referenced request body",
+			spec:   oas3,
+			method: "POST",
+			path:   "/hsm/v2/State/Components",
+			body:   `{"Components": [{"ID": "x1000c0s0b0n0", "NID": null}]}`,
+		},
+		{
+			name:    "missing required field",
+			spec:    oas3,
+			method:  "POST",
+			path:    "/hsm/v2/State/Components",
+			body:    `{"Components": [{"NID": 1}]}`,
+			invalid: true,
+		},
+		{
+			name:    "templated path",
+			spec:    oas3,
+			method:  "PUT",
+			path:    "/hsm/v2/State/Components/x1000c0s0b0n0",
+			body:    `{"ID": 5}`,
+			invalid: true,
+		},
+		{
+			name:    "literal path preferred over template",
+			spec:    oas3,
+			method:  "PUT",
+			path:    "/hsm/v2/State/Components/ByNID",
+			body:    `{"ID": "x1000c0s0b0n0"}`,
+			invalid: true,
+		},
+		{
+			name:     "operation without request body",
+			spec:     oas3,
+			method:   "DELETE",
+			path:     "/hsm/v2/State/Components/x1000c0s0b0n0",
+			body:     `{}`,
+			noSchema: true,
+		},
+		{
+			name:     "unknown path",
+			spec:     oas3,
+			method:   "POST",
+			path:     "/hsm/v2/groups",
+			body:     `{}`,
+			noSchema: true,
+		},
+		{
+			name:   "swagger body parameter",
+			spec:   swagger2,
+			method: "PUT",
+			path:   "/boot/v1/bootparameters",
+			body:   `{"kernel": "http://k", "hosts": ["x1"]}`,
+		},
+		{
+			name:    "swagger wrong type",
+			spec:    swagger2,
+			method:  "PUT",
+			path:    "/boot/v1/bootparameters",
+			body:    `{"kernel": "http://k", "hosts": "x1"}`,
+			invalid: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate(tt.method, tt.path, []byte(tt.body))
+			var verr *ValidationError
+			switch {
+			case tt.noSchema:
+				if !errors.Is(err, NoSchemaError) {
+					t.Errorf("Validate() = %v, want NoSchemaError", err)
+				}
+			case tt.invalid:
+				if !errors.As(err, &verr) || len(verr.Errors) == 0 {
+					t.Errorf("Validate() = %v, want *ValidationError", err)
+				}
+			case err != nil:
+				t.Errorf("Validate() = %v, want nil", err)
+			}
+		})
+	}
+}
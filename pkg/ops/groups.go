@@ -0,0 +1,132 @@
+package ops
+
+import (
+	"context"
+
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/discover"
+)
+
+// GroupSyncResult is the outcome of SyncGroups: what it changed and the results
+// of creating groups, and, by group label, of adding and removing members.
+// Verified is, by group label, how the members of the groups whose members
+// were edited differ from what the edit was expected to produce when read back.
+type GroupSyncResult struct {
+	discover.GroupSync
+	Created  Results
+	Added    map[string]Results
+	Removed  map[string]Results
+	Verified map[string]smd.MemberChanges
+}
+
+// Failed returns whether any request of r failed or any member edit was lost.
+func (r GroupSyncResult) Failed() bool {
+	if len(r.Created.Failed()) > 0 {
+		return true
+	}
+	for _, m := range []map[string]Results{r.Added, r.Removed} {
+		for _, res := range m {
+			if len(res.Failed()) > 0 {
+				return true
+			}
+		}
+	}
+	for _, mc := range r.Verified {
+		if mc.Conflict() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SyncGroups syncs groups, e.g. the groups of a discovery payload, with the
+// groups in SMD as computed by discover.SyncGroups: missing groups are created
+// and the members of existing ones that are not in them are added, as well as,
+// if prune is true, those that are in them but not in groups removed. Other
+// groups in SMD are left as is. The members of each group whose members were
+// edited are read back afterwards to verify the edit (see smd.CompareMembers),
+// so sc should not read from a cache.
+//
+// An error is returned if the groups in SMD cannot be read or a step cannot be
+// started. The requests that failed are in the result.
+func SyncGroups(ctx context.Context, sc *smd.SMDClient, token string, groups []smd.Group, prune bool) (GroupSyncResult, error) {
+	have, err := GetGroups(ctx, sc, token)
+	if err != nil {
+		return GroupSyncResult{}, err
+	}
+	r := GroupSyncResult{
+		GroupSync: discover.SyncGroups(groups, have, prune),
+		Added:     make(map[string]Results),
+		Removed:   make(map[string]Results),
+		Verified:  make(map[string]smd.MemberChanges),
+	}
+	before := make(map[string][]string, len(have))
+	for _, g := range have {
+		before[g.Label] = g.Members.IDs
+	}
+
+	if len(r.Create) > 0 {
+		c, err := smdWithContext(ctx, sc)
+		if err != nil {
+			return r, err
+		}
+		labels := make([]string, len(r.Create))
+		for i, g := range r.Create {
+			labels[i] = g.Label
+		}
+		henvs, errs, err := c.PostGroups(r.Create, token)
+		if err != nil {
+			return r, err
+		}
+		r.Created = newResults(labels, henvs, errs)
+	}
+	for _, g := range groups {
+		if members := r.Add[g.Label]; len(members) > 0 {
+			c, err := smdWithContext(ctx, sc)
+			if err != nil {
+				return r, err
+			}
+			henvs, errs, err := c.PostGroupMembers(token, g.Label, members...)
+			if err != nil {
+				return r, err
+			}
+			r.Added[g.Label] = newResults(members, henvs, errs)
+		}
+		if members := r.Remove[g.Label]; len(members) > 0 {
+			c, err := smdWithContext(ctx, sc)
+			if err != nil {
+				return r, err
+			}
+			henvs, errs, err := c.DeleteGroupMembers(token, g.Label, members...)
+			if err != nil {
+				return r, err
+			}
+			r.Removed[g.Label] = newResults(members, henvs, errs)
+		}
+		if len(r.Add[g.Label]) > 0 || len(r.Remove[g.Label]) > 0 {
+			after, err := getGroupMembers(ctx, sc, token, g.Label)
+			if err != nil {
+				return r, err
+			}
+			r.Verified[g.Label] = smd.CompareMembers(before[g.Label], after, r.Added[g.Label].Succeeded(), r.Removed[g.Label].Succeeded())
+		}
+	}
+
+	return r, nil
+}
+
+// getGroupMembers returns the IDs of the members of the group label in SMD.
+func getGroupMembers(ctx context.Context, sc *smd.SMDClient, token, label string) ([]string, error) {
+	c, err := smdWithContext(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	var gm smd.GroupMembers
+	if err := get("members of group "+label, func() (client.HTTPEnvelope, error) { return c.GetGroupMembers(label, token) }, &gm); err != nil {
+		return nil, err
+	}
+
+	return gm.IDs, nil
+}
@@ -0,0 +1,131 @@
+// Package ops implements ochami operations that talk to the OpenCHAMI
+// services, namely resolving targets and syncing groups, so that site tooling
+// can embed them. Other operations, such as reading payloads, confirming
+// deletions, dry runs, and sending discovery payloads, are implemented by the
+// commands and are not part of this package. Unlike the commands that wrap them, operations neither log,
+// prompt, nor exit: they take a context, typed inputs, and a client, and
+// return typed results and errors for the caller to report.
+//
+// The deadline of the context bounds the requests of an operation. Its
+// cancellation is checked before each step of an operation, but does not
+// interrupt requests in flight.
+package ops
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// ItemResult is the outcome of the request of an operation for one item, e.g.
+// a group member added. Status is the HTTP status of the response, which is
+// zero if none was received.
+type ItemResult struct {
+	Item   string `json:"item" yaml:"item"`
+	Status int    `json:"status,omitempty" yaml:"status,omitempty"`
+	Err    error  `json:"-" yaml:"-"`
+}
+
+// Results are the outcomes of the requests of an operation, in order.
+type Results []ItemResult
+
+// Failed returns the results in r whose requests failed.
+func (r Results) Failed() Results {
+	var failed Results
+	for _, res := range r {
+		if res.Err != nil {
+			failed = append(failed, res)
+		}
+	}
+
+	return failed
+}
+
+// Succeeded returns the items of the results in r whose requests succeeded, in
+// order.
+func (r Results) Succeeded() []string {
+	var items []string
+	for _, res := range r {
+		if res.Err == nil {
+			items = append(items, res.Item)
+		}
+	}
+
+	return items
+}
+
+// Errs returns the errors of the results in r, in order, which are nil for
+// requests that succeeded.
+func (r Results) Errs() []error {
+	errs := make([]error, len(r))
+	for i, res := range r {
+		errs[i] = res.Err
+	}
+
+	return errs
+}
+
+// newResults returns the results of the requests for items whose responses
+// and errors are henvs and errs, as returned by the bulk client functions.
+func newResults(items []string, henvs []client.HTTPEnvelope, errs []error) Results {
+	r := make(Results, len(items))
+	for i, item := range items {
+		r[i].Item = item
+		if i < len(henvs) {
+			r[i].Status = henvs[i].StatusCode
+		}
+		if i < len(errs) {
+			r[i].Err = errs[i]
+		}
+	}
+
+	return r
+}
+
+// smdWithContext returns a shallow copy of sc whose requests are bounded by the
+// deadline of ctx, or the error of ctx if it is done.
+func smdWithContext(ctx context.Context, sc *smd.SMDClient) (*smd.SMDClient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if sc == nil {
+		return nil, errors.New("no SMD client")
+	}
+	c := *sc
+	if d, ok := ctx.Deadline(); ok {
+		c.OchamiClient = sc.WithDeadline(d)
+	}
+
+	return &c, nil
+}
+
+// get requests kind from SMD with fn and unmarshals the response body into v.
+func get(kind string, fn func() (client.HTTPEnvelope, error), v any) error {
+	henv, err := fn()
+	if err != nil {
+		return fmt.Errorf("failed to request %s from SMD: %w", kind, err)
+	}
+	if err := json.Unmarshal(henv.Body, v); err != nil {
+		return fmt.Errorf("failed to unmarshal %s: %w", kind, err)
+	}
+
+	return nil
+}
+
+// GetGroups returns the groups in SMD.
+func GetGroups(ctx context.Context, sc *smd.SMDClient, token string) ([]smd.Group, error) {
+	c, err := smdWithContext(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	var groups []smd.Group
+	if err := get("groups", func() (client.HTTPEnvelope, error) { return c.GetGroups("", token) }, &groups); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
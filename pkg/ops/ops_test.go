@@ -0,0 +1,177 @@
+package ops
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+	"github.com/OpenCHAMI/ochami/pkg/testutil"
+)
+
+func newGroup(label string, members ...string) smd.Group {
+	g := smd.Group{Label: label}
+	g.Members.IDs = members
+	return g
+}
+
+func newFakeSMDClient(t *testing.T) (*testutil.FakeSMD, *smd.SMDClient) {
+	t.Helper()
+	f := testutil.NewFakeSMD(t)
+	sc, err := smd.NewClient(f.URL(), false)
+	if err != nil {
+		t.Fatalf("failed to create SMD client: %v", err)
+	}
+	return f, sc
+}
+
+func TestResolveTargets(t *testing.T) {
+	f, sc := newFakeSMDClient(t)
+	f.AddComponents(smd.Component{ID: "x3000c0s0b0n0", Type: "Node", NID: 1})
+	f.AddGroups(newGroup("compute", "x3000c0s1b0n0", "x3000c0s0b0n0"))
+
+	targets, err := smd.ParseTargets([]string{"nid:1", "group:compute", "x3000c0s2b0n0"})
+	if err != nil {
+		t.Fatalf("ParseTargets() error = %v", err)
+	}
+	got, err := ResolveTargets(context.Background(), sc, "", targets)
+	if err != nil {
+		t.Fatalf("ResolveTargets() error = %v", err)
+	}
+	want := []string{"x3000c0s0b0n0", "x3000c0s1b0n0", "x3000c0s2b0n0"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ResolveTargets() = %v, want %v", got, want)
+	}
+
+	// Xnames alone need no client
+	targets, _ = smd.ParseTargets([]string{"x3000c0s2b0n0"})
+	if TargetsNeedSMD(targets) {
+		t.Errorf("TargetsNeedSMD() = true for xnames")
+	}
+	if _, err := ResolveTargets(context.Background(), nil, "", targets); err != nil {
+		t.Errorf("ResolveTargets() without client error = %v", err)
+	}
+
+	// A done context fails before any request
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	targets, _ = smd.ParseTargets([]string{"nid:1"})
+	if _, err := ResolveTargets(ctx, sc, "", targets); err == nil {
+		t.Errorf("ResolveTargets() with canceled context error = nil")
+	}
+}
+
+func TestSyncGroups(t *testing.T) {
+	f, sc := newFakeSMDClient(t)
+	f.AddGroups(
+		newGroup("compute", "x3000c0s0b0n0", "x3000c0s9b0n0"),
+		newGroup("other", "x3000c0s9b0n0"),
+	)
+
+	groups := []smd.Group{
+		newGroup("compute", "x3000c0s0b0n0", "x3000c0s1b0n0"),
+		newGroup("gpu", "x3000c0s1b0n0"),
+	}
+	res, err := SyncGroups(context.Background(), sc, "", groups, true)
+	if err != nil {
+		t.Fatalf("SyncGroups() error = %v", err)
+	}
+	if res.Failed() {
+		t.Errorf("SyncGroups() failed: %+v", res)
+	}
+	if len(res.Created) != 1 || res.Created[0].Item != "gpu" {
+		t.Errorf("SyncGroups() Created = %+v, want gpu", res.Created)
+	}
+	if mc, ok := res.Verified["compute"]; !ok || mc.Conflict() || mc.Concurrent() {
+		t.Errorf("SyncGroups() Verified = %+v, want compute verified without changes", res.Verified)
+	}
+
+	want := []smd.Group{
+		newGroup("compute", "x3000c0s0b0n0", "x3000c0s1b0n0"),
+		newGroup("gpu", "x3000c0s1b0n0"),
+		newGroup("other", "x3000c0s9b0n0"),
+	}
+	got := f.Groups()
+	for i := range want {
+		if i >= len(got) || got[i].Label != want[i].Label || !reflect.DeepEqual(got[i].Members.IDs, want[i].Members.IDs) {
+			t.Fatalf("groups in SMD = %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestSyncGroups_Lost(t *testing.T) {
+	// SMD accepts the new member but does not show it afterwards, as if
+	// another client removed it concurrently
+	fs := testutil.NewFakeServer(t, "/hsm/v2")
+	fs.HandleJSON("GET "+smd.SMDRelpathGroups, http.StatusOK, []smd.Group{newGroup("compute", "x3000c0s0b0n0")})
+	fs.HandleJSON("POST "+smd.SMDRelpathGroups+"/compute/members", http.StatusOK, nil)
+	fs.HandleJSON("GET "+smd.SMDRelpathGroups+"/compute/members", http.StatusOK, smd.GroupMembers{IDs: []string{"x3000c0s0b0n0"}})
+	sc, err := smd.NewClient(fs.URL(), false)
+	if err != nil {
+		t.Fatalf("failed to create SMD client: %v", err)
+	}
+
+	res, err := SyncGroups(context.Background(), sc, "", []smd.Group{newGroup("compute", "x3000c0s1b0n0")}, false)
+	if err != nil {
+		t.Fatalf("SyncGroups() error = %v", err)
+	}
+	if !res.Failed() {
+		t.Errorf("SyncGroups() did not fail for a lost member: %+v", res)
+	}
+	if got := res.Verified["compute"].Lost; !reflect.DeepEqual(got, []string{"x3000c0s1b0n0"}) {
+		t.Errorf("SyncGroups() lost members = %v, want [x3000c0s1b0n0]", got)
+	}
+}
+
+func TestParseReportItems(t *testing.T) {
+	reimage := []byte(`{"image":"compute-v2","results":[
+		{"xname":"x3000c0s0b0n0","status":"booted"},
+		{"xname":"x3000c0s1b0n0","status":"timed-out"},
+		{"xname":"x3000c0s2b0n0","status":"Failed","error":"reboot failed"}
+	]}`)
+	items, err := ParseReportItems(reimage)
+	if err != nil {
+		t.Fatalf("ParseReportItems() error = %v", err)
+	}
+	want := []ReportItem{
+		{Xname: "x3000c0s0b0n0", Status: "booted"},
+		{Xname: "x3000c0s1b0n0", Status: "timed-out", Failed: true},
+		{Xname: "x3000c0s2b0n0", Status: "failed", Failed: true},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("ParseReportItems() = %+v, want %+v", items, want)
+	}
+
+	tests := []struct {
+		only []string
+		want []string
+	}{
+		{nil, []string{"x3000c0s0b0n0", "x3000c0s1b0n0", "x3000c0s2b0n0"}},
+		{[]string{"failed"}, []string{"x3000c0s1b0n0", "x3000c0s2b0n0"}},
+		{[]string{"SUCCEEDED"}, []string{"x3000c0s0b0n0"}},
+		{[]string{"timed-out", "booted"}, []string{"x3000c0s0b0n0", "x3000c0s1b0n0"}},
+		{[]string{"forced"}, nil},
+	}
+	for _, tt := range tests {
+		if got := SelectReportXnames(items, tt.only); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SelectReportXnames(%v) = %v, want %v", tt.only, got, tt.want)
+		}
+	}
+
+	// smd component wait prints a list of items, in YAML here.
+	wait := []byte("- xname: x3000c0s0b0n0\n  reached: true\n- xname: x3000c0s1b0n0\n  reached: false\n")
+	items, err = ParseReportItems(wait)
+	if err != nil {
+		t.Fatalf("ParseReportItems() error = %v", err)
+	}
+	if got := SelectReportXnames(items, []string{"failed"}); !reflect.DeepEqual(got, []string{"x3000c0s1b0n0"}) {
+		t.Errorf("SelectReportXnames() of wait report = %v, want [x3000c0s1b0n0]", got)
+	}
+
+	for _, bad := range []string{`"text"`, `{"counts":{}}`, `[{"status":"failed"}]`, `{`} {
+		if _, err := ParseReportItems([]byte(bad)); err == nil {
+			t.Errorf("ParseReportItems(%s) error = nil", bad)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package ops
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Classes of report items that SelectReportXnames selects besides the items
+// with a given status.
+const (
+	// ReportOnlyFailed selects the items that did not succeed: those with
+	// an error, those with a failing status (see reportFailedStatuses),
+	// and those that did not reach the state they were waited for.
+	ReportOnlyFailed = "failed"
+
+	// ReportOnlySucceeded selects the items that ReportOnlyFailed does not.
+	ReportOnlySucceeded = "succeeded"
+)
+
+// ReportOnlyHelp describes the classes of report items.
+var ReportOnlyHelp = map[string]string{
+	ReportOnlyFailed:    "Items that failed, timed out, or were skipped",
+	ReportOnlySucceeded: "Items that did not fail",
+}
+
+// reportFailedStatuses are the statuses and outcomes of report items that
+// did not succeed, e.g. nodes that timed out booting after being reimaged or
+// that were skipped because an earlier batch failed.
+var reportFailedStatuses = map[string]bool{
+	"failed":    true,
+	"error":     true,
+	"timed-out": true,
+	"skipped":   true,
+}
+
+// ReportItem is the outcome of a bulk command for one component, read from
+// the report it printed.
+type ReportItem struct {
+	Xname string
+	// Status is the status or outcome of the item (e.g. "booted" or
+	// "forced"), lowercased. It is empty if the report has none.
+	Status string
+	Failed bool
+}
+
+// ParseReportItems returns the items of the report data, in JSON or YAML, that
+// a bulk command printed, e.g. *node reimage*, *pcs power off*, or *smd
+// component wait*. The report is either an object with its items under
+// "results" or a list of items. Each item needs an "xname". An item failed if
+// it has an "error", a failing "status" or "outcome", or "reached" or "found"
+// set to false.
+func ParseReportItems(data []byte) ([]ReportItem, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal report: %w", err)
+	}
+	list, ok := doc.([]any)
+	if !ok {
+		obj, ok := doc.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("report is neither a list nor an object with results")
+		}
+		if list, ok = obj["results"].([]any); !ok {
+			return nil, fmt.Errorf("report has no list of results")
+		}
+	}
+
+	items := make([]ReportItem, 0, len(list))
+	for i, v := range list {
+		r, ok := v.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("item %d of report is not an object", i)
+		}
+		xname, _ := r["xname"].(string)
+		if xname == "" {
+			return nil, fmt.Errorf("item %d of report has no xname", i)
+		}
+		item := ReportItem{Xname: xname}
+		for _, k := range []string{"status", "outcome"} {
+			if s, ok := r[k].(string); ok && s != "" {
+				item.Status = strings.ToLower(s)
+				break
+			}
+		}
+		errMsg, _ := r["error"].(string)
+		item.Failed = errMsg != "" || reportFailedStatuses[item.Status]
+		for _, k := range []string{"reached", "found"} {
+			if b, ok := r[k].(bool); ok && !b {
+				item.Failed = true
+			}
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+// SelectReportXnames returns the xnames of the items that match any of only,
+// in order, or of all items if only is empty. Each of only is
+// ReportOnlyFailed, ReportOnlySucceeded, or a status, and is matched
+// regardless of case.
+func SelectReportXnames(items []ReportItem, only []string) []string {
+	var xnames []string
+	for _, item := range items {
+		if len(only) == 0 {
+			xnames = append(xnames, item.Xname)
+			continue
+		}
+		for _, o := range only {
+			o = strings.ToLower(o)
+			if (o == ReportOnlyFailed && item.Failed) ||
+				(o == ReportOnlySucceeded && !item.Failed) ||
+				(o == item.Status) {
+				xnames = append(xnames, item.Xname)
+				break
+			}
+		}
+	}
+
+	return xnames
+}
@@ -0,0 +1,56 @@
+package ops
+
+import (
+	"context"
+
+	"github.com/OpenCHAMI/ochami/pkg/client"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// TargetsNeedSMD returns whether resolving targets needs data from SMD, i.e.
+// whether any of them is not an xname.
+func TargetsNeedSMD(targets []smd.Target) bool {
+	for _, t := range targets {
+		if t.Kind != smd.TargetXname {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ResolveTargets resolves targets to xnames, in order and without duplicates,
+// fetching from SMD with sc only the data needed for the kinds of targets
+// passed. sc may be nil if TargetsNeedSMD(targets) is false.
+func ResolveTargets(ctx context.Context, sc *smd.SMDClient, token string, targets []smd.Target) ([]string, error) {
+	kinds := make(map[string]bool)
+	for _, t := range targets {
+		kinds[t.Kind] = true
+	}
+	var data smd.TargetData
+	if TargetsNeedSMD(targets) {
+		c, err := smdWithContext(ctx, sc)
+		if err != nil {
+			return nil, err
+		}
+		if kinds[smd.TargetNID] {
+			var comps smd.ComponentSlice
+			if err := get("components", c.GetComponentsAll, &comps); err != nil {
+				return nil, err
+			}
+			data.Components = comps.Components
+		}
+		if kinds[smd.TargetMAC] {
+			if err := get("ethernet interfaces", func() (client.HTTPEnvelope, error) { return c.GetEthernetInterfaces("", token) }, &data.EthernetInterfaces); err != nil {
+				return nil, err
+			}
+		}
+		if kinds[smd.TargetGroup] {
+			if data.Groups, err = GetGroups(ctx, c, token); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return smd.ResolveTargets(targets, data)
+}
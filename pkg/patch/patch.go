@@ -0,0 +1,412 @@
+// Package patch implements client-side application of JSON Patch (RFC 6902)
+// and JSON Merge Patch (RFC 7396) documents against JSON data.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// PatchType represents the supported patch document types.
+type PatchType string
+
+const (
+	PatchTypeJSON  PatchType = "json"
+	PatchTypeMerge PatchType = "merge"
+)
+
+var (
+	PatchTypeHelp = map[string]string{
+		string(PatchTypeJSON):  "JSON Patch (RFC 6902) list of operations",
+		string(PatchTypeMerge): "JSON Merge Patch (RFC 7396) partial document",
+	}
+)
+
+func (pt PatchType) String() string {
+	return string(pt)
+}
+
+func (pt *PatchType) Set(v string) error {
+	switch PatchType(v) {
+	case PatchTypeJSON,
+		PatchTypeMerge:
+		*pt = PatchType(v)
+		return nil
+	default:
+		return fmt.Errorf("must be one of %v", []PatchType{
+			PatchTypeJSON,
+			PatchTypeMerge,
+		})
+	}
+}
+
+func (pt PatchType) Type() string {
+	return "PatchType"
+}
+
+// Operation represents a single JSON Patch operation.
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Apply applies patch of type pt to the JSON document doc and returns the
+// resulting JSON document.
+func Apply(doc, patch []byte, pt PatchType) ([]byte, error) {
+	switch pt {
+	case PatchTypeJSON:
+		return ApplyJSONPatch(doc, patch)
+	case PatchTypeMerge:
+		return ApplyMergePatch(doc, patch)
+	default:
+		return nil, fmt.Errorf("unknown patch type: %s", pt)
+	}
+}
+
+// ApplyJSONPatch applies the JSON Patch (RFC 6902) document patch to the JSON
+// document doc and returns the result. Operations are applied in order and, if
+// any operation fails (including a failed "test"), an error is returned and no
+// result is produced.
+func ApplyJSONPatch(doc, patch []byte) ([]byte, error) {
+	var ops []Operation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal JSON patch: %w", err)
+	}
+	d, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal document: %w", err)
+	}
+	for i, op := range ops {
+		if d, err = applyOp(d, op); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s) failed: %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(d)
+}
+
+// ApplyMergePatch applies the JSON Merge Patch (RFC 7396) document patch to the
+// JSON document doc and returns the result. Keys in patch with a null value are
+// removed from doc, objects are merged recursively, and any other value
+// replaces the value in doc.
+func ApplyMergePatch(doc, patch []byte) ([]byte, error) {
+	d, err := decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal document: %w", err)
+	}
+	p, err := decode(patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal merge patch: %w", err)
+	}
+
+	return json.Marshal(mergePatch(d, p))
+}
+
+func decode(data []byte) (any, error) {
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func mergePatch(target, patch any) any {
+	p, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]any)
+	if !ok {
+		t = make(map[string]any)
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
+
+func applyOp(doc any, op Operation) (any, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		if len(op.Value) == 0 {
+			return nil, fmt.Errorf("missing value")
+		}
+		val, err := decode(op.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+		switch op.Op {
+		case "add":
+			return add(doc, path, val)
+		case "replace":
+			return replace(doc, path, val)
+		default:
+			cur, err := get(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !equal(cur, val) {
+				return nil, fmt.Errorf("test failed: value at path does not match")
+			}
+			return doc, nil
+		}
+	case "remove":
+		return remove(doc, path)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from: %w", err)
+		}
+		val, err := get(doc, from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from: %w", err)
+		}
+		if op.Op == "move" {
+			if len(path) > len(from) && reflect.DeepEqual(path[:len(from)], from) {
+				return nil, fmt.Errorf("cannot move a value into one of its children")
+			}
+			if doc, err = remove(doc, from); err != nil {
+				return nil, err
+			}
+		} else {
+			// Deep copy copied value so that later operations on
+			// one location do not affect the other.
+			b, err := json.Marshal(val)
+			if err != nil {
+				return nil, err
+			}
+			if val, err = decode(b); err != nil {
+				return nil, err
+			}
+		}
+		return add(doc, path, val)
+	default:
+		return nil, fmt.Errorf("unknown operation %q", op.Op)
+	}
+}
+
+// equal reports whether the decoded JSON values a and b are equal, comparing
+// numbers by value so that e.g. 1 and 1.0 are equal.
+func equal(a, b any) bool {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		ar, aok := new(big.Rat).SetString(av.String())
+		br, bok := new(big.Rat).SetString(bv.String())
+		if !aok || !bok {
+			return av == bv
+		}
+		return ar.Cmp(br) == 0
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			w, ok := bv[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equal(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// parsePointer splits the JSON Pointer (RFC 6901) p into its unescaped
+// reference tokens.
+func parsePointer(p string) ([]string, error) {
+	if p == "" {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(p, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must be empty or start with /", p)
+	}
+	tokens := strings.Split(p[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func arrayIndex(token string, length int, allowEnd bool) (int, error) {
+	if allowEnd && token == "-" {
+		return length, nil
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || (token != "0" && strings.HasPrefix(token, "0")) {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	max := length - 1
+	if allowEnd {
+		max = length
+	}
+	if idx > max {
+		return 0, fmt.Errorf("array index %d out of bounds", idx)
+	}
+	return idx, nil
+}
+
+func get(doc any, path []string) (any, error) {
+	cur := doc
+	for _, t := range path {
+		switch c := cur.(type) {
+		case map[string]any:
+			v, ok := c[t]
+			if !ok {
+				return nil, fmt.Errorf("path not found: key %q does not exist", t)
+			}
+			cur = v
+		case []any:
+			idx, err := arrayIndex(t, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			cur = c[idx]
+		default:
+			return nil, fmt.Errorf("path not found: cannot index into scalar with %q", t)
+		}
+	}
+	return cur, nil
+}
+
+// mutate walks doc along path and calls fn with the parent container and final
+// token of path, replacing the parent with the container fn returns.
+func mutate(doc any, path []string, fn func(parent any, token string) (any, error)) (any, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	switch c := doc.(type) {
+	case map[string]any:
+		child, ok := c[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("path not found: key %q does not exist", path[0])
+		}
+		nc, err := mutate(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		c[path[0]] = nc
+		return c, nil
+	case []any:
+		idx, err := arrayIndex(path[0], len(c), false)
+		if err != nil {
+			return nil, err
+		}
+		nc, err := mutate(c[idx], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		c[idx] = nc
+		return c, nil
+	default:
+		return nil, fmt.Errorf("path not found: cannot index into scalar with %q", path[0])
+	}
+}
+
+func add(doc any, path []string, val any) (any, error) {
+	if len(path) == 0 {
+		return val, nil
+	}
+	return mutate(doc, path, func(parent any, token string) (any, error) {
+		switch c := parent.(type) {
+		case map[string]any:
+			c[token] = val
+			return c, nil
+		case []any:
+			idx, err := arrayIndex(token, len(c), true)
+			if err != nil {
+				return nil, err
+			}
+			nc := make([]any, 0, len(c)+1)
+			nc = append(nc, c[:idx]...)
+			nc = append(nc, val)
+			return append(nc, c[idx:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot add to scalar")
+		}
+	})
+}
+
+func remove(doc any, path []string) (any, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	return mutate(doc, path, func(parent any, token string) (any, error) {
+		switch c := parent.(type) {
+		case map[string]any:
+			if _, ok := c[token]; !ok {
+				return nil, fmt.Errorf("path not found: key %q does not exist", token)
+			}
+			delete(c, token)
+			return c, nil
+		case []any:
+			idx, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			nc := make([]any, 0, len(c)-1)
+			nc = append(nc, c[:idx]...)
+			return append(nc, c[idx+1:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot remove from scalar")
+		}
+	})
+}
+
+func replace(doc any, path []string, val any) (any, error) {
+	if _, err := get(doc, path); err != nil {
+		return nil, err
+	}
+	if len(path) == 0 {
+		return val, nil
+	}
+	return mutate(doc, path, func(parent any, token string) (any, error) {
+		switch c := parent.(type) {
+		case map[string]any:
+			c[token] = val
+			return c, nil
+		case []any:
+			idx, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			c[idx] = val
+			return c, nil
+		default:
+			return nil, fmt.Errorf("cannot replace in scalar")
+		}
+	})
+}
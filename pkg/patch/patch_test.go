@@ -0,0 +1,199 @@
+package patch
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// jsonEqual compares two JSON documents semantically.
+func jsonEqual(t *testing.T, got, want []byte) bool {
+	t.Helper()
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("failed to unmarshal result %q: %v", got, err)
+	}
+	if err := json.Unmarshal(want, &w); err != nil {
+		t.Fatalf("failed to unmarshal expected %q: %v", want, err)
+	}
+	return reflect.DeepEqual(g, w)
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc := `{"label":"compute","tags":["a","b"],"members":{"ids":["x0"]},"nid":9007199254740993}`
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+		isErr bool
+	}{
+		{
+			name:  "add key",
+			patch: `[{"op":"add","path":"/description","value":"desc"}]`,
+			want:  `{"label":"compute","description":"desc","tags":["a","b"],"members":{"ids":["x0"]},"nid":9007199254740993}`,
+		},
+		{
+			name:  "add array end and index",
+			patch: `[{"op":"add","path":"/tags/-","value":"c"},{"op":"add","path":"/tags/0","value":"z"}]`,
+			want:  `{"label":"compute","tags":["z","a","b","c"],"members":{"ids":["x0"]},"nid":9007199254740993}`,
+		},
+		{
+			name:  "remove array element",
+			patch: `[{"op":"remove","path":"/tags/0"}]`,
+			want:  `{"label":"compute","tags":["b"],"members":{"ids":["x0"]},"nid":9007199254740993}`,
+		},
+		{
+			name:  "replace nested",
+			patch: `[{"op":"replace","path":"/members/ids/0","value":"x1"}]`,
+			want:  `{"label":"compute","tags":["a","b"],"members":{"ids":["x1"]},"nid":9007199254740993}`,
+		},
+		{
+			name:  "move and copy",
+			patch: `[{"op":"copy","from":"/label","path":"/description"},{"op":"move","from":"/tags","path":"/labels"}]`,
+			want:  `{"label":"compute","description":"compute","labels":["a","b"],"members":{"ids":["x0"]},"nid":9007199254740993}`,
+		},
+		{
+			name:  "test passes",
+			patch: `[{"op":"test","path":"/label","value":"compute"},{"op":"remove","path":"/nid"}]`,
+			want:  `{"label":"compute","tags":["a","b"],"members":{"ids":["x0"]}}`,
+		},
+		{
+			name:  "test compares numbers by value",
+			patch: `[{"op":"test","path":"","value":{"label":"compute","tags":["a","b"],"members":{"ids":["x0"]},"nid":9.007199254740993e15}},{"op":"remove","path":"/nid"}]`,
+			want:  `{"label":"compute","tags":["a","b"],"members":{"ids":["x0"]}}`,
+		},
+		{
+			name:  "test fails on close number",
+			patch: `[{"op":"test","path":"/nid","value":9007199254740992}]`,
+			isErr: true,
+		},
+		{
+			name:  "escaped pointer",
+			patch: `[{"op":"add","path":"/a~1b~0c","value":1}]`,
+			want:  `{"label":"compute","a/b~c":1,"tags":["a","b"],"members":{"ids":["x0"]},"nid":9007199254740993}`,
+		},
+		{
+			name:  "test fails",
+			patch: `[{"op":"test","path":"/label","value":"other"}]`,
+			isErr: true,
+		},
+		{
+			name:  "replace missing key",
+			patch: `[{"op":"replace","path":"/missing","value":1}]`,
+			isErr: true,
+		},
+		{
+			name:  "remove out of bounds",
+			patch: `[{"op":"remove","path":"/tags/5"}]`,
+			isErr: true,
+		},
+		{
+			name:  "move into child",
+			patch: `[{"op":"move","from":"/members","path":"/members/ids/foo"}]`,
+			isErr: true,
+		},
+		{
+			name:  "unknown op",
+			patch: `[{"op":"frobnicate","path":"/label"}]`,
+			isErr: true,
+		},
+		{
+			name:  "missing value",
+			patch: `[{"op":"add","path":"/x"}]`,
+			isErr: true,
+		},
+		{
+			name:  "invalid pointer",
+			patch: `[{"op":"remove","path":"label"}]`,
+			isErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyJSONPatch([]byte(doc), []byte(tt.patch))
+			if tt.isErr {
+				if err == nil {
+					t.Fatalf("expected error, got result %s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !jsonEqual(t, got, []byte(tt.want)) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyJSONPatch_PreservesLargeNumbers(t *testing.T) {
+	got, err := ApplyJSONPatch([]byte(`{"nid":9007199254740993}`), []byte(`[]`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != `{"nid":9007199254740993}` {
+		t.Errorf("got %s, want number preserved", got)
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		patch string
+		want  string
+	}{
+		{
+			name:  "replace and add",
+			doc:   `{"a":"b","c":{"d":"e"}}`,
+			patch: `{"a":"z","f":1}`,
+			want:  `{"a":"z","c":{"d":"e"},"f":1}`,
+		},
+		{
+			name:  "null removes",
+			doc:   `{"a":"b","c":{"d":"e","f":"g"}}`,
+			patch: `{"c":{"f":null}}`,
+			want:  `{"a":"b","c":{"d":"e"}}`,
+		},
+		{
+			name:  "arrays replaced",
+			doc:   `{"tags":["a","b"]}`,
+			patch: `{"tags":["c"]}`,
+			want:  `{"tags":["c"]}`,
+		},
+		{
+			name:  "non-object patch replaces",
+			doc:   `{"a":"b"}`,
+			patch: `["c"]`,
+			want:  `["c"]`,
+		},
+		{
+			name:  "object into scalar",
+			doc:   `{"a":"b"}`,
+			patch: `{"a":{"b":"c"}}`,
+			want:  `{"a":{"b":"c"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplyMergePatch([]byte(tt.doc), []byte(tt.patch))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !jsonEqual(t, got, []byte(tt.want)) {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPatchType_Set(t *testing.T) {
+	var pt PatchType
+	if err := pt.Set("merge"); err != nil || pt != PatchTypeMerge {
+		t.Errorf("Set(merge): got %q, %v", pt, err)
+	}
+	if err := pt.Set("bogus"); err == nil {
+		t.Errorf("Set(bogus): expected error")
+	}
+}
@@ -0,0 +1,111 @@
+// Package pool provides a worker pool for running an operation against many
+// targets concurrently, isolating targets from each other so that one that
+// hangs or panics does not stall or crash the rest of the batch.
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TargetTimeoutError is wrapped by the error reported for a target whose
+// operation did not finish within the per-target timeout.
+var TargetTimeoutError = errors.New("target timed out")
+
+// Run calls fn for each of the targets 0 through n-1, running up to
+// concurrency calls at once (at least one), and calls done with the result of
+// each target as it finishes. Calls to done are serialized, so done may update
+// shared state without locking.
+//
+// Each call to fn runs in its own goroutine and is passed a context that is
+// canceled once timeout has elapsed, if timeout is positive, or ctx is done.
+// If fn has not returned by then, the target is reported as failed with an
+// error wrapping TargetTimeoutError (or the error of ctx) and its worker moves
+// on to the next target; fn is left to return in the background and its result
+// is discarded. A panic in fn is recovered and reported as the target's error.
+// Run returns once every target has been reported to done.
+func Run(ctx context.Context, n, concurrency int, timeout time.Duration, fn func(ctx context.Context, i int) error, done func(i int, err error)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		work = make(chan int)
+	)
+	for w := 0; w < concurrency && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				err := runTarget(ctx, i, timeout, fn)
+				mu.Lock()
+				done(i, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+}
+
+// runTarget calls fn for target i in its own goroutine and returns its error,
+// or a timeout error if it does not return before its context is done.
+func runTarget(ctx context.Context, i int, timeout time.Duration, fn func(ctx context.Context, i int) error) error {
+	tctx, cancel := ctx, context.CancelFunc(func() {})
+	if timeout > 0 {
+		tctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	// Buffered so that an abandoned call can still send its result and exit
+	res := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				res <- fmt.Errorf("target %d panicked: %v", i, r)
+			}
+		}()
+		res <- fn(tctx, i)
+	}()
+
+	var err error
+	select {
+	case err = <-res:
+	case <-tctx.Done():
+		err = tctx.Err()
+	}
+	if err != nil && ctx.Err() == nil && errors.Is(tctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %s: %w", TargetTimeoutError, timeout, err)
+	}
+
+	return err
+}
+
+// Batches splits the targets 0 through n-1 into consecutive batches of up to
+// size targets each, returning the targets of each batch. If size is less than
+// one, all targets are in a single batch.
+func Batches(n, size int) [][]int {
+	if n <= 0 {
+		return nil
+	}
+	if size < 1 || size > n {
+		size = n
+	}
+	batches := make([][]int, 0, (n+size-1)/size)
+	for start := 0; start < n; start += size {
+		batch := make([]int, 0, size)
+		for i := start; i < start+size && i < n; i++ {
+			batch = append(batch, i)
+		}
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
@@ -0,0 +1,95 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	hang := make(chan struct{})
+	defer close(hang)
+
+	errBoom := errors.New("boom")
+	results := make(map[int]error)
+	start := time.Now()
+	Run(context.Background(), 5, 2, 100*time.Millisecond, func(ctx context.Context, i int) error {
+		switch i {
+		case 1:
+			// Ignores its context entirely, so must be abandoned
+			<-hang
+		case 2:
+			<-ctx.Done()
+			return ctx.Err()
+		case 3:
+			panic("oops")
+		case 4:
+			return errBoom
+		}
+		return nil
+	}, func(i int, err error) {
+		if _, ok := results[i]; ok {
+			t.Errorf("target %d reported twice", i)
+		}
+		results[i] = err
+	})
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Run() took %s, hung target was not isolated", elapsed)
+	}
+
+	if len(results) != 5 {
+		t.Fatalf("Run() reported %d targets, want 5", len(results))
+	}
+	if results[0] != nil {
+		t.Errorf("target 0: got error %v, want nil", results[0])
+	}
+	for _, i := range []int{1, 2} {
+		if !errors.Is(results[i], TargetTimeoutError) {
+			t.Errorf("target %d: got error %v, want TargetTimeoutError", i, results[i])
+		}
+	}
+	if results[3] == nil || errors.Is(results[3], TargetTimeoutError) {
+		t.Errorf("target 3: got error %v, want panic error", results[3])
+	}
+	if !errors.Is(results[4], errBoom) {
+		t.Errorf("target 4: got error %v, want %v", results[4], errBoom)
+	}
+}
+
+func TestRun_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var got []error
+	Run(ctx, 2, 1, time.Minute, func(ctx context.Context, i int) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, func(i int, err error) {
+		got = append(got, err)
+	})
+	for i, err := range got {
+		if !errors.Is(err, context.Canceled) || errors.Is(err, TargetTimeoutError) {
+			t.Errorf("target %d: got error %v, want context.Canceled", i, err)
+		}
+	}
+}
+
+func TestBatches(t *testing.T) {
+	tests := []struct {
+		n, size int
+		want    [][]int
+	}{
+		{n: 0, size: 2, want: nil},
+		{n: 5, size: 2, want: [][]int{{0, 1}, {2, 3}, {4}}},
+		{n: 4, size: 2, want: [][]int{{0, 1}, {2, 3}}},
+		{n: 3, size: 10, want: [][]int{{0, 1, 2}}},
+		{n: 3, size: 0, want: [][]int{{0, 1, 2}}},
+	}
+	for _, tt := range tests {
+		if got := Batches(tt.n, tt.size); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Batches(%d, %d) = %v, want %v", tt.n, tt.size, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+// Package redact pseudonymizes the infrastructure details in payloads, such as
+// hostnames, domains, MAC addresses, IP addresses, and UUIDs, so that they can
+// be shared (e.g. in bug reports) without leaking them. Each value is replaced
+// by the same pseudonym everywhere it appears, so that references between
+// records are preserved, and the structure of the payload is left as is.
+package redact
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Redacted replaces the values of credentials.
+const Redacted = "REDACTED"
+
+var (
+	macPattern   = regexp.MustCompile(`(?i)\b[0-9a-f]{2}([:-])[0-9a-f]{2}(?:[:-][0-9a-f]{2}){4}\b`)
+	ipv4Pattern  = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	uuidPattern  = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	hexMACRegex  = regexp.MustCompile(`(?i)^[0-9a-f]{12}$`)
+	xnamePattern = regexp.MustCompile(`(?i)^x\d+([a-z]+\d+)*$`)
+	labelPattern = regexp.MustCompile(`(?i)^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+	letterRegex  = regexp.MustCompile(`[A-Za-z]`)
+
+	// dottedPattern matches words that can be hostnames or FQDNs.
+	dottedPattern = regexp.MustCompile(`[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?(?:\.[A-Za-z0-9](?:[A-Za-z0-9-]*[A-Za-z0-9])?)*`)
+)
+
+// hostKeys are the keys (in lower case) whose values are hostnames or FQDNs.
+var hostKeys = map[string]bool{
+	"bmc_fqdn":       true,
+	"fqdn":           true,
+	"host":           true,
+	"hostname":       true,
+	"local-hostname": true,
+	"local_hostname": true,
+}
+
+// nodeKeys are the keys (in lower case) of objects that describe nodes or
+// BMCs, whose name is their hostname. Names in other objects (e.g. of groups
+// or users) are not hostnames.
+var nodeKeys = map[string]bool{
+	"id":    true,
+	"nid":   true,
+	"xname": true,
+}
+
+// secretKeys are the keys (in lower case) whose values are credentials, which
+// are replaced with Redacted.
+var secretKeys = map[string]bool{
+	"access-token": true,
+	"bmc-password": true,
+	"passwd":       true,
+	"password":     true,
+	"secret":       true,
+	"token":        true,
+}
+
+// Mapping is the pseudonyms of the values redacted by a Redactor, keyed by the
+// original values. Networks are the /24 networks of IPv4 addresses (e.g.
+// 172.16.0), whose host part is kept so that addresses stay in the same
+// networks, and MACs are keyed in lower case without separators. Saving the
+// mapping lets payloads redacted separately use the same pseudonyms, and lets
+// redacted values in replies be mapped back.
+type Mapping struct {
+	Hosts    map[string]string `json:"hosts" yaml:"hosts"`
+	Domains  map[string]string `json:"domains" yaml:"domains"`
+	MACs     map[string]string `json:"macs" yaml:"macs"`
+	Networks map[string]string `json:"networks" yaml:"networks"`
+	IPv6     map[string]string `json:"ipv6" yaml:"ipv6"`
+	UUIDs    map[string]string `json:"uuids" yaml:"uuids"`
+}
+
+// Redactor replaces values with pseudonyms, keeping the mapping between them.
+type Redactor struct {
+	m Mapping
+}
+
+// New returns a Redactor that starts from the pseudonyms in m, if not nil.
+func New(m *Mapping) *Redactor {
+	r := &Redactor{}
+	if m != nil {
+		r.m = *m
+	}
+	for _, mp := range []*map[string]string{&r.m.Hosts, &r.m.Domains, &r.m.MACs, &r.m.Networks, &r.m.IPv6, &r.m.UUIDs} {
+		if *mp == nil {
+			*mp = make(map[string]string)
+		}
+	}
+
+	return r
+}
+
+// Mapping returns the pseudonyms of all values redacted so far, including
+// those the Redactor was created with.
+func (r *Redactor) Mapping() Mapping {
+	return r.m
+}
+
+// Redact returns v, data unmarshalled from JSON or YAML, with its values
+// pseudonymized. Hostnames and domains are learned from the values of keys
+// such as name, hostname, and fqdn, and are then replaced wherever they appear
+// as words (e.g. in kernel parameters). Names are hostnames in objects with an
+// xname, NID, or ID, i.e. nodes and BMCs. Xnames are not hostnames, since they
+// are locations rather than names, and are kept. MAC addresses (also without
+// separators, if learned with them), IPv4 addresses, UUIDs, and strings that
+// are IPv6 addresses are replaced wherever they appear. Credentials, the values
+// of keys such as password and token, are replaced with Redacted. Map keys are
+// redacted like values, and objects are walked in the order of their keys so
+// that the same data always gets the same pseudonyms.
+func (r *Redactor) Redact(v any) any {
+	r.learn(v, "")
+	return r.walk(v, "")
+}
+
+// learn records the hostnames and domains in v, the value of key.
+func (r *Redactor) learn(v any, key string) {
+	switch t := v.(type) {
+	case map[string]any:
+		isNode := false
+		for k := range t {
+			isNode = isNode || nodeKeys[strings.ToLower(k)]
+		}
+		for _, k := range sortedKeys(t) {
+			if s, ok := t[k].(string); ok && isNode && strings.ToLower(k) == "name" {
+				r.learnHost(s)
+			}
+			r.learn(t[k], k)
+		}
+	case []any:
+		for _, e := range t {
+			r.learn(e, key)
+		}
+	case string:
+		if hostKeys[strings.ToLower(key)] {
+			r.learnHost(t)
+		}
+		// MAC addresses are learned first so that they are also
+		// replaced where they appear without separators
+		for _, mac := range macPattern.FindAllString(t, -1) {
+			r.mac(mac)
+		}
+	}
+}
+
+// learnHost records the hostname and domain of name, if it is a hostname or
+// FQDN that is not an xname or an address.
+func (r *Redactor) learnHost(name string) {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".")
+	if name == "" || xnamePattern.MatchString(name) {
+		return
+	}
+	if _, err := netip.ParseAddr(name); err == nil {
+		return
+	}
+	host, domain, _ := strings.Cut(name, ".")
+	if !labelPattern.MatchString(host) || !letterRegex.MatchString(host) || ipv4Pattern.MatchString(name) {
+		return
+	}
+	if _, ok := r.m.Hosts[host]; !ok {
+		r.m.Hosts[host] = fmt.Sprintf("host-%d", len(r.m.Hosts)+1)
+	}
+	if domain != "" {
+		if _, ok := r.m.Domains[domain]; !ok {
+			r.m.Domains[domain] = fmt.Sprintf("domain%d.example", len(r.m.Domains)+1)
+		}
+	}
+}
+
+// walk returns v, the value of key, redacted.
+func (r *Redactor) walk(v any, key string) any {
+	switch t := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for _, k := range sortedKeys(t) {
+			out[r.String(k)] = r.walk(t[k], k)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, e := range t {
+			out[i] = r.walk(e, key)
+		}
+		return out
+	case string:
+		if secretKeys[strings.ToLower(key)] && t != "" {
+			return Redacted
+		}
+		return r.String(t)
+	default:
+		return v
+	}
+}
+
+// String returns s with the values in it pseudonymized (see Redact).
+func (r *Redactor) String(s string) string {
+	if a, err := netip.ParseAddr(s); err == nil && a.Is6() && !a.Is4In6() {
+		return r.ipv6(a)
+	}
+	if p, err := netip.ParsePrefix(s); err == nil && p.Addr().Is6() {
+		return r.ipv6(p.Addr()) + fmt.Sprintf("/%d", p.Bits())
+	}
+	if hexMACRegex.MatchString(s) {
+		if mac, ok := r.m.MACs[strings.ToLower(s)]; ok {
+			if s == strings.ToUpper(s) {
+				return strings.ToUpper(mac)
+			}
+			return mac
+		}
+	}
+
+	s = uuidPattern.ReplaceAllStringFunc(s, r.uuid)
+	s = macPattern.ReplaceAllStringFunc(s, r.mac)
+	s = ipv4Pattern.ReplaceAllStringFunc(s, r.ipv4)
+	s = r.hosts(s)
+
+	return s
+}
+
+// uuid returns the pseudonym of UUID u, keeping its case.
+func (r *Redactor) uuid(u string) string {
+	key := strings.ToLower(u)
+	p, ok := r.m.UUIDs[key]
+	if !ok {
+		p = fmt.Sprintf("00000000-0000-4000-8000-%012x", len(r.m.UUIDs)+1)
+		r.m.UUIDs[key] = p
+	}
+	if u == strings.ToUpper(u) {
+		return strings.ToUpper(p)
+	}
+
+	return p
+}
+
+// mac returns the pseudonym of MAC address mac, a locally administered
+// address, keeping its separator and case.
+func (r *Redactor) mac(mac string) string {
+	sep := string(mac[2])
+	key := strings.ToLower(strings.NewReplacer(":", "", "-", "").Replace(mac))
+	p, ok := r.m.MACs[key]
+	if !ok {
+		n := len(r.m.MACs) + 1
+		p = fmt.Sprintf("0200%08x", n)
+		r.m.MACs[key] = p
+	}
+	out := strings.Join([]string{p[0:2], p[2:4], p[4:6], p[6:8], p[8:10], p[10:12]}, sep)
+	if mac == strings.ToUpper(mac) {
+		return strings.ToUpper(out)
+	}
+
+	return out
+}
+
+// ipv4 returns the pseudonym of IPv4 address ip, in 10.0.0.0/8 with the same
+// host part in the pseudonym of its /24 network. Addresses that do not
+// identify hosts, such as loopback addresses and netmasks, are kept.
+func (r *Redactor) ipv4(ip string) string {
+	a, err := netip.ParseAddr(ip)
+	if err != nil || !a.Is4() {
+		return ip
+	}
+	b := a.As4()
+	if a.IsLoopback() || a.IsUnspecified() || a.IsMulticast() || b[0] == 255 || b[0] == 0 {
+		return ip
+	}
+	network := fmt.Sprintf("%d.%d.%d", b[0], b[1], b[2])
+	p, ok := r.m.Networks[network]
+	if !ok {
+		n := len(r.m.Networks) + 1
+		p = fmt.Sprintf("10.%d.%d", n>>8&0xff, n&0xff)
+		r.m.Networks[network] = p
+	}
+
+	return fmt.Sprintf("%s.%d", p, b[3])
+}
+
+// ipv6 returns the pseudonym of IPv6 address a, in fd00::/64, keeping
+// loopback and unspecified addresses.
+func (r *Redactor) ipv6(a netip.Addr) string {
+	if a.IsLoopback() || a.IsUnspecified() {
+		return a.String()
+	}
+	key := a.String()
+	p, ok := r.m.IPv6[key]
+	if !ok {
+		p = fmt.Sprintf("fd00::%x", len(r.m.IPv6)+1)
+		r.m.IPv6[key] = p
+	}
+
+	return p
+}
+
+// hosts returns s with the learned hostnames and domains replaced where they
+// appear as words. In each dotted word, the longest suffix that is a domain is
+// replaced, as is each label before it that is a hostname.
+func (r *Redactor) hosts(s string) string {
+	if len(r.m.Hosts) == 0 && len(r.m.Domains) == 0 {
+		return s
+	}
+
+	return dottedPattern.ReplaceAllStringFunc(s, func(word string) string {
+		labels := strings.Split(word, ".")
+		end := len(labels)
+		for i := range labels {
+			if p, ok := r.m.Domains[strings.Join(labels[i:], ".")]; ok {
+				labels = append(labels[:i], p)
+				end = i
+				break
+			}
+		}
+		for i := 0; i < end; i++ {
+			if p, ok := r.m.Hosts[labels[i]]; ok {
+				labels[i] = p
+			}
+		}
+		return strings.Join(labels, ".")
+	})
+}
+
+// sortedKeys returns the keys of m in order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	return keys
+}
+
+// ReadMapping reads the mapping saved in the JSON file at path by WriteMapping.
+// If the file does not exist, nil is returned.
+func ReadMapping(path string) (*Mapping, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read mapping: %w", err)
+	}
+	var m Mapping
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mapping from %s: %w", path, err)
+	}
+
+	return &m, nil
+}
+
+// WriteMapping writes m to the JSON file at path, readable only by the user
+// since it maps pseudonyms back to the values they replace.
+func WriteMapping(path string, m Mapping) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal mapping: %w", err)
+	}
+	if err := os.WriteFile(path, append(b, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write mapping: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package redact
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	data := `{
+  "nodes": [
+    {
+      "name": "node01",
+      "nid": 1,
+      "xname": "x1000c1s7b0n0",
+      "bmc_mac": "de:ca:fc:0f:ee:01",
+      "bmc_ip": "172.16.0.101",
+      "bmc_fqdn": "node01-bmc.site.example.org",
+      "interfaces": [{"mac_addr": "DE:AD:BE:EE:EE:01", "ip_addrs": [{"network": "mgmt", "ip_addr": "172.16.1.1"}]}]
+    },
+    {
+      "name": "node02",
+      "nid": 2,
+      "xname": "x1000c1s7b1n0",
+      "bmc_mac": "de-ca-fc-0f-ee-02",
+      "bmc_ip": "172.16.0.102",
+      "interfaces": [{"mac_addr": "de:ad:be:ee:ee:02", "ip_addrs": [{"ip_addr": "fd12:3456::2"}]}]
+    }
+  ],
+  "iface": {"ID": "deadbeeeee01", "Description": "Interface 0 for node01"},
+  "params": "console=ttyS0 ip=172.16.1.1::172.16.1.254:255.255.255.0 nfsroot=node02.site.example.org:/srv",
+  "uuid": "3F2504E0-4F89-11D3-9A0C-0305E82C3301",
+  "users": [{"name": "root", "passwd": "hunter2"}],
+  "localhost": "127.0.0.1"
+}`
+	var v any
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New(nil)
+	got, err := json.Marshal(r.Redact(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := string(got)
+
+	for _, leaked := range []string{"node01", "node02", "site.example.org", "de:ca:fc", "DE:AD:BE", "deadbeeeee01", "172.16", "fd12", "3F2504E0", "hunter2"} {
+		if strings.Contains(out, leaked) {
+			t.Errorf("Redact() leaked %q: %s", leaked, out)
+		}
+	}
+	for _, kept := range []string{
+		`"xname":"x1000c1s7b0n0"`,
+		`"name":"root"`,
+		`"passwd":"REDACTED"`,
+		`"localhost":"127.0.0.1"`,
+		`"network":"mgmt"`,
+		// References are kept
+		`"bmc_fqdn":"host-1.domain1.example"`,
+		`"name":"host-2"`,
+		`"Description":"Interface 0 for host-2"`,
+		`nfsroot=host-3.domain1.example:/srv`,
+		// MACs keep their format and IPv4 addresses their host part
+		`"bmc_mac":"02-00-00-00-00-03"`,
+		`"ID":"020000000002"`,
+		`"mac_addr":"02:00:00:00:00:02"`,
+		`"bmc_ip":"10.0.1.101"`,
+		`ip=10.0.2.1::10.0.2.254:255.255.255.0`,
+		`"ip_addr":"fd00::1"`,
+		`"uuid":"00000000-0000-4000-8000-000000000001"`,
+	} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("Redact() output does not contain %s: %s", kept, out)
+		}
+	}
+
+	// A mapping saved and read back gives the same pseudonyms
+	path := filepath.Join(t.TempDir(), "mapping.json")
+	if err := WriteMapping(path, r.Mapping()); err != nil {
+		t.Fatal(err)
+	}
+	m, err := ReadMapping(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := json.Marshal(New(m).Redact(v))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != out {
+		t.Errorf("Redact() with saved mapping =\n%s\nwant\n%s", again, out)
+	}
+	if !reflect.DeepEqual(*m, r.Mapping()) {
+		t.Errorf("ReadMapping() = %+v, want %+v", *m, r.Mapping())
+	}
+	if m, err := ReadMapping(filepath.Join(t.TempDir(), "missing.json")); m != nil || err != nil {
+		t.Errorf("ReadMapping() of missing file = %v, %v, want nil, nil", m, err)
+	}
+}
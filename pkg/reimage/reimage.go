@@ -0,0 +1,242 @@
+// Package reimage implements the steps of reimaging nodes that do not talk to
+// the services: building boot parameters from an image manifest, splitting
+// nodes into waves to power-cycle, tracking their boot, and summarizing the
+// outcome for each node in a roster.
+package reimage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+	kargs "github.com/synackd/go-kargs"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/artifact"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// Ways of tracking the boot of nodes.
+const (
+	// TrackSMD tracks nodes by their SMD state: a node has booted once it
+	// is Ready after having been seen in another state since it was
+	// power-cycled.
+	TrackSMD = "smd"
+
+	// TrackBootScript tracks nodes by the BSS endpoint history: a node has
+	// booted once it fetched its boot script after it was power-cycled.
+	TrackBootScript = "boot-script"
+)
+
+// TrackMethods lists the ways of tracking the boot of nodes.
+var TrackMethods = []string{TrackSMD, TrackBootScript}
+
+// Statuses of a node in a Roster.
+const (
+	StatusParamsSet = "params-set" // Boot parameters set, not power-cycled
+	StatusRebooted  = "rebooted"   // Power-cycled, boot not tracked
+	StatusBooted    = "booted"     // Power-cycled and booted
+	StatusTimedOut  = "timed-out"  // Power-cycled but not booted in time
+	StatusFailed    = "failed"     // Setting boot parameters or power-cycling failed
+	StatusSkipped   = "skipped"    // Not power-cycled since an earlier wave failed
+)
+
+// BootParams returns the boot parameters of image img for the nodes hosts,
+// based on their current boot parameters current (which may be empty). The
+// kernel and initrd of the image replace the current ones, and the kernel
+// command line is that of the image, or the current one if the image has none.
+// If the image has a root filesystem, root= is set to a live image of it. The
+// rest of the current boot parameters (e.g. cloud-init data) is kept.
+func BootParams(img artifact.Image, current bssTypes.BootParams, hosts []string) bssTypes.BootParams {
+	bp := current
+	bp.Hosts = hosts
+	bp.Macs = nil
+	bp.Nids = nil
+	if img.Params != "" {
+		bp.Params = img.Params
+	}
+	if img.Kernel != nil {
+		bp.Kernel = img.Kernel.URI
+	}
+	if img.Initrd != nil {
+		bp.Initrd = img.Initrd.URI
+	}
+	if img.Rootfs != nil {
+		k := kargs.NewKargs([]byte(bp.Params))
+		k.SetKarg("root", "live:"+img.Rootfs.URI)
+		bp.Params = k.String()
+	}
+
+	return bp
+}
+
+// FindImage returns the image named name in m.
+func FindImage(m artifact.Manifest, name string) (artifact.Image, error) {
+	var names []string
+	for _, img := range m.Images {
+		if img.Name == name {
+			return img, nil
+		}
+		names = append(names, img.Name)
+	}
+
+	return artifact.Image{}, fmt.Errorf("image %q not in manifest (images: %s)", name, strings.Join(names, ", "))
+}
+
+// Waves splits xnames into waves of up to size nodes, in order. If size is not
+// positive, all nodes are in one wave.
+func Waves(xnames []string, size int) [][]string {
+	if len(xnames) == 0 {
+		return nil
+	}
+	if size <= 0 {
+		size = len(xnames)
+	}
+	var waves [][]string
+	for i := 0; i < len(xnames); i += size {
+		waves = append(waves, xnames[i:min(i+size, len(xnames))])
+	}
+
+	return waves
+}
+
+// Tracker tracks the boot of the nodes of a wave that was power-cycled at a
+// start time.
+type Tracker struct {
+	start   time.Time
+	left    map[string]bool // Nodes seen in a state other than Ready
+	booted  map[string]time.Time
+	pending []string
+}
+
+// NewTracker returns a Tracker of the boot of xnames, power-cycled at start.
+func NewTracker(xnames []string, start time.Time) *Tracker {
+	return &Tracker{
+		start:   start,
+		left:    make(map[string]bool),
+		booted:  make(map[string]time.Time),
+		pending: append([]string(nil), xnames...),
+	}
+}
+
+// UpdateSMD updates the tracker with the SMD components comps observed at now,
+// returning the nodes that booted since the last update. Nodes booted once they
+// are Ready after having been seen in another state.
+func (t *Tracker) UpdateSMD(comps []smd.Component, now time.Time) []string {
+	states := make(map[string]string, len(comps))
+	for _, c := range comps {
+		states[strings.ToLower(c.ID)] = c.State
+	}
+
+	return t.update(func(x string) (time.Time, bool) {
+		state, ok := states[strings.ToLower(x)]
+		if !ok {
+			return time.Time{}, false
+		}
+		if state != "Ready" {
+			t.left[x] = true
+			return time.Time{}, false
+		}
+		return now, t.left[x]
+	})
+}
+
+// UpdateBootScript updates the tracker with the BSS endpoint history history,
+// returning the nodes that booted since the last update. Nodes booted once they
+// fetched their boot script after the start of the tracker.
+func (t *Tracker) UpdateBootScript(history []bssTypes.EndpointAccess) []string {
+	fetched := make(map[string]time.Time)
+	for _, ea := range history {
+		if ea.Endpoint != bssTypes.EndpointTypeBootscript {
+			continue
+		}
+		ft := time.Unix(ea.LastEpoch, 0)
+		if last, ok := fetched[strings.ToLower(ea.Name)]; !ok || ft.After(last) {
+			fetched[strings.ToLower(ea.Name)] = ft
+		}
+	}
+
+	return t.update(func(x string) (time.Time, bool) {
+		ft, ok := fetched[strings.ToLower(x)]
+		// The endpoint history only has a resolution of seconds
+		return ft, ok && !ft.Before(t.start.Truncate(time.Second))
+	})
+}
+
+// update marks the pending nodes for which booted returns true as booted at the
+// returned time, returning them.
+func (t *Tracker) update(booted func(x string) (time.Time, bool)) []string {
+	var (
+		done    []string
+		pending []string
+	)
+	for _, x := range t.pending {
+		if at, ok := booted(x); ok {
+			t.booted[x] = at
+			done = append(done, x)
+		} else {
+			pending = append(pending, x)
+		}
+	}
+	t.pending = pending
+
+	return done
+}
+
+// Pending returns the nodes that have not booted yet.
+func (t *Tracker) Pending() []string {
+	return t.pending
+}
+
+// BootTime returns how long after the start of the tracker x booted, and
+// whether it has.
+func (t *Tracker) BootTime(x string) (time.Duration, bool) {
+	at, ok := t.booted[x]
+	if !ok {
+		return 0, false
+	}
+
+	return max(at.Sub(t.start), 0), true
+}
+
+// Result is the outcome of reimaging a node. Wave is the (1-based) wave the
+// node was power-cycled in, if it was to be.
+type Result struct {
+	Xname    string `json:"xname" yaml:"xname"`
+	Location string `json:"location,omitempty" yaml:"location,omitempty"`
+	Wave     int    `json:"wave,omitempty" yaml:"wave,omitempty"`
+	Status   string `json:"status" yaml:"status"`
+	BootTime string `json:"boot_time,omitempty" yaml:"boot_time,omitempty"`
+	Error    string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// Roster is the outcome of reimaging nodes with an image: the Result of each
+// node, sorted by xname, and the number of nodes with each status.
+type Roster struct {
+	Image   string         `json:"image" yaml:"image"`
+	Results []Result       `json:"results" yaml:"results"`
+	Counts  map[string]int `json:"counts" yaml:"counts"`
+}
+
+// NewRoster returns the roster of reimaging nodes with image from their
+// results.
+func NewRoster(image string, results []Result) Roster {
+	r := Roster{
+		Image:   image,
+		Results: append([]Result{}, results...),
+		Counts:  make(map[string]int),
+	}
+	sort.SliceStable(r.Results, func(i, j int) bool { return r.Results[i].Xname < r.Results[j].Xname })
+	for _, res := range r.Results {
+		r.Counts[res.Status]++
+	}
+
+	return r
+}
+
+// Failed returns whether any node failed to be reimaged, i.e. failed, timed
+// out, or was skipped.
+func (r Roster) Failed() bool {
+	return r.Counts[StatusFailed] > 0 || r.Counts[StatusTimedOut] > 0 || r.Counts[StatusSkipped] > 0
+}
@@ -0,0 +1,125 @@
+package reimage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/artifact"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+func TestBootParams(t *testing.T) {
+	current := bssTypes.BootParams{Params: "console=ttyS0 root=nfs:old", Kernel: "old-kernel", Initrd: "old-initrd"}
+	hosts := []string{"x1000c0s0b0n0"}
+
+	tests := []struct {
+		name string
+		img  artifact.Image
+		want bssTypes.BootParams
+	}{
+		{
+			name: "full image",
+			img: artifact.Image{
+				Name:   "compute",
+				Params: "console=ttyS0,115200 ip=dhcp",
+				Kernel: &artifact.Artifact{URI: "http://img/vmlinuz"},
+				Initrd: &artifact.Artifact{URI: "http://img/initrd"},
+				Rootfs: &artifact.Artifact{URI: "http://img/rootfs"},
+			},
+			want: bssTypes.BootParams{Hosts: hosts, Params: "console=ttyS0,115200 ip=dhcp root=live:http://img/rootfs", Kernel: "http://img/vmlinuz", Initrd: "http://img/initrd"},
+		},
+		{
+			name: "keeps current params",
+			img: artifact.Image{
+				Name:   "compute",
+				Kernel: &artifact.Artifact{URI: "http://img/vmlinuz"},
+				Rootfs: &artifact.Artifact{URI: "http://img/rootfs"},
+			},
+			want: bssTypes.BootParams{Hosts: hosts, Params: "console=ttyS0 root=live:http://img/rootfs", Kernel: "http://img/vmlinuz", Initrd: "old-initrd"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BootParams(tt.img, current, hosts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("BootParams() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindImage(t *testing.T) {
+	m := artifact.Manifest{Images: []artifact.Image{{Name: "a"}, {Name: "b"}}}
+	if img, err := FindImage(m, "b"); err != nil || img.Name != "b" {
+		t.Errorf("FindImage(b) = %+v, %v", img, err)
+	}
+	if _, err := FindImage(m, "c"); err == nil {
+		t.Error("FindImage(c) succeeded, want error")
+	}
+}
+
+func TestWaves(t *testing.T) {
+	xnames := []string{"a", "b", "c", "d", "e"}
+	if got, want := Waves(xnames, 2), [][]string{{"a", "b"}, {"c", "d"}, {"e"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Waves(2) = %v, want %v", got, want)
+	}
+	if got, want := Waves(xnames, 0), [][]string{xnames}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Waves(0) = %v, want %v", got, want)
+	}
+	if got := Waves(nil, 2); got != nil {
+		t.Errorf("Waves(nil) = %v, want nil", got)
+	}
+}
+
+func TestTracker_UpdateSMD(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	tr := NewTracker([]string{"x1", "x2"}, start)
+
+	// Nodes still Ready from before the power cycle have not booted yet
+	if got := tr.UpdateSMD([]smd.Component{{ID: "x1", State: "Ready"}, {ID: "x2", State: "Off"}}, start.Add(time.Minute)); len(got) != 0 {
+		t.Errorf("UpdateSMD() = %v, want none booted", got)
+	}
+	if got, want := tr.UpdateSMD([]smd.Component{{ID: "x1", State: "Ready"}, {ID: "x2", State: "Ready"}}, start.Add(3*time.Minute)), []string{"x2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UpdateSMD() = %v, want %v", got, want)
+	}
+	if got, want := tr.Pending(), []string{"x1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Pending() = %v, want %v", got, want)
+	}
+	if d, ok := tr.BootTime("x2"); !ok || d != 3*time.Minute {
+		t.Errorf("BootTime(x2) = %s, %t, want 3m0s, true", d, ok)
+	}
+}
+
+func TestTracker_UpdateBootScript(t *testing.T) {
+	start := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+	tr := NewTracker([]string{"x1", "x2"}, start)
+	history := []bssTypes.EndpointAccess{
+		{Name: "x1", Endpoint: bssTypes.EndpointTypeBootscript, LastEpoch: start.Add(-time.Hour).Unix()},
+		{Name: "x2", Endpoint: bssTypes.EndpointTypeBootscript, LastEpoch: start.Add(2 * time.Minute).Unix()},
+	}
+	if got, want := tr.UpdateBootScript(history), []string{"x2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UpdateBootScript() = %v, want %v", got, want)
+	}
+	if d, ok := tr.BootTime("x2"); !ok || d != 2*time.Minute {
+		t.Errorf("BootTime(x2) = %s, %t, want 2m0s, true", d, ok)
+	}
+}
+
+func TestNewRoster(t *testing.T) {
+	r := NewRoster("compute", []Result{
+		{Xname: "x2", Status: StatusBooted},
+		{Xname: "x1", Status: StatusSkipped},
+		{Xname: "x3", Status: StatusBooted},
+	})
+	if r.Results[0].Xname != "x1" {
+		t.Errorf("NewRoster() results not sorted: %+v", r.Results)
+	}
+	if want := map[string]int{StatusBooted: 2, StatusSkipped: 1}; !reflect.DeepEqual(r.Counts, want) {
+		t.Errorf("NewRoster() counts = %v, want %v", r.Counts, want)
+	}
+	if !r.Failed() {
+		t.Error("Failed() = false with a skipped node, want true")
+	}
+}
@@ -0,0 +1,314 @@
+// Package report converts the results of validation and diagnostic commands
+// into formats that CI systems can present natively, such as JUnit XML and
+// SARIF.
+package report
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ReportFormat represents a supported report format.
+type ReportFormat string
+
+const (
+	ReportFormatJUnit ReportFormat = "junit"
+	ReportFormatSARIF ReportFormat = "sarif"
+)
+
+var (
+	ReportFormatHelp = map[string]string{
+		string(ReportFormatJUnit): "JUnit XML format",
+		string(ReportFormatSARIF): "SARIF 2.1.0 JSON format",
+	}
+)
+
+func (rf ReportFormat) String() string {
+	return string(rf)
+}
+
+func (rf *ReportFormat) Set(v string) error {
+	switch ReportFormat(v) {
+	case ReportFormatJUnit,
+		ReportFormatSARIF:
+		*rf = ReportFormat(v)
+		return nil
+	default:
+		return fmt.Errorf("must be one of %v", []ReportFormat{
+			ReportFormatJUnit,
+			ReportFormatSARIF,
+		})
+	}
+}
+
+func (rf ReportFormat) Type() string {
+	return "ReportFormat"
+}
+
+// Levels of a Finding. These match the SARIF result levels.
+const (
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// Finding is a single problem found while checking a target.
+type Finding struct {
+	// Rule identifies the kind of problem, e.g. "unresolved-secret".
+	Rule    string
+	Level   string
+	Message string
+	// File and Line, if set, locate the problem in a file that was checked.
+	File string
+	Line int
+}
+
+// Result contains the findings for one checked target, e.g. a secret
+// reference or a node. A target without findings passed. Location, if set, is
+// the physical location of the target (see xname.Locations).
+type Result struct {
+	Target   string
+	Location string
+	Findings []Finding
+}
+
+// Report is the result of running one validation or diagnostic command.
+type Report struct {
+	// Name is the name of the command, e.g. "cloud-init secret check".
+	Name string
+	// ToolVersion is the version of ochami that produced the report.
+	ToolVersion string
+	Results     []Result
+}
+
+// Failed returns the number of results with at least one error finding.
+func (r Report) Failed() int {
+	var n int
+	for _, res := range r.Results {
+		for _, f := range res.Findings {
+			if f.Level == LevelError {
+				n++
+				break
+			}
+		}
+	}
+
+	return n
+}
+
+// Marshal returns r formatted as rf.
+func (r Report) Marshal(rf ReportFormat) ([]byte, error) {
+	switch rf {
+	case ReportFormatJUnit:
+		return r.JUnit()
+	case ReportFormatSARIF:
+		return r.SARIF()
+	default:
+		return nil, fmt.Errorf("unknown report format: %s", rf)
+	}
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name       string           `xml:"name,attr"`
+	ClassName  string           `xml:"classname,attr"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Failure    *junitFailure    `xml:"failure,omitempty"`
+	SystemOut  string           `xml:"system-out,omitempty"`
+}
+
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit returns r as JUnit XML, with one test suite for the report and one test
+// case per result. Results with error findings are failures and other findings
+// are included in the test case's output. The location of a result is a
+// property of its test case.
+func (r Report) JUnit() ([]byte, error) {
+	suite := junitTestSuite{Name: r.Name, Tests: len(r.Results)}
+	for _, res := range r.Results {
+		tc := junitTestCase{Name: res.Target, ClassName: r.Name}
+		if res.Location != "" {
+			tc.Properties = &junitProperties{Properties: []junitProperty{{Name: "location", Value: res.Location}}}
+		}
+		var errs, others []string
+		for _, f := range res.Findings {
+			line := fmt.Sprintf("%s: %s: %s", f.Level, f.Rule, f.Message)
+			if loc := f.location(); loc != "" {
+				line = loc + ": " + line
+			}
+			if f.Level == LevelError {
+				errs = append(errs, line)
+				if tc.Failure == nil {
+					tc.Failure = &junitFailure{Message: f.Message, Type: f.Rule}
+				}
+			} else {
+				others = append(others, line)
+			}
+		}
+		if tc.Failure != nil {
+			tc.Failure.Text = strings.Join(errs, "\n")
+			suite.Failures++
+		}
+		tc.SystemOut = strings.Join(others, "\n")
+		suite.Cases = append(suite.Cases, tc)
+	}
+	b, err := xml.MarshalIndent(junitTestSuites{Tests: suite.Tests, Failures: suite.Failures, Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JUnit report: %w", err)
+	}
+
+	return append([]byte(xml.Header), b...), nil
+}
+
+// location returns the file and line of f as "file:line", or "" if f has no
+// file.
+func (f Finding) location() string {
+	if f.File == "" {
+		return ""
+	}
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d", f.File, f.Line)
+	}
+	return f.File
+}
+
+// sarifSchema is the JSON schema of the SARIF version produced by SARIF.
+const sarifSchema = "https://json.schemastore.org/sarif-2.1.0.json"
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion          `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifLogicalLocation struct {
+	Name       string            `json:"name"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// SARIF returns r as a SARIF 2.1.0 log with one run, containing one result per
+// finding. The target of each finding is its logical location, with the
+// location of the target as a property, and its file and line, if any, are its
+// physical location.
+func (r Report) SARIF() ([]byte, error) {
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:           "ochami " + r.Name,
+			Version:        r.ToolVersion,
+			InformationURI: "https://github.com/OpenCHAMI/ochami",
+			Rules:          []sarifRule{},
+		}},
+		Results: []sarifResult{},
+	}
+	rules := make(map[string]bool)
+	for _, res := range r.Results {
+		for _, f := range res.Findings {
+			rules[f.Rule] = true
+			sr := sarifResult{RuleID: f.Rule, Level: f.Level, Message: sarifMessage{Text: f.Message}}
+			loc := sarifLocation{LogicalLocations: []sarifLogicalLocation{{Name: res.Target}}}
+			if res.Location != "" {
+				loc.LogicalLocations[0].Properties = map[string]string{"location": res.Location}
+			}
+			if f.File != "" {
+				loc.PhysicalLocation = &sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: f.File}}
+				if f.Line > 0 {
+					loc.PhysicalLocation.Region = &sarifRegion{StartLine: f.Line}
+				}
+			}
+			sr.Locations = []sarifLocation{loc}
+			run.Results = append(run.Results, sr)
+		}
+	}
+	ids := make([]string, 0, len(rules))
+	for id := range rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id})
+	}
+	b, err := json.MarshalIndent(sarifLog{Schema: sarifSchema, Version: "2.1.0", Runs: []sarifRun{run}}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal SARIF report: %w", err)
+	}
+
+	return b, nil
+}
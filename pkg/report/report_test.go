@@ -0,0 +1,152 @@
+package report
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+var testReport = Report{
+	Name:        "cloud-init secret check",
+	ToolVersion: "v0.0.0",
+	Results: []Result{
+		{Target: "env:OK"},
+		{Target: "env:MISSING", Findings: []Finding{
+			{Rule: "unresolved-secret", Level: LevelError, Message: "environment variable MISSING is not set", File: "compute.yaml", Line: 3},
+		}},
+		{Target: "file:/etc/pass", Findings: []Finding{
+			{Rule: "insecure-permissions", Level: LevelWarning, Message: "file is world-readable"},
+		}},
+	},
+}
+
+func TestReport_Failed(t *testing.T) {
+	if got := testReport.Failed(); got != 1 {
+		t.Errorf("Failed() = %d, want 1", got)
+	}
+}
+
+func TestReport_JUnit(t *testing.T) {
+	b, err := testReport.Marshal(ReportFormatJUnit)
+	if err != nil {
+		t.Fatalf("Marshal(junit) error = %v", err)
+	}
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites tests="3" failures="1">
+  <testsuite name="cloud-init secret check" tests="3" failures="1">
+    <testcase name="env:OK" classname="cloud-init secret check"></testcase>
+    <testcase name="env:MISSING" classname="cloud-init secret check">
+      <failure message="environment variable MISSING is not set" type="unresolved-secret">compute.yaml:3: error: unresolved-secret: environment variable MISSING is not set</failure>
+    </testcase>
+    <testcase name="file:/etc/pass" classname="cloud-init secret check">
+      <system-out>warning: insecure-permissions: file is world-readable</system-out>
+    </testcase>
+  </testsuite>
+</testsuites>`
+	if string(b) != want {
+		t.Errorf("Marshal(junit) =\n%s\nwant\n%s", b, want)
+	}
+}
+
+func TestReport_SARIF(t *testing.T) {
+	b, err := testReport.Marshal(ReportFormatSARIF)
+	if err != nil {
+		t.Fatalf("Marshal(sarif) error = %v", err)
+	}
+	var log struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation *struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region *struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+					LogicalLocations []struct {
+						Name string `json:"name"`
+					} `json:"logicalLocations"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(b, &log); err != nil {
+		t.Fatalf("failed to unmarshal SARIF: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 {
+		t.Fatalf("unexpected SARIF log: %s", b)
+	}
+	run := log.Runs[0]
+	if run.Tool.Driver.Name != "ochami cloud-init secret check" {
+		t.Errorf("driver name = %q", run.Tool.Driver.Name)
+	}
+	if len(run.Tool.Driver.Rules) != 2 || run.Tool.Driver.Rules[0].ID != "insecure-permissions" {
+		t.Errorf("rules = %+v, want sorted insecure-permissions, unresolved-secret", run.Tool.Driver.Rules)
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(run.Results))
+	}
+	r := run.Results[0]
+	if r.RuleID != "unresolved-secret" || r.Level != LevelError {
+		t.Errorf("result 0 = %+v", r)
+	}
+	loc := r.Locations[0]
+	if loc.PhysicalLocation == nil || loc.PhysicalLocation.ArtifactLocation.URI != "compute.yaml" || loc.PhysicalLocation.Region.StartLine != 3 {
+		t.Errorf("result 0 physical location = %+v", loc.PhysicalLocation)
+	}
+	if loc.LogicalLocations[0].Name != "env:MISSING" {
+		t.Errorf("result 0 logical location = %+v", loc.LogicalLocations)
+	}
+	if run.Results[1].Locations[0].PhysicalLocation != nil {
+		t.Errorf("result 1 has physical location without file")
+	}
+}
+
+func TestReport_Location(t *testing.T) {
+	r := Report{Name: "bss boot params lint", Results: []Result{
+		{Target: "x3000c0s30b0n0", Location: "Rack 12, U30, left node", Findings: []Finding{
+			{Rule: "missing-kernel", Level: LevelError, Message: "no kernel"},
+		}},
+	}}
+	b, err := r.JUnit()
+	if err != nil {
+		t.Fatalf("JUnit() error = %v", err)
+	}
+	if want := `<properties>
+        <property name="location" value="Rack 12, U30, left node"></property>
+      </properties>`; !strings.Contains(string(b), want) {
+		t.Errorf("JUnit() =\n%s\nwant it to contain\n%s", b, want)
+	}
+	b, err = r.SARIF()
+	if err != nil {
+		t.Fatalf("SARIF() error = %v", err)
+	}
+	if want := `"properties": {
+                    "location": "Rack 12, U30, left node"
+                  }`; !strings.Contains(string(b), want) {
+		t.Errorf("SARIF() =\n%s\nwant it to contain\n%s", b, want)
+	}
+}
+
+func TestReportFormat_Set(t *testing.T) {
+	var rf ReportFormat
+	if err := rf.Set("sarif"); err != nil || rf != ReportFormatSARIF {
+		t.Errorf("Set(sarif) = %v, %q", err, rf)
+	}
+	if err := rf.Set("tap"); err == nil || !strings.Contains(err.Error(), "junit") {
+		t.Errorf("Set(tap) error = %v, want error listing formats", err)
+	}
+}
@@ -0,0 +1,320 @@
+// Package run invokes ochami commands described by structured documents, so
+// that other programs can call ochami without building command lines, and
+// reports the outcome of each as structured data. Each operation runs as a
+// separate ochami process, since commands exit the process they run in.
+package run
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Statuses of a Result.
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Document describes operations to run in order. Flags are passed to every
+// operation, which can override them. Unless ContinueOnError is set, the
+// operations after one that fails are skipped. A document can also describe a
+// single operation with the fields of an Operation at the top level instead of
+// Operations, in which case Flags are its flags.
+type Document struct {
+	Flags           map[string]any `json:"flags,omitempty" yaml:"flags,omitempty"`
+	ContinueOnError bool           `json:"continue_on_error,omitempty" yaml:"continue_on_error,omitempty"`
+	Operations      []Operation    `json:"operations,omitempty" yaml:"operations,omitempty"`
+
+	ID      string   `json:"id,omitempty" yaml:"id,omitempty"`
+	Command string   `json:"command,omitempty" yaml:"command,omitempty"`
+	Args    []string `json:"args,omitempty" yaml:"args,omitempty"`
+	Payload any      `json:"payload,omitempty" yaml:"payload,omitempty"`
+	Stdin   string   `json:"stdin,omitempty" yaml:"stdin,omitempty"`
+}
+
+// Operation describes a single invocation of ochami. Command is the command
+// path (e.g. "smd component get") and Args its positional arguments. Each of
+// Flags is passed as --<name>=<value> (or -<name>=<value> for a one letter
+// name), a true bool as --<name>, and each element of a list as a separate
+// flag. Payload is passed as JSON on standard input with --data=@-, while
+// Stdin is passed as is, so at most one of them can be set. ID is an optional
+// label copied into the Result.
+type Operation struct {
+	ID      string         `json:"id,omitempty" yaml:"id,omitempty"`
+	Command string         `json:"command,omitempty" yaml:"command,omitempty"`
+	Args    []string       `json:"args,omitempty" yaml:"args,omitempty"`
+	Flags   map[string]any `json:"flags,omitempty" yaml:"flags,omitempty"`
+	Payload any            `json:"payload,omitempty" yaml:"payload,omitempty"`
+	Stdin   string         `json:"stdin,omitempty" yaml:"stdin,omitempty"`
+}
+
+// Result is the outcome of an Operation. Args are the arguments ochami was run
+// with, excluding the global ones of the Runner. Output is the standard output
+// of the command if it is JSON (a list if it is several JSON values), and
+// Stdout otherwise. Logs are the lines the command logged, as JSON objects
+// when they are. Error describes why the command could not be run.
+type Result struct {
+	ID             string   `json:"id,omitempty" yaml:"id,omitempty"`
+	Command        string   `json:"command" yaml:"command"`
+	Args           []string `json:"args,omitempty" yaml:"args,omitempty"`
+	Status         string   `json:"status" yaml:"status"`
+	ExitCode       int      `json:"exit_code" yaml:"exit_code"`
+	Output         any      `json:"output,omitempty" yaml:"output,omitempty"`
+	Stdout         string   `json:"stdout,omitempty" yaml:"stdout,omitempty"`
+	Logs           []any    `json:"logs,omitempty" yaml:"logs,omitempty"`
+	Error          string   `json:"error,omitempty" yaml:"error,omitempty"`
+	ElapsedSeconds float64  `json:"elapsed_seconds" yaml:"elapsed_seconds"`
+}
+
+// Report is the outcome of running a Document.
+type Report struct {
+	Results   []Result `json:"results" yaml:"results"`
+	Succeeded int      `json:"succeeded" yaml:"succeeded"`
+	Failed    int      `json:"failed" yaml:"failed"`
+	Skipped   int      `json:"skipped" yaml:"skipped"`
+}
+
+// Ops returns the operations of d, which is the single operation at its top
+// level if it has no Operations. An error is returned if it has both or
+// neither.
+func (d Document) Ops() ([]Operation, error) {
+	single := d.Command != ""
+	switch {
+	case single && len(d.Operations) > 0:
+		return nil, fmt.Errorf("document has both a top-level command and operations")
+	case single:
+		return []Operation{{ID: d.ID, Command: d.Command, Args: d.Args, Payload: d.Payload, Stdin: d.Stdin}}, nil
+	case len(d.Operations) == 0:
+		return nil, fmt.Errorf("document has no operations")
+	}
+
+	return d.Operations, nil
+}
+
+// Argv returns the arguments to run ochami with for op, with the flags in
+// defaults unless op overrides them, and what to pass on standard input.
+func (op Operation) Argv(defaults map[string]any) ([]string, []byte, error) {
+	argv := strings.Fields(op.Command)
+	if len(argv) == 0 {
+		return nil, nil, fmt.Errorf("operation has no command")
+	}
+	if argv[0] == "run" {
+		return nil, nil, fmt.Errorf("operations cannot use the run command")
+	}
+
+	flags := make(map[string]any, len(defaults)+len(op.Flags))
+	for k, v := range defaults {
+		flags[strings.TrimLeft(k, "-")] = v
+	}
+	for k, v := range op.Flags {
+		flags[strings.TrimLeft(k, "-")] = v
+	}
+	names := make([]string, 0, len(flags))
+	for k := range flags {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		args, err := flagArgs(name, flags[name])
+		if err != nil {
+			return nil, nil, err
+		}
+		argv = append(argv, args...)
+	}
+
+	var stdin []byte
+	switch {
+	case op.Payload != nil && op.Stdin != "":
+		return nil, nil, fmt.Errorf("operation has both payload and stdin")
+	case op.Payload != nil:
+		b, err := json.Marshal(op.Payload)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal payload: %w", err)
+		}
+		argv = append(argv, "--data=@-")
+		stdin = b
+	case op.Stdin != "":
+		stdin = []byte(op.Stdin)
+	}
+
+	if len(op.Args) > 0 {
+		argv = append(argv, "--")
+		argv = append(argv, op.Args...)
+	}
+
+	return argv, stdin, nil
+}
+
+// flagArgs returns the arguments that pass flag name with value v.
+func flagArgs(name string, v any) ([]string, error) {
+	if name == "" {
+		return nil, fmt.Errorf("flag with empty name")
+	}
+	flag := "--" + name
+	if len(name) == 1 {
+		flag = "-" + name
+	}
+	switch t := v.(type) {
+	case bool:
+		if t {
+			return []string{flag}, nil
+		}
+		return []string{flag + "=false"}, nil
+	case string:
+		return []string{flag + "=" + t}, nil
+	case int:
+		return []string{flag + "=" + strconv.Itoa(t)}, nil
+	case int64:
+		return []string{flag + "=" + strconv.FormatInt(t, 10)}, nil
+	case float64:
+		return []string{flag + "=" + strconv.FormatFloat(t, 'f', -1, 64)}, nil
+	case []any:
+		var args []string
+		for _, e := range t {
+			if _, ok := e.([]any); ok {
+				return nil, fmt.Errorf("flag %s: nested lists are not supported", name)
+			}
+			a, err := flagArgs(name, e)
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, a...)
+		}
+		return args, nil
+	}
+
+	return nil, fmt.Errorf("flag %s: unsupported value %v (%T)", name, v, v)
+}
+
+// ansiEscape matches the terminal escape sequences that color log lines.
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// Runner runs operations with the ochami executable at Executable, passing
+// GlobalArgs before the arguments of each.
+type Runner struct {
+	Executable string
+	GlobalArgs []string
+}
+
+// Run runs the operations of doc in order and returns the outcome of each. If
+// an operation fails, the remaining ones are skipped unless
+// doc.ContinueOnError is set.
+func (r Runner) Run(ctx context.Context, doc Document) (Report, error) {
+	ops, err := doc.Ops()
+	if err != nil {
+		return Report{}, err
+	}
+
+	var (
+		report Report
+		failed bool
+	)
+	for _, op := range ops {
+		res := Result{ID: op.ID, Command: op.Command}
+		if failed && !doc.ContinueOnError {
+			res.Status = StatusSkipped
+			report.Skipped++
+			report.Results = append(report.Results, res)
+			continue
+		}
+		res = r.run(ctx, op, doc.Flags)
+		if res.Status == StatusOK {
+			report.Succeeded++
+		} else {
+			report.Failed++
+			failed = true
+		}
+		report.Results = append(report.Results, res)
+	}
+
+	return report, nil
+}
+
+// run runs op, with the flags in defaults unless op overrides them.
+func (r Runner) run(ctx context.Context, op Operation, defaults map[string]any) Result {
+	res := Result{ID: op.ID, Command: op.Command, Status: StatusFailed, ExitCode: -1}
+	argv, stdin, err := op.Argv(defaults)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Args = argv
+
+	var stdout, stderr bytes.Buffer
+	c := exec.CommandContext(ctx, r.Executable, append(append([]string{}, r.GlobalArgs...), argv...)...)
+	c.Stdin = bytes.NewReader(stdin)
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	start := time.Now()
+	err = c.Run()
+	res.ElapsedSeconds = time.Since(start).Round(time.Millisecond).Seconds()
+	res.Output, res.Stdout = ParseOutput(stdout.Bytes())
+	res.Logs = ParseLogs(stderr.Bytes())
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		res.Status = StatusOK
+		res.ExitCode = 0
+	case errors.As(err, &exitErr):
+		res.ExitCode = exitErr.ExitCode()
+	default:
+		res.Error = err.Error()
+	}
+
+	return res
+}
+
+// ParseOutput returns the JSON value in stdout, or a list of them if there are
+// several. If stdout is not JSON, it is returned as a string instead.
+func ParseOutput(stdout []byte) (any, string) {
+	if len(bytes.TrimSpace(stdout)) == 0 {
+		return nil, ""
+	}
+	var values []any
+	dec := json.NewDecoder(bytes.NewReader(stdout))
+	for {
+		var v any
+		if err := dec.Decode(&v); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, string(stdout)
+		}
+		values = append(values, v)
+	}
+	if len(values) == 1 {
+		return values[0], ""
+	}
+
+	return values, ""
+}
+
+// ParseLogs returns the lines of stderr, each as a JSON object if it is one or
+// otherwise as a string without color escape sequences.
+func ParseLogs(stderr []byte) []any {
+	var logs []any
+	for _, line := range strings.Split(string(stderr), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err == nil {
+			logs = append(logs, entry)
+		} else {
+			logs = append(logs, ansiEscape.ReplaceAllString(line, ""))
+		}
+	}
+
+	return logs
+}
@@ -0,0 +1,130 @@
+package run
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestOperation_Argv(t *testing.T) {
+	op := Operation{
+		Command: "smd  component get",
+		Flags:   map[string]any{"xname": []any{"x1000c0s0b0n0", "x1000c0s1b0n0"}, "F": "yaml", "--no-pager": true, "verbose": float64(2), "cluster": "other"},
+	}
+	argv, stdin, err := op.Argv(map[string]any{"cluster": "default", "read-only": false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"smd", "component", "get", "-F=yaml", "--cluster=other", "--no-pager", "--read-only=false", "--verbose=2", "--xname=x1000c0s0b0n0", "--xname=x1000c0s1b0n0"}
+	if !reflect.DeepEqual(argv, want) || stdin != nil {
+		t.Errorf("Argv() = %q, %q, want %q, nil", argv, stdin, want)
+	}
+
+	op = Operation{Command: "smd group add", Payload: map[string]any{"label": "compute"}, Args: []string{"-weird"}}
+	argv, stdin, err = op.Argv(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"smd", "group", "add", "--data=@-", "--", "-weird"}; !reflect.DeepEqual(argv, want) || string(stdin) != `{"label":"compute"}` {
+		t.Errorf("Argv() = %q, %q", argv, stdin)
+	}
+
+	for _, op := range []Operation{
+		{},
+		{Command: "run"},
+		{Command: "smd group add", Payload: map[string]any{}, Stdin: "x"},
+		{Command: "smd group get", Flags: map[string]any{"name": map[string]any{"a": 1}}},
+		{Command: "smd group get", Flags: map[string]any{"name": []any{[]any{"a"}}}},
+	} {
+		if _, _, err := op.Argv(nil); err == nil {
+			t.Errorf("Argv() of %+v succeeded, want error", op)
+		}
+	}
+}
+
+func TestDocument_Ops(t *testing.T) {
+	var single Document
+	if err := yaml.Unmarshal([]byte(`{"command": "smd status", "flags": {"F": "yaml"}, "id": "s"}`), &single); err != nil {
+		t.Fatal(err)
+	}
+	ops, err := single.Ops()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Operation{{ID: "s", Command: "smd status"}}; !reflect.DeepEqual(ops, want) || single.Flags["F"] != "yaml" {
+		t.Errorf("Ops() = %+v, want %+v", ops, want)
+	}
+
+	if _, err := (Document{}).Ops(); err == nil {
+		t.Error("Ops() of empty document succeeded, want error")
+	}
+	if _, err := (Document{Command: "smd status", Operations: []Operation{{Command: "bss status"}}}).Ops(); err == nil {
+		t.Error("Ops() of document with both command and operations succeeded, want error")
+	}
+}
+
+func TestParseOutput(t *testing.T) {
+	tests := []struct {
+		stdout     string
+		wantOutput any
+		wantStdout string
+	}{
+		{stdout: ""},
+		{stdout: `{"a":1}` + "\n", wantOutput: map[string]any{"a": float64(1)}},
+		{stdout: "{\"a\":1}\n{\"a\":2}\n", wantOutput: []any{map[string]any{"a": float64(1)}, map[string]any{"a": float64(2)}}},
+		{stdout: "a: 1\n", wantStdout: "a: 1\n"},
+	}
+	for _, tt := range tests {
+		output, stdout := ParseOutput([]byte(tt.stdout))
+		if !reflect.DeepEqual(output, tt.wantOutput) || stdout != tt.wantStdout {
+			t.Errorf("ParseOutput(%q) = %v, %q, want %v, %q", tt.stdout, output, stdout, tt.wantOutput, tt.wantStdout)
+		}
+	}
+}
+
+func TestParseLogs(t *testing.T) {
+	stderr := "{\"level\":\"warn\",\"message\":\"a\"}\n\n\x1b[31mERR\x1b[0m \x1b[1mb\x1b[0m\n"
+	want := []any{map[string]any{"level": "warn", "message": "a"}, "ERR b"}
+	if logs := ParseLogs([]byte(stderr)); !reflect.DeepEqual(logs, want) {
+		t.Errorf("ParseLogs(%q) = %v, want %v", stderr, logs, want)
+	}
+}
+
+func TestRunner_Run(t *testing.T) {
+	// The shell stands in for ochami, failing for the "fail" command
+	r := Runner{Executable: "/bin/sh", GlobalArgs: []string{"-c", `
+if [ "$1" = fail ]; then echo '{"level":"error","message":"failed"}' >&2; exit 3; fi
+read -r line; echo "{\"args\":\"$*\",\"stdin\":$line}"`, "sh"}}
+	doc := Document{
+		Flags: map[string]any{"cluster": "c"},
+		Operations: []Operation{
+			{ID: "one", Command: "good", Payload: map[string]any{"x": 1}},
+			{Command: "fail"},
+			{Command: "good"},
+		},
+	}
+	report, err := r.Run(context.Background(), doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Succeeded != 1 || report.Failed != 1 || report.Skipped != 1 {
+		t.Fatalf("Run() = %+v, want 1 succeeded, 1 failed, and 1 skipped", report)
+	}
+	ok, failed, skipped := report.Results[0], report.Results[1], report.Results[2]
+	if want := map[string]any{"args": "good --cluster=c --data=@-", "stdin": map[string]any{"x": float64(1)}}; ok.Status != StatusOK || ok.ID != "one" || !reflect.DeepEqual(ok.Output, want) {
+		t.Errorf("Run() result 0 = %+v, want output %v", ok, want)
+	}
+	if want := []any{map[string]any{"level": "error", "message": "failed"}}; failed.Status != StatusFailed || failed.ExitCode != 3 || !reflect.DeepEqual(failed.Logs, want) {
+		t.Errorf("Run() result 1 = %+v, want exit code 3 and logs %v", failed, want)
+	}
+	if skipped.Status != StatusSkipped {
+		t.Errorf("Run() result 2 = %+v, want skipped", skipped)
+	}
+
+	doc.ContinueOnError = true
+	if report, _ := r.Run(context.Background(), doc); report.Succeeded != 2 || report.Failed != 1 {
+		t.Errorf("Run() with ContinueOnError = %+v, want 2 succeeded and 1 failed", report)
+	}
+}
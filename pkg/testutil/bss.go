@@ -0,0 +1,173 @@
+package testutil
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+)
+
+// FakeBSS is an in-memory fake of the BSS boot parameters API. Boot
+// parameters are stored per target (host xname, MAC address, or NID), so each
+// stored bssTypes.BootParams has exactly one of Hosts, Macs, or Nids set. Its
+// URL can be passed directly to bss.NewClient.
+type FakeBSS struct {
+	*FakeServer
+
+	mu     sync.Mutex
+	params map[string]bssTypes.BootParams
+}
+
+// NewFakeBSS starts a FakeBSS serving under /boot/v1. The server is closed when
+// the test finishes.
+func NewFakeBSS(t testing.TB) *FakeBSS {
+	t.Helper()
+	f := &FakeBSS{
+		FakeServer: NewFakeServer(t, "/boot/v1"),
+		params:     make(map[string]bssTypes.BootParams),
+	}
+
+	f.Handle("GET "+bss.BSSRelpathBootParams, f.getBootParams)
+	f.Handle("POST "+bss.BSSRelpathBootParams, f.modifyBootParams(false))
+	f.Handle("PUT "+bss.BSSRelpathBootParams, f.modifyBootParams(false))
+	f.Handle("PATCH "+bss.BSSRelpathBootParams, f.modifyBootParams(true))
+	f.Handle("DELETE "+bss.BSSRelpathBootParams, f.deleteBootParams)
+
+	return f
+}
+
+// AddBootParams stores bp for each of its hosts, MACs, and NIDs, replacing any
+// boot parameters already stored for them.
+func (f *FakeBSS) AddBootParams(bps ...bssTypes.BootParams) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, bp := range bps {
+		for key, single := range splitBootParams(bp) {
+			f.params[key] = single
+		}
+	}
+}
+
+// BootParams returns the stored boot parameters, one per target, sorted by
+// target.
+func (f *FakeBSS) BootParams() []bssTypes.BootParams {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return sortedValues(f.params)
+}
+
+func (f *FakeBSS) getBootParams(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := r.URL.Query()
+	if len(q) == 0 {
+		WriteJSON(w, http.StatusOK, sortedValues(f.params))
+		return
+	}
+	var keys []string
+	for _, x := range q["name"] {
+		keys = append(keys, bootParamsKey("host", x))
+	}
+	for _, m := range q["mac"] {
+		keys = append(keys, bootParamsKey("mac", m))
+	}
+	for _, n := range q["nid"] {
+		keys = append(keys, bootParamsKey("nid", n))
+	}
+	bps := []bssTypes.BootParams{}
+	for _, k := range keys {
+		if bp, ok := f.params[k]; ok {
+			bps = append(bps, bp)
+		}
+	}
+	if len(bps) == 0 {
+		writeProblem(w, http.StatusNotFound, "no boot parameters found for query")
+		return
+	}
+	WriteJSON(w, http.StatusOK, bps)
+}
+
+// modifyBootParams returns a handler that stores the boot parameters in the
+// request body for each of their targets. If merge is true, only the
+// non-empty kernel, initrd, and params of the request are applied to existing
+// boot parameters.
+func (f *FakeBSS) modifyBootParams(merge bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var bp bssTypes.BootParams
+		if !decodeBody(w, r, &bp) {
+			return
+		}
+		targets := splitBootParams(bp)
+		if len(targets) == 0 {
+			writeProblem(w, http.StatusBadRequest, "no hosts, macs, or nids specified")
+			return
+		}
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		for key, single := range targets {
+			if cur, ok := f.params[key]; ok && merge {
+				if single.Kernel == "" {
+					single.Kernel = cur.Kernel
+				}
+				if single.Initrd == "" {
+					single.Initrd = cur.Initrd
+				}
+				if single.Params == "" {
+					single.Params = cur.Params
+				}
+			}
+			f.params[key] = single
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (f *FakeBSS) deleteBootParams(w http.ResponseWriter, r *http.Request) {
+	var bp bssTypes.BootParams
+	if !decodeBody(w, r, &bp) {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for key := range splitBootParams(bp) {
+		delete(f.params, key)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// splitBootParams returns a copy of bp for each of its targets, keyed by
+// bootParamsKey.
+func splitBootParams(bp bssTypes.BootParams) map[string]bssTypes.BootParams {
+	out := make(map[string]bssTypes.BootParams)
+	base := bssTypes.BootParams{
+		Kernel:    bp.Kernel,
+		Initrd:    bp.Initrd,
+		Params:    bp.Params,
+		CloudInit: bp.CloudInit,
+	}
+	for _, x := range bp.Hosts {
+		single := base
+		single.Hosts = []string{x}
+		out[bootParamsKey("host", x)] = single
+	}
+	for _, m := range bp.Macs {
+		single := base
+		single.Macs = []string{m}
+		out[bootParamsKey("mac", m)] = single
+	}
+	for _, n := range bp.Nids {
+		single := base
+		single.Nids = []int32{n}
+		out[bootParamsKey("nid", strconv.Itoa(int(n)))] = single
+	}
+	return out
+}
+
+func bootParamsKey(kind, target string) string {
+	return fmt.Sprintf("%s:%s", kind, target)
+}
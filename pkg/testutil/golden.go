@@ -0,0 +1,118 @@
+// Package testutil provides helpers for writing tests against ochami behavior:
+// golden-file comparison of payloads, HTTP request matchers, and fake SMD and
+// BSS servers that can be used with the ochami clients.
+//
+// It is meant to be usable both by ochami's own tests and by external
+// integrators testing code that uses ochami packages.
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// UpdateGoldenEnv is the environment variable that, when set to a non-empty
+// value, causes AssertGolden and AssertGoldenJSON to (re)write golden files
+// with the actual output instead of comparing against them.
+const UpdateGoldenEnv = "OCHAMI_UPDATE_GOLDEN"
+
+// ScrubbedValue is the value that AssertGoldenJSON replaces the values of
+// scrubbed keys with.
+const ScrubbedValue = "<scrubbed>"
+
+// GoldenPath returns the path to the golden file called name, which is
+// testdata/<name>.golden relative to the package being tested.
+func GoldenPath(name string) string {
+	return filepath.Join("testdata", name+".golden")
+}
+
+// AssertGolden compares got against the contents of the golden file called
+// name (see GoldenPath). If UpdateGoldenEnv is set, the golden file is written
+// with got instead. The test fails if the golden file cannot be read or if its
+// contents do not match got.
+func AssertGolden(t testing.TB, name string, got []byte) {
+	t.Helper()
+	path := GoldenPath(name)
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("failed to update golden file %s: %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file %s (set %s=1 to create it): %v", path, UpdateGoldenEnv, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match golden file %s (set %s=1 to update it):\n--- got:\n%s\n--- want:\n%s", path, UpdateGoldenEnv, got, want)
+	}
+}
+
+// AssertGoldenJSON marshals v as indented JSON and compares it against the
+// golden file called name using AssertGolden. Before comparing, the values of
+// any object keys (at any depth) named in scrub are replaced with
+// ScrubbedValue. This is useful for fields that are not deterministic, such as
+// randomly-generated UUIDs.
+func AssertGoldenJSON(t testing.TB, name string, v any, scrub ...string) {
+	t.Helper()
+	got, err := MarshalGoldenJSON(v, scrub...)
+	if err != nil {
+		t.Fatalf("failed to marshal value for golden comparison: %v", err)
+	}
+	AssertGolden(t, name, got)
+}
+
+// MarshalGoldenJSON marshals v into indented JSON with a trailing newline,
+// replacing the values of any keys named in scrub with ScrubbedValue.
+func MarshalGoldenJSON(v any, scrub ...string) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		return nil, err
+	}
+	if len(scrub) > 0 {
+		keys := make(map[string]bool, len(scrub))
+		for _, k := range scrub {
+			keys[k] = true
+		}
+		generic = scrubKeys(generic, keys)
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(generic); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func scrubKeys(v any, keys map[string]bool) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			if keys[k] {
+				val[k] = ScrubbedValue
+			} else {
+				val[k] = scrubKeys(child, keys)
+			}
+		}
+		return val
+	case []any:
+		for i, child := range val {
+			val[i] = scrubKeys(child, keys)
+		}
+		return val
+	default:
+		return v
+	}
+}
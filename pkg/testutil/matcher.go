@@ -0,0 +1,75 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+)
+
+// RecordedRequest is an HTTP request received by a FakeServer, with its body
+// already read.
+type RecordedRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// RequestMatcher describes the expected properties of an HTTP request. Empty
+// fields are not checked.
+type RequestMatcher struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header map[string]string
+	// JSON, if not nil, is compared semantically against the request
+	// body after both are unmarshalled into generic values.
+	JSON any
+}
+
+// Match returns nil if r matches rm. Otherwise, an error describing the first
+// mismatch is returned.
+func (rm RequestMatcher) Match(r RecordedRequest) error {
+	if rm.Method != "" && r.Method != rm.Method {
+		return fmt.Errorf("method = %s, want %s", r.Method, rm.Method)
+	}
+	if rm.Path != "" && r.Path != rm.Path {
+		return fmt.Errorf("path = %s, want %s", r.Path, rm.Path)
+	}
+	for k, want := range rm.Query {
+		if got := r.Query[k]; !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("query %s = %v, want %v", k, got, want)
+		}
+	}
+	for k, want := range rm.Header {
+		if got := r.Header.Get(k); got != want {
+			return fmt.Errorf("header %s = %q, want %q", k, got, want)
+		}
+	}
+	if rm.JSON != nil {
+		wantBytes, err := json.Marshal(rm.JSON)
+		if err != nil {
+			return fmt.Errorf("failed to marshal expected JSON: %w", err)
+		}
+		var got, want any
+		if err := json.Unmarshal(r.Body, &got); err != nil {
+			return fmt.Errorf("request body is not JSON: %w", err)
+		}
+		if err := json.Unmarshal(wantBytes, &want); err != nil {
+			return fmt.Errorf("failed to unmarshal expected JSON: %w", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			return fmt.Errorf("body = %s, want %s", bytes.TrimSpace(r.Body), wantBytes)
+		}
+	}
+	return nil
+}
+
+// String returns a short description of rm for use in test failure messages.
+func (rm RequestMatcher) String() string {
+	return fmt.Sprintf("%s %s", rm.Method, rm.Path)
+}
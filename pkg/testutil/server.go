@@ -0,0 +1,115 @@
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// FakeServer is an httptest.Server that records every request it receives and
+// dispatches them to handlers registered with Handle or HandleJSON. Requests
+// that do not match any handler receive a 404.
+type FakeServer struct {
+	*httptest.Server
+	BasePath string
+
+	mux      *http.ServeMux
+	mu       sync.Mutex
+	requests []RecordedRequest
+}
+
+// NewFakeServer starts a FakeServer whose handlers are mounted under basePath
+// (e.g. "/hsm/v2"), which may be empty. The server is closed when the test
+// finishes.
+func NewFakeServer(t testing.TB, basePath string) *FakeServer {
+	t.Helper()
+	fs := &FakeServer{
+		BasePath: strings.TrimSuffix(basePath, "/"),
+		mux:      http.NewServeMux(),
+	}
+	fs.Server = httptest.NewServer(http.HandlerFunc(fs.serveHTTP))
+	t.Cleanup(fs.Close)
+	return fs
+}
+
+// URL returns the base URI of the server including BasePath, suitable for
+// passing to an ochami client constructor.
+func (fs *FakeServer) URL() string {
+	return fs.Server.URL + fs.BasePath
+}
+
+// Handle registers handler for pattern, which uses the http.ServeMux pattern
+// syntax (e.g. "GET /State/Components/{xname}") relative to BasePath.
+func (fs *FakeServer) Handle(pattern string, handler http.HandlerFunc) {
+	method, path, found := strings.Cut(pattern, " ")
+	if found {
+		pattern = method + " " + fs.BasePath + path
+	} else {
+		pattern = fs.BasePath + pattern
+	}
+	fs.mux.HandleFunc(pattern, handler)
+}
+
+// HandleJSON registers a handler for pattern that responds with status and v
+// marshalled as JSON.
+func (fs *FakeServer) HandleJSON(pattern string, status int, v any) {
+	fs.Handle(pattern, func(w http.ResponseWriter, r *http.Request) {
+		WriteJSON(w, status, v)
+	})
+}
+
+// Requests returns a copy of the requests received so far, in order.
+func (fs *FakeServer) Requests() []RecordedRequest {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return append([]RecordedRequest(nil), fs.requests...)
+}
+
+// AssertRequests fails the test unless the requests received so far match
+// matchers exactly, in order. Paths in matchers are relative to BasePath.
+func (fs *FakeServer) AssertRequests(t testing.TB, matchers ...RequestMatcher) {
+	t.Helper()
+	reqs := fs.Requests()
+	if len(reqs) != len(matchers) {
+		var got []string
+		for _, r := range reqs {
+			got = append(got, r.Method+" "+r.Path)
+		}
+		t.Fatalf("got %d requests %v, want %d", len(reqs), got, len(matchers))
+	}
+	for i, m := range matchers {
+		if err := m.Match(reqs[i]); err != nil {
+			t.Errorf("request %d (%s): %v", i, m, err)
+		}
+	}
+}
+
+func (fs *FakeServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body.Close()
+	fs.mu.Lock()
+	fs.requests = append(fs.requests, RecordedRequest{
+		Method: r.Method,
+		Path:   strings.TrimPrefix(r.URL.Path, fs.BasePath),
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	fs.mu.Unlock()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	fs.mux.ServeHTTP(w, r)
+}
+
+// WriteJSON writes v marshalled as JSON to w with status.
+func WriteJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if v != nil {
+		_ = json.NewEncoder(w).Encode(v)
+	}
+}
@@ -0,0 +1,458 @@
+package testutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+// FakeSMD is an in-memory fake of the subset of the SMD API used by ochami:
+// components, redfish endpoints, ethernet interfaces, and groups (including
+// membership). Its URL can be passed directly to smd.NewClient.
+type FakeSMD struct {
+	*FakeServer
+
+	mu         sync.Mutex
+	components map[string]smd.Component
+	rfes       map[string]json.RawMessage
+	ifaces     map[string]smd.EthernetInterface
+	groups     map[string]smd.Group
+}
+
+// NewFakeSMD starts a FakeSMD serving under /hsm/v2. The server is closed when
+// the test finishes.
+func NewFakeSMD(t testing.TB) *FakeSMD {
+	t.Helper()
+	f := &FakeSMD{
+		FakeServer: NewFakeServer(t, "/hsm/v2"),
+		components: make(map[string]smd.Component),
+		rfes:       make(map[string]json.RawMessage),
+		ifaces:     make(map[string]smd.EthernetInterface),
+		groups:     make(map[string]smd.Group),
+	}
+
+	// Components
+	f.Handle("GET "+smd.SMDRelpathComponents, f.getComponents)
+	f.Handle("POST "+smd.SMDRelpathComponents, f.postComponents)
+	f.Handle("DELETE "+smd.SMDRelpathComponents, f.deleteAll(func() { clear(f.components) }))
+	f.Handle("GET "+smd.SMDRelpathComponents+"/{xname}", f.getComponent)
+	f.Handle("PUT "+smd.SMDRelpathComponents+"/{xname}", f.putComponent)
+	f.Handle("DELETE "+smd.SMDRelpathComponents+"/{xname}", f.deleteOne(func(id string) bool { return deleteKey(f.components, id) }, "xname"))
+
+	// Redfish endpoints
+	f.Handle("GET "+smd.SMDRelpathRedfishEndpoints, f.getRedfishEndpoints)
+	f.Handle("POST "+smd.SMDRelpathRedfishEndpoints, f.putRedfishEndpoint)
+	f.Handle("DELETE "+smd.SMDRelpathRedfishEndpoints, f.deleteAll(func() { clear(f.rfes) }))
+	f.Handle("PUT "+smd.SMDRelpathRedfishEndpoints+"/{xname}", f.putRedfishEndpoint)
+	f.Handle("DELETE "+smd.SMDRelpathRedfishEndpoints+"/{xname}", f.deleteOne(func(id string) bool { return deleteKey(f.rfes, id) }, "xname"))
+
+	// Ethernet interfaces
+	f.Handle("GET "+smd.SMDRelpathEthernetInterfaces, f.getEthernetInterfaces)
+	f.Handle("POST "+smd.SMDRelpathEthernetInterfaces, f.postEthernetInterface)
+	f.Handle("DELETE "+smd.SMDRelpathEthernetInterfaces, f.deleteAll(func() { clear(f.ifaces) }))
+	f.Handle("DELETE "+smd.SMDRelpathEthernetInterfaces+"/{id}", f.deleteOne(func(id string) bool { return deleteKey(f.ifaces, id) }, "id"))
+
+	// Groups
+	f.Handle("GET "+smd.SMDRelpathGroups, f.getGroups)
+	f.Handle("POST "+smd.SMDRelpathGroups, f.postGroup)
+	f.Handle("GET "+smd.SMDRelpathGroups+"/{label}", f.getGroup)
+	f.Handle("PATCH "+smd.SMDRelpathGroups+"/{label}", f.patchGroup)
+	f.Handle("DELETE "+smd.SMDRelpathGroups+"/{label}", f.deleteOne(func(id string) bool { return deleteKey(f.groups, id) }, "label"))
+	f.Handle("GET "+smd.SMDRelpathGroups+"/{label}/members", f.getGroupMembers)
+	f.Handle("POST "+smd.SMDRelpathGroups+"/{label}/members", f.postGroupMember)
+	f.Handle("PUT "+smd.SMDRelpathGroups+"/{label}/members", f.putGroupMembers)
+	f.Handle("DELETE "+smd.SMDRelpathGroups+"/{label}/members/{id}", f.deleteGroupMember)
+
+	return f
+}
+
+// AddComponents adds (or replaces) components in the fake's store.
+func (f *FakeSMD) AddComponents(comps ...smd.Component) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, c := range comps {
+		f.components[c.ID] = c
+	}
+}
+
+// AddEthernetInterfaces adds (or replaces) ethernet interfaces in the fake's
+// store.
+func (f *FakeSMD) AddEthernetInterfaces(eis ...smd.EthernetInterface) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, ei := range eis {
+		f.ifaces[ei.ID] = ei
+	}
+}
+
+// AddGroups adds (or replaces) groups in the fake's store.
+func (f *FakeSMD) AddGroups(groups ...smd.Group) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, g := range groups {
+		f.groups[g.Label] = g
+	}
+}
+
+// Components returns the components in the fake's store, sorted by ID.
+func (f *FakeSMD) Components() []smd.Component {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return sortedValues(f.components)
+}
+
+// RedfishEndpoints returns the raw JSON of the redfish endpoints in the fake's
+// store, keyed by ID.
+func (f *FakeSMD) RedfishEndpoints() map[string]json.RawMessage {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]json.RawMessage, len(f.rfes))
+	for k, v := range f.rfes {
+		out[k] = v
+	}
+	return out
+}
+
+// EthernetInterfaces returns the ethernet interfaces in the fake's store,
+// sorted by ID.
+func (f *FakeSMD) EthernetInterfaces() []smd.EthernetInterface {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return sortedValues(f.ifaces)
+}
+
+// Groups returns the groups in the fake's store, sorted by label.
+func (f *FakeSMD) Groups() []smd.Group {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return sortedValues(f.groups)
+}
+
+func (f *FakeSMD) getComponents(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := r.URL.Query()
+	cs := smd.ComponentSlice{Components: []smd.Component{}}
+	for _, c := range sortedValues(f.components) {
+		if matchQuery(q, "id", c.ID) && matchQuery(q, "type", c.Type) && matchQuery(q, "role", c.Role) {
+			cs.Components = append(cs.Components, c)
+		}
+	}
+	WriteJSON(w, http.StatusOK, cs)
+}
+
+func (f *FakeSMD) getComponent(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.components[r.PathValue("xname")]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "no such xname")
+		return
+	}
+	WriteJSON(w, http.StatusOK, c)
+}
+
+func (f *FakeSMD) postComponents(w http.ResponseWriter, r *http.Request) {
+	var cs smd.ComponentSlice
+	if !decodeBody(w, r, &cs) {
+		return
+	}
+	f.AddComponents(cs.Components...)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *FakeSMD) putComponent(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Component smd.Component `json:"Component"`
+	}
+	if !decodeBody(w, r, &body) {
+		return
+	}
+	body.Component.ID = r.PathValue("xname")
+	f.AddComponents(body.Component)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *FakeSMD) getRedfishEndpoints(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := r.URL.Query()
+	ids := make([]string, 0, len(f.rfes))
+	for id := range f.rfes {
+		if matchQuery(q, "id", id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	rfes := make([]json.RawMessage, 0, len(ids))
+	for _, id := range ids {
+		rfes = append(rfes, f.rfes[id])
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"RedfishEndpoints": rfes})
+}
+
+func (f *FakeSMD) putRedfishEndpoint(w http.ResponseWriter, r *http.Request) {
+	var raw json.RawMessage
+	if !decodeBody(w, r, &raw) {
+		return
+	}
+	var idOnly struct {
+		ID string `json:"ID"`
+	}
+	_ = json.Unmarshal(raw, &idOnly)
+	if x := r.PathValue("xname"); x != "" {
+		idOnly.ID = x
+	}
+	if idOnly.ID == "" {
+		writeProblem(w, http.StatusBadRequest, "redfish endpoint has no ID")
+		return
+	}
+	f.mu.Lock()
+	f.rfes[idOnly.ID] = raw
+	f.mu.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *FakeSMD) getEthernetInterfaces(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := r.URL.Query()
+	eis := []smd.EthernetInterface{}
+	for _, ei := range sortedValues(f.ifaces) {
+		if matchQuery(q, "ComponentID", ei.ComponentID) && matchQuery(q, "MACAddress", ei.MACAddress) {
+			eis = append(eis, ei)
+		}
+	}
+	WriteJSON(w, http.StatusOK, eis)
+}
+
+func (f *FakeSMD) postEthernetInterface(w http.ResponseWriter, r *http.Request) {
+	var ei smd.EthernetInterface
+	if !decodeBody(w, r, &ei) {
+		return
+	}
+	if ei.ID == "" {
+		ei.ID = strings.ToLower(strings.ReplaceAll(ei.MACAddress, ":", ""))
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, exists := f.ifaces[ei.ID]; exists {
+		writeProblem(w, http.StatusConflict, "ethernet interface already exists")
+		return
+	}
+	f.ifaces[ei.ID] = ei
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (f *FakeSMD) getGroups(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	q := r.URL.Query()
+	groups := []smd.Group{}
+	for _, g := range sortedValues(f.groups) {
+		if matchQuery(q, "group", g.Label) {
+			groups = append(groups, g)
+		}
+	}
+	WriteJSON(w, http.StatusOK, groups)
+}
+
+func (f *FakeSMD) getGroup(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	g, ok := f.groups[r.PathValue("label")]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "no such group")
+		return
+	}
+	WriteJSON(w, http.StatusOK, g)
+}
+
+func (f *FakeSMD) postGroup(w http.ResponseWriter, r *http.Request) {
+	var g smd.Group
+	if !decodeBody(w, r, &g) {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, exists := f.groups[g.Label]; exists {
+		writeProblem(w, http.StatusConflict, "group already exists")
+		return
+	}
+	f.groups[g.Label] = g
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (f *FakeSMD) patchGroup(w http.ResponseWriter, r *http.Request) {
+	var g smd.Group
+	if !decodeBody(w, r, &g) {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	cur, ok := f.groups[r.PathValue("label")]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "no such group")
+		return
+	}
+	if g.Description != "" {
+		cur.Description = g.Description
+	}
+	if g.Tags != nil {
+		cur.Tags = g.Tags
+	}
+	f.groups[cur.Label] = cur
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *FakeSMD) getGroupMembers(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	g, ok := f.groups[r.PathValue("label")]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "no such group")
+		return
+	}
+	ids := g.Members.IDs
+	if ids == nil {
+		ids = []string{}
+	}
+	WriteJSON(w, http.StatusOK, map[string]any{"ids": ids})
+}
+
+func (f *FakeSMD) postGroupMember(w http.ResponseWriter, r *http.Request) {
+	var m struct {
+		ID string `json:"id"`
+	}
+	if !decodeBody(w, r, &m) {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	g, ok := f.groups[r.PathValue("label")]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "no such group")
+		return
+	}
+	if slices.Contains(g.Members.IDs, m.ID) {
+		writeProblem(w, http.StatusConflict, "member already in group")
+		return
+	}
+	g.Members.IDs = append(g.Members.IDs, m.ID)
+	f.groups[g.Label] = g
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (f *FakeSMD) putGroupMembers(w http.ResponseWriter, r *http.Request) {
+	var gm smd.GroupMembers
+	if !decodeBody(w, r, &gm) {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	g, ok := f.groups[r.PathValue("label")]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "no such group")
+		return
+	}
+	g.Members.IDs = gm.IDs
+	f.groups[g.Label] = g
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *FakeSMD) deleteGroupMember(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	g, ok := f.groups[r.PathValue("label")]
+	if !ok {
+		writeProblem(w, http.StatusNotFound, "no such group")
+		return
+	}
+	idx := slices.Index(g.Members.IDs, r.PathValue("id"))
+	if idx < 0 {
+		writeProblem(w, http.StatusNotFound, "no such member")
+		return
+	}
+	g.Members.IDs = slices.Delete(g.Members.IDs, idx, idx+1)
+	f.groups[g.Label] = g
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *FakeSMD) deleteAll(clearFn func()) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		clearFn()
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (f *FakeSMD) deleteOne(deleteFn func(string) bool, pathKey string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		found := deleteFn(r.PathValue(pathKey))
+		f.mu.Unlock()
+		if !found {
+			writeProblem(w, http.StatusNotFound, "not found")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// matchQuery returns true if q has no values for key or if one of them is
+// equal to value.
+func matchQuery(q map[string][]string, key, value string) bool {
+	vals, ok := q[key]
+	return !ok || slices.Contains(vals, value)
+}
+
+func deleteKey[V any](m map[string]V, key string) bool {
+	if _, ok := m[key]; !ok {
+		return false
+	}
+	delete(m, key)
+	return true
+}
+
+func sortedValues[V any](m map[string]V) []V {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	vals := make([]V, 0, len(keys))
+	for _, k := range keys {
+		vals = append(vals, m[k])
+	}
+	return vals
+}
+
+// decodeBody unmarshals the JSON request body into v. If this fails, a 400 is
+// written and false is returned.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeProblem(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// writeProblem writes an RFC 7807 problem response like the OpenCHAMI
+// services do.
+func writeProblem(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"type":   "about:blank",
+		"title":  http.StatusText(status),
+		"detail": detail,
+		"status": status,
+	})
+}
@@ -0,0 +1,217 @@
+package testutil
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/OpenCHAMI/bss/pkg/bssTypes"
+
+	"github.com/OpenCHAMI/ochami/pkg/client/bss"
+	"github.com/OpenCHAMI/ochami/pkg/client/smd"
+)
+
+func TestMarshalGoldenJSON(t *testing.T) {
+	v := map[string]any{
+		"name": "n1",
+		"UUID": "random",
+		"nested": []any{
+			map[string]any{"UUID": "random", "keep": 1},
+		},
+	}
+	got, err := MarshalGoldenJSON(v, "UUID")
+	if err != nil {
+		t.Fatalf("MarshalGoldenJSON returned error: %v", err)
+	}
+	want := `{
+  "UUID": "<scrubbed>",
+  "name": "n1",
+  "nested": [
+    {
+      "UUID": "<scrubbed>",
+      "keep": 1
+    }
+  ]
+}
+`
+	if string(got) != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestAssertGolden_Update(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	t.Setenv(UpdateGoldenEnv, "1")
+	AssertGolden(t, "update", []byte("data\n"))
+	got, err := os.ReadFile(filepath.Join(dir, "testdata", "update.golden"))
+	if err != nil {
+		t.Fatalf("golden file was not written: %v", err)
+	}
+	if string(got) != "data\n" {
+		t.Errorf("golden file contents = %q, want %q", got, "data\n")
+	}
+
+	t.Setenv(UpdateGoldenEnv, "")
+	AssertGolden(t, "update", []byte("data\n"))
+}
+
+func TestRequestMatcher_Match(t *testing.T) {
+	req := RecordedRequest{
+		Method: http.MethodPost,
+		Path:   "/groups",
+		Query:  url.Values{"a": {"1"}},
+		Header: http.Header{"Authorization": {"Bearer tok"}},
+		Body:   []byte(`{"label":"compute","tags":["a"]}`),
+	}
+	tests := []struct {
+		name    string
+		matcher RequestMatcher
+		wantErr bool
+	}{
+		{name: "empty matcher", matcher: RequestMatcher{}},
+		{
+			name: "all fields",
+			matcher: RequestMatcher{
+				Method: http.MethodPost,
+				Path:   "/groups",
+				Query:  url.Values{"a": {"1"}},
+				Header: map[string]string{"Authorization": "Bearer tok"},
+				JSON:   map[string]any{"tags": []string{"a"}, "label": "compute"},
+			},
+		},
+		{name: "wrong method", matcher: RequestMatcher{Method: http.MethodGet}, wantErr: true},
+		{name: "wrong path", matcher: RequestMatcher{Path: "/other"}, wantErr: true},
+		{name: "wrong query", matcher: RequestMatcher{Query: url.Values{"a": {"2"}}}, wantErr: true},
+		{name: "wrong header", matcher: RequestMatcher{Header: map[string]string{"Authorization": "x"}}, wantErr: true},
+		{name: "wrong body", matcher: RequestMatcher{JSON: map[string]any{"label": "io"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.matcher.Match(req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Match() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFakeSMD(t *testing.T) {
+	f := NewFakeSMD(t)
+	sc, err := smd.NewClient(f.URL(), false)
+	if err != nil {
+		t.Fatalf("failed to create SMD client: %v", err)
+	}
+
+	comps := smd.ComponentSlice{Components: []smd.Component{
+		{ID: "x1000c0s1b0n0", Type: "Node", NID: 2},
+		{ID: "x1000c0s0b0n0", Type: "Node", NID: 1},
+	}}
+	if _, err := sc.PostComponents(comps, "tok"); err != nil {
+		t.Fatalf("PostComponents returned error: %v", err)
+	}
+	var g smd.Group
+	g.Label = "compute"
+	if _, errs, err := sc.PostGroups([]smd.Group{g}, "tok"); err != nil || errs[0] != nil {
+		t.Fatalf("PostGroups returned error: %v %v", err, errs)
+	}
+	if _, errs, err := sc.PostGroupMembers("tok", "compute", "x1000c0s0b0n0"); err != nil || errs[0] != nil {
+		t.Fatalf("PostGroupMembers returned error: %v %v", err, errs)
+	}
+
+	henv, err := sc.GetComponentsAll()
+	if err != nil {
+		t.Fatalf("GetComponentsAll returned error: %v", err)
+	}
+	var gotComps smd.ComponentSlice
+	if err := json.Unmarshal(henv.Body, &gotComps); err != nil {
+		t.Fatalf("failed to unmarshal components: %v", err)
+	}
+	if len(gotComps.Components) != 2 || gotComps.Components[0].ID != "x1000c0s0b0n0" {
+		t.Errorf("components = %+v", gotComps.Components)
+	}
+
+	henv, err = sc.GetGroupMembers("compute", "tok")
+	if err != nil {
+		t.Fatalf("GetGroupMembers returned error: %v", err)
+	}
+	var members struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.Unmarshal(henv.Body, &members); err != nil {
+		t.Fatalf("failed to unmarshal members: %v", err)
+	}
+	if !reflect.DeepEqual(members.IDs, []string{"x1000c0s0b0n0"}) {
+		t.Errorf("members = %v", members.IDs)
+	}
+
+	if _, err := sc.GetGroupMembers("nonexistent", "tok"); err == nil {
+		t.Error("expected error getting members of nonexistent group")
+	}
+
+	f.AssertRequests(t,
+		RequestMatcher{Method: http.MethodPost, Path: smd.SMDRelpathComponents, JSON: comps},
+		RequestMatcher{Method: http.MethodPost, Path: smd.SMDRelpathGroups, Header: map[string]string{"Authorization": "Bearer tok"}},
+		RequestMatcher{Method: http.MethodPost, Path: smd.SMDRelpathGroups + "/compute/members", JSON: map[string]string{"id": "x1000c0s0b0n0"}},
+		RequestMatcher{Method: http.MethodGet, Path: smd.SMDRelpathComponents},
+		RequestMatcher{Method: http.MethodGet, Path: smd.SMDRelpathGroups + "/compute/members"},
+		RequestMatcher{Method: http.MethodGet, Path: smd.SMDRelpathGroups + "/nonexistent/members"},
+	)
+}
+
+func TestFakeBSS(t *testing.T) {
+	f := NewFakeBSS(t)
+	bc, err := bss.NewClient(f.URL(), false)
+	if err != nil {
+		t.Fatalf("failed to create BSS client: %v", err)
+	}
+
+	bp := bssTypes.BootParams{
+		Hosts:  []string{"x1000c0s0b0n0", "x1000c0s1b0n0"},
+		Kernel: "http://example.com/vmlinuz",
+		Params: "console=ttyS0",
+	}
+	if _, err := bc.PostBootParams(bp, ""); err != nil {
+		t.Fatalf("PostBootParams returned error: %v", err)
+	}
+	patch := bssTypes.BootParams{Hosts: []string{"x1000c0s1b0n0"}, Params: "quiet"}
+	if _, err := bc.PatchBootParams(patch, ""); err != nil {
+		t.Fatalf("PatchBootParams returned error: %v", err)
+	}
+
+	henv, err := bc.GetBootParams("name=x1000c0s1b0n0", "")
+	if err != nil {
+		t.Fatalf("GetBootParams returned error: %v", err)
+	}
+	var got []bssTypes.BootParams
+	if err := json.Unmarshal(henv.Body, &got); err != nil {
+		t.Fatalf("failed to unmarshal boot params: %v", err)
+	}
+	want := []bssTypes.BootParams{{
+		Hosts:  []string{"x1000c0s1b0n0"},
+		Kernel: "http://example.com/vmlinuz",
+		Params: "quiet",
+	}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("boot params = %+v, want %+v", got, want)
+	}
+
+	if _, err := bc.DeleteBootParams(bssTypes.BootParams{Hosts: []string{"x1000c0s0b0n0"}}, ""); err != nil {
+		t.Fatalf("DeleteBootParams returned error: %v", err)
+	}
+	if n := len(f.BootParams()); n != 1 {
+		t.Errorf("got %d stored boot params after delete, want 1", n)
+	}
+}
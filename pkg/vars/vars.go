@@ -0,0 +1,209 @@
+// Package vars implements a store of key/value variables of nodes and groups,
+// such as site-specific IP addresses or serial console settings, that are made
+// available to templates when rendering kernel parameters and cloud-init
+// configs. Variables are kept either in a local file or in the meta-data of a
+// cloud-init group.
+package vars
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CloudInitGroup is the name of the cloud-init group in whose meta-data the
+// variables of a cluster are stored when no file is configured for them. The
+// group has no members, so its meta-data is not served to any node.
+const CloudInitGroup = "ochami-vars"
+
+// Scope is the kind of entity that variables are set for.
+type Scope string
+
+const (
+	ScopeNode  Scope = "node"
+	ScopeGroup Scope = "group"
+)
+
+// keyRegexp matches valid variable keys, which must be identifiers so that
+// they can be referenced as fields in templates (e.g. .vars.console).
+var keyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Vars are the variables of nodes, by xname, and of groups, by name. Each maps
+// variable keys to values.
+type Vars struct {
+	Nodes  map[string]map[string]string `json:"nodes,omitempty" yaml:"nodes,omitempty"`
+	Groups map[string]map[string]string `json:"groups,omitempty" yaml:"groups,omitempty"`
+}
+
+// ParsePair parses the variable s of the form "<key>=<value>". Keys consist of
+// letters, digits, and underscores and do not start with a digit. Values can
+// be anything, including empty.
+func ParsePair(s string) (string, string, error) {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok {
+		return "", "", fmt.Errorf("invalid variable %q: expected <key>=<value>", s)
+	}
+	if err := CheckKey(key); err != nil {
+		return "", "", err
+	}
+
+	return key, value, nil
+}
+
+// CheckKey returns an error if key is not a valid variable key.
+func CheckKey(key string) error {
+	if !keyRegexp.MatchString(key) {
+		return fmt.Errorf("invalid variable key %q: must consist of letters, digits, and '_' and not start with a digit", key)
+	}
+	return nil
+}
+
+// scope returns the variables of v in scope s, creating the map if create is
+// true and it does not exist, along with the name of name in that scope.
+// Xnames are compared regardless of case, so node names are lowercased.
+func (v *Vars) scope(s Scope, name string, create bool) (map[string]map[string]string, string) {
+	m := &v.Groups
+	if s == ScopeNode {
+		m = &v.Nodes
+		name = strings.ToLower(name)
+	}
+	if *m == nil && create {
+		*m = make(map[string]map[string]string)
+	}
+
+	return *m, name
+}
+
+// Set sets key to value for name in scope s, returning the previous value and
+// whether key was set.
+func (v *Vars) Set(s Scope, name, key, value string) (string, bool) {
+	m, name := v.scope(s, name, true)
+	if m[name] == nil {
+		m[name] = make(map[string]string)
+	}
+	old, ok := m[name][key]
+	m[name][key] = value
+
+	return old, ok
+}
+
+// Get returns a copy of the variables of name in scope s, which is empty if
+// it has none.
+func (v Vars) Get(s Scope, name string) map[string]string {
+	m, name := v.scope(s, name, false)
+	out := maps.Clone(m[name])
+	if out == nil {
+		out = make(map[string]string)
+	}
+
+	return out
+}
+
+// Names returns the sorted names of the nodes or groups in scope s that have
+// variables set.
+func (v Vars) Names(s Scope) []string {
+	m, _ := v.scope(s, "", false)
+	return slices.Sorted(maps.Keys(m))
+}
+
+// Resolve returns the variables of the node xname, which is a member of
+// groups. The variables of its groups are merged in the order of their sorted
+// names, so the last group to set a key wins, and the variables of the node
+// itself override those of its groups.
+func (v Vars) Resolve(xname string, groups []string) map[string]string {
+	out := make(map[string]string)
+	for _, g := range slices.Sorted(slices.Values(groups)) {
+		maps.Copy(out, v.Groups[g])
+	}
+	maps.Copy(out, v.Get(ScopeNode, xname))
+
+	return out
+}
+
+// ReadFile reads the variables stored in the YAML or JSON file at path. If the
+// file does not exist, no variables are returned.
+func ReadFile(path string) (Vars, error) {
+	var v Vars
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return v, nil
+	} else if err != nil {
+		return v, fmt.Errorf("failed to read variables from %s: %w", path, err)
+	}
+	// YAML is a superset of JSON, so both can be read as YAML
+	if err := yaml.Unmarshal(b, &v); err != nil {
+		return v, fmt.Errorf("failed to unmarshal variables from %s: %w", path, err)
+	}
+
+	return v, nil
+}
+
+// WriteFile writes v to the file at path as YAML, replacing it atomically so
+// that readers never see a partially written file.
+func WriteFile(path string, v Vars) error {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal variables: %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write variables to %s: %w", path, err)
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write variables to %s: %w", path, err)
+	}
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write variables to %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write variables to %s: %w", path, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("failed to write variables to %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// FromMetaData returns the variables stored in md, the meta-data of
+// CloudInitGroup.
+func FromMetaData(md map[string]any) (Vars, error) {
+	var v Vars
+	b, err := json.Marshal(md)
+	if err != nil {
+		return v, fmt.Errorf("failed to marshal cloud-init meta-data: %w", err)
+	}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return v, fmt.Errorf("failed to unmarshal variables from cloud-init meta-data: %w", err)
+	}
+
+	return v, nil
+}
+
+// MetaData returns v as the meta-data of CloudInitGroup.
+func (v Vars) MetaData() map[string]any {
+	md := make(map[string]any)
+	if len(v.Nodes) > 0 {
+		md["nodes"] = v.Nodes
+	}
+	if len(v.Groups) > 0 {
+		md["groups"] = v.Groups
+	}
+
+	return md
+}
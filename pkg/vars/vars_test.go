@@ -0,0 +1,104 @@
+package vars
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParsePair(t *testing.T) {
+	tests := []struct {
+		in         string
+		key, value string
+		wantErr    bool
+	}{
+		{in: "console=ttyS0,115200", key: "console", value: "ttyS0,115200"},
+		{in: "ip_addr=10.0.0.1=x", key: "ip_addr", value: "10.0.0.1=x"},
+		{in: "empty=", key: "empty", value: ""},
+		{in: "novalue", wantErr: true},
+		{in: "1st=x", wantErr: true},
+		{in: "has-dash=x", wantErr: true},
+		{in: "=x", wantErr: true},
+	}
+	for _, tt := range tests {
+		key, value, err := ParsePair(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParsePair(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if key != tt.key || value != tt.value {
+			t.Errorf("ParsePair(%q) = %q, %q, want %q, %q", tt.in, key, value, tt.key, tt.value)
+		}
+	}
+}
+
+func TestVars_SetGetResolve(t *testing.T) {
+	var v Vars
+	if _, ok := v.Set(ScopeNode, "X1000C0S0B0N0", "console", "ttyS0"); ok {
+		t.Errorf("Set() of new key reported it was set")
+	}
+	if old, ok := v.Set(ScopeNode, "x1000c0s0b0n0", "console", "ttyS1"); !ok || old != "ttyS0" {
+		t.Errorf("Set() = %q, %v, want %q, true", old, ok, "ttyS0")
+	}
+	v.Set(ScopeGroup, "compute", "console", "ttyS0")
+	v.Set(ScopeGroup, "compute", "site", "a")
+	v.Set(ScopeGroup, "gpu", "site", "b")
+
+	if got, want := v.Get(ScopeNode, "X1000c0s0b0n0"), map[string]string{"console": "ttyS1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Get() = %v, want %v", got, want)
+	}
+	if got := v.Get(ScopeGroup, "none"); got == nil || len(got) != 0 {
+		t.Errorf("Get() of unknown group = %v, want empty map", got)
+	}
+	if got, want := v.Names(ScopeGroup), []string{"compute", "gpu"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+
+	want := map[string]string{"console": "ttyS1", "site": "b"}
+	if got := v.Resolve("x1000c0s0b0n0", []string{"gpu", "compute"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve() = %v, want %v", got, want)
+	}
+	want = map[string]string{"console": "ttyS0", "site": "a"}
+	if got := v.Resolve("x1000c0s1b0n0", []string{"compute"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Resolve() = %v, want %v", got, want)
+	}
+}
+
+func TestReadWriteFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "vars.yaml")
+	v, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() of missing file returned error: %v", err)
+	}
+	if !reflect.DeepEqual(v, Vars{}) {
+		t.Errorf("ReadFile() of missing file = %+v, want empty", v)
+	}
+
+	v.Set(ScopeNode, "x1000c0s0b0n0", "console", "ttyS0")
+	v.Set(ScopeGroup, "compute", "site", "a")
+	if err := WriteFile(path, v); err != nil {
+		t.Fatalf("WriteFile() returned error: %v", err)
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("ReadFile() = %+v, want %+v", got, v)
+	}
+}
+
+func TestMetaData(t *testing.T) {
+	var v Vars
+	v.Set(ScopeNode, "x1000c0s0b0n0", "console", "ttyS0")
+	got, err := FromMetaData(v.MetaData())
+	if err != nil {
+		t.Fatalf("FromMetaData() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("FromMetaData() = %+v, want %+v", got, v)
+	}
+	if got, err := FromMetaData(nil); err != nil || !reflect.DeepEqual(got, Vars{}) {
+		t.Errorf("FromMetaData(nil) = %+v, %v, want empty", got, err)
+	}
+}
@@ -0,0 +1,79 @@
+package xname
+
+import (
+	"regexp"
+	"strings"
+)
+
+// xnamePart matches one level of the xname hierarchy, a letter followed by a
+// number (e.g. c0 or s30).
+var xnamePart = regexp.MustCompile(`[a-z]\d+`)
+
+// partNames are the human-readable names of the levels of the xname hierarchy,
+// by letter, used to describe the levels below the nearest mapped ancestor.
+var partNames = map[byte]string{
+	'x': "cabinet",
+	'c': "chassis",
+	'r': "router",
+	's': "slot",
+	'w': "switch",
+	'b': "BMC",
+	'n': "node",
+}
+
+// Locations maps xnames to descriptions of their physical locations, so that
+// datacenter technicians can find hardware from command output, e.g.
+// x3000c0s30b0n0 to "Rack 12, U30, left node". A description applies to the
+// xname and everything under it unless a descendant has its own.
+type Locations struct {
+	Locations map[string]string `json:"locations" yaml:"locations"`
+}
+
+// Merge returns the locations of l with those of other added, the ones of
+// other taking precedence.
+func (l Locations) Merge(other Locations) Locations {
+	merged := Locations{Locations: make(map[string]string, len(l.Locations)+len(other.Locations))}
+	for _, src := range []Locations{l, other} {
+		for x, desc := range src.Locations {
+			merged.Locations[strings.ToLower(x)] = desc
+		}
+	}
+
+	return merged
+}
+
+// Describe returns the physical location of xname: the description of its
+// nearest mapped ancestor (or of itself), followed by the levels of the xname
+// hierarchy below that ancestor, e.g. "Rack 12, U30, BMC 0, node 1" for
+// x3000c0s30b0n1 if x3000c0s30 is mapped to "Rack 12, U30". Xnames are compared
+// regardless of case. If neither xname nor any of its ancestors is mapped, or
+// xname is not an xname (e.g. a MAC address), "" is returned.
+func (l Locations) Describe(xname string) string {
+	x := strings.ToLower(xname)
+	parts := xnamePart.FindAllString(x, -1)
+	if len(parts) == 0 || strings.Join(parts, "") != x || parts[0][0] != 'x' {
+		return ""
+	}
+	lower := make(map[string]string, len(l.Locations))
+	for k, desc := range l.Locations {
+		lower[strings.ToLower(k)] = desc
+	}
+
+	for depth := len(parts); depth > 0; depth-- {
+		desc, ok := lower[strings.Join(parts[:depth], "")]
+		if !ok {
+			continue
+		}
+		words := []string{desc}
+		for _, p := range parts[depth:] {
+			name, ok := partNames[p[0]]
+			if !ok {
+				name = p[:1]
+			}
+			words = append(words, name+" "+p[1:])
+		}
+		return strings.Join(words, ", ")
+	}
+
+	return ""
+}
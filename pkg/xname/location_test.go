@@ -0,0 +1,47 @@
+package xname
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLocations_Describe(t *testing.T) {
+	locs := Locations{Locations: map[string]string{
+		"x3000":          "Row A, Rack 12",
+		"X3000c0s30":     "Rack 12, U30",
+		"x3000c0s30b0n0": "Rack 12, U30, left node",
+	}}
+	tests := []struct {
+		xname string
+		want  string
+	}{
+		{xname: "x3000c0s30b0n0", want: "Rack 12, U30, left node"},
+		{xname: "x3000c0s30b0n1", want: "Rack 12, U30, BMC 0, node 1"},
+		{xname: "x3000c0s30b0", want: "Rack 12, U30, BMC 0"},
+		{xname: "X3000C0S30", want: "Rack 12, U30"},
+		{xname: "x3000c0s4b0n0", want: "Row A, Rack 12, chassis 0, slot 4, BMC 0, node 0"},
+		{xname: "x3000c0w14", want: "Row A, Rack 12, chassis 0, switch 14"},
+		{xname: "x3000c0s4e0", want: "Row A, Rack 12, chassis 0, slot 4, e 0"},
+		{xname: "x30001c0s4b0n0", want: ""},
+		{xname: "x1000c0s0b0n0", want: ""},
+		{xname: "de:ca:fc:0f:fe:e0", want: ""},
+		{xname: "compute", want: ""},
+		{xname: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.xname, func(t *testing.T) {
+			if got := locs.Describe(tt.xname); got != tt.want {
+				t.Errorf("Describe(%q) = %q, want %q", tt.xname, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocations_Merge(t *testing.T) {
+	file := Locations{Locations: map[string]string{"x3000": "Rack 12", "x3001": "Rack 13"}}
+	inline := Locations{Locations: map[string]string{"X3001": "Rack 13 (spare)"}}
+	want := Locations{Locations: map[string]string{"x3000": "Rack 12", "x3001": "Rack 13 (spare)"}}
+	if got := file.Merge(inline); !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %v, want %v", got, want)
+	}
+}
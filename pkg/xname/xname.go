@@ -2,6 +2,9 @@ package xname
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
 
 	"github.com/openchami/schemas/schemas/csm"
 )
@@ -40,3 +43,52 @@ func NodeXnameToBMCXname(xname string) (string, error) {
 	}
 	return bmcXnameStr, nil
 }
+
+// IsDescendant returns true if xname is ancestor or is located under it in the
+// xname hierarchy (e.g. x3000c0s0b0n0 is a descendant of x3000c0 but x3000c01
+// is not). Comparison is case insensitive.
+func IsDescendant(xname, ancestor string) bool {
+	x, a := strings.ToLower(xname), strings.ToLower(ancestor)
+	if a == "" || !strings.HasPrefix(x, a) {
+		return false
+	}
+	rest := x[len(a):]
+
+	return rest == "" || !unicode.IsDigit(rune(rest[0]))
+}
+
+// Depth returns the number of levels of the xname hierarchy in xname, i.e. the
+// number of letter-number pairs (e.g. 2 for x3000c0 and 5 for x3000c0s0b0n0).
+func Depth(xname string) int {
+	var depth int
+	for i, c := range xname {
+		if unicode.IsLetter(c) && (i == 0 || unicode.IsDigit(rune(xname[i-1]))) {
+			depth++
+		}
+	}
+
+	return depth
+}
+
+// Cabinet returns the number and xname of the cabinet (rack) that xname is in
+// (e.g. 3000 and x3000 for x3000c0s0b0n0), or false if xname does not start
+// with a cabinet.
+func Cabinet(xname string) (int, string, bool) {
+	x := strings.ToLower(xname)
+	if !strings.HasPrefix(x, "x") {
+		return 0, "", false
+	}
+	end := 1
+	for end < len(x) && unicode.IsDigit(rune(x[end])) {
+		end++
+	}
+	if end == 1 || (end < len(x) && !unicode.IsLetter(rune(x[end]))) {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(x[1:end])
+	if err != nil {
+		return 0, "", false
+	}
+
+	return n, x[:end], true
+}
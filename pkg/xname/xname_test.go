@@ -158,3 +158,61 @@ func TestNodeXnameToBMCXname(t *testing.T) {
 		})
 	}
 }
+
+func TestIsDescendant(t *testing.T) {
+	tests := []struct {
+		xname    string
+		ancestor string
+		want     bool
+	}{
+		{xname: "x3000c0s0b0n0", ancestor: "x3000c0", want: true},
+		{xname: "x3000c0", ancestor: "x3000c0", want: true},
+		{xname: "X3000C0S1B0", ancestor: "x3000c0", want: true},
+		{xname: "x3000c01s0b0", ancestor: "x3000c0", want: false},
+		{xname: "x3000c1s0b0", ancestor: "x3000c0", want: false},
+		{xname: "x3000c0", ancestor: "", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.xname+"/"+tt.ancestor, func(t *testing.T) {
+			if got := IsDescendant(tt.xname, tt.ancestor); got != tt.want {
+				t.Errorf("IsDescendant() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDepth(t *testing.T) {
+	tests := map[string]int{
+		"x3000":           1,
+		"x3000c0":         2,
+		"x3000c0s0b0n0":   5,
+		"x3000c0s0b0n0p1": 6,
+	}
+	for x, want := range tests {
+		if got := Depth(x); got != want {
+			t.Errorf("Depth(%s) = %d, want %d", x, got, want)
+		}
+	}
+}
+
+func TestCabinet(t *testing.T) {
+	tests := []struct {
+		xname string
+		num   int
+		cab   string
+		ok    bool
+	}{
+		{"x3000c0s0b0n0", 3000, "x3000", true},
+		{"X1001C0", 1001, "x1001", true},
+		{"x7", 7, "x7", true},
+		{"node07", 0, "", false},
+		{"x", 0, "", false},
+		{"x12-3", 0, "", false},
+	}
+	for _, tt := range tests {
+		num, cab, ok := Cabinet(tt.xname)
+		if num != tt.num || cab != tt.cab || ok != tt.ok {
+			t.Errorf("Cabinet(%s) = %d, %s, %t, want %d, %s, %t", tt.xname, num, cab, ok, tt.num, tt.cab, tt.ok)
+		}
+	}
+}